@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/market"
+	"nofx/mcp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MCP服务端模式
+// 在 POST /mcp 上提供 Model Context Protocol 工具，外部的MCP客户端可以查询行情、持仓并提交下单提议。
+// 下单提议不会直接执行：进入trader的人工审批队列（需启用 approval），与AI的交易想法一样由人批准。
+// 工具会暴露持仓并产生下单提议，端点与其他写操作一样需要 web_username / web_password 的 HTTP Basic 认证。
+
+// mcpServerVersion initialize 时返回的服务端版本
+const mcpServerVersion = "1.0.0"
+
+// EnableMCP 注册 /mcp 端点（需在 Start 之前调用）
+func (s *Server) EnableMCP() {
+	server := mcp.NewServer("nofx", mcpServerVersion)
+
+	server.AddTool(mcp.Tool{
+		Name:        "get_market_data",
+		Description: "获取币种的当前价格、EMA/MACD/RSI指标、持仓量、资金费率以及3分钟和4小时序列（与AI决策时看到的数据相同）",
+		InputSchema: objectSchema(map[string]interface{}{
+			"symbol": stringProp("币种，如 BTCUSDT"),
+		}, "symbol"),
+		Handler: s.mcpGetMarketData,
+	})
+
+	server.AddTool(mcp.Tool{
+		Name:        "get_positions",
+		Description: "获取trader的当前持仓（入场价、标记价、数量、杠杆、未实现盈亏、保证金、强平价）；不指定trader_id时返回所有trader",
+		InputSchema: objectSchema(map[string]interface{}{
+			"trader_id": stringProp("trader ID（可选）"),
+		}),
+		Handler: s.mcpGetPositions,
+	})
+
+	server.AddTool(mcp.Tool{
+		Name: "place_order_proposal",
+		Description: "提交开仓/加仓提议。提议按AI决策相同的规则校验（杠杆上限、仓位大小、止损止盈、风险回报比、币种黑白名单）后进入人工审批队列，" +
+			"只有人工批准后才会执行，超时未批准自动作废。返回提议ID和状态",
+		InputSchema: objectSchema(map[string]interface{}{
+			"trader_id":         stringProp("trader ID（可选，默认第一个trader）"),
+			"symbol":            stringProp("币种，如 BTCUSDT"),
+			"action":            map[string]interface{}{"type": "string", "enum": []string{"open_long", "open_short", "add_to_position"}},
+			"leverage":          map[string]interface{}{"type": "integer", "minimum": 1},
+			"position_size_usd": map[string]interface{}{"type": "number", "description": "仓位名义价值（USDT）"},
+			"stop_loss":         map[string]interface{}{"type": "number"},
+			"take_profit":       map[string]interface{}{"type": "number"},
+			"confidence":        map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 100},
+			"risk_usd":          map[string]interface{}{"type": "number", "description": "止损时的最大亏损（USDT）"},
+			"reasoning":         stringProp("提议理由（显示在审批通知中）"),
+		}, "symbol", "action", "leverage", "position_size_usd", "stop_loss", "take_profit", "reasoning"),
+		Handler: s.mcpPlaceOrderProposal,
+	})
+
+	s.router.POST("/mcp", s.requireAuth(), gin.WrapH(server))
+	log.Printf("✓ MCP服务端: POST /mcp（工具: get_market_data, get_positions, place_order_proposal）")
+}
+
+func (s *Server) mcpGetMarketData(args json.RawMessage) (interface{}, error) {
+	var params struct {
+		Symbol string `json:"symbol"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil || params.Symbol == "" {
+		return nil, fmt.Errorf("需要参数 symbol")
+	}
+	data, err := market.Get(market.Normalize(params.Symbol))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"symbol":  data.Symbol,
+		"summary": market.Format(data),
+		"data":    data,
+	}, nil
+}
+
+func (s *Server) mcpGetPositions(args json.RawMessage) (interface{}, error) {
+	var params struct {
+		TraderID string `json:"trader_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("参数无效: %w", err)
+	}
+
+	ids := []string{params.TraderID}
+	if params.TraderID == "" {
+		ids = s.traderManager.GetTraderIDs()
+	}
+	result := make(map[string]interface{}, len(ids))
+	for _, id := range ids {
+		trader, err := s.traderManager.GetTrader(id)
+		if err != nil {
+			return nil, err
+		}
+		positions, err := trader.GetPositions()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		if positions == nil {
+			positions = []map[string]interface{}{}
+		}
+		result[id] = positions
+	}
+	return result, nil
+}
+
+func (s *Server) mcpPlaceOrderProposal(args json.RawMessage) (interface{}, error) {
+	var params struct {
+		TraderID string `json:"trader_id"`
+		decision.Decision
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("参数无效: %w", err)
+	}
+
+	traderID := params.TraderID
+	if traderID == "" {
+		ids := s.traderManager.GetTraderIDs()
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("没有可用的trader")
+		}
+		traderID = ids[0]
+	}
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		return nil, err
+	}
+	idea, err := trader.ProposeIdea(params.Decision, "mcp")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"id":         idea.ID,
+		"trader_id":  idea.TraderID,
+		"status":     idea.Status,
+		"price":      idea.IntendedPrice,
+		"expires_at": idea.ExpiresAt,
+		"note":       "提议已进入人工审批队列，批准后才会执行",
+	}, nil
+}
+
+// objectSchema 工具参数的JSON Schema
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringProp(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
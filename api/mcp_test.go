@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"nofx/manager"
+	"strings"
+	"testing"
+)
+
+// serve 直接调用路由处理请求（不监听端口）
+func serve(s *Server, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func mcpRequest(body, username, password string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if username != "" || password != "" {
+		req.SetBasicAuth(username, password)
+	}
+	return req
+}
+
+func TestMCPRequiresAuth(t *testing.T) {
+	const toolsList = `{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`
+	const proposal = `{"jsonrpc": "2.0", "id": 2, "method": "tools/call", "params": {"name": "place_order_proposal", "arguments": {}}}`
+
+	s := NewServer(manager.NewTraderManager(), 0, "admin", "secret")
+	s.EnableMCP()
+
+	for _, tt := range []struct {
+		name               string
+		username, password string
+	}{
+		{name: "未认证"},
+		{name: "密码错误", username: "admin", password: "wrong"},
+		{name: "用户名错误", username: "root", password: "secret"},
+	} {
+		for _, body := range []string{toolsList, proposal} {
+			w := serve(s, mcpRequest(body, tt.username, tt.password))
+			if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("%s: status %d, body %s", tt.name, w.Code, w.Body.String())
+			}
+			if strings.Contains(w.Body.String(), "jsonrpc") {
+				t.Errorf("%s: 未认证的请求不应到达MCP服务端: %s", tt.name, w.Body.String())
+			}
+		}
+	}
+
+	w := serve(s, mcpRequest(toolsList, "admin", "secret"))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "place_order_proposal") {
+		t.Fatalf("认证后应返回工具列表: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMCPRefusedWithoutCredentialsConfigured(t *testing.T) {
+	s := NewServer(manager.NewTraderManager(), 0, "", "")
+	s.EnableMCP()
+	w := serve(s, mcpRequest(`{"jsonrpc": "2.0", "id": 1, "method": "initialize"}`, "", ""))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("未配置用户名密码时应拒绝: %d %s", w.Code, w.Body.String())
+	}
+}
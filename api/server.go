@@ -1,10 +1,23 @@
 package api
 
 import (
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"html/template"
 	"log"
 	"net/http"
+	"nofx/cache"
+	"nofx/decision"
+	"nofx/logger"
 	"nofx/manager"
+	"nofx/market"
+	"nofx/pool"
+	"nofx/ratelimit"
+	"nofx/trader"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +29,17 @@ type Server struct {
 	port          int
 	webUsername   string // Web dashboard username
 	webPassword   string // Web dashboard password
+
+	// ideaTrader 按ID获取处理审批链接的trader（默认从traderManager获取）
+	ideaTrader func(traderID string) (ideaDecider, error)
+}
+
+// ideaDecider 审批链接用到的trader操作
+type ideaDecider interface {
+	TradeIdea(id string) (trader.TradeIdea, bool)
+	VerifyIdeaToken(id, token string) bool
+	ApproveIdea(id string) (*trader.TradeIdea, error)
+	DenyIdea(id, reason string) (*trader.TradeIdea, error)
 }
 
 // NewServer 创建API服务器
@@ -35,6 +59,9 @@ func NewServer(traderManager *manager.TraderManager, port int, webUsername, webP
 		webUsername:   webUsername,
 		webPassword:   webPassword,
 	}
+	s.ideaTrader = func(traderID string) (ideaDecider, error) {
+		return traderManager.GetTrader(traderID)
+	}
 
 	// 设置路由
 	s.setupRoutes()
@@ -58,12 +85,35 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// requireAuth 写操作认证中间件：请求需带与 web_username / web_password 一致的 HTTP Basic 认证
+// 未配置用户名和密码时拒绝写操作（只读接口不受影响）
+func (s *Server) requireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.webUsername == "" && s.webPassword == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "写操作需要先在配置中设置 web_username / web_password"})
+			return
+		}
+		username, password, ok := c.Request.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(s.webUsername)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(s.webPassword)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="nofx"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "需要认证"})
+			return
+		}
+		c.Next()
+	}
+}
+
 // setupRoutes 设置路由
 func (s *Server) setupRoutes() {
 	// 健康检查
 	s.router.Any("/health", s.handleHealth)
 
-	// API路由组
+	// 实时事件推送（周期、决策、成交、止损触发、风控）
+	s.router.GET("/ws", s.handleWebSocket)
+
+	// API路由组（改变状态或产生AI调用费用的接口需要认证，见 requireAuth）
 	api := s.router.Group("/api")
 	{
 		// 登录认证（公开端点，不需要密码）
@@ -75,15 +125,84 @@ func (s *Server) setupRoutes() {
 		// Trader列表
 		api.GET("/traders", s.handleTraderList)
 
+		// 买入持有基准
+		api.GET("/benchmarks", s.handleBenchmarks)
+		api.GET("/benchmarks/history", s.handleBenchmarkHistory)
+
+		// 交易所限频预算
+		api.GET("/ratelimits", s.handleRateLimits)
+
+		// 内存缓存命中率和加载次数
+		api.GET("/caches", s.handleCaches)
+
+		// 默认币种列表及远程同步状态
+		api.GET("/coins/default", s.handleDefaultCoins)
+
+		// 行情数据源延迟/错误率和当前价数据源选择
+		api.GET("/market/providers", s.handleMarketProviders)
+		api.GET("/market/breakers", s.handleMarketBreakers)
+		api.GET("/market/capabilities", s.handleMarketCapabilities)
+		api.GET("/market/surges", s.handleMarketSurges)
+
+		// AI调用调度（并发名额与排队耗时）
+		api.GET("/ai-scheduler", s.handleAIScheduler)
+
+		// 交易循环卡死检测（心跳、卡死与重启次数）
+		api.GET("/watchdog", s.handleWatchdog)
+
+		// 跨trader的合并敞口（按币种汇总多空持仓）
+		api.GET("/exposure", s.handleExposure)
+
+		// 配置无效、未启动的trader（字段级错误）
+		api.GET("/config/errors", s.handleConfigErrors)
+
+		// 按决策理由检索历史决策（关键字 + 可选的语义相似度，跨trader）
+		api.GET("/decisions/search", s.handleDecisionSearch)
+
 		// 指定trader的数据（使用query参数 ?trader_id=xxx）
 		api.GET("/status", s.handleStatus)
 		api.GET("/account", s.handleAccount)
 		api.GET("/positions", s.handlePositions)
+		api.GET("/positions/lifecycle", s.handlePositionLifecycle)
 		api.GET("/decisions", s.handleDecisions)
 		api.GET("/decisions/latest", s.handleLatestDecisions)
+		api.GET("/decisions/prompt", s.handleDecisionPrompt)
+		api.GET("/decisions/market-snapshot", s.handleMarketSnapshot)
+		api.GET("/decisions/chart", s.handleDecisionChart)
+		api.GET("/reports/daily", s.handleDailyReport)
 		api.GET("/statistics", s.handleStatistics)
+		api.POST("/trades/import", s.requireAuth(), s.handleTradeImport)
+		api.GET("/reconciliation", s.handleReconciliation)
+		api.POST("/reconciliation", s.requireAuth(), s.handleRunReconciliation)
 		api.GET("/equity-history", s.handleEquityHistory)
 		api.GET("/performance", s.handlePerformance)
+		api.GET("/analytics/slippage", s.handleSlippage)
+		api.GET("/analytics/overtrading", s.handleOvertrading)
+		api.GET("/symbol-filter", s.handleGetSymbolFilter)
+		api.PUT("/symbol-filter", s.requireAuth(), s.handleUpdateSymbolFilter)
+		api.GET("/profile", s.handleGetProfile)
+		api.PUT("/profile", s.requireAuth(), s.handleUpdateProfile)
+		api.GET("/experiment", s.handleExperiment)
+		api.GET("/analytics/tags", s.handleTagReport)
+
+		// 操作员对决策和交易的备注/标签
+		api.GET("/annotations", s.handleAnnotations)
+		api.POST("/annotations", s.requireAuth(), s.handleAddAnnotation)
+		api.DELETE("/annotations", s.requireAuth(), s.handleDeleteAnnotation)
+		api.POST("/analyze", s.requireAuth(), s.handleAnalyze)
+
+		// 仓位预览：模拟一个开仓/加仓决策（不下单）
+		api.POST("/traders/:id/simulate", s.handleSimulate)
+
+		// 周期报告：上下文摘要、prompt大小、AI延迟、决策结果、下单成交和错误
+		api.GET("/traders/:id/cycles/:n", s.handleCycleReport)
+
+		// 人工审批的交易想法
+		api.GET("/ideas", s.handleTradeIdeas)
+		api.POST("/ideas/approve", s.requireAuth(), s.handleApproveIdea)
+		api.POST("/ideas/deny", s.requireAuth(), s.handleDenyIdea)
+		api.GET("/ideas/decide", s.handleConfirmIdeaLink) // 通知按钮链接：只显示确认页（需要token）
+		api.POST("/ideas/decide", s.handleDecideIdeaLink) // 确认页提交后执行（需要token）
 	}
 }
 
@@ -176,6 +295,113 @@ func (s *Server) handleTraderList(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// handleBenchmarks 所有买入持有基准的最新净值
+func (s *Server) handleBenchmarks(c *gin.Context) {
+	c.JSON(http.StatusOK, s.traderManager.GetBenchmarkSummaries())
+}
+
+// handleBenchmarkHistory 基准净值历史（与 /api/equity-history 对照计算alpha曲线）
+func (s *Server) handleBenchmarkHistory(c *gin.Context) {
+	benchmarkID := c.DefaultQuery("benchmark_id", "benchmark_btc")
+	b, err := s.traderManager.GetBenchmark(benchmarkID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	type BenchmarkPoint struct {
+		Timestamp   string  `json:"timestamp"`
+		TotalEquity float64 `json:"total_equity"`
+		TotalPnLPct float64 `json:"total_pnl_pct"`
+	}
+	history := make([]BenchmarkPoint, 0)
+	for _, p := range b.History() {
+		history = append(history, BenchmarkPoint{
+			Timestamp:   p.Timestamp.Format("2006-01-02 15:04:05"),
+			TotalEquity: p.Equity,
+			TotalPnLPct: p.ReturnPct,
+		})
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// handleRateLimits 各交易所限频预算（剩余权重、排队数、被限频冷却）
+func (s *Server) handleRateLimits(c *gin.Context) {
+	c.JSON(http.StatusOK, ratelimit.AllStats())
+}
+
+// handleCaches 内存缓存指标（命中率、加载次数、合并的并发请求）
+func (s *Server) handleCaches(c *gin.Context) {
+	c.JSON(http.StatusOK, cache.AllStats())
+}
+
+// handleDefaultCoins 默认币种列表及远程同步状态（ETag、最近一次更新和请求错误）
+func (s *Server) handleDefaultCoins(c *gin.Context) {
+	c.JSON(http.StatusOK, pool.GetDefaultCoinsStatus())
+}
+
+// handleMarketProviders 行情数据源健康统计和当前价数据源选择
+func (s *Server) handleMarketProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, market.ProviderSelection())
+}
+
+// handleMarketCapabilities 各行情数据源的能力矩阵（由一致性测试生成）
+func (s *Server) handleMarketCapabilities(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": market.CapabilityMatrix()})
+}
+
+// handleMarketBreakers 因行情数据异常被熔断的币种
+func (s *Server) handleMarketBreakers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"breakers": market.Breakers()})
+}
+
+// handleMarketSurges 最近一次成交量/持仓量异动扫描的结果
+func (s *Server) handleMarketSurges(c *gin.Context) {
+	snapshot := s.traderManager.GetSurges()
+	if snapshot == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "scan": snapshot})
+}
+
+// handleAIScheduler AI调用调度统计
+func (s *Server) handleAIScheduler(c *gin.Context) {
+	stats := s.traderManager.GetAISchedulerStats()
+	if stats == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "stats": stats})
+}
+
+// handleWatchdog 交易循环卡死检测统计
+func (s *Server) handleWatchdog(c *gin.Context) {
+	stats := s.traderManager.GetWatchdogStats()
+	if stats == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "stats": stats})
+}
+
+// handleExposure 跨trader的合并敞口
+func (s *Server) handleExposure(c *gin.Context) {
+	view := s.traderManager.GetExposure()
+	if view == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "exposure": view})
+}
+
+// handleConfigErrors 配置无效、未启动的trader
+func (s *Server) handleConfigErrors(c *gin.Context) {
+	invalid := s.traderManager.GetInvalidTraders()
+	c.JSON(http.StatusOK, gin.H{"count": len(invalid), "invalid_traders": invalid})
+}
+
 // handleStatus 系统状态
 func (s *Server) handleStatus(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
@@ -309,6 +535,202 @@ func (s *Server) handleLatestDecisions(c *gin.Context) {
 	c.JSON(http.StatusOK, records)
 }
 
+// handlePositionLifecycle 按持仓ID查询持仓的完整生命周期（开仓、加仓、部分平仓、调整止损止盈、平仓和持仓快照）
+func (s *Server) handlePositionLifecycle(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	positionID := c.Query("position_id")
+	if positionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少position_id参数"})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	lifecycle, err := trader.GetDecisionLogger().GetPositionLifecycle(positionID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, logger.ErrPositionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": fmt.Sprintf("获取持仓记录失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, lifecycle)
+}
+
+// handleDecisionPrompt 获取指定决策发送给AI的原始prompt
+func (s *Server) handleDecisionPrompt(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decisionID := c.Query("decision_id")
+	if decisionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少decision_id参数"})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	prompt, err := trader.GetDecisionLogger().GetPrompt(decisionID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, logger.ErrPromptNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": fmt.Sprintf("获取prompt失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":   traderID,
+		"decision_id": decisionID,
+		"prompt":      prompt,
+	})
+}
+
+// handleMarketSnapshot 获取指定决策周期AI看到的行情数据
+func (s *Server) handleMarketSnapshot(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decisionID := c.Query("decision_id")
+	if decisionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少decision_id参数"})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshot, err := trader.GetDecisionLogger().GetMarketSnapshot(decisionID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, logger.ErrSnapshotNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": fmt.Sprintf("获取行情快照失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":   traderID,
+		"decision_id": decisionID,
+		"market_data": snapshot,
+	})
+}
+
+// handleDecisionChart 指定决策的K线图（symbol 可选，format=png 返回PNG，默认SVG）
+func (s *Server) handleDecisionChart(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decisionID := c.Query("decision_id")
+	if decisionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少decision_id参数"})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ch, err := trader.DecisionChart(decisionID, c.Query("symbol"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, logger.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": fmt.Sprintf("生成K线图失败: %v", err),
+		})
+		return
+	}
+
+	contentType := "image/svg+xml"
+	render := ch.SVG
+	if c.Query("format") == "png" {
+		contentType = "image/png"
+		render = ch.PNG
+	}
+	data, err := render()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("生成K线图失败: %v", err),
+		})
+		return
+	}
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// handleDailyReport 指定trader的日报（date=YYYY-MM-DD，默认trader时区的昨天；format=text 返回推送用的文本）
+func (s *Server) handleDailyReport(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	date := time.Now().In(trader.Location()).AddDate(0, 0, -1)
+	if dateStr := c.Query("date"); dateStr != "" {
+		date, err = time.ParseInLocation("2006-01-02", dateStr, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date格式应为YYYY-MM-DD"})
+			return
+		}
+	}
+
+	report, err := s.traderManager.GetDailyReport(traderID, date)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "text" {
+		c.String(http.StatusOK, report.Format(trader.GetName()))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"report":    report,
+	})
+}
+
 // handleStatistics 统计信息
 func (s *Server) handleStatistics(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
@@ -334,6 +756,82 @@ func (s *Server) handleStatistics(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// handleTradeImport 导入第一条决策记录之前的交易所成交历史（?trader_id=xxx&days=30）
+func (s *Server) handleTradeImport(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	days := 30
+	if v := c.Query("days"); v != "" {
+		if days, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days 必须是整数"})
+			return
+		}
+	}
+
+	result, err := trader.ImportTradeHistory(days)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// handleReconciliation 最近一次对账结果（每日对账任务或手动对账）
+func (s *Server) handleReconciliation(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := trader.LastReconcileReport()
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "还没有对账结果，可以 POST /api/reconciliation 立即对账"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleRunReconciliation 立即对账最近 hours 小时（默认24）的决策日志与交易所成交/盈亏流水
+func (s *Server) handleRunReconciliation(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hours := 24
+	if v := c.Query("hours"); v != "" {
+		if hours, err = strconv.Atoi(v); err != nil || hours <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hours 必须是正整数"})
+			return
+		}
+	}
+
+	report, err := s.traderManager.Reconcile(traderID, time.Duration(hours)*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
 // handleEquityHistory 收益率历史数据
 func (s *Server) handleEquityHistory(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
@@ -358,6 +856,15 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 		return
 	}
 
+	// 导入的交易记录没有账户快照，不参与净值曲线
+	snapshots := records[:0]
+	for _, record := range records {
+		if !record.Imported {
+			snapshots = append(snapshots, record)
+		}
+	}
+	records = snapshots
+
 	// 构建收益率历史数据点
 	type EquityPoint struct {
 		Timestamp        string  `json:"timestamp"`
@@ -447,6 +954,649 @@ func (s *Server) handlePerformance(c *gin.Context) {
 	c.JSON(http.StatusOK, performance)
 }
 
+// handleSlippage 滑点与执行质量统计（不指定trader_id时汇总所有trader，cycles 默认500）
+func (s *Server) handleSlippage(c *gin.Context) {
+	cycles := 500
+	if cyclesStr := c.Query("cycles"); cyclesStr != "" {
+		n, err := strconv.Atoi(cyclesStr)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cycles必须是正整数"})
+			return
+		}
+		cycles = n
+	}
+
+	traderID := c.Query("trader_id")
+	if traderID != "" {
+		if _, err := s.traderManager.GetTrader(traderID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	analysis, err := s.traderManager.GetSlippageAnalysis(traderID, cycles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("统计滑点失败: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, analysis)
+}
+
+// handleOvertrading 过度交易检测（不指定trader_id时返回所有trader，hours 默认使用配置的统计小时数）
+func (s *Server) handleOvertrading(c *gin.Context) {
+	hours := 0
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		n, err := strconv.Atoi(hoursStr)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hours必须是正整数"})
+			return
+		}
+		hours = n
+	}
+
+	traderID := c.Query("trader_id")
+	if traderID != "" {
+		if _, err := s.traderManager.GetTrader(traderID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	reports, err := s.traderManager.GetOvertradingReports(traderID, hours)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("过度交易检测失败: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// symbolFilterResponse 黑白名单响应
+func symbolFilterResponse(traderID string, f *pool.SymbolFilter) gin.H {
+	return gin.H{
+		"trader_id":      traderID,
+		"blacklist":      f.Blacklist(),
+		"whitelist":      f.Whitelist(),
+		"whitelist_mode": f.WhitelistMode(),
+	}
+}
+
+// handleGetSymbolFilter 获取币种黑白名单
+func (s *Server) handleGetSymbolFilter(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, symbolFilterResponse(traderID, trader.GetSymbolFilter()))
+}
+
+// handleUpdateSymbolFilter 替换币种黑白名单，下一个周期生效（不写回配置文件，重启后恢复config.json中的设置）
+func (s *Server) handleUpdateSymbolFilter(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Blacklist []string `json:"blacklist"`
+		Whitelist []string `json:"whitelist"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请求格式错误: %v", err)})
+		return
+	}
+
+	filter := trader.GetSymbolFilter()
+	filter.Update(req.Blacklist, req.Whitelist)
+	log.Printf("🚫 [%s] 币种黑白名单已更新: 黑名单=%v 白名单=%v", trader.GetName(), filter.Blacklist(), filter.Whitelist())
+
+	c.JSON(http.StatusOK, symbolFilterResponse(traderID, filter))
+}
+
+// handleGetProfile 当前策略配置和可切换的策略配置
+func (s *Server) handleGetProfile(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"profile":   trader.GetProfile(),
+		"profiles":  s.traderManager.ProfileNames(),
+	})
+}
+
+// handleUpdateProfile 切换策略配置，当前周期结束后生效（不写回配置文件，重启后恢复config.json中的设置）
+func (s *Server) handleUpdateProfile(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Profile string `json:"profile"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Profile == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误: 需要 profile"})
+		return
+	}
+
+	if err := s.traderManager.ApplyProfile(traderID, req.Profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"profile":   req.Profile,
+		"profiles":  s.traderManager.ProfileNames(),
+	})
+}
+
+// handleExperiment A/B测试的当前变体和按变体的表现比较（?trader_id=xxx&days=30）
+func (s *Server) handleExperiment(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := trader.GetExperiment()
+	if status == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该trader未启用A/B测试"})
+		return
+	}
+
+	days := 30
+	if v := c.Query("days"); v != "" {
+		if days, err = strconv.Atoi(v); err != nil || days <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days 必须是正整数"})
+			return
+		}
+	}
+
+	report, err := trader.GetDecisionLogger().ExperimentReport(status.Name, status.Variants, days, time.Now().In(trader.Location()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("生成实验报告失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":  traderID,
+		"experiment": status,
+		"report":     report,
+	})
+}
+
+// handleAnnotations 操作员备注（?trader_id=xxx，可按 decision_id / position_id / tag 过滤）
+func (s *Server) handleAnnotations(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	annotations, err := trader.GetDecisionLogger().Annotations(logger.AnnotationFilter{
+		DecisionID: c.Query("decision_id"),
+		PositionID: c.Query("position_id"),
+		Tag:        c.Query("tag"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, annotations)
+}
+
+// handleAddAnnotation 给决策或交易加备注/标签（?trader_id=xxx，body: decision_id 或 position_id、note、tags、author）
+func (s *Server) handleAddAnnotation(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req logger.Annotation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请求格式错误: %v", err)})
+		return
+	}
+
+	annotation, err := trader.GetDecisionLogger().AddAnnotation(req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, logger.ErrRecordNotFound) || errors.Is(err, logger.ErrPositionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, annotation)
+}
+
+// handleDeleteAnnotation 删除备注（?trader_id=xxx&id=yyy）
+func (s *Server) handleDeleteAnnotation(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := trader.GetDecisionLogger().DeleteAnnotation(c.Query("id")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, logger.ErrAnnotationNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": c.Query("id")})
+}
+
+// handleTagReport 按操作员标签汇总最近平仓的交易（?trader_id=xxx&days=30）
+func (s *Server) handleTagReport(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	days := 30
+	if v := c.Query("days"); v != "" {
+		if days, err = strconv.Atoi(v); err != nil || days <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days 必须是正整数"})
+			return
+		}
+	}
+
+	report, err := trader.GetDecisionLogger().TagReport(days, time.Now().In(trader.Location()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("按标签汇总失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"report":    report,
+	})
+}
+
+// handleAnalyze 单币种即时分析（市场数据和技术指标，ask_ai 时附带AI意见，不执行任何决策）
+func (s *Server) handleAnalyze(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Symbol string `json:"symbol"`
+		AskAI  bool   `json:"ask_ai"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误: 需要 symbol"})
+		return
+	}
+
+	analysis, err := trader.AnalyzeSymbol(req.Symbol, req.AskAI)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, analysis)
+}
+
+// handleSimulate 模拟开仓/加仓决策：张数、保证金、估算强平价、手续费和下单后的保证金使用率，不下单
+func (s *Server) handleSimulate(c *gin.Context) {
+	trader, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var d decision.Decision
+	if err := c.ShouldBindJSON(&d); err != nil || d.Symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误: 需要决策JSON（symbol、action、position_size_usd、leverage）"})
+		return
+	}
+
+	sim, err := trader.SimulateDecision(d)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sim)
+}
+
+// handleCycleReport 指定周期的完整报告（n 为周期编号或决策ID，编号重复时取最新的一条）
+func (s *Server) handleCycleReport(c *gin.Context) {
+	trader, err := s.traderManager.GetTrader(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := trader.GetDecisionLogger().CycleReport(c.Param("n"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, logger.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": fmt.Sprintf("获取周期报告失败: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleDecisionSearch 在决策理由中检索（q 必填；不指定trader_id时检索所有trader，
+// from/to 为 YYYY-MM-DD，默认最近30天；启用语义检索时默认同时按相似度匹配，semantic=false 只做关键字匹配）
+func (s *Server) handleDecisionSearch(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q不能为空"})
+		return
+	}
+
+	traderID := c.Query("trader_id")
+	if traderID != "" {
+		if _, err := s.traderManager.GetTrader(traderID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if v := c.Query("to"); v != "" {
+		date, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to格式应为YYYY-MM-DD"})
+			return
+		}
+		to = date
+	}
+	from := to.AddDate(0, 0, -30)
+	if v := c.Query("from"); v != "" {
+		date, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from格式应为YYYY-MM-DD"})
+			return
+		}
+		from = date
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from不能晚于to"})
+		return
+	}
+
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit必须是正整数"})
+			return
+		}
+		limit = n
+	}
+
+	semantic := s.traderManager.SemanticSearchEnabled()
+	if v := c.Query("semantic"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "semantic必须是true或false"})
+			return
+		}
+		if b && !semantic {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "未启用语义检索（配置 semantic_search.enabled）"})
+			return
+		}
+		semantic = b
+	}
+
+	// to 包含当天
+	hits, err := s.traderManager.SearchDecisions(traderID, text, strings.ToUpper(c.Query("symbol")), from, to.AddDate(0, 0, 1).Add(-time.Nanosecond), limit, semantic)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("决策检索失败: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"query":    text,
+		"from":     from.Format("2006-01-02"),
+		"to":       to.Format("2006-01-02"),
+		"semantic": semantic,
+		"results":  hits,
+	})
+}
+
+// handleTradeIdeas 交易想法列表（最新的在前）
+func (s *Server) handleTradeIdeas(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":     traderID,
+		"approval_mode": trader.ApprovalEnabled(),
+		"ideas":         trader.TradeIdeas(),
+	})
+}
+
+// handleApproveIdea 批准并执行交易想法（?trader_id=xxx&id=yyy）
+func (s *Server) handleApproveIdea(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	idea, err := trader.ApproveIdea(c.Query("id"))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "idea": idea})
+		return
+	}
+	c.JSON(http.StatusOK, idea)
+}
+
+// handleDenyIdea 拒绝交易想法（?trader_id=xxx&id=yyy&reason=zzz）
+func (s *Server) handleDenyIdea(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	idea, err := trader.DenyIdea(c.Query("id"), c.Query("reason"))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, idea)
+}
+
+// ideaLinkActions 审批链接的操作及确认页按钮文字
+var ideaLinkActions = map[string]string{
+	"approve": "确认批准并执行",
+	"deny":    "确认拒绝",
+}
+
+// ideaConfirmPage 审批确认页：打开链接（GET）不改变状态，点击按钮后POST执行
+var ideaConfirmPage = template.Must(template.New("idea").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>交易想法审批</title></head>
+<body style="font-family: sans-serif; max-width: 640px; margin: 2em auto; padding: 0 1em">
+<h2>{{.Idea.Decision.Action}} {{.Idea.Decision.Symbol}}</h2>
+<p>trader: {{.TraderID}}<br>
+仓位 {{printf "%.2f" .Idea.Decision.PositionSizeUSD}} USDT，杠杆 {{.Idea.Decision.Leverage}}x，参考价 {{printf "%.4f" .Idea.IntendedPrice}}<br>
+止损 {{printf "%.4f" .Idea.Decision.StopLoss}} | 止盈 {{printf "%.4f" .Idea.Decision.TakeProfit}}<br>
+{{.Idea.ExpiresAt.Format "2006-01-02 15:04:05"}} 前有效</p>
+<p style="white-space: pre-wrap">{{.Idea.Decision.Reasoning}}</p>
+<form method="POST" action="decide">
+<input type="hidden" name="trader_id" value="{{.TraderID}}">
+<input type="hidden" name="id" value="{{.Idea.ID}}">
+<input type="hidden" name="action" value="{{.Action}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit" style="font-size: 1.2em; padding: 0.5em 1.5em">{{.Label}}</button>
+</form>
+</body></html>
+`))
+
+// handleConfirmIdeaLink 通知中批准/拒绝按钮打开的链接：token校验通过后显示确认页，不执行操作
+// （链接可能被聊天软件或浏览器预取，GET 不能改变状态）
+func (s *Server) handleConfirmIdeaLink(c *gin.Context) {
+	traderID, id, action, token := c.Query("trader_id"), c.Query("id"), c.Query("action"), c.Query("token")
+	label, ok := ideaLinkActions[action]
+	if !ok {
+		c.String(http.StatusBadRequest, "action必须是 approve 或 deny")
+		return
+	}
+	t, err := s.ideaTrader(traderID)
+	if err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+	if !t.VerifyIdeaToken(id, token) {
+		c.String(http.StatusForbidden, "链接无效、已使用或已过期")
+		return
+	}
+	idea, ok := t.TradeIdea(id)
+	if !ok {
+		c.String(http.StatusNotFound, "交易想法不存在")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := ideaConfirmPage.Execute(c.Writer, map[string]interface{}{
+		"TraderID": traderID,
+		"Idea":     idea,
+		"Action":   action,
+		"Token":    token,
+		"Label":    label,
+	}); err != nil {
+		log.Printf("⚠️ 渲染审批确认页失败: %v", err)
+	}
+}
+
+// handleDecideIdeaLink 确认页提交的批准/拒绝（表单字段 trader_id、id、action、token），token校验通过后执行，返回纯文本结果
+func (s *Server) handleDecideIdeaLink(c *gin.Context) {
+	traderID, id, action, token := c.PostForm("trader_id"), c.PostForm("id"), c.PostForm("action"), c.PostForm("token")
+	if _, ok := ideaLinkActions[action]; !ok {
+		c.String(http.StatusBadRequest, "action必须是 approve 或 deny")
+		return
+	}
+	t, err := s.ideaTrader(traderID)
+	if err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+	if !t.VerifyIdeaToken(id, token) {
+		c.String(http.StatusForbidden, "链接无效、已使用或已过期")
+		return
+	}
+
+	if action == "approve" {
+		idea, err := t.ApproveIdea(id)
+		if err != nil {
+			c.String(http.StatusConflict, fmt.Sprintf("批准失败: %v", err))
+			return
+		}
+		c.String(http.StatusOK, fmt.Sprintf("已批准并执行 %s %s: %s", idea.Decision.Action, idea.Decision.Symbol, idea.Result))
+		return
+	}
+	idea, err := t.DenyIdea(id, "")
+	if err != nil {
+		c.String(http.StatusConflict, fmt.Sprintf("拒绝失败: %v", err))
+		return
+	}
+	c.String(http.StatusOK, fmt.Sprintf("已拒绝 %s %s", idea.Decision.Action, idea.Decision.Symbol))
+}
+
 // Start 启动服务器
 func (s *Server) Start() error {
 	addr := fmt.Sprintf(":%d", s.port)
@@ -454,14 +1604,30 @@ func (s *Server) Start() error {
 	log.Printf("📊 API文档:")
 	log.Printf("  • GET  /api/competition      - 竞赛总览（对比所有trader）")
 	log.Printf("  • GET  /api/traders          - Trader列表")
+	log.Printf("  • GET  /api/benchmarks       - 买入持有基准（最新净值）")
+	log.Printf("  • GET  /api/benchmarks/history?benchmark_id=xxx - 基准净值历史")
+	log.Printf("  • GET  /api/ratelimits       - 交易所限频预算")
+	log.Printf("  • GET  /api/caches           - 内存缓存指标")
 	log.Printf("  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
 	log.Printf("  • GET  /api/account?trader_id=xxx    - 指定trader的账户信息")
 	log.Printf("  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
 	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志")
 	log.Printf("  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
+	log.Printf("  • GET  /api/decisions/prompt?trader_id=xxx&decision_id=yyy - 指定决策的原始prompt")
+	log.Printf("  • GET  /api/decisions/market-snapshot?trader_id=xxx&decision_id=yyy - 指定决策周期的行情快照")
+	log.Printf("  • GET  /api/reports/daily?trader_id=xxx&date=YYYY-MM-DD - 指定trader的日报（默认昨天）")
 	log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
 	log.Printf("  • GET  /api/equity-history?trader_id=xxx - 指定trader的收益率历史数据")
 	log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
+	log.Printf("  • GET  /api/symbol-filter?trader_id=xxx - 指定trader的币种黑白名单")
+	log.Printf("  • PUT  /api/symbol-filter?trader_id=xxx - 更新币种黑白名单（下个周期生效）")
+	log.Printf("  • POST /api/traders/:id/simulate - 模拟开仓/加仓决策（仓位预览，不下单）")
+	log.Printf("  • GET  /api/traders/:id/cycles/:n - 指定周期（编号或决策ID）的完整报告")
+	log.Printf("  • GET  /api/decisions/search?q=xxx[&trader_id=&symbol=&from=&to=] - 按决策理由检索历史决策（跨trader）")
+	log.Printf("  • GET  /api/ideas?trader_id=xxx - 人工审批模式的交易想法")
+	log.Printf("  • POST /api/ideas/approve?trader_id=xxx&id=yyy - 批准并执行交易想法")
+	log.Printf("  • POST /api/ideas/deny?trader_id=xxx&id=yyy - 拒绝交易想法")
+	log.Printf("  • GET  /ws?trader_id=xxx&types=a,b - WebSocket实时事件推送")
 	log.Printf("  • GET  /health               - 健康检查")
 	log.Println()
 
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"nofx/decision"
+	"nofx/manager"
+	"nofx/trader"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeIdeas 审批队列的替身：token 只对等待中的想法有效（与 trader.VerifyIdeaToken 一致）
+type fakeIdeas struct {
+	ideas    map[string]*trader.TradeIdea
+	tokens   map[string]string
+	approved []string
+	denied   []string
+}
+
+func newFakeIdeas() *fakeIdeas {
+	f := &fakeIdeas{ideas: make(map[string]*trader.TradeIdea), tokens: make(map[string]string)}
+	for _, id := range []string{"idea_1", "idea_2"} {
+		f.ideas[id] = &trader.TradeIdea{
+			ID:            id,
+			TraderID:      "t1",
+			Decision:      decision.Decision{Symbol: "ETHUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1500, StopLoss: 2900, TakeProfit: 3400, Reasoning: "突破 <阻力位>"},
+			IntendedPrice: 3000,
+			Status:        trader.IdeaPending,
+			ExpiresAt:     time.Now().Add(time.Minute),
+		}
+		f.tokens[id] = "token-" + id
+	}
+	return f
+}
+
+func (f *fakeIdeas) TradeIdea(id string) (trader.TradeIdea, bool) {
+	idea, ok := f.ideas[id]
+	if !ok {
+		return trader.TradeIdea{}, false
+	}
+	return *idea, true
+}
+
+func (f *fakeIdeas) VerifyIdeaToken(id, token string) bool {
+	idea, ok := f.ideas[id]
+	return ok && token != "" && token == f.tokens[id] && idea.Status == trader.IdeaPending
+}
+
+func (f *fakeIdeas) ApproveIdea(id string) (*trader.TradeIdea, error) {
+	idea := f.ideas[id]
+	if idea.Status != trader.IdeaPending {
+		return nil, fmt.Errorf("交易想法 %s 已处理（%s）", id, idea.Status)
+	}
+	idea.Status, idea.Result = trader.IdeaExecuted, "数量 0.5000 @ 3000.0000"
+	f.approved = append(f.approved, id)
+	return idea, nil
+}
+
+func (f *fakeIdeas) DenyIdea(id, reason string) (*trader.TradeIdea, error) {
+	idea := f.ideas[id]
+	if idea.Status != trader.IdeaPending {
+		return nil, fmt.Errorf("交易想法 %s 已处理（%s）", id, idea.Status)
+	}
+	idea.Status = trader.IdeaDenied
+	f.denied = append(f.denied, id)
+	return idea, nil
+}
+
+func newIdeaLinkServer(ideas *fakeIdeas) *Server {
+	s := NewServer(manager.NewTraderManager(), 0, "admin", "secret")
+	s.ideaTrader = func(traderID string) (ideaDecider, error) {
+		if traderID != "t1" {
+			return nil, fmt.Errorf("trader ID '%s' 不存在", traderID)
+		}
+		return ideas, nil
+	}
+	return s
+}
+
+func ideaLink(traderID, id, action, token string) url.Values {
+	return url.Values{"trader_id": {traderID}, "id": {id}, "action": {action}, "token": {token}}
+}
+
+func getIdeaLink(s *Server, q url.Values) *httptest.ResponseRecorder {
+	return serve(s, httptest.NewRequest(http.MethodGet, "/api/ideas/decide?"+q.Encode(), nil))
+}
+
+func postIdeaLink(s *Server, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/ideas/decide", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return serve(s, req)
+}
+
+func TestIdeaLinkGetOnlyConfirms(t *testing.T) {
+	ideas := newFakeIdeas()
+	s := newIdeaLinkServer(ideas)
+
+	// 打开链接（包括聊天软件预取）只显示确认页，重复打开也不改变状态
+	for i := 0; i < 2; i++ {
+		w := getIdeaLink(s, ideaLink("t1", "idea_1", "approve", "token-idea_1"))
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+			t.Fatalf("确认页: %d %s", w.Code, w.Body.String())
+		}
+		body := w.Body.String()
+		for _, want := range []string{`<form method="POST" action="decide">`, `name="token" value="token-idea_1"`, `name="action" value="approve"`, "确认批准并执行", "open_long ETHUSDT", "突破 &lt;阻力位&gt;"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("确认页缺少 %q", want)
+			}
+		}
+	}
+	if len(ideas.approved) != 0 || ideas.ideas["idea_1"].Status != trader.IdeaPending {
+		t.Fatalf("GET 不应改变状态: %v", ideas.approved)
+	}
+	if w := getIdeaLink(s, ideaLink("t1", "idea_1", "deny", "token-idea_1")); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "确认拒绝") || len(ideas.denied) != 0 {
+		t.Errorf("拒绝确认页: %d %v", w.Code, ideas.denied)
+	}
+
+	// 参数放在查询字符串里的 POST 不执行（只接受确认页提交的表单）
+	req := httptest.NewRequest(http.MethodPost, "/api/ideas/decide?"+ideaLink("t1", "idea_1", "approve", "token-idea_1").Encode(), nil)
+	if w := serve(s, req); w.Code != http.StatusBadRequest || len(ideas.approved) != 0 {
+		t.Errorf("查询参数 POST: %d %v", w.Code, ideas.approved)
+	}
+}
+
+func TestIdeaLinkRejectsInvalidToken(t *testing.T) {
+	ideas := newFakeIdeas()
+	s := newIdeaLinkServer(ideas)
+
+	for _, tt := range []struct {
+		name string
+		q    url.Values
+		code int
+	}{
+		{name: "错误的token", q: ideaLink("t1", "idea_1", "approve", "token-idea_2"), code: http.StatusForbidden},
+		{name: "缺少token", q: ideaLink("t1", "idea_1", "approve", ""), code: http.StatusForbidden},
+		{name: "不存在的想法", q: ideaLink("t1", "idea_9", "approve", "token-idea_1"), code: http.StatusForbidden},
+		{name: "不存在的trader", q: ideaLink("t9", "idea_1", "approve", "token-idea_1"), code: http.StatusNotFound},
+		{name: "无效的action", q: ideaLink("t1", "idea_1", "execute", "token-idea_1"), code: http.StatusBadRequest},
+	} {
+		if w := getIdeaLink(s, tt.q); w.Code != tt.code {
+			t.Errorf("GET %s: %d %s", tt.name, w.Code, w.Body.String())
+		}
+		if w := postIdeaLink(s, tt.q); w.Code != tt.code {
+			t.Errorf("POST %s: %d %s", tt.name, w.Code, w.Body.String())
+		}
+	}
+	if len(ideas.approved) != 0 || len(ideas.denied) != 0 {
+		t.Fatalf("无效链接不应执行: %v %v", ideas.approved, ideas.denied)
+	}
+}
+
+func TestIdeaLinkTokenSingleUse(t *testing.T) {
+	ideas := newFakeIdeas()
+	s := newIdeaLinkServer(ideas)
+
+	w := postIdeaLink(s, ideaLink("t1", "idea_1", "approve", "token-idea_1"))
+	if w.Code != http.StatusOK || w.Body.String() != "已批准并执行 open_long ETHUSDT: 数量 0.5000 @ 3000.0000" {
+		t.Fatalf("批准: %d %s", w.Code, w.Body.String())
+	}
+
+	// 同一链接再次使用（批准或改为拒绝）都被拒绝
+	for _, action := range []string{"approve", "deny"} {
+		if w := postIdeaLink(s, ideaLink("t1", "idea_1", action, "token-idea_1")); w.Code != http.StatusForbidden {
+			t.Errorf("重复使用 %s: %d %s", action, w.Code, w.Body.String())
+		}
+		if w := getIdeaLink(s, ideaLink("t1", "idea_1", action, "token-idea_1")); w.Code != http.StatusForbidden {
+			t.Errorf("已使用的链接不应显示确认页: %d", w.Code)
+		}
+	}
+	if strings.Join(ideas.approved, ",") != "idea_1" || len(ideas.denied) != 0 {
+		t.Fatalf("只应执行一次: %v %v", ideas.approved, ideas.denied)
+	}
+
+	// 另一个想法的链接不受影响
+	if w := postIdeaLink(s, ideaLink("t1", "idea_2", "deny", "token-idea_2")); w.Code != http.StatusOK || w.Body.String() != "已拒绝 open_long ETHUSDT" {
+		t.Fatalf("拒绝: %d %s", w.Code, w.Body.String())
+	}
+}
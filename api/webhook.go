@@ -0,0 +1,80 @@
+package api
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"nofx/trader"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 外部策略信号 webhook
+// 在 POST /webhook/signal 上接收 TradingView 警报等外部信号（JSON），令牌放在 URL 的 ?token= 或消息体的 token/passphrase 字段。
+// 默认作为候选币种提示发给所有trader（或 trader_id 指定的trader）；mode=proposal 时转为开仓提议进入人工审批队列。
+
+// EnableSignalWebhook 注册 /webhook/signal 端点（需在 Start 之前调用）
+func (s *Server) EnableSignalWebhook(token string) {
+	s.router.POST("/webhook/signal", func(c *gin.Context) {
+		s.handleSignalWebhook(c, token)
+	})
+	log.Printf("✓ 外部信号webhook: POST /webhook/signal")
+}
+
+func (s *Server) handleSignalWebhook(c *gin.Context, token string) {
+	var sig trader.WebhookSignal
+	if err := c.ShouldBindJSON(&sig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "信号格式无效: " + err.Error()})
+		return
+	}
+	provided := c.Query("token")
+	if provided == "" {
+		provided = sig.Token
+	}
+	if provided == "" {
+		provided = sig.Passphrase
+	}
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "令牌无效"})
+		return
+	}
+
+	traderID := sig.TraderID
+	if traderID == "" {
+		traderID = c.Query("trader_id")
+	}
+	ids := []string{traderID}
+	if traderID == "" {
+		ids = s.traderManager.GetTraderIDs()
+		sort.Strings(ids)
+		if sig.Mode == "proposal" && len(ids) > 0 {
+			ids = ids[:1] // 提议只提交给一个trader，避免重复下单
+		}
+	}
+	if len(ids) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "没有可用的trader"})
+		return
+	}
+
+	var results []*trader.SignalResult
+	errs := make(map[string]string)
+	for _, id := range ids {
+		t, err := s.traderManager.GetTrader(id)
+		if err != nil {
+			errs[id] = err.Error()
+			continue
+		}
+		result, err := t.HandleSignal(sig)
+		if err != nil {
+			errs[id] = err.Error()
+			continue
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "信号未被任何trader接受", "errors": errs})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"accepted": results, "errors": errs})
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"nofx/events"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// 实时事件推送
+// GET /ws 升级为WebSocket，推送事件总线上的事件（JSON，每条消息一个事件，见 events.Event）。
+// 可选参数 trader_id 只接收指定trader的事件，types 为逗号分隔的事件类型。客户端消费过慢时丢弃事件，
+// 序号 seq 出现跳跃即表示有丢失；连接只用于推送，客户端发送的消息被忽略。
+
+const (
+	wsBuffer       = 256
+	wsWriteTimeout = 10 * time.Second
+	wsPingInterval = 30 * time.Second
+)
+
+// 与其他API一样允许任意来源（见 corsMiddleware）
+var wsUpgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+// handleWebSocket 实时事件流
+func (s *Server) handleWebSocket(c *gin.Context) {
+	traderID := c.Query("trader_id")
+	types := make(map[events.Type]bool)
+	if raw := c.Query("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.TrimSpace(t)
+			if !isEventType(events.Type(t)) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "未知的事件类型: " + t, "types": events.Types})
+				return
+			}
+			types[events.Type(t)] = true
+		}
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // Upgrade 已返回错误响应
+	}
+	defer conn.Close()
+
+	sub := events.Subscribe(wsBuffer, func(e events.Event) bool {
+		return (traderID == "" || e.TraderID == traderID) && (len(types) == 0 || types[e.Type])
+	})
+	defer sub.Close()
+	log.Printf("🔌 WebSocket客户端已连接: %s（订阅数 %d）", c.ClientIP(), events.Subscribers())
+
+	// 读取并丢弃客户端消息，连接断开时结束推送
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case e := <-sub.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			if dropped := sub.Dropped(); dropped > 0 {
+				log.Printf("🔌 WebSocket客户端已断开: %s（消费过慢丢弃 %d 个事件）", c.ClientIP(), dropped)
+			}
+			return
+		}
+	}
+}
+
+func isEventType(t events.Type) bool {
+	for _, known := range events.Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 基准"trader"：模拟用相同初始资金买入并持有（BTC 或默认币种等权组合），不下单、不调用AI
+// 用于在排行榜和分析中计算每个AI trader相对被动持有的超额收益（alpha）
+
+// maxHistoryPoints 净值历史最多保留的点数（每3分钟一个点约20天）
+const maxHistoryPoints = 10000
+
+// Config 基准配置
+type Config struct {
+	ID             string   // 基准ID（同时作为状态文件目录名）
+	Name           string   // 显示名称
+	Symbols        []string // 持有的币种，多个时等权分配
+	InitialBalance float64  // 初始资金（USDT）
+	FeeRate        float64  // 买入手续费率（小数，如0.0005；0表示不计手续费）
+	StateDir       string   // 状态文件目录（重启后沿用首次启动时的买入价）
+}
+
+// Point 净值历史数据点
+type Point struct {
+	Timestamp time.Time `json:"timestamp"`
+	Equity    float64   `json:"equity"`     // 持仓市值（USDT）
+	ReturnPct float64   `json:"return_pct"` // 相对初始资金的收益率（%）
+}
+
+// state 持久化状态
+type state struct {
+	StartTime   time.Time          `json:"start_time"`   // 首次买入时间
+	StartPrices map[string]float64 `json:"start_prices"` // 买入价
+	Units       map[string]float64 `json:"units"`        // 持有数量
+	EntryFee    float64            `json:"entry_fee"`    // 买入时扣除的手续费（USDT）
+	History     []Point            `json:"history"`      // 净值历史
+}
+
+// Benchmark 买入持有基准
+type Benchmark struct {
+	id             string
+	name           string
+	symbols        []string
+	initialBalance float64
+	feeRate        float64
+	statePath      string
+
+	// priceFunc 获取币种最新价格（默认使用当前市场数据源的1分钟K线收盘价）
+	priceFunc func(symbol string) (float64, error)
+
+	state state
+	mu    sync.RWMutex
+}
+
+// New 创建基准，如果状态文件存在则恢复之前的买入价和净值历史
+func New(cfg Config) (*Benchmark, error) {
+	if len(cfg.Symbols) == 0 {
+		return nil, fmt.Errorf("基准 %s 至少需要一个币种", cfg.ID)
+	}
+	if cfg.InitialBalance <= 0 {
+		return nil, fmt.Errorf("基准 %s 初始资金必须大于0", cfg.ID)
+	}
+	if cfg.FeeRate < 0 || cfg.FeeRate >= 1 {
+		return nil, fmt.Errorf("基准 %s 手续费率无效: %v", cfg.ID, cfg.FeeRate)
+	}
+
+	symbols := make([]string, 0, len(cfg.Symbols))
+	for _, s := range cfg.Symbols {
+		symbols = append(symbols, normalizeSymbol(s))
+	}
+
+	b := &Benchmark{
+		id:             cfg.ID,
+		name:           cfg.Name,
+		symbols:        symbols,
+		initialBalance: cfg.InitialBalance,
+		feeRate:        cfg.FeeRate,
+		priceFunc:      latestPrice,
+	}
+
+	if cfg.StateDir != "" {
+		if err := os.MkdirAll(cfg.StateDir, 0755); err != nil {
+			return nil, fmt.Errorf("创建基准状态目录失败: %w", err)
+		}
+		b.statePath = filepath.Join(cfg.StateDir, "benchmark_state.json")
+		if err := b.load(); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// ID 基准ID
+func (b *Benchmark) ID() string { return b.id }
+
+// Name 基准名称
+func (b *Benchmark) Name() string { return b.name }
+
+// Symbols 持有的币种
+func (b *Benchmark) Symbols() []string { return append([]string(nil), b.symbols...) }
+
+// InitialBalance 初始资金
+func (b *Benchmark) InitialBalance() float64 { return b.initialBalance }
+
+// Update 按最新价格计算净值并记录一个历史点；首次调用时按当前价格等权买入（扣除吃单手续费）
+func (b *Benchmark) Update() (*Point, error) {
+	prices := make(map[string]float64, len(b.symbols))
+	for _, symbol := range b.symbols {
+		price, err := b.priceFunc(symbol)
+		if err != nil {
+			return nil, fmt.Errorf("获取 %s 价格失败: %w", symbol, err)
+		}
+		if price <= 0 {
+			return nil, fmt.Errorf("%s 价格无效: %v", symbol, price)
+		}
+		prices[symbol] = price
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if b.state.StartTime.IsZero() {
+		perSymbol := b.initialBalance / float64(len(b.symbols))
+		b.state.StartTime = now
+		b.state.StartPrices = prices
+		b.state.Units = make(map[string]float64, len(b.symbols))
+		b.state.EntryFee = b.initialBalance * b.feeRate
+		for symbol, price := range prices {
+			b.state.Units[symbol] = perSymbol * (1 - b.feeRate) / price
+		}
+		log.Printf("📐 基准 %s 已按当前价格买入: %v", b.name, prices)
+	}
+
+	equity := 0.0
+	for symbol, units := range b.state.Units {
+		price, ok := prices[symbol]
+		if !ok {
+			// 配置的币种有变化：已持有但不再报价的币种按买入价计算
+			price = b.state.StartPrices[symbol]
+		}
+		equity += units * price
+	}
+
+	point := Point{
+		Timestamp: now,
+		Equity:    equity,
+		ReturnPct: (equity - b.initialBalance) / b.initialBalance * 100,
+	}
+	b.state.History = append(b.state.History, point)
+	if len(b.state.History) > maxHistoryPoints {
+		b.state.History = b.state.History[len(b.state.History)-maxHistoryPoints:]
+	}
+
+	if err := b.saveLocked(); err != nil {
+		log.Printf("⚠️ 保存基准 %s 状态失败: %v", b.name, err)
+	}
+	return &point, nil
+}
+
+// Latest 最近一次净值，尚未更新过时返回nil
+func (b *Benchmark) Latest() *Point {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.state.History) == 0 {
+		return nil
+	}
+	p := b.state.History[len(b.state.History)-1]
+	return &p
+}
+
+// History 净值历史（从旧到新）
+func (b *Benchmark) History() []Point {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]Point(nil), b.state.History...)
+}
+
+// StartTime 首次买入时间（尚未买入时为零值）
+func (b *Benchmark) StartTime() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.state.StartTime
+}
+
+// EntryFee 买入时扣除的手续费（USDT）
+func (b *Benchmark) EntryFee() float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.state.EntryFee
+}
+
+// StartPrices 买入价
+func (b *Benchmark) StartPrices() map[string]float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	prices := make(map[string]float64, len(b.state.StartPrices))
+	for k, v := range b.state.StartPrices {
+		prices[k] = v
+	}
+	return prices
+}
+
+// load 从状态文件恢复
+func (b *Benchmark) load() error {
+	data, err := os.ReadFile(b.statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("读取基准状态失败: %w", err)
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("解析基准状态失败: %w", err)
+	}
+	b.state = s
+	if !s.StartTime.IsZero() {
+		log.Printf("📐 基准 %s 已恢复：%s 买入，%d 个历史点", b.name, s.StartTime.Format("2006-01-02 15:04:05"), len(s.History))
+	}
+	return nil
+}
+
+// saveLocked 写入状态文件（调用方持有锁）
+func (b *Benchmark) saveLocked() error {
+	if b.statePath == "" {
+		return nil
+	}
+	data, err := json.Marshal(b.state)
+	if err != nil {
+		return err
+	}
+	tmp := b.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.statePath)
+}
+
+// latestPrice 最新1分钟K线收盘价（启用延迟优选时使用该币种最快的健康数据源）
+func latestPrice(symbol string) (float64, error) {
+	return market.CurrentPrice(context.Background(), symbol)
+}
+
+// BasketName 等权组合的显示名称
+func BasketName(symbols []string) string {
+	names := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		names = append(names, strings.TrimSuffix(normalizeSymbol(s), "USDT"))
+	}
+	return "等权持有 " + strings.Join(names, "/")
+}
+
+// normalizeSymbol 统一为 XXXUSDT 格式（交易所格式转换由市场数据源负责）
+func normalizeSymbol(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if strings.HasSuffix(symbol, "USDT") {
+		return symbol
+	}
+	return symbol + "USDT"
+}
@@ -0,0 +1,142 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"nofx/market"
+	"testing"
+)
+
+// klineFeed 按顺序返回固定K线序列的收盘价（每次 Update 前进一根）
+type klineFeed struct {
+	klines map[string][]market.Kline
+	index  int
+}
+
+func (f *klineFeed) price(symbol string) (float64, error) {
+	klines, ok := f.klines[symbol]
+	if !ok || f.index >= len(klines) {
+		return 0, fmt.Errorf("%s 没有第%d根K线", symbol, f.index)
+	}
+	return klines[f.index].Close, nil
+}
+
+func closes(values ...float64) []market.Kline {
+	klines := make([]market.Kline, len(values))
+	for i, v := range values {
+		klines[i] = market.Kline{OpenTime: int64(i) * 180000, Close: v}
+	}
+	return klines
+}
+
+// run 按序列逐根更新，返回每一步的收益率
+func run(t *testing.T, b *Benchmark, feed *klineFeed, steps int) []float64 {
+	t.Helper()
+	b.priceFunc = feed.price
+	returns := make([]float64, steps)
+	for i := 0; i < steps; i++ {
+		feed.index = i
+		point, err := b.Update()
+		if err != nil {
+			t.Fatal(err)
+		}
+		returns[i] = point.ReturnPct
+	}
+	return returns
+}
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestBuyAndHoldReturnWithFee(t *testing.T) {
+	b, err := New(Config{ID: "btc", Name: "BTC", Symbols: []string{"btc"}, InitialBalance: 1000, FeeRate: 0.0005})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := &klineFeed{klines: map[string][]market.Kline{"BTCUSDT": closes(50000, 55000, 45000, 60000)}}
+	returns := run(t, b, feed, 4)
+
+	// 买入时扣 1000*0.05% = 0.5 USDT，持有 999.5/50000 = 0.01999 BTC
+	if !approx(b.EntryFee(), 0.5) || b.StartPrices()["BTCUSDT"] != 50000 {
+		t.Fatalf("手续费 %.4f, 买入价 %v", b.EntryFee(), b.StartPrices())
+	}
+	want := []float64{-0.05, 9.945, -10.045, 19.94}
+	for i := range want {
+		if !approx(returns[i], want[i]) {
+			t.Errorf("第%d根收益率 = %.6f%%, 期望 %.6f%%", i, returns[i], want[i])
+		}
+	}
+	if latest := b.Latest(); !approx(latest.Equity, 1199.4) || len(b.History()) != 4 {
+		t.Errorf("最新净值 = %+v, 历史 %d 个点", latest, len(b.History()))
+	}
+}
+
+func TestEqualWeightBasket(t *testing.T) {
+	b, err := New(Config{ID: "basket", Symbols: []string{"BTCUSDT", "ETH"}, InitialBalance: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := &klineFeed{klines: map[string][]market.Kline{
+		"BTCUSDT": closes(50000, 60000, 50000),
+		"ETHUSDT": closes(2000, 1800, 3000),
+	}}
+	returns := run(t, b, feed, 3)
+
+	// 各500 USDT：BTC +20% / ETH -10% → +5%；BTC 0% / ETH +50% → +25%
+	if want := []float64{0, 5, 25}; !approx(returns[0], want[0]) || !approx(returns[1], want[1]) || !approx(returns[2], want[2]) {
+		t.Errorf("收益率 = %v, 期望 %v", returns, want)
+	}
+	if b.EntryFee() != 0 {
+		t.Errorf("未配置费率时不扣手续费: %.4f", b.EntryFee())
+	}
+}
+
+func TestBenchmarkStateSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{ID: "btc", Symbols: []string{"BTCUSDT"}, InitialBalance: 1000, FeeRate: 0.001, StateDir: dir}
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := &klineFeed{klines: map[string][]market.Kline{"BTCUSDT": closes(40000, 44000, 48000)}}
+	run(t, b, feed, 2)
+
+	// 重启后沿用首次买入价和手续费，不重新买入
+	restarted, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.priceFunc = feed.price
+	feed.index = 2
+	point, err := restarted.Update()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(point.ReturnPct, 19.88) || !approx(restarted.EntryFee(), 1) || len(restarted.History()) != 3 {
+		t.Errorf("重启后收益率 %.4f%%, 手续费 %.4f, 历史 %d 个点", point.ReturnPct, restarted.EntryFee(), len(restarted.History()))
+	}
+}
+
+func TestBenchmarkInvalidInput(t *testing.T) {
+	for _, cfg := range []Config{
+		{ID: "empty", InitialBalance: 1000},
+		{ID: "zero", Symbols: []string{"BTCUSDT"}},
+		{ID: "fee", Symbols: []string{"BTCUSDT"}, InitialBalance: 1000, FeeRate: 1},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%s 应报错", cfg.ID)
+		}
+	}
+
+	// 价格无效时不买入、不记录
+	b, _ := New(Config{ID: "btc", Symbols: []string{"BTCUSDT"}, InitialBalance: 1000})
+	b.priceFunc = func(string) (float64, error) { return 0, nil }
+	if _, err := b.Update(); err == nil || !b.StartTime().IsZero() || b.Latest() != nil {
+		t.Errorf("价格为0时不应买入: %v", err)
+	}
+}
+
+func TestBasketName(t *testing.T) {
+	if got := BasketName([]string{"btcusdt", "ETH", " sol "}); got != "等权持有 BTC/ETH/SOL" {
+		t.Errorf("BasketName = %s", got)
+	}
+}
@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// 带过期时间的内存缓存
+// 交易器的余额/持仓缓存、合约精度缓存、行情的流动性画像等都是"读写锁 + 写入时间"的同一套逻辑，统一用这里的泛型实现：
+// 过期的条目在读取时丢弃，GetOrLoad 对同一个键的并发加载只请求一次（singleflight），避免缓存过期瞬间多个调用同时打到交易所。
+// Delete/Clear 递增代数：失效之前开始的加载结果不写入缓存，失效之后的调用也不会加入这次加载（下单后不会读到下单前的余额/持仓）。
+// 同名缓存（例如每个Gate.io交易器各自的余额缓存）共享一组命中/加载指标，见 AllStats。
+
+// NoExpiry 条目永不过期（合约精度、交易对格式转换等不会变化的数据）
+const NoExpiry time.Duration = -1
+
+type entry[V any] struct {
+	value    V
+	storedAt time.Time
+}
+
+// Cache 键值缓存，ttl 为 0 时不缓存（每次读取都重新加载）
+type Cache[K comparable, V any] struct {
+	mu    sync.RWMutex
+	ttl   time.Duration
+	items map[K]entry[V]
+	group singleflight.Group
+	gen   uint64 // 失效代数，Delete/Clear 时递增
+	stats *metrics
+}
+
+// New 创建缓存，name 用于指标汇总（如 "gateio.balance"）
+func New[K comparable, V any](name string, ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:   ttl,
+		items: make(map[K]entry[V]),
+		stats: metricsFor(name),
+	}
+}
+
+// SetTTL 修改有效期（已缓存的条目按新有效期判断是否过期）
+func (c *Cache[K, V]) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+func (c *Cache[K, V]) fresh(e entry[V]) bool {
+	return c.ttl == NoExpiry || (c.ttl > 0 && time.Since(e.storedAt) < c.ttl)
+}
+
+// Get 读取未过期的条目
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	e, ok := c.items[key]
+	fresh := ok && c.fresh(e)
+	c.mu.RUnlock()
+	if fresh {
+		c.stats.hits.Add(1)
+		return e.value, true
+	}
+	c.stats.misses.Add(1)
+	if ok {
+		c.mu.Lock()
+		if e, ok := c.items[key]; ok && !c.fresh(e) {
+			delete(c.items, key)
+			c.stats.entries.Add(-1)
+			c.stats.evictions.Add(1)
+		}
+		c.mu.Unlock()
+	}
+	var zero V
+	return zero, false
+}
+
+// Set 写入条目
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[key]; !ok {
+		c.stats.entries.Add(1)
+	}
+	c.items[key] = entry[V]{value: value, storedAt: time.Now()}
+}
+
+// setIfGen 加载开始后没有失效过时写入条目（失效前开始的加载结果可能已经过时，丢弃）
+func (c *Cache[K, V]) setIfGen(key K, value V, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		c.stats.staleLoads.Add(1)
+		return
+	}
+	if _, ok := c.items[key]; !ok {
+		c.stats.entries.Add(1)
+	}
+	c.items[key] = entry[V]{value: value, storedAt: time.Now()}
+}
+
+// GetOrLoad 读取条目，不存在或已过期时调用 load 加载并写入（加载失败、加载期间缓存失效时不缓存）
+// 同一个键、同一代的并发调用共享一次加载
+func (c *Cache[K, V]) GetOrLoad(key K, load func() (V, error)) (V, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+	c.mu.RLock()
+	gen := c.gen
+	c.mu.RUnlock()
+	v, err, shared := c.group.Do(fmt.Sprint(gen, "/", key), func() (interface{}, error) {
+		c.stats.loads.Add(1)
+		v, err := load()
+		if err != nil {
+			c.stats.loadErrors.Add(1)
+			return v, err
+		}
+		c.setIfGen(key, v, gen)
+		return v, nil
+	})
+	if shared {
+		c.stats.shared.Add(1)
+	}
+	value, _ := v.(V)
+	return value, err
+}
+
+// Delete 删除条目
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	if _, ok := c.items[key]; ok {
+		delete(c.items, key)
+		c.stats.entries.Add(-1)
+	}
+}
+
+// Clear 清空所有条目（下单后余额/持仓失效等）
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.stats.entries.Add(-int64(len(c.items)))
+	c.items = make(map[K]entry[V])
+}
+
+// Value 单值缓存（账户余额、持仓列表等）
+type Value[V any] struct {
+	c *Cache[struct{}, V]
+}
+
+// NewValue 创建单值缓存
+func NewValue[V any](name string, ttl time.Duration) *Value[V] {
+	return &Value[V]{c: New[struct{}, V](name, ttl)}
+}
+
+// SetTTL 修改有效期
+func (v *Value[V]) SetTTL(ttl time.Duration) { v.c.SetTTL(ttl) }
+
+// Get 读取未过期的值
+func (v *Value[V]) Get() (V, bool) { return v.c.Get(struct{}{}) }
+
+// Set 写入值
+func (v *Value[V]) Set(value V) { v.c.Set(struct{}{}, value) }
+
+// GetOrLoad 读取值，不存在或已过期时加载
+func (v *Value[V]) GetOrLoad(load func() (V, error)) (V, error) {
+	return v.c.GetOrLoad(struct{}{}, load)
+}
+
+// Invalidate 使缓存的值失效
+func (v *Value[V]) Invalidate() { v.c.Clear() }
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func statsFor(t *testing.T, name string) Stats {
+	t.Helper()
+	for _, s := range AllStats() {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("缺少缓存指标 %s", name)
+	return Stats{}
+}
+
+func TestGetOrLoadExpiresAndSkipsErrors(t *testing.T) {
+	c := New[string, int]("test.expiry", 50*time.Millisecond)
+	loads := 0
+	load := func() (int, error) {
+		loads++
+		return loads, nil
+	}
+	if v, _ := c.GetOrLoad("a", load); v != 1 {
+		t.Fatalf("首次加载 = %d", v)
+	}
+	if v, _ := c.GetOrLoad("a", load); v != 1 || loads != 1 {
+		t.Fatalf("有效期内应命中缓存: v=%d loads=%d", v, loads)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if v, _ := c.GetOrLoad("a", load); v != 2 {
+		t.Fatalf("过期后应重新加载: %d", v)
+	}
+
+	if _, err := c.GetOrLoad("b", func() (int, error) { return 0, errors.New("down") }); err == nil {
+		t.Fatal("应返回加载错误")
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("加载失败不应缓存")
+	}
+
+	c.Clear()
+	if _, ok := c.Get("a"); ok {
+		t.Error("Clear 后不应命中")
+	}
+
+	s := statsFor(t, "test.expiry")
+	if s.Loads != 3 || s.LoadErrors != 1 || s.Hits != 1 || s.Evictions != 1 || s.Entries != 0 {
+		t.Errorf("指标不符: %+v", s)
+	}
+}
+
+func TestTTLZeroAndNoExpiry(t *testing.T) {
+	v := NewValue[int]("test.disabled", 0)
+	v.Set(1)
+	if _, ok := v.Get(); ok {
+		t.Error("ttl 为 0 时不应缓存")
+	}
+	v.SetTTL(NoExpiry)
+	v.Set(2)
+	if got, ok := v.Get(); !ok || got != 2 {
+		t.Errorf("NoExpiry 应一直有效: %d %v", got, ok)
+	}
+	v.Invalidate()
+	if _, ok := v.Get(); ok {
+		t.Error("Invalidate 后不应命中")
+	}
+}
+
+func TestGetOrLoadCoalescesConcurrentLoads(t *testing.T) {
+	c := New[string, string]("test.singleflight", time.Minute)
+	var loads atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad("k", func() (string, error) {
+				loads.Add(1)
+				<-release
+				return "v", nil
+			})
+			if err != nil || v != "v" {
+				t.Errorf("GetOrLoad = %q, %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := loads.Load(); n != 1 {
+		t.Errorf("并发读取应只加载一次，实际 %d 次", n)
+	}
+}
+
+func TestGetOrLoadDropsLoadStartedBeforeInvalidate(t *testing.T) {
+	v := NewValue[string]("test.generation", time.Minute)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan string)
+	go func() {
+		got, _ := v.GetOrLoad(func() (string, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+		done <- got
+	}()
+	<-started
+
+	// 加载期间下单使缓存失效：之后的调用不加入旧的加载，旧的结果也不写入缓存
+	v.Invalidate()
+	got, err := v.GetOrLoad(func() (string, error) { return "fresh", nil })
+	if err != nil || got != "fresh" {
+		t.Fatalf("失效后的 GetOrLoad = %q, %v, 期望重新加载", got, err)
+	}
+	close(release)
+	if got := <-done; got != "stale" {
+		t.Errorf("失效前开始的调用 = %q, 期望返回它自己的加载结果", got)
+	}
+	if cached, ok := v.Get(); !ok || cached != "fresh" {
+		t.Errorf("缓存 = %q, %v, 失效前开始的加载不应覆盖", cached, ok)
+	}
+	if s := statsFor(t, "test.generation"); s.StaleLoads != 1 {
+		t.Errorf("StaleLoads = %d, 期望 1", s.StaleLoads)
+	}
+}
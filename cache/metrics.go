@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+type metrics struct {
+	name       string
+	entries    atomic.Int64
+	hits       atomic.Int64
+	misses     atomic.Int64
+	loads      atomic.Int64
+	loadErrors atomic.Int64
+	shared     atomic.Int64
+	evictions  atomic.Int64
+	staleLoads atomic.Int64
+}
+
+// Stats 同名缓存的汇总指标
+type Stats struct {
+	Name       string  `json:"name"`
+	Entries    int64   `json:"entries"`     // 当前条目数（含尚未读取到的过期条目）
+	Hits       int64   `json:"hits"`        // 命中次数
+	Misses     int64   `json:"misses"`      // 未命中或已过期次数
+	HitRate    float64 `json:"hit_rate"`    // 命中率（%）
+	Loads      int64   `json:"loads"`       // 实际加载次数
+	LoadErrors int64   `json:"load_errors"` // 加载失败次数
+	Shared     int64   `json:"shared"`      // 与并发调用共享加载结果的次数（被合并的请求）
+	Evictions  int64   `json:"evictions"`   // 过期丢弃的条目数
+	StaleLoads int64   `json:"stale_loads"` // 加载期间缓存失效、结果被丢弃的次数
+}
+
+var (
+	registry   = make(map[string]*metrics)
+	registryMu sync.Mutex
+)
+
+func metricsFor(name string) *metrics {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	m, ok := registry[name]
+	if !ok {
+		m = &metrics{name: name}
+		registry[name] = m
+	}
+	return m
+}
+
+// AllStats 返回所有缓存的指标（按名称排序）
+func AllStats() []Stats {
+	registryMu.Lock()
+	list := make([]*metrics, 0, len(registry))
+	for _, m := range registry {
+		list = append(list, m)
+	}
+	registryMu.Unlock()
+
+	stats := make([]Stats, 0, len(list))
+	for _, m := range list {
+		s := Stats{
+			Name:       m.name,
+			Entries:    m.entries.Load(),
+			Hits:       m.hits.Load(),
+			Misses:     m.misses.Load(),
+			Loads:      m.loads.Load(),
+			LoadErrors: m.loadErrors.Load(),
+			Shared:     m.shared.Load(),
+			Evictions:  m.evictions.Load(),
+			StaleLoads: m.staleLoads.Load(),
+		}
+		if total := s.Hits + s.Misses; total > 0 {
+			s.HitRate = float64(s.Hits) / float64(total) * 100
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
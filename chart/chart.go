@@ -0,0 +1,283 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// 服务端K线图渲染（纯标准库，无外部依赖）
+// SVG 带标题、价格刻度和价位标签，用于API/网页查看；
+// PNG 只画图形不画文字（标准库没有字体渲染），用于通知推送，价位数值由消息正文给出。
+
+// 默认尺寸
+const (
+	DefaultWidth  = 900
+	DefaultHeight = 480
+)
+
+// 边距（右侧留给价格刻度和价位标签）
+const (
+	marginTop    = 36
+	marginBottom = 28
+	marginLeft   = 12
+	marginRight  = 90
+)
+
+// 配色
+var (
+	ColorUp     = color.RGBA{0x26, 0xa6, 0x9a, 0xff} // 阳线
+	ColorDown   = color.RGBA{0xef, 0x53, 0x50, 0xff} // 阴线
+	ColorEntry  = color.RGBA{0x29, 0x62, 0xff, 0xff} // 入场价
+	ColorStop   = color.RGBA{0xd5, 0x00, 0x00, 0xff} // 止损价
+	ColorTarget = color.RGBA{0x00, 0xc8, 0x53, 0xff} // 止盈价
+	colorMarker = color.RGBA{0x9e, 0x9e, 0x9e, 0xff}
+	colorGrid   = color.RGBA{0xee, 0xee, 0xee, 0xff}
+	colorText   = color.RGBA{0x42, 0x42, 0x42, 0xff}
+	colorBg     = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// Level 水平价位线（入场、止损、止盈等）
+type Level struct {
+	Label string
+	Price float64
+	Color color.RGBA
+}
+
+// Chart K线图
+type Chart struct {
+	Title  string
+	Klines []market.Kline
+	Levels []Level
+	Marker time.Time // 竖线标注的时间（如决策时间），零值表示不标注
+	Width  int
+	Height int
+}
+
+// layout 坐标换算
+type layout struct {
+	width, height int
+	minPrice      float64
+	maxPrice      float64
+	candleWidth   float64
+}
+
+// newLayout 根据K线和价位线计算价格范围（上下各留5%空白）
+func (c *Chart) newLayout() (*layout, error) {
+	if len(c.Klines) == 0 {
+		return nil, fmt.Errorf("没有K线数据")
+	}
+	l := &layout{width: c.Width, height: c.Height, minPrice: math.Inf(1), maxPrice: math.Inf(-1)}
+	if l.width <= 0 {
+		l.width = DefaultWidth
+	}
+	if l.height <= 0 {
+		l.height = DefaultHeight
+	}
+	for _, k := range c.Klines {
+		l.minPrice = math.Min(l.minPrice, k.Low)
+		l.maxPrice = math.Max(l.maxPrice, k.High)
+	}
+	for _, lv := range c.Levels {
+		if lv.Price > 0 {
+			l.minPrice = math.Min(l.minPrice, lv.Price)
+			l.maxPrice = math.Max(l.maxPrice, lv.Price)
+		}
+	}
+	pad := (l.maxPrice - l.minPrice) * 0.05
+	if pad == 0 {
+		pad = l.maxPrice * 0.01
+	}
+	l.minPrice -= pad
+	l.maxPrice += pad
+	l.candleWidth = float64(l.width-marginLeft-marginRight) / float64(len(c.Klines))
+	return l, nil
+}
+
+// y 价格对应的纵坐标
+func (l *layout) y(price float64) float64 {
+	plotHeight := float64(l.height - marginTop - marginBottom)
+	return marginTop + (l.maxPrice-price)/(l.maxPrice-l.minPrice)*plotHeight
+}
+
+// x 第i根K线中心的横坐标
+func (l *layout) x(i int) float64 {
+	return marginLeft + (float64(i)+0.5)*l.candleWidth
+}
+
+// markerX 标注时间所在K线的横坐标（不在图表范围内时返回false）
+func (c *Chart) markerX(l *layout) (float64, bool) {
+	if c.Marker.IsZero() {
+		return 0, false
+	}
+	ms := c.Marker.UnixMilli()
+	for i, k := range c.Klines {
+		if ms >= k.OpenTime && ms <= k.CloseTime {
+			return l.x(i), true
+		}
+	}
+	return 0, false
+}
+
+// SVG 渲染为SVG
+func (c *Chart) SVG() ([]byte, error) {
+	l, err := c.newLayout()
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`, l.width, l.height, l.width, l.height))
+	sb.WriteString(fmt.Sprintf(`<rect width="%d" height="%d" fill="%s"/>`, l.width, l.height, hex(colorBg)))
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="22" font-size="14" fill="%s">%s</text>`, marginLeft, hex(colorText), html.EscapeString(c.Title)))
+
+	// 价格刻度
+	plotRight := float64(l.width - marginRight)
+	for i := 0; i <= 4; i++ {
+		price := l.minPrice + (l.maxPrice-l.minPrice)*float64(i)/4
+		y := l.y(price)
+		sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`, marginLeft, y, plotRight, y, hex(colorGrid)))
+		sb.WriteString(fmt.Sprintf(`<text x="%.1f" y="%.1f" fill="%s">%s</text>`, plotRight+4, y+4, hex(colorText), formatPrice(price)))
+	}
+
+	// 时间范围
+	first := time.UnixMilli(c.Klines[0].OpenTime).Format("01-02 15:04")
+	last := time.UnixMilli(c.Klines[len(c.Klines)-1].OpenTime).Format("01-02 15:04")
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="%d" fill="%s">%s</text>`, marginLeft, l.height-8, hex(colorText), first))
+	sb.WriteString(fmt.Sprintf(`<text x="%.1f" y="%d" fill="%s" text-anchor="end">%s</text>`, plotRight, l.height-8, hex(colorText), last))
+
+	if x, ok := c.markerX(l); ok {
+		sb.WriteString(fmt.Sprintf(`<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="%s" stroke-dasharray="2,3"/>`, x, marginTop, x, l.height-marginBottom, hex(colorMarker)))
+	}
+
+	// K线
+	bodyWidth := math.Max(1, l.candleWidth*0.7)
+	for i, k := range c.Klines {
+		fill := ColorUp
+		if k.Close < k.Open {
+			fill = ColorDown
+		}
+		x := l.x(i)
+		top := l.y(math.Max(k.Open, k.Close))
+		height := math.Max(1, l.y(math.Min(k.Open, k.Close))-top)
+		sb.WriteString(fmt.Sprintf(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`, x, l.y(k.High), x, l.y(k.Low), hex(fill)))
+		sb.WriteString(fmt.Sprintf(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`, x-bodyWidth/2, top, bodyWidth, height, hex(fill)))
+	}
+
+	// 价位线
+	for _, lv := range c.Levels {
+		if lv.Price <= 0 {
+			continue
+		}
+		y := l.y(lv.Price)
+		sb.WriteString(fmt.Sprintf(`<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-dasharray="6,4"/>`, marginLeft, y, plotRight, y, hex(lv.Color)))
+		sb.WriteString(fmt.Sprintf(`<text x="%.1f" y="%.1f" fill="%s">%s %s</text>`, plotRight+4, y-3, hex(lv.Color), html.EscapeString(lv.Label), formatPrice(lv.Price)))
+	}
+
+	sb.WriteString(`</svg>`)
+	return []byte(sb.String()), nil
+}
+
+// PNG 渲染为PNG（不含文字）
+func (c *Chart) PNG() ([]byte, error) {
+	l, err := c.newLayout()
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, l.width, l.height))
+	fillRect(img, 0, 0, l.width, l.height, colorBg)
+
+	plotRight := l.width - marginRight
+	for i := 0; i <= 4; i++ {
+		y := int(l.y(l.minPrice + (l.maxPrice-l.minPrice)*float64(i)/4))
+		hLine(img, marginLeft, plotRight, y, colorGrid, 0)
+	}
+	if x, ok := c.markerX(l); ok {
+		vLine(img, int(x), marginTop, l.height-marginBottom, colorMarker, 3)
+	}
+
+	bodyWidth := int(math.Max(1, l.candleWidth*0.7))
+	for i, k := range c.Klines {
+		fill := ColorUp
+		if k.Close < k.Open {
+			fill = ColorDown
+		}
+		x := int(l.x(i))
+		vLine(img, x, int(l.y(k.High)), int(l.y(k.Low)), fill, 0)
+		top := int(l.y(math.Max(k.Open, k.Close)))
+		bottom := int(math.Max(float64(top+1), l.y(math.Min(k.Open, k.Close))))
+		fillRect(img, x-bodyWidth/2, top, x-bodyWidth/2+bodyWidth, bottom, fill)
+	}
+
+	for _, lv := range c.Levels {
+		if lv.Price <= 0 {
+			continue
+		}
+		y := int(l.y(lv.Price))
+		hLine(img, marginLeft, l.width-marginLeft, y, lv.Color, 6)
+		hLine(img, marginLeft, l.width-marginLeft, y+1, lv.Color, 6)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("编码PNG失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// fillRect 填充矩形 [x0,x1) × [y0,y1)
+func fillRect(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// hLine 水平线，dash>0 时画虚线（dash为线段长度）
+func hLine(img *image.RGBA, x0, x1, y int, c color.RGBA, dash int) {
+	for x := x0; x <= x1; x++ {
+		if dash > 0 && (x-x0)/dash%2 == 1 {
+			continue
+		}
+		img.SetRGBA(x, y, c)
+	}
+}
+
+// vLine 垂直线，dash>0 时画虚线
+func vLine(img *image.RGBA, x, y0, y1 int, c color.RGBA, dash int) {
+	if y0 > y1 {
+		y0, y1 = y1, y0
+	}
+	for y := y0; y <= y1; y++ {
+		if dash > 0 && (y-y0)/dash%2 == 1 {
+			continue
+		}
+		img.SetRGBA(x, y, c)
+	}
+}
+
+// hex 颜色的十六进制表示
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// formatPrice 按价格量级保留有效小数位
+func formatPrice(price float64) string {
+	switch {
+	case price >= 1000:
+		return fmt.Sprintf("%.1f", price)
+	case price >= 1:
+		return fmt.Sprintf("%.3f", price)
+	default:
+		return fmt.Sprintf("%.6f", price)
+	}
+}
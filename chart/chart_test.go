@@ -0,0 +1,197 @@
+package chart
+
+import (
+	"bytes"
+	"image/png"
+	"math"
+	"nofx/market"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKlines n根1分钟K线，收盘价从 start 起每根加 step
+func testKlines(start time.Time, n int, open, step float64) []market.Kline {
+	klines := make([]market.Kline, n)
+	for i := range klines {
+		o := open + float64(i)*step
+		c := o + step
+		klines[i] = market.Kline{
+			OpenTime:  start.Add(time.Duration(i) * time.Minute).UnixMilli(),
+			CloseTime: start.Add(time.Duration(i+1)*time.Minute).UnixMilli() - 1,
+			Open:      o, Close: c,
+			High: math.Max(o, c) + 1, Low: math.Min(o, c) - 1,
+		}
+	}
+	return klines
+}
+
+func TestEmptyKlines(t *testing.T) {
+	c := &Chart{Title: "ETHUSDT"}
+	if _, err := c.SVG(); err == nil {
+		t.Error("没有K线时SVG应报错")
+	}
+	if _, err := c.PNG(); err == nil {
+		t.Error("没有K线时PNG应报错")
+	}
+}
+
+func TestSingleFlatKline(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	c := &Chart{Klines: []market.Kline{{OpenTime: start.UnixMilli(), CloseTime: start.Add(time.Minute).UnixMilli(), Open: 100, High: 100, Low: 100, Close: 100}}}
+
+	l, err := c.newLayout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 价格没有波动时上下各留1%
+	if l.minPrice != 99 || l.maxPrice != 101 || l.width != DefaultWidth || l.height != DefaultHeight {
+		t.Fatalf("布局 = %+v", l)
+	}
+	if y := l.y(100); math.IsNaN(y) || math.Abs(y-float64(marginTop+DefaultHeight-marginBottom)/2) > 1e-9 {
+		t.Errorf("价格应在纵向正中: %.2f", y)
+	}
+	if x := l.x(0); math.Abs(x-float64(marginLeft+DefaultWidth-marginRight)/2) > 1e-9 {
+		t.Errorf("唯一的K线应在横向正中: %.2f", x)
+	}
+
+	svg, err := c.SVG()
+	if err != nil || strings.Contains(string(svg), "NaN") {
+		t.Fatalf("SVG = %s, %v", svg, err)
+	}
+	data, err := c.PNG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil || img.Bounds().Dx() != DefaultWidth || img.Bounds().Dy() != DefaultHeight {
+		t.Fatalf("PNG尺寸不对: %v %v", img.Bounds(), err)
+	}
+}
+
+func TestPriceScaling(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	c := &Chart{
+		Klines: testKlines(start, 10, 100, 1), // Low 99, High 111
+		Levels: []Level{
+			{Label: "SL", Price: 90}, // 价位线扩展价格范围
+			{Label: "TP", Price: 0},  // 未设置的价位不影响范围
+			{Label: "X", Price: 105}, // 在K线范围内
+		},
+		Width:  600,
+		Height: 300,
+	}
+	l, err := c.newLayout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 90..111，上下各留5%（1.05）
+	if math.Abs(l.minPrice-88.95) > 1e-9 || math.Abs(l.maxPrice-112.05) > 1e-9 {
+		t.Fatalf("价格范围 = %.4f..%.4f", l.minPrice, l.maxPrice)
+	}
+	if l.y(l.maxPrice) != marginTop || l.y(l.minPrice) != float64(300-marginBottom) {
+		t.Errorf("价格范围应铺满绘图区: %.2f %.2f", l.y(l.maxPrice), l.y(l.minPrice))
+	}
+	if l.y(111) >= l.y(90) {
+		t.Error("高价应在上方")
+	}
+	if l.candleWidth != float64(600-marginLeft-marginRight)/10 || l.x(9) >= float64(600-marginRight) {
+		t.Errorf("K线宽度 = %.2f, 最后一根 x = %.2f", l.candleWidth, l.x(9))
+	}
+}
+
+func TestMarkerPlacement(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	klines := testKlines(start, 10, 100, 1)
+	tests := []struct {
+		name   string
+		marker time.Time
+		index  int // -1 表示不标注
+	}{
+		{name: "不标注", marker: time.Time{}, index: -1},
+		{name: "第一根开盘", marker: start, index: 0},
+		{name: "K线中间", marker: start.Add(4*time.Minute + 30*time.Second), index: 4},
+		{name: "最后一根收盘", marker: start.Add(10*time.Minute - time.Millisecond), index: 9},
+		{name: "早于图表", marker: start.Add(-time.Second), index: -1},
+		{name: "晚于图表", marker: start.Add(10 * time.Minute), index: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Chart{Klines: klines, Marker: tt.marker}
+			l, _ := c.newLayout()
+			x, ok := c.markerX(l)
+			if ok != (tt.index >= 0) || (ok && x != l.x(tt.index)) {
+				t.Fatalf("markerX = %.2f %v, 期望第 %d 根", x, ok, tt.index)
+			}
+			svg, _ := c.SVG()
+			if strings.Contains(string(svg), `stroke-dasharray="2,3"`) != ok {
+				t.Errorf("SVG中的标注线与 markerX 不一致")
+			}
+		})
+	}
+}
+
+func TestLevelsAndMarkerRendering(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	c := &Chart{
+		Title:  "ETHUSDT <open_long>",
+		Klines: testKlines(start, 20, 3000, 5),
+		Levels: []Level{
+			{Label: "Entry", Price: 3050, Color: ColorEntry},
+			{Label: "SL", Price: 2900, Color: ColorStop},
+			{Label: "TP", Price: 3400, Color: ColorTarget},
+			{Label: "Exit", Price: 0, Color: ColorTarget},
+		},
+		Marker: start.Add(5 * time.Minute),
+	}
+
+	svg, err := c.SVG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"ETHUSDT &lt;open_long&gt;", "Entry 3050.0", "SL 2900.0", "TP 3400.0", hex(ColorStop)} {
+		if !strings.Contains(string(svg), want) {
+			t.Errorf("SVG中缺少 %q", want)
+		}
+	}
+	if strings.Contains(string(svg), "Exit") {
+		t.Error("价格为0的价位线不应画出")
+	}
+
+	data, err := c.PNG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _ := c.newLayout()
+	// 价位线从左边距开始画（虚线的第一段）
+	for _, lv := range c.Levels[:3] {
+		r, g, b, _ := img.At(marginLeft, int(l.y(lv.Price))).RGBA()
+		if uint8(r>>8) != lv.Color.R || uint8(g>>8) != lv.Color.G || uint8(b>>8) != lv.Color.B {
+			t.Errorf("%s 价位线位置的颜色不对", lv.Label)
+		}
+	}
+	mx, _ := c.markerX(l)
+	r, g, b, _ := img.At(int(mx), marginTop).RGBA()
+	if uint8(r>>8) != colorMarker.R || uint8(g>>8) != colorMarker.G || uint8(b>>8) != colorMarker.B {
+		t.Error("标注线应画在决策时间所在的K线上")
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	tests := map[float64]string{
+		65000:    "65000.0",
+		1000:     "1000.0",
+		3.14159:  "3.142",
+		1:        "1.000",
+		0.000123: "0.000123",
+	}
+	for price, want := range tests {
+		if got := formatPrice(price); got != want {
+			t.Errorf("formatPrice(%v) = %s, 期望 %s", price, got, want)
+		}
+	}
+}
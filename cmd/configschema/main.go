@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"nofx/config"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 生成 config.json 的 JSON Schema
+//
+// 用法示例:
+//   go run ./cmd/configschema                      # 在仓库根目录执行，写入 config.schema.json
+//   go generate ./config                           # 同上（config/schema.go 中的 go:generate）
+//
+// 字段结构来自 config.Config（反射），字段说明取自 -src 目录中结构体字段的注释（行尾注释优先，其次是字段上方的注释）
+
+func main() {
+	src := flag.String("src", "config", "config 包源码目录（读取字段注释）")
+	out := flag.String("o", "config.schema.json", "输出文件")
+	flag.Parse()
+
+	descriptions, err := fieldComments(*src)
+	if err != nil {
+		fail("读取字段注释失败: %v", err)
+	}
+	data, err := json.MarshalIndent(config.GenerateSchema(descriptions), "", "  ")
+	if err != nil {
+		fail("序列化schema失败: %v", err)
+	}
+	if err := os.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+		fail("写入文件失败: %v", err)
+	}
+	fmt.Printf("✓ 已生成 %s（%d 个字段说明）\n", *out, len(descriptions))
+}
+
+// fieldComments 目录中所有结构体字段的注释，键为 类型名.字段名
+func fieldComments(dir string) (map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	comments := make(map[string]string)
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			st, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				return false
+			}
+			for _, field := range st.Fields.List {
+				text := commentText(field.Comment)
+				if text == "" {
+					text = commentText(field.Doc)
+				}
+				if text == "" {
+					continue
+				}
+				for _, name := range field.Names {
+					comments[spec.Name.Name+"."+name.Name] = text
+				}
+			}
+			return false
+		})
+	}
+	return comments, nil
+}
+
+// commentText 注释文本（多行合并为一行）
+func commentText(group *ast.CommentGroup) string {
+	if group == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(group.Text()), " ")
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "❌ "+format+"\n", args...)
+	os.Exit(1)
+}
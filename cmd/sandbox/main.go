@@ -0,0 +1,271 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"nofx/trader"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 测试网沙盒：在真实下单前验证API密钥和各项交易能力
+//
+// 用法示例:
+//   go run ./cmd/sandbox -exchange binance -api-key xxx -secret-key yyy
+//   go run ./cmd/sandbox -exchange hyperliquid -private-key xxx -wallet 0x...
+//   SANDBOX_API_KEY=xxx SANDBOX_SECRET_KEY=yyy go run ./cmd/sandbox -exchange gateio
+//
+// 默认只连接测试网；Aster 没有测试网，必须显式加 -mainnet 才会运行
+
+// checkResult 单项能力检查结果
+type checkResult struct {
+	name    string
+	passed  bool
+	skipped bool
+	detail  string
+	elapsed time.Duration
+}
+
+// sandbox 按脚本顺序执行检查
+type sandbox struct {
+	trader   trader.Trader
+	symbol   string
+	leverage int
+	usd      float64
+	results  []checkResult
+
+	price    float64
+	quantity float64
+	opened   bool
+}
+
+func main() {
+	exchange := flag.String("exchange", "", "交易所: binance | gateio | hyperliquid | aster")
+	apiKey := flag.String("api-key", os.Getenv("SANDBOX_API_KEY"), "API Key (binance/gateio)")
+	secretKey := flag.String("secret-key", os.Getenv("SANDBOX_SECRET_KEY"), "Secret Key (binance/gateio)")
+	privateKey := flag.String("private-key", os.Getenv("SANDBOX_PRIVATE_KEY"), "私钥 (hyperliquid/aster，不带0x前缀)")
+	wallet := flag.String("wallet", os.Getenv("SANDBOX_WALLET"), "钱包地址 (hyperliquid)")
+	asterUser := flag.String("aster-user", os.Getenv("SANDBOX_ASTER_USER"), "Aster主钱包地址")
+	asterSigner := flag.String("aster-signer", os.Getenv("SANDBOX_ASTER_SIGNER"), "Aster API钱包地址")
+	symbol := flag.String("symbol", "ETHUSDT", "测试币种")
+	leverage := flag.Int("leverage", 2, "测试杠杆")
+	usd := flag.Float64("usd", 30, "测试开仓名义价值(USDT)，需高于交易所最小下单金额")
+	mainnet := flag.Bool("mainnet", false, "允许连接主网（会产生真实交易！）")
+	flag.Parse()
+
+	if *exchange == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	t, err := newTrader(strings.ToLower(*exchange), *apiKey, *secretKey, *privateKey, *wallet, *asterUser, *asterSigner, !*mainnet)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	fmt.Println("╔════════════════════════════════════════════════════════════╗")
+	fmt.Printf("║    🧪 交易所沙盒检查: %-36s ║\n", *exchange)
+	fmt.Println("╚════════════════════════════════════════════════════════════╝")
+
+	s := &sandbox{
+		trader:   t,
+		symbol:   strings.ToUpper(*symbol),
+		leverage: *leverage,
+		usd:      *usd,
+	}
+	s.run()
+
+	if !s.report() {
+		os.Exit(1)
+	}
+}
+
+// newTrader 按交易所创建交易器，testnet=true 时使用测试网
+func newTrader(exchange, apiKey, secretKey, privateKey, wallet, asterUser, asterSigner string, testnet bool) (trader.Trader, error) {
+	switch exchange {
+	case "binance":
+		if apiKey == "" || secretKey == "" {
+			return nil, fmt.Errorf("binance 需要 -api-key 和 -secret-key")
+		}
+		return trader.NewFuturesTrader(apiKey, secretKey, testnet), nil
+	case "gateio":
+		if apiKey == "" || secretKey == "" {
+			return nil, fmt.Errorf("gateio 需要 -api-key 和 -secret-key")
+		}
+		return trader.NewGateioTrader(apiKey, secretKey, testnet)
+	case "hyperliquid":
+		if privateKey == "" || wallet == "" {
+			return nil, fmt.Errorf("hyperliquid 需要 -private-key 和 -wallet")
+		}
+		return trader.NewHyperliquidTrader(privateKey, wallet, testnet)
+	case "aster":
+		if testnet {
+			return nil, fmt.Errorf("aster 没有测试网，如确认要在主网用小额资金测试，请加 -mainnet")
+		}
+		if asterUser == "" || asterSigner == "" || privateKey == "" {
+			return nil, fmt.Errorf("aster 需要 -aster-user、-aster-signer 和 -private-key")
+		}
+		return trader.NewAsterTrader(asterUser, asterSigner, privateKey)
+	default:
+		return nil, fmt.Errorf("不支持的交易所: %s", exchange)
+	}
+}
+
+// run 依次执行：余额 → 杠杆 → 小额开仓 → 止损止盈 → 部分平仓 → 全部平仓 → 撤单
+func (s *sandbox) run() {
+	s.check("获取余额", true, func() (string, error) {
+		balance, err := s.trader.GetBalance()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("可用余额 %.2f USDT", toFloat(balance["availableBalance"])), nil
+	})
+
+	s.check("设置杠杆", true, func() (string, error) {
+		if err := s.trader.SetLeverage(s.symbol, s.leverage); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %dx", s.symbol, s.leverage), nil
+	})
+
+	s.check("小额开多", true, func() (string, error) {
+		price, err := s.trader.GetMarketPrice(s.symbol)
+		if err != nil {
+			return "", fmt.Errorf("获取价格失败: %w", err)
+		}
+		qtyStr, err := s.trader.FormatQuantity(s.symbol, s.usd/price)
+		if err != nil {
+			return "", fmt.Errorf("格式化数量失败: %w", err)
+		}
+		qty, _ := strconv.ParseFloat(qtyStr, 64)
+		if qty <= 0 {
+			return "", fmt.Errorf("数量 %s 过小，请调大 -usd", qtyStr)
+		}
+		if _, err := s.trader.OpenLong(s.symbol, qty, s.leverage, ""); err != nil {
+			return "", err
+		}
+		s.price = price
+		s.quantity = qty
+		s.opened = true
+		return fmt.Sprintf("数量 %s @ %.4f", qtyStr, price), nil
+	})
+
+	s.check("设置止损止盈", s.opened, func() (string, error) {
+		stopLoss := s.price * 0.95
+		takeProfit := s.price * 1.05
+		if err := s.trader.SetStopLoss(s.symbol, "LONG", s.quantity, stopLoss); err != nil {
+			return "", fmt.Errorf("止损: %w", err)
+		}
+		if err := s.trader.SetTakeProfit(s.symbol, "LONG", s.quantity, takeProfit); err != nil {
+			return "", fmt.Errorf("止盈: %w", err)
+		}
+		return fmt.Sprintf("止损 %.4f / 止盈 %.4f", stopLoss, takeProfit), nil
+	})
+
+	s.check("部分平仓", s.opened, func() (string, error) {
+		qtyStr, err := s.trader.FormatQuantity(s.symbol, s.quantity/2)
+		if err != nil {
+			return "", err
+		}
+		half, _ := strconv.ParseFloat(qtyStr, 64)
+		if half <= 0 {
+			return "", fmt.Errorf("半仓数量 %s 低于最小下单单位，请调大 -usd", qtyStr)
+		}
+		if _, err := s.trader.CloseLong(s.symbol, half, ""); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("平掉 %s", qtyStr), nil
+	})
+
+	s.check("全部平仓", s.opened, func() (string, error) {
+		if _, err := s.trader.CloseLong(s.symbol, 0, ""); err != nil {
+			return "", err
+		}
+		positions, err := s.trader.GetPositions()
+		if err != nil {
+			return "", fmt.Errorf("平仓后查询持仓失败: %w", err)
+		}
+		for _, pos := range positions {
+			if pos["symbol"] == s.symbol && pos["side"] == "long" {
+				return "", fmt.Errorf("平仓后仍有持仓: %v", pos["positionAmt"])
+			}
+		}
+		return "持仓已清空", nil
+	})
+
+	s.check("撤销挂单", true, func() (string, error) {
+		if err := s.trader.CancelAllOrders(s.symbol); err != nil {
+			return "", err
+		}
+		return "残留止损止盈单已撤销", nil
+	})
+}
+
+// check 执行单项检查，ready=false 时跳过（前置步骤失败）
+func (s *sandbox) check(name string, ready bool, fn func() (string, error)) {
+	if !ready {
+		s.results = append(s.results, checkResult{name: name, skipped: true, detail: "前置步骤失败，跳过"})
+		log.Printf("⏭  %s: 跳过", name)
+		return
+	}
+
+	log.Printf("🔄 %s...", name)
+	start := time.Now()
+	detail, err := fn()
+	result := checkResult{name: name, passed: err == nil, detail: detail, elapsed: time.Since(start)}
+	if err != nil {
+		result.detail = err.Error()
+		log.Printf("❌ %s失败: %v", name, err)
+	} else {
+		log.Printf("✓ %s: %s", name, detail)
+	}
+	s.results = append(s.results, result)
+}
+
+// report 打印汇总报告，全部通过返回true
+func (s *sandbox) report() bool {
+	fmt.Println()
+	fmt.Println("📋 检查报告")
+	fmt.Println(strings.Repeat("-", 70))
+	allPassed := true
+	for _, r := range s.results {
+		status := "✅ PASS"
+		switch {
+		case r.skipped:
+			status = "⏭  SKIP"
+			allPassed = false
+		case !r.passed:
+			status = "❌ FAIL"
+			allPassed = false
+		}
+		fmt.Printf("%s  %-12s %6.1fs  %s\n", status, r.name, r.elapsed.Seconds(), r.detail)
+	}
+	fmt.Println(strings.Repeat("-", 70))
+	if allPassed {
+		fmt.Println("🎉 全部能力检查通过，可以用这组密钥上线")
+	} else {
+		fmt.Println("⚠️  部分能力检查未通过，请检查密钥权限/账户余额/交易所限制后重试")
+		if s.opened {
+			fmt.Printf("⚠️  测试期间曾开仓，请登录交易所确认 %s 已无残留持仓和挂单\n", s.symbol)
+		}
+	}
+	return allPassed
+}
+
+// toFloat 将交易器返回的数值字段转为float64
+func toFloat(v interface{}) float64 {
+	switch val := v.(type) {
+	case float64:
+		return val
+	case int:
+		return float64(val)
+	case string:
+		f, _ := strconv.ParseFloat(val, 64)
+		return f
+	default:
+		return 0
+	}
+}
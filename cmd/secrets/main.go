@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"nofx/secrets"
+	"os"
+	"sort"
+)
+
+// 加密密钥文件工具
+//
+// 用法示例:
+//   NOFX_SECRETS_PASSPHRASE=xxx go run ./cmd/secrets seal -in plain.json -out secrets.enc
+//   NOFX_SECRETS_PASSPHRASE=xxx go run ./cmd/secrets list -in secrets.enc
+//
+// plain.json 格式: {"binance/key": "...", "binance/secret": "..."}，
+// 配置中用 secret://binance/key 引用。加密后请删除明文文件
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	in := fs.String("in", "", "输入文件")
+	out := fs.String("out", "secrets.enc", "输出文件（seal）")
+	fs.Parse(os.Args[2:])
+
+	passphrase := os.Getenv("NOFX_SECRETS_PASSPHRASE")
+	if passphrase == "" {
+		fail("请通过环境变量 NOFX_SECRETS_PASSPHRASE 提供口令")
+	}
+	if *in == "" {
+		fail("缺少 -in 参数")
+	}
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		fail("读取文件失败: %v", err)
+	}
+
+	switch os.Args[1] {
+	case "seal":
+		var plain map[string]string
+		if err := json.Unmarshal(data, &plain); err != nil {
+			fail("解析明文JSON失败: %v", err)
+		}
+		sealed, err := secrets.Seal(plain, passphrase)
+		if err != nil {
+			fail("加密失败: %v", err)
+		}
+		if err := os.WriteFile(*out, sealed, 0600); err != nil {
+			fail("写入文件失败: %v", err)
+		}
+		fmt.Printf("✓ 已加密 %d 个密钥到 %s\n", len(plain), *out)
+
+	case "list":
+		opened, err := secrets.Open(data, passphrase)
+		if err != nil {
+			fail("%v", err)
+		}
+		paths := make([]string, 0, len(opened))
+		for path := range opened {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Println(secrets.RefPrefix + path) // 只列出引用，不输出密钥值
+		}
+
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: secrets seal -in plain.json -out secrets.enc | secrets list -in secrets.enc")
+	os.Exit(2)
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "❌ "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 策略配置（strategy profiles）
+// 把提示词模板、验证规则（止损止盈自动补全）、仓位规则（杠杆和仓位大小）、额外指标集和扫描间隔打包成一个名字，
+// trader通过 profile 引用，不必逐个重复这些字段。启动时trader自身设置的字段优先，未设置的使用策略配置；
+// 策略配置中未设置的杠杆/仓位/止损补全使用全局配置。运行中可通过 /api/profile 切换，切换时整体替换为新配置的值。
+
+// 策略配置可选的额外指标（需同时在全局启用才会获取）
+var profileIndicators = []string{"relative_strength", "basis", "volatility", "flow", "range"}
+
+// StrategyProfileConfig 命名的策略配置
+type StrategyProfileConfig struct {
+	SystemPromptTemplate string              `json:"system_prompt_template,omitempty"` // 系统提示词模板名称
+	ScanIntervalMinutes  int                 `json:"scan_interval_minutes,omitempty"`  // 扫描间隔（分钟，默认3）
+	OrderType            string              `json:"order_type,omitempty"`             // 默认下单类型
+	SymbolEdgeDays       int                 `json:"symbol_edge_days,omitempty"`       // 分币种历史表现统计天数
+	Leverage             *LeverageConfig     `json:"leverage,omitempty"`               // 杠杆上限（未设置的字段使用全局配置）
+	PositionSize         *PositionSizeConfig `json:"position_size,omitempty"`          // 仓位大小（未设置的字段使用全局配置）
+	AutoStopLoss         *AutoStopLossConfig `json:"auto_stop_loss,omitempty"`         // 止损止盈自动补全（未设置时使用全局配置）
+	Indicators           []string            `json:"indicators,omitempty"`             // 写入prompt的额外指标（未设置表示全部已启用的指标）
+}
+
+// ExperimentConfig A/B测试配置：trader在 variants 引用的策略配置之间轮换
+// schedule 为 "alternate_days"（按天轮流，默认）或 "random_blocks"（每 len(variants) 个区组内随机排列，各出现一次）
+type ExperimentConfig struct {
+	Name       string   `json:"name,omitempty"`        // 实验名称（默认为trader ID）
+	Variants   []string `json:"variants"`              // 轮换的策略配置名称（至少2个，第一个为比较基准）
+	Schedule   string   `json:"schedule,omitempty"`    // 轮换方式
+	BlockHours int      `json:"block_hours,omitempty"` // random_blocks 的区组时长（小时，能整除24或是24的倍数，默认24）
+	Seed       int64    `json:"seed,omitempty"`        // random_blocks 的随机种子
+}
+
+// ProfileNames 所有策略配置的名称（排序）
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.StrategyProfiles))
+	for name := range c.StrategyProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateProfiles 校验策略配置，返回每个无效配置的错误说明
+func (c *Config) validateProfiles() map[string]string {
+	problems := make(map[string]string)
+	for name, p := range c.StrategyProfiles {
+		var errs []string
+		switch p.OrderType {
+		case "", "market", "ioc", "fok", "post_only":
+		default:
+			errs = append(errs, fmt.Sprintf("order_type '%s' 无效", p.OrderType))
+		}
+		if p.ScanIntervalMinutes < 0 {
+			errs = append(errs, "scan_interval_minutes 不能为负数")
+		}
+		for _, ind := range p.Indicators {
+			if !containsString(profileIndicators, ind) {
+				errs = append(errs, fmt.Sprintf("indicators 中的 '%s' 无效（可选: %s）", ind, strings.Join(profileIndicators, ", ")))
+			}
+		}
+		if p.PositionSize != nil && p.PositionSize.MinPositionSizeUSD > 0 && p.PositionSize.MaxPositionSizeUSD > 0 &&
+			p.PositionSize.MinPositionSizeUSD > p.PositionSize.MaxPositionSizeUSD {
+			errs = append(errs, "position_size 最小仓位大于最大仓位")
+		}
+		if len(errs) > 0 {
+			problems[name] = strings.Join(errs, "; ")
+		}
+	}
+	return problems
+}
+
+// applyProfile 用引用的策略配置填充trader未设置的字段
+func (c *Config) applyProfile(t *TraderConfig, index int, problems map[string]string) []FieldError {
+	if t.Profile == "" {
+		return nil
+	}
+	field := traderPath(t, index) + ".profile"
+	p, ok := c.StrategyProfiles[t.Profile]
+	if !ok {
+		return []FieldError{{Field: field, Message: fmt.Sprintf("策略配置 '%s' 不存在", t.Profile)}}
+	}
+	if problem, bad := problems[t.Profile]; bad {
+		return []FieldError{{Field: field, Message: fmt.Sprintf("策略配置 '%s' 无效: %s", t.Profile, problem)}}
+	}
+	if t.SystemPromptTemplate == "" {
+		t.SystemPromptTemplate = p.SystemPromptTemplate
+	}
+	if t.ScanIntervalMinutes <= 0 {
+		t.ScanIntervalMinutes = p.ScanIntervalMinutes
+	}
+	if t.OrderType == "" {
+		t.OrderType = p.OrderType
+	}
+	if t.SymbolEdgeDays == 0 {
+		t.SymbolEdgeDays = p.SymbolEdgeDays
+	}
+	return nil
+}
+
+// validateExperiment 校验A/B测试配置并填充默认值
+func (c *Config) validateExperiment(t *TraderConfig, index int, problems map[string]string) []FieldError {
+	exp := t.Experiment
+	if exp == nil {
+		return nil
+	}
+	prefix := traderPath(t, index) + ".experiment"
+	var errs []FieldError
+	if len(exp.Variants) < 2 {
+		errs = append(errs, FieldError{Field: prefix + ".variants", Message: "至少需要2个策略配置"})
+	}
+	seen := make(map[string]bool)
+	for j, name := range exp.Variants {
+		field := fmt.Sprintf("%s.variants[%d]", prefix, j)
+		if _, ok := c.StrategyProfiles[name]; !ok {
+			errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf("策略配置 '%s' 不存在", name)})
+		} else if problem, bad := problems[name]; bad {
+			errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf("策略配置 '%s' 无效: %s", name, problem)})
+		}
+		if seen[name] {
+			errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf("策略配置 '%s' 重复", name)})
+		}
+		seen[name] = true
+	}
+	switch exp.Schedule {
+	case "":
+		exp.Schedule = "alternate_days"
+	case "alternate_days", "random_blocks":
+	default:
+		errs = append(errs, FieldError{Field: prefix + ".schedule", Message: fmt.Sprintf("'%s' 无效（可选 alternate_days / random_blocks）", exp.Schedule)})
+	}
+	if exp.BlockHours <= 0 {
+		exp.BlockHours = 24
+	}
+	if (exp.BlockHours < 24 && 24%exp.BlockHours != 0) || (exp.BlockHours > 24 && exp.BlockHours%24 != 0) {
+		errs = append(errs, FieldError{Field: prefix + ".block_hours", Message: fmt.Sprintf("%d 无效（需能整除24或是24的倍数）", exp.BlockHours)})
+	}
+	if exp.Name == "" {
+		exp.Name = t.ID
+	}
+	return errs
+}
+
+// resolveProfiles 把全局的杠杆/仓位/止损补全配置合并进策略配置（需在全局默认值设置之后调用），无效的策略配置移除
+func (c *Config) resolveProfiles(problems map[string]string) {
+	for name, p := range c.StrategyProfiles {
+		if problem, bad := problems[name]; bad {
+			fmt.Printf("⚠️  警告: 策略配置 '%s' 无效，已忽略: %s\n", name, problem)
+			delete(c.StrategyProfiles, name)
+			continue
+		}
+		if p.ScanIntervalMinutes <= 0 {
+			p.ScanIntervalMinutes = 3
+		}
+
+		leverage := c.Leverage
+		if p.Leverage != nil {
+			if p.Leverage.BTCETHLeverage > 0 {
+				leverage.BTCETHLeverage = p.Leverage.BTCETHLeverage
+			}
+			if p.Leverage.AltcoinLeverage > 0 {
+				leverage.AltcoinLeverage = p.Leverage.AltcoinLeverage
+			}
+		}
+		p.Leverage = &leverage
+
+		size := c.PositionSize
+		if p.PositionSize != nil {
+			if p.PositionSize.MinPositionSizeUSD > 0 {
+				size.MinPositionSizeUSD = p.PositionSize.MinPositionSizeUSD
+			}
+			if p.PositionSize.MaxPositionSizeUSD > 0 {
+				size.MaxPositionSizeUSD = p.PositionSize.MaxPositionSizeUSD
+			}
+			if p.PositionSize.MaxMarginUsagePct > 0 {
+				size.MaxMarginUsagePct = p.PositionSize.MaxMarginUsagePct
+			}
+			if p.PositionSize.MaxPositionSizeMult > 0 {
+				size.MaxPositionSizeMult = p.PositionSize.MaxPositionSizeMult
+			}
+			if p.PositionSize.SafetyBufferPct > 0 {
+				size.SafetyBufferPct = p.PositionSize.SafetyBufferPct
+			}
+		}
+		p.PositionSize = &size
+
+		autoStop := c.AutoStopLoss
+		if p.AutoStopLoss != nil {
+			autoStop.Enabled = p.AutoStopLoss.Enabled
+			if p.AutoStopLoss.MinConfidence > 0 {
+				autoStop.MinConfidence = p.AutoStopLoss.MinConfidence
+			}
+			if p.AutoStopLoss.ATRMultiplier > 0 {
+				autoStop.ATRMultiplier = p.AutoStopLoss.ATRMultiplier
+			}
+			if p.AutoStopLoss.RiskRewardRatio >= 3.0 {
+				autoStop.RiskRewardRatio = p.AutoStopLoss.RiskRewardRatio
+			}
+		}
+		p.AutoStopLoss = &autoStop
+
+		c.StrategyProfiles[name] = p
+	}
+}
+
+// TraderRisk trader使用的杠杆、仓位大小和止损补全配置：引用了策略配置时使用策略配置（已合并全局配置）
+func (c *Config) TraderRisk(t TraderConfig) (LeverageConfig, PositionSizeConfig, AutoStopLossConfig) {
+	if p, ok := c.StrategyProfiles[t.Profile]; ok && t.Profile != "" && p.Leverage != nil {
+		return *p.Leverage, *p.PositionSize, *p.AutoStopLoss
+	}
+	return c.Leverage, c.PositionSize, c.AutoStopLoss
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
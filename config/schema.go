@@ -0,0 +1,286 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:generate go run ../cmd/configschema -src . -o ../config.schema.json
+
+// 配置的 JSON Schema
+// config.schema.json 由 Config 结构体生成（go generate ./config），字段说明取自结构体字段注释，
+// 在 config.json 顶部加上 "$schema": "./config.schema.json" 即可在编辑器中获得补全、说明和校验。
+// 加载配置时先按同一结构体生成的 schema 检查原始JSON：类型错误带完整字段路径（如 traders[binance_qwen].scan_interval_minutes）
+// 并终止加载；schema 中没有的字段（多为拼写错误，会被忽略）只记录在 UnknownFields 中。
+// 取值范围、必填项等语义检查仍由 Validate 完成（单个trader无效不影响其他trader）。
+
+// Schema JSON Schema 节点（只包含配置用到的关键字）
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // false（结构体）或值的 *Schema（map）
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// schemaEnums 取值固定的字符串字段（类型名.字段名），写入 schema 供编辑器提示
+var schemaEnums = map[string][]string{
+	"TraderConfig.AIModel":               {"qwen", "deepseek", "custom"},
+	"TraderConfig.Exchange":              {"binance", "hyperliquid", "aster", "gateio"},
+	"TraderConfig.DeepSeekModel":         {"deepseek-chat", "deepseek-reasoner"},
+	"TraderConfig.OrderType":             {"market", "ioc", "fok", "post_only"},
+	"TraderConfig.Strategy":              {"ai", "carry", "grid"},
+	"StrategyProfileConfig.OrderType":    {"market", "ioc", "fok", "post_only"},
+	"EnsembleConfig.Policy":              {"unanimous", "majority", "highest_confidence"},
+	"CarryConfig.HedgeExchange":          {"binance", "gateio"},
+	"FlowMetricsConfig.Period":           {"5m", "15m", "30m", "1h", "2h", "4h", "6h", "12h", "1d"},
+	"NotificationRuleConfig.MinSeverity": {"info", "warning", "critical"},
+	"NotificationRuleConfig.Delivery":    {"immediate", "digest", "drop"},
+}
+
+// GenerateSchema 由 Config 结构体生成 JSON Schema，descriptions 为字段说明（键为 类型名.字段名，可为 nil）
+func GenerateSchema(descriptions map[string]string) *Schema {
+	root := schemaFor(reflect.TypeOf(Config{}), descriptions)
+	root.Schema = "http://json-schema.org/draft-07/schema#"
+	root.Title = "nofx config.json"
+	root.Properties["$schema"] = &Schema{Type: "string", Description: "JSON Schema 路径（编辑器补全和校验用，加载时忽略）"}
+	return root
+}
+
+// schemaFor 类型对应的 schema
+func schemaFor(t reflect.Type, descriptions map[string]string) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+		addStructFields(s, t, descriptions)
+		return s
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaFor(t.Elem(), descriptions)}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaFor(t.Elem(), descriptions)}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	}
+	return &Schema{}
+}
+
+// addStructFields 按 json 标签加入结构体字段（匿名嵌入的结构体字段展开到同一层）
+func addStructFields(s *Schema, t reflect.Type, descriptions map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			addStructFields(s, f.Type, descriptions)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := schemaFor(f.Type, descriptions)
+		key := t.Name() + "." + f.Name
+		prop.Description = descriptions[key]
+		if enum, ok := schemaEnums[key]; ok {
+			prop.Enum = enum
+		}
+		s.Properties[name] = prop
+	}
+}
+
+// schemaCheck 按 schema 检查原始配置JSON的结果
+type schemaCheck struct {
+	errors  []FieldError // 类型错误
+	unknown []FieldError // schema 中没有的字段
+}
+
+// checkSchema 按 schema 检查原始配置JSON（语法错误时返回带行列号的错误）
+func checkSchema(data []byte, schema *Schema) (*schemaCheck, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw interface{}
+	if err := dec.Decode(&raw); err != nil {
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			line, col := lineColumn(data, syntaxErr.Offset-1) // Offset 包含出错的字符
+			return nil, fmt.Errorf("第%d行第%d列: %v", line, col, err)
+		}
+		return nil, err
+	}
+	check := &schemaCheck{}
+	check.walk("", raw, schema)
+	sort.Slice(check.unknown, func(i, j int) bool { return check.unknown[i].Field < check.unknown[j].Field })
+	return check, nil
+}
+
+// walk 递归检查值是否符合 schema（null 对任何字段都有效，与 json.Unmarshal 一致）
+func (c *schemaCheck) walk(path string, value interface{}, s *Schema) {
+	if value == nil || s.Type == "" {
+		return
+	}
+	mismatch := func() {
+		c.errors = append(c.errors, FieldError{
+			Field:   rootPath(path),
+			Message: fmt.Sprintf("应为%s，实际为%s %s", schemaTypeName(s.Type), schemaTypeName(jsonType(value)), shortJSON(value)),
+		})
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch()
+			return
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := joinPath(path, k)
+			if prop, ok := s.Properties[k]; ok {
+				c.walk(child, obj[k], prop)
+			} else if additional, ok := s.AdditionalProperties.(*Schema); ok {
+				c.walk(child, obj[k], additional)
+			} else {
+				c.unknown = append(c.unknown, FieldError{Field: child, Message: "未知字段（将被忽略）"})
+			}
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			mismatch()
+			return
+		}
+		for i, item := range list {
+			c.walk(path+elementKey(item, i), item, s.Items)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			mismatch()
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			mismatch()
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			mismatch()
+		} else if _, err := strconv.ParseInt(n.String(), 10, 64); err != nil {
+			mismatch()
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			mismatch()
+		}
+	}
+}
+
+// elementKey 数组元素在路径中的写法：有 id 字段的对象用 [id]（与 Validate 的字段路径一致），否则用下标
+func elementKey(item interface{}, index int) string {
+	if obj, ok := item.(map[string]interface{}); ok {
+		if id, ok := obj["id"].(string); ok && id != "" {
+			return "[" + id + "]"
+		}
+		return fmt.Sprintf("[#%d]", index)
+	}
+	return fmt.Sprintf("[%d]", index)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func rootPath(path string) string {
+	if path == "" {
+		return "(根)"
+	}
+	return path
+}
+
+// jsonType 解码后的值对应的 JSON 类型
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return "integer"
+		}
+		return "number"
+	}
+	return "null"
+}
+
+func schemaTypeName(t string) string {
+	switch t {
+	case "object":
+		return "对象"
+	case "array":
+		return "数组"
+	case "string":
+		return "字符串"
+	case "boolean":
+		return "布尔值"
+	case "integer":
+		return "整数"
+	case "number":
+		return "数字"
+	}
+	return t
+}
+
+// shortJSON 错误信息中引用的原值（过长时截断）
+func shortJSON(value interface{}) string {
+	data, _ := json.Marshal(value)
+	if len(data) > 40 {
+		return string(data[:37]) + "..."
+	}
+	return string(data)
+}
+
+// lineColumn 字节偏移对应的行列号（从1开始）
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
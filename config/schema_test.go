@@ -0,0 +1,156 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig 写入临时配置文件
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testTrader 能通过校验的最小trader配置（额外字段追加在末尾）
+func testTrader(id string, extra string) string {
+	return `{"id": "` + id + `", "name": "` + id + `", "enabled": true, "ai_model": "custom",
+		"custom_api_url": "http://localhost", "custom_api_key": "k", "custom_model_name": "m",
+		"exchange": "gateio", "gateio_api_key": "k", "gateio_secret_key": "s", "initial_balance": 1000` + extra + `}`
+}
+
+func TestSchemaUnknownFields(t *testing.T) {
+	check, err := checkSchema([]byte(`{
+		"$schema": "./config.schema.json",
+		"traders": [`+testTrader("t1", `, "scan_intervall_minutes": 3, "review": {"enabeld": true}`)+`],
+		"symbol_limits": {"ETHUSDT": {"max_leverage": 5, "max_levrage": 3}},
+		"api_port": 8080
+	}`), GenerateSchema(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(check.errors) != 0 {
+		t.Fatalf("不应有类型错误: %v", check.errors)
+	}
+	// 按路径排序；数组元素用 id，map 的值按值类型的 schema 检查
+	want := []string{
+		"api_port",
+		"symbol_limits.ETHUSDT.max_levrage",
+		"traders[t1].review.enabeld",
+		"traders[t1].scan_intervall_minutes",
+	}
+	if len(check.unknown) != len(want) {
+		t.Fatalf("未知字段 = %v", check.unknown)
+	}
+	for i, field := range want {
+		if check.unknown[i].Field != field || check.unknown[i].Message != "未知字段（将被忽略）" {
+			t.Errorf("第%d个未知字段 = %+v, 期望 %s", i, check.unknown[i], field)
+		}
+	}
+}
+
+func TestSchemaTypeMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string // 完整错误信息，空表示没有错误
+	}{
+		{name: "字符串写成数字", json: `{"traders": [{"id": "t1", "scan_interval_minutes": "3"}]}`,
+			want: `traders[t1].scan_interval_minutes: 应为整数，实际为字符串 "3"`},
+		{name: "整数字段写成小数", json: `{"traders": [{"id": "t1", "scan_interval_minutes": 1.5}]}`,
+			want: `traders[t1].scan_interval_minutes: 应为整数，实际为数字 1.5`},
+		{name: "数字字段可以是整数", json: `{"traders": [{"id": "t1", "initial_balance": 1000}]}`},
+		{name: "布尔写成字符串", json: `{"traders": [{"id": "t1", "enabled": "true"}]}`,
+			want: `traders[t1].enabled: 应为布尔值，实际为字符串 "true"`},
+		{name: "没有id的数组元素用下标", json: `{"traders": [{"id": "t1"}, {"enabled": 1}]}`,
+			want: `traders[#1].enabled: 应为布尔值，实际为整数 1`},
+		{name: "字符串数组的元素", json: `{"default_coins": ["BTCUSDT", 1]}`,
+			want: `default_coins[1]: 应为字符串，实际为整数 1`},
+		{name: "数组写成字符串", json: `{"default_coins": "BTCUSDT,ETHUSDT"}`,
+			want: `default_coins: 应为数组，实际为字符串 "BTCUSDT,ETHUSDT"`},
+		{name: "map的值", json: `{"symbol_limits": {"ETHUSDT": {"max_leverage": "5"}}}`,
+			want: `symbol_limits.ETHUSDT.max_leverage: 应为整数，实际为字符串 "5"`},
+		{name: "过长的原值截断", json: `{"traders": [{"id": "t1", "ai_model": {"name": "custom-model-with-a-very-long-name"}}]}`,
+			want: `traders[t1].ai_model: 应为字符串，实际为对象 {"name":"custom-model-with-a-very-lon...`},
+		{name: "根不是对象", json: `[]`, want: `(根): 应为对象，实际为数组 []`},
+		{name: "null 对任何字段有效", json: `{"traders": null, "api_server_port": null}`},
+	}
+	schema := GenerateSchema(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := checkSchema([]byte(tt.json), schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range check.errors {
+				got = append(got, e.Error())
+			}
+			if strings.Join(got, "; ") != tt.want {
+				t.Errorf("类型错误 = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaSyntaxErrorPosition(t *testing.T) {
+	_, err := checkSchema([]byte("{\n  \"traders\": [\n    {\"id\": \"t1\",}\n  ]\n}"), GenerateSchema(nil))
+	if err == nil || !strings.HasPrefix(err.Error(), "第3行第17列: ") {
+		t.Fatalf("语法错误应带行列号: %v", err)
+	}
+}
+
+func TestGenerateSchema(t *testing.T) {
+	schema := GenerateSchema(map[string]string{"TraderConfig.ScanIntervalMinutes": "扫描间隔"})
+	trader := schema.Properties["traders"].Items
+	if trader == nil || trader.Type != "object" || trader.AdditionalProperties != false {
+		t.Fatalf("traders 应为不允许额外字段的对象数组: %+v", schema.Properties["traders"])
+	}
+	if p := trader.Properties["scan_interval_minutes"]; p.Type != "integer" || p.Description != "扫描间隔" {
+		t.Errorf("scan_interval_minutes = %+v", p)
+	}
+	if p := trader.Properties["exchange"]; strings.Join(p.Enum, ",") != "binance,hyperliquid,aster,gateio" {
+		t.Errorf("exchange 的可选值 = %v", p.Enum)
+	}
+	limits := schema.Properties["symbol_limits"]
+	if value, ok := limits.AdditionalProperties.(*Schema); limits.Type != "object" || !ok || value.Properties["max_leverage"] == nil {
+		t.Errorf("symbol_limits 应为值是对象的 map: %+v", limits)
+	}
+	if schema.Properties["$schema"] == nil {
+		t.Error("应允许 $schema 字段")
+	}
+	if _, ok := schema.Properties["InvalidTraders"]; ok {
+		t.Error(`json:"-" 的字段不应出现在 schema 中`)
+	}
+}
+
+func TestLoadConfigSchemaErrors(t *testing.T) {
+	// 类型错误终止加载，报告所有错误的完整路径
+	_, err := LoadConfig(writeConfig(t, `{"traders": [`+testTrader("t1", `, "scan_interval_minutes": "3", "enabled": 1`)+`]}`))
+	want := `配置字段类型错误: traders[t1].enabled: 应为布尔值，实际为整数 1; traders[t1].scan_interval_minutes: 应为整数，实际为字符串 "3"`
+	if err == nil || err.Error() != want {
+		t.Fatalf("LoadConfig 错误 = %v\n期望 %s", err, want)
+	}
+
+	// 语法错误
+	if _, err := LoadConfig(writeConfig(t, `{"traders": [}`)); err == nil || !strings.HasPrefix(err.Error(), "解析配置文件失败: 第1行第14列") {
+		t.Fatalf("语法错误 = %v", err)
+	}
+
+	// 未知字段只记录，不影响加载
+	cfg, err := LoadConfig(writeConfig(t, `{"traders": [`+testTrader("t1", `, "scan_intervall_minutes": 5`)+`], "api_port": 9000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.UnknownFields) != 2 || cfg.UnknownFields[0].Field != "api_port" || cfg.UnknownFields[1].Field != "traders[t1].scan_intervall_minutes" {
+		t.Errorf("未知字段 = %v", cfg.UnknownFields)
+	}
+	if cfg.Traders[0].ScanIntervalMinutes != 3 || cfg.APIServerPort != 8080 {
+		t.Errorf("拼错的字段应被忽略（使用默认值）: %d %d", cfg.Traders[0].ScanIntervalMinutes, cfg.APIServerPort)
+	}
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 部分配置无效时的安全启动
+// Validate 逐个trader收集字段级错误（一个trader的所有错误一次报全），配置无效的trader不启动，
+// 记录在 Config.InvalidTraders 中，由 main 写入日志并通过 /api/config/errors 查询，其余trader正常启动。
+// 全局配置错误和所有trader都无效时仍然返回错误终止启动。
+
+// FieldError 配置字段错误
+type FieldError struct {
+	Field   string `json:"field"` // 如 "traders[binance_trader].binance_api_key"
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// InvalidTrader 配置无效、未启动的trader
+type InvalidTrader struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Enabled bool         `json:"enabled"`
+	Errors  []FieldError `json:"errors"`
+}
+
+var (
+	hexKeyPattern  = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+	addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+)
+
+// traderPath trader在字段路径中的名称（优先使用ID）
+func traderPath(t *TraderConfig, index int) string {
+	if t.ID != "" {
+		return fmt.Sprintf("traders[%s]", t.ID)
+	}
+	return fmt.Sprintf("traders[#%d]", index)
+}
+
+// validateTrader 校验单个trader，返回所有字段错误（同时填充默认值）
+func validateTrader(t *TraderConfig, index int, seenIDs map[string]bool, secretErrs []FieldError) []FieldError {
+	path := traderPath(t, index)
+	var errs []FieldError
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: path + "." + field, Message: fmt.Sprintf(format, args...)})
+	}
+	errs = append(errs, secretErrs...)
+
+	if t.ID == "" {
+		add("id", "不能为空")
+	} else if seenIDs[t.ID] {
+		add("id", "'%s' 重复", t.ID)
+	}
+	if t.Name == "" {
+		add("name", "不能为空")
+	}
+	if t.Timezone != "" {
+		if _, err := time.LoadLocation(t.Timezone); err != nil {
+			add("timezone", "'%s' 无效: %v", t.Timezone, err)
+		}
+	}
+	switch t.MarketDataFormat {
+	case "", "verbose", "compact":
+	default:
+		add("market_data_format", "必须是 'verbose' 或 'compact'，当前为 '%s'", t.MarketDataFormat)
+	}
+
+	aiRequired := t.Strategy != "carry" && t.Strategy != "grid" // 资金费套利和网格不调用AI
+	if aiRequired {
+		switch t.AIModel {
+		case "qwen":
+			if t.QwenKey == "" {
+				add("qwen_key", "使用Qwen时必须配置")
+			}
+		case "deepseek":
+			if t.DeepSeekKey == "" {
+				add("deepseek_key", "使用DeepSeek时必须配置")
+			}
+			switch t.DeepSeekModel {
+			case "", "deepseek-chat", "deepseek-reasoner":
+			default:
+				add("deepseek_model", "必须是 'deepseek-chat' 或 'deepseek-reasoner'，当前为 '%s'", t.DeepSeekModel)
+			}
+		case "custom":
+			if t.CustomAPIURL == "" {
+				add("custom_api_url", "使用自定义API时必须配置")
+			}
+			if t.CustomAPIKey == "" {
+				add("custom_api_key", "使用自定义API时必须配置")
+			}
+			if t.CustomModelName == "" {
+				add("custom_model_name", "使用自定义API时必须配置")
+			}
+		default:
+			add("ai_model", "必须是 'qwen', 'deepseek' 或 'custom'，当前为 '%s'", t.AIModel)
+		}
+	}
+
+	// 交易平台和对应的密钥
+	if t.Exchange == "" {
+		t.Exchange = "binance" // 默认使用币安
+	}
+	switch t.Exchange {
+	case "binance":
+		requireKey(add, "binance_api_key", t.BinanceAPIKey, "使用币安时必须配置")
+		requireKey(add, "binance_secret_key", t.BinanceSecretKey, "使用币安时必须配置")
+	case "hyperliquid":
+		if requireKey(add, "hyperliquid_private_key", t.HyperliquidPrivateKey, "使用Hyperliquid时必须配置") && !hexKeyPattern.MatchString(t.HyperliquidPrivateKey) {
+			add("hyperliquid_private_key", "必须是64位十六进制私钥（不带0x前缀）")
+		}
+		if t.HyperliquidWalletAddr != "" && !addressPattern.MatchString(t.HyperliquidWalletAddr) {
+			add("hyperliquid_wallet_addr", "必须是0x开头的40位十六进制地址")
+		}
+	case "aster":
+		if requireKey(add, "aster_user", t.AsterUser, "使用Aster时必须配置") && !addressPattern.MatchString(t.AsterUser) {
+			add("aster_user", "必须是0x开头的40位十六进制地址")
+		}
+		if requireKey(add, "aster_signer", t.AsterSigner, "使用Aster时必须配置") && !addressPattern.MatchString(t.AsterSigner) {
+			add("aster_signer", "必须是0x开头的40位十六进制地址")
+		}
+		if requireKey(add, "aster_private_key", t.AsterPrivateKey, "使用Aster时必须配置") && !hexKeyPattern.MatchString(strings.TrimPrefix(t.AsterPrivateKey, "0x")) {
+			add("aster_private_key", "必须是64位十六进制私钥")
+		}
+	case "gateio":
+		requireKey(add, "gateio_api_key", t.GateioAPIKey, "使用Gate.io时必须配置")
+		requireKey(add, "gateio_secret_key", t.GateioSecretKey, "使用Gate.io时必须配置")
+	default:
+		add("exchange", "必须是 'binance', 'hyperliquid', 'aster' 或 'gateio'，当前为 '%s'", t.Exchange)
+	}
+
+	switch t.OrderType {
+	case "", "market", "ioc", "fok", "post_only":
+	default:
+		add("order_type", "必须是 'market', 'ioc', 'fok' 或 'post_only'，当前为 '%s'", t.OrderType)
+	}
+
+	if t.Review.Enabled && t.Review.CustomAPIURL != "" && (t.Review.CustomAPIKey == "" || t.Review.CustomModelName == "") {
+		add("review", "使用独立模型时必须配置custom_api_key和custom_model_name")
+	}
+	if t.Ensemble.Enabled {
+		if n := len(t.Ensemble.Models); n < 1 || n > 2 {
+			add("ensemble.models", "需要配置1-2个额外模型（加上trader自身的模型共2-3个），当前为%d个", n)
+		}
+		for j, m := range t.Ensemble.Models {
+			if m.CustomAPIURL == "" || m.CustomAPIKey == "" || m.CustomModelName == "" {
+				add(fmt.Sprintf("ensemble.models[%d]", j), "必须配置custom_api_url、custom_api_key和custom_model_name")
+			}
+		}
+		switch t.Ensemble.Policy {
+		case "", "unanimous", "majority", "highest_confidence": // 空表示 majority
+		default:
+			add("ensemble.policy", "必须是 'unanimous', 'majority' 或 'highest_confidence'")
+		}
+	}
+
+	switch t.Strategy {
+	case "", "ai":
+	case "carry":
+		if err := validateCarry(*t); err != nil {
+			add("carry", "%v", err)
+		}
+	case "grid":
+		if err := validateGrid(t.Grid); err != nil {
+			add("grid", "%v", err)
+		}
+	default:
+		add("strategy", "必须是 'ai', 'carry' 或 'grid'，当前为 '%s'", t.Strategy)
+	}
+
+	// 互相冲突的限制
+	blacklist := make(map[string]bool, len(t.SymbolBlacklist))
+	for _, s := range t.SymbolBlacklist {
+		blacklist[strings.ToUpper(s)] = true
+	}
+	for _, s := range t.SymbolWhitelist {
+		if blacklist[strings.ToUpper(s)] {
+			add("symbol_whitelist", "%s 同时在黑名单中", s)
+		}
+	}
+
+	if t.Approval.TTLMinutes < 0 {
+		add("approval.ttl_minutes", "不能为负数")
+	}
+	if t.InitialBalance <= 0 {
+		add("initial_balance", "必须大于0")
+	}
+	if t.ScanIntervalMinutes <= 0 {
+		t.ScanIntervalMinutes = 3 // 默认3分钟
+	}
+	return errs
+}
+
+// requireKey 检查必填的密钥/地址（不能为空、不能包含空白字符），返回是否可以继续校验格式
+func requireKey(add func(field, format string, args ...interface{}), field, value, missing string) bool {
+	if value == "" {
+		add(field, "%s", missing)
+		return false
+	}
+	if strings.ContainsAny(value, " \t\r\n") {
+		add(field, "包含空白字符（复制时多带了空格或换行？）")
+		return false
+	}
+	return true
+}
+
+// summarizeInvalid 所有无效trader的错误（用于启动失败时的错误信息）
+func summarizeInvalid(invalid []InvalidTrader) string {
+	var parts []string
+	for _, t := range invalid {
+		for _, e := range t.Errors {
+			parts = append(parts, e.Error())
+		}
+	}
+	return strings.Join(parts, "; ")
+}
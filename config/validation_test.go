@@ -0,0 +1,110 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInvalidTraderIsolated(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `{"traders": [
+		`+testTrader("good1", ``)+`,
+		{"id": "bad", "name": "", "enabled": true, "ai_model": "custom", "custom_api_url": "http://localhost",
+		 "exchange": "gateio", "gateio_api_key": "key with space", "initial_balance": 0, "timezone": "Mars/Base"},
+		`+testTrader("good1", `, "name": "dup"`)+`,
+		`+testTrader("secret", `, "gateio_secret_key": "secret://missing/key"`)+`,
+		`+testTrader("good2", `, "enabled": false`)+`
+	]}`))
+	if err != nil {
+		t.Fatalf("部分trader无效时应正常加载: %v", err)
+	}
+
+	if len(cfg.Traders) != 2 || cfg.Traders[0].ID != "good1" || cfg.Traders[0].Name != "good1" || cfg.Traders[1].ID != "good2" {
+		t.Fatalf("有效的trader应保留（按原顺序）: %+v", cfg.Traders)
+	}
+	if cfg.Traders[0].ScanIntervalMinutes != 3 {
+		t.Errorf("有效trader应填充默认值: %d", cfg.Traders[0].ScanIntervalMinutes)
+	}
+
+	if len(cfg.InvalidTraders) != 3 {
+		t.Fatalf("无效trader = %+v", cfg.InvalidTraders)
+	}
+	// 一个trader的所有错误一次报全
+	bad := cfg.InvalidTraders[0]
+	if bad.ID != "bad" || !bad.Enabled {
+		t.Errorf("无效trader = %+v", bad)
+	}
+	var fields []string
+	for _, e := range bad.Errors {
+		fields = append(fields, e.Field)
+	}
+	want := []string{
+		"traders[bad].name",
+		"traders[bad].timezone",
+		"traders[bad].custom_api_key",
+		"traders[bad].custom_model_name",
+		"traders[bad].gateio_api_key",
+		"traders[bad].gateio_secret_key",
+		"traders[bad].initial_balance",
+	}
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Errorf("错误字段 = %v\n期望 %v", fields, want)
+	}
+	for _, e := range bad.Errors {
+		if e.Field == "traders[bad].gateio_api_key" && !strings.Contains(e.Message, "空白字符") {
+			t.Errorf("密钥中的空格: %s", e.Message)
+		}
+	}
+
+	// 重复的ID：后出现的无效，第一个不受影响
+	if dup := cfg.InvalidTraders[1]; dup.Name != "dup" || len(dup.Errors) != 1 || dup.Errors[0].Error() != "traders[good1].id: 'good1' 重复" {
+		t.Errorf("重复ID = %+v", dup)
+	}
+	// secret:// 引用解析失败只使该trader无效
+	if s := cfg.InvalidTraders[2]; s.ID != "secret" || len(s.Errors) != 1 || s.Errors[0].Field != "traders[secret].gateio_secret_key" {
+		t.Errorf("密钥引用错误 = %+v", s)
+	}
+}
+
+func TestAllTradersInvalid(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, `{"traders": [
+		{"id": "a", "name": "a", "ai_model": "gpt", "exchange": "gateio", "gateio_api_key": "k", "gateio_secret_key": "s", "initial_balance": 100},
+		{"id": "b", "name": "b", "ai_model": "custom", "custom_api_url": "u", "custom_api_key": "k", "custom_model_name": "m", "exchange": "okx", "initial_balance": 100}
+	]}`))
+	want := "配置验证失败: 所有trader的配置都无效: traders[a].ai_model: 必须是 'qwen', 'deepseek' 或 'custom'，当前为 'gpt'; " +
+		"traders[b].exchange: 必须是 'binance', 'hyperliquid', 'aster' 或 'gateio'，当前为 'okx'"
+	if err == nil || err.Error() != want {
+		t.Fatalf("错误 = %v\n期望 %s", err, want)
+	}
+
+	if _, err := LoadConfig(writeConfig(t, `{"traders": []}`)); err == nil || !strings.Contains(err.Error(), "至少需要配置一个trader") {
+		t.Fatalf("没有trader: %v", err)
+	}
+}
+
+func TestValidateTraderStrategyRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		trader TraderConfig
+		want   string // 期望的错误（字段: 信息），空表示有效
+	}{
+		{name: "网格不需要AI", trader: TraderConfig{ID: "g", Name: "g", Strategy: "grid", Exchange: "gateio", GateioAPIKey: "k", GateioSecretKey: "s", InitialBalance: 100,
+			Grid: []GridSymbolConfig{{Symbol: "ETHUSDT", Lower: 1000, Upper: 2000, Levels: 10, SizePerLevelUSD: 50}}}},
+		{name: "白名单与黑名单冲突", trader: TraderConfig{ID: "w", Name: "w", AIModel: "qwen", QwenKey: "k", Exchange: "gateio", GateioAPIKey: "k", GateioSecretKey: "s", InitialBalance: 100,
+			SymbolWhitelist: []string{"ethusdt"}, SymbolBlacklist: []string{"ETHUSDT"}}, want: "traders[w].symbol_whitelist: ethusdt 同时在黑名单中"},
+		{name: "Hyperliquid私钥格式", trader: TraderConfig{ID: "h", Name: "h", AIModel: "qwen", QwenKey: "k", Exchange: "hyperliquid", HyperliquidPrivateKey: "0xabc", InitialBalance: 100},
+			want: "traders[h].hyperliquid_private_key: 必须是64位十六进制私钥（不带0x前缀）"},
+		{name: "默认交易所为币安", trader: TraderConfig{ID: "b", Name: "b", AIModel: "qwen", QwenKey: "k", InitialBalance: 100},
+			want: "traders[b].binance_api_key: 使用币安时必须配置; traders[b].binance_secret_key: 使用币安时必须配置"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range validateTrader(&tt.trader, 0, map[string]bool{}, nil) {
+				got = append(got, e.Error())
+			}
+			if strings.Join(got, "; ") != tt.want {
+				t.Errorf("错误 = %q\n期望 %q", got, tt.want)
+			}
+		})
+	}
+}
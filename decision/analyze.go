@@ -0,0 +1,87 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/indicator"
+	"nofx/market"
+	"nofx/mcp"
+	"strings"
+	"time"
+)
+
+// 单币种即时分析
+// 看板和人工研究需要随时查看某个币种的市场数据和AI的看法，而不必等下一个交易周期、也不能触发下单。
+// 分析时构建只包含该币种的上下文（账户信息和该币种的持仓），按交易周期相同的流程获取市场数据和指标、
+// 生成相同格式的prompt；需要AI意见时调用AI并按相同规则解析校验，决策只返回、不执行。
+
+// SymbolAnalysis 单币种分析结果
+type SymbolAnalysis struct {
+	Symbol   string        `json:"symbol"`
+	Time     time.Time     `json:"time"`     // 行情快照时间
+	Analysis string        `json:"analysis"` // 市场数据和技术指标（与交易周期prompt中的格式相同）
+	Data     *market.Data  `json:"data"`
+	Opinion  *FullDecision `json:"opinion,omitempty"`  // AI意见（只返回，不执行）
+	AIError  string        `json:"ai_error,omitempty"` // 请求AI意见失败的原因（市场分析仍然返回）
+}
+
+// AnalyzeSymbol 为 symbol 构建单币种上下文并生成分析；mcpClient 不为 nil 时同时请求AI意见
+// ctx 提供账户信息和交易配置，其中的持仓只保留该币种，候选币种替换为该币种
+func AnalyzeSymbol(ctx *Context, symbol string, mcpClient *mcp.Client) (*SymbolAnalysis, error) {
+	symbol = market.Normalize(symbol)
+	var positions []PositionInfo
+	for _, pos := range ctx.Positions {
+		if pos.Symbol == symbol {
+			positions = append(positions, pos)
+		}
+	}
+	ctx.Positions = positions
+	ctx.CandidateCoins = []CandidateCoin{{Symbol: symbol}}
+
+	systemPrompt, userPrompt, err := prepareDecisionPrompts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := ctx.MarketDataMap[symbol]
+	if !ok {
+		return nil, fmt.Errorf("%s 没有可用的市场数据（获取失败，或持仓价值低于15M USD被流动性过滤）", symbol)
+	}
+
+	result := &SymbolAnalysis{
+		Symbol:   symbol,
+		Time:     ctx.MarketDataTime,
+		Analysis: formatSymbolAnalysis(ctx, symbol, data),
+		Data:     data,
+	}
+	if mcpClient == nil {
+		return result, nil
+	}
+
+	opinion, err := callAndParseDecision(ctx, mcpClient, systemPrompt, userPrompt)
+	if err != nil {
+		result.AIError = err.Error()
+		return result, nil
+	}
+	opinion.Timestamp = time.Now()
+	opinion.UserPrompt = userPrompt
+	result.Opinion = opinion
+	return result, nil
+}
+
+// formatSymbolAnalysis 单币种的市场数据、相对强弱和技术指标分析
+func formatSymbolAnalysis(ctx *Context, symbol string, data *market.Data) string {
+	var sb strings.Builder
+	if ctx.MarketFormat == market.FormatModeCompact {
+		sb.WriteString(compactMarketLegend)
+	}
+	sb.WriteString(market.FormatAs(data, ctx.MarketFormat))
+	sb.WriteString("\n")
+	writeRelativeStrength(&sb, ctx, symbol)
+
+	indicatorAnalysis := indicator.Analyze(data)
+	if indicatorAnalysis != "" && indicatorAnalysis != "No significant patterns detected in recent price action." {
+		sb.WriteString("\n### 📊 技术指标分析\n\n")
+		sb.WriteString(indicatorAnalysis)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
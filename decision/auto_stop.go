@@ -0,0 +1,89 @@
+package decision
+
+import (
+	"fmt"
+	"log"
+	"nofx/market"
+)
+
+// AI自动止损止盈补全
+// AI偶尔会在开仓决策中漏填止损/止盈，或者给出方向错误、风险回报比不达标的价格，验证时整条决策会被拒绝。
+// 开启后，对信心度达标的开仓决策用 ATR 倍数计算止损、按目标风险回报比计算止盈，替换缺失或无效的价格后继续执行。
+
+// AutoStopConfig 自动止损止盈配置
+type AutoStopConfig struct {
+	Enabled         bool    // 是否启用（默认关闭，缺失/无效的止损止盈直接拒绝）
+	MinConfidence   int     // 最低信心度（0-100），低于此值的决策仍然拒绝
+	ATRMultiplier   float64 // 止损距离 = 4小时ATR14 × 倍数
+	RiskRewardRatio float64 // 止盈距离 = 止损距离 × 风险回报比
+}
+
+// applyAutoStops 为缺失或无效止损止盈的开仓决策补全价格，返回替换说明
+func applyAutoStops(decisions []Decision, cfg AutoStopConfig, marketDataMap map[string]*market.Data) []string {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	var notes []string
+	for i := range decisions {
+		d := &decisions[i]
+		if d.Action != "open_long" && d.Action != "open_short" {
+			continue
+		}
+		if d.Confidence < cfg.MinConfidence {
+			continue
+		}
+		data, ok := marketDataMap[d.Symbol]
+		if !ok || data == nil || data.CurrentPrice <= 0 || data.LongerTermContext == nil || data.LongerTermContext.ATR14 <= 0 {
+			continue // 没有ATR数据无法计算，交给验证拒绝
+		}
+
+		note := autoStopsFor(d, data.CurrentPrice, data.LongerTermContext.ATR14, cfg)
+		if note == "" {
+			continue
+		}
+		log.Printf("🛡 %s %s 自动补全止损止盈: %s", d.Symbol, d.Action, note)
+		d.Reasoning += fmt.Sprintf(" [自动止损止盈: %s]", note)
+		notes = append(notes, fmt.Sprintf("%s %s: %s", d.Symbol, d.Action, note))
+	}
+	return notes
+}
+
+// autoStopsFor 计算单个开仓决策的止损止盈，价格有效时保留AI给出的值，无需替换时返回空字符串
+func autoStopsFor(d *Decision, price, atr float64, cfg AutoStopConfig) string {
+	direction := 1.0 // 做多：止损在下，止盈在上
+	if d.Action == "open_short" {
+		direction = -1.0
+	}
+
+	// 价格必须在当前价的正确一侧
+	slValid := d.StopLoss > 0 && (price-d.StopLoss)*direction > 0
+	tpValid := d.TakeProfit > 0 && (d.TakeProfit-price)*direction > 0
+
+	risk := atr * cfg.ATRMultiplier
+	if slValid {
+		risk = (price - d.StopLoss) * direction
+	}
+	// 止盈达不到目标风险回报比同样视为无效（验证时会被拒绝）
+	if tpValid && (d.TakeProfit-price)*direction < risk*cfg.RiskRewardRatio {
+		tpValid = false
+	}
+	if slValid && tpValid {
+		return ""
+	}
+
+	oldSL, oldTP := d.StopLoss, d.TakeProfit
+	if !slValid {
+		d.StopLoss = price - direction*risk
+	}
+	if !tpValid {
+		d.TakeProfit = price + direction*risk*cfg.RiskRewardRatio
+	}
+	if d.StopLoss <= 0 || d.TakeProfit <= 0 {
+		d.StopLoss, d.TakeProfit = oldSL, oldTP
+		return ""
+	}
+
+	return fmt.Sprintf("止损 %.4f→%.4f 止盈 %.4f→%.4f（ATR14=%.4f ×%.1f，风险回报比 %.1f:1）",
+		oldSL, d.StopLoss, oldTP, d.TakeProfit, atr, cfg.ATRMultiplier, cfg.RiskRewardRatio)
+}
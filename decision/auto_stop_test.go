@@ -0,0 +1,84 @@
+package decision
+
+import (
+	"math"
+	"nofx/market"
+	"testing"
+)
+
+func TestApplyAutoStops(t *testing.T) {
+	cfg := AutoStopConfig{Enabled: true, MinConfidence: 70, ATRMultiplier: 1.5, RiskRewardRatio: 3}
+	data := map[string]*market.Data{
+		"BTCUSDT": {Symbol: "BTCUSDT", CurrentPrice: 100, LongerTermContext: &market.LongerTermData{ATR14: 2}},
+	}
+
+	tests := []struct {
+		name           string
+		decision       Decision
+		wantSL, wantTP float64
+		wantNote       bool
+	}{
+		{
+			name:     "缺失止损止盈",
+			decision: Decision{Symbol: "BTCUSDT", Action: "open_long", Confidence: 80},
+			wantSL:   97, wantTP: 109, wantNote: true,
+		},
+		{
+			name:     "空单止损方向错误，保留有效止盈",
+			decision: Decision{Symbol: "BTCUSDT", Action: "open_short", Confidence: 80, StopLoss: 95, TakeProfit: 90},
+			wantSL:   103, wantTP: 90, wantNote: true,
+		},
+		{
+			name:     "止损有效，止盈风险回报比不足",
+			decision: Decision{Symbol: "BTCUSDT", Action: "open_long", Confidence: 80, StopLoss: 98, TakeProfit: 101},
+			wantSL:   98, wantTP: 106, wantNote: true,
+		},
+		{
+			name:     "价格有效不替换",
+			decision: Decision{Symbol: "BTCUSDT", Action: "open_long", Confidence: 80, StopLoss: 98, TakeProfit: 110},
+			wantSL:   98, wantTP: 110,
+		},
+		{
+			name:     "信心度不足不替换",
+			decision: Decision{Symbol: "BTCUSDT", Action: "open_long", Confidence: 50},
+		},
+		{
+			name:     "没有市场数据不替换",
+			decision: Decision{Symbol: "ETHUSDT", Action: "open_long", Confidence: 90},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decisions := []Decision{tt.decision}
+			notes := applyAutoStops(decisions, cfg, data)
+			if (len(notes) > 0) != tt.wantNote {
+				t.Fatalf("替换说明 = %v, 期望替换: %v", notes, tt.wantNote)
+			}
+			d := decisions[0]
+			if math.Abs(d.StopLoss-tt.wantSL) > 1e-9 || math.Abs(d.TakeProfit-tt.wantTP) > 1e-9 {
+				t.Errorf("止损/止盈 = %.4f/%.4f, 期望 %.4f/%.4f", d.StopLoss, d.TakeProfit, tt.wantSL, tt.wantTP)
+			}
+		})
+	}
+}
+
+func TestAutoStopsPassValidation(t *testing.T) {
+	cfg := AutoStopConfig{Enabled: true, MinConfidence: 70, ATRMultiplier: 1.5, RiskRewardRatio: 3}
+	data := map[string]*market.Data{
+		"SOLUSDT": {Symbol: "SOLUSDT", CurrentPrice: 150, LongerTermContext: &market.LongerTermData{ATR14: 4}},
+	}
+	response := `[{"symbol": "SOLUSDT", "action": "open_short", "leverage": 3, "position_size_usd": 500, "confidence": 85, "reasoning": "跌破支撑"}]`
+
+	if _, err := parseFullDecisionResponse(response, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, nil); err == nil {
+		t.Fatal("未启用时缺少止损止盈的决策应被拒绝")
+	}
+
+	full, err := parseFullDecisionResponse(response, 10000, 10, 5, 0, 0, nil, nil, cfg, data, nil)
+	if err != nil {
+		t.Fatalf("补全后应通过验证: %v", err)
+	}
+	if len(full.AutoStops) != 1 {
+		t.Errorf("应记录1条替换说明，实际: %v", full.AutoStops)
+	}
+}
@@ -1,6 +1,7 @@
 package decision
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -8,6 +9,7 @@ import (
 	"nofx/market"
 	"nofx/mcp"
 	"nofx/pool"
+	"nofx/tracing"
 	"regexp"
 	"strings"
 	"time"
@@ -15,6 +17,7 @@ import (
 
 // PositionInfo 持仓信息
 type PositionInfo struct {
+	PositionID       string  `json:"position_id,omitempty"` // 持仓ID（贯穿持仓的整个生命周期）
 	Symbol           string  `json:"symbol"`
 	Side             string  `json:"side"` // "long" or "short"
 	EntryPrice       float64 `json:"entry_price"`
@@ -25,24 +28,63 @@ type PositionInfo struct {
 	UnrealizedPnLPct float64 `json:"unrealized_pnl_pct"`
 	LiquidationPrice float64 `json:"liquidation_price"`
 	MarginUsed       float64 `json:"margin_used"`
+	ADLRanking       int     `json:"adl_ranking,omitempty"`   // 自动减仓排名 1-5（5最先被减仓，0表示未知）
+	RealizedPnL      float64 `json:"realized_pnl,omitempty"`  // 本仓位已实现盈亏（含手续费和资金费）
+	PositionMode     string  `json:"position_mode,omitempty"` // single / dual_long / dual_short
 	UpdateTime       int64   `json:"update_time"` // 持仓更新时间戳（毫秒）
+	StopLoss         float64 `json:"stop_loss"`   // 当前止损价（0表示未知/未设置）
+	TakeProfit       float64 `json:"take_profit"` // 当前止盈价（0表示未知/未设置）
+
+	CumulativeFunding float64 `json:"cumulative_funding"` // 持仓期间累计资金费（正数=净收到）
+
+	Plan *PositionPlan `json:"plan,omitempty"` // AI上次对该持仓陈述的计划（没有时为nil）
+}
+
+// PositionPlan AI对持仓陈述的计划（跨周期保存，写入下个周期的持仓信息）
+type PositionPlan struct {
+	Text      string    `json:"text"`
+	Action    string    `json:"action"`     // 陈述计划时的动作（open_long/hold/adjust_sl...）
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SimilarSetup 与当前行情相似的一次历史开仓及其结果（写入user prompt）
+type SimilarSetup struct {
+	Symbol    string    `json:"symbol"`
+	Side      string    `json:"side"`
+	Time      time.Time `json:"time"`  // 开仓时间
+	Score     float64   `json:"score"` // 相似度 0-1
+	Reasoning string    `json:"reasoning"`
+	PnL       float64   `json:"pnl"`
+	RMultiple *float64  `json:"r_multiple,omitempty"`
+	Duration  string    `json:"duration"`
+}
+
+// SymbolEdge 某币种最近的已平仓交易表现（写入user prompt）
+type SymbolEdge struct {
+	Symbol      string  `json:"symbol"`
+	Trades      int     `json:"trades"`
+	WinRate     float64 `json:"win_rate"`     // 胜率百分比
+	RealizedPnL float64 `json:"realized_pnl"` // 已实现盈亏（USDT）
+	AvgR        float64 `json:"avg_r"`        // 平均R倍数
+	RTrades     int     `json:"r_trades"`     // 计入平均R的交易数（0表示没有止损记录）
 }
 
 // AccountInfo 账户信息
 type AccountInfo struct {
-	TotalEquity      float64 `json:"total_equity"`      // 账户净值
-	AvailableBalance float64 `json:"available_balance"` // 可用余额
-	TotalPnL         float64 `json:"total_pnl"`         // 总盈亏
-	TotalPnLPct      float64 `json:"total_pnl_pct"`     // 总盈亏百分比
-	MarginUsed       float64 `json:"margin_used"`       // 已用保证金
-	MarginUsedPct    float64 `json:"margin_used_pct"`   // 保证金使用率
-	PositionCount    int     `json:"position_count"`    // 持仓数量
+	TotalEquity      float64 `json:"total_equity"`       // 账户净值
+	AvailableBalance float64 `json:"available_balance"`  // 可用余额
+	TotalPnL         float64 `json:"total_pnl"`          // 总盈亏
+	TotalPnLPct      float64 `json:"total_pnl_pct"`      // 总盈亏百分比
+	MarginUsed       float64 `json:"margin_used"`        // 已用保证金
+	MarginUsedPct    float64 `json:"margin_used_pct"`    // 保证金使用率
+	PositionCount    int     `json:"position_count"`     // 持仓数量
+	Currency         string  `json:"currency,omitempty"` // 金额的币种（空表示USDT）
 }
 
 // CandidateCoin 候选币种（来自币种池）
 type CandidateCoin struct {
 	Symbol  string   `json:"symbol"`
-	Sources []string `json:"sources"` // 来源: "ai500" 和/或 "oi_top"
+	Sources []string `json:"sources"` // 来源: "ai500" 和/或 "oi_top"，外部信号提示的币种带 "webhook"，成交量/持仓量异动的带 "anomaly"
 }
 
 // OITopData 持仓量增长Top数据（用于AI决策参考）
@@ -64,6 +106,7 @@ type Context struct {
 	Positions       []PositionInfo          `json:"positions"`
 	CandidateCoins  []CandidateCoin         `json:"candidate_coins"`
 	MarketDataMap   map[string]*market.Data `json:"-"` // 不序列化，但内部使用
+	MarketDataTime  time.Time               `json:"-"` // 行情快照时间（获取市场数据完成时）
 	OITopDataMap    map[string]*OITopData   `json:"-"` // OI Top数据映射
 	Performance     interface{}             `json:"-"` // 历史表现分析（logger.PerformanceAnalysis）
 	BTCETHLeverage      int     `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
@@ -71,34 +114,140 @@ type Context struct {
 	MinPositionSizeUSD  float64 `json:"-"` // 最小仓位大小（USD，0表示不限制）
 	MaxPositionSizeUSD  float64 `json:"-"` // 最大仓位大小（USD，0表示不限制）
 	SystemPromptTemplate string `json:"-"` // 系统提示词模板名称 (如 "default", "adaptive", "nof1")
+	SymbolFilter         *pool.SymbolFilter `json:"-"` // 币种黑白名单（nil表示不限制）
+	SymbolLimits         SymbolLimits       `json:"-"` // 币种单独的杠杆和仓位限制（nil表示只用全局限制）
+	AutoStop             AutoStopConfig     `json:"-"` // 缺失/无效止损止盈时自动补全（默认关闭）
+	RoundPrice           PriceRounder       `json:"-"` // 止损止盈按交易所价格精度取整（nil表示交易器不支持，不取整）
+	Trace                context.Context    `json:"-"` // 链路追踪上下文（交易周期的根span，nil表示不追踪）
+	CloseOnly            bool               `json:"-"` // 风控只允许平仓/减仓（开仓和加仓决策会被拦截）
+	RiskNotice           string             `json:"-"` // 当前风控限制说明（写入user prompt）
+	SymbolEdges          []SymbolEdge       `json:"-"` // 分币种历史表现（按已实现盈亏从高到低）
+	SymbolEdgeDays       int                `json:"-"` // 分币种表现的统计天数
+	ScanInterval         time.Duration      `json:"-"` // 扫描间隔（prompt中预期波动的时间窗口）
+	Indicators           []string           `json:"-"` // 写入prompt的额外指标（策略配置限定，nil表示全部已启用的指标）
+	MarketFormat         market.FormatMode  `json:"-"` // 行情数据格式（verbose 逐项描述 / compact 紧凑表格）
+
+	RelativeStrength map[string]*indicator.RelativeStrength `json:"-"` // 各币种相对BTC的强弱（启用时，供prompt和评分使用）
+
+	// Recall 获取市场数据后调用，按当前行情检索相似的历史情形（nil表示不启用）
+	Recall        func(marketData map[string]*market.Data) []SimilarSetup `json:"-"`
+	SimilarSetups []SimilarSetup                                         `json:"-"` // 相似的历史情形及结果
+
+	ExternalSignals []ExternalSignal `json:"-"` // 外部策略信号（webhook，未过期的）
+	Surges          map[string][]market.SurgeAlert `json:"-"` // 成交量/持仓量异动（币种 -> 超过阈值的窗口）
+
+	ExecutionFeedback *ExecutionFeedback `json:"-"` // 上个AI周期决策的实际执行结果（nil表示没有）
+
+	Overtrading *OvertradingFeedback `json:"-"` // 最近的过度交易行为（未启用检测或没有问题时为nil）
 }
 
+// DecisionSchemaVersion 当前决策JSON格式版本
+// v1: open_long/open_short/close_long/close_short/hold/wait
+// v2: 新增 adjust_sl/adjust_tp/partial_close/add_to_position/cancel_orders
+const DecisionSchemaVersion = 2
+
 // Decision AI的交易决策
 type Decision struct {
+	SchemaVersion   int     `json:"schema_version,omitempty"` // 决策格式版本（缺省视为v1）
 	Symbol          string  `json:"symbol"`
-	Action          string  `json:"action"` // "open_long", "open_short", "close_long", "close_short", "hold", "wait"
+	Action          string  `json:"action"`         // 见 validActions
+	Side            string  `json:"side,omitempty"` // 持仓管理动作的持仓方向 "long"/"short"（可选，缺省按现有持仓推断）
 	Leverage        int     `json:"leverage,omitempty"`
 	PositionSizeUSD float64 `json:"position_size_usd,omitempty"`
 	StopLoss        float64 `json:"stop_loss,omitempty"`
 	TakeProfit      float64 `json:"take_profit,omitempty"`
-	Confidence      int     `json:"confidence,omitempty"` // 信心度 (0-100)
-	RiskUSD         float64 `json:"risk_usd,omitempty"`   // 最大美元风险
+	ClosePercent    float64 `json:"close_percent,omitempty"` // partial_close 平仓百分比 (0-100)
+	OrderType       string  `json:"order_type,omitempty"`    // 下单类型 market/ioc/fok/post_only（可选，缺省按执行策略）
+	Confidence      int     `json:"confidence,omitempty"`    // 信心度 (0-100)
+	RiskUSD         float64 `json:"risk_usd,omitempty"`      // 最大美元风险
 	Reasoning       string  `json:"reasoning"`
+	Plan            string  `json:"plan,omitempty"` // 对该持仓的后续计划（可选，下个周期在持仓信息中回放）
+}
+
+// validActions 支持的标准action
+var validActions = map[string]bool{
+	"open_long":       true,
+	"open_short":      true,
+	"close_long":      true,
+	"close_short":     true,
+	"partial_close":   true,
+	"add_to_position": true,
+	"adjust_sl":       true,
+	"adjust_tp":       true,
+	"cancel_orders":   true,
+	"hold":            true,
+	"wait":            true,
 }
 
 // FullDecision AI的完整决策（包含思维链）
 type FullDecision struct {
-	UserPrompt string     `json:"user_prompt"` // 发送给AI的输入prompt
-	CoTTrace   string     `json:"cot_trace"`   // 思维链分析（AI输出）
-	Decisions  []Decision `json:"decisions"`   // 具体决策列表
-	Timestamp  time.Time  `json:"timestamp"`
+	SchemaVersion int        `json:"schema_version"` // 解析时使用的决策格式版本
+	UserPrompt    string     `json:"user_prompt"`    // 发送给AI的输入prompt
+	CoTTrace      string     `json:"cot_trace"`      // 思维链分析（AI输出）
+	Decisions     []Decision `json:"decisions"`      // 具体决策列表
+	Timestamp     time.Time  `json:"timestamp"`
+
+	Reasoning       string `json:"reasoning,omitempty"`        // 推理模型的推理过程（与回答分开，不参与解析）
+	ReasoningTokens int    `json:"reasoning_tokens,omitempty"` // 推理消耗的token数
+
+	ParseDiagnostics *ParseDiagnostics `json:"parse_diagnostics,omitempty"` // JSON修复/降级诊断（解析顺利时为nil）
+	AutoStops        []string          `json:"auto_stops,omitempty"`        // 自动补全止损止盈的说明（如果有）
+	PriceRounding    []string          `json:"price_rounding,omitempty"`    // 止损止盈按价格精度取整的说明（如果有）
+
+	Ensemble []EnsembleOutput `json:"ensemble,omitempty"` // 集成模式下各模型的输出（Decisions 为合并结果）
 }
 
 // GetFullDecision 获取AI的完整交易决策（批量分析所有币种和持仓）
 func GetFullDecision(ctx *Context, mcpClient *mcp.Client) (*FullDecision, error) {
+	// 1-2. 获取市场数据，构建 System Prompt 和 User Prompt
+	systemPrompt, userPrompt, err := prepareDecisionPrompts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3-4. 调用AI API并解析响应
+	decision, err := callAndParseDecision(ctx, mcpClient, systemPrompt, userPrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	decision.Timestamp = time.Now()
+	decision.UserPrompt = userPrompt // 保存输入prompt
+	return decision, nil
+}
+
+// prepareDecisionPrompts 为所有币种获取市场数据，并构建 System Prompt（固定规则）和 User Prompt（动态数据）
+func prepareDecisionPrompts(ctx *Context) (systemPrompt, userPrompt string, err error) {
 	// 1. 为所有币种获取市场数据
-	if err := fetchMarketDataForContext(ctx); err != nil {
-		return nil, fmt.Errorf("获取市场数据失败: %w", err)
+	dataCtx, dataSpan := tracing.Start(ctx.Trace, "decision.market_data")
+	err = fetchMarketDataForContext(dataCtx, ctx)
+	dataSpan.SetAttr("symbols", len(ctx.MarketDataMap))
+	dataSpan.RecordError(err)
+	dataSpan.End()
+	if err != nil {
+		return "", "", fmt.Errorf("获取市场数据失败: %w", err)
+	}
+	ctx.MarketDataTime = time.Now()
+	for _, data := range ctx.MarketDataMap {
+		// 策略配置未包含的指标不写入prompt（波动率同时不参与止盈距离检查，震荡区间同时不参与顺势开仓过滤）
+		if !ctx.indicatorEnabled(IndicatorBasis) {
+			data.Basis = nil
+		}
+		if !ctx.indicatorEnabled(IndicatorVolatility) {
+			data.Volatility = nil
+		}
+		if !ctx.indicatorEnabled(IndicatorFlow) {
+			data.Flow = nil
+		}
+		if !ctx.indicatorEnabled(IndicatorRange) {
+			data.Range = nil
+		}
+		if data.Volatility != nil {
+			data.Volatility.Horizon = ctx.ScanInterval // prompt中的预期波动按扫描间隔计算
+		}
+	}
+	if ctx.Recall != nil {
+		ctx.SimilarSetups = ctx.Recall(ctx.MarketDataMap)
 	}
 
 	// 2. 构建 System Prompt（固定规则）和 User Prompt（动态数据）
@@ -108,28 +257,35 @@ func GetFullDecision(ctx *Context, mcpClient *mcp.Client) (*FullDecision, error)
 	if templateName == "" {
 		templateName = "default" // Default template name
 	}
-	systemPrompt := buildSystemPromptWithFallback(ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, ctx.MinPositionSizeUSD, ctx.MaxPositionSizeUSD, templateName)
-	userPrompt := buildUserPrompt(ctx)
+	systemPrompt = buildSystemPromptWithFallback(ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, ctx.MinPositionSizeUSD, ctx.MaxPositionSizeUSD, ctx.SymbolLimits, templateName)
+	userPrompt = buildUserPrompt(ctx)
+	return systemPrompt, userPrompt, nil
+}
 
+// callAndParseDecision 调用AI API（使用 system + user prompt）并解析响应
+func callAndParseDecision(ctx *Context, mcpClient *mcp.Client, systemPrompt, userPrompt string) (*FullDecision, error) {
 	// 3. 调用AI API（使用 system + user prompt）
-	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
+	// 推理模型的推理过程单独记录，只解析最终回答
+	response, err := mcpClient.CallWithReasoningContext(ctx.Trace, systemPrompt, userPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("调用AI API失败: %w", err)
 	}
 
 	// 4. 解析AI响应
-	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, ctx.MinPositionSizeUSD, ctx.MaxPositionSizeUSD)
+	_, parseSpan := tracing.Start(ctx.Trace, "decision.parse")
+	decision, err := parseFullDecisionResponse(response.Content, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, ctx.MinPositionSizeUSD, ctx.MaxPositionSizeUSD, ctx.SymbolFilter, ctx.SymbolLimits, ctx.AutoStop, ctx.MarketDataMap, ctx.RoundPrice)
+	parseSpan.RecordError(err)
+	parseSpan.End()
 	if err != nil {
 		return nil, fmt.Errorf("解析AI响应失败: %w", err)
 	}
-
-	decision.Timestamp = time.Now()
-	decision.UserPrompt = userPrompt // 保存输入prompt
+	decision.Reasoning = response.Reasoning
+	decision.ReasoningTokens = response.ReasoningTokens
 	return decision, nil
 }
 
-// fetchMarketDataForContext 为上下文中的所有币种获取市场数据和OI数据
-func fetchMarketDataForContext(ctx *Context) error {
+// fetchMarketDataForContext 为上下文中的所有币种获取市场数据和OI数据（traceCtx 为追踪父span）
+func fetchMarketDataForContext(traceCtx context.Context, ctx *Context) error {
 	ctx.MarketDataMap = make(map[string]*market.Data)
 	ctx.OITopDataMap = make(map[string]*OITopData)
 
@@ -158,7 +314,7 @@ func fetchMarketDataForContext(ctx *Context) error {
 	}
 
 	for symbol := range symbolSet {
-		data, err := market.Get(symbol)
+		data, err := market.GetContext(traceCtx, symbol)
 		if err != nil {
 			// 单个币种失败不影响整体，只记录错误
 			continue
@@ -182,6 +338,11 @@ func fetchMarketDataForContext(ctx *Context) error {
 		ctx.MarketDataMap[symbol] = data
 	}
 
+	// 相对BTC强弱（启用时）
+	if indicator.RelativeStrengthEnabled() && ctx.indicatorEnabled(IndicatorRelativeStrength) {
+		ctx.RelativeStrength = fetchRelativeStrength(ctx.MarketDataMap)
+	}
+
 	// 加载OI Top数据（不影响主流程）
 	oiPositions, err := pool.GetOITopPositions()
 	if err == nil {
@@ -211,7 +372,7 @@ func calculateMaxCandidates(ctx *Context) int {
 }
 
 // buildSystemPrompt 构建 System Prompt（固定规则，可缓存）
-func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64) string {
+func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64, symbolLimits SymbolLimits) string {
 	var sb strings.Builder
 
 	// === 核心使命 ===
@@ -253,7 +414,9 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 		}
 	}
 	
-	sb.WriteString("4. **保证金**: 总使用率 ≤ 90%\n\n")
+	sb.WriteString("4. **保证金**: 总使用率 ≤ 90%\n")
+	writeSymbolLimits(&sb, symbolLimits)
+	sb.WriteString("\n")
 
 	// === 做空激励 ===
 	sb.WriteString("# 📉 做多做空平衡\n\n")
@@ -279,7 +442,7 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	sb.WriteString("**你拥有的完整数据**：\n")
 	sb.WriteString("- 📊 **原始序列**：3分钟价格序列(MidPrices数组) + 4小时K线序列\n")
 	sb.WriteString("- 📈 **技术序列**：EMA20序列、MACD序列、RSI7序列、RSI14序列\n")
-	sb.WriteString("- 💰 **资金序列**：成交量序列、持仓量(OI)序列、资金费率\n")
+	sb.WriteString("- 💰 **资金序列**：成交量序列、持仓量(OI)序列、资金费率、主动买卖量与买卖比序列、大户多空持仓比序列（如果有）\n")
 	sb.WriteString("- 🎯 **筛选标记**：AI500评分 / OI_Top排名（如果有标注）\n")
 	sb.WriteString("- 🕯️ **K线形态分析**：19种K线形态、Outside Day、Larry Williams策略信号（自动检测并显示在数据下方）\n\n")
 	sb.WriteString("**分析方法**（完全由你自主决定）：\n")
@@ -331,14 +494,11 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	sb.WriteString("⚠️ **如果响应长度受限，优先保证JSON数组完整输出，可以缩短思维链！**\n\n")
 	sb.WriteString("格式示例:\n\n")
 	sb.WriteString("```json\n[\n")
-	sb.WriteString(fmt.Sprintf("  {\"symbol\": \"BTCUSDT\", \"action\": \"open_short\", \"leverage\": %d, \"position_size_usd\": %.0f, \"stop_loss\": 103000, \"take_profit\": 97000, \"confidence\": 85, \"risk_usd\": 300, \"reasoning\": \"下跌趋势+MACD死叉\"},\n", btcEthLeverage, accountEquity*5))
-	sb.WriteString("  {\"symbol\": \"ETHUSDT\", \"action\": \"close_long\", \"reasoning\": \"止盈离场\"}\n")
+	sb.WriteString(fmt.Sprintf("  {\"schema_version\": %d, \"symbol\": \"BTCUSDT\", \"action\": \"open_short\", \"leverage\": %d, \"position_size_usd\": %.0f, \"stop_loss\": 103000, \"take_profit\": 97000, \"confidence\": 85, \"risk_usd\": 300, \"reasoning\": \"下跌趋势+MACD死叉\"},\n", DecisionSchemaVersion, btcEthLeverage, accountEquity*5))
+	sb.WriteString(fmt.Sprintf("  {\"schema_version\": %d, \"symbol\": \"SOLUSDT\", \"action\": \"adjust_sl\", \"side\": \"long\", \"stop_loss\": 182.5, \"reasoning\": \"浮盈超过2R，止损上移至保本\"},\n", DecisionSchemaVersion))
+	sb.WriteString(fmt.Sprintf("  {\"schema_version\": %d, \"symbol\": \"ETHUSDT\", \"action\": \"close_long\", \"reasoning\": \"止盈离场\"}\n", DecisionSchemaVersion))
 	sb.WriteString("]\n```\n\n")
-	sb.WriteString("**字段说明**:\n")
-	sb.WriteString("- `action`: open_long | open_short | close_long | close_short | hold | wait\n")
-	sb.WriteString("- `confidence`: 0-100（开仓建议≥75）\n")
-	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n")
-	sb.WriteString("- 平仓/持有/等待时只需: symbol, action, reasoning\n\n")
+	writeDecisionFieldSpec(&sb)
 	sb.WriteString("**输出要求**:\n")
 	sb.WriteString("1. 先写思维链分析（可简短）\n")
 	sb.WriteString("2. 然后必须输出一个有效的JSON数组，以 `[` 开始，以 `]` 结束\n")
@@ -392,10 +552,28 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	return sb.String()
 }
 
+// writeDecisionFieldSpec 写入决策字段说明（内置prompt和模板prompt共用）
+func writeDecisionFieldSpec(sb *strings.Builder) {
+	sb.WriteString("**字段说明**:\n")
+	sb.WriteString(fmt.Sprintf("- `schema_version`: 决策格式版本，当前为 %d（每个决策都要带上）\n", DecisionSchemaVersion))
+	sb.WriteString("- `action`: open_long | open_short | close_long | close_short | partial_close | add_to_position | adjust_sl | adjust_tp | cancel_orders | hold | wait\n")
+	sb.WriteString("- `confidence`: 0-100（开仓建议≥75）\n")
+	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n")
+	sb.WriteString("- 平仓/持有/等待时只需: symbol, action, reasoning\n")
+	sb.WriteString("- `plan`（可选）: 开仓/加仓/持有/调整持仓时写下对该持仓的后续计划（如\"持有至4h收盘站上3200，跌破3050离场\"），下个周期会在该持仓下原样回放，沿用或更新即可，不必重新推导\n")
+	sb.WriteString("- `order_type`（可选）: market（市价）| ioc（激进限价，默认）| fok（全部成交或撤单）| post_only（只做Maker，不保证成交），交易所不支持时自动回退\n\n")
+	sb.WriteString("**持仓管理动作**（不必完全平仓即可管理已有持仓；`side` 填 long/short，同币种同时持有多空仓时必填）:\n")
+	sb.WriteString("- `adjust_sl`: 移动止损，必填 stop_loss（如浮盈后上移止损保本）\n")
+	sb.WriteString("- `adjust_tp`: 调整止盈，必填 take_profit\n")
+	sb.WriteString("- `partial_close`: 部分平仓，必填 close_percent（1-99，平掉当前持仓的百分比）\n")
+	sb.WriteString("- `add_to_position`: 加仓，必填 position_size_usd（本次加仓的名义价值），可选 stop_loss/take_profit 更新保护单\n")
+	sb.WriteString("- `cancel_orders`: 撤销该币种所有挂单（含止损止盈，撤销后持仓无保护，慎用）\n\n")
+}
+
 // buildSystemPromptWithFallback 构建 System Prompt，优先使用模板，失败时回退到现有方法
 // Uses upstream prompt_manager method as default, falls back to existing buildSystemPrompt if template is nil/not found
 // templateName: 模板名称，如 "default", "adaptive", "nof1", "taro_long_prompts" (如果为空则使用 "default")
-func buildSystemPromptWithFallback(accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64, templateName string) string {
+func buildSystemPromptWithFallback(accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64, symbolLimits SymbolLimits, templateName string) string {
 	// Default to "default" if templateName is empty
 	if templateName == "" {
 		templateName = "default"
@@ -408,16 +586,16 @@ func buildSystemPromptWithFallback(accountEquity float64, btcEthLeverage, altcoi
 		// IMPORTANT: Append JSON format specification to ensure AI uses correct action format
 		// Templates may use buy_to_enter/sell_to_enter, but validation expects open_long/open_short
 		log.Printf("✓ 使用提示词模板: %s (upstream方法)", templateName)
-		return buildSystemPromptWithTemplate(template.Content, accountEquity, btcEthLeverage, altcoinLeverage, minPositionSizeUSD, maxPositionSizeUSD)
+		return buildSystemPromptWithTemplate(template.Content, accountEquity, btcEthLeverage, altcoinLeverage, minPositionSizeUSD, maxPositionSizeUSD, symbolLimits)
 	}
 	
 	// Fallback to existing buildSystemPrompt behavior if template is nil/not found
 	log.Printf("⚠️  提示词模板 '%s' 不可用，回退到内置prompt构建方法: %v", templateName, err)
-	return buildSystemPrompt(accountEquity, btcEthLeverage, altcoinLeverage, minPositionSizeUSD, maxPositionSizeUSD)
+	return buildSystemPrompt(accountEquity, btcEthLeverage, altcoinLeverage, minPositionSizeUSD, maxPositionSizeUSD, symbolLimits)
 }
 
 // buildSystemPromptWithTemplate 在模板内容后追加JSON格式说明和动态约束
-func buildSystemPromptWithTemplate(templateContent string, accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64) string {
+func buildSystemPromptWithTemplate(templateContent string, accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64, symbolLimits SymbolLimits) string {
 	var sb strings.Builder
 	
 	// 1. 添加模板内容
@@ -447,7 +625,9 @@ func buildSystemPromptWithTemplate(templateContent string, accountEquity float64
 	
 	sb.WriteString(fmt.Sprintf("3. 单币仓位: 山寨%.0f-%.0f U(%dx杠杆) | BTC/ETH %.0f-%.0f U(%dx杠杆)\n",
 		minAltcoinSize, maxAltcoinSize, altcoinLeverage, minBTCETHSize, maxBTCETHSize, btcEthLeverage))
-	sb.WriteString("4. 保证金: 总使用率 ≤ 90%\n\n")
+	sb.WriteString("4. 保证金: 总使用率 ≤ 90%\n")
+	writeSymbolLimits(&sb, symbolLimits)
+	sb.WriteString("\n")
 	
 	// 3. 输出格式 - 动态生成（关键：覆盖模板中的action格式）
 	sb.WriteString("# 输出格式\n\n")
@@ -455,14 +635,11 @@ func buildSystemPromptWithTemplate(templateContent string, accountEquity float64
 	sb.WriteString("⚠️ **如果响应长度受限，优先保证JSON数组完整输出，可以缩短思维链！**\n\n")
 	sb.WriteString("格式示例:\n\n")
 	sb.WriteString("```json\n[\n")
-	sb.WriteString(fmt.Sprintf("  {\"symbol\": \"BTCUSDT\", \"action\": \"open_short\", \"leverage\": %d, \"position_size_usd\": %.0f, \"stop_loss\": 103000, \"take_profit\": 97000, \"confidence\": 85, \"risk_usd\": 300, \"reasoning\": \"下跌趋势+MACD死叉\"},\n", btcEthLeverage, accountEquity*5))
-	sb.WriteString("  {\"symbol\": \"ETHUSDT\", \"action\": \"close_long\", \"reasoning\": \"止盈离场\"}\n")
+	sb.WriteString(fmt.Sprintf("  {\"schema_version\": %d, \"symbol\": \"BTCUSDT\", \"action\": \"open_short\", \"leverage\": %d, \"position_size_usd\": %.0f, \"stop_loss\": 103000, \"take_profit\": 97000, \"confidence\": 85, \"risk_usd\": 300, \"reasoning\": \"下跌趋势+MACD死叉\"},\n", DecisionSchemaVersion, btcEthLeverage, accountEquity*5))
+	sb.WriteString(fmt.Sprintf("  {\"schema_version\": %d, \"symbol\": \"SOLUSDT\", \"action\": \"adjust_sl\", \"side\": \"long\", \"stop_loss\": 182.5, \"reasoning\": \"浮盈超过2R，止损上移至保本\"},\n", DecisionSchemaVersion))
+	sb.WriteString(fmt.Sprintf("  {\"schema_version\": %d, \"symbol\": \"ETHUSDT\", \"action\": \"close_long\", \"reasoning\": \"止盈离场\"}\n", DecisionSchemaVersion))
 	sb.WriteString("]\n```\n\n")
-	sb.WriteString("**字段说明**:\n")
-	sb.WriteString("- `action`: open_long | open_short | close_long | close_short | hold | wait\n")
-	sb.WriteString("- `confidence`: 0-100（开仓建议≥75）\n")
-	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n")
-	sb.WriteString("- 平仓/持有/等待时只需: symbol, action, reasoning\n\n")
+	writeDecisionFieldSpec(&sb)
 	sb.WriteString("**输出要求**:\n")
 	sb.WriteString("1. 先写思维链分析（可简短）\n")
 	sb.WriteString("2. 然后必须输出一个有效的JSON数组，以 `[` 开始，以 `]` 结束\n")
@@ -473,6 +650,79 @@ func buildSystemPromptWithTemplate(templateContent string, accountEquity float64
 }
 
 // buildUserPrompt 构建 User Prompt（动态数据）
+// btcMarketData 取BTC行情（优先USDT交易对，其次其他计价币种）
+func btcMarketData(dataMap map[string]*market.Data) (*market.Data, bool) {
+	if data, ok := dataMap["BTCUSDT"]; ok {
+		return data, true
+	}
+	for symbol, data := range dataMap {
+		if market.BaseAsset(symbol) == "BTC" {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// 策略配置可选的额外指标
+const (
+	IndicatorRelativeStrength = "relative_strength"
+	IndicatorBasis            = "basis"
+	IndicatorVolatility       = "volatility"
+	IndicatorFlow             = "flow"
+	IndicatorRange            = "range"
+)
+
+// indicatorEnabled 额外指标是否写入prompt（未限定时全部写入，仍需全局启用）
+func (ctx *Context) indicatorEnabled(name string) bool {
+	if ctx.Indicators == nil {
+		return true
+	}
+	for _, ind := range ctx.Indicators {
+		if ind == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchRelativeStrength 计算各币种相对BTC的强弱（BTC本身跳过，单个币种失败不影响其他币种）
+func fetchRelativeStrength(marketData map[string]*market.Data) map[string]*indicator.RelativeStrength {
+	result := make(map[string]*indicator.RelativeStrength)
+	btcKlines := make(map[string][]market.Kline) // BTC交易对 -> 1h K线（按计价币种缓存）
+	for symbol := range marketData {
+		if market.BaseAsset(symbol) == "BTC" {
+			continue
+		}
+		btcSymbol := indicator.BTCSymbolFor(symbol)
+		klines, ok := btcKlines[btcSymbol]
+		if !ok {
+			var err error
+			if klines, err = indicator.FetchRelativeStrengthKlines(btcSymbol); err != nil {
+				log.Printf("⚠️  获取 %s K线失败，跳过相对强弱: %v", btcSymbol, err)
+			}
+			btcKlines[btcSymbol] = klines
+		}
+		if len(klines) == 0 {
+			continue
+		}
+		rs, err := indicator.RelativeStrengthVsBTC(symbol, klines)
+		if err != nil {
+			log.Printf("⚠️  %s 相对强弱计算失败: %v", symbol, err)
+			continue
+		}
+		result[symbol] = rs
+	}
+	return result
+}
+
+// writeRelativeStrength 写入币种相对BTC的强弱（未启用或没有数据时不写）
+func writeRelativeStrength(sb *strings.Builder, ctx *Context, symbol string) {
+	if rs, ok := ctx.RelativeStrength[symbol]; ok {
+		sb.WriteString(indicator.FormatRelativeStrength(rs))
+		sb.WriteString("\n")
+	}
+}
+
 func buildUserPrompt(ctx *Context) string {
 	var sb strings.Builder
 
@@ -481,7 +731,7 @@ func buildUserPrompt(ctx *Context) string {
 		ctx.CurrentTime, ctx.CallCount, ctx.RuntimeMinutes))
 
 	// BTC 市场
-	if btcData, hasBTC := ctx.MarketDataMap["BTCUSDT"]; hasBTC {
+	if btcData, hasBTC := btcMarketData(ctx.MarketDataMap); hasBTC {
 		sb.WriteString(fmt.Sprintf("**BTC**: %.2f (1h: %+.2f%%, 4h: %+.2f%%) | MACD: %.4f | RSI: %.2f\n\n",
 			btcData.CurrentPrice, btcData.PriceChange1h, btcData.PriceChange4h,
 			btcData.CurrentMACD, btcData.CurrentRSI7))
@@ -495,6 +745,19 @@ func buildUserPrompt(ctx *Context) string {
 		ctx.Account.TotalPnLPct,
 		ctx.Account.MarginUsedPct,
 		ctx.Account.PositionCount))
+	if c := ctx.Account.Currency; c != "" && c != "USDT" {
+		sb.WriteString(fmt.Sprintf("（账户金额单位为 %s：净值、盈亏、仓位限制以及 position_size_usd / risk_usd 都按 %s 计）\n\n", c, c))
+	}
+
+	if ctx.RiskNotice != "" {
+		sb.WriteString(fmt.Sprintf("**⚠️ 风控限制**: %s\n\n", ctx.RiskNotice))
+	}
+
+	writeExecutionFeedback(&sb, ctx)
+
+	if ctx.MarketFormat == market.FormatModeCompact {
+		sb.WriteString(compactMarketLegend)
+	}
 
 	// 持仓（完整市场数据）
 	if len(ctx.Positions) > 0 {
@@ -514,15 +777,38 @@ func buildUserPrompt(ctx *Context) string {
 				}
 			}
 
-			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场价%.4f 当前价%.4f | 盈亏%+.2f%% | 杠杆%dx | 保证金%.0f | 强平价%.4f%s\n\n",
+			protection := ""
+			if pos.StopLoss > 0 || pos.TakeProfit > 0 {
+				protection = fmt.Sprintf(" | 止损%.4f 止盈%.4f", pos.StopLoss, pos.TakeProfit)
+			}
+
+			// 累计资金费（未实现盈亏不包含资金费，这里一并给出含资金费的净盈亏）
+			funding := ""
+			if pos.CumulativeFunding != 0 {
+				funding = fmt.Sprintf(" | 累计资金费%+.2f USDT（含资金费净盈亏%+.2f USDT）",
+					pos.CumulativeFunding, pos.UnrealizedPnL+pos.CumulativeFunding)
+			}
+
+			// 交易所返回的仓位风险字段（Gate.io）
+			risk := ""
+			if pos.ADLRanking >= 1 && pos.ADLRanking <= 5 {
+				risk += fmt.Sprintf(" | ADL排名%d/5", pos.ADLRanking)
+			}
+			if pos.RealizedPnL != 0 {
+				risk += fmt.Sprintf(" | 已实现%+.2f USDT", pos.RealizedPnL)
+			}
+
+			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场价%.4f 当前价%.4f | 盈亏%+.2f%% | 杠杆%dx | 保证金%.0f | 强平价%.4f%s%s%s%s\n\n",
 				i+1, pos.Symbol, strings.ToUpper(pos.Side),
 				pos.EntryPrice, pos.MarkPrice, pos.UnrealizedPnLPct,
-				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration))
+				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, protection, risk, funding, holdingDuration))
+			writePositionPlan(&sb, pos.Plan)
 
 			// 使用FormatMarketData输出完整市场数据
 			if marketData, ok := ctx.MarketDataMap[pos.Symbol]; ok {
-				sb.WriteString(market.Format(marketData))
+				sb.WriteString(market.FormatAs(marketData, ctx.MarketFormat))
 				sb.WriteString("\n")
+				writeRelativeStrength(&sb, ctx, pos.Symbol)
 				
 				// 添加技术指标分析
 				indicatorAnalysis := indicator.Analyze(marketData)
@@ -547,17 +833,40 @@ func buildUserPrompt(ctx *Context) string {
 		}
 		displayedCount++
 
+		sources := make([]string, 0, len(coin.Sources))
+		webhook, anomaly := false, false
+		for _, src := range coin.Sources {
+			switch src {
+			case "webhook":
+				webhook = true
+			case "anomaly":
+				anomaly = true
+			default:
+				sources = append(sources, src)
+			}
+		}
 		sourceTags := ""
-		if len(coin.Sources) > 1 {
+		if len(sources) > 1 {
 			sourceTags = " (AI500+OI_Top双重信号)"
-		} else if len(coin.Sources) == 1 && coin.Sources[0] == "oi_top" {
+		} else if len(sources) == 1 && sources[0] == "oi_top" {
 			sourceTags = " (OI_Top持仓增长)"
 		}
+		if webhook {
+			sourceTags += " (外部信号)"
+		}
+		if anomaly {
+			sourceTags += " (异动)"
+		}
+		if marketData.Range != nil && marketData.Range.Ranging {
+			sourceTags += " (震荡区间)"
+		}
 
 		// 使用FormatMarketData输出完整市场数据
 		sb.WriteString(fmt.Sprintf("### %d. %s%s\n\n", displayedCount, coin.Symbol, sourceTags))
-		sb.WriteString(market.Format(marketData))
+		writeSurges(&sb, ctx.Surges[coin.Symbol])
+		sb.WriteString(market.FormatAs(marketData, ctx.MarketFormat))
 		sb.WriteString("\n")
+		writeRelativeStrength(&sb, ctx, coin.Symbol)
 		
 		// 添加技术指标分析
 		indicatorAnalysis := indicator.Analyze(marketData)
@@ -582,6 +891,11 @@ func buildUserPrompt(ctx *Context) string {
 			}
 		}
 	}
+	writeOvertrading(&sb, ctx)
+
+	writeSymbolEdges(&sb, ctx)
+	writeSimilarSetups(&sb, ctx)
+	writeExternalSignals(&sb, ctx)
 
 	sb.WriteString("---\n\n")
 	sb.WriteString("现在请分析并输出决策。\n\n")
@@ -593,34 +907,118 @@ func buildUserPrompt(ctx *Context) string {
 	return sb.String()
 }
 
+// writeSurges 写入币种的成交量/持仓量异动（z分数相对此前各周期）
+func writeSurges(sb *strings.Builder, alerts []market.SurgeAlert) {
+	if len(alerts) == 0 {
+		return
+	}
+	parts := make([]string, len(alerts))
+	for i, a := range alerts {
+		parts[i] = a.String()
+	}
+	sb.WriteString(fmt.Sprintf("**异动**: %s\n\n", strings.Join(parts, "; ")))
+}
+
+// writePositionPlan 回放AI上次对该持仓陈述的计划
+func writePositionPlan(sb *strings.Builder, plan *PositionPlan) {
+	if plan == nil || plan.Text == "" {
+		return
+	}
+	age := time.Since(plan.UpdatedAt).Round(time.Minute)
+	sb.WriteString(fmt.Sprintf("**上次计划**（%d分钟前，%s）: %s\n\n", int(age.Minutes()), plan.Action, plan.Text))
+}
+
+// compactMarketLegend 紧凑行情格式的字段说明（每个prompt只写一次）
+const compactMarketLegend = "**行情格式**: px=价格 chg=涨跌幅 oi=持仓量 funding=资金费率（next=距下次结算）；" +
+	"表格每列一个序列、每行一个时间点，从旧到新（最后一行为最新）；flow表中 taker_bs=主动买卖量比 top_ls=大户多空持仓比\n\n"
+
+// maxSymbolEdgeRows 分币种表现表格的最大行数（超出时保留最好和最差的各一半）
+const maxSymbolEdgeRows = 10
+
+// writeSymbolEdges 分币种历史表现表格
+func writeSymbolEdges(sb *strings.Builder, ctx *Context) {
+	edges := ctx.SymbolEdges
+	if len(edges) == 0 {
+		return
+	}
+	if len(edges) > maxSymbolEdgeRows {
+		half := maxSymbolEdgeRows / 2
+		edges = append(append([]SymbolEdge{}, edges[:half]...), edges[len(edges)-half:]...)
+	}
+
+	sb.WriteString(fmt.Sprintf("## 🎯 你的分币种历史表现（近%d天已平仓交易）\n", ctx.SymbolEdgeDays))
+	sb.WriteString("币种 | 交易数 | 胜率 | 已实现盈亏 | 平均R\n")
+	for _, e := range edges {
+		avgR := "-"
+		if e.RTrades > 0 {
+			avgR = fmt.Sprintf("%+.2fR", e.AvgR)
+		}
+		sb.WriteString(fmt.Sprintf("%s | %d | %.0f%% | %+.2f USDT | %s\n", e.Symbol, e.Trades, e.WinRate, e.RealizedPnL, avgR))
+	}
+	sb.WriteString("表现差的币种请提高开仓门槛或减小仓位\n\n")
+}
+
+// writeSimilarSetups 相似的历史情形及结果
+func writeSimilarSetups(sb *strings.Builder, ctx *Context) {
+	if len(ctx.SimilarSetups) == 0 {
+		return
+	}
+	sb.WriteString("## 🔍 相似的历史情形及结果（同币种、相近指标状态下你过去的开仓）\n")
+	for _, s := range ctx.SimilarSetups {
+		r := ""
+		if s.RMultiple != nil {
+			r = fmt.Sprintf("，%+.2fR", *s.RMultiple)
+		}
+		reasoning := []rune(s.Reasoning)
+		if len(reasoning) > 80 {
+			reasoning = append(reasoning[:80], []rune("...")...)
+		}
+		sb.WriteString(fmt.Sprintf("- %s %s %s（相似度%.2f）| 结果 %+.2f USDT%s，持仓%s | 当时理由: %s\n",
+			s.Symbol, s.Time.Format("01-02 15:04"), strings.ToUpper(s.Side), s.Score, s.PnL, r, s.Duration, string(reasoning)))
+	}
+	sb.WriteString("参考这些情形的结果，避免重复亏损的模式\n\n")
+}
+
 // parseFullDecisionResponse 解析AI的完整决策响应
-func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64) (*FullDecision, error) {
+func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64, symbolFilter *pool.SymbolFilter, symbolLimits SymbolLimits, autoStop AutoStopConfig, marketDataMap map[string]*market.Data, roundPrice PriceRounder) (*FullDecision, error) {
 	// 1. 提取思维链
 	cotTrace := extractCoTTrace(aiResponse)
 
     // 2. 提取JSON决策列表
-    decisions, err := extractDecisions(aiResponse)
-	if err != nil {
-		return &FullDecision{
-			CoTTrace:  cotTrace,
-			Decisions: []Decision{},
-		}, fmt.Errorf("提取决策失败: %w\n\n=== AI思维链分析 ===\n%s", err, cotTrace)
-	}
+    // 格式错误会被尽量修复，修复不了时降级为wait决策（诊断信息随结果返回）
+    decisions, diagnostics := extractDecisions(aiResponse)
 
     // 3. 规范化决策：将仓位大小基于最小/最大限制进行约束（不直接拒绝，先收敛到允许范围）
     decisions = normalizeDecisions(decisions, minPositionSizeUSD, maxPositionSizeUSD)
 
-    // 4. 验证决策
-	if err := validateDecisions(decisions, accountEquity, btcEthLeverage, altcoinLeverage, minPositionSizeUSD, maxPositionSizeUSD); err != nil {
+    // 4. 补全缺失/无效的止损止盈（可选，按ATR和目标风险回报比计算）
+    autoStops := applyAutoStops(decisions, autoStop, marketDataMap)
+
+    // 5. 止损止盈按价格精度取整（取整后风险回报比跌破下限时拒绝）
+    priceRounding, err := roundDecisionPrices(decisions, roundPrice, marketDataMap)
+
+    // 6. 验证决策
+	if err == nil {
+		err = validateDecisions(decisions, accountEquity, btcEthLeverage, altcoinLeverage, minPositionSizeUSD, maxPositionSizeUSD, symbolFilter, symbolLimits, marketDataMap)
+	}
+	if err != nil {
 		return &FullDecision{
-			CoTTrace:  cotTrace,
-			Decisions: decisions,
+			SchemaVersion:    DecisionSchemaVersion,
+			CoTTrace:         cotTrace,
+			Decisions:        decisions,
+			ParseDiagnostics: diagnostics,
+			AutoStops:        autoStops,
+			PriceRounding:    priceRounding,
 		}, fmt.Errorf("决策验证失败: %w\n\n=== AI思维链分析 ===\n%s", err, cotTrace)
 	}
 
 	return &FullDecision{
-		CoTTrace:  cotTrace,
-		Decisions: decisions,
+		SchemaVersion:    DecisionSchemaVersion,
+		CoTTrace:         cotTrace,
+		Decisions:        decisions,
+		ParseDiagnostics: diagnostics,
+		AutoStops:        autoStops,
+		PriceRounding:    priceRounding,
 	}, nil
 }
 
@@ -642,17 +1040,28 @@ func normalizeAction(action, reasoning string) string {
 		"close_position": "", // 同上
 		"long":           "open_long",
 		"short":          "open_short",
+
+		// v2 持仓管理动作的常见变体
+		"reduce":             "partial_close",
+		"reduce_position":    "partial_close",
+		"partial":            "partial_close",
+		"close_partial":      "partial_close",
+		"add":                "add_to_position",
+		"scale_in":           "add_to_position",
+		"increase_position":  "add_to_position",
+		"move_sl":            "adjust_sl",
+		"move_stop_loss":     "adjust_sl",
+		"update_stop_loss":   "adjust_sl",
+		"adjust_stop_loss":   "adjust_sl",
+		"move_tp":            "adjust_tp",
+		"update_take_profit": "adjust_tp",
+		"adjust_take_profit": "adjust_tp",
+		"cancel":             "cancel_orders",
+		"cancel_all":         "cancel_orders",
+		"cancel_all_orders":  "cancel_orders",
 	}
 
 	// 如果action已经是标准格式，直接返回
-	validActions := map[string]bool{
-		"open_long":   true,
-		"open_short":  true,
-		"close_long":  true,
-		"close_short": true,
-		"hold":        true,
-		"wait":        true,
-	}
 	if validActions[actionLower] {
 		return actionLower
 	}
@@ -707,8 +1116,12 @@ func normalizeDecisions(decisions []Decision, minPositionSizeUSD, maxPositionSiz
         // 1. 规范化action字段（处理AI可能使用的变体）
         decisions[i].Action = normalizeAction(decisions[i].Action, decisions[i].Reasoning)
 
-        // 2. 仅对开仓动作进行规范化
-        if decisions[i].Action == "open_long" || decisions[i].Action == "open_short" {
+        // 规范化持仓方向
+        decisions[i].Side = strings.ToLower(strings.TrimSpace(decisions[i].Side))
+        decisions[i].OrderType = strings.ToLower(strings.TrimSpace(decisions[i].OrderType))
+
+        // 2. 仅对开仓/加仓动作进行规范化
+        if decisions[i].Action == "open_long" || decisions[i].Action == "open_short" || decisions[i].Action == "add_to_position" {
             size := decisions[i].PositionSizeUSD
             // 下限：若配置了最小仓位，且size小于下限，则提升到下限
             if minPositionSizeUSD > 0 && size > 0 && size < minPositionSizeUSD {
@@ -745,9 +1158,19 @@ func extractCoTTrace(response string) string {
 }
 
 // extractDecisions 提取JSON决策列表
-func extractDecisions(response string) ([]Decision, error) {
+// 解析失败不会返回错误，而是降级为一个wait决策（让AI在下个周期重试），诊断信息记录在 ParseDiagnostics 中
+// 返回的诊断信息在解析顺利且未做任何修复时为nil
+func extractDecisions(response string) ([]Decision, *ParseDiagnostics) {
+	diag := &ParseDiagnostics{}
+
+	// 🔧 替换中文引号为英文引号（避免输入法自动转换）
+	response = fixMissingQuotes(response)
+
 	// 查找所有可能的JSON数组，验证哪个是决策数组
 	// 决策数组应该包含对象，而不是简单的数字数组
+	foundEmptyArray := false
+	var lastErr error
+	var lastContent string
 	searchStart := 0
 	for {
 		arrayStart := strings.Index(response[searchStart:], "[")
@@ -756,92 +1179,83 @@ func extractDecisions(response string) ([]Decision, error) {
 		}
 		arrayStart += searchStart // Adjust to absolute position
 
-		// 从 [ 开始，匹配括号找到对应的 ]
-		arrayEnd := findMatchingBracket(response, arrayStart)
-		if arrayEnd == -1 {
-			searchStart = arrayStart + 1
-			continue
+		// 从 [ 开始扫描并修复：尾随逗号、注释、单引号、截断等
+		repaired := repairJSONArray(response[arrayStart:])
+		jsonContent := strings.TrimSpace(repaired.json)
+
+		// 被截断的数组可能只是思维链中的普通 [ ，下次从下一个字符继续查找
+		nextStart := arrayStart + repaired.end
+		if repaired.truncated {
+			nextStart = arrayStart + 1
 		}
 
-		jsonContent := strings.TrimSpace(response[arrayStart : arrayEnd+1])
+		// 空数组：AI明确表示没有决策
+		if jsonContent == "[]" && !repaired.truncated {
+			foundEmptyArray = true
+			searchStart = nextStart
+			continue
+		}
 
 		// 快速检查：跳过明显不是决策数组的内容（纯数字数组）
 		// 决策数组应该包含 "symbol" 或 "action" 等关键字
 		if !strings.Contains(jsonContent, "\"symbol\"") && !strings.Contains(jsonContent, "\"action\"") {
 			// 这可能是价格数据数组，跳过
-			searchStart = arrayEnd + 1
+			searchStart = nextStart
 			continue
 		}
 
-		// 🔧 修复常见的JSON格式错误：缺少引号的字段值
-		// 匹配: "reasoning": 内容"}  或  "reasoning": 内容}  (没有引号)
-		// 修复为: "reasoning": "内容"}
-		// 使用简单的字符串扫描而不是正则表达式
-		jsonContent = fixMissingQuotes(jsonContent)
-
 		// 🔧 修复算术表达式：将 JSON 中的计算表达式（如 "150 * (0.62 - 0.61) * 5"）替换为计算结果
 		// 例如: "risk_usd": 150 * (0.62 - 0.61) * 5  ->  "risk_usd": 0.75
 		jsonContent = fixArithmeticExpressions(jsonContent)
 
 		// 解析JSON
 		var decisions []Decision
-		if err := json.Unmarshal([]byte(jsonContent), &decisions); err == nil {
-			// 验证这是一个有效的决策数组：至少有一个决策，且有symbol字段
-			if len(decisions) > 0 && decisions[0].Symbol != "" {
-				return decisions, nil
+		err := json.Unmarshal([]byte(jsonContent), &decisions)
+		// 验证这是一个有效的决策数组：至少有一个决策，且有symbol字段
+		if err == nil && len(decisions) > 0 && decisions[0].Symbol != "" {
+			diag.addRepairs(repaired.repairs)
+			diag.Dropped = repaired.dropped
+			if len(diag.Repairs) > 0 {
+				log.Printf("🔧 AI决策JSON已自动修复: %s", diag)
+				return decisions, diag
 			}
+			return decisions, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("决策缺少symbol字段")
 		}
+		lastErr = err
+		lastContent = jsonContent
 
 		// 如果解析失败或验证失败，继续查找下一个数组
-		searchStart = arrayEnd + 1
+		searchStart = nextStart
 	}
 
-	// 如果所有数组都解析失败，尝试最后一个找到的数组（向后兼容）
-	arrayStart := strings.LastIndex(response, "[")
-	if arrayStart == -1 {
-		// Fallback: 如果没有找到JSON数组，返回一个wait决策而不是报错
-		// 这样可以避免系统崩溃，让AI在下个周期重试
-		log.Printf("⚠️ 警告: AI响应中未找到JSON数组，返回wait决策")
-		return []Decision{
-			{
-				Symbol:   "",
-				Action:   "wait",
-				Reasoning: "AI响应格式错误，未找到JSON数组",
-			},
-		}, nil
+	if foundEmptyArray && lastErr == nil {
+		return []Decision{}, nil
 	}
 
-	arrayEnd := findMatchingBracket(response, arrayStart)
-	if arrayEnd == -1 {
-		// Fallback: 如果找到了[但没有找到]，也返回wait决策
-		log.Printf("⚠️ 警告: AI响应中JSON数组不完整（找到[但未找到]），返回wait决策")
-		return []Decision{
-			{
-				Symbol:   "",
-				Action:   "wait",
-				Reasoning: "AI响应格式错误，JSON数组不完整",
-			},
-		}, nil
+	// Fallback: 返回一个wait决策而不是报错，这样可以避免系统崩溃，让AI在下个周期重试
+	diag.Fallback = true
+	switch {
+	case lastErr != nil:
+		diag.Reason = fmt.Sprintf("JSON解析失败: %v", lastErr)
+		diag.Snippet = truncateSnippet(lastContent)
+		log.Printf("⚠️ 警告: %s，返回wait决策\nJSON内容: %s", diag.Reason, diag.Snippet)
+	case strings.Contains(response, "["):
+		diag.Reason = "JSON数组不完整或不包含决策"
+		log.Printf("⚠️ 警告: AI响应中%s，返回wait决策", diag.Reason)
+	default:
+		diag.Reason = "未找到JSON数组"
+		log.Printf("⚠️ 警告: AI响应中%s，返回wait决策", diag.Reason)
 	}
-
-	jsonContent := strings.TrimSpace(response[arrayStart : arrayEnd+1])
-	jsonContent = fixMissingQuotes(jsonContent)
-	jsonContent = fixArithmeticExpressions(jsonContent)
-
-	var decisions []Decision
-	if err := json.Unmarshal([]byte(jsonContent), &decisions); err != nil {
-		// 即使JSON解析失败，也返回wait决策而不是报错
-		log.Printf("⚠️ 警告: JSON解析失败: %v，返回wait决策\nJSON内容: %s", err, jsonContent)
-		return []Decision{
-			{
-				Symbol:   "",
-				Action:   "wait",
-				Reasoning: fmt.Sprintf("JSON解析失败: %v", err),
-			},
-		}, nil
-	}
-
-	return decisions, nil
+	return []Decision{
+		{
+			Symbol:    "",
+			Action:    "wait",
+			Reasoning: "AI响应格式错误，" + diag.Reason,
+		},
+	}, diag
 }
 
 // fixMissingQuotes 替换中文引号为英文引号（避免输入法自动转换）
@@ -893,61 +1307,94 @@ func fixArithmeticExpressions(jsonStr string) string {
 	return jsonStr
 }
 
-// validateDecisions 验证所有决策（需要账户信息和杠杆配置）
-func validateDecisions(decisions []Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64) error {
+// validateDecisions 验证所有决策（需要账户信息和杠杆配置；有波动率数据时检查止盈距离，临近资金费结算时检查费率方向）
+func validateDecisions(decisions []Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64, symbolFilter *pool.SymbolFilter, symbolLimits SymbolLimits, marketDataMap map[string]*market.Data) error {
+	maxMoves := getMaxTPDailyMoves()
 	for i, decision := range decisions {
-		if err := validateDecision(&decision, accountEquity, btcEthLeverage, altcoinLeverage, minPositionSizeUSD, maxPositionSizeUSD); err != nil {
+		if err := validateDecision(&decision, accountEquity, btcEthLeverage, altcoinLeverage, minPositionSizeUSD, maxPositionSizeUSD, symbolFilter, symbolLimits); err != nil {
+			return fmt.Errorf("决策 #%d 验证失败: %w", i+1, err)
+		}
+		if err := validateTPDistance(&decision, marketDataMap[decision.Symbol], maxMoves); err != nil {
+			return fmt.Errorf("决策 #%d 验证失败: %w", i+1, err)
+		}
+		if err := validateFundingWindow(&decision, marketDataMap[decision.Symbol], time.Now()); err != nil {
 			return fmt.Errorf("决策 #%d 验证失败: %w", i+1, err)
 		}
 	}
 	return nil
 }
 
-// findMatchingBracket 查找匹配的右括号
-func findMatchingBracket(s string, start int) int {
-	if start >= len(s) || s[start] != '[' {
-		return -1
-	}
-
-	depth := 0
-	for i := start; i < len(s); i++ {
-		switch s[i] {
-		case '[':
-			depth++
-		case ']':
-			depth--
-			if depth == 0 {
-				return i
-			}
-		}
-	}
-
-	return -1
+// ValidateDecision 按AI决策相同的规则验证外部提交的决策（如MCP下单提议）
+func ValidateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64, symbolFilter *pool.SymbolFilter, symbolLimits SymbolLimits) error {
+	return validateDecision(d, accountEquity, btcEthLeverage, altcoinLeverage, minPositionSizeUSD, maxPositionSizeUSD, symbolFilter, symbolLimits)
 }
 
 // validateDecision 验证单个决策的有效性
-func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64) error {
-	// 验证action
-	validActions := map[string]bool{
-		"open_long":   true,
-		"open_short":  true,
-		"close_long":  true,
-		"close_short": true,
-		"hold":        true,
-		"wait":        true,
+func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, minPositionSizeUSD, maxPositionSizeUSD float64, symbolFilter *pool.SymbolFilter, symbolLimits SymbolLimits) error {
+	// 验证格式版本（缺省按v1处理，兼容旧模板）
+	if d.SchemaVersion > DecisionSchemaVersion {
+		return fmt.Errorf("不支持的决策格式版本: v%d（当前最高v%d）", d.SchemaVersion, DecisionSchemaVersion)
 	}
 
+	// 验证action
 	if !validActions[d.Action] {
 		return fmt.Errorf("无效的action: %s", d.Action)
 	}
 
+	if d.Side != "" && d.Side != "long" && d.Side != "short" {
+		return fmt.Errorf("%s 的side必须是long或short: %s", d.Action, d.Side)
+	}
+
+	// 币种单独设置的仓位范围覆盖全局限制
+	minPositionSizeUSD, maxPositionSizeUSD = symbolLimits.PositionSizeRange(d.Symbol, minPositionSizeUSD, maxPositionSizeUSD)
+
+	switch d.OrderType {
+	case "", "market", "ioc", "fok", "post_only":
+	default:
+		return fmt.Errorf("无效的order_type: %s（可选: market, ioc, fok, post_only）", d.OrderType)
+	}
+
+	// 黑白名单只限制开仓和加仓，平仓/调整止损等持仓管理不受影响（加入黑名单前的持仓仍可正常退出）
+	switch d.Action {
+	case "open_long", "open_short", "add_to_position":
+		if !symbolFilter.Allowed(d.Symbol) {
+			return fmt.Errorf("%s 在黑名单中或不在白名单中，禁止%s", d.Symbol, d.Action)
+		}
+	}
+
+	// 持仓管理动作参数校验
+	switch d.Action {
+	case "adjust_sl":
+		if d.StopLoss <= 0 {
+			return fmt.Errorf("adjust_sl 必须提供大于0的stop_loss")
+		}
+	case "adjust_tp":
+		if d.TakeProfit <= 0 {
+			return fmt.Errorf("adjust_tp 必须提供大于0的take_profit")
+		}
+	case "partial_close":
+		if d.ClosePercent <= 0 || d.ClosePercent >= 100 {
+			return fmt.Errorf("partial_close 的close_percent必须在0-100之间（不含边界，全部平仓请用close_long/close_short）: %.2f", d.ClosePercent)
+		}
+	case "add_to_position":
+		if d.PositionSizeUSD <= 0 {
+			return fmt.Errorf("add_to_position 必须提供大于0的position_size_usd")
+		}
+		if maxPositionSizeUSD > 0 && d.PositionSizeUSD > maxPositionSizeUSD {
+			return fmt.Errorf("加仓大小 %.2f USDT 超过最大限制 %.2f USDT", d.PositionSizeUSD, maxPositionSizeUSD)
+		}
+		maxLeverage := symbolLimits.MaxLeverage(d.Symbol, btcEthLeverage, altcoinLeverage)
+		if d.Leverage < 0 || d.Leverage > maxLeverage {
+			return fmt.Errorf("杠杆必须在1-%d之间（%s）: %d", maxLeverage, d.Symbol, d.Leverage)
+		}
+	}
+
 	// 开仓操作必须提供完整参数
 	if d.Action == "open_long" || d.Action == "open_short" {
-		// 根据币种使用配置的杠杆上限
-		maxLeverage := altcoinLeverage          // 山寨币使用配置的杠杆
+		// 根据币种使用配置的杠杆上限（币种单独设置的上限优先）
+		maxLeverage := symbolLimits.MaxLeverage(d.Symbol, btcEthLeverage, altcoinLeverage)
 		maxPositionValue := accountEquity * 1.5 // 山寨币最多1.5倍账户净值
-		if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
-			maxLeverage = btcEthLeverage          // BTC和ETH使用配置的杠杆
+		if market.IsBTCOrETH(d.Symbol) {
 			maxPositionValue = accountEquity * 10 // BTC/ETH最多10倍账户净值
 		}
 
@@ -972,7 +1419,7 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 			// 如果没有配置USD限制，使用账户净值倍数限制（加1%容差以避免浮点数精度问题）
 			tolerance := maxPositionValue * 0.01 // 1%容差
 			if d.PositionSizeUSD > maxPositionValue+tolerance {
-				if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
+				if market.IsBTCOrETH(d.Symbol) {
 					return fmt.Errorf("BTC/ETH单币种仓位价值不能超过%.0f USDT（10倍账户净值），实际: %.0f", maxPositionValue, d.PositionSizeUSD)
 				} else {
 					return fmt.Errorf("山寨币单币种仓位价值不能超过%.0f USDT（1.5倍账户净值），实际: %.0f", maxPositionValue, d.PositionSizeUSD)
@@ -0,0 +1,193 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/mcp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 多模型集成决策
+// 同一份上下文（同样的 system/user prompt）同时发给 2-3 个模型，按策略合并各模型的决策：
+//   unanimous          - 所有模型都提出同一币种的同一动作才执行
+//   majority           - 超过半数模型提出同一币种的同一动作才执行（2个模型时等同 unanimous）
+//   highest_confidence - 每个币种采用信心度最高的模型给出的决策
+// 调用或解析失败的模型视为弃权（仍计入总数），全部失败时返回错误。
+// 多个模型提出同一动作时，采用其中信心度最高的那条（仓位、止损止盈以它为准）。
+
+// 集成策略
+const (
+	EnsembleUnanimous         = "unanimous"
+	EnsembleMajority          = "majority"
+	EnsembleHighestConfidence = "highest_confidence"
+)
+
+// EnsembleMember 参与集成的模型
+type EnsembleMember struct {
+	Name   string // 日志中显示的模型名称
+	Client *mcp.Client
+}
+
+// EnsembleOutput 单个模型的输出
+type EnsembleOutput struct {
+	Model     string     `json:"model"`
+	CoTTrace  string     `json:"cot_trace"`
+	Reasoning string     `json:"reasoning,omitempty"` // 推理模型的推理过程
+	Decisions []Decision `json:"decisions"`
+	Error     string     `json:"error,omitempty"` // 调用或解析失败原因（该模型弃权）
+}
+
+// GetEnsembleDecision 用多个模型获取决策并按策略合并
+func GetEnsembleDecision(ctx *Context, members []EnsembleMember, policy string) (*FullDecision, error) {
+	systemPrompt, userPrompt, err := prepareDecisionPrompts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 并发调用所有模型
+	outputs := make([]EnsembleOutput, len(members))
+	parsed := make([]*FullDecision, len(members))
+	var wg sync.WaitGroup
+	for i, m := range members {
+		wg.Add(1)
+		go func(i int, m EnsembleMember) {
+			defer wg.Done()
+			// 单个模型的panic只记为该模型失败，不影响其他模型
+			defer func() {
+				if r := recover(); r != nil {
+					outputs[i].Error = fmt.Sprintf("panic: %v", r)
+					parsed[i] = nil
+				}
+			}()
+			outputs[i].Model = m.Name
+			d, err := callAndParseDecision(ctx, m.Client, systemPrompt, userPrompt)
+			if err != nil {
+				outputs[i].Error = err.Error()
+				return
+			}
+			outputs[i].CoTTrace = d.CoTTrace
+			outputs[i].Reasoning = d.Reasoning
+			outputs[i].Decisions = d.Decisions
+			parsed[i] = d
+		}(i, m)
+	}
+	wg.Wait()
+
+	full := &FullDecision{
+		SchemaVersion: DecisionSchemaVersion,
+		UserPrompt:    userPrompt,
+		Timestamp:     time.Now(),
+		Ensemble:      outputs,
+	}
+	var cots, reasonings []string
+	var failures []string
+	for i, d := range parsed {
+		if d == nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", outputs[i].Model, outputs[i].Error))
+			continue
+		}
+		cots = append(cots, fmt.Sprintf("【%s】\n%s", outputs[i].Model, d.CoTTrace))
+		if d.Reasoning != "" {
+			reasonings = append(reasonings, fmt.Sprintf("【%s】\n%s", outputs[i].Model, d.Reasoning))
+		}
+		full.ReasoningTokens += d.ReasoningTokens
+		full.AutoStops = append(full.AutoStops, d.AutoStops...)
+		full.PriceRounding = append(full.PriceRounding, d.PriceRounding...)
+		if full.ParseDiagnostics == nil {
+			full.ParseDiagnostics = d.ParseDiagnostics
+		}
+	}
+	if len(failures) == len(members) {
+		return nil, fmt.Errorf("所有模型均未给出有效决策: %s", strings.Join(failures, "; "))
+	}
+
+	full.CoTTrace = strings.Join(cots, "\n\n")
+	full.Reasoning = strings.Join(reasonings, "\n\n")
+	full.Decisions = combineDecisions(outputs, policy)
+	return full, nil
+}
+
+// combineDecisions 按策略合并各模型的决策（wait/hold 不参与投票）
+func combineDecisions(outputs []EnsembleOutput, policy string) []Decision {
+	if policy == EnsembleHighestConfidence {
+		return combineByConfidence(outputs)
+	}
+
+	type proposal struct {
+		best  Decision
+		votes int
+	}
+	proposals := make(map[string]*proposal)
+	var order []string
+	for _, out := range outputs {
+		seen := make(map[string]bool) // 同一模型对同一动作只算一票
+		for _, d := range out.Decisions {
+			if d.Action == "wait" || d.Action == "hold" {
+				continue
+			}
+			key := d.Symbol + "|" + d.Action
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			p, ok := proposals[key]
+			if !ok {
+				p = &proposal{best: d}
+				proposals[key] = p
+				order = append(order, key)
+			} else if d.Confidence > p.best.Confidence {
+				p.best = d
+			}
+			p.votes++
+		}
+	}
+
+	var result []Decision
+	for _, key := range order {
+		p := proposals[key]
+		agreed := p.votes*2 > len(outputs)
+		if policy == EnsembleUnanimous {
+			agreed = p.votes == len(outputs)
+		}
+		if agreed {
+			result = append(result, p.best)
+		}
+	}
+	return result
+}
+
+// combineByConfidence 每个币种采用信心度最高的模型的全部决策（保持同一模型的平仓+反手等组合动作完整）
+func combineByConfidence(outputs []EnsembleOutput) []Decision {
+	type pick struct {
+		output     int
+		confidence int
+	}
+	picks := make(map[string]pick)
+	var symbols []string
+	for i, out := range outputs {
+		for _, d := range out.Decisions {
+			if d.Action == "wait" || d.Action == "hold" {
+				continue
+			}
+			p, ok := picks[d.Symbol]
+			if !ok {
+				symbols = append(symbols, d.Symbol)
+			}
+			if !ok || d.Confidence > p.confidence {
+				picks[d.Symbol] = pick{output: i, confidence: d.Confidence}
+			}
+		}
+	}
+
+	var result []Decision
+	for _, symbol := range symbols {
+		for _, d := range outputs[picks[symbol].output].Decisions {
+			if d.Symbol == symbol && d.Action != "wait" && d.Action != "hold" {
+				result = append(result, d)
+			}
+		}
+	}
+	return result
+}
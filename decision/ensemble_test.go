@@ -0,0 +1,44 @@
+package decision
+
+import "testing"
+
+func TestCombineDecisions(t *testing.T) {
+	outputs := []EnsembleOutput{
+		{Model: "a", Decisions: []Decision{
+			{Symbol: "ETHUSDT", Action: "open_long", Confidence: 70, PositionSizeUSD: 1000},
+			{Symbol: "BTCUSDT", Action: "close_long", Confidence: 80},
+		}},
+		{Model: "b", Decisions: []Decision{
+			{Symbol: "ETHUSDT", Action: "open_long", Confidence: 85, PositionSizeUSD: 800},
+			{Symbol: "SOLUSDT", Action: "open_short", Confidence: 95},
+		}},
+		{Model: "c", Error: "调用AI API失败"}, // 弃权
+	}
+
+	tests := []struct {
+		policy string
+		want   []string // symbol|action
+	}{
+		{EnsembleUnanimous, nil},
+		{EnsembleMajority, []string{"ETHUSDT|open_long"}},
+		{EnsembleHighestConfidence, []string{"ETHUSDT|open_long", "BTCUSDT|close_long", "SOLUSDT|open_short"}},
+	}
+	for _, tt := range tests {
+		got := combineDecisions(outputs, tt.policy)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: 决策 = %+v, 期望 %v", tt.policy, got, tt.want)
+			continue
+		}
+		for i, d := range got {
+			if d.Symbol+"|"+d.Action != tt.want[i] {
+				t.Errorf("%s: 第%d条 = %s|%s, 期望 %s", tt.policy, i, d.Symbol, d.Action, tt.want[i])
+			}
+		}
+	}
+
+	// 多个模型同意时采用信心度最高的那条
+	got := combineDecisions(outputs, EnsembleMajority)
+	if got[0].PositionSizeUSD != 800 {
+		t.Errorf("应采用信心度最高的决策参数, 实际仓位 %.0f", got[0].PositionSizeUSD)
+	}
+}
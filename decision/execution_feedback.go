@@ -0,0 +1,89 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 执行状态
+const (
+	ExecExecuted    = "executed"     // 已执行
+	ExecPartial     = "partial"      // 部分成交
+	ExecFailed      = "failed"       // 执行失败
+	ExecNotExecuted = "not_executed" // 被风控/限流/审批等拦截，没有下单
+)
+
+// ExecutionReport 上个周期一条决策的实际执行结果（写入下个周期的prompt，让AI知道指令是否生效）
+type ExecutionReport struct {
+	Symbol            string   `json:"symbol"`
+	Action            string   `json:"action"`
+	Status            string   `json:"status"`
+	Quantity          float64  `json:"quantity,omitempty"`           // 实际成交数量
+	RequestedQuantity float64  `json:"requested_quantity,omitempty"` // 请求的数量（部分成交时大于 Quantity）
+	Price             float64  `json:"price,omitempty"`              // 成交均价（交易所未返回时为决策时价格）
+	IntendedPrice     float64  `json:"intended_price,omitempty"`     // 决策时的价格
+	StopLoss          float64  `json:"stop_loss,omitempty"`          // 实际挂出的止损价
+	TakeProfit        float64  `json:"take_profit,omitempty"`        // 实际挂出的止盈价
+	ProtectionError   string   `json:"protection_error,omitempty"`   // 止损止盈挂单失败原因
+	Error             string   `json:"error,omitempty"`              // 执行失败原因
+	RejectReason      string   `json:"reject_reason,omitempty"`      // 交易所拒单原因（Reject*）
+	Notes             []string `json:"notes,omitempty"`              // 执行前的调整或拦截原因（如仓位缩减、限流跳过）
+}
+
+// ExecutionFeedback 上个AI周期的执行反馈
+type ExecutionFeedback struct {
+	Cycle   int               `json:"cycle"`
+	Time    time.Time         `json:"time"`
+	Reports []ExecutionReport `json:"reports"`
+}
+
+// writeExecutionFeedback 上个周期决策的执行结果
+func writeExecutionFeedback(sb *strings.Builder, ctx *Context) {
+	fb := ctx.ExecutionFeedback
+	if fb == nil || len(fb.Reports) == 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("## 🧾 上个周期决策执行反馈（周期 #%d，%s）\n", fb.Cycle, fb.Time.Format("15:04")))
+	for _, r := range fb.Reports {
+		sb.WriteString(fmt.Sprintf("- %s %s: %s\n", r.Symbol, r.Action, formatExecutionReport(r)))
+	}
+	sb.WriteString("以上为实际结果：未执行或失败的指令没有生效，需要时请根据当前情况重新决策。\n\n")
+}
+
+// formatExecutionReport 一条执行结果的说明
+func formatExecutionReport(r ExecutionReport) string {
+	var parts []string
+	switch r.Status {
+	case ExecExecuted, ExecPartial:
+		status := "✓ 已执行"
+		if r.Status == ExecPartial {
+			status = fmt.Sprintf("◐ 部分成交（请求 %.4f）", r.RequestedQuantity)
+		}
+		if r.Quantity > 0 && r.Price > 0 {
+			status += fmt.Sprintf("，成交 %.4f @ %.4f", r.Quantity, r.Price)
+			if r.IntendedPrice > 0 && r.IntendedPrice != r.Price {
+				status += fmt.Sprintf("（决策时价格 %.4f）", r.IntendedPrice)
+			}
+		}
+		parts = append(parts, status)
+		if r.StopLoss > 0 || r.TakeProfit > 0 {
+			if r.ProtectionError != "" {
+				parts = append(parts, fmt.Sprintf("⚠️ 止损止盈挂单失败（系统会重试）: %s", r.ProtectionError))
+			} else {
+				parts = append(parts, fmt.Sprintf("止损 %.4f / 止盈 %.4f 已挂出", r.StopLoss, r.TakeProfit))
+			}
+		}
+	case ExecFailed:
+		parts = append(parts, "❌ 执行失败: "+r.Error)
+		if hint, ok := rejectReasonHints[r.RejectReason]; ok {
+			parts = append(parts, fmt.Sprintf("被交易所拒绝：%s → %s", hint.label, hint.advice))
+		}
+	default:
+		parts = append(parts, "⏭ 未执行")
+	}
+	for _, note := range r.Notes {
+		parts = append(parts, note)
+	}
+	return strings.Join(parts, " | ")
+}
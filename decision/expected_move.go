@@ -0,0 +1,50 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+	"nofx/market"
+	"sync"
+)
+
+// 止盈距离与预期波动的合理性检查
+// 启用波动率数据后，开仓决策的止盈距离（相对当前价）不能超过 1σ 日波动的 maxTPDailyMoves 倍：
+// 远超近期波动的目标很难在合理时间内到达，通常是AI编造的价格。没有波动率数据时不检查。
+
+var (
+	maxTPDailyMoves   float64
+	maxTPDailyMovesMu sync.RWMutex
+)
+
+// SetMaxTPDailyMoves 设置止盈距离上限（1σ日波动的倍数，0表示不检查）
+func SetMaxTPDailyMoves(multiple float64) {
+	maxTPDailyMovesMu.Lock()
+	maxTPDailyMoves = multiple
+	maxTPDailyMovesMu.Unlock()
+}
+
+func getMaxTPDailyMoves() float64 {
+	maxTPDailyMovesMu.RLock()
+	defer maxTPDailyMovesMu.RUnlock()
+	return maxTPDailyMoves
+}
+
+// validateTPDistance 检查开仓决策的止盈距离是否超过预期波动的上限倍数
+func validateTPDistance(d *Decision, data *market.Data, maxMoves float64) error {
+	if maxMoves <= 0 || (d.Action != "open_long" && d.Action != "open_short") {
+		return nil
+	}
+	if data == nil || data.Volatility == nil || data.CurrentPrice <= 0 || d.TakeProfit <= 0 {
+		return nil
+	}
+	daily := data.Volatility.DailyMovePct()
+	if daily <= 0 {
+		return nil
+	}
+	distance := math.Abs(d.TakeProfit-data.CurrentPrice) / data.CurrentPrice * 100
+	if distance > daily*maxMoves {
+		return fmt.Errorf("%s 止盈距离 %.2f%% 是1σ日波动(%.2f%%)的%.1f倍，超过上限%.1f倍（止盈%.4f，当前价%.4f）",
+			d.Symbol, distance, daily, distance/daily, maxMoves, d.TakeProfit, data.CurrentPrice)
+	}
+	return nil
+}
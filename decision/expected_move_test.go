@@ -0,0 +1,43 @@
+package decision
+
+import (
+	"math"
+	"nofx/market"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTPDistanceVsExpectedMove(t *testing.T) {
+	SetMaxTPDailyMoves(3)
+	t.Cleanup(func() { SetMaxTPDailyMoves(0) })
+
+	// 年化波动率 2%×√365 → 1σ日波动 2%
+	vol := &market.VolatilityData{RealizedVol24h: 2 * math.Sqrt(365), RealizedVol7d: 20}
+	if move := vol.ExpectedMovePct(24 * time.Hour); math.Abs(move-2) > 1e-9 {
+		t.Fatalf("24小时预期波动 = %.4f%%，期望 2%%", move)
+	}
+	if move := vol.ExpectedMovePct(6 * time.Hour); math.Abs(move-1) > 1e-9 {
+		t.Fatalf("6小时预期波动 = %.4f%%，期望 1%%", move)
+	}
+	data := map[string]*market.Data{"SOLUSDT": {Symbol: "SOLUSDT", CurrentPrice: 100, Volatility: vol}}
+
+	// 止盈距离5%，在3倍日波动（6%）以内
+	ok := `[{"symbol": "SOLUSDT", "action": "open_long", "leverage": 3, "position_size_usd": 500, "stop_loss": 98.5, "take_profit": 105, "confidence": 80, "reasoning": "突破"}]`
+	if _, err := parseFullDecisionResponse(ok, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, nil); err != nil {
+		t.Fatalf("止盈距离在预期波动范围内应通过: %v", err)
+	}
+
+	// 止盈距离20%，是日波动的10倍
+	far := `[{"symbol": "SOLUSDT", "action": "open_short", "leverage": 3, "position_size_usd": 500, "stop_loss": 104, "take_profit": 80, "confidence": 80, "reasoning": "崩盘"}]`
+	_, err := parseFullDecisionResponse(far, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, nil)
+	if err == nil || !strings.Contains(err.Error(), "1σ日波动") {
+		t.Fatalf("止盈远超预期波动应被拒绝: %v", err)
+	}
+
+	// 没有波动率数据时不检查
+	data["SOLUSDT"].Volatility = nil
+	if _, err := parseFullDecisionResponse(far, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, nil); err != nil {
+		t.Fatalf("没有波动率数据时不应检查止盈距离: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExternalSignal 外部策略发来的信号（如 TradingView 警报），作为候选币种提示写入prompt
+type ExternalSignal struct {
+	Source     string    `json:"source"` // 来源（如 "tradingview"）
+	Symbol     string    `json:"symbol"`
+	Side       string    `json:"side"` // long/short/close_long/close_short
+	Price      float64   `json:"price,omitempty"`
+	StopLoss   float64   `json:"stop_loss,omitempty"`
+	TakeProfit float64   `json:"take_profit,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// externalSignalSides 外部信号方向的说明
+var externalSignalSides = map[string]string{
+	"long":        "做多",
+	"short":       "做空",
+	"close_long":  "平多",
+	"close_short": "平空",
+}
+
+// writeExternalSignals 外部信号（只作参考，由AI结合市场数据独立判断）
+func writeExternalSignals(sb *strings.Builder, ctx *Context) {
+	if len(ctx.ExternalSignals) == 0 {
+		return
+	}
+	sb.WriteString("## 📡 外部策略信号（仅供参考，必须结合市场数据独立判断，不满足你的开仓条件时忽略）\n")
+	for _, s := range ctx.ExternalSignals {
+		line := fmt.Sprintf("- %s %s（来源 %s，%s）", s.Symbol, externalSignalSides[s.Side], s.Source, s.ReceivedAt.Format("01-02 15:04"))
+		if s.Price > 0 {
+			line += fmt.Sprintf(" | 信号价 %.4f", s.Price)
+		}
+		if s.StopLoss > 0 || s.TakeProfit > 0 {
+			line += fmt.Sprintf(" | 止损 %.4f 止盈 %.4f", s.StopLoss, s.TakeProfit)
+		}
+		if s.Comment != "" {
+			comment := []rune(s.Comment)
+			if len(comment) > 120 {
+				comment = append(comment[:120], []rune("...")...)
+			}
+			line += " | " + string(comment)
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n")
+}
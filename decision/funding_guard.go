@@ -0,0 +1,56 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/market"
+	"sync"
+	"time"
+)
+
+// 资金费结算前的开仓限制
+// 开仓后很快就要结算资金费时，费率方向不利的新仓位会立即付出一笔资金费（多头在正费率时付费，空头在负费率时付费）。
+// 启用后，距离下次结算不足 minutesBefore 分钟、且费率绝对值超过 minRate 并对开仓方向不利时，拒绝开仓决策。
+// 方向有利（收取资金费）或费率不大时不限制。
+
+var fundingGuard = struct {
+	mu            sync.RWMutex
+	minutesBefore int
+	minRate       float64
+}{}
+
+// SetFundingEntryGuard 设置资金费结算前的开仓限制（minutesBefore<=0 表示不检查，minRate 为费率小数如 0.0005）
+func SetFundingEntryGuard(minutesBefore int, minRate float64) {
+	fundingGuard.mu.Lock()
+	fundingGuard.minutesBefore = minutesBefore
+	fundingGuard.minRate = minRate
+	fundingGuard.mu.Unlock()
+}
+
+func getFundingEntryGuard() (time.Duration, float64) {
+	fundingGuard.mu.RLock()
+	defer fundingGuard.mu.RUnlock()
+	return time.Duration(fundingGuard.minutesBefore) * time.Minute, fundingGuard.minRate
+}
+
+// validateFundingWindow 检查开仓决策是否处于资金费结算前的不利窗口
+func validateFundingWindow(d *Decision, data *market.Data, now time.Time) error {
+	window, minRate := getFundingEntryGuard()
+	if window <= 0 || (d.Action != "open_long" && d.Action != "open_short") || data == nil {
+		return nil
+	}
+	left := data.FundingCountdown(now)
+	if left <= 0 || left > window {
+		return nil
+	}
+	side := "多头"
+	against := data.FundingRate > minRate
+	if d.Action == "open_short" {
+		side = "空头"
+		against = data.FundingRate < -minRate
+	}
+	if !against {
+		return nil
+	}
+	return fmt.Errorf("%s 距离资金费结算仅剩 %d 分钟，费率 %.4f%% 对%s不利（超过 ±%.4f%%），结算前 %d 分钟内禁止此方向开仓",
+		d.Symbol, int(left.Minutes()), data.FundingRate*100, side, minRate*100, int(window.Minutes()))
+}
@@ -0,0 +1,45 @@
+package decision
+
+import (
+	"nofx/market"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFundingWindowBlocksAdverseEntries(t *testing.T) {
+	SetFundingEntryGuard(30, 0.0005)
+	t.Cleanup(func() { SetFundingEntryGuard(0, 0) })
+
+	data := map[string]*market.Data{"SOLUSDT": {Symbol: "SOLUSDT", CurrentPrice: 100, FundingRate: 0.001, NextFundingTime: time.Now().Add(10 * time.Minute)}}
+	long := `[{"symbol": "SOLUSDT", "action": "open_long", "leverage": 3, "position_size_usd": 500, "stop_loss": 98.5, "take_profit": 103, "confidence": 80, "reasoning": "突破"}]`
+	short := `[{"symbol": "SOLUSDT", "action": "open_short", "leverage": 3, "position_size_usd": 500, "stop_loss": 101.5, "take_profit": 97, "confidence": 80, "reasoning": "回落"}]`
+
+	// 结算前10分钟、正费率0.1%：多头付费被拒绝，空头收费可以开仓
+	_, err := parseFullDecisionResponse(long, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, nil)
+	if err == nil || !strings.Contains(err.Error(), "资金费结算") {
+		t.Fatalf("结算前付费方向开仓应被拒绝: %v", err)
+	}
+	if _, err := parseFullDecisionResponse(short, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, nil); err != nil {
+		t.Fatalf("收取资金费的方向不应限制: %v", err)
+	}
+
+	// 负费率时反过来限制空头
+	data["SOLUSDT"].FundingRate = -0.001
+	if _, err := parseFullDecisionResponse(short, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, nil); err == nil {
+		t.Fatal("负费率时结算前开空应被拒绝")
+	}
+
+	// 费率未超过阈值
+	data["SOLUSDT"].FundingRate = 0.0001
+	if _, err := parseFullDecisionResponse(long, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, nil); err != nil {
+		t.Fatalf("费率低于阈值时不应限制: %v", err)
+	}
+
+	// 距离结算超过30分钟
+	data["SOLUSDT"].FundingRate = 0.001
+	data["SOLUSDT"].NextFundingTime = time.Now().Add(2 * time.Hour)
+	if _, err := parseFullDecisionResponse(long, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, nil); err != nil {
+		t.Fatalf("距离结算较远时不应限制: %v", err)
+	}
+}
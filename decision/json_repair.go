@@ -0,0 +1,263 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AI输出JSON的容错修复层
+// 大模型经常输出"差不多是JSON"的内容：尾随逗号、注释、单引号字符串、字符串里的原始换行，
+// 或者因为max_tokens被截断导致数组没有闭合。这里在json.Unmarshal之前做一次宽松修复，
+// 修复不了的情况由 extractDecisions 降级为wait决策，不会让解析错误中断交易周期。
+
+// 修复类型（记录在 ParseDiagnostics.Repairs 中）
+const (
+	repairComment       = "comment"        // 移除 // 和 /* */ 注释
+	repairSingleQuote   = "single_quote"   // 单引号字符串转为双引号
+	repairTrailingComma = "trailing_comma" // 移除 ] 或 } 前的尾随逗号
+	repairControlChar   = "control_char"   // 转义字符串中的原始换行/制表符等控制字符
+	repairTruncated     = "truncated"      // 数组被截断：丢弃不完整的决策并补全 ]
+)
+
+// maxDiagnosticSnippet 诊断信息中保留的JSON片段最大长度
+const maxDiagnosticSnippet = 500
+
+// ParseDiagnostics 决策解析诊断信息
+type ParseDiagnostics struct {
+	Repairs  []string `json:"repairs,omitempty"` // 应用过的修复
+	Dropped  int      `json:"dropped,omitempty"` // 因截断被丢弃的不完整决策数
+	Fallback bool     `json:"fallback"`          // 是否降级为wait决策
+	Reason   string   `json:"reason,omitempty"`  // 降级原因
+	Snippet  string   `json:"snippet,omitempty"` // 解析失败的JSON片段（截断到500字符）
+}
+
+// String 单行摘要，用于日志和决策记录
+func (d *ParseDiagnostics) String() string {
+	if d == nil {
+		return ""
+	}
+	var parts []string
+	if len(d.Repairs) > 0 {
+		parts = append(parts, "修复: "+strings.Join(d.Repairs, ","))
+	}
+	if d.Dropped > 0 {
+		parts = append(parts, fmt.Sprintf("丢弃不完整决策: %d", d.Dropped))
+	}
+	if d.Fallback {
+		parts = append(parts, "降级为wait: "+d.Reason)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// addRepairs 追加修复类型（去重）
+func (d *ParseDiagnostics) addRepairs(repairs []string) {
+	for _, r := range repairs {
+		seen := false
+		for _, existing := range d.Repairs {
+			if existing == r {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			d.Repairs = append(d.Repairs, r)
+		}
+	}
+}
+
+// repairResult 单个JSON数组的修复结果
+type repairResult struct {
+	json      string   // 修复后的JSON
+	end       int      // 原文中数组结束位置（] 之后；被截断时为原文长度）
+	repairs   []string // 应用过的修复
+	truncated bool     // 数组未闭合
+	dropped   int      // 被丢弃的不完整顶层元素数
+}
+
+// repairJSONArray 从 s[0] 的 [ 开始扫描一个JSON数组并修复常见格式错误
+// 扫描时识别字符串，字符串内的括号、逗号、注释符号不会被误处理
+func repairJSONArray(s string) repairResult {
+	res := repairResult{end: len(s)}
+	if s == "" || s[0] != '[' {
+		return repairResult{json: "[]", end: 0}
+	}
+
+	mark := func(r string) {
+		for _, existing := range res.repairs {
+			if existing == r {
+				return
+			}
+		}
+		res.repairs = append(res.repairs, r)
+	}
+
+	out := make([]byte, 0, len(s)+8)
+	var stack []byte        // 未闭合的 [ 和 {
+	lastComplete := -1      // out 中最后一个完整顶层元素之后的位置
+	pendingElement := false // 最后一个完整元素之后是否又开始了新元素
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'':
+			str, n, closed, fixes := scanString(s[i:])
+			for _, f := range fixes {
+				mark(f)
+			}
+			if !closed {
+				// 字符串本身被截断
+				i = len(s)
+				pendingElement = true
+				continue
+			}
+			out = append(out, str...)
+			i += n
+			if len(stack) == 1 {
+				pendingElement = true
+			}
+
+		case c == '/' && i+1 < len(s) && s[i+1] == '/':
+			mark(repairComment)
+			j := strings.IndexByte(s[i:], '\n')
+			if j == -1 {
+				i = len(s)
+			} else {
+				i += j // 保留换行
+			}
+
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			mark(repairComment)
+			j := strings.Index(s[i+2:], "*/")
+			if j == -1 {
+				i = len(s)
+			} else {
+				i += j + 4
+			}
+
+		case c == '[' || c == '{':
+			if len(stack) == 1 {
+				pendingElement = true
+			}
+			stack = append(stack, c)
+			out = append(out, c)
+			i++
+
+		case c == ']' || c == '}':
+			if trimmed, ok := trimTrailingComma(out); ok {
+				out = trimmed
+				mark(repairTrailingComma)
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			out = append(out, c)
+			i++
+			if len(stack) == 0 {
+				res.json = string(out)
+				res.end = i
+				return res
+			}
+			if len(stack) == 1 {
+				lastComplete = len(out)
+				pendingElement = false
+			}
+
+		default:
+			if len(stack) == 1 && c != ',' && c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				pendingElement = true
+			}
+			out = append(out, c)
+			i++
+		}
+	}
+
+	// 数组未闭合：只保留完整的顶层元素，不完整的决策（可能缺少止损等字段）直接丢弃
+	res.truncated = true
+	mark(repairTruncated)
+	if pendingElement {
+		res.dropped = 1
+	}
+	if lastComplete == -1 {
+		res.json = "[]"
+		return res
+	}
+	res.json = string(out[:lastComplete]) + "]"
+	return res
+}
+
+// scanString 扫描从 s[0]（" 或 '）开始的字符串，返回合法的双引号JSON字符串
+// n 为消耗的原文字节数；closed=false 表示字符串在输入结束前未闭合
+func scanString(s string) (str string, n int, closed bool, fixes []string) {
+	quote := s[0]
+	var sb strings.Builder
+	sb.WriteByte('"')
+	if quote == '\'' {
+		fixes = append(fixes, repairSingleQuote)
+	}
+	controlFixed := false
+
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(s) {
+				return "", len(s), false, fixes
+			}
+			i++
+			if s[i] == '\'' {
+				sb.WriteByte('\'') // \' 在JSON中不是合法转义
+			} else {
+				sb.WriteByte('\\')
+				sb.WriteByte(s[i])
+			}
+		case c == quote:
+			sb.WriteByte('"')
+			return sb.String(), i + 1, true, fixes
+		case c == '"':
+			sb.WriteString(`\"`) // 单引号字符串中的双引号
+		case c < 0x20:
+			if !controlFixed {
+				fixes = append(fixes, repairControlChar)
+				controlFixed = true
+			}
+			switch c {
+			case '\n':
+				sb.WriteString(`\n`)
+			case '\r':
+				sb.WriteString(`\r`)
+			case '\t':
+				sb.WriteString(`\t`)
+			default:
+				fmt.Fprintf(&sb, `\u%04x`, c)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", len(s), false, fixes
+}
+
+// trimTrailingComma 去掉 out 末尾的逗号（忽略其后的空白）
+func trimTrailingComma(out []byte) ([]byte, bool) {
+	i := len(out) - 1
+	for i >= 0 && (out[i] == ' ' || out[i] == '\t' || out[i] == '\n' || out[i] == '\r') {
+		i--
+	}
+	if i >= 0 && out[i] == ',' {
+		return append(out[:i], out[i+1:]...), true
+	}
+	return out, false
+}
+
+// truncateSnippet 截断诊断片段
+func truncateSnippet(s string) string {
+	if len(s) <= maxDiagnosticSnippet {
+		return s
+	}
+	// 避免截断在UTF-8多字节字符中间
+	cut := maxDiagnosticSnippet
+	for cut > 0 && s[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return s[:cut] + "..."
+}
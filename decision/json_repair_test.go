@@ -0,0 +1,302 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExtractDecisionsRepairs(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     []Decision
+		repairs  []string
+	}{
+		{
+			name:     "合法JSON不做修复",
+			response: `分析完毕。` + "\n" + `[{"symbol": "BTCUSDT", "action": "wait", "reasoning": "无信号"}]`,
+			want:     []Decision{{Symbol: "BTCUSDT", Action: "wait", Reasoning: "无信号"}},
+		},
+		{
+			name:     "尾随逗号",
+			response: `[{"symbol": "BTCUSDT", "action": "hold", "reasoning": "持有",},]`,
+			want:     []Decision{{Symbol: "BTCUSDT", Action: "hold", Reasoning: "持有"}},
+			repairs:  []string{repairTrailingComma},
+		},
+		{
+			name: "行注释和块注释",
+			response: `[
+  // 第一个决策
+  {"symbol": "ETHUSDT", /* 方向 */ "action": "close_long", "reasoning": "见 https://example.com/a//b"}
+]`,
+			want:    []Decision{{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "见 https://example.com/a//b"}},
+			repairs: []string{repairComment},
+		},
+		{
+			name:     "单引号字符串",
+			response: `[{'symbol': 'SOLUSDT', 'action': 'wait', 'reasoning': 'it\'s "quiet"'}]`,
+			want:     []Decision{{Symbol: "SOLUSDT", Action: "wait", Reasoning: `it's "quiet"`}},
+			repairs:  []string{repairSingleQuote},
+		},
+		{
+			name:     "字符串中的原始换行",
+			response: "[{\"symbol\": \"BTCUSDT\", \"action\": \"wait\", \"reasoning\": \"第一行\n第二行\"}]",
+			want:     []Decision{{Symbol: "BTCUSDT", Action: "wait", Reasoning: "第一行\n第二行"}},
+			repairs:  []string{repairControlChar},
+		},
+		{
+			name: "截断时丢弃不完整的决策",
+			response: `[{"symbol": "BTCUSDT", "action": "hold", "reasoning": "持有"},
+{"symbol": "ETHUSDT", "action": "open_long", "leverage": 5, "position_size_usd": 1000, "stop_lo`,
+			want:    []Decision{{Symbol: "BTCUSDT", Action: "hold", Reasoning: "持有"}},
+			repairs: []string{repairTruncated},
+		},
+		{
+			name:     "字符串中的括号不影响匹配",
+			response: `区间[3000, 3100]震荡` + "\n" + `[{"symbol": "ETHUSDT", "action": "wait", "reasoning": "等待突破[3100]"}]`,
+			want:     []Decision{{Symbol: "ETHUSDT", Action: "wait", Reasoning: "等待突破[3100]"}},
+		},
+		{
+			name:     "思维链中未闭合的括号",
+			response: `[注意 仓位已满` + "\n" + `[{"symbol": "ETHUSDT", "action": "hold", "reasoning": "持有"}]`,
+			want:     []Decision{{Symbol: "ETHUSDT", Action: "hold", Reasoning: "持有"}},
+		},
+		{
+			name:     "空数组表示没有决策",
+			response: `观望。[]`,
+			want:     []Decision{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diag := extractDecisions(tt.response)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("决策 = %+v, 期望 %+v", got, tt.want)
+			}
+			if diag.String() != "" && diag.Fallback {
+				t.Fatalf("不应降级: %s", diag)
+			}
+			var repairs []string
+			if diag != nil {
+				repairs = diag.Repairs
+			}
+			if !reflect.DeepEqual(repairs, tt.repairs) {
+				t.Fatalf("修复 = %v, 期望 %v", repairs, tt.repairs)
+			}
+		})
+	}
+}
+
+func TestExtractDecisionsFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{"没有JSON", "市场不明朗，继续观望"},
+		{"只有数字数组", "近期价格 [1, 2, 3]"},
+		{"截断在第一个决策中", `[{"symbol": "BTCUSDT", "action": "open_lo`},
+		{"类型错误", `[{"symbol": "BTCUSDT", "action": "open_long", "leverage": "五倍"}]`},
+		{"缺少symbol", `[{"action": "wait"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diag := extractDecisions(tt.response)
+			if len(got) != 1 || got[0].Action != "wait" || got[0].Symbol != "" {
+				t.Fatalf("期望降级为单个wait决策, 得到 %+v", got)
+			}
+			if diag == nil || !diag.Fallback || diag.Reason == "" {
+				t.Fatalf("期望降级诊断信息, 得到 %+v", diag)
+			}
+		})
+	}
+}
+
+func TestParseFullDecisionResponseFallbackPassesValidation(t *testing.T) {
+	full, err := parseFullDecisionResponse(`思考中... [{"symbol": "BTCUSDT", "action": `, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, nil, nil)
+	if err != nil {
+		t.Fatalf("降级的wait决策应通过验证: %v", err)
+	}
+	if full.ParseDiagnostics == nil || !full.ParseDiagnostics.Fallback {
+		t.Fatalf("缺少降级诊断: %+v", full.ParseDiagnostics)
+	}
+}
+
+// 属性测试：随机生成决策，序列化时注入各种格式错误，解析结果必须是原决策（截断时为原决策的前缀）
+func TestExtractDecisionsProperty(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 2000; iter++ {
+		decisions := randomDecisions(rng)
+		mangled := mangleDecisions(rng, decisions)
+
+		truncateAt := -1
+		if rng.Intn(4) == 0 {
+			truncateAt = rng.Intn(len(mangled) + 1)
+			mangled = mangled[:truncateAt]
+		}
+		response := "思维链分析 [1, 2] 结束\n" + mangled
+
+		got, diag := extractDecisions(response)
+		if diag != nil && diag.Fallback {
+			if len(got) != 1 || got[0].Action != "wait" {
+				t.Fatalf("降级结果必须是单个wait: %+v", got)
+			}
+			if truncateAt == -1 {
+				t.Fatalf("未截断的输入不应降级: %s\n输入: %s", diag, mangled)
+			}
+			continue
+		}
+		if truncateAt == -1 {
+			if !reflect.DeepEqual(got, decisions) {
+				t.Fatalf("解析结果不一致\n输入: %s\n得到: %+v\n期望: %+v", mangled, got, decisions)
+			}
+			continue
+		}
+		if len(got) > len(decisions) || !reflect.DeepEqual(got, decisions[:len(got)]) {
+			t.Fatalf("截断后的解析结果必须是原决策的前缀\n输入: %s\n得到: %+v", mangled, got)
+		}
+	}
+}
+
+// FuzzExtractDecisions 任意输入都不能panic，且结果要么是解析出的决策，要么是单个wait降级决策
+func FuzzExtractDecisions(f *testing.F) {
+	f.Add(`[{"symbol": "BTCUSDT", "action": "wait", "reasoning": "无信号"}]`)
+	f.Add(`[{'symbol': 'BTCUSDT', 'action': 'hold',},] // done`)
+	f.Add("[{\"symbol\": \"ETHUSDT\", /* c */ \"action\": \"open_long\", \"reasoning\": \"a\nb")
+	f.Add(`[[[[{"action":`)
+	f.Add(`'\`)
+	f.Add(`[1, 2, 3]`)
+	f.Add(`[]`)
+
+	f.Fuzz(func(t *testing.T, response string) {
+		got, diag := extractDecisions(response)
+		if got == nil {
+			t.Fatal("决策列表不能为nil")
+		}
+		if diag != nil && diag.Fallback {
+			if len(got) != 1 || got[0].Action != "wait" || got[0].Symbol != "" {
+				t.Fatalf("降级结果必须是单个wait: %+v", got)
+			}
+			return
+		}
+		if len(got) > 0 && got[0].Symbol == "" {
+			t.Fatalf("解析出的决策缺少symbol: %+v", got)
+		}
+
+		// 修复层的扫描位置必须在输入范围内
+		for i := 0; i < len(response); i++ {
+			if response[i] == '[' {
+				if r := repairJSONArray(response[i:]); r.end <= 0 || r.end > len(response)-i {
+					t.Fatalf("扫描结束位置越界: %d (输入长度 %d)", r.end, len(response)-i)
+				}
+			}
+		}
+	})
+}
+
+var testActions = []string{"open_long", "open_short", "close_long", "close_short", "partial_close", "adjust_sl", "hold", "wait"}
+
+// testReasonings 故意包含括号、引号、注释符号、换行等容易干扰修复层的字符
+var testReasonings = []string{
+	"突破关键阻力位",
+	"区间[3000, 3100]震荡",
+	`it's "quiet", {wait}`,
+	"参考 https://example.com//path /* 不是注释 */",
+	"多行\n理由\t缩进",
+	`反斜杠 \ 和 \n 字面量`,
+	"",
+}
+
+func randomDecisions(rng *rand.Rand) []Decision {
+	n := 1 + rng.Intn(4)
+	decisions := make([]Decision, n)
+	for i := range decisions {
+		d := Decision{
+			Symbol:    []string{"BTCUSDT", "ETHUSDT", "SOLUSDT"}[rng.Intn(3)],
+			Action:    testActions[rng.Intn(len(testActions))],
+			Reasoning: testReasonings[rng.Intn(len(testReasonings))],
+		}
+		if rng.Intn(2) == 0 {
+			d.Leverage = 1 + rng.Intn(20)
+			d.PositionSizeUSD = float64(rng.Intn(100000)) / 10
+			d.StopLoss = float64(rng.Intn(1000000)) / 100
+			d.TakeProfit = float64(rng.Intn(1000000)) / 100
+			d.Confidence = rng.Intn(101)
+		}
+		decisions[i] = d
+	}
+	return decisions
+}
+
+// mangleDecisions 手工序列化决策，随机注入注释、尾随逗号、单引号和原始控制字符
+func mangleDecisions(rng *rand.Rand, decisions []Decision) string {
+	var sb strings.Builder
+	comment := func() {
+		switch rng.Intn(6) {
+		case 0:
+			sb.WriteString(" // 注释 [x], \"y\"\n")
+		case 1:
+			sb.WriteString(" /* 块注释 ] } */ ")
+		}
+	}
+	str := func(s string) string {
+		if rng.Intn(3) == 0 {
+			s = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+			return "'" + s + "'"
+		}
+		quoted, _ := json.Marshal(s)
+		if rng.Intn(3) == 0 && !strings.Contains(s, `\`) {
+			// 保留原始换行和制表符（未转义）
+			return strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(string(quoted))
+		}
+		return string(quoted)
+	}
+
+	sb.WriteString("[")
+	comment()
+	for i, d := range decisions {
+		fields := []string{
+			str("symbol") + ": " + str(d.Symbol),
+			str("action") + ": " + str(d.Action),
+		}
+		if d.Leverage != 0 {
+			fields = append(fields,
+				str("leverage")+": "+strconv.Itoa(d.Leverage),
+				str("position_size_usd")+": "+strconv.FormatFloat(d.PositionSizeUSD, 'f', -1, 64),
+				str("stop_loss")+": "+strconv.FormatFloat(d.StopLoss, 'f', -1, 64),
+				str("take_profit")+": "+strconv.FormatFloat(d.TakeProfit, 'f', -1, 64),
+				str("confidence")+": "+strconv.Itoa(d.Confidence),
+			)
+		}
+		fields = append(fields, str("reasoning")+": "+str(d.Reasoning))
+
+		sb.WriteString("\n  {")
+		for j, field := range fields {
+			comment()
+			sb.WriteString(field)
+			if j < len(fields)-1 || rng.Intn(3) == 0 {
+				sb.WriteString(",")
+			}
+		}
+		comment()
+		sb.WriteString("}")
+		if i < len(decisions)-1 || rng.Intn(3) == 0 {
+			sb.WriteString(",")
+		}
+		comment()
+	}
+	sb.WriteString("\n]")
+	return sb.String()
+}
+
+func ExampleParseDiagnostics_String() {
+	_, diag := extractDecisions(`[{"symbol": "BTCUSDT", "action": "hold",}, {"symbol": "ETH`)
+	fmt.Println(diag)
+	// Output: 修复: trailing_comma,truncated | 丢弃不完整决策: 1
+}
@@ -0,0 +1,72 @@
+package decision
+
+import (
+	"nofx/market"
+	"strings"
+	"testing"
+)
+
+func TestCompactMarketFormat(t *testing.T) {
+	series := func(start, step float64) []float64 {
+		values := make([]float64, 10)
+		for i := range values {
+			values[i] = start + step*float64(i)
+		}
+		return values
+	}
+	data := &market.Data{
+		Symbol:        "ETHUSDT",
+		CurrentPrice:  3009.5,
+		PriceChange1h: 0.42,
+		CurrentEMA20:  3001.234,
+		CurrentMACD:   0.45791,
+		CurrentRSI7:   61.2,
+		OpenInterest:  &market.OIData{Latest: 1234567.8, Average: 1200000},
+		FundingRate:   0.0001,
+		IntradaySeries: &market.IntradayData{
+			MidPrices:   series(3000.5, 1),
+			EMA20Values: series(2995.25, 0.5),
+			MACDValues:  series(-0.5, 0.1),
+			RSI7Values:  series(40, 2.5),
+			RSI14Values: series(45, 1),
+		},
+		LongerTermContext: &market.LongerTermData{
+			EMA20: 2980, EMA50: 2950, ATR3: 35.5, ATR14: 41.25, CurrentVolume: 15234.5, AverageVolume: 14000,
+			MACDValues: series(1, 0.5), RSI14Values: series(50, 1),
+		},
+	}
+	ctx := &Context{
+		CandidateCoins: []CandidateCoin{{Symbol: "ETHUSDT", Sources: []string{"ai500"}}},
+		MarketDataMap:  map[string]*market.Data{"ETHUSDT": data},
+	}
+
+	verbose := buildUserPrompt(ctx)
+	ctx.MarketFormat = market.FormatModeCompact
+	compact := buildUserPrompt(ctx)
+
+	if strings.Contains(verbose, "**行情格式**") || !strings.Contains(verbose, "Mid prices: [3000.500") {
+		t.Fatalf("默认应为逐项描述格式:\n%s", verbose)
+	}
+	for _, want := range []string{
+		"**行情格式**",
+		"px=3009.5 chg1h=+0.42% chg4h=+0.00% ema20=3001.2 macd=0.45791 rsi7=61.2",
+		"oi=1234568 oi_avg=1200000",
+		"    px  ema20 macd rsi7 rsi14",
+		"3009.5 2999.8  0.4 62.5    54", // 最后一行为最新值
+		"4h ema20=2980 ema50=2950 atr3=35.5 atr14=41.25 vol=15234 vol_avg=14000",
+	} {
+		if !strings.Contains(compact, want) {
+			t.Fatalf("紧凑格式缺少 %q:\n%s", want, compact)
+		}
+	}
+
+	// 行情部分的长度至少减少40%
+	verboseMarket, compactMarket := market.Format(data), market.FormatCompact(data)
+	if len(compactMarket)*10 > len(verboseMarket)*6 {
+		t.Fatalf("紧凑格式 %d 字节，逐项格式 %d 字节，应至少减少40%%", len(compactMarket), len(verboseMarket))
+	}
+
+	if _, err := market.ParseFormatMode("table"); err == nil {
+		t.Fatal("未知格式应报错")
+	}
+}
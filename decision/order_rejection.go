@@ -0,0 +1,45 @@
+package decision
+
+// 交易所拒单原因
+// 交易所拒绝订单时（保证金不足、只减仓违规、价格超限、低于最小下单金额等），交易员把各交易所的错误码归类为下面的原因，
+// 写入执行记录，并在下个周期的执行反馈中附上说明和调整建议，让AI据此调整仓位和价格，而不是重复同样的下单。
+
+const (
+	RejectInsufficientMargin = "insufficient_margin" // 保证金不足
+	RejectReduceOnly         = "reduce_only"         // 只减仓单被拒
+	RejectPriceOutOfBounds   = "price_out_of_bounds" // 价格超出交易所允许范围
+	RejectMinNotional        = "min_notional"        // 低于最小下单金额/数量
+	RejectMaxQuantity        = "max_quantity"        // 超过单笔最大数量
+	RejectPrecision          = "precision"           // 价格/数量精度不符
+	RejectWouldTrigger       = "would_trigger"       // 条件单会立即触发
+	RejectPostOnly           = "post_only"           // 只挂单会立即成交
+	RejectRiskLimit          = "risk_limit"          // 超过当前杠杆档位的持仓上限
+	RejectInvalidSymbol      = "invalid_symbol"      // 币种不存在或已下架
+)
+
+// rejectReasonHint 拒单原因的说明和给AI的调整建议
+type rejectReasonHint struct {
+	label  string
+	advice string
+}
+
+var rejectReasonHints = map[string]rejectReasonHint{
+	RejectInsufficientMargin: {"保证金不足", "减小仓位或降低杠杆，或先平掉部分持仓释放保证金"},
+	RejectReduceOnly:         {"只减仓单被拒（持仓不存在或平仓数量超过持仓）", "按当前持仓的方向和数量平仓"},
+	RejectPriceOutOfBounds:   {"价格超出交易所允许的范围", "按当前价格重新设定，不要偏离标记价格过远"},
+	RejectMinNotional:        {"订单金额低于交易所最小下单金额", "增大仓位到最小下单金额以上，或放弃该币种"},
+	RejectMaxQuantity:        {"数量超过交易所单笔上限", "减小仓位"},
+	RejectPrecision:          {"价格或数量精度不符合交易所要求", "按行情中的价格精度给出价格"},
+	RejectWouldTrigger:       {"止损/止盈价会立即触发", "止损止盈价必须在当前价格的正确一侧"},
+	RejectPostOnly:           {"只挂单会立即成交", "改用市价或离当前价更远的限价"},
+	RejectRiskLimit:          {"超过当前杠杆档位允许的最大持仓", "降低杠杆或减小仓位"},
+	RejectInvalidSymbol:      {"币种不可交易（不存在或已下架）", "不要再对该币种下单"},
+}
+
+// RejectReasonLabel 拒单原因的中文说明（未知原因原样返回）
+func RejectReasonLabel(reason string) string {
+	if hint, ok := rejectReasonHints[reason]; ok {
+		return hint.label
+	}
+	return reason
+}
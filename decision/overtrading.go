@@ -0,0 +1,49 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxOvertradingExamples prompt中列出的过度交易实例数（最近的几次）
+const maxOvertradingExamples = 5
+
+// OvertradingFeedback 最近的过度交易行为（写入user prompt的表现部分）
+type OvertradingFeedback struct {
+	Hours            int      // 统计的小时数
+	Trades           int      // 期间执行的交易笔数
+	Reentries        int      // 平仓后很快同方向再开仓的次数
+	Flips            int      // 平仓后很快反方向开仓的次数
+	Bursts           int      // 60分钟内交易笔数超限的时段数
+	MaxTradesInHour  int      // 任意60分钟内的最多交易笔数
+	ReentryMinutes   int      // 快速回补的判定分钟数
+	FlipMinutes      int      // 反手的判定分钟数
+	MaxTradesPerHour int      // 每小时交易笔数上限
+	Examples         []string // 最近的实例
+}
+
+// writeOvertrading 过度交易提醒（没有问题时不输出）
+func writeOvertrading(sb *strings.Builder, ctx *Context) {
+	o := ctx.Overtrading
+	if o == nil {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("## ⚠️ 交易行为提醒（近%d小时 %d 笔交易）\n", o.Hours, o.Trades))
+	if o.Reentries > 0 {
+		sb.WriteString(fmt.Sprintf("- 平仓后%d分钟内同方向再开仓 %d 次：平仓理由如果仍然成立就不要马上回补，理由不成立说明平仓过早\n", o.ReentryMinutes, o.Reentries))
+	}
+	if o.Flips > 0 {
+		sb.WriteString(fmt.Sprintf("- 平仓后%d分钟内反手开仓 %d 次：方向判断摇摆，没有明确信号时观望\n", o.FlipMinutes, o.Flips))
+	}
+	if o.Bursts > 0 {
+		sb.WriteString(fmt.Sprintf("- 60分钟内交易超过 %d 笔的时段 %d 个（最多 %d 笔）：交易过密，手续费和滑点会侵蚀收益\n", o.MaxTradesPerHour, o.Bursts, o.MaxTradesInHour))
+	}
+	examples := o.Examples
+	if len(examples) > maxOvertradingExamples {
+		examples = examples[len(examples)-maxOvertradingExamples:]
+	}
+	if len(examples) > 0 {
+		sb.WriteString("最近的实例: " + strings.Join(examples, "；") + "\n")
+	}
+	sb.WriteString("请只在信号明确时交易，避免频繁进出同一币种\n\n")
+}
@@ -0,0 +1,120 @@
+package decision
+
+import (
+	"fmt"
+	"log"
+	"nofx/market"
+)
+
+// 止损止盈按价格精度取整
+// AI给出的止损止盈常常比合约的最小价格变动（tick size）更精细，交易所挂条件单时会按自己的规则取整甚至直接拒单，
+// 实际触发价和通过验证的价格并不一致。交易器能给出价格精度时，开仓、加仓和调整止损止盈的价格在验证前先取整；
+// 取整后止损止盈与当前价重合，或风险回报比从达标跌到不达标时直接拒绝，而不是带着偏移的触发价下单。
+
+// minRiskReward 开仓决策的最低风险回报比（与 validateDecision 的硬约束一致）
+const minRiskReward = 3.0
+
+// PriceRounder 把价格取整到币种的最小价格变动（由交易器提供）
+type PriceRounder func(symbol string, price float64) (float64, error)
+
+// roundDecisionPrices 将决策的止损止盈取整到价格精度，返回取整说明
+// 取整后风险回报比跌破下限或价格失去意义时返回错误
+func roundDecisionPrices(decisions []Decision, round PriceRounder, marketDataMap map[string]*market.Data) ([]string, error) {
+	if round == nil {
+		return nil, nil
+	}
+
+	var notes []string
+	for i := range decisions {
+		d := &decisions[i]
+		switch d.Action {
+		case "open_long", "open_short", "add_to_position", "adjust_sl", "adjust_tp":
+		default:
+			continue
+		}
+
+		sl, err := roundPrice(round, d.Symbol, d.StopLoss)
+		if err != nil {
+			log.Printf("⚠️ %s 获取价格精度失败，止损止盈按原值验证: %v", d.Symbol, err)
+			continue
+		}
+		tp, err := roundPrice(round, d.Symbol, d.TakeProfit)
+		if err != nil {
+			log.Printf("⚠️ %s 获取价格精度失败，止损止盈按原值验证: %v", d.Symbol, err)
+			continue
+		}
+		if sl == d.StopLoss && tp == d.TakeProfit {
+			continue
+		}
+
+		if d.Action == "open_long" || d.Action == "open_short" {
+			if err := checkRoundedStops(d, sl, tp, marketDataMap); err != nil {
+				return notes, err
+			}
+		}
+
+		note := fmt.Sprintf("止损 %g→%g 止盈 %g→%g", d.StopLoss, sl, d.TakeProfit, tp)
+		log.Printf("📏 %s %s 止损止盈按价格精度取整: %s", d.Symbol, d.Action, note)
+		notes = append(notes, fmt.Sprintf("%s %s: %s", d.Symbol, d.Action, note))
+		d.StopLoss, d.TakeProfit = sl, tp
+	}
+	return notes, nil
+}
+
+// roundPrice 取整单个价格，未填写（0）的价格保持不变
+func roundPrice(round PriceRounder, symbol string, price float64) (float64, error) {
+	if price <= 0 {
+		return price, nil
+	}
+	rounded, err := round(symbol, price)
+	if err != nil {
+		return price, err
+	}
+	if rounded <= 0 {
+		return price, fmt.Errorf("价格 %g 取整后为 %g", price, rounded)
+	}
+	return rounded, nil
+}
+
+// checkRoundedStops 检查开仓决策的止损止盈取整后是否仍然有效
+func checkRoundedStops(d *Decision, sl, tp float64, marketDataMap map[string]*market.Data) error {
+	if sl > 0 && sl == tp {
+		return fmt.Errorf("%s %s 止损止盈按价格精度取整后重合（止损 %g→%g，止盈 %g→%g），价格间距小于最小价格变动",
+			d.Symbol, d.Action, d.StopLoss, sl, d.TakeProfit, tp)
+	}
+
+	data, ok := marketDataMap[d.Symbol]
+	if !ok || data == nil || data.CurrentPrice <= 0 || sl <= 0 || tp <= 0 {
+		return nil // 没有当前价无法计算实际风险回报比，交给后续验证
+	}
+	price := data.CurrentPrice
+	if sl == price || tp == price {
+		return fmt.Errorf("%s %s 止损止盈按价格精度取整后与当前价 %g 重合（止损 %g→%g，止盈 %g→%g）",
+			d.Symbol, d.Action, price, d.StopLoss, sl, d.TakeProfit, tp)
+	}
+
+	before := actualRiskReward(d.Action, price, d.StopLoss, d.TakeProfit)
+	after := actualRiskReward(d.Action, price, sl, tp)
+	if before >= minRiskReward && after < minRiskReward {
+		return fmt.Errorf("%s %s 止损止盈按价格精度取整后风险回报比从 %.2f:1 降至 %.2f:1，低于%.1f:1（止损 %g→%g，止盈 %g→%g，当前价 %g）",
+			d.Symbol, d.Action, before, after, minRiskReward, d.StopLoss, sl, d.TakeProfit, tp, price)
+	}
+	return nil
+}
+
+// RiskRewardAt 以指定价格入场时开仓决策的风险回报比（价格在错误一侧时为0），用于按实际成交价复核
+func RiskRewardAt(action string, price, sl, tp float64) float64 {
+	return actualRiskReward(action, price, sl, tp)
+}
+
+// actualRiskReward 以当前价入场的风险回报比（价格在错误一侧时为0）
+func actualRiskReward(action string, price, sl, tp float64) float64 {
+	risk, reward := price-sl, tp-price
+	if action == "open_short" {
+		risk, reward = sl-price, price-tp
+	}
+	if risk <= 0 || reward <= 0 {
+		return 0
+	}
+	return reward / risk
+}
@@ -0,0 +1,103 @@
+package decision
+
+import (
+	"errors"
+	"math"
+	"nofx/market"
+	"strings"
+	"testing"
+)
+
+// tickRounder 按固定tick size取整的价格精度
+func tickRounder(tick float64) PriceRounder {
+	return func(symbol string, price float64) (float64, error) {
+		return math.Round(price/tick) * tick, nil
+	}
+}
+
+func TestRoundDecisionPrices(t *testing.T) {
+	data := map[string]*market.Data{"SOLUSDT": {Symbol: "SOLUSDT", CurrentPrice: 100}}
+
+	tests := []struct {
+		name           string
+		decision       Decision
+		wantSL, wantTP float64
+		wantErr        string
+	}{
+		{
+			name:     "取整后仍达标",
+			decision: Decision{Symbol: "SOLUSDT", Action: "open_long", StopLoss: 95.2, TakeProfit: 118.7},
+			wantSL:   95, wantTP: 119,
+		},
+		{
+			name:     "取整后风险回报比跌破下限",
+			decision: Decision{Symbol: "SOLUSDT", Action: "open_long", StopLoss: 99.4, TakeProfit: 101.9},
+			wantErr:  "风险回报比从 3.17:1 降至 2.00:1",
+		},
+		{
+			name:     "止损取整到当前价",
+			decision: Decision{Symbol: "SOLUSDT", Action: "open_short", StopLoss: 100.3, TakeProfit: 97.6},
+			wantErr:  "与当前价 100 重合",
+		},
+		{
+			name:     "止损止盈取整后重合",
+			decision: Decision{Symbol: "ETHUSDT", Action: "open_long", StopLoss: 3000.2, TakeProfit: 3000.4},
+			wantErr:  "取整后重合",
+		},
+		{
+			name:     "调整止损只取整",
+			decision: Decision{Symbol: "SOLUSDT", Action: "adjust_sl", StopLoss: 99.6},
+			wantSL:   100,
+		},
+		{
+			name:     "平仓不处理",
+			decision: Decision{Symbol: "SOLUSDT", Action: "close_long", StopLoss: 99.6},
+			wantSL:   99.6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decisions := []Decision{tt.decision}
+			_, err := roundDecisionPrices(decisions, tickRounder(1), data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 = %v, 期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("不应报错: %v", err)
+			}
+			if d := decisions[0]; d.StopLoss != tt.wantSL || d.TakeProfit != tt.wantTP {
+				t.Errorf("止损/止盈 = %v/%v, 期望 %v/%v", d.StopLoss, d.TakeProfit, tt.wantSL, tt.wantTP)
+			}
+		})
+	}
+}
+
+func TestRoundDecisionPricesBeforeValidation(t *testing.T) {
+	data := map[string]*market.Data{"SOLUSDT": {Symbol: "SOLUSDT", CurrentPrice: 100}}
+	response := `[{"symbol": "SOLUSDT", "action": "open_long", "leverage": 3, "position_size_usd": 500, "stop_loss": 99.4, "take_profit": 101.9, "confidence": 85, "reasoning": "突破"}]`
+
+	if _, err := parseFullDecisionResponse(response, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, nil); err != nil {
+		t.Fatalf("不取整时应通过验证: %v", err)
+	}
+	if _, err := parseFullDecisionResponse(response, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, tickRounder(1)); err == nil {
+		t.Fatal("取整后风险回报比不达标应拒绝")
+	}
+
+	full, err := parseFullDecisionResponse(response, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, tickRounder(0.5))
+	if err != nil {
+		t.Fatalf("0.5取整后仍达标: %v", err)
+	}
+	if d := full.Decisions[0]; d.StopLoss != 99.5 || d.TakeProfit != 102 || len(full.PriceRounding) != 1 {
+		t.Errorf("决策 = %+v, 取整说明 = %v", d, full.PriceRounding)
+	}
+
+	// 获取精度失败时按原价格验证
+	failing := func(string, float64) (float64, error) { return 0, errors.New("down") }
+	if _, err := parseFullDecisionResponse(response, 10000, 10, 5, 0, 0, nil, nil, AutoStopConfig{}, data, failing); err != nil {
+		t.Fatalf("获取精度失败不应拒绝: %v", err)
+	}
+}
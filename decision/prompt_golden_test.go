@@ -0,0 +1,238 @@
+package decision
+
+import (
+	"flag"
+	"fmt"
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Prompt回归测试
+// 用固定的交易上下文渲染系统提示词和用户提示词，与 testdata/prompts/<名称>.system.txt / .user.txt 比较。
+// 措辞、约束数值或格式的任何变化都会失败并输出逐行差异，避免重构时无意中改动prompt。
+// 有意修改prompt后运行 go test ./decision -run TestPromptGolden -update-prompts 重新生成，
+// 并在提交中检查 testdata/prompts 的差异。
+
+var updatePrompts = flag.Bool("update-prompts", false, "按当前的prompt构建结果重写 testdata/prompts")
+
+const promptGoldenDir = "testdata/prompts"
+
+// promptFixture 一组用于比较的系统提示词和用户提示词
+type promptFixture struct {
+	name   string
+	system func() string
+	ctx    func() *Context
+}
+
+func TestPromptGolden(t *testing.T) {
+	fixtures := []promptFixture{
+		{
+			name:   "flat_account",
+			system: func() string { return buildSystemPrompt(1000, 10, 5, 0, 0, nil) },
+			ctx:    flatAccountContext,
+		},
+		{
+			name: "positions_template",
+			system: func() string {
+				return buildSystemPromptWithTemplate("# 策略\n\n只做趋势延续，逆势信号一律观望。", 2500, 5, 3, 20, 500,
+					SymbolLimits{"DOGEUSDT": {MaxLeverage: 2, MaxPositionSizeUSD: 200}})
+			},
+			ctx: positionsContext,
+		},
+		{
+			name:   "positions_compact",
+			system: func() string { return buildSystemPrompt(2500, 5, 3, 20, 500, nil) },
+			ctx: func() *Context {
+				ctx := positionsContext()
+				ctx.MarketFormat = market.FormatModeCompact
+				return ctx
+			},
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.name, func(t *testing.T) {
+			checkGolden(t, f.name+".system.txt", f.system())
+			checkGolden(t, f.name+".user.txt", buildUserPrompt(f.ctx()))
+		})
+	}
+}
+
+// checkGolden 与golden文件比较（-update-prompts 时重写golden文件）
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join(promptGoldenDir, name)
+	if *updatePrompts {
+		if err := os.MkdirAll(promptGoldenDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 %s 失败（新增fixture后用 -update-prompts 生成）: %v", path, err)
+	}
+	if string(want) != got {
+		t.Errorf("prompt与 %s 不一致（有意修改时运行 go test ./decision -run TestPromptGolden -update-prompts）:\n%s",
+			path, lineDiff(string(want), got))
+	}
+}
+
+// lineDiff 逐行差异（- golden文件，+ 当前输出），变化前后各保留2行上下文
+func lineDiff(want, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+
+	// 最长公共子序列，lcs[i][j] 对应 a[i:] 和 b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte // ' ' 相同, '-' 仅golden文件, '+' 仅当前输出
+		num  int  // 行号（- 和相同行为golden文件中的行号，+ 为当前输出中的行号）
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', i + 1, a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', i + 1, a[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', j + 1, b[j]})
+			j++
+		}
+	}
+
+	const context = 2
+	var sb strings.Builder
+	lastShown := -1
+	for k, l := range lines {
+		near := false
+		for d := -context; d <= context && !near; d++ {
+			if n := k + d; n >= 0 && n < len(lines) && lines[n].op != ' ' {
+				near = true
+			}
+		}
+		if !near {
+			continue
+		}
+		if lastShown >= 0 && k > lastShown+1 {
+			sb.WriteString("   ...\n")
+		}
+		sb.WriteString(fmt.Sprintf("%c %4d | %s\n", l.op, l.num, l.text))
+		lastShown = k
+	}
+	return sb.String()
+}
+
+// fixtureMarketData 固定的行情数据（日内序列逐步上涨）
+func fixtureMarketData(symbol string, price float64) *market.Data {
+	series := func(start, step float64) []float64 {
+		values := make([]float64, 10)
+		for i := range values {
+			values[i] = start + step*float64(i)
+		}
+		return values
+	}
+	step := price * 0.001
+	return &market.Data{
+		Symbol:        symbol,
+		CurrentPrice:  price,
+		PriceChange1h: 0.42,
+		PriceChange4h: -1.15,
+		CurrentEMA20:  price * 0.998,
+		CurrentMACD:   step * 0.5,
+		CurrentRSI7:   61.2,
+		OpenInterest:  &market.OIData{Latest: 1234567.8, Average: 1200000},
+		FundingRate:   0.0001,
+		IntradaySeries: &market.IntradayData{
+			MidPrices:   series(price-9*step, step),
+			EMA20Values: series(price-12*step, step*0.8),
+			MACDValues:  series(-step, step*0.2),
+			RSI7Values:  series(40, 2.5),
+			RSI14Values: series(45, 1),
+		},
+		LongerTermContext: &market.LongerTermData{
+			EMA20: price * 0.99, EMA50: price * 0.97, ATR3: price * 0.012, ATR14: price * 0.015,
+			CurrentVolume: 15234.5, AverageVolume: 14000,
+			MACDValues: series(step, step*0.5), RSI14Values: series(50, 1),
+		},
+	}
+}
+
+// flatAccountContext 空仓，两个来自币种池的候选币种
+func flatAccountContext() *Context {
+	return &Context{
+		CurrentTime:    "2026-01-02 03:04:05",
+		RuntimeMinutes: 42,
+		CallCount:      7,
+		Account: AccountInfo{
+			TotalEquity: 1000, AvailableBalance: 1000, TotalPnLPct: 0, MarginUsedPct: 0, PositionCount: 0,
+		},
+		CandidateCoins: []CandidateCoin{
+			{Symbol: "BTCUSDT", Sources: []string{"ai500", "oi_top"}},
+			{Symbol: "SOLUSDT", Sources: []string{"oi_top"}},
+		},
+		MarketDataMap: map[string]*market.Data{
+			"BTCUSDT": fixtureMarketData("BTCUSDT", 60000),
+			"SOLUSDT": fixtureMarketData("SOLUSDT", 150),
+		},
+	}
+}
+
+// positionsContext 一个带计划的持仓、风控限制和不同来源的候选币种
+func positionsContext() *Context {
+	now := time.Now()
+	return &Context{
+		CurrentTime:    "2026-01-02 03:04:05",
+		RuntimeMinutes: 180,
+		CallCount:      61,
+		Account: AccountInfo{
+			TotalEquity: 2500, AvailableBalance: 1800, TotalPnLPct: 3.5, MarginUsedPct: 28, PositionCount: 1,
+		},
+		RiskNotice: "日内亏损 3.20% 已超过 3.0%，单仓位上限缩减至 250 USDT",
+		Positions: []PositionInfo{{
+			PositionID: "pos-1", Symbol: "ETHUSDT", Side: "long",
+			EntryPrice: 2950, MarkPrice: 3000, Quantity: 0.5, Leverage: 3,
+			UnrealizedPnL: 25, UnrealizedPnLPct: 5.08, LiquidationPrice: 2000, MarginUsed: 500,
+			UpdateTime: now.Add(-95 * time.Minute).UnixMilli(),
+			StopLoss:   2900, TakeProfit: 3200, CumulativeFunding: -1.25,
+			Plan: &PositionPlan{Text: "持有至4h收盘站上3050再上移止损", Action: "open_long", UpdatedAt: now.Add(-30 * time.Minute)},
+		}},
+		CandidateCoins: []CandidateCoin{
+			{Symbol: "DOGEUSDT", Sources: []string{"anomaly"}},
+			{Symbol: "SOLUSDT", Sources: []string{"ai500"}},
+		},
+		MarketDataMap: map[string]*market.Data{
+			"ETHUSDT":  fixtureMarketData("ETHUSDT", 3000),
+			"DOGEUSDT": fixtureMarketData("DOGEUSDT", 0.2),
+			"SOLUSDT":  fixtureMarketData("SOLUSDT", 150),
+		},
+		Surges: map[string][]market.SurgeAlert{
+			"DOGEUSDT": {{Symbol: "DOGEUSDT", Window: "15m", Metric: "volume", Z: 4.2, Change: 3.1}},
+		},
+	}
+}
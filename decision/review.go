@@ -0,0 +1,221 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/mcp"
+	"nofx/tracing"
+	"strings"
+)
+
+// 二次复核（self-critique）
+// 第一轮决策解析完成后，由复核模型（可以是更便宜的模型，也可以是同一模型的第二次调用）
+// 对照同一份上下文和风控规则检查会增加风险的决策（开仓/加仓），逐条给出：
+//   approve   - 通过，照常执行
+//   veto      - 否决，直接丢弃该决策
+//   downgrade - 降级为 wait，保留复核理由
+// 复核调用失败或输出无法解析时不拦截（fail-open），错误记录在 ReviewResult.Error 中
+
+// 复核结论
+const (
+	VerdictApprove   = "approve"
+	VerdictVeto      = "veto"
+	VerdictDowngrade = "downgrade"
+)
+
+// ReviewVerdict 复核模型对单条决策的结论
+type ReviewVerdict struct {
+	Symbol  string `json:"symbol"`
+	Action  string `json:"action"`
+	Verdict string `json:"verdict"` // approve / veto / downgrade
+	Reason  string `json:"reason"`
+}
+
+// ReviewResult 一次复核的结果
+type ReviewResult struct {
+	Model    string          `json:"model"`
+	CoTTrace string          `json:"cot_trace"` // 复核模型的分析
+	Verdicts []ReviewVerdict `json:"verdicts"`
+	Error    string          `json:"error,omitempty"` // 复核失败原因（此时第一轮决策原样执行）
+}
+
+// reviewableActions 需要复核的动作（会增加风险敞口）
+var reviewableActions = map[string]bool{
+	"open_long":       true,
+	"open_short":      true,
+	"add_to_position": true,
+}
+
+// ReviewDecisions 用复核模型检查第一轮决策，并就地移除被否决的决策、把被降级的决策改为wait
+// 没有需要复核的决策时不调用AI，返回nil
+func ReviewDecisions(ctx *Context, full *FullDecision, client *mcp.Client) *ReviewResult {
+	var proposed []Decision
+	for _, d := range full.Decisions {
+		if reviewableActions[d.Action] {
+			proposed = append(proposed, d)
+		}
+	}
+	if len(proposed) == 0 {
+		return nil
+	}
+
+	traceCtx, span := tracing.Start(ctx.Trace, "decision.review")
+	defer span.End()
+	span.SetAttr("ai.model", client.Model)
+	span.SetAttr("decisions", len(proposed))
+
+	result := &ReviewResult{Model: client.Model}
+	proposedJSON, _ := json.MarshalIndent(proposed, "", "  ")
+	response, err := client.CallWithMessagesContext(traceCtx, buildReviewSystemPrompt(ctx), buildReviewUserPrompt(full.UserPrompt, string(proposedJSON)))
+	if err != nil {
+		span.RecordError(err)
+		result.Error = fmt.Sprintf("调用复核AI失败: %v", err)
+		return result
+	}
+
+	result.CoTTrace = extractCoTTrace(response)
+	verdicts, err := parseReviewVerdicts(response)
+	if err != nil {
+		span.RecordError(err)
+		result.Error = err.Error()
+		return result
+	}
+	result.Verdicts = verdicts
+	full.Decisions = applyReviewVerdicts(full.Decisions, verdicts)
+	return result
+}
+
+// buildReviewSystemPrompt 复核模型的系统提示：风控规则 + 输出格式
+func buildReviewSystemPrompt(ctx *Context) string {
+	var sb strings.Builder
+	sb.WriteString("你是加密货币合约交易的风控复核员。另一位交易员已经基于下面的市场数据给出了开仓/加仓决策，")
+	sb.WriteString("你的任务不是重新做交易，而是逐条检查这些决策是否违反风控规则、是否与数据矛盾。\n\n")
+
+	sb.WriteString("# 风控规则\n\n")
+	sb.WriteString("1. 风险回报比必须 ≥ 1:3，止损止盈方向必须正确（做多: 止损<入场<止盈；做空相反）\n")
+	sb.WriteString(fmt.Sprintf("2. 杠杆上限: BTC/ETH %dx，山寨币 %dx\n", ctx.BTCETHLeverage, ctx.AltcoinLeverage))
+	if lines := symbolLimitLines(ctx.SymbolLimits); len(lines) > 0 {
+		sb.WriteString("   币种单独限制（优先）: " + strings.Join(lines, "；") + "\n")
+	}
+	if ctx.MaxPositionSizeUSD > 0 {
+		sb.WriteString(fmt.Sprintf("3. 单仓位名义价值不超过 %.0f USDT\n", ctx.MaxPositionSizeUSD))
+	} else {
+		sb.WriteString(fmt.Sprintf("3. 单仓位名义价值不超过账户净值的合理倍数（当前净值 %.2f USDT）\n", ctx.Account.TotalEquity))
+	}
+	sb.WriteString("4. 保证金使用率不超过90%，不对已有同向持仓的币种重复开仓\n")
+	sb.WriteString("5. 决策理由必须能被给出的数据支持；理由与数据明显矛盾、追涨杀跌或信心度偏低的决策应拦截\n")
+	if ctx.RiskNotice != "" {
+		sb.WriteString(fmt.Sprintf("6. 当前风控限制: %s\n", ctx.RiskNotice))
+	}
+
+	sb.WriteString("\n# 结论\n\n")
+	sb.WriteString("- approve: 符合规则，可以执行\n")
+	sb.WriteString("- veto: 违反硬性规则（风险回报比、杠杆、仓位、止损方向等），直接否决\n")
+	sb.WriteString("- downgrade: 规则上允许但论据不足或时机不佳，降级为观望\n\n")
+
+	sb.WriteString("# 输出格式\n\n")
+	sb.WriteString("1. 简短的复核分析\n")
+	sb.WriteString("2. JSON数组，每条决策一个对象:\n")
+	sb.WriteString("```json\n")
+	sb.WriteString("[\n")
+	sb.WriteString("  {\"symbol\": \"BTCUSDT\", \"action\": \"open_long\", \"verdict\": \"approve\", \"reason\": \"...\"}\n")
+	sb.WriteString("]\n")
+	sb.WriteString("```\n")
+	return sb.String()
+}
+
+// buildReviewUserPrompt 复核模型的用户提示：第一轮的完整输入 + 待复核决策
+func buildReviewUserPrompt(userPrompt, proposedJSON string) string {
+	var sb strings.Builder
+	sb.WriteString("# 交易员看到的市场数据\n\n")
+	sb.WriteString(userPrompt)
+	sb.WriteString("\n\n---\n\n")
+	sb.WriteString("# 待复核的决策\n\n")
+	sb.WriteString("```json\n")
+	sb.WriteString(proposedJSON)
+	sb.WriteString("\n```\n\n")
+	sb.WriteString("请逐条复核并输出JSON数组。\n")
+	return sb.String()
+}
+
+// parseReviewVerdicts 从复核响应中提取结论数组
+func parseReviewVerdicts(response string) ([]ReviewVerdict, error) {
+	response = fixMissingQuotes(response)
+	searchStart := 0
+	for {
+		arrayStart := strings.Index(response[searchStart:], "[")
+		if arrayStart == -1 {
+			break
+		}
+		arrayStart += searchStart
+
+		repaired := repairJSONArray(response[arrayStart:])
+		var verdicts []ReviewVerdict
+		if err := json.Unmarshal([]byte(repaired.json), &verdicts); err == nil && validVerdicts(verdicts) {
+			return verdicts, nil
+		}
+		searchStart = arrayStart + 1
+	}
+	return nil, fmt.Errorf("复核响应中没有有效的结论JSON")
+}
+
+// validVerdicts 结论数组中每一项都必须指明币种和合法结论
+func validVerdicts(verdicts []ReviewVerdict) bool {
+	for i := range verdicts {
+		v := &verdicts[i]
+		v.Verdict = strings.ToLower(strings.TrimSpace(v.Verdict))
+		if v.Symbol == "" {
+			return false
+		}
+		switch v.Verdict {
+		case VerdictApprove, VerdictVeto, VerdictDowngrade:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// applyReviewVerdicts 按结论处理决策：veto 丢弃，downgrade 改为wait；没有对应结论的决策照常执行
+func applyReviewVerdicts(decisions []Decision, verdicts []ReviewVerdict) []Decision {
+	result := make([]Decision, 0, len(decisions))
+	for _, d := range decisions {
+		v := findVerdict(verdicts, d)
+		if v == nil || !reviewableActions[d.Action] {
+			result = append(result, d)
+			continue
+		}
+		switch v.Verdict {
+		case VerdictVeto:
+			continue
+		case VerdictDowngrade:
+			result = append(result, Decision{
+				SchemaVersion: d.SchemaVersion,
+				Symbol:        d.Symbol,
+				Action:        "wait",
+				Reasoning:     fmt.Sprintf("复核降级（原 %s）: %s", d.Action, v.Reason),
+			})
+		default:
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// findVerdict 查找决策对应的结论（优先匹配币种+动作，复核模型省略动作时按币种匹配）
+func findVerdict(verdicts []ReviewVerdict, d Decision) *ReviewVerdict {
+	var symbolMatch *ReviewVerdict
+	for i := range verdicts {
+		v := &verdicts[i]
+		if v.Symbol != d.Symbol {
+			continue
+		}
+		if v.Action == d.Action {
+			return v
+		}
+		if v.Action == "" && symbolMatch == nil {
+			symbolMatch = v
+		}
+	}
+	return symbolMatch
+}
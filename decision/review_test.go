@@ -0,0 +1,50 @@
+package decision
+
+import "testing"
+
+func TestParseReviewVerdicts(t *testing.T) {
+	response := `ETH 的止损距离过近，风险回报比不足 [1:2]。
+
+[{"symbol": "ETHUSDT", "action": "open_long", "verdict": "Veto", "reason": "风险回报比不足"},
+ {"symbol": "SOLUSDT", "verdict": "downgrade", "reason": "量能不足"},]`
+
+	verdicts, err := parseReviewVerdicts(response)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(verdicts) != 2 || verdicts[0].Verdict != VerdictVeto || verdicts[1].Verdict != VerdictDowngrade {
+		t.Fatalf("解析结果不符合预期: %+v", verdicts)
+	}
+
+	if _, err := parseReviewVerdicts(`[{"symbol": "ETHUSDT", "verdict": "maybe"}]`); err == nil {
+		t.Fatal("非法结论应解析失败")
+	}
+}
+
+func TestApplyReviewVerdicts(t *testing.T) {
+	decisions := []Decision{
+		{Symbol: "BTCUSDT", Action: "close_long"},
+		{Symbol: "ETHUSDT", Action: "open_long", PositionSizeUSD: 1000},
+		{Symbol: "SOLUSDT", Action: "open_short", PositionSizeUSD: 500},
+		{Symbol: "BNBUSDT", Action: "add_to_position", PositionSizeUSD: 300},
+	}
+	verdicts := []ReviewVerdict{
+		{Symbol: "BTCUSDT", Action: "close_long", Verdict: VerdictVeto}, // 平仓不受复核影响
+		{Symbol: "ETHUSDT", Action: "open_long", Verdict: VerdictVeto, Reason: "风险回报比不足"},
+		{Symbol: "SOLUSDT", Verdict: VerdictDowngrade, Reason: "量能不足"},
+	}
+
+	got := applyReviewVerdicts(decisions, verdicts)
+	if len(got) != 3 {
+		t.Fatalf("决策数量 = %d, 期望 3: %+v", len(got), got)
+	}
+	if got[0].Action != "close_long" {
+		t.Errorf("平仓决策应保留: %+v", got[0])
+	}
+	if got[1].Symbol != "SOLUSDT" || got[1].Action != "wait" || got[1].Reasoning != "复核降级（原 open_short）: 量能不足" {
+		t.Errorf("降级结果不符合预期: %+v", got[1])
+	}
+	if got[2].Symbol != "BNBUSDT" || got[2].Action != "add_to_position" {
+		t.Errorf("没有结论的决策应照常执行: %+v", got[2])
+	}
+}
@@ -0,0 +1,104 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/market"
+	"sort"
+	"strings"
+)
+
+// 币种单独的杠杆和仓位限制
+// 全局杠杆只区分BTC/ETH和山寨币，symbol_limits 可以为单个币种设置杠杆上限和最小/最大仓位（如 DOGE 最高3倍），
+// 决策验证时强制执行，并写入系统提示词的硬约束。未设置的项（0）沿用全局限制；
+// USDT交易对的限制对该币种的其他计价币种同样生效（与黑白名单一致）。
+
+// SymbolLimit 单个币种的限制（0表示沿用全局限制）
+type SymbolLimit struct {
+	MaxLeverage        int     // 杠杆上限（覆盖BTC/ETH或山寨币的全局上限）
+	MinPositionSizeUSD float64 // 最小仓位（USD）
+	MaxPositionSizeUSD float64 // 最大仓位（USD）
+}
+
+// SymbolLimits 币种 -> 限制（nil表示没有单独限制）
+type SymbolLimits map[string]SymbolLimit
+
+// lookup 币种的限制（先按交易对匹配，再匹配同币种的USDT交易对）
+func (l SymbolLimits) lookup(symbol string) (SymbolLimit, bool) {
+	if len(l) == 0 {
+		return SymbolLimit{}, false
+	}
+	symbol = strings.ToUpper(symbol)
+	if limit, ok := l[symbol]; ok {
+		return limit, true
+	}
+	limit, ok := l[market.WithQuote(symbol, market.DefaultQuote)]
+	return limit, ok
+}
+
+// MaxLeverage 币种的杠杆上限（未单独设置时按BTC/ETH或山寨币的全局上限）
+func (l SymbolLimits) MaxLeverage(symbol string, btcEthLeverage, altcoinLeverage int) int {
+	if limit, ok := l.lookup(symbol); ok && limit.MaxLeverage > 0 {
+		return limit.MaxLeverage
+	}
+	if market.IsBTCOrETH(symbol) {
+		return btcEthLeverage
+	}
+	return altcoinLeverage
+}
+
+// PositionSizeRange 币种的最小/最大仓位（未单独设置的项沿用全局限制，0表示不限制）
+func (l SymbolLimits) PositionSizeRange(symbol string, minPositionSizeUSD, maxPositionSizeUSD float64) (float64, float64) {
+	limit, ok := l.lookup(symbol)
+	if !ok {
+		return minPositionSizeUSD, maxPositionSizeUSD
+	}
+	if limit.MinPositionSizeUSD > 0 {
+		minPositionSizeUSD = limit.MinPositionSizeUSD
+	}
+	if limit.MaxPositionSizeUSD > 0 {
+		maxPositionSizeUSD = limit.MaxPositionSizeUSD
+	}
+	return minPositionSizeUSD, maxPositionSizeUSD
+}
+
+// writeSymbolLimits 系统提示词硬约束中的币种单独限制（没有时不输出）
+func writeSymbolLimits(sb *strings.Builder, limits SymbolLimits) {
+	lines := symbolLimitLines(limits)
+	if len(lines) == 0 {
+		return
+	}
+	sb.WriteString("5. 币种单独限制（优先于上面的通用限制，超出将被系统拒绝）:\n")
+	for _, line := range lines {
+		sb.WriteString("   - " + line + "\n")
+	}
+}
+
+// symbolLimitLines 每个币种一行限制说明（按币种排序）
+func symbolLimitLines(limits SymbolLimits) []string {
+	symbols := make([]string, 0, len(limits))
+	for symbol := range limits {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	var lines []string
+	for _, symbol := range symbols {
+		limit := limits[symbol]
+		var parts []string
+		if limit.MaxLeverage > 0 {
+			parts = append(parts, fmt.Sprintf("杠杆最高%dx", limit.MaxLeverage))
+		}
+		switch {
+		case limit.MinPositionSizeUSD > 0 && limit.MaxPositionSizeUSD > 0:
+			parts = append(parts, fmt.Sprintf("仓位 %.0f-%.0f USDT", limit.MinPositionSizeUSD, limit.MaxPositionSizeUSD))
+		case limit.MaxPositionSizeUSD > 0:
+			parts = append(parts, fmt.Sprintf("仓位不超过 %.0f USDT", limit.MaxPositionSizeUSD))
+		case limit.MinPositionSizeUSD > 0:
+			parts = append(parts, fmt.Sprintf("仓位不低于 %.0f USDT", limit.MinPositionSizeUSD))
+		}
+		if len(parts) > 0 {
+			lines = append(lines, symbol+": "+strings.Join(parts, "，"))
+		}
+	}
+	return lines
+}
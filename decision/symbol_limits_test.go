@@ -0,0 +1,71 @@
+package decision
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSymbolLimitsValidation(t *testing.T) {
+	limits := SymbolLimits{
+		"DOGEUSDT": {MaxLeverage: 3, MaxPositionSizeUSD: 200},
+		"SOLUSDT":  {MinPositionSizeUSD: 50},
+	}
+	open := func(symbol string, leverage int, size float64) Decision {
+		return Decision{Symbol: symbol, Action: "open_long", Leverage: leverage, PositionSizeUSD: size,
+			StopLoss: 90, TakeProfit: 140, Reasoning: "测试"}
+	}
+
+	tests := []struct {
+		name     string
+		decision Decision
+		wantErr  string
+	}{
+		{name: "币种上限内", decision: open("DOGEUSDT", 3, 150)},
+		{name: "超过币种杠杆上限", decision: open("DOGEUSDT", 5, 150), wantErr: "杠杆必须在1-3之间"},
+		{name: "USDT交易对的限制对其他计价币种生效", decision: open("DOGEUSDC", 4, 150), wantErr: "杠杆必须在1-3之间"},
+		{name: "超过币种最大仓位", decision: open("DOGEUSDT", 2, 300), wantErr: "超过最大限制 200.00 USDT"},
+		{name: "低于币种最小仓位", decision: open("SOLUSDT", 5, 30), wantErr: "低于最小限制 50.00 USDT"},
+		{name: "未设置的项沿用全局杠杆", decision: open("SOLUSDT", 10, 80), wantErr: "杠杆必须在1-5之间"},
+		{name: "其他币种不受影响", decision: open("XRPUSDT", 5, 300)},
+		{
+			name:     "加仓同样按币种杠杆上限",
+			decision: Decision{Symbol: "DOGEUSDT", Action: "add_to_position", Leverage: 4, PositionSizeUSD: 100},
+			wantErr:  "杠杆必须在1-3之间",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDecision(&tt.decision, 1000, 10, 5, 0, 1000, nil, limits)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("不应拒绝: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("错误应包含 %q，实际 %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSymbolLimitsInSystemPrompt(t *testing.T) {
+	limits := SymbolLimits{
+		"SOLUSDT":  {MaxLeverage: 5},
+		"DOGEUSDT": {MaxLeverage: 3, MinPositionSizeUSD: 20, MaxPositionSizeUSD: 200},
+	}
+	for name, prompt := range map[string]string{
+		"内置": buildSystemPrompt(1000, 10, 5, 0, 0, limits),
+		"模板": buildSystemPromptWithTemplate("模板内容", 1000, 10, 5, 0, 0, limits),
+	} {
+		want := "5. 币种单独限制（优先于上面的通用限制，超出将被系统拒绝）:\n" +
+			"   - DOGEUSDT: 杠杆最高3x，仓位 20-200 USDT\n" +
+			"   - SOLUSDT: 杠杆最高5x\n"
+		if !strings.Contains(prompt, want) {
+			t.Errorf("%s系统提示词缺少币种单独限制:\n%s", name, prompt)
+		}
+	}
+	if strings.Contains(buildSystemPrompt(1000, 10, 5, 0, 0, nil), "币种单独限制") {
+		t.Error("没有单独限制时不应输出该约束")
+	}
+}
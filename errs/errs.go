@@ -0,0 +1,171 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 统一错误分类
+// 各交易所/数据源的错误格式各不相同（HTTP状态码、数字错误码、字符串label、SDK错误类型），
+// 在源头（trader/provider 的请求层）归类为下面几种类型，调用方通过 errors.Is / errors.As 判断，
+// 按类型做出反应（限频退避、认证失败停止交易、网络错误重试），不再匹配错误字符串。
+
+var (
+	ErrRateLimited        = errors.New("请求被限频")
+	ErrInsufficientMargin = errors.New("保证金不足")
+	ErrInvalidSymbol      = errors.New("无效币种")
+	ErrOrderRejected      = errors.New("订单被拒绝")
+	ErrNetwork            = errors.New("网络错误")
+	ErrAuth               = errors.New("认证失败")
+)
+
+// kinds 所有错误类型（KindOf 按此顺序匹配）
+var kinds = []error{ErrAuth, ErrRateLimited, ErrInsufficientMargin, ErrInvalidSymbol, ErrOrderRejected, ErrNetwork}
+
+// Error 交易所/数据源返回的已归类错误
+type Error struct {
+	Kind       error         // 错误类型（上面的 Err* 之一，无法归类时为nil）
+	Source     string        // 交易所/数据源名称
+	Status     int           // HTTP状态码（非HTTP错误时为0）
+	Code       string        // 交易所原始错误码（数字码或label）
+	Message    string        // 交易所返回的错误信息
+	RetryAfter time.Duration // 建议的重试等待时间（限频时由 Retry-After 响应头给出，未知为0）
+	Err        error         // 底层错误
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Source)
+	if e.Kind != nil {
+		b.WriteString(" [" + e.Kind.Error() + "]")
+	}
+	if e.Status != 0 {
+		fmt.Fprintf(&b, " HTTP %d", e.Status)
+	}
+	if e.Code != "" {
+		b.WriteString(" code=" + e.Code)
+	}
+	switch {
+	case e.Message != "":
+		b.WriteString(": " + e.Message)
+	case e.Err != nil:
+		b.WriteString(": " + e.Err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap 同时暴露错误类型和底层错误，errors.Is 对两者都生效
+func (e *Error) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// New 创建已归类的错误
+func New(kind error, source, code, message string) *Error {
+	return &Error{Kind: kind, Source: source, Code: code, Message: message}
+}
+
+// Network 把请求发送/读取阶段的错误归类为网络错误（已归类的错误原样返回）
+func Network(source string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Kind: ErrNetwork, Source: source, Err: err}
+}
+
+// Classify 识别底层的网络错误（超时、连接重置、EOF），其他错误原样返回
+func Classify(source string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	if isTransport(err) {
+		return &Error{Kind: ErrNetwork, Source: source, Err: err}
+	}
+	return err
+}
+
+// isTransport 是否为传输层错误
+func isTransport(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// FromResponse 按HTTP状态码归类非2xx响应，交易所错误码由调用方进一步细化 Kind
+func FromResponse(source string, resp *http.Response, body []byte) *Error {
+	e := &Error{
+		Kind:    StatusKind(resp.StatusCode),
+		Source:  source,
+		Status:  resp.StatusCode,
+		Message: strings.TrimSpace(string(body)),
+	}
+	if e.Kind == ErrRateLimited {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			e.RetryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+	return e
+}
+
+// StatusKind HTTP状态码对应的错误类型（无法判断时返回nil）
+func StatusKind(status int) error {
+	switch status {
+	case http.StatusTooManyRequests, 418: // 418: 币安持续超限后封禁IP
+		return ErrRateLimited
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuth
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrNetwork
+	}
+	return nil
+}
+
+// KindOf 错误所属的类型（未归类时返回nil）
+func KindOf(err error) error {
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// Retryable 是否值得稍后重试（网络错误、限频）
+func Retryable(err error) bool {
+	return errors.Is(err, ErrNetwork) || errors.Is(err, ErrRateLimited)
+}
+
+// RetryAfter 错误建议的重试等待时间（未知时返回0）
+func RetryAfter(err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RetryAfter
+	}
+	return 0
+}
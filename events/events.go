@@ -0,0 +1,106 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 实时事件流：trader把周期开始、决策、成交、止损触发、风控事件发布到进程内的事件总线，
+// API的 /ws 端点把事件推送给仪表盘和外部自动化，不需要轮询。
+// 与 notify 不同，事件量大且不写日志；订阅者消费过慢时丢弃该订阅者的事件（计数），不阻塞交易流程。
+
+// Type 事件类型
+type Type string
+
+const (
+	CycleStarted Type = "cycle_started" // 交易周期开始
+	DecisionMade Type = "decision_made" // AI决策（经过风控过滤，即将执行）
+	OrderFilled  Type = "order_filled"  // 下单类动作成交（开仓、平仓、部分平仓、加仓）
+	SLTriggered  Type = "sl_triggered"  // 止损在交易所侧触发
+	RiskBreach   Type = "risk_breach"   // 触发风控（降风险等级升级、清仓暂停、强平/ADL）
+)
+
+// Types 全部事件类型
+var Types = []Type{CycleStarted, DecisionMade, OrderFilled, SLTriggered, RiskBreach}
+
+// Event 事件
+type Event struct {
+	Seq      uint64      `json:"seq"` // 进程内递增序号（客户端可据此发现丢失的事件）
+	Type     Type        `json:"type"`
+	TraderID string      `json:"trader_id"`
+	Symbol   string      `json:"symbol,omitempty"`
+	Time     time.Time   `json:"time"`
+	Data     interface{} `json:"data,omitempty"`
+}
+
+// Subscription 一个订阅
+type Subscription struct {
+	C <-chan Event
+
+	ch      chan Event
+	filter  func(Event) bool
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// Dropped 因消费过慢丢弃的事件数
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close 取消订阅（之后C会被关闭）
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		mu.Lock()
+		delete(subscribers, s)
+		mu.Unlock()
+		close(s.ch)
+	})
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers = make(map[*Subscription]struct{})
+	seq         atomic.Uint64
+)
+
+// Subscribe 订阅事件，filter为nil时接收全部事件；buffer为缓冲的事件数
+func Subscribe(buffer int, filter func(Event) bool) *Subscription {
+	ch := make(chan Event, buffer)
+	s := &Subscription{C: ch, ch: ch, filter: filter}
+	mu.Lock()
+	subscribers[s] = struct{}{}
+	mu.Unlock()
+	return s
+}
+
+// Publish 发布事件（不阻塞；没有订阅者时直接返回）
+func Publish(e Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(subscribers) == 0 {
+		return
+	}
+	e.Seq = seq.Add(1)
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	for s := range subscribers {
+		if s.filter != nil && !s.filter(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribers 当前订阅数
+func Subscribers() int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(subscribers)
+}
@@ -18,9 +18,17 @@ func Analyze(marketData *market.Data) string {
 	// Try to get klines from provider
 	provider, err := market.GetDefaultProvider()
 	if err == nil && marketData != nil {
-		// Get recent klines for pattern detection
-		klines3m, _ = provider.GetKlines(marketData.Symbol, "3m", 40)
-		klines4h, _ = provider.GetKlines(marketData.Symbol, "4h", 60)
+		// Use the symbol's configured provider so klines match the market data's symbol format
+		if p, perr := market.ProviderFor(marketData.Symbol); perr == nil {
+			provider = p
+		}
+		// Get recent klines for pattern detection (lookback window plus trend context)
+		limit3m := 40
+		if n := PatternLookback() + 10; n > limit3m {
+			limit3m = n
+		}
+		klines3m, _ = market.FetchKlines(provider, marketData.Symbol, "3m", limit3m)
+		klines4h, _ = market.FetchKlines(provider, marketData.Symbol, "4h", 60)
 	}
 	
 	// Detect candlestick patterns on 3m timeframe
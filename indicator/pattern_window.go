@@ -0,0 +1,111 @@
+package indicator
+
+import (
+	"nofx/market"
+	"sort"
+	"sync"
+)
+
+// DefaultPatternLookback is the default number of trailing bars scanned for candlestick patterns
+const DefaultPatternLookback = 10
+
+const (
+	// patternDecayPerBar is the confidence lost for every bar a pattern ages
+	patternDecayPerBar = 0.05
+	// minPatternConfidence is the decayed confidence below which a pattern is considered stale
+	minPatternConfidence = 0.4
+)
+
+var (
+	patternLookback   = DefaultPatternLookback
+	patternLookbackMu sync.RWMutex
+)
+
+// SetPatternLookback sets how many trailing bars DetectCandlestickPatterns scans (<= 0 restores the default)
+func SetPatternLookback(bars int) {
+	if bars <= 0 {
+		bars = DefaultPatternLookback
+	}
+	patternLookbackMu.Lock()
+	patternLookback = bars
+	patternLookbackMu.Unlock()
+}
+
+// PatternLookback returns the configured lookback window in bars
+func PatternLookback() int {
+	patternLookbackMu.RLock()
+	defer patternLookbackMu.RUnlock()
+	return patternLookback
+}
+
+// DetectCandlestickPatterns detects candlestick patterns formed within the configured trailing window
+func DetectCandlestickPatterns(klines []market.Kline) []PatternResult {
+	return DetectCandlestickPatternsWindow(klines, PatternLookback())
+}
+
+// DetectCandlestickPatternsWindow scans the last lookback bars for candlestick patterns.
+// Each result carries its age (BarsAgo) and a confidence decayed by that age. Stale signals are
+// suppressed: patterns whose decayed confidence drops below the minimum, directional patterns
+// already invalidated by later price action (a close beyond the pattern bar's extreme), and older
+// repeats of a pattern that formed again more recently.
+func DetectCandlestickPatternsWindow(klines []market.Kline, lookback int) []PatternResult {
+	if lookback <= 0 {
+		lookback = 1
+	}
+
+	var results []PatternResult
+	seen := make(map[string]bool)
+	for barsAgo := 0; barsAgo < lookback; barsAgo++ {
+		end := len(klines) - barsAgo
+		if end < 3 {
+			break
+		}
+		window := klines[:end]
+		patternBar := window[end-1]
+		later := klines[end:]
+
+		for _, p := range detectPatternsAtLatest(window) {
+			if seen[p.Pattern] {
+				continue // a more recent occurrence already reported
+			}
+			p.BarsAgo = barsAgo
+			p.Confidence *= 1 - patternDecayPerBar*float64(barsAgo)
+			if p.Confidence < minPatternConfidence {
+				continue
+			}
+			if patternInvalidated(p, patternBar, later) {
+				continue
+			}
+			seen[p.Pattern] = true
+			results = append(results, p)
+		}
+	}
+
+	// Freshest patterns first
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].BarsAgo < results[j].BarsAgo
+	})
+	return results
+}
+
+// patternInvalidated reports whether price closed beyond the pattern bar after it formed:
+// below its low for bullish patterns, above its high for bearish ones
+func patternInvalidated(p PatternResult, patternBar market.Kline, later []market.Kline) bool {
+	if isNeutralPattern(p.Pattern) {
+		return false
+	}
+	for _, k := range later {
+		if p.IsBullish && k.Close < patternBar.Low {
+			return true
+		}
+		if !p.IsBullish && k.Close > patternBar.High {
+			return true
+		}
+	}
+	return false
+}
+
+// isNeutralPattern reports whether a pattern signals indecision rather than direction
+func isNeutralPattern(name string) bool {
+	return name == "Doji" || name == "Spinning Top"
+}
@@ -0,0 +1,101 @@
+package indicator
+
+import (
+	"math"
+	"nofx/market"
+	"testing"
+)
+
+// candle builds a kline with the given open/close and shadows
+func candle(open, close, upper, lower float64) market.Kline {
+	return market.Kline{Open: open, Close: close, High: math.Max(open, close) + upper, Low: math.Min(open, close) - lower}
+}
+
+// soldiersSeries returns quiet bars, a Three White Soldiers completed by bar S3 (low 101.6), and
+// then barsAfter quiet bars closing at afterClose. All bars are bullish and the quiet bars have
+// small bodies, so no other pattern forms anywhere in the series.
+func soldiersSeries(barsAfter int, afterClose float64) []market.Kline {
+	var klines []market.Kline
+	for i := 0; i < 5; i++ {
+		klines = append(klines, candle(99.8, 100, 0.2, 0.2))
+	}
+	klines = append(klines,
+		candle(100, 101, 0.4, 0.4),
+		candle(101, 102, 0.4, 0.4),
+		candle(102, 103, 0.4, 0.4),
+	)
+	for i := 0; i < barsAfter; i++ {
+		klines = append(klines, candle(afterClose-0.2, afterClose, 0.2, 0.2))
+	}
+	return klines
+}
+
+func TestPatternAgeAndLookback(t *testing.T) {
+	for _, tt := range []struct {
+		barsAgo  int
+		lookback int
+		found    bool
+	}{
+		{barsAgo: 0, lookback: 10, found: true},
+		{barsAgo: 3, lookback: 10, found: true},
+		{barsAgo: 9, lookback: 10, found: true},   // last bar inside the window
+		{barsAgo: 10, lookback: 10, found: false}, // just outside the window
+		{barsAgo: 4, lookback: 4, found: false},
+		{barsAgo: 0, lookback: 0, found: true}, // lookback <= 0 scans the latest bar only
+		{barsAgo: 1, lookback: 0, found: false},
+		{barsAgo: 11, lookback: 20, found: true},  // 0.9 * (1 - 0.55) = 0.405
+		{barsAgo: 12, lookback: 20, found: false}, // 0.9 * (1 - 0.60) = 0.36, below the minimum
+	} {
+		results := DetectCandlestickPatternsWindow(soldiersSeries(tt.barsAgo, 103), tt.lookback)
+		if !tt.found {
+			if len(results) != 0 {
+				t.Errorf("barsAgo %d lookback %d: expected nothing, got %+v", tt.barsAgo, tt.lookback, results)
+			}
+			continue
+		}
+		if len(results) != 1 || results[0].Pattern != "Three White Soldiers" || !results[0].IsBullish {
+			t.Fatalf("barsAgo %d lookback %d: %+v", tt.barsAgo, tt.lookback, results)
+		}
+		want := 0.9 * (1 - patternDecayPerBar*float64(tt.barsAgo))
+		if results[0].BarsAgo != tt.barsAgo || math.Abs(results[0].Confidence-want) > 1e-9 {
+			t.Errorf("barsAgo %d: got age %d confidence %.4f, want %.4f", tt.barsAgo, results[0].BarsAgo, results[0].Confidence, want)
+		}
+	}
+}
+
+func TestPatternInvalidatedByLaterClose(t *testing.T) {
+	// A close below the pattern bar's low (101.6) invalidates the bullish pattern
+	if results := DetectCandlestickPatternsWindow(soldiersSeries(2, 101.5), 10); len(results) != 0 {
+		t.Errorf("invalidated pattern reported: %+v", results)
+	}
+	if results := DetectCandlestickPatternsWindow(soldiersSeries(2, 101.7), 10); len(results) != 1 {
+		t.Errorf("closes above the pattern bar's low keep it valid: %+v", results)
+	}
+}
+
+func TestPatternLatestOccurrenceWins(t *testing.T) {
+	// Soldiers completed 4 bars ago and again on the latest bar: only the fresh one is reported
+	klines := soldiersSeries(3, 103)
+	klines = append(klines, candle(103, 104, 0.4, 0.4), candle(104, 105, 0.4, 0.4), candle(105, 106, 0.4, 0.4))
+	results := DetectCandlestickPatternsWindow(klines, 10)
+	if len(results) != 1 || results[0].BarsAgo != 0 || results[0].Confidence != 0.9 {
+		t.Fatalf("results = %+v", results)
+	}
+}
+
+func TestPatternLookbackSetting(t *testing.T) {
+	t.Cleanup(func() { SetPatternLookback(0) })
+
+	klines := soldiersSeries(6, 103)
+	if results := DetectCandlestickPatterns(klines); len(results) != 1 || results[0].BarsAgo != 6 {
+		t.Fatalf("default lookback %d should include a 6-bar-old pattern: %+v", DefaultPatternLookback, results)
+	}
+	SetPatternLookback(5)
+	if PatternLookback() != 5 || len(DetectCandlestickPatterns(klines)) != 0 {
+		t.Error("lookback 5 should exclude a 6-bar-old pattern")
+	}
+	SetPatternLookback(-1)
+	if PatternLookback() != DefaultPatternLookback {
+		t.Errorf("non-positive lookback should restore the default, got %d", PatternLookback())
+	}
+}
@@ -25,6 +25,7 @@ type PatternResult struct {
 	Pattern string
 	IsBullish bool
 	Confidence float64 // 0.0 to 1.0
+	BarsAgo int // Age of the pattern: 0 = formed on the latest candle
 }
 
 // calculateCandleProperties calculates basic properties for a candle
@@ -78,8 +79,8 @@ func calculateCandleProperties(kline market.Kline) CandleProperties {
 	}
 }
 
-// DetectCandlestickPatterns detects all candlestick patterns on the latest candles
-func DetectCandlestickPatterns(klines []market.Kline) []PatternResult {
+// detectPatternsAtLatest detects all candlestick patterns completed by the last candle of klines
+func detectPatternsAtLatest(klines []market.Kline) []PatternResult {
 	if len(klines) < 3 {
 		return []PatternResult{}
 	}
@@ -0,0 +1,173 @@
+package indicator
+
+import (
+	"fmt"
+	"math"
+	"nofx/market"
+	"sync"
+)
+
+// Relative strength vs BTC: altcoin setups depend heavily on BTC-relative momentum, so each
+// candidate's 1h closes are divided by BTC's closes at the same open time. The resulting ratio
+// series gives an EMA trend and the percentage out/underperformance over 1h, 4h and 24h.
+
+const (
+	// RelativeStrengthInterval is the kline interval the ratio series is built from
+	RelativeStrengthInterval = "1h"
+	// RelativeStrengthEMAPeriod is the EMA period applied to the ratio series
+	RelativeStrengthEMAPeriod = 20
+	// relativeStrengthBars is the number of 1h klines fetched (24h window plus EMA warm-up)
+	relativeStrengthBars = 48
+)
+
+var (
+	relativeStrengthEnabled   bool
+	relativeStrengthEnabledMu sync.RWMutex
+)
+
+// SetRelativeStrengthEnabled turns the relative strength computation on or off (off by default,
+// it costs one extra kline request per candidate)
+func SetRelativeStrengthEnabled(enabled bool) {
+	relativeStrengthEnabledMu.Lock()
+	relativeStrengthEnabled = enabled
+	relativeStrengthEnabledMu.Unlock()
+}
+
+// RelativeStrengthEnabled reports whether relative strength is computed for candidates
+func RelativeStrengthEnabled() bool {
+	relativeStrengthEnabledMu.RLock()
+	defer relativeStrengthEnabledMu.RUnlock()
+	return relativeStrengthEnabled
+}
+
+// RelativeStrength is a symbol's momentum relative to BTC
+type RelativeStrength struct {
+	Symbol     string
+	Ratio      float64 // latest symbol close / BTC close
+	RatioEMA   float64 // EMA of the ratio series
+	RatioVsEMA float64 // % distance of the ratio from its EMA (positive: ratio trending up)
+	Outperf1h  float64 // % outperformance vs BTC over the last 1h
+	Outperf4h  float64 // % outperformance vs BTC over the last 4h
+	Outperf24h float64 // % outperformance vs BTC over the last 24h (0 when history is shorter)
+	Bars       int     // aligned bars used
+}
+
+// Score condenses relative strength into [-1, 1] for ranking candidates: positive when the symbol
+// outperforms BTC. Longer windows weigh more but are scaled by their typical move size
+// (4h moves ~2x, 24h moves ~4x a 1h move), and the ratio trend confirms the move.
+func (rs *RelativeStrength) Score() float64 {
+	if rs == nil {
+		return 0
+	}
+	raw := 0.2*rs.Outperf1h + 0.3*(rs.Outperf4h/2) + 0.3*(rs.Outperf24h/4) + 0.2*rs.RatioVsEMA
+	return math.Tanh(raw / 2)
+}
+
+// ComputeRelativeStrength builds the ratio series from klines aligned on open time.
+// Returns false when fewer than 5 bars align.
+func ComputeRelativeStrength(symbol string, klines, btcKlines []market.Kline) (*RelativeStrength, bool) {
+	btcClose := make(map[int64]float64, len(btcKlines))
+	for _, k := range btcKlines {
+		if k.Close > 0 {
+			btcClose[k.OpenTime] = k.Close
+		}
+	}
+	var ratios []float64
+	for _, k := range klines {
+		if btc, ok := btcClose[k.OpenTime]; ok && k.Close > 0 {
+			ratios = append(ratios, k.Close/btc)
+		}
+	}
+	if len(ratios) < 5 {
+		return nil, false
+	}
+
+	last := ratios[len(ratios)-1]
+	rs := &RelativeStrength{
+		Symbol: symbol,
+		Ratio:  last,
+		Bars:   len(ratios),
+	}
+	rs.RatioEMA = ratioEMA(ratios, RelativeStrengthEMAPeriod)
+	if rs.RatioEMA > 0 {
+		rs.RatioVsEMA = (last/rs.RatioEMA - 1) * 100
+	}
+	rs.Outperf1h = ratioChange(ratios, 1)
+	rs.Outperf4h = ratioChange(ratios, 4)
+	rs.Outperf24h = ratioChange(ratios, 24)
+	return rs, true
+}
+
+// RelativeStrengthVsBTC fetches the symbol's 1h klines from its configured provider and compares them
+// with btcKlines (see FetchRelativeStrengthKlines)
+func RelativeStrengthVsBTC(symbol string, btcKlines []market.Kline) (*RelativeStrength, error) {
+	klines, err := FetchRelativeStrengthKlines(symbol)
+	if err != nil {
+		return nil, err
+	}
+	rs, ok := ComputeRelativeStrength(symbol, klines, btcKlines)
+	if !ok {
+		return nil, fmt.Errorf("not enough bars aligned with BTC for %s", symbol)
+	}
+	return rs, nil
+}
+
+// FetchRelativeStrengthKlines fetches the 1h klines the ratio series is built from
+func FetchRelativeStrengthKlines(symbol string) ([]market.Kline, error) {
+	provider, err := market.ProviderFor(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return market.FetchKlines(provider, symbol, RelativeStrengthInterval, relativeStrengthBars)
+}
+
+// BTCSymbolFor returns the BTC pair quoted in the same asset as symbol (ETHUSDC -> BTCUSDC)
+func BTCSymbolFor(symbol string) string {
+	return "BTC" + market.QuoteOf(symbol)
+}
+
+// FormatRelativeStrength formats relative strength for AI prompts
+func FormatRelativeStrength(rs *RelativeStrength) string {
+	if rs == nil {
+		return ""
+	}
+	trend := "FLAT"
+	switch {
+	case rs.RatioVsEMA > 0.5:
+		trend = "RISING"
+	case rs.RatioVsEMA < -0.5:
+		trend = "FALLING"
+	}
+	return fmt.Sprintf("Relative strength vs BTC: 1h %+.2f%%, 4h %+.2f%%, 24h %+.2f%% | ratio vs EMA%d %+.2f%% (%s) | score %+.2f",
+		rs.Outperf1h, rs.Outperf4h, rs.Outperf24h, RelativeStrengthEMAPeriod, rs.RatioVsEMA, trend, rs.Score())
+}
+
+// ratioEMA computes the EMA of values, seeded with the SMA of the first period values
+// (falls back to the full series when it is shorter than period)
+func ratioEMA(values []float64, period int) float64 {
+	if len(values) < period {
+		period = len(values)
+	}
+	sum := 0.0
+	for i := 0; i < period; i++ {
+		sum += values[i]
+	}
+	ema := sum / float64(period)
+	multiplier := 2.0 / float64(period+1)
+	for i := period; i < len(values); i++ {
+		ema = (values[i]-ema)*multiplier + ema
+	}
+	return ema
+}
+
+// ratioChange returns the % change of the ratio over the last bars (0 when history is shorter)
+func ratioChange(ratios []float64, bars int) float64 {
+	if len(ratios) <= bars {
+		return 0
+	}
+	prev := ratios[len(ratios)-1-bars]
+	if prev <= 0 {
+		return 0
+	}
+	return (ratios[len(ratios)-1]/prev - 1) * 100
+}
@@ -0,0 +1,136 @@
+package indicator
+
+import (
+	"math"
+	"nofx/market"
+	"strings"
+	"testing"
+	"time"
+)
+
+var rsStart = time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+// hourlyCloses builds 1h klines starting at offset hours after rsStart with the given closes
+func hourlyCloses(offset int, closes ...float64) []market.Kline {
+	klines := make([]market.Kline, len(closes))
+	for i, c := range closes {
+		klines[i] = market.Kline{OpenTime: rsStart.Add(time.Duration(offset+i) * time.Hour).UnixMilli(), Close: c}
+	}
+	return klines
+}
+
+// series returns n closes where close[i] = f(i)
+func series(n int, f func(i int) float64) []float64 {
+	closes := make([]float64, n)
+	for i := range closes {
+		closes[i] = f(i)
+	}
+	return closes
+}
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestRelativeStrengthKnownSeries(t *testing.T) {
+	btc := hourlyCloses(0, series(30, func(i int) float64 { return 60000 + float64(i)*100 })...)
+
+	// Tracks BTC exactly: flat ratio, no outperformance
+	flat := hourlyCloses(0, series(30, func(i int) float64 { return (60000 + float64(i)*100) / 20 })...)
+	rs, ok := ComputeRelativeStrength("ETHUSDT", flat, btc)
+	if !ok {
+		t.Fatal("expected enough aligned bars")
+	}
+	if !approx(rs.Ratio, 0.05) || !approx(rs.RatioEMA, 0.05) || !approx(rs.Outperf1h, 0) || !approx(rs.Outperf24h, 0) || !approx(rs.Score(), 0) {
+		t.Errorf("flat ratio: %+v score %.4f", rs, rs.Score())
+	}
+
+	// Ratio grows 1% per bar: outperformance compounds over each window
+	up := hourlyCloses(0, series(30, func(i int) float64 { return (60000 + float64(i)*100) / 20 * math.Pow(1.01, float64(i)) })...)
+	rs, ok = ComputeRelativeStrength("ETHUSDT", up, btc)
+	if !ok || rs.Bars != 30 {
+		t.Fatalf("rs = %+v, %v", rs, ok)
+	}
+	if !approx(rs.Outperf1h, 1) || !approx(rs.Outperf4h, (math.Pow(1.01, 4)-1)*100) || !approx(rs.Outperf24h, (math.Pow(1.01, 24)-1)*100) {
+		t.Errorf("outperformance 1h %.6f 4h %.6f 24h %.6f", rs.Outperf1h, rs.Outperf4h, rs.Outperf24h)
+	}
+	if !approx(rs.Ratio, 0.05*math.Pow(1.01, 29)) || rs.RatioEMA >= rs.Ratio || rs.RatioVsEMA <= 0.5 {
+		t.Errorf("rising ratio should sit above its EMA: %+v", rs)
+	}
+	if score := rs.Score(); score <= 0 || score >= 1 {
+		t.Errorf("outperformer score = %.4f", score)
+	}
+	if got := FormatRelativeStrength(rs); !strings.Contains(got, "1h +1.00%, 4h +4.06%, 24h +26.97%") || !strings.Contains(got, "(RISING)") {
+		t.Errorf("FormatRelativeStrength = %s", got)
+	}
+
+	// Mirror image underperforms with the opposite score
+	down := hourlyCloses(0, series(30, func(i int) float64 { return (60000 + float64(i)*100) / 20 / math.Pow(1.01, float64(i)) })...)
+	weak, _ := ComputeRelativeStrength("ETHUSDT", down, btc)
+	if weak.Outperf1h >= 0 || weak.Score() >= 0 || !strings.Contains(FormatRelativeStrength(weak), "(FALLING)") {
+		t.Errorf("underperformer: %+v", weak)
+	}
+}
+
+func TestRelativeStrengthMismatchedLengths(t *testing.T) {
+	// Symbol listed 10h ago, BTC has 30h of history: only the overlapping bars count
+	btc := hourlyCloses(0, series(30, func(int) float64 { return 60000 })...)
+	symbol := hourlyCloses(20, series(10, func(i int) float64 { return 100 + float64(i) })...)
+	rs, ok := ComputeRelativeStrength("NEWUSDT", symbol, btc)
+	if !ok || rs.Bars != 10 {
+		t.Fatalf("rs = %+v, %v", rs, ok)
+	}
+	if !approx(rs.Outperf4h, (109.0/105-1)*100) || rs.Outperf24h != 0 {
+		t.Errorf("4h %.6f, 24h %.6f (24h needs more than 24 bars)", rs.Outperf4h, rs.Outperf24h)
+	}
+
+	// BTC shorter than the symbol
+	rs, ok = ComputeRelativeStrength("ETHUSDT", hourlyCloses(0, series(30, func(int) float64 { return 3000 })...), btc[:6])
+	if !ok || rs.Bars != 6 {
+		t.Errorf("short BTC history: %+v, %v", rs, ok)
+	}
+
+	// Fewer than 5 aligned bars, or no overlap at all
+	if _, ok := ComputeRelativeStrength("ETHUSDT", hourlyCloses(26, 1, 2, 3, 4, 5, 6), btc); ok {
+		t.Error("4 aligned bars should not be enough")
+	}
+	if _, ok := ComputeRelativeStrength("ETHUSDT", hourlyCloses(100, series(10, func(int) float64 { return 1 })...), btc); ok {
+		t.Error("no overlap should not be enough")
+	}
+	if _, ok := ComputeRelativeStrength("ETHUSDT", nil, btc); ok {
+		t.Error("no klines")
+	}
+}
+
+func TestRelativeStrengthZeroBenchmark(t *testing.T) {
+	// Zero BTC closes (bad data) are skipped instead of producing Inf/NaN
+	btcCloses := series(10, func(int) float64 { return 60000 })
+	btcCloses[9], btcCloses[4] = 0, 0
+	symbol := hourlyCloses(0, series(10, func(i int) float64 { return 3000 + float64(i)*30 })...)
+	rs, ok := ComputeRelativeStrength("ETHUSDT", symbol, hourlyCloses(0, btcCloses...))
+	if !ok || rs.Bars != 8 {
+		t.Fatalf("rs = %+v, %v", rs, ok)
+	}
+	if !approx(rs.Ratio, 3240.0/60000) {
+		t.Errorf("latest usable bar is #8: ratio %.6f", rs.Ratio)
+	}
+	for name, v := range map[string]float64{"ratio_ema": rs.RatioEMA, "vs_ema": rs.RatioVsEMA, "1h": rs.Outperf1h, "4h": rs.Outperf4h, "score": rs.Score()} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			t.Errorf("%s = %v", name, v)
+		}
+	}
+
+	if _, ok := ComputeRelativeStrength("ETHUSDT", symbol, hourlyCloses(0, series(10, func(int) float64 { return 0 })...)); ok {
+		t.Error("all-zero benchmark should not be usable")
+	}
+	var nilRS *RelativeStrength
+	if nilRS.Score() != 0 || FormatRelativeStrength(nil) != "" {
+		t.Error("nil relative strength")
+	}
+}
+
+func TestBTCSymbolFor(t *testing.T) {
+	for symbol, want := range map[string]string{"ETHUSDT": "BTCUSDT", "SOLUSDC": "BTCUSDC", "DOGE": "BTCUSDT"} {
+		if got := BTCSymbolFor(symbol); got != want {
+			t.Errorf("BTCSymbolFor(%s) = %s, want %s", symbol, got, want)
+		}
+	}
+}
@@ -27,16 +27,16 @@ func FormatAnalysis(summary SignalSummary) string {
 			if pattern.IsBullish {
 				direction = "BULLISH"
 				bullishCount++
-			} else if pattern.Pattern != "Doji" && pattern.Pattern != "Spinning Top" {
+			} else if !isNeutralPattern(pattern.Pattern) {
 				bearishCount++
 			}
 			
-			parts = append(parts, fmt.Sprintf("- %s (%s, Confidence: %.1f%%)", 
-				pattern.Pattern, direction, pattern.Confidence*100))
+			parts = append(parts, fmt.Sprintf("- %s (%s, Confidence: %.1f%%, %s)", 
+				pattern.Pattern, direction, pattern.Confidence*100, formatPatternAge(pattern.BarsAgo)))
 		}
 		
-		parts = append(parts, fmt.Sprintf("Summary: %d bullish patterns, %d bearish patterns detected", 
-			bullishCount, bearishCount))
+		parts = append(parts, fmt.Sprintf("Summary: %d bullish patterns, %d bearish patterns detected in the last %d bars", 
+			bullishCount, bearishCount, PatternLookback()))
 		parts = append(parts, "")
 	}
 	
@@ -77,7 +77,7 @@ func FormatAnalysis(summary SignalSummary) string {
 		for _, p := range summary.CandlestickPatterns {
 			if p.IsBullish {
 				bullishSignals++
-			} else if p.Pattern != "Doji" && p.Pattern != "Spinning Top" {
+			} else if !isNeutralPattern(p.Pattern) {
 				bearishSignals++
 			}
 		}
@@ -113,3 +113,14 @@ func FormatAnalysis(summary SignalSummary) string {
 	return strings.Join(parts, "\n")
 }
 
+// formatPatternAge describes when a pattern formed relative to the latest candle
+func formatPatternAge(barsAgo int) string {
+	switch barsAgo {
+	case 0:
+		return "current bar"
+	case 1:
+		return "1 bar ago"
+	default:
+		return fmt.Sprintf("%d bars ago", barsAgo)
+	}
+}
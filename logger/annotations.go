@@ -0,0 +1,350 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 人工备注和标签
+// 操作员可以给决策（按决策ID）或交易（按持仓ID）加备注和标签（如 "bad fill"、"news-driven"），
+// 保存在日志目录的 annotations.json，不修改决策记录本身。读取决策记录和分析交易表现时附带上；
+// 交易带上其持仓、开仓决策和平仓决策上的全部标签，TagReport 按标签汇总交易结果，用于定性复盘。
+
+const (
+	annotationsFile    = "annotations.json"
+	maxAnnotationNote  = 2000
+	maxAnnotationTag   = 64
+	maxAnnotationTags  = 10
+	untaggedTradeLabel = "(untagged)"
+)
+
+// ErrAnnotationNotFound 指定ID的备注不存在
+var ErrAnnotationNotFound = errors.New("备注不存在")
+
+// Annotation 操作员对决策或交易的备注
+type Annotation struct {
+	ID         string    `json:"id"`
+	DecisionID string    `json:"decision_id,omitempty"` // 备注的决策（与 PositionID 至少一个）
+	PositionID string    `json:"position_id,omitempty"` // 备注的交易（持仓ID）
+	Note       string    `json:"note,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	Author     string    `json:"author,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AnnotationFilter 查询条件（空字段不过滤）
+type AnnotationFilter struct {
+	DecisionID string
+	PositionID string
+	Tag        string
+}
+
+// AddAnnotation 添加备注（决策ID或持仓ID必须存在于决策记录中，备注和标签至少一个）
+func (l *DecisionLogger) AddAnnotation(a Annotation) (*Annotation, error) {
+	a.DecisionID = strings.TrimSpace(a.DecisionID)
+	a.PositionID = strings.TrimSpace(a.PositionID)
+	a.Note = strings.TrimSpace(a.Note)
+	a.Author = strings.TrimSpace(a.Author)
+	tags, err := normalizeTags(a.Tags)
+	if err != nil {
+		return nil, err
+	}
+	a.Tags = tags
+
+	switch {
+	case a.DecisionID == "" && a.PositionID == "":
+		return nil, fmt.Errorf("需要 decision_id 或 position_id")
+	case a.Note == "" && len(a.Tags) == 0:
+		return nil, fmt.Errorf("备注和标签不能都为空")
+	case len([]rune(a.Note)) > maxAnnotationNote:
+		return nil, fmt.Errorf("备注不能超过 %d 个字符", maxAnnotationNote)
+	}
+	if a.DecisionID != "" {
+		if _, err := l.GetRecord(a.DecisionID); err != nil {
+			return nil, err
+		}
+	}
+	if a.PositionID != "" {
+		if _, err := l.GetPositionLifecycle(a.PositionID); err != nil {
+			return nil, err
+		}
+	}
+
+	l.annotationsMu.Lock()
+	defer l.annotationsMu.Unlock()
+	annotations, err := l.loadAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	a.CreatedAt = time.Now()
+	a.ID = strconv.FormatInt(a.CreatedAt.UnixNano(), 36)
+	annotations = append(annotations, a)
+	if err := l.saveAnnotations(annotations); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// DeleteAnnotation 删除备注
+func (l *DecisionLogger) DeleteAnnotation(id string) error {
+	l.annotationsMu.Lock()
+	defer l.annotationsMu.Unlock()
+	annotations, err := l.loadAnnotations()
+	if err != nil {
+		return err
+	}
+	for i, a := range annotations {
+		if a.ID == id {
+			return l.saveAnnotations(append(annotations[:i], annotations[i+1:]...))
+		}
+	}
+	return ErrAnnotationNotFound
+}
+
+// Annotations 按条件查询备注（按添加时间正序）
+func (l *DecisionLogger) Annotations(filter AnnotationFilter) ([]Annotation, error) {
+	l.annotationsMu.Lock()
+	annotations, err := l.loadAnnotations()
+	l.annotationsMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	result := []Annotation{}
+	for _, a := range annotations {
+		if filter.DecisionID != "" && a.DecisionID != filter.DecisionID {
+			continue
+		}
+		if filter.PositionID != "" && a.PositionID != filter.PositionID {
+			continue
+		}
+		if filter.Tag != "" && !hasTag(a.Tags, filter.Tag) {
+			continue
+		}
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+// loadAnnotations 读取备注文件（不存在时为空）
+func (l *DecisionLogger) loadAnnotations() ([]Annotation, error) {
+	data, err := os.ReadFile(filepath.Join(l.logDir, annotationsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取备注失败: %w", err)
+	}
+	var annotations []Annotation
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return nil, fmt.Errorf("解析备注失败: %w", err)
+	}
+	return annotations, nil
+}
+
+// saveAnnotations 写入备注文件（先写临时文件再重命名）
+func (l *DecisionLogger) saveAnnotations(annotations []Annotation) error {
+	data, err := json.MarshalIndent(annotations, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化备注失败: %w", err)
+	}
+	path := filepath.Join(l.logDir, annotationsFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入备注失败: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// annotationIndex 按决策ID和持仓ID索引备注
+type annotationIndex struct {
+	byDecision map[string][]Annotation
+	byPosition map[string][]Annotation
+}
+
+// annotationIndex 读取全部备注并建立索引（读取失败时为空索引，备注不影响决策记录和统计本身）
+func (l *DecisionLogger) annotationIndex() annotationIndex {
+	index := annotationIndex{byDecision: map[string][]Annotation{}, byPosition: map[string][]Annotation{}}
+	l.annotationsMu.Lock()
+	annotations, err := l.loadAnnotations()
+	l.annotationsMu.Unlock()
+	if err != nil {
+		fmt.Printf("⚠ %v\n", err)
+		return index
+	}
+	for _, a := range annotations {
+		if a.DecisionID != "" {
+			index.byDecision[a.DecisionID] = append(index.byDecision[a.DecisionID], a)
+		}
+		if a.PositionID != "" {
+			index.byPosition[a.PositionID] = append(index.byPosition[a.PositionID], a)
+		}
+	}
+	return index
+}
+
+// attachAnnotations 决策记录附带其备注
+func (l *DecisionLogger) attachAnnotations(records ...*DecisionRecord) {
+	index := l.annotationIndex()
+	for _, record := range records {
+		record.Annotations = index.byDecision[record.DecisionID]
+	}
+}
+
+// annotateTrades 交易附带持仓、开仓决策和平仓决策上的标签和备注
+func (idx annotationIndex) annotateTrades(trades []TradeOutcome) {
+	for i := range trades {
+		t := &trades[i]
+		var related []Annotation
+		if t.PositionID != "" {
+			related = append(related, idx.byPosition[t.PositionID]...)
+		}
+		related = append(related, idx.byDecision[t.OpenDecisionID]...)
+		if t.CloseDecisionID != t.OpenDecisionID {
+			related = append(related, idx.byDecision[t.CloseDecisionID]...)
+		}
+		t.Tags, t.Notes = nil, nil
+		for _, a := range related {
+			for _, tag := range a.Tags {
+				if !hasTag(t.Tags, tag) {
+					t.Tags = append(t.Tags, tag)
+				}
+			}
+			if a.Note != "" {
+				t.Notes = append(t.Notes, a.Note)
+			}
+		}
+	}
+}
+
+// TagStats 带某个标签的交易统计
+type TagStats struct {
+	Tag      string   `json:"tag"`
+	Trades   int      `json:"trades"`
+	Wins     int      `json:"wins"`
+	WinRate  float64  `json:"win_rate"`
+	TotalPnL float64  `json:"total_pnl"`
+	AvgPnL   float64  `json:"avg_pnl"`
+	AvgR     *float64 `json:"avg_r,omitempty"` // 有止损记录的交易的平均R倍数
+	RTrades  int      `json:"r_trades"`
+}
+
+// TagReport 按标签汇总的交易结果（一笔交易有多个标签时计入每个标签）
+type TagReport struct {
+	Days     int            `json:"days"`
+	Trades   int            `json:"trades"`
+	Tags     []TagStats     `json:"tags"`     // 按交易数倒序
+	Untagged TagStats       `json:"untagged"` // 没有标签的交易
+	Recent   []TradeOutcome `json:"recent"`   // 带标签或备注的交易（最新的在前）
+}
+
+// TagReport 最近days天（含今天）平仓的交易按标签汇总
+func (l *DecisionLogger) TagReport(days int, now time.Time) (*TagReport, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var history, records []*DecisionRecord
+	for i := days + openLookbackDays - 1; i >= 0; i-- {
+		dayRecords, err := l.recordsForDay(today.AddDate(0, 0, -i))
+		if err != nil {
+			return nil, err
+		}
+		if i >= days {
+			history = append(history, dayRecords...)
+		} else {
+			records = append(records, dayRecords...)
+		}
+	}
+	trades := closedTrades(history, records)
+	l.annotationIndex().annotateTrades(trades)
+	return tagReport(days, trades), nil
+}
+
+// tagReport 按标签汇总交易
+func tagReport(days int, trades []TradeOutcome) *TagReport {
+	report := &TagReport{Days: days, Trades: len(trades), Tags: []TagStats{}, Untagged: TagStats{Tag: untaggedTradeLabel}, Recent: []TradeOutcome{}}
+	byTag := make(map[string]*TagStats)
+	rSums := make(map[*TagStats]float64)
+	add := func(s *TagStats, t TradeOutcome) {
+		s.Trades++
+		s.TotalPnL += t.PnL
+		if t.PnL > 0 {
+			s.Wins++
+		}
+		if t.RMultiple != nil {
+			s.RTrades++
+			rSums[s] += *t.RMultiple
+		}
+	}
+	for _, t := range trades {
+		if len(t.Tags) == 0 {
+			add(&report.Untagged, t)
+		}
+		for _, tag := range t.Tags {
+			s, ok := byTag[tag]
+			if !ok {
+				s = &TagStats{Tag: tag}
+				byTag[tag] = s
+			}
+			add(s, t)
+		}
+		if len(t.Tags) > 0 || len(t.Notes) > 0 {
+			report.Recent = append([]TradeOutcome{t}, report.Recent...)
+		}
+	}
+
+	finish := func(s *TagStats) {
+		if s.Trades > 0 {
+			s.WinRate = float64(s.Wins) / float64(s.Trades) * 100
+			s.AvgPnL = s.TotalPnL / float64(s.Trades)
+		}
+		if s.RTrades > 0 {
+			avg := rSums[s] / float64(s.RTrades)
+			s.AvgR = &avg
+		}
+	}
+	for _, s := range byTag {
+		finish(s)
+		report.Tags = append(report.Tags, *s)
+	}
+	finish(&report.Untagged)
+	sort.Slice(report.Tags, func(i, j int) bool {
+		if report.Tags[i].Trades != report.Tags[j].Trades {
+			return report.Tags[i].Trades > report.Tags[j].Trades
+		}
+		return report.Tags[i].Tag < report.Tags[j].Tag
+	})
+	return report
+}
+
+// normalizeTags 去掉首尾空白、转小写并去重
+func normalizeTags(tags []string) ([]string, error) {
+	var result []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || hasTag(result, tag) {
+			continue
+		}
+		if len([]rune(tag)) > maxAnnotationTag {
+			return nil, fmt.Errorf("标签不能超过 %d 个字符: %q", maxAnnotationTag, tag)
+		}
+		result = append(result, tag)
+	}
+	if len(result) > maxAnnotationTags {
+		return nil, fmt.Errorf("标签不能超过 %d 个", maxAnnotationTags)
+	}
+	return result, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
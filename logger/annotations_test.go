@@ -0,0 +1,242 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tooMany := make([]string, maxAnnotationTags+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("t", i+1)
+	}
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr string
+	}{
+		{name: "去空白转小写去重", tags: []string{" News-Driven ", "news-driven", "Bad Fill"}, want: []string{"news-driven", "bad fill"}},
+		{name: "空标签忽略", tags: []string{"", "  ", "fomo"}, want: []string{"fomo"}},
+		{name: "没有标签", tags: nil, want: nil},
+		{name: "标签过长", tags: []string{strings.Repeat("长", maxAnnotationTag+1)}, wantErr: "标签不能超过 64 个字符"},
+		{name: "标签长度按字符计", tags: []string{strings.Repeat("长", maxAnnotationTag)}, want: []string{strings.Repeat("长", maxAnnotationTag)}},
+		{name: "标签过多", tags: tooMany, wantErr: "标签不能超过 10 个"},
+		{name: "去重后不超过上限", tags: append(append([]string(nil), tooMany[:maxAnnotationTags]...), strings.ToUpper(tooMany[0])), want: tooMany[:maxAnnotationTags]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeTags(tt.tags)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, 期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") || len(got) != len(tt.want) {
+				t.Fatalf("标签 = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// annotatedLogger 带一个开仓决策（持仓 pos-1）的决策日志
+func annotatedLogger(t *testing.T) (*DecisionLogger, string) {
+	t.Helper()
+	l := NewDecisionLogger(t.TempDir())
+	record := &DecisionRecord{Success: true, Decisions: []DecisionAction{
+		{Action: "open_long", Symbol: "ETHUSDT", PositionID: "pos-1", Success: true},
+	}}
+	if err := l.LogDecision(record); err != nil {
+		t.Fatal(err)
+	}
+	return l, record.DecisionID
+}
+
+func TestAddAnnotationValidation(t *testing.T) {
+	l, decisionID := annotatedLogger(t)
+
+	tests := []struct {
+		name    string
+		a       Annotation
+		wantErr string
+		is      error
+	}{
+		{name: "没有决策ID和持仓ID", a: Annotation{Note: "x"}, wantErr: "需要 decision_id 或 position_id"},
+		{name: "ID只有空白", a: Annotation{DecisionID: "  ", Note: "x"}, wantErr: "需要 decision_id 或 position_id"},
+		{name: "备注和标签都为空", a: Annotation{DecisionID: decisionID, Note: "  ", Tags: []string{" "}}, wantErr: "备注和标签不能都为空"},
+		{name: "备注过长", a: Annotation{DecisionID: decisionID, Note: strings.Repeat("x", maxAnnotationNote+1)}, wantErr: "备注不能超过 2000 个字符"},
+		{name: "标签不合法", a: Annotation{DecisionID: decisionID, Tags: []string{strings.Repeat("x", maxAnnotationTag+1)}}, wantErr: "标签不能超过"},
+		{name: "决策不存在", a: Annotation{DecisionID: "20200101_000000_cycle1", Note: "x"}, is: ErrRecordNotFound},
+		{name: "持仓不存在", a: Annotation{PositionID: "pos-404", Note: "x"}, is: ErrPositionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := l.AddAnnotation(tt.a)
+			if err == nil {
+				t.Fatal("应报错")
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Fatalf("err = %v, 期望 %v", err, tt.is)
+			}
+			if tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, 期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+	if all, err := l.Annotations(AnnotationFilter{}); err != nil || len(all) != 0 {
+		t.Fatalf("校验失败的备注不应保存: %+v %v", all, err)
+	}
+
+	a, err := l.AddAnnotation(Annotation{DecisionID: " " + decisionID + " ", Note: " 追高 ", Author: " alice "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID == "" || a.DecisionID != decisionID || a.Note != "追高" || a.Author != "alice" || a.CreatedAt.IsZero() {
+		t.Fatalf("备注应去空白并生成ID: %+v", a)
+	}
+}
+
+func TestAnnotationFilter(t *testing.T) {
+	l, decisionID := annotatedLogger(t)
+	add := func(a Annotation) *Annotation {
+		t.Helper()
+		saved, err := l.AddAnnotation(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return saved
+	}
+	onDecision := add(Annotation{DecisionID: decisionID, Tags: []string{"news-driven"}})
+	onPosition := add(Annotation{PositionID: "pos-1", Note: "成交偏离", Tags: []string{"bad fill", "News-Driven"}})
+	both := add(Annotation{DecisionID: decisionID, PositionID: "pos-1", Note: "复盘"})
+
+	ids := func(list []Annotation) string {
+		var s []string
+		for _, a := range list {
+			s = append(s, a.ID)
+		}
+		return strings.Join(s, ",")
+	}
+	tests := []struct {
+		name   string
+		filter AnnotationFilter
+		want   []*Annotation
+	}{
+		{name: "不过滤（按添加顺序）", filter: AnnotationFilter{}, want: []*Annotation{onDecision, onPosition, both}},
+		{name: "按决策", filter: AnnotationFilter{DecisionID: decisionID}, want: []*Annotation{onDecision, both}},
+		{name: "按持仓", filter: AnnotationFilter{PositionID: "pos-1"}, want: []*Annotation{onPosition, both}},
+		{name: "按标签", filter: AnnotationFilter{Tag: "news-driven"}, want: []*Annotation{onDecision, onPosition}},
+		{name: "条件同时满足", filter: AnnotationFilter{DecisionID: decisionID, Tag: "news-driven"}, want: []*Annotation{onDecision}},
+		{name: "没有匹配", filter: AnnotationFilter{Tag: "fomo"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Annotations(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []Annotation
+			for _, a := range tt.want {
+				want = append(want, *a)
+			}
+			if got == nil || ids(got) != ids(want) {
+				t.Fatalf("查询结果 = [%s], 期望 [%s]", ids(got), ids(want))
+			}
+		})
+	}
+
+	if err := l.DeleteAnnotation(onPosition.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.DeleteAnnotation(onPosition.ID); !errors.Is(err, ErrAnnotationNotFound) {
+		t.Fatalf("重复删除应报不存在: %v", err)
+	}
+	if got, _ := l.Annotations(AnnotationFilter{Tag: "bad fill"}); len(got) != 0 {
+		t.Fatalf("删除后不应再查到: %+v", got)
+	}
+}
+
+func TestAnnotateTrades(t *testing.T) {
+	idx := annotationIndex{
+		byDecision: map[string][]Annotation{
+			"open":  {{Tags: []string{"news-driven"}, Note: "消息面"}},
+			"close": {{Tags: []string{"news-driven", "panic exit"}}},
+		},
+		byPosition: map[string][]Annotation{
+			"pos-1": {{Tags: []string{"bad fill"}, Note: "成交偏离"}},
+		},
+	}
+	trades := []TradeOutcome{
+		{PositionID: "pos-1", OpenDecisionID: "open", CloseDecisionID: "close"},
+		{OpenDecisionID: "open", CloseDecisionID: "open"}, // 同一决策开平仓，备注只计一次
+		{PositionID: "pos-2", OpenDecisionID: "other", Tags: []string{"stale"}},
+	}
+	idx.annotateTrades(trades)
+
+	if got := strings.Join(trades[0].Tags, ","); got != "bad fill,news-driven,panic exit" {
+		t.Errorf("交易带上持仓和开平仓决策的标签（去重）: %q", got)
+	}
+	if got := strings.Join(trades[0].Notes, ","); got != "成交偏离,消息面" {
+		t.Errorf("交易备注: %q", got)
+	}
+	if len(trades[1].Tags) != 1 || len(trades[1].Notes) != 1 {
+		t.Errorf("同一决策开平仓时备注不应重复: %+v", trades[1])
+	}
+	if trades[2].Tags != nil || trades[2].Notes != nil {
+		t.Errorf("没有备注的交易应清空旧标签: %+v", trades[2])
+	}
+}
+
+func TestTagReport(t *testing.T) {
+	r := func(v float64) *float64 { return &v }
+	trades := []TradeOutcome{
+		{Symbol: "ETHUSDT", PnL: 30, RMultiple: r(1.5), Tags: []string{"news-driven", "breakout"}},
+		{Symbol: "BTCUSDT", PnL: -20, RMultiple: r(-1), Tags: []string{"news-driven"}},
+		{Symbol: "SOLUSDT", PnL: 10, Tags: []string{"news-driven"}}, // 没有止损记录，不计入平均R
+		{Symbol: "XRPUSDT", PnL: -5},
+		{Symbol: "ADAUSDT", PnL: 8, Notes: []string{"只有备注"}},
+	}
+	report := tagReport(7, trades)
+
+	if report.Days != 7 || report.Trades != 5 || len(report.Tags) != 2 {
+		t.Fatalf("汇总 = %+v", report)
+	}
+	// 按交易数倒序
+	news, breakout := report.Tags[0], report.Tags[1]
+	if news.Tag != "news-driven" || news.Trades != 3 || news.Wins != 2 || news.TotalPnL != 20 {
+		t.Errorf("news-driven = %+v", news)
+	}
+	if news.WinRate < 66.66 || news.WinRate > 66.67 || news.AvgPnL < 6.66 || news.AvgPnL > 6.67 {
+		t.Errorf("news-driven 胜率和平均盈亏 = %+v", news)
+	}
+	if news.RTrades != 2 || news.AvgR == nil || *news.AvgR != 0.25 {
+		t.Errorf("news-driven 平均R只算有止损的交易: %+v", news)
+	}
+	if breakout.Tag != "breakout" || breakout.Trades != 1 || breakout.WinRate != 100 {
+		t.Errorf("一笔交易有多个标签时计入每个标签: %+v", breakout)
+	}
+
+	untagged := report.Untagged
+	if untagged.Tag != untaggedTradeLabel || untagged.Trades != 2 || untagged.Wins != 1 || untagged.TotalPnL != 3 || untagged.AvgR != nil {
+		t.Errorf("没有标签的交易 = %+v", untagged)
+	}
+
+	// 带标签或备注的交易，最新的在前
+	var recent []string
+	for _, trade := range report.Recent {
+		recent = append(recent, trade.Symbol)
+	}
+	if got := strings.Join(recent, ","); got != "ADAUSDT,SOLUSDT,BTCUSDT,ETHUSDT" {
+		t.Errorf("最近带标签的交易 = %s", got)
+	}
+
+	empty := tagReport(7, nil)
+	if empty.Trades != 0 || empty.Tags == nil || len(empty.Tags) != 0 || empty.Recent == nil || empty.Untagged.WinRate != 0 {
+		t.Errorf("没有交易时应返回空汇总（JSON为[]而不是null）: %+v", empty)
+	}
+}
@@ -0,0 +1,242 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 周期报告
+// 把一条决策记录整理成排查用的完整报告：上下文摘要、prompt/输出大小、AI延迟、每条决策及其结果
+// （执行/失败/被复核否决/被过滤跳过）、下单与成交、以及本周期的全部错误。
+// 周期编号在进程重启后从1重新计数，按编号查找时取最新的一条记录。
+
+// CycleReport 单个决策周期的报告
+type CycleReport struct {
+	DecisionID  string    `json:"decision_id"`
+	CycleNumber int       `json:"cycle_number"`
+	Timestamp   time.Time `json:"timestamp"`
+	Success     bool      `json:"success"`
+	TraceID     string    `json:"trace_id,omitempty"`
+	Experiment  string    `json:"experiment,omitempty"`
+	Variant     string    `json:"variant,omitempty"`
+
+	Context   CycleContextSummary `json:"context"`
+	Prompt    CyclePromptSizes    `json:"prompt"`
+	Latency   CycleLatency        `json:"latency"`
+	Decisions []CycleDecision     `json:"decisions"`
+	Orders    []DecisionAction    `json:"orders"`
+	Errors    []string            `json:"errors"`
+
+	ParseDiagnostics string   `json:"parse_diagnostics,omitempty"`
+	ExecutionLog     []string `json:"execution_log"`
+}
+
+// CycleContextSummary 周期开始时的账户与候选币种摘要
+type CycleContextSummary struct {
+	Account        AccountSnapshot    `json:"account"`
+	Positions      []PositionSnapshot `json:"positions"`
+	CandidateCoins []string           `json:"candidate_coins"`
+	MarketSnapshot bool               `json:"market_snapshot"`
+}
+
+// CyclePromptSizes prompt与AI输出的大小（字符数）
+type CyclePromptSizes struct {
+	PromptChars     int  `json:"prompt_chars"`     // 输入prompt（已归档且过期时为0）
+	PromptAvailable bool `json:"prompt_available"` // 输入prompt是否仍可检索
+	CoTChars        int  `json:"cot_chars"`
+	ReasoningChars  int  `json:"reasoning_chars,omitempty"`
+	ReasoningTokens int  `json:"reasoning_tokens,omitempty"`
+	DecisionChars   int  `json:"decision_chars"`
+}
+
+// CycleLatency 行情快照到AI返回的时间线
+type CycleLatency struct {
+	MarketDataAt      time.Time `json:"market_data_at,omitempty"`
+	AIResponseAt      time.Time `json:"ai_response_at,omitempty"`
+	DecisionLatencyMs int64     `json:"decision_latency_ms"`
+}
+
+// CycleDecision AI的一条决策及其结果
+type CycleDecision struct {
+	Symbol          string  `json:"symbol"`
+	Action          string  `json:"action"`
+	Side            string  `json:"side,omitempty"`
+	Leverage        int     `json:"leverage,omitempty"`
+	PositionSizeUSD float64 `json:"position_size_usd,omitempty"`
+	StopLoss        float64 `json:"stop_loss,omitempty"`
+	TakeProfit      float64 `json:"take_profit,omitempty"`
+	Confidence      int     `json:"confidence,omitempty"`
+	Reasoning       string  `json:"reasoning,omitempty"`
+
+	Outcome      string   `json:"outcome"`                 // executed / failed / vetoed / downgraded / skipped / no_action
+	Error        string   `json:"error,omitempty"`         // 执行失败的原因
+	RejectReason string   `json:"reject_reason,omitempty"` // 交易所拒单原因
+	Review       string   `json:"review,omitempty"`
+	Notes        []string `json:"notes,omitempty"` // 执行日志中关于该决策的条目（过滤、缩仓、审批等）
+}
+
+// CycleReport 按决策ID或周期编号生成周期报告（编号对应多条记录时取最新的一条）
+func (l *DecisionLogger) CycleReport(ref string) (*CycleReport, error) {
+	decisionID := ref
+	if n, err := strconv.Atoi(ref); err == nil {
+		if decisionID, err = l.findCycle(n); err != nil {
+			return nil, err
+		}
+	}
+	record, err := l.GetRecord(decisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CycleReport{
+		DecisionID:  record.DecisionID,
+		CycleNumber: record.CycleNumber,
+		Timestamp:   record.Timestamp,
+		Success:     record.Success,
+		TraceID:     record.TraceID,
+		Experiment:  record.Experiment,
+		Variant:     record.Variant,
+		Context: CycleContextSummary{
+			Account:        record.AccountState,
+			Positions:      record.Positions,
+			CandidateCoins: record.CandidateCoins,
+			MarketSnapshot: record.MarketSnapshot,
+		},
+		Prompt: CyclePromptSizes{
+			CoTChars:        len([]rune(record.CoTTrace)),
+			ReasoningChars:  len([]rune(record.ReasoningTrace)),
+			ReasoningTokens: record.ReasoningTokens,
+			DecisionChars:   len([]rune(record.DecisionJSON)),
+		},
+		Latency: CycleLatency{
+			MarketDataAt:      record.MarketDataAt,
+			AIResponseAt:      record.AIResponseAt,
+			DecisionLatencyMs: record.DecisionLatencyMs,
+		},
+		Orders:           record.Decisions,
+		ParseDiagnostics: record.ParseDiagnostics,
+		ExecutionLog:     record.ExecutionLog,
+	}
+
+	prompt, err := l.GetPrompt(record.DecisionID)
+	if err != nil && !errors.Is(err, ErrPromptNotFound) {
+		return nil, err
+	}
+	report.Prompt.PromptChars = len([]rune(prompt))
+	report.Prompt.PromptAvailable = err == nil
+
+	report.Decisions = cycleDecisions(record)
+	report.Errors = cycleErrors(record)
+	return report, nil
+}
+
+// findCycle 周期编号对应的最新决策ID（文件名 decision_{日期}_{时间}_cycle{N}.json，按名称排序即按时间排序）
+func (l *DecisionLogger) findCycle(n int) (string, error) {
+	entries, err := os.ReadDir(l.logDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrRecordNotFound
+		}
+		return "", fmt.Errorf("读取日志目录失败: %w", err)
+	}
+	suffix := fmt.Sprintf("_cycle%d.json", n)
+	var names []string
+	for _, entry := range entries {
+		if name := entry.Name(); isRecordFile(name) && strings.HasSuffix(name, suffix) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", ErrRecordNotFound
+	}
+	sort.Strings(names)
+	latest := names[len(names)-1]
+	return strings.TrimSuffix(strings.TrimPrefix(latest, "decision_"), ".json"), nil
+}
+
+// cycleDecisions 解析决策JSON，按 币种+动作 匹配执行结果、复核结论和执行日志
+func cycleDecisions(record *DecisionRecord) []CycleDecision {
+	var decisions []CycleDecision
+	if record.DecisionJSON == "" || json.Unmarshal([]byte(record.DecisionJSON), &decisions) != nil {
+		return []CycleDecision{}
+	}
+
+	// 同一币种同一动作可能执行多次，按顺序依次匹配
+	used := make([]bool, len(record.Decisions))
+	for i := range decisions {
+		d := &decisions[i]
+		key := d.Symbol + " " + d.Action
+		for _, line := range record.ExecutionLog {
+			if strings.Contains(line, key) {
+				d.Notes = append(d.Notes, line)
+			}
+		}
+		if record.Review != nil {
+			for _, v := range record.Review.Verdicts {
+				if v.Symbol == d.Symbol && v.Action == d.Action {
+					d.Review = v.Verdict
+				}
+			}
+		}
+
+		executed := -1
+		for j, action := range record.Decisions {
+			if !used[j] && action.Symbol == d.Symbol && action.Action == d.Action {
+				executed = j
+				break
+			}
+		}
+		if executed >= 0 {
+			used[executed] = true
+		}
+		switch {
+		case d.Action == "hold" || d.Action == "wait":
+			d.Outcome = "no_action"
+		case executed >= 0:
+			d.Outcome = "executed"
+			if !record.Decisions[executed].Success {
+				d.Outcome = "failed"
+				d.Error = record.Decisions[executed].Error
+				d.RejectReason = record.Decisions[executed].RejectReason
+			}
+		case d.Review == "veto":
+			d.Outcome = "vetoed"
+		case d.Review == "downgrade":
+			d.Outcome = "downgraded"
+		default:
+			d.Outcome = "skipped"
+		}
+	}
+	return decisions
+}
+
+// cycleErrors 周期错误、保护单失败、集成模型失败和执行日志中的错误/告警
+func cycleErrors(record *DecisionRecord) []string {
+	errs := []string{}
+	if record.ErrorMessage != "" {
+		errs = append(errs, record.ErrorMessage)
+	}
+	for _, action := range record.Decisions {
+		if action.ProtectionError != "" {
+			errs = append(errs, fmt.Sprintf("%s %s 保护单: %s", action.Symbol, action.Action, action.ProtectionError))
+		}
+	}
+	for _, model := range record.Ensemble {
+		if model.Error != "" {
+			errs = append(errs, fmt.Sprintf("集成模型 %s: %s", model.Model, model.Error))
+		}
+	}
+	// 执行失败、复核失败等已在执行日志中记录为❌/⚠️条目
+	for _, line := range record.ExecutionLog {
+		if strings.HasPrefix(line, "❌") || strings.HasPrefix(line, "⚠") {
+			errs = append(errs, line)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCycleDecisionsOutcomes(t *testing.T) {
+	record := &DecisionRecord{
+		DecisionJSON: `[
+			{"symbol": "BTCUSDT", "action": "open_long", "leverage": 5, "position_size_usd": 1500, "confidence": 90},
+			{"symbol": "BTCUSDT", "action": "open_long", "leverage": 3},
+			{"symbol": "ETHUSDT", "action": "open_short"},
+			{"symbol": "SOLUSDT", "action": "open_long"},
+			{"symbol": "DOGEUSDT", "action": "open_long"},
+			{"symbol": "XRPUSDT", "action": "close_long"},
+			{"symbol": "ADAUSDT", "action": "hold"},
+			{"symbol": "ALL", "action": "wait"}
+		]`,
+		Decisions: []DecisionAction{
+			{Symbol: "XRPUSDT", Action: "close_long", Success: false, Error: "仓位不存在", RejectReason: "reduce_only"},
+			{Symbol: "BTCUSDT", Action: "open_long", Success: true},
+			{Symbol: "BTCUSDT", Action: "open_long", Success: false, Error: "保证金不足"},
+		},
+		Review: &ReviewRecord{Verdicts: []ReviewVerdict{
+			{Symbol: "BTCUSDT", Action: "open_long", Verdict: "approve"},
+			{Symbol: "ETHUSDT", Action: "open_short", Verdict: "veto"},
+			{Symbol: "SOLUSDT", Action: "open_long", Verdict: "downgrade"},
+		}},
+		ExecutionLog: []string{
+			"⏭ DOGEUSDT open_long 被决策限流跳过",
+			"✓ BTCUSDT open_long 成功",
+			"❌ BTCUSDT open_long 失败: 保证金不足",
+		},
+	}
+	decisions := cycleDecisions(record)
+	if len(decisions) != 8 {
+		t.Fatalf("决策数 = %d", len(decisions))
+	}
+
+	want := []struct {
+		outcome, err, reject, review string
+		notes                        int
+	}{
+		{outcome: "executed", review: "approve", notes: 2}, // 同币种同动作按顺序匹配：第一条成功
+		{outcome: "failed", err: "保证金不足", review: "approve", notes: 2},
+		{outcome: "vetoed", review: "veto"},
+		{outcome: "downgraded", review: "downgrade"},
+		{outcome: "skipped", notes: 1},
+		{outcome: "failed", err: "仓位不存在", reject: "reduce_only"},
+		{outcome: "no_action"},
+		{outcome: "no_action"},
+	}
+	for i, w := range want {
+		d := decisions[i]
+		if d.Outcome != w.outcome || d.Error != w.err || d.RejectReason != w.reject || d.Review != w.review || len(d.Notes) != w.notes {
+			t.Errorf("决策 %d (%s %s) = %+v, 期望 %+v", i, d.Symbol, d.Action, d, w)
+		}
+	}
+	if d := decisions[0]; d.Leverage != 5 || d.PositionSizeUSD != 1500 || d.Confidence != 90 {
+		t.Errorf("决策参数应从决策JSON解析: %+v", d)
+	}
+	if !strings.Contains(decisions[4].Notes[0], "被决策限流跳过") {
+		t.Errorf("跳过的决策应附带执行日志中的原因: %v", decisions[4].Notes)
+	}
+}
+
+func TestCycleDecisionsWithoutDecisionJSON(t *testing.T) {
+	for _, decisionJSON := range []string{"", "not json", `{"symbol": "BTCUSDT"}`} {
+		record := &DecisionRecord{DecisionJSON: decisionJSON, Decisions: []DecisionAction{{Symbol: "BTCUSDT", Action: "open_long"}}}
+		if decisions := cycleDecisions(record); decisions == nil || len(decisions) != 0 {
+			t.Errorf("决策JSON为 %q 时应返回空列表: %#v", decisionJSON, decisions)
+		}
+	}
+}
+
+func TestCycleErrors(t *testing.T) {
+	record := &DecisionRecord{
+		ErrorMessage: "获取账户信息失败",
+		Decisions: []DecisionAction{
+			{Symbol: "BTCUSDT", Action: "open_long", ProtectionError: "止损单被拒"},
+			{Symbol: "ETHUSDT", Action: "open_long"},
+		},
+		Ensemble: []EnsembleModelRecord{{Model: "deepseek"}, {Model: "qwen", Error: "超时"}},
+		ExecutionLog: []string{
+			"✓ ETHUSDT open_long 成功",
+			"❌ SOLUSDT close_short 失败: 仓位不存在",
+			"⚠️ 复核失败，按原决策执行",
+			"注意 ❌ 不在行首",
+		},
+	}
+	want := []string{
+		"获取账户信息失败",
+		"BTCUSDT open_long 保护单: 止损单被拒",
+		"集成模型 qwen: 超时",
+		"❌ SOLUSDT close_short 失败: 仓位不存在",
+		"⚠️ 复核失败，按原决策执行",
+	}
+	if got := cycleErrors(record); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("错误列表 = %q", got)
+	}
+	if got := cycleErrors(&DecisionRecord{}); got == nil || len(got) != 0 {
+		t.Errorf("没有错误时应返回空列表（JSON为[]）: %#v", got)
+	}
+}
+
+func TestCycleReportLookup(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	base := time.Date(2026, 3, 10, 9, 0, 0, 0, time.Local)
+
+	first := &DecisionRecord{InputPrompt: "行情数据", CoTTrace: "思考过程", DecisionJSON: `[]`, Success: true,
+		AccountState: AccountSnapshot{TotalBalance: 1000}, CandidateCoins: []string{"BTCUSDT"}}
+	writeRecordAt(t, l, base, first)
+	writeRecordAt(t, l, base.Add(time.Minute), &DecisionRecord{})
+	// 进程重启后周期编号从1重新计数
+	l.cycleNumber = 0
+	restarted := &DecisionRecord{ReasoningTrace: "推理", ReasoningTokens: 12, DecisionLatencyMs: 800}
+	writeRecordAt(t, l, base.Add(time.Hour), restarted)
+
+	report, err := l.CycleReport("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.DecisionID != restarted.DecisionID {
+		t.Fatalf("编号对应多条记录时应取最新的一条: %s", report.DecisionID)
+	}
+	if report.Prompt.PromptAvailable || report.Prompt.PromptChars != 0 || report.Prompt.ReasoningChars != 2 || report.Prompt.ReasoningTokens != 12 {
+		t.Errorf("没有prompt的记录: %+v", report.Prompt)
+	}
+	if report.Latency.DecisionLatencyMs != 800 || report.Decisions == nil || report.Errors == nil {
+		t.Errorf("报告 = %+v", report)
+	}
+
+	byID, err := l.CycleReport(first.DecisionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byID.CycleNumber != 1 || !byID.Success || !byID.Prompt.PromptAvailable || byID.Prompt.PromptChars != 4 || byID.Prompt.CoTChars != 4 {
+		t.Errorf("按决策ID检索（字符数按rune计）: %+v", byID.Prompt)
+	}
+	if byID.Context.Account.TotalBalance != 1000 || len(byID.Context.CandidateCoins) != 1 {
+		t.Errorf("上下文摘要: %+v", byID.Context)
+	}
+
+	for _, ref := range []string{"3", "20200101_000000_cycle1"} {
+		if _, err := l.CycleReport(ref); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("%s 应返回 ErrRecordNotFound，实际 %v", ref, err)
+		}
+	}
+	if _, err := NewDecisionLogger(t.TempDir()+"/missing").CycleReport("1"); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("日志目录为空时应返回 ErrRecordNotFound，实际 %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sharpeTrendDays 日报中夏普比率趋势的天数
+const sharpeTrendDays = 7
+
+// openLookbackDays 匹配当日平仓时向前查找开仓记录的天数
+const openLookbackDays = 7
+
+// DailyReport 每日交易日报
+type DailyReport struct {
+	Date         string  `json:"date"` // YYYY-MM-DD
+	StartEquity  float64 `json:"start_equity"`
+	EndEquity    float64 `json:"end_equity"`
+	PnL          float64 `json:"pnl"`                     // 净值变化（已包含手续费和资金费，已扣除外部资金流动）
+	PnLPct       float64 `json:"pnl_pct"`                 // 相对当日起始净值
+	ExternalFlow float64 `json:"external_flow,omitempty"` // 当日检测到的充值/提现合计
+	Cycles       int     `json:"cycles"`
+
+	Opens        int            `json:"opens"`         // 当日开仓/加仓次数
+	ClosedTrades int            `json:"closed_trades"` // 当日平仓的交易数
+	WinRate      float64        `json:"win_rate"`      // 当日平仓交易的胜率
+	BestTrade    *TradeOutcome  `json:"best_trade,omitempty"`
+	WorstTrade   *TradeOutcome  `json:"worst_trade,omitempty"`
+	Trades       []TradeOutcome `json:"trades"`
+
+	Fees    float64 `json:"fees"`    // 估算手续费（成交额 × 费率）
+	Funding float64 `json:"funding"` // 资金费净收付（正数=净收到）
+
+	SharpeTrend []DailySharpe `json:"sharpe_trend"` // 最近几天的日内夏普比率（从旧到新）
+
+	Exposure Exposure `json:"exposure"` // 日终持仓敞口（当日最后一个周期的持仓快照）
+}
+
+// DailySharpe 单日夏普比率
+type DailySharpe struct {
+	Date   string  `json:"date"`
+	Sharpe float64 `json:"sharpe"`
+}
+
+// Exposure 持仓敞口
+type Exposure struct {
+	PositionCount int                `json:"position_count"`
+	Notional      float64            `json:"notional"` // 持仓名义价值合计
+	MarginUsedPct float64            `json:"margin_used_pct"`
+	Positions     []PositionSnapshot `json:"positions"`
+}
+
+// BuildDailyReport 根据决策日志生成指定日期的日报
+// feeRatePct: 估算手续费使用的费率百分比（如0.05表示万五）
+func (l *DecisionLogger) BuildDailyReport(date time.Time, feeRatePct float64) (*DailyReport, error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	records, err := l.recordsForDay(day)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DailyReport{
+		Date:   day.Format("2006-01-02"),
+		Cycles: len(records),
+		Trades: []TradeOutcome{},
+	}
+
+	if len(records) > 0 {
+		report.StartEquity = records[0].AccountState.TotalBalance
+		report.EndEquity = records[len(records)-1].AccountState.TotalBalance
+		for _, record := range records[1:] {
+			report.ExternalFlow += record.ExternalFlow
+		}
+		report.PnL = report.EndEquity - report.StartEquity - report.ExternalFlow
+		if report.StartEquity > 0 {
+			report.PnLPct = report.PnL / report.StartEquity * 100
+		}
+
+		last := records[len(records)-1]
+		report.Exposure = Exposure{
+			PositionCount: len(last.Positions),
+			MarginUsedPct: last.AccountState.MarginUsedPct,
+			Positions:     last.Positions,
+		}
+		for _, pos := range last.Positions {
+			report.Exposure.Notional += math.Abs(pos.PositionAmt) * pos.MarkPrice
+		}
+	}
+
+	for _, record := range records {
+		for _, f := range record.FundingPayments {
+			report.Funding += f.Amount
+		}
+		for _, action := range record.Decisions {
+			if !action.Success {
+				continue
+			}
+			switch action.Action {
+			case "open_long", "open_short", "add_to_position":
+				report.Opens++
+			}
+			// 平仓记录没有数量，平仓手续费在匹配交易后按开仓数量计算
+			report.Fees += math.Abs(action.Quantity*action.Price) * feeRatePct / 100
+		}
+	}
+
+	// 平仓交易：向前几天查找对应的开仓记录
+	var history []*DecisionRecord
+	for i := openLookbackDays; i >= 1; i-- {
+		prev, err := l.recordsForDay(day.AddDate(0, 0, -i))
+		if err == nil {
+			history = append(history, prev...)
+		}
+	}
+	report.Trades = closedTrades(history, records)
+	wins := 0
+	for i := range report.Trades {
+		t := &report.Trades[i]
+		report.Fees += math.Abs(t.Quantity*t.ClosePrice) * feeRatePct / 100
+		if t.PnL > 0 {
+			wins++
+		}
+		if report.BestTrade == nil || t.PnL > report.BestTrade.PnL {
+			report.BestTrade = t
+		}
+		if report.WorstTrade == nil || t.PnL < report.WorstTrade.PnL {
+			report.WorstTrade = t
+		}
+	}
+	report.ClosedTrades = len(report.Trades)
+	if report.ClosedTrades > 0 {
+		report.WinRate = float64(wins) / float64(report.ClosedTrades) * 100
+	}
+
+	for i := sharpeTrendDays - 1; i >= 0; i-- {
+		d := day.AddDate(0, 0, -i)
+		dayRecords := records
+		if i > 0 {
+			if dayRecords, err = l.recordsForDay(d); err != nil {
+				continue
+			}
+		}
+		if len(dayRecords) == 0 {
+			continue
+		}
+		report.SharpeTrend = append(report.SharpeTrend, DailySharpe{
+			Date:   d.Format("2006-01-02"),
+			Sharpe: l.calculateSharpeRatio(dayRecords),
+		})
+	}
+
+	return report, nil
+}
+
+// recordsForDay 指定日期的决策记录（按时间正序）
+// 日期按 day 所在时区的自然日划分（夏令时切换日为23或25小时）；日志文件按服务器本地日期命名，
+// trader时区与服务器不同时，一个自然日会跨两个文件日期。
+func (l *DecisionLogger) recordsForDay(day time.Time) ([]*DecisionRecord, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+
+	first := start.In(time.Local)
+	last := end.Add(-time.Nanosecond).In(time.Local)
+	var records []*DecisionRecord
+	for d := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.Local); !d.After(last); d = d.AddDate(0, 0, 1) {
+		fileRecords, err := l.GetRecordByDate(d)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range fileRecords {
+			if !record.Timestamp.Before(start) && record.Timestamp.Before(end) {
+				records = append(records, record)
+			}
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Timestamp.Before(records[j].Timestamp) })
+	return records, nil
+}
+
+// closedTrades 匹配开平仓，返回在 records 中平仓的交易（history 只用于查找更早的开仓）
+func closedTrades(history, records []*DecisionRecord) []TradeOutcome {
+	opens := make(map[string]DecisionAction) // symbol_side -> 开仓动作
+	variants := make(map[string]string)      // symbol_side -> 开仓时的A/B测试变体
+	decisions := make(map[string]string)     // symbol_side -> 开仓的决策ID
+	track := func(action DecisionAction) (string, bool) {
+		switch action.Action {
+		case "open_long", "close_long":
+			return action.Symbol + "_long", true
+		case "open_short", "close_short":
+			return action.Symbol + "_short", true
+		}
+		return "", false
+	}
+
+	for _, record := range history {
+		for _, action := range record.Decisions {
+			key, ok := track(action)
+			if !ok || !action.Success {
+				continue
+			}
+			if strings.HasPrefix(action.Action, "open_") {
+				opens[key] = action
+				variants[key] = record.Variant
+				decisions[key] = record.DecisionID
+			} else {
+				delete(opens, key)
+			}
+		}
+	}
+
+	trades := []TradeOutcome{}
+	for _, record := range records {
+		for _, action := range record.Decisions {
+			key, ok := track(action)
+			if !ok || !action.Success {
+				continue
+			}
+			if strings.HasPrefix(action.Action, "open_") {
+				opens[key] = action
+				variants[key] = record.Variant
+				decisions[key] = record.DecisionID
+				continue
+			}
+			open, exists := opens[key]
+			if !exists {
+				continue
+			}
+			delete(opens, key)
+			openPrice := open.OpenPrice()
+
+			side := strings.TrimPrefix(action.Action, "close_")
+			pnl := open.Quantity * (action.Price - openPrice)
+			if side == "short" {
+				pnl = -pnl
+			}
+			positionValue := open.Quantity * openPrice
+			marginUsed := positionValue
+			if open.Leverage > 0 {
+				marginUsed = positionValue / float64(open.Leverage)
+			}
+			pnlPct := 0.0
+			if marginUsed > 0 {
+				pnlPct = pnl / marginUsed * 100
+			}
+			var rMultiple *float64
+			if risk := open.Quantity * math.Abs(openPrice-open.StopLoss); open.StopLoss > 0 && risk > 0 {
+				r := pnl / risk
+				rMultiple = &r
+			}
+			trades = append(trades, TradeOutcome{
+				PositionID:    open.PositionID,
+				Symbol:        action.Symbol,
+				Side:          side,
+				Quantity:      open.Quantity,
+				Leverage:      open.Leverage,
+				OpenPrice:     openPrice,
+				ClosePrice:    action.Price,
+				PositionValue: positionValue,
+				MarginUsed:    marginUsed,
+				PnL:           pnl,
+				PnLPct:        pnlPct,
+				Duration:      action.Timestamp.Sub(open.Timestamp).String(),
+				OpenTime:      open.Timestamp,
+				CloseTime:     action.Timestamp,
+				RMultiple:     rMultiple,
+				Variant:       variants[key],
+
+				OpenDecisionID:  decisions[key],
+				CloseDecisionID: record.DecisionID,
+			})
+		}
+	}
+	return trades
+}
+
+// Format 格式化为适合推送的文本
+func (r *DailyReport) Format(traderName string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📊 %s 日报 %s\n\n", traderName, r.Date))
+	if r.Cycles == 0 {
+		sb.WriteString("当日没有决策记录\n")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("净值: %.2f → %.2f USDT (%+.2f, %+.2f%%)\n", r.StartEquity, r.EndEquity, r.PnL, r.PnLPct))
+	if r.ExternalFlow != 0 {
+		sb.WriteString(fmt.Sprintf("外部资金流动: %+.2f USDT（已从盈亏中扣除）\n", r.ExternalFlow))
+	}
+	sb.WriteString(fmt.Sprintf("周期: %d | 开仓/加仓: %d | 平仓: %d | 胜率: %.1f%%\n", r.Cycles, r.Opens, r.ClosedTrades, r.WinRate))
+	if r.BestTrade != nil {
+		sb.WriteString(fmt.Sprintf("最佳: %s %s %+.2f USDT (%+.2f%%)\n", r.BestTrade.Symbol, r.BestTrade.Side, r.BestTrade.PnL, r.BestTrade.PnLPct))
+	}
+	if r.WorstTrade != nil && r.ClosedTrades > 1 {
+		sb.WriteString(fmt.Sprintf("最差: %s %s %+.2f USDT (%+.2f%%)\n", r.WorstTrade.Symbol, r.WorstTrade.Side, r.WorstTrade.PnL, r.WorstTrade.PnLPct))
+	}
+	sb.WriteString(fmt.Sprintf("手续费(估): %.2f USDT | 资金费: %+.2f USDT\n", r.Fees, r.Funding))
+
+	if len(r.SharpeTrend) > 0 {
+		values := make([]string, 0, len(r.SharpeTrend))
+		for _, s := range r.SharpeTrend {
+			values = append(values, fmt.Sprintf("%.2f", s.Sharpe))
+		}
+		sb.WriteString(fmt.Sprintf("夏普趋势(%d天): %s\n", len(r.SharpeTrend), strings.Join(values, " → ")))
+	}
+
+	sb.WriteString(fmt.Sprintf("日终敞口: %d 个持仓, 名义价值 %.2f USDT, 保证金使用率 %.1f%%\n", r.Exposure.PositionCount, r.Exposure.Notional, r.Exposure.MarginUsedPct))
+	for _, pos := range r.Exposure.Positions {
+		sb.WriteString(fmt.Sprintf("  • %s %s %.4f @ %.4f (未实现 %+.2f)\n", pos.Symbol, pos.Side, pos.PositionAmt, pos.MarkPrice, pos.UnrealizedProfit))
+	}
+	return sb.String()
+}
@@ -0,0 +1,190 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeRecordAt 按指定时间写入决策记录（文件名按服务器本地日期，与 LogDecision 一致）
+func writeRecordAt(t *testing.T, l *DecisionLogger, ts time.Time, record *DecisionRecord) {
+	t.Helper()
+	l.cycleNumber++
+	record.CycleNumber = l.cycleNumber
+	record.Timestamp = ts
+	record.DecisionID = fmt.Sprintf("%s_cycle%d", ts.In(time.Local).Format("20060102_150405"), record.CycleNumber)
+	for i := range record.Decisions {
+		if record.Decisions[i].Timestamp.IsZero() {
+			record.Decisions[i].Timestamp = ts
+		}
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(l.logDir, "decision_"+record.DecisionID+".json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildDailyReportAggregation(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	at := func(d, h int) time.Time { return day.AddDate(0, 0, d).Add(time.Duration(h) * time.Hour) }
+
+	// 前几天开仓，当日平仓
+	writeRecordAt(t, l, at(-2, 10), &DecisionRecord{AccountState: AccountSnapshot{TotalBalance: 990}, Decisions: []DecisionAction{
+		{Action: "open_long", Symbol: "ETHUSDT", Quantity: 1, Price: 100, Leverage: 5, Success: true},
+	}})
+	writeRecordAt(t, l, at(-1, 10), &DecisionRecord{AccountState: AccountSnapshot{TotalBalance: 995}, Decisions: []DecisionAction{
+		{Action: "open_short", Symbol: "BTCUSDT", Quantity: 2, Price: 50, Leverage: 2, Success: true},
+	}})
+
+	writeRecordAt(t, l, at(0, 1), &DecisionRecord{
+		AccountState:    AccountSnapshot{TotalBalance: 1000},
+		ExternalFlow:    500, // 第一个周期的资金流动发生在前一天，不计入
+		FundingPayments: []FundingRecord{{Symbol: "ETHUSDT", Amount: -1}},
+		Decisions: []DecisionAction{
+			{Action: "open_long", Symbol: "SOLUSDT", Quantity: 10, Price: 10, Leverage: 3, Success: true},
+			{Action: "open_long", Symbol: "XRPUSDT", Quantity: 100, Price: 1, Success: false}, // 失败的动作不计
+		},
+	})
+	writeRecordAt(t, l, at(0, 5), &DecisionRecord{
+		AccountState:    AccountSnapshot{TotalBalance: 1100},
+		ExternalFlow:    50,
+		FundingPayments: []FundingRecord{{Symbol: "BTCUSDT", Amount: 3}},
+		Decisions: []DecisionAction{
+			{Action: "close_long", Symbol: "ETHUSDT", Price: 120, Success: true},  // +20
+			{Action: "close_short", Symbol: "BTCUSDT", Price: 60, Success: true}, // -20
+			{Action: "close_long", Symbol: "DOGEUSDT", Price: 1, Success: true},  // 找不到开仓，不计入
+		},
+	})
+	writeRecordAt(t, l, at(0, 9), &DecisionRecord{
+		AccountState: AccountSnapshot{TotalBalance: 1080, MarginUsedPct: 12},
+		Positions:    []PositionSnapshot{{Symbol: "SOLUSDT", Side: "long", PositionAmt: 10, MarkPrice: 11, UnrealizedProfit: 10}},
+	})
+	// 次日的记录不计入
+	writeRecordAt(t, l, at(1, 1), &DecisionRecord{AccountState: AccountSnapshot{TotalBalance: 5000}})
+
+	report, err := l.BuildDailyReport(at(0, 15), 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+
+	if report.Date != "2026-03-10" || report.Cycles != 3 || report.Opens != 1 {
+		t.Fatalf("日期/周期/开仓 = %+v", report)
+	}
+	if report.StartEquity != 1000 || report.EndEquity != 1080 || report.ExternalFlow != 50 || !near(report.PnL, 30) || !near(report.PnLPct, 3) {
+		t.Errorf("净值变化应扣除当日外部资金流动: %+v", report)
+	}
+	if !near(report.Funding, 2) {
+		t.Errorf("资金费 = %.4f, 期望 2", report.Funding)
+	}
+	if report.ClosedTrades != 2 || report.WinRate != 50 {
+		t.Fatalf("平仓 = %d, 胜率 = %.1f", report.ClosedTrades, report.WinRate)
+	}
+	if report.BestTrade.Symbol != "ETHUSDT" || !near(report.BestTrade.PnL, 20) || !near(report.BestTrade.PnLPct, 100) {
+		t.Errorf("最佳交易 = %+v", report.BestTrade)
+	}
+	if report.WorstTrade.Symbol != "BTCUSDT" || report.WorstTrade.Side != "short" || !near(report.WorstTrade.PnL, -20) {
+		t.Errorf("最差交易 = %+v", report.WorstTrade)
+	}
+	// 当日开仓 10×10 + 平仓按开仓数量 1×120 + 2×60，费率 0.1%
+	if !near(report.Fees, 0.34) {
+		t.Errorf("手续费 = %.4f, 期望 0.34", report.Fees)
+	}
+	if report.Exposure.PositionCount != 1 || !near(report.Exposure.Notional, 110) || report.Exposure.MarginUsedPct != 12 {
+		t.Errorf("日终敞口取最后一个周期: %+v", report.Exposure)
+	}
+	var trend []string
+	for _, s := range report.SharpeTrend {
+		trend = append(trend, s.Date)
+	}
+	if got := strings.Join(trend, ","); got != "2026-03-08,2026-03-09,2026-03-10" {
+		t.Errorf("夏普趋势只含有记录的日期（从旧到新）: %s", got)
+	}
+
+	text := report.Format("alpha")
+	for _, want := range []string{
+		"📊 alpha 日报 2026-03-10",
+		"净值: 1000.00 → 1080.00 USDT (+30.00, +3.00%)",
+		"外部资金流动: +50.00 USDT",
+		"周期: 3 | 开仓/加仓: 1 | 平仓: 2 | 胜率: 50.0%",
+		"最佳: ETHUSDT long +20.00 USDT",
+		"最差: BTCUSDT short -20.00 USDT",
+		"• SOLUSDT long 10.0000 @ 11.0000 (未实现 +10.00)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("日报文本缺少 %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestBuildDailyReportDayBoundary(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	// trader时区与服务器不同：一个自然日跨两个文件日期
+	zone := time.FixedZone("UTC+8", 8*3600)
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, zone)
+
+	writeRecordAt(t, l, day.Add(-time.Second), &DecisionRecord{AccountState: AccountSnapshot{TotalBalance: 900}, Decisions: []DecisionAction{
+		{Action: "open_long", Symbol: "ETHUSDT", Quantity: 1, Price: 100, Success: true},
+	}})
+	writeRecordAt(t, l, day, &DecisionRecord{AccountState: AccountSnapshot{TotalBalance: 1000}})
+	writeRecordAt(t, l, day.Add(24*time.Hour-time.Second), &DecisionRecord{AccountState: AccountSnapshot{TotalBalance: 1010}, Decisions: []DecisionAction{
+		{Action: "close_long", Symbol: "ETHUSDT", Price: 110, Success: true},
+	}})
+	writeRecordAt(t, l, day.Add(24*time.Hour), &DecisionRecord{AccountState: AccountSnapshot{TotalBalance: 2000}})
+
+	report, err := l.BuildDailyReport(day.Add(12*time.Hour), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 00:00:00 计入当日，次日 00:00:00 不计入
+	if report.Cycles != 2 || report.StartEquity != 1000 || report.EndEquity != 1010 {
+		t.Fatalf("按trader时区的自然日划分: %+v", report)
+	}
+	// 前一天最后一秒的开仓作为历史匹配，当日平仓计入
+	if report.ClosedTrades != 1 || report.Opens != 0 || math.Abs(report.Trades[0].PnL-10) > 1e-9 {
+		t.Fatalf("跨日交易: %+v", report.Trades)
+	}
+
+	prev, err := l.BuildDailyReport(day.Add(-time.Hour), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev.Cycles != 1 || prev.Opens != 1 || prev.ClosedTrades != 0 {
+		t.Fatalf("前一天只有开仓: %+v", prev)
+	}
+}
+
+func TestBuildDailyReportEmptyDay(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	// 前一天有记录，当天没有
+	writeRecordAt(t, l, day.Add(-time.Hour), &DecisionRecord{AccountState: AccountSnapshot{TotalBalance: 1000}, Decisions: []DecisionAction{
+		{Action: "open_long", Symbol: "ETHUSDT", Quantity: 1, Price: 100, Success: true},
+	}})
+
+	report, err := l.BuildDailyReport(day, 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Cycles != 0 || report.PnL != 0 || report.Fees != 0 || report.BestTrade != nil || report.Exposure.PositionCount != 0 {
+		t.Fatalf("空日报 = %+v", report)
+	}
+	if report.Trades == nil || len(report.Trades) != 0 {
+		t.Errorf("没有交易时应为空列表（JSON为[]）: %#v", report.Trades)
+	}
+	if len(report.SharpeTrend) != 1 || report.SharpeTrend[0].Date != "2026-03-09" {
+		t.Errorf("夏普趋势跳过没有记录的当天: %+v", report.SharpeTrend)
+	}
+	if text := report.Format("alpha"); !strings.Contains(text, "当日没有决策记录") || strings.Contains(text, "净值") {
+		t.Errorf("空日报文本:\n%s", text)
+	}
+}
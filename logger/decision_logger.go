@@ -2,19 +2,28 @@ package logger
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
+	"nofx/market"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
+// ErrRecordNotFound 指定ID的决策记录不存在
+var ErrRecordNotFound = errors.New("决策记录不存在")
+
 // DecisionRecord 决策记录
 type DecisionRecord struct {
+	DecisionID     string             `json:"decision_id"`     // 决策ID（同一trader内唯一，用于检索归档的prompt）
 	Timestamp      time.Time          `json:"timestamp"`       // 决策时间
 	CycleNumber    int                `json:"cycle_number"`    // 周期编号
-	InputPrompt    string             `json:"input_prompt"`    // 发送给AI的输入prompt
+	SchemaVersion  int                `json:"schema_version"`  // 决策JSON格式版本
+	InputPrompt    string             `json:"input_prompt"`    // 发送给AI的输入prompt（启用归档后为空，通过 GetPrompt 检索）
 	CoTTrace       string             `json:"cot_trace"`       // AI思维链（输出）
 	DecisionJSON   string             `json:"decision_json"`   // 决策JSON
 	AccountState   AccountSnapshot    `json:"account_state"`   // 账户状态快照
@@ -24,6 +33,59 @@ type DecisionRecord struct {
 	ExecutionLog   []string           `json:"execution_log"`   // 执行日志
 	Success        bool               `json:"success"`         // 是否成功
 	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
+
+	ParseDiagnostics string `json:"parse_diagnostics,omitempty"` // 决策JSON修复/降级诊断摘要（如果有）
+	ReasoningTrace   string `json:"reasoning_trace,omitempty"`   // 推理模型的推理过程（与思维链分开记录）
+	ReasoningTokens  int    `json:"reasoning_tokens,omitempty"`  // 推理消耗的token数
+	PromptArchived   bool   `json:"prompt_archived,omitempty"`   // 输入prompt已压缩归档（不再内联在记录中）
+	MarketSnapshot   bool   `json:"market_snapshot,omitempty"`   // 本周期的行情数据已保存快照（通过 GetMarketSnapshot 检索）
+
+	MarketData map[string]*market.Data `json:"-"` // 本周期AI看到的行情数据（启用快照时由 LogDecision 单独压缩保存）
+
+	FundingPayments []FundingRecord `json:"funding_payments,omitempty"` // 本周期新增的资金费收付记录
+
+	ExternalFlow float64 `json:"external_flow,omitempty"` // 本周期检测到的外部资金流动（充值为正、提现为负，统计收益时扣除）
+
+	TraceID string `json:"trace_id,omitempty"` // 本周期的链路追踪ID（启用追踪时，可在Jaeger中按此ID查找）
+
+	Imported bool `json:"imported,omitempty"` // 从交易所成交历史导入的记录（没有AI决策和账户快照）
+
+	Review *ReviewRecord `json:"review,omitempty"` // 二次复核记录（启用复核且有开仓/加仓决策时；DecisionJSON 为第一轮决策）
+
+	Ensemble []EnsembleModelRecord `json:"ensemble,omitempty"` // 集成模式下各模型的输出（DecisionJSON 为合并结果）
+
+	MarketDataAt      time.Time `json:"market_data_at,omitempty"`      // 行情快照时间
+	AIResponseAt      time.Time `json:"ai_response_at,omitempty"`      // AI返回决策的时间
+	DecisionLatencyMs int64     `json:"decision_latency_ms,omitempty"` // 行情快照到AI返回的耗时
+
+	Experiment string `json:"experiment,omitempty"` // A/B测试的实验名称（未参与实验时为空）
+	Variant    string `json:"variant,omitempty"`    // 本周期使用的实验变体（策略配置名称）
+
+	Annotations []Annotation `json:"annotations,omitempty"` // 操作员备注（单独保存，读取记录时附带）
+}
+
+// EnsembleModelRecord 集成模式下单个模型的输出
+type EnsembleModelRecord struct {
+	Model        string `json:"model"`
+	CoTTrace     string `json:"cot_trace"`
+	DecisionJSON string `json:"decision_json"`
+	Error        string `json:"error,omitempty"`
+}
+
+// ReviewRecord 二次复核记录
+type ReviewRecord struct {
+	Model    string          `json:"model"`
+	CoTTrace string          `json:"cot_trace"`
+	Verdicts []ReviewVerdict `json:"verdicts"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// ReviewVerdict 复核模型对单条决策的结论
+type ReviewVerdict struct {
+	Symbol  string `json:"symbol"`
+	Action  string `json:"action"`
+	Verdict string `json:"verdict"` // approve / veto / downgrade
+	Reason  string `json:"reason"`
 }
 
 // AccountSnapshot 账户状态快照
@@ -37,6 +99,7 @@ type AccountSnapshot struct {
 
 // PositionSnapshot 持仓快照
 type PositionSnapshot struct {
+	PositionID       string  `json:"position_id,omitempty"` // 持仓ID（开仓时生成，贯穿持仓的整个生命周期）
 	Symbol           string  `json:"symbol"`
 	Side             string  `json:"side"`
 	PositionAmt      float64 `json:"position_amt"`
@@ -45,25 +108,80 @@ type PositionSnapshot struct {
 	UnrealizedProfit float64 `json:"unrealized_profit"`
 	Leverage         float64 `json:"leverage"`
 	LiquidationPrice float64 `json:"liquidation_price"`
+
+	CumulativeFunding float64 `json:"cumulative_funding,omitempty"` // 持仓期间累计资金费（正数=净收到）
+}
+
+// FundingRecord 资金费收付记录
+type FundingRecord struct {
+	ID     string    `json:"id"`
+	Symbol string    `json:"symbol"`
+	Side   string    `json:"side"`
+	Amount float64   `json:"amount"` // 正数=收到，负数=支付
+	Time   time.Time `json:"time"`   // 结算时间
+	Source string    `json:"source"` // "exchange" 交易所流水, "estimated" 按费率估算
 }
 
 // DecisionAction 决策动作
 type DecisionAction struct {
-	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short
-	Symbol    string    `json:"symbol"`    // 币种
-	Quantity  float64   `json:"quantity"`  // 数量
-	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
-	Price     float64   `json:"price"`     // 执行价格
-	OrderID   int64     `json:"order_id"`  // 订单ID
-	Timestamp time.Time `json:"timestamp"` // 执行时间
-	Success   bool      `json:"success"`   // 是否成功
-	Error     string    `json:"error"`     // 错误信息
+	Action    string    `json:"action"`               // open_long, open_short, close_long, close_short, partial_close, add_to_position, adjust_sl, adjust_tp, cancel_orders
+	Symbol    string    `json:"symbol"`               // 币种
+	Quantity  float64   `json:"quantity"`             // 数量
+	Leverage  int       `json:"leverage"`             // 杠杆（开仓时）
+	Price     float64   `json:"price"`                // 执行价格
+	OrderID   int64     `json:"order_id"`             // 订单ID
+	OrderType string    `json:"order_type,omitempty"` // 实际使用的下单类型（market/ioc/fok/post_only）
+	Timestamp time.Time `json:"timestamp"`            // 执行时间
+	Success   bool      `json:"success"`              // 是否成功
+	Error     string    `json:"error"`                // 错误信息
+	StopLoss  float64   `json:"stop_loss,omitempty"`  // 止损价（开仓/加仓/调整保护单后挂出的止损，开仓时用于计算交易的R倍数）
+
+	PositionID string `json:"position_id,omitempty"` // 作用的持仓ID（撤单等不针对单个持仓的动作为空）
+
+	RejectReason string `json:"reject_reason,omitempty"` // 交易所拒单原因（见 decision.Reject*，非交易所拒单时为空）
+
+	// 执行质量（下单类动作，用于滑点统计）
+	Side          string  `json:"side,omitempty"`           // 订单方向 buy/sell
+	IntendedPrice float64 `json:"intended_price,omitempty"` // 决策时的市场价格（AI看到的价格）
+	LimitPrice    float64 `json:"limit_price,omitempty"`    // 提交的限价（市价单为0）
+	FillPrice     float64 `json:"fill_price,omitempty"`     // 实际成交均价（交易所未返回时为0）
+
+	// 大单拆分执行（未拆单时为空）
+	Slices       int    `json:"slices,omitempty"`        // 实际成交的子单数
+	SliceAborted string `json:"slice_aborted,omitempty"` // 放弃剩余子单的原因
+
+	// 成交跟踪（开仓/加仓；Quantity 为实际成交数量）
+	RequestedQuantity float64 `json:"requested_quantity,omitempty"` // 请求的数量（部分成交时大于 Quantity）
+	RemainderRetries  int     `json:"remainder_retries,omitempty"`  // 对未成交剩余部分的补单次数
+
+	// 保护单（开仓/加仓/调整保护单后挂出的止盈；挂单失败时记录原因，下个周期重试）
+	TakeProfit      float64 `json:"take_profit,omitempty"`
+	ProtectionError string  `json:"protection_error,omitempty"`
+
+	// 实际入场（开仓；风险回报比按止损止盈和入场价计算）
+	EntryPrice        float64 `json:"entry_price,omitempty"`         // 实际入场价（成交均价，交易所未返回时为下单时市价）
+	PlannedRiskReward float64 `json:"planned_risk_reward,omitempty"` // 按下单时市价计算的风险回报比
+	RiskReward        float64 `json:"risk_reward,omitempty"`         // 按实际入场价计算的风险回报比
+}
+
+// OpenPrice 开仓的入场价：有实际入场价时使用，旧记录回退到下单时市价
+func (a *DecisionAction) OpenPrice() float64 {
+	if a.EntryPrice > 0 {
+		return a.EntryPrice
+	}
+	return a.Price
 }
 
 // DecisionLogger 决策日志记录器
 type DecisionLogger struct {
-	logDir      string
-	cycleNumber int
+	logDir        string
+	cycleNumber   int
+	promptArchive *PromptArchive         // prompt快照归档（nil表示prompt内联保存在决策记录中）
+	marketArchive *MarketSnapshotArchive // 行情快照归档（nil表示不保存）
+	annotationsMu sync.Mutex             // 保护备注文件的读写
+
+	searchMu      sync.Mutex           // 保护决策检索的向量缓存
+	searchVectors map[string][]float64 // 向量模型|文本键 -> 决策理由的向量（决策检索用）
 }
 
 // NewDecisionLogger 创建决策日志记录器
@@ -89,10 +207,30 @@ func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 	record.CycleNumber = l.cycleNumber
 	record.Timestamp = time.Now()
 
-	// 生成文件名：decision_YYYYMMDD_HHMMSS_cycleN.json
-	filename := fmt.Sprintf("decision_%s_cycle%d.json",
+	// 决策ID：YYYYMMDD_HHMMSS_cycleN，文件名：decision_{决策ID}.json
+	record.DecisionID = fmt.Sprintf("%s_cycle%d",
 		record.Timestamp.Format("20060102_150405"),
 		record.CycleNumber)
+	filename := fmt.Sprintf("decision_%s.json", record.DecisionID)
+
+	// 启用归档时，prompt压缩单独保存，记录中只保留决策ID
+	if l.promptArchive != nil && record.InputPrompt != "" {
+		if err := l.promptArchive.Save(record.DecisionID, record.InputPrompt); err != nil {
+			fmt.Printf("⚠ 归档prompt失败，改为内联保存: %v\n", err)
+		} else {
+			record.InputPrompt = ""
+			record.PromptArchived = true
+		}
+	}
+
+	// 启用行情快照时，保存AI看到的原始行情数据
+	if l.marketArchive != nil && len(record.MarketData) > 0 {
+		if err := l.marketArchive.Save(record.DecisionID, record.MarketData); err != nil {
+			fmt.Printf("⚠ 保存行情快照失败: %v\n", err)
+		} else {
+			record.MarketSnapshot = true
+		}
+	}
 
 	filepath := filepath.Join(l.logDir, filename)
 
@@ -111,6 +249,116 @@ func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 	return nil
 }
 
+// EnablePromptArchive 启用prompt快照归档（保存在日志目录的 prompts 子目录，retentionDays<=0 表示永久保留）
+func (l *DecisionLogger) EnablePromptArchive(retentionDays int) error {
+	archive, err := NewPromptArchive(filepath.Join(l.logDir, "prompts"), retentionDays)
+	if err != nil {
+		return err
+	}
+	l.promptArchive = archive
+	return nil
+}
+
+// GetPrompt 获取指定决策发送给AI的原始prompt
+// 优先从归档读取；未归档的旧记录从决策记录中读取内联的prompt
+func (l *DecisionLogger) GetPrompt(decisionID string) (string, error) {
+	if l.promptArchive != nil {
+		prompt, err := l.promptArchive.Load(decisionID)
+		if err == nil {
+			return prompt, nil
+		}
+		if !errors.Is(err, ErrPromptNotFound) {
+			return "", err
+		}
+	}
+
+	record, err := l.GetRecord(decisionID)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return "", ErrPromptNotFound
+		}
+		return "", err
+	}
+	if record.InputPrompt == "" {
+		return "", ErrPromptNotFound // 已归档但快照已过期
+	}
+	return record.InputPrompt, nil
+}
+
+// GetRecord 按决策ID读取决策记录
+func (l *DecisionLogger) GetRecord(decisionID string) (*DecisionRecord, error) {
+	if !validDecisionID(decisionID) {
+		return nil, fmt.Errorf("无效的决策ID: %q", decisionID)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(l.logDir, fmt.Sprintf("decision_%s.json", decisionID)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("读取决策记录失败: %w", err)
+	}
+	var record DecisionRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("解析决策记录失败: %w", err)
+	}
+	record.DecisionID = decisionID
+	l.attachAnnotations(&record)
+	return &record, nil
+}
+
+// CleanOldPrompts 按归档保留天数清理过期的prompt快照
+func (l *DecisionLogger) CleanOldPrompts() error {
+	if l.promptArchive == nil {
+		return nil
+	}
+	removedCount, err := l.promptArchive.Cleanup()
+	if err != nil {
+		return err
+	}
+	if removedCount > 0 {
+		fmt.Printf("🗑️ 已清理 %d 个过期prompt快照（%d天前）\n", removedCount, l.promptArchive.retentionDays)
+	}
+	return nil
+}
+
+// EnableMarketSnapshots 启用行情快照（保存在日志目录的 market 子目录，retentionDays<=0 表示永久保留）
+func (l *DecisionLogger) EnableMarketSnapshots(retentionDays int) error {
+	archive, err := NewMarketSnapshotArchive(filepath.Join(l.logDir, "market"), retentionDays)
+	if err != nil {
+		return err
+	}
+	l.marketArchive = archive
+	return nil
+}
+
+// Dir 决策日志目录
+func (l *DecisionLogger) Dir() string {
+	return l.logDir
+}
+
+// GetMarketSnapshot 获取指定决策周期AI看到的行情数据
+func (l *DecisionLogger) GetMarketSnapshot(decisionID string) (map[string]*market.Data, error) {
+	if l.marketArchive == nil {
+		return nil, ErrSnapshotNotFound
+	}
+	return l.marketArchive.Load(decisionID)
+}
+
+// CleanOldMarketSnapshots 按保留天数清理过期的行情快照
+func (l *DecisionLogger) CleanOldMarketSnapshots() error {
+	if l.marketArchive == nil {
+		return nil
+	}
+	removedCount, err := l.marketArchive.Cleanup()
+	if err != nil {
+		return err
+	}
+	if removedCount > 0 {
+		fmt.Printf("🗑️ 已清理 %d 个过期行情快照（%d天前）\n", removedCount, l.marketArchive.retentionDays)
+	}
+	return nil
+}
+
 // GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
 func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
 	files, err := ioutil.ReadDir(l.logDir)
@@ -123,7 +371,7 @@ func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
 	count := 0
 	for i := len(files) - 1; i >= 0 && count < n; i-- {
 		file := files[i]
-		if file.IsDir() {
+		if file.IsDir() || !isRecordFile(file.Name()) {
 			continue
 		}
 
@@ -137,6 +385,7 @@ func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
 		if err := json.Unmarshal(data, &record); err != nil {
 			continue
 		}
+		fillDecisionID(&record, file.Name())
 
 		records = append(records, &record)
 		count++
@@ -146,6 +395,7 @@ func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
 	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
 		records[i], records[j] = records[j], records[i]
 	}
+	l.attachAnnotations(records...)
 
 	return records, nil
 }
@@ -161,8 +411,8 @@ func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, err
 	}
 
 	var records []*DecisionRecord
-	for _, filepath := range files {
-		data, err := ioutil.ReadFile(filepath)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
 		if err != nil {
 			continue
 		}
@@ -171,6 +421,7 @@ func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, err
 		if err := json.Unmarshal(data, &record); err != nil {
 			continue
 		}
+		fillDecisionID(&record, filepath.Base(file))
 
 		records = append(records, &record)
 	}
@@ -178,6 +429,18 @@ func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, err
 	return records, nil
 }
 
+// isRecordFile 是否为决策记录文件（目录中还有trader的状态文件，如 operations.json、risk_state.json）
+func isRecordFile(name string) bool {
+	return strings.HasPrefix(name, "decision_") && strings.HasSuffix(name, ".json")
+}
+
+// fillDecisionID 旧版本记录没有decision_id字段，从文件名 decision_{决策ID}.json 还原
+func fillDecisionID(record *DecisionRecord, filename string) {
+	if record.DecisionID == "" {
+		record.DecisionID = strings.TrimSuffix(strings.TrimPrefix(filename, "decision_"), ".json")
+	}
+}
+
 // CleanOldRecords 清理N天前的旧记录
 func (l *DecisionLogger) CleanOldRecords(days int) error {
 	cutoffTime := time.Now().AddDate(0, 0, -days)
@@ -189,7 +452,7 @@ func (l *DecisionLogger) CleanOldRecords(days int) error {
 
 	removedCount := 0
 	for _, file := range files {
-		if file.IsDir() {
+		if file.IsDir() || !isRecordFile(file.Name()) {
 			continue
 		}
 
@@ -220,7 +483,7 @@ func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 	stats := &Statistics{}
 
 	for _, file := range files {
-		if file.IsDir() {
+		if file.IsDir() || !isRecordFile(file.Name()) {
 			continue
 		}
 
@@ -235,8 +498,6 @@ func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 			continue
 		}
 
-		stats.TotalCycles++
-
 		for _, action := range record.Decisions {
 			if action.Success {
 				switch action.Action {
@@ -248,6 +509,11 @@ func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 			}
 		}
 
+		// 导入的记录只计入开平仓次数，不算交易周期
+		if record.Imported {
+			continue
+		}
+		stats.TotalCycles++
 		if record.Success {
 			stats.SuccessfulCycles++
 		} else {
@@ -269,20 +535,28 @@ type Statistics struct {
 
 // TradeOutcome 单笔交易结果
 type TradeOutcome struct {
-	Symbol        string    `json:"symbol"`         // 币种
-	Side          string    `json:"side"`           // long/short
-	Quantity      float64   `json:"quantity"`       // 仓位数量
-	Leverage      int       `json:"leverage"`       // 杠杆倍数
-	OpenPrice     float64   `json:"open_price"`     // 开仓价
-	ClosePrice    float64   `json:"close_price"`    // 平仓价
-	PositionValue float64   `json:"position_value"` // 仓位价值（quantity × openPrice）
-	MarginUsed    float64   `json:"margin_used"`    // 保证金使用（positionValue / leverage）
-	PnL           float64   `json:"pn_l"`           // 盈亏（USDT）
-	PnLPct        float64   `json:"pn_l_pct"`       // 盈亏百分比（相对保证金）
-	Duration      string    `json:"duration"`       // 持仓时长
-	OpenTime      time.Time `json:"open_time"`      // 开仓时间
-	CloseTime     time.Time `json:"close_time"`     // 平仓时间
-	WasStopLoss   bool      `json:"was_stop_loss"`  // 是否止损
+	PositionID    string    `json:"position_id,omitempty"` // 持仓ID（开仓记录没有时为空）
+	Symbol        string    `json:"symbol"`                // 币种
+	Side          string    `json:"side"`                  // long/short
+	Quantity      float64   `json:"quantity"`              // 仓位数量
+	Leverage      int       `json:"leverage"`              // 杠杆倍数
+	OpenPrice     float64   `json:"open_price"`            // 开仓价
+	ClosePrice    float64   `json:"close_price"`           // 平仓价
+	PositionValue float64   `json:"position_value"`        // 仓位价值（quantity × openPrice）
+	MarginUsed    float64   `json:"margin_used"`           // 保证金使用（positionValue / leverage）
+	PnL           float64   `json:"pn_l"`                  // 盈亏（USDT）
+	PnLPct        float64   `json:"pn_l_pct"`              // 盈亏百分比（相对保证金）
+	Duration      string    `json:"duration"`              // 持仓时长
+	OpenTime      time.Time `json:"open_time"`             // 开仓时间
+	CloseTime     time.Time `json:"close_time"`            // 平仓时间
+	WasStopLoss   bool      `json:"was_stop_loss"`         // 是否止损
+	RMultiple     *float64  `json:"r_multiple,omitempty"`  // R倍数（盈亏 / 开仓止损对应的风险），开仓没有记录止损时为空
+	Variant       string    `json:"variant,omitempty"`     // 开仓时的A/B测试变体
+
+	OpenDecisionID  string   `json:"open_decision_id,omitempty"`  // 开仓的决策ID
+	CloseDecisionID string   `json:"close_decision_id,omitempty"` // 平仓的决策ID
+	Tags            []string `json:"tags,omitempty"`              // 操作员标签（持仓和开平仓决策上的）
+	Notes           []string `json:"notes,omitempty"`             // 操作员备注
 }
 
 // PerformanceAnalysis 交易表现分析
@@ -358,11 +632,13 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 				case "open_long", "open_short":
 					// 记录开仓
 					openPositions[posKey] = map[string]interface{}{
-						"side":      side,
-						"openPrice": action.Price,
-						"openTime":  action.Timestamp,
-						"quantity":  action.Quantity,
-						"leverage":  action.Leverage,
+						"side":       side,
+						"openPrice":  action.OpenPrice(),
+						"openTime":   action.Timestamp,
+						"quantity":   action.Quantity,
+						"leverage":   action.Leverage,
+						"positionID": action.PositionID,
+						"decisionID": record.DecisionID,
 					}
 				case "close_long", "close_short":
 					// 移除已平仓记录
@@ -392,11 +668,13 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 			case "open_long", "open_short":
 				// 更新开仓记录（可能已经在预填充时记录过了）
 				openPositions[posKey] = map[string]interface{}{
-					"side":      side,
-					"openPrice": action.Price,
-					"openTime":  action.Timestamp,
-					"quantity":  action.Quantity,
-					"leverage":  action.Leverage,
+					"side":       side,
+					"openPrice":  action.OpenPrice(),
+					"openTime":   action.Timestamp,
+					"quantity":   action.Quantity,
+					"leverage":   action.Leverage,
+					"positionID": action.PositionID,
+					"decisionID": record.DecisionID,
 				}
 
 			case "close_long", "close_short":
@@ -427,7 +705,10 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					}
 
 					// 记录交易结果
+					positionID, _ := openPos["positionID"].(string)
+					openDecisionID, _ := openPos["decisionID"].(string)
 					outcome := TradeOutcome{
+						PositionID:    positionID,
 						Symbol:        symbol,
 						Side:          side,
 						Quantity:      quantity,
@@ -441,6 +722,9 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						Duration:      action.Timestamp.Sub(openTime).String(),
 						OpenTime:      openTime,
 						CloseTime:     action.Timestamp,
+
+						OpenDecisionID:  openDecisionID,
+						CloseDecisionID: record.DecisionID,
 					}
 
 					analysis.RecentTrades = append(analysis.RecentTrades, outcome)
@@ -536,6 +820,8 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		}
 	}
 
+	l.annotationIndex().annotateTrades(analysis.RecentTrades)
+
 	// 计算夏普比率（需要至少2个数据点）
 	analysis.SharpeRatio = l.calculateSharpeRatio(records)
 
@@ -552,12 +838,14 @@ func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64
 	// 提取每个周期的账户净值
 	// 注意：TotalBalance字段实际存储的是TotalEquity（账户总净值）
 	// TotalUnrealizedProfit字段实际存储的是TotalPnL（相对初始余额的盈亏）
-	var equities []float64
+	// ExternalFlow 为该周期检测到的充值/提现，计算收益时从净值变化中扣除
+	var equities, flows []float64
 	for _, record := range records {
 		// 直接使用TotalBalance，因为它已经是完整的账户净值
 		equity := record.AccountState.TotalBalance
 		if equity > 0 {
 			equities = append(equities, equity)
+			flows = append(flows, record.ExternalFlow)
 		}
 	}
 
@@ -569,7 +857,7 @@ func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64
 	var returns []float64
 	for i := 1; i < len(equities); i++ {
 		if equities[i-1] > 0 {
-			periodReturn := (equities[i] - equities[i-1]) / equities[i-1]
+			periodReturn := (equities[i] - flows[i] - equities[i-1]) / equities[i-1]
 			returns = append(returns, periodReturn)
 		}
 	}
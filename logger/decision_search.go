@@ -0,0 +1,246 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/vectorstore"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 历史决策检索
+// 在决策理由（每条决策的 reasoning 和整个周期的思维链）中检索，如"所有提到 funding squeeze 的决策"。
+// 关键字匹配要求查询中的所有词都出现（不区分大小写）；提供向量模型时还按相似度匹配没有出现关键字的相近表述，
+// 关键字命中的结果得分加1，排在纯语义命中之前。同一段理由的向量缓存在内存中，只计算一次。
+
+// 检索的文本字段
+const (
+	SearchFieldReasoning = "reasoning" // 单条决策的理由
+	SearchFieldCoT       = "cot"       // 周期的思维链
+)
+
+const (
+	searchSnippetRunes = 60   // 摘要中命中位置前后保留的字符数
+	searchEmbedRunes   = 2000 // 向量化时截取的最大字符数（思维链可能很长）
+	searchEmbedBatch   = 64   // 每次请求向量化的文本数
+)
+
+// DecisionSearchQuery 检索条件
+type DecisionSearchQuery struct {
+	Text     string               // 查询文本（空白分隔的词）
+	From, To time.Time            // 决策时间范围（含两端）
+	Symbol   string               // 只检索该币种的决策理由（为空时不限币种，同时检索思维链）
+	Limit    int                  // 最多返回条数（<=0 不限制）
+	Embedder vectorstore.Embedder // 语义匹配的向量模型（nil时只做关键字匹配）
+	MinScore float64              // 语义匹配的最低相似度
+}
+
+// DecisionSearchHit 一条命中
+type DecisionSearchHit struct {
+	DecisionID   string    `json:"decision_id"`
+	CycleNumber  int       `json:"cycle_number"`
+	Timestamp    time.Time `json:"timestamp"`
+	Symbol       string    `json:"symbol,omitempty"` // 思维链命中时为空
+	Action       string    `json:"action,omitempty"`
+	Field        string    `json:"field"` // reasoning / cot
+	Snippet      string    `json:"snippet"`
+	KeywordMatch bool      `json:"keyword_match"`
+	Similarity   float64   `json:"similarity,omitempty"` // 与查询的余弦相似度（未启用语义匹配时为0）
+	Score        float64   `json:"score"`
+}
+
+// searchDoc 一段可检索的文本
+type searchDoc struct {
+	key  string // 决策ID + 字段 + 序号（向量缓存的键）
+	hit  DecisionSearchHit
+	text string
+}
+
+// SearchDecisions 在时间范围内的决策记录中检索，按得分从高到低（同分时新的在前）
+func (l *DecisionLogger) SearchDecisions(q DecisionSearchQuery) ([]DecisionSearchHit, error) {
+	terms := strings.Fields(strings.ToLower(q.Text))
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("查询文本不能为空")
+	}
+
+	var docs []searchDoc
+	first, last := q.From.In(time.Local), q.To.In(time.Local)
+	for d := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.Local); !d.After(last); d = d.AddDate(0, 0, 1) {
+		records, err := l.GetRecordByDate(d)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if record.Timestamp.Before(q.From) || record.Timestamp.After(q.To) {
+				continue
+			}
+			docs = append(docs, searchDocs(record, q.Symbol)...)
+		}
+	}
+
+	var similarities []float64
+	if q.Embedder != nil && len(docs) > 0 {
+		var err error
+		if similarities, err = l.searchSimilarities(q.Embedder, q.Text, docs); err != nil {
+			return nil, fmt.Errorf("语义检索失败: %w", err)
+		}
+	}
+
+	hits := []DecisionSearchHit{}
+	for i, doc := range docs {
+		hit := doc.hit
+		lower := strings.ToLower(doc.text)
+		hit.KeywordMatch = true
+		for _, term := range terms {
+			if !strings.Contains(lower, term) {
+				hit.KeywordMatch = false
+				break
+			}
+		}
+		if similarities != nil {
+			hit.Similarity = similarities[i]
+		}
+		if !hit.KeywordMatch && (similarities == nil || hit.Similarity < q.MinScore) {
+			continue
+		}
+		hit.Score = hit.Similarity
+		if hit.KeywordMatch {
+			hit.Score++
+		}
+		hit.Snippet = searchSnippet(doc.text, terms)
+		hits = append(hits, hit)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Timestamp.After(hits[j].Timestamp)
+	})
+	if q.Limit > 0 && len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return hits, nil
+}
+
+// searchDocs 决策记录中可检索的文本：每条决策的理由，以及（不限币种时）思维链
+func searchDocs(record *DecisionRecord, symbol string) []searchDoc {
+	base := DecisionSearchHit{DecisionID: record.DecisionID, CycleNumber: record.CycleNumber, Timestamp: record.Timestamp}
+	var docs []searchDoc
+	if symbol == "" && strings.TrimSpace(record.CoTTrace) != "" {
+		hit := base
+		hit.Field = SearchFieldCoT
+		docs = append(docs, searchDoc{key: record.DecisionID + "/cot", hit: hit, text: record.CoTTrace})
+	}
+
+	var decisions []struct {
+		Symbol    string `json:"symbol"`
+		Action    string `json:"action"`
+		Reasoning string `json:"reasoning"`
+	}
+	if record.DecisionJSON == "" || json.Unmarshal([]byte(record.DecisionJSON), &decisions) != nil {
+		return docs
+	}
+	for i, d := range decisions {
+		if strings.TrimSpace(d.Reasoning) == "" || (symbol != "" && !strings.EqualFold(d.Symbol, symbol)) {
+			continue
+		}
+		hit := base
+		hit.Symbol, hit.Action, hit.Field = d.Symbol, d.Action, SearchFieldReasoning
+		docs = append(docs, searchDoc{key: fmt.Sprintf("%s/%d", record.DecisionID, i), hit: hit, text: d.Reasoning})
+	}
+	return docs
+}
+
+// searchSimilarities 每段文本与查询的余弦相似度（向量已归一化，即点积）
+func (l *DecisionLogger) searchSimilarities(embedder vectorstore.Embedder, query string, docs []searchDoc) ([]float64, error) {
+	vectors, err := embedder.Embed([]string{query})
+	if err != nil {
+		return nil, err
+	}
+	queryVec := vectors[0]
+	prefix := embedder.Name() + "|"
+
+	l.searchMu.Lock()
+	if l.searchVectors == nil {
+		l.searchVectors = make(map[string][]float64)
+	}
+	var missing []int
+	for i, doc := range docs {
+		if _, ok := l.searchVectors[prefix+doc.key]; !ok {
+			missing = append(missing, i)
+		}
+	}
+	l.searchMu.Unlock()
+
+	for start := 0; start < len(missing); start += searchEmbedBatch {
+		batch := missing[start:min(start+searchEmbedBatch, len(missing))]
+		texts := make([]string, len(batch))
+		for j, i := range batch {
+			texts[j] = truncateRunes(docs[i].text, searchEmbedRunes)
+		}
+		embedded, err := embedder.Embed(texts)
+		if err != nil {
+			return nil, err
+		}
+		l.searchMu.Lock()
+		for j, i := range batch {
+			l.searchVectors[prefix+docs[i].key] = embedded[j]
+		}
+		l.searchMu.Unlock()
+	}
+
+	similarities := make([]float64, len(docs))
+	l.searchMu.Lock()
+	defer l.searchMu.Unlock()
+	for i, doc := range docs {
+		vec := l.searchVectors[prefix+doc.key]
+		if len(vec) != len(queryVec) {
+			continue
+		}
+		for k := range queryVec {
+			similarities[i] += queryVec[k] * vec[k]
+		}
+	}
+	return similarities, nil
+}
+
+// searchSnippet 第一个命中词前后的文本（没有命中词时取开头）
+func searchSnippet(text string, terms []string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	pos := -1
+	for _, term := range terms {
+		if idx := strings.Index(string(lower), term); idx >= 0 {
+			pos = len([]rune(string(lower)[:idx]))
+			break
+		}
+	}
+	if len(runes) != len(lower) {
+		pos = -1 // 大小写转换改变了字符数，无法对应位置
+	}
+
+	start, end := 0, min(len(runes), 2*searchSnippetRunes)
+	if pos >= 0 {
+		start = max(0, pos-searchSnippetRunes)
+		end = min(len(runes), pos+searchSnippetRunes)
+	}
+	snippet := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// truncateRunes 截取前n个字符
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"encoding/json"
+	"nofx/vectorstore"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logReasons 记录一个周期，decisions 为 币种 -> 决策理由
+func logReasons(t *testing.T, l *DecisionLogger, cot string, reasons ...[2]string) *DecisionRecord {
+	t.Helper()
+	var decisions []map[string]string
+	for _, r := range reasons {
+		decisions = append(decisions, map[string]string{"symbol": r[0], "action": "wait", "reasoning": r[1]})
+	}
+	data, _ := json.Marshal(decisions)
+	record := &DecisionRecord{CoTTrace: cot, DecisionJSON: string(data), Success: true}
+	if err := l.LogDecision(record); err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func TestSearchDecisions(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	first := logReasons(t, l, "资金费率极端，空头拥挤。",
+		[2]string{"BTCUSDT", "Funding squeeze 可能引发空头回补，先观望"},
+		[2]string{"SOLUSDT", "funding rate elevated, no squeeze yet"})
+	logReasons(t, l, "趋势延续，但 funding 偏高。", [2]string{"ETHUSDT", "趋势延续但量能不足"})
+
+	now := time.Now()
+	query := DecisionSearchQuery{Text: "funding SQUEEZE", From: now.Add(-time.Hour), To: now.Add(time.Hour)}
+
+	// 关键字：所有词都出现（不区分大小写）；第二个周期的思维链只包含 funding，不命中
+	hits, err := l.SearchDecisions(query)
+	if err != nil {
+		t.Fatalf("检索失败: %v", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("应命中2条决策理由，实际 %+v", hits)
+	}
+	for _, hit := range hits {
+		if hit.DecisionID != first.DecisionID || hit.Field != SearchFieldReasoning || !hit.KeywordMatch || hit.Score != 1 {
+			t.Errorf("命中应为第一个周期的决策理由: %+v", hit)
+		}
+	}
+
+	// 思维链也参与检索（不限币种时）
+	query.Text = "空头拥挤"
+	if hits, _ = l.SearchDecisions(query); len(hits) != 1 || hits[0].Field != SearchFieldCoT || hits[0].Symbol != "" {
+		t.Errorf("应命中第一个周期的思维链，实际 %+v", hits)
+	}
+
+	// 按币种过滤：只检索该币种的理由，不检索思维链
+	query.Text, query.Symbol = "funding", "BTCUSDT"
+	if hits, _ = l.SearchDecisions(query); len(hits) != 1 || hits[0].Symbol != "BTCUSDT" || hits[0].Snippet == "" {
+		t.Errorf("按币种过滤应只命中BTC，实际 %+v", hits)
+	}
+	query.Symbol = ""
+
+	// 同分时新的在前，Limit 截断
+	query.Limit = 1
+	if hits, _ = l.SearchDecisions(query); len(hits) != 1 || hits[0].Field != SearchFieldCoT || hits[0].DecisionID == first.DecisionID {
+		t.Errorf("同分时应返回最新的命中，实际 %+v", hits)
+	}
+	query.Limit = 0
+
+	// 语义匹配：没有出现全部关键字的相近理由也能找到，排在关键字命中之后
+	query.Text = "funding rate squeeze"
+	query.Embedder = vectorstore.NewHashEmbedder(0)
+	query.MinScore = 0.3
+	if hits, err = l.SearchDecisions(query); err != nil {
+		t.Fatalf("语义检索失败: %v", err)
+	}
+	if len(hits) == 0 || hits[0].Symbol != "SOLUSDT" || !hits[0].KeywordMatch {
+		t.Fatalf("包含全部关键字的SOL理由应排在最前，实际 %+v", hits)
+	}
+	semantic := false
+	for _, hit := range hits {
+		if hit.Symbol == "BTCUSDT" {
+			semantic = !hit.KeywordMatch && hit.Similarity >= 0.3 && hit.Score < hits[0].Score
+		}
+		if hit.Symbol == "ETHUSDT" {
+			t.Errorf("不相关的理由不应命中: %+v", hit)
+		}
+	}
+	if !semantic {
+		t.Errorf("BTC理由应按相似度命中（不是关键字命中），实际 %+v", hits)
+	}
+
+	// 时间范围之外没有结果；空查询报错
+	query.From, query.To = now.Add(-48*time.Hour), now.Add(-24*time.Hour)
+	if hits, _ = l.SearchDecisions(query); len(hits) != 0 {
+		t.Errorf("时间范围之外不应有结果，实际 %+v", hits)
+	}
+	if _, err := l.SearchDecisions(DecisionSearchQuery{Text: "  "}); err == nil {
+		t.Error("空查询应报错")
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	text := strings.Repeat("甲", 100) + " Funding squeeze " + strings.Repeat("乙", 100)
+	snippet := []rune(searchSnippet(text, []string{"squeeze"}))
+	if snippet[0] != '…' || snippet[len(snippet)-1] != '…' || !strings.Contains(string(snippet), "Funding squeeze") {
+		t.Errorf("命中位置在中间时应保留命中词、前后截断: %q", string(snippet))
+	}
+	if got := searchSnippet("short text", []string{"missing"}); got != "short text" {
+		t.Errorf("没有命中词时取开头: %q", got)
+	}
+}
@@ -0,0 +1,230 @@
+package logger
+
+import (
+	"math"
+	"time"
+)
+
+// A/B测试报告
+// 按决策记录上的变体标签汇总实验：每个变体的周期数、平仓交易数、胜率、盈亏和平均R倍数；
+// 每个变体与第一个变体（基准）的单笔盈亏做 Welch t 检验（不假设两组方差相等），p < 0.05 视为差异显著。
+// 交易按开仓时的变体归属，跨区组持有的仓位算在开仓的变体上。
+
+// significanceLevel 显著性水平
+const significanceLevel = 0.05
+
+// VariantStats 单个变体的表现
+type VariantStats struct {
+	Variant  string  `json:"variant"`
+	Cycles   int     `json:"cycles"` // 使用该变体的决策周期数
+	Trades   int     `json:"trades"`
+	Wins     int     `json:"wins"`
+	WinRate  float64 `json:"win_rate"`  // 百分比
+	TotalPnL float64 `json:"total_pnl"` // USDT
+	AvgPnL   float64 `json:"avg_pnl"`   // 单笔平均盈亏
+	StdPnL   float64 `json:"std_pnl"`   // 单笔盈亏的样本标准差
+	AvgR     float64 `json:"avg_r"`
+	RTrades  int     `json:"r_trades"` // 有止损价、计入平均R的交易数
+}
+
+// VariantComparison 变体与基准的比较（Welch t 检验）
+type VariantComparison struct {
+	Baseline    string  `json:"baseline"`
+	Variant     string  `json:"variant"`
+	Diff        float64 `json:"diff"` // 单笔平均盈亏之差（变体 - 基准）
+	TStat       float64 `json:"t_stat"`
+	DF          float64 `json:"df"` // Welch-Satterthwaite 自由度
+	PValue      float64 `json:"p_value"`
+	Significant bool    `json:"significant"`
+	Note        string  `json:"note,omitempty"` // 无法检验的原因
+}
+
+// ExperimentReport 实验报告
+type ExperimentReport struct {
+	Experiment  string              `json:"experiment"`
+	Days        int                 `json:"days"`
+	Variants    []VariantStats      `json:"variants"`
+	Comparisons []VariantComparison `json:"comparisons"`
+}
+
+// ExperimentReport 最近days天（含今天）的实验报告，variants 的第一个为基准
+func (l *DecisionLogger) ExperimentReport(experiment string, variants []string, days int, now time.Time) (*ExperimentReport, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var history, records []*DecisionRecord
+	for i := days + openLookbackDays - 1; i >= 0; i-- {
+		dayRecords, err := l.recordsForDay(today.AddDate(0, 0, -i))
+		if err != nil {
+			return nil, err
+		}
+		if i >= days {
+			history = append(history, dayRecords...)
+		} else {
+			records = append(records, dayRecords...)
+		}
+	}
+	return experimentReport(experiment, variants, days, records, closedTrades(history, records)), nil
+}
+
+// experimentReport 按变体汇总周期和交易
+func experimentReport(experiment string, variants []string, days int, records []*DecisionRecord, trades []TradeOutcome) *ExperimentReport {
+	report := &ExperimentReport{Experiment: experiment, Days: days, Variants: []VariantStats{}, Comparisons: []VariantComparison{}}
+
+	index := make(map[string]int, len(variants))
+	pnls := make([][]float64, len(variants))
+	for i, name := range variants {
+		index[name] = i
+		report.Variants = append(report.Variants, VariantStats{Variant: name})
+	}
+	for _, record := range records {
+		if i, ok := index[record.Variant]; ok && record.Experiment == experiment {
+			report.Variants[i].Cycles++
+		}
+	}
+	totalR := make([]float64, len(variants))
+	for _, t := range trades {
+		i, ok := index[t.Variant]
+		if !ok {
+			continue
+		}
+		stats := &report.Variants[i]
+		stats.Trades++
+		stats.TotalPnL += t.PnL
+		if t.PnL > 0 {
+			stats.Wins++
+		}
+		if t.RMultiple != nil {
+			stats.RTrades++
+			totalR[i] += *t.RMultiple
+		}
+		pnls[i] = append(pnls[i], t.PnL)
+	}
+	for i := range report.Variants {
+		stats := &report.Variants[i]
+		if stats.Trades > 0 {
+			stats.WinRate = float64(stats.Wins) / float64(stats.Trades) * 100
+		}
+		stats.AvgPnL, stats.StdPnL = meanStd(pnls[i])
+		if stats.RTrades > 0 {
+			stats.AvgR = totalR[i] / float64(stats.RTrades)
+		}
+	}
+
+	for i := 1; i < len(variants); i++ {
+		report.Comparisons = append(report.Comparisons, welchTTest(variants[0], variants[i], pnls[0], pnls[i]))
+	}
+	return report
+}
+
+// welchTTest 比较两组单笔盈亏的均值
+func welchTTest(baseline, variant string, a, b []float64) VariantComparison {
+	c := VariantComparison{Baseline: baseline, Variant: variant, PValue: 1}
+	if len(a) < 2 || len(b) < 2 {
+		c.Note = "每个变体至少需要2笔平仓交易"
+		return c
+	}
+	meanA, stdA := meanStd(a)
+	meanB, stdB := meanStd(b)
+	c.Diff = meanB - meanA
+
+	va, vb := stdA*stdA/float64(len(a)), stdB*stdB/float64(len(b))
+	if va+vb == 0 {
+		c.Note = "两组盈亏都没有波动，无法检验"
+		return c
+	}
+	c.TStat = c.Diff / math.Sqrt(va+vb)
+	c.DF = (va + vb) * (va + vb) / (va*va/float64(len(a)-1) + vb*vb/float64(len(b)-1))
+	c.PValue = studentTwoTailed(c.TStat, c.DF)
+	c.Significant = c.PValue < significanceLevel
+	return c
+}
+
+// meanStd 均值和样本标准差
+func meanStd(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	ss := 0.0
+	for _, v := range values {
+		ss += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(ss / float64(len(values)-1))
+}
+
+// studentTwoTailed t 分布的双尾 p 值：P(|T| > |t|) = I_{df/(df+t²)}(df/2, 1/2)
+func studentTwoTailed(t, df float64) float64 {
+	return regularizedBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// regularizedBeta 正则化不完全 Beta 函数 I_x(a, b)（连分式展开）
+func regularizedBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(x, a, b) / a
+	}
+	return 1 - front*betaContinuedFraction(1-x, b, a)/b
+}
+
+// betaContinuedFraction 不完全 Beta 函数的连分式（修正 Lentz 算法）
+func betaContinuedFraction(x, a, b float64) float64 {
+	const (
+		maxIter = 200
+		eps     = 1e-14
+		tiny    = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		// 偶数项
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		// 奇数项
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < eps {
+			break
+		}
+	}
+	return h
+}
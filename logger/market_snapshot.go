@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nofx/market"
+	"os"
+	"path/filepath"
+)
+
+// ErrSnapshotNotFound 指定决策的行情快照不存在（未启用、已过期或决策ID错误）
+var ErrSnapshotNotFound = errors.New("行情快照不存在")
+
+// MarketSnapshotArchive 每个周期AI看到的行情数据（market.Data）快照
+// 事后重新拉取的K线/OI/资金费率可能已经变化，保存原始数据才能准确复现当时的决策输入，
+// 用于回测、回放和争议排查。快照以gzip压缩的JSON保存，按决策ID检索，过期自动清理。
+type MarketSnapshotArchive struct {
+	dir           string
+	retentionDays int
+}
+
+// NewMarketSnapshotArchive 创建行情快照归档，retentionDays<=0 表示永久保留
+func NewMarketSnapshotArchive(dir string, retentionDays int) (*MarketSnapshotArchive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建行情快照目录失败: %w", err)
+	}
+	return &MarketSnapshotArchive{dir: dir, retentionDays: retentionDays}, nil
+}
+
+// Save 压缩保存行情快照
+func (a *MarketSnapshotArchive) Save(decisionID string, data map[string]*market.Data) error {
+	path, err := a.path(decisionID)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化行情快照失败: %w", err)
+	}
+	if err := writeGzipFile(path, decisionID, payload); err != nil {
+		return fmt.Errorf("写入行情快照失败: %w", err)
+	}
+	return nil
+}
+
+// Load 读取指定决策的行情快照
+func (a *MarketSnapshotArchive) Load(decisionID string) (map[string]*market.Data, error) {
+	path, err := a.path(decisionID)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := readGzipFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSnapshotNotFound
+		}
+		return nil, fmt.Errorf("读取行情快照失败: %w", err)
+	}
+	var data map[string]*market.Data
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, fmt.Errorf("解析行情快照失败: %w", err)
+	}
+	return data, nil
+}
+
+// Cleanup 删除超过保留天数的快照，返回删除数量
+func (a *MarketSnapshotArchive) Cleanup() (int, error) {
+	return cleanupExpiredFiles(a.dir, a.retentionDays)
+}
+
+// path 决策ID对应的快照文件路径
+func (a *MarketSnapshotArchive) path(decisionID string) (string, error) {
+	if !validDecisionID(decisionID) {
+		return "", fmt.Errorf("无效的决策ID: %q", decisionID)
+	}
+	return filepath.Join(a.dir, decisionID+".json.gz"), nil
+}
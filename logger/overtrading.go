@@ -0,0 +1,189 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 过度交易检测
+// 从最近N小时的决策记录中找出三类行为模式：
+//   - 快速回补：同一币种平仓后 ReentryMinutes 分钟内又同方向开仓（刚认错就反悔）
+//   - 反复反手：同一币种平仓后 FlipMinutes 分钟内反方向开仓（多空来回切换）
+//   - 交易过密：任意60分钟内的开平仓/加减仓笔数超过 MaxTradesPerHour
+// 只统计执行成功的动作；交易所触发的止损止盈不在决策记录中，不计入。
+
+// OvertradingRules 过度交易的判定阈值
+type OvertradingRules struct {
+	ReentryMinutes   int `json:"reentry_minutes"`     // 平仓后多少分钟内同方向再开仓算快速回补
+	FlipMinutes      int `json:"flip_minutes"`        // 平仓后多少分钟内反方向开仓算反手
+	MaxTradesPerHour int `json:"max_trades_per_hour"` // 60分钟内的交易笔数上限
+}
+
+// 过度交易的模式类型
+const (
+	OvertradingReentry = "reentry" // 快速回补
+	OvertradingFlip    = "flip"    // 反复反手
+	OvertradingBurst   = "burst"   // 交易过密
+)
+
+// OvertradingEvent 一次过度交易
+type OvertradingEvent struct {
+	Type   string    `json:"type"`
+	Symbol string    `json:"symbol,omitempty"` // 交易过密时为空
+	Time   time.Time `json:"time"`
+	Detail string    `json:"detail"`
+}
+
+// OvertradingReport 过度交易检测结果
+type OvertradingReport struct {
+	Hours           int                `json:"hours"`
+	Rules           OvertradingRules   `json:"rules"`
+	Trades          int                `json:"trades"`             // 执行成功的开平仓/加减仓笔数
+	Reentries       int                `json:"reentries"`          // 快速回补次数
+	Flips           int                `json:"flips"`              // 反手次数
+	Bursts          int                `json:"bursts"`             // 交易过密的时段数
+	MaxTradesInHour int                `json:"max_trades_in_hour"` // 任意60分钟内的最多交易笔数
+	Flagged         bool               `json:"flagged"`            // 出现任一模式
+	Events          []OvertradingEvent `json:"events"`             // 按时间正序
+}
+
+// DetectOvertrading 检测最近hours小时内的过度交易
+func (l *DecisionLogger) DetectOvertrading(rules OvertradingRules, hours int, now time.Time) (*OvertradingReport, error) {
+	start := now.Add(-time.Duration(hours) * time.Hour)
+	var records []*DecisionRecord
+	for d := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()); !d.After(now); d = d.AddDate(0, 0, 1) {
+		dayRecords, err := l.recordsForDay(d)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range dayRecords {
+			if !record.Timestamp.Before(start) && !record.Timestamp.After(now) {
+				records = append(records, record)
+			}
+		}
+	}
+	return detectOvertrading(records, rules, hours), nil
+}
+
+// tradeEvent 一笔执行成功的交易动作
+type tradeEvent struct {
+	time   time.Time
+	symbol string
+	side   string // long / short（无法判断时为空）
+	open   bool   // open_long / open_short
+	close  bool   // close_long / close_short
+}
+
+// detectOvertrading 按时间顺序扫描交易动作
+func detectOvertrading(records []*DecisionRecord, rules OvertradingRules, hours int) *OvertradingReport {
+	report := &OvertradingReport{Hours: hours, Rules: rules, Events: []OvertradingEvent{}}
+
+	var events []tradeEvent
+	for _, record := range records {
+		for _, a := range record.Decisions {
+			if !a.Success {
+				continue
+			}
+			e := tradeEvent{time: a.Timestamp, symbol: a.Symbol}
+			if e.time.IsZero() {
+				e.time = record.Timestamp
+			}
+			switch a.Action {
+			case "open_long", "open_short":
+				e.side, e.open = strings.TrimPrefix(a.Action, "open_"), true
+			case "close_long", "close_short":
+				e.side, e.close = strings.TrimPrefix(a.Action, "close_"), true
+			case "add_to_position":
+				e.side = orderSide(a.Side, "buy")
+			case "partial_close":
+				e.side = orderSide(a.Side, "sell")
+			default:
+				continue
+			}
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].time.Before(events[j].time) })
+	report.Trades = len(events)
+
+	lastClose := make(map[string]tradeEvent)
+	for _, e := range events {
+		if e.close {
+			lastClose[e.symbol] = e
+			continue
+		}
+		if !e.open {
+			continue
+		}
+		prev, ok := lastClose[e.symbol]
+		if !ok {
+			continue
+		}
+		gap := e.time.Sub(prev.time)
+		switch {
+		case e.side != prev.side && gap <= time.Duration(rules.FlipMinutes)*time.Minute:
+			report.Flips++
+			report.Events = append(report.Events, OvertradingEvent{Type: OvertradingFlip, Symbol: e.symbol, Time: e.time,
+				Detail: fmt.Sprintf("%s 平%s后 %s 反手开%s", e.symbol, sideName(prev.side), formatGap(gap), sideName(e.side))})
+		case e.side == prev.side && gap <= time.Duration(rules.ReentryMinutes)*time.Minute:
+			report.Reentries++
+			report.Events = append(report.Events, OvertradingEvent{Type: OvertradingReentry, Symbol: e.symbol, Time: e.time,
+				Detail: fmt.Sprintf("%s 平%s后 %s 又开%s", e.symbol, sideName(prev.side), formatGap(gap), sideName(e.side))})
+		}
+		delete(lastClose, e.symbol)
+	}
+
+	// 交易过密：从每笔交易开始的60分钟窗口，超出上限的窗口记一次后跳到窗口之后
+	for i := 0; i < len(events); {
+		j := i
+		for j < len(events) && events[j].time.Sub(events[i].time) < time.Hour {
+			j++
+		}
+		count := j - i
+		if count > report.MaxTradesInHour {
+			report.MaxTradesInHour = count
+		}
+		if rules.MaxTradesPerHour > 0 && count > rules.MaxTradesPerHour {
+			report.Bursts++
+			report.Events = append(report.Events, OvertradingEvent{Type: OvertradingBurst, Time: events[i].time,
+				Detail: fmt.Sprintf("%s 起60分钟内 %d 笔交易（上限 %d）", events[i].time.Format("01-02 15:04"), count, rules.MaxTradesPerHour)})
+			i = j
+			continue
+		}
+		i++
+	}
+
+	sort.SliceStable(report.Events, func(i, j int) bool { return report.Events[i].Time.Before(report.Events[j].Time) })
+	report.Flagged = len(report.Events) > 0
+	return report
+}
+
+// orderSide 由订单方向推断持仓方向（buy 对应 long 的动作）
+func orderSide(side, longSide string) string {
+	switch side {
+	case "":
+		return ""
+	case longSide:
+		return "long"
+	default:
+		return "short"
+	}
+}
+
+func sideName(side string) string {
+	switch side {
+	case "long":
+		return "多"
+	case "short":
+		return "空"
+	default:
+		return "仓"
+	}
+}
+
+// formatGap 间隔时长（分钟）
+func formatGap(gap time.Duration) string {
+	return fmt.Sprintf("%.0f分钟", gap.Minutes())
+}
@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectOvertradingRules(t *testing.T) {
+	base := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	act := func(minutes int, action, symbol string) DecisionAction {
+		return DecisionAction{Action: action, Symbol: symbol, Timestamp: at(minutes), Success: true}
+	}
+	rules := OvertradingRules{ReentryMinutes: 15, FlipMinutes: 60, MaxTradesPerHour: 4}
+
+	tests := []struct {
+		name       string
+		actions    []DecisionAction
+		rules      OvertradingRules
+		trades     int
+		reentries  int
+		flips      int
+		bursts     int
+		maxInHour  int
+		wantEvents []string // 事件类型，按时间正序
+	}{
+		{
+			name:    "平仓后在回补窗口内同方向开仓",
+			actions: []DecisionAction{act(0, "open_long", "ETHUSDT"), act(10, "close_long", "ETHUSDT"), act(25, "open_long", "ETHUSDT")},
+			rules:   rules,
+			trades:  3, reentries: 1, maxInHour: 3,
+			wantEvents: []string{OvertradingReentry},
+		},
+		{
+			name:    "回补刚好超过窗口",
+			actions: []DecisionAction{act(10, "close_long", "ETHUSDT"), act(26, "open_long", "ETHUSDT")},
+			rules:   rules,
+			trades:  2, maxInHour: 2,
+		},
+		{
+			name:    "只有下一次开仓与平仓比较",
+			actions: []DecisionAction{act(0, "close_long", "ETHUSDT"), act(30, "open_long", "ETHUSDT"), act(35, "open_long", "ETHUSDT")},
+			rules:   rules,
+			trades:  3, maxInHour: 3,
+		},
+		{
+			name:    "平仓后在反手窗口内反方向开仓",
+			actions: []DecisionAction{act(0, "close_long", "ETHUSDT"), act(60, "open_short", "ETHUSDT")},
+			rules:   rules,
+			trades:  2, flips: 1, maxInHour: 1,
+			wantEvents: []string{OvertradingFlip},
+		},
+		{
+			name:    "反手超过窗口",
+			actions: []DecisionAction{act(0, "close_short", "ETHUSDT"), act(61, "open_long", "ETHUSDT")},
+			rules:   rules,
+			trades:  2, maxInHour: 1,
+		},
+		{
+			name:    "不同币种互不影响",
+			actions: []DecisionAction{act(0, "close_long", "ETHUSDT"), act(1, "open_long", "BTCUSDT"), act(2, "open_short", "SOLUSDT")},
+			rules:   rules,
+			trades:  3, maxInHour: 3,
+		},
+		{
+			name: "失败的动作和非交易动作不计",
+			actions: []DecisionAction{
+				act(0, "close_long", "ETHUSDT"),
+				{Action: "open_long", Symbol: "ETHUSDT", Timestamp: at(1), Success: false},
+				act(2, "adjust_sl", "ETHUSDT"),
+				act(3, "wait", "ETHUSDT"),
+			},
+			rules:  rules,
+			trades: 1, maxInHour: 1,
+		},
+		{
+			name: "60分钟内超过笔数上限（加减仓也计入）",
+			actions: []DecisionAction{
+				act(0, "open_long", "ETHUSDT"), act(10, "add_to_position", "ETHUSDT"), act(20, "partial_close", "ETHUSDT"),
+				act(30, "open_short", "BTCUSDT"), act(59, "close_short", "BTCUSDT"),
+			},
+			rules:  rules,
+			trades: 5, bursts: 1, maxInHour: 5,
+			wantEvents: []string{OvertradingBurst},
+		},
+		{
+			name: "刚好60分钟的交易不在同一窗口",
+			actions: []DecisionAction{
+				act(0, "open_long", "ETHUSDT"), act(10, "open_long", "BTCUSDT"), act(20, "open_long", "SOLUSDT"),
+				act(30, "open_long", "XRPUSDT"), act(60, "open_long", "ADAUSDT"),
+			},
+			rules:  rules,
+			trades: 5, maxInHour: 4,
+		},
+		{
+			name: "超出上限的窗口只记一次后跳到窗口之后",
+			actions: []DecisionAction{
+				act(0, "open_long", "A"), act(1, "open_long", "B"), act(2, "open_long", "C"), act(3, "open_long", "D"), act(4, "open_long", "E"),
+				act(30, "open_long", "F"),
+				act(70, "open_long", "G"), act(71, "open_long", "H"), act(72, "open_long", "I"), act(73, "open_long", "J"), act(74, "open_long", "K"),
+			},
+			rules:  rules,
+			trades: 11, bursts: 2, maxInHour: 6,
+			wantEvents: []string{OvertradingBurst, OvertradingBurst},
+		},
+		{
+			name: "上限为0时不检测交易过密",
+			actions: []DecisionAction{
+				act(0, "open_long", "A"), act(1, "open_long", "B"), act(2, "open_long", "C"),
+			},
+			rules:  OvertradingRules{ReentryMinutes: 15, FlipMinutes: 60},
+			trades: 3, maxInHour: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := detectOvertrading([]*DecisionRecord{{Timestamp: base, Decisions: tt.actions}}, tt.rules, 24)
+			if report.Trades != tt.trades || report.Reentries != tt.reentries || report.Flips != tt.flips || report.Bursts != tt.bursts || report.MaxTradesInHour != tt.maxInHour {
+				t.Fatalf("统计 = trades %d reentries %d flips %d bursts %d max %d",
+					report.Trades, report.Reentries, report.Flips, report.Bursts, report.MaxTradesInHour)
+			}
+			var types []string
+			for _, e := range report.Events {
+				types = append(types, e.Type)
+			}
+			if strings.Join(types, ",") != strings.Join(tt.wantEvents, ",") {
+				t.Fatalf("事件 = %+v, 期望 %v", report.Events, tt.wantEvents)
+			}
+			if report.Flagged != (len(tt.wantEvents) > 0) || report.Events == nil {
+				t.Fatalf("Flagged = %v, Events = %#v", report.Flagged, report.Events)
+			}
+		})
+	}
+}
+
+func TestDetectOvertradingEventDetail(t *testing.T) {
+	base := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	records := []*DecisionRecord{
+		{Timestamp: base, Decisions: []DecisionAction{{Action: "close_long", Symbol: "ETHUSDT", Success: true}}},
+		// 动作没有时间时使用记录时间
+		{Timestamp: base.Add(5 * time.Minute), Decisions: []DecisionAction{
+			{Action: "open_short", Symbol: "ETHUSDT", Success: true},
+			{Action: "close_short", Symbol: "BTCUSDT", Timestamp: base.Add(time.Minute), Success: true},
+		}},
+		{Timestamp: base.Add(10 * time.Minute), Decisions: []DecisionAction{{Action: "open_short", Symbol: "BTCUSDT", Success: true}}},
+	}
+	report := detectOvertrading(records, OvertradingRules{ReentryMinutes: 15, FlipMinutes: 60, MaxTradesPerHour: 3}, 6)
+
+	if report.Hours != 6 || len(report.Events) != 3 {
+		t.Fatalf("事件 = %+v", report.Events)
+	}
+	want := []struct{ typ, detail string }{
+		{OvertradingBurst, "03-10 09:00 起60分钟内 4 笔交易（上限 3）"},
+		{OvertradingFlip, "ETHUSDT 平多后 5分钟 反手开空"},
+		{OvertradingReentry, "BTCUSDT 平空后 9分钟 又开空"},
+	}
+	for i, w := range want {
+		if e := report.Events[i]; e.Type != w.typ || e.Detail != w.detail {
+			t.Errorf("事件 %d = %+v, 期望 %s %q", i, e, w.typ, w.detail)
+		}
+	}
+}
+
+func TestDetectOvertradingWindow(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	now := time.Date(2026, 3, 10, 2, 0, 0, 0, time.UTC)
+	// 窗口跨越午夜：前一天的平仓在窗口内，更早的不计
+	writeRecordAt(t, l, now.Add(-5*time.Hour), &DecisionRecord{Decisions: []DecisionAction{{Action: "open_long", Symbol: "ETHUSDT", Success: true}}})
+	writeRecordAt(t, l, now.Add(-3*time.Hour), &DecisionRecord{Decisions: []DecisionAction{{Action: "close_long", Symbol: "ETHUSDT", Success: true}}})
+	writeRecordAt(t, l, now.Add(-170*time.Minute), &DecisionRecord{Decisions: []DecisionAction{{Action: "open_long", Symbol: "ETHUSDT", Success: true}}})
+	writeRecordAt(t, l, now.Add(time.Minute), &DecisionRecord{Decisions: []DecisionAction{{Action: "open_long", Symbol: "BTCUSDT", Success: true}}})
+
+	report, err := l.DetectOvertrading(OvertradingRules{ReentryMinutes: 15, FlipMinutes: 60}, 4, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Trades != 2 || report.Reentries != 1 || !report.Flagged {
+		t.Fatalf("只统计最近4小时内的记录: %+v", report)
+	}
+}
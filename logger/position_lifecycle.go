@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrPositionNotFound 决策记录中没有该持仓ID（ID错误或记录已被清理）
+var ErrPositionNotFound = errors.New("没有该持仓ID的记录")
+
+// PositionEvent 持仓生命周期中的一个动作
+type PositionEvent struct {
+	DecisionID  string `json:"decision_id"`
+	CycleNumber int    `json:"cycle_number"`
+	DecisionAction
+}
+
+// PositionLifecycle 按持仓ID汇总的持仓生命周期
+type PositionLifecycle struct {
+	PositionID   string            `json:"position_id"`
+	Symbol       string            `json:"symbol"`
+	Side         string            `json:"side"`
+	Events       []PositionEvent   `json:"events"`                  // 开仓、加仓、部分平仓、调整止损止盈、平仓（按时间顺序，含失败的动作）
+	FirstSeen    time.Time         `json:"first_seen,omitempty"`    // 第一次出现在周期持仓快照中的时间
+	LastSeen     time.Time         `json:"last_seen,omitempty"`     // 最后一次出现在周期持仓快照中的时间
+	LastSnapshot *PositionSnapshot `json:"last_snapshot,omitempty"` // 最后一次持仓快照
+	Closed       bool              `json:"closed"`                  // 是否已通过本系统平仓（交易所侧平仓时只能从快照消失判断）
+}
+
+// GetPositionLifecycle 扫描所有决策记录，汇总指定持仓ID的动作和持仓快照
+func (l *DecisionLogger) GetPositionLifecycle(positionID string) (*PositionLifecycle, error) {
+	if positionID == "" {
+		return nil, fmt.Errorf("持仓ID不能为空")
+	}
+	files, err := os.ReadDir(l.logDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	lifecycle := &PositionLifecycle{PositionID: positionID, Events: []PositionEvent{}}
+	found := false
+	for _, file := range files {
+		if file.IsDir() || !isRecordFile(file.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(l.logDir, file.Name()))
+		if err != nil || !strings.Contains(string(data), positionID) {
+			continue
+		}
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+		fillDecisionID(&record, file.Name())
+
+		for _, pos := range record.Positions {
+			if pos.PositionID != positionID {
+				continue
+			}
+			found = true
+			lifecycle.Symbol, lifecycle.Side = pos.Symbol, pos.Side
+			if lifecycle.FirstSeen.IsZero() || record.Timestamp.Before(lifecycle.FirstSeen) {
+				lifecycle.FirstSeen = record.Timestamp
+			}
+			if record.Timestamp.After(lifecycle.LastSeen) {
+				lifecycle.LastSeen = record.Timestamp
+				snapshot := pos
+				lifecycle.LastSnapshot = &snapshot
+			}
+		}
+		for _, action := range record.Decisions {
+			if action.PositionID != positionID {
+				continue
+			}
+			found = true
+			lifecycle.Symbol = action.Symbol
+			lifecycle.Events = append(lifecycle.Events, PositionEvent{
+				DecisionID:     record.DecisionID,
+				CycleNumber:    record.CycleNumber,
+				DecisionAction: action,
+			})
+			switch action.Action {
+			case "open_long", "close_long":
+				lifecycle.Side = "long"
+			case "open_short", "close_short":
+				lifecycle.Side = "short"
+			}
+			if action.Success && strings.HasPrefix(action.Action, "close_") {
+				lifecycle.Closed = true
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrPositionNotFound, positionID)
+	}
+
+	sort.SliceStable(lifecycle.Events, func(i, j int) bool {
+		return lifecycle.Events[i].Timestamp.Before(lifecycle.Events[j].Timestamp)
+	})
+	return lifecycle, nil
+}
@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrPromptNotFound 指定决策的prompt快照不存在（未归档、已过期或决策ID错误）
+var ErrPromptNotFound = errors.New("prompt快照不存在")
+
+// PromptArchive prompt快照归档
+// 每个周期发送给AI的输入prompt可能有几十KB，直接内联在决策记录JSON中会让日志目录快速膨胀。
+// 归档后prompt以gzip压缩文件保存（文本压缩率通常在10倍以上），按决策ID检索，过期自动清理。
+type PromptArchive struct {
+	dir           string
+	retentionDays int
+}
+
+// NewPromptArchive 创建prompt归档，retentionDays<=0 表示永久保留
+func NewPromptArchive(dir string, retentionDays int) (*PromptArchive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建prompt归档目录失败: %w", err)
+	}
+	return &PromptArchive{dir: dir, retentionDays: retentionDays}, nil
+}
+
+// Save 压缩保存prompt快照
+func (a *PromptArchive) Save(decisionID, prompt string) error {
+	path, err := a.path(decisionID)
+	if err != nil {
+		return err
+	}
+	if err := writeGzipFile(path, decisionID, []byte(prompt)); err != nil {
+		return fmt.Errorf("写入prompt快照失败: %w", err)
+	}
+	return nil
+}
+
+// Load 读取指定决策的原始prompt
+func (a *PromptArchive) Load(decisionID string) (string, error) {
+	path, err := a.path(decisionID)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := readGzipFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrPromptNotFound
+		}
+		return "", fmt.Errorf("读取prompt快照失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// Cleanup 删除超过保留天数的快照，返回删除数量
+func (a *PromptArchive) Cleanup() (int, error) {
+	return cleanupExpiredFiles(a.dir, a.retentionDays)
+}
+
+// path 决策ID对应的快照文件路径（决策ID来自API参数，需要校验）
+func (a *PromptArchive) path(decisionID string) (string, error) {
+	if !validDecisionID(decisionID) {
+		return "", fmt.Errorf("无效的决策ID: %q", decisionID)
+	}
+	return filepath.Join(a.dir, decisionID+".txt.gz"), nil
+}
+
+// validDecisionID 决策ID不能为空，且不能包含路径分隔符
+func validDecisionID(decisionID string) bool {
+	return decisionID != "" && !strings.ContainsAny(decisionID, `/\`) && !strings.Contains(decisionID, "..")
+}
+
+// writeGzipFile 压缩写入文件（先写临时文件再重命名，避免读到写了一半的文件）
+func writeGzipFile(path, name string, data []byte) error {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return fmt.Errorf("创建压缩器失败: %w", err)
+	}
+	zw.Name = name
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("压缩失败: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("压缩失败: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// readGzipFile 读取并解压文件（文件不存在时返回的错误满足 os.IsNotExist）
+func readGzipFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("解压失败: %w", err)
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("解压失败: %w", err)
+	}
+	return data, nil
+}
+
+// cleanupExpiredFiles 删除目录中超过保留天数的文件，返回删除数量（retentionDays<=0 表示永久保留）
+func cleanupExpiredFiles(dir string, retentionDays int) (int, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+	cutoffTime := time.Now().AddDate(0, 0, -retentionDays)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("读取归档目录失败: %w", err)
+	}
+
+	removedCount := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoffTime) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			fmt.Printf("⚠ 删除过期快照失败 %s: %v\n", entry.Name(), err)
+			continue
+		}
+		removedCount++
+	}
+	return removedCount, nil
+}
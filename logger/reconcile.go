@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"sort"
+	"time"
+)
+
+// 对账用的决策日志查询
+// 与交易所成交/盈亏流水对账时，需要任意时间区间（不一定按自然日）内的决策记录和在区间内平仓的交易。
+
+// GetRecordsBetween 获取 [since, until) 之间的决策记录（按时间正序）
+func (l *DecisionLogger) GetRecordsBetween(since, until time.Time) ([]*DecisionRecord, error) {
+	// 文件按服务器本地日期命名
+	first := since.In(time.Local)
+	last := until.Add(-time.Nanosecond).In(time.Local)
+	var records []*DecisionRecord
+	for d := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.Local); !d.After(last); d = d.AddDate(0, 0, 1) {
+		fileRecords, err := l.GetRecordByDate(d)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range fileRecords {
+			if !record.Timestamp.Before(since) && record.Timestamp.Before(until) {
+				records = append(records, record)
+			}
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Timestamp.Before(records[j].Timestamp) })
+	return records, nil
+}
+
+// TradesClosedBetween 在 [since, until) 之间通过本系统平仓的交易（向前几天查找对应的开仓记录）
+func (l *DecisionLogger) TradesClosedBetween(since, until time.Time) ([]TradeOutcome, error) {
+	history, err := l.GetRecordsBetween(since.AddDate(0, 0, -openLookbackDays), since)
+	if err != nil {
+		return nil, err
+	}
+	records, err := l.GetRecordsBetween(since, until)
+	if err != nil {
+		return nil, err
+	}
+	return closedTrades(history, records), nil
+}
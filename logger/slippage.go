@@ -0,0 +1,179 @@
+package logger
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// 滑点与执行质量统计
+// 每笔下单记录三个价格：决策时价格（AI看到的价格）、下单时市价（DecisionAction.Price）、实际成交均价，
+// 总滑点 = 决策延迟造成的价格漂移 + 下单到成交的冲击，均以基点（bp）表示，正数表示对我们不利。
+// 限价单另外统计限价相对下单时市价的让价幅度，用于调整执行策略（如IOC让价幅度是否过大）。
+
+// ExecutionSample 单笔订单的执行价格
+type ExecutionSample struct {
+	Exchange      string    `json:"exchange"`
+	Symbol        string    `json:"symbol"`
+	Action        string    `json:"action"`
+	Side          string    `json:"side"` // buy / sell
+	OrderType     string    `json:"order_type"`
+	Quantity      float64   `json:"quantity"`
+	IntendedPrice float64   `json:"intended_price"`
+	MarketPrice   float64   `json:"market_price"`
+	LimitPrice    float64   `json:"limit_price"`
+	FillPrice     float64   `json:"fill_price"`
+	Time          time.Time `json:"time"`
+}
+
+// adverseBps price 相对 base 的不利偏移（基点）：买入时价格越高越不利，卖出相反
+func (s ExecutionSample) adverseBps(price, base float64) float64 {
+	if price <= 0 || base <= 0 {
+		return 0
+	}
+	bps := (price - base) / base * 10000
+	if s.Side == "sell" {
+		bps = -bps
+	}
+	return bps
+}
+
+// SlippageBps 总滑点：成交价相对决策时价格
+func (s ExecutionSample) SlippageBps() float64 {
+	return s.adverseBps(s.FillPrice, s.IntendedPrice)
+}
+
+// SlippageStats 一组订单的滑点统计（单位：基点，正数=不利）
+type SlippageStats struct {
+	Orders            int     `json:"orders"`
+	AvgBps            float64 `json:"avg_bps"`              // 平均总滑点（成交价 vs 决策时价格）
+	MedianBps         float64 `json:"median_bps"`           // 中位数
+	P90Bps            float64 `json:"p90_bps"`              // 90分位（最差的10%）
+	WorstBps          float64 `json:"worst_bps"`            // 最差一笔
+	AvgDelayBps       float64 `json:"avg_delay_bps"`        // 决策到下单之间的价格漂移
+	AvgImpactBps      float64 `json:"avg_impact_bps"`       // 下单时市价到成交价
+	LimitOrders       int     `json:"limit_orders"`         // 带限价的订单数
+	AvgLimitOffsetBps float64 `json:"avg_limit_offset_bps"` // 限价相对下单时市价的平均让价（仅限价单）
+	CostUSD           float64 `json:"cost_usd"`             // 滑点成本合计（数量 × 不利价差）
+}
+
+// SlippageAnalysis 滑点分析：总体以及按交易所、币种、下单类型分组
+type SlippageAnalysis struct {
+	Overall     *SlippageStats            `json:"overall"`
+	ByExchange  map[string]*SlippageStats `json:"by_exchange"`
+	BySymbol    map[string]*SlippageStats `json:"by_symbol"`
+	ByOrderType map[string]*SlippageStats `json:"by_order_type"`
+}
+
+// ExecutionSamples 最近N个周期中有成交价的订单
+func (l *DecisionLogger) ExecutionSamples(lookbackCycles int) ([]ExecutionSample, error) {
+	records, err := l.GetLatestRecords(lookbackCycles)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []ExecutionSample
+	for _, record := range records {
+		for _, a := range record.Decisions {
+			if !a.Success || a.FillPrice <= 0 || a.Side == "" {
+				continue
+			}
+			intended := a.IntendedPrice
+			if intended <= 0 {
+				intended = a.Price
+			}
+			samples = append(samples, ExecutionSample{
+				Symbol:        a.Symbol,
+				Action:        a.Action,
+				Side:          a.Side,
+				OrderType:     a.OrderType,
+				Quantity:      a.Quantity,
+				IntendedPrice: intended,
+				MarketPrice:   a.Price,
+				LimitPrice:    a.LimitPrice,
+				FillPrice:     a.FillPrice,
+				Time:          a.Timestamp,
+			})
+		}
+	}
+	return samples, nil
+}
+
+// AnalyzeSlippage 统计滑点
+func AnalyzeSlippage(samples []ExecutionSample) *SlippageAnalysis {
+	groups := map[string]map[string][]ExecutionSample{
+		"exchange":   {},
+		"symbol":     {},
+		"order_type": {},
+	}
+	for _, s := range samples {
+		groups["exchange"][s.Exchange] = append(groups["exchange"][s.Exchange], s)
+		groups["symbol"][s.Symbol] = append(groups["symbol"][s.Symbol], s)
+		orderType := s.OrderType
+		if orderType == "" {
+			orderType = "unknown"
+		}
+		groups["order_type"][orderType] = append(groups["order_type"][orderType], s)
+	}
+
+	summarize := func(byKey map[string][]ExecutionSample) map[string]*SlippageStats {
+		result := make(map[string]*SlippageStats, len(byKey))
+		for key, group := range byKey {
+			result[key] = slippageStats(group)
+		}
+		return result
+	}
+	return &SlippageAnalysis{
+		Overall:     slippageStats(samples),
+		ByExchange:  summarize(groups["exchange"]),
+		BySymbol:    summarize(groups["symbol"]),
+		ByOrderType: summarize(groups["order_type"]),
+	}
+}
+
+// slippageStats 计算一组订单的滑点统计
+func slippageStats(samples []ExecutionSample) *SlippageStats {
+	stats := &SlippageStats{Orders: len(samples)}
+	if len(samples) == 0 {
+		return stats
+	}
+
+	values := make([]float64, 0, len(samples))
+	var sum, delay, impact, limitOffset float64
+	for _, s := range samples {
+		bps := s.SlippageBps()
+		values = append(values, bps)
+		sum += bps
+		delay += s.adverseBps(s.MarketPrice, s.IntendedPrice)
+		impact += s.adverseBps(s.FillPrice, s.MarketPrice)
+		if s.LimitPrice > 0 {
+			stats.LimitOrders++
+			limitOffset += s.adverseBps(s.LimitPrice, s.MarketPrice)
+		}
+		stats.CostUSD += s.Quantity * s.IntendedPrice * bps / 10000
+	}
+
+	sort.Float64s(values)
+	n := float64(len(samples))
+	stats.AvgBps = sum / n
+	stats.MedianBps = percentile(values, 0.5)
+	stats.P90Bps = percentile(values, 0.9)
+	stats.WorstBps = values[len(values)-1]
+	stats.AvgDelayBps = delay / n
+	stats.AvgImpactBps = impact / n
+	if stats.LimitOrders > 0 {
+		stats.AvgLimitOffsetBps = limitOffset / float64(stats.LimitOrders)
+	}
+	return stats
+}
+
+// percentile 已排序数组的分位数（线性插值）
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAnalyzeSlippage(t *testing.T) {
+	samples := []ExecutionSample{
+		// 买入：决策 100，下单时 100.1（延迟 10bp），成交 100.3（冲击约 20bp），IOC 限价 100.6
+		{Exchange: "gateio", Symbol: "ETHUSDT", Side: "buy", OrderType: "ioc", Quantity: 10, IntendedPrice: 100, MarketPrice: 100.1, LimitPrice: 100.6, FillPrice: 100.3},
+		// 卖出：成交价低于决策价 40bp 为不利
+		{Exchange: "gateio", Symbol: "ETHUSDT", Side: "sell", OrderType: "market", Quantity: 10, IntendedPrice: 100, MarketPrice: 100, FillPrice: 99.6},
+		// 卖出价格更高：有利滑点为负数
+		{Exchange: "binance", Symbol: "BTCUSDT", Side: "sell", Quantity: 1, IntendedPrice: 1000, MarketPrice: 1000, FillPrice: 1001},
+	}
+	analysis := AnalyzeSlippage(samples)
+
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-6 }
+	overall := analysis.Overall
+	if overall.Orders != 3 || !near(overall.AvgBps, (30+40-10)/3.0) || !near(overall.MedianBps, 30) || !near(overall.WorstBps, 40) {
+		t.Fatalf("总体统计 = %+v", overall)
+	}
+	if !near(overall.P90Bps, 38) {
+		t.Errorf("90分位 = %.4f, 期望 38（30 与 40 之间插值）", overall.P90Bps)
+	}
+	if overall.LimitOrders != 1 || !near(overall.AvgLimitOffsetBps, 0.5/100.1*10000) {
+		t.Errorf("限价让价 = %+v", overall)
+	}
+	if !near(overall.CostUSD, 10*100*0.003+10*100*0.004-1*1000*0.001) {
+		t.Errorf("滑点成本 = %.4f", overall.CostUSD)
+	}
+
+	eth := analysis.BySymbol["ETHUSDT"]
+	if eth == nil || eth.Orders != 2 || !near(eth.AvgDelayBps, 5) || !near(eth.AvgImpactBps, (0.2/100.1*10000+40)/2) {
+		t.Errorf("ETHUSDT 统计 = %+v", eth)
+	}
+	if analysis.ByExchange["gateio"].Orders != 2 || analysis.ByExchange["binance"].Orders != 1 {
+		t.Errorf("按交易所分组 = %+v", analysis.ByExchange)
+	}
+	if analysis.ByOrderType["ioc"].Orders != 1 || analysis.ByOrderType["market"].Orders != 1 || analysis.ByOrderType["unknown"].Orders != 1 {
+		t.Errorf("按下单类型分组（未记录类型归为 unknown）= %+v", analysis.ByOrderType)
+	}
+
+	if empty := AnalyzeSlippage(nil); empty.Overall.Orders != 0 || len(empty.BySymbol) != 0 {
+		t.Errorf("没有样本时应返回空统计: %+v", empty)
+	}
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"sort"
+	"time"
+)
+
+// 按币种的历史表现（写入AI提示，让模型知道自己在哪些币种上做得好/差）
+// 统计最近N天内平仓的交易：已实现盈亏、胜率和平均R倍数（盈亏 / 开仓时止损对应的风险）。
+// 只有记录了开仓止损价的交易计入平均R。
+
+// SymbolEdge 单个币种的历史表现
+type SymbolEdge struct {
+	Symbol      string  `json:"symbol"`
+	Trades      int     `json:"trades"`
+	Wins        int     `json:"wins"`
+	WinRate     float64 `json:"win_rate"`     // 百分比
+	RealizedPnL float64 `json:"realized_pnl"` // USDT
+	AvgR        float64 `json:"avg_r"`        // 平均R倍数
+	RTrades     int     `json:"r_trades"`     // 有止损价、计入平均R的交易数
+}
+
+// SymbolEdges 最近days天平仓交易的分币种表现（按已实现盈亏从高到低）
+func (l *DecisionLogger) SymbolEdges(days int, now time.Time) ([]SymbolEdge, error) {
+	trades, err := l.ClosedTrades(days, now)
+	if err != nil {
+		return nil, err
+	}
+	return symbolEdges(trades), nil
+}
+
+// ClosedTrades 最近days天（含今天）平仓的交易，开仓记录向前多查找 openLookbackDays 天
+func (l *DecisionLogger) ClosedTrades(days int, now time.Time) ([]TradeOutcome, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var history, records []*DecisionRecord
+	for i := days + openLookbackDays - 1; i >= 0; i-- {
+		dayRecords, err := l.recordsForDay(today.AddDate(0, 0, -i))
+		if err != nil {
+			return nil, err
+		}
+		if i >= days {
+			history = append(history, dayRecords...)
+		} else {
+			records = append(records, dayRecords...)
+		}
+	}
+	return closedTrades(history, records), nil
+}
+
+// symbolEdges 按币种汇总交易
+func symbolEdges(trades []TradeOutcome) []SymbolEdge {
+	bySymbol := make(map[string]*SymbolEdge)
+	totalR := make(map[string]float64)
+	for _, t := range trades {
+		edge, ok := bySymbol[t.Symbol]
+		if !ok {
+			edge = &SymbolEdge{Symbol: t.Symbol}
+			bySymbol[t.Symbol] = edge
+		}
+		edge.Trades++
+		edge.RealizedPnL += t.PnL
+		if t.PnL > 0 {
+			edge.Wins++
+		}
+		if t.RMultiple != nil {
+			edge.RTrades++
+			totalR[t.Symbol] += *t.RMultiple
+		}
+	}
+
+	edges := make([]SymbolEdge, 0, len(bySymbol))
+	for symbol, edge := range bySymbol {
+		edge.WinRate = float64(edge.Wins) / float64(edge.Trades) * 100
+		if edge.RTrades > 0 {
+			edge.AvgR = totalR[symbol] / float64(edge.RTrades)
+		}
+		edges = append(edges, *edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].RealizedPnL != edges[j].RealizedPnL {
+			return edges[i].RealizedPnL > edges[j].RealizedPnL
+		}
+		return edges[i].Symbol < edges[j].Symbol
+	})
+	return edges
+}
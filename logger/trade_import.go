@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 导入的交易记录
+// 安装本系统之前在交易所完成的交易，由 trader 从交易所成交历史重建为开仓/平仓动作后写入决策日志，
+// 交易表现分析、按币种表现和日报从第一天起就有历史数据。导入的记录标记为 Imported，没有AI决策和账户快照
+// （周期统计和净值曲线会跳过）；文件名为 decision_{时间}_import_{导入ID}.json，同一导入ID只写入一次，重复导入不会产生重复记录。
+
+// importIDPattern 导入ID中允许的字符（其他字符替换为下划线）
+var importIDPattern = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// LogImported 保存导入的记录（使用记录自身的时间戳），同一导入ID的记录已存在时跳过，返回是否新写入
+func (l *DecisionLogger) LogImported(importID string, record *DecisionRecord) (bool, error) {
+	importID = importIDPattern.ReplaceAllString(importID, "_")
+	existing, err := filepath.Glob(filepath.Join(l.logDir, "decision_*_import_"+importID+".json"))
+	if err != nil {
+		return false, fmt.Errorf("查找导入记录失败: %w", err)
+	}
+	if len(existing) > 0 {
+		return false, nil
+	}
+
+	// 文件按服务器本地日期命名，与 LogDecision 一致
+	record.Imported = true
+	record.DecisionID = fmt.Sprintf("%s_import_%s", record.Timestamp.In(time.Local).Format("20060102_150405"), importID)
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("序列化导入记录失败: %w", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(l.logDir, "decision_"+record.DecisionID+".json"), data, 0644); err != nil {
+		return false, fmt.Errorf("写入导入记录失败: %w", err)
+	}
+	return true, nil
+}
+
+// FirstCycleTime 本系统第一条决策记录的时间（不含导入的记录），还没有记录时返回零值
+func (l *DecisionLogger) FirstCycleTime() (time.Time, error) {
+	files, err := ioutil.ReadDir(l.logDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	// 文件名以时间开头，按名称排序即按时间排序
+	for _, file := range files {
+		if file.IsDir() || !isRecordFile(file.Name()) || strings.Contains(file.Name(), "_import_") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(l.logDir, file.Name()))
+		if err != nil {
+			continue
+		}
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+		return record.Timestamp, nil
+	}
+	return time.Time{}, nil
+}
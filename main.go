@@ -1,13 +1,23 @@
 package main
 
 import (
+    "context"
     "fmt"
     "log"
     "nofx/api"
+    "nofx/benchmark"
     "nofx/config"
+    "nofx/decision"
+    "nofx/indicator"
+    "nofx/logger"
     "nofx/manager"
     "nofx/market"
+    "nofx/notify"
     "nofx/pool"
+    "nofx/secrets"
+    "nofx/tracing"
+    "nofx/trader"
+    "nofx/vectorstore"
     "os"
     "os/signal"
     "strconv"
@@ -22,10 +32,21 @@ func main() {
 	fmt.Println("╚════════════════════════════════════════════════════════════╝")
 	fmt.Println()
 
-	// 加载配置文件
+	// 日志输出前脱敏（API密钥等凭证在加载配置时登记）
+	log.SetOutput(secrets.NewRedactingWriter(os.Stderr))
+
+	// 加载配置文件（--validate-config 只检查配置，不启动交易）
 	configFile := "config.json"
-	if len(os.Args) > 1 {
-		configFile = os.Args[1]
+	validateOnly := false
+	for _, arg := range os.Args[1:] {
+		if arg == "--validate-config" {
+			validateOnly = true
+			continue
+		}
+		configFile = arg
+	}
+	if validateOnly {
+		os.Exit(validateConfig(configFile))
 	}
 
 	log.Printf("📋 加载配置文件: %s", configFile)
@@ -33,8 +54,14 @@ func main() {
 	if err != nil {
 		log.Fatalf("❌ 加载配置失败: %v", err)
 	}
+	for _, field := range cfg.UnknownFields {
+		log.Printf("⚠️  配置中的未知字段 %s 将被忽略（拼写错误？字段列表见 config.schema.json）", field.Field)
+	}
 
 	log.Printf("✓ 配置加载成功，共%d个trader参赛", len(cfg.Traders))
+	if len(cfg.InvalidTraders) > 0 {
+		log.Printf("⚠️  %d个trader配置无效，将跳过（详见下方日志和 /api/config/errors）", len(cfg.InvalidTraders))
+	}
 	
 	// Initialize prompt manager (loads prompts from prompts/ directory)
 	// Note: prompt_manager.go has init() that auto-loads, but we can also explicitly initialize
@@ -53,6 +80,15 @@ func main() {
 	// 初始化市场数据提供者
 	market.InitializeProviders()
 
+	// 通过描述文件接入的行情源（无需写代码）
+	for _, path := range cfg.MarketDataDescriptors {
+		name, err := market.RegisterDescriptorProvider(path)
+		if err != nil {
+			log.Fatalf("❌ 加载行情源描述文件失败: %v", err)
+		}
+		log.Printf("✓ 已注册自定义行情源: %s (%s)", name, path)
+	}
+
 	// 设置市场数据提供者
 	providerName := cfg.MarketDataProvider
 	if providerName == "" {
@@ -64,14 +100,134 @@ func main() {
 	} else {
 		log.Printf("✓ 市场数据源: %s", providerName)
 	}
+	if len(cfg.FastPriceProviders) > 0 {
+		if err := market.SetFastPriceProviders(cfg.FastPriceProviders); err != nil {
+			log.Fatalf("❌ 配置当前价数据源失败: %v", err)
+		}
+		log.Printf("✓ 当前价按延迟优选数据源: %v（历史K线仍使用 %s）", cfg.FastPriceProviders, providerName)
+	}
+	if len(cfg.SymbolProviders) > 0 {
+		if err := market.SetSymbolProviders(cfg.SymbolProviders); err != nil {
+			log.Fatalf("❌ 配置币种数据源失败: %v", err)
+		}
+		// 确认每个数据源确实有该币种，没有的回退到默认数据源
+		for _, warning := range market.ValidateSymbolProviders(context.Background()) {
+			log.Printf("⚠️  币种数据源不可用，改用 %s: %s", providerName, warning)
+		}
+	}
+	if cfg.MarketDataChecks.Enabled {
+		checks := cfg.MarketDataChecks
+		market.SetAnomalyConfig(market.AnomalyConfig{
+			Enabled:           true,
+			MaxBarMovePct:     checks.MaxBarMovePct,
+			MaxZeroVolumeBars: checks.MaxZeroVolumeBars,
+			MaxStaleBars:      checks.MaxStaleBars,
+			RecoveryFetches:   checks.RecoveryFetches,
+		})
+		log.Printf("✓ 行情数据异常检测: 单根K线变动>%.1f%% / 连续%d根零成交量 / 落后%d根K线 时熔断该币种，连续%d次正常后恢复",
+			checks.MaxBarMovePct, checks.MaxZeroVolumeBars, checks.MaxStaleBars, checks.RecoveryFetches)
+	}
+
+	// 设置通知渠道
+	if cfg.Notifications.Enabled {
+		notifier := notify.New()
+		if cfg.Notifications.TelegramBotToken != "" && cfg.Notifications.TelegramChatID != "" {
+			notifier.AddChannel(notify.NewTelegramChannel(cfg.Notifications.TelegramBotToken, cfg.Notifications.TelegramChatID))
+			log.Printf("✓ 通知渠道: Telegram")
+		}
+		if cfg.Notifications.WebhookURL != "" {
+			notifier.AddChannel(notify.NewWebhookChannel(cfg.Notifications.WebhookURL))
+			log.Printf("✓ 通知渠道: Webhook")
+		}
+		if len(cfg.Notifications.Rules) > 0 {
+			loc := time.Local
+			if cfg.Notifications.Timezone != "" {
+				if loc, err = time.LoadLocation(cfg.Notifications.Timezone); err != nil {
+					log.Fatalf("❌ 无效的通知时区 %q: %v", cfg.Notifications.Timezone, err)
+				}
+			}
+			if err := notifier.SetRules(notificationRules(cfg.Notifications.Rules),
+				time.Duration(cfg.Notifications.DigestIntervalMinutes)*time.Minute, loc); err != nil {
+				log.Fatalf("❌ 配置通知规则失败: %v", err)
+			}
+			log.Printf("✓ 通知规则: %d条，汇总周期%d分钟", len(cfg.Notifications.Rules), cfg.Notifications.DigestIntervalMinutes)
+		}
+		notify.SetDefault(notifier)
+	}
+
+	// 设置链路追踪
+	if cfg.Tracing.Enabled {
+		tracing.SetExporter(tracing.NewOTLPExporter(cfg.Tracing.Endpoint, cfg.Tracing.ServiceName))
+		log.Printf("✓ 链路追踪: 导出到 %s", cfg.Tracing.Endpoint)
+	}
+
+	// 设置K线形态扫描窗口
+	indicator.SetPatternLookback(cfg.PatternLookbackBars)
+	indicator.SetRelativeStrengthEnabled(cfg.RelativeStrengthVsBTC)
+	market.SetBasisEnabled(cfg.BasisData)
+	if cfg.Volatility.Enabled {
+		market.SetVolatilityEnabled(true)
+		decision.SetMaxTPDailyMoves(cfg.Volatility.MaxTPDailyMoves)
+	}
+	if cfg.RangeDetector.Enabled {
+		market.SetRangeConfig(market.RangeConfig{
+			Enabled:   true,
+			Period:    cfg.RangeDetector.Period,
+			Threshold: cfg.RangeDetector.Threshold,
+		})
+	}
+	if cfg.FlowMetrics.Enabled {
+		market.SetFlowConfig(market.FlowConfig{
+			Enabled: true,
+			Period:  cfg.FlowMetrics.Period,
+			Points:  cfg.FlowMetrics.Points,
+		})
+	}
+	if cfg.FundingEntryGuard.Enabled {
+		decision.SetFundingEntryGuard(cfg.FundingEntryGuard.MinutesBefore, cfg.FundingEntryGuard.MinRate)
+	}
+	if cfg.LiquidityHours.Enabled {
+		market.SetLiquidityConfig(market.LiquidityConfig{
+			Enabled:      true,
+			LookbackDays: cfg.LiquidityHours.LookbackDays,
+			VolumeRatio:  cfg.LiquidityHours.IlliquidVolumeRatio,
+			SpreadRatio:  cfg.LiquidityHours.IlliquidSpreadRatio,
+		})
+	}
+	if cfg.DepthLimit.Enabled {
+		market.SetDepthConfig(market.DepthConfig{
+			Enabled:     true,
+			VolumePct:   cfg.DepthLimit.VolumePct,
+			VolumeHours: cfg.DepthLimit.VolumeHours,
+			DepthPct:    cfg.DepthLimit.DepthPct,
+			BandPct:     cfg.DepthLimit.BandPct,
+		})
+		log.Printf("💧 深度仓位上限: 平均每小时成交额的%.1f%%（近%d小时）与盘口±%.1f%%挂单额的%.0f%%取较小值",
+			cfg.DepthLimit.VolumePct, cfg.DepthLimit.VolumeHours, cfg.DepthLimit.BandPct, cfg.DepthLimit.DepthPct)
+	}
 
 	// 设置默认主流币种列表
 	pool.SetDefaultCoins(cfg.DefaultCoins)
 
+	// 远程默认币种列表（启动时同步一次，之后定时刷新）
+	stopDefaultCoins := func() {}
+	if cfg.DefaultCoinsSource.URL != "" {
+		src := cfg.DefaultCoinsSource
+		stopDefaultCoins = pool.StartDefaultCoinsRefresher(pool.DefaultCoinsSource{
+			URL:               src.URL,
+			S3Region:          src.S3Region,
+			S3Endpoint:        src.S3Endpoint,
+			S3AccessKeyID:     src.S3AccessKeyID,
+			S3SecretAccessKey: src.S3SecretAccessKey,
+		}, time.Duration(src.RefreshMinutes)*time.Minute)
+		log.Printf("✓ 已配置远程默认币种列表（每%d分钟刷新）", src.RefreshMinutes)
+	}
+
 	// 设置是否使用默认主流币种
 	pool.SetUseDefaultCoins(cfg.UseDefaultCoins)
 	if cfg.UseDefaultCoins {
-		log.Printf("✓ 已启用默认主流币种列表（共%d个币种）: %v", len(cfg.DefaultCoins), cfg.DefaultCoins)
+		defaultCoins := pool.GetDefaultCoins()
+		log.Printf("✓ 已启用默认主流币种列表（共%d个币种）: %v", len(defaultCoins), defaultCoins)
 	}
 
 	// 设置币种池API URL
@@ -86,6 +242,14 @@ func main() {
 
 	// 创建TraderManager
 	traderManager := manager.NewTraderManager()
+	for _, invalid := range cfg.InvalidTraders {
+		logInvalidTrader(invalid)
+		traderManager.AddInvalidTrader(invalid)
+	}
+	if len(cfg.StrategyProfiles) > 0 {
+		traderManager.SetStrategyProfiles(cfg.StrategyProfiles)
+		log.Printf("🎛️ 策略配置: %v（可通过 /api/profile 切换）", cfg.ProfileNames())
+	}
 
 	// 添加所有启用的trader
 	enabledCount := 0
@@ -100,25 +264,213 @@ func main() {
 		log.Printf("📦 [%d/%d] 初始化 %s (%s模型)...",
 			i+1, len(cfg.Traders), traderCfg.Name, strings.ToUpper(traderCfg.AIModel))
 
+		// 引用了策略配置的trader使用策略配置中的杠杆、仓位和止损补全
+		leverage, positionSize, autoStopLoss := cfg.TraderRisk(traderCfg)
 		err := traderManager.AddTrader(
 			traderCfg,
 			cfg.CoinPoolAPIURL,
 			cfg.MaxDailyLoss,
 			cfg.MaxDrawdown,
 			cfg.StopTradingMinutes,
-			cfg.Leverage, // 传递杠杆配置
-			cfg.PositionSize, // 传递仓位大小配置
+			leverage, // 传递杠杆配置
+			positionSize, // 传递仓位大小配置
+			autoStopLoss, // 传递止损止盈自动补全配置
+			cfg.DeriskLadder, // 传递降风险阶梯配置
+			cfg.SymbolLimits, // 传递币种单独的杠杆和仓位限制
 		)
 		if err != nil {
-			log.Fatalf("❌ 初始化trader失败: %v", err)
+			// 安全启动：创建失败（密钥格式、计价币种、模型参数等）的trader不启动，其余trader照常运行
+			invalid := config.InvalidTrader{ID: traderCfg.ID, Name: traderCfg.Name, Enabled: true,
+				Errors: []config.FieldError{{Field: fmt.Sprintf("traders[%s]", traderCfg.ID), Message: err.Error()}}}
+			logInvalidTrader(invalid)
+			traderManager.AddInvalidTrader(invalid)
+			enabledCount--
 		}
 	}
 
 	// 检查是否至少有一个启用的trader
 	if enabledCount == 0 {
+		if len(traderManager.GetInvalidTraders()) > 0 {
+			log.Fatalf("❌ 没有可以启动的trader，配置无效的trader见上方日志")
+		}
 		log.Fatalf("❌ 没有启用的trader，请在config.json中设置至少一个trader的enabled=true")
 	}
 
+	// 启用prompt快照归档
+	if cfg.PromptArchiveEnabled {
+		if err := traderManager.EnablePromptArchive(cfg.PromptArchiveRetentionDays); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+
+	// 启用行情快照
+	if cfg.MarketSnapshotEnabled {
+		if err := traderManager.EnableMarketSnapshots(cfg.MarketSnapshotRetentionDays); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+
+	// 相似历史情形检索
+	if cfg.SimilarSetups.Enabled {
+		setups := cfg.SimilarSetups
+		embedder, err := vectorstore.NewEmbedder(setups.EmbeddingProvider, setups.EmbeddingBaseURL, setups.EmbeddingAPIKey, setups.EmbeddingModel)
+		if err != nil {
+			log.Fatalf("❌ 相似情形检索配置错误: %v", err)
+		}
+		if err := traderManager.EnableSimilarSetups(embedder, setups.TopK, setups.MinScore); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+
+	// 决策检索的语义匹配
+	if cfg.SemanticSearch.Enabled {
+		search := cfg.SemanticSearch
+		embedder, err := vectorstore.NewEmbedder(search.EmbeddingProvider, search.EmbeddingBaseURL, search.EmbeddingAPIKey, search.EmbeddingModel)
+		if err != nil {
+			log.Fatalf("❌ 决策语义检索配置错误: %v", err)
+		}
+		traderManager.EnableSemanticSearch(embedder, search.MinScore)
+	}
+
+	// 决策延迟预算与过期行情保护
+	if cfg.StaleDataGuard.Enabled {
+		traderManager.EnableStaleDataGuard(time.Duration(cfg.StaleDataGuard.LatencyBudgetSeconds)*time.Second, cfg.StaleDataGuard.MaxPriceMovePct)
+	}
+
+	// 挂条件单前的触发价检查
+	if cfg.TriggerPriceCheck.Enabled {
+		traderManager.EnableTriggerPriceCheck(cfg.TriggerPriceCheck.Policy)
+	}
+
+	// 交易所私有实时推送
+	if cfg.RealtimeStream.Enabled {
+		traderManager.EnableRealtimeStream(time.Duration(cfg.RealtimeStream.MinCycleGapSeconds) * time.Second)
+	}
+
+	// 外部资金流动检测
+	if cfg.EquityGuard.Enabled {
+		traderManager.EnableEquityGuard(cfg.EquityGuard.ThresholdPct)
+	}
+
+	// 净值/BTC急跌熔断
+	if cfg.FlashCrashGuard.Enabled {
+		flash := cfg.FlashCrashGuard
+		traderManager.EnableFlashCrashGuard(trader.FlashCrashConfig{
+			EquityDropPct:  flash.EquityDropPct,
+			EquityWindow:   time.Duration(flash.EquityWindowMinutes) * time.Minute,
+			BTCMovePct:     flash.BTCMovePct,
+			BTCWindow:      time.Duration(flash.BTCWindowMinutes) * time.Minute,
+			TightenStopPct: flash.TightenStopPct,
+			ResumeAfter:    time.Duration(flash.ResumeAfterMinutes) * time.Minute,
+		})
+	}
+
+	// 多币种决策并行执行
+	if cfg.ParallelExecution.Enabled {
+		traderManager.EnableParallelExecution(cfg.ParallelExecution.MaxConcurrency)
+	}
+
+	// 决策限流
+	if cfg.DecisionThrottle.Enabled {
+		traderManager.EnableDecisionThrottle(trader.DecisionThrottleConfig{
+			MaxNewPositions: cfg.DecisionThrottle.MaxNewPositionsPerCycle,
+			MaxPerSymbol:    cfg.DecisionThrottle.MaxActionsPerSymbol,
+		})
+	}
+
+	// 跨trader的合并敞口
+	if cfg.PortfolioExposure.Enabled {
+		traderManager.EnablePortfolioExposure(cfg.PortfolioExposure.MaxNetUSD)
+	}
+
+	// 外部策略信号
+	if cfg.SignalWebhook.Enabled {
+		traderManager.EnableExternalSignals(time.Duration(cfg.SignalWebhook.HintTTLMinutes) * time.Minute)
+	}
+
+	// 震荡区间的顺势开仓门槛
+	if cfg.RangeDetector.Enabled && cfg.RangeDetector.MinTrendConfidence > 0 {
+		traderManager.EnableRangeFilter(cfg.RangeDetector.MinTrendConfidence)
+	}
+
+	// 过度交易检测
+	if cfg.Overtrading.Enabled {
+		traderManager.EnableOvertradingDetector(trader.OvertradingConfig{
+			Rules: logger.OvertradingRules{
+				ReentryMinutes:   cfg.Overtrading.ReentryMinutes,
+				FlipMinutes:      cfg.Overtrading.FlipMinutes,
+				MaxTradesPerHour: cfg.Overtrading.MaxTradesPerHour,
+			},
+			LookbackHours: cfg.Overtrading.LookbackHours,
+		})
+	}
+
+	// 非流动时段仓位缩减
+	if cfg.LiquidityHours.Enabled {
+		traderManager.EnableLiquidityHours(cfg.LiquidityHours.SizeFactor)
+	}
+
+	// 大单拆分执行
+	if cfg.OrderSlicing.Enabled {
+		traderManager.EnableOrderSlicing(trader.OrderSlicingConfig{
+			Mode:              cfg.OrderSlicing.Mode,
+			BarVolumePct:      cfg.OrderSlicing.BarVolumePct,
+			Slices:            cfg.OrderSlicing.Slices,
+			Interval:          time.Duration(cfg.OrderSlicing.IntervalSeconds) * time.Second,
+			IcebergVisiblePct: cfg.OrderSlicing.IcebergVisiblePct,
+			MaxDriftPct:       cfg.OrderSlicing.MaxPriceDriftPct,
+		})
+	}
+
+	// 部分成交补单
+	if cfg.PartialFills.RetryRemainder {
+		traderManager.EnablePartialFillRetry(trader.PartialFillConfig{
+			MaxRetries:      cfg.PartialFills.MaxRetries,
+			MinRemainderPct: cfg.PartialFills.MinRemainderPct,
+		})
+	}
+
+	// 导入安装之前的交易所成交历史
+	if cfg.TradeImport.Enabled {
+		traderManager.ImportTradeHistory(cfg.TradeImport.Days)
+	}
+
+	// 开仓通知附带K线图
+	if cfg.Notifications.Enabled && cfg.Notifications.TradeCharts {
+		traderManager.EnableTradeCharts(cfg.Notifications.ChartBaseURL)
+	}
+
+	// 人工审批通知的批准/拒绝按钮（与图表链接共用API地址）
+	if cfg.Notifications.ChartBaseURL != "" {
+		traderManager.EnableApprovalLinks(cfg.Notifications.ChartBaseURL)
+	}
+
+	// 添加买入持有基准（BTC为主基准，可选默认币种等权组合）
+	if cfg.Benchmark.Enabled {
+		benchmarkConfigs := []benchmark.Config{{
+			ID:      "benchmark_btc",
+			Name:    "BTC 买入持有",
+			Symbols: []string{"BTCUSDT"},
+		}}
+		if cfg.Benchmark.IncludeBasket {
+			benchmarkConfigs = append(benchmarkConfigs, benchmark.Config{
+				ID:      "benchmark_basket",
+				Name:    benchmark.BasketName(cfg.DefaultCoins),
+				Symbols: cfg.DefaultCoins,
+			})
+		}
+		for _, bc := range benchmarkConfigs {
+			bc.InitialBalance = cfg.Benchmark.InitialBalance
+			bc.FeeRate = cfg.Benchmark.FeeRatePct / 100
+			bc.StateDir = fmt.Sprintf("decision_logs/%s", bc.ID)
+			b, err := benchmark.New(bc)
+			if err != nil {
+				log.Fatalf("❌ 初始化基准失败: %v", err)
+			}
+			traderManager.AddBenchmark(b)
+		}
+	}
+
 	fmt.Println()
 	fmt.Println("🏁 竞赛参赛者:")
 	for _, traderCfg := range cfg.Traders {
@@ -146,6 +498,12 @@ func main() {
 
 	// 创建并启动API服务器
 	apiServer := api.NewServer(traderManager, cfg.APIServerPort, cfg.WebUsername, cfg.WebPassword)
+	if cfg.MCPServer.Enabled {
+		apiServer.EnableMCP()
+	}
+	if cfg.SignalWebhook.Enabled {
+		apiServer.EnableSignalWebhook(cfg.SignalWebhook.Token)
+	}
 	go func() {
 		if err := apiServer.Start(); err != nil {
 			log.Printf("❌ API服务器错误: %v", err)
@@ -156,6 +514,11 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+    // 全局AI调用调度（需在启动trader之前设置）
+    if cfg.AIScheduler.MaxConcurrentCalls > 0 {
+        traderManager.EnableAIScheduler(cfg.AIScheduler.MaxConcurrentCalls, time.Duration(cfg.AIScheduler.StartStaggerSeconds)*time.Second)
+    }
+
     // 启动所有trader
     traderManager.StartAll()
 
@@ -165,6 +528,69 @@ func main() {
         time.Duration(cfg.DecisionLogCleanupIntervalHours)*time.Hour,
     )
 
+    // 启动基准净值采样任务
+    stopBenchmarks := func() {}
+    if cfg.Benchmark.Enabled {
+        stopBenchmarks = traderManager.StartBenchmarks(time.Duration(cfg.Benchmark.IntervalMinutes) * time.Minute)
+    }
+
+    // 启动交易所上下架监控
+    stopListingWatcher := func() {}
+    if cfg.ListingWatcher.Enabled {
+        stopListingWatcher = traderManager.StartListingWatcher(
+            time.Duration(cfg.ListingWatcher.IntervalMinutes)*time.Minute,
+            time.Duration(cfg.ListingWatcher.CloseBeforeMinutes)*time.Minute,
+        )
+    }
+
+    // 启动成交量/持仓量异动扫描
+    stopSurgeScanner := func() {}
+    if cfg.SurgeScanner.Enabled {
+        stopSurgeScanner = traderManager.StartSurgeScanner(market.SurgeConfig{
+            Windows:  cfg.SurgeScanner.Windows,
+            Lookback: cfg.SurgeScanner.Lookback,
+            VolumeZ:  cfg.SurgeScanner.VolumeZ,
+            OIZ:      cfg.SurgeScanner.OIZ,
+        }, cfg.SurgeScanner.Symbols, time.Duration(cfg.SurgeScanner.IntervalMinutes)*time.Minute)
+    }
+
+    // 启动交易循环卡死检测
+    stopWatchdog := func() {}
+    if cfg.Watchdog.Enabled {
+        stopWatchdog = traderManager.StartWatchdog(
+            cfg.Watchdog.StallFactor,
+            time.Duration(cfg.Watchdog.CheckIntervalSeconds)*time.Second,
+        )
+    }
+
+    // 启动每日日报推送
+    traderManager.SetReportFeeRate(cfg.DailyReport.FeeRatePct)
+    stopDailyReports := func() {}
+    if cfg.DailyReport.Enabled {
+        stopDailyReports = traderManager.StartDailyReports(cfg.DailyReport.Hour)
+    }
+
+    // 启动每日对账
+    traderManager.SetReconcileTolerance(cfg.Reconciliation.PnLTolerancePct)
+    stopReconciliation := func() {}
+    if cfg.Reconciliation.Enabled {
+        stopReconciliation = traderManager.StartReconciliation(cfg.Reconciliation.Hour, time.Duration(cfg.Reconciliation.LookbackHours)*time.Hour)
+    }
+
+    // 启动Telegram命令（只接受白名单聊天的命令）
+    stopTelegramCommands := func() {}
+    if cfg.Notifications.TelegramCommands && cfg.Notifications.TelegramBotToken != "" {
+        chatIDs := cfg.Notifications.TelegramCommandChatIDs
+        if len(chatIDs) == 0 && cfg.Notifications.TelegramChatID != "" {
+            chatIDs = []string{cfg.Notifications.TelegramChatID}
+        }
+        if len(chatIDs) == 0 {
+            log.Printf("⚠️ 未配置允许发送命令的Telegram chat ID，Telegram命令未启动")
+        } else {
+            stopTelegramCommands = traderManager.StartTelegramCommands(cfg.Notifications.TelegramBotToken, chatIDs)
+        }
+    }
+
 	// 等待退出信号
 	<-sigChan
     fmt.Println()
@@ -172,8 +598,76 @@ func main() {
     log.Println("📛 收到退出信号，正在停止所有trader...")
     // 停止清理任务
     stopCleanup()
-    traderManager.StopAll()
+    stopBenchmarks()
+    stopListingWatcher()
+    stopSurgeScanner()
+    stopWatchdog()
+    stopDailyReports()
+    stopReconciliation()
+    stopTelegramCommands()
+    stopDefaultCoins()
+    traderManager.Shutdown(cfg.Shutdown.Policy, time.Duration(cfg.Shutdown.TimeoutSeconds)*time.Second)
+    tracing.Shutdown() // 发送剩余的追踪数据
 
 	fmt.Println()
 	fmt.Println("👋 感谢使用AI交易竞赛系统！")
 }
+
+// logInvalidTrader 记录配置无效、未启动的trader（逐个字段），启用的trader同时发送通知
+func logInvalidTrader(invalid config.InvalidTrader) {
+	log.Printf("❌ trader %s (%s) 配置无效，未启动:", invalid.ID, invalid.Name)
+	messages := make([]string, 0, len(invalid.Errors))
+	for _, e := range invalid.Errors {
+		log.Printf("    • %s", e.Error())
+		messages = append(messages, e.Error())
+	}
+	if !invalid.Enabled {
+		return
+	}
+	notify.Send(notify.Event{
+		Type:     "config.invalid_trader",
+		Severity: notify.SeverityCritical,
+		TraderID: invalid.ID,
+		Title:    fmt.Sprintf("%s 配置无效，未启动", invalid.Name),
+		Message:  strings.Join(messages, "\n"),
+	})
+}
+
+// notificationRules 把配置中的通知规则转换为 notify 的规则
+func notificationRules(configs []config.NotificationRuleConfig) []notify.Rule {
+	rules := make([]notify.Rule, 0, len(configs))
+	for _, rc := range configs {
+		rules = append(rules, notify.Rule{
+			Events:      rc.Events,
+			MinSeverity: notify.Severity(rc.MinSeverity),
+			Symbols:     rc.Symbols,
+			Channels:    rc.Channels,
+			Delivery:    notify.Delivery(rc.Delivery),
+			QuietHours:  rc.QuietHours,
+		})
+	}
+	return rules
+}
+
+// validateConfig 只检查配置文件（schema、密钥引用和各trader的字段），不启动交易，返回进程退出码
+func validateConfig(configFile string) int {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		fmt.Printf("❌ %s 无效: %v\n", configFile, err)
+		return 1
+	}
+	for _, field := range cfg.UnknownFields {
+		fmt.Printf("⚠️  未知字段 %s 将被忽略\n", field.Field)
+	}
+	for _, invalid := range cfg.InvalidTraders {
+		fmt.Printf("❌ trader %s (%s) 配置无效:\n", invalid.ID, invalid.Name)
+		for _, e := range invalid.Errors {
+			fmt.Printf("    • %s\n", e.Error())
+		}
+	}
+	if len(cfg.InvalidTraders) > 0 {
+		return 1
+	}
+	fmt.Printf("✓ %s 有效（%d个trader）\n", configFile, len(cfg.Traders))
+	return 0
+}
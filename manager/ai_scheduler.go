@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"log"
+	"nofx/mcp"
+	"sort"
+	"time"
+)
+
+// EnableAIScheduler 启用全局AI调用调度：所有trader的AI调用（含复核、集成模型）共享 maxConcurrent 个并发名额，
+// 并把各trader的启动时间错开 stagger（为0时在最短扫描间隔内均匀分布），避免扫描周期同时触发。
+// 需在 StartAll 之前调用。
+func (tm *TraderManager) EnableAIScheduler(maxConcurrent int, stagger time.Duration) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	mcp.SetScheduler(mcp.NewScheduler(maxConcurrent))
+
+	if stagger <= 0 && len(tm.traders) > 1 {
+		var shortest time.Duration
+		for _, t := range tm.traders {
+			if interval := t.GetScanInterval(); interval > 0 && (shortest == 0 || interval < shortest) {
+				shortest = interval
+			}
+		}
+		stagger = shortest / time.Duration(len(tm.traders))
+	}
+	tm.startStagger = stagger
+	log.Printf("🚦 已启用AI调用调度：最多同时 %d 个调用，trader启动间隔 %v", maxConcurrent, stagger)
+}
+
+// GetAISchedulerStats AI调用调度统计（未启用时返回nil）
+func (tm *TraderManager) GetAISchedulerStats() *mcp.SchedulerStats {
+	scheduler := mcp.GetScheduler()
+	if scheduler == nil {
+		return nil
+	}
+	stats := scheduler.Stats()
+	return &stats
+}
+
+// sortedTraderIDsLocked 按ID排序的trader列表（保证启动顺序稳定），调用方需持有锁
+func (tm *TraderManager) sortedTraderIDsLocked() []string {
+	ids := make([]string, 0, len(tm.traders))
+	for id := range tm.traders {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
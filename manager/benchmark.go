@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"nofx/benchmark"
+	"time"
+)
+
+// AddBenchmark 添加买入持有基准（第一个添加的作为计算alpha的主基准）
+func (tm *TraderManager) AddBenchmark(b *benchmark.Benchmark) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.benchmarks = append(tm.benchmarks, b)
+	log.Printf("✓ 基准 '%s' 已添加（持有 %v，初始资金 %.0f USDT）", b.Name(), b.Symbols(), b.InitialBalance())
+}
+
+// GetBenchmark 获取指定ID的基准
+func (tm *TraderManager) GetBenchmark(id string) (*benchmark.Benchmark, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	for _, b := range tm.benchmarks {
+		if b.ID() == id {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("基准 '%s' 不存在", id)
+}
+
+// GetBenchmarkSummaries 所有基准的最新净值
+func (tm *TraderManager) GetBenchmarkSummaries() []map[string]interface{} {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.benchmarkSummariesLocked()
+}
+
+// benchmarkSummariesLocked 调用方需持有读锁
+func (tm *TraderManager) benchmarkSummariesLocked() []map[string]interface{} {
+	summaries := make([]map[string]interface{}, 0, len(tm.benchmarks))
+	for _, b := range tm.benchmarks {
+		summary := map[string]interface{}{
+			"benchmark_id":    b.ID(),
+			"name":            b.Name(),
+			"symbols":         b.Symbols(),
+			"initial_balance": b.InitialBalance(),
+			"is_benchmark":    true,
+		}
+		if latest := b.Latest(); latest != nil {
+			summary["total_equity"] = latest.Equity
+			summary["total_pnl"] = latest.Equity - b.InitialBalance()
+			summary["total_pnl_pct"] = latest.ReturnPct
+			summary["updated_at"] = latest.Timestamp
+		}
+		if start := b.StartTime(); !start.IsZero() {
+			summary["start_time"] = start
+			summary["start_prices"] = b.StartPrices()
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// StartBenchmarks 启动基准净值采样定时任务，返回停止函数
+func (tm *TraderManager) StartBenchmarks(interval time.Duration) func() {
+	stop := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// 立即执行一次，启动时完成买入
+		tm.updateBenchmarks()
+
+		for {
+			select {
+			case <-ticker.C:
+				tm.updateBenchmarks()
+			case <-stop:
+				log.Println("📐 基准采样任务已停止")
+				return
+			}
+		}
+	}()
+
+	log.Printf("📐 已启动基准采样任务：每%d分钟记录一次净值", int(interval.Minutes()))
+
+	return func() { close(stop) }
+}
+
+// updateBenchmarks 更新一次所有基准的净值
+func (tm *TraderManager) updateBenchmarks() {
+	tm.mu.RLock()
+	benchmarks := append([]*benchmark.Benchmark(nil), tm.benchmarks...)
+	tm.mu.RUnlock()
+
+	for _, b := range benchmarks {
+		if _, err := b.Update(); err != nil {
+			log.Printf("⚠️ 基准 %s 更新失败: %v", b.Name(), err)
+		}
+	}
+}
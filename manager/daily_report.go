@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"nofx/logger"
+	"nofx/notify"
+	"nofx/trader"
+	"time"
+)
+
+// defaultReportFeeRatePct 未配置时估算手续费使用的费率（%，主流交易所taker费率）
+const defaultReportFeeRatePct = 0.05
+
+// SetReportFeeRate 设置日报估算手续费使用的费率（%）
+func (tm *TraderManager) SetReportFeeRate(feeRatePct float64) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.reportFeeRatePct = feeRatePct
+}
+
+// GetDailyReport 生成指定trader在指定日期的日报（date 的年月日按trader时区的自然日统计）
+func (tm *TraderManager) GetDailyReport(traderID string, date time.Time) (*logger.DailyReport, error) {
+	t, err := tm.GetTrader(traderID)
+	if err != nil {
+		return nil, err
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, t.Location())
+
+	tm.mu.RLock()
+	feeRate := tm.reportFeeRatePct
+	tm.mu.RUnlock()
+	if feeRate <= 0 {
+		feeRate = defaultReportFeeRatePct
+	}
+
+	report, err := t.GetDecisionLogger().BuildDailyReport(date, feeRate)
+	if err != nil {
+		return nil, fmt.Errorf("生成日报失败: %w", err)
+	}
+	return report, nil
+}
+
+// StartDailyReports 启动每日日报任务：每天 hour 点（trader时区）为每个trader生成前一天的日报并推送，返回停止函数
+func (tm *TraderManager) StartDailyReports(hour int) func() {
+	stop := make(chan struct{})
+
+	go func() {
+		due := make(map[string]time.Time) // trader ID -> 下一次推送时间
+		for {
+			now := time.Now()
+			next := now.Add(time.Hour) // 还没有trader时定期检查
+			for id, t := range tm.GetAllTraders() {
+				if _, ok := due[id]; !ok {
+					due[id] = nextReportTime(now.In(t.Location()), hour)
+				}
+				if due[id].Before(next) {
+					next = due[id]
+				}
+			}
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+				now = time.Now()
+				for id, t := range tm.GetAllTraders() {
+					if at, ok := due[id]; ok && !now.Before(at) {
+						tm.sendDailyReport(id, t, now.In(t.Location()).AddDate(0, 0, -1))
+						delete(due, id)
+					}
+				}
+			case <-stop:
+				timer.Stop()
+				log.Println("📊 每日日报任务已停止")
+				return
+			}
+		}
+	}()
+
+	log.Printf("📊 已启动每日日报任务：每天 %02d:00（trader时区）推送前一天的日报", hour)
+
+	return func() { close(stop) }
+}
+
+// nextReportTime 下一次推送日报的时间（now 所在时区的 hour 点）
+func nextReportTime(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// sendDailyReport 生成并推送trader指定日期的日报
+func (tm *TraderManager) sendDailyReport(id string, t *trader.AutoTrader, date time.Time) {
+	report, err := tm.GetDailyReport(id, date)
+	if err != nil {
+		log.Printf("⚠️ %s %v", t.GetName(), err)
+		return
+	}
+	notify.Send(notify.Event{
+		Type:     "report.daily",
+		Severity: notify.SeverityInfo,
+		TraderID: id,
+		Title:    fmt.Sprintf("%s 日报 %s: %+.2f USDT (%+.2f%%)", t.GetName(), report.Date, report.PnL, report.PnLPct),
+		Message:  report.Format(t.GetName()),
+	})
+}
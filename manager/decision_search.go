@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"nofx/logger"
+	"nofx/trader"
+	"nofx/vectorstore"
+	"sort"
+	"time"
+)
+
+// TraderSearchHit 带trader信息的决策检索结果
+type TraderSearchHit struct {
+	TraderID   string `json:"trader_id"`
+	TraderName string `json:"trader_name"`
+	CycleURL   string `json:"cycle_url"` // 周期报告接口
+	logger.DecisionSearchHit
+}
+
+// EnableSemanticSearch 决策检索在关键字之外按向量相似度匹配
+func (tm *TraderManager) EnableSemanticSearch(embedder vectorstore.Embedder, minScore float64) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.searchEmbedder = embedder
+	tm.searchMinScore = minScore
+	log.Printf("🔎 已启用决策语义检索：向量 %s，相似度≥%.2f", embedder.Name(), minScore)
+}
+
+// SemanticSearchEnabled 决策检索是否启用语义匹配
+func (tm *TraderManager) SemanticSearchEnabled() bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.searchEmbedder != nil
+}
+
+// SearchDecisions 在决策理由中检索（traderID 为空时检索所有trader，semantic 为false时只做关键字匹配）
+func (tm *TraderManager) SearchDecisions(traderID, text, symbol string, from, to time.Time, limit int, semantic bool) ([]TraderSearchHit, error) {
+	traders := tm.GetAllTraders()
+	if traderID != "" {
+		t, err := tm.GetTrader(traderID)
+		if err != nil {
+			return nil, err
+		}
+		traders = map[string]*trader.AutoTrader{traderID: t}
+	}
+
+	query := logger.DecisionSearchQuery{Text: text, From: from, To: to, Symbol: symbol, Limit: limit}
+	if semantic {
+		tm.mu.RLock()
+		query.Embedder, query.MinScore = tm.searchEmbedder, tm.searchMinScore
+		tm.mu.RUnlock()
+		if query.Embedder == nil {
+			return nil, fmt.Errorf("未启用语义检索（配置 semantic_search.enabled）")
+		}
+	}
+
+	results := []TraderSearchHit{}
+	for id, t := range traders {
+		hits, err := t.GetDecisionLogger().SearchDecisions(query)
+		if err != nil {
+			return nil, fmt.Errorf("%s 决策检索失败: %w", t.GetName(), err)
+		}
+		for _, hit := range hits {
+			results = append(results, TraderSearchHit{
+				TraderID:          id,
+				TraderName:        t.GetName(),
+				CycleURL:          fmt.Sprintf("/api/traders/%s/cycles/%s", id, hit.DecisionID),
+				DecisionSearchHit: hit,
+			})
+		}
+	}
+
+	// 合并各trader的结果：得分从高到低，同分时新的在前
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
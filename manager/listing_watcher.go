@@ -0,0 +1,138 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"nofx/notify"
+	"nofx/trader"
+	"time"
+)
+
+// listingWatcher 监控各交易所合约列表的上新和下架
+type listingWatcher struct {
+	tm          *TraderManager
+	closeBefore time.Duration
+	known       map[string]map[string]trader.Instrument // exchange -> symbol -> 上次看到的合约信息
+}
+
+// StartListingWatcher 启动上下架监控定时任务，返回停止函数
+// closeBefore: 下架前多久主动平仓
+func (tm *TraderManager) StartListingWatcher(interval, closeBefore time.Duration) func() {
+	w := &listingWatcher{
+		tm:          tm,
+		closeBefore: closeBefore,
+		known:       make(map[string]map[string]trader.Instrument),
+	}
+	stop := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// 立即执行一次，启动时就能识别已公布的下架计划
+		w.check()
+
+		for {
+			select {
+			case <-ticker.C:
+				w.check()
+			case <-stop:
+				log.Println("📰 上下架监控已停止")
+				return
+			}
+		}
+	}()
+
+	log.Printf("📰 已启动上下架监控：每%d分钟检查一次，下架前%d分钟平仓", int(interval.Minutes()), int(closeBefore.Minutes()))
+
+	return func() { close(stop) }
+}
+
+// check 检查一次所有交易所（同一交易所的多个trader只查询一次）
+func (w *listingWatcher) check() {
+	byExchange := make(map[string][]*trader.AutoTrader)
+	for _, t := range w.tm.GetAllTraders() {
+		byExchange[t.GetExchange()] = append(byExchange[t.GetExchange()], t)
+	}
+
+	for exchange, traders := range byExchange {
+		instruments, err := traders[0].ListInstruments()
+		if err != nil {
+			log.Printf("⚠️ 获取 %s 合约列表失败: %v", exchange, err)
+			continue
+		}
+
+		deadlines := w.diff(exchange, instruments)
+		for _, t := range traders {
+			t.SetDelistings(deadlines, w.closeBefore)
+		}
+	}
+}
+
+// diff 对比上次的合约列表，发送上新/下架通知，返回当前所有下架中币种的截止时间
+func (w *listingWatcher) diff(exchange string, instruments []trader.Instrument) map[string]time.Time {
+	previous, initialized := w.known[exchange]
+	current := make(map[string]trader.Instrument, len(instruments))
+	deadlines := make(map[string]time.Time)
+
+	for _, inst := range instruments {
+		current[inst.Symbol] = inst
+		prev, existed := previous[inst.Symbol]
+
+		switch inst.Status {
+		case trader.InstrumentDelisting, trader.InstrumentDelisted:
+			deadlines[inst.Symbol] = inst.DelistAt
+			// 首次发现下架计划（包括启动时已公布的）或下架时间变化时通知
+			if !existed || !isDelisting(prev.Status) || !prev.DelistAt.Equal(inst.DelistAt) {
+				notifyDelisting(exchange, inst)
+			}
+
+		case trader.InstrumentTrading, trader.InstrumentPending:
+			// 第一次检查只建立基线，不把已有合约当作上新
+			if initialized && !existed {
+				notifyListing(exchange, inst)
+			}
+		}
+	}
+
+	w.known[exchange] = current
+	return deadlines
+}
+
+// isDelisting 是否处于下架流程
+func isDelisting(status trader.InstrumentStatus) bool {
+	return status == trader.InstrumentDelisting || status == trader.InstrumentDelisted
+}
+
+// notifyListing 上新通知
+func notifyListing(exchange string, inst trader.Instrument) {
+	message := fmt.Sprintf("%s 上线新永续合约 %s", exchange, inst.Symbol)
+	if inst.Status == trader.InstrumentPending {
+		message += "（尚未开放交易）"
+	}
+	if !inst.ListedAt.IsZero() {
+		message += "，上线时间 " + inst.ListedAt.Format("2006-01-02 15:04")
+	}
+	notify.Send(notify.Event{
+		Type:     "listing.new",
+		Severity: notify.SeverityInfo,
+		Symbol:   inst.Symbol,
+		Title:    fmt.Sprintf("%s 新上线 %s", exchange, inst.Symbol),
+		Message:  message,
+	})
+}
+
+// notifyDelisting 下架通知
+func notifyDelisting(exchange string, inst trader.Instrument) {
+	deadline := "时间未知，将尽快平仓"
+	if !inst.DelistAt.IsZero() {
+		deadline = "下架时间 " + inst.DelistAt.Format("2006-01-02 15:04")
+	}
+	notify.Send(notify.Event{
+		Type:     "listing.delisting",
+		Severity: notify.SeverityWarning,
+		Symbol:   inst.Symbol,
+		Title:    fmt.Sprintf("%s 将下架 %s", exchange, inst.Symbol),
+		Message:  fmt.Sprintf("%s %s 已进入下架流程（%s），已从候选币种中移除，持仓将在下架前自动平仓", exchange, inst.Symbol, deadline),
+	})
+}
@@ -0,0 +1,38 @@
+package manager
+
+import (
+	"fmt"
+	"nofx/logger"
+	"nofx/trader"
+	"sort"
+)
+
+// TraderOvertrading 单个trader的过度交易检测结果
+type TraderOvertrading struct {
+	TraderID   string                    `json:"trader_id"`
+	TraderName string                    `json:"trader_name"`
+	Report     *logger.OvertradingReport `json:"report"`
+}
+
+// GetOvertradingReports 最近hours小时的过度交易检测结果（hours<=0时使用配置的小时数），traderID 为空时返回所有trader
+func (tm *TraderManager) GetOvertradingReports(traderID string, hours int) ([]TraderOvertrading, error) {
+	traders := tm.GetAllTraders()
+	if traderID != "" {
+		t, err := tm.GetTrader(traderID)
+		if err != nil {
+			return nil, err
+		}
+		traders = map[string]*trader.AutoTrader{traderID: t}
+	}
+
+	results := make([]TraderOvertrading, 0, len(traders))
+	for id, t := range traders {
+		report, err := t.GetOvertradingReport(hours)
+		if err != nil {
+			return nil, fmt.Errorf("%s 过度交易检测失败: %w", t.GetName(), err)
+		}
+		results = append(results, TraderOvertrading{TraderID: id, TraderName: t.GetName(), Report: report})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].TraderID < results[j].TraderID })
+	return results, nil
+}
@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"fmt"
+	"nofx/config"
+	"nofx/decision"
+	"nofx/trader"
+	"sort"
+	"time"
+)
+
+// SetStrategyProfiles 设置可切换的策略配置（需在AddTrader之前调用，配置应已经过Validate）
+func (tm *TraderManager) SetStrategyProfiles(profiles map[string]config.StrategyProfileConfig) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.profiles = profiles
+}
+
+// ProfileNames 可切换的策略配置名称（排序）
+func (tm *TraderManager) ProfileNames() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	names := make([]string, 0, len(tm.profiles))
+	for name := range tm.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyProfile 把trader切换到指定的策略配置（当前周期结束后生效，watchdog重建trader时保持）
+// 切换后模板、扫描间隔、下单类型、杠杆、仓位和止损补全都使用新配置的值，trader在config.json中单独设置的这些字段不再生效
+func (tm *TraderManager) ApplyProfile(traderID, name string) error {
+	tm.mu.RLock()
+	at, ok := tm.traders[traderID]
+	p, found := tm.profiles[name]
+	tm.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("trader ID '%s' 不存在", traderID)
+	}
+	if !found {
+		return fmt.Errorf("策略配置 '%s' 不存在", name)
+	}
+
+	// 切换要等当前周期结束，不持有管理器的锁
+	profile := traderProfile(name, p)
+	if err := at.ApplyProfile(profile); err != nil {
+		return err
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	cfg := tm.configs[traderID]
+	profile.ApplyTo(&cfg)
+	tm.configs[traderID] = cfg
+	return nil
+}
+
+// traderProfile 转换为trader使用的策略配置（p 已由 Config.Validate 合并全局配置）
+func traderProfile(name string, p config.StrategyProfileConfig) trader.StrategyProfile {
+	profile := trader.StrategyProfile{
+		Name:                 name,
+		SystemPromptTemplate: p.SystemPromptTemplate,
+		ScanInterval:         time.Duration(p.ScanIntervalMinutes) * time.Minute,
+		OrderType:            p.OrderType,
+		SymbolEdgeDays:       p.SymbolEdgeDays,
+		Indicators:           p.Indicators,
+	}
+	if p.Leverage != nil {
+		profile.BTCETHLeverage = p.Leverage.BTCETHLeverage
+		profile.AltcoinLeverage = p.Leverage.AltcoinLeverage
+	}
+	if p.PositionSize != nil {
+		profile.MinPositionSizeUSD = p.PositionSize.MinPositionSizeUSD
+		profile.MaxPositionSizeUSD = p.PositionSize.MaxPositionSizeUSD
+		profile.MaxMarginUsagePct = p.PositionSize.MaxMarginUsagePct
+		profile.MaxPositionSizeMult = p.PositionSize.MaxPositionSizeMult
+		profile.SafetyBufferPct = p.PositionSize.SafetyBufferPct
+	}
+	if p.AutoStopLoss != nil {
+		profile.AutoStopLoss = decision.AutoStopConfig{
+			Enabled:         p.AutoStopLoss.Enabled,
+			MinConfidence:   p.AutoStopLoss.MinConfidence,
+			ATRMultiplier:   p.AutoStopLoss.ATRMultiplier,
+			RiskRewardRatio: p.AutoStopLoss.RiskRewardRatio,
+		}
+	}
+	return profile
+}
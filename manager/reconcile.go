@@ -0,0 +1,97 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"nofx/notify"
+	"nofx/trader"
+	"time"
+)
+
+// defaultReconcileTolerancePct 未配置时币种盈亏差异的容差（%）
+const defaultReconcileTolerancePct = 5.0
+
+// SetReconcileTolerance 设置对账时币种已实现盈亏差异的容差（%）
+func (tm *TraderManager) SetReconcileTolerance(tolerancePct float64) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.reconcileTolerancePct = tolerancePct
+}
+
+// Reconcile 对账指定trader最近 lookback 时间内的决策日志与交易所成交/盈亏流水
+func (tm *TraderManager) Reconcile(traderID string, lookback time.Duration) (*trader.ReconcileReport, error) {
+	t, err := tm.GetTrader(traderID)
+	if err != nil {
+		return nil, err
+	}
+
+	tm.mu.RLock()
+	tolerance := tm.reconcileTolerancePct
+	tm.mu.RUnlock()
+	if tolerance <= 0 {
+		tolerance = defaultReconcileTolerancePct
+	}
+
+	now := time.Now()
+	return t.Reconcile(now.Add(-lookback), now, tolerance)
+}
+
+// StartReconciliation 启动每日对账任务：每天 hour 点（trader时区）对账最近 lookback 时间，发现差异时推送，返回停止函数
+func (tm *TraderManager) StartReconciliation(hour int, lookback time.Duration) func() {
+	stop := make(chan struct{})
+
+	go func() {
+		due := make(map[string]time.Time) // trader ID -> 下一次对账时间
+		for {
+			now := time.Now()
+			next := now.Add(time.Hour) // 还没有trader时定期检查
+			for id, t := range tm.GetAllTraders() {
+				if _, ok := due[id]; !ok {
+					due[id] = nextReportTime(now.In(t.Location()), hour)
+				}
+				if due[id].Before(next) {
+					next = due[id]
+				}
+			}
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+				now = time.Now()
+				for id, t := range tm.GetAllTraders() {
+					if at, ok := due[id]; ok && !now.Before(at) {
+						tm.runReconciliation(id, t, lookback)
+						delete(due, id)
+					}
+				}
+			case <-stop:
+				timer.Stop()
+				log.Println("🧾 每日对账任务已停止")
+				return
+			}
+		}
+	}()
+
+	log.Printf("🧾 已启动每日对账任务：每天 %02d:00（trader时区）对账最近 %v 的成交和盈亏", hour, lookback)
+
+	return func() { close(stop) }
+}
+
+// runReconciliation 对账一个trader，有差异时推送对账报告
+func (tm *TraderManager) runReconciliation(id string, t *trader.AutoTrader, lookback time.Duration) {
+	report, err := tm.Reconcile(id, lookback)
+	if err != nil {
+		log.Printf("⚠️ %s 对账失败: %v", t.GetName(), err)
+		return
+	}
+	if len(report.Discrepancies) == 0 {
+		return
+	}
+	notify.Send(notify.Event{
+		Type:     "reconcile.discrepancy",
+		Severity: notify.SeverityWarning,
+		TraderID: id,
+		Title:    fmt.Sprintf("%s 对账发现 %d 项差异", t.GetName(), len(report.Discrepancies)),
+		Message:  report.Format(),
+	})
+}
@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"context"
+	"log"
+	"nofx/trader"
+	"sync"
+	"time"
+)
+
+// Shutdown 停止所有trader，并按退出策略（leave / breakeven / flatten）并行处理各trader的持仓，最多等待timeout
+func (tm *TraderManager) Shutdown(policy string, timeout time.Duration) {
+	tm.StopAll()
+	if policy == "" || policy == trader.ShutdownLeave {
+		return
+	}
+
+	log.Printf("⏏️  按退出策略 %s 处理持仓（最多等待 %v）...", policy, timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, t := range tm.GetAllTraders() {
+		wg.Add(1)
+		go func(at *trader.AutoTrader) {
+			defer wg.Done()
+			if err := at.Shutdown(ctx, policy); err != nil {
+				log.Printf("❌ %s 退出处理失败: %v", at.GetName(), err)
+			}
+		}(t)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("⏱  退出处理超时（%v），不再等待", timeout)
+	}
+}
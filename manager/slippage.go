@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"fmt"
+	"nofx/logger"
+	"nofx/trader"
+)
+
+// GetSlippageAnalysis 统计最近N个周期的滑点，traderID 为空时汇总所有trader（可按交易所对比）
+func (tm *TraderManager) GetSlippageAnalysis(traderID string, lookbackCycles int) (*logger.SlippageAnalysis, error) {
+	var traders []*trader.AutoTrader
+	if traderID != "" {
+		t, err := tm.GetTrader(traderID)
+		if err != nil {
+			return nil, err
+		}
+		traders = append(traders, t)
+	} else {
+		for _, t := range tm.GetAllTraders() {
+			traders = append(traders, t)
+		}
+	}
+
+	var samples []logger.ExecutionSample
+	for _, t := range traders {
+		traderSamples, err := t.GetDecisionLogger().ExecutionSamples(lookbackCycles)
+		if err != nil {
+			return nil, fmt.Errorf("%s 读取成交记录失败: %w", t.GetName(), err)
+		}
+		for i := range traderSamples {
+			traderSamples[i].Exchange = t.GetExchange()
+		}
+		samples = append(samples, traderSamples...)
+	}
+	return logger.AnalyzeSlippage(samples), nil
+}
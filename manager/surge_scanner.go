@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"log"
+	"nofx/market"
+	"nofx/pool"
+	"sort"
+	"sync"
+	"time"
+)
+
+// surgeScanner 定时扫描成交量/持仓量异动，把超过阈值的币种推送给所有trader作为候选币种
+type surgeScanner struct {
+	tm       *TraderManager
+	cfg      market.SurgeConfig
+	symbols  []string // 扫描的币种（为空时使用默认币种列表）
+	interval time.Duration
+
+	mu       sync.Mutex
+	snapshot SurgeSnapshot
+}
+
+// SurgeSnapshot 最近一次异动扫描的结果
+type SurgeSnapshot struct {
+	ScannedAt time.Time                      `json:"scanned_at"`
+	Scanned   int                            `json:"scanned"` // 扫描的币种数
+	Failed    int                            `json:"failed"`  // 获取数据失败的币种数
+	Alerts    map[string][]market.SurgeAlert `json:"alerts"`  // 超过阈值的币种
+}
+
+// StartSurgeScanner 启动异动扫描定时任务，返回停止函数
+// symbols 为空时扫描默认币种列表（随远程默认币种同步更新）
+func (tm *TraderManager) StartSurgeScanner(cfg market.SurgeConfig, symbols []string, interval time.Duration) func() {
+	s := &surgeScanner{tm: tm, cfg: cfg, symbols: symbols, interval: interval}
+	tm.mu.Lock()
+	tm.surges = s
+	tm.mu.Unlock()
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		s.scan()
+		for {
+			select {
+			case <-ticker.C:
+				s.scan()
+			case <-stop:
+				log.Println("📈 异动扫描已停止")
+				return
+			}
+		}
+	}()
+
+	log.Printf("📈 已启动成交量/持仓量异动扫描：每%d分钟一次", int(interval.Minutes()))
+	return func() { close(stop) }
+}
+
+// scan 扫描一次所有币种，结果推送给所有trader（有效期为两个扫描间隔，扫描失败时旧结果自然过期）
+func (s *surgeScanner) scan() {
+	symbols := s.symbols
+	if len(symbols) == 0 {
+		symbols = pool.GetDefaultCoins()
+	}
+
+	now := time.Now()
+	snapshot := SurgeSnapshot{ScannedAt: now, Alerts: make(map[string][]market.SurgeAlert)}
+	for _, symbol := range symbols {
+		provider, err := market.ProviderFor(symbol)
+		if err == nil {
+			var alerts []market.SurgeAlert
+			alerts, err = market.DetectSurges(provider, symbol, s.cfg, now)
+			if len(alerts) > 0 {
+				snapshot.Alerts[symbol] = alerts
+			}
+		}
+		snapshot.Scanned++
+		if err != nil {
+			snapshot.Failed++
+			log.Printf("⚠️ 异动扫描 %s 失败: %v", symbol, err)
+		}
+	}
+
+	if len(snapshot.Alerts) > 0 {
+		flagged := make([]string, 0, len(snapshot.Alerts))
+		for symbol, alerts := range snapshot.Alerts {
+			flagged = append(flagged, symbol+" "+alerts[0].String())
+		}
+		sort.Strings(flagged)
+		log.Printf("📈 异动币种 %d 个: %v", len(flagged), flagged)
+	}
+
+	s.mu.Lock()
+	s.snapshot = snapshot
+	s.mu.Unlock()
+
+	for _, t := range s.tm.GetAllTraders() {
+		t.SetSurgeAlerts(snapshot.Alerts, 2*s.interval)
+	}
+}
+
+// GetSurges 最近一次异动扫描的结果（未启用时为nil）
+func (tm *TraderManager) GetSurges() *SurgeSnapshot {
+	tm.mu.RLock()
+	s := tm.surges
+	tm.mu.RUnlock()
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := s.snapshot
+	return &snapshot
+}
@@ -0,0 +1,274 @@
+package manager
+
+import (
+	"fmt"
+	"nofx/notify"
+	"nofx/trader"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPauseMinutes /pause 未指定时长时的暂停时间
+const defaultPauseMinutes = 24 * 60
+
+// commandSymbolPattern /close 接受的币种格式（如 ETHUSDT、1000PEPEUSDT）
+var commandSymbolPattern = regexp.MustCompile(`^[A-Z0-9]{2,20}$`)
+
+// telegramHelp 命令说明
+const telegramHelp = `可用命令：
+/status — 所有trader的运行状态和净值
+/positions [trader] — 当前持仓
+/pause <trader|all> [分钟] — 暂停交易（默认24小时）
+/resume <trader|all> — 解除暂停
+/close <SYMBOL> [long|short] [trader] — 平仓
+/pnl [today|yesterday|YYYY-MM-DD] — 当日盈亏`
+
+// StartTelegramCommands 启动Telegram命令bot，只接受 allowedChatIDs 中聊天发来的命令，返回停止函数
+func (tm *TraderManager) StartTelegramCommands(token string, allowedChatIDs []string) func() {
+	return notify.NewTelegramBot(token, allowedChatIDs, tm.HandleCommand).Start()
+}
+
+// HandleCommand 执行一条运维命令并返回回复文本
+func (tm *TraderManager) HandleCommand(command string, args []string) string {
+	switch command {
+	case "status":
+		return tm.commandStatus()
+	case "positions":
+		return tm.commandPositions(args)
+	case "pause":
+		return tm.commandPause(args)
+	case "resume":
+		return tm.commandResume(args)
+	case "close":
+		return tm.commandClose(args)
+	case "pnl":
+		return tm.commandPnL(args)
+	case "start", "help":
+		return telegramHelp
+	default:
+		return fmt.Sprintf("未知命令 /%s\n\n%s", command, telegramHelp)
+	}
+}
+
+// commandTraders 按参数选出trader：空或all为全部（按ID排序）
+func (tm *TraderManager) commandTraders(id string) ([]*trader.AutoTrader, error) {
+	if id != "" && id != "all" {
+		t, err := tm.GetTrader(id)
+		if err != nil {
+			return nil, err
+		}
+		return []*trader.AutoTrader{t}, nil
+	}
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	var traders []*trader.AutoTrader
+	for _, traderID := range tm.sortedTraderIDsLocked() {
+		traders = append(traders, tm.traders[traderID])
+	}
+	return traders, nil
+}
+
+// commandStatus /status
+func (tm *TraderManager) commandStatus() string {
+	traders, _ := tm.commandTraders("")
+	if len(traders) == 0 {
+		return "没有运行中的trader"
+	}
+	var sb strings.Builder
+	for _, t := range traders {
+		status := t.GetStatus()
+		state := "运行中"
+		if running, _ := status["is_running"].(bool); !running {
+			state = "已停止"
+		}
+		if restrictions, _ := status["restrictions"].([]string); len(restrictions) > 0 {
+			state += "，" + strings.Join(restrictions, "，")
+		}
+		fmt.Fprintf(&sb, "%s (%s/%s): %s\n", t.GetID(), status["exchange"], t.GetAIModel(), state)
+
+		account, err := t.GetAccountInfo()
+		if err != nil {
+			fmt.Fprintf(&sb, "  账户获取失败: %v\n", err)
+			continue
+		}
+		fmt.Fprintf(&sb, "  净值 %.2f，总盈亏 %+.2f (%+.2f%%)，持仓 %v 个，保证金使用率 %.1f%%\n",
+			account["total_equity"], account["total_pnl"], account["total_pnl_pct"], account["position_count"], account["margin_used_pct"])
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// commandPositions /positions [trader]
+func (tm *TraderManager) commandPositions(args []string) string {
+	traders, err := tm.commandTraders(argAt(args, 0))
+	if err != nil {
+		return err.Error()
+	}
+	var sb strings.Builder
+	for _, t := range traders {
+		positions, err := t.GetPositions()
+		if err != nil {
+			fmt.Fprintf(&sb, "%s: %v\n", t.GetID(), err)
+			continue
+		}
+		sb.WriteString(formatPositions(t.GetID(), positions))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// formatPositions 一个trader的持仓列表（GetPositions 的结果）
+func formatPositions(traderID string, positions []map[string]interface{}) string {
+	if len(positions) == 0 {
+		return fmt.Sprintf("%s: 无持仓\n", traderID)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s:\n", traderID)
+	for _, pos := range positions {
+		fmt.Fprintf(&sb, "  %s %s %vx 数量 %.4f 开仓 %.4f 标记 %.4f 盈亏 %+.2f (%+.2f%%)\n",
+			pos["symbol"], strings.ToUpper(fmt.Sprint(pos["side"])), pos["leverage"], pos["quantity"],
+			pos["entry_price"], pos["mark_price"], pos["unrealized_pnl"], pos["unrealized_pnl_pct"])
+	}
+	return sb.String()
+}
+
+// commandPause /pause <trader|all> [分钟]
+func (tm *TraderManager) commandPause(args []string) string {
+	if len(args) == 0 {
+		return "用法: /pause <trader|all> [分钟]"
+	}
+	minutes := defaultPauseMinutes
+	if len(args) > 1 {
+		m, err := strconv.Atoi(args[1])
+		if err != nil || m <= 0 {
+			return fmt.Sprintf("无效的暂停时长: %s", args[1])
+		}
+		minutes = m
+	}
+	traders, err := tm.commandTraders(args[0])
+	if err != nil {
+		return err.Error()
+	}
+	until := time.Now().Add(time.Duration(minutes) * time.Minute)
+	for _, t := range traders {
+		t.Pause(time.Duration(minutes)*time.Minute, "Telegram人工暂停")
+	}
+	return fmt.Sprintf("⏸ 已暂停 %s 至 %s", traderIDs(traders), until.Format("01-02 15:04"))
+}
+
+// commandResume /resume <trader|all>
+func (tm *TraderManager) commandResume(args []string) string {
+	if len(args) == 0 {
+		return "用法: /resume <trader|all>"
+	}
+	traders, err := tm.commandTraders(args[0])
+	if err != nil {
+		return err.Error()
+	}
+	var resumed []*trader.AutoTrader
+	for _, t := range traders {
+		if t.Resume() {
+			resumed = append(resumed, t)
+		}
+	}
+	if len(resumed) == 0 {
+		return "没有处于暂停中的trader"
+	}
+	return fmt.Sprintf("▶️ 已恢复 %s", traderIDs(resumed))
+}
+
+// commandClose /close <SYMBOL> [long|short] [trader]
+func (tm *TraderManager) commandClose(args []string) string {
+	if len(args) == 0 {
+		return "用法: /close <SYMBOL> [long|short] [trader]"
+	}
+	symbol := strings.ToUpper(args[0])
+	if !commandSymbolPattern.MatchString(symbol) {
+		return fmt.Sprintf("无效的币种: %s（如 ETHUSDT）", args[0])
+	}
+	side, traderID := "", ""
+	for _, arg := range args[1:] {
+		if lower := strings.ToLower(arg); lower == "long" || lower == "short" {
+			side = lower
+		} else {
+			traderID = arg
+		}
+	}
+	traders, err := tm.commandTraders(traderID)
+	if err != nil {
+		return err.Error()
+	}
+
+	var sb strings.Builder
+	for _, t := range traders {
+		action, err := t.ClosePosition(symbol, side)
+		if err != nil {
+			if traderID != "" || !strings.Contains(err.Error(), "没有") {
+				fmt.Fprintf(&sb, "❌ %s: %v\n", t.GetID(), err)
+			}
+			continue
+		}
+		fmt.Fprintf(&sb, "✓ %s: %s %s 数量 %.4f @ %.4f\n", t.GetID(), symbol, action.Action, action.Quantity, action.Price)
+	}
+	if sb.Len() == 0 {
+		return fmt.Sprintf("%s 没有持仓", symbol)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// commandPnL /pnl [today|yesterday|YYYY-MM-DD]（today/yesterday 按各trader时区）
+func (tm *TraderManager) commandPnL(args []string) string {
+	var date time.Time // 指定日期，为零时按各trader时区的今天/昨天
+	daysAgo := 0
+	switch arg := strings.ToLower(argAt(args, 0)); arg {
+	case "", "today":
+	case "yesterday":
+		daysAgo = 1
+	default:
+		parsed, err := time.ParseInLocation("2006-01-02", arg, time.Local)
+		if err != nil {
+			return fmt.Sprintf("无效的日期: %s（today / yesterday / YYYY-MM-DD）", arg)
+		}
+		date = parsed
+	}
+
+	traders, _ := tm.commandTraders("")
+	var sb strings.Builder
+	sb.WriteString("📊 当日盈亏\n")
+	total := 0.0
+	for _, t := range traders {
+		day := date
+		if day.IsZero() {
+			day = time.Now().In(t.Location()).AddDate(0, 0, -daysAgo)
+		}
+		report, err := tm.GetDailyReport(t.GetID(), day)
+		if err != nil {
+			fmt.Fprintf(&sb, "%s: %v\n", t.GetID(), err)
+			continue
+		}
+		total += report.PnL
+		fmt.Fprintf(&sb, "%s %s: %+.2f USDT (%+.2f%%)，平仓 %d 笔，胜率 %.0f%%\n",
+			t.GetID(), report.Date, report.PnL, report.PnLPct, report.ClosedTrades, report.WinRate)
+	}
+	if len(traders) > 1 {
+		fmt.Fprintf(&sb, "合计: %+.2f USDT\n", total)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// argAt 第i个参数，不存在时为空
+func argAt(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
+
+// traderIDs 拼接trader ID
+func traderIDs(traders []*trader.AutoTrader) string {
+	ids := make([]string, 0, len(traders))
+	for _, t := range traders {
+		ids = append(ids, t.GetID())
+	}
+	return strings.Join(ids, ", ")
+}
@@ -0,0 +1,160 @@
+package manager
+
+import (
+	"nofx/logger"
+	"nofx/trader"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCommandManager 带两个未启动trader的管理器（不连接交易所，决策日志写在临时目录）
+func newCommandManager(t *testing.T, ids ...string) *TraderManager {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	tm := NewTraderManager()
+	for _, id := range ids {
+		at, err := trader.NewAutoTrader(trader.AutoTraderConfig{
+			ID:              id,
+			Name:            id,
+			AIModel:         "custom",
+			Exchange:        "gateio",
+			GateioAPIKey:    "test-key",
+			GateioSecretKey: "test-secret",
+			CustomAPIURL:    "http://127.0.0.1:1",
+			CustomAPIKey:    "test-key",
+			CustomModelName: "mock",
+			ScanInterval:    time.Minute,
+			InitialBalance:  1000,
+		})
+		if err != nil {
+			t.Fatalf("创建trader失败: %v", err)
+		}
+		tm.traders[id] = at
+	}
+	return tm
+}
+
+func paused(t *trader.AutoTrader) bool {
+	restrictions, _ := t.GetStatus()["restrictions"].([]string)
+	for _, r := range restrictions {
+		if strings.HasPrefix(r, "暂停交易至") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCommandPauseResume(t *testing.T) {
+	tm := newCommandManager(t, "alpha", "beta")
+	alpha, beta := tm.traders["alpha"], tm.traders["beta"]
+
+	if reply := tm.HandleCommand("pause", nil); !strings.HasPrefix(reply, "用法: /pause") {
+		t.Errorf("缺少参数应返回用法: %s", reply)
+	}
+	if reply := tm.HandleCommand("pause", []string{"alpha", "abc"}); !strings.Contains(reply, "无效的暂停时长") || paused(alpha) {
+		t.Errorf("无效时长不应暂停: %s", reply)
+	}
+	if reply := tm.HandleCommand("pause", []string{"gamma"}); !strings.Contains(reply, "gamma") || paused(alpha) {
+		t.Errorf("不存在的trader: %s", reply)
+	}
+
+	reply := tm.HandleCommand("pause", []string{"alpha", "30"})
+	if !strings.HasPrefix(reply, "⏸ 已暂停 alpha 至 ") || !paused(alpha) || paused(beta) {
+		t.Fatalf("应只暂停alpha: %s", reply)
+	}
+	if status := alpha.GetStatus(); status["stop_reason"] != "Telegram人工暂停" {
+		t.Errorf("暂停原因 = %v", status["stop_reason"])
+	}
+
+	if reply := tm.HandleCommand("resume", []string{"all"}); reply != "▶️ 已恢复 alpha" || paused(alpha) {
+		t.Fatalf("all 只恢复处于暂停中的trader: %s", reply)
+	}
+	if reply := tm.HandleCommand("resume", []string{"alpha"}); reply != "没有处于暂停中的trader" {
+		t.Errorf("重复恢复: %s", reply)
+	}
+
+	if reply := tm.HandleCommand("pause", []string{"all"}); !strings.HasPrefix(reply, "⏸ 已暂停 alpha, beta 至 ") || !paused(alpha) || !paused(beta) {
+		t.Fatalf("all 暂停全部trader: %s", reply)
+	}
+}
+
+func TestCommandCloseValidatesSymbol(t *testing.T) {
+	tm := newCommandManager(t, "alpha")
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "用法: /close <SYMBOL> [long|short] [trader]"},
+		{[]string{"ETH/USDT"}, "无效的币种: ETH/USDT"},
+		{[]string{"eth-usdt", "long"}, "无效的币种: eth-usdt"},
+		{[]string{"E"}, "无效的币种: E"},
+		{[]string{strings.Repeat("X", 21)}, "无效的币种"},
+		{[]string{"ETHUSDT", "long", "gamma"}, "gamma"}, // 币种合法，trader不存在
+	}
+	for _, tt := range tests {
+		if reply := tm.HandleCommand("close", tt.args); !strings.Contains(reply, tt.want) {
+			t.Errorf("/close %v = %q, 期望包含 %q", tt.args, reply, tt.want)
+		}
+	}
+}
+
+func TestFormatPositions(t *testing.T) {
+	if got := formatPositions("alpha", nil); got != "alpha: 无持仓\n" {
+		t.Errorf("无持仓: %q", got)
+	}
+	got := formatPositions("alpha", []map[string]interface{}{
+		{"symbol": "ETHUSDT", "side": "long", "leverage": 5, "quantity": 0.5, "entry_price": 3000.0, "mark_price": 3100.0, "unrealized_pnl": 50.0, "unrealized_pnl_pct": 16.67},
+		{"symbol": "BTCUSDT", "side": "short", "leverage": 3, "quantity": 0.01, "entry_price": 60000.0, "mark_price": 61000.0, "unrealized_pnl": -10.0, "unrealized_pnl_pct": -5.0},
+	})
+	want := "alpha:\n" +
+		"  ETHUSDT LONG 5x 数量 0.5000 开仓 3000.0000 标记 3100.0000 盈亏 +50.00 (+16.67%)\n" +
+		"  BTCUSDT SHORT 3x 数量 0.0100 开仓 60000.0000 标记 61000.0000 盈亏 -10.00 (-5.00%)\n"
+	if got != want {
+		t.Errorf("持仓格式:\n%s\n期望:\n%s", got, want)
+	}
+}
+
+func TestCommandPnL(t *testing.T) {
+	tm := newCommandManager(t, "alpha", "beta")
+	// alpha 今天两个周期：净值 1000 → 1025
+	for _, equity := range []float64{1000, 1025} {
+		if err := tm.traders["alpha"].GetDecisionLogger().LogDecision(&logger.DecisionRecord{AccountState: logger.AccountSnapshot{TotalBalance: equity}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reply := tm.HandleCommand("pnl", nil)
+	today := time.Now().In(tm.traders["alpha"].Location()).Format("2006-01-02")
+	for _, want := range []string{
+		"📊 当日盈亏",
+		"alpha " + today + ": +25.00 USDT (+2.50%)，平仓 0 笔，胜率 0%",
+		"beta " + today + ": +0.00 USDT (+0.00%)",
+		"合计: +25.00 USDT",
+	} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("/pnl 缺少 %q:\n%s", want, reply)
+		}
+	}
+
+	if reply := tm.HandleCommand("pnl", []string{"2026-02-30"}); !strings.HasPrefix(reply, "无效的日期: 2026-02-30") {
+		t.Errorf("无效日期: %s", reply)
+	}
+	if reply := tm.HandleCommand("pnl", []string{"2020-01-01"}); !strings.Contains(reply, "alpha 2020-01-01: +0.00 USDT") {
+		t.Errorf("指定日期: %s", reply)
+	}
+}
+
+func TestCommandUnknownAndHelp(t *testing.T) {
+	tm := newCommandManager(t)
+	reply := tm.HandleCommand("foo", nil)
+	if !strings.HasPrefix(reply, "未知命令 /foo") || !strings.Contains(reply, telegramHelp) {
+		t.Errorf("未知命令应回复用法: %s", reply)
+	}
+	if tm.HandleCommand("help", nil) != telegramHelp || tm.HandleCommand("start", nil) != telegramHelp {
+		t.Error("/help 和 /start 应回复命令说明")
+	}
+	if reply := tm.HandleCommand("status", nil); reply != "没有运行中的trader" {
+		t.Errorf("没有trader时 /status: %s", reply)
+	}
+}
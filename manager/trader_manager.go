@@ -3,27 +3,68 @@ package manager
 import (
 	"fmt"
 	"log"
+	"nofx/benchmark"
 	"nofx/config"
+	"nofx/decision"
+	"nofx/market"
+	"nofx/mcp"
+	"nofx/notify"
 	"nofx/trader"
+	"nofx/vectorstore"
 	"sync"
 	"time"
 )
 
 // TraderManager 管理多个trader实例
 type TraderManager struct {
-    traders map[string]*trader.AutoTrader // key: trader ID
-    mu      sync.RWMutex
+    traders    map[string]*trader.AutoTrader // key: trader ID
+    benchmarks []*benchmark.Benchmark        // 买入持有基准（第一个为计算alpha的主基准）
+    mu         sync.RWMutex
+
+    reportFeeRatePct      float64 // 日报估算手续费使用的费率（%）
+    reconcileTolerancePct float64 // 对账时币种盈亏差异的容差（%）
+
+    startStagger time.Duration  // trader之间的启动间隔（错开扫描周期）
+    stopStarting chan struct{}  // StopAll 时取消尚未启动的trader
+
+    configs  map[string]trader.AutoTraderConfig // 创建trader使用的配置（watchdog重建trader用）
+    setups   []func(*trader.AutoTrader) error   // 已对所有trader启用的功能（重建trader时按顺序重放）
+    watchdog *watchdog                          // 交易循环卡死检测（未启用时为nil）
+    exposure *trader.ExposureBook               // 跨trader的合并敞口（未启用时为nil）
+    surges   *surgeScanner                      // 成交量/持仓量异动扫描（未启用时为nil）
+
+    searchEmbedder vectorstore.Embedder // 决策检索的语义匹配（未启用时为nil，只做关键字匹配）
+    searchMinScore float64              // 语义匹配的最低相似度
+
+    invalid []config.InvalidTrader // 配置无效、未启动的trader（安全启动模式）
+
+    profiles map[string]config.StrategyProfileConfig // 可切换的策略配置（已合并全局配置）
 }
 
 // NewTraderManager 创建trader管理器
 func NewTraderManager() *TraderManager {
 	return &TraderManager{
 		traders: make(map[string]*trader.AutoTrader),
+		configs: make(map[string]trader.AutoTraderConfig),
 	}
 }
 
+// AddInvalidTrader 记录配置无效、未启动的trader（配置校验失败或创建trader失败）
+func (tm *TraderManager) AddInvalidTrader(t config.InvalidTrader) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.invalid = append(tm.invalid, t)
+}
+
+// GetInvalidTraders 配置无效、未启动的trader
+func (tm *TraderManager) GetInvalidTraders() []config.InvalidTrader {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return append([]config.InvalidTrader{}, tm.invalid...)
+}
+
 // AddTrader 添加一个trader
-func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, leverage config.LeverageConfig, positionSize config.PositionSizeConfig) error {
+func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, leverage config.LeverageConfig, positionSize config.PositionSizeConfig, autoStopLoss config.AutoStopLossConfig, derisk config.DeriskLadderConfig, limits map[string]config.SymbolLimitConfig) error {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
@@ -52,6 +93,7 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		CoinPoolAPIURL:        coinPoolURL,
 		UseQwen:               cfg.AIModel == "qwen",
 		DeepSeekKey:           cfg.DeepSeekKey,
+		DeepSeekModel:         cfg.DeepSeekModel,
 		QwenKey:               cfg.QwenKey,
 		CustomAPIURL:          cfg.CustomAPIURL,
 		CustomAPIKey:          cfg.CustomAPIKey,
@@ -60,6 +102,7 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		InitialBalance:        cfg.InitialBalance,
 		BTCETHLeverage:        leverage.BTCETHLeverage,  // 使用配置的杠杆倍数
 		AltcoinLeverage:       leverage.AltcoinLeverage, // 使用配置的杠杆倍数
+		SymbolLimits:          symbolLimits(limits),
 		MinPositionSizeUSD:    positionSize.MinPositionSizeUSD,
 		MaxPositionSizeUSD:    positionSize.MaxPositionSizeUSD,
 		MaxMarginUsagePct:     positionSize.MaxMarginUsagePct,
@@ -70,6 +113,98 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		MaxDrawdown:           maxDrawdown,
 		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
 		SystemPromptTemplate:  cfg.SystemPromptTemplate, // 系统提示词模板名称
+		MarketDataFormat:      cfg.MarketDataFormat,     // 行情数据格式
+		OrderType:             cfg.OrderType,            // 执行策略默认下单类型
+		AnchorStopsToFill:     cfg.AnchorStopsToFill,    // 止损止盈锚定成交价
+		SymbolBlacklist:       cfg.SymbolBlacklist,
+		SymbolWhitelist:       cfg.SymbolWhitelist,
+		ReviewEnabled:         cfg.Review.Enabled,
+		ReviewAPIURL:          cfg.Review.CustomAPIURL,
+		ReviewAPIKey:          cfg.Review.CustomAPIKey,
+		ReviewModelName:       cfg.Review.CustomModelName,
+		EnsemblePolicy:        cfg.Ensemble.Policy,
+		ProxyURL:              cfg.ProxyURL,
+		QuoteAsset:            cfg.QuoteAsset,
+		ReportingCurrency:     cfg.ReportingCurrency,
+		Timezone:              cfg.Timezone,
+		ApprovalMode:          cfg.Approval.Enabled,
+		ApprovalTTL:           time.Duration(cfg.Approval.TTLMinutes) * time.Minute,
+		SymbolEdgeDays:        cfg.SymbolEdgeDays,
+		Strategy:              cfg.Strategy,
+		Carry: trader.CarryConfig{
+			HedgeExchange:   cfg.Carry.HedgeExchange,
+			Symbols:         cfg.Carry.Symbols,
+			EntrySpreadPct:  cfg.Carry.EntrySpreadPct,
+			ExitSpreadPct:   cfg.Carry.ExitSpreadPct,
+			PositionSizeUSD: cfg.Carry.PositionSizeUSD,
+			Leverage:        cfg.Carry.Leverage,
+			MaxPositions:    cfg.Carry.MaxPositions,
+		},
+		AutoStopLoss: decision.AutoStopConfig{
+			Enabled:         autoStopLoss.Enabled,
+			MinConfidence:   autoStopLoss.MinConfidence,
+			ATRMultiplier:   autoStopLoss.ATRMultiplier,
+			RiskRewardRatio: autoStopLoss.RiskRewardRatio,
+		},
+		DeriskLadder: trader.DeriskConfig{
+			Enabled:           derisk.Enabled,
+			ReduceSizeLossPct: derisk.ReduceSizeLossPct,
+			CloseOnlyLossPct:  derisk.CloseOnlyLossPct,
+			FlattenLossPct:    derisk.FlattenLossPct,
+			SizeFactor:        derisk.SizeFactor,
+		},
+	}
+
+	for _, g := range cfg.Grid {
+		traderConfig.Grid = append(traderConfig.Grid, trader.GridSymbol{
+			Symbol:          g.Symbol,
+			Lower:           g.Lower,
+			Upper:           g.Upper,
+			Levels:          g.Levels,
+			SizePerLevelUSD: g.SizePerLevelUSD,
+			Leverage:        g.Leverage,
+			StopLossPct:     g.StopLossPct,
+		})
+	}
+
+	// 引用了策略配置时记录名称和指标集（模板、扫描间隔等已在配置校验时填入）
+	if p, ok := tm.profiles[cfg.Profile]; ok && cfg.Profile != "" {
+		traderConfig.Profile = cfg.Profile
+		traderConfig.Indicators = p.Indicators
+	}
+
+	// A/B测试的变体（名称已在配置校验时确认存在）
+	if exp := cfg.Experiment; exp != nil {
+		experiment := &trader.ExperimentConfig{
+			Name:     exp.Name,
+			Schedule: exp.Schedule,
+			Block:    time.Duration(exp.BlockHours) * time.Hour,
+			Seed:     exp.Seed,
+		}
+		for _, name := range exp.Variants {
+			experiment.Variants = append(experiment.Variants, traderProfile(name, tm.profiles[name]))
+		}
+		traderConfig.Experiment = experiment
+	}
+
+	traderConfig.ModelParams = mcp.ModelParams{
+		Temperature:     cfg.ModelParams.Temperature,
+		TopP:            cfg.ModelParams.TopP,
+		MaxTokens:       cfg.ModelParams.MaxTokens,
+		PresencePenalty: cfg.ModelParams.PresencePenalty,
+		ReasoningEffort: cfg.ModelParams.ReasoningEffort,
+
+		ReasoningAsContent: cfg.ModelParams.ReasoningAsContent,
+	}
+
+	if cfg.Ensemble.Enabled {
+		for _, m := range cfg.Ensemble.Models {
+			traderConfig.EnsembleModels = append(traderConfig.EnsembleModels, trader.EnsembleModelConfig{
+				APIURL:    m.CustomAPIURL,
+				APIKey:    m.CustomAPIKey,
+				ModelName: m.CustomModelName,
+			})
+		}
 	}
 
 	// 创建trader实例
@@ -78,11 +213,47 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		return fmt.Errorf("创建trader失败: %w", err)
 	}
 
+	// 配置了出口代理时做一次连通性自检（失败只告警，不阻止启动）
+	if cfg.ProxyURL != "" {
+		if err := at.CheckConnectivity(); err != nil {
+			notify.Send(notify.Event{
+				Type:     "trader.connectivity_failed",
+				Severity: notify.SeverityCritical,
+				TraderID: cfg.ID,
+				Title:    fmt.Sprintf("%s 经代理访问交易所失败", cfg.Name),
+				Message:  err.Error(),
+			})
+		}
+	}
+
+	// 账户权限自检：缺少合约交易权限或持仓模式不符时不启动，避免到第一笔下单才发现
+	if err := at.CheckAccountPermissions(); err != nil {
+		return err
+	}
+
 	tm.traders[cfg.ID] = at
+	tm.configs[cfg.ID] = traderConfig
 	log.Printf("✓ Trader '%s' (%s) 已添加", cfg.Name, cfg.AIModel)
 	return nil
 }
 
+// symbolLimits 币种单独的杠杆和仓位限制（键统一为大写交易对，只写币种名时按USDT交易对）
+func symbolLimits(limits map[string]config.SymbolLimitConfig) decision.SymbolLimits {
+	if len(limits) == 0 {
+		return nil
+	}
+	converted := make(decision.SymbolLimits, len(limits))
+	for symbol, limit := range limits {
+		base, quote := market.SplitSymbol(symbol)
+		converted[market.WithQuote(base, quote)] = decision.SymbolLimit{
+			MaxLeverage:        limit.MaxLeverage,
+			MinPositionSizeUSD: limit.MinPositionSizeUSD,
+			MaxPositionSizeUSD: limit.MaxPositionSizeUSD,
+		}
+	}
+	return converted
+}
+
 // GetTrader 获取指定ID的trader
 func (tm *TraderManager) GetTrader(id string) (*trader.AutoTrader, error) {
 	tm.mu.RLock()
@@ -121,31 +292,345 @@ func (tm *TraderManager) GetTraderIDs() []string {
 
 // StartAll 启动所有trader
 func (tm *TraderManager) StartAll() {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 
 	log.Println("🚀 启动所有Trader...")
-	for id, t := range tm.traders {
+	stopStarting := make(chan struct{})
+	tm.stopStarting = stopStarting
+	for i, id := range tm.sortedTraderIDsLocked() {
+		delay := time.Duration(i) * tm.startStagger
 		go func(traderID string, at *trader.AutoTrader) {
-			log.Printf("▶️  启动 %s...", at.GetName())
-			if err := at.Run(); err != nil {
-				log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
+			if delay > 0 {
+				log.Printf("⏱  %s 将在 %v 后启动（错开扫描周期）", at.GetName(), delay)
+				select {
+				case <-time.After(delay):
+				case <-stopStarting:
+					return
+				}
 			}
-		}(id, t)
+			runTrader(at)
+		}(id, tm.traders[id])
+	}
+}
+
+// runTrader 运行trader主循环（阻塞到停止）
+func runTrader(at *trader.AutoTrader) {
+	log.Printf("▶️  启动 %s...", at.GetName())
+	if err := at.Run(); err != nil {
+		log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
+	}
+}
+
+// applySetupLocked 对所有trader启用一项功能，并记录下来供watchdog重建trader时重放，调用方需持有写锁
+func (tm *TraderManager) applySetupLocked(setup func(*trader.AutoTrader) error) error {
+	tm.setups = append(tm.setups, setup)
+	for _, id := range tm.sortedTraderIDsLocked() {
+		if err := setup(tm.traders[id]); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // StopAll 停止所有trader
 func (tm *TraderManager) StopAll() {
-    tm.mu.RLock()
-    defer tm.mu.RUnlock()
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
 
     log.Println("⏹  停止所有Trader...")
+    if tm.stopStarting != nil {
+        close(tm.stopStarting)
+        tm.stopStarting = nil
+    }
     for _, t := range tm.traders {
         t.Stop()
     }
 }
 
+// EnablePromptArchive 为所有trader启用prompt快照归档
+func (tm *TraderManager) EnablePromptArchive(retentionDays int) error {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    err := tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        if err := at.GetDecisionLogger().EnablePromptArchive(retentionDays); err != nil {
+            return fmt.Errorf("%s 启用prompt归档失败: %w", at.GetName(), err)
+        }
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+    log.Printf("🗜️  已启用prompt快照归档：gzip压缩保存，保留%d天", retentionDays)
+    return nil
+}
+
+// EnableMarketSnapshots 为所有trader启用行情快照
+func (tm *TraderManager) EnableMarketSnapshots(retentionDays int) error {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    err := tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        if err := at.GetDecisionLogger().EnableMarketSnapshots(retentionDays); err != nil {
+            return fmt.Errorf("%s 启用行情快照失败: %w", at.GetName(), err)
+        }
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+    log.Printf("📸 已启用行情快照：每个周期的market数据gzip压缩保存，保留%d天", retentionDays)
+    return nil
+}
+
+// EnableSimilarSetups 为所有trader启用相似历史情形检索
+func (tm *TraderManager) EnableSimilarSetups(embedder vectorstore.Embedder, topK int, minScore float64) error {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    err := tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        if err := at.EnableSimilarSetups(embedder, topK, minScore); err != nil {
+            return fmt.Errorf("%s 启用相似情形检索失败: %w", at.GetName(), err)
+        }
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+    log.Printf("🔍 已启用相似历史情形检索：向量 %s，每个币种最多%d条，相似度≥%.2f", embedder.Name(), topK, minScore)
+    return nil
+}
+
+// EnableStaleDataGuard 为所有trader启用决策延迟预算与过期行情保护
+func (tm *TraderManager) EnableStaleDataGuard(budget time.Duration, maxMovePct float64) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableStaleDataGuard(budget, maxMovePct)
+        return nil
+    })
+    log.Printf("⏱ 已启用过期行情保护：决策超过%.0f秒或价格偏离快照超过%.2f%%时按最新价复核开仓", budget.Seconds(), maxMovePct)
+}
+
+// EnableTriggerPriceCheck 为所有trader启用挂条件单前的触发价检查
+func (tm *TraderManager) EnableTriggerPriceCheck(policy string) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableTriggerPriceCheck(policy)
+        return nil
+    })
+    log.Printf("📐 已启用触发价检查：止损止盈越过最新价/标记价/指数价时按 %s 处理", policy)
+}
+
+// EnableRealtimeStream 为所有trader启用交易所私有推送（不支持的交易所继续轮询）
+func (tm *TraderManager) EnableRealtimeStream(minCycleGap time.Duration) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableRealtimeStream(minCycleGap)
+        return nil
+    })
+    log.Printf("📡 已启用实时推送：交易所侧平仓后提前开始下一周期（与上个周期至少间隔%.0f秒）", minCycleGap.Seconds())
+}
+
+// EnableEquityGuard 为所有trader启用外部资金流动检测
+func (tm *TraderManager) EnableEquityGuard(thresholdPct float64) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableEquityGuard(thresholdPct)
+        return nil
+    })
+    log.Printf("💸 已启用净值对账：无法用交易解释的余额变化超过净值%.1f%%时视为充值/提现并调整业绩基准", thresholdPct)
+}
+
+// EnableParallelExecution 为所有trader启用多币种决策并行执行
+func (tm *TraderManager) EnableParallelExecution(maxConcurrency int) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableParallelExecution(maxConcurrency)
+        return nil
+    })
+    log.Printf("⚡ 已启用并行执行：同一阶段内不同币种的决策并发执行（最多%d个）", maxConcurrency)
+}
+
+// EnableFlashCrashGuard 为所有trader启用净值/BTC急跌熔断
+func (tm *TraderManager) EnableFlashCrashGuard(cfg trader.FlashCrashConfig) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableFlashCrashGuard(cfg)
+        return nil
+    })
+    log.Printf("🧯 已启用急跌熔断：净值%.0f分钟回撤%.1f%%或BTC %.0f分钟波动%.1f%%时只允许平仓，企稳%.0f分钟后恢复",
+        cfg.EquityWindow.Minutes(), cfg.EquityDropPct, cfg.BTCWindow.Minutes(), cfg.BTCMovePct, cfg.ResumeAfter.Minutes())
+}
+
+// EnableDecisionThrottle 为所有trader启用决策限流
+func (tm *TraderManager) EnableDecisionThrottle(cfg trader.DecisionThrottleConfig) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableDecisionThrottle(cfg)
+        return nil
+    })
+    log.Printf("🚦 已启用决策限流：每周期最多%d个新开仓，每个币种最多%d个动作（0表示不限制）", cfg.MaxNewPositions, cfg.MaxPerSymbol)
+}
+
+// EnablePortfolioExposure 启用跨trader的合并敞口：所有trader共享敞口账本，maxNetUSD>0 时限制单个币种的组合净敞口
+func (tm *TraderManager) EnablePortfolioExposure(maxNetUSD float64) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    book := trader.NewExposureBook(maxNetUSD)
+    tm.exposure = book
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.SetExposureBook(book)
+        return nil
+    })
+    if maxNetUSD > 0 {
+        log.Printf("🧮 已启用跨trader合并敞口：单个币种所有trader合计净敞口上限 %.0f USDT", maxNetUSD)
+    } else {
+        log.Printf("🧮 已启用跨trader合并敞口（只汇总，不限制）")
+    }
+}
+
+// EnableExternalSignals 所有trader接受外部策略信号（webhook），ttl 为候选币种提示的有效期
+func (tm *TraderManager) EnableExternalSignals(ttl time.Duration) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableExternalSignals(ttl)
+        return nil
+    })
+    log.Printf("📡 已启用外部策略信号：候选币种提示 %v 内有效", ttl)
+}
+
+// GetExposure 跨trader的合并敞口视图（未启用时返回nil）
+func (tm *TraderManager) GetExposure() *trader.ExposureView {
+    tm.mu.RLock()
+    book := tm.exposure
+    tm.mu.RUnlock()
+    if book == nil {
+        return nil
+    }
+    return book.View()
+}
+
+// EnableLiquidityHours 为所有trader启用非流动时段仓位缩减
+func (tm *TraderManager) EnableLiquidityHours(sizeFactor float64) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableLiquidityHours(sizeFactor)
+        return nil
+    })
+    log.Printf("🌙 已启用分时段流动性画像：山寨币在历史非流动时段开仓/加仓时仓位缩减为%.0f%%", sizeFactor*100)
+}
+
+// EnableOvertradingDetector 为所有trader启用过度交易检测
+func (tm *TraderManager) EnableOvertradingDetector(cfg trader.OvertradingConfig) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableOvertradingDetector(cfg)
+        return nil
+    })
+    log.Printf("🔁 已启用过度交易检测：近%d小时内平仓后%d分钟内同向再开、%d分钟内反手、每小时超过%d笔交易时提醒AI",
+        cfg.LookbackHours, cfg.Rules.ReentryMinutes, cfg.Rules.FlipMinutes, cfg.Rules.MaxTradesPerHour)
+}
+
+// EnableRangeFilter 所有trader在震荡区间的币种上顺势开仓需要至少 minConfidence 的信心度
+func (tm *TraderManager) EnableRangeFilter(minConfidence int) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableRangeFilter(minConfidence)
+        return nil
+    })
+    log.Printf("🌀 已启用震荡过滤：震荡区间的币种上顺势开仓需要信心度 ≥ %d", minConfidence)
+}
+
+// EnablePartialFillRetry 为所有trader启用部分成交补单
+func (tm *TraderManager) EnablePartialFillRetry(cfg trader.PartialFillConfig) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnablePartialFillRetry(cfg)
+        return nil
+    })
+    log.Printf("🔁 已启用部分成交补单：剩余部分超过请求数量%.0f%%时重新下单（最多%d次）", cfg.MinRemainderPct, cfg.MaxRetries)
+}
+
+// ImportTradeHistory 在后台为所有trader导入安装之前的交易所成交历史（不支持的交易所记录警告）
+func (tm *TraderManager) ImportTradeHistory(days int) {
+    tm.mu.RLock()
+    traders := make([]*trader.AutoTrader, 0, len(tm.traders))
+    for _, id := range tm.sortedTraderIDsLocked() {
+        traders = append(traders, tm.traders[id])
+    }
+    tm.mu.RUnlock()
+
+    log.Printf("📥 开始导入最近%d天的交易所成交历史（本系统第一条决策记录之前）", days)
+    go func() {
+        for _, at := range traders {
+            if _, err := at.ImportTradeHistory(days); err != nil {
+                log.Printf("⚠️ [%s] 导入成交历史失败: %v", at.GetName(), err)
+            }
+        }
+    }()
+}
+
+// EnableOrderSlicing 为所有trader启用大单拆分执行
+func (tm *TraderManager) EnableOrderSlicing(cfg trader.OrderSlicingConfig) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableOrderSlicing(cfg)
+        return nil
+    })
+    log.Printf("🧊 已启用大单拆分：开仓名义价值超过3分钟平均成交额%.1f%%时按%s拆成子单执行", cfg.BarVolumePct, cfg.Mode)
+}
+
+// EnableTradeCharts 为所有trader启用开仓通知（附带决策K线图）
+func (tm *TraderManager) EnableTradeCharts(baseURL string) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableTradeCharts(baseURL)
+        return nil
+    })
+    log.Printf("📈 已启用开仓通知：附带决策K线图")
+}
+
+// EnableApprovalLinks 设置人工审批通知中按钮链接使用的API地址
+func (tm *TraderManager) EnableApprovalLinks(baseURL string) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+
+    tm.applySetupLocked(func(at *trader.AutoTrader) error {
+        at.EnableApprovalLinks(baseURL)
+        return nil
+    })
+}
+
 // StartDecisionLogCleanup 启动决策日志清理定时任务（与机器人一起运行）
 // 返回一个停止函数用于优雅关闭
 func (tm *TraderManager) StartDecisionLogCleanup(retentionDays int, interval time.Duration) func() {
@@ -190,6 +675,12 @@ func (tm *TraderManager) runDecisionLogCleanup(retentionDays int) {
         if err := dl.CleanOldRecords(retentionDays); err != nil {
             log.Printf("⚠️ 决策日志清理失败（%s）: %v", at.GetName(), err)
         }
+        if err := dl.CleanOldPrompts(); err != nil {
+            log.Printf("⚠️ prompt快照清理失败（%s）: %v", at.GetName(), err)
+        }
+        if err := dl.CleanOldMarketSnapshots(); err != nil {
+            log.Printf("⚠️ 行情快照清理失败（%s）: %v", at.GetName(), err)
+        }
     }
 }
 
@@ -209,10 +700,11 @@ func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 
 		status := t.GetStatus()
 
-		traders = append(traders, map[string]interface{}{
+		entry := map[string]interface{}{
 			"trader_id":       t.GetID(),
 			"trader_name":     t.GetName(),
 			"ai_model":        t.GetAIModel(),
+			"strategy":        status["strategy"],
 			"total_equity":    account["total_equity"],
 			"total_pnl":       account["total_pnl"],
 			"total_pnl_pct":   account["total_pnl_pct"],
@@ -220,11 +712,21 @@ func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 			"margin_used_pct": account["margin_used_pct"],
 			"call_count":      status["call_count"],
 			"is_running":      status["is_running"],
-		})
+		}
+		// 相对主基准（BTC买入持有）的超额收益
+		if len(tm.benchmarks) > 0 {
+			if latest := tm.benchmarks[0].Latest(); latest != nil {
+				if pnlPct, ok := account["total_pnl_pct"].(float64); ok {
+					entry["alpha_pct"] = pnlPct - latest.ReturnPct
+				}
+			}
+		}
+		traders = append(traders, entry)
 	}
 
 	comparison["traders"] = traders
 	comparison["count"] = len(traders)
+	comparison["benchmarks"] = tm.benchmarkSummariesLocked()
 
 	return comparison, nil
 }
@@ -0,0 +1,258 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nofx/notify"
+	"nofx/trader"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 交易循环卡死检测
+// trader 的主循环每轮都会更新心跳；运行中的trader超过 stallFactor 个扫描间隔没有心跳时视为卡死
+// （交易所/AI请求无超时挂起、锁死等）。watchdog 保存所有goroutine的堆栈，让旧实例退役，
+// 用原配置新建实例并重放已启用的功能后重新启动。新实例第一个周期会恢复未完成的开仓/加仓操作
+// （补挂保护单或回滚），风控暂停和降风险状态从 risk_state.json 恢复。
+// 卡住的旧goroutine无法被强制结束：退役后它醒来不再执行决策和下单，也不再写状态文件。
+// 新实例在旧周期返回后才创建，旧周期超过 retireTimeout 仍未返回时照常替换。
+
+// retireTimeout 等待卡死实例当前周期返回的最长时间
+const retireTimeout = 30 * time.Second
+
+// watchdog 交易循环卡死检测
+type watchdog struct {
+	tm          *TraderManager
+	stallFactor int
+	mu          sync.Mutex
+	stats       map[string]*WatchdogTraderStats
+}
+
+// WatchdogTraderStats 单个trader的卡死检测统计
+type WatchdogTraderStats struct {
+	TraderID      string    `json:"trader_id"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	Stalls        int       `json:"stalls"`       // 检测到卡死的次数
+	Restarts      int       `json:"restarts"`     // 成功重启的次数
+	CyclePanics   int       `json:"cycle_panics"` // 周期内捕获的panic次数
+	LastStallAt   time.Time `json:"last_stall_at,omitempty"`
+	LastDump      string    `json:"last_dump,omitempty"`  // 最近一次goroutine堆栈文件
+	LastError     string    `json:"last_error,omitempty"` // 最近一次重启失败的原因
+}
+
+// WatchdogStats 卡死检测统计（用于API）
+type WatchdogStats struct {
+	StallFactor int                   `json:"stall_factor"`
+	Stalls      int                   `json:"stalls"`
+	Restarts    int                   `json:"restarts"`
+	Traders     []WatchdogTraderStats `json:"traders"`
+}
+
+// StartWatchdog 启动交易循环卡死检测，返回停止函数
+// stallFactor: 超过多少个扫描间隔没有心跳视为卡死
+func (tm *TraderManager) StartWatchdog(stallFactor int, checkInterval time.Duration) func() {
+	w := &watchdog{
+		tm:          tm,
+		stallFactor: stallFactor,
+		stats:       make(map[string]*WatchdogTraderStats),
+	}
+	tm.mu.Lock()
+	tm.watchdog = w
+	tm.mu.Unlock()
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.check()
+			case <-stop:
+				log.Println("🐕 卡死检测已停止")
+				return
+			}
+		}
+	}()
+
+	log.Printf("🐕 已启动卡死检测：超过%d个扫描间隔没有心跳时保存堆栈并重启trader，每%.0f秒检查一次", stallFactor, checkInterval.Seconds())
+	return func() { close(stop) }
+}
+
+// check 检查所有trader的心跳
+func (w *watchdog) check() {
+	now := time.Now()
+	for id, t := range w.tm.GetAllTraders() {
+		heartbeat := t.LastHeartbeat()
+		w.mu.Lock()
+		stats := w.statsFor(id)
+		stats.LastHeartbeat = heartbeat
+		stats.CyclePanics = t.GetPanicStats().Count
+		w.mu.Unlock()
+
+		// 尚未启动（错开启动中）或已停止的trader不检测（已退役但重启失败的实例继续重试）
+		if heartbeat.IsZero() || (!t.IsRunning() && !t.IsRetired()) {
+			continue
+		}
+		timeout := time.Duration(w.stallFactor) * t.GetScanInterval()
+		if now.Sub(heartbeat) <= timeout {
+			continue
+		}
+		w.handleStall(id, t, now.Sub(heartbeat))
+	}
+}
+
+// handleStall 保存堆栈并重启卡死的trader
+func (w *watchdog) handleStall(id string, t *trader.AutoTrader, silence time.Duration) {
+	log.Printf("🐕 [%s] 已 %v 没有心跳（扫描间隔 %v），判定交易循环卡死", t.GetName(), silence.Round(time.Second), t.GetScanInterval())
+
+	dump, dumpErr := dumpGoroutines(id)
+	if dumpErr != nil {
+		log.Printf("⚠️ [%s] 保存goroutine堆栈失败: %v", t.GetName(), dumpErr)
+	}
+
+	w.mu.Lock()
+	stats := w.statsFor(id)
+	stats.Stalls++
+	stats.LastStallAt = time.Now()
+	stats.LastDump = dump
+	w.mu.Unlock()
+
+	err := w.tm.restartTrader(id, t)
+
+	w.mu.Lock()
+	if err != nil {
+		stats.LastError = err.Error()
+	} else {
+		stats.Restarts++
+		stats.LastError = ""
+	}
+	w.mu.Unlock()
+
+	if err != nil {
+		log.Printf("❌ [%s] 重启失败，下次检查时重试: %v", t.GetName(), err)
+		notify.Send(notify.Event{
+			Type:     "trader.restart_failed",
+			Severity: notify.SeverityCritical,
+			TraderID: id,
+			Title:    fmt.Sprintf("%s 交易循环卡死，重启失败", t.GetName()),
+			Message:  fmt.Sprintf("已 %v 没有完成周期，重启失败（下次检查时重试）: %v。堆栈: %s", silence.Round(time.Second), err, dump),
+		})
+		return
+	}
+	notify.Send(notify.Event{
+		Type:     "trader.restarted",
+		Severity: notify.SeverityCritical,
+		TraderID: id,
+		Title:    fmt.Sprintf("%s 交易循环卡死，已自动重启", t.GetName()),
+		Message:  fmt.Sprintf("已 %v 没有完成周期（扫描间隔 %v），已重建trader并恢复未完成的操作。堆栈: %s", silence.Round(time.Second), t.GetScanInterval(), dump),
+	})
+}
+
+// statsFor 获取trader的统计（不存在时创建），调用方需持有锁
+func (w *watchdog) statsFor(id string) *WatchdogTraderStats {
+	stats, ok := w.stats[id]
+	if !ok {
+		stats = &WatchdogTraderStats{TraderID: id}
+		w.stats[id] = stats
+	}
+	return stats
+}
+
+// snapshot 统计快照
+func (w *watchdog) snapshot() WatchdogStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	result := WatchdogStats{StallFactor: w.stallFactor, Traders: make([]WatchdogTraderStats, 0, len(w.stats))}
+	for _, stats := range w.stats {
+		result.Stalls += stats.Stalls
+		result.Restarts += stats.Restarts
+		result.Traders = append(result.Traders, *stats)
+	}
+	sort.Slice(result.Traders, func(i, j int) bool { return result.Traders[i].TraderID < result.Traders[j].TraderID })
+	return result
+}
+
+// GetWatchdogStats 卡死检测统计（未启用时返回nil）
+func (tm *TraderManager) GetWatchdogStats() *WatchdogStats {
+	tm.mu.RLock()
+	w := tm.watchdog
+	tm.mu.RUnlock()
+	if w == nil {
+		return nil
+	}
+	stats := w.snapshot()
+	return &stats
+}
+
+// restartTrader 让卡死的实例退役，用原配置新建trader替换并启动
+// 先退役旧实例并等待其当前周期返回（最多 retireTimeout），再创建新实例，避免两个实例同时下单和写状态文件。
+// 创建失败时旧实例保持退役，下次检查时重试。
+func (tm *TraderManager) restartTrader(id string, old *trader.AutoTrader) error {
+	tm.mu.RLock()
+	cfg, ok := tm.configs[id]
+	setups := append([]func(*trader.AutoTrader) error(nil), tm.setups...)
+	current := tm.traders[id]
+	tm.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("trader ID '%s' 没有保存的配置", id)
+	}
+	if current != old {
+		return fmt.Errorf("trader ID '%s' 已被替换", id)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), retireTimeout)
+	err := old.Retire(ctx)
+	cancel()
+	if err != nil {
+		log.Printf("⚠️ [%s] %v，旧实例已退役，继续重建", old.GetName(), err)
+	}
+
+	at, err := trader.NewAutoTrader(cfg)
+	if err != nil {
+		return fmt.Errorf("创建trader失败: %w", err)
+	}
+	for _, setup := range setups {
+		if err := setup(at); err != nil {
+			return err
+		}
+	}
+	// 运行中通过API修改的黑白名单
+	at.GetSymbolFilter().Update(old.GetSymbolFilter().Blacklist(), old.GetSymbolFilter().Whitelist())
+
+	tm.mu.Lock()
+	if tm.traders[id] != old {
+		tm.mu.Unlock()
+		return fmt.Errorf("trader ID '%s' 已被替换", id)
+	}
+	tm.traders[id] = at
+	tm.mu.Unlock()
+
+	go runTrader(at)
+	log.Printf("🐕 [%s] 已重建并重新启动", at.GetName())
+	return nil
+}
+
+// dumpGoroutines 把所有goroutine的堆栈写入 decision_logs/<trader_id>/watchdog/ 下，返回文件路径
+func dumpGoroutines(traderID string) (string, error) {
+	dir := filepath.Join("decision_logs", traderID, "watchdog")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("goroutines_%s.txt", time.Now().Format("20060102_150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		return "", err
+	}
+	return path, nil
+}
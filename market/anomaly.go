@@ -0,0 +1,141 @@
+package market
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Market data anomaly detection and per-symbol circuit breaker
+// Every fetch of the short-term (3m) series is checked for a price jump between the last two
+// bars, a streak of zero-volume bars and a stale latest bar. A failed check trips the breaker
+// for the symbol: Get returns ErrUnreliableData, so the symbol is left out of the prompt and
+// trades that need its data are refused. The data keeps being fetched and checked; after
+// RecoveryFetches consecutive clean fetches the breaker resets.
+
+// ErrUnreliableData is returned while the breaker for a symbol is open
+var ErrUnreliableData = errors.New("market data unreliable")
+
+// AnomalyConfig thresholds for the sanity checks (zero disables a check)
+type AnomalyConfig struct {
+	Enabled           bool
+	MaxBarMovePct     float64 // max close-to-close move between the last two bars, percent
+	MaxZeroVolumeBars int     // max consecutive zero-volume bars at the end of the series
+	MaxStaleBars      int     // latest bar may start at most this many intervals ago
+	RecoveryFetches   int     // consecutive clean fetches needed to reset the breaker (min 1)
+}
+
+// BreakerState is an open circuit breaker for one symbol
+type BreakerState struct {
+	Symbol       string    `json:"symbol"`
+	Reason       string    `json:"reason"`
+	TrippedAt    time.Time `json:"tripped_at"`
+	CleanFetches int       `json:"clean_fetches"` // clean fetches since the last anomaly
+}
+
+var anomalies = struct {
+	mu       sync.Mutex
+	config   AnomalyConfig
+	breakers map[string]*BreakerState
+}{
+	breakers: make(map[string]*BreakerState),
+}
+
+// SetAnomalyConfig enables (or disables) the data checks; open breakers are cleared
+func SetAnomalyConfig(cfg AnomalyConfig) {
+	if cfg.RecoveryFetches < 1 {
+		cfg.RecoveryFetches = 1
+	}
+	anomalies.mu.Lock()
+	defer anomalies.mu.Unlock()
+	anomalies.config = cfg
+	anomalies.breakers = make(map[string]*BreakerState)
+}
+
+// Breakers returns the open circuit breakers sorted by symbol
+func Breakers() []BreakerState {
+	anomalies.mu.Lock()
+	defer anomalies.mu.Unlock()
+	states := make([]BreakerState, 0, len(anomalies.breakers))
+	for _, b := range anomalies.breakers {
+		states = append(states, *b)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Symbol < states[j].Symbol })
+	return states
+}
+
+// detectAnomaly returns a description of the first failed check, or "" if the bars look sane
+func detectAnomaly(cfg AnomalyConfig, klines []Kline, interval time.Duration, now time.Time) string {
+	n := len(klines)
+	if n == 0 {
+		return "no bars"
+	}
+	last := klines[n-1]
+
+	if cfg.MaxBarMovePct > 0 && n >= 2 {
+		prev := klines[n-2].Close
+		if prev > 0 {
+			move := (last.Close - prev) / prev * 100
+			if math.Abs(move) > cfg.MaxBarMovePct {
+				return fmt.Sprintf("price moved %+.2f%% from previous bar (limit %.2f%%)", move, cfg.MaxBarMovePct)
+			}
+		}
+	}
+
+	if cfg.MaxZeroVolumeBars > 0 {
+		streak := 0
+		for i := n - 1; i >= 0 && klines[i].Volume == 0; i-- {
+			streak++
+		}
+		if streak > cfg.MaxZeroVolumeBars {
+			return fmt.Sprintf("%d consecutive zero-volume bars (limit %d)", streak, cfg.MaxZeroVolumeBars)
+		}
+	}
+
+	if cfg.MaxStaleBars > 0 && interval > 0 {
+		age := now.Sub(time.UnixMilli(last.OpenTime))
+		if age > time.Duration(cfg.MaxStaleBars)*interval {
+			return fmt.Sprintf("latest bar is %s old (limit %d bars)", age.Truncate(time.Second), cfg.MaxStaleBars)
+		}
+	}
+	return ""
+}
+
+// checkData runs the checks on a fetched series and updates the breaker for the symbol.
+// It returns an error wrapping ErrUnreliableData while the breaker is open.
+func checkData(symbol string, klines []Kline, interval time.Duration, now time.Time) error {
+	anomalies.mu.Lock()
+	defer anomalies.mu.Unlock()
+	cfg := anomalies.config
+	if !cfg.Enabled {
+		return nil
+	}
+
+	breaker := anomalies.breakers[symbol]
+	if reason := detectAnomaly(cfg, klines, interval, now); reason != "" {
+		if breaker == nil {
+			breaker = &BreakerState{Symbol: symbol, TrippedAt: now}
+			anomalies.breakers[symbol] = breaker
+			log.Printf("🚨 [market data] %s: %s, pausing the symbol until consistent data returns", symbol, reason)
+		}
+		breaker.Reason = reason
+		breaker.CleanFetches = 0
+		return fmt.Errorf("%s: %w: %s", symbol, ErrUnreliableData, reason)
+	}
+
+	if breaker == nil {
+		return nil
+	}
+	breaker.CleanFetches++
+	if breaker.CleanFetches < cfg.RecoveryFetches {
+		return fmt.Errorf("%s: %w: recovering (%d/%d clean fetches, last anomaly: %s)",
+			symbol, ErrUnreliableData, breaker.CleanFetches, cfg.RecoveryFetches, breaker.Reason)
+	}
+	delete(anomalies.breakers, symbol)
+	log.Printf("✓ [market data] %s: data consistent again after %s, breaker reset", symbol, now.Sub(breaker.TrippedAt).Truncate(time.Second))
+	return nil
+}
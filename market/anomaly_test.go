@@ -0,0 +1,170 @@
+package market
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// anomalyBars n根3分钟K线，最后一根开盘于 now，收盘价和成交量由 fn 给出
+func anomalyBars(now time.Time, n int, fn func(i int) (close, volume float64)) []Kline {
+	klines := make([]Kline, n)
+	for i := range klines {
+		c, v := fn(i)
+		klines[i] = Kline{OpenTime: now.Add(-time.Duration(n-1-i) * 3 * time.Minute).UnixMilli(), Close: c, Volume: v}
+	}
+	return klines
+}
+
+func TestDetectAnomaly(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	cfg := AnomalyConfig{Enabled: true, MaxBarMovePct: 10, MaxZeroVolumeBars: 2, MaxStaleBars: 3}
+	flat := func(int) (float64, float64) { return 100, 5 }
+
+	tests := []struct {
+		name   string
+		cfg    AnomalyConfig
+		klines []Kline
+		now    time.Time
+		want   string // 期望的描述片段，空表示正常
+	}{
+		{name: "正常", cfg: cfg, klines: anomalyBars(now, 10, flat), now: now},
+		{name: "没有K线", cfg: cfg, klines: nil, now: now, want: "no bars"},
+		{name: "单根K线不检查涨跌幅", cfg: cfg, klines: anomalyBars(now, 1, flat), now: now},
+		{name: "涨幅刚好等于上限", cfg: cfg, now: now, klines: anomalyBars(now, 5, func(i int) (float64, float64) {
+			if i == 4 {
+				return 110, 5
+			}
+			return 100, 5
+		})},
+		{name: "涨幅超过上限", cfg: cfg, now: now, want: "price moved +10.50% from previous bar (limit 10.00%)", klines: anomalyBars(now, 5, func(i int) (float64, float64) {
+			if i == 4 {
+				return 110.5, 5
+			}
+			return 100, 5
+		})},
+		{name: "跌幅超过上限", cfg: cfg, now: now, want: "price moved -50.00%", klines: anomalyBars(now, 5, func(i int) (float64, float64) {
+			if i == 4 {
+				return 50, 5
+			}
+			return 100, 5
+		})},
+		{name: "前一根收盘价为0时跳过涨跌幅", cfg: cfg, now: now, klines: anomalyBars(now, 2, func(i int) (float64, float64) { return float64(i * 100), 5 })},
+		{name: "只比较最后两根", cfg: cfg, now: now, klines: anomalyBars(now, 5, func(i int) (float64, float64) {
+			if i == 1 {
+				return 300, 5
+			}
+			return 100, 5
+		})},
+		{name: "零成交量刚好等于上限", cfg: cfg, now: now, klines: anomalyBars(now, 5, func(i int) (float64, float64) {
+			if i >= 3 {
+				return 100, 0
+			}
+			return 100, 5
+		})},
+		{name: "连续零成交量超过上限", cfg: cfg, now: now, want: "3 consecutive zero-volume bars (limit 2)", klines: anomalyBars(now, 5, func(i int) (float64, float64) {
+			if i >= 2 {
+				return 100, 0
+			}
+			return 100, 5
+		})},
+		{name: "零成交量不在末尾不计", cfg: cfg, now: now, klines: anomalyBars(now, 6, func(i int) (float64, float64) {
+			if i < 5 {
+				return 100, 0
+			}
+			return 100, 5
+		})},
+		{name: "最新K线刚好3个周期前", cfg: cfg, klines: anomalyBars(now, 5, flat), now: now.Add(9 * time.Minute)},
+		{name: "最新K线过旧", cfg: cfg, klines: anomalyBars(now, 5, flat), now: now.Add(9*time.Minute + time.Second), want: "latest bar is 9m1s old (limit 3 bars)"},
+		{name: "阈值为0时不检查", cfg: AnomalyConfig{Enabled: true}, klines: anomalyBars(now, 3, func(i int) (float64, float64) { return float64(100 + i*100), 0 }), now: now.Add(time.Hour)},
+		{name: "先报告涨跌幅", cfg: cfg, now: now.Add(time.Hour), want: "price moved", klines: anomalyBars(now, 3, func(i int) (float64, float64) { return float64(100 + i*100), 0 })},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectAnomaly(tt.cfg, tt.klines, 3*time.Minute, tt.now)
+			if tt.want == "" && got != "" {
+				t.Fatalf("不应判定为异常: %s", got)
+			}
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Fatalf("异常描述 = %q, 期望包含 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDataCircuitBreaker(t *testing.T) {
+	t.Cleanup(func() { SetAnomalyConfig(AnomalyConfig{}) })
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	clean := anomalyBars(now, 5, func(int) (float64, float64) { return 100, 5 })
+	spike := anomalyBars(now, 5, func(i int) (float64, float64) {
+		if i == 4 {
+			return 150, 5
+		}
+		return 100, 5
+	})
+
+	// 未启用时不检查
+	SetAnomalyConfig(AnomalyConfig{MaxBarMovePct: 10})
+	if err := checkData("ETHUSDT", spike, 3*time.Minute, now); err != nil {
+		t.Fatalf("未启用时不应检查: %v", err)
+	}
+
+	SetAnomalyConfig(AnomalyConfig{Enabled: true, MaxBarMovePct: 10, RecoveryFetches: 3})
+	if err := checkData("ETHUSDT", clean, 3*time.Minute, now); err != nil {
+		t.Fatalf("正常数据不应熔断: %v", err)
+	}
+
+	// 异常：熔断
+	err := checkData("ETHUSDT", spike, 3*time.Minute, now)
+	if !errors.Is(err, ErrUnreliableData) || !strings.Contains(err.Error(), "price moved +50.00%") {
+		t.Fatalf("异常数据应熔断: %v", err)
+	}
+	breakers := Breakers()
+	if len(breakers) != 1 || breakers[0].Symbol != "ETHUSDT" || !breakers[0].TrippedAt.Equal(now) {
+		t.Fatalf("熔断状态 = %+v", breakers)
+	}
+	if err := checkData("BTCUSDT", clean, 3*time.Minute, now); err != nil {
+		t.Fatalf("熔断按币种隔离: %v", err)
+	}
+
+	// 恢复中：正常数据计数，未达到次数前仍返回错误
+	for i := 1; i <= 2; i++ {
+		err := checkData("ETHUSDT", clean, 3*time.Minute, now.Add(time.Duration(i)*time.Minute))
+		if !errors.Is(err, ErrUnreliableData) || !strings.Contains(err.Error(), "recovering") {
+			t.Fatalf("第%d次正常数据仍应熔断: %v", i, err)
+		}
+		if got := Breakers()[0].CleanFetches; got != i {
+			t.Fatalf("正常次数 = %d, 期望 %d", got, i)
+		}
+	}
+
+	// 恢复途中再次异常：计数清零，熔断时间不变
+	later := now.Add(5 * time.Minute)
+	if err := checkData("ETHUSDT", spike, 3*time.Minute, later); !errors.Is(err, ErrUnreliableData) {
+		t.Fatalf("再次异常应保持熔断: %v", err)
+	}
+	if b := Breakers()[0]; b.CleanFetches != 0 || !b.TrippedAt.Equal(now) {
+		t.Fatalf("再次异常后计数应清零: %+v", b)
+	}
+
+	// 连续3次正常后解除
+	for i := 1; i <= 3; i++ {
+		err = checkData("ETHUSDT", clean, 3*time.Minute, later.Add(time.Duration(i)*time.Minute))
+	}
+	if err != nil || len(Breakers()) != 0 {
+		t.Fatalf("连续正常后应解除熔断: %v %+v", err, Breakers())
+	}
+
+	// 重新配置时清除熔断；恢复次数至少为1
+	SetAnomalyConfig(AnomalyConfig{Enabled: true, MaxBarMovePct: 10})
+	checkData("ETHUSDT", spike, 3*time.Minute, now)
+	if err := checkData("ETHUSDT", clean, 3*time.Minute, now); err != nil {
+		t.Fatalf("恢复次数为0时按1处理: %v", err)
+	}
+	checkData("ETHUSDT", spike, 3*time.Minute, now)
+	SetAnomalyConfig(AnomalyConfig{Enabled: true, MaxBarMovePct: 10})
+	if len(Breakers()) != 0 {
+		t.Fatal("重新配置应清除熔断")
+	}
+}
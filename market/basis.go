@@ -0,0 +1,124 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Spot-futures basis
+// Providers that expose the spot index behind a perpetual (Binance premiumIndex, Gate.io contract info)
+// implement IndexPriceProvider. Every fetch records basis = (mark - index) / index in percent; the last
+// basisHistoryLen samples per symbol (at most one per basisSampleSpacing, later fetches within the
+// spacing replace the newest sample) are shown in the prompt, since an extreme or fast-moving basis
+// often precedes squeezes. History lives in memory and restarts empty.
+
+const (
+	basisHistoryLen    = 10
+	basisSampleSpacing = time.Minute
+)
+
+// IndexPriceProvider is implemented by providers that expose the mark and spot index price of a perpetual
+type IndexPriceProvider interface {
+	// GetIndexPrice returns the perpetual's mark price and the spot index price
+	GetIndexPrice(symbol string) (markPrice, indexPrice float64, err error)
+}
+
+// BasisData perp mark price vs spot index
+type BasisData struct {
+	MarkPrice  float64
+	IndexPrice float64
+	BasisPct   float64   // (mark - index) / index * 100
+	History    []float64 // recent basis samples in percent, oldest → latest (includes the current one)
+}
+
+type basisSample struct {
+	at  time.Time
+	pct float64
+}
+
+var basis = struct {
+	mu      sync.Mutex
+	enabled bool
+	history map[string][]basisSample
+}{
+	history: make(map[string][]basisSample),
+}
+
+// SetBasisEnabled turns basis fetching on or off (one extra request per symbol and fetch)
+func SetBasisEnabled(enabled bool) {
+	basis.mu.Lock()
+	defer basis.mu.Unlock()
+	basis.enabled = enabled
+}
+
+func basisEnabled() bool {
+	basis.mu.Lock()
+	defer basis.mu.Unlock()
+	return basis.enabled
+}
+
+// fetchBasis returns the current basis with its history, or nil when disabled or unsupported by the provider
+func fetchBasis(ctx context.Context, provider MarketDataProvider, symbol string) (*BasisData, error) {
+	if !basisEnabled() {
+		return nil, nil
+	}
+	ip, ok := provider.(IndexPriceProvider)
+	if !ok {
+		return nil, nil
+	}
+	mark, index, err := tracedIndexPrice(ctx, provider, ip, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if mark <= 0 || index <= 0 {
+		return nil, fmt.Errorf("%s: no index price for %s", provider.GetName(), symbol)
+	}
+	pct := (mark - index) / index * 100
+	return &BasisData{
+		MarkPrice:  mark,
+		IndexPrice: index,
+		BasisPct:   pct,
+		History:    recordBasis(provider.GetName()+":"+symbol, pct, time.Now()),
+	}, nil
+}
+
+// recordBasis adds a sample to the symbol's history and returns a copy of it
+func recordBasis(key string, pct float64, now time.Time) []float64 {
+	basis.mu.Lock()
+	defer basis.mu.Unlock()
+
+	samples := basis.history[key]
+	if n := len(samples); n > 0 && now.Sub(samples[n-1].at) < basisSampleSpacing {
+		samples[n-1].pct = pct
+	} else {
+		samples = append(samples, basisSample{at: now, pct: pct})
+		if len(samples) > basisHistoryLen {
+			samples = samples[len(samples)-basisHistoryLen:]
+		}
+	}
+	basis.history[key] = samples
+
+	values := make([]float64, len(samples))
+	for i, s := range samples {
+		values[i] = s.pct
+	}
+	return values
+}
+
+// formatBasis the prompt lines for the basis
+func formatBasis(b *BasisData) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Basis (perp mark vs spot index): mark = %.4f, index = %.4f, basis = %+.4f%%\n\n",
+		b.MarkPrice, b.IndexPrice, b.BasisPct))
+	if len(b.History) > 1 {
+		values := make([]string, len(b.History))
+		for i, v := range b.History {
+			values[i] = fmt.Sprintf("%+.4f%%", v)
+		}
+		sb.WriteString(fmt.Sprintf("Basis history (≥1 min apart, oldest → latest): [%s]\n\n", strings.Join(values, ", ")))
+	}
+	return sb.String()
+}
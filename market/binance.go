@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"nofx/errs"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // BinanceProvider implements MarketDataProvider for Binance exchange
@@ -21,6 +24,13 @@ func NewBinanceProvider() *BinanceProvider {
 	}
 }
 
+// NewBinanceProviderWithBaseURL creates a Binance provider against a custom endpoint (e.g. testnet or a mock server)
+func NewBinanceProviderWithBaseURL(baseURL string) *BinanceProvider {
+	return &BinanceProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
 // GetName returns the provider name
 func (p *BinanceProvider) GetName() string {
 	return "binance"
@@ -40,16 +50,28 @@ func (p *BinanceProvider) GetKlines(symbol, interval string, limit int) ([]Kline
 	symbol = p.NormalizeSymbol(symbol)
 	url := fmt.Sprintf("%s/fapi/v1/klines?symbol=%s&interval=%s&limit=%d",
 		p.baseURL, symbol, interval, limit)
+	return p.fetchKlines(url)
+}
 
-	resp, err := http.Get(url)
+// GetKlinesRange fetches candlesticks whose open time falls within [start, end]
+func (p *BinanceProvider) GetKlinesRange(symbol, interval string, start, end time.Time) ([]Kline, error) {
+	symbol = p.NormalizeSymbol(symbol)
+	url := fmt.Sprintf("%s/fapi/v1/klines?symbol=%s&interval=%s&startTime=%d&endTime=%d&limit=1500",
+		p.baseURL, symbol, interval, start.UnixMilli(), end.UnixMilli())
+	return p.fetchKlines(url)
+}
+
+// fetchKlines requests and parses a klines endpoint
+func (p *BinanceProvider) fetchKlines(url string) ([]Kline, error) {
+	resp, err := rateLimitedGet("binance", url)
 	if err != nil {
-		return nil, fmt.Errorf("binance klines request failed: %w", err)
+		return nil, fmt.Errorf("binance klines request failed: %w", errs.Network("binance", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("binance klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("binance klines API error: %w", errs.FromResponse("binance", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -91,15 +113,15 @@ func (p *BinanceProvider) GetOpenInterest(symbol string) (*OIData, error) {
 	symbol = p.NormalizeSymbol(symbol)
 	url := fmt.Sprintf("%s/fapi/v1/openInterest?symbol=%s", p.baseURL, symbol)
 
-	resp, err := http.Get(url)
+	resp, err := rateLimitedGet("binance", url)
 	if err != nil {
-		return nil, fmt.Errorf("binance open interest request failed: %w", err)
+		return nil, fmt.Errorf("binance open interest request failed: %w", errs.Network("binance", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("binance open interest API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("binance open interest API error: %w", errs.FromResponse("binance", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -127,23 +149,29 @@ func (p *BinanceProvider) GetOpenInterest(symbol string) (*OIData, error) {
 
 // GetFundingRate fetches funding rate from Binance
 func (p *BinanceProvider) GetFundingRate(symbol string) (float64, error) {
+	rate, _, err := p.GetFundingSchedule(symbol)
+	return rate, err
+}
+
+// GetFundingSchedule fetches the funding rate and the next funding time from Binance premiumIndex
+func (p *BinanceProvider) GetFundingSchedule(symbol string) (float64, time.Time, error) {
 	symbol = p.NormalizeSymbol(symbol)
 	url := fmt.Sprintf("%s/fapi/v1/premiumIndex?symbol=%s", p.baseURL, symbol)
 
-	resp, err := http.Get(url)
+	resp, err := rateLimitedGet("binance", url)
 	if err != nil {
-		return 0, fmt.Errorf("binance funding rate request failed: %w", err)
+		return 0, time.Time{}, fmt.Errorf("binance funding rate request failed: %w", errs.Network("binance", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return 0, fmt.Errorf("binance funding rate API error (status %d): %s", resp.StatusCode, string(body))
+		return 0, time.Time{}, fmt.Errorf("binance funding rate API error: %w", errs.FromResponse("binance", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return 0, fmt.Errorf("binance funding rate read failed: %w", err)
+		return 0, time.Time{}, fmt.Errorf("binance funding rate read failed: %w", err)
 	}
 
 	var result struct {
@@ -157,10 +185,155 @@ func (p *BinanceProvider) GetFundingRate(symbol string) (float64, error) {
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, fmt.Errorf("binance funding rate parse failed: %w", err)
+		return 0, time.Time{}, fmt.Errorf("binance funding rate parse failed: %w", err)
 	}
 
 	rate, _ := strconv.ParseFloat(result.LastFundingRate, 64)
-	return rate, nil
+	var next time.Time
+	if result.NextFundingTime > 0 {
+		next = time.UnixMilli(result.NextFundingTime)
+	}
+	return rate, next, nil
+}
+
+// GetIndexPrice fetches mark price and spot index price from Binance premiumIndex
+func (p *BinanceProvider) GetIndexPrice(symbol string) (float64, float64, error) {
+	symbol = p.NormalizeSymbol(symbol)
+	url := fmt.Sprintf("%s/fapi/v1/premiumIndex?symbol=%s", p.baseURL, symbol)
+
+	resp, err := rateLimitedGet("binance", url)
+	if err != nil {
+		return 0, 0, fmt.Errorf("binance index price request failed: %w", errs.Network("binance", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, 0, fmt.Errorf("binance index price read failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("binance index price API error: %w", errs.FromResponse("binance", resp, body))
+	}
+
+	var result struct {
+		MarkPrice  string `json:"markPrice"`
+		IndexPrice string `json:"indexPrice"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, 0, fmt.Errorf("binance index price parse failed: %w", err)
+	}
+
+	mark, _ := strconv.ParseFloat(result.MarkPrice, 64)
+	index, _ := strconv.ParseFloat(result.IndexPrice, 64)
+	return mark, index, nil
+}
+
+// GetFlowMetrics fetches taker buy/sell volume (takerlongshortRatio) and the top traders' long/short
+// position ratio (topLongShortPositionRatio) from Binance futures trading statistics
+func (p *BinanceProvider) GetFlowMetrics(symbol, period string, limit int) (*FlowData, error) {
+	symbol = p.NormalizeSymbol(symbol)
+	query := fmt.Sprintf("symbol=%s&period=%s&limit=%d", symbol, period, limit)
+
+	var taker []struct {
+		BuySellRatio string `json:"buySellRatio"`
+		BuyVol       string `json:"buyVol"`
+		SellVol      string `json:"sellVol"`
+		Timestamp    int64  `json:"timestamp"`
+	}
+	if err := p.fetchStats("takerlongshortRatio", query, &taker); err != nil {
+		return nil, err
+	}
+	var top []struct {
+		LongShortRatio string `json:"longShortRatio"`
+		LongAccount    string `json:"longAccount"`
+		Timestamp      int64  `json:"timestamp"`
+	}
+	if err := p.fetchStats("topLongShortPositionRatio", query, &top); err != nil {
+		return nil, err
+	}
+	sort.Slice(taker, func(i, j int) bool { return taker[i].Timestamp < taker[j].Timestamp })
+	sort.Slice(top, func(i, j int) bool { return top[i].Timestamp < top[j].Timestamp })
+
+	data := &FlowData{Period: period}
+	for _, t := range taker {
+		ratio, _ := strconv.ParseFloat(t.BuySellRatio, 64)
+		buy, _ := strconv.ParseFloat(t.BuyVol, 64)
+		sell, _ := strconv.ParseFloat(t.SellVol, 64)
+		data.TakerBuySellRatio = append(data.TakerBuySellRatio, ratio)
+		data.TakerBuyVolume = append(data.TakerBuyVolume, buy)
+		data.TakerSellVolume = append(data.TakerSellVolume, sell)
+	}
+	for _, t := range top {
+		ratio, _ := strconv.ParseFloat(t.LongShortRatio, 64)
+		data.TopLongShortRatio = append(data.TopLongShortRatio, ratio)
+	}
+	if n := len(top); n > 0 {
+		long, _ := strconv.ParseFloat(top[n-1].LongAccount, 64)
+		data.TopLongPct = long * 100
+	}
+	if len(data.TakerBuySellRatio) == 0 && len(data.TopLongShortRatio) == 0 {
+		return nil, fmt.Errorf("binance has no trading statistics for %s", symbol)
+	}
+	return data, nil
+}
+
+// fetchStats fetches one of the /futures/data trading statistics endpoints
+func (p *BinanceProvider) fetchStats(endpoint, query string, v interface{}) error {
+	url := fmt.Sprintf("%s/futures/data/%s?%s", p.baseURL, endpoint, query)
+
+	resp, err := rateLimitedGet("binance", url)
+	if err != nil {
+		return fmt.Errorf("binance %s request failed: %w", endpoint, errs.Network("binance", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("binance %s read failed: %w", endpoint, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("binance %s API error: %w", endpoint, errs.FromResponse("binance", resp, body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("binance %s parse failed: %w", endpoint, err)
+	}
+	return nil
 }
 
+// GetBookDepth sums the order book notional within ±bandPct percent of the mid price (/fapi/v1/depth, top 500 levels)
+func (p *BinanceProvider) GetBookDepth(symbol string, bandPct float64) (*BookDepth, error) {
+	symbol = p.NormalizeSymbol(symbol)
+	url := fmt.Sprintf("%s/fapi/v1/depth?symbol=%s&limit=500", p.baseURL, symbol)
+
+	resp, err := rateLimitedGet("binance", url)
+	if err != nil {
+		return nil, fmt.Errorf("binance order book request failed: %w", errs.Network("binance", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("binance order book read failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("binance order book API error: %w", errs.FromResponse("binance", resp, body))
+	}
+
+	var result struct {
+		Bids [][2]string `json:"bids"` // [price, quantity]
+		Asks [][2]string `json:"asks"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("binance order book parse failed: %w", err)
+	}
+	levels := func(raw [][2]string) [][2]float64 {
+		out := make([][2]float64, 0, len(raw))
+		for _, l := range raw {
+			price, _ := strconv.ParseFloat(l[0], 64)
+			qty, _ := strconv.ParseFloat(l[1], 64)
+			out = append(out, [2]float64{price, qty})
+		}
+		return out
+	}
+	return bookDepthWithin(levels(result.Bids), levels(result.Asks), bandPct)
+}
@@ -0,0 +1,78 @@
+package market
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"sort"
+)
+
+// Provider capability matrix
+// capabilities.json is generated by the provider conformance suite (go test ./market -run TestProviderConformance -update):
+// every provider with recorded fixtures in testdata/conformance is run against them, and the intervals that return
+// bars of the requested size, the unit of the reported volume, and whether open interest / funding rate match the
+// golden values are written to the file. Providers without fixtures (and descriptor providers) are listed as unverified.
+
+// ConformanceIntervals the kline intervals checked by the conformance suite
+var ConformanceIntervals = []string{"1m", "3m", "5m", "15m", "1h", "4h", "1d"}
+
+// ProviderCapabilities what a market data provider supports
+type ProviderCapabilities struct {
+	Provider     string   `json:"provider"`
+	Verified     bool     `json:"verified"`              // covered by the conformance fixtures
+	Intervals    []string `json:"intervals"`             // conformance intervals that return bars of the requested size
+	Resampled    []string `json:"resampled,omitempty"`   // intervals not served natively, built by FetchKlines from a finer interval
+	VolumeUnit   string   `json:"volume_unit,omitempty"` // unit of Kline.Volume: "base", "quote" or "contracts"
+	OpenInterest bool     `json:"open_interest"`         // open interest in the base asset
+	FundingRate  bool     `json:"funding_rate"`
+	KlineRange   bool     `json:"kline_range"`  // implements KlineRangeProvider (historical windows)
+	IndexPrice   bool     `json:"index_price"`  // implements IndexPriceProvider (spot-futures basis)
+	Flow         bool     `json:"flow"`         // implements FlowProvider (taker volume, long/short ratio)
+	FundingTime  bool     `json:"funding_time"` // next funding time reported by the exchange (otherwise estimated from the 8h clock)
+	Depth        bool     `json:"depth"`        // implements DepthProvider (order book depth for the position limit)
+	Issues       []string `json:"issues,omitempty"`
+}
+
+//go:embed capabilities.json
+var capabilitiesJSON []byte
+
+// verifiedCapabilities parses the generated matrix
+func verifiedCapabilities() map[string]ProviderCapabilities {
+	var list []ProviderCapabilities
+	if err := json.Unmarshal(capabilitiesJSON, &list); err != nil {
+		log.Printf("⚠️  capabilities.json parse failed: %v", err)
+		return nil
+	}
+	result := make(map[string]ProviderCapabilities, len(list))
+	for _, c := range list {
+		result[c.Provider] = c
+	}
+	return result
+}
+
+// CapabilityMatrix capabilities of every registered provider, sorted by name
+func CapabilityMatrix() []ProviderCapabilities {
+	verified := verifiedCapabilities()
+	names := ListProviders()
+	sort.Strings(names)
+
+	matrix := make([]ProviderCapabilities, 0, len(names))
+	for _, name := range names {
+		provider, err := GetProvider(name)
+		if err != nil {
+			continue
+		}
+		c, ok := verified[name]
+		if !ok {
+			c = ProviderCapabilities{Provider: name, Intervals: []string{}}
+		}
+		_, c.KlineRange = provider.(KlineRangeProvider)
+		_, c.IndexPrice = provider.(IndexPriceProvider)
+		_, c.Flow = provider.(FlowProvider)
+		_, c.FundingTime = provider.(FundingScheduleProvider)
+		_, c.Depth = provider.(DepthProvider)
+		c.Resampled = ResampledIntervals(provider)
+		matrix = append(matrix, c)
+	}
+	return matrix
+}
@@ -0,0 +1,406 @@
+package market
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Provider conformance suite
+// testdata/conformance/golden.json is one canonical market (5 bars per interval in base-asset volume, open interest
+// in the base asset, funding rate as a fraction). Each <provider>.json holds the exchange's responses for that market,
+// keyed by request URL, in the exchange's own format (ordering, timestamp units, volume units, interval names).
+// Every registered provider with a fixture file is run against its responses through a replaying transport and
+// its output is compared with the golden market; the resulting capability matrix must match capabilities.json.
+// Run with -update after adding fixtures or changing a provider to regenerate capabilities.json.
+
+var updateCapabilities = flag.Bool("update", false, "rewrite capabilities.json from the conformance run")
+
+const conformanceDir = "testdata/conformance"
+
+type goldenBar struct {
+	OpenTime    int64   `json:"open_time"`
+	Open        float64 `json:"open"`
+	High        float64 `json:"high"`
+	Low         float64 `json:"low"`
+	Close       float64 `json:"close"`
+	Volume      float64 `json:"volume"`       // base asset
+	QuoteVolume float64 `json:"quote_volume"` // quote asset
+}
+
+type goldenFlow struct {
+	Period            string    `json:"period"`
+	TakerBuySellRatio []float64 `json:"taker_buy_sell_ratio"`
+	TakerBuyVolume    []float64 `json:"taker_buy_volume"` // base asset
+	TakerSellVolume   []float64 `json:"taker_sell_volume"`
+	TopLongShortRatio []float64 `json:"top_long_short_ratio"`
+	TopLongPct        float64   `json:"top_long_pct"`
+}
+
+type goldenMarket struct {
+	Symbol       string                 `json:"symbol"`
+	OpenInterest float64                `json:"open_interest"`
+	FundingRate  float64                `json:"funding_rate"`
+	NextFunding  int64                  `json:"next_funding_time"` // milliseconds
+	Flow         goldenFlow             `json:"flow"`
+	Klines       map[string][]goldenBar `json:"klines"`
+}
+
+type fixtureFile struct {
+	Provider  string `json:"provider"`
+	Responses []struct {
+		URL    string          `json:"url"`
+		Status int             `json:"status,omitempty"` // default 200
+		Body   json.RawMessage `json:"body"`
+	} `json:"responses"`
+}
+
+// replayTransport serves recorded responses by URL; unknown URLs get a 404 naming the URL
+type replayTransport struct {
+	responses map[string]int
+	bodies    map[string][]byte
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	body, ok := t.bodies[url]
+	status := t.responses[url]
+	if !ok {
+		status, body = http.StatusNotFound, []byte(fmt.Sprintf(`{"error":"no fixture for %s"}`, url))
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func loadJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+}
+
+// useFixtures routes all HTTP requests of the providers to the recorded responses
+func useFixtures(t *testing.T, fixture fixtureFile) {
+	t.Helper()
+	transport := &replayTransport{responses: make(map[string]int), bodies: make(map[string][]byte)}
+	for _, r := range fixture.Responses {
+		status := r.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		transport.responses[r.URL] = status
+		transport.bodies[r.URL] = r.Body
+	}
+	original := http.DefaultTransport
+	http.DefaultTransport = transport
+	t.Cleanup(func() { http.DefaultTransport = original })
+}
+
+func intervalMillis(interval string) int64 {
+	minutes := map[string]int64{"1m": 1, "3m": 3, "5m": 5, "15m": 15, "30m": 30, "1h": 60, "4h": 240, "1d": 1440}
+	return minutes[interval] * 60 * 1000
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))+1e-12
+}
+
+// checkKlines verifies one interval against the golden bars, returning the volume unit or the problem found
+func checkKlines(klines []Kline, interval string, golden []goldenBar) (volumeUnit string, problem string) {
+	if len(klines) == 0 {
+		return "", "no klines"
+	}
+	for _, k := range klines {
+		if k.OpenTime < 1e12 || k.OpenTime >= 1e13 {
+			return "", fmt.Sprintf("open time %d is not in milliseconds", k.OpenTime)
+		}
+	}
+	step := intervalMillis(interval)
+	for i := 1; i < len(klines); i++ {
+		gap := klines[i].OpenTime - klines[i-1].OpenTime
+		if gap <= 0 {
+			return "", "klines are not in ascending time order"
+		}
+		if gap != step {
+			return "", fmt.Sprintf("returns %s bars", formatHorizon(time.Duration(gap)*time.Millisecond))
+		}
+	}
+	for _, k := range klines {
+		if span := k.CloseTime - k.OpenTime; span != step && span != step-1 {
+			return "", fmt.Sprintf("close time is %dms after open time, want %dms", span, step)
+		}
+	}
+
+	byTime := make(map[int64]goldenBar, len(golden))
+	for _, g := range golden {
+		byTime[g.OpenTime] = g
+	}
+	units := make(map[string]bool)
+	for _, k := range klines {
+		g, ok := byTime[k.OpenTime]
+		if !ok {
+			return "", fmt.Sprintf("unexpected bar at %d", k.OpenTime)
+		}
+		if !closeTo(k.Open, g.Open) || !closeTo(k.High, g.High) || !closeTo(k.Low, g.Low) || !closeTo(k.Close, g.Close) {
+			return "", fmt.Sprintf("OHLC at %d = %v/%v/%v/%v, want %v/%v/%v/%v",
+				k.OpenTime, k.Open, k.High, k.Low, k.Close, g.Open, g.High, g.Low, g.Close)
+		}
+		switch {
+		case closeTo(k.Volume, g.Volume):
+			units["base"] = true
+		case closeTo(k.Volume, g.QuoteVolume):
+			units["quote"] = true
+		default:
+			units["contracts"] = true
+		}
+	}
+	if len(units) > 1 {
+		return "", "volume unit differs between bars"
+	}
+	for unit := range units {
+		volumeUnit = unit
+	}
+	return volumeUnit, ""
+}
+
+func seriesCloseTo(got, want []float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if !closeTo(got[i], want[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkFlow verifies the order flow metrics against the golden series, returning the problem found
+func checkFlow(provider FlowProvider, symbol string, golden goldenFlow) string {
+	data, err := provider.GetFlowMetrics(symbol, golden.Period, len(golden.TakerBuySellRatio))
+	if err != nil {
+		return err.Error()
+	}
+	switch {
+	case !seriesCloseTo(data.TakerBuySellRatio, golden.TakerBuySellRatio):
+		return fmt.Sprintf("taker buy/sell ratio %v, want %v oldest first", data.TakerBuySellRatio, golden.TakerBuySellRatio)
+	case !seriesCloseTo(data.TakerBuyVolume, golden.TakerBuyVolume) || !seriesCloseTo(data.TakerSellVolume, golden.TakerSellVolume):
+		return fmt.Sprintf("taker volume %v/%v, want %v/%v in the base asset", data.TakerBuyVolume, data.TakerSellVolume, golden.TakerBuyVolume, golden.TakerSellVolume)
+	case !seriesCloseTo(data.TopLongShortRatio, golden.TopLongShortRatio):
+		return fmt.Sprintf("top trader long/short ratio %v, want %v oldest first", data.TopLongShortRatio, golden.TopLongShortRatio)
+	case !closeTo(data.TopLongPct, golden.TopLongPct):
+		return fmt.Sprintf("top trader long share %v%%, want %v%%", data.TopLongPct, golden.TopLongPct)
+	}
+	return ""
+}
+
+// runConformance runs a provider against its fixtures and builds its capability entry
+func runConformance(name string, provider MarketDataProvider, golden goldenMarket) ProviderCapabilities {
+	c := ProviderCapabilities{Provider: name, Verified: true, Intervals: []string{}}
+	_, c.KlineRange = provider.(KlineRangeProvider)
+	_, c.IndexPrice = provider.(IndexPriceProvider)
+	fp, isFlow := provider.(FlowProvider)
+	c.Flow = isFlow
+	sp, isSchedule := provider.(FundingScheduleProvider)
+	c.FundingTime = isSchedule
+	_, c.Depth = provider.(DepthProvider)
+
+	c.Resampled = ResampledIntervals(provider)
+	np, partial := provider.(NativeIntervalProvider)
+	for _, interval := range ConformanceIntervals {
+		if partial && !isNativeInterval(np, interval) {
+			continue // built by FetchKlines (see TestResampledKlines)
+		}
+		klines, err := provider.GetKlines(golden.Symbol, interval, 5)
+		if err != nil {
+			c.Issues = append(c.Issues, fmt.Sprintf("%s: %v", interval, err))
+			continue
+		}
+		unit, problem := checkKlines(klines, interval, golden.Klines[interval])
+		if problem != "" {
+			c.Issues = append(c.Issues, fmt.Sprintf("%s: %s", interval, problem))
+			continue
+		}
+		if c.VolumeUnit != "" && c.VolumeUnit != unit {
+			c.Issues = append(c.Issues, fmt.Sprintf("%s: volume in %s, other intervals in %s", interval, unit, c.VolumeUnit))
+		} else if c.VolumeUnit == "" {
+			c.VolumeUnit = unit
+		}
+		c.Intervals = append(c.Intervals, interval)
+	}
+	if c.VolumeUnit != "" && c.VolumeUnit != "base" {
+		c.Issues = append(c.Issues, fmt.Sprintf("kline volume is in %s, not the base asset", c.VolumeUnit))
+	}
+
+	if oi, err := provider.GetOpenInterest(golden.Symbol); err == nil {
+		if closeTo(oi.Latest, golden.OpenInterest) {
+			c.OpenInterest = true
+		} else {
+			c.Issues = append(c.Issues, fmt.Sprintf("open interest %v, want %v in the base asset", oi.Latest, golden.OpenInterest))
+		}
+	}
+	if rate, err := provider.GetFundingRate(golden.Symbol); err == nil {
+		if closeTo(rate, golden.FundingRate) {
+			c.FundingRate = true
+		} else {
+			c.Issues = append(c.Issues, fmt.Sprintf("funding rate %v, want %v", rate, golden.FundingRate))
+		}
+	}
+	if isSchedule {
+		if _, next, err := sp.GetFundingSchedule(golden.Symbol); err != nil {
+			c.Issues = append(c.Issues, fmt.Sprintf("funding schedule: %v", err))
+		} else if next.UnixMilli() != golden.NextFunding {
+			c.Issues = append(c.Issues, fmt.Sprintf("next funding time %d, want %d", next.UnixMilli(), golden.NextFunding))
+		}
+	}
+	if isFlow {
+		if problem := checkFlow(fp, golden.Symbol, golden.Flow); problem != "" {
+			c.Issues = append(c.Issues, "flow: "+problem)
+		}
+	}
+	return c
+}
+
+func TestProviderConformance(t *testing.T) {
+	InitializeProviders()
+	var golden goldenMarket
+	loadJSON(t, filepath.Join(conformanceDir, "golden.json"), &golden)
+
+	names := ListProviders()
+	sort.Strings(names)
+	var matrix []ProviderCapabilities
+	for _, name := range names {
+		path := filepath.Join(conformanceDir, name+".json")
+		if _, err := os.Stat(path); err != nil {
+			continue // no recorded fixtures: listed as unverified
+		}
+		provider, err := GetProvider(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(name, func(t *testing.T) {
+			var fixture fixtureFile
+			loadJSON(t, path, &fixture)
+			useFixtures(t, fixture)
+			matrix = append(matrix, runConformance(name, provider, golden))
+		})
+	}
+
+	got, err := json.MarshalIndent(matrix, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+	if *updateCapabilities {
+		if err := os.WriteFile("capabilities.json", got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if !bytes.Equal(got, capabilitiesJSON) {
+		t.Errorf("capability matrix differs from capabilities.json (run go test ./market -run TestProviderConformance -update):\n%s", got)
+	}
+}
+
+func TestCapabilityMatrixListsAllProviders(t *testing.T) {
+	InitializeProviders()
+	matrix := CapabilityMatrix()
+	if len(matrix) != len(ListProviders()) {
+		t.Fatalf("matrix has %d providers, registry has %d", len(matrix), len(ListProviders()))
+	}
+	verified := 0
+	for _, c := range matrix {
+		if c.Verified {
+			verified++
+		}
+		if c.Provider == "binance" && (!c.KlineRange || !c.IndexPrice || !c.OpenInterest || c.VolumeUnit != "base") {
+			t.Errorf("binance capabilities: %+v", c)
+		}
+	}
+	if verified == 0 {
+		t.Error("no provider is verified by fixtures")
+	}
+	if strings.TrimSpace(string(capabilitiesJSON)) == "" {
+		t.Error("capabilities.json is empty")
+	}
+}
+
+// minuteProvider serves only 1m klines: a flat series with one bar per minute from `start`
+type minuteProvider struct {
+	start int64
+	bars  int
+}
+
+func (p *minuteProvider) GetName() string                      { return "minutes" }
+func (p *minuteProvider) NormalizeSymbol(symbol string) string { return symbol }
+func (p *minuteProvider) NativeIntervals() []string            { return []string{"1m"} }
+func (p *minuteProvider) GetOpenInterest(string) (*OIData, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (p *minuteProvider) GetFundingRate(string) (float64, error) {
+	return 0, fmt.Errorf("not supported")
+}
+
+func (p *minuteProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	if interval != "1m" {
+		return nil, unsupportedInterval("minutes", interval)
+	}
+	var klines []Kline
+	for i := max(0, p.bars-limit); i < p.bars; i++ {
+		open := p.start + int64(i)*60000
+		price := float64(100 + i)
+		klines = append(klines, Kline{OpenTime: open, Open: price, High: price + 0.5, Low: price - 0.5, Close: price + 0.25, Volume: 1, CloseTime: open + 59999})
+	}
+	return klines, nil
+}
+
+func TestResampledKlines(t *testing.T) {
+	// 3m boundaries are at multiples of 180000ms; the series starts one minute after one
+	start := int64(1761868080000 + 60000)
+	provider := &minuteProvider{start: start, bars: 14}
+
+	if _, err := provider.GetKlines("BTCUSDT", "3m", 3); err == nil {
+		t.Fatal("provider should refuse non-native intervals")
+	}
+	klines, err := FetchKlines(provider, "BTCUSDT", "3m", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bars 0-1 form a partial leading bucket, bars 2-13 four full 3m bars
+	if len(klines) != 4 {
+		t.Fatalf("got %d 3m bars, want 4", len(klines))
+	}
+	if unit, problem := checkKlines(klines, "3m", nil); problem != "" && !strings.HasPrefix(problem, "unexpected bar") {
+		t.Fatalf("resampled bars: %s (%s)", problem, unit)
+	}
+	first := klines[0]
+	if first.OpenTime%180000 != 0 || first.Open != 102 || first.Close != 104.25 || first.High != 104.5 || first.Low != 101.5 || first.Volume != 3 {
+		t.Errorf("first 3m bar = %+v", first)
+	}
+	if first.CloseTime != first.OpenTime+179999 {
+		t.Errorf("close time convention not kept: %d", first.CloseTime-first.OpenTime)
+	}
+
+	if got := ResampledIntervals(provider); len(got) != len(ConformanceIntervals)-1 {
+		t.Errorf("resampled intervals = %v", got)
+	}
+}
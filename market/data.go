@@ -1,11 +1,14 @@
 package market
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math"
+	"nofx/tracing"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Data 市场数据结构
@@ -19,6 +22,14 @@ type Data struct {
 	CurrentRSI7       float64
 	OpenInterest      *OIData
 	FundingRate       float64
+	NextFundingTime   time.Time         // 下次资金费结算时间
+	FundingEstimated  bool              // 下次结算时间按UTC 0/8/16点推算（数据源未提供）
+	Flow              *FlowData         // 主动买卖量与大户多空持仓比（未启用或数据源不支持时为nil）
+	Basis             *BasisData        // 永续标记价格相对现货指数的基差（未启用或数据源不支持时为nil）
+	Volatility        *VolatilityData   // 已实现波动率（未启用或获取失败时为nil）
+	Liquidity         *LiquidityProfile // 分时段流动性画像（未启用、BTC/ETH或获取失败时为nil）
+	Range             *RangeData        // 4小时震荡指数（未启用或K线不足时为nil）
+	Depth             *DepthLimit       // 按成交量和盘口深度计算的最大仓位（未启用或数据不足时为nil）
 	IntradaySeries    *IntradayData
 	LongerTermContext *LongerTermData
 }
@@ -61,17 +72,43 @@ type Kline struct {
 	CloseTime int64
 }
 
-// Get 获取指定代币的市场数据 (使用默认provider)
+// Get 获取指定代币的市场数据 (使用该币种配置的provider，未配置时使用默认provider)
 func Get(symbol string) (*Data, error) {
-	provider, err := GetDefaultProvider()
+	return GetContext(context.Background(), symbol)
+}
+
+// GetContext 同 Get，ctx 中的追踪 span 作为本次数据获取的父 span
+func GetContext(ctx context.Context, symbol string) (*Data, error) {
+	provider, err := ProviderFor(symbol)
 	if err != nil {
 		return nil, fmt.Errorf("获取市场数据提供者失败: %v", err)
 	}
-	return GetWithProvider(symbol, provider)
+	data, err := GetWithProviderContext(ctx, symbol, provider)
+	if err != nil {
+		// 币种指定的数据源失败时回退到默认数据源
+		if defaultProvider, defErr := GetDefaultProvider(); defErr == nil && defaultProvider.GetName() != provider.GetName() {
+			log.Printf("⚠️  [市场数据] %s 从 %s 获取失败（%v），回退到默认数据源 %s", symbol, provider.GetName(), err, defaultProvider.GetName())
+			return GetWithProviderContext(ctx, symbol, defaultProvider)
+		}
+	}
+	return data, err
 }
 
 // GetWithProvider 使用指定的provider获取市场数据
 func GetWithProvider(symbol string, provider MarketDataProvider) (*Data, error) {
+	return GetWithProviderContext(context.Background(), symbol, provider)
+}
+
+// GetWithProviderContext 使用指定的provider获取市场数据，每次请求记录为追踪子 span
+func GetWithProviderContext(ctx context.Context, symbol string, provider MarketDataProvider) (data *Data, err error) {
+	ctx, span := tracing.Start(ctx, "market.get")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	span.SetAttr("symbol", symbol)
+	span.SetAttr("provider", provider.GetName())
+
 	providerName := provider.GetName()
 	log.Printf("📊 [市场数据] 使用 %s 获取 %s 的市场数据", providerName, symbol)
 	
@@ -79,7 +116,7 @@ func GetWithProvider(symbol string, provider MarketDataProvider) (*Data, error)
 	normalizedSymbol := provider.NormalizeSymbol(symbol)
 
 	// 获取3分钟K线数据 (最近10个)
-	klines3m, err := provider.GetKlines(symbol, "3m", 40) // 多获取一些用于计算
+	klines3m, err := tracedKlines(ctx, provider, symbol, "3m", 40) // 多获取一些用于计算
 	if err != nil {
 		return nil, fmt.Errorf("获取3分钟K线失败: %v", err)
 	}
@@ -89,8 +126,14 @@ func GetWithProvider(symbol string, provider MarketDataProvider) (*Data, error)
 		return nil, fmt.Errorf("3分钟K线数据为空: %s", symbol)
 	}
 
+	// Sanity checks: an anomalous series trips the symbol's circuit breaker
+	if err := checkData(symbol, klines3m, 3*time.Minute, time.Now()); err != nil {
+		span.SetAttr("anomaly", err.Error())
+		return nil, err
+	}
+
 	// 获取4小时K线数据 (最近10个)
-	klines4h, err := provider.GetKlines(symbol, "4h", 60) // 多获取用于计算指标
+	klines4h, err := tracedKlines(ctx, provider, symbol, "4h", 60) // 多获取用于计算指标
 	if err != nil {
 		return nil, fmt.Errorf("获取4小时K线失败: %v", err)
 	}
@@ -102,6 +145,16 @@ func GetWithProvider(symbol string, provider MarketDataProvider) (*Data, error)
 
 	// 计算当前指标 (基于3分钟最新数据)
 	currentPrice := klines3m[len(klines3m)-1].Close
+	if fastPriceEnabled() {
+		// 当前价对延迟敏感：使用该币种最快的健康数据源（历史K线和指标仍来自指定的数据源）
+		if fast, reason, err := selectPriceProvider(symbol); err == nil && fast.GetName() != providerName {
+			if bar, err := latestBarFrom(ctx, fast, symbol, "3m"); err == nil {
+				currentPrice = bar.Close
+				span.SetAttr("price_provider", fast.GetName())
+				span.SetAttr("price_selection_reason", reason)
+			}
+		}
+	}
 	currentEMA20 := calculateEMA(klines3m, 20)
 	currentMACD := calculateMACD(klines3m)
 	currentRSI7 := calculateRSI(klines3m, 7)
@@ -126,14 +179,44 @@ func GetWithProvider(symbol string, provider MarketDataProvider) (*Data, error)
 	}
 
 	// 获取OI数据
-	oiData, err := provider.GetOpenInterest(symbol)
-	if err != nil {
+	oiData, oiErr := tracedOpenInterest(ctx, provider, symbol)
+	if oiErr != nil {
 		// OI失败不影响整体,使用默认值
 		oiData = &OIData{Latest: 0, Average: 0}
 	}
 
-	// 获取Funding Rate
-	fundingRate, _ := provider.GetFundingRate(symbol)
+	// 获取Funding Rate和下次结算时间
+	fundingRate, nextFunding, fundingEstimated, _ := fetchFunding(ctx, provider, symbol)
+
+	// 获取主动买卖量与大户多空比（失败不影响整体）
+	flowData, flowErr := fetchFlow(ctx, provider, normalizedSymbol)
+	if flowErr != nil {
+		log.Printf("⚠️  [市场数据] %s 获取资金流数据失败: %v", symbol, flowErr)
+	}
+
+	// 获取基差（失败不影响整体）
+	basisData, basisErr := fetchBasis(ctx, provider, symbol)
+	if basisErr != nil {
+		log.Printf("⚠️  [市场数据] %s 获取基差失败: %v", symbol, basisErr)
+	}
+
+	// 获取已实现波动率（失败不影响整体）
+	volatility, volErr := fetchVolatility(ctx, provider, symbol)
+	if volErr != nil {
+		log.Printf("⚠️  [市场数据] %s 计算波动率失败: %v", symbol, volErr)
+	}
+
+	// 获取分时段流动性画像（失败不影响整体）
+	liquidityProfile, liqErr := fetchLiquidity(ctx, provider, normalizedSymbol)
+	if liqErr != nil {
+		log.Printf("⚠️  [市场数据] %s 计算流动性画像失败: %v", symbol, liqErr)
+	}
+
+	// 按成交量和盘口深度计算最大仓位（盘口获取失败时仍使用成交量上限）
+	depthLimit, depthErr := fetchDepthLimit(ctx, provider, normalizedSymbol)
+	if depthErr != nil {
+		log.Printf("⚠️  [市场数据] %s 计算深度仓位上限失败: %v", symbol, depthErr)
+	}
 
 	// 计算日内系列数据
 	intradayData := calculateIntradaySeries(klines3m)
@@ -141,6 +224,9 @@ func GetWithProvider(symbol string, provider MarketDataProvider) (*Data, error)
 	// 计算长期数据
 	longerTermData := calculateLongerTermData(klines4h)
 
+	// 震荡区间检测（使用已获取的4小时K线）
+	rangeData := calculateRange(klines4h)
+
 	log.Printf("✓ [市场数据] %s (%s) 数据获取完成: 价格=%.2f, EMA20=%.2f, MACD=%.4f, RSI7=%.2f", 
 		symbol, providerName, currentPrice, currentEMA20, currentMACD, currentRSI7)
 
@@ -154,6 +240,14 @@ func GetWithProvider(symbol string, provider MarketDataProvider) (*Data, error)
 		CurrentRSI7:       currentRSI7,
 		OpenInterest:      oiData,
 		FundingRate:       fundingRate,
+		NextFundingTime:   nextFunding,
+		FundingEstimated:  fundingEstimated,
+		Flow:              flowData,
+		Basis:             basisData,
+		Volatility:        volatility,
+		Liquidity:         liquidityProfile,
+		Range:             rangeData,
+		Depth:             depthLimit,
 		IntradaySeries:    intradayData,
 		LongerTermContext: longerTermData,
 	}, nil
@@ -165,7 +259,7 @@ func getKlines(symbol, interval string, limit int) ([]Kline, error) {
 	if err != nil {
 		return nil, fmt.Errorf("provider not initialized: %v", err)
 	}
-	return provider.GetKlines(symbol, interval, limit)
+	return FetchKlines(provider, symbol, interval, limit)
 }
 
 // calculateEMA 计算EMA
@@ -406,7 +500,31 @@ func Format(data *Data) string {
 			data.OpenInterest.Latest, data.OpenInterest.Average))
 	}
 
-	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
+	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e%s\n\n", data.FundingRate, formatFundingCountdown(data, time.Now())))
+
+	if data.Flow != nil {
+		sb.WriteString(formatFlow(data.Flow))
+	}
+
+	if data.Basis != nil {
+		sb.WriteString(formatBasis(data.Basis))
+	}
+
+	if data.Volatility != nil {
+		sb.WriteString(formatVolatility(data.Volatility, data.CurrentPrice))
+	}
+
+	if data.Liquidity != nil {
+		sb.WriteString(formatLiquidity(data.Liquidity))
+	}
+
+	if data.Range != nil {
+		sb.WriteString(formatRange(data.Range, data.CurrentPrice))
+	}
+
+	if data.Depth != nil {
+		sb.WriteString(formatDepthLimit(data.Depth))
+	}
 
 	if data.IntradaySeries != nil {
 		sb.WriteString("Intraday series (3‑minute intervals, oldest → latest):\n\n")
@@ -470,11 +588,11 @@ func Normalize(symbol string) string {
 	provider, err := GetDefaultProvider()
 	if err != nil {
 		// Fallback to simple uppercase if provider not initialized
-		symbol = strings.ToUpper(symbol)
-		if strings.HasSuffix(symbol, "USDT") {
-			return symbol
+		base, quote := SplitSymbol(symbol)
+		if quote == "" {
+			quote = DefaultQuote
 		}
-		return symbol + "USDT"
+		return base + quote
 	}
 	return provider.NormalizeSymbol(symbol)
 }
@@ -0,0 +1,240 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"nofx/cache"
+	"nofx/tracing"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Depth-aware position limit
+// Open interest filtering keeps illiquid symbols out of the candidate list, but an altcoin that passes it can
+// still trade too little per hour, or rest too little size near the price, to absorb the position the AI sizes.
+// With the limit enabled every fetch computes a per-symbol max notional:
+//
+//	min(VolumePct% × average 1h notional volume of the last VolumeHours bars,
+//	    DepthPct% × the thinner side of the order book within ±BandPct% of the mid price)
+//
+// The volume cap needs the provider's kline volume in the base or quote asset (see capabilities.json); the
+// depth cap needs a DepthProvider. Whichever is unavailable is left out; with neither there is no limit.
+// The prompt shows the limit and the trader clamps entries to it. Hourly volume changes slowly and is
+// cached for depthVolumeRefresh; the order book is read on every fetch.
+
+const (
+	depthVolumeInterval = "1h"
+	depthVolumeRefresh  = 10 * time.Minute
+	maxDepthVolumeHours = 168
+)
+
+// DepthConfig settings of the depth-aware position limit
+type DepthConfig struct {
+	Enabled     bool
+	VolumePct   float64 // max notional as percent of the average 1h notional volume (default 1, <= 0 = no volume cap)
+	VolumeHours int     // 1h bars averaged (default 24, at most 168)
+	DepthPct    float64 // max notional as percent of the thinner book side within the band (default 50, <= 0 = no depth cap)
+	BandPct     float64 // book depth counted within ±BandPct% of the mid price (default 1)
+}
+
+// DepthProvider is implemented by providers that expose the order book
+type DepthProvider interface {
+	// GetBookDepth returns the notional (quote asset) resting on each side within ±bandPct percent of the mid price
+	GetBookDepth(symbol string, bandPct float64) (*BookDepth, error)
+}
+
+// BookDepth order book notional near the mid price
+type BookDepth struct {
+	MidPrice    float64
+	BidNotional float64 // quote asset resting on the bid side within the band
+	AskNotional float64 // quote asset resting on the ask side within the band
+}
+
+// Thinner notional of the thinner side
+func (b *BookDepth) Thinner() float64 {
+	return math.Min(b.BidNotional, b.AskNotional)
+}
+
+// DepthLimit per-symbol max position notional from volume and order book depth
+type DepthLimit struct {
+	AvgHourlyVolume float64    // average 1h notional volume (0 when unknown)
+	VolumeHours     int        // bars averaged
+	VolumeCap       float64    // VolumePct% of AvgHourlyVolume (0 = no volume cap)
+	Book            *BookDepth // nil when the provider has no order book or the request failed
+	BandPct         float64
+	DepthCap        float64 // DepthPct% of the thinner book side (0 = no depth cap)
+	MaxNotional     float64 // the smaller of the caps
+}
+
+// Binding describes which cap sets the limit
+func (l *DepthLimit) Binding() string {
+	if l.DepthCap > 0 && (l.VolumeCap <= 0 || l.DepthCap < l.VolumeCap) {
+		return "order book depth"
+	}
+	return "hourly volume"
+}
+
+var depth = struct {
+	mu      sync.Mutex
+	cfg     DepthConfig
+	volumes *cache.Cache[string, float64]
+}{
+	volumes: cache.New[string, float64]("market.depth_volume", depthVolumeRefresh),
+}
+
+// SetDepthConfig sets the depth-aware position limit settings (one order book request per symbol and fetch,
+// one extra kline request per symbol every 10 minutes)
+func SetDepthConfig(cfg DepthConfig) {
+	if cfg.VolumePct == 0 {
+		cfg.VolumePct = 1
+	}
+	if cfg.VolumeHours <= 0 {
+		cfg.VolumeHours = 24
+	}
+	if cfg.VolumeHours > maxDepthVolumeHours {
+		cfg.VolumeHours = maxDepthVolumeHours
+	}
+	if cfg.DepthPct == 0 {
+		cfg.DepthPct = 50
+	}
+	if cfg.BandPct <= 0 {
+		cfg.BandPct = 1
+	}
+	depth.mu.Lock()
+	defer depth.mu.Unlock()
+	depth.cfg = cfg
+	depth.volumes.Clear()
+}
+
+func depthConfig() DepthConfig {
+	depth.mu.Lock()
+	defer depth.mu.Unlock()
+	return depth.cfg
+}
+
+// fetchDepthLimit returns the symbol's max position notional, or nil when disabled or when neither cap is available.
+// A failed order book request still returns the volume cap together with the error.
+func fetchDepthLimit(ctx context.Context, provider MarketDataProvider, symbol string) (*DepthLimit, error) {
+	cfg := depthConfig()
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	limit := &DepthLimit{VolumeHours: cfg.VolumeHours, BandPct: cfg.BandPct}
+	var errs []string
+
+	if cfg.VolumePct > 0 {
+		if unit := volumeUnit(provider.GetName()); unit == "base" || unit == "quote" {
+			avg, err := depth.volumes.GetOrLoad(symbol, func() (float64, error) {
+				klines, err := tracedKlines(ctx, provider, symbol, depthVolumeInterval, cfg.VolumeHours)
+				if err != nil {
+					return 0, err
+				}
+				return averageNotional(klines, unit)
+			})
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("hourly volume: %v", err))
+			} else {
+				limit.AvgHourlyVolume = avg
+				limit.VolumeCap = avg * cfg.VolumePct / 100
+			}
+		}
+	}
+
+	if dp, ok := provider.(DepthProvider); ok && cfg.DepthPct > 0 {
+		_, span := tracing.Start(ctx, "market.depth")
+		span.SetAttr("provider", provider.GetName())
+		span.SetAttr("symbol", symbol)
+		start := time.Now()
+		book, err := dp.GetBookDepth(symbol, cfg.BandPct)
+		recordCall(provider.GetName(), symbol, time.Since(start), err)
+		span.RecordError(err)
+		span.End()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("order book: %v", err))
+		} else {
+			limit.Book = book
+			limit.DepthCap = book.Thinner() * cfg.DepthPct / 100
+		}
+	}
+
+	switch {
+	case limit.VolumeCap > 0 && limit.DepthCap > 0:
+		limit.MaxNotional = math.Min(limit.VolumeCap, limit.DepthCap)
+	case limit.VolumeCap > 0:
+		limit.MaxNotional = limit.VolumeCap
+	case limit.DepthCap > 0:
+		limit.MaxNotional = limit.DepthCap
+	}
+
+	var err error
+	if len(errs) > 0 {
+		err = fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	if limit.MaxNotional <= 0 {
+		return nil, err
+	}
+	return limit, err
+}
+
+// volumeUnit the verified unit of the provider's kline volume ("" when unknown)
+func volumeUnit(provider string) string {
+	return verifiedCapabilities()[provider].VolumeUnit
+}
+
+// averageNotional average notional volume per bar in the quote asset
+func averageNotional(klines []Kline, unit string) (float64, error) {
+	if len(klines) == 0 {
+		return 0, fmt.Errorf("no 1h klines")
+	}
+	total := 0.0
+	for _, k := range klines {
+		if unit == "quote" {
+			total += k.Volume
+		} else {
+			total += k.Volume * k.Close
+		}
+	}
+	return total / float64(len(klines)), nil
+}
+
+// bookDepthWithin sums the notional of the levels within ±bandPct percent of the mid price;
+// bids and asks are [price, size in the base asset] ordered best first
+func bookDepthWithin(bids, asks [][2]float64, bandPct float64) (*BookDepth, error) {
+	if len(bids) == 0 || len(asks) == 0 {
+		return nil, fmt.Errorf("empty order book")
+	}
+	mid := (bids[0][0] + asks[0][0]) / 2
+	if mid <= 0 {
+		return nil, fmt.Errorf("invalid order book prices")
+	}
+	low, high := mid*(1-bandPct/100), mid*(1+bandPct/100)
+	book := &BookDepth{MidPrice: mid}
+	for _, level := range bids {
+		if level[0] < low {
+			break
+		}
+		book.BidNotional += level[0] * level[1]
+	}
+	for _, level := range asks {
+		if level[0] > high {
+			break
+		}
+		book.AskNotional += level[0] * level[1]
+	}
+	return book, nil
+}
+
+// formatDepthLimit the prompt line for the depth-aware position limit
+func formatDepthLimit(l *DepthLimit) string {
+	var parts []string
+	if l.VolumeCap > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f from hourly volume (avg %.0f over %dh)", l.VolumeCap, l.AvgHourlyVolume, l.VolumeHours))
+	}
+	if l.DepthCap > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f from order book depth (±%.1f%%: bids %.0f / asks %.0f)", l.DepthCap, l.BandPct, l.Book.BidNotional, l.Book.AskNotional))
+	}
+	return fmt.Sprintf("Max position size (liquidity): %.0f USDT notional, limited by %s [%s]; larger entries are clamped\n\n",
+		l.MaxNotional, l.Binding(), strings.Join(parts, "; "))
+}
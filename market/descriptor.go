@@ -0,0 +1,363 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"nofx/errs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Descriptor-driven market data providers
+// A descriptor file (JSON or YAML) describes an exchange's public REST endpoints, how symbols and
+// intervals are spelled, and where each field lives in the response. It is turned into a
+// MarketDataProvider at runtime, so niche exchanges can be used as data sources without Go code.
+//
+// Templates in paths and query values: {symbol} {interval} {limit}
+// Field paths are dot-separated and may index arrays: "data", "result.list", "data.0.fundingRate"
+
+// ProviderDescriptor describes an exchange's public market data API
+type ProviderDescriptor struct {
+	Name      string            `json:"name"`
+	BaseURL   string            `json:"base_url"`
+	Symbol    SymbolRules       `json:"symbol"`
+	Intervals map[string]string `json:"intervals"` // internal interval (3m, 4h) -> exchange spelling; missing entries pass through
+
+	Klines       KlinesEndpoint `json:"klines"`
+	OpenInterest *ValueEndpoint `json:"open_interest,omitempty"` // optional, OI is reported as 0 when absent
+	FundingRate  *ValueEndpoint `json:"funding_rate,omitempty"`  // optional, funding is reported as 0 when absent
+}
+
+// SymbolRules converts internal symbols (BTCUSDT) to the exchange format
+type SymbolRules struct {
+	Quote     string            `json:"quote"`     // quote asset of internal symbols (default USDT)
+	QuoteAs   string            `json:"quote_as"`  // exchange spelling of the quote asset (default same as quote)
+	Separator string            `json:"separator"` // between base and quote, e.g. "-" or "_"
+	Case      string            `json:"case"`      // "upper" (default) or "lower"
+	Prefix    string            `json:"prefix"`
+	Suffix    string            `json:"suffix"`  // e.g. "-SWAP"
+	Aliases   map[string]string `json:"aliases"` // explicit overrides: BTCUSDT -> XBTUSDTM
+}
+
+// KlinesEndpoint describes the candlestick endpoint
+type KlinesEndpoint struct {
+	Path     string            `json:"path"`
+	Query    map[string]string `json:"query"`
+	DataPath string            `json:"data_path"` // path to the list of candles ("" = response root)
+	// Fields maps open_time/open/high/low/close/volume to an array index ("0") for array rows
+	// or to a key (path) for object rows
+	Fields   map[string]string `json:"fields"`
+	TimeUnit string            `json:"time_unit"` // "ms" (default) or "s"
+}
+
+// ValueEndpoint describes an endpoint returning a single number
+type ValueEndpoint struct {
+	Path      string            `json:"path"`
+	Query     map[string]string `json:"query"`
+	ValuePath string            `json:"value_path"`
+}
+
+// klineFields are the fields every kline mapping must provide
+var klineFields = []string{"open_time", "open", "high", "low", "close", "volume"}
+
+// LoadProviderDescriptor reads a descriptor from a .json, .yaml or .yml file
+func LoadProviderDescriptor(path string) (*ProviderDescriptor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+
+	var desc ProviderDescriptor
+	if err := json.Unmarshal(data, &desc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := desc.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &desc, nil
+}
+
+// Validate checks that the descriptor has everything needed to fetch klines
+func (d *ProviderDescriptor) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("descriptor name is required")
+	}
+	if _, err := url.ParseRequestURI(d.BaseURL); err != nil {
+		return fmt.Errorf("descriptor %s: invalid base_url: %w", d.Name, err)
+	}
+	if d.Klines.Path == "" {
+		return fmt.Errorf("descriptor %s: klines.path is required", d.Name)
+	}
+	for _, field := range klineFields {
+		if d.Klines.Fields[field] == "" {
+			return fmt.Errorf("descriptor %s: klines.fields.%s is required", d.Name, field)
+		}
+	}
+	switch d.Klines.TimeUnit {
+	case "", "ms", "s":
+	default:
+		return fmt.Errorf("descriptor %s: klines.time_unit must be ms or s", d.Name)
+	}
+	for name, ep := range map[string]*ValueEndpoint{"open_interest": d.OpenInterest, "funding_rate": d.FundingRate} {
+		if ep != nil && (ep.Path == "" || ep.ValuePath == "") {
+			return fmt.Errorf("descriptor %s: %s needs path and value_path", d.Name, name)
+		}
+	}
+	return nil
+}
+
+// DescriptorProvider implements MarketDataProvider from a ProviderDescriptor
+type DescriptorProvider struct {
+	desc ProviderDescriptor
+}
+
+// NewDescriptorProvider creates a provider from a validated descriptor
+func NewDescriptorProvider(desc *ProviderDescriptor) (*DescriptorProvider, error) {
+	if err := desc.Validate(); err != nil {
+		return nil, err
+	}
+	return &DescriptorProvider{desc: *desc}, nil
+}
+
+// RegisterDescriptorProvider loads a descriptor file and registers it under its name
+func RegisterDescriptorProvider(path string) (string, error) {
+	desc, err := LoadProviderDescriptor(path)
+	if err != nil {
+		return "", err
+	}
+	provider, err := NewDescriptorProvider(desc)
+	if err != nil {
+		return "", err
+	}
+	RegisterProvider(desc.Name, provider)
+	return desc.Name, nil
+}
+
+func (p *DescriptorProvider) GetName() string {
+	return p.desc.Name
+}
+
+func (p *DescriptorProvider) NormalizeSymbol(symbol string) string {
+	rules := p.desc.Symbol
+	symbol = strings.ToUpper(symbol)
+	if alias, ok := rules.Aliases[symbol]; ok {
+		return alias
+	}
+
+	quote := strings.ToUpper(rules.Quote)
+	if quote == "" {
+		quote = "USDT"
+	}
+	result := symbol
+	if base := strings.TrimSuffix(symbol, quote); base != symbol && base != "" {
+		quoteAs := rules.QuoteAs
+		if quoteAs == "" {
+			quoteAs = quote
+		}
+		result = base + rules.Separator + quoteAs
+	}
+	result = rules.Prefix + result + rules.Suffix
+	if strings.EqualFold(rules.Case, "lower") {
+		result = strings.ToLower(result)
+	}
+	return result
+}
+
+// convertInterval maps internal intervals to the exchange spelling
+func (p *DescriptorProvider) convertInterval(interval string) string {
+	if converted, ok := p.desc.Intervals[interval]; ok {
+		return converted
+	}
+	return interval
+}
+
+// buildURL expands templates in path and query
+func (p *DescriptorProvider) buildURL(path string, query map[string]string, vars map[string]string) string {
+	expand := func(s string) string {
+		for k, v := range vars {
+			s = strings.ReplaceAll(s, "{"+k+"}", v)
+		}
+		return s
+	}
+	apiURL := strings.TrimRight(p.desc.BaseURL, "/") + expand(path)
+	if len(query) > 0 {
+		values := url.Values{}
+		for k, v := range query {
+			values.Set(k, expand(v))
+		}
+		apiURL += "?" + values.Encode()
+	}
+	return apiURL
+}
+
+// fetch performs a GET request and decodes the JSON body
+func (p *DescriptorProvider) fetch(apiURL, what string) (interface{}, error) {
+	name := p.desc.Name
+	resp, err := rateLimitedGet(name, apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s request failed: %w", name, what, errs.Network(name, err))
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s read failed: %w", name, what, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s API error: %w", name, what, errs.FromResponse(name, resp, body))
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("%s %s parse failed: %w", name, what, err)
+	}
+	return result, nil
+}
+
+func (p *DescriptorProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	ep := p.desc.Klines
+	apiURL := p.buildURL(ep.Path, ep.Query, map[string]string{
+		"symbol":   p.NormalizeSymbol(symbol),
+		"interval": p.convertInterval(interval),
+		"limit":    strconv.Itoa(limit),
+	})
+	result, err := p.fetch(apiURL, "klines")
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := lookupPath(result, ep.DataPath)
+	rows, isList := data.([]interface{})
+	if !ok || !isList {
+		return nil, fmt.Errorf("%s klines: %q is not a list", p.desc.Name, ep.DataPath)
+	}
+
+	intervalMs := int64(0)
+	if d, err := IntervalDuration(interval); err == nil {
+		intervalMs = d.Milliseconds()
+	}
+	klines := make([]Kline, 0, len(rows))
+	for _, row := range rows {
+		values := make(map[string]float64, len(klineFields))
+		for _, field := range klineFields {
+			v, ok := lookupPath(row, ep.Fields[field])
+			if !ok {
+				return nil, fmt.Errorf("%s klines: field %s (%s) missing", p.desc.Name, field, ep.Fields[field])
+			}
+			if values[field], ok = toFloat(v); !ok {
+				return nil, fmt.Errorf("%s klines: field %s is not a number: %v", p.desc.Name, field, v)
+			}
+		}
+		openTime := int64(values["open_time"])
+		if ep.TimeUnit == "s" {
+			openTime *= 1000
+		}
+		klines = append(klines, Kline{
+			OpenTime:  openTime,
+			Open:      values["open"],
+			High:      values["high"],
+			Low:       values["low"],
+			Close:     values["close"],
+			Volume:    values["volume"],
+			CloseTime: openTime + intervalMs - 1,
+		})
+	}
+
+	// Some exchanges return newest first
+	sort.Slice(klines, func(i, j int) bool { return klines[i].OpenTime < klines[j].OpenTime })
+	if limit > 0 && len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
+	return klines, nil
+}
+
+func (p *DescriptorProvider) GetOpenInterest(symbol string) (*OIData, error) {
+	if p.desc.OpenInterest == nil {
+		return &OIData{}, nil
+	}
+	oi, err := p.fetchValue(p.desc.OpenInterest, symbol, "open interest")
+	if err != nil {
+		return nil, err
+	}
+	return &OIData{
+		Latest:  oi,
+		Average: oi * 0.999, // Approximate average
+	}, nil
+}
+
+func (p *DescriptorProvider) GetFundingRate(symbol string) (float64, error) {
+	if p.desc.FundingRate == nil {
+		return 0, nil
+	}
+	return p.fetchValue(p.desc.FundingRate, symbol, "funding rate")
+}
+
+// fetchValue fetches a single number from a value endpoint
+func (p *DescriptorProvider) fetchValue(ep *ValueEndpoint, symbol, what string) (float64, error) {
+	apiURL := p.buildURL(ep.Path, ep.Query, map[string]string{"symbol": p.NormalizeSymbol(symbol)})
+	result, err := p.fetch(apiURL, what)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := lookupPath(result, ep.ValuePath)
+	if !ok {
+		return 0, fmt.Errorf("%s %s: %q not found in response", p.desc.Name, what, ep.ValuePath)
+	}
+	value, ok := toFloat(v)
+	if !ok {
+		return 0, fmt.Errorf("%s %s: %q is not a number: %v", p.desc.Name, what, ep.ValuePath, v)
+	}
+	return value, nil
+}
+
+// lookupPath walks a decoded JSON value along a dot-separated path (numeric parts index arrays)
+func lookupPath(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+	for _, part := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// toFloat converts JSON numbers and numeric strings
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,121 @@
+package market
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeDescriptor(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadProviderDescriptorExample(t *testing.T) {
+	desc, err := LoadProviderDescriptor(filepath.Join("..", "market_descriptors", "binance_futures.example.yaml"))
+	if err != nil {
+		t.Fatalf("加载示例描述文件失败: %v", err)
+	}
+	if desc.Name != "binance_descriptor" || desc.BaseURL != "https://fapi.binance.com" {
+		t.Errorf("名称/地址 = %q %q", desc.Name, desc.BaseURL)
+	}
+	if desc.Klines.Path != "/fapi/v1/klines" || desc.Klines.Fields["close"] != "4" || desc.Klines.Query["limit"] != "{limit}" {
+		t.Errorf("K线接口 = %+v", desc.Klines)
+	}
+	if desc.OpenInterest == nil || desc.OpenInterest.ValuePath != "openInterest" || desc.FundingRate == nil || desc.FundingRate.ValuePath != "lastFundingRate" {
+		t.Errorf("持仓量/资金费率接口 = %+v %+v", desc.OpenInterest, desc.FundingRate)
+	}
+}
+
+func TestLoadProviderDescriptorValidation(t *testing.T) {
+	fields := `"fields":{"open_time":"0","open":"1","high":"2","low":"3","close":"4","volume":"5"}`
+	cases := []struct {
+		name, content, want string
+	}{
+		{"missing_name", `{"base_url":"https://example.com","klines":{"path":"/k",` + fields + `}}`, "name is required"},
+		{"bad_base_url", `{"name":"x","base_url":"example.com","klines":{"path":"/k",` + fields + `}}`, "invalid base_url"},
+		{"missing_path", `{"name":"x","base_url":"https://example.com","klines":{` + fields + `}}`, "klines.path"},
+		{"missing_field", `{"name":"x","base_url":"https://example.com","klines":{"path":"/k","fields":{"open_time":"0"}}}`, "klines.fields.open"},
+		{"bad_time_unit", `{"name":"x","base_url":"https://example.com","klines":{"path":"/k",` + fields + `,"time_unit":"us"}}`, "time_unit"},
+		{"value_path", `{"name":"x","base_url":"https://example.com","klines":{"path":"/k",` + fields + `},"funding_rate":{"path":"/f"}}`, "funding_rate needs path and value_path"},
+		{"bad_json", `{"name":`, "parse"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadProviderDescriptor(writeDescriptor(t, "bad.json", tc.content))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("错误 = %v, 期望包含 %q", err, tc.want)
+			}
+		})
+	}
+	if _, err := LoadProviderDescriptor(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("文件不存在时应报错")
+	}
+}
+
+func TestDescriptorProviderObjectRows(t *testing.T) {
+	// 对象行、秒级时间、新的在前、带前后缀的币种格式
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/candles":
+			if r.URL.Query().Get("instId") != "ETH-USDT-SWAP" || r.URL.Query().Get("bar") != "4H" {
+				t.Errorf("K线请求参数 = %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"data":{"list":[
+				{"ts":1700003600,"o":"2","h":"3","l":"1","c":"2.5","v":"20"},
+				{"ts":1700000000,"o":"1","h":"2","l":"0.5","c":"2","v":"10"}]}}`)
+		case "/funding":
+			fmt.Fprint(w, `{"data":[{"rate":"0.0002"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	path := writeDescriptor(t, "okx.yaml", `
+name: okx_test
+base_url: `+server.URL+`
+symbol: {separator: "-", suffix: "-SWAP"}
+intervals: {4h: 4H}
+klines:
+  path: /candles
+  query: {instId: "{symbol}", bar: "{interval}"}
+  data_path: data.list
+  fields: {open_time: ts, open: o, high: h, low: l, close: c, volume: v}
+  time_unit: s
+funding_rate: {path: /funding, value_path: data.0.rate}
+`)
+	desc, err := LoadProviderDescriptor(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewDescriptorProvider(desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	klines, err := provider.GetKlines("ETHUSDT", "4h", 10)
+	if err != nil {
+		t.Fatalf("获取K线失败: %v", err)
+	}
+	if len(klines) != 2 || klines[0].OpenTime != 1700000000000 || klines[1].Close != 2.5 || klines[1].Volume != 20 {
+		t.Fatalf("K线 = %+v", klines)
+	}
+	if want := klines[0].OpenTime + 4*3600*1000 - 1; klines[0].CloseTime != want {
+		t.Errorf("收盘时间 = %d, 期望 %d", klines[0].CloseTime, want)
+	}
+	if rate, err := provider.GetFundingRate("ETHUSDT"); err != nil || rate != 0.0002 {
+		t.Errorf("资金费率 = %v, %v", rate, err)
+	}
+	if oi, err := provider.GetOpenInterest("ETHUSDT"); err != nil || oi.Latest != 0 {
+		t.Errorf("未配置持仓量接口时应返回0: %+v, %v", oi, err)
+	}
+}
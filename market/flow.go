@@ -0,0 +1,122 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"nofx/tracing"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Order flow metrics
+// Providers backed by an exchange that publishes trading statistics (Binance futures/data) implement
+// FlowProvider. Each fetch gets the last Points periods of taker buy/sell volume with their ratio and the
+// top traders' long/short position ratio, shown next to open interest and funding in the prompt: a rising
+// buy/sell ratio without price follow-through, or crowded top-trader positioning, often precedes reversals.
+// Providers without these statistics are skipped.
+
+const maxFlowPoints = 30
+
+// flowPeriods the periods accepted by the statistics endpoints
+var flowPeriods = []string{"5m", "15m", "30m", "1h", "2h", "4h", "6h", "12h", "1d"}
+
+// FlowConfig settings of the order flow metrics
+type FlowConfig struct {
+	Enabled bool
+	Period  string // statistics period (default "5m")
+	Points  int    // periods shown in the prompt (default 10, at most 30)
+}
+
+// FlowProvider is implemented by providers that expose taker volume and long/short ratio statistics
+type FlowProvider interface {
+	// GetFlowMetrics returns the last limit periods of taker flow and top trader positioning, oldest → latest
+	GetFlowMetrics(symbol, period string, limit int) (*FlowData, error)
+}
+
+// FlowData taker flow and top trader positioning per period, oldest → latest
+type FlowData struct {
+	Period            string
+	TakerBuySellRatio []float64 // taker buy volume / taker sell volume
+	TakerBuyVolume    []float64 // base asset
+	TakerSellVolume   []float64 // base asset
+	TopLongShortRatio []float64 // top traders' long/short position ratio
+	TopLongPct        float64   // latest share of top traders' positions that are long, percent
+}
+
+var flow = struct {
+	mu  sync.Mutex
+	cfg FlowConfig
+}{}
+
+// SetFlowConfig sets the order flow metrics settings (two extra requests per symbol and fetch)
+func SetFlowConfig(cfg FlowConfig) {
+	if !isFlowPeriod(cfg.Period) {
+		cfg.Period = "5m"
+	}
+	if cfg.Points <= 0 {
+		cfg.Points = 10
+	}
+	if cfg.Points > maxFlowPoints {
+		cfg.Points = maxFlowPoints
+	}
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+	flow.cfg = cfg
+}
+
+func flowConfig() FlowConfig {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+	return flow.cfg
+}
+
+// isFlowPeriod reports whether the statistics endpoints accept the period
+func isFlowPeriod(period string) bool {
+	for _, p := range flowPeriods {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchFlow returns the order flow metrics, or nil when disabled or unsupported by the provider
+func fetchFlow(ctx context.Context, provider MarketDataProvider, symbol string) (*FlowData, error) {
+	cfg := flowConfig()
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	fp, ok := provider.(FlowProvider)
+	if !ok {
+		return nil, nil
+	}
+
+	_, span := tracing.Start(ctx, "market.flow")
+	defer span.End()
+	span.SetAttr("provider", provider.GetName())
+	span.SetAttr("symbol", symbol)
+	span.SetAttr("period", cfg.Period)
+
+	start := time.Now()
+	data, err := fp.GetFlowMetrics(symbol, cfg.Period, cfg.Points)
+	recordCall(provider.GetName(), symbol, time.Since(start), err)
+	span.RecordError(err)
+	return data, err
+}
+
+// formatFlow the prompt lines for the order flow metrics
+func formatFlow(f *FlowData) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Order flow (%s periods, oldest → latest):\n\n", f.Period))
+	if len(f.TakerBuySellRatio) > 0 {
+		sb.WriteString(fmt.Sprintf("Taker buy/sell volume ratio: %s\n\n", formatFloatSlice(f.TakerBuySellRatio)))
+		sb.WriteString(fmt.Sprintf("Taker buy volume: %s\n\n", formatFloatSlice(f.TakerBuyVolume)))
+		sb.WriteString(fmt.Sprintf("Taker sell volume: %s\n\n", formatFloatSlice(f.TakerSellVolume)))
+	}
+	if len(f.TopLongShortRatio) > 0 {
+		sb.WriteString(fmt.Sprintf("Top trader long/short position ratio: %s (latest %.1f%% of top trader positions long)\n\n",
+			formatFloatSlice(f.TopLongShortRatio), f.TopLongPct))
+	}
+	return sb.String()
+}
@@ -0,0 +1,195 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatMode selects how market data is rendered into the prompt
+type FormatMode string
+
+const (
+	FormatModeVerbose FormatMode = "verbose" // one labelled sentence per value and bracketed series (default)
+	FormatModeCompact FormatMode = "compact" // key=value header plus fixed-width tables, far fewer tokens
+)
+
+// FormatModes all supported modes
+var FormatModes = []FormatMode{FormatModeVerbose, FormatModeCompact}
+
+// ParseFormatMode validates a configured mode; empty means verbose
+func ParseFormatMode(s string) (FormatMode, error) {
+	switch FormatMode(strings.ToLower(strings.TrimSpace(s))) {
+	case "", FormatModeVerbose:
+		return FormatModeVerbose, nil
+	case FormatModeCompact:
+		return FormatModeCompact, nil
+	}
+	return "", fmt.Errorf("unknown market data format %q (supported: %v)", s, FormatModes)
+}
+
+// FormatAs renders market data in the given mode (unknown modes fall back to verbose)
+func FormatAs(data *Data, mode FormatMode) string {
+	if mode == FormatModeCompact {
+		return FormatCompact(data)
+	}
+	return Format(data)
+}
+
+// FormatCompact renders the same data as Format with short keys and one table per timeframe:
+// each series becomes a column, rows run oldest → latest, so numbers are not repeated in prose
+func FormatCompact(data *Data) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("px=%s chg1h=%+.2f%% chg4h=%+.2f%% ema20=%s macd=%s rsi7=%s\n",
+		compactFloat(data.CurrentPrice), data.PriceChange1h, data.PriceChange4h,
+		compactFloat(data.CurrentEMA20), compactFloat(data.CurrentMACD), compactFloat(data.CurrentRSI7)))
+
+	sb.WriteString(fmt.Sprintf("funding=%.2e", data.FundingRate))
+	if left := data.FundingCountdown(time.Now()); left > 0 {
+		sb.WriteString(" next=" + formatHorizon(left.Round(time.Minute)))
+		if data.FundingEstimated {
+			sb.WriteString("(est)")
+		}
+	}
+	if data.OpenInterest != nil {
+		sb.WriteString(fmt.Sprintf(" oi=%s oi_avg=%s", compactFloat(data.OpenInterest.Latest), compactFloat(data.OpenInterest.Average)))
+	}
+	sb.WriteString("\n")
+
+	// Single-value extras are already short; only their blank separator lines are dropped
+	var extras strings.Builder
+	if data.Basis != nil {
+		extras.WriteString(formatBasis(data.Basis))
+	}
+	if data.Volatility != nil {
+		extras.WriteString(formatVolatility(data.Volatility, data.CurrentPrice))
+	}
+	if data.Liquidity != nil {
+		extras.WriteString(formatLiquidity(data.Liquidity))
+	}
+	if data.Range != nil {
+		extras.WriteString(formatRange(data.Range, data.CurrentPrice))
+	}
+	if data.Depth != nil {
+		extras.WriteString(formatDepthLimit(data.Depth))
+	}
+	sb.WriteString(strings.ReplaceAll(extras.String(), "\n\n", "\n"))
+
+	if f := data.Flow; f != nil && (len(f.TakerBuySellRatio) > 0 || len(f.TopLongShortRatio) > 0) {
+		sb.WriteString(fmt.Sprintf("flow %s (oldest→latest", f.Period))
+		if len(f.TopLongShortRatio) > 0 {
+			sb.WriteString(fmt.Sprintf(", top traders %.1f%% long", f.TopLongPct))
+		}
+		sb.WriteString(")\n")
+		writeTable(&sb, []tableColumn{
+			{"taker_bs", f.TakerBuySellRatio},
+			{"buy_vol", f.TakerBuyVolume},
+			{"sell_vol", f.TakerSellVolume},
+			{"top_ls", f.TopLongShortRatio},
+		})
+	}
+
+	if s := data.IntradaySeries; s != nil {
+		sb.WriteString("3m (oldest→latest)\n")
+		writeTable(&sb, []tableColumn{
+			{"px", s.MidPrices},
+			{"ema20", s.EMA20Values},
+			{"macd", s.MACDValues},
+			{"rsi7", s.RSI7Values},
+			{"rsi14", s.RSI14Values},
+		})
+	}
+
+	if l := data.LongerTermContext; l != nil {
+		sb.WriteString(fmt.Sprintf("4h ema20=%s ema50=%s atr3=%s atr14=%s vol=%s vol_avg=%s (oldest→latest)\n",
+			compactFloat(l.EMA20), compactFloat(l.EMA50), compactFloat(l.ATR3), compactFloat(l.ATR14),
+			compactFloat(l.CurrentVolume), compactFloat(l.AverageVolume)))
+		writeTable(&sb, []tableColumn{
+			{"macd", l.MACDValues},
+			{"rsi14", l.RSI14Values},
+		})
+	}
+
+	return sb.String()
+}
+
+// tableColumn one series of a compact table
+type tableColumn struct {
+	name   string
+	values []float64
+}
+
+// writeTable writes right-aligned columns; empty columns are skipped and shorter series are
+// aligned to the latest row (missing older cells are "-")
+func writeTable(sb *strings.Builder, columns []tableColumn) {
+	var cols []tableColumn
+	rows := 0
+	for _, c := range columns {
+		if len(c.values) == 0 {
+			continue
+		}
+		cols = append(cols, c)
+		if len(c.values) > rows {
+			rows = len(c.values)
+		}
+	}
+	if len(cols) == 0 {
+		return
+	}
+
+	cells := make([][]string, len(cols))
+	widths := make([]int, len(cols))
+	for i, c := range cols {
+		cells[i] = make([]string, rows)
+		widths[i] = len(c.name)
+		offset := rows - len(c.values)
+		for r := 0; r < rows; r++ {
+			cell := "-"
+			if r >= offset {
+				cell = compactFloat(c.values[r-offset])
+			}
+			cells[i][r] = cell
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+
+	writeRow := func(cell func(i int) string) {
+		for i := range cols {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(fmt.Sprintf("%*s", widths[i], cell(i)))
+		}
+		sb.WriteByte('\n')
+	}
+	writeRow(func(i int) string { return cols[i].name })
+	for r := 0; r < rows; r++ {
+		writeRow(func(i int) string { return cells[i][r] })
+	}
+}
+
+// compactFloat about five significant digits without trailing zeros (60000, 3001.2, 48.17, 0.45791)
+func compactFloat(v float64) string {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	decimals := 4 - int(math.Floor(math.Log10(math.Abs(v))))
+	if decimals < 0 {
+		decimals = 0
+	} else if decimals > 8 {
+		decimals = 8
+	}
+	s := strconv.FormatFloat(v, 'f', decimals, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Funding countdown
+// Providers that report the next funding settlement with the rate (Binance premiumIndex, Gate.io contract
+// info) implement FundingScheduleProvider, so the countdown costs no extra request. For the others the next
+// settlement is estimated from the clock: most perpetuals settle every 8 hours at 00:00, 08:00 and 16:00 UTC.
+// The prompt shows the time left until the next settlement next to the funding rate.
+
+const defaultFundingInterval = 8 * time.Hour
+
+// FundingScheduleProvider is implemented by providers that report the next funding time with the rate
+type FundingScheduleProvider interface {
+	// GetFundingSchedule returns the current funding rate and the time of the next funding settlement
+	GetFundingSchedule(symbol string) (rate float64, next time.Time, err error)
+}
+
+// nextFundingBoundary the next clock-aligned settlement after now (every interval from 00:00 UTC)
+func nextFundingBoundary(now time.Time, interval time.Duration) time.Time {
+	return now.UTC().Truncate(interval).Add(interval)
+}
+
+// fetchFunding returns the funding rate and the next settlement time; estimated is true when the time
+// comes from the 8-hour clock schedule instead of the provider
+func fetchFunding(ctx context.Context, provider MarketDataProvider, symbol string) (rate float64, next time.Time, estimated bool, err error) {
+	now := time.Now()
+	sp, ok := provider.(FundingScheduleProvider)
+	if !ok {
+		rate, err = tracedFundingRate(ctx, provider, symbol)
+		return rate, nextFundingBoundary(now, defaultFundingInterval), true, err
+	}
+	rate, next, err = tracedFundingSchedule(ctx, provider, sp, symbol)
+	if err == nil && !next.After(now) {
+		// settlement time missing or already passed (stale response)
+		return rate, nextFundingBoundary(now, defaultFundingInterval), true, nil
+	}
+	return rate, next, false, err
+}
+
+// FundingCountdown time left until the next funding settlement (0 when unknown or already passed)
+func (d *Data) FundingCountdown(now time.Time) time.Duration {
+	if d.NextFundingTime.IsZero() || !d.NextFundingTime.After(now) {
+		return 0
+	}
+	return d.NextFundingTime.Sub(now)
+}
+
+// formatFundingCountdown the countdown suffix of the funding rate line
+func formatFundingCountdown(d *Data, now time.Time) string {
+	left := d.FundingCountdown(now)
+	if left <= 0 {
+		return ""
+	}
+	suffix := fmt.Sprintf(" (next funding in %s at %s UTC", formatHorizon(left.Round(time.Minute)), d.NextFundingTime.UTC().Format("15:04"))
+	if d.FundingEstimated {
+		suffix += ", estimated from the 8h schedule"
+	}
+	return suffix + ")"
+}
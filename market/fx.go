@@ -0,0 +1,69 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"nofx/cache"
+	"strings"
+	"time"
+)
+
+// Currency conversion
+// Most exchanges here settle in USDT, but Hyperliquid settles in USDC and Binance can hold USDC/FDUSD margin next to
+// USDT. Amounts in different currencies are converted at market rates instead of being counted 1:1: a currency's
+// value in USDT is the last price of its <CURRENCY>USDT market from the market providers, and any pair converts
+// through USDT. USD (as reported by Hyperliquid) is priced as USDC. Rates are cached for fxRefresh.
+
+const fxRefresh = time.Minute
+
+// usdAlias is the market used to price plain USD balances
+const usdAlias = "USDC"
+
+var fxValues = cache.New[string, float64]("market.fx", fxRefresh)
+
+// NormalizeCurrency upper-cases a currency code, empty means DefaultQuote
+func NormalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return DefaultQuote
+	}
+	return currency
+}
+
+// ConversionRate returns how many units of to one unit of from is worth
+func ConversionRate(ctx context.Context, from, to string) (float64, error) {
+	from, to = NormalizeCurrency(from), NormalizeCurrency(to)
+	if from == to {
+		return 1, nil
+	}
+	fromValue, err := usdtValue(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+	toValue, err := usdtValue(ctx, to)
+	if err != nil {
+		return 0, err
+	}
+	return fromValue / toValue, nil
+}
+
+// usdtValue the USDT value of one unit of currency
+func usdtValue(ctx context.Context, currency string) (float64, error) {
+	if currency == "USD" {
+		currency = usdAlias
+	}
+	if currency == DefaultQuote {
+		return 1, nil
+	}
+	return fxValues.GetOrLoad(currency, func() (float64, error) {
+		symbol := currency + DefaultQuote
+		price, err := CurrentPrice(ctx, symbol)
+		if err != nil {
+			return 0, fmt.Errorf("%s rate: %w", symbol, err)
+		}
+		if price <= 0 {
+			return 0, fmt.Errorf("%s rate: invalid price %v", symbol, price)
+		}
+		return price, nil
+	})
+}
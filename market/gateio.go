@@ -7,8 +7,10 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"nofx/errs"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // GateioProvider implements MarketDataProvider for Gate.io exchange
@@ -23,6 +25,13 @@ func NewGateioProvider() *GateioProvider {
 	}
 }
 
+// NewGateioProviderWithBaseURL creates a Gate.io provider against a custom endpoint (e.g. a mock server)
+func NewGateioProviderWithBaseURL(baseURL string) *GateioProvider {
+	return &GateioProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
 // GetName returns the provider name
 func (p *GateioProvider) GetName() string {
 	return "gateio"
@@ -34,13 +43,8 @@ func (p *GateioProvider) NormalizeSymbol(symbol string) string {
 	// Remove hyphens first
 	symbol = strings.ReplaceAll(symbol, "-", "")
 	// Convert to underscore format
-	if strings.HasSuffix(symbol, "USDT") && len(symbol) > 4 {
-		base := symbol[:len(symbol)-4]
-		return base + "_USDT"
-	}
-	if strings.HasSuffix(symbol, "USDC") && len(symbol) > 4 {
-		base := symbol[:len(symbol)-4]
-		return base + "_USDC"
+	if base, quote := SplitSymbol(symbol); quote != "" {
+		return base + "_" + quote
 	}
 	if strings.HasSuffix(symbol, "BTC") && len(symbol) > 3 {
 		base := symbol[:len(symbol)-3]
@@ -106,16 +110,32 @@ func (p *GateioProvider) GetKlines(symbol, interval string, limit int) ([]Kline,
 		p.baseURL, url.QueryEscape(symbol), interval, limit)
 
 	log.Printf("📊 [Gate.io] 获取K线数据: %s (%s) -> %s, 间隔=%s, 数量=%d", originalSymbol, symbol, apiURL, interval, limit)
+	return p.fetchKlines(originalSymbol, interval, apiURL)
+}
 
-	resp, err := http.Get(apiURL)
+// GetKlinesRange fetches candlesticks whose open time falls within [start, end]
+func (p *GateioProvider) GetKlinesRange(symbol, interval string, start, end time.Time) ([]Kline, error) {
+	originalSymbol := symbol
+	symbol = p.NormalizeSymbol(symbol)
+	interval = p.convertInterval(interval)
+
+	// Gate.io does not accept limit together with from/to
+	apiURL := fmt.Sprintf("%s/futures/usdt/candlesticks?contract=%s&interval=%s&from=%d&to=%d",
+		p.baseURL, url.QueryEscape(symbol), interval, start.Unix(), end.Unix())
+	return p.fetchKlines(originalSymbol, interval, apiURL)
+}
+
+// fetchKlines requests and parses a candlesticks endpoint (interval must already be in Gate.io format)
+func (p *GateioProvider) fetchKlines(originalSymbol, interval, apiURL string) ([]Kline, error) {
+	resp, err := rateLimitedGet("gateio", apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("gateio klines request failed: %w", err)
+		return nil, fmt.Errorf("gateio klines request failed: %w", errs.Network("gateio", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("gateio klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("gateio klines API error: %w", errs.FromResponse("gateio", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -172,15 +192,15 @@ func (p *GateioProvider) GetOpenInterest(symbol string) (*OIData, error) {
 
 	log.Printf("📊 [Gate.io] 获取持仓量数据: %s -> %s", originalSymbol, symbol)
 
-	resp, err := http.Get(apiURL)
+	resp, err := rateLimitedGet("gateio", apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("gateio open interest request failed: %w", err)
+		return nil, fmt.Errorf("gateio open interest request failed: %w", errs.Network("gateio", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("gateio open interest API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("gateio open interest API error: %w", errs.FromResponse("gateio", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -199,6 +219,10 @@ func (p *GateioProvider) GetOpenInterest(symbol string) (*OIData, error) {
 	if oi == 0 {
 		oi = parseFloatSafe(result["open_interest"])
 	}
+	// position_size is in contracts; convert to the base asset like the other providers
+	if multiplier := parseFloatSafe(result["quanto_multiplier"]); multiplier > 0 {
+		oi *= multiplier
+	}
 
 	oiData := &OIData{
 		Latest:  oi,
@@ -210,35 +234,76 @@ func (p *GateioProvider) GetOpenInterest(symbol string) (*OIData, error) {
 
 // GetFundingRate fetches funding rate from Gate.io
 func (p *GateioProvider) GetFundingRate(symbol string) (float64, error) {
+	rate, _, err := p.GetFundingSchedule(symbol)
+	return rate, err
+}
+
+// GetFundingSchedule fetches the funding rate and the next funding time (funding_next_apply) from Gate.io contract info
+func (p *GateioProvider) GetFundingSchedule(symbol string) (float64, time.Time, error) {
 	symbol = p.NormalizeSymbol(symbol)
 	apiURL := fmt.Sprintf("%s/futures/usdt/contracts/%s", p.baseURL, symbol)
 
-	resp, err := http.Get(apiURL)
+	resp, err := rateLimitedGet("gateio", apiURL)
 	if err != nil {
-		return 0, fmt.Errorf("gateio funding rate request failed: %w", err)
+		return 0, time.Time{}, fmt.Errorf("gateio funding rate request failed: %w", errs.Network("gateio", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return 0, fmt.Errorf("gateio funding rate API error (status %d): %s", resp.StatusCode, string(body))
+		return 0, time.Time{}, fmt.Errorf("gateio funding rate API error: %w", errs.FromResponse("gateio", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return 0, fmt.Errorf("gateio funding rate read failed: %w", err)
+		return 0, time.Time{}, fmt.Errorf("gateio funding rate read failed: %w", err)
 	}
 
 	var result struct {
-		FundingRate interface{} `json:"funding_rate"`
+		FundingRate      interface{} `json:"funding_rate"`
+		FundingNextApply interface{} `json:"funding_next_apply"` // unix seconds
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, fmt.Errorf("gateio funding rate parse failed: %w", err)
+		return 0, time.Time{}, fmt.Errorf("gateio funding rate parse failed: %w", err)
 	}
 
 	rate := parseFloatSafe(result.FundingRate)
-	return rate, nil
+	var next time.Time
+	if sec := parseFloatSafe(result.FundingNextApply); sec > 0 {
+		next = time.Unix(int64(sec), 0)
+	}
+	return rate, next, nil
+}
+
+// GetIndexPrice fetches mark price and spot index price from Gate.io contract info
+func (p *GateioProvider) GetIndexPrice(symbol string) (float64, float64, error) {
+	symbol = p.NormalizeSymbol(symbol)
+	apiURL := fmt.Sprintf("%s/futures/usdt/contracts/%s", p.baseURL, symbol)
+
+	resp, err := rateLimitedGet("gateio", apiURL)
+	if err != nil {
+		return 0, 0, fmt.Errorf("gateio index price request failed: %w", errs.Network("gateio", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, 0, fmt.Errorf("gateio index price read failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("gateio index price API error: %w", errs.FromResponse("gateio", resp, body))
+	}
+
+	var result struct {
+		MarkPrice  interface{} `json:"mark_price"`
+		IndexPrice interface{} `json:"index_price"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, 0, fmt.Errorf("gateio index price parse failed: %w", err)
+	}
+
+	return parseFloatSafe(result.MarkPrice), parseFloatSafe(result.IndexPrice), nil
 }
 
 // parseFloatSafe safely parses interface{} to float64
@@ -258,3 +323,65 @@ func parseFloatSafe(v interface{}) float64 {
 	}
 }
 
+
+// GetBookDepth sums the order book notional within ±bandPct percent of the mid price (top 100 levels;
+// sizes are in contracts and converted with the contract's quanto_multiplier)
+func (p *GateioProvider) GetBookDepth(symbol string, bandPct float64) (*BookDepth, error) {
+	symbol = p.NormalizeSymbol(symbol)
+
+	var contract struct {
+		QuantoMultiplier interface{} `json:"quanto_multiplier"`
+	}
+	if err := p.getJSON(fmt.Sprintf("%s/futures/usdt/contracts/%s", p.baseURL, symbol), "contract", &contract); err != nil {
+		return nil, err
+	}
+	multiplier := parseFloatSafe(contract.QuantoMultiplier)
+	if multiplier <= 0 {
+		return nil, fmt.Errorf("gateio contract %s has no quanto_multiplier", symbol)
+	}
+
+	var book struct {
+		Bids []struct {
+			P interface{} `json:"p"`
+			S interface{} `json:"s"`
+		} `json:"bids"`
+		Asks []struct {
+			P interface{} `json:"p"`
+			S interface{} `json:"s"`
+		} `json:"asks"`
+	}
+	apiURL := fmt.Sprintf("%s/futures/usdt/order_book?contract=%s&limit=100", p.baseURL, url.QueryEscape(symbol))
+	if err := p.getJSON(apiURL, "order book", &book); err != nil {
+		return nil, err
+	}
+	bids := make([][2]float64, 0, len(book.Bids))
+	for _, l := range book.Bids {
+		bids = append(bids, [2]float64{parseFloatSafe(l.P), parseFloatSafe(l.S) * multiplier})
+	}
+	asks := make([][2]float64, 0, len(book.Asks))
+	for _, l := range book.Asks {
+		asks = append(asks, [2]float64{parseFloatSafe(l.P), parseFloatSafe(l.S) * multiplier})
+	}
+	return bookDepthWithin(bids, asks, bandPct)
+}
+
+// getJSON requests a public endpoint and decodes the response
+func (p *GateioProvider) getJSON(apiURL, what string, v interface{}) error {
+	resp, err := rateLimitedGet("gateio", apiURL)
+	if err != nil {
+		return fmt.Errorf("gateio %s request failed: %w", what, errs.Network("gateio", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("gateio %s read failed: %w", what, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("gateio %s API error: %w", what, errs.FromResponse("gateio", resp, body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("gateio %s parse failed: %w", what, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package market
+
+import (
+	"fmt"
+	"time"
+)
+
+// intervalDurations maps supported kline intervals to their length
+var intervalDurations = map[string]time.Duration{
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+// IntervalDuration returns the length of a kline interval such as "3m" or "4h"
+func IntervalDuration(interval string) (time.Duration, error) {
+	d, ok := intervalDurations[interval]
+	if !ok {
+		return 0, fmt.Errorf("unsupported kline interval: %s", interval)
+	}
+	return d, nil
+}
+
+// GetKlinesAround fetches up to `before` klines before and `after` klines after the given time.
+// Providers implementing KlineRangeProvider are queried for the exact window; other providers
+// only expose the latest klines, so the window is cut from those (old timestamps may return nothing).
+func GetKlinesAround(provider MarketDataProvider, symbol, interval string, at time.Time, before, after int) ([]Kline, error) {
+	step, err := IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+	start := at.Truncate(step).Add(-time.Duration(before) * step)
+	end := at.Add(time.Duration(after) * step)
+
+	var klines []Kline
+	if rp, ok := provider.(KlineRangeProvider); ok {
+		klines, err = rp.GetKlinesRange(symbol, interval, start, end)
+	} else {
+		limit := int(time.Since(start)/step) + 1
+		if limit > 1500 {
+			return nil, fmt.Errorf("%s does not support historical klines", provider.GetName())
+		}
+		klines, err = FetchKlines(provider, symbol, interval, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	window := make([]Kline, 0, before+after+1)
+	for _, k := range klines {
+		if k.OpenTime >= start.UnixMilli() && k.OpenTime <= end.UnixMilli() {
+			window = append(window, k)
+		}
+	}
+	return window, nil
+}
@@ -0,0 +1,284 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"nofx/tracing"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Latency-aware provider selection
+// Every provider call made through the traced helpers records its latency and outcome per
+// provider and symbol. Latency-sensitive calls (current price, last bar) go to the fastest
+// healthy provider among the configured candidates for that symbol; full history keeps using
+// the configured default provider so indicators stay consistent.
+//
+// A provider/symbol is healthy when its recent error rate is at most maxHealthyErrorRate.
+// Candidates without recent samples are probed first, so new or recovered providers get
+// measured; samples expire after sampleMaxAge, which also gives failing providers another try.
+
+const (
+	latencyWindow       = 20               // recent calls kept per provider/symbol
+	sampleMaxAge        = 10 * time.Minute // older samples are ignored
+	maxHealthyErrorRate = 0.2
+)
+
+// Selection reasons
+const (
+	SelectionFastest  = "fastest"  // lowest average latency among healthy candidates
+	SelectionProbe    = "probe"    // candidate had no recent samples for the symbol
+	SelectionFallback = "fallback" // no healthy candidate, used the default provider
+)
+
+// callSample is the outcome of one provider call
+type callSample struct {
+	at      time.Time
+	latency time.Duration
+	ok      bool
+}
+
+type healthKey struct {
+	provider string
+	symbol   string
+}
+
+// PriceSelection is the provider chosen for a latency-sensitive call
+type PriceSelection struct {
+	Symbol   string    `json:"symbol"`
+	Provider string    `json:"provider"`
+	Reason   string    `json:"reason"`
+	Time     time.Time `json:"time"`
+}
+
+// ProviderHealthStats summarizes recent calls of one provider across symbols
+type ProviderHealthStats struct {
+	Provider     string  `json:"provider"`
+	Calls        int     `json:"calls"` // calls within the window
+	Errors       int     `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"` // successful calls only
+	Selected     int64   `json:"selected"`       // times chosen for latency-sensitive calls
+}
+
+// ProviderSelectionStats is the state of latency-aware selection
+type ProviderSelectionStats struct {
+	Enabled    bool                  `json:"enabled"`
+	Candidates []string              `json:"candidates"`
+	Fallbacks  int64                 `json:"fallbacks"`
+	Providers  []ProviderHealthStats `json:"providers"`
+	Selections []PriceSelection      `json:"selections"` // latest selection per symbol
+
+	SymbolProviders map[string]string `json:"symbol_providers"` // per-symbol provider overrides
+}
+
+var health = struct {
+	mu         sync.Mutex
+	samples    map[healthKey][]callSample
+	candidates []string
+	selected   map[string]int64
+	fallbacks  int64
+	last       map[string]PriceSelection
+}{
+	samples:  make(map[healthKey][]callSample),
+	selected: make(map[string]int64),
+	last:     make(map[string]PriceSelection),
+}
+
+// SetFastPriceProviders sets the candidate providers for latency-sensitive calls
+// (empty disables selection; the default provider is always a candidate)
+func SetFastPriceProviders(names []string) error {
+	for _, name := range names {
+		if _, err := GetProvider(name); err != nil {
+			return err
+		}
+	}
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	health.candidates = append([]string(nil), names...)
+	return nil
+}
+
+// recordCall stores the outcome of a provider call
+func recordCall(provider, symbol string, latency time.Duration, err error) {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	key := healthKey{provider, symbol}
+	samples := append(health.samples[key], callSample{at: time.Now(), latency: latency, ok: err == nil})
+	if len(samples) > latencyWindow {
+		samples = samples[len(samples)-latencyWindow:]
+	}
+	health.samples[key] = samples
+}
+
+// windowStats returns call count, error count and average successful latency of recent samples
+func windowStats(samples []callSample, now time.Time) (calls, errors int, avg time.Duration) {
+	var total time.Duration
+	for _, s := range samples {
+		if now.Sub(s.at) > sampleMaxAge {
+			continue
+		}
+		calls++
+		if !s.ok {
+			errors++
+			continue
+		}
+		total += s.latency
+	}
+	if ok := calls - errors; ok > 0 {
+		avg = total / time.Duration(ok)
+	}
+	return calls, errors, avg
+}
+
+// selectPriceProvider chooses the provider for a latency-sensitive call on symbol
+func selectPriceProvider(symbol string) (MarketDataProvider, string, error) {
+	defaultProvider, err := ProviderFor(symbol) // 币种指定的数据源作为该币种的默认候选
+	if err != nil {
+		return nil, "", err
+	}
+
+	health.mu.Lock()
+	defer health.mu.Unlock()
+
+	names := append([]string{defaultProvider.GetName()}, health.candidates...)
+	now := time.Now()
+	var best MarketDataProvider
+	var bestLatency time.Duration
+	reason := SelectionFallback
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		provider, err := GetProvider(name)
+		if err != nil {
+			continue
+		}
+		calls, errors, avg := windowStats(health.samples[healthKey{name, symbol}], now)
+		if calls == 0 {
+			best, reason = provider, SelectionProbe
+			break
+		}
+		if float64(errors)/float64(calls) > maxHealthyErrorRate {
+			continue
+		}
+		if best == nil || avg < bestLatency {
+			best, bestLatency, reason = provider, avg, SelectionFastest
+		}
+	}
+	if best == nil {
+		best = defaultProvider
+		health.fallbacks++
+	}
+
+	health.selected[best.GetName()]++
+	health.last[symbol] = PriceSelection{Symbol: symbol, Provider: best.GetName(), Reason: reason, Time: now}
+	return best, reason, nil
+}
+
+// fastPriceEnabled reports whether candidates are configured
+func fastPriceEnabled() bool {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	return len(health.candidates) > 0
+}
+
+// LatestBar returns the most recent (possibly still forming) bar from the fastest healthy
+// provider for the symbol, falling back to the default provider if that call fails
+func LatestBar(ctx context.Context, symbol, interval string) (Kline, string, error) {
+	ctx, span := tracing.Start(ctx, "market.latest_bar")
+	defer span.End()
+	span.SetAttr("symbol", symbol)
+
+	provider, reason, err := selectPriceProvider(symbol)
+	if err != nil {
+		span.RecordError(err)
+		return Kline{}, "", err
+	}
+	span.SetAttr("selected_provider", provider.GetName())
+	span.SetAttr("selection_reason", reason)
+
+	bar, err := latestBarFrom(ctx, provider, symbol, interval)
+	if err != nil && reason != SelectionFallback {
+		defaultProvider, defErr := ProviderFor(symbol)
+		if defErr == nil && defaultProvider.GetName() != provider.GetName() {
+			span.SetAttr("fallback_provider", defaultProvider.GetName())
+			provider = defaultProvider
+			bar, err = latestBarFrom(ctx, provider, symbol, interval)
+		}
+	}
+	span.RecordError(err)
+	return bar, provider.GetName(), err
+}
+
+// CurrentPrice returns the close of the latest 1m bar from the fastest healthy provider
+func CurrentPrice(ctx context.Context, symbol string) (float64, error) {
+	bar, _, err := LatestBar(ctx, symbol, "1m")
+	if err != nil {
+		return 0, err
+	}
+	return bar.Close, nil
+}
+
+// latestBarFrom fetches the last bar from one provider
+func latestBarFrom(ctx context.Context, provider MarketDataProvider, symbol, interval string) (Kline, error) {
+	klines, err := tracedKlines(ctx, provider, symbol, interval, 1)
+	if err != nil {
+		return Kline{}, err
+	}
+	if len(klines) == 0 {
+		return Kline{}, fmt.Errorf("%s returned no %s klines for %s", provider.GetName(), interval, symbol)
+	}
+	return klines[len(klines)-1], nil
+}
+
+// ProviderSelection returns latency/error metrics per provider and the latest selections
+func ProviderSelection() ProviderSelectionStats {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+
+	stats := ProviderSelectionStats{
+		Enabled:    len(health.candidates) > 0,
+		Candidates: append([]string{}, health.candidates...),
+		Fallbacks:  health.fallbacks,
+		Providers:  []ProviderHealthStats{},
+		Selections: []PriceSelection{},
+
+		SymbolProviders: SymbolProviders(),
+	}
+
+	now := time.Now()
+	byProvider := make(map[string][]callSample)
+	for key, samples := range health.samples {
+		byProvider[key.provider] = append(byProvider[key.provider], samples...)
+	}
+	for name := range health.selected {
+		if _, ok := byProvider[name]; !ok {
+			byProvider[name] = nil
+		}
+	}
+	for name, samples := range byProvider {
+		calls, errors, avg := windowStats(samples, now)
+		s := ProviderHealthStats{
+			Provider:     name,
+			Calls:        calls,
+			Errors:       errors,
+			AvgLatencyMs: float64(avg) / float64(time.Millisecond),
+			Selected:     health.selected[name],
+		}
+		if calls > 0 {
+			s.ErrorRate = float64(errors) / float64(calls)
+		}
+		stats.Providers = append(stats.Providers, s)
+	}
+	sort.Slice(stats.Providers, func(i, j int) bool { return stats.Providers[i].Provider < stats.Providers[j].Provider })
+
+	for _, sel := range health.last {
+		stats.Selections = append(stats.Selections, sel)
+	}
+	sort.Slice(stats.Selections, func(i, j int) bool { return stats.Selections[i].Symbol < stats.Selections[j].Symbol })
+	return stats
+}
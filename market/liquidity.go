@@ -0,0 +1,246 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"nofx/cache"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hourly liquidity profile (altcoins)
+// From LookbackDays of 1h klines, the average notional volume (volume × close) and an estimated bid-ask
+// spread (Corwin-Schultz high-low estimator over consecutive bars) are collected per UTC hour of day.
+// An hour is illiquid when its average volume is below VolumeRatio × the median hour, or its spread is
+// above SpreadRatio × the median spread. The prompt shows the current hour against the median and the
+// list of illiquid hours, with a warning when the current hour is one of them; the trader reduces the
+// size of entries made in those hours. BTC and ETH are skipped. Profiles change slowly, so each symbol's
+// profile is cached for liquidityRefresh.
+
+const (
+	liquidityInterval   = "1h"
+	liquidityRefresh    = 6 * time.Hour
+	liquidityMinSamples = 3 // hours with fewer bars are never flagged
+	maxLiquidityDays    = 60
+)
+
+// LiquidityConfig settings of the hourly liquidity profile
+type LiquidityConfig struct {
+	Enabled      bool
+	LookbackDays int     // days of 1h klines (default 14, at most 60)
+	VolumeRatio  float64 // illiquid when the hour's volume < VolumeRatio × median (default 0.5)
+	SpreadRatio  float64 // illiquid when the hour's spread > SpreadRatio × median (<= 0 = volume only)
+}
+
+// HourLiquidity average liquidity of one UTC hour of day
+type HourLiquidity struct {
+	Hour      int     // UTC hour of day (0-23)
+	AvgVolume float64 // average notional volume of the hour's bars (quote asset)
+	SpreadPct float64 // average estimated bid-ask spread in percent
+	Samples   int     // number of bars
+	Illiquid  bool
+}
+
+// LiquidityProfile per-hour liquidity of a symbol
+type LiquidityProfile struct {
+	Hours           [24]HourLiquidity
+	MedianVolume    float64
+	MedianSpreadPct float64
+	LookbackDays    int
+	CurrentHour     int // UTC hour of the fetch
+}
+
+// Current liquidity of the hour of the fetch
+func (p *LiquidityProfile) Current() HourLiquidity {
+	return p.Hours[p.CurrentHour]
+}
+
+// IlliquidNow reports whether the fetch happened in one of the symbol's illiquid hours
+func (p *LiquidityProfile) IlliquidNow() bool {
+	return p.Hours[p.CurrentHour].Illiquid
+}
+
+// IlliquidHours the illiquid UTC hours in order
+func (p *LiquidityProfile) IlliquidHours() []int {
+	var hours []int
+	for _, h := range p.Hours {
+		if h.Illiquid {
+			hours = append(hours, h.Hour)
+		}
+	}
+	return hours
+}
+
+var liquidity = struct {
+	mu       sync.Mutex
+	cfg      LiquidityConfig
+	profiles *cache.Cache[string, LiquidityProfile]
+}{
+	profiles: cache.New[string, LiquidityProfile]("market.liquidity", liquidityRefresh),
+}
+
+// SetLiquidityConfig sets the hourly liquidity profile settings (one extra kline request per symbol every 6 hours)
+func SetLiquidityConfig(cfg LiquidityConfig) {
+	if cfg.LookbackDays <= 0 {
+		cfg.LookbackDays = 14
+	}
+	if cfg.LookbackDays > maxLiquidityDays {
+		cfg.LookbackDays = maxLiquidityDays
+	}
+	if cfg.VolumeRatio <= 0 {
+		cfg.VolumeRatio = 0.5
+	}
+	if cfg.SpreadRatio < 0 {
+		cfg.SpreadRatio = 0
+	}
+	liquidity.mu.Lock()
+	defer liquidity.mu.Unlock()
+	liquidity.cfg = cfg
+	liquidity.profiles.Clear()
+}
+
+func liquidityConfig() LiquidityConfig {
+	liquidity.mu.Lock()
+	defer liquidity.mu.Unlock()
+	return liquidity.cfg
+}
+
+// isMajor BTC and ETH perpetuals (deep books around the clock)
+func isMajor(symbol string) bool {
+	for _, base := range []string{"BTC", "ETH"} {
+		for _, quote := range []string{"USDT", "USDC", "USD"} {
+			if strings.EqualFold(symbol, base+quote) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fetchLiquidity returns the symbol's liquidity profile at the current hour, or nil when disabled or for BTC/ETH
+func fetchLiquidity(ctx context.Context, provider MarketDataProvider, symbol string) (*LiquidityProfile, error) {
+	cfg := liquidityConfig()
+	if !cfg.Enabled || isMajor(symbol) {
+		return nil, nil
+	}
+	profile, err := liquidity.profiles.GetOrLoad(symbol, func() (LiquidityProfile, error) {
+		klines, err := tracedKlines(ctx, provider, symbol, liquidityInterval, cfg.LookbackDays*24)
+		if err != nil {
+			return LiquidityProfile{}, err
+		}
+		profile, err := buildLiquidityProfile(klines, cfg)
+		if err != nil {
+			return LiquidityProfile{}, err
+		}
+		return *profile, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	profile.CurrentHour = time.Now().UTC().Hour()
+	return &profile, nil
+}
+
+// buildLiquidityProfile aggregates 1h klines by UTC hour and flags the illiquid hours
+func buildLiquidityProfile(klines []Kline, cfg LiquidityConfig) (*LiquidityProfile, error) {
+	if len(klines) < 48 {
+		return nil, fmt.Errorf("not enough 1h klines for the liquidity profile: %d", len(klines))
+	}
+	profile := &LiquidityProfile{LookbackDays: (len(klines) + 23) / 24}
+	var volumeSum, spreadSum [24]float64
+	var spreadSamples [24]int
+	for i, k := range klines {
+		hour := time.UnixMilli(k.OpenTime).UTC().Hour()
+		volumeSum[hour] += k.Volume * k.Close
+		profile.Hours[hour].Samples++
+		if i > 0 {
+			if spread, ok := highLowSpread(klines[i-1], k); ok {
+				spreadSum[hour] += spread
+				spreadSamples[hour]++
+			}
+		}
+	}
+
+	var volumes, spreads []float64
+	for hour := range profile.Hours {
+		h := &profile.Hours[hour]
+		h.Hour = hour
+		if h.Samples > 0 {
+			h.AvgVolume = volumeSum[hour] / float64(h.Samples)
+			volumes = append(volumes, h.AvgVolume)
+		}
+		if spreadSamples[hour] > 0 {
+			h.SpreadPct = spreadSum[hour] / float64(spreadSamples[hour]) * 100
+			spreads = append(spreads, h.SpreadPct)
+		}
+	}
+	profile.MedianVolume = median(volumes)
+	profile.MedianSpreadPct = median(spreads)
+
+	for hour := range profile.Hours {
+		h := &profile.Hours[hour]
+		if h.Samples < liquidityMinSamples {
+			continue
+		}
+		thinVolume := profile.MedianVolume > 0 && h.AvgVolume < cfg.VolumeRatio*profile.MedianVolume
+		wideSpread := cfg.SpreadRatio > 0 && profile.MedianSpreadPct > 0 && h.SpreadPct > cfg.SpreadRatio*profile.MedianSpreadPct
+		h.Illiquid = thinVolume || wideSpread
+	}
+	return profile, nil
+}
+
+// highLowSpread Corwin-Schultz bid-ask spread estimate (fraction of price) from two consecutive bars;
+// negative estimates are clamped to 0
+func highLowSpread(prev, cur Kline) (float64, bool) {
+	if prev.Low <= 0 || cur.Low <= 0 || prev.High < prev.Low || cur.High < cur.Low {
+		return 0, false
+	}
+	beta := math.Pow(math.Log(prev.High/prev.Low), 2) + math.Pow(math.Log(cur.High/cur.Low), 2)
+	gamma := math.Pow(math.Log(math.Max(prev.High, cur.High)/math.Min(prev.Low, cur.Low)), 2)
+	k := 3 - 2*math.Sqrt2
+	alpha := (math.Sqrt(2*beta)-math.Sqrt(beta))/k - math.Sqrt(gamma/k)
+	spread := 2 * (math.Exp(alpha) - 1) / (1 + math.Exp(alpha))
+	if spread < 0 || math.IsNaN(spread) {
+		spread = 0
+	}
+	return spread, true
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// formatLiquidity the prompt lines for the current hour and the illiquid hours
+func formatLiquidity(p *LiquidityProfile) string {
+	cur := p.Current()
+	volumePct := 0.0
+	if p.MedianVolume > 0 {
+		volumePct = cur.AvgVolume / p.MedianVolume * 100
+	}
+	line := fmt.Sprintf("Hourly liquidity (last %d days, UTC): this hour (%02d:00) averages %.0f%% of the median hour's volume, est. spread %.3f%% (median %.3f%%)",
+		p.LookbackDays, cur.Hour, volumePct, cur.SpreadPct, p.MedianSpreadPct)
+	if hours := p.IlliquidHours(); len(hours) > 0 {
+		labels := make([]string, len(hours))
+		for i, h := range hours {
+			labels[i] = fmt.Sprintf("%02d", h)
+		}
+		line += fmt.Sprintf("; illiquid hours: %s", strings.Join(labels, ", "))
+	}
+	line += "\n\n"
+	if cur.Illiquid {
+		line += "⚠️ Entering now is during this symbol's historically illiquid hours: expect wider spreads, more slippage and easier stop hunts; new entries are size-reduced by the risk engine.\n\n"
+	}
+	return line
+}
@@ -2,7 +2,11 @@ package market
 
 import (
 	"fmt"
+	"net/http"
+	"nofx/ratelimit"
+	"sort"
 	"sync"
+	"time"
 )
 
 // MarketDataProvider defines the interface for fetching market data from different exchanges
@@ -23,6 +27,12 @@ type MarketDataProvider interface {
 	GetName() string
 }
 
+// KlineRangeProvider is implemented by providers that can fetch klines for a historical time window
+type KlineRangeProvider interface {
+	// GetKlinesRange fetches candlesticks whose open time falls within [start, end]
+	GetKlinesRange(symbol, interval string, start, end time.Time) ([]Kline, error)
+}
+
 // ProviderRegistry manages available market data providers
 type ProviderRegistry struct {
 	providers map[string]MarketDataProvider
@@ -77,6 +87,7 @@ func SetDefaultProviderName(name string) error {
 		return fmt.Errorf("cannot set default provider: %w", err)
 	}
 	defaultProviderName = name
+	fxValues.Clear() // rates came from the previous provider
 	return nil
 }
 
@@ -121,3 +132,15 @@ func InitializeProviders() {
 	SetDefaultProviderName("binance")
 }
 
+
+// rateLimitedGet sends a GET request through the exchange's shared rate limit scheduler,
+// so market data never starves order placement of request budget
+func rateLimitedGet(exchange, apiURL string) (*http.Response, error) {
+	client := &http.Client{Transport: ratelimit.NewTransport(ratelimit.Get(exchange), nil)}
+	return client.Get(apiURL)
+}
+
+// sortKlinesAscending orders klines oldest first (some exchanges return the newest candle first)
+func sortKlinesAscending(klines []Kline) {
+	sort.SliceStable(klines, func(i, j int) bool { return klines[i].OpenTime < klines[j].OpenTime })
+}
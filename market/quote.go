@@ -0,0 +1,71 @@
+package market
+
+import "strings"
+
+// DefaultQuote is the quote asset assumed when a symbol carries none
+const DefaultQuote = "USDT"
+
+// QuoteAssets lists the quote assets recognized in symbols such as BTCUSDC or ETHEUR.
+// Longer suffixes come first so that FDUSD/BUSD are not mistaken for other quotes.
+var QuoteAssets = []string{"FDUSD", "USDT", "USDC", "BUSD", "EUR"}
+
+// stableQuotes are quote assets treated as 1 USD when summing balances and sizing positions
+var stableQuotes = map[string]bool{"USDT": true, "USDC": true, "FDUSD": true, "BUSD": true}
+
+// SplitSymbol splits a symbol into base and quote asset (BTCUSDC -> BTC, USDC).
+// Exchange formats (BTC_USDT, BTC-USDT-SWAP) are accepted; quote is empty when the symbol
+// has no recognized quote suffix.
+func SplitSymbol(symbol string) (base, quote string) {
+	symbol = strings.TrimSuffix(strings.ToUpper(symbol), "-SWAP")
+	symbol = strings.NewReplacer("_", "", "-", "", "/", "").Replace(symbol)
+	for _, q := range QuoteAssets {
+		if strings.HasSuffix(symbol, q) && len(symbol) > len(q) {
+			return symbol[:len(symbol)-len(q)], q
+		}
+	}
+	return symbol, ""
+}
+
+// BaseAsset returns the base asset of a symbol (ETHUSDC -> ETH)
+func BaseAsset(symbol string) string {
+	base, _ := SplitSymbol(symbol)
+	return base
+}
+
+// QuoteOf returns the quote asset of a symbol, DefaultQuote when none is recognized
+func QuoteOf(symbol string) string {
+	if _, quote := SplitSymbol(symbol); quote != "" {
+		return quote
+	}
+	return DefaultQuote
+}
+
+// WithQuote rewrites a symbol to the given quote asset (ETHUSDT, USDC -> ETHUSDC)
+func WithQuote(symbol, quote string) string {
+	if quote == "" {
+		quote = DefaultQuote
+	}
+	return BaseAsset(symbol) + strings.ToUpper(quote)
+}
+
+// IsQuoteAsset reports whether asset is a recognized quote asset
+func IsQuoteAsset(asset string) bool {
+	asset = strings.ToUpper(asset)
+	for _, q := range QuoteAssets {
+		if q == asset {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStableQuote reports whether asset is a USD stablecoin (counted 1:1 as USD)
+func IsStableQuote(asset string) bool {
+	return stableQuotes[strings.ToUpper(asset)]
+}
+
+// IsBTCOrETH reports whether the symbol is a BTC or ETH pair in any quote asset
+func IsBTCOrETH(symbol string) bool {
+	base := BaseAsset(symbol)
+	return base == "BTC" || base == "ETH"
+}
@@ -0,0 +1,118 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Range-bound market detection
+// The choppiness index over the last N 4h bars (from the 4h klines already fetched, no extra request)
+// compares the sum of true ranges with the high-low range of the window:
+// CHOP = 100 × log10(ΣTR / (highest high − lowest low)) / log10(N).
+// Near 100 price keeps reversing inside a box, near 0 it travels in one direction. Above the threshold
+// (default 61.8) the symbol is flagged as ranging: the prompt warns that trend-following signals are
+// unreliable there, and traders can require a higher confidence for trend-following entries.
+
+const (
+	defaultRangePeriod    = 14
+	defaultRangeThreshold = 61.8
+)
+
+// RangeConfig settings of the range detector
+type RangeConfig struct {
+	Enabled   bool
+	Period    int     // 4h bars in the choppiness window (default 14)
+	Threshold float64 // choppiness index above which a symbol is ranging (default 61.8)
+}
+
+// RangeData choppiness of a symbol on the 4h timeframe
+type RangeData struct {
+	Choppiness float64 // choppiness index 0-100
+	Period     int     // 4h bars in the window
+	Ranging    bool    // choppiness above the threshold
+	High       float64 // highest high of the window
+	Low        float64 // lowest low of the window
+}
+
+// Position where the price sits inside the window's range: 0 at the low, 1 at the high
+func (r *RangeData) Position(price float64) float64 {
+	if r.High <= r.Low {
+		return 0.5
+	}
+	return math.Max(0, math.Min(1, (price-r.Low)/(r.High-r.Low)))
+}
+
+var rangeDetector struct {
+	mu  sync.RWMutex
+	cfg RangeConfig
+}
+
+// SetRangeConfig configures the range detector (off by default)
+func SetRangeConfig(cfg RangeConfig) {
+	if cfg.Period < 2 {
+		cfg.Period = defaultRangePeriod
+	}
+	if cfg.Threshold <= 0 || cfg.Threshold >= 100 {
+		cfg.Threshold = defaultRangeThreshold
+	}
+	rangeDetector.mu.Lock()
+	defer rangeDetector.mu.Unlock()
+	rangeDetector.cfg = cfg
+}
+
+func currentRangeConfig() RangeConfig {
+	rangeDetector.mu.RLock()
+	defer rangeDetector.mu.RUnlock()
+	return rangeDetector.cfg
+}
+
+// calculateRange the choppiness of the last bars, or nil when disabled or the history is too short
+func calculateRange(klines4h []Kline) *RangeData {
+	cfg := currentRangeConfig()
+	if !cfg.Enabled {
+		return nil
+	}
+	chop, high, low, ok := choppinessIndex(klines4h, cfg.Period)
+	if !ok {
+		return nil
+	}
+	return &RangeData{
+		Choppiness: chop,
+		Period:     cfg.Period,
+		Ranging:    chop > cfg.Threshold,
+		High:       high,
+		Low:        low,
+	}
+}
+
+// choppinessIndex CHOP over the last period bars (needs one more bar for the first true range)
+func choppinessIndex(klines []Kline, period int) (chop, high, low float64, ok bool) {
+	if period < 2 || len(klines) < period+1 {
+		return 0, 0, 0, false
+	}
+	window := klines[len(klines)-period:]
+	high, low = window[0].High, window[0].Low
+	sumTR := 0.0
+	for i, k := range window {
+		prevClose := klines[len(klines)-period+i-1].Close
+		sumTR += math.Max(k.High-k.Low, math.Max(math.Abs(k.High-prevClose), math.Abs(k.Low-prevClose)))
+		high = math.Max(high, k.High)
+		low = math.Min(low, k.Low)
+	}
+	if high <= low || sumTR <= 0 {
+		return 0, 0, 0, false
+	}
+	return 100 * math.Log10(sumTR/(high-low)) / math.Log10(float64(period)), high, low, true
+}
+
+// formatRange the prompt line for the choppiness and, when ranging, the box and a warning about trend signals
+func formatRange(r *RangeData, price float64) string {
+	line := fmt.Sprintf("Choppiness index (4h, %d bars): %.1f", r.Period, r.Choppiness)
+	if !r.Ranging {
+		return line + " (trending)\n\n"
+	}
+	return line + fmt.Sprintf(" → RANGE-BOUND between %.4f and %.4f (price at %.0f%% of the range). "+
+		"Trend-following signals (EMA/MACD crosses, breakouts) are unreliable here; prefer fading the range edges or waiting for a confirmed breakout.\n\n",
+		r.Low, r.High, r.Position(price)*100)
+}
@@ -0,0 +1,133 @@
+package market
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kline resampling
+// Some exchanges do not serve every interval the strategy uses (Coinbase has no 3m or 4h candles, BitMEX only
+// 1m/5m/1h/1d). Such providers list their native intervals and refuse the others instead of silently returning
+// the closest size. FetchKlines builds a missing interval from the largest native interval that divides it:
+// it fetches enough finer bars, groups them into buckets aligned to UTC multiples of the requested interval
+// (the same boundaries exchanges use) and drops a leading bucket that is only partly covered. The last bucket
+// may still be forming, like the last bar of a native request.
+
+// NativeIntervalProvider is implemented by providers that serve only some kline intervals natively
+type NativeIntervalProvider interface {
+	// NativeIntervals the intervals GetKlines serves directly
+	NativeIntervals() []string
+}
+
+// FetchKlines fetches klines in the requested interval, resampling from a finer native interval when the provider
+// does not serve it directly
+func FetchKlines(provider MarketDataProvider, symbol, interval string, limit int) ([]Kline, error) {
+	np, ok := provider.(NativeIntervalProvider)
+	if !ok || isNativeInterval(np, interval) {
+		return provider.GetKlines(symbol, interval, limit)
+	}
+	source, factor, err := resampleSource(np.NativeIntervals(), interval)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", provider.GetName(), err)
+	}
+	klines, err := provider.GetKlines(symbol, source, limit*factor+factor-1)
+	if err != nil {
+		return nil, err
+	}
+	sourceStep, _ := IntervalDuration(source)
+	resampled := aggregateKlines(klines, sourceStep, sourceStep*time.Duration(factor))
+	if len(resampled) > limit {
+		resampled = resampled[len(resampled)-limit:]
+	}
+	return resampled, nil
+}
+
+// ResampledIntervals the conformance intervals a provider serves by resampling
+func ResampledIntervals(provider MarketDataProvider) []string {
+	np, ok := provider.(NativeIntervalProvider)
+	if !ok {
+		return nil
+	}
+	var resampled []string
+	for _, interval := range ConformanceIntervals {
+		if isNativeInterval(np, interval) {
+			continue
+		}
+		if _, _, err := resampleSource(np.NativeIntervals(), interval); err == nil {
+			resampled = append(resampled, interval)
+		}
+	}
+	return resampled
+}
+
+func isNativeInterval(np NativeIntervalProvider, interval string) bool {
+	for _, native := range np.NativeIntervals() {
+		if native == interval {
+			return true
+		}
+	}
+	return false
+}
+
+// unsupportedInterval the error providers return for intervals they do not serve natively
+func unsupportedInterval(provider string, interval string) error {
+	return fmt.Errorf("%s has no native %s klines (use FetchKlines to resample)", provider, interval)
+}
+
+// resampleSource the largest native interval that divides the target, and how many of its bars make one target bar
+func resampleSource(native []string, interval string) (string, int, error) {
+	target, err := IntervalDuration(interval)
+	if err != nil {
+		return "", 0, err
+	}
+	best, bestStep := "", time.Duration(0)
+	for _, n := range native {
+		step, err := IntervalDuration(n)
+		if err != nil || step >= target || target%step != 0 {
+			continue
+		}
+		if step > bestStep {
+			best, bestStep = n, step
+		}
+	}
+	if best == "" {
+		return "", 0, fmt.Errorf("no native interval to build %s klines from", interval)
+	}
+	return best, int(target / bestStep), nil
+}
+
+// aggregateKlines groups ascending source bars into target-sized bars aligned to UTC multiples of the target
+func aggregateKlines(klines []Kline, source, target time.Duration) []Kline {
+	sourceMs, targetMs := source.Milliseconds(), target.Milliseconds()
+	// the leading bucket is dropped when the first source bar does not start it
+	partial := int64(-1)
+	if len(klines) > 0 && klines[0].OpenTime%targetMs != 0 {
+		partial = klines[0].OpenTime - klines[0].OpenTime%targetMs
+	}
+	var result []Kline
+	for _, k := range klines {
+		start := k.OpenTime - k.OpenTime%targetMs
+		if start == partial {
+			continue
+		}
+		if n := len(result); n > 0 && result[n-1].OpenTime == start {
+			bar := &result[n-1]
+			bar.High = max(bar.High, k.High)
+			bar.Low = min(bar.Low, k.Low)
+			bar.Close = k.Close
+			bar.Volume += k.Volume
+			continue
+		}
+		result = append(result, Kline{
+			OpenTime: start,
+			Open:     k.Open,
+			High:     k.High,
+			Low:      k.Low,
+			Close:    k.Close,
+			Volume:   k.Volume,
+			// keep the provider's close time convention (end of bar or one millisecond before)
+			CloseTime: start + targetMs + (k.CloseTime - k.OpenTime - sourceMs),
+		})
+	}
+	return result
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"nofx/errs"
 	"strconv"
 	"strings"
 	"time"
@@ -38,13 +39,12 @@ func (p *OKXProvider) GetName() string {
 func (p *OKXProvider) NormalizeSymbol(symbol string) string {
 	symbol = strings.ToUpper(symbol)
 	symbol = strings.ReplaceAll(symbol, "_", "-")
-	if !strings.Contains(symbol, "-") && strings.HasSuffix(symbol, "USDT") {
-		base := symbol[:len(symbol)-4]
-		return base + "-USDT-SWAP" // OKX perpetual futures use -SWAP suffix
+	if strings.HasSuffix(symbol, "-SWAP") {
+		return symbol
 	}
-	// If already has -USDT, convert to -USDT-SWAP for futures
-	if strings.HasSuffix(symbol, "-USDT") && !strings.HasSuffix(symbol, "-SWAP") {
-		return symbol + "-SWAP"
+	// OKX perpetual futures use BASE-QUOTE-SWAP (USDT and USDC margined)
+	if base, quote := SplitSymbol(symbol); quote != "" {
+		return base + "-" + quote + "-SWAP"
 	}
 	return symbol
 }
@@ -73,15 +73,15 @@ func (p *OKXProvider) GetKlines(symbol, interval string, limit int) ([]Kline, er
 	apiURL := fmt.Sprintf("%s/market/candles?instId=%s&bar=%s&limit=%d",
 		p.baseURL, url.QueryEscape(symbol), interval, limit)
 
-	resp, err := http.Get(apiURL)
+	resp, err := rateLimitedGet("okx", apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("okx klines request failed: %w", err)
+		return nil, fmt.Errorf("okx klines request failed: %w", errs.Network("okx", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("okx klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("okx klines API error: %w", errs.FromResponse("okx", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -109,12 +109,16 @@ func (p *OKXProvider) GetKlines(symbol, interval string, limit int) ([]Kline, er
 			continue
 		}
 		// OKX format: [timestamp, open, high, low, close, volume, volumeCurrency, ...]
+		// volume is in contracts for SWAP instruments; volumeCurrency is in the base asset
 		openTime, _ := strconv.ParseInt(item[0], 10, 64)
 		open, _ := strconv.ParseFloat(item[1], 64)
 		high, _ := strconv.ParseFloat(item[2], 64)
 		low, _ := strconv.ParseFloat(item[3], 64)
 		close, _ := strconv.ParseFloat(item[4], 64)
 		volume, _ := strconv.ParseFloat(item[5], 64)
+		if len(item) > 6 {
+			volume, _ = strconv.ParseFloat(item[6], 64)
+		}
 
 		// Calculate close time (interval in milliseconds)
 		intervalSeconds := getOKXIntervalSeconds(interval)
@@ -131,6 +135,8 @@ func (p *OKXProvider) GetKlines(symbol, interval string, limit int) ([]Kline, er
 		}
 	}
 
+	// OKX returns the newest candle first
+	sortKlinesAscending(klines)
 	return klines, nil
 }
 
@@ -138,15 +144,15 @@ func (p *OKXProvider) GetOpenInterest(symbol string) (*OIData, error) {
 	symbol = p.NormalizeSymbol(symbol)
 	apiURL := fmt.Sprintf("%s/public/open-interest?instId=%s", p.baseURL, url.QueryEscape(symbol))
 
-	resp, err := http.Get(apiURL)
+	resp, err := rateLimitedGet("okx", apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("okx open interest request failed: %w", err)
+		return nil, fmt.Errorf("okx open interest request failed: %w", errs.Network("okx", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("okx open interest API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("okx open interest API error: %w", errs.FromResponse("okx", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -173,7 +179,11 @@ func (p *OKXProvider) GetOpenInterest(symbol string) (*OIData, error) {
 		return nil, fmt.Errorf("okx API error: %s", result.Msg)
 	}
 
-	oi, _ := strconv.ParseFloat(result.Data[0].Oi, 64)
+	// oi is in contracts; oiCcy is in the base asset like the other providers
+	oi, _ := strconv.ParseFloat(result.Data[0].OiCcy, 64)
+	if oi == 0 {
+		oi, _ = strconv.ParseFloat(result.Data[0].Oi, 64)
+	}
 
 	return &OIData{
 		Latest:  oi,
@@ -185,15 +195,15 @@ func (p *OKXProvider) GetFundingRate(symbol string) (float64, error) {
 	symbol = p.NormalizeSymbol(symbol)
 	apiURL := fmt.Sprintf("%s/public/funding-rate?instId=%s", p.baseURL, url.QueryEscape(symbol))
 
-	resp, err := http.Get(apiURL)
+	resp, err := rateLimitedGet("okx", apiURL)
 	if err != nil {
-		return 0, fmt.Errorf("okx funding rate request failed: %w", err)
+		return 0, fmt.Errorf("okx funding rate request failed: %w", errs.Network("okx", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return 0, fmt.Errorf("okx funding rate API error (status %d): %s", resp.StatusCode, string(body))
+		return 0, fmt.Errorf("okx funding rate API error: %w", errs.FromResponse("okx", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -289,13 +299,13 @@ func (p *BybitProvider) GetKlines(symbol, interval string, limit int) ([]Kline,
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("bybit klines request failed: %w", err)
+		return nil, fmt.Errorf("bybit klines request failed: %w", errs.Network("bybit", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("bybit klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("bybit klines API error: %w", errs.FromResponse("bybit", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -348,6 +358,8 @@ func (p *BybitProvider) GetKlines(symbol, interval string, limit int) ([]Kline,
 		}
 	}
 
+	// Bybit returns the newest candle first
+	sortKlinesAscending(klines)
 	return klines, nil
 }
 
@@ -357,13 +369,13 @@ func (p *BybitProvider) GetOpenInterest(symbol string) (*OIData, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("bybit open interest request failed: %w", err)
+		return nil, fmt.Errorf("bybit open interest request failed: %w", errs.Network("bybit", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("bybit open interest API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("bybit open interest API error: %w", errs.FromResponse("bybit", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -404,13 +416,13 @@ func (p *BybitProvider) GetFundingRate(symbol string) (float64, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return 0, fmt.Errorf("bybit funding rate request failed: %w", err)
+		return 0, fmt.Errorf("bybit funding rate request failed: %w", errs.Network("bybit", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return 0, fmt.Errorf("bybit funding rate API error (status %d): %s", resp.StatusCode, string(body))
+		return 0, fmt.Errorf("bybit funding rate API error: %w", errs.FromResponse("bybit", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -498,7 +510,15 @@ func (p *HuobiProvider) convertInterval(interval string) string {
 	return "3min" // Default
 }
 
+// NativeIntervals intervals Huobi serves directly: 1min, 5min, 15min, 30min, 60min, 4hour, 1day (no 3min)
+func (p *HuobiProvider) NativeIntervals() []string {
+	return []string{"1m", "5m", "15m", "30m", "1h", "4h", "1d"}
+}
+
 func (p *HuobiProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	if !isNativeInterval(p, interval) {
+		return nil, unsupportedInterval("huobi", interval)
+	}
 	symbol = p.NormalizeSymbol(symbol)
 	interval = p.convertInterval(interval)
 	
@@ -507,13 +527,13 @@ func (p *HuobiProvider) GetKlines(symbol, interval string, limit int) ([]Kline,
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("huobi klines request failed: %w", err)
+		return nil, fmt.Errorf("huobi klines request failed: %w", errs.Network("huobi", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("huobi klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("huobi klines API error: %w", errs.FromResponse("huobi", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -580,7 +600,7 @@ func (p *HuobiProvider) GetOpenInterest(symbol string) (*OIData, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("huobi open interest request failed: %w", err)
+		return nil, fmt.Errorf("huobi open interest request failed: %w", errs.Network("huobi", err))
 	}
 	defer resp.Body.Close()
 
@@ -590,7 +610,7 @@ func (p *HuobiProvider) GetOpenInterest(symbol string) (*OIData, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("huobi open interest API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("huobi open interest API error: %w", errs.FromResponse("huobi", resp, body))
 	}
 
 	var result struct {
@@ -637,7 +657,7 @@ func (p *HuobiProvider) GetFundingRate(symbol string) (float64, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return 0, fmt.Errorf("huobi funding rate request failed: %w", err)
+		return 0, fmt.Errorf("huobi funding rate request failed: %w", errs.Network("huobi", err))
 	}
 	defer resp.Body.Close()
 
@@ -647,7 +667,7 @@ func (p *HuobiProvider) GetFundingRate(symbol string) (float64, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("huobi funding rate API error (status %d): %s", resp.StatusCode, string(body))
+		return 0, fmt.Errorf("huobi funding rate API error: %w", errs.FromResponse("huobi", resp, body))
 	}
 
 	var result struct {
@@ -753,13 +773,13 @@ func (p *KuCoinProvider) GetKlines(symbol, interval string, limit int) ([]Kline,
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("kucoin klines request failed: %w", err)
+		return nil, fmt.Errorf("kucoin klines request failed: %w", errs.Network("kucoin", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("kucoin klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("kucoin klines API error: %w", errs.FromResponse("kucoin", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -829,7 +849,7 @@ func (p *KuCoinProvider) GetOpenInterest(symbol string) (*OIData, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("kucoin open interest request failed: %w", err)
+		return nil, fmt.Errorf("kucoin open interest request failed: %w", errs.Network("kucoin", err))
 	}
 	defer resp.Body.Close()
 
@@ -839,7 +859,7 @@ func (p *KuCoinProvider) GetOpenInterest(symbol string) (*OIData, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("kucoin open interest API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("kucoin open interest API error: %w", errs.FromResponse("kucoin", resp, body))
 	}
 
 	var result struct {
@@ -872,7 +892,7 @@ func (p *KuCoinProvider) GetFundingRate(symbol string) (float64, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return 0, fmt.Errorf("kucoin funding rate request failed: %w", err)
+		return 0, fmt.Errorf("kucoin funding rate request failed: %w", errs.Network("kucoin", err))
 	}
 	defer resp.Body.Close()
 
@@ -882,7 +902,7 @@ func (p *KuCoinProvider) GetFundingRate(symbol string) (float64, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("kucoin funding rate API error (status %d): %s", resp.StatusCode, string(body))
+		return 0, fmt.Errorf("kucoin funding rate API error: %w", errs.FromResponse("kucoin", resp, body))
 	}
 
 	var result struct {
@@ -961,7 +981,15 @@ func (p *BitfinexProvider) convertInterval(interval string) string {
 	return "3m" // Default
 }
 
+// NativeIntervals intervals Bitfinex serves directly: 1m, 5m, 15m, 30m, 1h, 3h, 6h, 12h, 1D (no 3m or 4h)
+func (p *BitfinexProvider) NativeIntervals() []string {
+	return []string{"1m", "5m", "15m", "30m", "1h", "1d"}
+}
+
 func (p *BitfinexProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	if !isNativeInterval(p, interval) {
+		return nil, unsupportedInterval("bitfinex", interval)
+	}
 	symbol = p.NormalizeSymbol(symbol)
 	interval = p.convertInterval(interval)
 	
@@ -971,13 +999,13 @@ func (p *BitfinexProvider) GetKlines(symbol, interval string, limit int) ([]Klin
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("bitfinex klines request failed: %w", err)
+		return nil, fmt.Errorf("bitfinex klines request failed: %w", errs.Network("bitfinex", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("bitfinex klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("bitfinex klines API error: %w", errs.FromResponse("bitfinex", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -1086,15 +1114,12 @@ func (p *CoinbaseProvider) NormalizeSymbol(symbol string) string {
 
 func (p *CoinbaseProvider) convertInterval(interval string) string {
 	// Coinbase public API granularity (in seconds): 60, 300, 900, 3600, 21600, 86400
-	// Map to closest supported granularity
+	// Other intervals are resampled by FetchKlines
 	intervalMap := map[string]int64{
-		"1m":  60,   // 1 minute -> 60 seconds
-		"3m":  300,  // 3 minutes -> use 5 minutes (300 seconds) as closest
-		"5m":  300,  // 5 minutes -> 300 seconds
-		"15m": 900,  // 15 minutes -> 900 seconds
-		"30m": 900,  // 30 minutes -> use 15 minutes (900 seconds) as closest
-		"1h":  3600, // 1 hour -> 3600 seconds
-		"4h":  21600, // 4 hours -> use 6 hours (21600 seconds) as closest
+		"1m":  60,    // 1 minute -> 60 seconds
+		"5m":  300,   // 5 minutes -> 300 seconds
+		"15m": 900,   // 15 minutes -> 900 seconds
+		"1h":  3600,  // 1 hour -> 3600 seconds
 		"1d":  86400, // 1 day -> 86400 seconds
 	}
 	if seconds, ok := intervalMap[interval]; ok {
@@ -1103,7 +1128,15 @@ func (p *CoinbaseProvider) convertInterval(interval string) string {
 	return "300" // Default to 5 minutes
 }
 
+// NativeIntervals intervals Coinbase serves directly: 60, 300, 900, 3600, 21600, 86400 seconds (no 3m, 30m or 4h)
+func (p *CoinbaseProvider) NativeIntervals() []string {
+	return []string{"1m", "5m", "15m", "1h", "1d"}
+}
+
 func (p *CoinbaseProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	if !isNativeInterval(p, interval) {
+		return nil, unsupportedInterval("coinbase", interval)
+	}
 	symbol = p.NormalizeSymbol(symbol)
 	granularityStr := p.convertInterval(interval) // Returns granularity in seconds as string
 	
@@ -1113,13 +1146,13 @@ func (p *CoinbaseProvider) GetKlines(symbol, interval string, limit int) ([]Klin
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("coinbase klines request failed: %w", err)
+		return nil, fmt.Errorf("coinbase klines request failed: %w", errs.Network("coinbase", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("coinbase klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("coinbase klines API error: %w", errs.FromResponse("coinbase", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -1133,17 +1166,8 @@ func (p *CoinbaseProvider) GetKlines(symbol, interval string, limit int) ([]Klin
 		return nil, fmt.Errorf("coinbase klines parse failed: %w", err)
 	}
 
-	// Take only the requested limit (most recent)
-	startIdx := 0
-	if len(rawData) > limit {
-		startIdx = len(rawData) - limit
-	}
-	recentData := rawData[startIdx:]
-
-	klines := make([]Kline, 0, len(recentData))
-	// Process in reverse to get most recent first (Coinbase returns oldest first)
-	for i := len(recentData) - 1; i >= 0; i-- {
-		item := recentData[i]
+	klines := make([]Kline, 0, len(rawData))
+	for _, item := range rawData {
 		if len(item) < 6 {
 			continue
 		}
@@ -1170,6 +1194,11 @@ func (p *CoinbaseProvider) GetKlines(symbol, interval string, limit int) ([]Klin
 		})
 	}
 
+	// Coinbase returns the newest candle first and ignores limit: keep the most recent ones, oldest first
+	sortKlinesAscending(klines)
+	if len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
 	return klines, nil
 }
 
@@ -1234,13 +1263,13 @@ func (p *BinanceUSProvider) GetKlines(symbol, interval string, limit int) ([]Kli
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("binance_us klines request failed: %w", err)
+		return nil, fmt.Errorf("binance_us klines request failed: %w", errs.Network("binance_us", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("binance_us klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("binance_us klines API error: %w", errs.FromResponse("binance_us", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -1331,13 +1360,13 @@ func (p *BitstampProvider) GetKlines(symbol, interval string, limit int) ([]Klin
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("bitstamp klines request failed: %w", err)
+		return nil, fmt.Errorf("bitstamp klines request failed: %w", errs.Network("bitstamp", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("bitstamp klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("bitstamp klines API error: %w", errs.FromResponse("bitstamp", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -1436,7 +1465,15 @@ func (p *BitmexProvider) convertInterval(interval string) string {
 	return "5m"
 }
 
+// NativeIntervals intervals BitMEX serves directly: 1m, 5m, 1h, 1d
+func (p *BitmexProvider) NativeIntervals() []string {
+	return []string{"1m", "5m", "1h", "1d"}
+}
+
 func (p *BitmexProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	if !isNativeInterval(p, interval) {
+		return nil, unsupportedInterval("bitmex", interval)
+	}
 	symbol = p.NormalizeSymbol(symbol)
 	interval = p.convertInterval(interval)
 	apiURL := fmt.Sprintf("%s/trade/bucketed?symbol=%s&binSize=%s&count=%d&reverse=true",
@@ -1444,13 +1481,13 @@ func (p *BitmexProvider) GetKlines(symbol, interval string, limit int) ([]Kline,
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("bitmex klines request failed: %w", err)
+		return nil, fmt.Errorf("bitmex klines request failed: %w", errs.Network("bitmex", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("bitmex klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("bitmex klines API error: %w", errs.FromResponse("bitmex", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -1497,12 +1534,12 @@ func (p *BitmexProvider) GetOpenInterest(symbol string) (*OIData, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("bitmex open interest request failed: %w", err)
+		return nil, fmt.Errorf("bitmex open interest request failed: %w", errs.Network("bitmex", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("bitmex open interest API error (status %d)", resp.StatusCode)
+		return nil, fmt.Errorf("bitmex open interest API error: %w", errs.FromResponse("bitmex", resp, nil))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -1533,12 +1570,12 @@ func (p *BitmexProvider) GetFundingRate(symbol string) (float64, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return 0, fmt.Errorf("bitmex funding rate request failed: %w", err)
+		return 0, fmt.Errorf("bitmex funding rate request failed: %w", errs.Network("bitmex", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("bitmex funding rate API error (status %d)", resp.StatusCode)
+		return 0, fmt.Errorf("bitmex funding rate API error: %w", errs.FromResponse("bitmex", resp, nil))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -1621,7 +1658,15 @@ func (p *DeribitProvider) convertInterval(interval string) string {
 	return "5"
 }
 
+// NativeIntervals intervals Deribit serves directly: 1, 3, 5, 10, 15, 30, 60, 120, 180, 360, 720 minutes and 1D (no 4h)
+func (p *DeribitProvider) NativeIntervals() []string {
+	return []string{"1m", "3m", "5m", "15m", "30m", "1h", "1d"}
+}
+
 func (p *DeribitProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	if !isNativeInterval(p, interval) {
+		return nil, unsupportedInterval("deribit", interval)
+	}
 	symbol = p.NormalizeSymbol(symbol)
 	intervalMinutes := p.convertInterval(interval) // Now returns minutes as string
 	endTime := int64(time.Now().Unix() * 1000)
@@ -1633,13 +1678,13 @@ func (p *DeribitProvider) GetKlines(symbol, interval string, limit int) ([]Kline
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("deribit klines request failed: %w", err)
+		return nil, fmt.Errorf("deribit klines request failed: %w", errs.Network("deribit", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("deribit klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("deribit klines API error: %w", errs.FromResponse("deribit", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -1695,7 +1740,7 @@ func (p *DeribitProvider) GetOpenInterest(symbol string) (*OIData, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("deribit open interest request failed: %w", err)
+		return nil, fmt.Errorf("deribit open interest request failed: %w", errs.Network("deribit", err))
 	}
 	defer resp.Body.Close()
 
@@ -1705,7 +1750,7 @@ func (p *DeribitProvider) GetOpenInterest(symbol string) (*OIData, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("deribit open interest API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("deribit open interest API error: %w", errs.FromResponse("deribit", resp, body))
 	}
 
 	var result struct {
@@ -1737,7 +1782,7 @@ func (p *DeribitProvider) GetFundingRate(symbol string) (float64, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return 0, fmt.Errorf("deribit funding rate request failed: %w", err)
+		return 0, fmt.Errorf("deribit funding rate request failed: %w", errs.Network("deribit", err))
 	}
 	defer resp.Body.Close()
 
@@ -1747,7 +1792,7 @@ func (p *DeribitProvider) GetFundingRate(symbol string) (float64, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("deribit funding rate API error (status %d): %s", resp.StatusCode, string(body))
+		return 0, fmt.Errorf("deribit funding rate API error: %w", errs.FromResponse("deribit", resp, body))
 	}
 
 	var result struct {
@@ -1849,13 +1894,13 @@ func (p *HitBTCProvider) GetKlines(symbol, interval string, limit int) ([]Kline,
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("hitbtc klines request failed: %w", err)
+		return nil, fmt.Errorf("hitbtc klines request failed: %w", errs.Network("hitbtc", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("hitbtc klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("hitbtc klines API error: %w", errs.FromResponse("hitbtc", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -1975,13 +2020,13 @@ func (p *BitgetProvider) GetKlines(symbol, interval string, limit int) ([]Kline,
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("bitget klines request failed: %w", err)
+		return nil, fmt.Errorf("bitget klines request failed: %w", errs.Network("bitget", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("bitget klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("bitget klines API error: %w", errs.FromResponse("bitget", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -2041,12 +2086,12 @@ func (p *BitgetProvider) GetOpenInterest(symbol string) (*OIData, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("bitget open interest request failed: %w", err)
+		return nil, fmt.Errorf("bitget open interest request failed: %w", errs.Network("bitget", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("bitget open interest API error (status %d)", resp.StatusCode)
+		return nil, fmt.Errorf("bitget open interest API error: %w", errs.FromResponse("bitget", resp, nil))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -2084,7 +2129,7 @@ func (p *BitgetProvider) GetFundingRate(symbol string) (float64, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return 0, fmt.Errorf("bitget funding rate request failed: %w", err)
+		return 0, fmt.Errorf("bitget funding rate request failed: %w", errs.Network("bitget", err))
 	}
 	defer resp.Body.Close()
 
@@ -2094,7 +2139,7 @@ func (p *BitgetProvider) GetFundingRate(symbol string) (float64, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("bitget funding rate API error (status %d)", resp.StatusCode)
+		return 0, fmt.Errorf("bitget funding rate API error: %w", errs.FromResponse("bitget", resp, nil))
 	}
 
 	var result struct {
@@ -2183,13 +2228,13 @@ func (p *MEXCProvider) GetKlines(symbol, interval string, limit int) ([]Kline, e
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("mexc klines request failed: %w", err)
+		return nil, fmt.Errorf("mexc klines request failed: %w", errs.Network("mexc", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("mexc klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("mexc klines API error: %w", errs.FromResponse("mexc", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -2245,12 +2290,12 @@ func (p *MEXCProvider) GetOpenInterest(symbol string) (*OIData, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("mexc open interest request failed: %w", err)
+		return nil, fmt.Errorf("mexc open interest request failed: %w", errs.Network("mexc", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("mexc open interest API error (status %d)", resp.StatusCode)
+		return nil, fmt.Errorf("mexc open interest API error: %w", errs.FromResponse("mexc", resp, nil))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -2285,12 +2330,12 @@ func (p *MEXCProvider) GetFundingRate(symbol string) (float64, error) {
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return 0, fmt.Errorf("mexc funding rate request failed: %w", err)
+		return 0, fmt.Errorf("mexc funding rate request failed: %w", errs.Network("mexc", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("mexc funding rate API error (status %d)", resp.StatusCode)
+		return 0, fmt.Errorf("mexc funding rate API error: %w", errs.FromResponse("mexc", resp, nil))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -2383,13 +2428,13 @@ func (p *CryptoComProvider) GetKlines(symbol, interval string, limit int) ([]Kli
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("crypto_com klines request failed: %w", err)
+		return nil, fmt.Errorf("crypto_com klines request failed: %w", errs.Network("crypto_com", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("crypto_com klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("crypto_com klines API error: %w", errs.FromResponse("crypto_com", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -2519,7 +2564,15 @@ func (p *KrakenProvider) convertInterval(interval string) string {
 	return "5"
 }
 
+// NativeIntervals intervals Kraken serves directly: 1, 5, 15, 30, 60, 240, 1440 minutes (no 3m)
+func (p *KrakenProvider) NativeIntervals() []string {
+	return []string{"1m", "5m", "15m", "30m", "1h", "4h", "1d"}
+}
+
 func (p *KrakenProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	if !isNativeInterval(p, interval) {
+		return nil, unsupportedInterval("kraken", interval)
+	}
 	symbol = p.NormalizeSymbol(symbol)
 	intervalMinutes := p.convertInterval(interval)
 	apiURL := fmt.Sprintf("%s/OHLC?pair=%s&interval=%s",
@@ -2527,13 +2580,13 @@ func (p *KrakenProvider) GetKlines(symbol, interval string, limit int) ([]Kline,
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("kraken klines request failed: %w", err)
+		return nil, fmt.Errorf("kraken klines request failed: %w", errs.Network("kraken", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("kraken klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("kraken klines API error: %w", errs.FromResponse("kraken", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -2681,7 +2734,15 @@ func (p *GeminiProvider) convertInterval(interval string) string {
 	return "5m"
 }
 
+// NativeIntervals intervals Gemini serves directly: 1m, 5m, 15m, 30m, 1hr, 6hr, 1day (no 3m or 4h)
+func (p *GeminiProvider) NativeIntervals() []string {
+	return []string{"1m", "5m", "15m", "30m", "1h", "1d"}
+}
+
 func (p *GeminiProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	if !isNativeInterval(p, interval) {
+		return nil, unsupportedInterval("gemini", interval)
+	}
 	symbol = p.NormalizeSymbol(symbol)
 	interval = p.convertInterval(interval)
 	apiURL := fmt.Sprintf("%s/candles/%s/%s?limit=%d",
@@ -2689,13 +2750,13 @@ func (p *GeminiProvider) GetKlines(symbol, interval string, limit int) ([]Kline,
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("gemini klines request failed: %w", err)
+		return nil, fmt.Errorf("gemini klines request failed: %w", errs.Network("gemini", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("gemini klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("gemini klines API error: %w", errs.FromResponse("gemini", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -2814,13 +2875,13 @@ func (p *DigifinexProvider) GetKlines(symbol, interval string, limit int) ([]Kli
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("digifinex klines request failed: %w", err)
+		return nil, fmt.Errorf("digifinex klines request failed: %w", errs.Network("digifinex", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("digifinex klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("digifinex klines API error: %w", errs.FromResponse("digifinex", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -2949,13 +3010,13 @@ func (p *WhitebitProvider) GetKlines(symbol, interval string, limit int) ([]Klin
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("whitebit klines request failed: %w", err)
+		return nil, fmt.Errorf("whitebit klines request failed: %w", errs.Network("whitebit", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("whitebit klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("whitebit klines API error: %w", errs.FromResponse("whitebit", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -3098,13 +3159,13 @@ func (p *UpbitProvider) GetKlines(symbol, interval string, limit int) ([]Kline,
 
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("upbit klines request failed: %w", err)
+		return nil, fmt.Errorf("upbit klines request failed: %w", errs.Network("upbit", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("upbit klines API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("upbit klines API error: %w", errs.FromResponse("upbit", resp, body))
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -3297,7 +3358,7 @@ func (p *AlpacaCryptoProvider) GetKlines(symbol, interval string, limit int) ([]
 		CryptoFeed: marketdata.US,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("alpaca crypto klines request failed: %w", err)
+		return nil, fmt.Errorf("alpaca crypto klines request failed: %w", errs.Network("alpaca", err))
 	}
 	
 	// Convert Alpaca bars to NOFX Kline format
@@ -0,0 +1,180 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Volume and open interest surge detection
+// For each configured window (e.g. 5m, 15m, 1h) the latest closed bar's volume and the latest
+// open interest change are compared with the previous Lookback periods as a z-score. A volume
+// z-score or an absolute OI change z-score above its threshold is a surge. The scanner uses this
+// to add symbols to the candidate pool without relying on the external AI500/OI Top APIs.
+// OI history needs a provider implementing OIHistoryProvider; others are checked on volume only.
+
+// SurgeConfig thresholds of the surge detector
+type SurgeConfig struct {
+	Windows  []string // bar intervals checked (default 5m, 15m, 1h)
+	Lookback int      // previous periods the latest one is compared with (default 30, min 5)
+	VolumeZ  float64  // volume z-score threshold (default 3)
+	OIZ      float64  // OI change z-score threshold, absolute (default 3)
+}
+
+// SurgeAlert one window of one symbol that exceeded a threshold
+type SurgeAlert struct {
+	Symbol string    `json:"symbol"`
+	Window string    `json:"window"`
+	Metric string    `json:"metric"` // "volume" | "oi"
+	Z      float64   `json:"z"`
+	Change float64   `json:"change"` // volume: latest / average; oi: latest change, percent
+	At     time.Time `json:"at"`
+}
+
+// String short description for the prompt (1h volume z=4.2 (3.1x avg))
+func (a SurgeAlert) String() string {
+	if a.Metric == "oi" {
+		return fmt.Sprintf("%s OI z=%+.1f (%+.2f%%)", a.Window, a.Z, a.Change)
+	}
+	return fmt.Sprintf("%s volume z=%.1f (%.1fx avg)", a.Window, a.Z, a.Change)
+}
+
+// OIHistoryProvider is implemented by providers that publish open interest history
+type OIHistoryProvider interface {
+	// GetOpenInterestHistory returns the open interest of the last limit periods, oldest → latest
+	GetOpenInterestHistory(symbol, period string, limit int) ([]float64, error)
+}
+
+// withDefaults fills unset thresholds
+func (cfg SurgeConfig) withDefaults() SurgeConfig {
+	if len(cfg.Windows) == 0 {
+		cfg.Windows = []string{"5m", "15m", "1h"}
+	}
+	if cfg.Lookback <= 0 {
+		cfg.Lookback = 30
+	}
+	if cfg.Lookback < 5 {
+		cfg.Lookback = 5
+	}
+	if cfg.VolumeZ <= 0 {
+		cfg.VolumeZ = 3
+	}
+	if cfg.OIZ <= 0 {
+		cfg.OIZ = 3
+	}
+	return cfg
+}
+
+// DetectSurges checks every window of symbol and returns the alerts, strongest first
+func DetectSurges(provider MarketDataProvider, symbol string, cfg SurgeConfig, now time.Time) ([]SurgeAlert, error) {
+	cfg = cfg.withDefaults()
+	oiProvider, hasOI := provider.(OIHistoryProvider)
+
+	var alerts []SurgeAlert
+	var lastErr error
+	checked := 0
+	for _, window := range cfg.Windows {
+		// the last bar is still forming: compare the latest closed bar with the ones before it
+		klines, err := provider.GetKlines(symbol, window, cfg.Lookback+2)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		checked++
+		if len(klines) >= 2 {
+			volumes := make([]float64, 0, len(klines)-1)
+			for _, k := range klines[:len(klines)-1] {
+				volumes = append(volumes, k.Volume)
+			}
+			if z, ok := latestZScore(volumes); ok && z >= cfg.VolumeZ {
+				alerts = append(alerts, SurgeAlert{
+					Symbol: symbol, Window: window, Metric: "volume", Z: z,
+					Change: volumes[len(volumes)-1] / mean(volumes[:len(volumes)-1]), At: now,
+				})
+			}
+		}
+
+		if !hasOI {
+			continue
+		}
+		history, err := oiProvider.GetOpenInterestHistory(symbol, window, cfg.Lookback+2)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		changes := pctChanges(history)
+		if z, ok := latestZScore(changes); ok && math.Abs(z) >= cfg.OIZ {
+			alerts = append(alerts, SurgeAlert{
+				Symbol: symbol, Window: window, Metric: "oi", Z: z, Change: changes[len(changes)-1], At: now,
+			})
+		}
+	}
+	if checked == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool { return math.Abs(alerts[i].Z) > math.Abs(alerts[j].Z) })
+	return alerts, nil
+}
+
+// latestZScore z-score of the last value against the values before it (needs 5 earlier values and nonzero spread)
+func latestZScore(values []float64) (float64, bool) {
+	if len(values) < 6 {
+		return 0, false
+	}
+	history := values[:len(values)-1]
+	avg := mean(history)
+	variance := 0.0
+	for _, v := range history {
+		variance += (v - avg) * (v - avg)
+	}
+	std := math.Sqrt(variance / float64(len(history)))
+	if std == 0 {
+		return 0, false
+	}
+	return (values[len(values)-1] - avg) / std, true
+}
+
+// pctChanges period-over-period changes in percent (periods starting from zero are skipped)
+func pctChanges(values []float64) []float64 {
+	var changes []float64
+	for i := 1; i < len(values); i++ {
+		if values[i-1] > 0 {
+			changes = append(changes, (values[i]-values[i-1])/values[i-1]*100)
+		}
+	}
+	return changes
+}
+
+// mean average of values (0 when empty)
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// GetOpenInterestHistory fetches the open interest per period from Binance futures trading statistics (openInterestHist)
+func (p *BinanceProvider) GetOpenInterestHistory(symbol, period string, limit int) ([]float64, error) {
+	symbol = p.NormalizeSymbol(symbol)
+	var points []struct {
+		SumOpenInterest string `json:"sumOpenInterest"`
+		Timestamp       int64  `json:"timestamp"`
+	}
+	if err := p.fetchStats("openInterestHist", fmt.Sprintf("symbol=%s&period=%s&limit=%d", symbol, period, limit), &points); err != nil {
+		return nil, err
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Timestamp < points[j].Timestamp })
+	history := make([]float64, 0, len(points))
+	for _, pt := range points {
+		oi, _ := strconv.ParseFloat(pt.SumOpenInterest, 64)
+		history = append(history, oi)
+	}
+	return history, nil
+}
@@ -0,0 +1,70 @@
+package market
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// surgeProvider fixed volumes and OI history per window
+type surgeProvider struct {
+	volumes map[string][]float64
+	oi      map[string][]float64
+}
+
+func (p *surgeProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	var klines []Kline
+	for _, v := range p.volumes[interval] {
+		klines = append(klines, Kline{Close: 100, Volume: v})
+	}
+	return klines, nil
+}
+func (p *surgeProvider) GetOpenInterest(symbol string) (*OIData, error) { return &OIData{}, nil }
+func (p *surgeProvider) GetFundingRate(symbol string) (float64, error)  { return 0, nil }
+func (p *surgeProvider) NormalizeSymbol(symbol string) string           { return symbol }
+func (p *surgeProvider) GetName() string                                { return "surge" }
+func (p *surgeProvider) GetOpenInterestHistory(symbol, period string, limit int) ([]float64, error) {
+	return p.oi[period], nil
+}
+
+func TestDetectSurges(t *testing.T) {
+	flat := func(n int, v float64, last ...float64) []float64 {
+		values := make([]float64, 0, n+len(last))
+		for i := 0; i < n; i++ {
+			values = append(values, v+float64(i%3)) // small spread
+		}
+		return append(values, last...)
+	}
+	provider := &surgeProvider{
+		volumes: map[string][]float64{
+			"5m":  flat(20, 100, 500, 90), // latest closed bar 500 (the last bar is still forming)
+			"15m": flat(20, 300, 301, 5000),
+		},
+		oi: map[string][]float64{
+			"5m":  {1000, 1001, 1002, 1001, 1002, 1003, 1002, 1003, 1004, 950}, // -5% drop
+			"15m": {1000, 1001, 1002, 1003, 1004, 1005, 1006, 1007, 1008, 1009},
+		},
+	}
+
+	alerts, err := DetectSurges(provider, "SOLUSDT", SurgeConfig{Windows: []string{"5m", "15m"}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("期望 5m 成交量和 5m 持仓量两条异动（15m 最新一根未收盘不计）: %+v", alerts)
+	}
+	for _, a := range alerts {
+		if a.Window != "5m" {
+			t.Errorf("只有5m窗口超过阈值: %+v", a)
+		}
+		if a.Metric == "oi" && (a.Z > -3 || a.Change > -5) {
+			t.Errorf("持仓量骤降应为负z分数: %+v", a)
+		}
+		if a.Metric == "volume" && a.Change < 4 {
+			t.Errorf("成交量倍数 = %.2f", a.Change)
+		}
+	}
+	if math.Abs(alerts[0].Z) < math.Abs(alerts[1].Z) {
+		t.Errorf("异动应按强度排序: %+v", alerts)
+	}
+}
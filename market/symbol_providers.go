@@ -0,0 +1,107 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// Per-symbol provider overrides: specific symbols (DOGEUSDT) or whole coins (BTC, any quote)
+// fetch market data from a chosen provider instead of the global default.
+// A failing override falls back to the default provider for that call.
+
+var symbolProviders = struct {
+	mu      sync.RWMutex
+	mapping map[string]string // symbol or base asset -> provider name
+}{mapping: make(map[string]string)}
+
+// SetSymbolProviders replaces the overrides. Keys are symbols (DOGEUSDT) or base assets (BTC);
+// every provider must be registered.
+func SetSymbolProviders(mapping map[string]string) error {
+	normalized := make(map[string]string, len(mapping))
+	for key, name := range mapping {
+		if _, err := GetProvider(name); err != nil {
+			return fmt.Errorf("symbol provider for %s: %w", key, err)
+		}
+		normalized[symbolProviderKey(key)] = name
+	}
+	symbolProviders.mu.Lock()
+	symbolProviders.mapping = normalized
+	symbolProviders.mu.Unlock()
+	return nil
+}
+
+// SymbolProviders returns a copy of the current overrides
+func SymbolProviders() map[string]string {
+	symbolProviders.mu.RLock()
+	defer symbolProviders.mu.RUnlock()
+	mapping := make(map[string]string, len(symbolProviders.mapping))
+	for key, name := range symbolProviders.mapping {
+		mapping[key] = name
+	}
+	return mapping
+}
+
+// symbolProviderKey normalizes a mapping key: symbols keep their quote, bare coins stay bare
+func symbolProviderKey(key string) string {
+	base, quote := SplitSymbol(key)
+	return base + quote
+}
+
+// overrideFor returns the provider name configured for symbol (exact symbol first, then its coin)
+func overrideFor(symbol string) (string, bool) {
+	base, quote := SplitSymbol(symbol)
+	symbolProviders.mu.RLock()
+	defer symbolProviders.mu.RUnlock()
+	if name, ok := symbolProviders.mapping[base+quote]; ok {
+		return name, true
+	}
+	name, ok := symbolProviders.mapping[base]
+	return name, ok
+}
+
+// ProviderFor returns the provider for symbol: its override if configured, otherwise the default
+func ProviderFor(symbol string) (MarketDataProvider, error) {
+	if name, ok := overrideFor(symbol); ok {
+		if provider, err := GetProvider(name); err == nil {
+			return provider, nil
+		}
+	}
+	return GetDefaultProvider()
+}
+
+// ValidateSymbolProviders checks that every override's provider actually lists the symbol
+// (bare coins are checked against their USDT pair). Overrides that fail are removed, so those
+// symbols use the default provider; the failures are returned as warnings.
+func ValidateSymbolProviders(ctx context.Context) []string {
+	mapping := SymbolProviders()
+	keys := make([]string, 0, len(mapping))
+	for key := range mapping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var warnings []string
+	for _, key := range keys {
+		name := mapping[key]
+		symbol := key
+		if _, quote := SplitSymbol(key); quote == "" {
+			symbol = key + DefaultQuote
+		}
+		provider, err := GetProvider(name)
+		if err == nil {
+			_, err = latestBarFrom(ctx, provider, symbol, "1h")
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s -> %s: %v", key, name, err))
+			symbolProviders.mu.Lock()
+			delete(symbolProviders.mapping, key)
+			symbolProviders.mu.Unlock()
+			continue
+		}
+		log.Printf("✓ [市场数据] %s 使用数据源 %s", key, name)
+	}
+	return warnings
+}
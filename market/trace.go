@@ -0,0 +1,80 @@
+package market
+
+import (
+	"context"
+	"nofx/tracing"
+	"time"
+)
+
+// tracedKlines 获取K线，记录为追踪 span（耗时和结果计入数据源健康统计）
+func tracedKlines(ctx context.Context, provider MarketDataProvider, symbol, interval string, limit int) ([]Kline, error) {
+	_, span := tracing.Start(ctx, "market.klines")
+	defer span.End()
+	span.SetAttr("provider", provider.GetName())
+	span.SetAttr("symbol", symbol)
+	span.SetAttr("interval", interval)
+	span.SetAttr("limit", limit)
+
+	start := time.Now()
+	klines, err := FetchKlines(provider, symbol, interval, limit)
+	recordCall(provider.GetName(), symbol, time.Since(start), err)
+	span.RecordError(err)
+	span.SetAttr("count", len(klines))
+	return klines, err
+}
+
+// tracedOpenInterest 获取持仓量，记录为追踪 span
+func tracedOpenInterest(ctx context.Context, provider MarketDataProvider, symbol string) (*OIData, error) {
+	_, span := tracing.Start(ctx, "market.open_interest")
+	defer span.End()
+	span.SetAttr("provider", provider.GetName())
+	span.SetAttr("symbol", symbol)
+
+	start := time.Now()
+	data, err := provider.GetOpenInterest(symbol)
+	recordCall(provider.GetName(), symbol, time.Since(start), err)
+	span.RecordError(err)
+	return data, err
+}
+
+// tracedFundingRate 获取资金费率，记录为追踪 span
+func tracedFundingRate(ctx context.Context, provider MarketDataProvider, symbol string) (float64, error) {
+	_, span := tracing.Start(ctx, "market.funding_rate")
+	defer span.End()
+	span.SetAttr("provider", provider.GetName())
+	span.SetAttr("symbol", symbol)
+
+	start := time.Now()
+	rate, err := provider.GetFundingRate(symbol)
+	recordCall(provider.GetName(), symbol, time.Since(start), err)
+	span.RecordError(err)
+	return rate, err
+}
+
+// tracedIndexPrice 获取标记价格和现货指数价格，记录为追踪 span
+func tracedIndexPrice(ctx context.Context, provider MarketDataProvider, ip IndexPriceProvider, symbol string) (float64, float64, error) {
+	_, span := tracing.Start(ctx, "market.index_price")
+	defer span.End()
+	span.SetAttr("provider", provider.GetName())
+	span.SetAttr("symbol", symbol)
+
+	start := time.Now()
+	mark, index, err := ip.GetIndexPrice(symbol)
+	recordCall(provider.GetName(), symbol, time.Since(start), err)
+	span.RecordError(err)
+	return mark, index, err
+}
+
+// tracedFundingSchedule 获取资金费率和下次结算时间，记录为追踪 span
+func tracedFundingSchedule(ctx context.Context, provider MarketDataProvider, sp FundingScheduleProvider, symbol string) (float64, time.Time, error) {
+	_, span := tracing.Start(ctx, "market.funding_rate")
+	defer span.End()
+	span.SetAttr("provider", provider.GetName())
+	span.SetAttr("symbol", symbol)
+
+	start := time.Now()
+	rate, next, err := sp.GetFundingSchedule(symbol)
+	recordCall(provider.GetName(), symbol, time.Since(start), err)
+	span.RecordError(err)
+	return rate, next, err
+}
@@ -0,0 +1,136 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Realized volatility and expected move
+// From 1h closes (7 days plus one bar) the annualized realized volatility over the last 24h and 7d
+// is computed as the stdev of hourly log returns × sqrt(hours per year). The expected move is the
+// 1σ price move over a horizon, scaled from the 24h volatility by sqrt(horizon / 1 year). The prompt
+// shows the expected move over the trader's scan interval; decision validation compares take-profit
+// distances with the 1σ daily move (using the larger of the two volatilities).
+
+const (
+	volatilityInterval = "1h"
+	volatilityBars     = 7*24 + 1
+	hoursPerYear       = 24 * 365
+)
+
+// VolatilityData realized volatility of a symbol
+type VolatilityData struct {
+	RealizedVol24h float64       // annualized realized volatility over the last 24h, percent
+	RealizedVol7d  float64       // annualized realized volatility over the last 7d, percent
+	Horizon        time.Duration // horizon of ExpectedMove in Format (the trader's scan interval, 0 = not shown)
+}
+
+// ExpectedMovePct 1σ expected move over the horizon in percent, from the 24h volatility
+// (falls back to 7d when the 24h window is missing)
+func (v *VolatilityData) ExpectedMovePct(horizon time.Duration) float64 {
+	vol := v.RealizedVol24h
+	if vol <= 0 {
+		vol = v.RealizedVol7d
+	}
+	return vol * math.Sqrt(horizon.Hours()/hoursPerYear)
+}
+
+// DailyMovePct 1σ move over 24h in percent, from the larger of the 24h and 7d volatility
+func (v *VolatilityData) DailyMovePct() float64 {
+	return math.Max(v.RealizedVol24h, v.RealizedVol7d) * math.Sqrt(24.0/hoursPerYear)
+}
+
+var (
+	volatilityEnabled   bool
+	volatilityEnabledMu sync.RWMutex
+)
+
+// SetVolatilityEnabled turns the volatility computation on or off (one extra kline request per symbol and fetch)
+func SetVolatilityEnabled(enabled bool) {
+	volatilityEnabledMu.Lock()
+	volatilityEnabled = enabled
+	volatilityEnabledMu.Unlock()
+}
+
+// VolatilityEnabled reports whether realized volatility is fetched with the market data
+func VolatilityEnabled() bool {
+	volatilityEnabledMu.RLock()
+	defer volatilityEnabledMu.RUnlock()
+	return volatilityEnabled
+}
+
+// fetchVolatility returns the realized volatility from 1h klines, or nil when disabled
+func fetchVolatility(ctx context.Context, provider MarketDataProvider, symbol string) (*VolatilityData, error) {
+	if !VolatilityEnabled() {
+		return nil, nil
+	}
+	klines, err := tracedKlines(ctx, provider, symbol, volatilityInterval, volatilityBars)
+	if err != nil {
+		return nil, err
+	}
+	if len(klines) < 25 {
+		return nil, fmt.Errorf("not enough 1h klines for volatility: %d", len(klines))
+	}
+	return &VolatilityData{
+		RealizedVol24h: realizedVolatility(klines, 24),
+		RealizedVol7d:  realizedVolatility(klines, 7*24),
+	}, nil
+}
+
+// realizedVolatility annualized stdev of the last n hourly log returns in percent (fewer when the series is shorter)
+func realizedVolatility(klines []Kline, n int) float64 {
+	if n > len(klines)-1 {
+		n = len(klines) - 1
+	}
+	if n < 2 {
+		return 0
+	}
+	returns := make([]float64, 0, n)
+	for i := len(klines) - n; i < len(klines); i++ {
+		prev, cur := klines[i-1].Close, klines[i].Close
+		if prev <= 0 || cur <= 0 {
+			continue
+		}
+		returns = append(returns, math.Log(cur/prev))
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	return math.Sqrt(variance) * math.Sqrt(hoursPerYear) * 100
+}
+
+// formatVolatility the prompt line for the volatility and the expected move over the horizon
+func formatVolatility(v *VolatilityData, price float64) string {
+	line := fmt.Sprintf("Realized volatility (annualized, from 1h returns): 24h = %.1f%%, 7d = %.1f%%", v.RealizedVol24h, v.RealizedVol7d)
+	if v.Horizon > 0 {
+		move := v.ExpectedMovePct(v.Horizon)
+		line += fmt.Sprintf("; expected move over the next %s (1σ) = ±%.3f%% (±%.4f)", formatHorizon(v.Horizon), move, price*move/100)
+	}
+	daily := v.DailyMovePct()
+	line += fmt.Sprintf("; 1σ daily move = ±%.2f%%", daily)
+	return line + "\n\n"
+}
+
+// formatHorizon 3m / 1h / 1h30m
+func formatHorizon(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -2,11 +2,13 @@ package mcp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"nofx/tracing"
 	"strings"
 	"time"
 )
@@ -30,7 +32,8 @@ type Client struct {
 	BaseURL    string
 	Model      string
 	Timeout    time.Duration
-	UseFullURL bool // 是否使用完整URL（不添加/chat/completions）
+	UseFullURL bool        // 是否使用完整URL（不添加/chat/completions）
+	Params     ModelParams // 采样参数（通过 SetParams 校验后设置）
 }
 
 func New() *Client {
@@ -85,8 +88,8 @@ func (cfg *Client) SetCustomAPI(apiURL, apiKey, modelName string) {
 		cfg.UseFullURL = false
 	}
 
-	cfg.Model = modelName
 	cfg.Timeout = 120 * time.Second
+	cfg.SetModel(modelName)
 }
 
 // SetClient 设置完整的AI配置（高级用户）
@@ -99,8 +102,36 @@ func (cfg *Client) SetClient(Client Client) {
 
 // CallWithMessages 使用 system + user prompt 调用AI API（推荐）
 func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	return cfg.CallWithMessagesContext(context.Background(), systemPrompt, userPrompt)
+}
+
+// CallWithMessagesContext 同 CallWithMessages，整个调用和每次尝试记录为 ctx 下的追踪 span
+func (cfg *Client) CallWithMessagesContext(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	resp, err := cfg.CallWithReasoningContext(ctx, systemPrompt, userPrompt)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// CallWithReasoningContext 同 CallWithMessagesContext，推理模型的推理过程与最终回答分开返回
+func (cfg *Client) CallWithReasoningContext(ctx context.Context, systemPrompt, userPrompt string) (result *Response, err error) {
+	ctx, span := tracing.Start(ctx, "ai.call")
+	defer func() {
+		span.RecordError(err)
+		if result != nil {
+			span.SetAttr("response_chars", len(result.Content))
+			span.SetAttr("reasoning_chars", len(result.Reasoning))
+			span.SetAttr("reasoning_tokens", result.ReasoningTokens)
+		}
+		span.End()
+	}()
+	span.SetAttr("provider", string(cfg.Provider))
+	span.SetAttr("model", cfg.Model)
+	span.SetAttr("prompt_chars", len(systemPrompt)+len(userPrompt))
+
 	if cfg.APIKey == "" {
-		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
+		return nil, fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
 	}
 
 	// 重试配置
@@ -112,7 +143,11 @@ func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, er
 			fmt.Printf("⚠️  AI API调用失败，正在重试 (%d/%d)...\n", attempt, maxRetries)
 		}
 
-		result, err := cfg.callOnce(systemPrompt, userPrompt)
+		_, attemptSpan := tracing.Start(ctx, "ai.attempt")
+		attemptSpan.SetAttr("attempt", attempt)
+		result, err := cfg.scheduledCall(ctx, attemptSpan, systemPrompt, userPrompt)
+		attemptSpan.RecordError(err)
+		attemptSpan.End()
 		if err == nil {
 			if attempt > 1 {
 				fmt.Printf("✓ AI API重试成功\n")
@@ -123,7 +158,7 @@ func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, er
 		lastErr = err
 		// 如果不是网络错误，不重试
 		if !isRetryableError(err) {
-			return "", err
+			return nil, err
 		}
 
 		// 重试前等待
@@ -134,14 +169,37 @@ func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, er
 		}
 	}
 
-	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+	return nil, fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+}
+
+// scheduledCall 启用全局调度器时先排队取得并发名额再调用
+func (cfg *Client) scheduledCall(ctx context.Context, span *tracing.Span, systemPrompt, userPrompt string) (*Response, error) {
+	scheduler := GetScheduler()
+	if scheduler == nil {
+		return cfg.callOnce(systemPrompt, userPrompt)
+	}
+
+	release, wait, err := scheduler.Acquire(ctx)
+	span.SetAttr("queue_wait_ms", wait.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("等待AI调用名额时取消: %w", err)
+	}
+	defer release()
+	if wait > time.Second {
+		log.Printf("⏳ AI调用排队 %.1f 秒（%s）", wait.Seconds(), cfg.Model)
+	}
+	return cfg.callOnce(systemPrompt, userPrompt)
 }
 
 // callOnce 单次调用AI API（内部使用）
-func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
+func (cfg *Client) callOnce(systemPrompt, userPrompt string) (*Response, error) {
 	// 如果是Gemini API，使用特殊的请求格式
 	if cfg.Provider == ProviderGemini {
-		return cfg.callGeminiAPI(systemPrompt, userPrompt)
+		text, err := cfg.callGeminiAPI(systemPrompt, userPrompt)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Content: text}, nil
 	}
 
 	// 如果是Hugging Face API，使用特殊的请求格式
@@ -168,18 +226,17 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 
 	// 构建请求体
 	requestBody := map[string]interface{}{
-		"model":       cfg.Model,
-		"messages":    messages,
-		"temperature": 0.5, // 降低temperature以提高JSON格式稳定性
-		"max_tokens":  8000, // 增加token限制以容纳长思维链和JSON决策
+		"model":    cfg.Model,
+		"messages": messages,
 	}
+	cfg.applyChatParams(requestBody)
 
 	// 注意：response_format 参数仅 OpenAI 支持，DeepSeek/Qwen 不支持
 	// 我们通过强化 prompt 和后处理来确保 JSON 格式正确
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
 	// 创建HTTP请求
@@ -193,7 +250,7 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 	}
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -214,57 +271,50 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 	client := &http.Client{Timeout: cfg.Timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送请求失败: %w", err)
+		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	// 解析响应
+	// 解析响应（推理模型的推理过程在 reasoning_content 或 reasoning 字段）
 	var result struct {
 		Choices []struct {
 			Message struct {
-				Content   string `json:"content"`
-				Reasoning string `json:"reasoning"`
+				Content          string `json:"content"`
+				Reasoning        string `json:"reasoning"`
+				ReasoningContent string `json:"reasoning_content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage struct {
+			CompletionTokensDetails struct {
+				ReasoningTokens int `json:"reasoning_tokens"`
+			} `json:"completion_tokens_details"`
+		} `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
+		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("API返回空响应")
+		return nil, fmt.Errorf("API返回空响应")
 	}
 
-	content := result.Choices[0].Message.Content
-	reasoning := result.Choices[0].Message.Reasoning
-	
-	// 如果content为空，尝试使用reasoning字段（用于支持reasoning模式的模型，如Qwen3）
-	if content == "" || content == " " || content == "<s>" || content == "<s> " {
-		if reasoning != "" && reasoning != " " {
-			content = reasoning
-		}
-	}
-	
-	// 清理响应内容（移除<s>等标记）
-	content = cleanResponse(content)
-	
-	if content == "" || content == " " || content == "<s>" || content == "<s> " {
-		// 如果内容为空或只有标记，返回一个默认的成功响应
-		return "{\"decision\": \"hold\", \"reasoning\": \"AI模型返回空响应，建议保持观望\"}", nil
+	message := result.Choices[0].Message
+	reasoning := message.ReasoningContent
+	if reasoning == "" {
+		reasoning = message.Reasoning
 	}
-
-	return content, nil
+	return cfg.buildResponse(message.Content, reasoning, result.Usage.CompletionTokensDetails.ReasoningTokens)
 }
 
 // isRetryableError 判断错误是否可重试
@@ -312,12 +362,19 @@ func (cfg *Client) callGeminiAPI(systemPrompt, userPrompt string) (string, error
 		},
 	})
 
+	generationConfig := map[string]interface{}{
+		"temperature":     cfg.temperature(),
+		"maxOutputTokens": cfg.maxTokens(8192), // 增加token限制以容纳模型的内部推理和实际输出
+	}
+	if cfg.Params.TopP != nil {
+		generationConfig["topP"] = *cfg.Params.TopP
+	}
+	if cfg.Params.PresencePenalty != nil {
+		generationConfig["presencePenalty"] = *cfg.Params.PresencePenalty
+	}
 	requestBody := map[string]interface{}{
-		"contents": contents,
-		"generationConfig": map[string]interface{}{
-			"temperature":     0.5,
-			"maxOutputTokens": 8192, // 增加token限制以容纳模型的内部推理和实际输出
-		},
+		"contents":         contents,
+		"generationConfig": generationConfig,
 	}
 
 	jsonData, err := json.Marshal(requestBody)
@@ -393,7 +450,7 @@ func (cfg *Client) callGeminiAPI(systemPrompt, userPrompt string) (string, error
 }
 
 // callHuggingFaceAPI 调用Hugging Face Inference API
-func (cfg *Client) callHuggingFaceAPI(systemPrompt, userPrompt string) (string, error) {
+func (cfg *Client) callHuggingFaceAPI(systemPrompt, userPrompt string) (*Response, error) {
 	// 检测是否为新版 Inference Providers API (OpenAI兼容格式)
 	isNewAPI := strings.Contains(cfg.BaseURL, "router.huggingface.co")
 	
@@ -426,20 +483,19 @@ func (cfg *Client) callHuggingFaceAPI(systemPrompt, userPrompt string) (string,
 		})
 
 		requestBody := map[string]interface{}{
-			"model":       cfg.Model,
-			"messages":    messages,
-			"temperature": 0.5,
-			"max_tokens":  8000,
+			"model":    cfg.Model,
+			"messages": messages,
 		}
+		cfg.applyChatParams(requestBody)
 
 		jsonData, err := json.Marshal(requestBody)
 		if err != nil {
-			return "", fmt.Errorf("序列化Hugging Face请求失败: %w", err)
+			return nil, fmt.Errorf("序列化Hugging Face请求失败: %w", err)
 		}
 
 		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 		if err != nil {
-			return "", fmt.Errorf("创建Hugging Face请求失败: %w", err)
+			return nil, fmt.Errorf("创建Hugging Face请求失败: %w", err)
 		}
 
 		req.Header.Set("Content-Type", "application/json")
@@ -448,51 +504,47 @@ func (cfg *Client) callHuggingFaceAPI(systemPrompt, userPrompt string) (string,
 		client := &http.Client{Timeout: cfg.Timeout}
 		resp, err := client.Do(req)
 		if err != nil {
-			return "", fmt.Errorf("发送Hugging Face请求失败: %w", err)
+			return nil, fmt.Errorf("发送Hugging Face请求失败: %w", err)
 		}
 		defer resp.Body.Close()
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return "", fmt.Errorf("读取Hugging Face响应失败: %w", err)
+			return nil, fmt.Errorf("读取Hugging Face响应失败: %w", err)
 		}
 
 		if resp.StatusCode != http.StatusOK {
 			if resp.StatusCode == 503 || resp.StatusCode == 202 {
-				return "", fmt.Errorf("Hugging Face模型正在加载中，请稍后重试 (status %d)", resp.StatusCode)
+				return nil, fmt.Errorf("Hugging Face模型正在加载中，请稍后重试 (status %d)", resp.StatusCode)
 			}
-			return "", fmt.Errorf("Hugging Face API返回错误 (status %d): %s", resp.StatusCode, string(body))
+			return nil, fmt.Errorf("Hugging Face API返回错误 (status %d): %s", resp.StatusCode, string(body))
 		}
 
 		// 解析 OpenAI 兼容格式的响应
 		var result struct {
 			Choices []struct {
 				Message struct {
-					Content   string `json:"content"`
-					Reasoning string `json:"reasoning"`
+					Content          string `json:"content"`
+					Reasoning        string `json:"reasoning"`
+					ReasoningContent string `json:"reasoning_content"`
 				} `json:"message"`
 			} `json:"choices"`
 		}
 
 		if err := json.Unmarshal(body, &result); err != nil {
-			return "", fmt.Errorf("解析Hugging Face响应失败: %w", err)
+			return nil, fmt.Errorf("解析Hugging Face响应失败: %w", err)
 		}
 
 		if len(result.Choices) == 0 {
-			return "", fmt.Errorf("Hugging Face API返回空响应")
+			return nil, fmt.Errorf("Hugging Face API返回空响应")
 		}
 
-		content := result.Choices[0].Message.Content
-		reasoning := result.Choices[0].Message.Reasoning
-		
-		// 如果content为空，尝试使用reasoning字段
-		if content == "" || content == " " {
-			if reasoning != "" && reasoning != " " {
-				content = reasoning
-			}
+		message := result.Choices[0].Message
+		reasoning := message.ReasoningContent
+		if reasoning == "" {
+			reasoning = message.Reasoning
 		}
-
-		return content, nil
+		return cfg.buildResponse(message.Content, reasoning, 0)
 	}
 
 	// 旧版 API 格式处理
@@ -505,23 +557,27 @@ func (cfg *Client) callHuggingFaceAPI(systemPrompt, userPrompt string) (string,
 		fullContent = userPrompt
 	}
 
+	parameters := map[string]interface{}{
+		"temperature":      cfg.temperature(),
+		"max_new_tokens":   cfg.maxTokens(2000),
+		"return_full_text": false,
+	}
+	if cfg.Params.TopP != nil {
+		parameters["top_p"] = *cfg.Params.TopP
+	}
 	requestBody := map[string]interface{}{
-		"inputs": fullContent,
-		"parameters": map[string]interface{}{
-			"temperature":     0.5,
-			"max_new_tokens":  2000,
-			"return_full_text": false,
-		},
+		"inputs":     fullContent,
+		"parameters": parameters,
 	}
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化Hugging Face请求失败: %w", err)
+		return nil, fmt.Errorf("序列化Hugging Face请求失败: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("创建Hugging Face请求失败: %w", err)
+		return nil, fmt.Errorf("创建Hugging Face请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -531,22 +587,22 @@ func (cfg *Client) callHuggingFaceAPI(systemPrompt, userPrompt string) (string,
 	client := &http.Client{Timeout: cfg.Timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送Hugging Face请求失败: %w", err)
+		return nil, fmt.Errorf("发送Hugging Face请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取Hugging Face响应失败: %w", err)
+		return nil, fmt.Errorf("读取Hugging Face响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		// Hugging Face可能返回202 (model is loading)，需要等待
 		if resp.StatusCode == 503 || resp.StatusCode == 202 {
-			return "", fmt.Errorf("Hugging Face模型正在加载中，请稍后重试 (status %d)", resp.StatusCode)
+			return nil, fmt.Errorf("Hugging Face模型正在加载中，请稍后重试 (status %d)", resp.StatusCode)
 		}
-		return "", fmt.Errorf("Hugging Face API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("Hugging Face API返回错误 (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	// 解析Hugging Face响应格式
@@ -559,30 +615,24 @@ func (cfg *Client) callHuggingFaceAPI(systemPrompt, userPrompt string) (string,
 		// 数组格式
 		if generatedText, ok := hfResult[0]["generated_text"].(string); ok {
 			// 移除原始输入（Hugging Face返回完整文本）
-			if strings.HasPrefix(generatedText, fullContent) {
-				return strings.TrimPrefix(generatedText, fullContent), nil
-			}
-			return generatedText, nil
+			return cfg.buildResponse(strings.TrimPrefix(generatedText, fullContent), "", 0)
 		}
 	}
 
 	// 尝试解析为单个对象
 	if err := json.Unmarshal(body, &hfResultSingle); err == nil {
 		if generatedText, ok := hfResultSingle["generated_text"].(string); ok {
-			if strings.HasPrefix(generatedText, fullContent) {
-				return strings.TrimPrefix(generatedText, fullContent), nil
-			}
-			return generatedText, nil
+			return cfg.buildResponse(strings.TrimPrefix(generatedText, fullContent), "", 0)
 		}
 	}
 
 	// 如果以上都失败，尝试作为字符串数组处理
 	var textArray []string
 	if err := json.Unmarshal(body, &textArray); err == nil && len(textArray) > 0 {
-		return textArray[0], nil
+		return cfg.buildResponse(textArray[0], "", 0)
 	}
 
-	return "", fmt.Errorf("无法解析Hugging Face响应: %s", string(body))
+	return nil, fmt.Errorf("无法解析Hugging Face响应: %s", string(body))
 }
 
 // cleanResponse 清理AI响应内容，移除特殊标记
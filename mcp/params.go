@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 模型采样参数
+// 未设置的参数使用默认值（temperature 0.5、max_tokens 8000），其余不发送，由服务端决定。
+// 各提供商支持的参数不同，设置了不支持的参数时 SetParams 返回错误（启动时即发现配置问题，而不是调用时被API拒绝）。
+
+const (
+	defaultTemperature = 0.5  // 降低temperature以提高JSON格式稳定性
+	defaultMaxTokens   = 8000 // 容纳长思维链和JSON决策
+)
+
+// ModelParams 模型采样参数（指针/零值表示未设置）
+type ModelParams struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"top_p,omitempty"`
+	MaxTokens       int      `json:"max_tokens,omitempty"`
+	PresencePenalty *float64 `json:"presence_penalty,omitempty"`
+	ReasoningEffort string   `json:"reasoning_effort,omitempty"` // low / medium / high（o系列等推理模型）
+
+	ReasoningAsContent bool `json:"reasoning_as_content,omitempty"` // 推理模型没有最终回答时使用推理过程作为回答（默认报错）
+}
+
+// providerCaps 提供商支持的参数
+type providerCaps struct {
+	topP            bool
+	presencePenalty bool
+	reasoningEffort bool
+	maxTokens       int // max_tokens 上限（0表示不限制）
+}
+
+// capabilities 当前提供商和模型支持的参数
+func (cfg *Client) capabilities() providerCaps {
+	switch cfg.Provider {
+	case ProviderDeepSeek:
+		if isDeepSeekReasoner(cfg.Model) {
+			return providerCaps{maxTokens: maxDeepSeekReasonerTokens}
+		}
+		return providerCaps{topP: true, presencePenalty: true, maxTokens: 8192}
+	case ProviderQwen:
+		return providerCaps{topP: true, presencePenalty: true, maxTokens: 8192}
+	case ProviderGemini:
+		return providerCaps{topP: true, presencePenalty: true}
+	case ProviderHuggingFace:
+		return providerCaps{topP: true}
+	default:
+		// 自定义OpenAI兼容API：推理模型只支持 reasoning_effort，不支持采样参数（DeepSeek推理模型两者都不支持）
+		if isDeepSeekReasoner(cfg.Model) {
+			return providerCaps{}
+		}
+		if isReasoningModel(cfg.Model) {
+			return providerCaps{reasoningEffort: true}
+		}
+		return providerCaps{topP: true, presencePenalty: true}
+	}
+}
+
+// isReasoningModel OpenAI o系列等推理模型（不接受 temperature/top_p/presence_penalty，用 max_completion_tokens）
+func isReasoningModel(model string) bool {
+	model = strings.ToLower(model)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if model == prefix || strings.HasPrefix(model, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// SetParams 校验并设置模型参数（需在设置提供商和模型之后调用）
+func (cfg *Client) SetParams(params ModelParams) error {
+	caps := cfg.capabilities()
+	name := fmt.Sprintf("%s (%s)", cfg.Provider, cfg.Model)
+	reasoning := cfg.IsReasoningModel()
+
+	if t := params.Temperature; t != nil {
+		if reasoning {
+			return fmt.Errorf("%s 是推理模型，不支持 temperature", name)
+		}
+		if *t < 0 || *t > 2 {
+			return fmt.Errorf("temperature 必须在 0-2 之间: %v", *t)
+		}
+	}
+	if p := params.TopP; p != nil {
+		if !caps.topP {
+			return fmt.Errorf("%s 不支持 top_p", name)
+		}
+		if *p <= 0 || *p > 1 {
+			return fmt.Errorf("top_p 必须在 (0, 1] 之间: %v", *p)
+		}
+	}
+	if p := params.PresencePenalty; p != nil {
+		if !caps.presencePenalty {
+			return fmt.Errorf("%s 不支持 presence_penalty", name)
+		}
+		if *p < -2 || *p > 2 {
+			return fmt.Errorf("presence_penalty 必须在 -2 到 2 之间: %v", *p)
+		}
+	}
+	if params.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens 不能为负数: %d", params.MaxTokens)
+	}
+	if caps.maxTokens > 0 && params.MaxTokens > caps.maxTokens {
+		return fmt.Errorf("%s 的 max_tokens 上限为 %d: %d", name, caps.maxTokens, params.MaxTokens)
+	}
+	if params.ReasoningEffort != "" {
+		if !caps.reasoningEffort {
+			return fmt.Errorf("%s 不支持 reasoning_effort（仅OpenAI兼容API的推理模型支持）", name)
+		}
+		switch params.ReasoningEffort {
+		case "low", "medium", "high":
+		default:
+			return fmt.Errorf("reasoning_effort 必须是 low/medium/high: %s", params.ReasoningEffort)
+		}
+	}
+
+	cfg.Params = params
+	return nil
+}
+
+// temperature 生效的 temperature
+func (cfg *Client) temperature() float64 {
+	if cfg.Params.Temperature != nil {
+		return *cfg.Params.Temperature
+	}
+	return defaultTemperature
+}
+
+// maxTokens 生效的 max_tokens（fallback 为该接口的默认值）
+func (cfg *Client) maxTokens(fallback int) int {
+	if cfg.Params.MaxTokens > 0 {
+		return cfg.Params.MaxTokens
+	}
+	return fallback
+}
+
+// applyChatParams 把参数写入OpenAI兼容格式的请求体
+func (cfg *Client) applyChatParams(body map[string]interface{}) {
+	if isDeepSeekReasoner(cfg.Model) {
+		body["max_tokens"] = cfg.maxTokens(defaultReasoningMaxTokens) // 包含推理token
+		return
+	}
+	if cfg.Provider == ProviderCustom && isReasoningModel(cfg.Model) {
+		body["max_completion_tokens"] = cfg.maxTokens(defaultMaxTokens)
+		if cfg.Params.ReasoningEffort != "" {
+			body["reasoning_effort"] = cfg.Params.ReasoningEffort
+		}
+		return
+	}
+	body["temperature"] = cfg.temperature()
+	body["max_tokens"] = cfg.maxTokens(defaultMaxTokens)
+	if cfg.Params.TopP != nil {
+		body["top_p"] = *cfg.Params.TopP
+	}
+	if cfg.Params.PresencePenalty != nil {
+		body["presence_penalty"] = *cfg.Params.PresencePenalty
+	}
+}
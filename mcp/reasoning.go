@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// 推理模型支持
+// 两类推理模型的请求参数不同：
+//   OpenAI o系列/gpt-5（自定义API）：发送 max_completion_tokens 和 reasoning_effort，不发送采样参数
+//   DeepSeek deepseek-reasoner / R1：发送 max_tokens（包含推理token，默认32000），不发送采样参数
+// 响应中的推理过程（reasoning_content / reasoning 字段，或正文里的 <think>...</think>）与最终回答分开返回，
+// 只有最终回答作为 content 交给决策解析。模型只返回推理没有最终回答时（通常是 max_tokens 耗尽）返回错误，
+// 除非配置了 reasoning_as_content 才把推理当作回答使用。
+
+const (
+	defaultReasoningMaxTokens = 32000             // deepseek-reasoner 的默认输出上限（含推理）
+	maxDeepSeekReasonerTokens = 65536             // deepseek-reasoner 的 max_tokens 上限
+	reasoningTimeout          = 300 * time.Second // 推理模型响应较慢
+)
+
+// Response AI的回答（最终回答与推理过程分开）
+type Response struct {
+	Content         string // 最终回答
+	Reasoning       string // 推理过程（模型未返回时为空）
+	ReasoningTokens int    // 推理消耗的token数（接口未返回时为0）
+}
+
+// isDeepSeekReasoner DeepSeek推理模型（deepseek-reasoner，或其他平台托管的 deepseek-r1）
+func isDeepSeekReasoner(model string) bool {
+	model = strings.ToLower(model)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	return strings.HasPrefix(model, "deepseek-reasoner") || strings.HasPrefix(model, "deepseek-r1")
+}
+
+// IsReasoningModel 当前模型是否为推理模型（不接受采样参数）
+func (cfg *Client) IsReasoningModel() bool {
+	return isDeepSeekReasoner(cfg.Model) || (cfg.Provider == ProviderCustom && isReasoningModel(cfg.Model))
+}
+
+// SetModel 设置模型名称（推理模型延长超时）
+func (cfg *Client) SetModel(model string) {
+	cfg.Model = model
+	if cfg.IsReasoningModel() && cfg.Timeout < reasoningTimeout {
+		cfg.Timeout = reasoningTimeout
+	}
+}
+
+// splitThinking 把正文中的 <think>...</think> 推理块从回答中分离出来
+func splitThinking(content string) (answer, thinking string) {
+	var thoughts []string
+	for {
+		start := strings.Index(content, "<think>")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(content[start:], "</think>")
+		if end < 0 {
+			// 推理块未闭合（输出被截断）：其后全部视为推理
+			thoughts = append(thoughts, strings.TrimSpace(content[start+len("<think>"):]))
+			content = content[:start]
+			break
+		}
+		thoughts = append(thoughts, strings.TrimSpace(content[start+len("<think>"):start+end]))
+		content = content[:start] + content[start+end+len("</think>"):]
+	}
+	return content, strings.Join(thoughts, "\n\n")
+}
+
+// buildResponse 分离推理过程和最终回答；只有推理没有回答时，除非配置了 ReasoningAsContent，返回错误
+func (cfg *Client) buildResponse(content, reasoning string, reasoningTokens int) (*Response, error) {
+	content, thinking := splitThinking(content)
+	content = cleanResponse(content)
+	reasoning = strings.TrimSpace(reasoning)
+	if thinking != "" {
+		reasoning = strings.TrimSpace(reasoning + "\n\n" + thinking)
+	}
+	resp := &Response{Content: content, Reasoning: reasoning, ReasoningTokens: reasoningTokens}
+
+	if content == "" && reasoning != "" {
+		if !cfg.Params.ReasoningAsContent {
+			return nil, fmt.Errorf("%s 只返回了推理过程（%d字符），没有最终回答，可能达到了 max_tokens 上限", cfg.Model, len(reasoning))
+		}
+		log.Printf("⚠️  %s 没有最终回答，按配置使用推理过程作为回答", cfg.Model)
+		resp.Content = reasoning
+	}
+	if resp.Content == "" {
+		// 如果内容为空或只有标记，返回一个默认的成功响应
+		resp.Content = "{\"decision\": \"hold\", \"reasoning\": \"AI模型返回空响应，建议保持观望\"}"
+	}
+	return resp, nil
+}
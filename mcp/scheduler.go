@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 全局AI调用调度
+// 多个trader的扫描周期可能同时触发，一起打到AI提供商容易被限频或超时。
+// 启用调度器后，所有 Client 的每次请求（含重试的每次尝试）都要先取得一个并发名额，
+// 超过上限的调用按先到先得排队，排队耗时计入统计。
+
+// Scheduler 限制同时进行的AI调用数量
+type Scheduler struct {
+	slots chan struct{}
+
+	mu          sync.Mutex
+	waiting     int
+	totalCalls  int64
+	queuedCalls int64
+	totalWait   time.Duration
+	maxWait     time.Duration
+	lastWait    time.Duration
+}
+
+// SchedulerStats 调度器统计
+type SchedulerStats struct {
+	MaxConcurrent int     `json:"max_concurrent"`
+	Active        int     `json:"active"`       // 正在进行的调用
+	Waiting       int     `json:"waiting"`      // 排队中的调用
+	TotalCalls    int64   `json:"total_calls"`  // 累计调用次数
+	QueuedCalls   int64   `json:"queued_calls"` // 需要排队的调用次数
+	AvgWaitMs     float64 `json:"avg_wait_ms"`  // 平均排队耗时（所有调用）
+	MaxWaitMs     float64 `json:"max_wait_ms"`  // 最长排队耗时
+	LastWaitMs    float64 `json:"last_wait_ms"` // 最近一次调用的排队耗时
+}
+
+// NewScheduler 创建调度器，maxConcurrent 为同时进行的AI调用上限
+func NewScheduler(maxConcurrent int) *Scheduler {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &Scheduler{slots: make(chan struct{}, maxConcurrent)}
+}
+
+// Acquire 取得一个并发名额，返回释放函数和排队耗时；ctx 取消时放弃排队
+func (s *Scheduler) Acquire(ctx context.Context) (func(), time.Duration, error) {
+	start := time.Now()
+	select {
+	case s.slots <- struct{}{}:
+		s.record(0)
+		return s.release, 0, nil
+	default:
+	}
+
+	s.mu.Lock()
+	s.waiting++
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.waiting--
+		s.mu.Unlock()
+	}()
+
+	select {
+	case s.slots <- struct{}{}:
+		wait := time.Since(start)
+		s.record(wait)
+		return s.release, wait, nil
+	case <-ctx.Done():
+		return nil, time.Since(start), ctx.Err()
+	}
+}
+
+// release 归还名额
+func (s *Scheduler) release() {
+	<-s.slots
+}
+
+// record 记录一次调用的排队耗时
+func (s *Scheduler) record(wait time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totalCalls++
+	if wait > 0 {
+		s.queuedCalls++
+	}
+	s.totalWait += wait
+	s.lastWait = wait
+	if wait > s.maxWait {
+		s.maxWait = wait
+	}
+}
+
+// Stats 当前统计
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := SchedulerStats{
+		MaxConcurrent: cap(s.slots),
+		Active:        len(s.slots),
+		Waiting:       s.waiting,
+		TotalCalls:    s.totalCalls,
+		QueuedCalls:   s.queuedCalls,
+		MaxWaitMs:     float64(s.maxWait) / float64(time.Millisecond),
+		LastWaitMs:    float64(s.lastWait) / float64(time.Millisecond),
+	}
+	if s.totalCalls > 0 {
+		stats.AvgWaitMs = float64(s.totalWait) / float64(s.totalCalls) / float64(time.Millisecond)
+	}
+	return stats
+}
+
+var (
+	globalScheduler   *Scheduler
+	globalSchedulerMu sync.RWMutex
+)
+
+// SetScheduler 设置全局调度器（nil 表示不限制并发）
+func SetScheduler(s *Scheduler) {
+	globalSchedulerMu.Lock()
+	defer globalSchedulerMu.Unlock()
+	globalScheduler = s
+}
+
+// GetScheduler 当前的全局调度器（未启用时为nil）
+func GetScheduler() *Scheduler {
+	globalSchedulerMu.RLock()
+	defer globalSchedulerMu.RUnlock()
+	return globalScheduler
+}
@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Model Context Protocol 服务端
+// 把本系统的能力作为MCP工具暴露给外部的MCP客户端（Claude Desktop、各类agent框架），
+// 协议为 JSON-RPC 2.0，传输层为 Streamable HTTP 的单次响应形式：
+// 客户端 POST 一条JSON-RPC消息，请求返回 application/json 响应，通知返回 202。
+// 支持 initialize / ping / tools/list / tools/call，工具由调用方注册。
+
+// ProtocolVersion 服务端实现的MCP协议版本
+const ProtocolVersion = "2025-03-26"
+
+// JSON-RPC 错误码
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Tool MCP工具
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"` // JSON Schema（type 为 object）
+
+	// Handler 执行工具，返回值序列化为JSON文本返回给客户端；返回错误时结果标记为 isError
+	Handler func(args json.RawMessage) (interface{}, error) `json:"-"`
+}
+
+// Server MCP服务端
+type Server struct {
+	name    string
+	version string
+
+	mu    sync.RWMutex
+	tools map[string]Tool
+}
+
+// NewServer 创建MCP服务端，name/version 在 initialize 时返回给客户端
+func NewServer(name, version string) *Server {
+	return &Server{name: name, version: version, tools: make(map[string]Tool)}
+}
+
+// AddTool 注册工具（同名工具被替换）
+func (s *Server) AddTool(tool Tool) {
+	if tool.InputSchema == nil {
+		tool.InputSchema = map[string]interface{}{"type": "object"}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tools[tool.Name] = tool
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // 缺省表示通知
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// toolContent 工具结果中的一段文本
+type toolContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type toolResult struct {
+	Content []toolContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+// ServeHTTP 处理一条JSON-RPC消息
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "MCP endpoint only accepts POST", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeRPC(w, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "无法解析JSON-RPC消息: " + err.Error()}})
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		writeRPC(w, rpcResponse{JSONRPC: "2.0", ID: idOrNull(req.ID), Error: &rpcError{codeInvalidRequest, "不是有效的JSON-RPC 2.0请求"}})
+		return
+	}
+
+	result, rpcErr := s.handle(req)
+	if len(req.ID) == 0 {
+		// 通知（如 notifications/initialized）不需要响应
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeRPC(w, rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr})
+}
+
+// handle 分发JSON-RPC方法
+func (s *Server) handle(req rpcRequest) (interface{}, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+		}, nil
+
+	case "ping":
+		return map[string]interface{}{}, nil
+
+	case "tools/list":
+		s.mu.RLock()
+		tools := make([]Tool, 0, len(s.tools))
+		for _, tool := range s.tools {
+			tools = append(tools, tool)
+		}
+		s.mu.RUnlock()
+		sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+		return map[string]interface{}{"tools": tools}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{codeInvalidParams, "tools/call 参数无效: " + err.Error()}
+		}
+		s.mu.RLock()
+		tool, ok := s.tools[params.Name]
+		s.mu.RUnlock()
+		if !ok {
+			return nil, &rpcError{codeInvalidParams, fmt.Sprintf("未知工具: %s", params.Name)}
+		}
+		if len(params.Arguments) == 0 {
+			params.Arguments = json.RawMessage("{}")
+		}
+		return callTool(tool, params.Arguments), nil
+
+	default:
+		if len(req.ID) == 0 {
+			return nil, nil // 未处理的通知直接忽略
+		}
+		return nil, &rpcError{codeMethodNotFound, "不支持的方法: " + req.Method}
+	}
+}
+
+// callTool 执行工具，执行错误作为工具结果返回（isError），让模型能看到并调整
+func callTool(tool Tool, args json.RawMessage) toolResult {
+	value, err := tool.Handler(args)
+	if err != nil {
+		log.Printf("⚠️ [MCP] 工具 %s 执行失败: %v", tool.Name, err)
+		return toolResult{Content: []toolContent{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+	text, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return toolResult{Content: []toolContent{{Type: "text", Text: "序列化结果失败: " + err.Error()}}, IsError: true}
+	}
+	return toolResult{Content: []toolContent{{Type: "text", Text: string(text)}}}
+}
+
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
+
+func writeRPC(w http.ResponseWriter, resp rpcResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
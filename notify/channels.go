@@ -0,0 +1,145 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// httpClient 通知渠道共用的HTTP客户端
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// TelegramChannel 通过Telegram Bot推送
+type TelegramChannel struct {
+	token  string
+	chatID string
+	apiURL string
+}
+
+// NewTelegramChannel 创建Telegram渠道
+func NewTelegramChannel(token, chatID string) *TelegramChannel {
+	return &TelegramChannel{token: token, chatID: chatID, apiURL: "https://api.telegram.org"}
+}
+
+// Name 渠道名称
+func (c *TelegramChannel) Name() string { return "telegram" }
+
+// Send 发送消息
+func (c *TelegramChannel) Send(e Event) error {
+	text := fmt.Sprintf("%s %s\n%s", severityIcon(e.Severity), e.Title, e.Message)
+	if e.Link != "" {
+		text += "\n" + e.Link
+	}
+	if e.TraderID != "" {
+		text += "\n\ntrader: " + e.TraderID
+	}
+	markup := inlineKeyboard(e.Actions)
+	if len(e.Image) > 0 {
+		return c.sendPhoto(text, e.Image, markup)
+	}
+
+	form := url.Values{}
+	form.Set("chat_id", c.chatID)
+	form.Set("text", text)
+	if markup != "" {
+		form.Set("reply_markup", markup)
+	}
+
+	resp, err := httpClient.PostForm(fmt.Sprintf("%s/bot%s/sendMessage", c.apiURL, c.token), form)
+	if err != nil {
+		return err
+	}
+	return checkResponse(resp)
+}
+
+// telegramCaptionLimit Telegram图片说明的最大长度（字符）
+const telegramCaptionLimit = 1024
+
+// sendPhoto 发送图片，消息正文作为图片说明
+func (c *TelegramChannel) sendPhoto(caption string, image []byte, markup string) error {
+	if runes := []rune(caption); len(runes) > telegramCaptionLimit {
+		caption = string(runes[:telegramCaptionLimit-1]) + "…"
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("chat_id", c.chatID)
+	mw.WriteField("caption", caption)
+	if markup != "" {
+		mw.WriteField("reply_markup", markup)
+	}
+	part, err := mw.CreateFormFile("photo", "chart.png")
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(image); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Post(fmt.Sprintf("%s/bot%s/sendPhoto", c.apiURL, c.token), mw.FormDataContentType(), &body)
+	if err != nil {
+		return err
+	}
+	return checkResponse(resp)
+}
+
+// inlineKeyboard 把操作按钮转为Telegram的inline键盘（一行，按钮打开URL），没有按钮时为空
+func inlineKeyboard(actions []Action) string {
+	if len(actions) == 0 {
+		return ""
+	}
+	type button struct {
+		Text string `json:"text"`
+		URL  string `json:"url"`
+	}
+	row := make([]button, 0, len(actions))
+	for _, a := range actions {
+		row = append(row, button{Text: a.Label, URL: a.URL})
+	}
+	data, _ := json.Marshal(map[string]interface{}{"inline_keyboard": [][]button{row}})
+	return string(data)
+}
+
+// checkResponse 检查推送接口的响应状态并关闭响应体
+func checkResponse(resp *http.Response) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// WebhookChannel 以JSON POST推送到自定义地址
+type WebhookChannel struct {
+	url string
+}
+
+// NewWebhookChannel 创建Webhook渠道
+func NewWebhookChannel(url string) *WebhookChannel {
+	return &WebhookChannel{url: url}
+}
+
+// Name 渠道名称
+func (c *WebhookChannel) Name() string { return "webhook" }
+
+// Send 发送事件JSON
+func (c *WebhookChannel) Send(e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Post(c.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return checkResponse(resp)
+}
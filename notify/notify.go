@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// 通知子系统：交易所上下架、风控触发等需要运维人员关注的事件统一从这里发出。
+// 事件总是写入日志；配置了通知渠道（Telegram、Webhook）时异步推送，推送失败只记录日志，不影响交易流程。
+
+// Severity 事件级别
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Event 通知事件
+type Event struct {
+	Type     string    `json:"type"`                // 事件类型，如 "listing.new", "listing.delisting"
+	Severity Severity  `json:"severity"`            // 级别
+	TraderID string    `json:"trader_id,omitempty"` // 相关trader（全局事件为空）
+	Symbol   string    `json:"symbol,omitempty"`    // 相关币种
+	Title    string    `json:"title"`               // 标题（一行摘要）
+	Message  string    `json:"message"`             // 详细内容
+	Link     string    `json:"link,omitempty"`      // 相关链接（如决策K线图）
+	Image    []byte    `json:"image,omitempty"`     // 附带的PNG图片（如决策K线图，JSON中为base64）
+	Actions  []Action  `json:"actions,omitempty"`   // 操作按钮（如批准/拒绝交易想法）
+	Time     time.Time `json:"time"`
+}
+
+// Action 事件附带的操作按钮（打开URL完成操作）
+type Action struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// Channel 通知渠道
+type Channel interface {
+	Name() string
+	Send(e Event) error
+}
+
+// Notifier 按通知规则把事件分发到渠道（未设置规则时分发到所有渠道）
+type Notifier struct {
+	channels []Channel
+	mu       sync.RWMutex
+
+	rules          []Rule                    // 通知规则（按顺序匹配）
+	digestInterval time.Duration             // 汇总周期
+	location       *time.Location            // 免打扰时段使用的时区
+	pending        map[string][]pendingEvent // 渠道名 -> 等待汇总推送的事件
+	timer          *time.Timer               // 下一次汇总推送
+}
+
+// New 创建通知器
+func New(channels ...Channel) *Notifier {
+	return &Notifier{channels: channels}
+}
+
+// AddChannel 添加通知渠道
+func (n *Notifier) AddChannel(c Channel) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.channels = append(n.channels, c)
+}
+
+// Notify 按通知规则异步推送事件（立即推送或放入汇总）
+func (n *Notifier) Notify(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	n.mu.Lock()
+	immediate, digest, quietEnd := n.routeLocked(e)
+	immediate = append([]Channel(nil), immediate...)
+	if len(digest) > 0 {
+		n.enqueueLocked(digest, e, quietEnd)
+	}
+	n.mu.Unlock()
+
+	for _, c := range immediate {
+		go n.send(c, e)
+	}
+}
+
+// send 推送事件到一个渠道，失败只记录日志
+func (n *Notifier) send(c Channel, e Event) {
+	if err := c.Send(e); err != nil {
+		log.Printf("⚠️ 通知推送失败 [%s] %s: %v", c.Name(), e.Title, err)
+	}
+}
+
+var (
+	defaultNotifier   *Notifier
+	defaultNotifierMu sync.RWMutex
+)
+
+// SetDefault 设置全局通知器（nil 表示只写日志）
+func SetDefault(n *Notifier) {
+	defaultNotifierMu.Lock()
+	defer defaultNotifierMu.Unlock()
+	defaultNotifier = n
+}
+
+// Send 发送事件：写入日志，并通过全局通知器推送
+func Send(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	log.Printf("%s %s: %s", severityIcon(e.Severity), e.Title, e.Message)
+
+	defaultNotifierMu.RLock()
+	n := defaultNotifier
+	defaultNotifierMu.RUnlock()
+	if n != nil {
+		n.Notify(e)
+	}
+}
+
+// severityIcon 级别对应的图标
+func severityIcon(s Severity) string {
+	switch s {
+	case SeverityCritical:
+		return "🚨"
+	case SeverityWarning:
+		return "⚠️"
+	default:
+		return "🔔"
+	}
+}
@@ -0,0 +1,344 @@
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 通知规则
+// 规则按顺序匹配，第一条匹配的规则决定事件的去向；没有规则匹配的事件立即推送到所有渠道（与未配置规则时相同）。
+// 匹配条件：事件类型（"listing.*" 匹配前缀）、最低级别、币种，均为空时匹配所有事件。
+// 去向：
+//   - immediate：立即推送到规则的渠道（为空时所有渠道）
+//   - digest：放入汇总，每个汇总周期合并为一条 notify.digest 事件推送
+//   - drop：只写日志，不推送
+// 规则可以设置免打扰时段（如 "23:00-07:00"），期间匹配的非 critical 事件放入汇总，免打扰结束时推送。
+
+// Delivery 事件的推送方式
+type Delivery string
+
+const (
+	DeliveryImmediate Delivery = "immediate"
+	DeliveryDigest    Delivery = "digest"
+	DeliveryDrop      Delivery = "drop"
+)
+
+// Rule 通知规则
+type Rule struct {
+	Events      []string // 事件类型，"xxx.*" 匹配前缀（为空匹配所有）
+	MinSeverity Severity // 最低级别（为空不限）
+	Symbols     []string // 币种（为空不限；不为空时不匹配没有币种的事件）
+	Channels    []string // 推送渠道名（为空时所有渠道）
+	Delivery    Delivery // 推送方式（为空时立即推送）
+	QuietHours  string   // 免打扰时段 "HH:MM-HH:MM"（为空不限）
+
+	quietFrom, quietTo int // 免打扰起止（一天中的分钟数）
+}
+
+// severityRank 级别排序（越严重越大）
+func severityRank(s Severity) int {
+	switch s {
+	case SeverityCritical:
+		return 2
+	case SeverityWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// parseQuietHours 解析 "HH:MM-HH:MM"，返回一天中的起止分钟数
+func parseQuietHours(s string) (int, int, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("免打扰时段格式应为 HH:MM-HH:MM: %q", s)
+	}
+	var minutes [2]int
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return 0, 0, fmt.Errorf("免打扰时段格式应为 HH:MM-HH:MM: %q", s)
+		}
+		minutes[i] = t.Hour()*60 + t.Minute()
+	}
+	if minutes[0] == minutes[1] {
+		return 0, 0, fmt.Errorf("免打扰时段起止时间相同: %q", s)
+	}
+	return minutes[0], minutes[1], nil
+}
+
+// matches 事件是否满足规则的匹配条件
+func (r *Rule) matches(e Event) bool {
+	if len(r.Events) > 0 {
+		matched := false
+		for _, pattern := range r.Events {
+			if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+				matched = strings.HasPrefix(e.Type, prefix)
+			} else {
+				matched = e.Type == pattern
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if r.MinSeverity != "" && severityRank(e.Severity) < severityRank(r.MinSeverity) {
+		return false
+	}
+	if len(r.Symbols) > 0 {
+		matched := false
+		for _, symbol := range r.Symbols {
+			if e.Symbol != "" && strings.EqualFold(symbol, e.Symbol) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// quietUntil t 处于免打扰时段时返回时段结束时间
+func (r *Rule) quietUntil(t time.Time) (time.Time, bool) {
+	if r.QuietHours == "" {
+		return time.Time{}, false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	var quiet bool
+	if r.quietFrom < r.quietTo {
+		quiet = minute >= r.quietFrom && minute < r.quietTo
+	} else { // 跨零点，如 23:00-07:00
+		quiet = minute >= r.quietFrom || minute < r.quietTo
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+	end := time.Date(t.Year(), t.Month(), t.Day(), r.quietTo/60, r.quietTo%60, 0, 0, t.Location())
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, true
+}
+
+// allows 规则是否推送到该渠道
+func (r *Rule) allows(channel string) bool {
+	if len(r.Channels) == 0 {
+		return true
+	}
+	for _, name := range r.Channels {
+		if name == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingEvent 等待汇总推送的事件
+type pendingEvent struct {
+	event Event
+	due   time.Time // 推送时间
+	quiet bool      // 因免打扰时段延后（免打扰结束时推送，不并入汇总周期）
+}
+
+// SetRules 设置通知规则和汇总周期（interval<=0 时为60分钟），loc 为免打扰时段使用的时区（nil 为本地时区）
+func (n *Notifier) SetRules(rules []Rule, interval time.Duration, loc *time.Location) error {
+	parsed := make([]Rule, len(rules))
+	for i, rule := range rules {
+		switch rule.Delivery {
+		case "":
+			rule.Delivery = DeliveryImmediate
+		case DeliveryImmediate, DeliveryDigest, DeliveryDrop:
+		default:
+			return fmt.Errorf("通知规则%d: 未知的推送方式 %q", i+1, rule.Delivery)
+		}
+		if rule.QuietHours != "" {
+			from, to, err := parseQuietHours(rule.QuietHours)
+			if err != nil {
+				return fmt.Errorf("通知规则%d: %w", i+1, err)
+			}
+			rule.quietFrom, rule.quietTo = from, to
+		}
+		parsed[i] = rule
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.rules = parsed
+	n.digestInterval = interval
+	n.location = loc
+	return nil
+}
+
+// routeLocked 按规则决定事件推送到哪些渠道：立即推送的渠道、放入汇总的渠道，以及放入汇总的事件是否因免打扰延后到 quietEnd（调用方持有锁）
+func (n *Notifier) routeLocked(e Event) (immediate, digest []Channel, quietEnd time.Time) {
+	var rule *Rule
+	for i := range n.rules {
+		if n.rules[i].matches(e) {
+			rule = &n.rules[i]
+			break
+		}
+	}
+	if rule == nil {
+		return n.channels, nil, time.Time{}
+	}
+	if rule.Delivery == DeliveryDrop {
+		return nil, nil, time.Time{}
+	}
+
+	var selected []Channel
+	for _, c := range n.channels {
+		if rule.allows(c.Name()) {
+			selected = append(selected, c)
+		}
+	}
+	if e.Severity != SeverityCritical {
+		if end, quiet := rule.quietUntil(e.Time.In(n.location)); quiet {
+			return nil, selected, end
+		}
+	}
+	if rule.Delivery == DeliveryDigest {
+		return nil, selected, time.Time{}
+	}
+	return selected, nil, time.Time{}
+}
+
+// enqueueLocked 把事件放入渠道的汇总并重设定时器（调用方持有锁）
+// 汇总周期从渠道第一条待汇总事件开始计算，免打扰延后的事件在 quietEnd 推送
+func (n *Notifier) enqueueLocked(channels []Channel, e Event, quietEnd time.Time) {
+	if n.pending == nil {
+		n.pending = make(map[string][]pendingEvent)
+	}
+	for _, c := range channels {
+		item := pendingEvent{event: e, due: quietEnd, quiet: !quietEnd.IsZero()}
+		if !item.quiet {
+			item.due = e.Time.Add(n.digestInterval)
+			for _, p := range n.pending[c.Name()] {
+				if !p.quiet && p.due.Before(item.due) {
+					item.due = p.due
+				}
+			}
+		}
+		n.pending[c.Name()] = append(n.pending[c.Name()], item)
+	}
+	n.scheduleLocked()
+}
+
+// scheduleLocked 定时器设为最早的推送时间（调用方持有锁）
+func (n *Notifier) scheduleLocked() {
+	var next time.Time
+	for _, items := range n.pending {
+		for _, item := range items {
+			if next.IsZero() || item.due.Before(next) {
+				next = item.due
+			}
+		}
+	}
+	if n.timer != nil {
+		n.timer.Stop()
+		n.timer = nil
+	}
+	if next.IsZero() {
+		return
+	}
+	n.timer = time.AfterFunc(time.Until(next), func() { n.flushDue(time.Now()) })
+}
+
+// flushDue 推送到期的汇总（每个渠道合并为一条事件）
+func (n *Notifier) flushDue(now time.Time) {
+	n.mu.Lock()
+	digests := make(map[Channel]Event)
+	for _, c := range n.channels {
+		var due []Event
+		var kept []pendingEvent
+		for _, item := range n.pending[c.Name()] {
+			if item.due.After(now) {
+				kept = append(kept, item)
+			} else {
+				due = append(due, item.event)
+			}
+		}
+		if len(kept) == 0 {
+			delete(n.pending, c.Name())
+		} else {
+			n.pending[c.Name()] = kept
+		}
+		if len(due) > 0 {
+			digests[c] = digestEvent(due, now)
+		}
+	}
+	n.scheduleLocked()
+	n.mu.Unlock()
+
+	for c, e := range digests {
+		go n.send(c, e)
+	}
+}
+
+// PendingDigest 返回各渠道等待汇总推送的事件数
+func (n *Notifier) PendingDigest() map[string]int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	counts := make(map[string]int, len(n.pending))
+	for name, items := range n.pending {
+		counts[name] = len(items)
+	}
+	return counts
+}
+
+// digestMaxLines 汇总消息最多列出的事件数
+const digestMaxLines = 20
+
+// digestEvent 把多条事件合并为一条汇总事件（级别取最高，正文按时间列出标题并统计各类型数量）
+func digestEvent(events []Event, now time.Time) Event {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	severity := SeverityInfo
+	counts := make(map[string]int)
+	for _, e := range events {
+		if severityRank(e.Severity) > severityRank(severity) {
+			severity = e.Severity
+		}
+		counts[e.Type]++
+	}
+
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	var b strings.Builder
+	for i, t := range types {
+		if i > 0 {
+			b.WriteString("，")
+		}
+		fmt.Fprintf(&b, "%s ×%d", t, counts[t])
+	}
+	b.WriteString("\n")
+	for i, e := range events {
+		if i == digestMaxLines {
+			fmt.Fprintf(&b, "\n…另有%d条", len(events)-digestMaxLines)
+			break
+		}
+		fmt.Fprintf(&b, "\n%s %s %s", e.Time.Format("01-02 15:04"), severityIcon(e.Severity), e.Title)
+	}
+
+	return Event{
+		Type:     "notify.digest",
+		Severity: severity,
+		Title:    fmt.Sprintf("通知汇总：%d条", len(events)),
+		Message:  b.String(),
+		Time:     now,
+	}
+}
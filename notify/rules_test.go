@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingChannel 记录收到的事件
+type recordingChannel struct {
+	name   string
+	mu     sync.Mutex
+	events []Event
+	sent   chan struct{}
+}
+
+func newRecordingChannel(name string) *recordingChannel {
+	return &recordingChannel{name: name, sent: make(chan struct{}, 16)}
+}
+
+func (c *recordingChannel) Name() string { return c.name }
+
+func (c *recordingChannel) Send(e Event) error {
+	c.mu.Lock()
+	c.events = append(c.events, e)
+	c.mu.Unlock()
+	c.sent <- struct{}{}
+	return nil
+}
+
+// wait 等待收到 n 条事件并返回全部事件
+func (c *recordingChannel) wait(t *testing.T, n int) []Event {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-c.sent:
+		case <-time.After(time.Second):
+			t.Fatalf("渠道 %s 只收到 %d 条事件，期望 %d 条", c.name, i, n)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Event(nil), c.events...)
+}
+
+// none 确认渠道没有收到事件
+func (c *recordingChannel) none(t *testing.T) {
+	t.Helper()
+	select {
+	case <-c.sent:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		t.Fatalf("渠道 %s 不应收到事件: %+v", c.name, c.events)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotifierRulesRouting(t *testing.T) {
+	tg, wh := newRecordingChannel("telegram"), newRecordingChannel("webhook")
+	n := New(tg, wh)
+	err := n.SetRules([]Rule{
+		{Events: []string{"trade.opened"}, Delivery: DeliveryDrop},
+		{Events: []string{"listing.*"}, Symbols: []string{"ETHUSDT"}, Channels: []string{"webhook"}},
+		{MinSeverity: SeverityCritical, Channels: []string{"telegram"}},
+	}, time.Hour, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n.Notify(Event{Type: "trade.opened", Severity: SeverityCritical})
+	tg.none(t)
+	wh.none(t)
+
+	n.Notify(Event{Type: "listing.delisting", Symbol: "ethusdt", Severity: SeverityWarning})
+	if events := wh.wait(t, 1); events[0].Type != "listing.delisting" {
+		t.Fatalf("webhook 应收到下架事件: %+v", events)
+	}
+	tg.none(t)
+
+	// 其他币种的下架事件不匹配第二条规则，critical 按第三条只推送到 Telegram
+	n.Notify(Event{Type: "listing.delisting", Symbol: "BTCUSDT", Severity: SeverityCritical})
+	tg.wait(t, 1)
+	wh.none(t)
+
+	// 没有规则匹配时推送到所有渠道
+	n.Notify(Event{Type: "watchdog.stall", Severity: SeverityInfo})
+	tg.wait(t, 1)
+	wh.wait(t, 1)
+}
+
+func TestNotifierDigest(t *testing.T) {
+	tg := newRecordingChannel("telegram")
+	n := New(tg)
+	if err := n.SetRules([]Rule{{Events: []string{"trade.*"}, Delivery: DeliveryDigest}}, time.Hour, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	n.Notify(Event{Type: "trade.opened", Severity: SeverityInfo, Title: "开多 ETHUSDT", Time: start})
+	n.Notify(Event{Type: "trade.opened", Severity: SeverityWarning, Title: "开空 BTCUSDT", Time: start.Add(time.Minute)})
+	tg.none(t)
+	if pending := n.PendingDigest()["telegram"]; pending != 2 {
+		t.Fatalf("应有2条事件等待汇总，实际 %d", pending)
+	}
+
+	// 汇总周期未到不推送，到期后合并为一条
+	n.flushDue(start.Add(30 * time.Minute))
+	tg.none(t)
+	n.flushDue(start.Add(time.Hour))
+	events := tg.wait(t, 1)
+	digest := events[0]
+	if digest.Type != "notify.digest" || digest.Severity != SeverityWarning || !strings.Contains(digest.Title, "2条") {
+		t.Fatalf("汇总事件不符合预期: %+v", digest)
+	}
+	if !strings.Contains(digest.Message, "trade.opened ×2") || !strings.Contains(digest.Message, "开空 BTCUSDT") {
+		t.Fatalf("汇总正文应列出事件: %s", digest.Message)
+	}
+	if pending := n.PendingDigest()["telegram"]; pending != 0 {
+		t.Fatalf("推送后不应再有待汇总事件，实际 %d", pending)
+	}
+}
+
+func TestNotifierQuietHours(t *testing.T) {
+	tg := newRecordingChannel("telegram")
+	n := New(tg)
+	if err := n.SetRules([]Rule{{QuietHours: "23:00-07:00"}}, time.Hour, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+
+	// 使用未来的日期，避免定时器在测试中到期
+	day := time.Now().UTC().AddDate(0, 0, 2)
+	night := time.Date(day.Year(), day.Month(), day.Day(), 23, 30, 0, 0, time.UTC)
+	morning := night.Add(7*time.Hour + 30*time.Minute)
+	n.Notify(Event{Type: "listing.new", Severity: SeverityWarning, Title: "新上架", Time: night})
+	tg.none(t)
+
+	// critical 不受免打扰限制
+	n.Notify(Event{Type: "risk.flash_crash", Severity: SeverityCritical, Time: night})
+	tg.wait(t, 1)
+
+	// 免打扰结束（次日07:00）时推送汇总
+	n.flushDue(morning.Add(-time.Minute))
+	tg.none(t)
+	n.flushDue(morning)
+	events := tg.wait(t, 1)
+	if last := events[len(events)-1]; last.Type != "notify.digest" || !strings.Contains(last.Message, "新上架") {
+		t.Fatalf("免打扰结束应推送汇总: %+v", last)
+	}
+
+	// 白天照常立即推送
+	n.Notify(Event{Type: "listing.new", Severity: SeverityInfo, Time: morning.Add(5 * time.Hour)})
+	tg.wait(t, 1)
+}
+
+func TestSetRulesRejectsInvalid(t *testing.T) {
+	n := New()
+	if err := n.SetRules([]Rule{{QuietHours: "23:00"}}, time.Hour, nil); err == nil {
+		t.Fatal("免打扰时段格式错误时应返回错误")
+	}
+	if err := n.SetRules([]Rule{{Delivery: "later"}}, time.Hour, nil); err == nil {
+		t.Fatal("未知推送方式应返回错误")
+	}
+}
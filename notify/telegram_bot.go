@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Telegram 双向命令：长轮询 getUpdates 接收命令，执行后回复到发送命令的聊天。
+// 只处理 chat ID 白名单内的消息，其它聊天的命令直接忽略（不回复，避免暴露bot用途）。
+
+// telegramPollTimeout getUpdates 长轮询的等待时间
+const telegramPollTimeout = 30 * time.Second
+
+// telegramMessageLimit Telegram单条消息的最大长度（字符）
+const telegramMessageLimit = 4096
+
+// CommandHandler 处理一条命令（不含斜杠，小写）并返回回复文本
+type CommandHandler func(command string, args []string) string
+
+// TelegramBot 接收Telegram命令的bot
+type TelegramBot struct {
+	token   string
+	apiURL  string
+	allowed map[string]bool
+	handler CommandHandler
+	client  *http.Client
+	offset  int64
+}
+
+// NewTelegramBot 创建命令bot，allowedChatIDs 为允许发送命令的chat ID
+func NewTelegramBot(token string, allowedChatIDs []string, handler CommandHandler) *TelegramBot {
+	allowed := make(map[string]bool, len(allowedChatIDs))
+	for _, id := range allowedChatIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			allowed[id] = true
+		}
+	}
+	return &TelegramBot{
+		token:   token,
+		apiURL:  "https://api.telegram.org",
+		allowed: allowed,
+		handler: handler,
+		// 长轮询请求会挂起 telegramPollTimeout，超时要比它长
+		client: &http.Client{Timeout: telegramPollTimeout + 10*time.Second},
+	}
+}
+
+// telegramUpdate getUpdates 返回的更新（只解析文本消息）
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+// Start 启动命令轮询，返回停止函数
+func (b *TelegramBot) Start() func() {
+	stop := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				log.Println("🤖 Telegram命令已停止")
+				return
+			default:
+			}
+
+			updates, err := b.getUpdates()
+			if err != nil {
+				log.Printf("⚠️ 获取Telegram命令失败: %v", err)
+				select {
+				case <-time.After(5 * time.Second):
+				case <-stop:
+					log.Println("🤖 Telegram命令已停止")
+					return
+				}
+				continue
+			}
+			for _, u := range updates {
+				b.offset = u.UpdateID + 1
+				b.handleUpdate(u)
+			}
+		}
+	}()
+
+	log.Printf("🤖 已启动Telegram命令：%d 个授权聊天", len(b.allowed))
+
+	return func() { close(stop) }
+}
+
+// getUpdates 拉取新的消息
+func (b *TelegramBot) getUpdates() ([]telegramUpdate, error) {
+	query := url.Values{}
+	query.Set("offset", fmt.Sprint(b.offset))
+	query.Set("timeout", fmt.Sprint(int(telegramPollTimeout.Seconds())))
+	query.Set("allowed_updates", `["message"]`)
+
+	resp, err := b.client.Get(fmt.Sprintf("%s/bot%s/getUpdates?%s", b.apiURL, b.token, query.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool             `json:"ok"`
+		Description string           `json:"description"`
+		Result      []telegramUpdate `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, result.Description)
+	}
+	return result.Result, nil
+}
+
+// handleUpdate 校验来源并执行命令
+func (b *TelegramBot) handleUpdate(u telegramUpdate) {
+	if u.Message == nil || !strings.HasPrefix(u.Message.Text, "/") {
+		return
+	}
+	chatID := fmt.Sprint(u.Message.Chat.ID)
+	if !b.allowed[chatID] {
+		log.Printf("⚠️ 忽略未授权聊天 %s 的Telegram命令: %s", chatID, u.Message.Text)
+		return
+	}
+
+	command, args := ParseCommand(u.Message.Text)
+	log.Printf("🤖 Telegram命令 (chat %s): %s", chatID, u.Message.Text)
+	reply := b.handler(command, args)
+	if reply == "" {
+		return
+	}
+	if err := b.reply(chatID, reply); err != nil {
+		log.Printf("⚠️ 回复Telegram命令失败: %v", err)
+	}
+}
+
+// ParseCommand 拆分命令和参数（/pause@mybot trader1 30 -> pause, [trader1 30]）
+func ParseCommand(text string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	command := strings.TrimPrefix(fields[0], "/")
+	if i := strings.Index(command, "@"); i >= 0 {
+		command = command[:i] // 群聊中的 /command@botname
+	}
+	return strings.ToLower(command), fields[1:]
+}
+
+// reply 回复到指定聊天，超长时截断
+func (b *TelegramBot) reply(chatID, text string) error {
+	if runes := []rune(text); len(runes) > telegramMessageLimit {
+		text = string(runes[:telegramMessageLimit-1]) + "…"
+	}
+	form := url.Values{}
+	form.Set("chat_id", chatID)
+	form.Set("text", text)
+	resp, err := httpClient.PostForm(fmt.Sprintf("%s/bot%s/sendMessage", b.apiURL, b.token), form)
+	if err != nil {
+		return err
+	}
+	return checkResponse(resp)
+}
@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTelegram 模拟 Telegram Bot API：getUpdates 返回预设的更新，记录 sendMessage
+type fakeTelegram struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	updates []telegramUpdate
+	offsets []string
+	sent    []map[string]string // chat_id, text
+}
+
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+	t.Helper()
+	f := &fakeTelegram{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+			f.offsets = append(f.offsets, r.URL.Query().Get("offset"))
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": f.updates})
+			f.updates = nil
+		case strings.HasSuffix(r.URL.Path, "/sendMessage"):
+			r.ParseForm()
+			f.sent = append(f.sent, map[string]string{"chat_id": r.Form.Get("chat_id"), "text": r.Form.Get("text")})
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeTelegram) Sent() []map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]string(nil), f.sent...)
+}
+
+// message 构造一条文本消息更新
+func message(updateID, chatID int64, text string) telegramUpdate {
+	var u telegramUpdate
+	data, _ := json.Marshal(map[string]interface{}{
+		"update_id": updateID,
+		"message":   map[string]interface{}{"text": text, "chat": map[string]interface{}{"id": chatID}},
+	})
+	json.Unmarshal(data, &u)
+	return u
+}
+
+// commandRecorder 记录收到的命令并返回固定回复
+type commandRecorder struct {
+	calls []string
+	reply string
+}
+
+func (c *commandRecorder) handle(command string, args []string) string {
+	c.calls = append(c.calls, strings.Join(append([]string{command}, args...), " "))
+	return c.reply
+}
+
+func TestTelegramBotHandleUpdate(t *testing.T) {
+	api := newFakeTelegram(t)
+	handler := &commandRecorder{reply: "ok"}
+	bot := NewTelegramBot("token", []string{" 100 ", "", "-200"}, handler.handle)
+	bot.apiURL = api.server.URL
+
+	bot.handleUpdate(message(1, 999, "/pause all"))            // 未授权聊天：忽略且不回复
+	bot.handleUpdate(message(2, 100, "hello"))                 // 不是命令
+	bot.handleUpdate(telegramUpdate{UpdateID: 3})              // 不是文本消息
+	bot.handleUpdate(message(4, 100, "/Pause@nofx_bot t1 30")) // 群聊格式的命令
+	bot.handleUpdate(message(5, -200, "/status"))
+
+	if got := strings.Join(handler.calls, "|"); got != "pause t1 30|status" {
+		t.Fatalf("只应执行授权聊天的命令: %q", got)
+	}
+	sent := api.Sent()
+	if len(sent) != 2 || sent[0]["chat_id"] != "100" || sent[1]["chat_id"] != "-200" || sent[0]["text"] != "ok" {
+		t.Fatalf("回复应发到发送命令的聊天: %+v", sent)
+	}
+
+	// 空回复不发送；超长回复截断
+	handler.reply = ""
+	bot.handleUpdate(message(6, 100, "/noop"))
+	handler.reply = strings.Repeat("长", telegramMessageLimit+10)
+	bot.handleUpdate(message(7, 100, "/long"))
+	sent = api.Sent()
+	if len(sent) != 3 {
+		t.Fatalf("空回复不应发送: %d 条", len(sent))
+	}
+	if text := []rune(sent[2]["text"]); len(text) != telegramMessageLimit || !strings.HasSuffix(sent[2]["text"], "…") {
+		t.Errorf("超长回复应截断到 %d 字符，实际 %d", telegramMessageLimit, len(text))
+	}
+}
+
+func TestTelegramBotGetUpdatesOffset(t *testing.T) {
+	api := newFakeTelegram(t)
+	api.updates = []telegramUpdate{message(41, 100, "/status"), message(42, 100, "/pnl")}
+	bot := NewTelegramBot("token", []string{"100"}, func(string, []string) string { return "" })
+	bot.apiURL = api.server.URL
+
+	updates, err := bot.getUpdates()
+	if err != nil || len(updates) != 2 || updates[1].Message.Text != "/pnl" {
+		t.Fatalf("getUpdates = %+v, %v", updates, err)
+	}
+	bot.offset = updates[1].UpdateID + 1
+	if _, err := bot.getUpdates(); err != nil {
+		t.Fatal(err)
+	}
+	if api.offsets[0] != "0" || api.offsets[1] != "43" {
+		t.Errorf("下次拉取应从最后一条之后开始: %v", api.offsets)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text    string
+		command string
+		args    string
+	}{
+		{"/status", "status", ""},
+		{"/Close ethusdt long", "close", "ethusdt long"},
+		{"/pause@nofx_bot  t1   30 ", "pause", "t1 30"},
+		{"   ", "", ""},
+	}
+	for _, tt := range tests {
+		command, args := ParseCommand(tt.text)
+		if command != tt.command || strings.Join(args, " ") != tt.args {
+			t.Errorf("ParseCommand(%q) = %q %q", tt.text, command, args)
+		}
+	}
+}
@@ -6,13 +6,18 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"nofx/market"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
-// defaultMainstreamCoins 默认主流币种池（从配置文件读取）
+// defaultCoinsMu 保护 defaultMainstreamCoins（远程列表刷新时在后台更新）
+var defaultCoinsMu sync.RWMutex
+
+// defaultMainstreamCoins 默认主流币种池（从配置文件或远程列表读取）
 var defaultMainstreamCoins = []string{
 	"BTCUSDT",
 	"ETHUSDT",
@@ -86,23 +91,32 @@ func SetUseDefaultCoins(useDefault bool) {
 // SetDefaultCoins 设置默认主流币种列表
 func SetDefaultCoins(coins []string) {
 	if len(coins) > 0 {
+		defaultCoinsMu.Lock()
 		defaultMainstreamCoins = coins
+		defaultCoinsMu.Unlock()
 		log.Printf("✓ 已设置默认币种池（共%d个币种）: %v", len(coins), coins)
 	}
 }
 
+// GetDefaultCoins 当前的默认主流币种列表
+func GetDefaultCoins() []string {
+	defaultCoinsMu.RLock()
+	defer defaultCoinsMu.RUnlock()
+	return append([]string(nil), defaultMainstreamCoins...)
+}
+
 // GetCoinPool 获取币种池列表（带重试和缓存机制）
 func GetCoinPool() ([]CoinInfo, error) {
 	// 优先检查是否启用默认币种列表
 	if coinPoolConfig.UseDefaultCoins {
 		log.Printf("✓ 已启用默认主流币种列表")
-		return convertSymbolsToCoins(defaultMainstreamCoins), nil
+		return convertSymbolsToCoins(GetDefaultCoins()), nil
 	}
 
 	// 检查API URL是否配置
 	if strings.TrimSpace(coinPoolConfig.APIURL) == "" {
 		log.Printf("⚠️  未配置币种池API URL，使用默认主流币种列表")
-		return convertSymbolsToCoins(defaultMainstreamCoins), nil
+		return convertSymbolsToCoins(GetDefaultCoins()), nil
 	}
 
 	maxRetries := 3
@@ -141,7 +155,7 @@ func GetCoinPool() ([]CoinInfo, error) {
 
 	// 缓存也失败，使用默认主流币种
 	log.Printf("⚠️  无法加载缓存数据（最后错误: %v），使用默认主流币种列表", lastErr)
-	return convertSymbolsToCoins(defaultMainstreamCoins), nil
+	return convertSymbolsToCoins(GetDefaultCoins()), nil
 }
 
 // fetchCoinPool 实际执行币种池请求
@@ -324,9 +338,9 @@ func normalizeSymbol(symbol string) string {
 	// 转为大写
 	symbol = toUpper(symbol)
 
-	// 确保以USDT结尾
-	if !endsWith(symbol, "USDT") {
-		symbol = symbol + "USDT"
+	// 已带计价币种（USDT/USDC/FDUSD等）的保持不变，否则默认USDT
+	if _, quote := market.SplitSymbol(symbol); quote == "" && !market.IsQuoteAsset(symbol) {
+		symbol = symbol + market.DefaultQuote
 	}
 
 	return symbol
@@ -355,13 +369,6 @@ func toUpper(s string) string {
 	return result
 }
 
-func endsWith(s, suffix string) bool {
-	if len(s) < len(suffix) {
-		return false
-	}
-	return s[len(s)-len(suffix):] == suffix
-}
-
 // convertSymbolsToCoins 将币种符号列表转换为CoinInfo列表
 func convertSymbolsToCoins(symbols []string) []CoinInfo {
 	coins := make([]CoinInfo, 0, len(symbols))
@@ -643,3 +650,17 @@ func GetMergedCoinPool(ai500Limit int) (*MergedCoinPool, error) {
 
 	return merged, nil
 }
+
+// ConvertQuote 把候选币种换成trader使用的计价币种（AI500/OI Top按USDT交易对给出，如 ETHUSDT -> ETHUSDC）
+func (m *MergedCoinPool) ConvertQuote(quote string) {
+	if quote == "" || quote == market.DefaultQuote {
+		return
+	}
+	sources := make(map[string][]string, len(m.SymbolSources))
+	for i, symbol := range m.AllSymbols {
+		converted := market.WithQuote(symbol, quote)
+		m.AllSymbols[i] = converted
+		sources[converted] = m.SymbolSources[symbol]
+	}
+	m.SymbolSources = sources
+}
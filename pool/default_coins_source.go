@@ -0,0 +1,397 @@
+package pool
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"nofx/notify"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 远程默认币种列表
+// 多个实例部署时，默认币种列表可以放在一个http(s)地址或S3对象上集中维护，按间隔刷新，不需要逐个修改配置。
+// 请求带上次的 ETag / Last-Modified（If-None-Match / If-Modified-Since），未变化时服务端返回304，不重复下载。
+// 最近一次成功获取的列表保存在币种池缓存目录，重启后远程源不可用时仍使用它；请求失败或列表为空时保留当前列表。
+// 列表格式：JSON数组 ["BTCUSDT", ...]、{"coins": [...]}，或每行一个币种的文本（# 开头为注释）。
+
+const defaultCoinsCacheFile = "default_coins.json"
+
+// DefaultCoinsSource 远程默认币种列表的来源
+type DefaultCoinsSource struct {
+	URL               string // http(s):// 地址或 s3://bucket/key
+	S3Region          string // S3区域（默认us-east-1）
+	S3Endpoint        string // S3兼容服务地址（如MinIO，为空时使用AWS）
+	S3AccessKeyID     string // 为空时匿名访问（公开对象）
+	S3SecretAccessKey string
+}
+
+// DefaultCoinsStatus 远程默认币种列表的同步状态（用于API）
+type DefaultCoinsStatus struct {
+	Source       string    `json:"source"` // 远程地址，不含查询参数和用户信息（未配置时为 "config"）
+	Coins        []string  `json:"coins"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at,omitempty"` // 最近一次列表变化
+	CheckedAt    time.Time `json:"checked_at,omitempty"` // 最近一次请求
+	LastError    string    `json:"last_error,omitempty"` // 最近一次请求失败的原因（成功后清空）
+}
+
+// defaultCoinsCache 持久化的远程列表
+type defaultCoinsCache struct {
+	Source       string    `json:"source"`
+	Coins        []string  `json:"coins"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// defaultCoinsRefresher 定时同步远程默认币种列表
+type defaultCoinsRefresher struct {
+	source DefaultCoinsSource
+	client *http.Client
+
+	mu     sync.Mutex
+	status DefaultCoinsStatus
+}
+
+var (
+	activeRefresherMu sync.Mutex
+	activeRefresher   *defaultCoinsRefresher
+)
+
+// StartDefaultCoinsRefresher 立即同步一次远程默认币种列表，之后每隔interval刷新，返回停止函数
+func StartDefaultCoinsRefresher(source DefaultCoinsSource, interval time.Duration) func() {
+	r := &defaultCoinsRefresher{
+		source: source,
+		client: &http.Client{Timeout: coinPoolConfig.Timeout},
+		status: DefaultCoinsStatus{Source: displaySource(source.URL)},
+	}
+	activeRefresherMu.Lock()
+	activeRefresher = r
+	activeRefresherMu.Unlock()
+
+	r.loadCache()
+	r.refresh()
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.refresh()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
+
+// GetDefaultCoinsStatus 默认币种列表及其远程同步状态
+func GetDefaultCoinsStatus() DefaultCoinsStatus {
+	activeRefresherMu.Lock()
+	r := activeRefresher
+	activeRefresherMu.Unlock()
+	if r == nil {
+		return DefaultCoinsStatus{Source: "config", Coins: GetDefaultCoins()}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := r.status
+	status.Coins = GetDefaultCoins()
+	return status
+}
+
+// refresh 请求一次远程列表，有变化时替换默认币种列表
+func (r *defaultCoinsRefresher) refresh() {
+	r.mu.Lock()
+	etag, lastModified := r.status.ETag, r.status.LastModified
+	r.mu.Unlock()
+
+	coins, newETag, newLastModified, err := r.fetch(etag, lastModified)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.CheckedAt = time.Now()
+	if err != nil {
+		r.status.LastError = err.Error()
+		log.Printf("⚠️  刷新远程默认币种列表失败（继续使用当前列表）: %v", err)
+		return
+	}
+	r.status.LastError = ""
+	if coins == nil {
+		return // 304 未变化
+	}
+	r.status.ETag, r.status.LastModified = newETag, newLastModified
+
+	added, removed := diffSymbols(GetDefaultCoins(), coins)
+	if len(added) > 0 || len(removed) > 0 {
+		r.status.UpdatedAt = time.Now()
+		SetDefaultCoins(coins)
+		log.Printf("🔄 远程默认币种列表已更新: 新增 %v，移除 %v", added, removed)
+		notify.Send(notify.Event{
+			Type:     "pool.default_coins_updated",
+			Severity: notify.SeverityInfo,
+			Title:    fmt.Sprintf("默认币种列表已更新（共%d个币种）", len(coins)),
+			Message:  fmt.Sprintf("新增: %s\n移除: %s", formatSymbols(added), formatSymbols(removed)),
+		})
+	}
+	if err := r.saveCache(coins); err != nil {
+		log.Printf("⚠️  保存远程默认币种列表缓存失败: %v", err)
+	}
+}
+
+// fetch 条件请求远程列表；未变化（304）时coins为nil
+func (r *defaultCoinsRefresher) fetch(etag, lastModified string) (coins []string, newETag, newLastModified string, err error) {
+	req, err := r.newRequest()
+	if err != nil {
+		return nil, "", "", err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, "", "", nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", fmt.Errorf("返回错误 (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	coins, err = parseDefaultCoins(body)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return coins, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"), nil
+}
+
+// newRequest 构造GET请求（s3:// 地址按配置的密钥做SigV4签名）
+func (r *defaultCoinsRefresher) newRequest() (*http.Request, error) {
+	u, err := url.Parse(r.source.URL)
+	if err != nil {
+		return nil, fmt.Errorf("无效的地址: %w", err)
+	}
+	if u.Scheme != "s3" {
+		return http.NewRequest(http.MethodGet, r.source.URL, nil)
+	}
+
+	region := r.source.S3Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("S3地址格式应为 s3://bucket/key: %s", r.source.URL)
+	}
+	var objectURL string
+	if r.source.S3Endpoint != "" {
+		// S3兼容服务使用路径风格
+		objectURL = strings.TrimRight(r.source.S3Endpoint, "/") + "/" + bucket + "/" + s3EscapePath(key)
+	} else {
+		objectURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, s3EscapePath(key))
+	}
+	req, err := http.NewRequest(http.MethodGet, objectURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.source.S3AccessKeyID != "" {
+		signS3Request(req, region, r.source.S3AccessKeyID, r.source.S3SecretAccessKey, time.Now().UTC())
+	}
+	return req, nil
+}
+
+// signS3Request AWS Signature V4 签名（不签名请求体）
+func signS3Request(req *http.Request, region, accessKeyID, secretAccessKey string, now time.Time) {
+	const payloadHash = "UNSIGNED-PAYLOAD"
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3EscapePath 按SigV4规则编码对象路径（保留 / 和非保留字符）
+func s3EscapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// parseDefaultCoins 解析列表（JSON数组、{"coins": [...]} 或每行一个币种），标准化并去重
+func parseDefaultCoins(body []byte) ([]string, error) {
+	text := strings.TrimSpace(string(body))
+	var raw []string
+	switch {
+	case strings.HasPrefix(text, "["):
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return nil, fmt.Errorf("JSON解析失败: %w", err)
+		}
+	case strings.HasPrefix(text, "{"):
+		var wrapped struct {
+			Coins []string `json:"coins"`
+		}
+		if err := json.Unmarshal([]byte(text), &wrapped); err != nil {
+			return nil, fmt.Errorf("JSON解析失败: %w", err)
+		}
+		raw = wrapped.Coins
+	default:
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			raw = append(raw, strings.Split(line, ",")...)
+		}
+	}
+
+	coins := make([]string, 0, len(raw))
+	seen := make(map[string]bool)
+	for _, symbol := range raw {
+		if strings.TrimSpace(symbol) == "" {
+			continue
+		}
+		symbol = normalizeSymbol(symbol)
+		if !seen[symbol] {
+			seen[symbol] = true
+			coins = append(coins, symbol)
+		}
+	}
+	if len(coins) == 0 {
+		return nil, fmt.Errorf("币种列表为空")
+	}
+	return coins, nil
+}
+
+// loadCache 启动时加载上次成功获取的列表（来源相同时），远程源不可用时使用
+func (r *defaultCoinsRefresher) loadCache() {
+	data, err := os.ReadFile(filepath.Join(coinPoolConfig.CacheDir, defaultCoinsCacheFile))
+	if err != nil {
+		return
+	}
+	var cache defaultCoinsCache
+	if err := json.Unmarshal(data, &cache); err != nil || cache.Source != r.source.URL || len(cache.Coins) == 0 {
+		return
+	}
+	SetDefaultCoins(cache.Coins)
+	r.mu.Lock()
+	r.status.ETag, r.status.LastModified, r.status.UpdatedAt = cache.ETag, cache.LastModified, cache.UpdatedAt
+	r.mu.Unlock()
+	log.Printf("📂 已加载缓存的远程默认币种列表（%s）", cache.UpdatedAt.Format("2006-01-02 15:04:05"))
+}
+
+// saveCache 保存最近一次获取的列表（调用方持有r.mu）
+func (r *defaultCoinsRefresher) saveCache(coins []string) error {
+	if err := os.MkdirAll(coinPoolConfig.CacheDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(defaultCoinsCache{
+		Source:       r.source.URL,
+		Coins:        coins,
+		ETag:         r.status.ETag,
+		LastModified: r.status.LastModified,
+		UpdatedAt:    r.status.UpdatedAt,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(coinPoolConfig.CacheDir, defaultCoinsCacheFile), data, 0644)
+}
+
+// diffSymbols 新列表相对旧列表新增和移除的币种
+func diffSymbols(old, updated []string) (added, removed []string) {
+	inOld := make(map[string]bool, len(old))
+	for _, s := range old {
+		inOld[s] = true
+	}
+	inUpdated := make(map[string]bool, len(updated))
+	for _, s := range updated {
+		inUpdated[s] = true
+		if !inOld[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range old {
+		if !inUpdated[s] {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+// displaySource 去掉地址中的用户信息和查询参数（可能带令牌）
+func displaySource(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(invalid)"
+	}
+	u.User, u.RawQuery = nil, ""
+	return u.String()
+}
+
+// formatSymbols 币种列表（空时为“无”）
+func formatSymbols(symbols []string) string {
+	if len(symbols) == 0 {
+		return "无"
+	}
+	return strings.Join(symbols, ", ")
+}
@@ -0,0 +1,138 @@
+package pool
+
+import (
+	"nofx/market"
+	"sort"
+	"sync"
+)
+
+// SymbolFilter 单个trader的币种黑白名单
+// 黑名单中的币种永不开仓；白名单非空时进入白名单模式，只交易白名单中的币种。
+// 名单可以在运行中通过API更新，下一个周期生效，无需重启
+type SymbolFilter struct {
+	blacklist map[string]bool
+	whitelist map[string]bool
+	mu        sync.RWMutex
+}
+
+// NewSymbolFilter 创建黑白名单（币种自动标准化为 XXXUSDT 格式）
+func NewSymbolFilter(blacklist, whitelist []string) *SymbolFilter {
+	f := &SymbolFilter{}
+	f.Update(blacklist, whitelist)
+	return f
+}
+
+// Update 替换黑白名单
+func (f *SymbolFilter) Update(blacklist, whitelist []string) {
+	black := toSymbolSet(blacklist)
+	white := toSymbolSet(whitelist)
+
+	f.mu.Lock()
+	f.blacklist = black
+	f.whitelist = white
+	f.mu.Unlock()
+}
+
+// Blacklist 当前黑名单（已排序）
+func (f *SymbolFilter) Blacklist() []string {
+	if f == nil {
+		return []string{}
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return sortedSymbols(f.blacklist)
+}
+
+// Whitelist 当前白名单（已排序，为空表示不限制）
+func (f *SymbolFilter) Whitelist() []string {
+	if f == nil {
+		return []string{}
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return sortedSymbols(f.whitelist)
+}
+
+// WhitelistMode 是否处于白名单模式
+func (f *SymbolFilter) WhitelistMode() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.whitelist) > 0
+}
+
+// Allowed 币种是否允许交易（nil 表示不限制）
+func (f *SymbolFilter) Allowed(symbol string) bool {
+	if f == nil {
+		return true
+	}
+	symbol = normalizeSymbol(symbol)
+	// 名单中的USDT交易对对该币种的其他计价币种同样生效（如黑名单 DOGEUSDT 也禁止 DOGEUSDC）
+	usdtSymbol := market.WithQuote(symbol, market.DefaultQuote)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.blacklist[symbol] || f.blacklist[usdtSymbol] {
+		return false
+	}
+	if len(f.whitelist) > 0 && !f.whitelist[symbol] && !f.whitelist[usdtSymbol] {
+		return false
+	}
+	return true
+}
+
+// Filter 过滤出允许交易的币种（保持原有顺序）
+func (f *SymbolFilter) Filter(symbols []string) []string {
+	allowed := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		if f.Allowed(symbol) {
+			allowed = append(allowed, symbol)
+		}
+	}
+	return allowed
+}
+
+func toSymbolSet(symbols []string) map[string]bool {
+	set := make(map[string]bool, len(symbols))
+	for _, s := range symbols {
+		if s = normalizeSymbol(s); !market.IsQuoteAsset(s) {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+func sortedSymbols(set map[string]bool) []string {
+	symbols := make([]string, 0, len(set))
+	for s := range set {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// ApplySymbolFilter 按trader的黑白名单过滤候选币种
+// 白名单模式下候选币种就是白名单（不在AI500/OI Top中的币种来源标记为 "whitelist"）
+func (m *MergedCoinPool) ApplySymbolFilter(f *SymbolFilter) {
+	if f == nil {
+		return
+	}
+
+	if f.WhitelistMode() {
+		m.AllSymbols = m.AllSymbols[:0]
+		for _, symbol := range f.Whitelist() {
+			if !f.Allowed(symbol) {
+				continue // 同时在黑名单中，黑名单优先
+			}
+			m.AllSymbols = append(m.AllSymbols, symbol)
+			if len(m.SymbolSources[symbol]) == 0 {
+				m.SymbolSources[symbol] = []string{"whitelist"}
+			}
+		}
+		return
+	}
+
+	m.AllSymbols = f.Filter(m.AllSymbols)
+}
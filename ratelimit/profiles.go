@@ -0,0 +1,177 @@
+package ratelimit
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 各交易所文档中的限频规则
+// Binance/Aster: 按权重计算（REQUEST_WEIGHT 2400/分钟，下单 300/10秒、1200/分钟）
+// Gate.io: 按端点计算（下单/撤单 100次/秒，其余每个端点 200次/10秒）
+// OKX: 按端点计算（每个端点 20~40次/2秒）
+// Hyperliquid: 按权重计算（1200/分钟，/exchange 权重1，/info 权重2~20）
+
+func binanceStyleProfile(exchange, prefix string) Profile {
+	order := map[string]int{"weight": 1, "orders_10s": 1, "orders_1m": 1}
+	return Profile{
+		Exchange: exchange,
+		Buckets: []BucketSpec{
+			{Name: "weight", Limit: 2400, Window: time.Minute},
+			{Name: "orders_10s", Limit: 300, Window: 10 * time.Second},
+			{Name: "orders_1m", Limit: 1200, Window: time.Minute},
+		},
+		Rules: []EndpointRule{
+			{Method: "POST", Prefix: prefix + "/v1/order", Costs: order, Priority: PriorityOrder},
+			{Method: "POST", Prefix: prefix + "/v3/order", Costs: order, Priority: PriorityOrder},
+			{Method: "POST", Prefix: prefix + "/v1/batchOrders", Costs: map[string]int{"weight": 5, "orders_10s": 5, "orders_1m": 1}, Priority: PriorityOrder},
+			{Method: "DELETE", Prefix: prefix + "/v1/", Costs: map[string]int{"weight": 1}, Priority: PriorityOrder},
+			{Method: "DELETE", Prefix: prefix + "/v3/", Costs: map[string]int{"weight": 1}, Priority: PriorityOrder},
+			{Prefix: prefix + "/v1/leverage", Costs: map[string]int{"weight": 1}, Priority: PriorityOrder},
+			{Prefix: prefix + "/v1/marginType", Costs: map[string]int{"weight": 1}, Priority: PriorityOrder},
+			{Prefix: prefix + "/v3/leverage", Costs: map[string]int{"weight": 1}, Priority: PriorityOrder},
+			{Prefix: prefix + "/v3/marginType", Costs: map[string]int{"weight": 1}, Priority: PriorityOrder},
+			{Prefix: prefix + "/v2/account", Costs: map[string]int{"weight": 5}, Priority: PriorityPosition},
+			{Prefix: prefix + "/v2/balance", Costs: map[string]int{"weight": 5}, Priority: PriorityPosition},
+			{Prefix: prefix + "/v2/positionRisk", Costs: map[string]int{"weight": 5}, Priority: PriorityPosition},
+			{Prefix: prefix + "/v3/account", Costs: map[string]int{"weight": 5}, Priority: PriorityPosition},
+			{Prefix: prefix + "/v3/balance", Costs: map[string]int{"weight": 5}, Priority: PriorityPosition},
+			{Prefix: prefix + "/v3/positionRisk", Costs: map[string]int{"weight": 5}, Priority: PriorityPosition},
+			{Prefix: prefix + "/v1/openOrders", Costs: map[string]int{"weight": 1}, Priority: PriorityPosition},
+			{Prefix: prefix + "/v3/openOrders", Costs: map[string]int{"weight": 1}, Priority: PriorityPosition},
+			{Prefix: prefix + "/v1/income", Costs: map[string]int{"weight": 30}, Priority: PriorityPosition},
+			{Prefix: prefix + "/v3/income", Costs: map[string]int{"weight": 30}, Priority: PriorityPosition},
+			{Prefix: prefix + "/v1/exchangeInfo", Costs: map[string]int{"weight": 1}, Priority: PriorityMarketData},
+			{Prefix: prefix + "/v1/klines", Costs: map[string]int{"weight": 2}, Priority: PriorityMarketData},
+			{Prefix: prefix + "/v1/ticker/price", Costs: map[string]int{"weight": 1}, Priority: PriorityMarketData},
+			{Prefix: prefix + "/v1/premiumIndex", Costs: map[string]int{"weight": 1}, Priority: PriorityMarketData},
+			{Prefix: prefix + "/v1/openInterest", Costs: map[string]int{"weight": 1}, Priority: PriorityMarketData},
+		},
+		DefaultRule: EndpointRule{Costs: map[string]int{"weight": 1}, Priority: PriorityPosition},
+	}
+}
+
+func gateioProfile() Profile {
+	return Profile{
+		Exchange: "gateio",
+		Buckets: []BucketSpec{
+			{Name: "orders", Limit: 100, Window: time.Second},
+			{Name: "price_orders", Limit: 100, Window: time.Second},
+			{Name: "positions", Limit: 200, Window: 10 * time.Second},
+			{Name: "accounts", Limit: 200, Window: 10 * time.Second},
+			{Name: "contracts", Limit: 200, Window: 10 * time.Second},
+			{Name: "candlesticks", Limit: 200, Window: 10 * time.Second},
+			{Name: "tickers", Limit: 200, Window: 10 * time.Second},
+			{Name: "other", Limit: 200, Window: 10 * time.Second},
+		},
+		Rules: []EndpointRule{
+			{Prefix: "/api/v4/futures/usdt/orders", Costs: map[string]int{"orders": 1}, Priority: PriorityOrder},
+			{Prefix: "/api/v4/futures/usdt/price_orders", Costs: map[string]int{"price_orders": 1}, Priority: PriorityOrder},
+			{Method: "POST", Prefix: "/api/v4/futures/usdt/positions", Costs: map[string]int{"positions": 1}, Priority: PriorityOrder},
+			{Prefix: "/api/v4/futures/usdt/positions", Costs: map[string]int{"positions": 1}, Priority: PriorityPosition},
+			{Prefix: "/api/v4/futures/usdt/accounts", Costs: map[string]int{"accounts": 1}, Priority: PriorityPosition},
+			{Prefix: "/api/v4/futures/usdt/contracts", Costs: map[string]int{"contracts": 1}, Priority: PriorityMarketData},
+			{Prefix: "/api/v4/futures/usdt/candlesticks", Costs: map[string]int{"candlesticks": 1}, Priority: PriorityMarketData},
+			{Prefix: "/api/v4/futures/usdt/tickers", Costs: map[string]int{"tickers": 1}, Priority: PriorityMarketData},
+		},
+		DefaultRule: EndpointRule{Costs: map[string]int{"other": 1}, Priority: PriorityPosition},
+	}
+}
+
+func okxProfile() Profile {
+	return Profile{
+		Exchange: "okx",
+		Buckets: []BucketSpec{
+			{Name: "candles", Limit: 40, Window: 2 * time.Second},
+			{Name: "open_interest", Limit: 20, Window: 2 * time.Second},
+			{Name: "funding_rate", Limit: 20, Window: 2 * time.Second},
+			{Name: "other", Limit: 20, Window: 2 * time.Second},
+		},
+		Rules: []EndpointRule{
+			{Prefix: "/api/v5/market/candles", Costs: map[string]int{"candles": 1}, Priority: PriorityMarketData},
+			{Prefix: "/api/v5/public/open-interest", Costs: map[string]int{"open_interest": 1}, Priority: PriorityMarketData},
+			{Prefix: "/api/v5/public/funding-rate", Costs: map[string]int{"funding_rate": 1}, Priority: PriorityMarketData},
+		},
+		DefaultRule: EndpointRule{Costs: map[string]int{"other": 1}, Priority: PriorityMarketData},
+	}
+}
+
+func hyperliquidProfile() Profile {
+	return Profile{
+		Exchange: "hyperliquid",
+		Buckets: []BucketSpec{
+			{Name: "weight", Limit: 1200, Window: time.Minute},
+		},
+		Rules: []EndpointRule{
+			{Prefix: "/exchange", Costs: map[string]int{"weight": 1}, Priority: PriorityOrder},
+			{Prefix: "/info", Costs: map[string]int{"weight": 2}, Priority: PriorityPosition},
+		},
+		DefaultRule: EndpointRule{Costs: map[string]int{"weight": 1}, Priority: PriorityPosition},
+	}
+}
+
+// DefaultProfile 返回交易所的默认限频配置，未知交易所返回保守配置
+func DefaultProfile(exchange string) Profile {
+	switch strings.ToLower(exchange) {
+	case "binance":
+		return binanceStyleProfile("binance", "/fapi")
+	case "aster":
+		return binanceStyleProfile("aster", "/fapi")
+	case "gateio":
+		return gateioProfile()
+	case "okx":
+		return okxProfile()
+	case "hyperliquid":
+		return hyperliquidProfile()
+	default:
+		return Profile{
+			Exchange:    strings.ToLower(exchange),
+			Buckets:     []BucketSpec{{Name: "requests", Limit: 10, Window: time.Second}},
+			DefaultRule: EndpointRule{Costs: map[string]int{"requests": 1}, Priority: PriorityPosition},
+		}
+	}
+}
+
+var (
+	schedulers   = make(map[string]*Scheduler)
+	schedulersMu sync.RWMutex
+)
+
+// Get 获取交易所的全局调度器（同一交易所的所有交易员和行情请求共享预算）
+func Get(exchange string) *Scheduler {
+	name := strings.ToLower(exchange)
+
+	schedulersMu.RLock()
+	s, ok := schedulers[name]
+	schedulersMu.RUnlock()
+	if ok {
+		return s
+	}
+
+	schedulersMu.Lock()
+	defer schedulersMu.Unlock()
+	if s, ok := schedulers[name]; ok {
+		return s
+	}
+	s = NewScheduler(DefaultProfile(name))
+	schedulers[name] = s
+	return s
+}
+
+// AllStats 返回所有已创建调度器的预算指标
+func AllStats() []SchedulerStats {
+	schedulersMu.RLock()
+	list := make([]*Scheduler, 0, len(schedulers))
+	for _, s := range schedulers {
+		list = append(list, s)
+	}
+	schedulersMu.RUnlock()
+
+	stats := make([]SchedulerStats, 0, len(list))
+	for _, s := range list {
+		stats = append(stats, s.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Exchange < stats[j].Exchange })
+	return stats
+}
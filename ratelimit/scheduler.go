@@ -0,0 +1,312 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Priority 请求优先级（数值越小越优先）
+type Priority int
+
+const (
+	PriorityOrder      Priority = iota // 下单/撤单/止盈止损/杠杆设置
+	PriorityPosition                   // 持仓/余额/账户查询
+	PriorityMarketData                 // 行情数据（K线、持仓量、资金费率）
+)
+
+// String 返回优先级名称
+func (p Priority) String() string {
+	switch p {
+	case PriorityOrder:
+		return "order"
+	case PriorityPosition:
+		return "position"
+	case PriorityMarketData:
+		return "market_data"
+	default:
+		return fmt.Sprintf("priority_%d", int(p))
+	}
+}
+
+const priorityLevels = 3
+
+// BucketSpec 限频窗口定义（交易所文档中的一条限制）
+type BucketSpec struct {
+	Name   string        // 窗口名称，如 "weight"、"orders_10s"
+	Limit  int           // 窗口内允许的总权重/次数
+	Window time.Duration // 窗口长度
+}
+
+// EndpointRule 端点规则：匹配请求并给出消耗的预算和优先级
+type EndpointRule struct {
+	Method   string         // HTTP方法，空表示任意
+	Prefix   string         // 路径前缀
+	Costs    map[string]int // 窗口名称 -> 消耗权重
+	Priority Priority
+}
+
+// Profile 交易所限频配置
+type Profile struct {
+	Exchange    string
+	Buckets     []BucketSpec
+	Rules       []EndpointRule // 按顺序匹配，第一个命中的生效
+	DefaultRule EndpointRule   // 未命中任何规则时使用
+}
+
+type bucket struct {
+	spec        BucketSpec
+	used        int
+	windowStart time.Time
+}
+
+// reset 窗口到期时清零（按交易所习惯对齐到整窗口）
+func (b *bucket) reset(now time.Time) {
+	start := now.Truncate(b.spec.Window)
+	if start.After(b.windowStart) {
+		b.windowStart = start
+		b.used = 0
+	}
+}
+
+func (b *bucket) remaining() int {
+	if r := b.spec.Limit - b.used; r > 0 {
+		return r
+	}
+	return 0
+}
+
+// BucketStats 单个窗口的预算指标
+type BucketStats struct {
+	Name      string  `json:"name"`
+	Limit     int     `json:"limit"`
+	Used      int     `json:"used"`
+	Remaining int     `json:"remaining"`
+	WindowSec float64 `json:"window_sec"`
+	ResetInMs int64   `json:"reset_in_ms"`
+}
+
+// SchedulerStats 调度器指标
+type SchedulerStats struct {
+	Exchange     string           `json:"exchange"`
+	Buckets      []BucketStats    `json:"buckets"`
+	Waiting      map[string]int   `json:"waiting"`       // 各优先级排队数
+	Granted      map[string]int64 `json:"granted"`       // 各优先级累计放行数
+	Throttled    int64            `json:"throttled"`     // 因预算不足而等待过的请求数
+	BannedUntil  *time.Time       `json:"banned_until"`  // 被交易所限频（429/418）后的冷却截止时间
+	LastThrottle *time.Time       `json:"last_throttle"` // 最近一次等待的时间
+}
+
+// Scheduler 单个交易所的请求调度器
+// 所有请求在发出前调用 Wait 申请预算；预算不足时按优先级排队，
+// 高优先级请求（下单）永远先于低优先级请求（行情）获得预算
+type Scheduler struct {
+	profile Profile
+
+	mu           sync.Mutex
+	buckets      map[string]*bucket
+	waiting      [priorityLevels]int
+	granted      [priorityLevels]int64
+	throttled    int64
+	bannedUntil  time.Time
+	lastThrottle time.Time
+	notify       chan struct{}
+}
+
+// NewScheduler 根据限频配置创建调度器
+func NewScheduler(profile Profile) *Scheduler {
+	s := &Scheduler{
+		profile: profile,
+		buckets: make(map[string]*bucket),
+		notify:  make(chan struct{}),
+	}
+	for _, spec := range profile.Buckets {
+		s.buckets[spec.Name] = &bucket{spec: spec}
+	}
+	return s
+}
+
+// Exchange 返回调度器对应的交易所名称
+func (s *Scheduler) Exchange() string {
+	return s.profile.Exchange
+}
+
+// Classify 根据HTTP方法和路径匹配端点规则
+func (s *Scheduler) Classify(method, path string) EndpointRule {
+	method = strings.ToUpper(method)
+	for _, rule := range s.profile.Rules {
+		if rule.Method != "" && rule.Method != method {
+			continue
+		}
+		if strings.HasPrefix(path, rule.Prefix) {
+			return rule
+		}
+	}
+	return s.profile.DefaultRule
+}
+
+// WaitRequest 为一个HTTP请求申请预算（按端点规则）
+func (s *Scheduler) WaitRequest(ctx context.Context, method, path string) error {
+	rule := s.Classify(method, path)
+	return s.Wait(ctx, rule.Priority, rule.Costs)
+}
+
+// Wait 申请预算，预算不足或有更高优先级请求排队时阻塞
+func (s *Scheduler) Wait(ctx context.Context, priority Priority, costs map[string]int) error {
+	if priority < 0 || int(priority) >= priorityLevels {
+		priority = PriorityMarketData
+	}
+
+	queued := false
+	defer func() {
+		if queued {
+			s.mu.Lock()
+			s.waiting[priority]--
+			s.mu.Unlock()
+			s.broadcast()
+		}
+	}()
+
+	for {
+		s.mu.Lock()
+		now := time.Now()
+		delay := s.delayLocked(now, priority, costs)
+		if delay == 0 {
+			for name, cost := range costs {
+				if b, ok := s.buckets[name]; ok {
+					b.used += cost
+				}
+			}
+			s.granted[priority]++
+			s.mu.Unlock()
+			return nil
+		}
+		if !queued {
+			queued = true
+			s.waiting[priority]++
+			s.throttled++
+			s.lastThrottle = now
+		}
+		notify := s.notify
+		s.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s 限频等待被取消: %w", s.profile.Exchange, ctx.Err())
+		case <-notify:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// delayLocked 计算需要等待的时间，0 表示可以立即放行（调用方需持有锁）
+func (s *Scheduler) delayLocked(now time.Time, priority Priority, costs map[string]int) time.Duration {
+	if now.Before(s.bannedUntil) {
+		return s.bannedUntil.Sub(now)
+	}
+
+	// 有更高优先级请求在排队时让行
+	for p := 0; p < int(priority); p++ {
+		if s.waiting[p] > 0 {
+			return 50 * time.Millisecond
+		}
+	}
+
+	var delay time.Duration
+	for name, cost := range costs {
+		b, ok := s.buckets[name]
+		if !ok {
+			continue
+		}
+		b.reset(now)
+		// 单次消耗超过整个窗口时只要求窗口为空，避免永久阻塞
+		if b.used == 0 || b.used+cost <= b.spec.Limit {
+			continue
+		}
+		if wait := b.windowStart.Add(b.spec.Window).Sub(now); wait > delay {
+			delay = wait
+		}
+	}
+	if delay > 0 && delay < time.Millisecond {
+		delay = time.Millisecond
+	}
+	return delay
+}
+
+// broadcast 唤醒所有等待者重新检查预算
+func (s *Scheduler) broadcast() {
+	s.mu.Lock()
+	close(s.notify)
+	s.notify = make(chan struct{})
+	s.mu.Unlock()
+}
+
+// SyncUsage 使用交易所返回的已用权重校正本地计数（如币安 X-MBX-USED-WEIGHT-1M）
+func (s *Scheduler) SyncUsage(bucketName string, used int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.buckets[bucketName]
+	if !ok {
+		return
+	}
+	b.reset(time.Now())
+	if used > b.used {
+		b.used = used
+	}
+}
+
+// Penalize 交易所返回限频错误（429/418）后暂停该交易所所有请求
+func (s *Scheduler) Penalize(d time.Duration) {
+	s.mu.Lock()
+	until := time.Now().Add(d)
+	if until.After(s.bannedUntil) {
+		s.bannedUntil = until
+	}
+	s.mu.Unlock()
+}
+
+// Stats 返回当前预算指标
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	stats := SchedulerStats{
+		Exchange:  s.profile.Exchange,
+		Waiting:   make(map[string]int, priorityLevels),
+		Granted:   make(map[string]int64, priorityLevels),
+		Throttled: s.throttled,
+	}
+	for p := 0; p < priorityLevels; p++ {
+		stats.Waiting[Priority(p).String()] = s.waiting[p]
+		stats.Granted[Priority(p).String()] = s.granted[p]
+	}
+	for _, spec := range s.profile.Buckets {
+		b := s.buckets[spec.Name]
+		b.reset(now)
+		stats.Buckets = append(stats.Buckets, BucketStats{
+			Name:      spec.Name,
+			Limit:     spec.Limit,
+			Used:      b.used,
+			Remaining: b.remaining(),
+			WindowSec: spec.Window.Seconds(),
+			ResetInMs: b.windowStart.Add(spec.Window).Sub(now).Milliseconds(),
+		})
+	}
+	sort.Slice(stats.Buckets, func(i, j int) bool { return stats.Buckets[i].Name < stats.Buckets[j].Name })
+	if now.Before(s.bannedUntil) {
+		until := s.bannedUntil
+		stats.BannedUntil = &until
+	}
+	if !s.lastThrottle.IsZero() {
+		last := s.lastThrottle
+		stats.LastThrottle = &last
+	}
+	return stats
+}
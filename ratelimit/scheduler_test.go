@@ -0,0 +1,240 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testScheduler(limit int, window time.Duration) *Scheduler {
+	return NewScheduler(Profile{
+		Exchange: "test",
+		Buckets:  []BucketSpec{{Name: "weight", Limit: limit, Window: window}},
+		Rules: []EndpointRule{
+			{Method: "POST", Prefix: "/order", Costs: map[string]int{"weight": 1}, Priority: PriorityOrder},
+		},
+		DefaultRule: EndpointRule{Costs: map[string]int{"weight": 1}, Priority: PriorityMarketData},
+	})
+}
+
+// waitQueued 等到指定优先级有请求排队
+func waitQueued(t *testing.T, s *Scheduler, p Priority, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); s.Stats().Waiting[p.String()] != n; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s 排队数应为 %d，实际 %d", p, n, s.Stats().Waiting[p.String()])
+		}
+	}
+}
+
+func TestWeightWindowReset(t *testing.T) {
+	const window = 100 * time.Millisecond
+	s := testScheduler(2, window)
+	ctx := context.Background()
+	cost := map[string]int{"weight": 1}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Wait(ctx, PriorityMarketData, cost); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := s.Stats()
+	if b := stats.Buckets[0]; b.Used != 2 || b.Remaining != 0 || b.ResetInMs > window.Milliseconds() {
+		t.Fatalf("窗口用尽: %+v", b)
+	}
+
+	// 预算用尽：等到下一个整窗口
+	start := time.Now()
+	if err := s.Wait(ctx, PriorityMarketData, cost); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	if nextWindow := start.Truncate(window).Add(window); time.Now().Before(nextWindow) || elapsed > window+50*time.Millisecond {
+		t.Fatalf("应在下一个窗口开始时放行，等待了 %v", elapsed)
+	}
+	stats = s.Stats()
+	if stats.Throttled != 1 || stats.LastThrottle == nil || stats.Granted["market_data"] != 3 {
+		t.Errorf("限频统计 = %+v", stats)
+	}
+	if b := stats.Buckets[0]; b.Used > 1 {
+		t.Errorf("新窗口只计本次请求: %+v", b)
+	}
+
+	// 单次消耗超过整个窗口：窗口为空时放行，不会永久阻塞
+	time.Sleep(window)
+	done := make(chan error, 1)
+	go func() { done <- s.Wait(ctx, PriorityOrder, map[string]int{"weight": 5}) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(3 * window):
+		t.Fatal("超过窗口上限的单次请求应在窗口为空时放行")
+	}
+}
+
+func TestHigherPriorityGoesFirst(t *testing.T) {
+	const window = 200 * time.Millisecond
+	s := testScheduler(1, window)
+	ctx := context.Background()
+	if err := s.Wait(ctx, PriorityMarketData, map[string]int{"weight": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	granted := make(chan Priority, 2)
+	wait := func(p Priority) {
+		if err := s.Wait(ctx, p, map[string]int{"weight": 1}); err != nil {
+			t.Error(err)
+		}
+		granted <- p
+	}
+	// 行情请求先排队，下单请求后到
+	go wait(PriorityMarketData)
+	waitQueued(t, s, PriorityMarketData, 1)
+	go wait(PriorityOrder)
+	waitQueued(t, s, PriorityOrder, 1)
+
+	if first := <-granted; first != PriorityOrder {
+		t.Fatalf("新窗口应先放行下单请求，实际 %s", first)
+	}
+	// 下单请求放行后行情请求不再让行，等到再下一个窗口
+	select {
+	case second := <-granted:
+		if second != PriorityMarketData {
+			t.Fatalf("第二个放行的应为行情请求，实际 %s", second)
+		}
+	case <-time.After(3 * window):
+		t.Fatal("行情请求应在下一个窗口放行")
+	}
+	if stats := s.Stats(); stats.Granted["order"] != 1 || stats.Granted["market_data"] != 2 || stats.Waiting["market_data"] != 0 {
+		t.Errorf("放行统计 = %+v", stats)
+	}
+}
+
+func TestBanBackoff(t *testing.T) {
+	s := testScheduler(100, time.Minute)
+
+	// 429 + Retry-After: 按交易所给出的时间暂停
+	before := time.Now()
+	s.Observe(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"2"}}})
+	stats := s.Stats()
+	if stats.BannedUntil == nil || stats.BannedUntil.Sub(before) < 2*time.Second || stats.BannedUntil.Sub(before) > 3*time.Second {
+		t.Fatalf("Retry-After 2秒: %v", stats.BannedUntil)
+	}
+	// 冷却期内请求阻塞（任何优先级）
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	err := s.Wait(ctx, PriorityOrder, map[string]int{"weight": 1})
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("冷却期内下单也应等待: %v", err)
+	}
+
+	// 418 没有 Retry-After：默认10秒；更短的暂停不会缩短冷却
+	s.Observe(&http.Response{StatusCode: 418, Header: http.Header{}})
+	banned := *s.Stats().BannedUntil
+	if banned.Sub(before) < 10*time.Second {
+		t.Fatalf("418 应默认暂停10秒: %v", banned.Sub(before))
+	}
+	s.Penalize(time.Second)
+	if got := *s.Stats().BannedUntil; !got.Equal(banned) {
+		t.Errorf("更短的暂停不应缩短冷却: %v -> %v", banned, got)
+	}
+
+	// 冷却结束后放行
+	s2 := testScheduler(100, time.Minute)
+	s2.Penalize(100 * time.Millisecond)
+	start := time.Now()
+	if err := s2.Wait(context.Background(), PriorityOrder, map[string]int{"weight": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("冷却结束前不应放行: %v", elapsed)
+	}
+	if s2.Stats().BannedUntil != nil {
+		t.Error("冷却结束后不应再显示暂停")
+	}
+}
+
+func TestObserveSyncsUsedWeight(t *testing.T) {
+	s := testScheduler(10, 24*time.Hour)
+	s.Observe(&http.Response{StatusCode: http.StatusOK, Header: http.Header{"X-Mbx-Used-Weight-1m": []string{"8"}}})
+	if b := s.Stats().Buckets[0]; b.Used != 8 {
+		t.Fatalf("应按交易所返回的已用权重校正: %+v", b)
+	}
+	// 交易所返回的值比本地少时保留本地计数
+	s.Observe(&http.Response{StatusCode: http.StatusOK, Header: http.Header{"X-Mbx-Used-Weight-1m": []string{"3"}}})
+	if b := s.Stats().Buckets[0]; b.Used != 8 {
+		t.Fatalf("不应调低本地计数: %+v", b)
+	}
+}
+
+func TestCancelWhileQueued(t *testing.T) {
+	s := testScheduler(1, 24*time.Hour)
+	if err := s.Wait(context.Background(), PriorityOrder, map[string]int{"weight": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Wait(ctx, PriorityOrder, map[string]int{"weight": 1}) }()
+	waitQueued(t, s, PriorityOrder, 1)
+
+	// 排队的下单请求被取消后，不带预算消耗的行情请求不再让行
+	free := make(chan error, 1)
+	go func() { free <- s.Wait(context.Background(), PriorityMarketData, nil) }()
+	waitQueued(t, s, PriorityMarketData, 1)
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("取消应返回 context.Canceled: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("取消后应立即返回")
+	}
+	select {
+	case err := <-free:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("高优先级请求取消后，低优先级请求应被唤醒")
+	}
+	stats := s.Stats()
+	if stats.Waiting["order"] != 0 || stats.Waiting["market_data"] != 0 || stats.Granted["order"] != 1 || stats.Buckets[0].Used != 1 {
+		t.Errorf("取消的请求不应计入: %+v", stats)
+	}
+}
+
+func TestTransportPenalizesOnTooManyRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	s := testScheduler(100, 24*time.Hour)
+	client := &http.Client{Transport: NewTransport(s, nil)}
+	resp, err := client.Post(server.URL+"/order", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	stats := s.Stats()
+	if stats.Granted["order"] != 1 || stats.BannedUntil == nil || time.Until(*stats.BannedUntil) < 29*time.Second {
+		t.Fatalf("POST /order 按下单优先级计数，429 后暂停: %+v", stats)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/klines", nil)
+	if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("暂停期间请求应等待到取消: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transport 在发送前向调度器申请预算的 http.RoundTripper
+// 可直接挂到第三方SDK的 http.Client 上（如 go-binance）
+type Transport struct {
+	Scheduler *Scheduler
+	Base      http.RoundTripper
+}
+
+// NewTransport 创建限频Transport，base 为空时使用 http.DefaultTransport
+func NewTransport(s *Scheduler, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Scheduler: s, Base: base}
+}
+
+// NewClient 创建带限频的 http.Client
+func NewClient(exchange string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: NewTransport(Get(exchange), nil),
+	}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.Scheduler.WaitRequest(req.Context(), req.Method, req.URL.Path); err != nil {
+		return nil, err
+	}
+	resp, err := t.Base.RoundTrip(req)
+	if resp != nil {
+		t.Scheduler.Observe(resp)
+	}
+	return resp, err
+}
+
+// Observe 根据响应头/状态码校正预算
+func (s *Scheduler) Observe(resp *http.Response) {
+	// 币安/Aster 在响应头中返回当前窗口已用权重
+	for key, values := range resp.Header {
+		if len(values) == 0 {
+			continue
+		}
+		lower := strings.ToLower(key)
+		switch {
+		case lower == "x-mbx-used-weight-1m":
+			if used, err := strconv.Atoi(values[0]); err == nil {
+				s.SyncUsage("weight", used)
+			}
+		case lower == "x-mbx-order-count-10s":
+			if used, err := strconv.Atoi(values[0]); err == nil {
+				s.SyncUsage("orders_10s", used)
+			}
+		case lower == "x-mbx-order-count-1m":
+			if used, err := strconv.Atoi(values[0]); err == nil {
+				s.SyncUsage("orders_1m", used)
+			}
+		}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == 418 {
+		backoff := 10 * time.Second
+		if sec, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && sec > 0 {
+			backoff = time.Duration(sec) * time.Second
+		}
+		log.Printf("⚠️ %s 返回限频错误 (status %d)，暂停请求 %v", s.profile.Exchange, resp.StatusCode, backoff)
+		s.Penalize(backoff)
+	}
+}
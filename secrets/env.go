@@ -0,0 +1,35 @@
+package secrets
+
+import (
+	"os"
+	"strings"
+)
+
+// EnvProvider 从环境变量读取密钥：secret://binance/key → NOFX_SECRET_BINANCE_KEY
+type EnvProvider struct{}
+
+// Name 来源名称
+func (EnvProvider) Name() string { return "env" }
+
+// Get 读取环境变量
+func (EnvProvider) Get(path string) (string, error) {
+	value, ok := os.LookupEnv(EnvName(path))
+	if !ok || value == "" {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// EnvName 密钥路径对应的环境变量名（字母数字以外的字符替换为下划线）
+func EnvName(path string) string {
+	var b strings.Builder
+	b.WriteString("NOFX_SECRET_")
+	for _, r := range strings.ToUpper(path) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 加密密钥文件
+// 内容是 {"binance/key": "...", ...} 的JSON，用 NaCl secretbox（XSalsa20-Poly1305）加密，
+// 密钥由口令经 scrypt 派生。文件格式: "nofx-secrets-v1:" + base64(salt[16] | nonce[24] | 密文)
+
+const (
+	fileMagic   = "nofx-secrets-v1:"
+	saltSize    = 16
+	nonceSize   = 24
+	scryptN     = 1 << 15
+	scryptR     = 8
+	scryptP     = 1
+	fileKeySize = 32
+)
+
+// FileProvider 从加密密钥文件读取密钥（创建时解密一次）
+type FileProvider struct {
+	path    string
+	secrets map[string]string
+}
+
+// NewFileProvider 打开并解密密钥文件
+func NewFileProvider(path, passphrase string) (*FileProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	secrets, err := Open(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range secrets {
+		Register(v)
+	}
+	return &FileProvider{path: path, secrets: secrets}, nil
+}
+
+// Name 来源名称
+func (p *FileProvider) Name() string { return "file:" + p.path }
+
+// Get 读取密钥
+func (p *FileProvider) Get(path string) (string, error) {
+	value, ok := p.secrets[path]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Seal 用口令加密密钥表
+func Seal(secrets map[string]string, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("口令不能为空")
+	}
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	var salt [saltSize]byte
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(passphrase, salt[:])
+	if err != nil {
+		return nil, err
+	}
+
+	out := append(salt[:], nonce[:]...)
+	out = secretbox.Seal(out, plain, &nonce, key)
+	return []byte(fileMagic + base64.StdEncoding.EncodeToString(out) + "\n"), nil
+}
+
+// Open 解密 Seal 生成的内容
+func Open(data []byte, passphrase string) (map[string]string, error) {
+	text := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(text, fileMagic) {
+		return nil, errors.New("不是有效的密钥文件")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(text, fileMagic))
+	if err != nil || len(raw) < saltSize+nonceSize+secretbox.Overhead {
+		return nil, errors.New("密钥文件已损坏")
+	}
+
+	var nonce [nonceSize]byte
+	copy(nonce[:], raw[saltSize:saltSize+nonceSize])
+	key, err := deriveKey(passphrase, raw[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	plain, ok := secretbox.Open(nil, raw[saltSize+nonceSize:], &nonce, key)
+	if !ok {
+		return nil, errors.New("解密密钥文件失败：口令错误或文件被篡改")
+	}
+
+	var secrets map[string]string
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("解析密钥文件失败: %w", err)
+	}
+	return secrets, nil
+}
+
+// deriveKey 由口令派生 secretbox 密钥
+func deriveKey(passphrase string, salt []byte) (*[fileKeySize]byte, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, fileKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %w", err)
+	}
+	var key [fileKeySize]byte
+	copy(key[:], derived)
+	return &key, nil
+}
@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// minRedactLength 过短的值不脱敏（容易误伤普通文本）
+const minRedactLength = 6
+
+// redactedMask 脱敏后的替换文本
+const redactedMask = "****"
+
+var (
+	registered = make(map[string]bool)
+	replacer   *strings.Replacer
+	redactMu   sync.RWMutex
+)
+
+// Register 登记需要从日志中脱敏的值
+func Register(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minRedactLength {
+		return
+	}
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	if registered[value] {
+		return
+	}
+	registered[value] = true
+
+	// 长的值优先替换，避免一个密钥是另一个的子串时只替换一半
+	values := make([]string, 0, len(registered))
+	for v := range registered {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		pairs = append(pairs, v, redactedMask)
+	}
+	replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact 把文本中登记过的值替换为 ****
+func Redact(s string) string {
+	redactMu.RLock()
+	r := replacer
+	redactMu.RUnlock()
+	if r == nil {
+		return s
+	}
+	return r.Replace(s)
+}
+
+// redactingWriter 写入前脱敏
+type redactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter 包装日志输出，写入前脱敏（log 包每条日志调用一次 Write）
+func NewRedactingWriter(w io.Writer) io.Writer {
+	return &redactingWriter{w: w}
+}
+
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	redacted := Redact(string(p))
+	if _, err := io.WriteString(rw.w, redacted); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,81 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 密钥管理
+// 配置文件中的凭证可以写成引用 secret://<路径>（如 secret://binance/key），加载配置时按顺序从
+// 环境变量、加密密钥文件、HashiCorp Vault 中解析出真实值；不是引用的值原样使用（兼容明文配置）。
+// 所有解析出的密钥（以及明文配置的凭证）都会登记到脱敏列表，经过 RedactingWriter 的日志输出中会被替换为 ****。
+
+// RefPrefix 密钥引用前缀
+const RefPrefix = "secret://"
+
+// ErrNotFound 来源中没有该密钥（继续尝试下一个来源）
+var ErrNotFound = errors.New("密钥不存在")
+
+// Provider 密钥来源
+type Provider interface {
+	// Name 来源名称（用于日志）
+	Name() string
+	// Get 按路径获取密钥（如 "binance/key"），不存在时返回 ErrNotFound
+	Get(path string) (string, error)
+}
+
+// Manager 按顺序从多个来源解析密钥引用
+type Manager struct {
+	providers []Provider
+}
+
+// NewManager 创建密钥管理器，providers 按优先级排列
+func NewManager(providers ...Provider) *Manager {
+	return &Manager{providers: providers}
+}
+
+// IsRef 是否为密钥引用
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, RefPrefix)
+}
+
+// Resolve 解析密钥引用；不是引用时原样返回。解析出的值会登记脱敏
+func (m *Manager) Resolve(value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	path := strings.Trim(strings.TrimPrefix(value, RefPrefix), "/")
+	if path == "" {
+		return "", fmt.Errorf("无效的密钥引用: %s", value)
+	}
+
+	for _, p := range m.providers {
+		secret, err := p.Get(path)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("从 %s 获取密钥 %s 失败: %w", p.Name(), path, err)
+		}
+		Register(secret)
+		return secret, nil
+	}
+	return "", fmt.Errorf("未找到密钥 %s: %w", path, ErrNotFound)
+}
+
+// ResolveAll 解析一组字段（原地替换），遇到第一个错误即返回
+func (m *Manager) ResolveAll(fields map[string]*string) error {
+	for name, field := range fields {
+		if field == nil || *field == "" {
+			continue
+		}
+		resolved, err := m.Resolve(*field)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*field = resolved
+		Register(resolved) // 明文配置的凭证同样脱敏
+	}
+	return nil
+}
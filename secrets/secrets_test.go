@@ -0,0 +1,96 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveOrder(t *testing.T) {
+	dir := t.TempDir()
+	sealed, err := Seal(map[string]string{"binance/key": "file-binance-key", "gateio/secret": "file-gateio-secret"}, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "secrets.enc")
+	if err := os.WriteFile(path, sealed, 0600); err != nil {
+		t.Fatal(err)
+	}
+	fileProvider, err := NewFileProvider(path, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/deepseek" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"key":"vault-deepseek-key"}}}`))
+	}))
+	defer vault.Close()
+
+	t.Setenv("NOFX_SECRET_BINANCE_KEY", "env-binance-key")
+	m := NewManager(EnvProvider{}, fileProvider, NewVaultProvider(vault.URL, "vault-token", ""))
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"secret://binance/key", "env-binance-key"}, // 环境变量优先于文件
+		{"secret://gateio/secret", "file-gateio-secret"},
+		{"secret://deepseek/key", "vault-deepseek-key"},
+		{"plain-value", "plain-value"},
+	}
+	for _, tt := range tests {
+		got, err := m.Resolve(tt.ref)
+		if err != nil {
+			t.Errorf("Resolve(%s) 失败: %v", tt.ref, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%s) = %s, 期望 %s", tt.ref, got, tt.want)
+		}
+	}
+
+	if _, err := m.Resolve("secret://missing/key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("不存在的密钥应返回 ErrNotFound, 实际: %v", err)
+	}
+}
+
+func TestOpenWrongPassphrase(t *testing.T) {
+	sealed, err := Seal(map[string]string{"a/b": "value"}, "right")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(sealed, "wrong"); err == nil {
+		t.Fatal("错误口令应解密失败")
+	}
+}
+
+func TestRedactingWriter(t *testing.T) {
+	Register("sk-live-1234567890")
+	Register("abc") // 过短，不脱敏
+
+	var buf bytes.Buffer
+	logger := log.New(NewRedactingWriter(&buf), "", 0)
+	logger.Printf("使用密钥 sk-live-1234567890 连接 abc")
+
+	out := buf.String()
+	if strings.Contains(out, "sk-live-1234567890") {
+		t.Errorf("密钥未脱敏: %s", out)
+	}
+	if !strings.Contains(out, "****") || !strings.Contains(out, "abc") {
+		t.Errorf("脱敏结果不符合预期: %s", out)
+	}
+}
@@ -0,0 +1,107 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultProvider 从 HashiCorp Vault KV v2 读取密钥
+// secret://binance/key → GET {address}/v1/{mount}/data/binance 的 data.key 字段
+type VaultProvider struct {
+	address string
+	token   string
+	mount   string
+	client  *http.Client
+
+	cache map[string]map[string]string // 同一路径只请求一次
+	mu    sync.Mutex
+}
+
+// NewVaultProvider 创建 Vault 来源，mount 为空时使用 "secret"
+func NewVaultProvider(address, token, mount string) *VaultProvider {
+	if mount == "" {
+		mount = "secret"
+	}
+	return &VaultProvider{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		mount:   strings.Trim(mount, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		cache:   make(map[string]map[string]string),
+	}
+}
+
+// Name 来源名称
+func (p *VaultProvider) Name() string { return "vault" }
+
+// Get 读取密钥，路径最后一段是字段名
+func (p *VaultProvider) Get(path string) (string, error) {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "", ErrNotFound // Vault 需要 <路径>/<字段>
+	}
+	secretPath, field := path[:i], path[i+1:]
+
+	data, err := p.read(secretPath)
+	if err != nil {
+		return "", err
+	}
+	value, ok := data[field]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// read 读取一个 KV v2 密钥的全部字段
+func (p *VaultProvider) read(secretPath string) (map[string]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if data, ok := p.cache[secretPath]; ok {
+		return data, nil
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/v1/%s/data/%s", p.address, p.mount, secretPath), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求Vault失败: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		p.cache[secretPath] = map[string]string{}
+		return p.cache[secretPath], nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Vault返回错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析Vault响应失败: %w", err)
+	}
+
+	data := make(map[string]string, len(result.Data.Data))
+	for k, v := range result.Data.Data {
+		if s, ok := v.(string); ok {
+			data[k] = s
+		}
+	}
+	p.cache[secretPath] = data
+	return data, nil
+}
@@ -0,0 +1,182 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// OTLPExporter 通过 OTLP/HTTP（JSON编码）批量导出 span
+// Jaeger（1.35+，端口4318）和 OpenTelemetry Collector 都可以直接接收，默认地址 http://localhost:4318/v1/traces
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+
+	spans chan *SpanData
+	done  chan struct{}
+}
+
+const (
+	otlpBatchSize     = 256
+	otlpFlushInterval = 5 * time.Second
+	otlpQueueSize     = 4096
+)
+
+// NewOTLPExporter 创建导出器并启动后台发送
+func NewOTLPExporter(endpoint, serviceName string) *OTLPExporter {
+	e := &OTLPExporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		spans:       make(chan *SpanData, otlpQueueSize),
+		done:        make(chan struct{}),
+	}
+	go e.loop()
+	return e
+}
+
+// Export 加入发送队列（队列满时丢弃，追踪不能拖慢交易）
+func (e *OTLPExporter) Export(span *SpanData) {
+	select {
+	case e.spans <- span:
+	default:
+	}
+}
+
+// Shutdown 发送剩余的 span 后停止
+func (e *OTLPExporter) Shutdown() {
+	close(e.spans)
+	<-e.done
+}
+
+func (e *OTLPExporter) loop() {
+	defer close(e.done)
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	var batch []*SpanData
+	for {
+		select {
+		case span, ok := <-e.spans:
+			if !ok {
+				e.send(batch)
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= otlpBatchSize {
+				e.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			e.send(batch)
+			batch = nil
+		}
+	}
+}
+
+// send 发送一批 span，失败只记录日志
+func (e *OTLPExporter) send(batch []*SpanData) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := json.Marshal(e.payload(batch))
+	if err != nil {
+		log.Printf("⚠️ 序列化追踪数据失败: %v", err)
+		return
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("⚠️ 导出追踪数据失败: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("⚠️ 导出追踪数据失败: HTTP %d", resp.StatusCode)
+	}
+}
+
+// OTLP JSON 结构（只包含用到的字段）
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 1=OK, 2=ERROR
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"` // 1=INTERNAL
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+// payload 转换为 ExportTraceServiceRequest
+func (e *OTLPExporter) payload(batch []*SpanData) map[string]interface{} {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		span := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentID,
+			Name:              s.Name,
+			Kind:              1,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: 1},
+		}
+		for k, v := range s.Attributes {
+			span.Attributes = append(span.Attributes, otlpKeyValue{Key: k, Value: otlpValue(v)})
+		}
+		if s.Error != "" {
+			span.Status = otlpStatus{Code: 2, Message: s.Error}
+		}
+		spans = append(spans, span)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []otlpKeyValue{{Key: "service.name", Value: otlpValue(e.serviceName)}},
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "nofx"},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+// otlpValue 转换为 OTLP AnyValue
+func otlpValue(v interface{}) map[string]interface{} {
+	switch val := v.(type) {
+	case string:
+		return map[string]interface{}{"stringValue": val}
+	case bool:
+		return map[string]interface{}{"boolValue": val}
+	case int:
+		return map[string]interface{}{"intValue": strconv.Itoa(val)}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+	case float64:
+		return map[string]interface{}{"doubleValue": val}
+	default:
+		return map[string]interface{}{"stringValue": fmt.Sprintf("%v", val)}
+	}
+}
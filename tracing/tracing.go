@@ -0,0 +1,172 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// 请求链路追踪
+// 每个交易周期是一条 trace 的根 span，市场数据获取、AI调用、下单等步骤作为子 span 挂在下面，
+// 通过 context 传递父子关系。结束的 span 交给 Exporter 批量导出（OTLP/HTTP JSON，Jaeger/OTel Collector 可直接接收）。
+// 未启用时 Start 返回 nil span，所有方法对 nil 安全，调用方无需判断。
+
+// Span 一次计时的操作
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]interface{}
+	err      error
+	mu       sync.Mutex
+}
+
+// Exporter 接收已结束的 span
+type Exporter interface {
+	Export(span *SpanData)
+	Shutdown()
+}
+
+// SpanData 已结束 span 的只读快照
+type SpanData struct {
+	TraceID    string
+	SpanID     string
+	ParentID   string // 根 span 为空
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{}
+	Error      string // 为空表示成功
+}
+
+type spanKey struct{}
+
+var (
+	exporter   Exporter
+	exporterMu sync.RWMutex
+)
+
+// SetExporter 设置全局导出器（nil 关闭追踪）
+func SetExporter(e Exporter) {
+	exporterMu.Lock()
+	exporter = e
+	exporterMu.Unlock()
+}
+
+// Shutdown 关闭追踪并发送导出器中剩余的 span
+func Shutdown() {
+	exporterMu.Lock()
+	e := exporter
+	exporter = nil
+	exporterMu.Unlock()
+	if e != nil {
+		e.Shutdown()
+	}
+}
+
+// Enabled 是否启用了追踪
+func Enabled() bool {
+	exporterMu.RLock()
+	defer exporterMu.RUnlock()
+	return exporter != nil
+}
+
+// Start 创建 span：ctx 中已有 span 时作为其子 span，否则开始一条新的 trace
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !Enabled() {
+		return ctx, nil
+	}
+
+	span := &Span{name: name, start: time.Now(), attrs: make(map[string]interface{})}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFromContext 获取 ctx 中的当前 span（没有时返回nil）
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SetAttr 设置属性（值支持 string、bool、整数、浮点数，其他类型按字符串导出）
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs[key] = value
+	s.mu.Unlock()
+}
+
+// RecordError 标记 span 失败（err 为nil时忽略）
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// End 结束 span 并导出（重复调用只导出一次）
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	data := &SpanData{
+		TraceID:    hex.EncodeToString(s.traceID[:]),
+		SpanID:     hex.EncodeToString(s.spanID[:]),
+		Name:       s.name,
+		Start:      s.start,
+		End:        s.end,
+		Attributes: make(map[string]interface{}, len(s.attrs)),
+	}
+	if s.parentID != [8]byte{} {
+		data.ParentID = hex.EncodeToString(s.parentID[:])
+	}
+	for k, v := range s.attrs {
+		data.Attributes[k] = v
+	}
+	if s.err != nil {
+		data.Error = s.err.Error()
+	}
+	s.mu.Unlock()
+
+	exporterMu.RLock()
+	e := exporter
+	exporterMu.RUnlock()
+	if e != nil {
+		e.Export(data)
+	}
+}
+
+// TraceID 所属 trace 的ID（用于写入日志，方便在 Jaeger 中查找；nil span 返回空字符串）
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSpansExportedAsOTLP(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("无效的OTLP JSON: %v", err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	SetExporter(NewOTLPExporter(server.URL, "nofx-test"))
+
+	ctx, root := Start(context.Background(), "trading.cycle")
+	root.SetAttr("cycle", 3)
+	_, child := Start(ctx, "ai.call")
+	child.RecordError(errors.New("timeout"))
+	child.End()
+	root.End()
+
+	Shutdown()
+
+	payload := <-received
+	spans := payload["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("导出 %d 个span, 期望 2", len(spans))
+	}
+
+	aiCall := spans[0].(map[string]interface{})
+	cycle := spans[1].(map[string]interface{})
+	if aiCall["traceId"] != cycle["traceId"] || aiCall["traceId"] != root.TraceID() {
+		t.Errorf("子span应属于同一条trace: %v / %v", aiCall["traceId"], cycle["traceId"])
+	}
+	if aiCall["parentSpanId"] != cycle["spanId"] {
+		t.Errorf("parentSpanId = %v, 期望 %v", aiCall["parentSpanId"], cycle["spanId"])
+	}
+	if _, ok := cycle["parentSpanId"]; ok {
+		t.Error("根span不应有parentSpanId")
+	}
+	if status := aiCall["status"].(map[string]interface{}); status["code"].(float64) != 2 {
+		t.Errorf("失败的span状态应为ERROR: %v", status)
+	}
+}
+
+func TestDisabledTracingIsNoop(t *testing.T) {
+	ctx, span := Start(context.Background(), "noop")
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Fatal("未启用追踪时不应创建span")
+	}
+	// nil span 的方法可以安全调用
+	span.SetAttr("k", "v")
+	span.RecordError(errors.New("x"))
+	span.End()
+	if span.TraceID() != "" {
+		t.Error("nil span 的 TraceID 应为空")
+	}
+}
@@ -0,0 +1,105 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"nofx/errs"
+	"nofx/notify"
+)
+
+// 账户权限自检
+// API密钥缺少合约交易权限、账户持仓模式和交易器的下单方式不一致时，问题要到第一笔下单才暴露，
+// 那时AI已经做完决策、风控已经放行。添加trader时查询一次密钥权限和账户设置：缺少交易权限或持仓模式不符时
+// 直接不启动该trader并给出处理办法；密钥开启了提现权限时告警（交易机器人不需要提现，密钥泄露后损失无法挽回）；
+// 查到的账户等级和手续费率写入日志和状态接口。
+
+const (
+	PositionModeHedge  = "hedge"   // 双向持仓（多空分开持有）
+	PositionModeOneWay = "one_way" // 单向持仓
+)
+
+// AccountPermissions API密钥权限和账户设置
+type AccountPermissions struct {
+	CanTrade      bool    `json:"can_trade"`               // 允许合约交易
+	CanWithdraw   bool    `json:"can_withdraw"`            // 密钥允许提现
+	WithdrawKnown bool    `json:"withdraw_known"`          // 是否查到了提现权限（部分交易所没有查询接口）
+	PositionMode  string  `json:"position_mode,omitempty"` // 账户当前的持仓模式
+	RequiredMode  string  `json:"required_mode,omitempty"` // 交易器下单要求的持仓模式（空表示两种都支持）
+	Tier          string  `json:"tier,omitempty"`          // 账户等级（如 "VIP 0"，空表示未知）
+	MakerFeeRate  float64 `json:"maker_fee_rate"`          // 挂单费率（0.0002 = 0.02%，负数为返佣）
+	TakerFeeRate  float64 `json:"taker_fee_rate"`          // 吃单费率
+	FeesKnown     bool    `json:"fees_known"`              // 是否查到了手续费率
+}
+
+// AccountPermissionChecker 能查询API密钥权限和账户设置的交易器（可选接口）
+type AccountPermissionChecker interface {
+	// GetAccountPermissions 查询密钥权限、持仓模式、账户等级和手续费率（查不到的项保持零值）
+	GetAccountPermissions() (*AccountPermissions, error)
+}
+
+// CheckAccountPermissions 启动自检：缺少合约交易权限或持仓模式不符时返回错误，开启提现权限时告警
+// 交易所暂时无法访问时只告警（不因网络问题阻止启动）
+func (at *AutoTrader) CheckAccountPermissions() error {
+	checker, ok := at.trader.(AccountPermissionChecker)
+	if !ok {
+		log.Printf("ℹ️ [%s] %s 不支持账户权限自检，跳过", at.name, at.exchange)
+		return nil
+	}
+
+	perms, err := checker.GetAccountPermissions()
+	if err != nil {
+		if errors.Is(err, errs.ErrAuth) {
+			return fmt.Errorf("[%s] %s API密钥无效或无权访问（检查密钥是否正确、是否开启合约权限、IP白名单是否包含本机出口IP）: %w", at.name, at.exchange, err)
+		}
+		log.Printf("⚠️ [%s] 账户权限自检失败，跳过: %v", at.name, err)
+		return nil
+	}
+	at.accountPermissions = perms
+
+	if !perms.CanTrade {
+		return fmt.Errorf("[%s] %s API密钥没有合约交易权限：请在交易所API管理中为该密钥开启合约（Futures）交易权限", at.name, at.exchange)
+	}
+	if perms.RequiredMode != "" && perms.PositionMode != "" && perms.PositionMode != perms.RequiredMode {
+		return fmt.Errorf("[%s] %s 账户为%s模式，本系统在该交易所按%s下单：请在交易所合约设置中切换为%s（需先平掉所有持仓、撤销挂单）",
+			at.name, at.exchange, positionModeName(perms.PositionMode), positionModeName(perms.RequiredMode), positionModeName(perms.RequiredMode))
+	}
+
+	switch {
+	case perms.CanWithdraw:
+		log.Printf("⚠️ [%s] API密钥开启了提现权限，建议在交易所API管理中关闭（交易不需要提现权限）", at.name)
+		notify.Send(notify.Event{
+			Type:     "trader.withdraw_permission",
+			Severity: notify.SeverityWarning,
+			TraderID: at.id,
+			Title:    fmt.Sprintf("%s API密钥开启了提现权限", at.name),
+			Message:  "交易不需要提现权限，密钥泄露后资金可能被转走，建议在交易所API管理中关闭",
+		})
+	case !perms.WithdrawKnown:
+		log.Printf("ℹ️ [%s] %s 无法查询密钥的提现权限，请自行确认已关闭", at.name, at.exchange)
+	}
+
+	tier := perms.Tier
+	if tier == "" {
+		tier = "未知"
+	}
+	fees := "未知"
+	if perms.FeesKnown {
+		fees = fmt.Sprintf("挂单 %.4f%% / 吃单 %.4f%%", perms.MakerFeeRate*100, perms.TakerFeeRate*100)
+	}
+	log.Printf("✅ [%s] 账户权限自检通过：合约交易已开启，持仓模式 %s，账户等级 %s，手续费 %s",
+		at.name, positionModeName(perms.PositionMode), tier, fees)
+	return nil
+}
+
+// positionModeName 持仓模式的中文名称
+func positionModeName(mode string) string {
+	switch mode {
+	case PositionModeHedge:
+		return "双向持仓"
+	case PositionModeOneWay:
+		return "单向持仓"
+	default:
+		return "未知"
+	}
+}
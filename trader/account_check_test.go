@@ -0,0 +1,54 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIntegrationAccountPermissions(t *testing.T) {
+	t.Run("binance", func(t *testing.T) {
+		ex, ai := setupIntegration(t)
+		at := newIntegrationTrader(t, ex, ai, "binance")
+
+		if err := at.CheckAccountPermissions(); err != nil {
+			t.Fatalf("双向持仓账户应通过自检: %v", err)
+		}
+		perms := at.accountPermissions
+		if perms == nil || perms.PositionMode != PositionModeHedge || perms.Tier != "VIP 1" || !perms.FeesKnown || perms.TakerFeeRate != 0.00045 {
+			t.Fatalf("自检结果 = %+v", perms)
+		}
+		if at.GetStatus()["account_check"] != perms {
+			t.Error("状态接口应包含自检结果")
+		}
+
+		// 单向持仓：币安按 LONG/SHORT 下单会被拒，启动时直接报错
+		ex.SetAccountSettings(true, false)
+		if err := at.CheckAccountPermissions(); err == nil || !strings.Contains(err.Error(), "切换为双向持仓") {
+			t.Fatalf("单向持仓账户应报错: %v", err)
+		}
+
+		ex.SetAccountSettings(false, true)
+		if err := at.CheckAccountPermissions(); err == nil || !strings.Contains(err.Error(), "没有合约交易权限") {
+			t.Fatalf("无交易权限应报错: %v", err)
+		}
+	})
+
+	t.Run("gateio", func(t *testing.T) {
+		ex, ai := setupIntegration(t)
+		at := newIntegrationTrader(t, ex, ai, "gateio")
+
+		// Gate.io 两种持仓模式都支持
+		ex.SetAccountSettings(true, false)
+		if err := at.CheckAccountPermissions(); err != nil {
+			t.Fatalf("单向持仓账户应通过自检: %v", err)
+		}
+		if perms := at.accountPermissions; perms.PositionMode != PositionModeOneWay || perms.WithdrawKnown {
+			t.Fatalf("自检结果 = %+v", perms)
+		}
+
+		ex.SetAccountSettings(false, true)
+		if err := at.CheckAccountPermissions(); err == nil || !strings.Contains(err.Error(), "没有合约交易权限") {
+			t.Fatalf("只读密钥应报错: %v", err)
+		}
+	})
+}
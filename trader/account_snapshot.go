@@ -0,0 +1,109 @@
+package trader
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 账户快照
+// 余额和持仓来自两个接口、各自带缓存时，两者可能相隔数秒甚至一个缓存周期（例如下单后持仓缓存已失效、余额仍是旧值），
+// 据此算出的净值、保证金使用率等比例互相矛盾。账户快照把两者放在一起获取：交易所能一次返回时只请求一次，
+// 否则同时发起两个请求（不使用缓存），派生字段只在这里按同一份数据计算。
+
+// AccountSnapshotter 能给出同一时刻余额和持仓的交易器（可选接口，未实现时同时请求 GetBalance 和 GetPositions）
+type AccountSnapshotter interface {
+	// GetAccountSnapshot 同时获取余额和持仓（不使用缓存）
+	GetAccountSnapshot() (balance map[string]interface{}, positions []map[string]interface{}, err error)
+}
+
+// AccountSnapshot 同一时刻的账户余额、持仓及派生字段
+type AccountSnapshot struct {
+	Balance   map[string]interface{}
+	Positions []map[string]interface{}
+	Time      time.Time
+
+	WalletBalance    float64 // 钱包余额（不含未实现盈亏）
+	UnrealizedPnL    float64 // 未实现盈亏
+	TotalEquity      float64 // 净值 = 钱包余额 + 未实现盈亏
+	AvailableBalance float64 // 可用余额
+	MarginUsed       float64 // 持仓占用保证金
+	MarginUsedPct    float64 // 保证金使用率（占净值）
+}
+
+// fetchBalanceAndPositions 同时请求余额和持仓
+func fetchBalanceAndPositions(getBalance func() (map[string]interface{}, error), getPositions func() ([]map[string]interface{}, error)) (map[string]interface{}, []map[string]interface{}, error) {
+	var (
+		wg                 sync.WaitGroup
+		balance            map[string]interface{}
+		positions          []map[string]interface{}
+		balanceErr, posErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer recoverAsError(&balanceErr)
+		balance, balanceErr = getBalance()
+	}()
+	go func() {
+		defer wg.Done()
+		defer recoverAsError(&posErr)
+		positions, posErr = getPositions()
+	}()
+	wg.Wait()
+	if balanceErr != nil {
+		return nil, nil, fmt.Errorf("获取账户余额失败: %w", balanceErr)
+	}
+	if posErr != nil {
+		return nil, nil, fmt.Errorf("获取持仓失败: %w", posErr)
+	}
+	return balance, positions, nil
+}
+
+// accountSnapshot 获取账户快照并计算派生字段
+func (at *AutoTrader) accountSnapshot() (*AccountSnapshot, error) {
+	var (
+		balance   map[string]interface{}
+		positions []map[string]interface{}
+		err       error
+	)
+	if s, ok := at.trader.(AccountSnapshotter); ok {
+		balance, positions, err = s.GetAccountSnapshot()
+	} else {
+		balance, positions, err = fetchBalanceAndPositions(at.trader.GetBalance, at.trader.GetPositions)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rate, err := at.settlementRate()
+	if err != nil {
+		return nil, err
+	}
+	balance, positions = convertBalance(balance, rate), convertPositions(positions, rate)
+
+	snap := &AccountSnapshot{Balance: balance, Positions: positions, Time: time.Now()}
+	snap.WalletBalance, _ = balance["totalWalletBalance"].(float64)
+	snap.UnrealizedPnL, _ = balance["totalUnrealizedProfit"].(float64)
+	snap.AvailableBalance, _ = balance["availableBalance"].(float64)
+	snap.TotalEquity = snap.WalletBalance + snap.UnrealizedPnL
+	for _, pos := range positions {
+		markPrice, _ := pos["markPrice"].(float64)
+		quantity, _ := pos["positionAmt"].(float64)
+		if quantity < 0 {
+			quantity = -quantity
+		}
+		snap.MarginUsed += positionMargin(pos, quantity, markPrice, positionLeverage(pos))
+	}
+	if snap.TotalEquity > 0 {
+		snap.MarginUsedPct = snap.MarginUsed / snap.TotalEquity * 100
+	}
+	return snap, nil
+}
+
+// positionLeverage 持仓的杠杆倍数（交易所未返回时按10倍）
+func positionLeverage(pos map[string]interface{}) int {
+	if lev, ok := pos["leverage"].(float64); ok {
+		return int(lev)
+	}
+	return 10
+}
@@ -0,0 +1,40 @@
+package trader
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestIntegrationAccountSnapshotConsistent(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.trader.(*GateioTrader).balanceCache.SetTTL(time.Minute)
+
+	// 开仓前先读一次余额：Gate下单后只让持仓缓存失效，余额缓存仍是开仓前的值
+	if _, err := at.trader.GetBalance(); err != nil {
+		t.Fatal(err)
+	}
+	ai.Enqueue(t, "开多ETH。", openLongETH(3000))
+	runCycle(t, at)
+	if ex.GatePosition("ETHUSDT").size == 0 {
+		t.Fatal("ETH 应已开仓")
+	}
+
+	info, err := at.GetAccountInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	equity := info["total_equity"].(float64)
+	available := info["available_balance"].(float64)
+	margin := info["margin_used"].(float64)
+	if margin <= 0 {
+		t.Fatalf("持仓保证金应大于0: %v", info)
+	}
+	if math.Abs(equity-available-margin) > 1 {
+		t.Errorf("净值 %.2f 应等于可用 %.2f + 保证金 %.2f（余额和持仓来自不同时刻）", equity, available, margin)
+	}
+	if pct := info["margin_used_pct"].(float64); math.Abs(pct-margin/equity*100) > 1e-9 {
+		t.Errorf("保证金使用率 %.4f 与净值、保证金不一致", pct)
+	}
+}
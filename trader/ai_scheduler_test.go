@@ -0,0 +1,43 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/mcp"
+	"testing"
+)
+
+func TestIntegrationAISchedulerSerializesEnsembleCalls(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	scheduler := mcp.NewScheduler(1)
+	mcp.SetScheduler(scheduler)
+	t.Cleanup(func() { mcp.SetScheduler(nil) })
+
+	others := []*mockAI{newMockAI(t), newMockAI(t)}
+	at.ensemble = []decision.EnsembleMember{{Name: "primary", Client: at.mcpClient}}
+	for i, m := range others {
+		client := mcp.New()
+		client.SetCustomAPI(m.URL(), "test-key", "mock")
+		at.ensemble = append(at.ensemble, decision.EnsembleMember{Name: []string{"second", "third"}[i], Client: client})
+	}
+	at.config.EnsemblePolicy = decision.EnsembleMajority
+
+	// 三个模型并发调用，但同时只允许一个AI请求：全部排队完成，决策照常执行
+	ai.Enqueue(t, "ETH突破。", openLongETH(1500))
+	others[0].Enqueue(t, "ETH放量。", openLongETH(1500))
+	others[1].Enqueue(t, "ETH观望。")
+	record := runCycle(t, at)
+
+	requireActionSuccess(t, record, "open_long")
+	stats := scheduler.Stats()
+	if stats.TotalCalls != 3 {
+		t.Errorf("应经过调度器3次调用, 实际 %d", stats.TotalCalls)
+	}
+	if stats.Active != 0 || stats.Waiting != 0 {
+		t.Errorf("周期结束后不应有进行中或排队的调用: %+v", stats)
+	}
+	if stats.MaxConcurrent != 1 {
+		t.Errorf("并发上限应为1, 实际 %d", stats.MaxConcurrent)
+	}
+}
@@ -0,0 +1,98 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/market"
+	"nofx/mcp"
+	"time"
+)
+
+// AnalyzeSymbol 单币种即时分析（用于API），askAI 时同时请求AI意见，决策只返回、不执行
+// 不参与交易周期：不写决策日志，也不更新持仓跟踪状态
+func (at *AutoTrader) AnalyzeSymbol(symbol string, askAI bool) (*decision.SymbolAnalysis, error) {
+	symbol = market.Normalize(symbol)
+	snap, err := at.accountSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	account := at.accountInfo(snap)
+	positions := snap.Positions
+
+	var positionInfos []decision.PositionInfo
+	for _, pos := range positions {
+		if s, _ := pos["symbol"].(string); s != symbol {
+			continue
+		}
+		side, _ := pos["side"].(string)
+		entryPrice, _ := pos["entryPrice"].(float64)
+		markPrice, _ := pos["markPrice"].(float64)
+		quantity, _ := pos["positionAmt"].(float64)
+		if quantity < 0 {
+			quantity = -quantity
+		}
+		unrealizedPnl, _ := pos["unRealizedProfit"].(float64)
+		liquidationPrice, _ := pos["liquidationPrice"].(float64)
+		leverage := positionLeverage(pos)
+		pnlPct := 0.0
+		if entryPrice > 0 {
+			if side == "long" {
+				pnlPct = ((markPrice - entryPrice) / entryPrice) * float64(leverage) * 100
+			} else {
+				pnlPct = ((entryPrice - markPrice) / entryPrice) * float64(leverage) * 100
+			}
+		}
+		positionInfos = append(positionInfos, decision.PositionInfo{
+			PositionID:        at.positionIDs.get(symbol + "_" + side),
+			Symbol:            symbol,
+			Side:              side,
+			EntryPrice:        entryPrice,
+			MarkPrice:         markPrice,
+			Quantity:          quantity,
+			Leverage:          leverage,
+			UnrealizedPnL:     unrealizedPnl,
+			UnrealizedPnLPct:  pnlPct,
+			LiquidationPrice:  liquidationPrice,
+			MarginUsed:        positionMargin(pos, quantity, markPrice, leverage),
+			CumulativeFunding: at.funding.Cumulative(symbol, side),
+		})
+	}
+
+	accountFloat := func(key string) float64 {
+		v, _ := account[key].(float64)
+		return v
+	}
+	positionCount, _ := account["position_count"].(int)
+	ctx := &decision.Context{
+		CurrentTime:          time.Now().Format("2006-01-02 15:04:05"),
+		RuntimeMinutes:       int(time.Since(at.startTime).Minutes()),
+		CallCount:            at.callCount,
+		BTCETHLeverage:       at.config.BTCETHLeverage,
+		AltcoinLeverage:      at.config.AltcoinLeverage,
+		MinPositionSizeUSD:   at.config.MinPositionSizeUSD,
+		MaxPositionSizeUSD:   at.config.MaxPositionSizeUSD,
+		SystemPromptTemplate: at.config.SystemPromptTemplate,
+		SymbolFilter:         at.symbolFilter,
+		SymbolLimits:         at.config.SymbolLimits,
+		AutoStop:             at.config.AutoStopLoss,
+		RoundPrice:           at.priceRounder(),
+		ScanInterval:         at.config.ScanInterval,
+		Indicators:           at.config.Indicators,
+		MarketFormat:         market.FormatMode(at.config.MarketDataFormat),
+		Account: decision.AccountInfo{
+			TotalEquity:      accountFloat("total_equity"),
+			AvailableBalance: accountFloat("available_balance"),
+			TotalPnL:         accountFloat("total_pnl"),
+			TotalPnLPct:      accountFloat("total_pnl_pct"),
+			MarginUsed:       accountFloat("margin_used"),
+			MarginUsedPct:    accountFloat("margin_used_pct"),
+			PositionCount:    positionCount,
+		},
+		Positions: positionInfos,
+	}
+
+	var client *mcp.Client
+	if askAI {
+		client = at.mcpClient
+	}
+	return decision.AnalyzeSymbol(ctx, symbol, client)
+}
@@ -0,0 +1,56 @@
+package trader
+
+import (
+	"nofx/decision"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationAnalyzeSymbol(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	before := ex.GatePosition("ETHUSDT")
+
+	// 不请求AI：只返回市场数据和指标
+	analysis, err := at.AnalyzeSymbol("ethusdt", false)
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if analysis.Symbol != "ETHUSDT" || analysis.Data == nil || analysis.Data.CurrentPrice <= 0 {
+		t.Fatalf("分析结果不符合预期: %+v", analysis)
+	}
+	if !strings.Contains(analysis.Analysis, "current_price") || analysis.Opinion != nil {
+		t.Errorf("应只包含市场数据: %q", analysis.Analysis)
+	}
+	if len(ai.Prompts()) != 1 {
+		t.Fatalf("不请求AI时不应调用AI，实际调用 %d 次", len(ai.Prompts()))
+	}
+
+	// 请求AI：prompt只包含该币种（含持仓），决策只返回不执行
+	ai.Enqueue(t, "趋势减弱，平多。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "动能减弱"})
+	analysis, err = at.AnalyzeSymbol("ETHUSDT", true)
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if analysis.AIError != "" || analysis.Opinion == nil || len(analysis.Opinion.Decisions) != 1 ||
+		analysis.Opinion.Decisions[0].Action != "close_long" {
+		t.Fatalf("AI意见不符合预期: %+v (错误: %s)", analysis.Opinion, analysis.AIError)
+	}
+	prompts := ai.Prompts()
+	last := prompts[len(prompts)-1]
+	for _, want := range []string{"## 当前持仓", "ETHUSDT LONG", "## 候选币种 (1个)"} {
+		if !strings.Contains(last, want) {
+			t.Errorf("AI输入中缺少 %q", want)
+		}
+	}
+	if after := ex.GatePosition("ETHUSDT"); after.size != before.size {
+		t.Errorf("分析不应执行决策: 持仓 %v -> %v", before.size, after.size)
+	}
+	records, err := at.decisionLogger.GetLatestRecords(10)
+	if err != nil || len(records) != 1 {
+		t.Errorf("分析不应写入决策日志: %d 条记录, %v", len(records), err)
+	}
+}
@@ -0,0 +1,72 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"testing"
+	"time"
+)
+
+func TestIntegrationOperatorAnnotations(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	dl := at.GetDecisionLogger()
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	openRecord := runCycle(t, at)
+	requireActionSuccess(t, openRecord, "open_long")
+	positionID := openRecord.Decisions[0].PositionID
+	ex.SetPrice("ETHUSDT", 2950)
+	ai.Enqueue(t, "止损离场。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "跌破支撑"})
+	closeRecord := runCycle(t, at)
+	requireActionSuccess(t, closeRecord, "close_long")
+
+	// 开仓决策标记为新闻驱动，持仓标记为成交差
+	news, err := dl.AddAnnotation(logger.Annotation{DecisionID: openRecord.DecisionID, Tags: []string{" News-Driven ", "news-driven"}, Author: "alice"})
+	if err != nil {
+		t.Fatalf("添加决策备注失败: %v", err)
+	}
+	if _, err := dl.AddAnnotation(logger.Annotation{PositionID: positionID, Note: "成交偏离0.4%", Tags: []string{"bad fill"}}); err != nil {
+		t.Fatalf("添加交易备注失败: %v", err)
+	}
+
+	// 决策记录附带备注
+	record, err := dl.GetRecord(openRecord.DecisionID)
+	if err != nil || len(record.Annotations) != 1 || record.Annotations[0].Author != "alice" {
+		t.Fatalf("决策记录应附带备注: %+v %v", record, err)
+	}
+	if byTag, _ := dl.Annotations(logger.AnnotationFilter{Tag: "bad fill"}); len(byTag) != 1 || byTag[0].PositionID != positionID {
+		t.Fatalf("按标签查询: %+v", byTag)
+	}
+
+	// 交易表现分析中的交易带上持仓和开仓决策的标签
+	analysis, err := dl.AnalyzePerformance(10)
+	if err != nil || len(analysis.RecentTrades) != 1 {
+		t.Fatalf("分析交易表现失败: %+v %v", analysis, err)
+	}
+	trade := analysis.RecentTrades[0]
+	if len(trade.Tags) != 2 || len(trade.Notes) != 1 || trade.OpenDecisionID != openRecord.DecisionID || trade.CloseDecisionID != closeRecord.DecisionID {
+		t.Fatalf("交易应附带标签和备注: %+v", trade)
+	}
+
+	// 按标签汇总：0.5 ETH × -50 = -25
+	report, err := dl.TagReport(7, time.Now().In(at.location))
+	if err != nil {
+		t.Fatalf("按标签汇总失败: %v", err)
+	}
+	if report.Trades != 1 || len(report.Tags) != 2 || report.Untagged.Trades != 0 || len(report.Recent) != 1 {
+		t.Fatalf("标签汇总不对: %+v", report)
+	}
+	for _, s := range report.Tags {
+		if s.Trades != 1 || s.Wins != 0 || s.TotalPnL > -24.99 || s.TotalPnL < -25.01 || s.AvgR == nil || *s.AvgR != -0.5 {
+			t.Errorf("标签 %s 的统计不对: %+v", s.Tag, s)
+		}
+	}
+
+	if err := dl.DeleteAnnotation(news.ID); err != nil {
+		t.Fatalf("删除备注失败: %v", err)
+	}
+	if record, _ := dl.GetRecord(openRecord.DecisionID); len(record.Annotations) != 0 {
+		t.Fatal("删除后决策记录不应再附带备注")
+	}
+}
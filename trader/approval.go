@@ -0,0 +1,480 @@
+package trader
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/notify"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 人工审批模式
+// 启用后AI的开仓/加仓决策不直接执行，而是作为交易想法（附完整推理和K线图）进入审批队列，
+// 由人通过API、看板或Telegram按钮在有效期内批准或拒绝；超时未批准的想法视为wait。
+// 平仓、部分平仓、调整止损止盈等风险维护动作照常自动执行。
+// 批准时重新检查风控暂停和降风险阶梯，按当前行情执行，结果单独记一条决策记录。
+
+// 交易想法状态
+const (
+	IdeaPending  = "pending"  // 等待审批
+	IdeaExecuted = "executed" // 已批准并执行成功
+	IdeaFailed   = "failed"   // 已批准但执行失败
+	IdeaDenied   = "denied"   // 已拒绝
+	IdeaExpired  = "expired"  // 超时未审批（视为wait）
+)
+
+const (
+	defaultApprovalTTL = 15 * time.Minute
+	maxTradeIdeas      = 100 // 内存中保留的交易想法数量（含已处理）
+)
+
+// TradeIdea 等待人工审批的开仓/加仓决策
+type TradeIdea struct {
+	ID            string            `json:"id"`
+	TraderID      string            `json:"trader_id"`
+	DecisionID    string            `json:"decision_id"` // 产生该想法的决策记录
+	Decision      decision.Decision `json:"decision"`
+	CoTTrace      string            `json:"cot_trace"`      // 该周期AI的完整思维链
+	IntendedPrice float64           `json:"intended_price"` // 决策时的价格
+	Status        string            `json:"status"`
+	CreatedAt     time.Time         `json:"created_at"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	DecidedAt     time.Time         `json:"decided_at,omitempty"`
+	Result        string            `json:"result,omitempty"` // 执行结果/拒绝原因
+	ChartURL      string            `json:"chart_url,omitempty"`
+	Source        string            `json:"source,omitempty"`     // 想法来源（为空表示AI决策，"mcp" 为外部MCP客户端提议）
+	Experiment    string            `json:"experiment,omitempty"` // 产生该想法时的实验和变体（未参与A/B测试时为空）
+	Variant       string            `json:"variant,omitempty"`
+
+	token string // 通知按钮链接中的一次性校验值
+}
+
+// approvalQueue 交易想法队列
+type approvalQueue struct {
+	ttl     time.Duration
+	baseURL string // API地址，用于生成审批按钮和图表链接；为空时通知不带按钮
+
+	mu    sync.Mutex
+	ideas []*TradeIdea
+}
+
+// newApprovalQueue 创建审批队列（ttl<=0 时使用默认有效期）
+func newApprovalQueue(ttl time.Duration) *approvalQueue {
+	if ttl <= 0 {
+		ttl = defaultApprovalTTL
+	}
+	return &approvalQueue{ttl: ttl}
+}
+
+// add 加入新想法，同一币种同一动作的旧想法被取代
+func (q *approvalQueue) add(idea *TradeIdea) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, old := range q.ideas {
+		if old.Status == IdeaPending && old.Decision.Symbol == idea.Decision.Symbol && old.Decision.Action == idea.Decision.Action {
+			old.Status = IdeaExpired
+			old.DecidedAt = idea.CreatedAt
+			old.Result = "被新的交易想法取代"
+		}
+	}
+	q.ideas = append(q.ideas, idea)
+	if len(q.ideas) > maxTradeIdeas {
+		q.ideas = q.ideas[len(q.ideas)-maxTradeIdeas:]
+	}
+}
+
+// expire 把超时的想法标记为过期，返回本次过期的想法
+func (q *approvalQueue) expire(now time.Time) []*TradeIdea {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var expired []*TradeIdea
+	for _, idea := range q.ideas {
+		if idea.Status == IdeaPending && now.After(idea.ExpiresAt) {
+			idea.Status = IdeaExpired
+			idea.DecidedAt = now
+			idea.Result = "审批超时，视为wait"
+			expired = append(expired, idea)
+		}
+	}
+	return expired
+}
+
+// find 按ID查找
+func (q *approvalQueue) find(id string) *TradeIdea {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, idea := range q.ideas {
+		if idea.ID == id {
+			return idea
+		}
+	}
+	return nil
+}
+
+// decide 把等待中的想法改为指定状态（不是等待状态时返回错误）
+func (q *approvalQueue) decide(idea *TradeIdea, status, result string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if idea.Status != IdeaPending {
+		return fmt.Errorf("交易想法 %s 已处理（%s）", idea.ID, idea.Status)
+	}
+	idea.Status = status
+	idea.DecidedAt = time.Now()
+	idea.Result = result
+	return nil
+}
+
+// list 全部想法（最新的在前）
+func (q *approvalQueue) list() []TradeIdea {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ideas := make([]TradeIdea, 0, len(q.ideas))
+	for i := len(q.ideas) - 1; i >= 0; i-- {
+		ideas = append(ideas, *q.ideas[i])
+	}
+	return ideas
+}
+
+// EnableApprovalLinks 设置审批通知中按钮和图表链接使用的API地址
+func (at *AutoTrader) EnableApprovalLinks(baseURL string) {
+	if at.approval != nil {
+		at.approval.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// ApprovalEnabled 是否启用人工审批模式
+func (at *AutoTrader) ApprovalEnabled() bool {
+	return at.approval != nil
+}
+
+// queueForApproval 把开仓/加仓决策放入审批队列，返回其余需要立即执行的决策
+func (at *AutoTrader) queueForApproval(ctx *decision.Context, full *decision.FullDecision, decisions []decision.Decision, record *logger.DecisionRecord) ([]decision.Decision, []*TradeIdea) {
+	var rest []decision.Decision
+	var queued []*TradeIdea
+	now := time.Now()
+	for _, d := range decisions {
+		if !entryActions[d.Action] {
+			rest = append(rest, d)
+			continue
+		}
+		idea := &TradeIdea{
+			ID:            fmt.Sprintf("idea_%s_%d", d.Symbol, now.UnixNano()+int64(len(queued))),
+			TraderID:      at.id,
+			Decision:      d,
+			CoTTrace:      full.CoTTrace,
+			IntendedPrice: intendedPrice(ctx, d.Symbol),
+			Status:        IdeaPending,
+			CreatedAt:     now,
+			ExpiresAt:     now.Add(at.approval.ttl),
+			Experiment:    record.Experiment,
+			Variant:       record.Variant,
+			token:         newIdeaToken(),
+		}
+		at.approval.add(idea)
+		queued = append(queued, idea)
+		log.Printf("  📝 %s %s 已提交人工审批（%s 前有效）", d.Symbol, d.Action, idea.ExpiresAt.Format("15:04:05"))
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("📝 %s %s 已提交人工审批: %s", d.Symbol, d.Action, idea.ID))
+	}
+	return rest, queued
+}
+
+// expireIdeas 超时的想法视为wait，记入本周期的执行日志
+func (at *AutoTrader) expireIdeas(record *logger.DecisionRecord) {
+	if at.approval == nil {
+		return
+	}
+	for _, idea := range at.approval.expire(time.Now()) {
+		log.Printf("⌛ 交易想法 %s（%s %s）审批超时，视为wait", idea.ID, idea.Decision.Symbol, idea.Decision.Action)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⌛ %s %s 审批超时，视为wait: %s", idea.Decision.Symbol, idea.Decision.Action, idea.ID))
+	}
+}
+
+// notifyIdeas 推送待审批的想法（附K线图、推理和批准/拒绝按钮），需要决策ID，在保存记录之后调用
+func (at *AutoTrader) notifyIdeas(record *logger.DecisionRecord, ideas []*TradeIdea) {
+	for _, idea := range ideas {
+		at.approval.mu.Lock()
+		idea.DecisionID = record.DecisionID
+		idea.ChartURL = decisionChartURL(at.approval.baseURL, at.id, record.DecisionID, idea.Decision.Symbol)
+		at.approval.mu.Unlock()
+
+		d := idea.Decision
+		event := at.ideaEvent(idea)
+		c, err := at.recordChart(record, d.Symbol)
+		if err == nil {
+			event.Image, err = c.PNG()
+		}
+		if err != nil {
+			log.Printf("⚠️ %s 生成K线图失败，通知不附带图片: %v", d.Symbol, err)
+		}
+		notify.Send(event)
+	}
+}
+
+// ideaEvent 待审批想法的通知（不含K线图）
+func (at *AutoTrader) ideaEvent(idea *TradeIdea) notify.Event {
+	d := idea.Decision
+	title := fmt.Sprintf("%s 待审批: %s %s", at.name, d.Action, d.Symbol)
+	if idea.Source != "" {
+		title += fmt.Sprintf("（来自%s）", idea.Source)
+	}
+	return notify.Event{
+		Type:     "trade.idea",
+		Severity: notify.SeverityInfo,
+		TraderID: at.id,
+		Symbol:   d.Symbol,
+		Title:    title,
+		Message: fmt.Sprintf("仓位 %.2f USDT，杠杆 %dx，参考价 %.4f\n止损 %.4f | 止盈 %.4f\n%s\n%s 前未批准视为wait",
+			d.PositionSizeUSD, d.Leverage, idea.IntendedPrice, d.StopLoss, d.TakeProfit, d.Reasoning, idea.ExpiresAt.Format("15:04:05")),
+		Link:    idea.ChartURL,
+		Actions: at.ideaActions(idea),
+	}
+}
+
+// ProposeIdea 外部提交的开仓/加仓提议（如MCP客户端），按AI决策的规则校验后进入审批队列，
+// 与AI的想法一样只有人工批准后才会执行
+func (at *AutoTrader) ProposeIdea(d decision.Decision, source string) (*TradeIdea, error) {
+	if at.approval == nil {
+		return nil, fmt.Errorf("trader %s 未启用人工审批模式，不接受下单提议", at.id)
+	}
+	if !entryActions[d.Action] {
+		return nil, fmt.Errorf("只能提议开仓/加仓（open_long/open_short/add_to_position），收到: %s", d.Action)
+	}
+	d.Symbol = market.Normalize(d.Symbol)
+
+	balance, err := at.getBalance()
+	if err != nil {
+		return nil, fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	wallet, _ := balance["totalWalletBalance"].(float64)
+	unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+	if err := decision.ValidateDecision(&d, wallet+unrealized, at.config.BTCETHLeverage, at.config.AltcoinLeverage,
+		at.config.MinPositionSizeUSD, at.config.MaxPositionSizeUSD, at.symbolFilter, at.config.SymbolLimits); err != nil {
+		return nil, err
+	}
+	data, err := market.Get(d.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 行情失败: %w", d.Symbol, err)
+	}
+
+	now := time.Now()
+	idea := &TradeIdea{
+		ID:            fmt.Sprintf("idea_%s_%d", d.Symbol, now.UnixNano()),
+		TraderID:      at.id,
+		Decision:      d,
+		IntendedPrice: data.CurrentPrice,
+		Status:        IdeaPending,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(at.approval.ttl),
+		Source:        source,
+		token:         newIdeaToken(),
+	}
+	at.approval.add(idea)
+	log.Printf("📝 收到%s下单提议 %s: %s %s %.2f USDT（%s 前有效）", source, idea.ID, d.Symbol, d.Action, d.PositionSizeUSD, idea.ExpiresAt.Format("15:04:05"))
+	notify.Send(at.ideaEvent(idea))
+	return idea, nil
+}
+
+// ideaActions 通知中的批准/拒绝按钮（未配置API地址时为空）
+func (at *AutoTrader) ideaActions(idea *TradeIdea) []notify.Action {
+	if at.approval.baseURL == "" {
+		return nil
+	}
+	link := func(action string) string {
+		q := url.Values{}
+		q.Set("trader_id", at.id)
+		q.Set("id", idea.ID)
+		q.Set("action", action)
+		q.Set("token", idea.token)
+		return at.approval.baseURL + "/api/ideas/decide?" + q.Encode()
+	}
+	return []notify.Action{
+		{Label: "✅ 批准", URL: link("approve")},
+		{Label: "❌ 拒绝", URL: link("deny")},
+	}
+}
+
+// TradeIdeas 全部交易想法（最新的在前）
+func (at *AutoTrader) TradeIdeas() []TradeIdea {
+	if at.approval == nil {
+		return []TradeIdea{}
+	}
+	for _, idea := range at.approval.expire(time.Now()) {
+		log.Printf("⌛ 交易想法 %s（%s %s）审批超时，视为wait", idea.ID, idea.Decision.Symbol, idea.Decision.Action)
+	}
+	return at.approval.list()
+}
+
+// TradeIdea 按ID查找交易想法（超时的先标记为过期）
+func (at *AutoTrader) TradeIdea(id string) (TradeIdea, bool) {
+	if at.approval == nil {
+		return TradeIdea{}, false
+	}
+	at.approval.expire(time.Now())
+	at.approval.mu.Lock()
+	defer at.approval.mu.Unlock()
+	for _, idea := range at.approval.ideas {
+		if idea.ID == id {
+			return *idea, true
+		}
+	}
+	return TradeIdea{}, false
+}
+
+// VerifyIdeaToken 校验通知按钮链接中的token（一次性：想法已处理或已过期后链接失效）
+func (at *AutoTrader) VerifyIdeaToken(id, token string) bool {
+	if at.approval == nil || token == "" {
+		return false
+	}
+	at.approval.expire(time.Now())
+	idea := at.approval.find(id)
+	if idea == nil {
+		return false
+	}
+	at.approval.mu.Lock()
+	defer at.approval.mu.Unlock()
+	return idea.Status == IdeaPending && subtle.ConstantTimeCompare([]byte(idea.token), []byte(token)) == 1
+}
+
+// ApproveIdea 批准并立即执行交易想法
+func (at *AutoTrader) ApproveIdea(id string) (*TradeIdea, error) {
+	idea, err := at.pendingIdea(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 与交易周期互斥，避免和AI决策同时下单
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+
+	if time.Now().Before(at.stopUntil) {
+		return nil, fmt.Errorf("风险控制暂停中（%s），直到 %s", at.stopReason, at.stopUntil.Format("15:04:05"))
+	}
+	if at.derisk.level >= DeriskCloseOnly {
+		return nil, fmt.Errorf("降风险阶梯为 %s，只允许平仓", at.derisk.level)
+	}
+	if halted, reason := at.flashGuard.active(); halted {
+		return nil, fmt.Errorf("急跌熔断中（%s），只允许平仓", reason)
+	}
+	if err := at.approval.decide(idea, IdeaExecuted, ""); err != nil {
+		return nil, err
+	}
+
+	d := idea.Decision
+	log.Printf("👤 人工批准交易想法 %s: %s %s", idea.ID, d.Symbol, d.Action)
+	origin := "决策 " + idea.DecisionID
+	if idea.Source != "" {
+		origin = idea.Source + " 提议"
+	}
+	record := &logger.DecisionRecord{
+		ExecutionLog: []string{fmt.Sprintf("👤 人工批准交易想法 %s（来自%s）", idea.ID, origin)},
+		Success:      true,
+		CoTTrace:     idea.CoTTrace,
+		Experiment:   idea.Experiment,
+		Variant:      idea.Variant,
+	}
+	if data, err := json.MarshalIndent([]decision.Decision{d}, "", "  "); err == nil {
+		record.DecisionJSON = string(data)
+	}
+	if balance, err := at.getBalance(); err == nil {
+		wallet, _ := balance["totalWalletBalance"].(float64)
+		unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+		available, _ := balance["availableBalance"].(float64)
+		record.AccountState = logger.AccountSnapshot{
+			TotalBalance:          wallet + unrealized,
+			AvailableBalance:      available,
+			TotalUnrealizedProfit: unrealized,
+		}
+	}
+
+	actionRecord := logger.DecisionAction{
+		Action:        d.Action,
+		Symbol:        d.Symbol,
+		Leverage:      d.Leverage,
+		Timestamp:     time.Now(),
+		IntendedPrice: idea.IntendedPrice,
+	}
+	execErr := at.executeDecisionWithRecord(&d, &actionRecord)
+	at.approval.mu.Lock()
+	idea.DecidedAt = time.Now()
+	if execErr != nil {
+		idea.Status = IdeaFailed
+		idea.Result = execErr.Error()
+	} else {
+		idea.Result = fmt.Sprintf("数量 %.4f @ %.4f", actionRecord.Quantity, actionRecord.Price)
+	}
+	at.approval.mu.Unlock()
+
+	if execErr != nil {
+		log.Printf("❌ 执行交易想法失败 (%s %s): %v", d.Symbol, d.Action, execErr)
+		actionRecord.Error = execErr.Error()
+		actionRecord.RejectReason = orderRejectReason(execErr)
+		record.Success = false
+		record.ErrorMessage = execErr.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, execErr))
+	} else {
+		actionRecord.Success = true
+		at.rememberPlan(&decision.Context{}, &d, &actionRecord) // 没有周期上下文，只记录开仓的计划
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+	}
+	record.Decisions = append(record.Decisions, actionRecord)
+
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+	at.notifyOpenedTrades(record)
+
+	if execErr != nil {
+		return idea, fmt.Errorf("执行失败: %w", execErr)
+	}
+	return idea, nil
+}
+
+// DenyIdea 拒绝交易想法
+func (at *AutoTrader) DenyIdea(id, reason string) (*TradeIdea, error) {
+	idea, err := at.pendingIdea(id)
+	if err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		reason = "人工拒绝"
+	}
+	if err := at.approval.decide(idea, IdeaDenied, reason); err != nil {
+		return nil, err
+	}
+	log.Printf("👤 人工拒绝交易想法 %s: %s %s（%s）", idea.ID, idea.Decision.Symbol, idea.Decision.Action, reason)
+	return idea, nil
+}
+
+// pendingIdea 查找仍在等待审批的想法（超时的先标记为过期）
+func (at *AutoTrader) pendingIdea(id string) (*TradeIdea, error) {
+	if at.approval == nil {
+		return nil, fmt.Errorf("trader %s 未启用人工审批模式", at.id)
+	}
+	at.approval.expire(time.Now())
+	idea := at.approval.find(id)
+	if idea == nil {
+		return nil, fmt.Errorf("交易想法不存在: %s", id)
+	}
+	if idea.Status != IdeaPending {
+		return nil, fmt.Errorf("交易想法 %s 已处理（%s）", id, idea.Status)
+	}
+	return idea, nil
+}
+
+// newIdeaToken 随机生成按钮链接的校验值
+func newIdeaToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,183 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/notify"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegrationApprovalQueue(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.approval = newApprovalQueue(time.Minute)
+	at.EnableApprovalLinks("http://localhost:8080/")
+
+	capture := &captureChannel{events: make(chan notify.Event, 10)}
+	notify.SetDefault(notify.New(capture))
+	t.Cleanup(func() { notify.SetDefault(nil) })
+
+	// AI开仓决策进入审批队列，不下单
+	ai.Enqueue(t, "突破阻力位，开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	requireExecutionLog(t, record.ExecutionLog, "已提交人工审批")
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 0 {
+		t.Fatalf("审批前不应开仓: %+v", pos)
+	}
+
+	ideas := at.TradeIdeas()
+	if len(ideas) != 1 || ideas[0].Status != IdeaPending || ideas[0].DecisionID != record.DecisionID {
+		t.Fatalf("交易想法不符合预期: %+v", ideas)
+	}
+	idea := ideas[0]
+	if !strings.Contains(idea.CoTTrace, "突破阻力位") {
+		t.Errorf("交易想法应保存完整推理: %q", idea.CoTTrace)
+	}
+
+	// 通知附带K线图和批准/拒绝按钮
+	select {
+	case e := <-capture.events:
+		if e.Type != "trade.idea" || len(e.Actions) != 2 || len(e.Image) == 0 {
+			t.Fatalf("审批通知不符合预期: %+v", e)
+		}
+		if !strings.Contains(e.Actions[0].URL, "/api/ideas/decide?") || !strings.Contains(e.Actions[0].URL, "action=approve") {
+			t.Errorf("批准按钮链接错误: %s", e.Actions[0].URL)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有收到审批通知")
+	}
+	if at.VerifyIdeaToken(idea.ID, "wrong") {
+		t.Error("错误的token不应通过校验")
+	}
+	token := at.approval.find(idea.ID).token
+	if !at.VerifyIdeaToken(idea.ID, token) {
+		t.Fatal("等待中的想法的token应通过校验")
+	}
+	if found, ok := at.TradeIdea(idea.ID); !ok || found.Status != IdeaPending || found.Decision.Symbol != "ETHUSDT" {
+		t.Errorf("TradeIdea = %+v, %v", found, ok)
+	}
+
+	// 批准后按决策开仓并挂止损止盈
+	approved, err := at.ApproveIdea(idea.ID)
+	if err != nil {
+		t.Fatalf("批准失败: %v", err)
+	}
+	if approved.Status != IdeaExecuted {
+		t.Errorf("批准后状态 = %s", approved.Status)
+	}
+	if pos := ex.GatePosition("ETHUSDT"); pos.size <= 0 {
+		t.Fatalf("批准后应开多: %+v", pos)
+	}
+	if open := ex.Triggers("gateio", "open"); len(open) != 2 {
+		t.Errorf("条件单数量 = %d，期望2", len(open))
+	}
+	if _, err := at.ApproveIdea(idea.ID); err == nil {
+		t.Error("已执行的想法不应再次批准")
+	}
+	if at.VerifyIdeaToken(idea.ID, token) {
+		t.Error("想法处理后按钮链接应失效")
+	}
+	records, err := at.decisionLogger.GetLatestRecords(1)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("读取决策记录失败: %v", err)
+	}
+	requireExecutionLog(t, records[0].ExecutionLog, "人工批准交易想法 "+idea.ID)
+	requireActionSuccess(t, records[0], "open_long")
+
+	// 加仓想法被拒绝；平仓不需要审批，照常执行
+	add := decision.Decision{Symbol: "ETHUSDT", Action: "add_to_position", PositionSizeUSD: 500, StopLoss: 2900, TakeProfit: 3400, Reasoning: "继续加仓"}
+	ai.Enqueue(t, "加仓。", add)
+	runCycle(t, at)
+	pending := at.TradeIdeas()[0]
+	if pending.Decision.Action != "add_to_position" || pending.Status != IdeaPending {
+		t.Fatalf("加仓应进入审批队列: %+v", pending)
+	}
+	if _, err := at.DenyIdea(pending.ID, "仓位已够"); err != nil {
+		t.Fatalf("拒绝失败: %v", err)
+	}
+	if got := at.TradeIdeas()[0]; got.Status != IdeaDenied || got.Result != "仓位已够" {
+		t.Errorf("拒绝后状态不符合预期: %+v", got)
+	}
+
+	ai.Enqueue(t, "止盈离场。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "锁定利润"})
+	record = runCycle(t, at)
+	requireActionSuccess(t, record, "close_long")
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 0 {
+		t.Errorf("平仓应自动执行: %+v", pos)
+	}
+}
+
+func TestIntegrationApprovalExpiresToWait(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.approval = newApprovalQueue(time.Minute)
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	runCycle(t, at)
+	idea := at.TradeIdeas()[0]
+
+	// 超过有效期：下个周期记为wait，之后不能再批准
+	at.approval.mu.Lock()
+	at.approval.ideas[0].ExpiresAt = time.Now().Add(-time.Second)
+	at.approval.mu.Unlock()
+
+	ai.Enqueue(t, "观望。", decision.Decision{Symbol: "ETHUSDT", Action: "wait", Reasoning: "等待回调"})
+	record := runCycle(t, at)
+	requireExecutionLog(t, record.ExecutionLog, "审批超时，视为wait")
+
+	if _, err := at.ApproveIdea(idea.ID); err == nil {
+		t.Error("过期的想法不应被批准")
+	}
+	if at.VerifyIdeaToken(idea.ID, at.approval.find(idea.ID).token) {
+		t.Error("过期的想法的按钮链接应失效")
+	}
+	if got := at.TradeIdeas()[0]; got.Status != IdeaExpired {
+		t.Errorf("状态 = %s，期望 expired", got.Status)
+	}
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 0 {
+		t.Errorf("过期的想法不应开仓: %+v", pos)
+	}
+}
+
+func TestIntegrationProposedIdeaNeedsApproval(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	if _, err := at.ProposeIdea(openLongETH(1500), "mcp"); err == nil {
+		t.Fatal("未启用人工审批时不应接受下单提议")
+	}
+	at.approval = newApprovalQueue(time.Minute)
+
+	if _, err := at.ProposeIdea(decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "止盈"}, "mcp"); err == nil {
+		t.Error("只能提议开仓/加仓")
+	}
+	invalid := openLongETH(1500)
+	invalid.Leverage = 50 // 超过配置的杠杆上限
+	if _, err := at.ProposeIdea(invalid, "mcp"); err == nil {
+		t.Error("提议应按AI决策规则校验")
+	}
+
+	idea, err := at.ProposeIdea(openLongETH(1500), "mcp")
+	if err != nil {
+		t.Fatalf("提交提议失败: %v", err)
+	}
+	if idea.Status != IdeaPending || idea.Source != "mcp" || idea.IntendedPrice != 3000 {
+		t.Fatalf("提议不符合预期: %+v", idea)
+	}
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 0 {
+		t.Fatal("批准前不应开仓")
+	}
+
+	if _, err := at.ApproveIdea(idea.ID); err != nil {
+		t.Fatalf("批准失败: %v", err)
+	}
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 50 {
+		t.Errorf("批准后持仓 = %v 张，期望 50", pos.size)
+	}
+	records, err := at.decisionLogger.GetLatestRecords(1)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("读取决策记录失败: %v", err)
+	}
+	requireExecutionLog(t, records[0].ExecutionLog, "来自mcp 提议")
+}
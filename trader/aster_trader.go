@@ -13,10 +13,13 @@ import (
 	"math/big"
 	"net/http"
 	"net/url"
+	"nofx/cache"
+	"nofx/errs"
+	"nofx/market"
+	"nofx/ratelimit"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -26,6 +29,8 @@ import (
 
 // AsterTrader Aster交易平台实现
 type AsterTrader struct {
+	positionTags // 止损止盈单的客户端订单ID（按持仓ID）
+
 	ctx        context.Context
 	user       string           // 主钱包地址 (ERC20)
 	signer     string           // API钱包地址
@@ -34,8 +39,10 @@ type AsterTrader struct {
 	baseURL    string
 
 	// 缓存交易对精度信息
-	symbolPrecision map[string]SymbolPrecision
-	mu              sync.RWMutex
+	symbolPrecision *cache.Cache[string, SymbolPrecision]
+
+	// 下单类型（默认激进IOC限价单）
+	orderTypeSelector
 }
 
 // SymbolPrecision 交易对精度信息
@@ -62,16 +69,17 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 		user:            user,
 		signer:          signer,
 		privateKey:      privKey,
-		symbolPrecision: make(map[string]SymbolPrecision),
+		symbolPrecision: cache.New[string, SymbolPrecision]("aster.precision", cache.NoExpiry),
 		client: &http.Client{
 			Timeout: 30 * time.Second, // 增加到30秒
-			Transport: &http.Transport{
+			Transport: ratelimit.NewTransport(ratelimit.Get("aster"), &http.Transport{
 				TLSHandshakeTimeout:   10 * time.Second,
 				ResponseHeaderTimeout: 10 * time.Second,
 				IdleConnTimeout:       90 * time.Second,
-			},
+			}),
 		},
-		baseURL: "https://fapi.asterdex.com",
+		baseURL:           "https://fapi.asterdex.com",
+		orderTypeSelector: newOrderTypeSelector(OrderTypeIOC, OrderTypeMarket, OrderTypeFOK, OrderTypePostOnly),
 	}, nil
 }
 
@@ -82,17 +90,17 @@ func (t *AsterTrader) genNonce() uint64 {
 
 // getPrecision 获取交易对精度信息
 func (t *AsterTrader) getPrecision(symbol string) (SymbolPrecision, error) {
-	t.mu.RLock()
-	if prec, ok := t.symbolPrecision[symbol]; ok {
-		t.mu.RUnlock()
-		return prec, nil
-	}
-	t.mu.RUnlock()
+	return t.symbolPrecision.GetOrLoad(symbol, func() (SymbolPrecision, error) {
+		return t.loadPrecisions(symbol)
+	})
+}
 
+// loadPrecisions 获取交易所信息，缓存所有交易对的精度并返回 symbol 的精度
+func (t *AsterTrader) loadPrecisions(symbol string) (SymbolPrecision, error) {
 	// 获取交易所信息
 	resp, err := t.client.Get(t.baseURL + "/fapi/v3/exchangeInfo")
 	if err != nil {
-		return SymbolPrecision{}, err
+		return SymbolPrecision{}, errs.Network("aster", err)
 	}
 	defer resp.Body.Close()
 
@@ -111,7 +119,7 @@ func (t *AsterTrader) getPrecision(symbol string) (SymbolPrecision, error) {
 	}
 
 	// 缓存所有交易对的精度
-	t.mu.Lock()
+	var found *SymbolPrecision
 	for _, s := range info.Symbols {
 		prec := SymbolPrecision{
 			PricePrecision:    s.PricePrecision,
@@ -133,12 +141,14 @@ func (t *AsterTrader) getPrecision(symbol string) (SymbolPrecision, error) {
 			}
 		}
 
-		t.symbolPrecision[s.Symbol] = prec
+		t.symbolPrecision.Set(s.Symbol, prec)
+		if s.Symbol == symbol {
+			found = &prec
+		}
 	}
-	t.mu.Unlock()
 
-	if prec, ok := t.symbolPrecision[symbol]; ok {
-		return prec, nil
+	if found != nil {
+		return *found, nil
 	}
 
 	return SymbolPrecision{}, fmt.Errorf("未找到交易对 %s 的精度信息", symbol)
@@ -174,6 +184,18 @@ func (t *AsterTrader) formatPrice(symbol string, price float64) (float64, error)
 	return math.Round(price*multiplier) / multiplier, nil
 }
 
+// RoundPrice 将价格取整到交易对的tick size
+func (t *AsterTrader) RoundPrice(symbol string, price float64) (float64, error) {
+	prec, err := t.getPrecision(symbol)
+	if err != nil {
+		return 0, err
+	}
+	if prec.TickSize > 0 {
+		return roundToTick(price, prec.TickSize), nil
+	}
+	return t.formatPrice(symbol, price)
+}
+
 // formatQuantity 格式化数量到正确精度和step size
 func (t *AsterTrader) formatQuantity(symbol string, quantity float64) (float64, error) {
 	prec, err := t.getPrecision(symbol)
@@ -346,10 +368,8 @@ func (t *AsterTrader) request(method, endpoint string, params map[string]interfa
 
 		lastErr = err
 
-		// 如果是网络超时或临时错误，重试
-		if strings.Contains(err.Error(), "timeout") ||
-			strings.Contains(err.Error(), "connection reset") ||
-			strings.Contains(err.Error(), "EOF") {
+		// 网络超时或临时错误，重试
+		if errors.Is(err, errs.ErrNetwork) {
 			if attempt < maxRetries {
 				waitTime := time.Duration(attempt) * time.Second
 				time.Sleep(waitTime)
@@ -384,13 +404,13 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 
 		resp, err := t.client.Do(req)
 		if err != nil {
-			return nil, err
+			return nil, errs.Network("aster", err)
 		}
 		defer resp.Body.Close()
 
 		body, _ := io.ReadAll(resp.Body)
 		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+			return nil, asterError(resp, body)
 		}
 		return body, nil
 
@@ -410,13 +430,13 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 
 		resp, err := t.client.Do(req)
 		if err != nil {
-			return nil, err
+			return nil, errs.Network("aster", err)
 		}
 		defer resp.Body.Close()
 
 		body, _ := io.ReadAll(resp.Body)
 		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+			return nil, asterError(resp, body)
 		}
 		return body, nil
 
@@ -425,6 +445,28 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 	}
 }
 
+// timeInForce 下单类型对应的有效方式
+func (t *AsterTrader) timeInForce(orderType OrderType) string {
+	switch orderType {
+	case OrderTypeFOK:
+		return "FOK"
+	case OrderTypePostOnly:
+		return "GTX"
+	default:
+		return "IOC"
+	}
+}
+
+// applyMarketOrder 市价单类型时把限价单参数改为市价单
+func (t *AsterTrader) applyMarketOrder(params map[string]interface{}, orderType OrderType) {
+	if orderType != OrderTypeMarket {
+		return
+	}
+	params["type"] = "MARKET"
+	delete(params, "price")
+	delete(params, "timeInForce")
+}
+
 // GetBalance 获取账户余额
 func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 	params := make(map[string]interface{})
@@ -438,23 +480,25 @@ func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 		return nil, err
 	}
 
-	// 查找USDT余额
+	// 合计USDT、USDC等稳定币保证金余额
 	totalBalance := 0.0
 	availableBalance := 0.0
 	crossUnPnl := 0.0
 
 	for _, bal := range balances {
-		if asset, ok := bal["asset"].(string); ok && asset == "USDT" {
+		if asset, ok := bal["asset"].(string); ok && market.IsStableQuote(asset) {
 			if wb, ok := bal["balance"].(string); ok {
-				totalBalance, _ = strconv.ParseFloat(wb, 64)
+				v, _ := strconv.ParseFloat(wb, 64)
+				totalBalance += v
 			}
 			if avail, ok := bal["availableBalance"].(string); ok {
-				availableBalance, _ = strconv.ParseFloat(avail, 64)
+				v, _ := strconv.ParseFloat(avail, 64)
+				availableBalance += v
 			}
 			if unpnl, ok := bal["crossUnPnl"].(string); ok {
-				crossUnPnl, _ = strconv.ParseFloat(unpnl, 64)
+				v, _ := strconv.ParseFloat(unpnl, 64)
+				crossUnPnl += v
 			}
-			break
 		}
 	}
 
@@ -521,7 +565,12 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 }
 
 // OpenLong 开多单
-func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -538,8 +587,8 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 		return nil, err
 	}
 
-	// 使用限价单模拟市价单（价格设置得稍高一些以确保成交）
-	limitPrice := price * 1.01
+	// 使用限价单模拟市价单（价格设置得稍高一些以确保成交，post_only 时挂在盘口内侧）
+	limitPrice := limitPriceFor(price, true, orderType)
 
 	// 格式化价格和数量到正确精度
 	formattedPrice, err := t.formatPrice(symbol, limitPrice)
@@ -569,11 +618,13 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 		"positionSide": "BOTH",
 		"type":         "LIMIT",
 		"side":         "BUY",
-		"timeInForce":  "GTC",
+		"timeInForce":  t.timeInForce(orderType),
 		"quantity":     qtyStr,
 		"price":        priceStr,
 	}
 
+	t.applyMarketOrder(params, orderType)
+
 	body, err := t.request("POST", "/fapi/v3/order", params)
 	if err != nil {
 		return nil, err
@@ -588,7 +639,12 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 }
 
 // OpenShort 开空单
-func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -606,7 +662,7 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 	}
 
 	// 使用限价单模拟市价单（价格设置得稍低一些以确保成交）
-	limitPrice := price * 0.99
+	limitPrice := limitPriceFor(price, false, orderType)
 
 	// 格式化价格和数量到正确精度
 	formattedPrice, err := t.formatPrice(symbol, limitPrice)
@@ -636,11 +692,13 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 		"positionSide": "BOTH",
 		"type":         "LIMIT",
 		"side":         "SELL",
-		"timeInForce":  "GTC",
+		"timeInForce":  t.timeInForce(orderType),
 		"quantity":     qtyStr,
 		"price":        priceStr,
 	}
 
+	t.applyMarketOrder(params, orderType)
+
 	body, err := t.request("POST", "/fapi/v3/order", params)
 	if err != nil {
 		return nil, err
@@ -655,7 +713,12 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 }
 
 // CloseLong 平多单
-func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *AsterTrader) CloseLong(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -681,7 +744,7 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		return nil, err
 	}
 
-	limitPrice := price * 0.99
+	limitPrice := limitPriceFor(price, false, orderType)
 
 	// 格式化价格和数量到正确精度
 	formattedPrice, err := t.formatPrice(symbol, limitPrice)
@@ -711,11 +774,13 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		"positionSide": "BOTH",
 		"type":         "LIMIT",
 		"side":         "SELL",
-		"timeInForce":  "GTC",
+		"timeInForce":  t.timeInForce(orderType),
 		"quantity":     qtyStr,
 		"price":        priceStr,
 	}
 
+	t.applyMarketOrder(params, orderType)
+
 	body, err := t.request("POST", "/fapi/v3/order", params)
 	if err != nil {
 		return nil, err
@@ -737,7 +802,12 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 }
 
 // CloseShort 平空单
-func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *AsterTrader) CloseShort(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -764,7 +834,7 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		return nil, err
 	}
 
-	limitPrice := price * 1.01
+	limitPrice := limitPriceFor(price, true, orderType)
 
 	// 格式化价格和数量到正确精度
 	formattedPrice, err := t.formatPrice(symbol, limitPrice)
@@ -794,11 +864,13 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		"positionSide": "BOTH",
 		"type":         "LIMIT",
 		"side":         "BUY",
-		"timeInForce":  "GTC",
+		"timeInForce":  t.timeInForce(orderType),
 		"quantity":     qtyStr,
 		"price":        priceStr,
 	}
 
+	t.applyMarketOrder(params, orderType)
+
 	body, err := t.request("POST", "/fapi/v3/order", params)
 	if err != nil {
 		return nil, err
@@ -819,14 +891,42 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 	return result, nil
 }
 
-// SetLeverage 设置杠杆倍数
+// currentLeverage 币种当前的杠杆倍数（无持仓时交易所同样返回）
+func (t *AsterTrader) currentLeverage(symbol string) (int, error) {
+	body, err := t.request("GET", "/fapi/v3/positionRisk", map[string]interface{}{"symbol": symbol})
+	if err != nil {
+		return 0, err
+	}
+	var positions []map[string]interface{}
+	if err := json.Unmarshal(body, &positions); err != nil {
+		return 0, err
+	}
+	for _, pos := range positions {
+		if pos["symbol"] == symbol {
+			leverage, _ := strconv.Atoi(fmt.Sprint(pos["leverage"]))
+			return leverage, nil
+		}
+	}
+	return 0, fmt.Errorf("%s 没有杠杆信息", symbol)
+}
+
+// SetLeverage 设置杠杆倍数（当前已是目标杠杆时跳过）
 func (t *AsterTrader) SetLeverage(symbol string, leverage int) error {
+	if current, err := t.currentLeverage(symbol); err == nil && current == leverage {
+		log.Printf("  ✓ %s 杠杆已是 %dx，无需切换", symbol, leverage)
+		return nil
+	}
+
 	params := map[string]interface{}{
 		"symbol":   symbol,
 		"leverage": leverage,
 	}
 
 	_, err := t.request("POST", "/fapi/v3/leverage", params)
+	if isNotModified(err) {
+		log.Printf("  ✓ %s 杠杆已是 %dx", symbol, leverage)
+		return nil
+	}
 	return err
 }
 
@@ -835,13 +935,13 @@ func (t *AsterTrader) GetMarketPrice(symbol string) (float64, error) {
 	// 使用ticker接口获取当前价格
 	resp, err := t.client.Get(fmt.Sprintf("%s/fapi/v3/ticker/price?symbol=%s", t.baseURL, symbol))
 	if err != nil {
-		return 0, err
+		return 0, errs.Network("aster", err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		return 0, asterError(resp, body)
 	}
 
 	var result map[string]interface{}
@@ -893,6 +993,9 @@ func (t *AsterTrader) SetStopLoss(symbol string, positionSide string, quantity,
 		"quantity":     qtyStr,
 		"timeInForce":  "GTC",
 	}
+	if clientID := t.protectionClientID(symbol, positionSide, "sl"); clientID != "" {
+		params["newClientOrderId"] = clientID
+	}
 
 	_, err = t.request("POST", "/fapi/v3/order", params)
 	return err
@@ -934,6 +1037,9 @@ func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity
 		"quantity":     qtyStr,
 		"timeInForce":  "GTC",
 	}
+	if clientID := t.protectionClientID(symbol, positionSide, "tp"); clientID != "" {
+		params["newClientOrderId"] = clientID
+	}
 
 	_, err = t.request("POST", "/fapi/v3/order", params)
 	return err
@@ -957,3 +1063,134 @@ func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, e
 	}
 	return fmt.Sprintf("%v", formatted), nil
 }
+
+// GetFundingPayments 获取资金费流水（收入历史中的 FUNDING_FEE）
+func (t *AsterTrader) GetFundingPayments(since time.Time) ([]FundingPayment, error) {
+	params := map[string]interface{}{
+		"incomeType": "FUNDING_FEE",
+		"startTime":  since.UnixMilli(),
+		"limit":      1000,
+	}
+	body, err := t.request("GET", "/fapi/v3/income", params)
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费流水失败: %w", err)
+	}
+
+	var incomes []struct {
+		Symbol string      `json:"symbol"`
+		Income string      `json:"income"`
+		Time   int64       `json:"time"`
+		TranID json.Number `json:"tranId"`
+	}
+	if err := json.Unmarshal(body, &incomes); err != nil {
+		return nil, fmt.Errorf("解析资金费流水失败: %w", err)
+	}
+
+	payments := make([]FundingPayment, 0, len(incomes))
+	for _, income := range incomes {
+		amount, _ := strconv.ParseFloat(income.Income, 64)
+		payments = append(payments, FundingPayment{
+			ID:     income.TranID.String(),
+			Symbol: income.Symbol,
+			Amount: amount,
+			Time:   time.UnixMilli(income.Time),
+		})
+	}
+	return payments, nil
+}
+
+// ListInstruments 获取USDT永续合约列表（字段与币安相同）
+func (t *AsterTrader) ListInstruments() ([]Instrument, error) {
+	resp, err := t.client.Get(t.baseURL + "/fapi/v3/exchangeInfo")
+	if err != nil {
+		return nil, fmt.Errorf("获取交易所信息失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取交易所信息失败: %w", err)
+	}
+	var info struct {
+		Symbols []struct {
+			Symbol       string `json:"symbol"`
+			Status       string `json:"status"`
+			ContractType string `json:"contractType"`
+			QuoteAsset   string `json:"quoteAsset"`
+			OnboardDate  int64  `json:"onboardDate"`
+			DeliveryDate int64  `json:"deliveryDate"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("解析交易所信息失败: %w", err)
+	}
+
+	now := time.Now()
+	instruments := make([]Instrument, 0, len(info.Symbols))
+	for _, s := range info.Symbols {
+		if s.ContractType != "PERPETUAL" || !market.IsStableQuote(s.QuoteAsset) {
+			continue
+		}
+		instruments = append(instruments, binanceStyleInstrument(s.Symbol, s.Status, s.OnboardDate, s.DeliveryDate, now))
+	}
+	return instruments, nil
+}
+
+// GetAccountPermissions 查询交易权限、持仓模式、手续费等级和费率
+// Aster 通过API钱包签名下单，API钱包没有提现能力
+func (t *AsterTrader) GetAccountPermissions() (*AccountPermissions, error) {
+	body, err := t.request("GET", "/fapi/v3/account", map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+	var account struct {
+		CanTrade bool `json:"canTrade"`
+		FeeTier  int  `json:"feeTier"`
+	}
+	if err := json.Unmarshal(body, &account); err != nil {
+		return nil, fmt.Errorf("解析账户信息失败: %w", err)
+	}
+
+	body, err = t.request("GET", "/fapi/v3/positionSide/dual", map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓模式失败: %w", err)
+	}
+	var mode struct {
+		DualSidePosition bool `json:"dualSidePosition"`
+	}
+	if err := json.Unmarshal(body, &mode); err != nil {
+		return nil, fmt.Errorf("解析持仓模式失败: %w", err)
+	}
+
+	perms := &AccountPermissions{
+		CanTrade:      account.CanTrade,
+		WithdrawKnown: true,
+		PositionMode:  PositionModeOneWay,
+		RequiredMode:  PositionModeOneWay, // 下单使用 positionSide BOTH
+		Tier:          fmt.Sprintf("VIP %d", account.FeeTier),
+	}
+	if mode.DualSidePosition {
+		perms.PositionMode = PositionModeHedge
+	}
+
+	body, err = t.request("GET", "/fapi/v3/commissionRate", map[string]interface{}{"symbol": "BTCUSDT"})
+	if err != nil {
+		log.Printf("⚠️ 获取Aster手续费率失败: %v", err)
+		return perms, nil
+	}
+	var rates struct {
+		MakerCommissionRate string `json:"makerCommissionRate"`
+		TakerCommissionRate string `json:"takerCommissionRate"`
+	}
+	if err := json.Unmarshal(body, &rates); err == nil {
+		perms.MakerFeeRate, _ = strconv.ParseFloat(rates.MakerCommissionRate, 64)
+		perms.TakerFeeRate, _ = strconv.ParseFloat(rates.TakerCommissionRate, 64)
+		perms.FeesKnown = true
+	}
+	return perms, nil
+}
+
+// SetProxy 之后的请求经过出口代理（保留限频层和超时设置）
+func (t *AsterTrader) SetProxy(proxyURL *url.URL) {
+	t.client.Transport = withProxy(t.client.Transport, proxyURL)
+}
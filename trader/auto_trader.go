@@ -1,18 +1,35 @@
 package trader
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"nofx/decision"
+	"nofx/errs"
+	"nofx/events"
 	"nofx/logger"
 	"nofx/market"
 	"nofx/mcp"
+	"nofx/notify"
 	"nofx/pool"
+	"nofx/tracing"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// 交易策略
+const (
+	StrategyAI    = "ai"    // AI方向性交易（默认）
+	StrategyCarry = "carry" // 资金费套利
+	StrategyGrid  = "grid"  // 网格/DCA（确定性基准）
+)
+
 // AutoTraderConfig 自动交易配置（简化版 - AI全权决策）
 type AutoTraderConfig struct {
 	// Trader标识
@@ -47,8 +64,9 @@ type AutoTraderConfig struct {
 
 	// AI配置
 	UseQwen     bool
-	DeepSeekKey string
-	QwenKey     string
+	DeepSeekKey   string
+	DeepSeekModel string // deepseek-chat（默认）或 deepseek-reasoner
+	QwenKey       string
 
 	// 自定义AI API配置
 	CustomAPIURL    string
@@ -62,8 +80,9 @@ type AutoTraderConfig struct {
 	InitialBalance float64 // 初始金额（用于计算盈亏，需手动设置）
 
 	// 杠杆配置
-	BTCETHLeverage  int // BTC和ETH的杠杆倍数
-	AltcoinLeverage int // 山寨币的杠杆倍数
+	BTCETHLeverage  int                   // BTC和ETH的杠杆倍数
+	AltcoinLeverage int                   // 山寨币的杠杆倍数
+	SymbolLimits    decision.SymbolLimits // 币种单独的杠杆上限和仓位范围（覆盖上面的全局限制）
 
 	// 仓位大小配置
 	MinPositionSizeUSD       float64 // 最小仓位大小（USD，0表示不限制）
@@ -77,9 +96,77 @@ type AutoTraderConfig struct {
 	MaxDailyLoss    float64       // 最大日亏损百分比（提示）
 	MaxDrawdown     float64       // 最大回撤百分比（提示）
 	StopTradingTime time.Duration // 触发风控后暂停时长
+	DeriskLadder    DeriskConfig  // 日内亏损降风险阶梯
 	
 	// Prompt template configuration (optional)
 	SystemPromptTemplate string // 系统提示词模板名称 (如 "default", "adaptive", "nof1")
+
+	// 行情数据写入prompt的格式：verbose（默认，逐项描述）或 compact（紧凑表格，token少得多）
+	MarketDataFormat string
+
+	// 执行策略：默认下单类型（market/ioc/fok/post_only，空表示交易所默认），AI决策中的order_type优先
+	OrderType string
+
+	// 开仓成交价与下单时市价不同时，止损止盈随成交价平移（保持止损/止盈距离）
+	AnchorStopsToFill bool
+
+	// 币种黑白名单：黑名单永不开仓，白名单非空时只交易白名单币种（运行中可通过API更新）
+	SymbolBlacklist []string
+	SymbolWhitelist []string
+
+	// AI漏填或给出无效止损止盈时，按ATR和目标风险回报比自动补全（默认关闭）
+	AutoStopLoss decision.AutoStopConfig
+
+	// 主模型采样参数（temperature/top_p/max_tokens/presence_penalty/reasoning_effort，未设置的使用默认值）
+	ModelParams mcp.ModelParams
+
+	// 二次复核：开仓/加仓决策执行前由复核模型检查（URL为空时复用主模型）
+	ReviewEnabled   bool
+	ReviewAPIURL    string
+	ReviewAPIKey    string
+	ReviewModelName string
+
+	// 多模型集成：主模型加上这些模型，按策略合并决策（为空表示只用主模型）
+	EnsembleModels []EnsembleModelConfig
+	EnsemblePolicy string // unanimous / majority / highest_confidence（空表示 majority）
+
+	// 出口代理（http/https/socks5，用于绑定IP白名单的API密钥），空表示直连
+	ProxyURL string
+
+	// 计价币种（USDT/USDC，空表示USDT）：候选币种换成该计价币种的交易对
+	QuoteAsset string
+
+	// 报告币种（USDT/USDC/USD/BTC等，空表示与交易所结算币种相同）：余额、盈亏和仓位限制按该币种表示
+	ReportingCurrency string
+
+	// 时区（IANA名称，如 Asia/Shanghai），日重置和日报按该时区的自然日划分，空表示服务器本地时区
+	Timezone string
+
+	// 分币种历史表现写入AI提示的统计天数（0表示默认14天，负数表示不写入）
+	SymbolEdgeDays int
+
+	// 策略配置名称（空表示未使用），以及写入prompt的额外指标（nil表示全部已启用的指标）
+	Profile    string
+	Indicators []string
+
+	// A/B测试：在多个策略配置之间按计划轮换（nil表示未启用）
+	Experiment *ExperimentConfig
+
+	// 人工审批模式：开仓/加仓作为交易想法等待人工批准，超过有效期视为wait（0表示默认15分钟）
+	ApprovalMode bool
+	ApprovalTTL  time.Duration
+
+	// 交易策略："ai"（默认）、"carry"（资金费套利）或 "grid"（网格），后两者不调用AI
+	Strategy string
+	Carry    CarryConfig
+	Grid     []GridSymbol
+}
+
+// EnsembleModelConfig 集成中的额外模型（OpenAI格式API）
+type EnsembleModelConfig struct {
+	APIURL    string
+	APIKey    string
+	ModelName string
 }
 
 // AutoTrader 自动交易器
@@ -91,15 +178,71 @@ type AutoTrader struct {
 	config                AutoTraderConfig
 	trader                Trader // 使用Trader接口（支持多平台）
 	mcpClient             *mcp.Client
+	reviewClient          *mcp.Client            // 二次复核模型（未启用时为nil）
+	ensemble              []decision.EnsembleMember // 集成模式的全部模型（未启用时为nil）
 	decisionLogger        *logger.DecisionLogger // 决策日志记录器
 	initialBalance        float64
+	settlement            string // 交易所结算币种（余额按 settlement→报告币种 换算）
 	dailyPnL              float64
 	lastResetTime         time.Time
+	location              *time.Location // trader时区（日重置和日报的零点）
 	stopUntil             time.Time
+	stopReason            string // 风控暂停的原因
+	riskStatePath         string // 风控状态文件（重启后恢复暂停和降风险等级）
+	backoffUntil          time.Time // 被交易所限频后暂停到此时间
 	isRunning             bool
 	startTime             time.Time        // 系统启动时间
 	callCount             int              // AI调用次数
 	positionFirstSeenTime map[string]int64 // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	positionStops         map[string]*protectionPrices // 持仓当前止损止盈价 (symbol_side -> 价格)
+	positionIDs           *positionRegistry            // 当前持仓的ID（持久化，部分平仓和重启后不变）
+	plans                 *planMemory                  // AI对各持仓陈述的计划（持久化，下个周期回放）
+	symbolFilter          *pool.SymbolFilter           // 币种黑白名单
+	funding               *fundingTracker              // 持仓资金费累计
+	delistingFilter       *pool.SymbolFilter           // 即将下架的币种（由ListingWatcher更新）
+	delistings            delistingState               // 下架计划（用于下架前平仓）
+	derisk                deriskState                  // 当日降风险等级
+	tradeCharts           *tradeChartConfig            // 开仓通知附带K线图（未启用时为nil）
+	operations            *operationJournal            // 进行中的开仓/加仓操作（崩溃后恢复）
+	approval              *approvalQueue               // 人工审批的交易想法（未启用时为nil）
+	staleGuard            *staleGuard                  // 过期行情保护（未启用时为nil）
+	cycleMu               sync.Mutex                   // 交易周期与人工批准的执行互斥
+	panics                panicTracker                 // 周期panic计数
+	stateMu               sync.Mutex                   // 执行决策时共享状态（止损止盈记录、开仓时间）的互斥，并行执行时需要
+	execConcurrency       int                          // 多币种并行执行的并发数（<=1 为串行）
+	setups                *similarSetups               // 相似历史情形检索（未启用时为nil）
+	carry                 *carryStrategy               // 资金费套利策略（其他策略时为nil）
+	grid                  *gridStrategy                // 网格策略（其他策略时为nil）
+	stream                *realtimeStream              // 交易所私有推送（未启用时为nil）
+	equityGuard           *equityGuard                 // 外部资金流动检测（未启用时为nil）
+	flashGuard            *flashGuard                  // 急跌熔断（未启用时为nil）
+	triggerCheck          *triggerCheck                // 挂条件单前的触发价检查（未启用时为nil）
+	lastReconcile         *ReconcileReport             // 最近一次对账结果（还没有对账时为nil）
+	slicing               *OrderSlicingConfig          // 大单拆分执行（未启用时为nil）
+	partialFill           *PartialFillConfig           // 部分成交后的补单策略（未启用时为nil，只按实际成交数量处理）
+	throttle              *DecisionThrottleConfig      // 决策限流（未启用时为nil）
+	exposure              *ExposureBook                // 跨trader的合并敞口账本（未启用时为nil）
+	exposureAccount       string                       // 在敞口账本中的账户标识
+	signals               *signalBook                  // 外部策略信号提示（未启用时为nil）
+	surges                surgeBook                    // 成交量/持仓量异动（异动扫描推送）
+	lastExecution         *decision.ExecutionFeedback  // 上个AI周期决策的执行结果（写入下个周期的prompt）
+	illiquidSizeFactor    float64                      // 非流动时段开仓/加仓的仓位系数（0表示不缩减）
+	rangeMinConfidence    int                          // 震荡币种上顺势开仓的最低信心度（0表示不限制）
+	overtrading           *overtradingState            // 过度交易检测（未启用时为nil）
+	intervalChanged       chan time.Duration           // 切换策略配置后的扫描间隔（交易循环据此重置定时器）
+	externalFlows         float64                      // 累计检测到的外部资金流动（已计入初始余额）
+	lastCycleAt           time.Time                    // 上个周期结束时间
+	accountPermissions    *AccountPermissions          // 启动自检查到的密钥权限和账户设置（未自检时为nil）
+	experiment            *experimentState             // 策略A/B测试（未启用时为nil）
+	heartbeat             atomic.Int64                 // 交易循环最近一次心跳（UnixNano，watchdog检测卡死用）
+	shuttingDown          atomic.Bool                  // 正在按退出策略处理持仓，不再开始新的周期
+	retired               atomic.Bool                  // 已被watchdog替换的旧实例，不再执行决策和下单
+}
+
+// protectionPrices 持仓的止损止盈价（调整止损/部分平仓/加仓后用于重新挂保护单）
+type protectionPrices struct {
+	StopLoss   float64
+	TakeProfit float64
 }
 
 // NewAutoTrader 创建自动交易器
@@ -133,7 +276,41 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 	} else {
 		// 默认使用DeepSeek
 		mcpClient.SetDeepSeekAPIKey(config.DeepSeekKey)
-		log.Printf("🤖 [%s] 使用DeepSeek AI", config.Name)
+		if config.DeepSeekModel != "" {
+			mcpClient.SetModel(config.DeepSeekModel)
+		}
+		log.Printf("🤖 [%s] 使用DeepSeek AI (模型: %s)", config.Name, mcpClient.Model)
+	}
+	if err := mcpClient.SetParams(config.ModelParams); err != nil {
+		return nil, fmt.Errorf("模型参数无效: %w", err)
+	}
+
+	// 初始化集成模型（第一个为主模型）
+	var ensemble []decision.EnsembleMember
+	if len(config.EnsembleModels) > 0 {
+		ensemble = append(ensemble, decision.EnsembleMember{Name: mcpClient.Model, Client: mcpClient})
+		for _, m := range config.EnsembleModels {
+			client := mcp.New()
+			client.SetCustomAPI(m.APIURL, m.APIKey, m.ModelName)
+			ensemble = append(ensemble, decision.EnsembleMember{Name: m.ModelName, Client: client})
+		}
+		if config.EnsemblePolicy == "" {
+			config.EnsemblePolicy = decision.EnsembleMajority
+		}
+		log.Printf("🗳 [%s] 启用多模型集成: %d 个模型, 策略 %s", config.Name, len(ensemble), config.EnsemblePolicy)
+	}
+
+	// 初始化二次复核模型
+	var reviewClient *mcp.Client
+	if config.ReviewEnabled {
+		reviewClient = mcpClient
+		if config.ReviewAPIURL != "" {
+			reviewClient = mcp.New()
+			reviewClient.SetCustomAPI(config.ReviewAPIURL, config.ReviewAPIKey, config.ReviewModelName)
+			log.Printf("🔍 [%s] 启用二次复核: %s (模型: %s)", config.Name, config.ReviewAPIURL, config.ReviewModelName)
+		} else {
+			log.Printf("🔍 [%s] 启用二次复核（复用主模型）", config.Name)
+		}
 	}
 
 	// 初始化币种池API
@@ -147,10 +324,189 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 	}
 
 	// 根据配置创建对应的交易器
+	trader, err := newExchangeTrader(config, config.Exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.SymbolEdgeDays == 0 {
+		config.SymbolEdgeDays = defaultSymbolEdgeDays
+	}
+
+	// 时区（默认服务器本地时区）
+	location, err := loadTraderLocation(config.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	if location != time.Local {
+		log.Printf("🕒 [%s] 时区: %s（日重置和日报按该时区零点）", config.Name, location)
+	}
+
+	// 计价币种（默认USDT）
+	quote, err := resolveQuoteAsset(config.Exchange, config.QuoteAsset)
+	if err != nil {
+		return nil, err
+	}
+	config.QuoteAsset = quote
+	if configurable, ok := trader.(QuoteConfigurable); ok {
+		configurable.SetQuoteAsset(quote)
+	}
+	if quote != market.DefaultQuote {
+		log.Printf("💵 [%s] 计价币种: %s（候选币种使用 %s 交易对）", config.Name, quote, quote)
+	}
+
+	// 报告币种（默认与结算币种相同，不换算）
+	settlement := settlementCurrency(trader)
+	if config.ReportingCurrency == "" {
+		config.ReportingCurrency = settlement
+	}
+	config.ReportingCurrency = market.NormalizeCurrency(config.ReportingCurrency)
+	if config.ReportingCurrency != settlement {
+		log.Printf("💱 [%s] 报告币种: %s（交易所以 %s 结算，余额和盈亏按行情汇率换算）", config.Name, config.ReportingCurrency, settlement)
+	}
+
+	// 出口代理（交易所API密钥绑定IP白名单时使用）
+	if config.ProxyURL != "" {
+		if err := applyProxy(trader, config.Exchange, config.ProxyURL); err != nil {
+			return nil, fmt.Errorf("代理配置错误: %w", err)
+		}
+		log.Printf("🌐 [%s] 交易所请求经代理发出: %s", config.Name, redactedProxyURL(config.ProxyURL))
+	}
+
+	// 资金费套利：对冲腿在另一家交易所，两条腿组合为一个交易器
+	var carry *carryStrategy
+	var grid *gridStrategy
+	switch config.Strategy {
+	case "", StrategyAI:
+	case StrategyCarry:
+		carry, err = newCarryStrategy(config, trader)
+		if err != nil {
+			return nil, fmt.Errorf("资金费套利配置错误: %w", err)
+		}
+		trader = &carryPair{primary: carry.primary, hedge: carry.hedge, primaryName: carry.primaryName, hedgeName: carry.hedgeName}
+		log.Printf("💱 [%s] 资金费套利策略: %s / %s，币种 %v，费率差 ≥%.4f%% 开仓、<%.4f%% 平仓，每腿 %.0f USDT",
+			config.Name, carry.primaryName, carry.hedgeName, carry.config.Symbols, carry.config.EntrySpreadPct, carry.config.ExitSpreadPct, carry.config.PositionSizeUSD)
+	case StrategyGrid:
+		grid, err = newGridStrategy(config.Grid)
+		if err != nil {
+			return nil, fmt.Errorf("网格配置错误: %w", err)
+		}
+		for _, g := range grid.symbols {
+			log.Printf("📐 [%s] 网格策略: %s %.4f-%.4f 共 %d 格，每格 %.0f USDT，%dx", config.Name, g.Symbol, g.Lower, g.Upper, g.Levels, g.SizePerLevelUSD, g.Leverage)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的交易策略: %s（可选 ai / carry / grid）", config.Strategy)
+	}
+
+	// 校验执行策略的下单类型是否被交易所支持
+	orderType, err := ParseOrderType(config.OrderType)
+	if err != nil {
+		return nil, fmt.Errorf("执行策略配置错误: %w", err)
+	}
+	if orderType != "" && !SupportsOrderType(trader, orderType) {
+		return nil, fmt.Errorf("%s 不支持下单类型 %s（支持: %v）", config.Exchange, orderType, trader.SupportedOrderTypes())
+	}
+	log.Printf("🧾 [%s] 支持的下单类型: %v，默认: %s", config.Name, trader.SupportedOrderTypes(), orderTypeOrDefault(orderType, trader))
+
+	marketFormat, err := market.ParseFormatMode(config.MarketDataFormat)
+	if err != nil {
+		return nil, fmt.Errorf("行情数据格式配置错误: %w", err)
+	}
+	config.MarketDataFormat = string(marketFormat)
+
+	// 验证初始金额配置
+	if config.InitialBalance <= 0 {
+		return nil, fmt.Errorf("初始金额必须大于0，请在配置中设置InitialBalance")
+	}
+
+	// 初始化决策日志记录器（使用trader ID创建独立目录）
+	logDir := fmt.Sprintf("decision_logs/%s", config.ID)
+	decisionLogger := logger.NewDecisionLogger(logDir)
+
+	// 上次进程未完成的开仓/加仓操作（第一个周期开始前恢复）
+	operations, err := loadOperationJournal(filepath.Join(logDir, "operations.json"))
+	if err != nil {
+		return nil, fmt.Errorf("读取操作日志失败: %w", err)
+	}
+	if pending := operations.pending(); len(pending) > 0 {
+		log.Printf("🔁 [%s] 发现 %d 个未完成的操作，将在第一个周期开始前恢复", config.Name, len(pending))
+	}
+
+	positionIDs, err := loadPositionRegistry(filepath.Join(logDir, "positions.json"))
+	if err != nil {
+		return nil, fmt.Errorf("读取持仓ID失败: %w", err)
+	}
+	plans, err := loadPlanMemory(filepath.Join(logDir, "plans.json"))
+	if err != nil {
+		return nil, fmt.Errorf("读取持仓计划失败: %w", err)
+	}
+
+	// 资金费：交易所支持流水查询时使用实际记录，否则按费率估算
+	fundingProvider, _ := trader.(FundingHistoryProvider)
+
+	var approval *approvalQueue
+	if config.ApprovalMode {
+		approval = newApprovalQueue(config.ApprovalTTL)
+		log.Printf("👤 [%s] 启用人工审批模式: 开仓/加仓需在 %v 内批准", config.Name, approval.ttl)
+	}
+
+	at := &AutoTrader{
+		id:                    config.ID,
+		name:                  config.Name,
+		aiModel:               config.AIModel,
+		exchange:              config.Exchange,
+		config:                config,
+		trader:                trader,
+		mcpClient:             mcpClient,
+		reviewClient:          reviewClient,
+		ensemble:              ensemble,
+		decisionLogger:        decisionLogger,
+		initialBalance:        config.InitialBalance,
+		settlement:            settlement,
+		lastResetTime:         time.Now(),
+		startTime:             time.Now(),
+		location:              location,
+		callCount:             0,
+		isRunning:             false,
+		positionFirstSeenTime: make(map[string]int64),
+		positionStops:         make(map[string]*protectionPrices),
+		positionIDs:           positionIDs,
+		plans:                 plans,
+		symbolFilter:          pool.NewSymbolFilter(config.SymbolBlacklist, config.SymbolWhitelist),
+		funding:               newFundingTracker(fundingProvider, fundingIntervalFor(config.Exchange)),
+		delistingFilter:       pool.NewSymbolFilter(nil, nil),
+		operations:            operations,
+		approval:              approval,
+		riskStatePath:         filepath.Join(logDir, "risk_state.json"),
+		carry:                 carry,
+		grid:                  grid,
+		intervalChanged:       make(chan time.Duration, 1),
+	}
+
+	// 上次进程的风控暂停和当日降风险状态
+	riskState, err := loadRiskState(at.riskStatePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取风控状态失败: %w", err)
+	}
+	if riskState != nil {
+		at.restoreRiskState(riskState)
+	}
+
+	if config.Experiment != nil {
+		if err := at.EnableExperiment(*config.Experiment); err != nil {
+			return nil, err
+		}
+	}
+
+	return at, nil
+}
+
+// newExchangeTrader 按交易所创建交易器（使用配置中该交易所的密钥）
+func newExchangeTrader(config AutoTraderConfig, exchange string) (Trader, error) {
 	var trader Trader
 	var err error
 
-    switch config.Exchange {
+    switch exchange {
 	case "binance":
 		if config.BinanceTestnet {
 			log.Printf("🏦 [%s] 使用币安合约交易 (测试网)", config.Name)
@@ -181,39 +537,15 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
             return nil, fmt.Errorf("初始化Gate.io交易器失败: %w", err)
         }
 	default:
-		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
-	}
-
-	// 验证初始金额配置
-	if config.InitialBalance <= 0 {
-		return nil, fmt.Errorf("初始金额必须大于0，请在配置中设置InitialBalance")
+		return nil, fmt.Errorf("不支持的交易平台: %s", exchange)
 	}
-
-	// 初始化决策日志记录器（使用trader ID创建独立目录）
-	logDir := fmt.Sprintf("decision_logs/%s", config.ID)
-	decisionLogger := logger.NewDecisionLogger(logDir)
-
-	return &AutoTrader{
-		id:                    config.ID,
-		name:                  config.Name,
-		aiModel:               config.AIModel,
-		exchange:              config.Exchange,
-		config:                config,
-		trader:                trader,
-		mcpClient:             mcpClient,
-		decisionLogger:        decisionLogger,
-		initialBalance:        config.InitialBalance,
-		lastResetTime:         time.Now(),
-		startTime:             time.Now(),
-		callCount:             0,
-		isRunning:             false,
-		positionFirstSeenTime: make(map[string]int64),
-	}, nil
+	return trader, nil
 }
 
 // Run 运行自动交易主循环
 func (at *AutoTrader) Run() error {
 	at.isRunning = true
+	at.beat()
 	log.Println("🚀 AI驱动自动交易系统启动")
 	log.Printf("💰 初始余额: %.2f USDT", at.initialBalance)
 	log.Printf("⚙️  扫描间隔: %v", at.config.ScanInterval)
@@ -222,17 +554,44 @@ func (at *AutoTrader) Run() error {
 	ticker := time.NewTicker(at.config.ScanInterval)
 	defer ticker.Stop()
 
+	// 交易所侧平仓（止损止盈、强平）的实时推送会提前触发周期
+	stopStream := at.startStream()
+	defer stopStream()
+	var wake chan PositionClosedEvent
+	if at.stream != nil {
+		wake = at.stream.wake
+	}
+
 	// 首次立即执行
-	if err := at.runCycle(); err != nil {
+	if err := at.runCycleSafely(); err != nil {
 		log.Printf("❌ 执行失败: %v", err)
+		at.handleTradingError(err)
 	}
 
 	for at.isRunning {
+		// 每轮循环（含跳过的周期）都更新心跳，周期卡住时心跳停止
+		at.beat()
 		select {
 		case <-ticker.C:
-			if err := at.runCycle(); err != nil {
+			if time.Now().Before(at.backoffUntil) {
+				log.Printf("⏳ 限频退避中，跳过本周期（%s 后恢复）", at.backoffUntil.Format("15:04:05"))
+				continue
+			}
+			if err := at.runCycleSafely(); err != nil {
 				log.Printf("❌ 执行失败: %v", err)
+				at.handleTradingError(err)
+			}
+		case event := <-wake:
+			if !at.streamCycleDue(event) {
+				continue
 			}
+			if err := at.runCycleSafely(); err != nil {
+				log.Printf("❌ 执行失败: %v", err)
+				at.handleTradingError(err)
+			}
+		case interval := <-at.intervalChanged:
+			ticker.Reset(interval)
+			log.Printf("⚙️  扫描间隔调整为: %v", interval)
 		}
 	}
 
@@ -245,39 +604,124 @@ func (at *AutoTrader) Stop() {
 	log.Println("⏹ 自动交易系统停止")
 }
 
+// beat 更新交易循环心跳
+func (at *AutoTrader) beat() {
+	at.heartbeat.Store(time.Now().UnixNano())
+}
+
+// LastHeartbeat 交易循环最近一次心跳时间（尚未启动时为零值）
+func (at *AutoTrader) LastHeartbeat() time.Time {
+	nanos := at.heartbeat.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// IsRunning 交易循环是否在运行（Stop 或认证失败后为false）
+func (at *AutoTrader) IsRunning() bool {
+	return at.isRunning
+}
+
+// rateLimitBackoff 限频且交易所未给出 Retry-After 时的默认暂停时长
+const rateLimitBackoff = time.Minute
+
+// handleTradingError 按错误类型做出反应，返回 true 表示本周期不应继续下单
+func (at *AutoTrader) handleTradingError(err error) bool {
+	switch {
+	case errors.Is(err, errs.ErrAuth):
+		// API密钥失效或权限不足，继续运行只会反复失败
+		at.Stop()
+		notify.Send(notify.Event{
+			Type:     "trader.auth_failed",
+			Severity: notify.SeverityCritical,
+			TraderID: at.id,
+			Title:    fmt.Sprintf("%s API认证失败，已停止交易", at.name),
+			Message:  fmt.Sprintf("%s 认证失败，请检查API密钥和权限后重启trader: %v", at.exchange, err),
+		})
+		return true
+
+	case errors.Is(err, errs.ErrRateLimited):
+		wait := errs.RetryAfter(err)
+		if wait <= 0 {
+			wait = rateLimitBackoff
+		}
+		at.backoffUntil = time.Now().Add(wait)
+		notify.Send(notify.Event{
+			Type:     "trader.rate_limited",
+			Severity: notify.SeverityWarning,
+			TraderID: at.id,
+			Title:    fmt.Sprintf("%s 请求被限频", at.name),
+			Message:  fmt.Sprintf("%s 返回限频错误，暂停 %v 后再继续: %v", at.exchange, wait, err),
+		})
+		return true
+	}
+	return false
+}
+
 // runCycle 运行一个交易周期（使用AI全权决策）
-func (at *AutoTrader) runCycle() error {
+func (at *AutoTrader) runCycle() (err error) {
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+	if at.shuttingDown.Load() || at.retired.Load() {
+		return nil
+	}
+	defer func() { at.lastCycleAt = time.Now() }()
 	at.callCount++
 
-	log.Printf("\n" + strings.Repeat("=", 70))
+	// 每个周期是一条trace的根span
+	traceCtx, span := tracing.Start(context.Background(), "trading.cycle")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	span.SetAttr("trader.id", at.id)
+	span.SetAttr("exchange", at.exchange)
+	span.SetAttr("cycle", at.callCount)
+
+	log.Print("\n" + strings.Repeat("=", 70))
 	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
-	log.Printf(strings.Repeat("=", 70))
+	log.Print(strings.Repeat("=", 70))
+	at.publishEvent(events.CycleStarted, "", CycleStartedData{Cycle: at.callCount})
+
+	// A/B测试：按计划切换到当前区组的变体
+	at.rotateExperiment(time.Now())
 
 	// 创建决策记录
 	record := &logger.DecisionRecord{
 		ExecutionLog: []string{},
 		Success:      true,
+		TraceID:      span.TraceID(),
 	}
+	record.Experiment, record.Variant = at.experimentTag()
+
+	// 上次未完成的开仓/加仓：完成挂保护单或回滚（暂停交易期间也要处理）
+	at.resumeOperations(record)
+	at.expireIdeas(record)
 
 	// 1. 检查是否需要停止交易
 	if time.Now().Before(at.stopUntil) {
 		remaining := at.stopUntil.Sub(time.Now())
-		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
+		log.Printf("⏸ 风险控制：暂停交易中（%s），剩余 %.0f 分钟", at.stopReason, remaining.Minutes())
 		record.Success = false
-		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
+		record.ErrorMessage = fmt.Sprintf("风险控制暂停中（%s），剩余 %.0f 分钟", at.stopReason, remaining.Minutes())
 		at.decisionLogger.LogDecision(record)
 		return nil
 	}
 
-	// 2. 重置日盈亏（每天重置）
-	if time.Since(at.lastResetTime) > 24*time.Hour {
+	// 2. 重置日盈亏（trader时区跨过零点时重置）
+	if at.dayChanged(time.Now()) {
 		at.dailyPnL = 0
 		at.lastResetTime = time.Now()
+		at.resetDerisk()
 		log.Println("📅 日盈亏已重置")
 	}
 
+	// 即将下架的币种：截止前主动平仓
+	at.closeDelistingPositions(record)
+
 	// 3. 收集交易上下文
-	ctx, err := at.buildTradingContext()
+	ctx, err := at.buildTradingContext(traceCtx)
 	if err != nil {
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("构建交易上下文失败: %v", err)
@@ -285,6 +729,11 @@ func (at *AutoTrader) runCycle() error {
 		return fmt.Errorf("构建交易上下文失败: %w", err)
 	}
 
+	// 外部资金流动（充值/提现）计入业绩基准，不当作交易盈亏
+	at.reconcileEquity(ctx, record)
+	// 刷新跨trader合并敞口中本账户的持仓
+	at.reportExposure(ctx.Positions)
+
 	// 保存账户状态快照
 	record.AccountState = logger.AccountSnapshot{
 		TotalBalance:          ctx.Account.TotalEquity,
@@ -294,9 +743,22 @@ func (at *AutoTrader) runCycle() error {
 		MarginUsedPct:         ctx.Account.MarginUsedPct,
 	}
 
+	// 日内亏损降风险阶梯（达到清仓线时平掉全部持仓并暂停）
+	if at.applyDeriskLadder(ctx, record) {
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
+	at.applyRiskNotice(ctx)
+	// 净值/BTC急跌熔断：只允许平仓，企稳后自动恢复
+	at.applyFlashGuard(ctx, record)
+
+	// 更新持仓累计资金费（记入决策日志，并随持仓信息提供给AI）
+	record.FundingPayments = at.syncFunding(ctx.Positions)
+
 	// 保存持仓快照
 	for _, pos := range ctx.Positions {
 		record.Positions = append(record.Positions, logger.PositionSnapshot{
+			PositionID:       pos.PositionID,
 			Symbol:           pos.Symbol,
 			Side:             pos.Side,
 			PositionAmt:      pos.Quantity,
@@ -305,6 +767,8 @@ func (at *AutoTrader) runCycle() error {
 			UnrealizedProfit: pos.UnrealizedPnL,
 			Leverage:         float64(pos.Leverage),
 			LiquidationPrice: pos.LiquidationPrice,
+
+			CumulativeFunding: pos.CumulativeFunding,
 		})
 	}
 
@@ -316,14 +780,35 @@ func (at *AutoTrader) runCycle() error {
 	log.Printf("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
 		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.PositionCount)
 
+	// 资金费套利策略：按费率差开平对冲组合，不调用AI
+	if at.carry != nil {
+		at.runCarry(ctx, record)
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
+
 	// 4. 调用AI获取完整决策
 	log.Println("🤖 正在请求AI分析并决策...")
-	decision, err := decision.GetFullDecision(ctx, at.mcpClient)
+	ctx.Trace = traceCtx
+	decision, err := at.getDecision(ctx)
+	record.MarketData = ctx.MarketDataMap // 启用行情快照时随决策记录保存
 
 	// 即使有错误，也保存思维链、决策和输入prompt（用于debug）
+	recordDecisionTiming(ctx, decision, record)
 	if decision != nil {
 		record.InputPrompt = decision.UserPrompt
 		record.CoTTrace = decision.CoTTrace
+		record.ReasoningTrace = decision.Reasoning
+		record.ReasoningTokens = decision.ReasoningTokens
+		record.SchemaVersion = decision.SchemaVersion
+		record.ParseDiagnostics = decision.ParseDiagnostics.String()
+		record.Ensemble = ensembleRecords(decision.Ensemble)
+		for _, note := range decision.AutoStops {
+			record.ExecutionLog = append(record.ExecutionLog, "🛡 自动补全止损止盈 "+note)
+		}
+		for _, note := range decision.PriceRounding {
+			record.ExecutionLog = append(record.ExecutionLog, "📏 止损止盈按价格精度取整 "+note)
+		}
 		if len(decision.Decisions) > 0 {
 			decisionJSON, _ := json.MarshalIndent(decision.Decisions, "", "  ")
 			record.DecisionJSON = string(decisionJSON)
@@ -336,11 +821,11 @@ func (at *AutoTrader) runCycle() error {
 
 		// 打印AI思维链（即使有错误）
 		if decision != nil && decision.CoTTrace != "" {
-			log.Printf("\n" + strings.Repeat("-", 70))
+			log.Print("\n" + strings.Repeat("-", 70))
 			log.Println("💭 AI思维链分析（错误情况）:")
 			log.Println(strings.Repeat("-", 70))
 			log.Println(decision.CoTTrace)
-			log.Printf(strings.Repeat("-", 70) + "\n")
+			log.Print(strings.Repeat("-", 70) + "\n")
 		}
 
 		at.decisionLogger.LogDecision(record)
@@ -348,100 +833,121 @@ func (at *AutoTrader) runCycle() error {
 	}
 
 	// 5. 打印AI思维链
-	log.Printf("\n" + strings.Repeat("-", 70))
+	log.Print("\n" + strings.Repeat("-", 70))
 	log.Println("💭 AI思维链分析:")
 	log.Println(strings.Repeat("-", 70))
 	log.Println(decision.CoTTrace)
-	log.Printf(strings.Repeat("-", 70) + "\n")
+	log.Print(strings.Repeat("-", 70) + "\n")
+
+	// 二次复核：否决/降级开仓和加仓决策
+	if at.reviewClient != nil {
+		at.reviewDecisions(ctx, decision, record)
+	}
 
 	// 6. 打印AI决策
 	log.Printf("📋 AI决策列表 (%d 个):\n", len(decision.Decisions))
 	for i, d := range decision.Decisions {
 		log.Printf("  [%d] %s: %s - %s", i+1, d.Symbol, d.Action, d.Reasoning)
-		if d.Action == "open_long" || d.Action == "open_short" {
+		switch d.Action {
+		case "open_long", "open_short":
 			log.Printf("      杠杆: %dx | 仓位: %.2f USDT | 止损: %.4f | 止盈: %.4f",
 				d.Leverage, d.PositionSizeUSD, d.StopLoss, d.TakeProfit)
+		case "add_to_position":
+			log.Printf("      加仓: %.2f USDT | 止损: %.4f | 止盈: %.4f", d.PositionSizeUSD, d.StopLoss, d.TakeProfit)
+		case "partial_close":
+			log.Printf("      平仓比例: %.1f%%", d.ClosePercent)
+		case "adjust_sl", "adjust_tp":
+			log.Printf("      新止损: %.4f | 新止盈: %.4f", d.StopLoss, d.TakeProfit)
 		}
 	}
 	log.Println()
 
 	// 7. 对决策排序：确保先平仓后开仓（防止仓位叠加超限）
 	sortedDecisions := sortDecisionsByPriority(decision.Decisions)
+	if ctx.CloseOnly {
+		sortedDecisions = filterCloseOnly(sortedDecisions, at.closeOnlyReason(), record)
+	}
 
-	log.Println("🔄 执行顺序（已优化）: 先平仓→后开仓")
+	// 决策限流：每周期新开仓数和每币种动作数的硬性上限
+	if at.throttle != nil {
+		sortedDecisions = throttleDecisions(sortedDecisions, *at.throttle, record)
+	}
+
+	// 震荡区间的币种：信心度不足的顺势开仓不执行
+	sortedDecisions = at.filterRangingEntries(ctx, sortedDecisions, record)
+
+	// 币种历史上的非流动时段：开仓/加仓按系数缩小仓位
+	sortedDecisions = at.reduceIlliquidEntries(ctx, sortedDecisions, record)
+
+	// 成交稀薄的币种：开仓/加仓不超过按成交额和盘口深度计算的仓位上限
+	sortedDecisions = at.clampDepthEntries(ctx, sortedDecisions, record)
+
+	// 决策延迟超预算或价格偏离快照时，按最新价复核开仓/加仓
+	sortedDecisions = at.guardStaleDecisions(ctx, sortedDecisions, record)
+
+	// 推演本周期所有决策的保证金占用，超出上限时按信心度缩小或放弃开仓/加仓
+	sortedDecisions = at.forecastMargin(ctx, sortedDecisions, record)
+
+	// 人工审批模式：开仓/加仓进入审批队列，其余动作照常执行
+	var ideas []*TradeIdea
+	if at.approval != nil {
+		sortedDecisions, ideas = at.queueForApproval(ctx, decision, sortedDecisions, record)
+	}
+
+	log.Println("🔄 执行顺序（已优化）: 先平仓→撤单→调整保护单→后开仓")
+	if at.execConcurrency > 1 {
+		log.Printf("  ⚡ 同一阶段内不同币种并行执行（最多 %d 个）", at.execConcurrency)
+	}
 	for i, d := range sortedDecisions {
 		log.Printf("  [%d] %s %s", i+1, d.Symbol, d.Action)
 	}
 	log.Println()
 
 	// 执行决策并记录结果
-	for _, d := range sortedDecisions {
-		actionRecord := logger.DecisionAction{
-			Action:    d.Action,
-			Symbol:    d.Symbol,
-			Quantity:  0,
-			Leverage:  d.Leverage,
-			Price:     0,
-			Timestamp: time.Now(),
-			Success:   false,
-		}
-
-		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
-			log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
-			actionRecord.Error = err.Error()
-			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
-		} else {
-			actionRecord.Success = true
-			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
-			// 成功执行后短暂延迟
-			time.Sleep(1 * time.Second)
-		}
-
-		record.Decisions = append(record.Decisions, actionRecord)
-	}
+	at.publishDecisions(sortedDecisions)
+	at.executeDecisions(traceCtx, ctx, sortedDecisions, record)
+	// 下个周期把执行结果反馈给AI
+	at.lastExecution = collectExecutionFeedback(at.callCount, decision.Decisions, record)
 
 	// 8. 保存决策记录
 	if err := at.decisionLogger.LogDecision(record); err != nil {
 		log.Printf("⚠ 保存决策记录失败: %v", err)
 	}
+	at.indexOpenedSetups(ctx, decision.Decisions, record)
+
+	// 开仓通知和待审批通知（附带K线图，需要决策ID，所以在保存记录之后）
+	at.notifyOpenedTrades(record)
+	at.notifyIdeas(record, ideas)
 
 	return nil
 }
 
-// buildTradingContext 构建交易上下文
-func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
-	// 1. 获取账户信息
-	balance, err := at.trader.GetBalance()
-	if err != nil {
-		return nil, fmt.Errorf("获取账户余额失败: %w", err)
-	}
-
-	// 获取账户字段
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
-
-	if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
+// positionMargin 持仓占用保证金：优先用交易所返回的margin（Gate.io），否则按 数量×标记价/杠杆 估算
+func positionMargin(pos map[string]interface{}, quantity, markPrice float64, leverage int) float64 {
+	if margin, ok := pos["margin"].(float64); ok && margin > 0 {
+		return margin
 	}
-	if unrealized, ok := balance["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
-	}
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
-	}
-
-	// Total Equity = 钱包余额 + 未实现盈亏
-	totalEquity := totalWalletBalance + totalUnrealizedProfit
+	return (quantity * markPrice) / float64(leverage)
+}
 
-	// 2. 获取持仓信息
-	positions, err := at.trader.GetPositions()
+// buildTradingContext 构建交易上下文
+func (at *AutoTrader) buildTradingContext(traceCtx context.Context) (*decision.Context, error) {
+	// 1. 获取账户快照（余额和持仓同时获取，派生字段按同一份数据计算）
+	_, snapshotSpan := tracing.Start(traceCtx, "trader.AccountSnapshot")
+	snap, err := at.accountSnapshot()
+	snapshotSpan.RecordError(err)
+	snapshotSpan.End()
 	if err != nil {
-		return nil, fmt.Errorf("获取持仓失败: %w", err)
+		return nil, err
 	}
+	totalEquity := snap.TotalEquity
+	availableBalance := snap.AvailableBalance
+	totalMarginUsed := snap.MarginUsed
+	marginUsedPct := snap.MarginUsedPct
 
+	// 2. 持仓信息
+	positions := snap.Positions
 	var positionInfos []decision.PositionInfo
-	totalMarginUsed := 0.0
 
 	// 当前持仓的key集合（用于清理已平仓的记录）
 	currentPositionKeys := make(map[string]bool)
@@ -459,13 +965,12 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		unrealizedPnl, _ := pos["unRealizedProfit"].(float64)
 		liquidationPrice, _ := pos["liquidationPrice"].(float64)
 
-		// 计算占用保证金（估算）
-		leverage := 10 // 默认值，实际应该从持仓信息获取
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
-		totalMarginUsed += marginUsed
+		// 占用保证金（交易所未返回时估算）
+		leverage := positionLeverage(pos)
+		marginUsed := positionMargin(pos, quantity, markPrice, leverage)
+		adlRanking, _ := pos["adlRanking"].(int)
+		realizedPnl, _ := pos["realisedPnl"].(float64)
+		positionMode, _ := pos["mode"].(string)
 
 		// 计算盈亏百分比
 		pnlPct := 0.0
@@ -483,8 +988,16 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
 		}
 		updateTime := at.positionFirstSeenTime[posKey]
+		positionID := at.positionIDs.ensure(posKey)
+
+		// 当前止损止盈价（仅限本系统设置的）
+		stopLoss, takeProfit := 0.0, 0.0
+		if stops, ok := at.positionStops[posKey]; ok {
+			stopLoss, takeProfit = stops.StopLoss, stops.TakeProfit
+		}
 
 		positionInfos = append(positionInfos, decision.PositionInfo{
+			PositionID:       positionID,
 			Symbol:           symbol,
 			Side:             side,
 			EntryPrice:       entryPrice,
@@ -495,16 +1008,30 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			UnrealizedPnLPct: pnlPct,
 			LiquidationPrice: liquidationPrice,
 			MarginUsed:       marginUsed,
+			ADLRanking:       adlRanking,
+			RealizedPnL:      realizedPnl,
+			PositionMode:     positionMode,
 			UpdateTime:       updateTime,
+			StopLoss:         stopLoss,
+			TakeProfit:       takeProfit,
+			Plan:             at.plans.get(posKey, positionID),
 		})
 	}
 
 	// 清理已平仓的持仓记录
+	at.publishVanishedStops(currentPositionKeys)
 	for key := range at.positionFirstSeenTime {
 		if !currentPositionKeys[key] {
 			delete(at.positionFirstSeenTime, key)
 		}
 	}
+	for key := range at.positionStops {
+		if !currentPositionKeys[key] {
+			delete(at.positionStops, key)
+		}
+	}
+	at.positionIDs.prune(currentPositionKeys)
+	at.plans.prune(currentPositionKeys)
 
 	// 3. 获取合并的候选币种池（AI500 + OI Top，去重）
 	// 无论有没有持仓，都分析相同数量的币种（让AI看到所有好机会）
@@ -517,6 +1044,11 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		return nil, fmt.Errorf("获取合并币种池失败: %w", err)
 	}
 
+	// 按黑白名单过滤（名单可在运行中更新，每个周期重新应用）
+	mergedPool.ApplySymbolFilter(at.symbolFilter)
+	mergedPool.ConvertQuote(at.config.QuoteAsset)    // 换成trader的计价币种
+	mergedPool.ApplySymbolFilter(at.delistingFilter) // 即将下架的币种
+
 	// 构建候选币种列表（包含来源信息）
 	var candidateCoins []decision.CandidateCoin
 	for _, symbol := range mergedPool.AllSymbols {
@@ -537,11 +1069,6 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		totalPnLPct = (totalPnL / at.initialBalance) * 100
 	}
 
-	marginUsedPct := 0.0
-	if totalEquity > 0 {
-		marginUsedPct = (totalMarginUsed / totalEquity) * 100
-	}
-
 	// 5. 分析历史表现（最近100个周期，避免长期持仓的交易记录丢失）
 	// 假设每3分钟一个周期，100个周期 = 5小时，足够覆盖大部分交易
 	performance, err := at.decisionLogger.AnalyzePerformance(100)
@@ -561,6 +1088,13 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		MinPositionSizeUSD: at.config.MinPositionSizeUSD,
 		MaxPositionSizeUSD: at.config.MaxPositionSizeUSD,
 		SystemPromptTemplate: at.config.SystemPromptTemplate, // 系统提示词模板名称
+		SymbolFilter:       at.symbolFilter,                  // 币种黑白名单（验证开仓决策）
+		SymbolLimits:       at.config.SymbolLimits,           // 币种单独的杠杆和仓位限制
+		AutoStop:           at.config.AutoStopLoss,           // 止损止盈自动补全
+		RoundPrice:         at.priceRounder(),                // 止损止盈按交易所价格精度取整
+		ScanInterval:       at.config.ScanInterval,           // prompt中预期波动的时间窗口
+		Indicators:         at.config.Indicators,             // 策略配置限定的额外指标
+		MarketFormat:       market.FormatMode(at.config.MarketDataFormat), // 行情数据格式
 		Account: decision.AccountInfo{
 			TotalEquity:      totalEquity,
 			AvailableBalance: availableBalance,
@@ -569,17 +1103,56 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			MarginUsed:       totalMarginUsed,
 			MarginUsedPct:    marginUsedPct,
 			PositionCount:    len(positionInfos),
+			Currency:         at.config.ReportingCurrency,
 		},
 		Positions:      positionInfos,
 		CandidateCoins: candidateCoins,
 		Performance:    performance, // 添加历史表现分析
+
+		ExecutionFeedback: at.lastExecution,
 	}
+	at.addSymbolEdges(ctx)
+	at.addOvertradingFeedback(ctx)
+	at.addSimilarSetups(ctx)
+	at.addSurgeCandidates(ctx)
+	at.addExternalSignals(ctx)
 
 	return ctx, nil
 }
 
 // executeDecisionWithRecord 执行AI决策并记录详细信息
-func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) (err error) {
+	if err := at.checkRetired(); err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			at.publishOrderFilled(actionRecord)
+		}
+	}()
+	switch decision.Action {
+	case "open_long", "open_short", "close_long", "close_short", "partial_close", "add_to_position":
+		// 下单类动作按决策/执行策略选择下单类型，随每次开平仓调用传给交易器
+		actionRecord.OrderType = string(at.selectOrderType(decision))
+	}
+
+	if decision.Action == "open_long" || decision.Action == "open_short" {
+		actionRecord.StopLoss = decision.StopLoss // 用于计算交易的R倍数
+		actionRecord.TakeProfit = decision.TakeProfit
+	}
+	if at.exposure != nil && (decision.Action == "open_long" || decision.Action == "open_short" || decision.Action == "add_to_position") {
+		if err := at.reserveExposure(decision); err != nil {
+			return err
+		}
+	}
+	if at.equityGuard != nil && (decision.Action == "open_long" || decision.Action == "open_short" || decision.Action == "add_to_position") {
+		defer func() {
+			at.stateMu.Lock()
+			at.equityGuard.openedNotional += actionRecord.Quantity * actionRecord.Price
+			at.stateMu.Unlock()
+		}()
+	}
+
 	switch decision.Action {
 	case "open_long":
 		return at.executeOpenLongWithRecord(decision, actionRecord)
@@ -589,6 +1162,14 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 		return at.executeCloseLongWithRecord(decision, actionRecord)
 	case "close_short":
 		return at.executeCloseShortWithRecord(decision, actionRecord)
+	case "partial_close":
+		return at.executePartialCloseWithRecord(decision, actionRecord)
+	case "add_to_position":
+		return at.executeAddToPositionWithRecord(decision, actionRecord)
+	case "adjust_sl", "adjust_tp":
+		return at.executeAdjustProtectionWithRecord(decision, actionRecord)
+	case "cancel_orders":
+		return at.executeCancelOrdersWithRecord(decision, actionRecord)
 	case "hold", "wait":
 		// 无需执行，仅记录
 		return nil
@@ -597,6 +1178,40 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 	}
 }
 
+// selectOrderType 选择本次下单类型：决策指定 > 执行策略配置 > 交易所默认
+// 交易所不支持指定类型时回退，不阻塞交易
+func (at *AutoTrader) selectOrderType(decision *decision.Decision) OrderType {
+	candidates := []string{decision.OrderType, at.config.OrderType}
+	for _, candidate := range candidates {
+		orderType, err := ParseOrderType(candidate)
+		if err != nil {
+			log.Printf("  ⚠️ %v，忽略", err)
+			continue
+		}
+		if orderType == "" {
+			continue
+		}
+		if !SupportsOrderType(at.trader, orderType) {
+			log.Printf("  ⚠️ 交易所不支持下单类型 %s（支持: %v），回退", orderType, at.trader.SupportedOrderTypes())
+			continue
+		}
+		log.Printf("  🧾 下单类型: %s", orderType)
+		return orderType
+	}
+	return orderTypeOrDefault("", at.trader)
+}
+
+// orderTypeOrDefault 返回配置的下单类型，未配置时返回交易所默认类型
+func orderTypeOrDefault(orderType OrderType, trader Trader) OrderType {
+	if orderType != "" {
+		return orderType
+	}
+	if supported := trader.SupportedOrderTypes(); len(supported) > 0 {
+		return supported[0]
+	}
+	return ""
+}
+
 // executeOpenLongWithRecord 执行开多仓并记录详细信息
 func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	log.Printf("  📈 开多仓: %s", decision.Symbol)
@@ -619,7 +1234,7 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 
 	// 检查可用余额和保证金
 	if at.config.CheckAvailableBeforeOpen {
-		balance, err := at.trader.GetBalance()
+		balance, err := at.getBalance()
 		if err == nil {
 			availableBalance := 0.0
 			totalEquity := 0.0
@@ -668,7 +1283,7 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 					if lev, ok := pos["leverage"].(float64); ok {
 						leverage = int(lev)
 					}
-					totalMarginUsed += (quantity * markPrice) / float64(leverage)
+					totalMarginUsed += positionMargin(pos, quantity, markPrice, leverage)
 				}
 
 				// 加上新仓位的保证金
@@ -686,15 +1301,42 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	}
 
 	// 计算数量
-	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
+	quantity, err := at.quantityFor(decision.Symbol, decision.PositionSizeUSD, marketData.CurrentPrice)
+	if err != nil {
+		return err
+	}
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
 
+	// 记录操作（进程中途退出时，下个周期据此完成或回滚）
+	op := &pendingOperation{
+		Action:     "open_long",
+		Symbol:     decision.Symbol,
+		Side:       "long",
+		PositionID: newPositionID(),
+		Quantity:   quantity,
+		Leverage:   decision.Leverage,
+		StopLoss:   decision.StopLoss,
+		TakeProfit: decision.TakeProfit,
+	}
+	actionRecord.PositionID = op.PositionID
+	if err := at.beginOperation(op); err != nil {
+		return err
+	}
+
 	// 开仓
-	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage)
+	order, filled, err := at.placeOpenOrder(decision.Symbol, "long", quantity, decision.Leverage, marketData.CurrentPrice, decision.StopLoss, actionRecord)
 	if err != nil {
+		at.abortOperation(op)
 		return err
 	}
+	recordFill(actionRecord, order, true)
+	at.captureEntry(decision, actionRecord, marketData.CurrentPrice)
+	op.StopLoss, op.TakeProfit = decision.StopLoss, decision.TakeProfit
+	// 部分成交或拆单中途放弃时按实际成交数量挂保护单
+	quantity = filled
+	actionRecord.Quantity = filled
+	op.Quantity = filled
 
 	// 记录订单ID
 	if orderID, ok := order["orderId"].(int64); ok {
@@ -705,14 +1347,35 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_long"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
-
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
+	at.setPositionProtection(posKey, &protectionPrices{StopLoss: decision.StopLoss, TakeProfit: decision.TakeProfit}, true)
+	at.positionIDs.set(posKey, op.PositionID)
+	at.tagProtectionOrders(decision.Symbol, "long", op.PositionID)
+
+	// 会立即触发的止损止盈按策略重新推导或不挂
+	slErr, tpErr := at.guardEntryTriggers(decision, "long", actionRecord)
+	op.StopLoss, op.TakeProfit = decision.StopLoss, decision.TakeProfit
+
+	// 设置止损止盈（失败时操作保留在日志中，下个周期重新挂单）
+	var protectErr error
+	at.advanceOperation(op, stepSettingStopLoss)
+	if slErr != nil {
+		log.Printf("  ⚠ 不挂止损: %v", slErr)
+		protectErr = fmt.Errorf("未挂止损: %w", slErr)
+	} else if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
 		log.Printf("  ⚠ 设置止损失败: %v", err)
+		protectErr = fmt.Errorf("设置止损失败: %w", err)
 	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
+	at.advanceOperation(op, stepSettingTakeProfit)
+	if tpErr != nil {
+		log.Printf("  ⚠ 不挂止盈: %v", tpErr)
+		protectErr = fmt.Errorf("未挂止盈: %w", tpErr)
+	} else if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
 		log.Printf("  ⚠ 设置止盈失败: %v", err)
+		protectErr = fmt.Errorf("设置止盈失败: %w", err)
+	}
+	at.completeOperation(op, protectErr)
+	if protectErr != nil {
+		actionRecord.ProtectionError = protectErr.Error()
 	}
 
 	return nil
@@ -740,7 +1403,7 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 
 	// 检查可用余额和保证金
 	if at.config.CheckAvailableBeforeOpen {
-		balance, err := at.trader.GetBalance()
+		balance, err := at.getBalance()
 		if err == nil {
 			availableBalance := 0.0
 			totalEquity := 0.0
@@ -789,7 +1452,7 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 					if lev, ok := pos["leverage"].(float64); ok {
 						leverage = int(lev)
 					}
-					totalMarginUsed += (quantity * markPrice) / float64(leverage)
+					totalMarginUsed += positionMargin(pos, quantity, markPrice, leverage)
 				}
 
 				// 加上新仓位的保证金
@@ -807,15 +1470,42 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	}
 
 	// 计算数量
-	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
+	quantity, err := at.quantityFor(decision.Symbol, decision.PositionSizeUSD, marketData.CurrentPrice)
+	if err != nil {
+		return err
+	}
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
 
+	// 记录操作（进程中途退出时，下个周期据此完成或回滚）
+	op := &pendingOperation{
+		Action:     "open_short",
+		Symbol:     decision.Symbol,
+		Side:       "short",
+		PositionID: newPositionID(),
+		Quantity:   quantity,
+		Leverage:   decision.Leverage,
+		StopLoss:   decision.StopLoss,
+		TakeProfit: decision.TakeProfit,
+	}
+	actionRecord.PositionID = op.PositionID
+	if err := at.beginOperation(op); err != nil {
+		return err
+	}
+
 	// 开仓
-	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage)
+	order, filled, err := at.placeOpenOrder(decision.Symbol, "short", quantity, decision.Leverage, marketData.CurrentPrice, decision.StopLoss, actionRecord)
 	if err != nil {
+		at.abortOperation(op)
 		return err
 	}
+	recordFill(actionRecord, order, false)
+	at.captureEntry(decision, actionRecord, marketData.CurrentPrice)
+	op.StopLoss, op.TakeProfit = decision.StopLoss, decision.TakeProfit
+	// 部分成交或拆单中途放弃时按实际成交数量挂保护单
+	quantity = filled
+	actionRecord.Quantity = filled
+	op.Quantity = filled
 
 	// 记录订单ID
 	if orderID, ok := order["orderId"].(int64); ok {
@@ -826,14 +1516,35 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_short"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
-
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
+	at.setPositionProtection(posKey, &protectionPrices{StopLoss: decision.StopLoss, TakeProfit: decision.TakeProfit}, true)
+	at.positionIDs.set(posKey, op.PositionID)
+	at.tagProtectionOrders(decision.Symbol, "short", op.PositionID)
+
+	// 会立即触发的止损止盈按策略重新推导或不挂
+	slErr, tpErr := at.guardEntryTriggers(decision, "short", actionRecord)
+	op.StopLoss, op.TakeProfit = decision.StopLoss, decision.TakeProfit
+
+	// 设置止损止盈（失败时操作保留在日志中，下个周期重新挂单）
+	var protectErr error
+	at.advanceOperation(op, stepSettingStopLoss)
+	if slErr != nil {
+		log.Printf("  ⚠ 不挂止损: %v", slErr)
+		protectErr = fmt.Errorf("未挂止损: %w", slErr)
+	} else if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
 		log.Printf("  ⚠ 设置止损失败: %v", err)
+		protectErr = fmt.Errorf("设置止损失败: %w", err)
 	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
+	at.advanceOperation(op, stepSettingTakeProfit)
+	if tpErr != nil {
+		log.Printf("  ⚠ 不挂止盈: %v", tpErr)
+		protectErr = fmt.Errorf("未挂止盈: %w", tpErr)
+	} else if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
 		log.Printf("  ⚠ 设置止盈失败: %v", err)
+		protectErr = fmt.Errorf("设置止盈失败: %w", err)
+	}
+	at.completeOperation(op, protectErr)
+	if protectErr != nil {
+		actionRecord.ProtectionError = protectErr.Error()
 	}
 
 	return nil
@@ -850,11 +1561,18 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 	}
 	actionRecord.Price = marketData.CurrentPrice
 
+	actionRecord.PositionID = at.positionID(decision.Symbol, "long")
+
 	// 平仓
-	order, err := at.trader.CloseLong(decision.Symbol, 0) // 0 = 全部平仓
+	if err := at.checkRetired(); err != nil {
+		return err
+	}
+	order, err := at.trader.CloseLong(decision.Symbol, 0, OrderType(actionRecord.OrderType)) // 0 = 全部平仓
 	if err != nil {
 		return err
 	}
+	recordFill(actionRecord, order, false)
+	at.clearPositionProtection(decision.Symbol + "_long")
 
 	// 记录订单ID
 	if orderID, ok := order["orderId"].(int64); ok {
@@ -876,11 +1594,18 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 	}
 	actionRecord.Price = marketData.CurrentPrice
 
+	actionRecord.PositionID = at.positionID(decision.Symbol, "short")
+
 	// 平仓
-	order, err := at.trader.CloseShort(decision.Symbol, 0) // 0 = 全部平仓
+	if err := at.checkRetired(); err != nil {
+		return err
+	}
+	order, err := at.trader.CloseShort(decision.Symbol, 0, OrderType(actionRecord.OrderType)) // 0 = 全部平仓
 	if err != nil {
 		return err
 	}
+	recordFill(actionRecord, order, true)
+	at.clearPositionProtection(decision.Symbol + "_short")
 
 	// 记录订单ID
 	if orderID, ok := order["orderId"].(int64); ok {
@@ -891,11 +1616,361 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 	return nil
 }
 
+// findPosition 查找持仓，返回方向、数量（正数）和杠杆
+// side为空时按币种推断，同币种同时持有多空仓时必须明确side
+func (at *AutoTrader) findPosition(symbol, side string) (string, float64, int, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var matched []map[string]interface{}
+	for _, pos := range positions {
+		if pos["symbol"] != symbol {
+			continue
+		}
+		if side != "" && pos["side"] != side {
+			continue
+		}
+		matched = append(matched, pos)
+	}
+
+	if len(matched) == 0 {
+		if side == "" {
+			return "", 0, 0, fmt.Errorf("%s 没有持仓", symbol)
+		}
+		return "", 0, 0, fmt.Errorf("%s 没有%s持仓", symbol, side)
+	}
+	if len(matched) > 1 {
+		return "", 0, 0, fmt.Errorf("%s 同时持有多空仓，请在决策中指定side", symbol)
+	}
+
+	pos := matched[0]
+	posSide, _ := pos["side"].(string)
+	quantity, _ := pos["positionAmt"].(float64)
+	if quantity < 0 {
+		quantity = -quantity
+	}
+	leverage := 0
+	if lev, ok := pos["leverage"].(float64); ok {
+		leverage = int(lev)
+	}
+	return posSide, quantity, leverage, nil
+}
+
+// replaceProtection 按持仓的止损止盈价为剩余仓位重新挂保护单
+// 记录中没有的价格从交易所现有条件单恢复（重启后记录为空），一个价格都不知道时不撤任何挂单。
+// 交易所支持单独撤单时只撤该方向需要重新挂的条件单，否则撤销该币种所有挂单后恢复另一方向的保护单。
+func (at *AutoTrader) replaceProtection(symbol, side string, quantity float64) error {
+	if err := at.checkRetired(); err != nil {
+		return err
+	}
+	existing, selective, err := at.sideProtectionOrders(symbol, side)
+	if err != nil {
+		return fmt.Errorf("%w，保留原有止损止盈", err)
+	}
+
+	if quantity <= 0 {
+		// 已没有仓位，只撤该方向的保护单
+		if selective {
+			return at.cancelProtectionOrders(symbol, existing, "stop_loss", "take_profit")
+		}
+		if err := at.trader.CancelAllOrders(symbol); err != nil {
+			log.Printf("  ⚠ 撤销原有止损止盈失败（可能没有挂单）: %v", err)
+		}
+		at.restoreProtection(symbol, oppositeSide(side))
+		return nil
+	}
+
+	stops := at.knownProtection(symbol, side, existing)
+	if stops.StopLoss <= 0 && stops.TakeProfit <= 0 {
+		log.Printf("  ⚠ %s %s 没有已知的止损止盈价，保留交易所上原有挂单", symbol, side)
+		return fmt.Errorf("%s %s 没有已知的止损止盈价，未撤销原有挂单", symbol, side)
+	}
+
+	// 先检查触发价，会立即触发的条件单不挂，交易所上原有的同类条件单保留
+	slErr, tpErr := at.checkTriggers(symbol, side, stops)
+	triggerErr := joinTriggerErrors(slErr, tpErr)
+
+	// 只替换有价格且通过检查的那类条件单，另一类保留在交易所上
+	var kinds []string
+	if stops.StopLoss > 0 && slErr == nil {
+		kinds = append(kinds, "stop_loss")
+	}
+	if stops.TakeProfit > 0 && tpErr == nil {
+		kinds = append(kinds, "take_profit")
+	}
+	if selective {
+		if err := at.cancelProtectionOrders(symbol, existing, kinds...); err != nil {
+			return err
+		}
+	} else if triggerErr != nil {
+		// 只能撤销所有挂单，原有保护单全部保留
+		return fmt.Errorf("未重新挂保护单，保留原有止损止盈: %w", triggerErr)
+	} else if err := at.trader.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 撤销原有止损止盈失败（可能没有挂单）: %v", err)
+	}
+	// 撤销所有挂单（或加仓下单时交易所已撤销所有挂单）后恢复另一方向的保护单
+	at.restoreProtection(symbol, oppositeSide(side))
+
+	at.tagProtectionOrders(symbol, side, at.positionID(symbol, side))
+	positionSide := strings.ToUpper(side)
+	for _, kind := range kinds {
+		if kind == "stop_loss" {
+			if err := at.trader.SetStopLoss(symbol, positionSide, quantity, stops.StopLoss); err != nil {
+				return fmt.Errorf("设置止损失败: %w", err)
+			}
+		} else if err := at.trader.SetTakeProfit(symbol, positionSide, quantity, stops.TakeProfit); err != nil {
+			return fmt.Errorf("设置止盈失败: %w", err)
+		}
+	}
+	if triggerErr != nil {
+		return fmt.Errorf("未挂保护单（保留原有条件单）: %w", triggerErr)
+	}
+	return nil
+}
+
+// executePartialCloseWithRecord 执行部分平仓并记录详细信息
+func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	side, quantity, _, err := at.findPosition(decision.Symbol, decision.Side)
+	if err != nil {
+		return err
+	}
+	log.Printf("  ✂️ 部分平仓: %s %s %.1f%%", decision.Symbol, side, decision.ClosePercent)
+	actionRecord.PositionID = at.positionID(decision.Symbol, side)
+
+	marketData, err := market.Get(decision.Symbol)
+	if err != nil {
+		return err
+	}
+	actionRecord.Price = marketData.CurrentPrice
+
+	closeQty := quantity * decision.ClosePercent / 100
+	actionRecord.Quantity = closeQty
+
+	at.rememberProtection(decision.Symbol) // 平仓可能撤销该币种所有挂单，先记下交易所上的止损止盈价
+	if err := at.checkRetired(); err != nil {
+		return err
+	}
+	var order map[string]interface{}
+	if side == "long" {
+		order, err = at.trader.CloseLong(decision.Symbol, closeQty, OrderType(actionRecord.OrderType))
+	} else {
+		order, err = at.trader.CloseShort(decision.Symbol, closeQty, OrderType(actionRecord.OrderType))
+	}
+	if err != nil {
+		return err
+	}
+	recordFill(actionRecord, order, side == "short")
+
+	if orderID, ok := order["orderId"].(int64); ok {
+		actionRecord.OrderID = orderID
+	}
+
+	// 剩余仓位重新挂止损止盈（数量已变化）
+	remaining := quantity - closeQty
+	if err := at.replaceProtection(decision.Symbol, side, remaining); err != nil {
+		log.Printf("  ⚠ 部分平仓后重新设置止损止盈失败: %v", err)
+	}
+
+	log.Printf("  ✓ 部分平仓成功，平掉 %.4f，剩余 %.4f", closeQty, remaining)
+	return nil
+}
+
+// executeAddToPositionWithRecord 执行加仓并记录详细信息
+func (at *AutoTrader) executeAddToPositionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	side, quantity, currentLeverage, err := at.findPosition(decision.Symbol, decision.Side)
+	if err != nil {
+		return err
+	}
+	log.Printf("  ➕ 加仓: %s %s %.2f USDT", decision.Symbol, side, decision.PositionSizeUSD)
+	actionRecord.PositionID = at.positionID(decision.Symbol, side)
+
+	leverage := decision.Leverage
+	if leverage <= 0 {
+		leverage = currentLeverage
+	}
+	if leverage <= 0 {
+		return fmt.Errorf("无法确定 %s 的杠杆倍数，请在决策中提供leverage", decision.Symbol)
+	}
+	actionRecord.Leverage = leverage
+
+	marketData, err := market.Get(decision.Symbol)
+	if err != nil {
+		return err
+	}
+
+	// 检查可用余额
+	if at.config.CheckAvailableBeforeOpen {
+		balance, err := at.getBalance()
+		if err == nil {
+			availableBalance, _ := balance["availableBalance"].(float64)
+			requiredMargin := decision.PositionSizeUSD / float64(leverage)
+			totalRequired := requiredMargin * (1 + at.config.SafetyBufferPct/100.0)
+			if availableBalance < totalRequired {
+				return fmt.Errorf("❌ 可用余额不足：加仓需要 %.2f USDT（含缓冲），可用 %.2f USDT", totalRequired, availableBalance)
+			}
+		}
+	}
+
+	addQty, err := at.quantityFor(decision.Symbol, decision.PositionSizeUSD, marketData.CurrentPrice)
+	if err != nil {
+		return err
+	}
+	actionRecord.Quantity = addQty
+	actionRecord.Price = marketData.CurrentPrice
+
+	// 记录操作：下单会撤销原有保护单，未成交时需要按原止损止盈恢复
+	posKey := decision.Symbol + "_" + side
+	op := &pendingOperation{
+		Action:       "add_to_position",
+		Symbol:       decision.Symbol,
+		Side:         side,
+		PositionID:   actionRecord.PositionID,
+		Quantity:     addQty,
+		BaseQuantity: quantity,
+		Leverage:     leverage,
+	}
+	at.rememberProtection(decision.Symbol) // 下单会撤销原有保护单，先记下交易所上的止损止盈价
+	if prev, ok := at.positionProtection(posKey); ok {
+		op.PrevStopLoss, op.PrevTakeProfit = prev.StopLoss, prev.TakeProfit
+	}
+	op.StopLoss, op.TakeProfit = op.PrevStopLoss, op.PrevTakeProfit
+	if decision.StopLoss > 0 {
+		op.StopLoss = decision.StopLoss
+	}
+	if decision.TakeProfit > 0 {
+		op.TakeProfit = decision.TakeProfit
+	}
+	if err := at.beginOperation(op); err != nil {
+		return err
+	}
+
+	order, filled, err := at.placeOpenOrder(decision.Symbol, side, addQty, leverage, marketData.CurrentPrice, op.StopLoss, actionRecord)
+	if err != nil {
+		at.abortOperation(op)
+		return err
+	}
+	recordFill(actionRecord, order, side == "long")
+	addQty = filled
+	actionRecord.Quantity = filled
+	op.Quantity = filled
+
+	if orderID, ok := order["orderId"].(int64); ok {
+		actionRecord.OrderID = orderID
+	}
+
+	// 更新止损止盈（决策中提供的价格优先）
+	at.setPositionProtection(posKey, &protectionPrices{StopLoss: op.StopLoss, TakeProfit: op.TakeProfit}, false)
+
+	// 开仓会撤销原有挂单，按加仓后的总数量重新挂单
+	at.advanceOperation(op, stepSettingStopLoss)
+	actionRecord.StopLoss, actionRecord.TakeProfit = op.StopLoss, op.TakeProfit
+	err = at.replaceProtection(decision.Symbol, side, quantity+addQty)
+	if err != nil {
+		log.Printf("  ⚠ 加仓后重新设置止损止盈失败: %v", err)
+		actionRecord.ProtectionError = err.Error()
+	}
+	at.completeOperation(op, err)
+
+	log.Printf("  ✓ 加仓成功，订单ID: %v, 数量: %.4f", order["orderId"], addQty)
+	return nil
+}
+
+// executeAdjustProtectionWithRecord 执行止损/止盈调整（adjust_sl / adjust_tp）并记录详细信息
+func (at *AutoTrader) executeAdjustProtectionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	side, quantity, _, err := at.findPosition(decision.Symbol, decision.Side)
+	if err != nil {
+		return err
+	}
+
+	marketData, err := market.Get(decision.Symbol)
+	if err != nil {
+		return err
+	}
+	price := marketData.CurrentPrice
+	actionRecord.Price = price
+	actionRecord.Quantity = quantity
+	actionRecord.PositionID = at.positionID(decision.Symbol, side)
+
+	posKey := decision.Symbol + "_" + side
+	existing, _, err := at.sideProtectionOrders(decision.Symbol, side)
+	if err != nil {
+		return err
+	}
+	stops := at.knownProtection(decision.Symbol, side, existing)
+	newStops := stops
+	if decision.StopLoss > 0 {
+		newStops.StopLoss = decision.StopLoss
+	}
+	if decision.TakeProfit > 0 {
+		newStops.TakeProfit = decision.TakeProfit
+	}
+
+	// 止损止盈必须位于当前价格正确的一侧，否则会立即触发
+	if side == "long" {
+		if newStops.StopLoss > 0 && newStops.StopLoss >= price {
+			return fmt.Errorf("多仓止损价 %.4f 必须低于当前价 %.4f", newStops.StopLoss, price)
+		}
+		if newStops.TakeProfit > 0 && newStops.TakeProfit <= price {
+			return fmt.Errorf("多仓止盈价 %.4f 必须高于当前价 %.4f", newStops.TakeProfit, price)
+		}
+	} else {
+		if newStops.StopLoss > 0 && newStops.StopLoss <= price {
+			return fmt.Errorf("空仓止损价 %.4f 必须高于当前价 %.4f", newStops.StopLoss, price)
+		}
+		if newStops.TakeProfit > 0 && newStops.TakeProfit >= price {
+			return fmt.Errorf("空仓止盈价 %.4f 必须低于当前价 %.4f", newStops.TakeProfit, price)
+		}
+	}
+
+	log.Printf("  🎯 调整保护单: %s %s 止损 %.4f → %.4f | 止盈 %.4f → %.4f",
+		decision.Symbol, side, stops.StopLoss, newStops.StopLoss, stops.TakeProfit, newStops.TakeProfit)
+
+	// 按最新价、标记价和指数价检查要调整的价格，不通过时不改动原有保护单
+	changed := protectionPrices{StopLoss: decision.StopLoss, TakeProfit: decision.TakeProfit}
+	if err := joinTriggerErrors(at.checkTriggers(decision.Symbol, side, changed)); err != nil {
+		return err
+	}
+
+	at.setPositionProtection(posKey, &newStops, false)
+	actionRecord.StopLoss, actionRecord.TakeProfit = newStops.StopLoss, newStops.TakeProfit
+	if err := at.replaceProtection(decision.Symbol, side, quantity); err != nil {
+		return err
+	}
+
+	log.Printf("  ✓ 保护单调整成功")
+	return nil
+}
+
+// executeCancelOrdersWithRecord 撤销币种所有挂单并记录详细信息
+func (at *AutoTrader) executeCancelOrdersWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	log.Printf("  🗑 撤销挂单: %s", decision.Symbol)
+
+	if err := at.checkRetired(); err != nil {
+		return err
+	}
+	if err := at.trader.CancelAllOrders(decision.Symbol); err != nil {
+		return err
+	}
+
+	// 保护单已全部撤销，清除记录的价格
+	at.clearPositionProtection(decision.Symbol+"_long", decision.Symbol+"_short")
+
+	log.Printf("  ✓ 撤单成功（%s 持仓当前无止损止盈保护）", decision.Symbol)
+	return nil
+}
+
 // GetID 获取trader ID
 func (at *AutoTrader) GetID() string {
 	return at.id
 }
 
+// GetScanInterval 获取扫描间隔
+func (at *AutoTrader) GetScanInterval() time.Duration {
+	return at.config.ScanInterval
+}
+
 // GetName 获取trader名称
 func (at *AutoTrader) GetName() string {
 	return at.name
@@ -903,6 +1978,10 @@ func (at *AutoTrader) GetName() string {
 
 // GetAIModel 获取AI模型
 func (at *AutoTrader) GetAIModel() string {
+	// 不调用AI的策略返回策略名（排行榜上作为基准参赛）
+	if strategy := at.strategy(); strategy != StrategyAI {
+		return strategy
+	}
 	// 如果是custom模型，返回custom_model_name；否则返回aiModel
 	if at.aiModel == "custom" && at.config.CustomModelName != "" {
 		return at.config.CustomModelName
@@ -910,6 +1989,11 @@ func (at *AutoTrader) GetAIModel() string {
 	return at.aiModel
 }
 
+// GetSymbolFilter 获取币种黑白名单（更新后下一个周期生效）
+func (at *AutoTrader) GetSymbolFilter() *pool.SymbolFilter {
+	return at.symbolFilter
+}
+
 // GetDecisionLogger 获取决策日志记录器
 func (at *AutoTrader) GetDecisionLogger() *logger.DecisionLogger {
 	return at.decisionLogger
@@ -928,66 +2012,57 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		"ai_model":        at.aiModel,
 		"exchange":        at.exchange,
 		"is_running":      at.isRunning,
-		"start_time":      at.startTime.Format(time.RFC3339),
+		"start_time":      at.startTime.In(at.location).Format(time.RFC3339),
 		"runtime_minutes": int(time.Since(at.startTime).Minutes()),
 		"call_count":      at.callCount,
 		"initial_balance": at.initialBalance,
 		"scan_interval":   at.config.ScanInterval.String(),
-		"stop_until":      at.stopUntil.Format(time.RFC3339),
-		"last_reset_time": at.lastResetTime.Format(time.RFC3339),
+		"stop_until":      at.stopUntil.In(at.location).Format(time.RFC3339),
+		"stop_reason":     at.stopReason,
+		"derisk_level":    at.derisk.level.String(),
+		"restrictions":    at.riskRestrictions(),
+		"approval_mode":   at.approval != nil,
+		"strategy":        at.strategy(),
+		"profile":         at.config.Profile,
+		"quote_asset":     at.config.QuoteAsset,
+		"last_reset_time": at.lastResetTime.In(at.location).Format(time.RFC3339),
+		"timezone":        at.location.String(),
 		"ai_provider":     aiProvider,
+		"account_check":   at.accountPermissions,
+		"cycle_panics":    at.GetPanicStats().Count,
+	}
+}
+
+// strategy 交易策略名称
+func (at *AutoTrader) strategy() string {
+	switch {
+	case at.carry != nil:
+		return StrategyCarry
+	case at.grid != nil:
+		return StrategyGrid
+	default:
+		return StrategyAI
 	}
 }
 
 // GetAccountInfo 获取账户信息（用于API）
 func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
-	balance, err := at.trader.GetBalance()
+	snap, err := at.accountSnapshot()
 	if err != nil {
-		return nil, fmt.Errorf("获取余额失败: %w", err)
-	}
-
-	// 获取账户字段
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
-
-	if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
-	}
-	if unrealized, ok := balance["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
+		return nil, err
 	}
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
-	}
-
-	// Total Equity = 钱包余额 + 未实现盈亏
-	totalEquity := totalWalletBalance + totalUnrealizedProfit
+	return at.accountInfo(snap), nil
+}
 
-	// 获取持仓计算总保证金
-	positions, err := at.trader.GetPositions()
-	if err != nil {
-		return nil, fmt.Errorf("获取持仓失败: %w", err)
-	}
+// accountInfo 由账户快照生成账户信息
+func (at *AutoTrader) accountInfo(snap *AccountSnapshot) map[string]interface{} {
+	totalEquity := snap.TotalEquity
+	positions := snap.Positions
 
-	totalMarginUsed := 0.0
 	totalUnrealizedPnL := 0.0
 	for _, pos := range positions {
-		// Safe type assertions with defaults
-		markPrice, _ := pos["markPrice"].(float64)
-		quantity, _ := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
 		unrealizedPnl, _ := pos["unRealizedProfit"].(float64)
 		totalUnrealizedPnL += unrealizedPnl
-
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
-		totalMarginUsed += marginUsed
 	}
 
 	totalPnL := totalEquity - at.initialBalance
@@ -996,17 +2071,16 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 		totalPnLPct = (totalPnL / at.initialBalance) * 100
 	}
 
-	marginUsedPct := 0.0
-	if totalEquity > 0 {
-		marginUsedPct = (totalMarginUsed / totalEquity) * 100
-	}
-
 	return map[string]interface{}{
 		// 核心字段
 		"total_equity":      totalEquity,           // 账户净值 = wallet + unrealized
-		"wallet_balance":    totalWalletBalance,    // 钱包余额（不含未实现盈亏）
-		"unrealized_profit": totalUnrealizedProfit, // 未实现盈亏（从API）
-		"available_balance": availableBalance,      // 可用余额
+		"wallet_balance":    snap.WalletBalance,    // 钱包余额（不含未实现盈亏）
+		"unrealized_profit": snap.UnrealizedPnL,    // 未实现盈亏（从API）
+		"available_balance": snap.AvailableBalance, // 可用余额
+
+		// 币种
+		"currency":   at.config.ReportingCurrency, // 以上金额的币种（报告币种）
+		"settlement": at.settlement,               // 交易所结算币种
 
 		// 盈亏统计
 		"total_pnl":            totalPnL,           // 总盈亏 = equity - initial
@@ -1017,14 +2091,14 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 
 		// 持仓信息
 		"position_count":  len(positions),  // 持仓数量
-		"margin_used":     totalMarginUsed, // 保证金占用
-		"margin_used_pct": marginUsedPct,   // 保证金使用率
-	}, nil
+		"margin_used":     snap.MarginUsed,    // 保证金占用
+		"margin_used_pct": snap.MarginUsedPct, // 保证金使用率
+	}
 }
 
 // GetPositions 获取持仓列表（用于API）
 func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
-	positions, err := at.trader.GetPositions()
+	positions, err := at.getPositions()
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
@@ -1043,10 +2117,7 @@ func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
 		unrealizedPnl, _ := pos["unRealizedProfit"].(float64)
 		liquidationPrice, _ := pos["liquidationPrice"].(float64)
 
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
+		leverage := positionLeverage(pos)
 
 		pnlPct := 0.0
 		if side == "long" {
@@ -1055,9 +2126,11 @@ func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
 			pnlPct = ((entryPrice - markPrice) / entryPrice) * float64(leverage) * 100
 		}
 
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := positionMargin(pos, quantity, markPrice, leverage)
+		funding := at.funding.Cumulative(symbol, side)
 
 		result = append(result, map[string]interface{}{
+			"position_id":        at.positionIDs.get(symbol + "_" + side),
 			"symbol":             symbol,
 			"side":               side,
 			"entry_price":        entryPrice,
@@ -1068,45 +2141,32 @@ func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
 			"unrealized_pnl_pct": pnlPct,
 			"liquidation_price":  liquidationPrice,
 			"margin_used":        marginUsed,
+			"adl_ranking":        pos["adlRanking"],
+			"realized_pnl":       pos["realisedPnl"],
+			"position_mode":      pos["mode"],
+			"cumulative_funding": funding,
+			"net_pnl":            unrealizedPnl + funding, // 未实现盈亏 + 累计资金费
 		})
 	}
 
 	return result, nil
 }
 
-// sortDecisionsByPriority 对决策排序：先平仓，再开仓，最后hold/wait
+// sortDecisionsByPriority 对决策排序：先平仓，再撤单、调整保护单，再开仓，最后hold/wait
 // 这样可以避免换仓时仓位叠加超限
 func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision {
 	if len(decisions) <= 1 {
 		return decisions
 	}
 
-	// 定义优先级
-	getActionPriority := func(action string) int {
-		switch action {
-		case "close_long", "close_short":
-			return 1 // 最高优先级：先平仓
-		case "open_long", "open_short":
-			return 2 // 次优先级：后开仓
-		case "hold", "wait":
-			return 3 // 最低优先级：观望
-		default:
-			return 999 // 未知动作放最后
-		}
-	}
-
 	// 复制决策列表
 	sorted := make([]decision.Decision, len(decisions))
 	copy(sorted, decisions)
 
-	// 按优先级排序
-	for i := 0; i < len(sorted)-1; i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if getActionPriority(sorted[i].Action) > getActionPriority(sorted[j].Action) {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
-		}
-	}
+	// 按优先级稳定排序（同一优先级保持AI给出的顺序）
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return actionPriority(sorted[i].Action) < actionPriority(sorted[j].Action)
+	})
 
 	return sorted
 }
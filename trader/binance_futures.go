@@ -4,34 +4,46 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
+	"nofx/cache"
+	"nofx/market"
+	"nofx/ratelimit"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
+	"github.com/adshao/go-binance/v2"
 	"github.com/adshao/go-binance/v2/futures"
 )
 
+// 切换杠杆/保证金模式后的冷却时间（避免冷却期错误）
+var (
+	leverageCooldown   = 5 * time.Second
+	marginTypeCooldown = 3 * time.Second
+)
+
 // FuturesTrader 币安合约交易器
 type FuturesTrader struct {
 	client *futures.Client
+	positionTags // 止损止盈单的客户端订单ID（按持仓ID）
 
-	// 余额缓存
-	cachedBalance     map[string]interface{}
-	balanceCacheTime  time.Time
-	balanceCacheMutex sync.RWMutex
+	// 余额和持仓缓存（15秒）
+	balanceCache   *cache.Value[map[string]interface{}]
+	positionsCache *cache.Value[[]map[string]interface{}]
 
-	// 持仓缓存
-	cachedPositions     []map[string]interface{}
-	positionsCacheTime  time.Time
-	positionsCacheMutex sync.RWMutex
+	// 各交易对的价格tick size（1小时）
+	tickSizes *cache.Value[map[string]float64]
 
-	// 缓存有效期（15秒）
-	cacheDuration time.Duration
+	// 下单类型（默认市价单）
+	orderTypeSelector
 }
 
 // NewFuturesTrader 创建合约交易器
 func NewFuturesTrader(apiKey, secretKey string, testnet bool) *FuturesTrader {
 	client := futures.NewClient(apiKey, secretKey)
+	// 所有请求经过币安限频调度器（与行情请求共享权重预算）
+	client.HTTPClient = &http.Client{Transport: ratelimit.NewTransport(ratelimit.Get("binance"), nil)}
 	
 	// 如果使用测试网，设置测试网baseURL
 	if testnet {
@@ -40,67 +52,79 @@ func NewFuturesTrader(apiKey, secretKey string, testnet bool) *FuturesTrader {
 	}
 	
 	return &FuturesTrader{
-		client:        client,
-		cacheDuration: 15 * time.Second, // 15秒缓存
+		client:            client,
+		balanceCache:      cache.NewValue[map[string]interface{}]("binance.balance", 15*time.Second),
+		positionsCache:    cache.NewValue[[]map[string]interface{}]("binance.positions", 15*time.Second),
+		tickSizes:         cache.NewValue[map[string]float64]("binance.ticksize", time.Hour),
+		orderTypeSelector: newOrderTypeSelector(OrderTypeMarket, OrderTypeIOC, OrderTypeFOK, OrderTypePostOnly),
 	}
 }
 
 // GetBalance 获取账户余额（带缓存）
 func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
-	// 先检查缓存是否有效
-	t.balanceCacheMutex.RLock()
-	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
-		cacheAge := time.Since(t.balanceCacheTime)
-		t.balanceCacheMutex.RUnlock()
-		log.Printf("✓ 使用缓存的账户余额（缓存时间: %.1f秒前）", cacheAge.Seconds())
-		return t.cachedBalance, nil
-	}
-	t.balanceCacheMutex.RUnlock()
+	return t.balanceCache.GetOrLoad(t.fetchBalance)
+}
 
-	// 缓存过期或不存在，调用API
+// fetchBalance 调用API获取账户余额
+func (t *FuturesTrader) fetchBalance() (map[string]interface{}, error) {
 	log.Printf("🔄 缓存过期，正在调用币安API获取账户余额...")
 	account, err := t.client.NewGetAccountService().Do(context.Background())
 	if err != nil {
 		log.Printf("❌ 币安API调用失败: %v", err)
-		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+		return nil, fmt.Errorf("获取账户信息失败: %w", binanceError(err))
 	}
 
 	result := make(map[string]interface{})
-	result["totalWalletBalance"], _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
-	result["availableBalance"], _ = strconv.ParseFloat(account.AvailableBalance, 64)
-	result["totalUnrealizedProfit"], _ = strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
+	wallet, _ := strconv.ParseFloat(account.TotalWalletBalance, 64)
+	available, _ := strconv.ParseFloat(account.AvailableBalance, 64)
+	unrealized, _ := strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
+
+	// 单币保证金模式下账户总额只包含USDT，USDC等其他稳定币保证金（USDC永续）需要按汇率折成USDT加上；
+	// 联合保证金模式下账户总额已按USD合计了所有资产
+	if !account.MultiAssetsMargin {
+		for _, asset := range account.Assets {
+			if asset.Asset == "USDT" || !market.IsStableQuote(asset.Asset) {
+				continue
+			}
+			w, _ := strconv.ParseFloat(asset.WalletBalance, 64)
+			a, _ := strconv.ParseFloat(asset.AvailableBalance, 64)
+			u, _ := strconv.ParseFloat(asset.UnrealizedProfit, 64)
+			if w == 0 && u == 0 {
+				continue
+			}
+			rate, err := market.ConversionRate(context.Background(), asset.Asset, "USDT")
+			if err != nil {
+				log.Printf("⚠️  %s→USDT 汇率获取失败，按1:1计入: %v", asset.Asset, err)
+				rate = 1
+			}
+			wallet += w * rate
+			available += a * rate
+			unrealized += u * rate
+		}
+	}
+	result["totalWalletBalance"] = wallet
+	result["availableBalance"] = available
+	result["totalUnrealizedProfit"] = unrealized
 
 	log.Printf("✓ 币安API返回: 总余额=%s, 可用=%s, 未实现盈亏=%s",
 		account.TotalWalletBalance,
 		account.AvailableBalance,
 		account.TotalUnrealizedProfit)
 
-	// 更新缓存
-	t.balanceCacheMutex.Lock()
-	t.cachedBalance = result
-	t.balanceCacheTime = time.Now()
-	t.balanceCacheMutex.Unlock()
-
 	return result, nil
 }
 
 // GetPositions 获取所有持仓（带缓存）
 func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
-	// 先检查缓存是否有效
-	t.positionsCacheMutex.RLock()
-	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
-		cacheAge := time.Since(t.positionsCacheTime)
-		t.positionsCacheMutex.RUnlock()
-		log.Printf("✓ 使用缓存的持仓信息（缓存时间: %.1f秒前）", cacheAge.Seconds())
-		return t.cachedPositions, nil
-	}
-	t.positionsCacheMutex.RUnlock()
+	return t.positionsCache.GetOrLoad(t.fetchPositions)
+}
 
-	// 缓存过期或不存在，调用API
+// fetchPositions 调用API获取所有持仓
+func (t *FuturesTrader) fetchPositions() ([]map[string]interface{}, error) {
 	log.Printf("🔄 缓存过期，正在调用币安API获取持仓信息...")
 	positions, err := t.client.NewGetPositionRiskService().Do(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("获取持仓失败: %w", err)
+		return nil, fmt.Errorf("获取持仓失败: %w", binanceError(err))
 	}
 
 	var result []map[string]interface{}
@@ -129,46 +153,48 @@ func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
 		result = append(result, posMap)
 	}
 
-	// 更新缓存
-	t.positionsCacheMutex.Lock()
-	t.cachedPositions = result
-	t.positionsCacheTime = time.Now()
-	t.positionsCacheMutex.Unlock()
-
 	return result, nil
 }
 
-// SetLeverage 设置杠杆（智能判断+冷却期）
-func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
-	// 先尝试获取当前杠杆（从持仓信息）
-	currentLeverage := 0
-	positions, err := t.GetPositions()
-	if err == nil {
-		for _, pos := range positions {
-			if pos["symbol"] == symbol {
-				if lev, ok := pos["leverage"].(float64); ok {
-					currentLeverage = int(lev)
-					break
-				}
-			}
+// GetAccountSnapshot 同时获取余额和持仓（两个缓存各自过期，快照时都绕过缓存）
+func (t *FuturesTrader) GetAccountSnapshot() (map[string]interface{}, []map[string]interface{}, error) {
+	t.balanceCache.Invalidate()
+	t.positionsCache.Invalidate()
+	return fetchBalanceAndPositions(t.GetBalance, t.GetPositions)
+}
+
+// leverageSettings 币种当前的杠杆和保证金模式（isolated/cross，无持仓时交易所同样返回）
+func (t *FuturesTrader) leverageSettings(symbol string) (int, string, error) {
+	risks, err := t.client.NewGetPositionRiskService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return 0, "", binanceError(err)
+	}
+	for _, risk := range risks {
+		if risk.Symbol == symbol {
+			leverage, _ := strconv.Atoi(risk.Leverage)
+			return leverage, strings.ToLower(risk.MarginType), nil
 		}
 	}
+	return 0, "", fmt.Errorf("%s 没有杠杆信息", symbol)
+}
 
-	// 如果当前杠杆已经是目标杠杆，跳过
-	if currentLeverage == leverage && currentLeverage > 0 {
+// SetLeverage 设置杠杆（当前已是目标杠杆时跳过，避免每次开仓都调用并等待冷却期）
+func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
+	// 先查询当前杠杆（查询失败时直接切换）
+	if current, _, err := t.leverageSettings(symbol); err == nil && current == leverage {
 		log.Printf("  ✓ %s 杠杆已是 %dx，无需切换", symbol, leverage)
 		return nil
 	}
 
 	// 切换杠杆
-	_, err = t.client.NewChangeLeverageService().
+	_, err := t.client.NewChangeLeverageService().
 		Symbol(symbol).
 		Leverage(leverage).
 		Do(context.Background())
 
 	if err != nil {
-		// 如果错误信息包含"No need to change"，说明杠杆已经是目标值
-		if contains(err.Error(), "No need to change") {
+		// 交易所返回"无需修改"，说明杠杆已经是目标值
+		if err = binanceError(err); isNotModified(err) {
 			log.Printf("  ✓ %s 杠杆已是 %dx", symbol, leverage)
 			return nil
 		}
@@ -177,15 +203,20 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 
 	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
 
-	// 切换杠杆后等待5秒（避免冷却期错误）
-	log.Printf("  ⏱ 等待5秒冷却期...")
-	time.Sleep(5 * time.Second)
+	// 切换杠杆后等待冷却期
+	log.Printf("  ⏱ 等待%v冷却期...", leverageCooldown)
+	time.Sleep(leverageCooldown)
 
 	return nil
 }
 
-// SetMarginType 设置保证金模式
+// SetMarginType 设置保证金模式（当前已是目标模式时跳过）
 func (t *FuturesTrader) SetMarginType(symbol string, marginType futures.MarginType) error {
+	if _, current, err := t.leverageSettings(symbol); err == nil && current == strings.ToLower(string(marginType)) {
+		log.Printf("  ✓ %s 保证金模式已是 %s", symbol, marginType)
+		return nil
+	}
+
 	err := t.client.NewChangeMarginTypeService().
 		Symbol(symbol).
 		MarginType(marginType).
@@ -193,7 +224,7 @@ func (t *FuturesTrader) SetMarginType(symbol string, marginType futures.MarginTy
 
 	if err != nil {
 		// 如果已经是该模式，不算错误
-		if contains(err.Error(), "No need to change") {
+		if err = binanceError(err); isNotModified(err) {
 			log.Printf("  ✓ %s 保证金模式已是 %s", symbol, marginType)
 			return nil
 		}
@@ -202,15 +233,20 @@ func (t *FuturesTrader) SetMarginType(symbol string, marginType futures.MarginTy
 
 	log.Printf("  ✓ %s 保证金模式已切换为 %s", symbol, marginType)
 
-	// 切换保证金模式后等待3秒（避免冷却期错误）
-	log.Printf("  ⏱ 等待3秒冷却期...")
-	time.Sleep(3 * time.Second)
+	// 切换保证金模式后等待冷却期
+	log.Printf("  ⏱ 等待%v冷却期...", marginTypeCooldown)
+	time.Sleep(marginTypeCooldown)
 
 	return nil
 }
 
 // OpenLong 开多仓
-func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -232,17 +268,19 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 		return nil, err
 	}
 
-	// 创建市价买入订单
-	order, err := t.client.NewCreateOrderService().
+	// 创建买入订单（按本次下单类型）
+	svc := t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(futures.SideTypeBuy).
 		PositionSide(futures.PositionSideTypeLong).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		Do(context.Background())
+		Quantity(quantityStr)
+	if err := t.applyOrderType(svc, symbol, true, orderType); err != nil {
+		return nil, err
+	}
+	order, err := svc.Do(context.Background())
 
 	if err != nil {
-		return nil, fmt.Errorf("开多仓失败: %w", err)
+		return nil, fmt.Errorf("开多仓失败: %w", binanceError(err))
 	}
 
 	log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
@@ -252,11 +290,20 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 	result["orderId"] = order.OrderID
 	result["symbol"] = order.Symbol
 	result["status"] = order.Status
+	result["price"] = order.Price
+	result["avgPrice"] = order.AvgPrice
+	result["origQty"] = order.OrigQuantity
+	result["executedQty"] = order.ExecutedQuantity
 	return result, nil
 }
 
 // OpenShort 开空仓
-func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -278,17 +325,19 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 		return nil, err
 	}
 
-	// 创建市价卖出订单
-	order, err := t.client.NewCreateOrderService().
+	// 创建卖出订单（按本次下单类型）
+	svc := t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(futures.SideTypeSell).
 		PositionSide(futures.PositionSideTypeShort).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		Do(context.Background())
+		Quantity(quantityStr)
+	if err := t.applyOrderType(svc, symbol, false, orderType); err != nil {
+		return nil, err
+	}
+	order, err := svc.Do(context.Background())
 
 	if err != nil {
-		return nil, fmt.Errorf("开空仓失败: %w", err)
+		return nil, fmt.Errorf("开空仓失败: %w", binanceError(err))
 	}
 
 	log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
@@ -298,11 +347,33 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 	result["orderId"] = order.OrderID
 	result["symbol"] = order.Symbol
 	result["status"] = order.Status
+	result["price"] = order.Price
+	result["avgPrice"] = order.AvgPrice
+	result["origQty"] = order.OrigQuantity
+	result["executedQty"] = order.ExecutedQuantity
 	return result, nil
 }
 
+// GetOrderFill 查询订单的成交情况
+func (t *FuturesTrader) GetOrderFill(symbol string, order map[string]interface{}) (*OrderFill, error) {
+	orderID, ok := order["orderId"].(int64)
+	if !ok {
+		return nil, fmt.Errorf("下单结果中没有订单ID")
+	}
+	o, err := t.client.NewGetOrderService().Symbol(symbol).OrderID(orderID).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", binanceError(err))
+	}
+	return binanceOrderFill(string(o.Status), o.OrigQuantity, o.ExecutedQuantity, o.AvgPrice, 0), nil
+}
+
 // CloseLong 平多仓
-func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) CloseLong(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -328,17 +399,19 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 		return nil, err
 	}
 
-	// 创建市价卖出订单（平多）
-	order, err := t.client.NewCreateOrderService().
+	// 创建卖出订单（平多）（按本次下单类型）
+	svc := t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(futures.SideTypeSell).
 		PositionSide(futures.PositionSideTypeLong).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		Do(context.Background())
+		Quantity(quantityStr)
+	if err := t.applyOrderType(svc, symbol, false, orderType); err != nil {
+		return nil, err
+	}
+	order, err := svc.Do(context.Background())
 
 	if err != nil {
-		return nil, fmt.Errorf("平多仓失败: %w", err)
+		return nil, fmt.Errorf("平多仓失败: %w", binanceError(err))
 	}
 
 	log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, quantityStr)
@@ -352,11 +425,18 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 	result["orderId"] = order.OrderID
 	result["symbol"] = order.Symbol
 	result["status"] = order.Status
+	result["price"] = order.Price
+	result["avgPrice"] = order.AvgPrice
 	return result, nil
 }
 
 // CloseShort 平空仓
-func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) CloseShort(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -382,17 +462,19 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 		return nil, err
 	}
 
-	// 创建市价买入订单（平空）
-	order, err := t.client.NewCreateOrderService().
+	// 创建买入订单（平空）（按本次下单类型）
+	svc := t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(futures.SideTypeBuy).
 		PositionSide(futures.PositionSideTypeShort).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		Do(context.Background())
+		Quantity(quantityStr)
+	if err := t.applyOrderType(svc, symbol, true, orderType); err != nil {
+		return nil, err
+	}
+	order, err := svc.Do(context.Background())
 
 	if err != nil {
-		return nil, fmt.Errorf("平空仓失败: %w", err)
+		return nil, fmt.Errorf("平空仓失败: %w", binanceError(err))
 	}
 
 	log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, quantityStr)
@@ -406,9 +488,38 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 	result["orderId"] = order.OrderID
 	result["symbol"] = order.Symbol
 	result["status"] = order.Status
+	result["price"] = order.Price
+	result["avgPrice"] = order.AvgPrice
 	return result, nil
 }
 
+// applyOrderType 按下单类型设置订单类型、限价和有效方式
+func (t *FuturesTrader) applyOrderType(svc *futures.CreateOrderService, symbol string, isBuy bool, orderType OrderType) error {
+	if orderType == OrderTypeMarket {
+		svc.Type(futures.OrderTypeMarket)
+		return nil
+	}
+
+	price, err := t.GetMarketPrice(symbol)
+	if err != nil {
+		return err
+	}
+	priceStr, err := t.FormatPrice(symbol, limitPriceFor(price, isBuy, orderType))
+	if err != nil {
+		return fmt.Errorf("格式化限价失败: %w", err)
+	}
+
+	timeInForce := futures.TimeInForceTypeIOC
+	switch orderType {
+	case OrderTypeFOK:
+		timeInForce = futures.TimeInForceTypeFOK
+	case OrderTypePostOnly:
+		timeInForce = futures.TimeInForceTypeGTX
+	}
+	svc.Type(futures.OrderTypeLimit).Price(priceStr).TimeInForce(timeInForce)
+	return nil
+}
+
 // CancelAllOrders 取消该币种的所有挂单
 func (t *FuturesTrader) CancelAllOrders(symbol string) error {
 	err := t.client.NewCancelAllOpenOrdersService().
@@ -416,18 +527,72 @@ func (t *FuturesTrader) CancelAllOrders(symbol string) error {
 		Do(context.Background())
 
 	if err != nil {
-		return fmt.Errorf("取消挂单失败: %w", err)
+		return fmt.Errorf("取消挂单失败: %w", binanceError(err))
 	}
 
 	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
 	return nil
 }
 
+// GetProtectionOrders 获取该币种未触发的止损止盈单（STOP_MARKET / TAKE_PROFIT_MARKET，实现 ProtectionOrderManager）
+func (t *FuturesTrader) GetProtectionOrders(symbol string) ([]ProtectionOrder, error) {
+	orders, err := t.client.NewListOpenOrdersService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", binanceError(err))
+	}
+
+	var result []ProtectionOrder
+	for _, order := range orders {
+		var kind string
+		switch order.Type {
+		case futures.OrderTypeStopMarket:
+			kind = "stop_loss"
+		case futures.OrderTypeTakeProfitMarket:
+			kind = "take_profit"
+		default:
+			continue
+		}
+		// 双向持仓按 positionSide，单向持仓（BOTH）卖单保护多仓
+		side := "long"
+		if order.PositionSide == futures.PositionSideTypeShort ||
+			(order.PositionSide != futures.PositionSideTypeLong && order.Side == futures.SideTypeBuy) {
+			side = "short"
+		}
+		price, _ := strconv.ParseFloat(order.StopPrice, 64)
+		quantity, _ := strconv.ParseFloat(order.OrigQuantity, 64)
+		if order.ClosePosition {
+			quantity = 0
+		}
+		result = append(result, ProtectionOrder{
+			ID:       strconv.FormatInt(order.OrderID, 10),
+			Symbol:   symbol,
+			Side:     side,
+			Kind:     kind,
+			Price:    price,
+			Quantity: quantity,
+		})
+	}
+	return result, nil
+}
+
+// CancelProtectionOrder 撤销一个止损止盈单（实现 ProtectionOrderManager）
+func (t *FuturesTrader) CancelProtectionOrder(symbol, id string) error {
+	orderID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID %q", id)
+	}
+	if _, err := t.client.NewCancelOrderService().Symbol(symbol).OrderID(orderID).Do(context.Background()); err != nil {
+		return fmt.Errorf("取消订单失败: %w", binanceError(err))
+	}
+	log.Printf("  ✓ 已取消 %s 订单 %s", symbol, id)
+	return nil
+}
+
 // GetMarketPrice 获取市场价格
 func (t *FuturesTrader) GetMarketPrice(symbol string) (float64, error) {
 	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(context.Background())
 	if err != nil {
-		return 0, fmt.Errorf("获取价格失败: %w", err)
+		return 0, fmt.Errorf("获取价格失败: %w", binanceError(err))
 	}
 
 	if len(prices) == 0 {
@@ -474,7 +639,7 @@ func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity
 		return fmt.Errorf("格式化止损价格失败: %w", err)
 	}
 
-	_, err = t.client.NewCreateOrderService().
+	service := t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(side).
 		PositionSide(posSide).
@@ -482,11 +647,14 @@ func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity
 		StopPrice(stopPriceStr).
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
-		ClosePosition(true).
-		Do(context.Background())
+		ClosePosition(true)
+	if clientID := t.protectionClientID(symbol, positionSide, "sl"); clientID != "" {
+		service = service.NewClientOrderID(clientID)
+	}
+	_, err = service.Do(context.Background())
 
 	if err != nil {
-		return fmt.Errorf("设置止损失败: %w", err)
+		return fmt.Errorf("设置止损失败: %w", binanceError(err))
 	}
 
 	log.Printf("  止损价设置: %s", stopPriceStr)
@@ -517,7 +685,7 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 		return fmt.Errorf("格式化止盈价格失败: %w", err)
 	}
 
-	_, err = t.client.NewCreateOrderService().
+	service := t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(side).
 		PositionSide(posSide).
@@ -525,11 +693,14 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 		StopPrice(takeProfitPriceStr).
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
-		ClosePosition(true).
-		Do(context.Background())
+		ClosePosition(true)
+	if clientID := t.protectionClientID(symbol, positionSide, "tp"); clientID != "" {
+		service = service.NewClientOrderID(clientID)
+	}
+	_, err = service.Do(context.Background())
 
 	if err != nil {
-		return fmt.Errorf("设置止盈失败: %w", err)
+		return fmt.Errorf("设置止盈失败: %w", binanceError(err))
 	}
 
 	log.Printf("  止盈价设置: %s", takeProfitPriceStr)
@@ -540,7 +711,7 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
 	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
 	if err != nil {
-		return 0, fmt.Errorf("获取交易规则失败: %w", err)
+		return 0, fmt.Errorf("获取交易规则失败: %w", binanceError(err))
 	}
 
 	for _, s := range exchangeInfo.Symbols {
@@ -565,7 +736,7 @@ func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
 func (t *FuturesTrader) GetPricePrecision(symbol string) (int, error) {
 	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
 	if err != nil {
-		return 0, fmt.Errorf("获取交易规则失败: %w", err)
+		return 0, fmt.Errorf("获取交易规则失败: %w", binanceError(err))
 	}
 
 	for _, s := range exchangeInfo.Symbols {
@@ -587,6 +758,42 @@ func (t *FuturesTrader) GetPricePrecision(symbol string) (int, error) {
 	return 1, nil // 默认精度为1（对于BTC/ETH等大额币种）
 }
 
+// RoundPrice 将价格取整到交易对的tick size（PRICE_FILTER）
+func (t *FuturesTrader) RoundPrice(symbol string, price float64) (float64, error) {
+	tickSizes, err := t.tickSizes.GetOrLoad(t.loadTickSizes)
+	if err != nil {
+		return 0, err
+	}
+	tick, ok := tickSizes[symbol]
+	if !ok {
+		return 0, fmt.Errorf("%s 未找到价格精度信息", symbol)
+	}
+	return roundToTick(price, tick), nil
+}
+
+// loadTickSizes 从交易规则读取所有交易对的tick size
+func (t *FuturesTrader) loadTickSizes() (map[string]float64, error) {
+	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则失败: %w", binanceError(err))
+	}
+
+	tickSizes := make(map[string]float64, len(exchangeInfo.Symbols))
+	for _, s := range exchangeInfo.Symbols {
+		for _, filter := range s.Filters {
+			if filter["filterType"] != "PRICE_FILTER" {
+				continue
+			}
+			if tickSizeStr, ok := filter["tickSize"].(string); ok {
+				if tick, err := strconv.ParseFloat(tickSizeStr, 64); err == nil && tick > 0 {
+					tickSizes[s.Symbol] = tick
+				}
+			}
+		}
+	}
+	return tickSizes, nil
+}
+
 // calculatePrecision 从stepSize计算精度
 func calculatePrecision(stepSize string) int {
 	// 去除尾部的0
@@ -654,11 +861,184 @@ func (t *FuturesTrader) FormatPrice(symbol string, price float64) (string, error
 	return fmt.Sprintf(format, price), nil
 }
 
-// 辅助函数
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && stringContains(s, substr)
+// GetFundingPayments 获取资金费流水（收入历史中的 FUNDING_FEE）
+func (t *FuturesTrader) GetFundingPayments(since time.Time) ([]FundingPayment, error) {
+	incomes, err := t.client.NewGetIncomeHistoryService().
+		IncomeType("FUNDING_FEE").
+		StartTime(since.UnixMilli()).
+		Limit(1000).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费流水失败: %w", binanceError(err))
+	}
+
+	payments := make([]FundingPayment, 0, len(incomes))
+	for _, income := range incomes {
+		amount, _ := strconv.ParseFloat(income.Income, 64)
+		payments = append(payments, FundingPayment{
+			ID:     strconv.FormatInt(income.TranID, 10),
+			Symbol: income.Symbol,
+			Amount: amount,
+			Time:   time.UnixMilli(income.Time),
+		})
+	}
+	return payments, nil
+}
+
+// GetRealizedPnL 获取已实现盈亏流水（收入历史中的 REALIZED_PNL）
+func (t *FuturesTrader) GetRealizedPnL(since, until time.Time) ([]RealizedPnLRecord, error) {
+	var records []RealizedPnLRecord
+	for start := since.UnixMilli(); start < until.UnixMilli(); {
+		incomes, err := t.client.NewGetIncomeHistoryService().
+			IncomeType("REALIZED_PNL").
+			StartTime(start).
+			EndTime(until.UnixMilli() - 1).
+			Limit(1000).
+			Do(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("获取盈亏流水失败: %w", binanceError(err))
+		}
+		for _, income := range incomes {
+			amount, _ := strconv.ParseFloat(income.Income, 64)
+			records = append(records, RealizedPnLRecord{
+				Symbol: income.Symbol,
+				Amount: amount,
+				Time:   time.UnixMilli(income.Time),
+			})
+		}
+		if len(incomes) < 1000 {
+			break
+		}
+		start = incomes[len(incomes)-1].Time + 1
+	}
+	return records, nil
+}
+
+// binanceTradeWindow 成交历史接口单次查询的最长时间范围
+const binanceTradeWindow = 7 * 24 * time.Hour
+
+// GetTradeHistory 获取成交历史：成交接口必须指定币种，先从收入历史的手续费记录（每笔成交都有）找出交易过的币种，
+// 再按币种、每次最多7天查询成交
+func (t *FuturesTrader) GetTradeHistory(since, until time.Time) ([]HistoricalFill, error) {
+	symbols := make(map[string]bool)
+	for start := since.UnixMilli(); start < until.UnixMilli(); {
+		incomes, err := t.client.NewGetIncomeHistoryService().
+			IncomeType("COMMISSION").
+			StartTime(start).
+			EndTime(until.UnixMilli() - 1).
+			Limit(1000).
+			Do(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("获取手续费流水失败: %w", binanceError(err))
+		}
+		for _, income := range incomes {
+			if income.Symbol != "" {
+				symbols[income.Symbol] = true
+			}
+		}
+		if len(incomes) < 1000 {
+			break
+		}
+		start = incomes[len(incomes)-1].Time + 1
+	}
+
+	var fills []HistoricalFill
+	for symbol := range symbols {
+		for windowStart := since; windowStart.Before(until); windowStart = windowStart.Add(binanceTradeWindow) {
+			windowEnd := windowStart.Add(binanceTradeWindow)
+			if windowEnd.After(until) {
+				windowEnd = until
+			}
+			for start := windowStart.UnixMilli(); start < windowEnd.UnixMilli(); {
+				trades, err := t.client.NewListAccountTradeService().
+					Symbol(symbol).
+					StartTime(start).
+					EndTime(windowEnd.UnixMilli() - 1).
+					Limit(1000).
+					Do(context.Background())
+				if err != nil {
+					return nil, fmt.Errorf("获取 %s 成交历史失败: %w", symbol, binanceError(err))
+				}
+				for _, trade := range trades {
+					qty, _ := strconv.ParseFloat(trade.Quantity, 64)
+					price, _ := strconv.ParseFloat(trade.Price, 64)
+					fill := HistoricalFill{
+						ID:       strconv.FormatInt(trade.ID, 10),
+						Symbol:   trade.Symbol,
+						Side:     "buy",
+						Quantity: qty,
+						Price:    price,
+						Time:     time.UnixMilli(trade.Time),
+					}
+					if trade.Side == futures.SideTypeSell {
+						fill.Side = "sell"
+					}
+					switch trade.PositionSide {
+					case futures.PositionSideTypeLong:
+						fill.PositionSide = "long"
+					case futures.PositionSideTypeShort:
+						fill.PositionSide = "short"
+					}
+					// 有已实现盈亏的成交是平仓成交（单向持仓反手时只有一部分是平仓，按全部计）
+					if pnl, _ := strconv.ParseFloat(trade.RealizedPnl, 64); pnl != 0 {
+						fill.ClosedQty = qty
+					}
+					fills = append(fills, fill)
+				}
+				if len(trades) < 1000 {
+					break
+				}
+				start = trades[len(trades)-1].Time + 1
+			}
+		}
+	}
+	return fills, nil
+}
+
+// ListInstruments 获取稳定币（USDT/USDC等）永续合约列表
+// 计划下架的永续合约 deliveryDate 会被设置为下架时间（正常永续合约是2100年的占位值）
+func (t *FuturesTrader) ListInstruments() ([]Instrument, error) {
+	info, err := t.client.NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取交易所信息失败: %w", binanceError(err))
+	}
+
+	now := time.Now()
+	instruments := make([]Instrument, 0, len(info.Symbols))
+	for _, s := range info.Symbols {
+		if s.ContractType != futures.ContractTypePerpetual || !market.IsStableQuote(s.QuoteAsset) {
+			continue
+		}
+		instruments = append(instruments, binanceStyleInstrument(s.Symbol, s.Status, s.OnboardDate, s.DeliveryDate, now))
+	}
+	return instruments, nil
+}
+
+// binanceStyleInstrument 解析币安格式的合约状态（Aster与币安相同）
+func binanceStyleInstrument(symbol, status string, onboardDate, deliveryDate int64, now time.Time) Instrument {
+	inst := Instrument{Symbol: symbol, Status: InstrumentTrading}
+	if onboardDate > 0 {
+		inst.ListedAt = time.UnixMilli(onboardDate)
+	}
+	switch status {
+	case "PENDING_TRADING":
+		inst.Status = InstrumentPending
+	case "SETTLING", "CLOSE", "DELIVERING", "DELIVERED", "PRE_DELIVERING", "PRE_SETTLE":
+		inst.Status = InstrumentDelisted
+	}
+	if deliveryDate > 0 {
+		delistAt := time.UnixMilli(deliveryDate)
+		if delistAt.Sub(now) < permanentDeliveryCutoff {
+			inst.DelistAt = delistAt
+			if inst.Status == InstrumentTrading {
+				inst.Status = InstrumentDelisting
+			}
+		}
+	}
+	return inst
 }
 
+// 辅助函数
 func stringContains(s, substr string) bool {
 	for i := 0; i <= len(s)-len(substr); i++ {
 		if s[i:i+len(substr)] == substr {
@@ -667,3 +1047,53 @@ func stringContains(s, substr string) bool {
 	}
 	return false
 }
+
+// SetProxy 之后的请求经过出口代理（保留限频层）
+func (t *FuturesTrader) SetProxy(proxyURL *url.URL) {
+	t.client.HTTPClient.Transport = withProxy(t.client.HTTPClient.Transport, proxyURL)
+}
+
+// GetAccountPermissions 查询密钥权限（仅正式环境可查）、持仓模式、手续费等级和费率
+func (t *FuturesTrader) GetAccountPermissions() (*AccountPermissions, error) {
+	ctx := context.Background()
+	account, err := t.client.NewGetAccountService().Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取账户信息失败: %w", binanceError(err))
+	}
+	mode, err := t.client.NewGetPositionModeService().Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓模式失败: %w", binanceError(err))
+	}
+
+	perms := &AccountPermissions{
+		CanTrade:     account.CanTrade,
+		PositionMode: PositionModeOneWay,
+		RequiredMode: PositionModeHedge, // 开平仓都带 positionSide LONG/SHORT
+		Tier:         fmt.Sprintf("VIP %d", account.FeeTier),
+	}
+	if mode.DualSidePosition {
+		perms.PositionMode = PositionModeHedge
+	}
+
+	if rates, err := t.client.NewCommissionRateService().Symbol("BTCUSDT").Do(ctx); err != nil {
+		log.Printf("⚠️ 获取币安手续费率失败: %v", binanceError(err))
+	} else {
+		perms.MakerFeeRate, _ = strconv.ParseFloat(rates.MakerCommissionRate, 64)
+		perms.TakerFeeRate, _ = strconv.ParseFloat(rates.TakerCommissionRate, 64)
+		perms.FeesKnown = true
+	}
+
+	// 密钥权限在现货域名的 /sapi 接口，测试网没有
+	if t.client.BaseURL == futures.BaseApiMainUrl {
+		spot := binance.NewClient(t.client.APIKey, t.client.SecretKey)
+		spot.HTTPClient = &http.Client{Transport: t.client.HTTPClient.Transport}
+		if restrictions, err := spot.NewGetAPIKeyPermission().Do(ctx); err != nil {
+			log.Printf("⚠️ 获取币安API密钥权限失败: %v", binanceError(err))
+		} else {
+			perms.CanTrade = perms.CanTrade && restrictions.EnableFutures
+			perms.CanWithdraw = restrictions.EnableWithdrawals
+			perms.WithdrawKnown = true
+		}
+	}
+	return perms, nil
+}
@@ -0,0 +1,500 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 资金费套利策略（strategy: "carry"）
+// 不调用AI：在资金费率差足够大的币种上做Delta中性组合——费率高的交易所开空、费率低的交易所开多，
+// 两腿数量相同，价格涨跌互相抵消，收取两边的费率之差；费率差收敛到退出阈值以下（或反向）时两腿同时平仓。
+// 交易器接口只有永续合约，没有现货，因此不支持「现货多 + 永续空」，对冲腿使用另一家交易所的永续合约。
+// 两腿包装为一个组合交易器（carryPair）作为trader的交易器，净值、持仓、风控暂停、降风险阶梯和下架平仓照常生效。
+
+const (
+	defaultCarryEntrySpreadPct = 0.03 // 每8小时 0.03%（年化约33%）
+	defaultCarryExitSpreadPct  = 0.005
+	defaultCarryLeverage       = 2
+	defaultCarryMaxPositions   = 3
+	carryRateInterval          = 8 * time.Hour // 费率统一换算为每8小时
+)
+
+// CarryConfig 资金费套利配置（费率差为百分比，按每8小时换算）
+type CarryConfig struct {
+	HedgeExchange   string   // 对冲腿所在交易所（使用trader配置中该交易所的密钥）
+	Symbols         []string // 候选币种
+	EntrySpreadPct  float64  // 开仓的费率差阈值（默认0.03）
+	ExitSpreadPct   float64  // 平仓的费率差阈值（默认0.005）
+	PositionSizeUSD float64  // 每腿名义价值（USDT）
+	Leverage        int      // 两腿杠杆（默认2）
+	MaxPositions    int      // 同时持有的组合数（默认3）
+}
+
+// carryStrategy 资金费套利运行状态
+type carryStrategy struct {
+	config       CarryConfig
+	primary      Trader
+	hedge        Trader
+	primaryName  string
+	hedgeName    string
+	primaryRates market.MarketDataProvider // 查询资金费率
+	hedgeRates   market.MarketDataProvider
+}
+
+// newCarryStrategy 校验配置、补全默认值并创建对冲腿的交易器
+func newCarryStrategy(traderConfig AutoTraderConfig, primary Trader) (*carryStrategy, error) {
+	config, primaryName := traderConfig.Carry, traderConfig.Exchange
+	if config.HedgeExchange == "" || config.HedgeExchange == primaryName {
+		return nil, fmt.Errorf("carry.hedge_exchange必须是与exchange不同的交易所")
+	}
+	if len(config.Symbols) == 0 {
+		return nil, fmt.Errorf("carry.symbols不能为空")
+	}
+	if config.PositionSizeUSD <= 0 {
+		return nil, fmt.Errorf("carry.position_size_usd必须大于0")
+	}
+	if config.EntrySpreadPct <= 0 {
+		config.EntrySpreadPct = defaultCarryEntrySpreadPct
+	}
+	if config.ExitSpreadPct <= 0 {
+		config.ExitSpreadPct = defaultCarryExitSpreadPct
+	}
+	if config.ExitSpreadPct >= config.EntrySpreadPct {
+		return nil, fmt.Errorf("carry.exit_spread_pct(%.4f)必须小于entry_spread_pct(%.4f)", config.ExitSpreadPct, config.EntrySpreadPct)
+	}
+	if config.Leverage <= 0 {
+		config.Leverage = defaultCarryLeverage
+	}
+	if config.MaxPositions <= 0 {
+		config.MaxPositions = defaultCarryMaxPositions
+	}
+	symbols := make([]string, len(config.Symbols))
+	for i, symbol := range config.Symbols {
+		symbols[i] = market.Normalize(symbol)
+	}
+	config.Symbols = symbols
+
+	primaryRates, err := market.GetProvider(primaryName)
+	if err != nil {
+		return nil, fmt.Errorf("资金费套利需要 %s 的资金费率数据: %w", primaryName, err)
+	}
+	hedgeRates, err := market.GetProvider(config.HedgeExchange)
+	if err != nil {
+		return nil, fmt.Errorf("资金费套利需要 %s 的资金费率数据: %w", config.HedgeExchange, err)
+	}
+
+	hedge, err := newExchangeTrader(traderConfig, config.HedgeExchange)
+	if err != nil {
+		return nil, fmt.Errorf("初始化对冲腿失败: %w", err)
+	}
+	if traderConfig.ProxyURL != "" {
+		if err := applyProxy(hedge, config.HedgeExchange, traderConfig.ProxyURL); err != nil {
+			return nil, fmt.Errorf("代理配置错误: %w", err)
+		}
+	}
+
+	return &carryStrategy{
+		config:       config,
+		primary:      primary,
+		hedge:        hedge,
+		primaryName:  primaryName,
+		hedgeName:    config.HedgeExchange,
+		primaryRates: primaryRates,
+		hedgeRates:   hedgeRates,
+	}, nil
+}
+
+// rates 两边每8小时的资金费率（百分比）
+func (c *carryStrategy) rates(symbol string) (primary, hedge float64, err error) {
+	p, err := c.primaryRates.GetFundingRate(symbol)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s 资金费率: %w", c.primaryName, err)
+	}
+	h, err := c.hedgeRates.GetFundingRate(symbol)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s 资金费率: %w", c.hedgeName, err)
+	}
+	per8h := func(rate float64, exchange string) float64 {
+		return rate * 100 * float64(carryRateInterval) / float64(fundingIntervalFor(exchange))
+	}
+	return per8h(p, c.primaryName), per8h(h, c.hedgeName), nil
+}
+
+// legSides 各币种在一条腿上的持仓方向
+func legSides(t Trader) (map[string]string, error) {
+	positions, err := t.GetPositions()
+	if err != nil {
+		return nil, err
+	}
+	sides := make(map[string]string)
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		sides[symbol] = side
+	}
+	return sides, nil
+}
+
+// runCarry 资金费套利周期：平掉费率差收敛的组合和单腿持仓，再按费率差开新组合（替代AI决策）
+func (at *AutoTrader) runCarry(ctx *decision.Context, record *logger.DecisionRecord) {
+	c := at.carry
+	primarySides, err := legSides(c.primary)
+	if err != nil {
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("获取 %s 持仓失败: %v", c.primaryName, err)
+		return
+	}
+	hedgeSides, err := legSides(c.hedge)
+	if err != nil {
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("获取 %s 持仓失败: %v", c.hedgeName, err)
+		return
+	}
+
+	// 配置的币种加上仍有持仓的币种（从候选中移除后也要能平仓）
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, symbol := range c.config.Symbols {
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	var extra []string
+	for _, sides := range []map[string]string{primarySides, hedgeSides} {
+		for symbol := range sides {
+			if !seen[symbol] {
+				seen[symbol] = true
+				extra = append(extra, symbol)
+			}
+		}
+	}
+	sort.Strings(extra)
+	symbols = append(symbols, extra...)
+
+	open := 0
+	for _, symbol := range symbols {
+		if primarySides[symbol] != "" && hedgeSides[symbol] != "" {
+			open++
+		}
+	}
+
+	for _, symbol := range symbols {
+		primarySide, hedgeSide := primarySides[symbol], hedgeSides[symbol]
+		if primarySide != "" && hedgeSide == "" || primarySide == "" && hedgeSide != "" {
+			// 单腿持仓（另一条腿被强平/下架平仓或上次开仓未完成）不再对冲，直接平掉
+			at.closeCarryLegs(record, symbol, primarySide, hedgeSide, "单腿持仓，已失去对冲")
+			continue
+		}
+
+		primaryRate, hedgeRate, err := c.rates(symbol)
+		if err != nil {
+			log.Printf("⚠️  %s %v", symbol, err)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ %s 获取资金费率失败: %v", symbol, err))
+			continue
+		}
+		spread := primaryRate - hedgeRate
+		log.Printf("💱 %s 资金费率 %s %.4f%% / %s %.4f%%（每8小时），费率差 %+.4f%%",
+			symbol, c.primaryName, primaryRate, c.hedgeName, hedgeRate, spread)
+
+		if primarySide != "" {
+			// 组合收取的费率差：主腿为空时收 primary-hedge，为多时收 hedge-primary
+			captured := spread
+			if primarySide == "long" {
+				captured = -spread
+			}
+			if captured < c.config.ExitSpreadPct {
+				at.closeCarryLegs(record, symbol, primarySide, hedgeSide,
+					fmt.Sprintf("费率差收敛至 %.4f%%（退出阈值 %.4f%%）", captured, c.config.ExitSpreadPct))
+				open--
+			}
+			continue
+		}
+
+		if math.Abs(spread) < c.config.EntrySpreadPct {
+			continue
+		}
+		switch {
+		case ctx.CloseOnly:
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏸ %s 费率差 %.4f%% 达到开仓阈值，但当前只允许平仓", symbol, math.Abs(spread)))
+			continue
+		case !at.symbolFilter.Allowed(symbol) || !at.delistingFilter.Allowed(symbol):
+			continue
+		case open >= c.config.MaxPositions:
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏸ %s 费率差 %.4f%% 达到开仓阈值，但已持有 %d 个组合", symbol, math.Abs(spread), open))
+			continue
+		}
+
+		primarySide = "long"
+		if spread > 0 {
+			primarySide = "short" // 主腿费率更高，主腿开空收取资金费
+		}
+		if at.openCarryLegs(record, symbol, primarySide, math.Abs(spread)) {
+			open++
+		}
+	}
+}
+
+// openCarryLegs 开一个组合：先开主腿，对冲腿失败时回滚主腿
+func (at *AutoTrader) openCarryLegs(record *logger.DecisionRecord, symbol, primarySide string, spread float64) bool {
+	c := at.carry
+	action := logger.DecisionAction{
+		Action:    "open_carry",
+		Symbol:    symbol,
+		Leverage:  c.config.Leverage,
+		Timestamp: time.Now(),
+	}
+	defer func() { record.Decisions = append(record.Decisions, action) }()
+
+	fail := func(err error) bool {
+		action.Error = err.Error()
+		log.Printf("❌ %s 资金费套利开仓失败: %v", symbol, err)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s open_carry 失败: %v", symbol, err))
+		return false
+	}
+
+	price, err := c.primary.GetMarketPrice(symbol)
+	if err != nil {
+		return fail(fmt.Errorf("获取价格失败: %w", err))
+	}
+	action.Price = price
+
+	// 两腿数量取两边精度格式化后的较小值，保持Delta中性
+	quantity := c.config.PositionSizeUSD / price
+	for _, t := range []Trader{c.primary, c.hedge} {
+		formatted, err := t.FormatQuantity(symbol, quantity)
+		if err != nil {
+			return fail(fmt.Errorf("格式化数量失败: %w", err))
+		}
+		if q, err := strconv.ParseFloat(formatted, 64); err == nil && q < quantity {
+			quantity = q
+		}
+	}
+	if quantity <= 0 {
+		return fail(fmt.Errorf("仓位 %.2f USDT 低于最小下单数量", c.config.PositionSizeUSD))
+	}
+	action.Quantity = quantity
+
+	hedgeSide := oppositeSide(primarySide)
+	if _, err := openLeg(c.primary, primarySide, symbol, quantity, c.config.Leverage); err != nil {
+		return fail(fmt.Errorf("%s %s腿开仓失败: %w", c.primaryName, sideLabel(primarySide), err))
+	}
+	if _, err := openLeg(c.hedge, hedgeSide, symbol, quantity, c.config.Leverage); err != nil {
+		if _, rollbackErr := closeLeg(c.primary, primarySide, symbol); rollbackErr != nil {
+			return fail(fmt.Errorf("%s %s腿开仓失败: %w（回滚 %s 失败，需人工处理: %v）", c.hedgeName, sideLabel(hedgeSide), err, c.primaryName, rollbackErr))
+		}
+		return fail(fmt.Errorf("%s %s腿开仓失败，已回滚 %s: %w", c.hedgeName, sideLabel(hedgeSide), c.primaryName, err))
+	}
+
+	action.Success = true
+	log.Printf("✓ %s 资金费套利开仓: %s %s / %s %s %.6f，费率差 %.4f%%/8h",
+		symbol, c.primaryName, sideLabel(primarySide), c.hedgeName, sideLabel(hedgeSide), quantity, spread)
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s open_carry 成功: %s %s / %s %s %.6f，费率差 %.4f%%/8h",
+		symbol, c.primaryName, sideLabel(primarySide), c.hedgeName, sideLabel(hedgeSide), quantity, spread))
+	return true
+}
+
+// closeCarryLegs 平掉币种在两条腿上的持仓（方向为空表示该腿没有持仓）
+func (at *AutoTrader) closeCarryLegs(record *logger.DecisionRecord, symbol, primarySide, hedgeSide, reason string) {
+	c := at.carry
+	action := logger.DecisionAction{
+		Action:    "close_carry",
+		Symbol:    symbol,
+		Timestamp: time.Now(),
+		Success:   true,
+	}
+	if price, err := c.primary.GetMarketPrice(symbol); err == nil {
+		action.Price = price
+	}
+
+	var failures []string
+	for i, leg := range []carryLeg{{c.primaryName, c.primary}, {c.hedgeName, c.hedge}} {
+		side := []string{primarySide, hedgeSide}[i]
+		if side == "" {
+			continue
+		}
+		if _, err := closeLeg(leg.trader, side, symbol); err != nil {
+			failures = append(failures, fmt.Sprintf("%s %s腿: %v", leg.name, sideLabel(side), err))
+		}
+	}
+
+	if len(failures) > 0 {
+		action.Success = false
+		action.Error = strings.Join(failures, "; ")
+		log.Printf("❌ %s 资金费套利平仓失败（%s）: %s", symbol, reason, action.Error)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s close_carry 失败（%s）: %s", symbol, reason, action.Error))
+	} else {
+		log.Printf("✓ %s 资金费套利平仓: %s", symbol, reason)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s close_carry 成功: %s", symbol, reason))
+	}
+	record.Decisions = append(record.Decisions, action)
+}
+
+func openLeg(t Trader, side, symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if side == "long" {
+		return t.OpenLong(symbol, quantity, leverage, "")
+	}
+	return t.OpenShort(symbol, quantity, leverage, "")
+}
+
+func closeLeg(t Trader, side, symbol string) (map[string]interface{}, error) {
+	if side == "long" {
+		return t.CloseLong(symbol, 0, "")
+	}
+	return t.CloseShort(symbol, 0, "")
+}
+
+func oppositeSide(side string) string {
+	if side == "long" {
+		return "short"
+	}
+	return "long"
+}
+
+func sideLabel(side string) string {
+	if side == "long" {
+		return "多"
+	}
+	return "空"
+}
+
+// carryPair 资金费套利的两条腿组合为一个交易器：余额相加、持仓合并（带 exchange 字段），
+// 平仓按持仓所在的腿转发，风控（暂停、降风险清仓、下架平仓）因此对两条腿同时生效。
+// 开仓必须成对进行，只能由 runCarry 直接对两条腿下单。
+type carryPair struct {
+	primary, hedge         Trader
+	primaryName, hedgeName string
+}
+
+// carryLeg 组合中的一条腿
+type carryLeg struct {
+	name   string
+	trader Trader
+}
+
+func (p *carryPair) legs() []carryLeg {
+	return []carryLeg{{p.primaryName, p.primary}, {p.hedgeName, p.hedge}}
+}
+
+func (p *carryPair) GetBalance() (map[string]interface{}, error) {
+	total := map[string]interface{}{}
+	for _, leg := range p.legs() {
+		balance, err := leg.trader.GetBalance()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", leg.name, err)
+		}
+		for _, key := range []string{"totalWalletBalance", "totalUnrealizedProfit", "availableBalance"} {
+			v, _ := balance[key].(float64)
+			sum, _ := total[key].(float64)
+			total[key] = sum + v
+		}
+	}
+	return total, nil
+}
+
+func (p *carryPair) GetPositions() ([]map[string]interface{}, error) {
+	var all []map[string]interface{}
+	for _, leg := range p.legs() {
+		positions, err := leg.trader.GetPositions()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", leg.name, err)
+		}
+		for _, pos := range positions {
+			pos["exchange"] = leg.name
+			all = append(all, pos)
+		}
+	}
+	return all, nil
+}
+
+func (p *carryPair) OpenLong(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error) {
+	return nil, fmt.Errorf("资金费套利组合只能成对开仓")
+}
+
+func (p *carryPair) OpenShort(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error) {
+	return nil, fmt.Errorf("资金费套利组合只能成对开仓")
+}
+
+// legFor 持有该币种该方向仓位的腿
+func (p *carryPair) legFor(symbol, side string) (Trader, error) {
+	for _, leg := range p.legs() {
+		sides, err := legSides(leg.trader)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", leg.name, err)
+		}
+		if sides[symbol] == side {
+			return leg.trader, nil
+		}
+	}
+	return nil, fmt.Errorf("没有找到 %s %s仓", symbol, sideLabel(side))
+}
+
+func (p *carryPair) CloseLong(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error) {
+	t, err := p.legFor(symbol, "long")
+	if err != nil {
+		return nil, err
+	}
+	return t.CloseLong(symbol, quantity, orderType)
+}
+
+func (p *carryPair) CloseShort(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error) {
+	t, err := p.legFor(symbol, "short")
+	if err != nil {
+		return nil, err
+	}
+	return t.CloseShort(symbol, quantity, orderType)
+}
+
+func (p *carryPair) SetLeverage(symbol string, leverage int) error {
+	for _, leg := range p.legs() {
+		if err := leg.trader.SetLeverage(symbol, leverage); err != nil {
+			return fmt.Errorf("%s: %w", leg.name, err)
+		}
+	}
+	return nil
+}
+
+func (p *carryPair) GetMarketPrice(symbol string) (float64, error) {
+	return p.primary.GetMarketPrice(symbol)
+}
+
+// SetStopLoss 组合两腿互为对冲，不挂止损止盈
+func (p *carryPair) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	return fmt.Errorf("资金费套利组合不挂止损单")
+}
+
+func (p *carryPair) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	return fmt.Errorf("资金费套利组合不挂止盈单")
+}
+
+func (p *carryPair) CancelAllOrders(symbol string) error {
+	for _, leg := range p.legs() {
+		if err := leg.trader.CancelAllOrders(symbol); err != nil {
+			return fmt.Errorf("%s: %w", leg.name, err)
+		}
+	}
+	return nil
+}
+
+func (p *carryPair) FormatQuantity(symbol string, quantity float64) (string, error) {
+	return p.primary.FormatQuantity(symbol, quantity)
+}
+
+// SupportedOrderTypes 两条腿都支持的下单类型
+func (p *carryPair) SupportedOrderTypes() []OrderType {
+	var types []OrderType
+	for _, orderType := range p.primary.SupportedOrderTypes() {
+		if SupportsOrderType(p.hedge, orderType) {
+			types = append(types, orderType)
+		}
+	}
+	return types
+}
@@ -0,0 +1,119 @@
+package trader
+
+import (
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newCarryTrader 创建主腿Gate.io、对冲腿币安的资金费套利trader（两条腿和资金费率都指向模拟交易所）
+func newCarryTrader(t *testing.T, ex *mockExchange, ai *mockAI) *AutoTrader {
+	t.Helper()
+	market.RegisterProvider("gateio", market.NewGateioProviderWithBaseURL(ex.URL()+"/api/v4"))
+	t.Cleanup(func() { market.RegisterProvider("gateio", market.NewGateioProvider()) })
+
+	at, err := NewAutoTrader(AutoTraderConfig{
+		ID:               "it_carry",
+		Name:             "integration_carry",
+		AIModel:          "custom",
+		Exchange:         "gateio",
+		BinanceAPIKey:    "test-key",
+		BinanceSecretKey: "test-secret",
+		GateioAPIKey:     "test-key",
+		GateioSecretKey:  "test-secret",
+		CustomAPIURL:     ai.URL(),
+		CustomAPIKey:     "test-key",
+		CustomModelName:  "mock",
+		ScanInterval:     time.Minute,
+		InitialBalance:   10000,
+		Strategy:         StrategyCarry,
+		Carry: CarryConfig{
+			HedgeExchange:   "binance",
+			Symbols:         []string{"ETHUSDT"},
+			PositionSizeUSD: 1500,
+			Leverage:        2,
+		},
+	})
+	if err != nil {
+		t.Fatalf("创建AutoTrader失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join("decision_logs", at.id), 0755); err != nil {
+		t.Fatal(err)
+	}
+	pointAtMock(t, at.carry.primary, ex)
+	pointAtMock(t, at.carry.hedge, ex)
+	return at
+}
+
+func TestIntegrationCarryOpensAndClosesHedgedPair(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newCarryTrader(t, ex, ai)
+
+	// Gate.io 0.06% vs 币安 0.01%：费率差 0.05% 超过默认开仓阈值 0.03%
+	ex.SetFundingRate("gateio", "ETHUSDT", 0.0006)
+	ex.SetFundingRate("binance", "ETHUSDT", 0.0001)
+
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_carry")
+	if size := ex.GatePosition("ETHUSDT").size; size != -50 {
+		t.Fatalf("Gate.io 应开空 50 张（0.5 ETH），实际 %v", size)
+	}
+	if size := ex.BinancePosition("ETHUSDT", "LONG").size; size != 0.5 {
+		t.Fatalf("币安应开多 0.5 ETH，实际 %v", size)
+	}
+	if n := len(ai.Prompts()); n != 0 {
+		t.Fatalf("carry策略不应调用AI，实际调用 %d 次", n)
+	}
+
+	// 两条腿合并为trader的持仓
+	positions, err := at.GetPositions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 2 {
+		t.Fatalf("应有两条腿的持仓，实际 %d", len(positions))
+	}
+
+	// 费率差未收敛时继续持有
+	record = runCycle(t, at)
+	if len(record.Decisions) != 0 {
+		t.Fatalf("费率差仍在阈值之上，不应有动作: %+v", record.Decisions)
+	}
+
+	// 费率差收敛到退出阈值以下，两腿同时平仓
+	ex.SetFundingRate("gateio", "ETHUSDT", 0.00012)
+	record = runCycle(t, at)
+	requireActionSuccess(t, record, "close_carry")
+	if size := ex.GatePosition("ETHUSDT").size; size != 0 {
+		t.Fatalf("Gate.io 空仓应已平掉，剩余 %v", size)
+	}
+	if size := ex.BinancePosition("ETHUSDT", "LONG").size; size != 0 {
+		t.Fatalf("币安多仓应已平掉，剩余 %v", size)
+	}
+}
+
+func TestIntegrationCarryClosesOrphanLeg(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newCarryTrader(t, ex, ai)
+
+	// 币安费率更高：币安开空、Gate.io开多
+	ex.SetFundingRate("binance", "ETHUSDT", 0.0008)
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_carry")
+	if size := ex.GatePosition("ETHUSDT").size; size != 50 {
+		t.Fatalf("Gate.io 应开多 50 张，实际 %v", size)
+	}
+
+	// 对冲腿在系统外被平掉，剩下的单腿不再对冲，下个周期平掉
+	if _, err := at.carry.hedge.CloseShort("ETHUSDT", 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	record = runCycle(t, at)
+	requireActionSuccess(t, record, "close_carry")
+	requireExecutionLog(t, record.ExecutionLog, "单腿持仓")
+	if size := ex.GatePosition("ETHUSDT").size; size != 0 {
+		t.Fatalf("单腿持仓应已平掉，剩余 %v", size)
+	}
+}
@@ -0,0 +1,201 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"nofx/chart"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/notify"
+	"strings"
+)
+
+// 决策K线图
+// 画出决策时间前后的3分钟K线，并标注入场价、止损、止盈，方便事后查看AI当时依据的行情。
+// 通过API按决策ID查看（SVG/PNG），启用后开仓通知也会附带PNG图和查看链接。
+
+const (
+	chartInterval     = "3m"
+	chartKlinesBefore = 60 // 决策前3小时
+	chartKlinesAfter  = 40 // 决策后2小时（决策刚发生时只有已走出的K线）
+)
+
+// entryActions 有入场价的动作（默认绘制的币种、开仓通知）
+var entryActions = map[string]bool{
+	"open_long":       true,
+	"open_short":      true,
+	"add_to_position": true,
+}
+
+// tradeChartConfig 开仓通知附带K线图的配置
+type tradeChartConfig struct {
+	baseURL string // API地址（如 http://host:8080），用于生成图表链接；为空时不附带链接
+}
+
+// EnableTradeCharts 开仓通知附带K线图
+func (at *AutoTrader) EnableTradeCharts(baseURL string) {
+	at.tradeCharts = &tradeChartConfig{baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// DecisionChart 生成指定决策的K线图
+// symbol 为空时取该决策第一个开仓/加仓的币种，没有开仓时取第一条决策的币种
+func (at *AutoTrader) DecisionChart(decisionID, symbol string) (*chart.Chart, error) {
+	record, err := at.decisionLogger.GetRecord(decisionID)
+	if err != nil {
+		return nil, err
+	}
+	return at.recordChart(record, symbol)
+}
+
+// recordChart 根据决策记录生成K线图
+func (at *AutoTrader) recordChart(record *logger.DecisionRecord, symbol string) (*chart.Chart, error) {
+	var decisions []decision.Decision
+	if record.DecisionJSON != "" {
+		if err := json.Unmarshal([]byte(record.DecisionJSON), &decisions); err != nil {
+			return nil, fmt.Errorf("解析决策JSON失败: %w", err)
+		}
+	}
+	if symbol == "" {
+		symbol = chartSymbol(record, decisions)
+		if symbol == "" {
+			return nil, fmt.Errorf("决策 %s 没有涉及任何币种", record.DecisionID)
+		}
+	}
+
+	provider, err := market.ProviderFor(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取市场数据提供者失败: %w", err)
+	}
+	klines, err := market.GetKlinesAround(provider, symbol, chartInterval, record.Timestamp, chartKlinesBefore, chartKlinesAfter)
+	if err != nil {
+		return nil, fmt.Errorf("获取K线失败: %w", err)
+	}
+	if len(klines) == 0 {
+		return nil, fmt.Errorf("%s 在决策时间附近没有K线数据", symbol)
+	}
+
+	action := ""
+	var levels []chart.Level
+	for _, d := range decisions {
+		if d.Symbol != symbol || d.Action == "wait" || d.Action == "hold" {
+			continue
+		}
+		action = d.Action
+		levels = append(levels,
+			chart.Level{Label: "SL", Price: d.StopLoss, Color: chart.ColorStop},
+			chart.Level{Label: "TP", Price: d.TakeProfit, Color: chart.ColorTarget},
+		)
+		break
+	}
+	if entry := chartEntryPrice(record, symbol); entry > 0 {
+		levels = append(levels, chart.Level{Label: "Entry", Price: entry, Color: chart.ColorEntry})
+	}
+
+	title := symbol
+	if action != "" {
+		title += " " + action
+	}
+	return &chart.Chart{
+		Title:  fmt.Sprintf("%s · %s · %s", title, at.name, record.Timestamp.Format("2006-01-02 15:04")),
+		Klines: klines,
+		Levels: levels,
+		Marker: record.Timestamp,
+	}, nil
+}
+
+// chartSymbol 默认绘制的币种：优先开仓/加仓，其次第一条非观望决策
+func chartSymbol(record *logger.DecisionRecord, decisions []decision.Decision) string {
+	for _, a := range record.Decisions {
+		if entryActions[a.Action] {
+			return a.Symbol
+		}
+	}
+	for _, d := range decisions {
+		if d.Symbol != "" && d.Action != "wait" && d.Action != "hold" {
+			return d.Symbol
+		}
+	}
+	return ""
+}
+
+// chartEntryPrice 入场价：本次成交的开仓/加仓价格，没有时取决策前的持仓均价
+func chartEntryPrice(record *logger.DecisionRecord, symbol string) float64 {
+	for _, a := range record.Decisions {
+		if a.Symbol == symbol && a.Success && a.Price > 0 && entryActions[a.Action] {
+			return a.Price
+		}
+	}
+	for _, pos := range record.Positions {
+		if pos.Symbol == symbol {
+			return pos.EntryPrice
+		}
+	}
+	return 0
+}
+
+// chartLink 决策K线图的API链接
+func (at *AutoTrader) chartLink(decisionID, symbol string) string {
+	if at.tradeCharts == nil {
+		return ""
+	}
+	return decisionChartURL(at.tradeCharts.baseURL, at.id, decisionID, symbol)
+}
+
+// decisionChartURL 决策K线图的API链接（baseURL为空时返回空）
+func decisionChartURL(baseURL, traderID, decisionID, symbol string) string {
+	if baseURL == "" {
+		return ""
+	}
+	q := url.Values{}
+	q.Set("trader_id", traderID)
+	q.Set("decision_id", decisionID)
+	q.Set("symbol", symbol)
+	return baseURL + "/api/decisions/chart?" + q.Encode()
+}
+
+// notifyOpenedTrades 开仓/加仓成功后推送通知，附带决策K线图
+func (at *AutoTrader) notifyOpenedTrades(record *logger.DecisionRecord) {
+	if at.tradeCharts == nil {
+		return
+	}
+	var decisions []decision.Decision
+	json.Unmarshal([]byte(record.DecisionJSON), &decisions)
+
+	for _, a := range record.Decisions {
+		if !a.Success || !entryActions[a.Action] {
+			continue
+		}
+		event := notify.Event{
+			Type:     "trade.opened",
+			Severity: notify.SeverityInfo,
+			TraderID: at.id,
+			Symbol:   a.Symbol,
+			Title:    fmt.Sprintf("%s %s %s", at.name, a.Action, a.Symbol),
+			Message:  fmt.Sprintf("数量 %.4f @ %.4f，杠杆 %dx", a.Quantity, a.Price, a.Leverage),
+			Link:     at.chartLink(record.DecisionID, a.Symbol),
+		}
+		for _, d := range decisions {
+			if d.Symbol == a.Symbol && d.Action == a.Action {
+				if d.StopLoss > 0 || d.TakeProfit > 0 {
+					event.Message += fmt.Sprintf("\n止损 %.4f | 止盈 %.4f", d.StopLoss, d.TakeProfit)
+				}
+				if d.Reasoning != "" {
+					event.Message += "\n" + d.Reasoning
+				}
+				break
+			}
+		}
+
+		c, err := at.recordChart(record, a.Symbol)
+		if err == nil {
+			event.Image, err = c.PNG()
+		}
+		if err != nil {
+			log.Printf("⚠️ %s 生成K线图失败，通知不附带图片: %v", a.Symbol, err)
+		}
+		notify.Send(event)
+	}
+}
@@ -0,0 +1,72 @@
+package trader
+
+import (
+	"bytes"
+	"nofx/notify"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureChannel 记录推送的事件
+type captureChannel struct {
+	events chan notify.Event
+}
+
+func (c *captureChannel) Name() string { return "capture" }
+
+func (c *captureChannel) Send(e notify.Event) error {
+	c.events <- e
+	return nil
+}
+
+func TestIntegrationDecisionChart(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableTradeCharts("http://localhost:8080/")
+
+	capture := &captureChannel{events: make(chan notify.Event, 10)}
+	notify.SetDefault(notify.New(capture))
+	t.Cleanup(func() { notify.SetDefault(nil) })
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+
+	// 开仓通知附带PNG图和图表链接
+	select {
+	case e := <-capture.events:
+		if e.Type != "trade.opened" || e.Symbol != "ETHUSDT" {
+			t.Fatalf("通知事件不符合预期: %+v", e)
+		}
+		if !bytes.HasPrefix(e.Image, []byte("\x89PNG")) {
+			t.Error("开仓通知应附带PNG格式的K线图")
+		}
+		if !strings.HasPrefix(e.Link, "http://localhost:8080/api/decisions/chart?") || !strings.Contains(e.Link, "decision_id="+record.DecisionID) {
+			t.Errorf("图表链接不符合预期: %s", e.Link)
+		}
+		if !strings.Contains(e.Message, "止损 2900.0000 | 止盈 3400.0000") {
+			t.Errorf("通知正文缺少止损止盈: %s", e.Message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有收到开仓通知")
+	}
+
+	// 按决策ID生成SVG，未指定币种时取开仓的币种
+	c, err := at.DecisionChart(record.DecisionID, "")
+	if err != nil {
+		t.Fatalf("生成K线图失败: %v", err)
+	}
+	if len(c.Klines) == 0 || len(c.Klines) > chartKlinesBefore+chartKlinesAfter+1 {
+		t.Errorf("K线数量 = %d", len(c.Klines))
+	}
+	svg, err := c.SVG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"ETHUSDT open_long", "SL 2900.0", "TP 3400.0", "Entry 3000.0"} {
+		if !strings.Contains(string(svg), want) {
+			t.Errorf("SVG中缺少 %q", want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"nofx/market"
+)
+
+// 账户报告币种
+// 交易所返回的余额和盈亏以结算币种表示（Hyperliquid为USDC，其余为USDT）。trader配置 reporting_currency 后，
+// 余额、持仓盈亏和保证金在读取时按行情数据源的汇率（见 market.ConversionRate，缓存1分钟）从结算币种换算成报告币种，
+// 之后的净值、盈亏统计、风控、仓位上限和prompt都以报告币种表示；initial_balance 和仓位限制也按报告币种填写。
+// 开仓时仓位金额（报告币种）再换算成交易对计价币种后计算数量。未配置时与结算币种相同，不做换算。
+
+// SettlementCurrencyReporter 不以USDT结算的交易器（可选接口，未实现时按USDT）
+type SettlementCurrencyReporter interface {
+	// SettlementCurrency 余额和盈亏的币种
+	SettlementCurrency() string
+}
+
+// balanceMoneyFields 余额中以结算币种表示的字段
+var balanceMoneyFields = []string{"totalWalletBalance", "availableBalance", "totalUnrealizedProfit"}
+
+// positionMoneyFields 持仓中以结算币种表示的字段
+var positionMoneyFields = []string{"unRealizedProfit", "realisedPnl", "margin"}
+
+// settlementCurrency 交易器的结算币种
+func settlementCurrency(t Trader) string {
+	if r, ok := t.(SettlementCurrencyReporter); ok {
+		return market.NormalizeCurrency(r.SettlementCurrency())
+	}
+	return market.DefaultQuote
+}
+
+// GetReportingCurrency 余额和盈亏使用的币种
+func (at *AutoTrader) GetReportingCurrency() string {
+	return at.config.ReportingCurrency
+}
+
+// settlementRate 1单位结算币种折合多少报告币种
+func (at *AutoTrader) settlementRate() (float64, error) {
+	if at.settlement == at.config.ReportingCurrency {
+		return 1, nil
+	}
+	rate, err := market.ConversionRate(context.Background(), at.settlement, at.config.ReportingCurrency)
+	if err != nil {
+		return 0, fmt.Errorf("获取 %s→%s 汇率失败: %w", at.settlement, at.config.ReportingCurrency, err)
+	}
+	return rate, nil
+}
+
+// getBalance 获取余额（已换算成报告币种）
+func (at *AutoTrader) getBalance() (map[string]interface{}, error) {
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		return nil, err
+	}
+	rate, err := at.settlementRate()
+	if err != nil {
+		return nil, err
+	}
+	return convertBalance(balance, rate), nil
+}
+
+// getPositions 获取持仓（盈亏和保证金已换算成报告币种）
+func (at *AutoTrader) getPositions() ([]map[string]interface{}, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, err
+	}
+	rate, err := at.settlementRate()
+	if err != nil {
+		return nil, err
+	}
+	return convertPositions(positions, rate), nil
+}
+
+// convertBalance 余额字段乘以汇率（复制一份，交易器缓存的原始数据不变）
+func convertBalance(balance map[string]interface{}, rate float64) map[string]interface{} {
+	if rate == 1 {
+		return balance
+	}
+	converted := make(map[string]interface{}, len(balance))
+	for k, v := range balance {
+		converted[k] = v
+	}
+	for _, field := range balanceMoneyFields {
+		if v, ok := converted[field].(float64); ok {
+			converted[field] = v * rate
+		}
+	}
+	return converted
+}
+
+// convertPositions 持仓的盈亏和保证金乘以汇率；交易所未返回保证金时按结算币种估算后换算，避免下游按价格重新估算
+func convertPositions(positions []map[string]interface{}, rate float64) []map[string]interface{} {
+	if rate == 1 {
+		return positions
+	}
+	converted := make([]map[string]interface{}, 0, len(positions))
+	for _, pos := range positions {
+		c := make(map[string]interface{}, len(pos)+1)
+		for k, v := range pos {
+			c[k] = v
+		}
+		if margin, ok := c["margin"].(float64); !ok || margin <= 0 {
+			markPrice, _ := c["markPrice"].(float64)
+			quantity, _ := c["positionAmt"].(float64)
+			if quantity < 0 {
+				quantity = -quantity
+			}
+			c["margin"] = positionMargin(c, quantity, markPrice, positionLeverage(c))
+		}
+		for _, field := range positionMoneyFields {
+			if v, ok := c[field].(float64); ok {
+				c[field] = v * rate
+			}
+		}
+		converted = append(converted, c)
+	}
+	return converted
+}
+
+// quantityFor 仓位金额（报告币种）按交易对的价格折算成数量
+func (at *AutoTrader) quantityFor(symbol string, sizeUSD, price float64) (float64, error) {
+	quote := market.QuoteOf(symbol)
+	if quote == at.config.ReportingCurrency {
+		return sizeUSD / price, nil
+	}
+	rate, err := market.ConversionRate(context.Background(), at.config.ReportingCurrency, quote)
+	if err != nil {
+		return 0, fmt.Errorf("获取 %s→%s 汇率失败: %w", at.config.ReportingCurrency, quote, err)
+	}
+	return sizeUSD * rate / price, nil
+}
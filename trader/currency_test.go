@@ -0,0 +1,46 @@
+package trader
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationReportingCurrency(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.config.ReportingCurrency = "USDC"
+	ex.SetPrice("USDCUSDT", 1.25) // 1 USDT = 0.8 USDC（放大汇率便于区分）
+
+	// 账户 10000 USDT 按 0.8 换算为 8000 USDC
+	info, err := at.GetAccountInfo()
+	if err != nil {
+		t.Fatalf("获取账户信息失败: %v", err)
+	}
+	if equity := info["total_equity"].(float64); math.Abs(equity-8000) > 1e-6 {
+		t.Fatalf("净值应换算成报告币种: %.2f", equity)
+	}
+	if info["currency"] != "USDC" || info["settlement"] != "USDT" {
+		t.Fatalf("账户信息应标明币种: %v / %v", info["currency"], info["settlement"])
+	}
+
+	// 1200 USDC 的仓位折合 1500 USDT，按 3000 的价格为 0.5 ETH（50张）
+	ai.Enqueue(t, "开多。", openLongETH(1200))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	if size := ex.GatePosition("ETHUSDT").size; size != 50 {
+		t.Fatalf("仓位金额应先换算成计价币种再计算数量: %v张", size)
+	}
+	if prompt := ai.Prompts()[0]; !strings.Contains(prompt, "账户金额单位为 USDC") {
+		t.Fatalf("prompt中应说明金额单位:\n%s", prompt)
+	}
+
+	// 持仓盈亏同样换算：0.5 ETH × 100 = 50 USDT → 40 USDC
+	ex.SetPrice("ETHUSDT", 3100)
+	positions, err := at.GetPositions()
+	if err != nil || len(positions) != 1 {
+		t.Fatalf("获取持仓失败: %v %v", positions, err)
+	}
+	if pnl := positions[0]["unrealized_pnl"].(float64); math.Abs(pnl-40) > 1e-6 {
+		t.Fatalf("未实现盈亏应换算成报告币种: %.4f", pnl)
+	}
+}
@@ -0,0 +1,79 @@
+package trader
+
+import (
+	"nofx/decision"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationCycleReport(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableDecisionThrottle(DecisionThrottleConfig{MaxNewPositions: 1, MaxPerSymbol: 1})
+
+	openBTC := decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1500,
+		StopLoss: 58000, TakeProfit: 66000, Confidence: 90, RiskUSD: 50, Reasoning: "更强的突破",
+	}
+	ai.Enqueue(t, "开多BTC和ETH，平掉SOL空仓。", openLongETH(1500), openBTC,
+		decision.Decision{Symbol: "SOLUSDT", Action: "close_short", Reasoning: "止盈"},
+		decision.Decision{Symbol: "XRPUSDT", Action: "wait", Reasoning: "观望"})
+	record := runCycle(t, at)
+
+	report, err := at.GetDecisionLogger().CycleReport("1")
+	if err != nil {
+		t.Fatalf("生成周期报告失败: %v", err)
+	}
+	if report.DecisionID != record.DecisionID || report.CycleNumber != 1 {
+		t.Fatalf("按周期编号应找到本周期的记录，实际 %s #%d", report.DecisionID, report.CycleNumber)
+	}
+
+	outcomes := make(map[string]cycleOutcome)
+	for _, d := range report.Decisions {
+		outcomes[d.Symbol+" "+d.Action] = cycleOutcome{d.Outcome, d.Error, strings.Join(d.Notes, "\n")}
+	}
+	want := map[string]string{
+		"BTCUSDT open_long":   "executed",
+		"ETHUSDT open_long":   "skipped",
+		"SOLUSDT close_short": "failed",
+		"XRPUSDT wait":        "no_action",
+	}
+	for key, outcome := range want {
+		if got := outcomes[key].outcome; got != outcome {
+			t.Errorf("%s 的结果应为 %s，实际 %q", key, outcome, got)
+		}
+	}
+	if !strings.Contains(outcomes["ETHUSDT open_long"].notes, "被决策限流跳过") {
+		t.Errorf("跳过的决策应附带执行日志中的原因，实际 %q", outcomes["ETHUSDT open_long"].notes)
+	}
+	if outcomes["SOLUSDT close_short"].err == "" {
+		t.Error("失败的决策应附带错误信息")
+	}
+
+	filled := false
+	for _, order := range report.Orders {
+		if order.Symbol == "BTCUSDT" && order.Success && order.Quantity > 0 {
+			filled = true
+		}
+	}
+	if !filled {
+		t.Error("下单记录应包含BTC的成交")
+	}
+	if !containsLine(report.Errors, "❌ SOLUSDT close_short 失败") {
+		t.Errorf("错误列表应包含执行失败，实际 %v", report.Errors)
+	}
+}
+
+// cycleOutcome 报告中一条决策的结果（测试比较用）
+type cycleOutcome struct {
+	outcome, err, notes string
+}
+
+func containsLine(lines []string, prefix string) bool {
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
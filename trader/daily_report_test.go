@@ -0,0 +1,46 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegrationDailyReport(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	ex.SetPrice("ETHUSDT", 3100)
+	ai.Enqueue(t, "止盈。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "锁定利润"})
+	requireActionSuccess(t, runCycle(t, at), "close_long")
+	runCycle(t, at) // 观望周期：持仓快照为空
+
+	report, err := at.decisionLogger.BuildDailyReport(time.Now(), 0.05)
+	if err != nil {
+		t.Fatalf("生成日报失败: %v", err)
+	}
+	if report.Cycles != 3 || report.Opens != 1 || report.ClosedTrades != 1 {
+		t.Fatalf("周期/开仓/平仓数不符合预期: %+v", report)
+	}
+	// 0.5 ETH 从 3000 涨到 3100
+	if report.BestTrade == nil || math.Abs(report.BestTrade.PnL-50) > 0.01 || report.WinRate != 100 {
+		t.Fatalf("交易盈亏不符合预期: %+v", report.BestTrade)
+	}
+	// 手续费 = (1500 + 1550) × 0.05%
+	if math.Abs(report.Fees-1.525) > 0.001 {
+		t.Errorf("手续费估算 = %.4f, 期望 1.525", report.Fees)
+	}
+	if report.Exposure.PositionCount != 0 {
+		t.Errorf("平仓后日终敞口应为空: %+v", report.Exposure)
+	}
+
+	text := report.Format(at.name)
+	if !strings.Contains(text, "胜率: 100.0%") || !strings.Contains(text, "最佳: ETHUSDT long +50.00 USDT") {
+		t.Errorf("日报文本不符合预期:\n%s", text)
+	}
+}
@@ -0,0 +1,28 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"testing"
+	"time"
+)
+
+func TestIntegrationDecisionSearch(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	ai.Enqueue(t, "资金费率极端，空头拥挤。",
+		decision.Decision{Symbol: "BTCUSDT", Action: "wait", Reasoning: "Funding squeeze 可能引发空头回补，先观望"},
+		decision.Decision{Symbol: "SOLUSDT", Action: "wait", Reasoning: "funding rate elevated, no squeeze yet"})
+	first := runCycle(t, at)
+
+	// AI给出的决策理由写入决策日志后可以检索（检索规则见 logger 的单元测试）
+	now := time.Now()
+	hits, err := at.GetDecisionLogger().SearchDecisions(logger.DecisionSearchQuery{Text: "funding SQUEEZE", Symbol: "BTCUSDT", From: now.Add(-time.Hour), To: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("检索失败: %v", err)
+	}
+	if len(hits) != 1 || hits[0].DecisionID != first.DecisionID || hits[0].Action != "wait" || hits[0].Field != logger.SearchFieldReasoning {
+		t.Fatalf("应命中第一个周期BTC的决策理由，实际 %+v", hits)
+	}
+}
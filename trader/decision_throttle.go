@@ -0,0 +1,82 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"sort"
+)
+
+// 决策限流
+// 解析后的决策在执行前按硬性上限裁剪，防止一次AI响应同时开出大量仓位：
+// 每个周期最多 maxNewPositions 个新开仓（open_long/open_short），每个币种最多 maxPerSymbol 个动作（hold/wait不计）。
+// 超出上限时按信心度从高到低保留，信心度相同时先平仓后开仓（执行阶段顺序），再按AI给出的顺序，结果是确定的。
+// 被裁掉的决策写入执行日志，不执行。
+
+// DecisionThrottleConfig 决策限流配置（0表示不限制）
+type DecisionThrottleConfig struct {
+	MaxNewPositions int // 每个周期最多新开仓数
+	MaxPerSymbol    int // 每个币种每个周期最多动作数
+}
+
+// EnableDecisionThrottle 启用决策限流
+func (at *AutoTrader) EnableDecisionThrottle(cfg DecisionThrottleConfig) {
+	at.throttle = &cfg
+}
+
+// throttleDecisions 按上限裁剪决策，保持输入（已排序）的顺序
+func throttleDecisions(decisions []decision.Decision, cfg DecisionThrottleConfig, record *logger.DecisionRecord) []decision.Decision {
+	if cfg.MaxNewPositions <= 0 && cfg.MaxPerSymbol <= 0 {
+		return decisions
+	}
+
+	// 保留优先级：信心度高的优先，其次执行阶段靠前的，最后按原顺序
+	ranked := make([]int, len(decisions))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		da, db := decisions[ranked[a]], decisions[ranked[b]]
+		if da.Confidence != db.Confidence {
+			return da.Confidence > db.Confidence
+		}
+		return actionPriority(da.Action) < actionPriority(db.Action)
+	})
+
+	dropped := make(map[int]string)
+	newPositions := 0
+	perSymbol := make(map[string]int)
+	for _, i := range ranked {
+		d := decisions[i]
+		if d.Action == "hold" || d.Action == "wait" {
+			continue
+		}
+		if cfg.MaxPerSymbol > 0 && perSymbol[d.Symbol] >= cfg.MaxPerSymbol {
+			dropped[i] = fmt.Sprintf("%s 本周期已有%d个动作（上限%d）", d.Symbol, perSymbol[d.Symbol], cfg.MaxPerSymbol)
+			continue
+		}
+		if d.Action == "open_long" || d.Action == "open_short" {
+			if cfg.MaxNewPositions > 0 && newPositions >= cfg.MaxNewPositions {
+				dropped[i] = fmt.Sprintf("本周期已开%d个新仓（上限%d）", newPositions, cfg.MaxNewPositions)
+				continue
+			}
+			newPositions++
+		}
+		perSymbol[d.Symbol]++
+	}
+	if len(dropped) == 0 {
+		return decisions
+	}
+
+	kept := make([]decision.Decision, 0, len(decisions)-len(dropped))
+	for i, d := range decisions {
+		if reason, ok := dropped[i]; ok {
+			log.Printf("  🚦 决策限流，跳过 %s %s（信心度%d）: %s", d.Symbol, d.Action, d.Confidence, reason)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚦 %s %s 被决策限流跳过（信心度%d）: %s", d.Symbol, d.Action, d.Confidence, reason))
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept
+}
@@ -0,0 +1,60 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"reflect"
+	"testing"
+)
+
+func TestThrottleDecisions(t *testing.T) {
+	decisions := sortDecisionsByPriority([]decision.Decision{
+		{Symbol: "SOLUSDT", Action: "open_long", Confidence: 70},
+		{Symbol: "ETHUSDT", Action: "open_short", Confidence: 75},
+		{Symbol: "BTCUSDT", Action: "open_long", Confidence: 85},
+		{Symbol: "DOGEUSDT", Action: "open_long", Confidence: 75},
+		{Symbol: "ETHUSDT", Action: "close_long", Confidence: 75},
+		{Symbol: "XRPUSDT", Action: "wait"},
+		{Symbol: "XRPUSDT", Action: "adjust_sl", Confidence: 60},
+	})
+	record := &logger.DecisionRecord{}
+	kept := throttleDecisions(decisions, DecisionThrottleConfig{MaxNewPositions: 2, MaxPerSymbol: 1}, record)
+
+	var got []string
+	for _, d := range kept {
+		got = append(got, d.Symbol+" "+d.Action)
+	}
+	// ETH：信心度相同时平仓优先于开仓；新开仓保留信心度最高的 BTC 和 DOGE
+	want := []string{"ETHUSDT close_long", "XRPUSDT adjust_sl", "BTCUSDT open_long", "DOGEUSDT open_long", "XRPUSDT wait"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("保留的决策 = %v, 期望 %v", got, want)
+	}
+	if len(record.ExecutionLog) != 2 {
+		t.Errorf("被跳过的决策应写入执行日志: %v", record.ExecutionLog)
+	}
+
+	// 不限制时原样返回
+	if all := throttleDecisions(decisions, DecisionThrottleConfig{MaxNewPositions: -1, MaxPerSymbol: -1}, record); len(all) != len(decisions) {
+		t.Errorf("不限制时不应跳过决策: %d/%d", len(all), len(decisions))
+	}
+}
+
+func TestIntegrationDecisionThrottle(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableDecisionThrottle(DecisionThrottleConfig{MaxNewPositions: 1, MaxPerSymbol: 1})
+
+	openBTC := decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1500,
+		StopLoss: 58000, TakeProfit: 66000, Confidence: 90, RiskUSD: 50, Reasoning: "更强的突破",
+	}
+	ai.Enqueue(t, "ETH 和 BTC 同时开多。", openLongETH(1500), openBTC)
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+	if len(record.Decisions) != 1 || record.Decisions[0].Symbol != "BTCUSDT" {
+		t.Fatalf("只应执行信心度最高的开仓: %+v", record.Decisions)
+	}
+	if ex.GatePosition("ETHUSDT").size != 0 || ex.GatePosition("BTCUSDT").size <= 0 {
+		t.Fatal("只应有BTC持仓")
+	}
+}
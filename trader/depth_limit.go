@@ -0,0 +1,84 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// 按深度限制单币种仓位
+// 启用深度仓位上限后，行情数据带有按近1小时平均成交额和盘口深度计算的最大名义价值（见 market.DepthLimit）。
+// 开仓/加仓执行前，该币种已有持仓加上本次仓位超过上限的部分被截掉，止损风险金额同比例缩小；
+// 剩余额度不足最小仓位时放弃该开仓/加仓。OI过滤只看整体规模，挡不住成交稀薄的山寨币上的超大仓位。
+
+// clampDepthEntries 开仓/加仓的仓位不超过币种的深度仓位上限
+func (at *AutoTrader) clampDepthEntries(ctx *decision.Context, decisions []decision.Decision, record *logger.DecisionRecord) []decision.Decision {
+	kept := decisions[:0]
+	for _, d := range decisions {
+		if d.Action != "open_long" && d.Action != "open_short" && d.Action != "add_to_position" {
+			kept = append(kept, d)
+			continue
+		}
+		data, ok := ctx.MarketDataMap[d.Symbol]
+		if !ok || data.Depth == nil || d.PositionSizeUSD <= 0 {
+			kept = append(kept, d)
+			continue
+		}
+
+		limit := data.Depth
+		held := heldNotional(ctx.Positions, d)
+		room := limit.MaxNotional - held
+		if d.PositionSizeUSD <= room {
+			kept = append(kept, d)
+			continue
+		}
+
+		reason := fmt.Sprintf("深度仓位上限 %.2f USDT（受%s限制", limit.MaxNotional, depthBindingName(limit.Binding()))
+		if held > 0 {
+			reason += fmt.Sprintf("，已持有 %.2f USDT", held)
+		}
+		reason += "）"
+		if room <= 0 || room < ctx.MinPositionSizeUSD {
+			log.Printf("  💧 %s %s 跳过：%s，剩余额度 %.2f USDT 不足最小仓位", d.Symbol, d.Action, reason, room)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("💧 %s %s 跳过：%s，剩余额度 %.2f USDT 不足最小仓位 %.2f USDT",
+				d.Symbol, d.Action, reason, room, ctx.MinPositionSizeUSD))
+			continue
+		}
+
+		log.Printf("  💧 %s %s 仓位 %.2f → %.2f USDT：%s", d.Symbol, d.Action, d.PositionSizeUSD, room, reason)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("💧 %s %s 仓位 %.2f → %.2f USDT：%s",
+			d.Symbol, d.Action, d.PositionSizeUSD, room, reason))
+		d.RiskUSD *= room / d.PositionSizeUSD
+		d.PositionSizeUSD = room
+		kept = append(kept, d)
+	}
+	return kept
+}
+
+// heldNotional 决策币种上已有的同方向持仓名义价值（加仓计入该币种的全部持仓）
+func heldNotional(positions []decision.PositionInfo, d decision.Decision) float64 {
+	total := 0.0
+	for _, p := range positions {
+		if p.Symbol != d.Symbol {
+			continue
+		}
+		if (d.Action == "open_long" && p.Side != "long") || (d.Action == "open_short" && p.Side != "short") {
+			continue
+		}
+		price := p.MarkPrice
+		if price <= 0 {
+			price = p.EntryPrice
+		}
+		total += p.Quantity * price
+	}
+	return total
+}
+
+// depthBindingName 起限制作用的上限的中文名称
+func depthBindingName(binding string) string {
+	if binding == "order book depth" {
+		return "盘口深度"
+	}
+	return "小时成交额"
+}
@@ -0,0 +1,67 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+	"testing"
+)
+
+func TestClampDepthEntries(t *testing.T) {
+	at := &AutoTrader{}
+
+	// PEPE：小时成交额 20万 → 上限 2000；盘口较薄一侧 3000 → 上限 1500，盘口起限制作用
+	thin := &market.DepthLimit{
+		AvgHourlyVolume: 200000, VolumeHours: 24, VolumeCap: 2000,
+		Book: &market.BookDepth{MidPrice: 0.00001, BidNotional: 3000, AskNotional: 4000}, BandPct: 1, DepthCap: 1500,
+		MaxNotional: 1500,
+	}
+	// WIF：上限 800，已持有 600 的多仓
+	held := &market.DepthLimit{AvgHourlyVolume: 80000, VolumeHours: 24, VolumeCap: 800, MaxNotional: 800}
+	ctx := &decision.Context{
+		MinPositionSizeUSD: 300,
+		MarketDataMap: map[string]*market.Data{
+			"PEPEUSDT": {Symbol: "PEPEUSDT", CurrentPrice: 0.00001, Depth: thin},
+			"WIFUSDT":  {Symbol: "WIFUSDT", CurrentPrice: 2, Depth: held},
+			"BTCUSDT":  {Symbol: "BTCUSDT", CurrentPrice: 60000},
+		},
+		Positions: []decision.PositionInfo{
+			{Symbol: "WIFUSDT", Side: "long", Quantity: 300, MarkPrice: 2},
+		},
+	}
+	decisions := []decision.Decision{
+		{Symbol: "PEPEUSDT", Action: "open_long", PositionSizeUSD: 3000, RiskUSD: 60},
+		{Symbol: "WIFUSDT", Action: "add_to_position", PositionSizeUSD: 500},
+		{Symbol: "WIFUSDT", Action: "open_short", PositionSizeUSD: 600},
+		{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 5000},
+		{Symbol: "PEPEUSDT", Action: "close_short"},
+	}
+	record := &logger.DecisionRecord{}
+	decisions = at.clampDepthEntries(ctx, decisions, record)
+
+	if len(decisions) != 4 {
+		t.Fatalf("剩余额度不足最小仓位的加仓应被跳过: %+v", decisions)
+	}
+	if got := decisions[0].PositionSizeUSD; got != 1500 {
+		t.Fatalf("开仓应截到深度上限，实际 %.2f", got)
+	}
+	if math.Abs(decisions[0].RiskUSD-30) > 1e-9 {
+		t.Fatalf("风险金额应同比例缩小，实际 %.2f", decisions[0].RiskUSD)
+	}
+	if decisions[1].Action != "open_short" || decisions[1].PositionSizeUSD != 600 {
+		t.Fatalf("反方向开仓不计入已有多仓，不应截断: %+v", decisions[1])
+	}
+	if decisions[2].PositionSizeUSD != 5000 {
+		t.Fatal("没有深度数据的币种不应截断")
+	}
+	requireExecutionLog(t, record.ExecutionLog, "PEPEUSDT open_long 仓位 3000.00 → 1500.00 USDT：深度仓位上限 1500.00 USDT（受盘口深度限制）")
+	requireExecutionLog(t, record.ExecutionLog, "WIFUSDT add_to_position 跳过：深度仓位上限 800.00 USDT（受小时成交额限制，已持有 600.00 USDT），剩余额度 200.00 USDT 不足最小仓位")
+
+	// prompt中给出仓位上限
+	prompt := market.Format(&market.Data{Symbol: "PEPEUSDT", CurrentPrice: 0.00001, Depth: thin})
+	if !strings.Contains(prompt, "Max position size (liquidity): 1500 USDT notional, limited by order book depth") {
+		t.Fatalf("prompt中应有深度仓位上限:\n%s", prompt)
+	}
+}
@@ -0,0 +1,202 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/events"
+	"nofx/logger"
+	"nofx/notify"
+)
+
+// 日内亏损降风险阶梯
+// 以当日起始净值为基准计算日内亏损，亏损越大限制越严：
+//   1. 亏损 ≥ ReduceSizeLossPct：单仓位上限缩减为 SizeFactor 倍
+//   2. 亏损 ≥ CloseOnlyLossPct：只允许平仓/减仓，不再开新仓或加仓
+//   3. 亏损 ≥ FlattenLossPct：平掉全部持仓，暂停交易 StopTradingTime（冷却结束后当日仍只允许平仓）
+// 阶梯当天只升不降，每日重置时恢复正常。每升一级都会推送通知，并在AI提示中说明当前限制。
+
+// DeriskLevel 降风险等级
+type DeriskLevel int
+
+const (
+	DeriskNone       DeriskLevel = iota // 正常
+	DeriskReduceSize                    // 缩减仓位
+	DeriskCloseOnly                     // 只允许平仓
+	DeriskFlatten                       // 已清仓暂停
+)
+
+// String 等级名称
+func (l DeriskLevel) String() string {
+	switch l {
+	case DeriskReduceSize:
+		return "reduce_size"
+	case DeriskCloseOnly:
+		return "close_only"
+	case DeriskFlatten:
+		return "flatten"
+	default:
+		return "normal"
+	}
+}
+
+// DeriskConfig 降风险阶梯配置（亏损百分比均为正数）
+type DeriskConfig struct {
+	Enabled           bool
+	ReduceSizeLossPct float64 // 缩减仓位的日内亏损阈值
+	CloseOnlyLossPct  float64 // 只允许平仓的日内亏损阈值
+	FlattenLossPct    float64 // 清仓暂停的日内亏损阈值
+	SizeFactor        float64 // 缩减后的仓位系数（如0.5）
+}
+
+// levelFor 日内亏损百分比对应的等级
+func (c DeriskConfig) levelFor(lossPct float64) DeriskLevel {
+	switch {
+	case !c.Enabled:
+		return DeriskNone
+	case c.FlattenLossPct > 0 && lossPct >= c.FlattenLossPct:
+		return DeriskFlatten
+	case c.CloseOnlyLossPct > 0 && lossPct >= c.CloseOnlyLossPct:
+		return DeriskCloseOnly
+	case c.ReduceSizeLossPct > 0 && lossPct >= c.ReduceSizeLossPct:
+		return DeriskReduceSize
+	}
+	return DeriskNone
+}
+
+// deriskState 当日的降风险状态
+type deriskState struct {
+	level          DeriskLevel
+	dayStartEquity float64 // 当日起始净值（每日重置后的第一个周期记录）
+}
+
+// resetDerisk 每日重置（同时清除已结束的风控暂停原因）
+func (at *AutoTrader) resetDerisk() {
+	at.derisk = deriskState{}
+	at.stopReason = ""
+	at.saveRiskState()
+}
+
+// applyDeriskLadder 根据日内亏损更新降风险等级并把限制写入决策上下文
+// 返回 true 表示本周期已清仓暂停，不再请求AI
+func (at *AutoTrader) applyDeriskLadder(ctx *decision.Context, record *logger.DecisionRecord) bool {
+	cfg := at.config.DeriskLadder
+	equity := ctx.Account.TotalEquity
+	if at.derisk.dayStartEquity <= 0 {
+		at.derisk.dayStartEquity = equity
+		at.saveRiskState()
+	}
+	at.dailyPnL = equity - at.derisk.dayStartEquity
+	if !cfg.Enabled || at.derisk.dayStartEquity <= 0 {
+		return false
+	}
+
+	lossPct := -at.dailyPnL / at.derisk.dayStartEquity * 100
+	halted := false
+	if level := cfg.levelFor(lossPct); level > at.derisk.level {
+		at.derisk.level = level
+		at.saveRiskState()
+		at.notifyDerisk(level, lossPct)
+		at.publishEvent(events.RiskBreach, "", RiskBreachData{
+			Kind: "derisk", Level: level.String(), LossPct: lossPct,
+			Reason: fmt.Sprintf("日内亏损 %.2f%%，降风险等级升至 %s", lossPct, level),
+		})
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ 日内亏损 %.2f%%，降风险等级升至 %s", lossPct, level))
+
+		if level == DeriskFlatten {
+			at.flattenAll(record)
+			at.haltTrading(fmt.Sprintf("日内亏损 %.2f%% 触发清仓线", lossPct), at.config.StopTradingTime)
+			halted = true
+		}
+	}
+
+	switch at.derisk.level {
+	case DeriskReduceSize:
+		maxSize := ctx.MaxPositionSizeUSD
+		if maxSize <= 0 {
+			maxSize = equity // 未配置仓位上限时以账户净值为基准
+		}
+		ctx.MaxPositionSizeUSD = maxSize * cfg.SizeFactor
+		if ctx.MinPositionSizeUSD > ctx.MaxPositionSizeUSD {
+			ctx.MinPositionSizeUSD = 0
+		}
+		ctx.RiskNotice = fmt.Sprintf("日内亏损 %.2f%% 已超过 %.1f%%，单仓位上限缩减至 %.0f USDT", lossPct, cfg.ReduceSizeLossPct, ctx.MaxPositionSizeUSD)
+	case DeriskCloseOnly, DeriskFlatten:
+		ctx.CloseOnly = true
+		ctx.RiskNotice = fmt.Sprintf("日内亏损 %.2f%% 已超过 %.1f%%，今日只允许平仓/减仓，禁止开新仓和加仓", lossPct, cfg.CloseOnlyLossPct)
+	}
+	return halted
+}
+
+// filterCloseOnly 只允许平仓时移除开仓和加仓决策
+func filterCloseOnly(decisions []decision.Decision, reason string, record *logger.DecisionRecord) []decision.Decision {
+	filtered := decisions[:0:0]
+	for _, d := range decisions {
+		if d.Action == "open_long" || d.Action == "open_short" || d.Action == "add_to_position" {
+			log.Printf("  ⛔ %s只允许平仓，跳过 %s %s", reason, d.Symbol, d.Action)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⛔ %s %s 被%s拦截（只允许平仓）", d.Symbol, d.Action, reason))
+			continue
+		}
+		filtered = append(filtered, d)
+	}
+	return filtered
+}
+
+// flattenAll 平掉全部持仓
+func (at *AutoTrader) flattenAll(record *logger.DecisionRecord) {
+	if err := at.checkRetired(); err != nil {
+		return
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ 清仓前获取持仓失败: %v", err))
+		return
+	}
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		var closeErr error
+		if side == "long" {
+			_, closeErr = at.trader.CloseLong(symbol, 0, "")
+		} else {
+			_, closeErr = at.trader.CloseShort(symbol, 0, "")
+		}
+		if closeErr != nil {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 清仓失败: %v", symbol, side, closeErr))
+			notify.Send(notify.Event{
+				Type:     "risk.flatten_failed",
+				Severity: notify.SeverityCritical,
+				TraderID: at.id,
+				Symbol:   symbol,
+				Title:    fmt.Sprintf("%s 降风险清仓失败", symbol),
+				Message:  fmt.Sprintf("%s 持仓（%s）清仓失败，请人工处理: %v", symbol, side, closeErr),
+			})
+			continue
+		}
+		delete(at.positionStops, symbol+"_"+side)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 已因降风险清仓", symbol, side))
+	}
+}
+
+// notifyDerisk 降风险等级升级通知
+func (at *AutoTrader) notifyDerisk(level DeriskLevel, lossPct float64) {
+	cfg := at.config.DeriskLadder
+	event := notify.Event{
+		Type:     "risk.derisk_" + level.String(),
+		Severity: notify.SeverityWarning,
+		TraderID: at.id,
+	}
+	switch level {
+	case DeriskReduceSize:
+		event.Title = fmt.Sprintf("%s 日内亏损 %.2f%%，仓位上限减半", at.name, lossPct)
+		event.Message = fmt.Sprintf("日内亏损超过 %.1f%%，单仓位上限缩减为 %.0f%%", cfg.ReduceSizeLossPct, cfg.SizeFactor*100)
+	case DeriskCloseOnly:
+		event.Title = fmt.Sprintf("%s 日内亏损 %.2f%%，只允许平仓", at.name, lossPct)
+		event.Message = fmt.Sprintf("日内亏损超过 %.1f%%，今日不再开新仓", cfg.CloseOnlyLossPct)
+	case DeriskFlatten:
+		event.Severity = notify.SeverityCritical
+		event.Title = fmt.Sprintf("%s 日内亏损 %.2f%%，已清仓暂停", at.name, lossPct)
+		event.Message = fmt.Sprintf("日内亏损超过 %.1f%%，已平掉全部持仓并暂停交易 %.0f 分钟", cfg.FlattenLossPct, at.config.StopTradingTime.Minutes())
+	}
+	notify.Send(event)
+}
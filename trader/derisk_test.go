@@ -0,0 +1,90 @@
+package trader
+
+import (
+	"nofx/decision"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeriskLevelFor(t *testing.T) {
+	cfg := DeriskConfig{Enabled: true, ReduceSizeLossPct: 3, CloseOnlyLossPct: 5, FlattenLossPct: 8, SizeFactor: 0.5}
+	tests := []struct {
+		lossPct float64
+		want    DeriskLevel
+	}{
+		{-2, DeriskNone}, // 盈利
+		{2.9, DeriskNone},
+		{3, DeriskReduceSize},
+		{5.5, DeriskCloseOnly},
+		{8, DeriskFlatten},
+	}
+	for _, tt := range tests {
+		if got := cfg.levelFor(tt.lossPct); got != tt.want {
+			t.Errorf("levelFor(%.1f) = %s, 期望 %s", tt.lossPct, got, tt.want)
+		}
+	}
+
+	cfg.Enabled = false
+	if got := cfg.levelFor(20); got != DeriskNone {
+		t.Errorf("未启用时应始终为 normal, 实际 %s", got)
+	}
+}
+
+func TestIntegrationDeriskLadder(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.config.DeriskLadder = DeriskConfig{Enabled: true, ReduceSizeLossPct: 0.5, CloseOnlyLossPct: 1, FlattenLossPct: 2, SizeFactor: 0.5}
+	at.config.StopTradingTime = 30 * time.Minute
+
+	// 周期1：开多 0.5 ETH（止损放远，避免先触发止损）
+	open := openLongETH(1500)
+	open.StopLoss = 2000
+	open.TakeProfit = 4500
+	ai.Enqueue(t, "开多。", open)
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	// 周期2：亏损 0.9% → 仓位上限减半（未配置上限时按净值 9910 计算），并在AI提示中说明
+	ex.SetPrice("ETHUSDT", 2820)
+	runCycle(t, at)
+	if at.derisk.level != DeriskReduceSize {
+		t.Fatalf("降风险等级 = %s, 期望 reduce_size", at.derisk.level)
+	}
+	prompts := ai.Prompts()
+	if !strings.Contains(prompts[len(prompts)-1], "单仓位上限缩减至 4955 USDT") {
+		t.Fatalf("AI输入中缺少仓位缩减说明: %s", prompts[len(prompts)-1])
+	}
+
+	// 周期3：亏损 1.5% → 只允许平仓，开仓决策被拦截
+	ex.SetPrice("ETHUSDT", 2700)
+	ai.Enqueue(t, "抄底BTC。", decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1000,
+		StopLoss: 58000, TakeProfit: 66000, Confidence: 80, RiskUSD: 20, Reasoning: "超跌反弹",
+	})
+	record := runCycle(t, at)
+	if at.derisk.level != DeriskCloseOnly {
+		t.Fatalf("降风险等级 = %s, 期望 close_only", at.derisk.level)
+	}
+	for _, a := range record.Decisions {
+		if a.Action == "open_long" {
+			t.Fatalf("只允许平仓时仍执行了开仓: %+v", a)
+		}
+	}
+
+	// 周期4：亏损 2.5% → 清仓并暂停，不再请求AI
+	ex.SetPrice("ETHUSDT", 2500)
+	promptCount := len(ai.Prompts())
+	runCycle(t, at)
+	if at.derisk.level != DeriskFlatten {
+		t.Fatalf("降风险等级 = %s, 期望 flatten", at.derisk.level)
+	}
+	if size := ex.GatePosition("ETHUSDT").size; size != 0 {
+		t.Fatalf("清仓后仍有持仓: %v张", size)
+	}
+	if !at.stopUntil.After(time.Now().Add(29 * time.Minute)) {
+		t.Fatalf("清仓后应暂停交易, stopUntil = %v", at.stopUntil)
+	}
+	if len(ai.Prompts()) != promptCount {
+		t.Fatal("清仓周期不应请求AI")
+	}
+}
@@ -0,0 +1,36 @@
+package trader
+
+import (
+	"encoding/json"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// getDecision 获取决策：网格策略按网格计算；启用集成时由多个模型投票，否则只调用主模型
+func (at *AutoTrader) getDecision(ctx *decision.Context) (*decision.FullDecision, error) {
+	if at.grid != nil {
+		return at.gridDecision(ctx)
+	}
+	if len(at.ensemble) > 0 {
+		return decision.GetEnsembleDecision(ctx, at.ensemble, at.config.EnsemblePolicy)
+	}
+	return decision.GetFullDecision(ctx, at.mcpClient)
+}
+
+// ensembleRecords 把各模型的输出转换为决策日志记录
+func ensembleRecords(outputs []decision.EnsembleOutput) []logger.EnsembleModelRecord {
+	var records []logger.EnsembleModelRecord
+	for _, out := range outputs {
+		r := logger.EnsembleModelRecord{
+			Model:    out.Model,
+			CoTTrace: out.CoTTrace,
+			Error:    out.Error,
+		}
+		if len(out.Decisions) > 0 {
+			data, _ := json.MarshalIndent(out.Decisions, "", "  ")
+			r.DecisionJSON = string(data)
+		}
+		records = append(records, r)
+	}
+	return records
+}
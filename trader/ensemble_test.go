@@ -0,0 +1,43 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/mcp"
+	"testing"
+)
+
+func TestIntegrationEnsembleMajority(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	others := []*mockAI{newMockAI(t), newMockAI(t)}
+	at.ensemble = []decision.EnsembleMember{{Name: "primary", Client: at.mcpClient}}
+	for i, m := range others {
+		client := mcp.New()
+		client.SetCustomAPI(m.URL(), "test-key", "mock")
+		at.ensemble = append(at.ensemble, decision.EnsembleMember{Name: []string{"second", "third"}[i], Client: client})
+	}
+	at.config.EnsemblePolicy = decision.EnsembleMajority
+
+	// 两个模型同意开多ETH，一个模型幻觉出开空BTC：只执行多数同意的决策
+	ai.Enqueue(t, "ETH突破。", openLongETH(1500))
+	others[0].Enqueue(t, "ETH放量。", openLongETH(1500))
+	others[1].Enqueue(t, "BTC要崩。", decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_short", Leverage: 5, PositionSizeUSD: 1000,
+		StopLoss: 62000, TakeProfit: 54000, Confidence: 90, RiskUSD: 20, Reasoning: "顶部背离",
+	})
+	record := runCycle(t, at)
+
+	requireActionSuccess(t, record, "open_long")
+	for _, a := range record.Decisions {
+		if a.Symbol == "BTCUSDT" {
+			t.Fatalf("单个模型的决策不应被执行: %+v", a)
+		}
+	}
+	if len(record.Ensemble) != 3 {
+		t.Fatalf("应记录3个模型的输出, 实际 %d", len(record.Ensemble))
+	}
+	if record.Ensemble[2].Model != "third" || record.Ensemble[2].DecisionJSON == "" {
+		t.Errorf("模型输出记录不完整: %+v", record.Ensemble[2])
+	}
+}
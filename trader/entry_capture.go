@@ -0,0 +1,55 @@
+package trader
+
+import (
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// 实际入场价
+// 决策验证时还不知道成交价，开仓成交后以成交均价作为持仓的入场价（交易所未返回时用下单时市价），
+// 写入决策记录供交易统计、R倍数和日报使用，并按实际入场价重新计算风险回报比。
+// 启用 anchor_stops_to_fill 时，止损止盈随成交价平移（保持AI给出的止损/止盈距离），再按价格精度取整。
+
+// captureEntry 记录开仓的实际入场价和风险回报比，按配置将止损止盈锚定到成交价（更新 d 的止损止盈）
+func (at *AutoTrader) captureEntry(d *decision.Decision, actionRecord *logger.DecisionAction, marketPrice float64) {
+	entry := actionRecord.FillPrice
+	if entry <= 0 {
+		entry = marketPrice
+	}
+	actionRecord.EntryPrice = entry
+	actionRecord.PlannedRiskReward = decision.RiskRewardAt(d.Action, marketPrice, d.StopLoss, d.TakeProfit)
+
+	if at.config.AnchorStopsToFill && actionRecord.FillPrice > 0 && entry != marketPrice {
+		if sl, tp, ok := at.anchorStops(d, entry-marketPrice); ok {
+			log.Printf("  📌 止损止盈锚定成交价 %g（市价 %g）: 止损 %g→%g 止盈 %g→%g", entry, marketPrice, d.StopLoss, sl, d.TakeProfit, tp)
+			d.StopLoss, d.TakeProfit = sl, tp
+			actionRecord.StopLoss, actionRecord.TakeProfit = sl, tp
+		}
+	}
+
+	actionRecord.RiskReward = decision.RiskRewardAt(d.Action, entry, d.StopLoss, d.TakeProfit)
+	if actionRecord.RiskReward < actionRecord.PlannedRiskReward {
+		log.Printf("  ⚠ 按成交价 %g 计算的风险回报比 %.2f:1（下单时 %.2f:1）", entry, actionRecord.RiskReward, actionRecord.PlannedRiskReward)
+	}
+}
+
+// anchorStops 止损止盈平移 shift 并取整，平移后为非正数或取整后重合时返回false
+func (at *AutoTrader) anchorStops(d *decision.Decision, shift float64) (float64, float64, bool) {
+	sl, tp := d.StopLoss+shift, d.TakeProfit+shift
+	if sl <= 0 || tp <= 0 {
+		return 0, 0, false
+	}
+	if round := at.priceRounder(); round != nil {
+		if rounded, err := round(d.Symbol, sl); err == nil && rounded > 0 {
+			sl = rounded
+		}
+		if rounded, err := round(d.Symbol, tp); err == nil && rounded > 0 {
+			tp = rounded
+		}
+	}
+	if sl == tp {
+		return 0, 0, false
+	}
+	return sl, tp, true
+}
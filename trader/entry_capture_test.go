@@ -0,0 +1,48 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"testing"
+	"time"
+)
+
+func TestIntegrationEntryCaptureAnchorsStops(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.config.AnchorStopsToFill = true
+	ex.SetFillSlippage(0.002) // 成交价 3006，比下单时市价高 6
+
+	ai.Enqueue(t, "开多。", openLongETH(1500)) // 止损2900 止盈3400
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+
+	open := record.Decisions[0]
+	if math.Abs(open.EntryPrice-3006) > 0.01 || open.Price != 3000 {
+		t.Fatalf("入场价应为成交均价、Price保留下单时市价: entry=%.2f price=%.2f", open.EntryPrice, open.Price)
+	}
+	if math.Abs(open.StopLoss-2906) > 0.01 || math.Abs(open.TakeProfit-3406) > 0.01 {
+		t.Fatalf("止损止盈应随成交价平移: sl=%.2f tp=%.2f", open.StopLoss, open.TakeProfit)
+	}
+	if math.Abs(open.PlannedRiskReward-4) > 0.01 || math.Abs(open.RiskReward-4) > 0.01 {
+		t.Errorf("锚定后风险回报比应保持 4:1: planned=%.2f actual=%.2f", open.PlannedRiskReward, open.RiskReward)
+	}
+	for _, tr := range ex.Triggers("gateio", "open") {
+		if want := map[string]float64{"stop_loss": 2906, "take_profit": 3406}[tr.kind]; math.Abs(tr.price-want) > 0.01 {
+			t.Errorf("%s 触发价 = %.2f，期望 %.2f", tr.kind, tr.price, want)
+		}
+	}
+
+	// 交易统计使用实际入场价
+	ex.SetFillSlippage(0)
+	ex.SetPrice("ETHUSDT", 3100)
+	ai.Enqueue(t, "平多。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "止盈离场"})
+	requireActionSuccess(t, runCycle(t, at), "close_long")
+	trades, err := at.decisionLogger.ClosedTrades(1, time.Now())
+	if err != nil || len(trades) != 1 {
+		t.Fatalf("交易记录: %+v, %v", trades, err)
+	}
+	if trade := trades[0]; math.Abs(trade.OpenPrice-3006) > 0.01 || trade.RMultiple == nil || math.Abs(*trade.RMultiple-0.94) > 0.01 {
+		t.Fatalf("交易统计应按实际入场价计算（入场3006 止损2906 平仓3100 → 约0.94R）: %+v", trade)
+	}
+}
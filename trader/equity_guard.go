@@ -0,0 +1,194 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/notify"
+	"time"
+)
+
+// 账户净值对账（外部资金流动检测）
+// 钱包余额（净值去掉未实现盈亏）只会因平仓的已实现盈亏、手续费和资金费变化。每个周期把钱包余额的变化
+// 与交易所流水对账：上个快照以来的已实现盈亏流水和资金费流水，手续费计入允许误差。差额超过阈值视为外部资金流动
+// （充值、提现、划转或交易所异常）：通知运维，把差额计入业绩基准（初始余额、当日起始净值）并写入决策记录，
+// 总盈亏、降风险阶梯、夏普比率和日报都按扣除资金流动后的净值计算。
+// 交易所不提供盈亏流水、查询失败或平掉的持仓还没有流水时无法确认差额的来源（可能只是止损滑点），
+// 此时只告警，不调整业绩基准。
+
+const (
+	defaultExternalFlowPct = 2.0  // 差额超过净值的该百分比才视为外部资金流动
+	minExternalFlowUSD     = 10.0 // 差额的最小绝对值（小账户避免误报）
+	closeSlippagePct       = 2.0  // 没有流水时：平仓价相对上个周期标记价的允许偏移（按平掉的名义价值计）
+	feeFundingSlackPct     = 0.2  // 手续费（没有资金费流水时含资金费）的允许误差（按持仓、开仓和平仓的名义价值计）
+	unseenPositionSlackPct = 10.0 // 没有流水时：上个快照之后开仓、本快照之前已平仓（止损止盈）的持仓盈亏未知，按开仓名义价值的该比例放宽
+)
+
+// equityGuard 净值对账状态
+type equityGuard struct {
+	thresholdPct   float64
+	lastWallet     float64                          // 上个周期的钱包余额
+	lastPos        map[string]decision.PositionInfo // 上个周期的持仓 (symbol_side)
+	lastTime       time.Time                        // 上个周期的快照时间
+	seen           bool
+	openedNotional float64 // 上个快照之后开仓/加仓的名义价值
+}
+
+// EnableEquityGuard 启用外部资金流动检测，thresholdPct 为判定阈值（净值的百分比）
+func (at *AutoTrader) EnableEquityGuard(thresholdPct float64) {
+	if thresholdPct <= 0 {
+		thresholdPct = defaultExternalFlowPct
+	}
+	at.equityGuard = &equityGuard{thresholdPct: thresholdPct}
+}
+
+// ledgerIncome 交易所流水中 [since, until) 之间的已实现盈亏和资金费，以及有盈亏流水的币种
+// （交易所不提供盈亏流水时 ok 为 false）
+func (at *AutoTrader) ledgerIncome(since, until time.Time) (income float64, symbols map[string]bool, ok bool, err error) {
+	provider, ok := at.trader.(RealizedPnLProvider)
+	if !ok {
+		return 0, nil, false, nil
+	}
+	records, err := provider.GetRealizedPnL(since, until)
+	if err != nil {
+		return 0, nil, true, fmt.Errorf("获取盈亏流水失败: %w", err)
+	}
+	symbols = make(map[string]bool)
+	for _, r := range records {
+		income += r.Amount
+		symbols[r.Symbol] = true
+	}
+	if funding, isProvider := at.trader.(FundingHistoryProvider); isProvider {
+		payments, err := funding.GetFundingPayments(since)
+		if err != nil {
+			return 0, nil, true, fmt.Errorf("获取资金费流水失败: %w", err)
+		}
+		for _, p := range payments {
+			if !p.Time.Before(since) && p.Time.Before(until) {
+				income += p.Amount
+			}
+		}
+	}
+	return income, symbols, true, nil
+}
+
+// reconcileEquity 对账本周期的钱包余额变化，确认是外部资金流动时调整业绩基准
+func (at *AutoTrader) reconcileEquity(ctx *decision.Context, record *logger.DecisionRecord) {
+	g := at.equityGuard
+	if g == nil || ctx.Account.TotalEquity <= 0 {
+		return
+	}
+
+	now := time.Now()
+	wallet := ctx.Account.TotalEquity
+	positions := make(map[string]decision.PositionInfo, len(ctx.Positions))
+	notional := 0.0
+	for _, pos := range ctx.Positions {
+		wallet -= pos.UnrealizedPnL
+		positions[pos.Symbol+"_"+pos.Side] = pos
+		notional += pos.Quantity * pos.MarkPrice
+	}
+	prevWallet, prevPos, prevTime, seen, opened := g.lastWallet, g.lastPos, g.lastTime, g.seen, g.openedNotional
+	g.lastWallet, g.lastPos, g.lastTime, g.seen, g.openedNotional = wallet, positions, now, true, 0
+	if !seen {
+		return
+	}
+
+	// 上个周期以来平掉/减掉的持仓
+	estimated := 0.0 // 按上个周期的未实现盈亏估算的已实现盈亏
+	closedNotional := 0.0
+	closedSymbols := make(map[string]bool)
+	for key, prev := range prevPos {
+		if prev.Quantity <= 0 {
+			continue
+		}
+		closedQty := prev.Quantity
+		if cur, ok := positions[key]; ok {
+			closedQty = math.Max(prev.Quantity-cur.Quantity, 0)
+		}
+		if closedQty == 0 {
+			continue
+		}
+		estimated += prev.UnrealizedPnL * closedQty / prev.Quantity
+		closedNotional += closedQty * prev.MarkPrice
+		closedSymbols[prev.Symbol] = true
+	}
+
+	change := wallet - prevWallet
+	income, ledgerSymbols, hasLedger, err := at.ledgerIncome(prevTime, now)
+	// 平掉的持仓还没有盈亏流水（交易所流水延迟）时无法确认
+	ambiguous := ""
+	switch {
+	case !hasLedger:
+		ambiguous = "交易所不提供盈亏流水"
+	case err != nil:
+		ambiguous = err.Error()
+	default:
+		for symbol := range closedSymbols {
+			if !ledgerSymbols[symbol] {
+				ambiguous = fmt.Sprintf("%s 平仓后还没有盈亏流水", symbol)
+				break
+			}
+		}
+	}
+
+	explained := income
+	slack := (notional + opened + closedNotional) * feeFundingSlackPct / 100
+	if ambiguous != "" {
+		// 没有可用的流水：按估算的盈亏放宽误差，只用于判断是否告警
+		explained = estimated
+		slack += closedNotional*closeSlippagePct/100 + opened*unseenPositionSlackPct/100
+	}
+	flow := change - explained
+	threshold := math.Max(ctx.Account.TotalEquity*g.thresholdPct/100, minExternalFlowUSD) + slack
+	if math.Abs(flow) <= threshold {
+		return
+	}
+
+	if ambiguous != "" {
+		// 无法确认是外部资金流动（可能是止损滑点等真实亏损）：只告警，不调整业绩基准
+		log.Printf("⚠️ [%s] 钱包余额变化 %+.2f USDT 无法用估算的交易盈亏 %+.2f 解释（%s），未调整业绩基准", at.name, change, explained, ambiguous)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ 钱包余额变化 %+.2f USDT，估算的交易盈亏 %+.2f，差额 %+.2f 无法确认来源（%s），未调整业绩基准",
+			change, explained, flow, ambiguous))
+		notify.Send(notify.Event{
+			Type:     "account.unexplained_balance",
+			Severity: notify.SeverityWarning,
+			TraderID: at.id,
+			Title:    fmt.Sprintf("%s 钱包余额变化无法对账 %+.2f USDT", at.name, flow),
+			Message: fmt.Sprintf("钱包余额变化 %+.2f USDT，按持仓估算的交易盈亏 %+.2f USDT，无法用交易所流水确认差额的来源（%s）。差额按交易盈亏计算，未调整业绩基准；如有充值/提现请检查账户",
+				change, explained, ambiguous),
+		})
+		return
+	}
+
+	// 外部资金流动：计入业绩基准，避免被当成交易盈亏
+	at.initialBalance += flow
+	at.externalFlows += flow
+	if at.derisk.dayStartEquity > 0 {
+		at.derisk.dayStartEquity += flow
+	}
+	at.saveRiskState()
+	ctx.Account.TotalPnL -= flow
+	if at.initialBalance > 0 {
+		ctx.Account.TotalPnLPct = ctx.Account.TotalPnL / at.initialBalance * 100
+	}
+	record.ExternalFlow = flow
+
+	kind := "转入/充值"
+	if flow < 0 {
+		kind = "转出/提现"
+	}
+	log.Printf("💸 [%s] 检测到外部资金流动 %+.2f USDT（%s）：钱包余额变化 %+.2f，交易所流水 %+.2f", at.name, flow, kind, change, explained)
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("💸 检测到外部资金流动 %+.2f USDT（%s），钱包余额变化 %+.2f，交易所流水 %+.2f，已调整业绩基准",
+		flow, kind, change, explained))
+	notify.Send(notify.Event{
+		Type:     "account.external_flow",
+		Severity: notify.SeverityWarning,
+		TraderID: at.id,
+		Title:    fmt.Sprintf("%s 检测到外部资金流动 %+.2f USDT", at.name, flow),
+		Message: fmt.Sprintf("钱包余额变化 %+.2f USDT，其中交易所盈亏和资金费流水 %+.2f USDT，差额按%s处理并计入业绩基准（初始余额调整为 %.2f）。如非本人操作请检查账户",
+			change, explained, kind, at.initialBalance),
+	})
+}
@@ -0,0 +1,90 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationEquityGuardDetectsDeposit(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	ex.RecordFills()
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableEquityGuard(2)
+	initial := at.initialBalance
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	// 平仓盈利由交易所盈亏流水解释，不算外部资金流动
+	ex.SetPrice("ETHUSDT", 3100)
+	runCycle(t, at)
+	ai.Enqueue(t, "止盈。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "锁定利润"})
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "close_long")
+	if record.ExternalFlow != 0 || at.initialBalance != initial {
+		t.Fatalf("正常平仓不应视为外部资金流动: flow=%.2f initial=%.2f", record.ExternalFlow, at.initialBalance)
+	}
+
+	// 充值 2000 USDT
+	ex.mu.Lock()
+	ex.balance += 2000
+	ex.mu.Unlock()
+	record = runCycle(t, at)
+	if math.Abs(record.ExternalFlow-2000) > 1 {
+		t.Fatalf("应检测到 +2000 外部资金流动, got %.2f", record.ExternalFlow)
+	}
+	requireExecutionLog(t, record.ExecutionLog, "外部资金流动")
+	if math.Abs(at.initialBalance-(initial+record.ExternalFlow)) > 1e-9 {
+		t.Errorf("初始余额应调整为 %.2f, got %.2f", initial+record.ExternalFlow, at.initialBalance)
+	}
+	// 总盈亏只包含交易盈亏（约 +50 减手续费）
+	if pnl := record.AccountState.TotalUnrealizedProfit; pnl < 40 || pnl > 50 {
+		t.Errorf("扣除充值后的总盈亏 = %.2f, 期望约 +50", pnl)
+	}
+
+	// 重启后累计的资金流动仍计入初始余额
+	restarted := newIntegrationTrader(t, ex, ai, "gateio")
+	if math.Abs(restarted.initialBalance-at.initialBalance) > 1e-9 {
+		t.Errorf("重启后初始余额 = %.2f, 期望 %.2f", restarted.initialBalance, at.initialBalance)
+	}
+}
+
+func TestIntegrationEquityGuardSlippedStop(t *testing.T) {
+	for _, ledger := range []bool{true, false} {
+		t.Run(map[bool]string{true: "ledger", false: "no_ledger"}[ledger], func(t *testing.T) {
+			ex, ai := setupIntegration(t)
+			if ledger {
+				ex.RecordFills()
+			}
+			at := newIntegrationTrader(t, ex, ai, "gateio")
+			at.EnableEquityGuard(2)
+			initial := at.initialBalance
+
+			ai.Enqueue(t, "开多。", openLongETH(1500))
+			requireActionSuccess(t, runCycle(t, at), "open_long")
+			ex.SetPrice("ETHUSDT", 2950)
+			runCycle(t, at)
+
+			// 价格跳空击穿止损 2900，按 2000 成交：亏损远超上个周期的未实现盈亏
+			ex.SetPrice("ETHUSDT", 2000)
+			record := runCycle(t, at)
+			if record.ExternalFlow != 0 || at.initialBalance != initial || at.externalFlows != 0 {
+				t.Fatalf("止损滑点不应计为外部资金流动: flow=%.2f initial=%.2f", record.ExternalFlow, at.initialBalance)
+			}
+			unexplained := false
+			for _, line := range record.ExecutionLog {
+				if strings.Contains(line, "无法确认来源") {
+					unexplained = true
+				}
+			}
+			if ledger && unexplained {
+				t.Errorf("盈亏流水可以解释止损亏损，不应告警: %v", record.ExecutionLog)
+			}
+			if !ledger && !unexplained {
+				t.Errorf("没有盈亏流水时应告警无法确认: %v", record.ExecutionLog)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"nofx/errs"
+	"strconv"
+	"strings"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/sonirico/go-hyperliquid"
+)
+
+// 各交易所错误码 → 统一错误类型（见 nofx/errs）
+// 只列出需要区别对待的错误码，其余错误保留原始错误码和信息，Kind 按HTTP状态码判断
+
+// binanceErrorKinds 币安合约错误码（Aster 兼容同一套错误码）
+var binanceErrorKinds = map[int64]error{
+	-1003: errs.ErrRateLimited, // TOO_MANY_REQUESTS
+	-1015: errs.ErrRateLimited, // TOO_MANY_ORDERS
+	-1002: errs.ErrAuth,        // UNAUTHORIZED
+	-1022: errs.ErrAuth,        // INVALID_SIGNATURE
+	-2014: errs.ErrAuth,        // BAD_API_KEY_FMT
+	-2015: errs.ErrAuth,        // REJECTED_MBX_KEY
+	-1121: errs.ErrInvalidSymbol,
+	-4141: errs.ErrInvalidSymbol, // 合约已下架
+	-2018: errs.ErrInsufficientMargin,
+	-2019: errs.ErrInsufficientMargin,
+	-1111: errs.ErrOrderRejected, // 精度超限
+	-2010: errs.ErrOrderRejected,
+	-2021: errs.ErrOrderRejected, // 条件单会立即触发
+	-2022: errs.ErrOrderRejected, // ReduceOnly 被拒
+	-4003: errs.ErrOrderRejected, // 数量小于等于0
+	-4005: errs.ErrOrderRejected, // 数量超过上限
+	-4164: errs.ErrOrderRejected, // 名义价值低于下限
+	-4016: errs.ErrOrderRejected, // 价格高于标记价格上限
+	-4024: errs.ErrOrderRejected, // 价格低于标记价格下限
+	-4131: errs.ErrOrderRejected, // 对手价超出 PERCENT_PRICE 限制
+	-5022: errs.ErrOrderRejected, // 只挂单会立即成交
+	-2027: errs.ErrOrderRejected, // 超过当前杠杆的最大持仓
+}
+
+// gateioErrorKinds Gate.io 错误label
+var gateioErrorKinds = map[string]error{
+	"TOO_MANY_REQUESTS":       errs.ErrRateLimited,
+	"INVALID_KEY":             errs.ErrAuth,
+	"INVALID_SIGNATURE":       errs.ErrAuth,
+	"INVALID_CREDENTIALS":     errs.ErrAuth,
+	"MISSING_REQUIRED_HEADER": errs.ErrAuth,
+	"REQUEST_EXPIRED":         errs.ErrAuth,
+	"IP_FORBIDDEN":            errs.ErrAuth,
+	"READ_ONLY":               errs.ErrAuth,
+	"FORBIDDEN":               errs.ErrAuth,
+	"INSUFFICIENT_AVAILABLE":  errs.ErrInsufficientMargin,
+	"BALANCE_NOT_ENOUGH":      errs.ErrInsufficientMargin,
+	"CONTRACT_NOT_FOUND":      errs.ErrInvalidSymbol,
+	"CONTRACT_IN_DELISTING":   errs.ErrInvalidSymbol,
+	"SIZE_TOO_LARGE":          errs.ErrOrderRejected,
+	"SIZE_TOO_SMALL":          errs.ErrOrderRejected,
+	"PRICE_TOO_DEVIATED":      errs.ErrOrderRejected,
+	"REDUCE_ONLY_FAIL":        errs.ErrOrderRejected,
+	"RISK_LIMIT_EXCEEDED":     errs.ErrOrderRejected,
+	"LIQUIDATE_IMMEDIATELY":   errs.ErrOrderRejected,
+	"ORDER_POC_IMMEDIATE":     errs.ErrOrderRejected,
+	"ORDER_FOK":               errs.ErrOrderRejected,
+}
+
+// hyperliquidErrorKinds Hyperliquid 只返回错误文本，按关键字归类（小写匹配）
+var hyperliquidErrorKinds = []struct {
+	keyword string
+	kind    error
+}{
+	{"rate limit", errs.ErrRateLimited},
+	{"too many", errs.ErrRateLimited},
+	{"insufficient margin", errs.ErrInsufficientMargin},
+	{"does not exist", errs.ErrAuth}, // 钱包/API钱包不存在
+	{"unknown asset", errs.ErrInvalidSymbol},
+	{"invalid asset", errs.ErrInvalidSymbol},
+	{"order could not immediately match", errs.ErrOrderRejected},
+	{"reduce only", errs.ErrOrderRejected},
+	{"minimum value", errs.ErrOrderRejected},
+	{"tick size", errs.ErrOrderRejected},
+}
+
+// binanceNotModifiedCodes 币安/Aster 的"无需修改"错误码（目标值与当前值相同）
+var binanceNotModifiedCodes = map[string]bool{
+	"-4046": true, // No need to change margin type
+	"-4059": true, // No need to change position side
+}
+
+// notModifiedKeywords 各交易所"无需修改"的错误文本（小写匹配）
+var notModifiedKeywords = []string{"no need to change", "not modified", "not changed", "unchanged", "same as current"}
+
+// isNotModified 设置杠杆/保证金模式时交易所返回"无需修改"（目标值已是当前值），调用方视为成功
+func isNotModified(err error) bool {
+	if err == nil {
+		return false
+	}
+	var classified *errs.Error
+	if errors.As(err, &classified) && (classified.Source == "binance" || classified.Source == "aster") && binanceNotModifiedCodes[classified.Code] {
+		return true
+	}
+	lower := strings.ToLower(err.Error())
+	for _, keyword := range notModifiedKeywords {
+		if strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// binanceError 归类 go-binance 返回的错误
+func binanceError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) {
+		return &errs.Error{
+			Kind:    binanceErrorKinds[apiErr.Code],
+			Source:  "binance",
+			Code:    strconv.FormatInt(apiErr.Code, 10),
+			Message: apiErr.Message,
+			Err:     err,
+		}
+	}
+	return errs.Classify("binance", err)
+}
+
+// asterError 归类 Aster 的非200响应（body: {"code":-2019,"msg":"..."}）
+func asterError(resp *http.Response, body []byte) error {
+	e := errs.FromResponse("aster", resp, body)
+	var payload struct {
+		Code int64  `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Code != 0 {
+		e.Code = strconv.FormatInt(payload.Code, 10)
+		e.Message = payload.Msg
+		if kind, ok := binanceErrorKinds[payload.Code]; ok {
+			e.Kind = kind
+		}
+	}
+	return e
+}
+
+// gateioError 归类 Gate.io 的非2xx响应（body: {"label":"...","message":"..."}）
+func gateioError(resp *http.Response, body []byte) error {
+	e := errs.FromResponse("gateio", resp, body)
+	var payload struct {
+		Label   string `json:"label"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Label != "" {
+		e.Code = payload.Label
+		e.Message = payload.Message
+		if kind, ok := gateioErrorKinds[payload.Label]; ok {
+			e.Kind = kind
+		}
+	}
+	return e
+}
+
+// hyperliquidError 归类 go-hyperliquid 返回的错误
+func hyperliquidError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var classified *errs.Error
+	if errors.As(err, &classified) {
+		return err
+	}
+	e := &errs.Error{Source: "hyperliquid", Err: err}
+	var apiErr hyperliquid.APIError
+	if errors.As(err, &apiErr) {
+		e.Code = strconv.Itoa(apiErr.Code)
+		e.Message = apiErr.Message
+	}
+	lower := strings.ToLower(err.Error())
+	for _, rule := range hyperliquidErrorKinds {
+		if strings.Contains(lower, rule.keyword) {
+			e.Kind = rule.kind
+			return e
+		}
+	}
+	if network := errs.Classify("hyperliquid", err); network != err {
+		return network
+	}
+	if e.Code == "" {
+		return err // 无法归类，保留原始错误
+	}
+	return e
+}
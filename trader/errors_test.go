@@ -0,0 +1,109 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"nofx/errs"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+func TestExchangeErrorKinds(t *testing.T) {
+	response := func(status int) *http.Response {
+		return &http.Response{StatusCode: status, Header: http.Header{}}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"币安限频", binanceError(&common.APIError{Code: -1003, Message: "Too many requests"}), errs.ErrRateLimited},
+		{"币安保证金不足", binanceError(&common.APIError{Code: -2019, Message: "Margin is insufficient."}), errs.ErrInsufficientMargin},
+		{"币安无效币种", binanceError(&common.APIError{Code: -1121, Message: "Invalid symbol."}), errs.ErrInvalidSymbol},
+		{"币安密钥错误", binanceError(&common.APIError{Code: -2015, Message: "Invalid API-key"}), errs.ErrAuth},
+		{"币安网络错误", binanceError(io.ErrUnexpectedEOF), errs.ErrNetwork},
+		{"Aster订单被拒", asterError(response(400), []byte(`{"code":-4164,"msg":"Order's notional must be no smaller than 5.0"}`)), errs.ErrOrderRejected},
+		{"Aster HTTP 429", asterError(response(429), []byte(`rate limited`)), errs.ErrRateLimited},
+		{"Gate保证金不足", gateioError(response(400), []byte(`{"label":"INSUFFICIENT_AVAILABLE","message":"balance not enough"}`)), errs.ErrInsufficientMargin},
+		{"Gate合约不存在", gateioError(response(400), []byte(`{"label":"CONTRACT_NOT_FOUND","message":"contract not found"}`)), errs.ErrInvalidSymbol},
+		{"Gate签名错误", gateioError(response(401), []byte(`{"label":"INVALID_SIGNATURE","message":"Signature mismatch"}`)), errs.ErrAuth},
+		{"Hyperliquid保证金不足", hyperliquidError(errors.New("Insufficient margin to place order. asset=3")), errs.ErrInsufficientMargin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 上层用 fmt.Errorf 包装后仍然可以识别
+			wrapped := fmt.Errorf("开仓失败: %w", tt.err)
+			if !errors.Is(wrapped, tt.want) {
+				t.Errorf("errors.Is(%v, %v) = false", wrapped, tt.want)
+			}
+			if kind := errs.KindOf(wrapped); kind != tt.want {
+				t.Errorf("KindOf = %v, 期望 %v", kind, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNotModified(t *testing.T) {
+	response := func(status int) *http.Response {
+		return &http.Response{StatusCode: status, Header: http.Header{}}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"币安保证金模式无需修改", binanceError(&common.APIError{Code: -4046, Message: "No need to change margin type."}), true},
+		{"Aster持仓模式无需修改", asterError(response(400), []byte(`{"code":-4059,"msg":"No need to change position side."}`)), true},
+		{"Gate杠杆未修改", gateioError(response(400), []byte(`{"label":"INVALID_PARAM_VALUE","message":"leverage not modified"}`)), true},
+		{"Hyperliquid杠杆未变", hyperliquidError(errors.New("Leverage unchanged")), true},
+		{"币安其他错误码", binanceError(&common.APIError{Code: -4028, Message: "Leverage 200 is not valid"}), false},
+		{"Gate其他错误", gateioError(response(400), []byte(`{"label":"LEVERAGE_TOO_HIGH","message":"leverage too high"}`)), false},
+	}
+	for _, tt := range tests {
+		if got := isNotModified(fmt.Errorf("设置杠杆失败: %w", tt.err)); got != tt.want {
+			t.Errorf("%s: isNotModified = %v, 期望 %v", tt.name, got, tt.want)
+		}
+	}
+	if isNotModified(nil) {
+		t.Error("nil 不是无需修改错误")
+	}
+}
+
+func TestGateioRateLimitRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"label": "TOO_MANY_REQUESTS", "message": "Request Rate limit Exceeded"})
+	}))
+	defer server.Close()
+
+	tr, err := NewGateioTrader("test-key", "test-secret", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.baseURL = server.URL
+	tr.client = server.Client()
+
+	_, err = tr.doRequest("GET", "/futures/usdt/accounts", nil, "")
+	if !errors.Is(err, errs.ErrRateLimited) {
+		t.Fatalf("期望限频错误, 实际: %v", err)
+	}
+	if !errs.Retryable(err) {
+		t.Error("限频错误应可重试")
+	}
+	if got := errs.RetryAfter(err); got != 7*time.Second {
+		t.Errorf("RetryAfter = %v, 期望 7s", got)
+	}
+
+	var apiErr *errs.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "TOO_MANY_REQUESTS" {
+		t.Errorf("应保留交易所错误码: %+v", apiErr)
+	}
+}
@@ -0,0 +1,105 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"nofx/events"
+	"nofx/logger"
+	"strings"
+)
+
+// 实时事件（见 events 包，由API的 /ws 推送）
+// 周期开始、风控过滤后的决策、下单类动作成交、交易所侧止损触发和风控事件（降风险升级、强平/ADL）发布到事件总线。
+// 交易所实时推送（realtime_stream）给出平仓原因；没有推送时，持仓在两个周期之间消失且当前价更接近记录的止损价
+// 而不是止盈价，视为止损触发（inferred=true）。
+
+// CycleStartedData cycle_started 事件内容
+type CycleStartedData struct {
+	Cycle int `json:"cycle"`
+}
+
+// DecisionEventData decision_made 事件内容（一个决策一个事件）
+type DecisionEventData struct {
+	Cycle int `json:"cycle"`
+	decision.Decision
+}
+
+// SLTriggeredData sl_triggered 事件内容
+type SLTriggeredData struct {
+	Side     string  `json:"side"`
+	StopLoss float64 `json:"stop_loss,omitempty"` // 记录的止损价
+	Price    float64 `json:"price,omitempty"`     // 推断时的当前价
+	Reason   string  `json:"reason"`
+	Inferred bool    `json:"inferred"` // 按价格推断（没有交易所推送）
+}
+
+// RiskBreachData risk_breach 事件内容
+type RiskBreachData struct {
+	Kind    string  `json:"kind"`               // derisk / flash_crash / liquidation
+	Level   string  `json:"level,omitempty"`    // 降风险等级
+	LossPct float64 `json:"loss_pct,omitempty"` // 日内亏损百分比
+	Side    string  `json:"side,omitempty"`
+	Reason  string  `json:"reason"`
+}
+
+// publishEvent 发布事件
+func (at *AutoTrader) publishEvent(t events.Type, symbol string, data interface{}) {
+	events.Publish(events.Event{Type: t, TraderID: at.id, Symbol: symbol, Data: data})
+}
+
+// publishDecisions 发布即将执行的决策
+func (at *AutoTrader) publishDecisions(decisions []decision.Decision) {
+	for _, d := range decisions {
+		at.publishEvent(events.DecisionMade, d.Symbol, DecisionEventData{Cycle: at.callCount, Decision: d})
+	}
+}
+
+// publishOrderFilled 下单类动作成功后发布成交事件
+func (at *AutoTrader) publishOrderFilled(action *logger.DecisionAction) {
+	switch action.Action {
+	case "open_long", "open_short", "close_long", "close_short", "partial_close", "add_to_position":
+		filled := *action
+		filled.Success = true // 调用方在返回后才标记成功
+		at.publishEvent(events.OrderFilled, action.Symbol, filled)
+	}
+}
+
+// publishExchangeClose 交易所推送的平仓：止损触发和强平/ADL
+func (at *AutoTrader) publishExchangeClose(event PositionClosedEvent) {
+	switch {
+	case strings.Contains(event.Reason, "止损"):
+		at.publishEvent(events.SLTriggered, event.Symbol, SLTriggeredData{Side: event.Side, Reason: event.Reason})
+	case strings.Contains(event.Reason, "强平") || strings.Contains(event.Reason, "ADL"):
+		at.publishEvent(events.RiskBreach, event.Symbol, RiskBreachData{Kind: "liquidation", Side: event.Side, Reason: event.Reason})
+	}
+}
+
+// publishVanishedStops 没有交易所推送时，按价格推断消失的持仓是否因止损平仓（只在有订阅者时查询价格）
+func (at *AutoTrader) publishVanishedStops(current map[string]bool) {
+	if events.Subscribers() == 0 {
+		return
+	}
+	if _, ok := at.trader.(StreamingTrader); ok && at.stream != nil {
+		return // 推送会给出准确原因
+	}
+	for posKey, stops := range at.positionStops {
+		if current[posKey] || stops == nil || stops.StopLoss <= 0 {
+			continue
+		}
+		idx := strings.LastIndex(posKey, "_")
+		if idx < 0 {
+			continue
+		}
+		symbol, side := posKey[:idx], posKey[idx+1:]
+		price, err := at.trader.GetMarketPrice(symbol)
+		if err != nil || price <= 0 {
+			continue
+		}
+		if stops.TakeProfit > 0 && math.Abs(price-stops.TakeProfit) < math.Abs(price-stops.StopLoss) {
+			continue
+		}
+		at.publishEvent(events.SLTriggered, symbol, SLTriggeredData{
+			Side: side, StopLoss: stops.StopLoss, Price: price, Reason: "持仓消失，当前价接近止损价", Inferred: true,
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/events"
+	"nofx/logger"
+	"testing"
+)
+
+// drainEvents 取出已发布的事件
+func drainEvents(sub *events.Subscription) []events.Event {
+	var got []events.Event
+	for {
+		select {
+		case e := <-sub.C:
+			got = append(got, e)
+		default:
+			return got
+		}
+	}
+}
+
+func eventTypes(list []events.Event) []events.Type {
+	types := make([]events.Type, 0, len(list))
+	for _, e := range list {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestIntegrationEventStream(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	sub := events.Subscribe(64, func(e events.Event) bool { return e.TraderID == at.id })
+	defer sub.Close()
+
+	// 开仓周期：周期开始 → 决策 → 成交
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	got := drainEvents(sub)
+	want := []events.Type{events.CycleStarted, events.DecisionMade, events.OrderFilled}
+	if types := eventTypes(got); len(types) != len(want) || types[0] != want[0] || types[1] != want[1] || types[2] != want[2] {
+		t.Fatalf("事件顺序 = %v, 期望 %v", types, want)
+	}
+	if d := got[1].Data.(DecisionEventData); d.Cycle != 1 || d.Action != "open_long" || got[1].Symbol != "ETHUSDT" {
+		t.Fatalf("决策事件内容不对: %+v", got[1])
+	}
+	if fill := got[2].Data.(logger.DecisionAction); !fill.Success || fill.Quantity <= 0 || fill.Price <= 0 {
+		t.Fatalf("成交事件应带成交数量和价格: %+v", fill)
+	}
+	if got[1].Seq >= got[2].Seq {
+		t.Fatalf("序号应递增: %d, %d", got[1].Seq, got[2].Seq)
+	}
+
+	// 止损在交易所侧触发：下个周期发现持仓消失且价格接近止损价
+	ex.SetPrice("ETHUSDT", 2890)
+	ai.Enqueue(t, "观望。", decision.Decision{Symbol: "BTCUSDT", Action: "wait", Reasoning: "无信号"})
+	runCycle(t, at)
+	var sl *events.Event
+	for _, e := range drainEvents(sub) {
+		if e.Type == events.SLTriggered {
+			e := e
+			sl = &e
+		}
+	}
+	if sl == nil {
+		t.Fatal("止损触发后应发布 sl_triggered")
+	}
+	if data := sl.Data.(SLTriggeredData); !data.Inferred || data.Side != "long" || data.StopLoss != 2900 || sl.Symbol != "ETHUSDT" {
+		t.Fatalf("止损事件内容不对: %+v", sl)
+	}
+
+	// 交易所推送的强平
+	at.publishExchangeClose(PositionClosedEvent{Symbol: "BTCUSDT", Side: "short", Reason: "强平"})
+	if got := drainEvents(sub); len(got) != 1 || got[0].Type != events.RiskBreach || got[0].Data.(RiskBreachData).Kind != "liquidation" {
+		t.Fatalf("强平应发布 risk_breach: %+v", got)
+	}
+}
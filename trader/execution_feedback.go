@@ -0,0 +1,69 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// 决策执行反馈
+// 每个AI周期结束后，把每条决策的实际结果（成交数量和价格、失败原因、挂出的止损止盈、被风控/限流/审批拦截的原因）
+// 整理成执行报告，写入下个周期的prompt，让AI知道上个周期的指令是否生效，避免基于没有发生的成交继续决策。
+// 报告按决策与执行记录（同币种同动作按顺序对应）以及执行日志中提到该决策的行生成；wait/hold 不报告。
+
+// collectExecutionFeedback 整理本周期AI决策的执行结果
+func collectExecutionFeedback(cycle int, decisions []decision.Decision, record *logger.DecisionRecord) *decision.ExecutionFeedback {
+	fb := &decision.ExecutionFeedback{Cycle: cycle, Time: time.Now()}
+	used := make([]bool, len(record.Decisions))
+	for _, d := range decisions {
+		if d.Action == "wait" || d.Action == "hold" {
+			continue
+		}
+		report := decision.ExecutionReport{Symbol: d.Symbol, Action: d.Action, Status: decision.ExecNotExecuted}
+		for i := range record.Decisions {
+			action := &record.Decisions[i]
+			if used[i] || action.Symbol != d.Symbol || action.Action != d.Action {
+				continue
+			}
+			used[i] = true
+			fillExecutionReport(&report, action)
+			break
+		}
+
+		// 执行日志中提到该决策的调整/拦截说明（执行结果本身已在报告中）
+		key := d.Symbol + " " + d.Action
+		for _, line := range record.ExecutionLog {
+			if !strings.Contains(line, key) || strings.HasPrefix(line, "✓ "+key) || strings.HasPrefix(line, "❌ "+key) {
+				continue
+			}
+			report.Notes = append(report.Notes, line)
+		}
+		fb.Reports = append(fb.Reports, report)
+	}
+	return fb
+}
+
+// fillExecutionReport 按执行记录填写报告
+func fillExecutionReport(report *decision.ExecutionReport, action *logger.DecisionAction) {
+	if !action.Success {
+		report.Status = decision.ExecFailed
+		report.Error = action.Error
+		report.RejectReason = action.RejectReason
+		return
+	}
+	report.Status = decision.ExecExecuted
+	if action.RequestedQuantity > action.Quantity {
+		report.Status = decision.ExecPartial
+		report.RequestedQuantity = action.RequestedQuantity
+	}
+	report.Quantity = action.Quantity
+	report.Price = action.FillPrice
+	if report.Price == 0 {
+		report.Price = action.Price
+	}
+	report.IntendedPrice = action.IntendedPrice
+	report.StopLoss = action.StopLoss
+	report.TakeProfit = action.TakeProfit
+	report.ProtectionError = action.ProtectionError
+}
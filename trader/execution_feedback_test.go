@@ -0,0 +1,50 @@
+package trader
+
+import (
+	"nofx/decision"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationExecutionFeedback(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableDecisionThrottle(DecisionThrottleConfig{MaxNewPositions: 1, MaxPerSymbol: 1})
+
+	openBTC := decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1500,
+		StopLoss: 58000, TakeProfit: 66000, Confidence: 90, RiskUSD: 50, Reasoning: "更强的突破",
+	}
+	ai.Enqueue(t, "开多BTC和ETH，平掉SOL空仓。", openLongETH(1500), openBTC,
+		decision.Decision{Symbol: "SOLUSDT", Action: "close_short", Reasoning: "止盈"},
+		decision.Decision{Symbol: "XRPUSDT", Action: "wait", Reasoning: "观望"})
+	runCycle(t, at)
+
+	ai.Enqueue(t, "观望。")
+	runCycle(t, at)
+	prompt := ai.Prompts()[1]
+	if strings.Contains(ai.Prompts()[0], "上个周期决策执行反馈") {
+		t.Error("第一个周期不应有执行反馈")
+	}
+	for _, want := range []string{
+		"## 🧾 上个周期决策执行反馈（周期 #1",
+		"BTCUSDT open_long: ✓ 已执行，成交",
+		"止损 58000.0000 / 止盈 66000.0000 已挂出",
+		"ETHUSDT open_long: ⏭ 未执行 | 🚦 ETHUSDT open_long 被决策限流跳过",
+		"SOLUSDT close_short: ❌ 执行失败",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("AI输入中缺少 %q", want)
+		}
+	}
+	if strings.Contains(prompt, "XRPUSDT wait") {
+		t.Error("wait 不应出现在执行反馈中")
+	}
+
+	// 反馈只针对上一个周期（周期 #2 没有需要执行的决策）
+	ai.Enqueue(t, "观望。")
+	runCycle(t, at)
+	if last := ai.Prompts()[2]; strings.Contains(last, "上个周期决策执行反馈") {
+		t.Errorf("执行反馈应替换为上个周期的结果")
+	}
+}
@@ -0,0 +1,171 @@
+package trader
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// 策略A/B测试
+// 实验让trader按计划在两个或多个策略配置（变体）之间轮换，用同一个账户、同一段行情比较提示词模板、杠杆或扫描间隔的效果：
+// alternate_days 按天轮流；random_blocks 把时间切成固定时长的区组，每 N 个连续区组（N 为变体数）内各变体随机排列、
+// 各出现一次（随机区组设计，避免"周一总是A"这类与时间相关的偏差，同时保持各变体的运行时长均衡）。
+// 区组按trader时区的零点对齐。每个周期开始前按当前时间确定变体，变化时切换策略配置；
+// 决策记录标记实验名称和变体，平仓交易沿用开仓时的变体，按变体汇总和显著性检验见 logger.ExperimentReport。
+
+const (
+	ExperimentAlternateDays = "alternate_days" // 按天轮流
+	ExperimentRandomBlocks  = "random_blocks"  // 随机区组
+)
+
+// ExperimentConfig 实验配置
+type ExperimentConfig struct {
+	Name     string            // 实验名称（决策记录中的标签）
+	Schedule string            // ExperimentAlternateDays / ExperimentRandomBlocks
+	Block    time.Duration     // random_blocks 的区组时长（整小时，能整除24小时或是24小时的整数倍）
+	Seed     int64             // random_blocks 的随机种子（同一种子得到同一排列）
+	Variants []StrategyProfile // 轮换的策略配置（至少2个）
+}
+
+// ExperimentStatus 实验当前状态（用于API）
+type ExperimentStatus struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Variants []string  `json:"variants"`
+	Active   string    `json:"active"`    // 当前变体
+	BlockEnd time.Time `json:"block_end"` // 当前区组结束（下一次可能切换）的时间
+}
+
+// experimentState 运行中的实验
+type experimentState struct {
+	config  ExperimentConfig
+	active  int       // 当前变体序号（-1 表示尚未切换）
+	blockAt time.Time // 当前区组结束时间
+}
+
+// EnableExperiment 启用A/B测试（下个周期开始时切换到当前区组的变体）
+func (at *AutoTrader) EnableExperiment(cfg ExperimentConfig) error {
+	if at.strategy() != StrategyAI {
+		return fmt.Errorf("实验 '%s': A/B测试只支持AI策略（当前 %s）", cfg.Name, at.strategy())
+	}
+	if len(cfg.Variants) < 2 {
+		return fmt.Errorf("实验 '%s' 至少需要2个变体", cfg.Name)
+	}
+	if cfg.Name == "" {
+		cfg.Name = at.id
+	}
+	switch cfg.Schedule {
+	case "":
+		cfg.Schedule = ExperimentAlternateDays
+	case ExperimentAlternateDays, ExperimentRandomBlocks:
+	default:
+		return fmt.Errorf("实验 '%s' 的轮换方式 '%s' 无效（可选 %s / %s）", cfg.Name, cfg.Schedule, ExperimentAlternateDays, ExperimentRandomBlocks)
+	}
+	if cfg.Schedule == ExperimentAlternateDays || cfg.Block <= 0 {
+		cfg.Block = 24 * time.Hour
+	}
+	if err := validExperimentBlock(cfg.Block); err != nil {
+		return fmt.Errorf("实验 '%s': %w", cfg.Name, err)
+	}
+	for _, p := range cfg.Variants {
+		if err := at.checkProfile(p); err != nil {
+			return fmt.Errorf("实验 '%s' 的变体 '%s' 无效: %w", cfg.Name, p.Name, err)
+		}
+	}
+
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+	at.experiment = &experimentState{config: cfg, active: -1}
+	log.Printf("🧪 [%s] 启用A/B测试 %s：%d 个变体，%s，区组 %v", at.name, cfg.Name, len(cfg.Variants), cfg.Schedule, cfg.Block)
+	return nil
+}
+
+// validExperimentBlock 区组时长必须是整小时，并能与日界对齐
+func validExperimentBlock(block time.Duration) error {
+	hours := int(block / time.Hour)
+	if block%time.Hour != 0 || hours <= 0 || (hours < 24 && 24%hours != 0) || (hours > 24 && hours%24 != 0) {
+		return fmt.Errorf("区组时长 %v 无效（需为能整除24的小时数或24小时的整数倍）", block)
+	}
+	return nil
+}
+
+// variantAt 某个时刻所在区组的变体序号和区组结束时间
+func (c ExperimentConfig) variantAt(t time.Time, loc *time.Location) (int, time.Time) {
+	local := t.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400 // 日历日序号（不受夏令时影响）
+
+	hours := int64(c.Block / time.Hour)
+	var index int64
+	var end time.Time
+	if hours <= 24 {
+		perDay := 24 / hours
+		slot := int64(local.Hour()) / hours
+		index = day*perDay + slot
+		end = midnight.Add(time.Duration((slot+1)*hours) * time.Hour)
+		if slot == perDay-1 {
+			end = midnight.AddDate(0, 0, 1)
+		}
+	} else {
+		days := hours / 24
+		index = day / days
+		end = midnight.AddDate(0, 0, int(days-day%days))
+	}
+
+	n := int64(len(c.Variants))
+	if c.Schedule != ExperimentRandomBlocks {
+		return int(index % n), end
+	}
+	// 每 n 个区组为一组，组内随机排列
+	h := fnv.New64a()
+	h.Write([]byte(c.Name))
+	group := index / n
+	perm := rand.New(rand.NewSource(c.Seed ^ int64(h.Sum64()) ^ group)).Perm(int(n))
+	return perm[index%n], end
+}
+
+// rotateExperiment 切换到当前区组的变体，调用方需持有 cycleMu
+func (at *AutoTrader) rotateExperiment(now time.Time) {
+	exp := at.experiment
+	if exp == nil {
+		return
+	}
+	index, end := exp.config.variantAt(now, at.location)
+	exp.blockAt = end
+	p := exp.config.Variants[index]
+	if index == exp.active && at.config.Profile == p.Name {
+		return // 区组内手动切换过策略配置时，下个周期恢复为实验变体
+	}
+	at.applyProfileLocked(p)
+	exp.active = index
+	log.Printf("🧪 [%s] 实验 %s 切换到变体 %s（%s 前有效）", at.name, exp.config.Name, p.Name, end.In(at.location).Format("2006-01-02 15:04"))
+}
+
+// experimentTag 当前实验名称和变体（未启用实验或尚未切换时为空）
+func (at *AutoTrader) experimentTag() (string, string) {
+	exp := at.experiment
+	if exp == nil || exp.active < 0 {
+		return "", ""
+	}
+	return exp.config.Name, at.config.Profile
+}
+
+// GetExperiment 实验当前状态（未启用实验时为nil）
+func (at *AutoTrader) GetExperiment() *ExperimentStatus {
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+	exp := at.experiment
+	if exp == nil {
+		return nil
+	}
+	status := &ExperimentStatus{Name: exp.config.Name, Schedule: exp.config.Schedule, BlockEnd: exp.blockAt}
+	for _, p := range exp.config.Variants {
+		status.Variants = append(status.Variants, p.Name)
+	}
+	if exp.active >= 0 {
+		status.Active = exp.config.Variants[exp.active].Name
+	}
+	return status
+}
@@ -0,0 +1,146 @@
+package trader
+
+import (
+	"nofx/decision"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExperimentSchedule(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	variants := []StrategyProfile{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	// 按天轮流：相邻两天不同，隔天回到同一变体，区组在当地零点结束
+	days := ExperimentConfig{Name: "days", Schedule: ExperimentAlternateDays, Block: 24 * time.Hour, Variants: variants[:2]}
+	start := time.Date(2026, 3, 10, 23, 30, 0, 0, loc)
+	first, end := days.variantAt(start, loc)
+	if !end.Equal(time.Date(2026, 3, 11, 0, 0, 0, 0, loc)) {
+		t.Fatalf("区组应在当地零点结束: %v", end)
+	}
+	second, _ := days.variantAt(end, loc)
+	third, _ := days.variantAt(end.Add(24*time.Hour), loc)
+	if first == second || first != third {
+		t.Fatalf("应按天轮流: %d %d %d", first, second, third)
+	}
+
+	// 随机区组：每3个连续区组内各变体出现一次，各组排列不同
+	blocks := ExperimentConfig{Name: "blocks", Schedule: ExperimentRandomBlocks, Block: 8 * time.Hour, Seed: 7, Variants: variants}
+	at := time.Date(2026, 3, 10, 0, 0, 0, 0, loc)
+	var sequence []int
+	for i := 0; i < 30; i++ {
+		index, end := blocks.variantAt(at, loc)
+		if want := at.Add(8 * time.Hour); !end.Equal(want) {
+			t.Fatalf("区组 %d 结束时间 %v，期望 %v", i, end, want)
+		}
+		again, _ := blocks.variantAt(at.Add(7*time.Hour), loc)
+		if again != index {
+			t.Fatalf("同一区组内变体不应变化: %d -> %d", index, again)
+		}
+		sequence = append(sequence, index)
+		at = end
+	}
+	// 8小时区组、3个变体时每组恰好是一天
+	for g := 0; g < len(sequence); g += 3 {
+		seen := make(map[int]bool)
+		for _, v := range sequence[g : g+3] {
+			seen[v] = true
+		}
+		if len(seen) != 3 {
+			t.Fatalf("每组3个区组应各出现一次: %v", sequence[g:g+3])
+		}
+	}
+	identical := true
+	for g := 3; g < len(sequence); g += 3 {
+		if !equalInts(sequence[g:g+3], sequence[:3]) {
+			identical = false
+		}
+	}
+	if identical {
+		t.Errorf("各组的排列不应完全相同: %v", sequence)
+	}
+
+	if err := validExperimentBlock(5 * time.Hour); err == nil {
+		t.Error("5小时区组不能与日界对齐，应拒绝")
+	}
+	if err := validExperimentBlock(48 * time.Hour); err != nil {
+		t.Errorf("48小时区组应允许: %v", err)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+func TestIntegrationExperimentTagsDecisions(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	variants := []StrategyProfile{
+		{Name: "fast", ScanInterval: 3 * time.Minute, BTCETHLeverage: 5, AltcoinLeverage: 5, MaxMarginUsagePct: 80, SafetyBufferPct: 5},
+		{Name: "slow", ScanInterval: 15 * time.Minute, BTCETHLeverage: 5, AltcoinLeverage: 5, MaxMarginUsagePct: 80, SafetyBufferPct: 5},
+	}
+	if err := at.EnableExperiment(ExperimentConfig{Name: "speed", Variants: variants[:1]}); err == nil {
+		t.Fatal("只有1个变体应拒绝")
+	}
+	if err := at.EnableExperiment(ExperimentConfig{Name: "speed", Schedule: "weekly", Variants: variants}); err == nil {
+		t.Fatal("无效的轮换方式应拒绝")
+	}
+	if err := at.EnableExperiment(ExperimentConfig{Name: "speed", Variants: variants}); err != nil {
+		t.Fatalf("启用实验失败: %v", err)
+	}
+	if status := at.GetExperiment(); status == nil || status.Active != "" || status.Schedule != ExperimentAlternateDays {
+		t.Fatalf("首个周期前不应有当前变体: %+v", status)
+	}
+
+	index, _ := at.experiment.config.variantAt(time.Now(), at.location)
+	want := variants[index].Name
+
+	// 开仓周期切换到当天的变体，决策记录带上实验标签
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+	if record.Experiment != "speed" || record.Variant != want {
+		t.Fatalf("决策记录应标记实验和变体 %s: %s/%s", want, record.Experiment, record.Variant)
+	}
+	if at.GetProfile() != want || at.GetScanInterval() != variants[index].ScanInterval {
+		t.Fatalf("应切换到变体 %s: %s %v", want, at.GetProfile(), at.GetScanInterval())
+	}
+
+	// 手动切换策略配置后，下个周期恢复为实验变体
+	other := variants[1-index]
+	if err := at.ApplyProfile(other); err != nil {
+		t.Fatal(err)
+	}
+	ex.SetPrice("ETHUSDT", 3100)
+	ai.Enqueue(t, "止盈。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "到达目标"})
+	record = runCycle(t, at)
+	requireActionSuccess(t, record, "close_long")
+	if record.Variant != want || at.GetProfile() != want {
+		t.Fatalf("应恢复为实验变体 %s: %s %s", want, record.Variant, at.GetProfile())
+	}
+
+	// 按变体汇总：交易算在开仓时的变体上
+	status := at.GetExperiment()
+	report, err := at.decisionLogger.ExperimentReport(status.Name, status.Variants, 7, time.Now().In(at.location))
+	if err != nil {
+		t.Fatalf("生成实验报告失败: %v", err)
+	}
+	for _, stats := range report.Variants {
+		if stats.Variant == want {
+			if stats.Cycles != 2 || stats.Trades != 1 || stats.Wins != 1 || stats.TotalPnL <= 0 {
+				t.Errorf("变体 %s 的统计不对: %+v", want, stats)
+			}
+		} else if stats.Cycles != 0 || stats.Trades != 0 {
+			t.Errorf("变体 %s 不应有记录: %+v", stats.Variant, stats)
+		}
+	}
+	if len(report.Comparisons) != 1 || !strings.Contains(report.Comparisons[0].Note, "至少需要2笔") {
+		t.Errorf("样本不足时应说明无法检验: %+v", report.Comparisons)
+	}
+}
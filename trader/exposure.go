@@ -0,0 +1,224 @@
+package trader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 跨trader的合并敞口
+// 多个trader交易同一个币种（或共用同一个交易所账户）时，单个trader的风控看不到组合整体的方向性敞口。
+// 各trader每个周期把交易所持仓（名义价值）报告到共享的 ExposureBook，按账户（交易所+API Key/钱包地址）归并，
+// 共用账户的trader看到的是同一批持仓，只计一次。合并视图给出每个币种所有账户的多头、空头和净敞口。
+// 设置了 maxNetUSD 时，开仓/加仓前检查：执行后该币种的组合净敞口绝对值超过上限、且比执行前更大时拒绝（减少净敞口的开仓不受限）。
+// 检查和计入在同一把锁内完成，并发的trader不会同时越过上限；本周期的开仓按决策仓位计入，
+// 平仓和实际成交数量在该trader下个周期按交易所持仓刷新。
+
+// ExposureBook 所有trader共享的持仓敞口账本
+type ExposureBook struct {
+	mu        sync.Mutex
+	maxNetUSD float64                     // 单个币种组合净敞口上限（0表示不限制）
+	accounts  map[string]*accountExposure // 账户 -> 持仓
+}
+
+// accountExposure 一个交易所账户的持仓
+type accountExposure struct {
+	traders   map[string]bool    // 报告该账户的trader ID
+	legs      map[string]float64 // symbol_side -> 名义价值（USDT，正数）
+	updatedAt time.Time
+}
+
+// SymbolExposure 单个币种在所有账户上的合并敞口
+type SymbolExposure struct {
+	Symbol   string             `json:"symbol"`
+	LongUSD  float64            `json:"long_usd"`
+	ShortUSD float64            `json:"short_usd"`
+	NetUSD   float64            `json:"net_usd"`  // 多头 - 空头
+	Accounts map[string]float64 `json:"accounts"` // 各账户的净敞口（键为该账户上的trader ID，共用账户时用逗号连接）
+}
+
+// AccountExposureInfo 账户的报告状态
+type AccountExposureInfo struct {
+	Account   string    `json:"account"` // 交易所:账户标识的哈希前缀
+	Traders   []string  `json:"traders"`
+	UpdatedAt time.Time `json:"updated_at"` // 最近一次按交易所持仓刷新的时间
+}
+
+// ExposureView 合并敞口视图
+type ExposureView struct {
+	MaxNetUSD float64               `json:"max_net_usd"` // 0表示不限制
+	GrossUSD  float64               `json:"gross_usd"`   // 所有币种多空名义价值之和
+	Symbols   []SymbolExposure      `json:"symbols"`     // 按净敞口绝对值从大到小
+	Accounts  []AccountExposureInfo `json:"accounts"`
+}
+
+// NewExposureBook 创建敞口账本，maxNetUSD 为单个币种组合净敞口上限（0表示只汇总不限制）
+func NewExposureBook(maxNetUSD float64) *ExposureBook {
+	return &ExposureBook{maxNetUSD: maxNetUSD, accounts: make(map[string]*accountExposure)}
+}
+
+// accountLocked 账户的持仓记录（不存在时创建），调用方需持有锁
+func (b *ExposureBook) accountLocked(account, traderID string) *accountExposure {
+	acc, ok := b.accounts[account]
+	if !ok {
+		acc = &accountExposure{traders: make(map[string]bool), legs: make(map[string]float64)}
+		b.accounts[account] = acc
+	}
+	acc.traders[traderID] = true
+	return acc
+}
+
+// update 按交易所持仓刷新账户的敞口（替换之前的记录和本周期计入的开仓）
+func (b *ExposureBook) update(account, traderID string, positions []decision.PositionInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	acc := b.accountLocked(account, traderID)
+	acc.legs = make(map[string]float64, len(positions))
+	for _, pos := range positions {
+		acc.legs[pos.Symbol+"_"+pos.Side] += pos.Quantity * pos.MarkPrice
+	}
+	acc.updatedAt = time.Now()
+}
+
+// netLocked 币种在所有账户上的净敞口，调用方需持有锁
+func (b *ExposureBook) netLocked(symbol string) float64 {
+	net := 0.0
+	for _, acc := range b.accounts {
+		net += acc.legs[symbol+"_long"] - acc.legs[symbol+"_short"]
+	}
+	return net
+}
+
+// reserve 检查开仓后的净敞口是否超过上限，未超过时计入账户
+func (b *ExposureBook) reserve(account, traderID, symbol, side string, notional float64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delta := notional
+	if side == "short" {
+		delta = -notional
+	}
+	net := b.netLocked(symbol)
+	after := net + delta
+	if b.maxNetUSD > 0 && math.Abs(after) > b.maxNetUSD && math.Abs(after) > math.Abs(net) {
+		return fmt.Errorf("❌ %s 组合净敞口超限：所有trader当前净敞口 %+.2f USDT，本次%s %.2f USDT 后为 %+.2f USDT，超过上限 %.2f USDT",
+			symbol, net, side, notional, after, b.maxNetUSD)
+	}
+	b.accountLocked(account, traderID).legs[symbol+"_"+side] += notional
+	return nil
+}
+
+// View 合并敞口视图
+func (b *ExposureBook) View() *ExposureView {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	view := &ExposureView{MaxNetUSD: b.maxNetUSD, Symbols: []SymbolExposure{}, Accounts: []AccountExposureInfo{}}
+	symbols := make(map[string]*SymbolExposure)
+	for account, acc := range b.accounts {
+		traders := make([]string, 0, len(acc.traders))
+		for id := range acc.traders {
+			traders = append(traders, id)
+		}
+		sort.Strings(traders)
+		label := strings.Join(traders, ",")
+		view.Accounts = append(view.Accounts, AccountExposureInfo{Account: account, Traders: traders, UpdatedAt: acc.updatedAt})
+
+		for leg, notional := range acc.legs {
+			if notional <= 0 {
+				continue
+			}
+			idx := strings.LastIndex(leg, "_")
+			symbol, side := leg[:idx], leg[idx+1:]
+			s, ok := symbols[symbol]
+			if !ok {
+				s = &SymbolExposure{Symbol: symbol, Accounts: make(map[string]float64)}
+				symbols[symbol] = s
+			}
+			if side == "short" {
+				s.ShortUSD += notional
+				s.Accounts[label] -= notional
+			} else {
+				s.LongUSD += notional
+				s.Accounts[label] += notional
+			}
+			view.GrossUSD += notional
+		}
+	}
+	for _, s := range symbols {
+		s.NetUSD = s.LongUSD - s.ShortUSD
+		view.Symbols = append(view.Symbols, *s)
+	}
+	sort.Slice(view.Symbols, func(i, j int) bool {
+		ni, nj := math.Abs(view.Symbols[i].NetUSD), math.Abs(view.Symbols[j].NetUSD)
+		if ni != nj {
+			return ni > nj
+		}
+		return view.Symbols[i].Symbol < view.Symbols[j].Symbol
+	})
+	sort.Slice(view.Accounts, func(i, j int) bool { return view.Accounts[i].Account < view.Accounts[j].Account })
+	return view
+}
+
+// SetExposureBook 接入共享的敞口账本（每个周期报告持仓，开仓/加仓前检查组合净敞口上限）
+func (at *AutoTrader) SetExposureBook(book *ExposureBook) {
+	at.exposure = book
+	at.exposureAccount = at.accountKey()
+}
+
+// accountKey 交易所账户标识（交易所+API Key/钱包地址的哈希前缀），共用账户的trader得到相同的标识
+func (at *AutoTrader) accountKey() string {
+	var identity string
+	switch at.exchange {
+	case "binance":
+		identity = at.config.BinanceAPIKey
+	case "gateio":
+		identity = at.config.GateioAPIKey
+	case "hyperliquid":
+		identity = strings.ToLower(at.config.HyperliquidWalletAddr)
+	case "aster":
+		identity = strings.ToLower(at.config.AsterUser)
+	}
+	if identity == "" {
+		return at.exchange + ":" + at.id
+	}
+	sum := sha256.Sum256([]byte(identity))
+	return at.exchange + ":" + hex.EncodeToString(sum[:4])
+}
+
+// reportExposure 按本周期的交易所持仓刷新敞口账本
+func (at *AutoTrader) reportExposure(positions []decision.PositionInfo) {
+	if at.exposure != nil {
+		at.exposure.update(at.exposureAccount, at.id, positions)
+	}
+}
+
+// reserveExposure 开仓/加仓前检查组合净敞口上限并计入账本
+func (at *AutoTrader) reserveExposure(d *decision.Decision) error {
+	side := d.Side
+	switch d.Action {
+	case "open_long":
+		side = "long"
+	case "open_short":
+		side = "short"
+	default:
+		if side == "" {
+			posSide, _, _, err := at.findPosition(d.Symbol, "")
+			if err != nil {
+				return err
+			}
+			side = posSide
+		}
+	}
+	if err := at.exposure.reserve(at.exposureAccount, at.id, d.Symbol, side, d.PositionSizeUSD); err != nil {
+		log.Printf("  🧮 [%s] %v", at.name, err)
+		return err
+	}
+	return nil
+}
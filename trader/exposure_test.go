@@ -0,0 +1,72 @@
+package trader
+
+import (
+	"nofx/decision"
+	"strings"
+	"testing"
+)
+
+func TestExposureBookSharedAccount(t *testing.T) {
+	book := NewExposureBook(0)
+	positions := []decision.PositionInfo{{Symbol: "ETHUSDT", Side: "long", Quantity: 1, MarkPrice: 3000}}
+
+	// 共用账户的两个trader报告同一批持仓，只计一次
+	book.update("gateio:abcd", "a", positions)
+	book.update("gateio:abcd", "b", positions)
+	book.update("binance:1234", "c", []decision.PositionInfo{{Symbol: "ETHUSDT", Side: "short", Quantity: 0.5, MarkPrice: 3000}})
+
+	view := book.View()
+	if len(view.Symbols) != 1 || len(view.Accounts) != 2 {
+		t.Fatalf("视图不符合预期: %+v", view)
+	}
+	eth := view.Symbols[0]
+	if eth.LongUSD != 3000 || eth.ShortUSD != 1500 || eth.NetUSD != 1500 || view.GrossUSD != 4500 {
+		t.Errorf("ETHUSDT 敞口不符合预期: %+v (gross %.0f)", eth, view.GrossUSD)
+	}
+	if eth.Accounts["a,b"] != 3000 || eth.Accounts["c"] != -1500 {
+		t.Errorf("分账户敞口不符合预期: %v", eth.Accounts)
+	}
+
+	// 刷新后替换之前的持仓
+	book.update("gateio:abcd", "a", nil)
+	if view := book.View(); view.Symbols[0].NetUSD != -1500 {
+		t.Errorf("刷新后净敞口应为 -1500: %+v", view.Symbols[0])
+	}
+}
+
+func TestIntegrationPortfolioExposureCap(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	book := NewExposureBook(2000)
+	gate := newIntegrationTrader(t, ex, ai, "gateio")
+	gate.SetExposureBook(book)
+	binance := newIntegrationTrader(t, ex, ai, "binance")
+	binance.SetExposureBook(book)
+	if gate.exposureAccount == binance.exposureAccount {
+		t.Fatalf("不同交易所的账户标识应不同: %s", gate.exposureAccount)
+	}
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, gate), "open_long")
+
+	// 另一个trader继续开多会使合计净敞口超过上限
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, binance)
+	if len(record.Decisions) != 1 || record.Decisions[0].Success || !strings.Contains(record.Decisions[0].Error, "组合净敞口超限") {
+		t.Fatalf("超过组合净敞口上限的开仓应被拒绝: %+v", record.Decisions)
+	}
+	if pos := ex.BinancePosition("ETHUSDT", "LONG"); pos.size != 0 {
+		t.Errorf("被拒绝的开仓不应下单: %+v", pos)
+	}
+
+	// 减少净敞口的开仓不受限
+	ai.Enqueue(t, "开空对冲。", decision.Decision{
+		Symbol: "ETHUSDT", Action: "open_short", Leverage: 5, PositionSizeUSD: 1000,
+		StopLoss: 3100, TakeProfit: 2600, Confidence: 80, RiskUSD: 40, Reasoning: "对冲",
+	})
+	requireActionSuccess(t, runCycle(t, binance), "open_short")
+
+	view := book.View()
+	if len(view.Symbols) != 1 || view.Symbols[0].LongUSD != 1500 || view.Symbols[0].ShortUSD != 1000 {
+		t.Fatalf("合并敞口不符合预期: %+v", view.Symbols)
+	}
+}
@@ -0,0 +1,268 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/events"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/notify"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 急跌熔断
+// 与日内亏损降风险阶梯（按当日起始净值）相互独立，只看短时间内的变化速度：
+//   - 账户净值在 EquityWindow 内从窗口最高点回撤超过 EquityDropPct（按每个周期的净值采样）
+//   - BTC 最近 BTCWindow 的1分钟K线中，最高/最低价相对窗口起点的波动超过 BTCMovePct（插针也算）
+// 任一条件成立即熔断：只允许平仓/减仓，不开新仓、不加仓；TightenStopPct>0 时熔断当下把持仓止损收紧到距当前价该百分比以内。
+// 条件连续 ResumeAfter 不再成立（行情企稳）后自动恢复。熔断和恢复都推送通知并写入决策记录。
+// 检测到的外部资金流动会平移净值样本，提现不会误触发；熔断状态不持久化，重启后重新采样。
+
+const (
+	defaultFlashEquityWindow = 15 * time.Minute
+	defaultFlashBTCWindow    = 5 * time.Minute
+	defaultFlashResumeAfter  = 30 * time.Minute
+	flashBTCSymbol           = "BTCUSDT"
+)
+
+// FlashCrashConfig 急跌熔断配置（阈值<=0表示不检查该条件）
+type FlashCrashConfig struct {
+	EquityDropPct  float64       // 净值在窗口内的最大回撤百分比
+	EquityWindow   time.Duration // 净值回撤的时间窗口
+	BTCMovePct     float64       // BTC在窗口内的最大波动百分比
+	BTCWindow      time.Duration // BTC波动的时间窗口
+	TightenStopPct float64       // 熔断时止损收紧到距当前价的百分比（0表示不调整止损）
+	ResumeAfter    time.Duration // 条件持续不成立多久后恢复
+}
+
+// equitySample 一个周期的净值
+type equitySample struct {
+	at     time.Time
+	equity float64
+}
+
+// flashGuard 急跌熔断状态
+type flashGuard struct {
+	cfg     FlashCrashConfig
+	samples []equitySample // 窗口内的净值样本（按时间顺序）
+
+	mu      sync.Mutex // 熔断状态也会被状态接口读取
+	halted  bool
+	since   time.Time // 熔断开始时间
+	lastHit time.Time // 最近一次条件成立的时间
+	reason  string
+}
+
+// EnableFlashCrashGuard 启用急跌熔断
+func (at *AutoTrader) EnableFlashCrashGuard(cfg FlashCrashConfig) {
+	if cfg.EquityWindow <= 0 {
+		cfg.EquityWindow = defaultFlashEquityWindow
+	}
+	if cfg.BTCWindow <= 0 {
+		cfg.BTCWindow = defaultFlashBTCWindow
+	}
+	if cfg.ResumeAfter <= 0 {
+		cfg.ResumeAfter = defaultFlashResumeAfter
+	}
+	at.flashGuard = &flashGuard{cfg: cfg}
+}
+
+// active 是否处于熔断中，以及熔断原因
+func (g *flashGuard) active() (bool, string) {
+	if g == nil {
+		return false, ""
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.halted, g.reason
+}
+
+// observeEquity 记录本周期净值，返回窗口内回撤超过阈值的说明（未超过时为空）
+// flow 为本周期检测到的外部资金流动，之前的样本按它平移
+func (g *flashGuard) observeEquity(now time.Time, equity, flow float64) string {
+	if equity <= 0 {
+		return ""
+	}
+	kept := g.samples[:0]
+	for _, s := range g.samples {
+		if now.Sub(s.at) <= g.cfg.EquityWindow {
+			s.equity += flow
+			kept = append(kept, s)
+		}
+	}
+	g.samples = append(kept, equitySample{at: now, equity: equity})
+	if g.cfg.EquityDropPct <= 0 {
+		return ""
+	}
+
+	peak := g.samples[0]
+	for _, s := range g.samples {
+		if s.equity > peak.equity {
+			peak = s
+		}
+	}
+	if peak.equity <= 0 {
+		return ""
+	}
+	dropPct := (peak.equity - equity) / peak.equity * 100
+	if dropPct < g.cfg.EquityDropPct {
+		return ""
+	}
+	return fmt.Sprintf("净值 %.0f 分钟内从 %.2f 回撤至 %.2f（-%.2f%%，阈值 %.1f%%）",
+		math.Ceil(now.Sub(peak.at).Minutes()), peak.equity, equity, dropPct, g.cfg.EquityDropPct)
+}
+
+// checkBTCMove BTC在窗口内的波动超过阈值时返回说明（获取K线失败时不判断）
+func (g *flashGuard) checkBTCMove() string {
+	if g.cfg.BTCMovePct <= 0 {
+		return ""
+	}
+	provider, err := market.ProviderFor(flashBTCSymbol)
+	if err != nil {
+		log.Printf("⚠ 急跌熔断：获取BTC行情源失败: %v", err)
+		return ""
+	}
+	minutes := int(math.Ceil(g.cfg.BTCWindow.Minutes()))
+	klines, err := market.FetchKlines(provider, flashBTCSymbol, "1m", minutes)
+	if err != nil || len(klines) == 0 || klines[0].Open <= 0 {
+		log.Printf("⚠ 急跌熔断：获取BTC 1分钟K线失败: %v", err)
+		return ""
+	}
+
+	ref := klines[0].Open
+	movePct := 0.0
+	for _, k := range klines {
+		if up := (k.High/ref - 1) * 100; math.Abs(up) > math.Abs(movePct) {
+			movePct = up
+		}
+		if down := (k.Low/ref - 1) * 100; math.Abs(down) > math.Abs(movePct) {
+			movePct = down
+		}
+	}
+	if math.Abs(movePct) < g.cfg.BTCMovePct {
+		return ""
+	}
+	return fmt.Sprintf("BTC %d 分钟内波动 %+.2f%%（阈值 %.1f%%）", minutes, movePct, g.cfg.BTCMovePct)
+}
+
+// applyFlashGuard 检查急跌条件并更新熔断状态，熔断中把只允许平仓的限制写入决策上下文
+func (at *AutoTrader) applyFlashGuard(ctx *decision.Context, record *logger.DecisionRecord) {
+	g := at.flashGuard
+	if g == nil {
+		return
+	}
+	now := time.Now()
+	var reasons []string
+	if reason := g.observeEquity(now, ctx.Account.TotalEquity, record.ExternalFlow); reason != "" {
+		reasons = append(reasons, reason)
+	}
+	if reason := g.checkBTCMove(); reason != "" {
+		reasons = append(reasons, reason)
+	}
+
+	g.mu.Lock()
+	tripped, resumed := false, false
+	if len(reasons) > 0 {
+		g.lastHit = now
+		if !g.halted {
+			g.halted, g.since, g.reason = true, now, strings.Join(reasons, "；")
+			tripped = true
+		}
+	} else if g.halted && now.Sub(g.lastHit) >= g.cfg.ResumeAfter {
+		g.halted = false
+		resumed = true
+	}
+	halted, reason, since := g.halted, g.reason, g.since
+	g.mu.Unlock()
+
+	switch {
+	case tripped:
+		at.tripFlashGuard(ctx, reason, record)
+	case resumed:
+		log.Printf("✅ [%s] 行情已企稳 %.0f 分钟，急跌熔断解除", at.name, g.cfg.ResumeAfter.Minutes())
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✅ 急跌熔断解除（%s 起，%s）", since.In(at.location).Format("15:04"), reason))
+		notify.Send(notify.Event{
+			Type:     "risk.flash_crash_resumed",
+			Severity: notify.SeverityInfo,
+			TraderID: at.id,
+			Title:    fmt.Sprintf("%s 急跌熔断解除，恢复开仓", at.name),
+			Message:  fmt.Sprintf("急跌条件已连续 %.0f 分钟不成立。熔断原因：%s", g.cfg.ResumeAfter.Minutes(), reason),
+		})
+	}
+	if !halted {
+		return
+	}
+
+	ctx.CloseOnly = true
+	notice := fmt.Sprintf("急跌熔断中（%s），只允许平仓/减仓，禁止开新仓和加仓，行情企稳 %.0f 分钟后自动恢复", reason, g.cfg.ResumeAfter.Minutes())
+	if ctx.RiskNotice != "" {
+		notice = ctx.RiskNotice + "；" + notice
+	}
+	ctx.RiskNotice = notice
+}
+
+// tripFlashGuard 熔断：记录、通知，按配置收紧持仓止损
+func (at *AutoTrader) tripFlashGuard(ctx *decision.Context, reason string, record *logger.DecisionRecord) {
+	log.Printf("🧯 [%s] 急跌熔断: %s", at.name, reason)
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🧯 急跌熔断: %s，只允许平仓", reason))
+	at.publishEvent(events.RiskBreach, "", RiskBreachData{Kind: "flash_crash", Reason: reason})
+
+	tightened := 0
+	if pct := at.flashGuard.cfg.TightenStopPct; pct > 0 {
+		for _, pos := range ctx.Positions {
+			if at.tightenFlashStop(pos, pct, record) {
+				tightened++
+			}
+		}
+	}
+
+	message := fmt.Sprintf("%s。暂停开新仓和加仓，行情企稳 %.0f 分钟后自动恢复", reason, at.flashGuard.cfg.ResumeAfter.Minutes())
+	if tightened > 0 {
+		message += fmt.Sprintf("；已收紧 %d 个持仓的止损", tightened)
+	}
+	notify.Send(notify.Event{
+		Type:     "risk.flash_crash",
+		Severity: notify.SeverityCritical,
+		TraderID: at.id,
+		Title:    fmt.Sprintf("%s 急跌熔断，只允许平仓", at.name),
+		Message:  message,
+	})
+}
+
+// tightenFlashStop 把持仓止损收紧到距当前价 pct% 以内（已经更紧时不动），返回是否调整
+func (at *AutoTrader) tightenFlashStop(pos decision.PositionInfo, pct float64, record *logger.DecisionRecord) bool {
+	data, err := market.Get(pos.Symbol)
+	if err != nil || data.CurrentPrice <= 0 {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s 获取价格失败，未收紧止损: %v", pos.Symbol, err))
+		return false
+	}
+	price := data.CurrentPrice
+
+	target := price * (1 - pct/100)
+	if pos.Side == "short" {
+		target = price * (1 + pct/100)
+	}
+	if stops, ok := at.positionProtection(pos.Symbol + "_" + pos.Side); ok && stops.StopLoss > 0 {
+		if (pos.Side == "long" && stops.StopLoss >= target) || (pos.Side == "short" && stops.StopLoss <= target) {
+			return false
+		}
+	}
+
+	d := decision.Decision{Symbol: pos.Symbol, Side: pos.Side, Action: "adjust_sl", StopLoss: target, Reasoning: "急跌熔断收紧止损"}
+	return at.executeRiskAction(&d, record) == nil
+}
+
+// closeOnlyReason 只允许平仓的来源（执行日志用）
+func (at *AutoTrader) closeOnlyReason() string {
+	if at.derisk.level >= DeriskCloseOnly {
+		return "降风险阶梯"
+	}
+	if halted, _ := at.flashGuard.active(); halted {
+		return "急跌熔断"
+	}
+	return "风控"
+}
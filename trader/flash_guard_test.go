@@ -0,0 +1,83 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlashGuardEquityDrop(t *testing.T) {
+	g := &flashGuard{cfg: FlashCrashConfig{EquityDropPct: 3, EquityWindow: 15 * time.Minute}}
+	t0 := time.Now()
+
+	if reason := g.observeEquity(t0, 10000, 0); reason != "" {
+		t.Fatalf("第一个样本不应触发: %s", reason)
+	}
+	// 提现1000：样本按资金流动平移，不算回撤
+	if reason := g.observeEquity(t0.Add(3*time.Minute), 9000, -1000); reason != "" {
+		t.Errorf("外部资金流动不应触发熔断: %s", reason)
+	}
+	if reason := g.observeEquity(t0.Add(6*time.Minute), 8700, 0); !strings.Contains(reason, "回撤至 8700.00") {
+		t.Errorf("窗口内回撤3.3%%应触发，实际 %q", reason)
+	}
+	// 窗口之外的高点不再计入
+	if reason := g.observeEquity(t0.Add(30*time.Minute), 8650, 0); reason != "" {
+		t.Errorf("窗口外的高点不应触发: %s", reason)
+	}
+}
+
+func TestIntegrationFlashCrashGuard(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableFlashCrashGuard(FlashCrashConfig{BTCMovePct: 2, TightenStopPct: 1, ResumeAfter: 10 * time.Minute})
+
+	ai.Enqueue(t, "开多ETH。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	// BTC 5分钟内急跌3%：熔断，开仓被拦截，ETH止损收紧到当前价下方1%
+	ex.SetPriceMove("BTCUSDT", 60000, 58200)
+	ai.Enqueue(t, "逢低开多BTC。", decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1500,
+		StopLoss: 56000, TakeProfit: 64000, Confidence: 80, RiskUSD: 50, Reasoning: "超跌",
+	})
+	record := runCycle(t, at)
+	log := strings.Join(record.ExecutionLog, "\n")
+	if !strings.Contains(log, "急跌熔断: BTC") || !strings.Contains(log, "BTCUSDT open_long 被急跌熔断拦截") {
+		t.Errorf("应熔断并拦截开仓，执行日志:\n%s", log)
+	}
+	if ex.GatePosition("BTCUSDT").size != 0 {
+		t.Error("熔断中不应开仓")
+	}
+	if !strings.Contains(ai.Prompts()[1], "急跌熔断中") {
+		t.Error("AI输入应说明熔断限制")
+	}
+	stop := 0.0
+	for _, tr := range ex.Triggers("gateio", "open") {
+		if tr.symbol == "ETHUSDT" && tr.kind == "stop_loss" {
+			stop = tr.price
+		}
+	}
+	if math.Abs(stop-2970) > 0.01 {
+		t.Errorf("ETH止损应收紧到 2970，实际 %.2f", stop)
+	}
+	if halted, _ := at.flashGuard.active(); !halted {
+		t.Fatal("应处于熔断中")
+	}
+
+	// 行情企稳超过恢复时间后自动解除
+	ex.SetPriceMove("BTCUSDT", 0, 58200)
+	at.flashGuard.lastHit = time.Now().Add(-11 * time.Minute)
+	ai.Enqueue(t, "观望。")
+	record = runCycle(t, at)
+	if !strings.Contains(strings.Join(record.ExecutionLog, "\n"), "急跌熔断解除") {
+		t.Errorf("企稳后应解除熔断，执行日志: %v", record.ExecutionLog)
+	}
+	if halted, _ := at.flashGuard.active(); halted {
+		t.Error("熔断应已解除")
+	}
+	if strings.Contains(ai.Prompts()[2], "急跌熔断中") {
+		t.Error("解除后AI输入不应再有熔断限制")
+	}
+}
@@ -0,0 +1,291 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"sync"
+	"time"
+)
+
+// 资金费追踪
+// 永续合约每个结算周期（大多数交易所8小时，Hyperliquid 1小时）在多空之间收付资金费，
+// 持仓时间较长时资金费会显著影响实际盈亏，但交易所返回的未实现盈亏并不包含这部分。
+// 交易所提供资金费流水接口时直接拉取实际收付记录；否则按 资金费率 × 名义价值 在每个结算点估算。
+// 只统计本系统看到持仓之后发生的资金费，持仓平仓后累计值清零。
+
+// FundingPayment 一笔资金费收付记录
+type FundingPayment struct {
+	ID     string    `json:"id"`     // 记录ID（交易所流水ID，或估算记录的 symbol_side_结算时间）
+	Symbol string    `json:"symbol"` // 币种
+	Side   string    `json:"side"`   // 持仓方向（交易所流水不区分方向时为空，由追踪器按持仓分摊）
+	Amount float64   `json:"amount"` // 金额（USDT，正数=收到，负数=支付）
+	Time   time.Time `json:"time"`   // 结算时间
+	Source string    `json:"source"` // 来源: "exchange" 交易所流水, "estimated" 按费率估算
+}
+
+// FundingHistoryProvider 可以查询资金费流水的交易器（可选接口）
+type FundingHistoryProvider interface {
+	// GetFundingPayments 获取 since 之后的资金费收付记录
+	GetFundingPayments(since time.Time) ([]FundingPayment, error)
+}
+
+// fundingPosition 参与资金费统计的持仓
+type fundingPosition struct {
+	key       string // symbol_side
+	symbol    string
+	side      string
+	notional  float64   // 名义价值（数量 × 标记价格）
+	firstSeen time.Time // 本系统首次看到该持仓的时间
+}
+
+// fundingTracker 按持仓累计资金费
+type fundingTracker struct {
+	interval time.Duration // 结算间隔（估算模式使用）
+	provider FundingHistoryProvider
+
+	// rateFunc 获取当前资金费率（默认使用当前市场数据源）
+	rateFunc func(symbol string) (float64, error)
+
+	lastSync       time.Time            // 上次成功拉取交易所流水的时间
+	seen           map[string]time.Time // 已处理的流水ID -> 结算时间（去重）
+	cumulative     map[string]float64   // symbol_side -> 累计资金费
+	lastSettlement map[string]time.Time // symbol_side -> 已估算到的结算点（估算模式）
+	mu             sync.RWMutex
+}
+
+// newFundingTracker 创建资金费追踪器，provider 为 nil 时使用估算模式
+func newFundingTracker(provider FundingHistoryProvider, interval time.Duration) *fundingTracker {
+	return &fundingTracker{
+		interval:       interval,
+		provider:       provider,
+		rateFunc:       currentFundingRate,
+		seen:           make(map[string]time.Time),
+		cumulative:     make(map[string]float64),
+		lastSettlement: make(map[string]time.Time),
+	}
+}
+
+// fundingIntervalFor 交易所的资金费结算间隔
+func fundingIntervalFor(exchange string) time.Duration {
+	if exchange == "hyperliquid" {
+		return time.Hour
+	}
+	return 8 * time.Hour
+}
+
+// Cumulative 持仓的累计资金费（正数=净收到）
+func (f *fundingTracker) Cumulative(symbol, side string) float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.cumulative[symbol+"_"+side]
+}
+
+// sync 更新资金费并返回本次新增的记录，已平仓持仓的累计值会被清理
+func (f *fundingTracker) sync(positions []fundingPosition, now time.Time) []FundingPayment {
+	var payments []FundingPayment
+	if f.provider != nil {
+		fetched, err := f.fetch(positions, now)
+		if err != nil {
+			// 不影响交易周期，下个周期从同一时间点重新拉取
+			log.Printf("⚠️ 获取资金费流水失败: %v", err)
+		}
+		payments = fetched
+	} else {
+		payments = f.estimate(positions, now)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current := make(map[string]bool, len(positions))
+	for _, pos := range positions {
+		current[pos.key] = true
+	}
+	for key := range f.cumulative {
+		if !current[key] {
+			delete(f.cumulative, key)
+		}
+	}
+	for key := range f.lastSettlement {
+		if !current[key] {
+			delete(f.lastSettlement, key)
+		}
+	}
+
+	for _, p := range payments {
+		f.cumulative[p.Symbol+"_"+p.Side] += p.Amount
+	}
+	return payments
+}
+
+// fetch 拉取交易所流水并分摊到当前持仓
+func (f *fundingTracker) fetch(positions []fundingPosition, now time.Time) ([]FundingPayment, error) {
+	since := f.lastSync
+	if since.IsZero() {
+		// 首次拉取：从最早的持仓开始
+		since = now
+		for _, pos := range positions {
+			if pos.firstSeen.Before(since) {
+				since = pos.firstSeen
+			}
+		}
+	}
+
+	// 多拉一小段，避免交易所流水入账延迟导致漏记（重复的由ID去重）
+	records, err := f.provider.GetFundingPayments(since.Add(-10 * time.Minute))
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	var fresh []FundingPayment
+	for _, r := range records {
+		if _, ok := f.seen[r.ID]; ok {
+			continue
+		}
+		f.seen[r.ID] = r.Time
+		fresh = append(fresh, r)
+	}
+	// 清理过旧的去重记录
+	for id, t := range f.seen {
+		if t.Before(since.Add(-24 * time.Hour)) {
+			delete(f.seen, id)
+		}
+	}
+	f.lastSync = now
+	f.mu.Unlock()
+
+	var payments []FundingPayment
+	for _, r := range fresh {
+		payments = append(payments, attributeFunding(r, positions)...)
+	}
+	return payments, nil
+}
+
+// attributeFunding 把一笔流水分摊到对应持仓
+// 交易所流水只区分币种时（双向持仓同时持有多空），按名义价值比例分摊
+func attributeFunding(r FundingPayment, positions []fundingPosition) []FundingPayment {
+	var matched []fundingPosition
+	totalNotional := 0.0
+	for _, pos := range positions {
+		if pos.symbol != r.Symbol || (r.Side != "" && pos.side != r.Side) {
+			continue
+		}
+		if r.Time.Before(pos.firstSeen) {
+			continue // 本系统看到该持仓之前的资金费（可能属于已平仓的旧持仓）
+		}
+		matched = append(matched, pos)
+		totalNotional += pos.notional
+	}
+
+	var payments []FundingPayment
+	for _, pos := range matched {
+		share := 1.0 / float64(len(matched))
+		if totalNotional > 0 {
+			share = pos.notional / totalNotional
+		}
+		p := r
+		p.Side = pos.side
+		p.Amount = r.Amount * share
+		p.Source = "exchange"
+		payments = append(payments, p)
+	}
+	return payments
+}
+
+// estimate 按 资金费率 × 名义价值 估算每个新跨过的结算点的资金费
+// 正费率时多头支付、空头收到；费率按8小时口径换算到实际结算间隔
+func (f *fundingTracker) estimate(positions []fundingPosition, now time.Time) []FundingPayment {
+	settlement := now.UTC().Truncate(f.interval)
+
+	var payments []FundingPayment
+	for _, pos := range positions {
+		f.mu.RLock()
+		last, ok := f.lastSettlement[pos.key]
+		f.mu.RUnlock()
+
+		if !ok {
+			// 首次看到该持仓：从下一个结算点开始计算
+			last = pos.firstSeen.UTC().Truncate(f.interval)
+		}
+		if !settlement.After(last) {
+			f.mu.Lock()
+			f.lastSettlement[pos.key] = last
+			f.mu.Unlock()
+			continue
+		}
+
+		rate, err := f.rateFunc(pos.symbol)
+		if err != nil {
+			log.Printf("⚠️ 获取 %s 资金费率失败，跳过本次资金费估算: %v", pos.symbol, err)
+			continue
+		}
+
+		direction := 1.0
+		if pos.side == "short" {
+			direction = -1.0
+		}
+		periods := float64(settlement.Sub(last) / f.interval)
+		amount := -direction * rate * pos.notional * periods * float64(f.interval) / float64(8*time.Hour)
+
+		payments = append(payments, FundingPayment{
+			ID:     fmt.Sprintf("%s_%d", pos.key, settlement.Unix()),
+			Symbol: pos.symbol,
+			Side:   pos.side,
+			Amount: amount,
+			Time:   settlement,
+			Source: "estimated",
+		})
+
+		f.mu.Lock()
+		f.lastSettlement[pos.key] = settlement
+		f.mu.Unlock()
+	}
+	return payments
+}
+
+// currentFundingRate 使用该币种市场数据源的当前资金费率
+func currentFundingRate(symbol string) (float64, error) {
+	provider, err := market.ProviderFor(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return provider.GetFundingRate(symbol)
+}
+
+// syncFunding 更新持仓的累计资金费，并返回本周期新增的资金费记录（写入决策日志）
+func (at *AutoTrader) syncFunding(positions []decision.PositionInfo) []logger.FundingRecord {
+	fundingPositions := make([]fundingPosition, 0, len(positions))
+	for _, pos := range positions {
+		fundingPositions = append(fundingPositions, fundingPosition{
+			key:       pos.Symbol + "_" + pos.Side,
+			symbol:    pos.Symbol,
+			side:      pos.Side,
+			notional:  pos.Quantity * pos.MarkPrice,
+			firstSeen: time.UnixMilli(pos.UpdateTime),
+		})
+	}
+
+	payments := at.funding.sync(fundingPositions, time.Now())
+
+	var records []logger.FundingRecord
+	for _, p := range payments {
+		log.Printf("  💸 资金费 %s %s: %+.4f USDT (%s)", p.Symbol, p.Side, p.Amount, p.Source)
+		records = append(records, logger.FundingRecord{
+			ID:     p.ID,
+			Symbol: p.Symbol,
+			Side:   p.Side,
+			Amount: p.Amount,
+			Time:   p.Time,
+			Source: p.Source,
+		})
+	}
+
+	for i := range positions {
+		positions[i].CumulativeFunding = at.funding.Cumulative(positions[i].Symbol, positions[i].Side)
+	}
+	return records
+}
@@ -0,0 +1,82 @@
+package trader
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+type stubFundingProvider struct {
+	payments []FundingPayment
+}
+
+func (s *stubFundingProvider) GetFundingPayments(since time.Time) ([]FundingPayment, error) {
+	return s.payments, nil
+}
+
+func TestFundingTrackerEstimate(t *testing.T) {
+	f := newFundingTracker(nil, 8*time.Hour)
+	f.rateFunc = func(symbol string) (float64, error) { return 0.0001, nil }
+
+	opened := time.Date(2026, 1, 1, 6, 0, 0, 0, time.UTC)
+	positions := []fundingPosition{
+		{key: "BTCUSDT_long", symbol: "BTCUSDT", side: "long", notional: 10000, firstSeen: opened},
+		{key: "ETHUSDT_short", symbol: "ETHUSDT", side: "short", notional: 5000, firstSeen: opened},
+	}
+
+	// 还没跨过结算点
+	if got := f.sync(positions, opened.Add(time.Hour)); len(got) != 0 {
+		t.Fatalf("结算前不应产生资金费: %+v", got)
+	}
+
+	// 跨过 08:00 和 16:00 两个结算点
+	got := f.sync(positions, time.Date(2026, 1, 1, 16, 30, 0, 0, time.UTC))
+	if len(got) != 2 {
+		t.Fatalf("期望2条资金费记录，实际 %d", len(got))
+	}
+	if v := f.Cumulative("BTCUSDT", "long"); math.Abs(v-(-2)) > 1e-9 {
+		t.Errorf("多头应支付 2 USDT，实际 %v", v)
+	}
+	if v := f.Cumulative("ETHUSDT", "short"); math.Abs(v-1) > 1e-9 {
+		t.Errorf("空头应收到 1 USDT，实际 %v", v)
+	}
+
+	// 同一结算点不重复计算；平仓后清零
+	if got := f.sync(positions[:1], time.Date(2026, 1, 1, 17, 0, 0, 0, time.UTC)); len(got) != 0 {
+		t.Fatalf("同一结算点不应重复计算: %+v", got)
+	}
+	if v := f.Cumulative("ETHUSDT", "short"); v != 0 {
+		t.Errorf("平仓后累计资金费应清零，实际 %v", v)
+	}
+}
+
+func TestFundingTrackerExchangeAttribution(t *testing.T) {
+	opened := time.Date(2026, 1, 1, 6, 0, 0, 0, time.UTC)
+	provider := &stubFundingProvider{payments: []FundingPayment{
+		{ID: "1", Symbol: "BTCUSDT", Amount: -3, Time: opened.Add(2 * time.Hour)},
+		{ID: "2", Symbol: "BTCUSDT", Amount: -5, Time: opened.Add(-2 * time.Hour)}, // 持仓出现之前
+		{ID: "3", Symbol: "SOLUSDT", Amount: 1, Time: opened.Add(2 * time.Hour)},   // 无持仓
+	}}
+	f := newFundingTracker(provider, 8*time.Hour)
+
+	// 双向持仓：按名义价值 2:1 分摊
+	positions := []fundingPosition{
+		{key: "BTCUSDT_long", symbol: "BTCUSDT", side: "long", notional: 20000, firstSeen: opened},
+		{key: "BTCUSDT_short", symbol: "BTCUSDT", side: "short", notional: 10000, firstSeen: opened},
+	}
+	got := f.sync(positions, opened.Add(3*time.Hour))
+	if len(got) != 2 {
+		t.Fatalf("期望2条分摊记录，实际 %d: %+v", len(got), got)
+	}
+	if v := f.Cumulative("BTCUSDT", "long"); math.Abs(v-(-2)) > 1e-9 {
+		t.Errorf("多头分摊应为 -2，实际 %v", v)
+	}
+	if v := f.Cumulative("BTCUSDT", "short"); math.Abs(v-(-1)) > 1e-9 {
+		t.Errorf("空头分摊应为 -1，实际 %v", v)
+	}
+
+	// 重复返回的流水按ID去重
+	if got := f.sync(positions, opened.Add(4*time.Hour)); len(got) != 0 {
+		t.Fatalf("重复流水不应再次计入: %+v", got)
+	}
+}
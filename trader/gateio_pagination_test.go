@@ -0,0 +1,89 @@
+package trader
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestGateioPaginatedPositions(t *testing.T) {
+	const total = 230
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("holding") != "true" {
+			t.Errorf("持仓请求应带 holding=true: %s", r.URL.RawQuery)
+		}
+		list := make([]map[string]interface{}, 0, total)
+		for i := 0; i < total; i++ {
+			list = append(list, map[string]interface{}{
+				"contract":    fmt.Sprintf("C%d_USDT", i),
+				"size":        1,
+				"leverage":    "5",
+				"entry_price": "10",
+				"value":       "10",
+			})
+		}
+		writeJSON(w, http.StatusOK, paginate(list, r))
+	}))
+	defer server.Close()
+
+	tr, err := NewGateioTrader("test-key", "test-secret", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.baseURL = server.URL
+	tr.client = server.Client()
+	tr.positionsCache.SetTTL(0)
+
+	positions, err := tr.GetPositions()
+	if err != nil {
+		t.Fatalf("获取持仓失败: %v", err)
+	}
+	if len(positions) != total {
+		t.Fatalf("应返回全部 %d 个持仓，实际 %d", total, len(positions))
+	}
+	if want := total/gateioPageLimit + 1; requests != want {
+		t.Errorf("应请求 %d 页，实际 %d", want, requests)
+	}
+	if last := positions[total-1]["symbol"]; last != "C"+strconv.Itoa(total-1)+"USDT" {
+		t.Errorf("最后一个持仓 = %v", last)
+	}
+}
+
+func TestGateioPaginatedPriceOrders(t *testing.T) {
+	const total = 130
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("status") != "open" || r.URL.Query().Get("contract") != "ETH_USDT" {
+			t.Errorf("条件单请求参数 = %s", r.URL.RawQuery)
+		}
+		list := make([]map[string]interface{}, 0, total)
+		for i := 0; i < total; i++ {
+			list = append(list, map[string]interface{}{"id": i + 1, "status": "open"})
+		}
+		writeJSON(w, http.StatusOK, paginate(list, r))
+	}))
+	defer server.Close()
+
+	tr, err := NewGateioTrader("test-key", "test-secret", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.baseURL = server.URL
+	tr.client = server.Client()
+
+	orders, err := tr.GetOpenPriceOrders("ETHUSDT")
+	if err != nil {
+		t.Fatalf("获取条件单失败: %v", err)
+	}
+	if len(orders) != total {
+		t.Fatalf("应返回全部 %d 个条件单，实际 %d", total, len(orders))
+	}
+	if want := total/gateioPageLimit + 1; requests != want {
+		t.Errorf("应请求 %d 页，实际 %d", want, requests)
+	}
+}
@@ -0,0 +1,43 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIntegrationGateioDualModePositionFields(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	ex.gateDualMode = true
+	ex.gateADL = 2
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	// 交易所累计的已实现盈亏（手续费、资金费）
+	ex.mu.Lock()
+	ex.gatePositions["ETH_USDT"].realised = -1.5
+	ex.mu.Unlock()
+	ex.SetPrice("ETHUSDT", 3100)
+
+	positions, err := at.GetPositions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 1 || positions[0]["side"] != "long" || positions[0]["position_mode"] != "dual_long" {
+		t.Fatalf("持仓 = %v", positions)
+	}
+	// 保证金取交易所返回值（按开仓价 50张 × 0.01 × 3000 / 5），而不是按标记价估算的 310
+	if margin := positions[0]["margin_used"].(float64); margin != 300 {
+		t.Errorf("保证金 = %.2f，期望 300", margin)
+	}
+
+	runCycle(t, at)
+	prompts := ai.Prompts()
+	last := prompts[len(prompts)-1]
+	for _, want := range []string{"保证金300", "ADL排名2/5", "已实现-1.50 USDT"} {
+		if !strings.Contains(last, want) {
+			t.Errorf("AI输入中缺少 %q", want)
+		}
+	}
+}
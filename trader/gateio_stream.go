@@ -0,0 +1,355 @@
+package trader
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Gate.io 私有WebSocket推送（futures.orders / futures.usertrades / futures.positions）
+// 收到成交和持仓变化时立即让余额、持仓缓存失效；持仓被止损止盈、强平或网页端平掉时回调通知，
+// 不用等下一次扫描才发现。断线后自动重连，重连期间可能漏掉的变化同样通过缓存失效兜底。
+
+const (
+	gateioWSURL        = "wss://fx-ws.gateio.ws/v4/ws/usdt"
+	gateioTestnetWSURL = "wss://fx-ws-testnet.gateio.ws/v4/ws/usdt"
+
+	gateioPingInterval    = 10 * time.Second
+	gateioReadTimeout     = 60 * time.Second // 超过该时间没有任何消息（包括pong）视为断线
+	gateioReconnectMin    = 2 * time.Second
+	gateioReconnectMax    = time.Minute
+	gateioStreamAllEvents = "!all"
+)
+
+// gateioStreamChannels 订阅的私有频道
+var gateioStreamChannels = []string{"futures.orders", "futures.usertrades", "futures.positions"}
+
+// gateioStream 一个GateioTrader的私有推送连接
+type gateioStream struct {
+	t        *GateioTrader
+	userID   string
+	onClosed func(PositionClosedEvent)
+
+	mu        sync.Mutex
+	sizes     map[string]float64 // contract|mode -> 持仓张数（检测平仓）
+	closeText map[string]string  // contract -> 最近一笔成交的平仓订单 text/finish_as（判断平仓原因）
+	stop      chan struct{}
+}
+
+// StartStream 连接Gate.io私有推送，交易所侧平仓时回调onClosed，返回停止函数
+func (t *GateioTrader) StartStream(onClosed func(PositionClosedEvent)) (func(), error) {
+	userID, err := t.fetchUserID()
+	if err != nil {
+		return nil, fmt.Errorf("获取Gate.io用户ID失败: %w", err)
+	}
+	s := &gateioStream{
+		t:         t,
+		userID:    userID,
+		onClosed:  onClosed,
+		sizes:     make(map[string]float64),
+		closeText: make(map[string]string),
+		stop:      make(chan struct{}),
+	}
+	go s.run()
+	log.Printf("📡 Gate.io私有推送已启动（订单 / 成交 / 持仓）")
+
+	var once sync.Once
+	return func() { once.Do(func() { close(s.stop) }) }, nil
+}
+
+// fetchUserID 私有频道的订阅参数需要用户ID（期货账户接口返回的 user 字段）
+func (t *GateioTrader) fetchUserID() (string, error) {
+	data, err := t.doRequest("GET", "/futures/usdt/accounts", nil, "")
+	if err != nil {
+		return "", err
+	}
+	var account struct {
+		User json.Number `json:"user"`
+	}
+	if err := json.Unmarshal(data, &account); err != nil {
+		return "", fmt.Errorf("解析账户信息失败: %w", err)
+	}
+	if account.User == "" {
+		return "", fmt.Errorf("账户信息中没有 user 字段")
+	}
+	return account.User.String(), nil
+}
+
+// invalidateCaches 余额和持仓缓存失效，下次查询时重新请求
+func (t *GateioTrader) invalidateCaches() {
+	t.balanceCache.Invalidate()
+	t.positionsCache.Invalidate()
+}
+
+// signChannel 私有频道订阅签名：HMAC-SHA512("channel=%s&event=%s&time=%d", secret)
+func (t *GateioTrader) signChannel(channel, event string, ts int64) string {
+	mac := hmac.New(sha512.New, []byte(t.secretKey))
+	mac.Write([]byte(fmt.Sprintf("channel=%s&event=%s&time=%d", channel, event, ts)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// run 保持连接，断线后按指数退避重连
+func (s *gateioStream) run() {
+	backoff := gateioReconnectMin
+	for {
+		connected := time.Now()
+		err := s.connect()
+		select {
+		case <-s.stop:
+			log.Println("📡 Gate.io私有推送已停止")
+			return
+		default:
+		}
+		// 连接维持了一段时间说明不是持续失败，退避从头开始
+		if time.Since(connected) > gateioReconnectMax {
+			backoff = gateioReconnectMin
+		}
+		log.Printf("⚠️ Gate.io私有推送断开: %v，%v 后重连", err, backoff)
+		// 断线期间的变化收不到了，下次查询走REST
+		s.t.invalidateCaches()
+
+		select {
+		case <-time.After(backoff):
+		case <-s.stop:
+			log.Println("📡 Gate.io私有推送已停止")
+			return
+		}
+		if backoff *= 2; backoff > gateioReconnectMax {
+			backoff = gateioReconnectMax
+		}
+	}
+}
+
+// connect 建立一次连接并处理消息，直到出错或停止
+func (s *gateioStream) connect() error {
+	dialer := *websocket.DefaultDialer
+	if s.t.proxyURL != nil {
+		dialer.Proxy = http.ProxyURL(s.t.proxyURL)
+	}
+	conn, _, err := dialer.Dial(s.t.wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	// 连接（或重连）后以REST持仓为基准检测平仓
+	if err := s.seedPositions(); err != nil {
+		return err
+	}
+	for _, channel := range gateioStreamChannels {
+		if err := s.subscribe(conn, channel); err != nil {
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	var writeMu sync.Mutex
+	go func() {
+		ticker := time.NewTicker(gateioPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				writeMu.Lock()
+				err := conn.WriteJSON(map[string]interface{}{"time": time.Now().Unix(), "channel": "futures.ping"})
+				writeMu.Unlock()
+				if err != nil {
+					return
+				}
+			case <-s.stop:
+				conn.Close() // 让 ReadMessage 返回
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(gateioReadTimeout))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		s.handleMessage(data)
+	}
+}
+
+// subscribe 订阅一个私有频道（所有合约）
+func (s *gateioStream) subscribe(conn *websocket.Conn, channel string) error {
+	ts := time.Now().Unix()
+	return conn.WriteJSON(map[string]interface{}{
+		"time":    ts,
+		"channel": channel,
+		"event":   "subscribe",
+		"payload": []string{s.userID, gateioStreamAllEvents},
+		"auth": map[string]string{
+			"method": "api_key",
+			"KEY":    s.t.apiKey,
+			"SIGN":   s.t.signChannel(channel, "subscribe", ts),
+		},
+	})
+}
+
+// seedPositions 用REST持仓初始化各合约的持仓张数
+func (s *gateioStream) seedPositions() error {
+	raw, err := s.t.fetchPositions()
+	if err != nil {
+		return fmt.Errorf("获取持仓失败: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sizes = make(map[string]float64)
+	for _, p := range raw {
+		contract, _ := p["contract"].(string)
+		mode, _ := p["mode"].(string)
+		s.sizes[contract+"|"+mode] = streamFloat(p["size"])
+	}
+	return nil
+}
+
+// gateioStreamMessage 推送消息
+type gateioStreamMessage struct {
+	Channel string `json:"channel"`
+	Event   string `json:"event"`
+	Error   *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Result json.RawMessage `json:"result"`
+}
+
+// handleMessage 处理一条推送
+func (s *gateioStream) handleMessage(data []byte) {
+	var msg gateioStreamMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("⚠️ 解析Gate.io推送失败: %v", err)
+		return
+	}
+	if msg.Error != nil {
+		log.Printf("⚠️ Gate.io推送 %s %s 失败: %d %s", msg.Channel, msg.Event, msg.Error.Code, msg.Error.Message)
+		return
+	}
+	if msg.Event != "update" {
+		return
+	}
+
+	var items []map[string]interface{}
+	if err := json.Unmarshal(msg.Result, &items); err != nil {
+		return
+	}
+	s.t.invalidateCaches()
+
+	switch msg.Channel {
+	case "futures.orders":
+		for _, o := range items {
+			s.handleOrder(o)
+		}
+	case "futures.usertrades":
+		for _, trade := range items {
+			contract, _ := trade["contract"].(string)
+			log.Printf("📡 Gate.io成交: %s %v 张 @ %v", s.t.convertSymbolFromGateio(contract), trade["size"], trade["price"])
+		}
+	case "futures.positions":
+		for _, p := range items {
+			s.handlePosition(p)
+		}
+	}
+}
+
+// handleOrder 记录平仓订单的来源（text / finish_as），持仓推送时据此判断平仓原因
+func (s *gateioStream) handleOrder(o map[string]interface{}) {
+	status, _ := o["status"].(string)
+	reduceOnly, _ := o["is_reduce_only"].(bool)
+	isClose, _ := o["is_close"].(bool)
+	isLiq, _ := o["is_liq"].(bool)
+	if status != "finished" || !(reduceOnly || isClose || isLiq) {
+		return
+	}
+	contract, _ := o["contract"].(string)
+	text, _ := o["text"].(string)
+	finishAs, _ := o["finish_as"].(string)
+	if isLiq {
+		finishAs = "liquidated"
+	}
+	s.mu.Lock()
+	s.closeText[contract] = text + "|" + finishAs
+	s.mu.Unlock()
+}
+
+// handlePosition 持仓张数变为0时判断平仓原因，不是本程序下的平仓单则回调
+func (s *gateioStream) handlePosition(p map[string]interface{}) {
+	contract, _ := p["contract"].(string)
+	mode, _ := p["mode"].(string)
+	size := streamFloat(p["size"])
+	key := contract + "|" + mode
+
+	s.mu.Lock()
+	prev := s.sizes[key]
+	s.sizes[key] = size
+	source := s.closeText[contract]
+	if size == 0 {
+		delete(s.closeText, contract)
+	}
+	s.mu.Unlock()
+
+	if size != 0 || prev == 0 {
+		return
+	}
+	side := "long"
+	if prev < 0 || mode == "dual_short" {
+		side = "short"
+	}
+	symbol := s.t.convertSymbolFromGateio(contract)
+	reason, own := gateioCloseReason(symbol, source)
+	if own {
+		return // 本程序的平仓单（AI决策、人工命令）
+	}
+	log.Printf("📡 Gate.io推送: %s %s 已平仓（%s）", symbol, side, reason)
+	if s.onClosed != nil {
+		s.onClosed(PositionClosedEvent{Symbol: symbol, Side: side, Reason: reason, Time: time.Now()})
+	}
+}
+
+// gateioCloseReason 根据平仓订单的 text|finish_as 判断原因，own 表示本程序直接下的平仓单
+func gateioCloseReason(symbol, source string) (reason string, own bool) {
+	text, finishAs, _ := strings.Cut(source, "|")
+	switch {
+	case finishAs == "liquidated" || text == "liquidation":
+		return "强平", false
+	case finishAs == "auto_deleveraged" || text == "auto_deleveraging":
+		return "ADL自动减仓", false
+	case strings.HasPrefix(text, "t-sl-"):
+		return "止损触发", false
+	case strings.HasPrefix(text, "t-tp-"):
+		return "止盈触发", false
+	case text == "t-"+symbol:
+		return "", true
+	case source == "":
+		return "交易所侧平仓", false
+	}
+	return fmt.Sprintf("交易所侧平仓（%s）", text), false
+}
+
+// streamFloat 推送中的数值可能是数字或字符串
+func streamFloat(v interface{}) float64 {
+	switch val := v.(type) {
+	case float64:
+		return val
+	case string:
+		f, _ := strconv.ParseFloat(val, 64)
+		return f
+	}
+	return 0
+}
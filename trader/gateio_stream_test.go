@@ -0,0 +1,101 @@
+package trader
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestIntegrationGateioStreamStopOut(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	gt := at.trader.(*GateioTrader)
+
+	// 模拟Gate.io私有推送：收齐订阅后推送止损单成交和持仓归零
+	subscribed := make(chan map[string]interface{}, len(gateioStreamChannels))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for range gateioStreamChannels {
+			var sub map[string]interface{}
+			if err := conn.ReadJSON(&sub); err != nil {
+				return
+			}
+			subscribed <- sub
+		}
+		conn.WriteJSON(map[string]interface{}{
+			"channel": "futures.orders", "event": "update",
+			"result": []map[string]interface{}{{
+				"contract": "ETH_USDT", "status": "finished", "finish_as": "filled",
+				"is_reduce_only": true, "text": "t-sl-ETHUSDT", "size": -50, "left": 0,
+			}},
+		})
+		conn.WriteJSON(map[string]interface{}{
+			"channel": "futures.positions", "event": "update",
+			"result": []map[string]interface{}{{"contract": "ETH_USDT", "size": 0, "mode": "single"}},
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	gt.wsURL = "ws" + strings.TrimPrefix(server.URL, "http")
+
+	at.EnableRealtimeStream(time.Minute)
+	closed := make(chan PositionClosedEvent, 1)
+	stop, err := gt.StartStream(func(e PositionClosedEvent) { closed <- e })
+	if err != nil {
+		t.Fatalf("启动推送失败: %v", err)
+	}
+	defer stop()
+
+	for range gateioStreamChannels {
+		sub := <-subscribed
+		channel, _ := sub["channel"].(string)
+		payload, _ := sub["payload"].([]interface{})
+		auth, _ := sub["auth"].(map[string]interface{})
+		ts := int64(sub["time"].(float64))
+		if len(payload) == 0 || payload[0] != "10001" {
+			t.Errorf("%s 订阅参数应包含用户ID: %v", channel, payload)
+		}
+		if auth["SIGN"] != gt.signChannel(channel, "subscribe", ts) {
+			t.Errorf("%s 订阅签名不正确", channel)
+		}
+	}
+
+	select {
+	case e := <-closed:
+		if e.Symbol != "ETHUSDT" || e.Side != "long" || e.Reason != "止损触发" {
+			t.Fatalf("平仓事件 = %+v", e)
+		}
+		// 刚执行过周期，不立即提前；超过最短间隔后提前执行
+		if at.streamCycleDue(e) {
+			t.Error("距上个周期太近时不应提前执行")
+		}
+		at.lastCycleAt = time.Now().Add(-2 * time.Minute)
+		if !at.streamCycleDue(e) {
+			t.Error("超过最短间隔后应提前执行")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有收到平仓事件")
+	}
+
+	// 本程序下的平仓单不回调
+	if _, own := gateioCloseReason("ETHUSDT", "t-ETHUSDT|filled"); !own {
+		t.Error("t-<symbol> 平仓单应识别为本程序下单")
+	}
+	if reason, _ := gateioCloseReason("ETHUSDT", "|liquidated"); reason != "强平" {
+		t.Errorf("强平原因 = %s", reason)
+	}
+}
@@ -5,16 +5,19 @@ import (
     "crypto/sha512"
     "encoding/hex"
     "encoding/json"
+    "errors"
     "fmt"
     "io"
     "log"
     "math"
     "net/http"
     "net/url"
+    "nofx/cache"
+    "nofx/errs"
+    "nofx/ratelimit"
     "regexp"
     "strconv"
     "strings"
-    "sync"
     "time"
 )
 
@@ -28,41 +31,39 @@ type ContractInfo struct {
 
 // GateioTrader Gate.io交易器实现（HTTP 客户端 + 简单缓存）
 type GateioTrader struct {
+    positionTags // 止损止盈单的text（按持仓ID）
+
     apiKey    string
     secretKey string
     testnet   bool
     baseURL   string
+    wsURL     string // 私有WebSocket推送地址
+    proxyURL  *url.URL
     client    *http.Client
 
-    // Cache
-    cachedBalance     map[string]interface{}
-    balanceCacheTime  time.Time
-    balanceCacheMutex sync.RWMutex
-
-    cachedPositions     []map[string]interface{}
-    positionsCacheTime  time.Time
-    positionsCacheMutex sync.RWMutex
-
-    cacheDuration time.Duration
+    // Balance and positions cache (15s)
+    balanceCache   *cache.Value[map[string]interface{}]
+    positionsCache *cache.Value[[]map[string]interface{}]
 
     // Contract precision cache
-    contractPrecision map[string]ContractInfo
-    precisionMutex    sync.RWMutex
+    contractPrecision *cache.Cache[string, ContractInfo]
+
+    // 下单类型（默认激进IOC限价单）
+    orderTypeSelector
 
     // Symbol conversion cache
-    symbolCache struct {
-        toGateio   map[string]string // BTCUSDT -> BTC_USDT
-        fromGateio map[string]string // BTC_USDT -> BTCUSDT
-        mu         sync.RWMutex
-    }
+    toGateioSymbol   *cache.Cache[string, string] // BTCUSDT -> BTC_USDT
+    fromGateioSymbol *cache.Cache[string, string] // BTC_USDT -> BTCUSDT
 }
 
 // NewGateioTrader 创建Gate.io交易器
 func NewGateioTrader(apiKey, secretKey string, testnet bool) (*GateioTrader, error) {
     baseURL := "https://api.gateio.ws/api/v4"
+    wsURL := gateioWSURL
     if testnet {
         // Gate.io testnet uses different base URL
         baseURL = "https://api-testnet.gateapi.io/api/v4"
+        wsURL = gateioTestnetWSURL
         log.Printf("✓ Gate.io 测试网模式已启用 (BaseURL: %s)", baseURL)
     } else {
         log.Printf("✓ Gate.io 主网模式 (BaseURL: %s)", baseURL)
@@ -73,17 +74,44 @@ func NewGateioTrader(apiKey, secretKey string, testnet bool) (*GateioTrader, err
         secretKey:         secretKey,
         testnet:           testnet,
         baseURL:           baseURL,
-        client:            &http.Client{Timeout: 30 * time.Second},
-        cacheDuration:     15 * time.Second,
-        contractPrecision: make(map[string]ContractInfo),
+        wsURL:             wsURL,
+        client:            ratelimit.NewClient("gateio", 30*time.Second),
+        balanceCache:      cache.NewValue[map[string]interface{}]("gateio.balance", 15*time.Second),
+        positionsCache:    cache.NewValue[[]map[string]interface{}]("gateio.positions", 15*time.Second),
+        contractPrecision: cache.New[string, ContractInfo]("gateio.contracts", cache.NoExpiry),
+        toGateioSymbol:    cache.New[string, string]("gateio.symbols", cache.NoExpiry),
+        fromGateioSymbol:  cache.New[string, string]("gateio.symbols", cache.NoExpiry),
+        orderTypeSelector: newOrderTypeSelector(OrderTypeIOC, OrderTypeMarket, OrderTypeFOK, OrderTypePostOnly),
     }
-    t.symbolCache.toGateio = make(map[string]string)
-    t.symbolCache.fromGateio = make(map[string]string)
     return t, nil
 }
 
 // --- Helpers ---
 
+// orderPriceAndTIF returns the order price and time-in-force for the order type
+// market: price "0" + ioc (Gate.io true market order)
+// ioc/fok: aggressive limit ±1%
+// post_only: limit just inside the market with tif "poc" (pending-or-cancelled)
+func (t *GateioTrader) orderPriceAndTIF(symbol string, marketPrice float64, isBuy bool, orderType OrderType) (string, string, error) {
+    if orderType == OrderTypeMarket {
+        return "0", "ioc", nil
+    }
+
+    priceStr, err := t.FormatPrice(symbol, limitPriceFor(marketPrice, isBuy, orderType))
+    if err != nil {
+        return "", "", fmt.Errorf("格式化价格失败: %w", err)
+    }
+
+    switch orderType {
+    case OrderTypeFOK:
+        return priceStr, "fok", nil
+    case OrderTypePostOnly:
+        return priceStr, "poc", nil
+    default:
+        return priceStr, "ioc", nil
+    }
+}
+
 func (t *GateioTrader) signRequest(method, path, query, body string, timestamp string) string {
     // Gate.io API v4 signature format:
     // HMAC-SHA512(METHOD\nPREFIX+PATH\nQUERY\nBODY_HASH\nTIMESTAMP, secret_key)
@@ -145,36 +173,183 @@ func (t *GateioTrader) doRequest(method, path string, query url.Values, body str
 
     resp, err := t.client.Do(req)
     if err != nil {
-        return nil, fmt.Errorf("发送请求失败: %w", err)
+        return nil, fmt.Errorf("发送请求失败: %w", errs.Network("gateio", err))
     }
     defer resp.Body.Close()
 
     data, err := io.ReadAll(resp.Body)
     if err != nil {
-        return nil, fmt.Errorf("读取响应失败: %w", err)
+        return nil, fmt.Errorf("读取响应失败: %w", errs.Network("gateio", err))
     }
     if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-        return nil, fmt.Errorf("Gate.io API返回错误 (status %d): %s", resp.StatusCode, string(data))
+        return nil, fmt.Errorf("Gate.io API返回错误: %w", gateioError(resp, data))
     }
     return data, nil
 }
 
+// Gate.io 列表接口按 limit/offset 分页，单页最多100条
+const (
+    gateioPageLimit = 100
+    gateioMaxPages  = 50 // 防止接口异常时无限翻页
+)
+
+// doPaginatedRequest fetches every page of a Gate.io list endpoint (GET with limit/offset)
+func (t *GateioTrader) doPaginatedRequest(path string, query url.Values) ([]map[string]interface{}, error) {
+    params := url.Values{}
+    for k, v := range query {
+        params[k] = v
+    }
+    params.Set("limit", strconv.Itoa(gateioPageLimit))
+
+    var all []map[string]interface{}
+    for page := 0; page < gateioMaxPages; page++ {
+        params.Set("offset", strconv.Itoa(page*gateioPageLimit))
+        data, err := t.doRequest("GET", path, params, "")
+        if err != nil {
+            return nil, err
+        }
+
+        var items []map[string]interface{}
+        if err := json.Unmarshal(data, &items); err != nil {
+            return nil, fmt.Errorf("解析分页响应失败 (%s): %w", path, err)
+        }
+        all = append(all, items...)
+        if len(items) < gateioPageLimit {
+            return all, nil
+        }
+    }
+    log.Printf("⚠️  %s 超过 %d 页，只返回前 %d 条", path, gateioMaxPages, len(all))
+    return all, nil
+}
+
+// fetchPositions fetches all open positions (holding=true filters out empty contracts server-side)
+func (t *GateioTrader) fetchPositions() ([]map[string]interface{}, error) {
+    query := url.Values{}
+    query.Set("holding", "true")
+    return t.doPaginatedRequest("/futures/usdt/positions", query)
+}
+
+// GetOpenPriceOrders lists open price-triggered orders (stop loss / take profit), symbol empty = all contracts
+func (t *GateioTrader) GetOpenPriceOrders(symbol string) ([]map[string]interface{}, error) {
+    query := url.Values{}
+    query.Set("status", "open")
+    if symbol != "" {
+        query.Set("contract", t.convertSymbolToGateio(symbol))
+    }
+    orders, err := t.doPaginatedRequest("/futures/usdt/price_orders", query)
+    if err != nil {
+        return nil, fmt.Errorf("获取条件单失败: %w", err)
+    }
+    return orders, nil
+}
+
+// GetProtectionOrders lists the symbol's open stop loss / take profit price orders (implements ProtectionOrderManager).
+// The kind comes from the "t-sl-" / "t-tp-" text set by SetStopLoss / SetTakeProfit, falling back to the trigger rule;
+// a negative size closes a long position
+func (t *GateioTrader) GetProtectionOrders(symbol string) ([]ProtectionOrder, error) {
+    orders, err := t.GetOpenPriceOrders(symbol)
+    if err != nil {
+        return nil, err
+    }
+    contractInfo, err := t.getContractInfo(symbol)
+    if err != nil {
+        return nil, fmt.Errorf("获取合约信息失败: %w", err)
+    }
+    multiplier := contractInfo.QuantoMultiplier
+    if multiplier <= 0 {
+        multiplier = 1
+    }
+
+    var result []ProtectionOrder
+    for _, order := range orders {
+        initial, _ := order["initial"].(map[string]interface{})
+        trigger, _ := order["trigger"].(map[string]interface{})
+        if initial == nil || trigger == nil {
+            continue
+        }
+        size := orderPrice(initial, "size")
+        side := "long"
+        if size > 0 {
+            side = "short"
+        }
+        text, _ := initial["text"].(string)
+        var kind string
+        switch {
+        case strings.HasPrefix(text, "t-sl-"):
+            kind = "stop_loss"
+        case strings.HasPrefix(text, "t-tp-"):
+            kind = "take_profit"
+        case (orderPrice(trigger, "rule") == 1) == (side == "long"): // rule 1 = price >=
+            kind = "take_profit"
+        default:
+            kind = "stop_loss"
+        }
+        result = append(result, ProtectionOrder{
+            ID:       fmt.Sprintf("%.0f", orderPrice(order, "id")),
+            Symbol:   symbol,
+            Side:     side,
+            Kind:     kind,
+            Price:    orderPrice(trigger, "price"),
+            Quantity: math.Abs(size) * multiplier,
+        })
+    }
+    return result, nil
+}
+
+// CancelProtectionOrder cancels a single price order (implements ProtectionOrderManager)
+func (t *GateioTrader) CancelProtectionOrder(symbol, id string) error {
+    if _, err := t.doRequest("DELETE", "/futures/usdt/price_orders/"+id, nil, ""); err != nil {
+        return fmt.Errorf("取消条件单失败: %w", err)
+    }
+    log.Printf("  ✓ 已取消 %s 条件单 %s", symbol, id)
+    return nil
+}
+
+// GetOrderFill queries the order by id; size and left are in contracts and converted to base quantity
+// with the quanto multiplier. An order is final once its status is "finished" (filled, ioc, cancelled...)
+func (t *GateioTrader) GetOrderFill(symbol string, order map[string]interface{}) (*OrderFill, error) {
+    id := orderPrice(order, "id")
+    if id <= 0 {
+        return nil, fmt.Errorf("下单结果中没有订单ID")
+    }
+    data, err := t.doRequest("GET", fmt.Sprintf("/futures/usdt/orders/%.0f", id), nil, "")
+    if err != nil {
+        return nil, fmt.Errorf("查询订单失败: %w", err)
+    }
+    var result map[string]interface{}
+    if err := json.Unmarshal(data, &result); err != nil {
+        return nil, fmt.Errorf("解析订单响应失败: %w", err)
+    }
+
+    contractInfo, err := t.getContractInfo(symbol)
+    if err != nil {
+        return nil, err
+    }
+    multiplier := contractInfo.QuantoMultiplier
+    if multiplier <= 0 {
+        multiplier = 1
+    }
+    size := math.Abs(orderPrice(result, "size"))
+    left := math.Abs(orderPrice(result, "left"))
+    return &OrderFill{
+        Requested: size * multiplier,
+        Filled:    (size - left) * multiplier,
+        AvgPrice:  orderPrice(result, "fill_price"),
+        Final:     result["status"] == "finished",
+    }, nil
+}
+
 // convertSymbolToGateio converts internal symbol format to Gate.io format
 // Examples: BTCUSDT -> BTC_USDT
 func (t *GateioTrader) convertSymbolToGateio(symbol string) string {
-    t.symbolCache.mu.RLock()
-    if v, ok := t.symbolCache.toGateio[symbol]; ok {
-        t.symbolCache.mu.RUnlock()
+    if v, ok := t.toGateioSymbol.Get(symbol); ok {
         return v
     }
-    t.symbolCache.mu.RUnlock()
 
     re := regexp.MustCompile(`^([A-Z]+)(USDT|USDC|BTC|ETH|BUSD)$`)
     if m := re.FindStringSubmatch(symbol); len(m) == 3 {
         converted := fmt.Sprintf("%s_%s", m[1], m[2])
-        t.symbolCache.mu.Lock()
-        t.symbolCache.toGateio[symbol] = converted
-        t.symbolCache.mu.Unlock()
+        t.toGateioSymbol.Set(symbol, converted)
         return converted
     }
     if strings.Contains(symbol, "_") {
@@ -186,30 +361,22 @@ func (t *GateioTrader) convertSymbolToGateio(symbol string) string {
 
 // convertSymbolFromGateio converts Gate.io format back to internal format
 func (t *GateioTrader) convertSymbolFromGateio(gateioSymbol string) string {
-    t.symbolCache.mu.RLock()
-    if v, ok := t.symbolCache.fromGateio[gateioSymbol]; ok {
-        t.symbolCache.mu.RUnlock()
+    if v, ok := t.fromGateioSymbol.Get(gateioSymbol); ok {
         return v
     }
-    t.symbolCache.mu.RUnlock()
     converted := strings.ReplaceAll(gateioSymbol, "_", "")
-    t.symbolCache.mu.Lock()
-    t.symbolCache.fromGateio[gateioSymbol] = converted
-    t.symbolCache.mu.Unlock()
+    t.fromGateioSymbol.Set(gateioSymbol, converted)
     return converted
 }
 
 // --- Trader interface stubs (to be completed) ---
 
 func (t *GateioTrader) GetBalance() (map[string]interface{}, error) {
-    // GET /futures/usdt/accounts with caching
-    t.balanceCacheMutex.RLock()
-    if time.Since(t.balanceCacheTime) < t.cacheDuration && t.cachedBalance != nil {
-        defer t.balanceCacheMutex.RUnlock()
-        return t.cachedBalance, nil
-    }
-    t.balanceCacheMutex.RUnlock()
+    return t.balanceCache.GetOrLoad(t.loadBalance)
+}
 
+// loadBalance GET /futures/usdt/accounts
+func (t *GateioTrader) loadBalance() (map[string]interface{}, error) {
     data, err := t.doRequest("GET", "/futures/usdt/accounts", nil, "")
     if err != nil {
         return nil, err
@@ -270,33 +437,21 @@ func (t *GateioTrader) GetBalance() (map[string]interface{}, error) {
         "total_equity":          parseFloat(acc["total"]),
         "total_unrealized_pnl":  totalUnrealizedPnL,
     }
-    t.balanceCacheMutex.Lock()
-    t.cachedBalance = resp
-    t.balanceCacheTime = time.Now()
-    t.balanceCacheMutex.Unlock()
     return resp, nil
 }
 
 func (t *GateioTrader) GetPositions() ([]map[string]interface{}, error) {
-    // GET /futures/usdt/positions with caching and symbol conversion
-    t.positionsCacheMutex.RLock()
-    if time.Since(t.positionsCacheTime) < t.cacheDuration && t.cachedPositions != nil {
-        defer t.positionsCacheMutex.RUnlock()
-        return t.cachedPositions, nil
-    }
-    t.positionsCacheMutex.RUnlock()
+    return t.positionsCache.GetOrLoad(t.loadPositions)
+}
 
-    data, err := t.doRequest("GET", "/futures/usdt/positions", nil, "")
+// loadPositions GET /futures/usdt/positions with symbol conversion
+func (t *GateioTrader) loadPositions() ([]map[string]interface{}, error) {
+    // Gate.io returns numeric values as strings, parse flexibly
+    raw, err := t.fetchPositions()
     if err != nil {
         return nil, err
     }
     
-    // Gate.io returns numeric values as strings, parse flexibly
-    var raw []map[string]interface{}
-    if err := json.Unmarshal(data, &raw); err != nil {
-        return nil, fmt.Errorf("解析持仓响应失败: %w", err)
-    }
-    
     // Helper to parse float from string or number
     parseFloat := func(v interface{}) float64 {
         switch val := v.(type) {
@@ -338,6 +493,14 @@ func (t *GateioTrader) GetPositions() ([]map[string]interface{}, error) {
             side = "short"
             size = -size  // Make size positive
         }
+        // Dual mode (hedge): the same contract is returned once per side, mode tells which one
+        mode, _ := p["mode"].(string)
+        switch mode {
+        case "dual_long":
+            side = "long"
+        case "dual_short":
+            side = "short"
+        }
         
         // Calculate mark price from value and size
         markPrice := 0.0
@@ -356,16 +519,28 @@ func (t *GateioTrader) GetPositions() ([]map[string]interface{}, error) {
             "unRealizedProfit":   unrealizedPnl,
             "liquidationPrice":   parseFloat(p["liq_price"]),
             "side":               side,
+            "margin":             parseFloat(p["margin"]),            // Position margin reported by Gate.io
+            "adlRanking":         int(parseFloat(p["adl_ranking"])),  // 1-5, 5 = first in ADL queue
+            "realisedPnl":        parseFloat(p["realised_pnl"]),
+            "mode":               mode,                               // single / dual_long / dual_short
         })
     }
-    t.positionsCacheMutex.Lock()
-    t.cachedPositions = positions
-    t.positionsCacheTime = time.Now()
-    t.positionsCacheMutex.Unlock()
     return positions, nil
 }
 
-func (t *GateioTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+// GetAccountSnapshot fetches balance and positions together, bypassing both caches
+// (orders only invalidate the positions cache, so a cached balance can lag behind the positions)
+func (t *GateioTrader) GetAccountSnapshot() (map[string]interface{}, []map[string]interface{}, error) {
+    t.invalidateCaches()
+    return fetchBalanceAndPositions(t.GetBalance, t.GetPositions)
+}
+
+func (t *GateioTrader) OpenLong(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error) {
+    orderType, err := t.resolveOrderType(orderType)
+    if err != nil {
+        return nil, err
+    }
+
     // Cancel existing orders first
     if err := t.CancelAllOrders(symbol); err != nil {
         log.Printf("  ⚠ 取消旧订单失败: %v", err)
@@ -390,13 +565,10 @@ func (t *GateioTrader) OpenLong(symbol string, quantity float64, leverage int) (
         return nil, fmt.Errorf("获取市场价格失败: %w", err)
     }
 
-    // Use aggressive limit price (1% above market for long)
-    limitPrice := price * 1.01
-    
-    // Format price according to contract's tick size
-    priceStr, err := t.FormatPrice(symbol, limitPrice)
+    // Price and time-in-force follow the requested order type
+    priceStr, tif, err := t.orderPriceAndTIF(symbol, price, true, orderType)
     if err != nil {
-        return nil, fmt.Errorf("格式化价格失败: %w", err)
+        return nil, err
     }
 
     // Gate.io order: size is positive for long, negative for short
@@ -423,7 +595,7 @@ func (t *GateioTrader) OpenLong(symbol string, quantity float64, leverage int) (
         "contract": gateSymbol,
         "size":     sizeInContracts, // Positive for long, integer (contracts)
         "price":    priceStr,        // String: formatted price
-        "tif":      "ioc",           // Immediate or Cancel (market-like)
+        "tif":      tif,             // ioc / fok / poc depending on order type
         "text":     fmt.Sprintf("t-%s", symbol), // Client order ID
         "reduce_only": false,        // Not reducing existing position
     }
@@ -446,14 +618,17 @@ func (t *GateioTrader) OpenLong(symbol string, quantity float64, leverage int) (
     log.Printf("✓ 开多仓成功: %s 数量: %d contracts", symbol, sizeInContracts)
 
     // Invalidate position cache
-    t.positionsCacheMutex.Lock()
-    t.positionsCacheTime = time.Time{}
-    t.positionsCacheMutex.Unlock()
+    t.positionsCache.Invalidate()
 
     return result, nil
 }
 
-func (t *GateioTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *GateioTrader) OpenShort(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error) {
+    orderType, err := t.resolveOrderType(orderType)
+    if err != nil {
+        return nil, err
+    }
+
     // Cancel existing orders first
     if err := t.CancelAllOrders(symbol); err != nil {
         log.Printf("  ⚠ 取消旧订单失败: %v", err)
@@ -478,13 +653,10 @@ func (t *GateioTrader) OpenShort(symbol string, quantity float64, leverage int)
         return nil, fmt.Errorf("获取市场价格失败: %w", err)
     }
 
-    // Use aggressive limit price (1% below market for short)
-    limitPrice := price * 0.99
-    
-    // Format price according to contract's tick size
-    priceStr, err := t.FormatPrice(symbol, limitPrice)
+    // Price and time-in-force follow the requested order type
+    priceStr, tif, err := t.orderPriceAndTIF(symbol, price, false, orderType)
     if err != nil {
-        return nil, fmt.Errorf("格式化价格失败: %w", err)
+        return nil, err
     }
 
     // Gate.io order: size is negative for short
@@ -515,7 +687,7 @@ func (t *GateioTrader) OpenShort(symbol string, quantity float64, leverage int)
         "contract": gateSymbol,
         "size":     sizeInContracts, // Integer: negative for short, positive for long
         "price":    priceStr,        // String: formatted price
-        "tif":      "ioc",           // Immediate or Cancel (market-like)
+        "tif":      tif,             // ioc / fok / poc depending on order type
         "text":     fmt.Sprintf("t-%s", symbol), // Client order ID
         "reduce_only": false,        // Not reducing existing position
     }
@@ -548,28 +720,26 @@ func (t *GateioTrader) OpenShort(symbol string, quantity float64, leverage int)
     log.Printf("✓ 开空仓成功: %s 数量: %d contracts", symbol, -sizeInContracts)
 
     // Invalidate position cache
-    t.positionsCacheMutex.Lock()
-    t.positionsCacheTime = time.Time{}
-    t.positionsCacheMutex.Unlock()
+    t.positionsCache.Invalidate()
 
     return result, nil
 }
 
-func (t *GateioTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *GateioTrader) CloseLong(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error) {
+    orderType, err := t.resolveOrderType(orderType)
+    if err != nil {
+        return nil, err
+    }
+
     gateSymbol := t.convertSymbolToGateio(symbol)
 
     // Fetch position directly from Gate.io API to get exact size in contracts
     // This avoids conversion issues and ensures we use the exact position size
-    data, err := t.doRequest("GET", "/futures/usdt/positions", nil, "")
+    raw, err := t.fetchPositions()
     if err != nil {
         return nil, fmt.Errorf("获取持仓失败: %w", err)
     }
 
-    var raw []map[string]interface{}
-    if err := json.Unmarshal(data, &raw); err != nil {
-        return nil, fmt.Errorf("解析持仓响应失败: %w", err)
-    }
-
     // Helper to parse float from string or number
     parseFloat := func(v interface{}) float64 {
         switch val := v.(type) {
@@ -653,13 +823,10 @@ func (t *GateioTrader) CloseLong(symbol string, quantity float64) (map[string]in
         return nil, fmt.Errorf("获取市场价格失败: %w", err)
     }
 
-    // Use aggressive limit price (1% below market to close long)
-    limitPrice := price * 0.99
-    
-    // Format price according to contract's tick size
-    priceStr, err := t.FormatPrice(symbol, limitPrice)
+    // Price and time-in-force follow the requested order type
+    priceStr, tif, err := t.orderPriceAndTIF(symbol, price, false, orderType)
     if err != nil {
-        return nil, fmt.Errorf("格式化价格失败: %w", err)
+        return nil, err
     }
 
     // Gate.io: to close long, use negative size with reduce_only
@@ -669,7 +836,7 @@ func (t *GateioTrader) CloseLong(symbol string, quantity float64) (map[string]in
         "contract":    gateSymbol,
         "size":        sizeInContracts, // Negative to close long, integer (contracts)
         "price":       priceStr,
-        "tif":         "ioc",
+        "tif":         tif,
         "text":        fmt.Sprintf("t-%s", symbol), // Client order ID
         "reduce_only": true, // Important: reduce only to close position
     }
@@ -679,7 +846,7 @@ func (t *GateioTrader) CloseLong(symbol string, quantity float64) (map[string]in
         return nil, fmt.Errorf("序列化订单失败: %w", err)
     }
 
-    data, err = t.doRequest("POST", "/futures/usdt/orders", nil, string(bodyJSON))
+    data, err := t.doRequest("POST", "/futures/usdt/orders", nil, string(bodyJSON))
     if err != nil {
         return nil, fmt.Errorf("平多仓失败: %w", err)
     }
@@ -692,28 +859,26 @@ func (t *GateioTrader) CloseLong(symbol string, quantity float64) (map[string]in
     log.Printf("✓ 平多仓成功: %s 数量: %d contracts", symbol, -sizeInContracts)
 
     // Invalidate position cache
-    t.positionsCacheMutex.Lock()
-    t.positionsCacheTime = time.Time{}
-    t.positionsCacheMutex.Unlock()
+    t.positionsCache.Invalidate()
 
     return result, nil
 }
 
-func (t *GateioTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *GateioTrader) CloseShort(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error) {
+    orderType, err := t.resolveOrderType(orderType)
+    if err != nil {
+        return nil, err
+    }
+
     gateSymbol := t.convertSymbolToGateio(symbol)
 
     // Fetch position directly from Gate.io API to get exact size in contracts
     // This avoids conversion issues and ensures we use the exact position size
-    data, err := t.doRequest("GET", "/futures/usdt/positions", nil, "")
+    raw, err := t.fetchPositions()
     if err != nil {
         return nil, fmt.Errorf("获取持仓失败: %w", err)
     }
 
-    var raw []map[string]interface{}
-    if err := json.Unmarshal(data, &raw); err != nil {
-        return nil, fmt.Errorf("解析持仓响应失败: %w", err)
-    }
-
     // Helper to parse float from string or number
     parseFloat := func(v interface{}) float64 {
         switch val := v.(type) {
@@ -812,13 +977,10 @@ func (t *GateioTrader) CloseShort(symbol string, quantity float64) (map[string]i
         return nil, fmt.Errorf("获取市场价格失败: %w", err)
     }
 
-    // Use aggressive limit price (1% above market to close short)
-    limitPrice := price * 1.01
-    
-    // Format price according to contract's tick size
-    priceStr, err := t.FormatPrice(symbol, limitPrice)
+    // Price and time-in-force follow the requested order type
+    priceStr, tif, err := t.orderPriceAndTIF(symbol, price, true, orderType)
     if err != nil {
-        return nil, fmt.Errorf("格式化价格失败: %w", err)
+        return nil, err
     }
 
     // Gate.io: to close short, use positive size with reduce_only
@@ -826,7 +988,7 @@ func (t *GateioTrader) CloseShort(symbol string, quantity float64) (map[string]i
         "contract":    gateSymbol,
         "size":        sizeInContracts, // Positive to close short, integer (contracts)
         "price":       priceStr,
-        "tif":         "ioc",
+        "tif":         tif,
         "text":        fmt.Sprintf("t-%s", symbol), // Client order ID
         "reduce_only": true, // Important: reduce only to close position
     }
@@ -836,7 +998,7 @@ func (t *GateioTrader) CloseShort(symbol string, quantity float64) (map[string]i
         return nil, fmt.Errorf("序列化订单失败: %w", err)
     }
 
-    data, err = t.doRequest("POST", "/futures/usdt/orders", nil, string(bodyJSON))
+    data, err := t.doRequest("POST", "/futures/usdt/orders", nil, string(bodyJSON))
     if err != nil {
         return nil, fmt.Errorf("平空仓失败: %w", err)
     }
@@ -849,9 +1011,7 @@ func (t *GateioTrader) CloseShort(symbol string, quantity float64) (map[string]i
     log.Printf("✓ 平空仓成功: %s 数量: %d contracts", symbol, sizeInContracts)
 
     // Invalidate position cache
-    t.positionsCacheMutex.Lock()
-    t.positionsCacheTime = time.Time{}
-    t.positionsCacheMutex.Unlock()
+    t.positionsCache.Invalidate()
 
     return result, nil
 }
@@ -869,12 +1029,25 @@ func (t *GateioTrader) CancelAllOrders(symbol string) error {
         return nil
     }
 
+    // Stop-loss / take-profit are price-triggered orders and live in a separate list
+    priceQuery := url.Values{}
+    priceQuery.Set("contract", gateSymbol)
+    if _, err := t.doRequest("DELETE", "/futures/usdt/price_orders", priceQuery, ""); err != nil {
+        log.Printf("⚠️  取消条件单失败 (可能没有条件单): %v", err)
+    }
+
     log.Printf("✓ 已取消 %s 的所有订单", symbol)
     return nil
 }
 
 func (t *GateioTrader) SetLeverage(symbol string, leverage int) error {
     gateSymbol := t.convertSymbolToGateio(symbol)
+
+    // Skip the call when the contract already has this isolated leverage
+    if current, err := t.currentLeverage(gateSymbol); err == nil && current == leverage {
+        log.Printf("  ✓ %s 杠杆已是 %dx，无需切换", symbol, leverage)
+        return nil
+    }
     
     // Gate.io requires leverage as query parameter (not in body) based on API docs
     query := url.Values{}
@@ -887,6 +1060,11 @@ func (t *GateioTrader) SetLeverage(symbol string, leverage int) error {
 
     _, err := t.doRequest("POST", fmt.Sprintf("/futures/usdt/positions/%s/leverage", gateSymbol), query, "")
     if err != nil {
+        // Some position modes reject setting the leverage it already has
+        if isNotModified(err) {
+            log.Printf("  ✓ %s 杠杆已是 %dx", symbol, leverage)
+            return nil
+        }
         return fmt.Errorf("设置杠杆失败: %w", err)
     }
 
@@ -894,24 +1072,65 @@ func (t *GateioTrader) SetLeverage(symbol string, leverage int) error {
     return nil
 }
 
+// currentLeverage returns the contract's current leverage (0 means cross margin).
+// Gate.io reports the leverage even when there is no position; in dual (hedge) mode
+// the single-position endpoint is rejected and the dual_comp one returns both sides.
+func (t *GateioTrader) currentLeverage(gateSymbol string) (int, error) {
+    data, err := t.doRequest("GET", fmt.Sprintf("/futures/usdt/positions/%s", gateSymbol), nil, "")
+    if err != nil {
+        data, err = t.doRequest("GET", fmt.Sprintf("/futures/usdt/dual_comp/positions/%s", gateSymbol), nil, "")
+        if err != nil {
+            return 0, err
+        }
+    }
+
+    var positions []map[string]interface{}
+    if err := json.Unmarshal(data, &positions); err != nil {
+        var single map[string]interface{}
+        if err := json.Unmarshal(data, &single); err != nil {
+            return 0, fmt.Errorf("解析持仓失败: %w", err)
+        }
+        positions = []map[string]interface{}{single}
+    }
+    leverage := -1
+    for _, p := range positions {
+        lev, err := strconv.Atoi(fmt.Sprint(p["leverage"]))
+        if err != nil {
+            return 0, fmt.Errorf("%s 杠杆格式无效: %v", gateSymbol, p["leverage"])
+        }
+        if leverage >= 0 && lev != leverage {
+            return 0, fmt.Errorf("%s 多空两侧杠杆不同", gateSymbol)
+        }
+        leverage = lev
+    }
+    if leverage < 0 {
+        return 0, fmt.Errorf("%s 没有杠杆信息", gateSymbol)
+    }
+    return leverage, nil
+}
+
 // getContractInfo fetches contract information including precision and min order size
 func (t *GateioTrader) getContractInfo(symbol string) (*ContractInfo, error) {
-    t.precisionMutex.RLock()
-    if info, ok := t.contractPrecision[symbol]; ok {
-        t.precisionMutex.RUnlock()
-        return &info, nil
+    info, err := t.contractPrecision.GetOrLoad(symbol, func() (ContractInfo, error) {
+        return t.loadContractInfo(symbol)
+    })
+    if err != nil {
+        return nil, err
     }
-    t.precisionMutex.RUnlock()
+    return &info, nil
+}
 
+// loadContractInfo GET /futures/usdt/contracts/{contract}
+func (t *GateioTrader) loadContractInfo(symbol string) (ContractInfo, error) {
     gateSymbol := t.convertSymbolToGateio(symbol)
     data, err := t.doRequest("GET", fmt.Sprintf("/futures/usdt/contracts/%s", gateSymbol), nil, "")
     if err != nil {
-        return nil, fmt.Errorf("获取合约信息失败: %w", err)
+        return ContractInfo{}, fmt.Errorf("获取合约信息失败: %w", err)
     }
 
     var contract map[string]interface{}
     if err := json.Unmarshal(data, &contract); err != nil {
-        return nil, fmt.Errorf("解析合约信息失败: %w", err)
+        return ContractInfo{}, fmt.Errorf("解析合约信息失败: %w", err)
     }
 
     // Debug: Log raw contract data for testnet
@@ -974,11 +1193,7 @@ func (t *GateioTrader) getContractInfo(symbol string) (*ContractInfo, error) {
             info.QuantoMultiplier, info.OrderSizeMin, info.OrderPriceMin, info.TickSize)
     }
 
-    t.precisionMutex.Lock()
-    t.contractPrecision[symbol] = info
-    t.precisionMutex.Unlock()
-
-    return &info, nil
+    return info, nil
 }
 
 func (t *GateioTrader) GetMarketPrice(symbol string) (float64, error) {
@@ -1034,6 +1249,14 @@ func (t *GateioTrader) GetMarketPrice(symbol string) (float64, error) {
     return price, nil
 }
 
+// protectionText 止损止盈单text的后缀：有持仓ID时使用持仓ID，否则为币种（text最长28字节）
+func (t *GateioTrader) protectionText(symbol, positionSide string) string {
+    if tag := t.positionTag(symbol, positionSide); tag != "" {
+        return tag
+    }
+    return symbol
+}
+
 func (t *GateioTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
     gateSymbol := t.convertSymbolToGateio(symbol)
 
@@ -1079,7 +1302,7 @@ func (t *GateioTrader) SetStopLoss(symbol string, positionSide string, quantity,
             "size":         sizeInContracts, // Use explicit size instead of auto_size
             "price":        "0", // Market price when triggered
             "tif":          "ioc",
-            "text":         fmt.Sprintf("t-sl-%s", symbol),
+            "text":         fmt.Sprintf("t-sl-%s", t.protectionText(symbol, positionSide)),
             "reduce_only":  true, // Required for closing orders
         },
         "trigger": map[string]interface{}{
@@ -1188,7 +1411,7 @@ func (t *GateioTrader) SetTakeProfit(symbol string, positionSide string, quantit
             "size":         sizeInContracts, // Use explicit size instead of auto_size
             "price":        "0", // Market price when triggered
             "tif":          "ioc",
-            "text":         fmt.Sprintf("t-tp-%s", symbol),
+            "text":         fmt.Sprintf("t-tp-%s", t.protectionText(symbol, positionSide)),
             "reduce_only":  true, // Required for closing orders
         },
         "trigger": map[string]interface{}{
@@ -1270,6 +1493,32 @@ func (t *GateioTrader) FormatQuantity(symbol string, quantity float64) (string,
     return fmt.Sprintf("%.6f", quantity), nil
 }
 
+// RoundPrice rounds a price to the contract's tick size (order_price_round)
+func (t *GateioTrader) RoundPrice(symbol string, price float64) (float64, error) {
+    info, err := t.getContractInfo(symbol)
+    if err != nil {
+        return 0, err
+    }
+    return roundToTick(price, info.TickSize), nil
+}
+
+// ContractSize converts a base quantity to order contracts, rounded the same way as OpenLong/OpenShort
+func (t *GateioTrader) ContractSize(symbol string, quantity float64) (int64, float64, error) {
+    info, err := t.getContractInfo(symbol)
+    if err != nil {
+        return 0, 0, err
+    }
+    multiplier := info.QuantoMultiplier
+    if multiplier <= 0 {
+        multiplier = 1
+    }
+    contracts := int64(quantity/multiplier + 0.5)
+    if minContracts := int64(info.OrderSizeMin); contracts < minContracts {
+        contracts = minContracts
+    }
+    return contracts, multiplier, nil
+}
+
 // FormatPrice formats price according to contract's tick size
 func (t *GateioTrader) FormatPrice(symbol string, price float64) (string, error) {
     info, err := t.getContractInfo(symbol)
@@ -1312,3 +1561,246 @@ func decodeJSON(data []byte, v interface{}) error {
 }
 
 
+
+// GetFundingPayments 获取资金费流水（合约账户变更历史中 type=fund 的记录）
+func (t *GateioTrader) GetFundingPayments(since time.Time) ([]FundingPayment, error) {
+    query := url.Values{}
+    query.Set("type", "fund")
+    query.Set("from", strconv.FormatInt(since.Unix(), 10))
+    query.Set("limit", "1000")
+
+    data, err := t.doRequest("GET", "/futures/usdt/account_book", query, "")
+    if err != nil {
+        return nil, fmt.Errorf("获取资金费流水失败: %w", err)
+    }
+
+    var raw []map[string]interface{}
+    if err := json.Unmarshal(data, &raw); err != nil {
+        return nil, fmt.Errorf("解析资金费流水失败: %w", err)
+    }
+
+    parseFloat := func(v interface{}) float64 {
+        switch val := v.(type) {
+        case float64:
+            return val
+        case string:
+            f, _ := strconv.ParseFloat(val, 64)
+            return f
+        }
+        return 0
+    }
+
+    payments := make([]FundingPayment, 0, len(raw))
+    for _, r := range raw {
+        contract, _ := r["contract"].(string)
+        if contract == "" {
+            continue
+        }
+        ts := parseFloat(r["time"])
+        payments = append(payments, FundingPayment{
+            ID:     fmt.Sprintf("%v_%v", r["id"], ts),
+            Symbol: t.convertSymbolFromGateio(contract),
+            Amount: parseFloat(r["change"]),
+            Time:   time.Unix(0, int64(ts*float64(time.Second))),
+        })
+    }
+    return payments, nil
+}
+
+// GetRealizedPnL 获取已实现盈亏流水（合约账户变更历史中 type=pnl 的记录）
+func (t *GateioTrader) GetRealizedPnL(since, until time.Time) ([]RealizedPnLRecord, error) {
+    query := url.Values{}
+    query.Set("type", "pnl")
+    query.Set("from", strconv.FormatInt(since.Unix(), 10))
+    query.Set("to", strconv.FormatInt(until.Unix(), 10))
+    query.Set("limit", "1000")
+
+    data, err := t.doRequest("GET", "/futures/usdt/account_book", query, "")
+    if err != nil {
+        return nil, fmt.Errorf("获取盈亏流水失败: %w", err)
+    }
+
+    var raw []map[string]interface{}
+    if err := json.Unmarshal(data, &raw); err != nil {
+        return nil, fmt.Errorf("解析盈亏流水失败: %w", err)
+    }
+
+    records := make([]RealizedPnLRecord, 0, len(raw))
+    for _, r := range raw {
+        contract, _ := r["contract"].(string)
+        if contract == "" {
+            continue
+        }
+        ts := time.Unix(0, int64(orderPrice(r, "time")*float64(time.Second)))
+        if ts.Before(since) || !ts.Before(until) {
+            continue
+        }
+        records = append(records, RealizedPnLRecord{
+            Symbol: t.convertSymbolFromGateio(contract),
+            Amount: orderPrice(r, "change"),
+            Time:   ts,
+        })
+    }
+    return records, nil
+}
+
+// GetTradeHistory fetches fills from /futures/usdt/my_trades (newest first, paged with limit/offset until
+// older than since). Sizes are in contracts and converted with the quanto multiplier; close_size is the
+// part of the fill that reduced an existing position.
+func (t *GateioTrader) GetTradeHistory(since, until time.Time) ([]HistoricalFill, error) {
+    params := url.Values{}
+    params.Set("limit", strconv.Itoa(gateioPageLimit))
+
+    var fills []HistoricalFill
+    for page := 0; page < gateioMaxPages; page++ {
+        params.Set("offset", strconv.Itoa(page*gateioPageLimit))
+        data, err := t.doRequest("GET", "/futures/usdt/my_trades", params, "")
+        if err != nil {
+            return nil, fmt.Errorf("获取成交历史失败: %w", err)
+        }
+        var items []map[string]interface{}
+        if err := json.Unmarshal(data, &items); err != nil {
+            return nil, fmt.Errorf("解析成交历史失败: %w", err)
+        }
+
+        for _, item := range items {
+            created := time.Unix(0, int64(orderPrice(item, "create_time")*float64(time.Second)))
+            if created.Before(since) {
+                return fills, nil
+            }
+            if !created.Before(until) {
+                continue
+            }
+            contract, _ := item["contract"].(string)
+            symbol := t.convertSymbolFromGateio(contract)
+            multiplier := 1.0
+            if info, err := t.getContractInfo(symbol); err == nil && info.QuantoMultiplier > 0 {
+                multiplier = info.QuantoMultiplier
+            }
+            size := orderPrice(item, "size")
+            fill := HistoricalFill{
+                ID:        fmt.Sprintf("%.0f", orderPrice(item, "id")),
+                Symbol:    symbol,
+                Side:      "buy",
+                Quantity:  math.Abs(size) * multiplier,
+                Price:     orderPrice(item, "price"),
+                ClosedQty: math.Abs(orderPrice(item, "close_size")) * multiplier,
+                Time:      created,
+            }
+            if size < 0 {
+                fill.Side = "sell"
+            }
+            fills = append(fills, fill)
+        }
+        if len(items) < gateioPageLimit {
+            return fills, nil
+        }
+    }
+    log.Printf("⚠️  成交历史超过 %d 页，只导入最近 %d 笔", gateioMaxPages, len(fills))
+    return fills, nil
+}
+
+// ListInstruments 获取USDT永续合约列表
+func (t *GateioTrader) ListInstruments() ([]Instrument, error) {
+    data, err := t.doRequest("GET", "/futures/usdt/contracts", nil, "")
+    if err != nil {
+        return nil, fmt.Errorf("获取合约列表失败: %w", err)
+    }
+
+    var raw []map[string]interface{}
+    if err := json.Unmarshal(data, &raw); err != nil {
+        return nil, fmt.Errorf("解析合约列表失败: %w", err)
+    }
+
+    parseFloat := func(v interface{}) float64 {
+        switch val := v.(type) {
+        case float64:
+            return val
+        case string:
+            f, _ := strconv.ParseFloat(val, 64)
+            return f
+        }
+        return 0
+    }
+    unixTime := func(v interface{}) time.Time {
+        if sec := parseFloat(v); sec > 0 {
+            return time.Unix(int64(sec), 0)
+        }
+        return time.Time{}
+    }
+
+    instruments := make([]Instrument, 0, len(raw))
+    for _, c := range raw {
+        name, _ := c["name"].(string)
+        if name == "" {
+            continue
+        }
+        inst := Instrument{
+            Symbol:   t.convertSymbolFromGateio(name),
+            Status:   InstrumentTrading,
+            ListedAt: unixTime(c["create_time"]),
+            DelistAt: unixTime(c["delisting_time"]),
+        }
+        if delisting, _ := c["in_delisting"].(bool); delisting {
+            inst.Status = InstrumentDelisting
+        }
+        if delistedAt := unixTime(c["delisted_time"]); !delistedAt.IsZero() && delistedAt.Before(time.Now()) {
+            inst.Status = InstrumentDelisted
+        }
+        instruments = append(instruments, inst)
+    }
+    return instruments, nil
+}
+
+// SetProxy 之后的请求经过出口代理（保留限频层）
+func (t *GateioTrader) SetProxy(proxyURL *url.URL) {
+    t.proxyURL = proxyURL
+    t.client.Transport = withProxy(t.client.Transport, proxyURL)
+}
+
+// GetAccountPermissions checks futures trading permission, position mode, fee tier and rates.
+// Gate.io has no endpoint listing API key permissions, so trading permission is probed with a
+// harmless write (disabling the cancel-all countdown, which this system never sets) and the
+// withdrawal permission is reported as unknown.
+func (t *GateioTrader) GetAccountPermissions() (*AccountPermissions, error) {
+    data, err := t.doRequest("GET", "/futures/usdt/accounts", nil, "")
+    if err != nil {
+        return nil, fmt.Errorf("获取合约账户失败: %w", err)
+    }
+    var account struct {
+        InDualMode bool `json:"in_dual_mode"`
+    }
+    if err := json.Unmarshal(data, &account); err != nil {
+        return nil, fmt.Errorf("解析合约账户失败: %w", err)
+    }
+
+    perms := &AccountPermissions{CanTrade: true, PositionMode: PositionModeOneWay}
+    if account.InDualMode {
+        perms.PositionMode = PositionModeHedge
+    }
+
+    if _, err := t.doRequest("POST", "/futures/usdt/countdown_cancel_all", nil, `{"timeout":0}`); err != nil {
+        if errors.Is(err, errs.ErrAuth) {
+            perms.CanTrade = false
+        } else {
+            log.Printf("⚠️ 检查Gate.io合约交易权限失败: %v", err)
+        }
+    }
+
+    if data, err := t.doRequest("GET", "/wallet/fee", nil, ""); err != nil {
+        log.Printf("⚠️ 获取Gate.io手续费率失败: %v", err)
+    } else {
+        var fee struct {
+            Tier            int    `json:"tier"`
+            FuturesMakerFee string `json:"futures_maker_fee"`
+            FuturesTakerFee string `json:"futures_taker_fee"`
+        }
+        if err := json.Unmarshal(data, &fee); err == nil {
+            perms.Tier = fmt.Sprintf("VIP %d", fee.Tier)
+            perms.MakerFeeRate, _ = strconv.ParseFloat(fee.FuturesMakerFee, 64)
+            perms.TakerFeeRate, _ = strconv.ParseFloat(fee.FuturesTakerFee, 64)
+            perms.FeesKnown = true
+        }
+    }
+    return perms, nil
+}
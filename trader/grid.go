@@ -0,0 +1,176 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// 网格/DCA策略（strategy: "grid"）
+// 不调用AI的确定性基准：每个币种在 [lower, upper] 区间内等距划分 levels 格，价格每跌过一条网格线持有一格
+// （size_per_level_usd），涨回网格线之上卖出一格；价格高于 upper 时全部卖出，低于 lower 时持满不再加仓。
+// 只做多。目标格数按当前价格计算，持有格数由交易所持仓推算（持仓名义价值 ÷ 每格金额），因此重启后无需额外状态。
+// 策略只负责产生决策（open_long / add_to_position / partial_close / close_long），
+// 之后与AI决策走同一套执行流程：只允许平仓时过滤开仓、操作日志、止损止盈挂单和决策记录。
+
+const defaultGridStopLossPct = 5.0
+
+// GridSymbol 单个币种的网格配置
+type GridSymbol struct {
+	Symbol          string
+	Lower           float64 // 区间下沿
+	Upper           float64 // 区间上沿
+	Levels          int     // 格数
+	SizePerLevelUSD float64 // 每格名义价值（USDT）
+	Leverage        int     // 杠杆（默认1）
+	StopLossPct     float64 // 止损价低于下沿的百分比（默认5）
+}
+
+// step 每格的价格间距
+func (g GridSymbol) step() float64 {
+	return (g.Upper - g.Lower) / float64(g.Levels)
+}
+
+// targetLevels 价格对应应持有的格数：上沿以下每跌过一条网格线持有一格
+func (g GridSymbol) targetLevels(price float64) int {
+	if price >= g.Upper {
+		return 0
+	}
+	levels := int(math.Floor((g.Upper-price)/g.step() + 1e-9))
+	if levels > g.Levels {
+		levels = g.Levels
+	}
+	return levels
+}
+
+func (g GridSymbol) stopLoss() float64 {
+	return g.Lower * (1 - g.StopLossPct/100)
+}
+
+// takeProfit 上沿再上一格：价格在周期之间越过上沿时由止盈单兜底
+func (g GridSymbol) takeProfit() float64 {
+	return g.Upper + g.step()
+}
+
+// gridStrategy 网格策略配置
+type gridStrategy struct {
+	symbols []GridSymbol
+}
+
+// newGridStrategy 校验配置并补全默认值
+func newGridStrategy(symbols []GridSymbol) (*gridStrategy, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("grid至少需要配置一个币种")
+	}
+	seen := make(map[string]bool)
+	grid := &gridStrategy{}
+	for _, g := range symbols {
+		g.Symbol = market.Normalize(g.Symbol)
+		switch {
+		case seen[g.Symbol]:
+			return nil, fmt.Errorf("grid币种 %s 重复", g.Symbol)
+		case g.Lower <= 0 || g.Upper <= g.Lower:
+			return nil, fmt.Errorf("grid %s: 需要 0 < lower < upper", g.Symbol)
+		case g.Levels <= 0:
+			return nil, fmt.Errorf("grid %s: levels必须大于0", g.Symbol)
+		case g.SizePerLevelUSD <= 0:
+			return nil, fmt.Errorf("grid %s: size_per_level_usd必须大于0", g.Symbol)
+		}
+		if g.Leverage <= 0 {
+			g.Leverage = 1
+		}
+		if g.StopLossPct <= 0 {
+			g.StopLossPct = defaultGridStopLossPct
+		}
+		seen[g.Symbol] = true
+		grid.symbols = append(grid.symbols, g)
+	}
+	return grid, nil
+}
+
+// gridDecision 按网格产生本周期的决策（替代AI决策）
+func (at *AutoTrader) gridDecision(ctx *decision.Context) (*decision.FullDecision, error) {
+	if ctx.MarketDataMap == nil {
+		ctx.MarketDataMap = make(map[string]*market.Data)
+	}
+	positions := make(map[string]decision.PositionInfo)
+	for _, pos := range ctx.Positions {
+		positions[pos.Symbol+"_"+pos.Side] = pos
+	}
+
+	var decisions []decision.Decision
+	var notes []string
+	for _, g := range at.grid.symbols {
+		data, err := market.Get(g.Symbol)
+		if err != nil {
+			log.Printf("⚠️  获取 %s 行情失败: %v", g.Symbol, err)
+			notes = append(notes, fmt.Sprintf("%s: 获取行情失败，跳过（%v）", g.Symbol, err))
+			continue
+		}
+		ctx.MarketDataMap[g.Symbol] = data
+		price := data.CurrentPrice
+
+		if _, ok := positions[g.Symbol+"_short"]; ok {
+			notes = append(notes, fmt.Sprintf("%s: 存在空仓，网格只做多，跳过", g.Symbol))
+			continue
+		}
+
+		held := 0
+		pos, holding := positions[g.Symbol+"_long"]
+		if holding {
+			held = int(math.Round(pos.Quantity * pos.EntryPrice / g.SizePerLevelUSD))
+			if held < 1 {
+				held = 1 // 不足一格的残余持仓按一格计
+			}
+		}
+		target := g.targetLevels(price)
+		if ctx.MaxPositionSizeUSD > 0 {
+			// 降风险阶梯缩减了单仓位上限时少持几格
+			if maxLevels := int(ctx.MaxPositionSizeUSD / g.SizePerLevelUSD); target > maxLevels {
+				target = maxLevels
+			}
+		}
+		note := fmt.Sprintf("%s: 价格 %.4f，区间 %.4f-%.4f 共 %d 格（每格 %.4f），目标持有 %d 格，当前 %d 格",
+			g.Symbol, price, g.Lower, g.Upper, g.Levels, g.step(), target, held)
+		notes = append(notes, note)
+
+		d := decision.Decision{
+			Symbol:     g.Symbol,
+			Leverage:   g.Leverage,
+			StopLoss:   g.stopLoss(),
+			TakeProfit: g.takeProfit(),
+			Confidence: 100,
+			Reasoning:  "网格 " + note,
+		}
+		switch {
+		case target > held && held == 0:
+			d.Action = "open_long"
+			d.PositionSizeUSD = float64(target) * g.SizePerLevelUSD
+			d.RiskUSD = d.PositionSizeUSD * (price - d.StopLoss) / price
+		case target > held:
+			d.Action = "add_to_position"
+			d.Side = "long"
+			d.PositionSizeUSD = float64(target-held) * g.SizePerLevelUSD
+		case target < held && target == 0:
+			d.Action = "close_long"
+		case target < held:
+			d.Action = "partial_close"
+			d.Side = "long"
+			d.ClosePercent = float64(held-target) / float64(held) * 100
+		default:
+			continue
+		}
+		decisions = append(decisions, d)
+	}
+
+	ctx.MarketDataTime = time.Now()
+	return &decision.FullDecision{
+		CoTTrace:  "网格策略（不调用AI）\n" + strings.Join(notes, "\n"),
+		Decisions: decisions,
+		Timestamp: time.Now(),
+	}, nil
+}
@@ -0,0 +1,117 @@
+package trader
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newGridTrader 创建币安上的网格trader：ETH 2500-3500 共10格（每格100），每格200 USDT
+func newGridTrader(t *testing.T, ex *mockExchange, ai *mockAI) *AutoTrader {
+	t.Helper()
+	at, err := NewAutoTrader(AutoTraderConfig{
+		ID:                       "it_grid",
+		Name:                     "integration_grid",
+		AIModel:                  "custom",
+		Exchange:                 "binance",
+		BinanceAPIKey:            "test-key",
+		BinanceSecretKey:         "test-secret",
+		CustomAPIURL:             ai.URL(),
+		CustomAPIKey:             "test-key",
+		CustomModelName:          "mock",
+		ScanInterval:             time.Minute,
+		InitialBalance:           10000,
+		MaxMarginUsagePct:        80,
+		SafetyBufferPct:          5,
+		CheckAvailableBeforeOpen: true,
+		Strategy:                 StrategyGrid,
+		Grid: []GridSymbol{{
+			Symbol:          "ETHUSDT",
+			Lower:           2500,
+			Upper:           3500,
+			Levels:          10,
+			SizePerLevelUSD: 200,
+			Leverage:        2,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("创建AutoTrader失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join("decision_logs", at.id), 0755); err != nil {
+		t.Fatal(err)
+	}
+	pointAtMock(t, at.trader, ex)
+	return at
+}
+
+func TestGridTargetLevels(t *testing.T) {
+	g := GridSymbol{Lower: 2500, Upper: 3500, Levels: 10}
+	for _, tc := range []struct {
+		price float64
+		want  int
+	}{
+		{3600, 0}, {3500, 0}, {3450, 0}, {3400, 1}, {3000, 5}, {2999, 5}, {2500, 10}, {2000, 10},
+	} {
+		if got := g.targetLevels(tc.price); got != tc.want {
+			t.Errorf("targetLevels(%v) = %d, want %d", tc.price, got, tc.want)
+		}
+	}
+}
+
+func TestIntegrationGridBuysDipsAndSellsRallies(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newGridTrader(t, ex, ai)
+	longSize := func() float64 { return ex.BinancePosition("ETHUSDT", "LONG").size }
+
+	// 3000 位于上沿以下5格：一次买入5格（1000 USDT），止损挂在下沿以下5%
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+	if size := longSize(); math.Abs(size-1000.0/3000) > 0.002 {
+		t.Fatalf("应买入约 0.333 ETH，实际 %v", size)
+	}
+	stops := ex.Triggers("binance", "open")
+	if len(stops) != 2 {
+		t.Fatalf("应挂止损止盈两个条件单，实际 %d", len(stops))
+	}
+	for _, s := range stops {
+		if s.kind == "stop_loss" && s.price != 2375 {
+			t.Fatalf("止损价应为 2375，实际 %v", s.price)
+		}
+	}
+
+	// 价格不变时不交易
+	if record = runCycle(t, at); len(record.Decisions) != 0 {
+		t.Fatalf("价格未越过网格线不应交易: %+v", record.Decisions)
+	}
+
+	// 跌到 2780：再买2格
+	ex.SetPrice("ETHUSDT", 2780)
+	requireActionSuccess(t, runCycle(t, at), "add_to_position")
+	if size := longSize(); math.Abs(size-(1000.0/3000+400.0/2780)) > 0.003 {
+		t.Fatalf("加仓后应持有约 0.477 ETH，实际 %v", size)
+	}
+
+	// 涨到 3150：目标3格，卖出7格中的4格
+	before := longSize()
+	ex.SetPrice("ETHUSDT", 3150)
+	requireActionSuccess(t, runCycle(t, at), "partial_close")
+	if size := longSize(); math.Abs(size-before*3/7) > 0.003 {
+		t.Fatalf("应剩余约 3/7 持仓（%.4f），实际 %v", before*3/7, size)
+	}
+
+	// 突破上沿（未到上沿再上一格的止盈价3600）：全部卖出
+	ex.SetPrice("ETHUSDT", 3550)
+	requireActionSuccess(t, runCycle(t, at), "close_long")
+	if size := longSize(); size != 0 {
+		t.Fatalf("突破上沿应清仓，剩余 %v", size)
+	}
+
+	if n := len(ai.Prompts()); n != 0 {
+		t.Fatalf("网格策略不应调用AI，实际调用 %d 次", n)
+	}
+	if model := at.GetAIModel(); model != StrategyGrid {
+		t.Fatalf("排行榜上应显示策略名 grid，实际 %q", model)
+	}
+}
@@ -0,0 +1,42 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntegrationHeartbeatStopsWhileCycleStuck(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	if !at.LastHeartbeat().IsZero() {
+		t.Fatal("启动前不应有心跳")
+	}
+
+	// AI请求挂起，模拟卡住的周期
+	release := make(chan struct{})
+	ai.OnRequest(func() { <-release })
+	ai.Enqueue(t, "观望。")
+
+	go at.Run()
+	defer at.Stop()
+
+	var started time.Time
+	for deadline := time.Now().Add(5 * time.Second); started.IsZero(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Run 启动后应有心跳")
+		}
+		started = at.LastHeartbeat()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if hb := at.LastHeartbeat(); !hb.Equal(started) {
+		t.Fatalf("周期卡住时心跳不应更新: %v -> %v", started, hb)
+	}
+
+	close(release)
+	for deadline := time.Now().Add(5 * time.Second); !at.LastHeartbeat().After(started); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("周期完成后心跳应更新")
+		}
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"nofx/market"
+	"nofx/ratelimit"
 	"strconv"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
@@ -17,6 +20,10 @@ type HyperliquidTrader struct {
 	ctx        context.Context
 	walletAddr string
 	meta       *hyperliquid.Meta // 缓存meta信息（包含精度等）
+	quote      string            // 交易对命名使用的计价币种（结算始终是USDC）
+
+	// 下单类型（Hyperliquid没有原生市价单和FOK，默认激进IOC限价单）
+	orderTypeSelector
 }
 
 // NewHyperliquidTrader 创建Hyperliquid交易器
@@ -59,28 +66,40 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 	// 获取meta信息（包含精度等配置）
 	meta, err := exchange.Info().Meta(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("获取meta信息失败: %w", err)
+		return nil, fmt.Errorf("获取meta信息失败: %w", hyperliquidError(err))
 	}
 
 	return &HyperliquidTrader{
-		exchange:   exchange,
-		ctx:        ctx,
-		walletAddr: walletAddr,
-		meta:       meta,
+		exchange:          exchange,
+		ctx:               ctx,
+		walletAddr:        walletAddr,
+		meta:              meta,
+		quote:             market.DefaultQuote,
+		orderTypeSelector: newOrderTypeSelector(OrderTypeIOC, OrderTypePostOnly),
 	}, nil
 }
 
+// SettlementCurrency Hyperliquid统一以USDC结算
+func (t *HyperliquidTrader) SettlementCurrency() string {
+	return "USDC"
+}
+
 // GetBalance 获取账户余额
 func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	log.Printf("🔄 正在调用Hyperliquid API获取账户余额...")
 
 	// 获取账户状态
+	t.throttle("/info")
 	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
 	if err != nil {
 		log.Printf("❌ Hyperliquid API调用失败: %v", err)
-		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+		return nil, fmt.Errorf("获取账户信息失败: %w", hyperliquidError(err))
 	}
+	return t.parseBalance(accountState), nil
+}
 
+// parseBalance 从账户状态解析余额信息
+func (t *HyperliquidTrader) parseBalance(accountState *hyperliquid.UserState) map[string]interface{} {
 	// 解析余额信息（MarginSummary字段都是string）
 	result := make(map[string]interface{})
 
@@ -118,17 +137,22 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 		result["availableBalance"],
 		totalMarginUsed)
 
-	return result, nil
+	return result
 }
 
 // GetPositions 获取所有持仓
 func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 	// 获取账户状态
+	t.throttle("/info")
 	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
 	if err != nil {
-		return nil, fmt.Errorf("获取持仓失败: %w", err)
+		return nil, fmt.Errorf("获取持仓失败: %w", hyperliquidError(err))
 	}
+	return t.parsePositions(accountState), nil
+}
 
+// parsePositions 从账户状态解析持仓列表
+func (t *HyperliquidTrader) parsePositions(accountState *hyperliquid.UserState) []map[string]interface{} {
 	var result []map[string]interface{}
 
 	// 遍历所有持仓
@@ -144,8 +168,8 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 
 		posMap := make(map[string]interface{})
 
-		// 标准化symbol格式（Hyperliquid使用如"BTC"，我们转换为"BTCUSDT"）
-		symbol := position.Coin + "USDT"
+		// 标准化symbol格式（Hyperliquid使用如"BTC"，我们转换为"BTCUSDT"或"BTCUSDC"）
+		symbol := position.Coin + t.quote
 		posMap["symbol"] = symbol
 
 		// 持仓数量和方向
@@ -184,7 +208,17 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 		result = append(result, posMap)
 	}
 
-	return result, nil
+	return result
+}
+
+// GetAccountSnapshot 余额和持仓来自同一次账户状态查询
+func (t *HyperliquidTrader) GetAccountSnapshot() (map[string]interface{}, []map[string]interface{}, error) {
+	t.throttle("/info")
+	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取账户信息失败: %w", hyperliquidError(err))
+	}
+	return t.parseBalance(accountState), t.parsePositions(accountState), nil
 }
 
 // SetLeverage 设置杠杆
@@ -192,9 +226,26 @@ func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 	// Hyperliquid symbol格式（去掉USDT后缀）
 	coin := convertSymbolToHyperliquid(symbol)
 
+	// 已有持仓且已是逐仓目标杠杆时跳过（无持仓时账户状态中没有杠杆信息，直接设置）
+	t.throttle("/info")
+	if state, err := t.exchange.Info().UserState(t.ctx, t.walletAddr); err == nil {
+		for _, assetPos := range state.AssetPositions {
+			lev := assetPos.Position.Leverage
+			if assetPos.Position.Coin == coin && lev.Type == "isolated" && lev.Value == leverage {
+				log.Printf("  ✓ %s 杠杆已是 %dx，无需切换", symbol, leverage)
+				return nil
+			}
+		}
+	}
+
 	// 调用UpdateLeverage (leverage int, name string, isCross bool)
+	t.throttle("/exchange")
 	_, err := t.exchange.UpdateLeverage(t.ctx, leverage, coin, false) // false = 逐仓模式
 	if err != nil {
+		if err = hyperliquidError(err); isNotModified(err) {
+			log.Printf("  ✓ %s 杠杆已是 %dx", symbol, leverage)
+			return nil
+		}
 		return fmt.Errorf("设置杠杆失败: %w", err)
 	}
 
@@ -203,7 +254,12 @@ func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 }
 
 // OpenLong 开多仓
-func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -228,8 +284,9 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
 
 	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 1.01)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*1.01, aggressivePrice)
+	limitPrice := limitPriceFor(price, true, orderType)
+	aggressivePrice := t.roundPriceToSigfigs(limitPrice)
+	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", limitPrice, aggressivePrice)
 
 	// 创建市价买入订单（使用IOC limit order with aggressive price）
 	order := hyperliquid.CreateOrderRequest{
@@ -239,15 +296,16 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 		Price: aggressivePrice, // 使用处理后的价格
 		OrderType: hyperliquid.OrderType{
 			Limit: &hyperliquid.LimitOrderType{
-				Tif: hyperliquid.TifIoc, // Immediate or Cancel (类似市价单)
+				Tif: t.tif(orderType), // 默认IOC（类似市价单），post_only 使用Alo
 			},
 		},
 		ReduceOnly: false,
 	}
 
+	t.throttle("/exchange")
 	_, err = t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
-		return nil, fmt.Errorf("开多仓失败: %w", err)
+		return nil, fmt.Errorf("开多仓失败: %w", hyperliquidError(err))
 	}
 
 	log.Printf("✓ 开多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
@@ -256,12 +314,18 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 	result["orderId"] = 0 // Hyperliquid没有返回order ID
 	result["symbol"] = symbol
 	result["status"] = "FILLED"
+	result["price"] = aggressivePrice // 提交的限价（成交均价未返回）
 
 	return result, nil
 }
 
 // OpenShort 开空仓
-func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -286,8 +350,9 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
 
 	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 0.99)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*0.99, aggressivePrice)
+	limitPrice := limitPriceFor(price, false, orderType)
+	aggressivePrice := t.roundPriceToSigfigs(limitPrice)
+	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", limitPrice, aggressivePrice)
 
 	// 创建市价卖出订单
 	order := hyperliquid.CreateOrderRequest{
@@ -297,15 +362,16 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 		Price: aggressivePrice, // 使用处理后的价格
 		OrderType: hyperliquid.OrderType{
 			Limit: &hyperliquid.LimitOrderType{
-				Tif: hyperliquid.TifIoc,
+				Tif: t.tif(orderType),
 			},
 		},
 		ReduceOnly: false,
 	}
 
+	t.throttle("/exchange")
 	_, err = t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
-		return nil, fmt.Errorf("开空仓失败: %w", err)
+		return nil, fmt.Errorf("开空仓失败: %w", hyperliquidError(err))
 	}
 
 	log.Printf("✓ 开空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
@@ -314,12 +380,18 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 	result["orderId"] = 0
 	result["symbol"] = symbol
 	result["status"] = "FILLED"
+	result["price"] = aggressivePrice // 提交的限价（成交均价未返回）
 
 	return result, nil
 }
 
 // CloseLong 平多仓
-func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -353,8 +425,9 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
 
 	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 0.99)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*0.99, aggressivePrice)
+	limitPrice := limitPriceFor(price, false, orderType)
+	aggressivePrice := t.roundPriceToSigfigs(limitPrice)
+	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", limitPrice, aggressivePrice)
 
 	// 创建平仓订单（卖出 + ReduceOnly）
 	order := hyperliquid.CreateOrderRequest{
@@ -364,15 +437,16 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		Price: aggressivePrice, // 使用处理后的价格
 		OrderType: hyperliquid.OrderType{
 			Limit: &hyperliquid.LimitOrderType{
-				Tif: hyperliquid.TifIoc,
+				Tif: t.tif(orderType),
 			},
 		},
 		ReduceOnly: true, // 只平仓，不开新仓
 	}
 
+	t.throttle("/exchange")
 	_, err = t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
-		return nil, fmt.Errorf("平多仓失败: %w", err)
+		return nil, fmt.Errorf("平多仓失败: %w", hyperliquidError(err))
 	}
 
 	log.Printf("✓ 平多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
@@ -386,12 +460,18 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 	result["orderId"] = 0
 	result["symbol"] = symbol
 	result["status"] = "FILLED"
+	result["price"] = aggressivePrice // 提交的限价（成交均价未返回）
 
 	return result, nil
 }
 
 // CloseShort 平空仓
-func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error) {
+	orderType, err := t.resolveOrderType(orderType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -425,8 +505,9 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
 
 	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 1.01)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*1.01, aggressivePrice)
+	limitPrice := limitPriceFor(price, true, orderType)
+	aggressivePrice := t.roundPriceToSigfigs(limitPrice)
+	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", limitPrice, aggressivePrice)
 
 	// 创建平仓订单（买入 + ReduceOnly）
 	order := hyperliquid.CreateOrderRequest{
@@ -436,15 +517,16 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		Price: aggressivePrice, // 使用处理后的价格
 		OrderType: hyperliquid.OrderType{
 			Limit: &hyperliquid.LimitOrderType{
-				Tif: hyperliquid.TifIoc,
+				Tif: t.tif(orderType),
 			},
 		},
 		ReduceOnly: true,
 	}
 
+	t.throttle("/exchange")
 	_, err = t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
-		return nil, fmt.Errorf("平空仓失败: %w", err)
+		return nil, fmt.Errorf("平空仓失败: %w", hyperliquidError(err))
 	}
 
 	log.Printf("✓ 平空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
@@ -458,6 +540,7 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 	result["orderId"] = 0
 	result["symbol"] = symbol
 	result["status"] = "FILLED"
+	result["price"] = aggressivePrice // 提交的限价（成交均价未返回）
 
 	return result, nil
 }
@@ -467,14 +550,16 @@ func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
 	coin := convertSymbolToHyperliquid(symbol)
 
 	// 获取所有挂单
+	t.throttle("/info")
 	openOrders, err := t.exchange.Info().OpenOrders(t.ctx, t.walletAddr)
 	if err != nil {
-		return fmt.Errorf("获取挂单失败: %w", err)
+		return fmt.Errorf("获取挂单失败: %w", hyperliquidError(err))
 	}
 
 	// 取消该币种的所有挂单
 	for _, order := range openOrders {
 		if order.Coin == coin {
+			t.throttle("/exchange")
 			_, err := t.exchange.Cancel(t.ctx, coin, order.Oid)
 			if err != nil {
 				log.Printf("  ⚠ 取消订单失败 (oid=%d): %v", order.Oid, err)
@@ -491,9 +576,10 @@ func (t *HyperliquidTrader) GetMarketPrice(symbol string) (float64, error) {
 	coin := convertSymbolToHyperliquid(symbol)
 
 	// 获取所有市场价格
+	t.throttle("/info")
 	allMids, err := t.exchange.Info().AllMids(t.ctx)
 	if err != nil {
-		return 0, fmt.Errorf("获取价格失败: %w", err)
+		return 0, fmt.Errorf("获取价格失败: %w", hyperliquidError(err))
 	}
 
 	// 查找对应币种的价格（allMids是map[string]string）
@@ -536,9 +622,10 @@ func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quan
 		ReduceOnly: true,
 	}
 
+	t.throttle("/exchange")
 	_, err := t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
-		return fmt.Errorf("设置止损失败: %w", err)
+		return fmt.Errorf("设置止损失败: %w", hyperliquidError(err))
 	}
 
 	log.Printf("  止损价设置: %.4f", roundedStopPrice)
@@ -573,9 +660,10 @@ func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, qu
 		ReduceOnly: true,
 	}
 
+	t.throttle("/exchange")
 	_, err := t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
-		return fmt.Errorf("设置止盈失败: %w", err)
+		return fmt.Errorf("设置止盈失败: %w", hyperliquidError(err))
 	}
 
 	log.Printf("  止盈价设置: %.4f", roundedTakeProfitPrice)
@@ -662,12 +750,39 @@ func (t *HyperliquidTrader) roundPriceToSigfigs(price float64) float64 {
 	return rounded
 }
 
+// RoundPrice 将价格取整到Hyperliquid要求的5位有效数字
+func (t *HyperliquidTrader) RoundPrice(symbol string, price float64) (float64, error) {
+	return t.roundPriceToSigfigs(price), nil
+}
+
+// tif 下单类型对应的Hyperliquid有效方式
+func (t *HyperliquidTrader) tif(orderType OrderType) hyperliquid.Tif {
+	if orderType == OrderTypePostOnly {
+		return hyperliquid.TifAlo
+	}
+	return hyperliquid.TifIoc
+}
+
+// throttle 向Hyperliquid限频调度器申请预算（go-hyperliquid 不支持注入自定义 http.Client）
+func (t *HyperliquidTrader) throttle(path string) {
+	if err := ratelimit.Get("hyperliquid").WaitRequest(t.ctx, "POST", path); err != nil {
+		log.Printf("⚠️ Hyperliquid限频等待失败: %v", err)
+	}
+}
+
+// SetQuoteAsset 持仓和合约列表中的交易对使用该计价币种
+func (t *HyperliquidTrader) SetQuoteAsset(quote string) {
+	t.quote = quote
+}
+
 // convertSymbolToHyperliquid 将标准symbol转换为Hyperliquid格式
-// 例如: "BTCUSDT" -> "BTC"
+// 例如: "BTCUSDT" -> "BTC"，"BTCUSDC" -> "BTC"
 func convertSymbolToHyperliquid(symbol string) string {
-	// 去掉USDT后缀
-	if len(symbol) > 4 && symbol[len(symbol)-4:] == "USDT" {
-		return symbol[:len(symbol)-4]
+	// 去掉计价币种后缀（保留币种名大小写，如 kPEPE）
+	for _, quote := range market.QuoteAssets {
+		if len(symbol) > len(quote) && strings.HasSuffix(symbol, quote) {
+			return symbol[:len(symbol)-len(quote)]
+		}
 	}
 	return symbol
 }
@@ -679,3 +794,22 @@ func absFloat(x float64) float64 {
 	}
 	return x
 }
+
+// ListInstruments 获取永续合约列表（Hyperliquid只提供是否已下架，不提供下架时间）
+func (t *HyperliquidTrader) ListInstruments() ([]Instrument, error) {
+	t.throttle("/info")
+	meta, err := t.exchange.Info().Meta(t.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取meta信息失败: %w", hyperliquidError(err))
+	}
+
+	instruments := make([]Instrument, 0, len(meta.Universe))
+	for _, asset := range meta.Universe {
+		inst := Instrument{Symbol: asset.Name + t.quote, Status: InstrumentTrading}
+		if asset.IsDelisted {
+			inst.Status = InstrumentDelisted
+		}
+		instruments = append(instruments, inst)
+	}
+	return instruments, nil
+}
@@ -0,0 +1,305 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/pool"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 集成测试：用模拟交易所 + 模拟AI驱动 AutoTrader 跑完整交易周期，不需要真实密钥
+// go test -short 时跳过
+
+// setupIntegration 切换到临时目录（决策日志写在这里），并把行情和币种池指向模拟交易所
+func setupIntegration(t *testing.T) (*mockExchange, *mockAI) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("跳过集成测试（-short）")
+	}
+
+	// 模拟交易所没有冷却期，不必按真实时间等待
+	shortenDelays(t)
+
+	ex := newMockExchange(t) // 需要在切换目录前读取 testdata
+	ai := newMockAI(t)
+	t.Chdir(t.TempDir())
+
+	market.RegisterProvider("binance", market.NewBinanceProviderWithBaseURL(ex.URL()))
+	if err := market.SetDefaultProviderName("binance"); err != nil {
+		t.Fatal(err)
+	}
+	pool.SetUseDefaultCoins(true)
+	pool.SetDefaultCoins([]string{"ETHUSDT"})
+
+	ex.SetPrice("ETHUSDT", 3000)
+	ex.SetPrice("BTCUSDT", 60000)
+	ex.SetPrice("USDCUSDT", 1)
+	return ex, ai
+}
+
+// shortenDelays 把冷却期和执行间隔缩短为0，测试结束后恢复
+func shortenDelays(t *testing.T) {
+	leverage, marginType, postExecution, fillQuery := leverageCooldown, marginTypeCooldown, postExecutionDelay, fillQueryDelay
+	leverageCooldown, marginTypeCooldown, postExecutionDelay, fillQueryDelay = 0, 0, 0, time.Millisecond
+	t.Cleanup(func() {
+		leverageCooldown, marginTypeCooldown, postExecutionDelay, fillQueryDelay = leverage, marginType, postExecution, fillQuery
+	})
+}
+
+// newIntegrationTrader 按交易所创建 AutoTrader，并把交易器的请求地址改到模拟交易所
+func newIntegrationTrader(t *testing.T, ex *mockExchange, ai *mockAI, exchange string) *AutoTrader {
+	t.Helper()
+	// 模拟重启：决策ID精确到秒且周期号从1重新计数，等到下一秒再创建，避免覆盖重启前的记录
+	if records, _ := filepath.Glob(filepath.Join("decision_logs", "it_"+exchange, "decision_*.json")); len(records) > 0 {
+		time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	}
+	at, err := NewAutoTrader(AutoTraderConfig{
+		ID:                       "it_" + exchange,
+		Name:                     "integration_" + exchange,
+		AIModel:                  "custom",
+		Exchange:                 exchange,
+		BinanceAPIKey:            "test-key",
+		BinanceSecretKey:         "test-secret",
+		GateioAPIKey:             "test-key",
+		GateioSecretKey:          "test-secret",
+		CustomAPIURL:             ai.URL(),
+		CustomAPIKey:             "test-key",
+		CustomModelName:          "mock",
+		ScanInterval:             time.Minute,
+		InitialBalance:           10000,
+		BTCETHLeverage:           10,
+		AltcoinLeverage:          5,
+		MaxMarginUsagePct:        80,
+		SafetyBufferPct:          5,
+		CheckAvailableBeforeOpen: true,
+	})
+	if err != nil {
+		t.Fatalf("创建AutoTrader失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join("decision_logs", at.id), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	pointAtMock(t, at.trader, ex)
+	return at
+}
+
+// pointAtMock 把交易器的请求地址改到模拟交易所
+func pointAtMock(t *testing.T, trader Trader, ex *mockExchange) {
+	t.Helper()
+	switch tr := trader.(type) {
+	case *GateioTrader:
+		tr.baseURL = ex.URL() + "/api/v4"
+		tr.balanceCache.SetTTL(0)
+		tr.positionsCache.SetTTL(0)
+	case *FuturesTrader:
+		tr.client.BaseURL = ex.URL()
+		tr.balanceCache.SetTTL(0)
+		tr.positionsCache.SetTTL(0)
+	default:
+		t.Fatalf("不支持的交易器类型: %T", tr)
+	}
+}
+
+// runCycle 执行一个周期并返回该周期的决策记录
+func runCycle(t *testing.T, at *AutoTrader) *logger.DecisionRecord {
+	t.Helper()
+	// 同一秒内的多个周期按文件名排序不可靠（cycle10 排在 cycle9 前），按本周期新增的文件读取
+	before, _ := filepath.Glob(filepath.Join(at.decisionLogger.Dir(), "decision_*.json"))
+	if err := at.runCycle(); err != nil {
+		t.Fatalf("周期 #%d 执行失败: %v", at.callCount, err)
+	}
+	after, _ := filepath.Glob(filepath.Join(at.decisionLogger.Dir(), "decision_*.json"))
+	for _, file := range after {
+		if !slices.Contains(before, file) {
+			record, err := at.decisionLogger.GetRecord(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "decision_"), ".json"))
+			if err != nil {
+				t.Fatalf("读取决策记录失败: %v", err)
+			}
+			return record
+		}
+	}
+	t.Fatalf("周期 #%d 没有写入决策记录", at.callCount)
+	return nil
+}
+
+func requireActionSuccess(t *testing.T, record *logger.DecisionRecord, action string) {
+	t.Helper()
+	for _, a := range record.Decisions {
+		if a.Action == action {
+			if !a.Success {
+				t.Fatalf("%s 执行失败: %s", action, a.Error)
+			}
+			return
+		}
+	}
+	t.Fatalf("决策记录中没有 %s 动作: %+v", action, record.Decisions)
+}
+
+func openLongETH(sizeUSD float64) decision.Decision {
+	return decision.Decision{
+		Symbol:          "ETHUSDT",
+		Action:          "open_long",
+		Leverage:        5,
+		PositionSizeUSD: sizeUSD,
+		StopLoss:        2900,
+		TakeProfit:      3400,
+		Confidence:      80,
+		RiskUSD:         50,
+		Reasoning:       "突破关键阻力位",
+	}
+}
+
+func TestIntegrationGateioStopLossHit(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	// 周期1：开多 0.5 ETH（50张），挂止损止盈
+	ai.Enqueue(t, "ETH 放量突破，开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+
+	pos := ex.GatePosition("ETHUSDT")
+	if pos.size != 50 || pos.entryPrice != 3000 || pos.leverage != 5 {
+		t.Fatalf("开仓后持仓 = %+v，期望 50张 @ 3000，5倍杠杆", pos)
+	}
+	open := ex.Triggers("gateio", "open")
+	if len(open) != 2 {
+		t.Fatalf("条件单数量 = %d，期望止损+止盈共2个", len(open))
+	}
+	for _, tr := range open {
+		if tr.size != 50 || tr.side != "long" {
+			t.Fatalf("条件单 %+v 数量/方向错误", tr)
+		}
+		if (tr.kind == "stop_loss" && (tr.price != 2900 || tr.above)) || (tr.kind == "take_profit" && (tr.price != 3400 || !tr.above)) {
+			t.Fatalf("条件单触发条件错误: %+v", tr)
+		}
+	}
+	if stops := at.positionStops["ETHUSDT_long"]; stops == nil || stops.StopLoss != 2900 || stops.TakeProfit != 3400 {
+		t.Fatalf("本地止损止盈记录 = %+v", stops)
+	}
+
+	// 周期2：价格跌破止损，交易所触发止损平仓，下一周期同步状态
+	ex.SetPrice("ETHUSDT", 2890)
+	if size := ex.GatePosition("ETHUSDT").size; size != 0 {
+		t.Fatalf("止损触发后仍有持仓: %v张", size)
+	}
+	if fired := ex.Triggers("gateio", "finished"); len(fired) != 1 || fired[0].kind != "stop_loss" {
+		t.Fatalf("已触发条件单 = %+v，期望只有止损", fired)
+	}
+
+	record = runCycle(t, at)
+	if len(record.Positions) != 0 {
+		t.Fatalf("止损后决策记录仍有持仓: %+v", record.Positions)
+	}
+	if _, ok := at.positionStops["ETHUSDT_long"]; ok {
+		t.Fatal("止损后未清理本地止损止盈记录")
+	}
+	if _, ok := at.positionFirstSeenTime["ETHUSDT_long"]; ok {
+		t.Fatal("止损后未清理持仓首次出现时间")
+	}
+	// 0.5 ETH × (2890 - 3000) = -55 USDT
+	if loss := ex.Balance() - 10000; loss > -54.99 || loss < -55.01 {
+		t.Fatalf("止损已实现盈亏 = %.2f，期望 -55", loss)
+	}
+}
+
+func TestIntegrationGateioOpenAndClose(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	// 周期2：价格上涨，AI 看到持仓后主动平仓
+	ex.SetPrice("ETHUSDT", 3100)
+	ai.Enqueue(t, "接近阻力位，止盈离场。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "锁定利润"})
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "close_long")
+
+	if len(record.Positions) != 1 || record.Positions[0].Symbol != "ETHUSDT" || record.Positions[0].Side != "long" {
+		t.Fatalf("平仓前持仓快照 = %+v", record.Positions)
+	}
+	prompts := ai.Prompts()
+	if !strings.Contains(prompts[len(prompts)-1], "ETHUSDT") {
+		t.Fatal("AI输入中缺少当前持仓")
+	}
+	if size := ex.GatePosition("ETHUSDT").size; size != 0 {
+		t.Fatalf("平仓后仍有持仓: %v张", size)
+	}
+	if _, ok := at.positionStops["ETHUSDT_long"]; ok {
+		t.Fatal("平仓后未清理本地止损止盈记录")
+	}
+	// 0.5 ETH × (3100 - 3000) = 50 USDT
+	if profit := ex.Balance() - 10000; profit < 49.99 || profit > 50.01 {
+		t.Fatalf("已实现盈亏 = %.2f，期望 50", profit)
+	}
+}
+
+func TestIntegrationRestartReconciliation(t *testing.T) {
+	ex, ai := setupIntegration(t)
+
+	// 第一个进程开仓后退出
+	first := newIntegrationTrader(t, ex, ai, "gateio")
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, first), "open_long")
+
+	// 重启：新进程没有任何内存状态，应从交易所同步到已有持仓
+	restarted := newIntegrationTrader(t, ex, ai, "gateio")
+	record := runCycle(t, restarted)
+	if len(record.Positions) != 1 || record.Positions[0].Symbol != "ETHUSDT" || record.Positions[0].Side != "long" {
+		t.Fatalf("重启后持仓快照 = %+v", record.Positions)
+	}
+	if record.Positions[0].EntryPrice != 3000 {
+		t.Fatalf("重启后开仓价 = %v，期望 3000", record.Positions[0].EntryPrice)
+	}
+	if _, ok := restarted.positionFirstSeenTime["ETHUSDT_long"]; !ok {
+		t.Fatal("重启后未记录持仓首次出现时间")
+	}
+	prompts := ai.Prompts()
+	if !strings.Contains(prompts[len(prompts)-1], "ETHUSDT") {
+		t.Fatal("重启后AI输入中缺少已有持仓")
+	}
+	// 交易所上的止损止盈仍然有效
+	if open := ex.Triggers("gateio", "open"); len(open) != 2 {
+		t.Fatalf("重启后条件单数量 = %d，期望2", len(open))
+	}
+
+	// 重启后的进程可以正常管理该持仓
+	ai.Enqueue(t, "平仓。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "重启后减少风险"})
+	requireActionSuccess(t, runCycle(t, restarted), "close_long")
+	if size := ex.GatePosition("ETHUSDT").size; size != 0 {
+		t.Fatalf("平仓后仍有持仓: %v张", size)
+	}
+}
+
+func TestIntegrationBinanceOpenAndClose(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "binance")
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	if pos := ex.BinancePosition("ETHUSDT", "LONG"); pos.size != 0.5 || pos.leverage != 5 {
+		t.Fatalf("开仓后持仓 = %+v，期望 0.5 ETH，5倍杠杆", pos)
+	}
+	if open := ex.Triggers("binance", "open"); len(open) != 2 {
+		t.Fatalf("条件单数量 = %d，期望止损+止盈共2个", len(open))
+	}
+
+	ai.Enqueue(t, "平仓。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "趋势减弱"})
+	requireActionSuccess(t, runCycle(t, at), "close_long")
+
+	if qty := ex.BinancePosition("ETHUSDT", "LONG").size; qty != 0 {
+		t.Fatalf("平仓后仍有持仓: %v", qty)
+	}
+	if open := ex.Triggers("binance", "open"); len(open) != 0 {
+		t.Fatalf("平仓后仍有 %d 个条件单未撤销", len(open))
+	}
+}
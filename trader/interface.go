@@ -9,17 +9,17 @@ type Trader interface {
 	// GetPositions 获取所有持仓
 	GetPositions() ([]map[string]interface{}, error)
 
-	// OpenLong 开多仓
-	OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error)
+	// OpenLong 开多仓（orderType 为空表示交易所默认下单类型，不支持时返回错误）
+	OpenLong(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error)
 
 	// OpenShort 开空仓
-	OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error)
+	OpenShort(symbol string, quantity float64, leverage int, orderType OrderType) (map[string]interface{}, error)
 
 	// CloseLong 平多仓（quantity=0表示全部平仓）
-	CloseLong(symbol string, quantity float64) (map[string]interface{}, error)
+	CloseLong(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error)
 
 	// CloseShort 平空仓（quantity=0表示全部平仓）
-	CloseShort(symbol string, quantity float64) (map[string]interface{}, error)
+	CloseShort(symbol string, quantity float64, orderType OrderType) (map[string]interface{}, error)
 
 	// SetLeverage 设置杠杆
 	SetLeverage(symbol string, leverage int) error
@@ -38,4 +38,7 @@ type Trader interface {
 
 	// FormatQuantity 格式化数量到正确的精度
 	FormatQuantity(symbol string, quantity float64) (string, error)
+
+	// SupportedOrderTypes 获取交易所支持的下单类型（第一个为默认类型）
+	SupportedOrderTypes() []OrderType
 }
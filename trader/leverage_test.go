@@ -0,0 +1,42 @@
+package trader
+
+import (
+	"nofx/decision"
+	"testing"
+)
+
+func TestIntegrationLeverageSetOnlyWhenChanged(t *testing.T) {
+	for _, exchange := range []string{"gateio", "binance"} {
+		t.Run(exchange, func(t *testing.T) {
+			ex, ai := setupIntegration(t)
+			at := newIntegrationTrader(t, ex, ai, exchange)
+
+			ai.Enqueue(t, "开多。", openLongETH(1500))
+			requireActionSuccess(t, runCycle(t, at), "open_long")
+			if calls := ex.LeverageCalls(exchange); calls != 1 {
+				t.Fatalf("首次开仓应设置一次杠杆，实际 %d 次", calls)
+			}
+
+			ai.Enqueue(t, "平仓。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "离场"})
+			requireActionSuccess(t, runCycle(t, at), "close_long")
+
+			// 杠杆未变：无持仓时同样从交易所查到当前杠杆，不再调用设置接口
+			ai.Enqueue(t, "再次开多。", openLongETH(1500))
+			requireActionSuccess(t, runCycle(t, at), "open_long")
+			if calls := ex.LeverageCalls(exchange); calls != 1 {
+				t.Errorf("杠杆未变时不应再次设置，实际 %d 次", calls)
+			}
+
+			// 杠杆变化时重新设置
+			ai.Enqueue(t, "平仓。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "离场"})
+			requireActionSuccess(t, runCycle(t, at), "close_long")
+			d := openLongETH(1500)
+			d.Leverage = 3
+			ai.Enqueue(t, "降杠杆开多。", d)
+			requireActionSuccess(t, runCycle(t, at), "open_long")
+			if calls := ex.LeverageCalls(exchange); calls != 2 {
+				t.Errorf("杠杆变化时应重新设置，实际共 %d 次", calls)
+			}
+		})
+	}
+}
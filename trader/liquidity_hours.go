@@ -0,0 +1,55 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// 非流动时段仓位缩减
+// 启用分时段流动性画像后，山寨币的行情数据带有按UTC小时统计的平均成交额和估算价差。
+// 当前小时属于该币种历史上的非流动时段时，prompt中会提示AI，执行前开仓/加仓的仓位再乘以 sizeFactor
+// （不低于最小仓位），止损风险金额同比例缩小。
+
+// EnableLiquidityHours 启用非流动时段仓位缩减
+func (at *AutoTrader) EnableLiquidityHours(sizeFactor float64) {
+	at.illiquidSizeFactor = sizeFactor
+}
+
+// reduceIlliquidEntries 当前处于非流动时段的币种，开仓/加仓仓位按系数缩小
+func (at *AutoTrader) reduceIlliquidEntries(ctx *decision.Context, decisions []decision.Decision, record *logger.DecisionRecord) []decision.Decision {
+	if at.illiquidSizeFactor <= 0 || at.illiquidSizeFactor >= 1 {
+		return decisions
+	}
+	for i := range decisions {
+		d := &decisions[i]
+		if d.Action != "open_long" && d.Action != "open_short" && d.Action != "add_to_position" {
+			continue
+		}
+		data, ok := ctx.MarketDataMap[d.Symbol]
+		if !ok || data.Liquidity == nil || !data.Liquidity.IlliquidNow() || d.PositionSizeUSD <= 0 {
+			continue
+		}
+
+		size := d.PositionSizeUSD * at.illiquidSizeFactor
+		if size < ctx.MinPositionSizeUSD {
+			size = ctx.MinPositionSizeUSD
+		}
+		if size >= d.PositionSizeUSD {
+			continue
+		}
+		hour := data.Liquidity.Current()
+		volumePct := 0.0
+		if data.Liquidity.MedianVolume > 0 {
+			volumePct = hour.AvgVolume / data.Liquidity.MedianVolume * 100
+		}
+		log.Printf("  🌙 非流动时段: %s %s 仓位 %.2f → %.2f USDT（UTC %02d时成交额为中位数的%.0f%%）",
+			d.Symbol, d.Action, d.PositionSizeUSD, size, hour.Hour, volumePct)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🌙 非流动时段: %s %s 仓位 %.2f → %.2f USDT（UTC %02d时平均成交额为中位数的%.0f%%，估算价差 %.3f%%）",
+			d.Symbol, d.Action, d.PositionSizeUSD, size, hour.Hour, volumePct, hour.SpreadPct))
+		d.RiskUSD *= size / d.PositionSizeUSD
+		d.PositionSizeUSD = size
+	}
+	return decisions
+}
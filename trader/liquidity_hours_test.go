@@ -0,0 +1,59 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+	"testing"
+)
+
+func TestReduceIlliquidEntries(t *testing.T) {
+	at := &AutoTrader{}
+	at.EnableLiquidityHours(0.5)
+
+	// SOL 当前小时成交额只有中位数的20%，DOGE 当前小时正常
+	illiquid := &market.LiquidityProfile{MedianVolume: 1e6, MedianSpreadPct: 0.05, LookbackDays: 14, CurrentHour: 3}
+	illiquid.Hours[3] = market.HourLiquidity{Hour: 3, AvgVolume: 2e5, SpreadPct: 0.12, Samples: 14, Illiquid: true}
+	normal := &market.LiquidityProfile{MedianVolume: 1e6, LookbackDays: 14, CurrentHour: 14}
+	normal.Hours[14] = market.HourLiquidity{Hour: 14, AvgVolume: 1.4e6, Samples: 14}
+	ctx := &decision.Context{
+		MinPositionSizeUSD: 300,
+		MarketDataMap: map[string]*market.Data{
+			"SOLUSDT":  {Symbol: "SOLUSDT", Liquidity: illiquid},
+			"DOGEUSDT": {Symbol: "DOGEUSDT", Liquidity: normal},
+		},
+	}
+	decisions := []decision.Decision{
+		{Symbol: "SOLUSDT", Action: "open_long", PositionSizeUSD: 1000, RiskUSD: 40},
+		{Symbol: "SOLUSDT", Action: "add_to_position", PositionSizeUSD: 400},
+		{Symbol: "SOLUSDT", Action: "close_short"},
+		{Symbol: "DOGEUSDT", Action: "open_short", PositionSizeUSD: 1000},
+	}
+	record := &logger.DecisionRecord{}
+	decisions = at.reduceIlliquidEntries(ctx, decisions, record)
+
+	if got := decisions[0].PositionSizeUSD; got != 500 {
+		t.Fatalf("非流动时段开仓应缩减一半，实际 %.2f", got)
+	}
+	if math.Abs(decisions[0].RiskUSD-20) > 1e-9 {
+		t.Fatalf("风险金额应同比例缩小，实际 %.2f", decisions[0].RiskUSD)
+	}
+	if got := decisions[1].PositionSizeUSD; got != 300 {
+		t.Fatalf("缩减后的加仓不应低于最小仓位，实际 %.2f", got)
+	}
+	if got := decisions[3].PositionSizeUSD; got != 1000 {
+		t.Fatalf("流动性正常的币种不应缩减，实际 %.2f", got)
+	}
+	requireExecutionLog(t, record.ExecutionLog, "非流动时段: SOLUSDT open_long 仓位 1000.00 → 500.00")
+	if len(record.ExecutionLog) != 2 {
+		t.Fatalf("只有SOL的开仓和加仓应被缩减: %v", record.ExecutionLog)
+	}
+
+	// 非流动时段在prompt中给出警告
+	prompt := market.Format(&market.Data{Symbol: "SOLUSDT", CurrentPrice: 150, Liquidity: illiquid})
+	if !strings.Contains(prompt, "this hour (03:00) averages 20% of the median hour's volume") || !strings.Contains(prompt, "historically illiquid hours") {
+		t.Fatalf("prompt中应有非流动时段警告:\n%s", prompt)
+	}
+}
@@ -0,0 +1,157 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/logger"
+	"nofx/notify"
+	"sync"
+	"time"
+)
+
+// 交易所上下架
+// 下架的永续合约在截止时间会被交易所强制结算，结算价往往很差。ListingWatcher 定期拉取各交易所的合约列表，
+// 把即将下架的币种通过 SetDelistings 告知trader：这些币种从候选池中移除，
+// 已有持仓在截止时间前（默认提前60分钟）由交易周期主动平仓。
+
+// InstrumentStatus 合约状态
+type InstrumentStatus string
+
+const (
+	InstrumentTrading   InstrumentStatus = "trading"   // 正常交易
+	InstrumentPending   InstrumentStatus = "pending"   // 已公布，尚未开放交易
+	InstrumentDelisting InstrumentStatus = "delisting" // 已公布下架，仍可交易（DelistAt 为截止时间，未知时为零值）
+	InstrumentDelisted  InstrumentStatus = "delisted"  // 已下架/结算中
+)
+
+// Instrument 永续合约信息
+type Instrument struct {
+	Symbol   string           `json:"symbol"` // XXXUSDT 格式
+	Status   InstrumentStatus `json:"status"`
+	ListedAt time.Time        `json:"listed_at,omitempty"` // 上线时间（未知时为零值）
+	DelistAt time.Time        `json:"delist_at,omitempty"` // 下架时间（未安排或未知时为零值）
+}
+
+// InstrumentLister 可以查询合约列表的交易器（可选接口）
+type InstrumentLister interface {
+	// ListInstruments 获取交易所的USDT永续合约列表
+	ListInstruments() ([]Instrument, error)
+}
+
+// permanentDeliveryCutoff 永续合约的交割时间是一个很远的占位值，近一年内的交割时间才视为下架计划
+const permanentDeliveryCutoff = 365 * 24 * time.Hour
+
+// delistingState trader当前已知的下架计划
+type delistingState struct {
+	deadlines   map[string]time.Time // symbol -> 下架时间（零值表示时间未知，尽快平仓）
+	closeBefore time.Duration        // 提前平仓时长
+	mu          sync.RWMutex
+}
+
+// ListInstruments 获取交易所合约列表（交易所不支持时返回错误）
+func (at *AutoTrader) ListInstruments() ([]Instrument, error) {
+	lister, ok := at.trader.(InstrumentLister)
+	if !ok {
+		return nil, fmt.Errorf("%s 不支持查询合约列表", at.exchange)
+	}
+	return lister.ListInstruments()
+}
+
+// GetExchange 获取交易平台名称
+func (at *AutoTrader) GetExchange() string {
+	return at.exchange
+}
+
+// SetDelistings 更新即将下架的币种（替换之前的列表），这些币种不再作为候选币种，
+// 持仓会在下架前 closeBefore 时长内被主动平仓
+func (at *AutoTrader) SetDelistings(deadlines map[string]time.Time, closeBefore time.Duration) {
+	symbols := make([]string, 0, len(deadlines))
+	for symbol := range deadlines {
+		symbols = append(symbols, symbol)
+	}
+	at.delistingFilter.Update(symbols, nil)
+
+	at.delistings.mu.Lock()
+	at.delistings.deadlines = deadlines
+	at.delistings.closeBefore = closeBefore
+	at.delistings.mu.Unlock()
+}
+
+// GetDelistings 当前已知的下架计划
+func (at *AutoTrader) GetDelistings() map[string]time.Time {
+	at.delistings.mu.RLock()
+	defer at.delistings.mu.RUnlock()
+	result := make(map[string]time.Time, len(at.delistings.deadlines))
+	for symbol, deadline := range at.delistings.deadlines {
+		result[symbol] = deadline
+	}
+	return result
+}
+
+// closeDelistingPositions 在下架截止前平掉受影响的持仓，结果写入执行日志
+func (at *AutoTrader) closeDelistingPositions(record *logger.DecisionRecord) {
+	if err := at.checkRetired(); err != nil {
+		return
+	}
+	at.delistings.mu.RLock()
+	deadlines := at.delistings.deadlines
+	closeBefore := at.delistings.closeBefore
+	at.delistings.mu.RUnlock()
+	if len(deadlines) == 0 {
+		return
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️ 检查下架币种持仓失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		deadline, ok := deadlines[symbol]
+		if !ok {
+			continue
+		}
+		if !deadline.IsZero() && deadline.Sub(now) > closeBefore {
+			continue // 还没到提前平仓时间
+		}
+
+		var closeErr error
+		if side == "long" {
+			_, closeErr = at.trader.CloseLong(symbol, 0, "")
+		} else {
+			_, closeErr = at.trader.CloseShort(symbol, 0, "")
+		}
+
+		deadlineText := "时间未知"
+		if !deadline.IsZero() {
+			deadlineText = deadline.Format("2006-01-02 15:04")
+		}
+		if closeErr != nil {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 下架前平仓失败: %v", symbol, side, closeErr))
+			notify.Send(notify.Event{
+				Type:     "listing.force_close_failed",
+				Severity: notify.SeverityCritical,
+				TraderID: at.id,
+				Symbol:   symbol,
+				Title:    fmt.Sprintf("%s 下架前平仓失败", symbol),
+				Message:  fmt.Sprintf("%s 持仓（%s）平仓失败，下架时间 %s，请人工处理: %v", symbol, side, deadlineText, closeErr),
+			})
+			continue
+		}
+
+		delete(at.positionStops, symbol+"_"+side)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 因下架已平仓（下架时间 %s）", symbol, side, deadlineText))
+		notify.Send(notify.Event{
+			Type:     "listing.force_close",
+			Severity: notify.SeverityWarning,
+			TraderID: at.id,
+			Symbol:   symbol,
+			Title:    fmt.Sprintf("%s 即将下架，已平仓", symbol),
+			Message:  fmt.Sprintf("%s 持仓（%s）已在下架前主动平仓，下架时间 %s", symbol, side, deadlineText),
+		})
+	}
+}
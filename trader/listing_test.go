@@ -0,0 +1,37 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBinanceStyleInstrument(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	permanent := int64(4133404800000) // 2100-12-25，正常永续合约的占位交割时间
+	delistAt := now.Add(48 * time.Hour)
+
+	tests := []struct {
+		name         string
+		status       string
+		deliveryDate int64
+		wantStatus   InstrumentStatus
+		wantDelistAt time.Time
+	}{
+		{"正常交易", "TRADING", permanent, InstrumentTrading, time.Time{}},
+		{"计划下架", "TRADING", delistAt.UnixMilli(), InstrumentDelisting, delistAt},
+		{"结算中", "SETTLING", delistAt.UnixMilli(), InstrumentDelisted, delistAt},
+		{"待上线", "PENDING_TRADING", permanent, InstrumentPending, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := binanceStyleInstrument("XYZUSDT", tt.status, now.UnixMilli(), tt.deliveryDate, now)
+			if inst.Status != tt.wantStatus {
+				t.Errorf("状态 = %s, 期望 %s", inst.Status, tt.wantStatus)
+			}
+			if !inst.DelistAt.Equal(tt.wantDelistAt) {
+				t.Errorf("下架时间 = %v, 期望 %v", inst.DelistAt, tt.wantDelistAt)
+			}
+		})
+	}
+}
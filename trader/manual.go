@@ -0,0 +1,89 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// 人工操作（Telegram命令等）
+// 与交易周期互斥执行；暂停沿用风控暂停的状态（stopUntil），重启后同样会恢复。
+
+// Pause 人工暂停交易 d 时长（暂停期间不请求AI、不开仓，未完成的开仓仍会补挂保护单）
+func (at *AutoTrader) Pause(d time.Duration, reason string) {
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+	if reason == "" {
+		reason = "人工暂停"
+	}
+	at.haltTrading(reason, d)
+}
+
+// Resume 解除暂停（人工暂停和风控暂停都会解除）
+func (at *AutoTrader) Resume() bool {
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+	if !time.Now().Before(at.stopUntil) {
+		return false
+	}
+	at.stopUntil = time.Time{}
+	at.saveRiskState()
+	log.Printf("▶️ [%s] 人工解除暂停（原因: %s）", at.name, at.stopReason)
+	return true
+}
+
+// ClosePosition 人工平仓，side为空时按币种推断（同时持有多空仓时必须指定）
+// 返回成交记录，平仓结果写入决策日志
+func (at *AutoTrader) ClosePosition(symbol, side string) (*logger.DecisionAction, error) {
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+
+	symbol = strings.ToUpper(symbol)
+	posSide, quantity, _, err := at.findPosition(symbol, strings.ToLower(side))
+	if err != nil {
+		return nil, err
+	}
+
+	d := decision.Decision{Symbol: symbol, Action: "close_" + posSide, Reasoning: "人工平仓"}
+	actionRecord := logger.DecisionAction{
+		Action:    d.Action,
+		Symbol:    symbol,
+		Quantity:  quantity,
+		Timestamp: time.Now(),
+	}
+	record := &logger.DecisionRecord{
+		ExecutionLog: []string{fmt.Sprintf("👤 人工平仓 %s %s", symbol, posSide)},
+		Success:      true,
+	}
+
+	log.Printf("👤 [%s] 人工平仓 %s %s", at.name, symbol, posSide)
+	var execErr error
+	if posSide == "long" {
+		execErr = at.executeCloseLongWithRecord(&d, &actionRecord)
+	} else {
+		execErr = at.executeCloseShortWithRecord(&d, &actionRecord)
+	}
+	if execErr != nil {
+		actionRecord.Error = execErr.Error()
+		actionRecord.RejectReason = orderRejectReason(execErr)
+		record.Success = false
+		record.ErrorMessage = execErr.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", symbol, d.Action, execErr))
+	} else {
+		actionRecord.Success = true
+		at.publishOrderFilled(&actionRecord)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", symbol, d.Action))
+	}
+	record.Decisions = append(record.Decisions, actionRecord)
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+
+	if execErr != nil {
+		return nil, fmt.Errorf("平仓失败: %w", execErr)
+	}
+	return &actionRecord, nil
+}
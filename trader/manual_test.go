@@ -0,0 +1,50 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegrationManualPauseAndClose(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "binance")
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	// 人工暂停期间不请求AI
+	at.Pause(30*time.Minute, "")
+	record := runCycle(t, at)
+	if record.Success || !strings.Contains(record.ErrorMessage, "人工暂停") {
+		t.Fatalf("暂停期间周期应跳过: %+v", record)
+	}
+	if n := len(ai.Prompts()); n != 1 {
+		t.Fatalf("暂停期间不应请求AI，实际请求 %d 次", n)
+	}
+	if !at.Resume() {
+		t.Fatal("暂停中的trader应能恢复")
+	}
+	if at.Resume() {
+		t.Fatal("未暂停的trader不应再次恢复")
+	}
+
+	if _, err := at.ClosePosition("BTCUSDT", ""); err == nil {
+		t.Fatal("没有持仓的币种平仓应报错")
+	}
+	action, err := at.ClosePosition("ethusdt", "")
+	if err != nil {
+		t.Fatalf("人工平仓失败: %v", err)
+	}
+	if action.Action != "close_long" || action.Quantity <= 0 {
+		t.Fatalf("平仓记录不正确: %+v", action)
+	}
+	if size := ex.BinancePosition("ETHUSDT", "LONG").size; size != 0 {
+		t.Fatalf("平仓后仍有持仓 %v", size)
+	}
+	records, err := at.decisionLogger.GetLatestRecords(1)
+	if err != nil || len(records) == 0 {
+		t.Fatalf("读取决策记录失败: %v", err)
+	}
+	requireExecutionLog(t, records[0].ExecutionLog, "人工平仓")
+}
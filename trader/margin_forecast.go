@@ -0,0 +1,133 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"sort"
+)
+
+// 批量决策的保证金预测
+// 单笔开仓前的检查只看当时的账户，同一周期的多笔开仓会先后占用保证金，后面的订单到下单时才因超限失败。
+// 执行前先把本周期的决策合起来推演：平仓和部分平仓释放的保证金计入可用额度，开仓/加仓按决策杠杆计算占用，
+// 按信心度从高到低分配额度，放不下的缩小仓位，缩到低于最小仓位时放弃（转为观望）。
+
+const (
+	marginForecastSlack = 0.99 // 分配额度时预留的比例，避免成交价和标记价的差异让单笔检查超出上限
+	minForecastSizeUSD  = 10.0 // 缩小后低于该名义价值的仓位直接放弃（交易所最小下单额通常在5-100 USDT）
+)
+
+// forecastMargin 推演本周期决策的保证金占用，按信心度缩小或放弃超出保证金使用率上限的开仓/加仓
+func (at *AutoTrader) forecastMargin(ctx *decision.Context, decisions []decision.Decision, record *logger.DecisionRecord) []decision.Decision {
+	if !at.config.CheckAvailableBeforeOpen || at.config.MaxMarginUsagePct <= 0 || ctx.Account.TotalEquity <= 0 {
+		return decisions
+	}
+
+	positions := make(map[string]decision.PositionInfo)
+	for _, pos := range ctx.Positions {
+		positions[pos.Symbol+"_"+pos.Side] = pos
+	}
+
+	// 本周期平仓释放的保证金
+	released := 0.0
+	var entries []int
+	for i, d := range decisions {
+		switch d.Action {
+		case "close_long":
+			released += positions[d.Symbol+"_long"].MarginUsed
+		case "close_short":
+			released += positions[d.Symbol+"_short"].MarginUsed
+		case "partial_close":
+			if pos, ok := forecastPosition(positions, d); ok {
+				released += pos.MarginUsed * d.ClosePercent / 100
+			}
+		case "open_long", "open_short", "add_to_position":
+			entries = append(entries, i)
+		}
+	}
+	if len(entries) == 0 {
+		return decisions
+	}
+
+	// 额度：保证金使用率上限和可用余额（含安全缓冲）取较小者
+	capRoom := ctx.Account.TotalEquity*at.config.MaxMarginUsagePct/100 - (ctx.Account.MarginUsed - released)
+	availableRoom := (ctx.Account.AvailableBalance + released) / (1 + at.config.SafetyBufferPct/100)
+	room := capRoom
+	if availableRoom < room {
+		room = availableRoom
+	}
+	room *= marginForecastSlack
+
+	// 信心度高的先分配
+	sort.SliceStable(entries, func(a, b int) bool {
+		return decisions[entries[a]].Confidence > decisions[entries[b]].Confidence
+	})
+
+	dropped := make(map[int]bool)
+	totalRequired := 0.0
+	for _, i := range entries {
+		d := &decisions[i]
+		leverage := d.Leverage
+		if leverage <= 0 && d.Action == "add_to_position" {
+			if pos, ok := forecastPosition(positions, *d); ok {
+				leverage = pos.Leverage
+			}
+		}
+		if leverage <= 0 || d.PositionSizeUSD <= 0 {
+			continue // 参数不完整，由执行时报错
+		}
+		required := d.PositionSizeUSD / float64(leverage)
+		totalRequired += required
+		if required <= room {
+			room -= required
+			continue
+		}
+
+		scaledSize := room * float64(leverage)
+		if room <= 0 || scaledSize < ctx.MinPositionSizeUSD || scaledSize < minForecastSizeUSD {
+			dropped[i] = true
+			log.Printf("  📉 保证金预测: %s %s 放弃（需要保证金 %.2f，剩余额度 %.2f）", d.Symbol, d.Action, required, room)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("📉 保证金预测: %s %s 转为观望：需要保证金 %.2f USDT，本周期剩余额度 %.2f USDT（信心度 %d）",
+				d.Symbol, d.Action, required, room, d.Confidence))
+			continue
+		}
+
+		ratio := scaledSize / d.PositionSizeUSD
+		log.Printf("  📉 保证金预测: %s %s 仓位 %.2f → %.2f USDT", d.Symbol, d.Action, d.PositionSizeUSD, scaledSize)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("📉 保证金预测: %s %s 仓位 %.2f → %.2f USDT，保持保证金使用率不超过 %.0f%%",
+			d.Symbol, d.Action, d.PositionSizeUSD, scaledSize, at.config.MaxMarginUsagePct))
+		d.PositionSizeUSD = scaledSize
+		d.RiskUSD *= ratio
+		room = 0
+	}
+
+	if len(dropped) == 0 {
+		return decisions
+	}
+	kept := make([]decision.Decision, 0, len(decisions)-len(dropped))
+	for i, d := range decisions {
+		if !dropped[i] {
+			kept = append(kept, d)
+		}
+	}
+	log.Printf("  📉 保证金预测: 本周期开仓/加仓共需保证金 %.2f USDT，放弃 %d 个", totalRequired, len(dropped))
+	return kept
+}
+
+// forecastPosition 找到决策对应的持仓（未指定方向时该币种只能有一个方向的持仓）
+func forecastPosition(positions map[string]decision.PositionInfo, d decision.Decision) (decision.PositionInfo, bool) {
+	if d.Side != "" {
+		pos, ok := positions[d.Symbol+"_"+d.Side]
+		return pos, ok
+	}
+	long, hasLong := positions[d.Symbol+"_long"]
+	short, hasShort := positions[d.Symbol+"_short"]
+	switch {
+	case hasLong && !hasShort:
+		return long, true
+	case hasShort && !hasLong:
+		return short, true
+	}
+	return decision.PositionInfo{}, false
+}
@@ -0,0 +1,57 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"testing"
+)
+
+func TestIntegrationMarginForecast(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "binance")
+	at.config.MinPositionSizeUSD = 1000
+
+	// 净值10000、上限80%：ETH占用4000保证金，BTC原本需要6000，只能缩小到剩余额度
+	eth := openLongETH(40000)
+	eth.Leverage = 10
+	eth.Confidence = 90
+	eth.RiskUSD = 1000
+	btc := decision.Decision{
+		Symbol:          "BTCUSDT",
+		Action:          "open_long",
+		Leverage:        10,
+		PositionSizeUSD: 60000,
+		StopLoss:        58000,
+		TakeProfit:      68000,
+		Confidence:      70,
+		RiskUSD:         2000,
+		Reasoning:       "跟随趋势",
+	}
+	ai.Enqueue(t, "同时开多ETH和BTC。", btc, eth)
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+	for _, a := range record.Decisions {
+		if !a.Success {
+			t.Fatalf("%s %s 不应在下单时因保证金失败: %s", a.Symbol, a.Action, a.Error)
+		}
+	}
+	requireExecutionLog(t, record.ExecutionLog, "保证金预测: BTCUSDT open_long 仓位 60000.00")
+	if size := ex.BinancePosition("ETHUSDT", "LONG").size; math.Abs(size-40000.0/3000) > 0.01 {
+		t.Fatalf("信心度高的ETH应按原仓位开仓，实际 %v", size)
+	}
+	btcValue := ex.BinancePosition("BTCUSDT", "LONG").size * 60000
+	if btcValue <= 30000 || btcValue >= 40000 {
+		t.Fatalf("BTC仓位应缩小到约39000 USDT，实际 %.0f", btcValue)
+	}
+
+	// 额度用完后，缩小到最小仓位以下的加仓转为观望
+	ai.Enqueue(t, "加仓ETH。", decision.Decision{
+		Symbol: "ETHUSDT", Action: "add_to_position", Side: "long", Leverage: 10, PositionSizeUSD: 20000,
+		StopLoss: 2900, TakeProfit: 3400, Confidence: 80, Reasoning: "回踩加仓",
+	})
+	record = runCycle(t, at)
+	if len(record.Decisions) != 0 {
+		t.Fatalf("超出保证金上限的加仓应转为观望: %+v", record.Decisions)
+	}
+	requireExecutionLog(t, record.ExecutionLog, "转为观望")
+}
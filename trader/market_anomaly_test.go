@@ -0,0 +1,78 @@
+package trader
+
+import (
+	"errors"
+	"nofx/market"
+	"strings"
+	"testing"
+)
+
+// spikeProvider 在模拟交易所行情上制造最新3分钟K线的价格跳变
+type spikeProvider struct {
+	*market.BinanceProvider
+	spike bool
+}
+
+func (p *spikeProvider) GetName() string {
+	return "it_spike"
+}
+
+func (p *spikeProvider) GetKlines(symbol, interval string, limit int) ([]market.Kline, error) {
+	klines, err := p.BinanceProvider.GetKlines(symbol, interval, limit)
+	if err != nil || !p.spike || interval != "3m" || len(klines) == 0 {
+		return klines, err
+	}
+	last := &klines[len(klines)-1]
+	last.Close *= 1.2
+	last.High = last.Close
+	return klines, nil
+}
+
+func TestIntegrationMarketDataCircuitBreaker(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	provider := &spikeProvider{BinanceProvider: market.NewBinanceProviderWithBaseURL(ex.URL()), spike: true}
+	market.RegisterProvider(provider.GetName(), provider)
+	if err := market.SetDefaultProviderName(provider.GetName()); err != nil {
+		t.Fatal(err)
+	}
+	market.SetAnomalyConfig(market.AnomalyConfig{Enabled: true, MaxBarMovePct: 5, MaxZeroVolumeBars: 3, MaxStaleBars: 3, RecoveryFetches: 2})
+	t.Cleanup(func() { market.SetAnomalyConfig(market.AnomalyConfig{}) })
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	// 价格跳变：ETH 不写入AI提示，开仓被拒绝
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	if len(record.Decisions) != 1 || record.Decisions[0].Success {
+		t.Fatalf("熔断期间开仓应失败: %+v", record.Decisions)
+	}
+	if prompts := ai.Prompts(); strings.Contains(prompts[len(prompts)-1], "latest ETHUSDT open interest") {
+		t.Error("异常币种不应写入AI提示")
+	}
+	breakers := market.Breakers()
+	if len(breakers) == 0 || !strings.Contains(breakers[0].Reason, "price moved") {
+		t.Fatalf("熔断状态 = %+v", breakers)
+	}
+	if ex.GatePosition("ETHUSDT").size != 0 {
+		t.Fatal("熔断期间不应开仓")
+	}
+
+	// 数据恢复正常：连续两次正常后解除熔断
+	provider.spike = false
+	if _, err := market.Get("ETHUSDT"); !errors.Is(err, market.ErrUnreliableData) {
+		t.Fatalf("第一次正常数据仍应处于熔断: %v", err)
+	}
+	if _, err := market.Get("ETHUSDT"); err != nil {
+		t.Fatalf("连续两次正常后应解除熔断: %v", err)
+	}
+	for _, b := range market.Breakers() {
+		if b.Symbol == "ETHUSDT" {
+			t.Fatalf("ETHUSDT 熔断未解除: %+v", b)
+		}
+	}
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	if prompts := ai.Prompts(); !strings.Contains(prompts[len(prompts)-1], "latest ETHUSDT open interest") {
+		t.Error("恢复后应写入AI提示")
+	}
+}
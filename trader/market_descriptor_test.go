@@ -0,0 +1,64 @@
+package trader
+
+import (
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationDescriptorMarketProvider(t *testing.T) {
+	example, err := os.ReadFile(filepath.Join("..", "market_descriptors", "binance_futures.example.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex, ai := setupIntegration(t)
+
+	// 示例描述文件指向模拟交易所
+	path := filepath.Join(t.TempDir(), "binance.yaml")
+	descriptor := strings.Replace(string(example), "https://fapi.binance.com", ex.URL(), 1)
+	if err := os.WriteFile(path, []byte(descriptor), 0644); err != nil {
+		t.Fatal(err)
+	}
+	name, err := market.RegisterDescriptorProvider(path)
+	if err != nil {
+		t.Fatalf("加载描述文件失败: %v", err)
+	}
+	provider, err := market.GetProvider(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 与内置的币安行情源返回一致
+	builtin := market.NewBinanceProviderWithBaseURL(ex.URL())
+	want, err := builtin.GetKlines("ETHUSDT", "3m", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := provider.GetKlines("ETHUSDT", "3m", 20)
+	if err != nil {
+		t.Fatalf("描述文件行情源获取K线失败: %v", err)
+	}
+	if len(got) != len(want) || len(got) == 0 {
+		t.Fatalf("K线数量 = %d，期望 %d", len(got), len(want))
+	}
+	last := len(got) - 1
+	if got[last].OpenTime != want[last].OpenTime || got[last].Close != want[last].Close || got[last].CloseTime != want[last].CloseTime {
+		t.Fatalf("K线不一致: %+v vs %+v", got[last], want[last])
+	}
+	if oi, err := provider.GetOpenInterest("ETHUSDT"); err != nil || oi.Latest != 100000000 {
+		t.Fatalf("持仓量 = %+v, %v", oi, err)
+	}
+	if rate, err := provider.GetFundingRate("ETHUSDT"); err != nil || rate != 0.0001 {
+		t.Fatalf("资金费率 = %v, %v", rate, err)
+	}
+
+	// 作为默认行情源驱动完整周期
+	if err := market.SetDefaultProviderName(name); err != nil {
+		t.Fatal(err)
+	}
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+}
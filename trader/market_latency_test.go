@@ -0,0 +1,94 @@
+package trader
+
+import (
+	"context"
+	"errors"
+	"nofx/market"
+	"testing"
+	"time"
+)
+
+// latencyProvider 在模拟交易所行情上增加固定延迟或返回错误
+type latencyProvider struct {
+	*market.BinanceProvider
+	name  string
+	delay time.Duration
+	fail  bool
+}
+
+func (p *latencyProvider) GetName() string {
+	return p.name
+}
+
+func (p *latencyProvider) GetKlines(symbol, interval string, limit int) ([]market.Kline, error) {
+	time.Sleep(p.delay)
+	if p.fail {
+		return nil, errors.New("模拟数据源故障")
+	}
+	return p.BinanceProvider.GetKlines(symbol, interval, limit)
+}
+
+func TestIntegrationFastPriceProviderSelection(t *testing.T) {
+	ex, _ := setupIntegration(t)
+	for _, p := range []*latencyProvider{
+		{name: "it_slow", delay: 30 * time.Millisecond},
+		{name: "it_fast"},
+		{name: "it_broken", fail: true},
+	} {
+		p.BinanceProvider = market.NewBinanceProviderWithBaseURL(ex.URL())
+		market.RegisterProvider(p.name, p)
+	}
+	if err := market.SetDefaultProviderName("it_slow"); err != nil {
+		t.Fatal(err)
+	}
+	if err := market.SetFastPriceProviders([]string{"it_broken", "it_fast"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { market.SetFastPriceProviders(nil) })
+
+	// 依次探测默认数据源、故障数据源（失败后回退默认）、快速数据源，之后固定选择最快的健康数据源
+	for i := 0; i < 6; i++ {
+		price, err := market.CurrentPrice(context.Background(), "ETHUSDT")
+		if err != nil {
+			t.Fatalf("第%d次获取当前价失败: %v", i+1, err)
+		}
+		if price != 3000 {
+			t.Fatalf("当前价 = %v，期望 3000", price)
+		}
+	}
+
+	stats := market.ProviderSelection()
+	if !stats.Enabled {
+		t.Fatal("应启用延迟优选")
+	}
+	var selection *market.PriceSelection
+	for i := range stats.Selections {
+		if stats.Selections[i].Symbol == "ETHUSDT" {
+			selection = &stats.Selections[i]
+		}
+	}
+	if selection == nil || selection.Provider != "it_fast" || selection.Reason != market.SelectionFastest {
+		t.Fatalf("最近一次选择 = %+v，期望 it_fast/fastest", selection)
+	}
+	byName := make(map[string]market.ProviderHealthStats)
+	for _, p := range stats.Providers {
+		byName[p.Provider] = p
+	}
+	if broken := byName["it_broken"]; broken.ErrorRate != 1 || broken.Selected != 1 {
+		t.Errorf("故障数据源统计 = %+v", broken)
+	}
+	if slow, fast := byName["it_slow"], byName["it_fast"]; slow.AvgLatencyMs <= fast.AvgLatencyMs || fast.Selected < 3 {
+		t.Errorf("延迟统计 slow=%+v fast=%+v", slow, fast)
+	}
+
+	// 完整行情仍来自默认数据源，只有当前价走快速数据源
+	before := byName["it_fast"].Selected
+	if _, err := market.Get("ETHUSDT"); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range market.ProviderSelection().Providers {
+		if p.Provider == "it_fast" && p.Selected != before+1 {
+			t.Errorf("market.Get 应为当前价选择一次快速数据源: %d -> %d", before, p.Selected)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package trader
+
+import (
+	"errors"
+	"nofx/logger"
+	"testing"
+)
+
+func TestIntegrationMarketSnapshot(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	if err := at.decisionLogger.EnableMarketSnapshots(30); err != nil {
+		t.Fatal(err)
+	}
+
+	record := runCycle(t, at)
+	if !record.MarketSnapshot {
+		t.Fatal("决策记录应标记已保存行情快照")
+	}
+
+	// 价格变化后，快照仍是AI当时看到的数据
+	ex.SetPrice("ETHUSDT", 3300)
+	snapshot, err := at.decisionLogger.GetMarketSnapshot(record.DecisionID)
+	if err != nil {
+		t.Fatalf("读取行情快照失败: %v", err)
+	}
+	eth := snapshot["ETHUSDT"]
+	if eth == nil || eth.CurrentPrice != 3000 {
+		t.Fatalf("快照中的ETH行情不符合预期: %+v", eth)
+	}
+	if eth.IntradaySeries == nil || len(eth.IntradaySeries.MidPrices) == 0 {
+		t.Error("快照应包含完整的日内序列")
+	}
+
+	if _, err := at.decisionLogger.GetMarketSnapshot("20200101_000000_cycle1"); !errors.Is(err, logger.ErrSnapshotNotFound) {
+		t.Errorf("不存在的快照应返回 ErrSnapshotNotFound, 实际: %v", err)
+	}
+}
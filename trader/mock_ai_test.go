@@ -0,0 +1,159 @@
+package trader
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"nofx/decision"
+	"sync"
+	"testing"
+)
+
+// mockAI 模拟 OpenAI 兼容的 /chat/completions 接口，按队列顺序返回预设的决策
+// 队列为空时返回 wait，便于只关心部分周期的测试
+type mockAI struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	responses []mockReply
+	prompts   []string                 // 收到的 user prompt
+	requests  []map[string]interface{} // 收到的请求体（检查采样参数）
+	onRequest func()                   // 返回回复前调用（模拟AI思考期间行情变化）
+}
+
+// mockReply 一轮AI回复（reasoning 非空时模拟推理模型，放在 reasoning_content 字段）
+type mockReply struct {
+	content   string
+	reasoning string
+}
+
+func newMockAI(t *testing.T) *mockAI {
+	t.Helper()
+	m := &mockAI{}
+	m.server = httptest.NewServer(http.HandlerFunc(m.handle))
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// URL 模拟AI接口地址（作为 custom_api_url 使用）
+func (m *mockAI) URL() string {
+	return m.server.URL
+}
+
+// Enqueue 追加一轮AI回复：思维链 + 决策JSON
+func (m *mockAI) Enqueue(t *testing.T, cot string, decisions ...decision.Decision) {
+	t.Helper()
+	for i := range decisions {
+		decisions[i].SchemaVersion = decision.DecisionSchemaVersion
+	}
+	data, err := json.MarshalIndent(decisions, "", "  ")
+	if err != nil {
+		t.Fatalf("序列化模拟决策失败: %v", err)
+	}
+	m.mu.Lock()
+	m.responses = append(m.responses, mockReply{content: cot + "\n\n" + string(data)})
+	m.mu.Unlock()
+}
+
+// EnqueueRaw 追加一轮原样返回的AI回复（如二次复核的结论）
+func (m *mockAI) EnqueueRaw(content string) {
+	m.mu.Lock()
+	m.responses = append(m.responses, mockReply{content: content})
+	m.mu.Unlock()
+}
+
+// EnqueueReasoning 追加一轮推理模型的回复：推理过程 + 最终回答（content 为空模拟只有推理没有回答）
+func (m *mockAI) EnqueueReasoning(reasoning, content string) {
+	m.mu.Lock()
+	m.responses = append(m.responses, mockReply{content: content, reasoning: reasoning})
+	m.mu.Unlock()
+}
+
+// OnRequest 设置每次请求返回前执行的回调
+func (m *mockAI) OnRequest(fn func()) {
+	m.mu.Lock()
+	m.onRequest = fn
+	m.mu.Unlock()
+}
+
+// Prompts 返回收到的 user prompt
+func (m *mockAI) Prompts() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.prompts...)
+}
+
+// LastRequest 返回最近一次请求体
+func (m *mockAI) LastRequest() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.requests) == 0 {
+		return nil
+	}
+	return m.requests[len(m.requests)-1]
+}
+
+func (m *mockAI) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") == "" {
+		http.Error(w, `{"error":{"message":"unauthorized"}}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	var body map[string]interface{}
+	data, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(data, &req)
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &body)
+	}
+	if err != nil {
+		http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	m.requests = append(m.requests, body)
+	for _, msg := range req.Messages {
+		if msg.Role == "user" {
+			m.prompts = append(m.prompts, msg.Content)
+		}
+	}
+	reply := mockReply{content: `市场无明显机会，继续观望。
+
+[{"schema_version": 2, "symbol": "BTCUSDT", "action": "wait", "reasoning": "无信号"}]`}
+	if len(m.responses) > 0 {
+		reply = m.responses[0]
+		m.responses = m.responses[1:]
+	}
+	onRequest := m.onRequest
+	m.mu.Unlock()
+	if onRequest != nil {
+		onRequest()
+	}
+
+	message := map[string]string{"role": "assistant", "content": reply.content}
+	usage := map[string]interface{}{}
+	if reply.reasoning != "" {
+		message["reasoning_content"] = reply.reasoning
+		usage["completion_tokens_details"] = map[string]int{"reasoning_tokens": len([]rune(reply.reasoning))}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":     "chatcmpl-mock",
+		"object": "chat.completion",
+		"model":  "mock",
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"finish_reason": "stop",
+			"message":       message,
+		}},
+		"usage": usage,
+	})
+}
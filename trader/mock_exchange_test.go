@@ -0,0 +1,1224 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockExchange 基于 httptest 的模拟交易所
+// 同时提供 Gate.io USDT永续（/api/v4/futures/usdt/...）、币安U本位合约（/fapi/...）
+// 和币安行情接口，按内存中的价格/持仓/条件单状态返回与真实交易所一致的响应格式
+type mockExchange struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	prices  map[string]float64 // 内部符号 ETHUSDT -> 最新价
+	moves   map[string]float64 // 内部符号 -> K线起点价（K线从该价线性走到最新价，模拟急涨急跌）
+	indexes map[string]float64 // 内部符号 -> 现货指数价（未设置时等于最新价）
+	balance float64            // 钱包余额（USDT，已实现盈亏计入）
+	nextID  int64
+
+	gateContracts map[string]map[string]interface{} // ETH_USDT -> 合约信息（testdata/gateio_contracts.json）
+	gatePositions map[string]*mockPosition          // ETH_USDT -> 单向持仓（size 为合约张数，负数为空仓）
+	gateLeverage  map[string]int                    // ETH_USDT -> 杠杆
+	gateDualMode  bool                              // 持仓按双向模式返回（mode 为 dual_long / dual_short）
+	gateADL       int                               // 持仓返回的 adl_ranking
+
+	binanceExchangeInfo []byte                   // testdata/binance_exchange_info.json
+	binancePositions    map[string]*mockPosition // ETHUSDT_LONG -> 双向持仓（size 为币数量，恒为正）
+	binanceLeverage     map[string]int           // ETHUSDT -> 杠杆
+
+	leverageCalls map[string]int // "gateio" | "binance" -> 设置杠杆的请求次数
+
+	triggers []*mockTrigger // 止损止盈条件单
+
+	fillSlippage float64 // 成交价相对最新价的不利偏移比例（0.001 = 10bp），模拟滑点
+
+	fundingRates map[string]float64 // "gateio:ETHUSDT" -> 当前资金费率（未设置时为0.0001）
+
+	oneWayMode  bool // 账户为单向持仓模式（默认双向，权限自检用）
+	readOnlyKey bool // API密钥只读（Gate.io 写接口返回 READ_ONLY）
+
+	partialFills []float64                        // 后续IOC开仓单依次只成交的比例（模拟盘口深度不足）
+	rejections   []map[string]string              // 后续Gate.io订单依次返回的拒单错误（label/message）
+	orders       map[int64]map[string]interface{} // 订单ID -> 最终状态（查询订单用）
+
+	history     []mockFill // 成交历史（Gate.io my_trades / 币安 userTrades）
+	recordFills bool       // Gate.io 订单和条件单的成交也写入成交历史（对账测试用）
+}
+
+// mockFill 成交历史中的一笔成交
+type mockFill struct {
+	exchange     string    // "gateio" | "binance"
+	symbol       string    // 内部符号
+	side         string    // "buy" | "sell"
+	positionSide string    // 币安双向持仓 "LONG" | "SHORT"
+	size         float64   // Gate.io 为合约张数，币安为币数量
+	price        float64   // 成交价
+	closed       float64   // 其中平仓的数量（Gate.io close_size；币安不为0时返回已实现盈亏）
+	pnl          float64   // 已实现盈亏（Gate.io account_book type=pnl）
+	time         time.Time // 成交时间
+}
+
+type mockPosition struct {
+	size       float64
+	entryPrice float64
+	leverage   int
+	realised   float64 // 减仓累计的已实现盈亏（USDT）
+}
+
+// mockTrigger 条件单（Gate.io price_orders / 币安 STOP_MARKET、TAKE_PROFIT_MARKET）
+type mockTrigger struct {
+	id       int64
+	exchange string  // "gateio" | "binance"
+	symbol   string  // 内部符号
+	side     string  // 保护的持仓方向 "long" | "short"
+	kind     string  // "stop_loss" | "take_profit"
+	price    float64 // 触发价
+	above    bool    // true: 价格 >= 触发价时触发；false: 价格 <= 触发价时触发
+	size     float64 // 平仓数量（Gate.io 为合约张数，币安为币数量），0 表示全部平仓
+	status   string  // "open" | "finished" | "cancelled"
+	clientID string  // 客户端订单ID（Gate.io 为 text，币安为 newClientOrderId）
+}
+
+func newMockExchange(t *testing.T) *mockExchange {
+	t.Helper()
+
+	contractsJSON, err := os.ReadFile("testdata/gateio_contracts.json")
+	if err != nil {
+		t.Fatalf("读取Gate.io合约fixture失败: %v", err)
+	}
+	var contracts []map[string]interface{}
+	if err := json.Unmarshal(contractsJSON, &contracts); err != nil {
+		t.Fatalf("解析Gate.io合约fixture失败: %v", err)
+	}
+	exchangeInfo, err := os.ReadFile("testdata/binance_exchange_info.json")
+	if err != nil {
+		t.Fatalf("读取币安exchangeInfo fixture失败: %v", err)
+	}
+
+	m := &mockExchange{
+		prices:              make(map[string]float64),
+		moves:               make(map[string]float64),
+		indexes:             make(map[string]float64),
+		balance:             10000,
+		nextID:              1000,
+		gateContracts:       make(map[string]map[string]interface{}),
+		gatePositions:       make(map[string]*mockPosition),
+		gateLeverage:        make(map[string]int),
+		binanceExchangeInfo: exchangeInfo,
+		binancePositions:    make(map[string]*mockPosition),
+		binanceLeverage:     make(map[string]int),
+		leverageCalls:       make(map[string]int),
+		fundingRates:        make(map[string]float64),
+		orders:              make(map[int64]map[string]interface{}),
+	}
+	for _, c := range contracts {
+		name, _ := c["name"].(string)
+		m.gateContracts[name] = c
+	}
+
+	m.server = httptest.NewServer(http.HandlerFunc(m.handle))
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// SetFundingRate 设置交易所（"gateio" | "binance"）的当前资金费率
+func (m *mockExchange) SetFundingRate(exchange, symbol string, rate float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fundingRates[exchange+":"+symbol] = rate
+}
+
+// SetPartialFills 后续的IOC开仓单依次只成交给定比例，其余部分撤销（0 表示完全不成交）
+func (m *mockExchange) SetPartialFills(ratios ...float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.partialFills = append(m.partialFills, ratios...)
+}
+
+// RejectOrders 后续的Gate.io订单依次以给定label被拒绝（如 INSUFFICIENT_AVAILABLE）
+func (m *mockExchange) RejectOrders(labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, label := range labels {
+		m.rejections = append(m.rejections, map[string]string{"label": label, "message": strings.ToLower(label)})
+	}
+}
+
+// AddHistoricalFills 添加成交历史
+func (m *mockExchange) AddHistoricalFills(fills ...mockFill) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history = append(m.history, fills...)
+}
+
+// RecordFills Gate.io 订单和条件单的成交写入成交历史（平仓成交同时写入盈亏流水）
+func (m *mockExchange) RecordFills() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordFills = true
+}
+
+// historyLocked 交易所的成交历史（按时间正序，成交ID为序号+1）
+func (m *mockExchange) historyLocked(exchange string) ([]mockFill, []int) {
+	var fills []mockFill
+	var ids []int
+	for i, f := range m.history {
+		if f.exchange == exchange {
+			fills = append(fills, f)
+			ids = append(ids, i+1)
+		}
+	}
+	return fills, ids
+}
+
+// nextFillRatioLocked IOC开仓单的成交比例（没有设置部分成交时为1）
+func (m *mockExchange) nextFillRatioLocked() float64 {
+	if len(m.partialFills) == 0 {
+		return 1
+	}
+	ratio := m.partialFills[0]
+	m.partialFills = m.partialFills[1:]
+	return ratio
+}
+
+func (m *mockExchange) fundingRateLocked(exchange, symbol string) float64 {
+	if rate, ok := m.fundingRates[exchange+":"+symbol]; ok {
+		return rate
+	}
+	return 0.0001
+}
+
+// URL 模拟交易所地址
+func (m *mockExchange) URL() string {
+	return m.server.URL
+}
+
+// SetPrice 设置最新价并撮合触发的条件单
+func (m *mockExchange) SetPrice(symbol string, price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prices[symbol] = price
+	m.checkTriggersLocked()
+}
+
+// SetPriceMove 设置最新价，并让K线从 from 线性走到最新价（from<=0 时恢复为围绕最新价轻微波动）
+func (m *mockExchange) SetPriceMove(symbol string, from, price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if from > 0 {
+		m.moves[symbol] = from
+	} else {
+		delete(m.moves, symbol)
+	}
+	m.prices[symbol] = price
+	m.checkTriggersLocked()
+}
+
+// SetIndexPrice 设置现货指数价（price<=0 时恢复为等于最新价）
+func (m *mockExchange) SetIndexPrice(symbol string, price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if price > 0 {
+		m.indexes[symbol] = price
+	} else {
+		delete(m.indexes, symbol)
+	}
+}
+
+// SetAccountSettings 设置账户持仓模式和API密钥是否只读
+func (m *mockExchange) SetAccountSettings(oneWayMode, readOnlyKey bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.oneWayMode = oneWayMode
+	m.readOnlyKey = readOnlyKey
+}
+
+// SetFillSlippage 设置后续成交的不利偏移比例（买入成交价更高，卖出更低）
+func (m *mockExchange) SetFillSlippage(ratio float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fillSlippage = ratio
+}
+
+// fillPriceLocked 含滑点的成交价
+func (m *mockExchange) fillPriceLocked(market float64, isBuy bool) float64 {
+	if isBuy {
+		return market * (1 + m.fillSlippage)
+	}
+	return market * (1 - m.fillSlippage)
+}
+
+// GatePosition 返回 Gate.io 持仓（size 为合约张数，负数为空仓），无持仓时返回零值
+func (m *mockExchange) GatePosition(symbol string) mockPosition {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pos, ok := m.gatePositions[gateContract(symbol)]; ok {
+		return *pos
+	}
+	return mockPosition{}
+}
+
+// BinancePosition 返回币安双向持仓（size 为币数量），无持仓时返回零值
+func (m *mockExchange) BinancePosition(symbol, positionSide string) mockPosition {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pos, ok := m.binancePositions[symbol+"_"+positionSide]; ok {
+		return *pos
+	}
+	return mockPosition{}
+}
+
+// LeverageCalls 返回交易所（"gateio" | "binance"）收到的设置杠杆请求次数
+func (m *mockExchange) LeverageCalls(exchange string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.leverageCalls[exchange]
+}
+
+// Balance 返回钱包余额（含已实现盈亏）
+func (m *mockExchange) Balance() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.balance
+}
+
+// GateOrders Gate.io 提交过的普通订单（按订单ID正序）
+func (m *mockExchange) GateOrders() []map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []int64
+	for id, order := range m.orders {
+		if _, ok := order["contract"]; ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	orders := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		orders = append(orders, m.orders[id])
+	}
+	return orders
+}
+
+// Triggers 返回指定交易所和状态的条件单
+func (m *mockExchange) Triggers(exchange, status string) []mockTrigger {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []mockTrigger
+	for _, tr := range m.triggers {
+		if tr.exchange == exchange && tr.status == status {
+			list = append(list, *tr)
+		}
+	}
+	return list
+}
+
+func (m *mockExchange) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/v4/futures/usdt/"):
+		public := r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/api/v4/futures/usdt/contracts/") // 行情接口不需要签名
+		if !public && (r.Header.Get("KEY") == "" || r.Header.Get("SIGN") == "") {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"label": "INVALID_KEY", "message": "missing signature"})
+			return
+		}
+		m.handleGateio(w, r, strings.TrimPrefix(r.URL.Path, "/api/v4/futures/usdt"), body)
+	case strings.HasPrefix(r.URL.Path, "/fapi/"):
+		params := r.URL.Query()
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			for k, v := range form {
+				params[k] = v
+			}
+		}
+		m.handleBinance(w, r, params)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// --- Gate.io ---
+
+func gateContract(symbol string) string {
+	return strings.TrimSuffix(symbol, "USDT") + "_USDT"
+}
+
+func gateSymbol(contract string) string {
+	return strings.ReplaceAll(contract, "_", "")
+}
+
+func (m *mockExchange) quanto(contract string) float64 {
+	if c, ok := m.gateContracts[contract]; ok {
+		q, _ := strconv.ParseFloat(fmt.Sprint(c["quanto_multiplier"]), 64)
+		return q
+	}
+	return 1
+}
+
+func (m *mockExchange) handleGateio(w http.ResponseWriter, r *http.Request, path string, body []byte) {
+	switch {
+	case r.Method == "GET" && path == "/accounts":
+		upnl, margin := m.gateExposureLocked()
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"user":            10001,
+			"currency":        "USDT",
+			"total":           formatFloat(m.balance + upnl),
+			"unrealised_pnl":  formatFloat(upnl),
+			"position_margin": formatFloat(margin),
+			"available":       formatFloat(m.balance + upnl - margin),
+			"in_dual_mode":    !m.oneWayMode,
+		})
+
+	case r.Method == "POST" && path == "/countdown_cancel_all":
+		if m.readOnlyKey {
+			writeJSON(w, http.StatusForbidden, map[string]string{"label": "READ_ONLY", "message": "API key is read only"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"triggerTime": 0})
+
+	case r.Method == "GET" && path == "/positions":
+		list := []map[string]interface{}{}
+		for contract, pos := range m.gatePositions {
+			price := m.prices[gateSymbol(contract)]
+			value := pos.size * m.quanto(contract) * price
+			mode := "single"
+			if m.gateDualMode {
+				mode = "dual_long"
+				if pos.size < 0 {
+					mode = "dual_short"
+				}
+			}
+			list = append(list, map[string]interface{}{
+				"contract":       contract,
+				"size":           int64(pos.size),
+				"leverage":       strconv.Itoa(pos.leverage),
+				"entry_price":    formatFloat(pos.entryPrice),
+				"mark_price":     formatFloat(price),
+				"value":          formatFloat(math.Abs(value)),
+				"unrealised_pnl": formatFloat(pos.size * m.quanto(contract) * (price - pos.entryPrice)),
+				"liq_price":      "0",
+				"margin":         formatFloat(math.Abs(pos.size) * m.quanto(contract) * pos.entryPrice / float64(pos.leverage)),
+				"adl_ranking":    m.gateADL,
+				"realised_pnl":   formatFloat(pos.realised),
+				"mode":           mode,
+			})
+		}
+		writeJSON(w, http.StatusOK, paginate(list, r))
+
+	case r.Method == "GET" && strings.HasPrefix(path, "/positions/"):
+		// 单个合约的持仓（无持仓时同样返回杠杆）
+		contract := strings.TrimPrefix(path, "/positions/")
+		leverage := m.gateLeverage[contract]
+		if pos, ok := m.gatePositions[contract]; ok {
+			leverage = pos.leverage
+		} else if leverage == 0 {
+			leverage = 10
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"contract": contract, "leverage": strconv.Itoa(leverage), "mode": "single"})
+
+	case r.Method == "POST" && strings.HasPrefix(path, "/positions/") && strings.HasSuffix(path, "/leverage"):
+		contract := strings.TrimSuffix(strings.TrimPrefix(path, "/positions/"), "/leverage")
+		leverage, err := strconv.Atoi(r.URL.Query().Get("leverage"))
+		if err != nil || leverage <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"label": "INVALID_PARAM_VALUE", "message": "invalid leverage"})
+			return
+		}
+		m.gateLeverage[contract] = leverage
+		m.leverageCalls["gateio"]++
+		writeJSON(w, http.StatusOK, map[string]interface{}{"contract": contract, "leverage": strconv.Itoa(leverage), "size": 0})
+
+	case r.Method == "GET" && strings.HasPrefix(path, "/contracts/"):
+		contract := strings.TrimPrefix(path, "/contracts/")
+		info, ok := m.gateContracts[contract]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"label": "CONTRACT_NOT_FOUND", "message": contract})
+			return
+		}
+		withFunding := make(map[string]interface{}, len(info)+1)
+		for k, v := range info {
+			withFunding[k] = v
+		}
+		withFunding["funding_rate"] = formatFloat(m.fundingRateLocked("gateio", gateSymbol(contract)))
+		writeJSON(w, http.StatusOK, withFunding)
+
+	case r.Method == "GET" && path == "/account_book":
+		list := []map[string]interface{}{}
+		if r.URL.Query().Get("type") == "pnl" {
+			from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+			fills, ids := m.historyLocked("gateio")
+			for i, f := range fills {
+				if f.pnl != 0 && f.time.Unix() >= from {
+					list = append(list, map[string]interface{}{
+						"id":       ids[i],
+						"time":     float64(f.time.UnixMilli()) / 1000,
+						"change":   formatFloat(f.pnl),
+						"type":     "pnl",
+						"contract": strings.Replace(f.symbol, "USDT", "_USDT", 1),
+					})
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	case r.Method == "GET" && path == "/my_trades":
+		// 最新的在前
+		fills, ids := m.historyLocked("gateio")
+		list := make([]map[string]interface{}, 0, len(fills))
+		for i := len(fills) - 1; i >= 0; i-- {
+			f := fills[i]
+			size, closed := f.size, f.closed
+			if f.side == "sell" {
+				size, closed = -size, -closed
+			}
+			list = append(list, map[string]interface{}{
+				"id":          ids[i],
+				"create_time": float64(f.time.UnixMilli()) / 1000,
+				"contract":    strings.Replace(f.symbol, "USDT", "_USDT", 1),
+				"size":        size,
+				"close_size":  closed,
+				"price":       formatFloat(f.price),
+			})
+		}
+		writeJSON(w, http.StatusOK, paginate(list, r))
+
+	case r.Method == "GET" && path == "/tickers":
+		contract := r.URL.Query().Get("contract")
+		price := m.prices[gateSymbol(contract)]
+		writeJSON(w, http.StatusOK, []map[string]string{{
+			"contract":   contract,
+			"last":       formatFloat(price),
+			"mark_price": formatFloat(price),
+		}})
+
+	case r.Method == "POST" && path == "/orders":
+		var order struct {
+			Contract   string `json:"contract"`
+			Size       int64  `json:"size"`
+			Price      string `json:"price"`
+			Tif        string `json:"tif"`
+			ReduceOnly bool   `json:"reduce_only"`
+		}
+		if err := json.Unmarshal(body, &order); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"label": "INVALID_REQUEST_BODY", "message": err.Error()})
+			return
+		}
+		m.handleGateOrderLocked(w, order.Contract, float64(order.Size), order.Price, order.Tif, order.ReduceOnly)
+
+	case r.Method == "GET" && strings.HasPrefix(path, "/orders/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/orders/"), 10, 64)
+		order, ok := m.orders[id]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"label": "ORDER_NOT_FOUND", "message": "order not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, order)
+
+	case r.Method == "DELETE" && path == "/orders":
+		// 市价/IOC单即时成交，没有挂单
+		writeJSON(w, http.StatusOK, []interface{}{})
+
+	case r.Method == "POST" && path == "/price_orders":
+		var order struct {
+			Initial struct {
+				Contract string `json:"contract"`
+				Size     int64  `json:"size"`
+				Text     string `json:"text"`
+			} `json:"initial"`
+			Trigger struct {
+				Price string `json:"price"`
+				Rule  int    `json:"rule"`
+			} `json:"trigger"`
+		}
+		if err := json.Unmarshal(body, &order); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"label": "INVALID_REQUEST_BODY", "message": err.Error()})
+			return
+		}
+		price, _ := strconv.ParseFloat(order.Trigger.Price, 64)
+		side := "long"
+		if order.Initial.Size > 0 {
+			side = "short" // 正数张数平空
+		}
+		above := order.Trigger.Rule == 1
+		kind := "stop_loss"
+		if (side == "long") == above {
+			kind = "take_profit"
+		}
+		m.nextID++
+		m.triggers = append(m.triggers, &mockTrigger{
+			id:       m.nextID,
+			exchange: "gateio",
+			symbol:   gateSymbol(order.Initial.Contract),
+			side:     side,
+			kind:     kind,
+			price:    price,
+			above:    above,
+			size:     math.Abs(float64(order.Initial.Size)),
+			status:   "open",
+			clientID: order.Initial.Text,
+		})
+		writeJSON(w, http.StatusCreated, map[string]int64{"id": m.nextID})
+
+	case r.Method == "GET" && path == "/price_orders":
+		status := r.URL.Query().Get("status")
+		contract := r.URL.Query().Get("contract")
+		list := []map[string]interface{}{}
+		for _, tr := range m.triggers {
+			if tr.exchange != "gateio" || (status != "" && tr.status != status) || (contract != "" && gateContract(tr.symbol) != contract) {
+				continue
+			}
+			size := tr.size
+			if tr.side == "long" {
+				size = -size
+			}
+			rule := 2
+			if tr.above {
+				rule = 1
+			}
+			list = append(list, map[string]interface{}{
+				"id":      tr.id,
+				"status":  tr.status,
+				"initial": map[string]interface{}{"contract": gateContract(tr.symbol), "size": size, "text": tr.clientID},
+				"trigger": map[string]interface{}{"price": formatFloat(tr.price), "rule": rule},
+			})
+		}
+		writeJSON(w, http.StatusOK, paginate(list, r))
+
+	case r.Method == "DELETE" && strings.HasPrefix(path, "/price_orders/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/price_orders/"), 10, 64)
+		for _, tr := range m.triggers {
+			if tr.exchange == "gateio" && tr.id == id && tr.status == "open" {
+				tr.status = "cancelled"
+				writeJSON(w, http.StatusOK, map[string]interface{}{"id": tr.id, "status": "finished", "finish_as": "cancelled"})
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"label": "ORDER_NOT_FOUND", "message": "price order not found"})
+
+	case r.Method == "DELETE" && path == "/price_orders":
+		symbol := gateSymbol(r.URL.Query().Get("contract"))
+		list := []map[string]interface{}{}
+		for _, tr := range m.triggers {
+			if tr.exchange == "gateio" && tr.symbol == symbol && tr.status == "open" {
+				tr.status = "cancelled"
+				list = append(list, map[string]interface{}{"id": tr.id, "status": "finished", "finish_as": "cancelled"})
+			}
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"label": "NOT_FOUND", "message": r.Method + " " + path})
+	}
+}
+
+// handleGateOrderLocked 撮合 Gate.io 订单：price "0" 为市价单，其余按限价与最新价比较
+func (m *mockExchange) handleGateOrderLocked(w http.ResponseWriter, contract string, size float64, priceStr, tif string, reduceOnly bool) {
+	market := m.prices[gateSymbol(contract)]
+	if market <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"label": "CONTRACT_NOT_FOUND", "message": contract})
+		return
+	}
+	if len(m.rejections) > 0 {
+		writeJSON(w, http.StatusBadRequest, m.rejections[0])
+		m.rejections = m.rejections[1:]
+		return
+	}
+	limit, _ := strconv.ParseFloat(priceStr, 64)
+	isMarket := priceStr == "0"
+	if isMarket && tif != "ioc" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"label": "INVALID_PARAM_VALUE", "message": "market order requires tif ioc"})
+		return
+	}
+	marketable := isMarket || (size > 0 && limit >= market) || (size < 0 && limit <= market)
+	if tif == "poc" && marketable {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"label": "ORDER_POC_IMMEDIATE", "message": "order would immediately match"})
+		return
+	}
+
+	m.nextID++
+	resp := map[string]interface{}{
+		"id":         m.nextID,
+		"contract":   contract,
+		"size":       int64(size),
+		"price":      priceStr,
+		"tif":        tif,
+		"status":     "finished",
+		"finish_as":  "filled",
+		"left":       0,
+		"fill_price": formatFloat(m.fillPriceLocked(market, size > 0)),
+	}
+	switch {
+	case tif == "poc":
+		resp["status"] = "open"
+		resp["finish_as"] = ""
+		resp["left"] = int64(size)
+	case !marketable:
+		// IOC/FOK 不能立即成交则撤销
+		resp["finish_as"] = tif
+		resp["left"] = int64(size)
+	default:
+		if tif == "ioc" && !reduceOnly {
+			if ratio := m.nextFillRatioLocked(); ratio < 1 {
+				// 盘口深度不足：只成交一部分，其余撤销
+				resp["finish_as"] = "ioc"
+				size = math.Round(size * ratio)
+			}
+		}
+		filled := m.fillGateLocked(contract, size, m.fillPriceLocked(market, size > 0), reduceOnly)
+		resp["left"] = resp["size"].(int64) - int64(filled)
+	}
+	m.orders[m.nextID] = resp
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// fillGateLocked 成交后更新单向持仓，返回成交张数（带方向）
+func (m *mockExchange) fillGateLocked(contract string, size, price float64, reduceOnly bool) float64 {
+	pos, ok := m.gatePositions[contract]
+	if !ok {
+		if reduceOnly {
+			return 0
+		}
+		leverage := m.gateLeverage[contract]
+		if leverage == 0 {
+			leverage = 10
+		}
+		pos = &mockPosition{leverage: leverage}
+		m.gatePositions[contract] = pos
+	}
+
+	if reduceOnly {
+		if pos.size == 0 || (pos.size > 0) == (size > 0) {
+			return 0
+		}
+		if math.Abs(size) > math.Abs(pos.size) {
+			size = -pos.size
+		}
+	}
+
+	quanto := m.quanto(contract)
+	fill := mockFill{exchange: "gateio", symbol: gateSymbol(contract), side: "buy", size: math.Abs(size), price: price, time: time.Now()}
+	if size < 0 {
+		fill.side = "sell"
+	}
+	switch {
+	case pos.size == 0 || (pos.size > 0) == (size > 0):
+		// 开仓/加仓：更新均价
+		total := pos.size + size
+		pos.entryPrice = (pos.entryPrice*math.Abs(pos.size) + price*math.Abs(size)) / math.Abs(total)
+		pos.size = total
+	default:
+		// 减仓：结算已实现盈亏
+		closed := math.Min(math.Abs(size), math.Abs(pos.size))
+		direction := 1.0
+		if pos.size < 0 {
+			direction = -1
+		}
+		realised := closed * quanto * (price - pos.entryPrice) * direction
+		m.balance += realised
+		pos.realised += realised
+		pos.size += size
+		if math.Abs(pos.size) < 1e-9 {
+			delete(m.gatePositions, contract)
+		}
+		fill.closed, fill.pnl = closed, realised
+	}
+	if m.recordFills && size != 0 {
+		m.history = append(m.history, fill)
+	}
+	return size
+}
+
+func (m *mockExchange) gateExposureLocked() (upnl, margin float64) {
+	for contract, pos := range m.gatePositions {
+		price := m.prices[gateSymbol(contract)]
+		quanto := m.quanto(contract)
+		upnl += pos.size * quanto * (price - pos.entryPrice)
+		margin += math.Abs(pos.size) * quanto * price / float64(pos.leverage)
+	}
+	return upnl, margin
+}
+
+// --- Binance ---
+
+func (m *mockExchange) handleBinance(w http.ResponseWriter, r *http.Request, params url.Values) {
+	symbol := params.Get("symbol")
+	path := r.URL.Path
+
+	switch {
+	// 行情
+	case r.Method == "GET" && path == "/fapi/v1/klines":
+		limit, _ := strconv.Atoi(params.Get("limit"))
+		writeJSON(w, http.StatusOK, m.klinesLocked(symbol, params.Get("interval"), limit))
+
+	case r.Method == "GET" && path == "/fapi/v1/openInterest":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"symbol":       symbol,
+			"openInterest": "100000000", // 足够大，通过流动性过滤
+			"time":         time.Now().UnixMilli(),
+		})
+
+	case r.Method == "GET" && path == "/fapi/v1/premiumIndex":
+		index, ok := m.indexes[symbol]
+		if !ok {
+			index = m.prices[symbol]
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"symbol":          symbol,
+			"markPrice":       formatFloat(m.prices[symbol]),
+			"indexPrice":      formatFloat(index),
+			"lastFundingRate": formatFloat(m.fundingRateLocked("binance", symbol)),
+			"nextFundingTime": time.Now().Add(time.Hour).UnixMilli(),
+			"interestRate":    "0.00010000",
+			"time":            time.Now().UnixMilli(),
+		})
+
+	case r.Method == "GET" && path == "/fapi/v2/ticker/price":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"symbol": symbol,
+			"price":  formatFloat(m.prices[symbol]),
+			"time":   time.Now().UnixMilli(),
+		})
+
+	case r.Method == "GET" && path == "/fapi/v1/exchangeInfo":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(m.binanceExchangeInfo)
+
+	// 账户
+	case r.Method == "GET" && path == "/fapi/v2/account":
+		upnl, margin := m.binanceExposureLocked()
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"totalWalletBalance":    formatFloat(m.balance),
+			"totalUnrealizedProfit": formatFloat(upnl),
+			"totalMarginBalance":    formatFloat(m.balance + upnl),
+			"availableBalance":      formatFloat(m.balance + upnl - margin),
+			"canTrade":              !m.readOnlyKey,
+			"feeTier":               1,
+			"assets":                []interface{}{},
+			"positions":             []interface{}{},
+		})
+
+	case r.Method == "GET" && path == "/fapi/v2/positionRisk":
+		list := []map[string]interface{}{}
+		for key, pos := range m.binancePositions {
+			parts := strings.SplitN(key, "_", 2)
+			if symbol != "" && parts[0] != symbol {
+				continue
+			}
+			amt := pos.size
+			if parts[1] == "SHORT" {
+				amt = -amt
+			}
+			price := m.prices[parts[0]]
+			list = append(list, map[string]interface{}{
+				"symbol":           parts[0],
+				"positionSide":     parts[1],
+				"positionAmt":      formatFloat(amt),
+				"entryPrice":       formatFloat(pos.entryPrice),
+				"markPrice":        formatFloat(price),
+				"unRealizedProfit": formatFloat(amt * (price - pos.entryPrice)),
+				"leverage":         strconv.Itoa(pos.leverage),
+				"liquidationPrice": "0",
+				"marginType":       "isolated",
+			})
+		}
+		if symbol != "" && len(list) == 0 {
+			// 指定币种且无持仓时返回该币种的杠杆设置
+			leverage := m.binanceLeverage[symbol]
+			if leverage == 0 {
+				leverage = 20
+			}
+			list = append(list, map[string]interface{}{
+				"symbol":       symbol,
+				"positionSide": "BOTH",
+				"positionAmt":  "0",
+				"leverage":     strconv.Itoa(leverage),
+				"marginType":   "isolated",
+			})
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	case r.Method == "POST" && path == "/fapi/v1/leverage":
+		leverage, _ := strconv.Atoi(params.Get("leverage"))
+		m.binanceLeverage[symbol] = leverage
+		m.leverageCalls["binance"]++
+		writeJSON(w, http.StatusOK, map[string]interface{}{"symbol": symbol, "leverage": leverage, "maxNotionalValue": "1000000"})
+
+	case r.Method == "GET" && path == "/fapi/v1/positionSide/dual":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"dualSidePosition": !m.oneWayMode})
+
+	case r.Method == "GET" && path == "/fapi/v1/commissionRate":
+		writeJSON(w, http.StatusOK, map[string]string{"symbol": params.Get("symbol"), "makerCommissionRate": "0.000180", "takerCommissionRate": "0.000450"})
+
+	case r.Method == "POST" && path == "/fapi/v1/marginType":
+		// 账户已是逐仓模式
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"code": -4046, "msg": "No need to change margin type."})
+
+	// 订单
+	case r.Method == "POST" && path == "/fapi/v1/order":
+		m.handleBinanceOrderLocked(w, params)
+
+	case r.Method == "GET" && path == "/fapi/v1/order":
+		id, _ := strconv.ParseInt(params.Get("orderId"), 10, 64)
+		order, ok := m.orders[id]
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"code": -2013, "msg": "Order does not exist."})
+			return
+		}
+		writeJSON(w, http.StatusOK, order)
+
+	case r.Method == "DELETE" && path == "/fapi/v1/allOpenOrders":
+		for _, tr := range m.triggers {
+			if tr.exchange == "binance" && tr.symbol == symbol && tr.status == "open" {
+				tr.status = "cancelled"
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"code": 200, "msg": "The operation of cancel all open order is done."})
+
+	case r.Method == "GET" && path == "/fapi/v1/income":
+		list := []map[string]interface{}{}
+		if params.Get("incomeType") == "COMMISSION" {
+			fills, ids := m.historyLocked("binance")
+			for i, f := range fills {
+				if inTimeRange(f.time, params) {
+					list = append(list, map[string]interface{}{"symbol": f.symbol, "incomeType": "COMMISSION", "income": "-0.01", "asset": "USDT", "time": f.time.UnixMilli(), "tranId": ids[i]})
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	case r.Method == "GET" && path == "/fapi/v1/userTrades":
+		list := []map[string]interface{}{}
+		fills, ids := m.historyLocked("binance")
+		for i, f := range fills {
+			if f.symbol != symbol || !inTimeRange(f.time, params) {
+				continue
+			}
+			pnl := "0"
+			if f.closed > 0 {
+				pnl = "1.5"
+			}
+			list = append(list, map[string]interface{}{
+				"id":           ids[i],
+				"symbol":       f.symbol,
+				"side":         strings.ToUpper(f.side),
+				"positionSide": f.positionSide,
+				"qty":          formatFloat(f.size),
+				"price":        formatFloat(f.price),
+				"realizedPnl":  pnl,
+				"time":         f.time.UnixMilli(),
+			})
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	case r.Method == "GET" && path == "/fapi/v1/openOrders":
+		list := []map[string]interface{}{}
+		for _, tr := range m.triggers {
+			if tr.exchange == "binance" && tr.status == "open" && (symbol == "" || tr.symbol == symbol) {
+				orderType, side := "STOP_MARKET", "SELL"
+				if tr.kind == "take_profit" {
+					orderType = "TAKE_PROFIT_MARKET"
+				}
+				if tr.side == "short" {
+					side = "BUY"
+				}
+				list = append(list, map[string]interface{}{
+					"orderId":       tr.id,
+					"symbol":        tr.symbol,
+					"type":          orderType,
+					"side":          side,
+					"positionSide":  strings.ToUpper(tr.side),
+					"stopPrice":     formatFloat(tr.price),
+					"origQty":       formatFloat(tr.size),
+					"closePosition": tr.size == 0,
+					"status":        "NEW",
+				})
+			}
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	case r.Method == "DELETE" && path == "/fapi/v1/order":
+		id, _ := strconv.ParseInt(params.Get("orderId"), 10, 64)
+		for _, tr := range m.triggers {
+			if tr.exchange == "binance" && tr.id == id && tr.status == "open" {
+				tr.status = "cancelled"
+				writeJSON(w, http.StatusOK, map[string]interface{}{"orderId": tr.id, "symbol": tr.symbol, "status": "CANCELED"})
+				return
+			}
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"code": -2011, "msg": "Unknown order sent."})
+
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"code": -5000, "msg": "path not found: " + r.Method + " " + path})
+	}
+}
+
+// handleBinanceOrderLocked 撮合币安订单（双向持仓模式）
+func (m *mockExchange) handleBinanceOrderLocked(w http.ResponseWriter, params url.Values) {
+	symbol := params.Get("symbol")
+	side := params.Get("side")
+	positionSide := params.Get("positionSide")
+	orderType := params.Get("type")
+	quantity, _ := strconv.ParseFloat(params.Get("quantity"), 64)
+	market := m.prices[symbol]
+	if market <= 0 || (positionSide != "LONG" && positionSide != "SHORT") {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"code": -1121, "msg": "Invalid symbol or position side."})
+		return
+	}
+
+	m.nextID++
+	resp := map[string]interface{}{
+		"orderId":      m.nextID,
+		"symbol":       symbol,
+		"side":         side,
+		"positionSide": positionSide,
+		"type":         orderType,
+		"origQty":      params.Get("quantity"),
+		"executedQty":  "0",
+		"avgPrice":     "0",
+		"status":       "NEW",
+		"updateTime":   time.Now().UnixMilli(),
+	}
+
+	switch orderType {
+	case "STOP_MARKET", "TAKE_PROFIT_MARKET":
+		stopPrice, _ := strconv.ParseFloat(params.Get("stopPrice"), 64)
+		size := quantity
+		if params.Get("closePosition") == "true" {
+			size = 0
+		}
+		kind := "stop_loss"
+		if orderType == "TAKE_PROFIT_MARKET" {
+			kind = "take_profit"
+		}
+		m.triggers = append(m.triggers, &mockTrigger{
+			id:       m.nextID,
+			exchange: "binance",
+			symbol:   symbol,
+			side:     strings.ToLower(positionSide),
+			kind:     kind,
+			price:    stopPrice,
+			above:    (kind == "take_profit") == (positionSide == "LONG"),
+			size:     size,
+			status:   "open",
+			clientID: params.Get("newClientOrderId"),
+		})
+		resp["stopPrice"] = params.Get("stopPrice")
+
+	case "MARKET", "LIMIT":
+		marketable := orderType == "MARKET"
+		if orderType == "LIMIT" {
+			limit, _ := strconv.ParseFloat(params.Get("price"), 64)
+			marketable = (side == "BUY" && limit >= market) || (side == "SELL" && limit <= market)
+			if params.Get("timeInForce") == "GTX" && marketable {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"code": -5022, "msg": "Due to the order could not be executed as maker, the Post Only order will be rejected."})
+				return
+			}
+		}
+		if !marketable {
+			resp["status"] = "EXPIRED"
+			break
+		}
+		opening := (side == "BUY") == (positionSide == "LONG")
+		fillPrice := m.fillPriceLocked(market, side == "BUY")
+		resp["status"] = "FILLED"
+		if opening && orderType == "LIMIT" && params.Get("timeInForce") == "IOC" {
+			if ratio := m.nextFillRatioLocked(); ratio < 1 {
+				// 盘口深度不足：只成交一部分，其余过期
+				resp["status"] = "EXPIRED"
+				quantity *= ratio
+			}
+		}
+		if quantity == 0 {
+			resp["avgPrice"] = "0"
+			break
+		}
+		filled := m.fillBinanceLocked(symbol, positionSide, quantity, fillPrice, opening)
+		resp["executedQty"] = formatFloat(filled)
+		resp["avgPrice"] = formatFloat(fillPrice)
+
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"code": -1116, "msg": "Invalid orderType."})
+		return
+	}
+	m.orders[m.nextID] = resp
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// fillBinanceLocked 成交后更新双向持仓，返回成交数量
+func (m *mockExchange) fillBinanceLocked(symbol, positionSide string, quantity, price float64, opening bool) float64 {
+	key := symbol + "_" + positionSide
+	pos, ok := m.binancePositions[key]
+	if opening {
+		if !ok {
+			leverage := m.binanceLeverage[symbol]
+			if leverage == 0 {
+				leverage = 20
+			}
+			pos = &mockPosition{leverage: leverage}
+			m.binancePositions[key] = pos
+		}
+		pos.entryPrice = (pos.entryPrice*pos.size + price*quantity) / (pos.size + quantity)
+		pos.size += quantity
+		return quantity
+	}
+
+	if !ok {
+		return 0
+	}
+	if quantity == 0 || quantity > pos.size {
+		quantity = pos.size
+	}
+	direction := 1.0
+	if positionSide == "SHORT" {
+		direction = -1
+	}
+	m.balance += quantity * (price - pos.entryPrice) * direction
+	pos.size -= quantity
+	if pos.size < 1e-9 {
+		delete(m.binancePositions, key)
+	}
+	return quantity
+}
+
+func (m *mockExchange) binanceExposureLocked() (upnl, margin float64) {
+	for key, pos := range m.binancePositions {
+		parts := strings.SplitN(key, "_", 2)
+		price := m.prices[parts[0]]
+		direction := 1.0
+		if parts[1] == "SHORT" {
+			direction = -1
+		}
+		upnl += pos.size * (price - pos.entryPrice) * direction
+		margin += pos.size * price / float64(pos.leverage)
+	}
+	return upnl, margin
+}
+
+// klinesLocked 生成以最新价收盘的K线（轻微波动，保证指标可计算）
+func (m *mockExchange) klinesLocked(symbol, interval string, limit int) [][]interface{} {
+	price := m.prices[symbol]
+	step := 3 * time.Minute
+	switch interval {
+	case "4h":
+		step = 4 * time.Hour
+	case "1m":
+		step = time.Minute
+	}
+	from, moving := m.moves[symbol]
+	if limit <= 0 {
+		limit = 500
+	}
+
+	now := time.Now().Truncate(step)
+	klines := make([][]interface{}, 0, limit)
+	for i := 0; i < limit; i++ {
+		offset := limit - 1 - i
+		closePrice := price * (1 + 0.002*math.Sin(float64(offset)))
+		if offset == 0 {
+			closePrice = price
+		}
+		openPrice := price * (1 + 0.002*math.Sin(float64(offset+1)))
+		if moving {
+			openPrice = from + (price-from)*float64(i)/float64(limit)
+			closePrice = from + (price-from)*float64(i+1)/float64(limit)
+		}
+		openTime := now.Add(-time.Duration(offset) * step)
+		klines = append(klines, []interface{}{
+			openTime.UnixMilli(),
+			formatFloat(openPrice),
+			formatFloat(math.Max(openPrice, closePrice) * 1.001),
+			formatFloat(math.Min(openPrice, closePrice) * 0.999),
+			formatFloat(closePrice),
+			"1000",
+			openTime.Add(step).UnixMilli() - 1,
+			"3000000",
+			100,
+			"500",
+			"1500000",
+			"0",
+		})
+	}
+	return klines
+}
+
+// --- 条件单撮合 ---
+
+// checkTriggersLocked 价格变动后检查条件单，触发后按市价减仓
+func (m *mockExchange) checkTriggersLocked() {
+	for _, tr := range m.triggers {
+		if tr.status != "open" {
+			continue
+		}
+		price := m.prices[tr.symbol]
+		if price <= 0 || (tr.above && price < tr.price) || (!tr.above && price > tr.price) {
+			continue
+		}
+
+		tr.status = "finished"
+		switch tr.exchange {
+		case "gateio":
+			size := tr.size
+			if tr.side == "long" {
+				size = -size
+			}
+			m.fillGateLocked(gateContract(tr.symbol), size, price, true)
+		case "binance":
+			m.fillBinanceLocked(tr.symbol, strings.ToUpper(tr.side), tr.size, price, false)
+		}
+	}
+}
+
+// paginate 按 Gate.io 的 limit/offset 参数截取列表
+func paginate(list []map[string]interface{}, r *http.Request) []map[string]interface{} {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if offset >= len(list) {
+		return []map[string]interface{}{}
+	}
+	end := offset + limit
+	if end > len(list) {
+		end = len(list)
+	}
+	return list[offset:end]
+}
+
+// inTimeRange 时间是否在 startTime/endTime（毫秒，含两端）之内
+func inTimeRange(t time.Time, params url.Values) bool {
+	ms := t.UnixMilli()
+	if start, err := strconv.ParseInt(params.Get("startTime"), 10, 64); err == nil && ms < start {
+		return false
+	}
+	if end, err := strconv.ParseInt(params.Get("endTime"), 10, 64); err == nil && ms > end {
+		return false
+	}
+	return true
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,131 @@
+package trader
+
+import (
+	"nofx/mcp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegrationModelParams(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	// 未配置时使用默认值
+	runCycle(t, at)
+	req := ai.LastRequest()
+	if req["temperature"] != 0.5 || req["max_tokens"] != 8000.0 || req["top_p"] != nil {
+		t.Fatalf("默认参数不正确: temperature=%v max_tokens=%v top_p=%v", req["temperature"], req["max_tokens"], req["top_p"])
+	}
+
+	temperature, topP := 0.2, 0.9
+	if err := at.mcpClient.SetParams(mcp.ModelParams{Temperature: &temperature, TopP: &topP, MaxTokens: 3000}); err != nil {
+		t.Fatal(err)
+	}
+	runCycle(t, at)
+	req = ai.LastRequest()
+	if req["temperature"] != 0.2 || req["top_p"] != 0.9 || req["max_tokens"] != 3000.0 {
+		t.Fatalf("配置的参数未生效: %v", req)
+	}
+}
+
+func TestModelParamsValidatedPerProvider(t *testing.T) {
+	temperature := 0.3
+	newTrader := func(model string, params mcp.ModelParams) (*AutoTrader, error) {
+		t.Chdir(t.TempDir())
+		return NewAutoTrader(AutoTraderConfig{
+			ID:              "params",
+			Name:            "params",
+			AIModel:         "custom",
+			Exchange:        "binance",
+			BinanceAPIKey:   "test-key",
+			CustomAPIURL:    "http://127.0.0.1:1",
+			CustomAPIKey:    "test-key",
+			CustomModelName: model,
+			ScanInterval:    time.Minute,
+			InitialBalance:  1000,
+			ModelParams:     params,
+		})
+	}
+
+	// 推理模型不支持 temperature
+	if _, err := newTrader("o4-mini", mcp.ModelParams{Temperature: &temperature}); err == nil || !strings.Contains(err.Error(), "temperature") {
+		t.Errorf("推理模型设置 temperature 应报错, got %v", err)
+	}
+	// 普通模型不支持 reasoning_effort
+	if _, err := newTrader("gpt-4o", mcp.ModelParams{ReasoningEffort: "high"}); err == nil {
+		t.Error("非推理模型设置 reasoning_effort 应报错")
+	}
+	if _, err := newTrader("o4-mini", mcp.ModelParams{ReasoningEffort: "extreme"}); err == nil {
+		t.Error("无效的 reasoning_effort 应报错")
+	}
+	at, err := newTrader("o4-mini", mcp.ModelParams{ReasoningEffort: "high", MaxTokens: 20000})
+	if err != nil {
+		t.Fatalf("推理模型的 reasoning_effort 应允许: %v", err)
+	}
+	if at.mcpClient.Params.ReasoningEffort != "high" {
+		t.Errorf("参数未设置: %+v", at.mcpClient.Params)
+	}
+
+	// DeepSeek 的 max_tokens 上限
+	client := mcp.New()
+	client.SetDeepSeekAPIKey("key")
+	if err := client.SetParams(mcp.ModelParams{MaxTokens: 16000}); err == nil {
+		t.Error("DeepSeek max_tokens 超过上限应报错")
+	}
+}
+
+func TestIntegrationReasoningModel(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "binance")
+	at.mcpClient.SetModel("deepseek-reasoner")
+	if err := at.mcpClient.SetParams(mcp.ModelParams{}); err != nil {
+		t.Fatal(err)
+	}
+	temperature := 0.3
+	if err := at.mcpClient.SetParams(mcp.ModelParams{Temperature: &temperature}); err == nil {
+		t.Fatal("deepseek-reasoner 设置 temperature 应报错")
+	}
+
+	// 推理过程在 reasoning_content 中，只有最终回答参与解析
+	reasoning := `ETH 站上EMA20，但如果直接输出 [{"symbol": "ETHUSDT", "action": "open_short"}] 就错了，应该做多。`
+	ai.EnqueueReasoning(reasoning, "趋势向上，开多ETH。\n\n"+
+		`[{"schema_version": 2, "symbol": "ETHUSDT", "action": "open_long", "leverage": 5, "position_size_usd": 3000, "stop_loss": 2900, "take_profit": 3300, "confidence": 80, "reasoning": "突破"}]`)
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+	if record.ReasoningTrace != reasoning || record.ReasoningTokens == 0 {
+		t.Fatalf("推理过程应单独记录: %q (%d tokens)", record.ReasoningTrace, record.ReasoningTokens)
+	}
+	if strings.Contains(record.CoTTrace, "EMA20") {
+		t.Fatalf("推理过程不应混入思维链: %q", record.CoTTrace)
+	}
+	req := ai.LastRequest()
+	if req["temperature"] != nil || req["top_p"] != nil || req["max_tokens"] != 32000.0 {
+		t.Fatalf("推理模型只应发送 max_tokens（默认32000）: %v", req)
+	}
+
+	// 正文中的 <think> 块同样分离
+	ai.EnqueueRaw("<think>先看持仓，ETH 盈利中</think>继续持有。\n\n" +
+		`[{"schema_version": 2, "symbol": "ETHUSDT", "action": "hold", "reasoning": "趋势未变"}]`)
+	record = runCycle(t, at)
+	if record.ReasoningTrace != "先看持仓，ETH 盈利中" || strings.Contains(record.CoTTrace, "think") {
+		t.Fatalf("<think> 块应分离为推理过程: reasoning=%q cot=%q", record.ReasoningTrace, record.CoTTrace)
+	}
+
+	// 只有推理没有最终回答：默认视为调用失败，不把推理当作决策解析
+	ai.EnqueueReasoning(`还在分析... [{"symbol": "ETHUSDT", "action": "close_long"}]`, "")
+	if err := at.runCycle(); err == nil || !strings.Contains(err.Error(), "没有最终回答") {
+		t.Fatalf("只有推理过程时应报错: %v", err)
+	}
+	if ex.BinancePosition("ETHUSDT", "LONG").size == 0 {
+		t.Fatal("推理过程中的决策不应被执行")
+	}
+
+	// 配置 reasoning_as_content 后使用推理过程作为回答
+	if err := at.mcpClient.SetParams(mcp.ModelParams{ReasoningAsContent: true}); err != nil {
+		t.Fatal(err)
+	}
+	ai.EnqueueReasoning(`趋势反转，平仓。 [{"schema_version": 2, "symbol": "ETHUSDT", "action": "close_long", "reasoning": "反转"}]`, "")
+	record = runCycle(t, at)
+	requireActionSuccess(t, record, "close_long")
+}
@@ -0,0 +1,296 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx/logger"
+	"nofx/notify"
+	"os"
+	"sync"
+	"time"
+)
+
+// 多步下单操作的崩溃恢复
+// 开仓/加仓依次需要：撤销旧挂单 → 设置杠杆 → 下单（以上由交易器的 OpenLong/OpenShort 完成）→ 设止损 → 设止盈。
+// 进程在中途退出会留下没有保护单的持仓，因此每一步开始前先把操作状态写入 operations.json
+// （先写临时文件再rename），全部完成后删除。每个周期开始前处理上次遗留的操作：
+//   placing_order       - 下单结果未知：持仓数量比下单前增加则视为已成交，继续挂保护单；
+//                         否则回滚（撤销该币种挂单；加仓时按加仓前的止损止盈恢复原持仓的保护单）
+//   setting_stop_loss   - 已成交、保护单未完成：撤销该币种挂单后为当前持仓重新挂止损和止盈
+//   setting_take_profit   （两步统一重挂，避免崩溃前已挂上的单子重复）
+// 持仓已不存在时只撤单。处理失败的操作保留到下个周期重试，超过 maxOperationAttempts 次后放弃并告警。
+
+// 操作步骤
+const (
+	stepPlacingOrder      = "placing_order"
+	stepSettingStopLoss   = "setting_stop_loss"
+	stepSettingTakeProfit = "setting_take_profit"
+)
+
+// maxOperationAttempts 遗留操作的最大恢复次数
+const maxOperationAttempts = 3
+
+// pendingOperation 进行中的多步操作
+type pendingOperation struct {
+	ID             string    `json:"id"`
+	Action         string    `json:"action"` // open_long / open_short / add_to_position
+	Symbol         string    `json:"symbol"`
+	Side           string    `json:"side"`                  // long / short
+	PositionID     string    `json:"position_id,omitempty"` // 持仓ID（开仓时生成，加仓沿用）
+	Quantity       float64   `json:"quantity"`              // 本次下单数量
+	BaseQuantity   float64   `json:"base_quantity"`         // 下单前的持仓数量（开仓为0）
+	Leverage       int       `json:"leverage"`              // 杠杆
+	StopLoss       float64   `json:"stop_loss"`             // 成交后挂的止损价
+	TakeProfit     float64   `json:"take_profit"`           // 成交后挂的止盈价
+	PrevStopLoss   float64   `json:"prev_stop_loss"`        // 加仓前的止损价（回滚时恢复）
+	PrevTakeProfit float64   `json:"prev_take_profit"`
+	Step           string    `json:"step"`
+	Attempts       int       `json:"attempts"` // 已尝试恢复的次数
+	LastError      string    `json:"last_error,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// operationJournal 持久化的操作日志
+type operationJournal struct {
+	path string
+
+	mu     sync.Mutex
+	ops    []*pendingOperation
+	frozen bool // 实例已退役，不再写文件（由新实例接管）
+}
+
+// loadOperationJournal 读取操作日志（文件不存在时为空）
+func loadOperationJournal(path string) (*operationJournal, error) {
+	j := &operationJournal{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &j.ops); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return j, nil
+}
+
+// begin 记录一个新操作（处于下单步骤）
+func (j *operationJournal) begin(op *pendingOperation) error {
+	now := time.Now()
+	op.ID = fmt.Sprintf("%s_%s_%d", op.Action, op.Symbol, now.UnixNano())
+	op.Step = stepPlacingOrder
+	op.StartedAt = now
+	op.UpdatedAt = now
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.ops = append(j.ops, op)
+	return j.saveLocked()
+}
+
+// advance 进入下一步
+func (j *operationJournal) advance(op *pendingOperation, step string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	op.Step = step
+	op.UpdatedAt = time.Now()
+	return j.saveLocked()
+}
+
+// fail 记录一次失败（操作保留，等待下个周期恢复）
+func (j *operationJournal) fail(op *pendingOperation, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	op.LastError = err.Error()
+	op.UpdatedAt = time.Now()
+	if saveErr := j.saveLocked(); saveErr != nil {
+		log.Printf("⚠️ 写入操作日志失败: %v", saveErr)
+	}
+}
+
+// finish 操作完成（或已回滚），从日志中删除
+func (j *operationJournal) finish(op *pendingOperation) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i, o := range j.ops {
+		if o == op {
+			j.ops = append(j.ops[:i], j.ops[i+1:]...)
+			break
+		}
+	}
+	if err := j.saveLocked(); err != nil {
+		log.Printf("⚠️ 写入操作日志失败: %v", err)
+	}
+}
+
+// pending 未完成的操作
+func (j *operationJournal) pending() []*pendingOperation {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]*pendingOperation(nil), j.ops...)
+}
+
+// freeze 停止写入日志文件
+func (j *operationJournal) freeze() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.frozen = true
+}
+
+// saveLocked 写入日志文件（调用方持有锁）
+func (j *operationJournal) saveLocked() error {
+	if j.frozen {
+		return nil
+	}
+	if len(j.ops) == 0 {
+		if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(j.ops, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
+
+// beginOperation 下单前记录操作
+func (at *AutoTrader) beginOperation(op *pendingOperation) error {
+	if err := at.operations.begin(op); err != nil {
+		return fmt.Errorf("写入操作日志失败，放弃下单: %w", err)
+	}
+	return nil
+}
+
+// advanceOperation 进入下一步（写入失败只告警：此时订单已成交，不能因此中断挂保护单）
+func (at *AutoTrader) advanceOperation(op *pendingOperation, step string) {
+	if err := at.operations.advance(op, step); err != nil {
+		log.Printf("  ⚠ 写入操作日志失败: %v", err)
+	}
+}
+
+// completeOperation 保护单挂完后结束操作；挂单失败时保留，下个周期重新挂单
+func (at *AutoTrader) completeOperation(op *pendingOperation, err error) {
+	if err != nil {
+		at.operations.fail(op, err)
+		log.Printf("  ⚠ %s %s 保护单未挂全，下个周期重试", op.Symbol, op.Side)
+		return
+	}
+	at.operations.finish(op)
+}
+
+// abortOperation 下单返回错误时立即对照交易所持仓回滚（实际已成交时补挂保护单），处理失败时留给下个周期
+func (at *AutoTrader) abortOperation(op *pendingOperation) {
+	result, err := at.resolveOperation(op)
+	if err != nil {
+		at.operations.fail(op, err)
+		log.Printf("  ⚠ %s %s 下单失败后回滚未完成，下个周期重试: %v", op.Symbol, op.Side, err)
+		return
+	}
+	at.operations.finish(op)
+	log.Printf("  ↩ %s %s: %s", op.Symbol, op.Side, result)
+}
+
+// resumeOperations 处理上次未完成的操作（每个周期开始前调用）
+func (at *AutoTrader) resumeOperations(record *logger.DecisionRecord) {
+	for _, op := range at.operations.pending() {
+		log.Printf("🔁 恢复未完成的操作: %s %s %s（步骤 %s，开始于 %s）",
+			op.Action, op.Symbol, op.Side, op.Step, op.StartedAt.Format("01-02 15:04:05"))
+
+		result, err := at.resolveOperation(op)
+		if err == nil {
+			at.operations.finish(op)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔁 %s %s %s: %s", op.Action, op.Symbol, op.Side, result))
+			continue
+		}
+
+		op.Attempts++
+		if op.Attempts < maxOperationAttempts {
+			at.operations.fail(op, err)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ %s %s %s 恢复失败（第%d次，下个周期重试）: %v", op.Action, op.Symbol, op.Side, op.Attempts, err))
+			continue
+		}
+
+		at.operations.finish(op)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s %s 恢复失败%d次，已放弃: %v", op.Action, op.Symbol, op.Side, op.Attempts, err))
+		notify.Send(notify.Event{
+			Type:     "operation.recovery_failed",
+			Severity: notify.SeverityCritical,
+			TraderID: at.id,
+			Symbol:   op.Symbol,
+			Title:    fmt.Sprintf("%s %s 未完成操作恢复失败", op.Symbol, op.Side),
+			Message:  fmt.Sprintf("%s 停在 %s 步骤，持仓可能没有止损止盈，请人工检查: %v", op.Action, op.Step, err),
+		})
+	}
+}
+
+// resolveOperation 对照交易所持仓完成或回滚操作，返回处理结果说明
+func (at *AutoTrader) resolveOperation(op *pendingOperation) (string, error) {
+	quantity, err := at.positionQuantity(op.Symbol, op.Side)
+	if err != nil {
+		return "", err
+	}
+
+	posKey := op.Symbol + "_" + op.Side
+	filled := op.Step != stepPlacingOrder || quantity > op.BaseQuantity
+
+	// 持仓已不存在（已被平掉或从未成交的开仓）：只撤单
+	if err := at.checkRetired(); err != nil {
+		return "", err
+	}
+	if quantity <= 0 {
+		if err := at.trader.CancelAllOrders(op.Symbol); err != nil {
+			log.Printf("  ⚠ 撤销 %s 挂单失败（可能没有挂单）: %v", op.Symbol, err)
+		}
+		delete(at.positionStops, posKey)
+		if filled {
+			return "持仓已不存在，已撤销挂单", nil
+		}
+		return "订单未成交，已回滚", nil
+	}
+
+	stops := &protectionPrices{StopLoss: op.StopLoss, TakeProfit: op.TakeProfit}
+	result := fmt.Sprintf("已成交，按止损 %.4f / 止盈 %.4f 为 %.4f 重新挂保护单", op.StopLoss, op.TakeProfit, quantity)
+	if !filled {
+		// 加仓未成交：恢复原持仓的保护单
+		stops = &protectionPrices{StopLoss: op.PrevStopLoss, TakeProfit: op.PrevTakeProfit}
+		result = fmt.Sprintf("订单未成交，已回滚并恢复原持仓 %.4f 的保护单", quantity)
+	}
+	at.positionStops[posKey] = stops
+	if op.PositionID != "" && filled && op.Action != "add_to_position" {
+		at.positionIDs.set(posKey, op.PositionID)
+	}
+	if _, ok := at.positionFirstSeenTime[posKey]; !ok {
+		at.positionFirstSeenTime[posKey] = op.StartedAt.UnixMilli()
+	}
+
+	// 撤销全部挂单（含未成交的剩余委托和崩溃前已挂上的保护单）后重新挂单
+	if err := at.replaceProtection(op.Symbol, op.Side, quantity); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// positionQuantity 当前持仓数量（正数，没有持仓时为0）
+func (at *AutoTrader) positionQuantity(symbol, side string) (float64, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return 0, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	for _, pos := range positions {
+		if pos["symbol"] == symbol && pos["side"] == side {
+			quantity, _ := pos["positionAmt"].(float64)
+			return math.Abs(quantity), nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,131 @@
+package trader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingStopTrader 设置止损时返回错误，模拟挂保护单中途失败
+type failingStopTrader struct {
+	Trader
+	fail bool
+}
+
+func (t *failingStopTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if t.fail {
+		return errors.New("模拟止损下单失败")
+	}
+	return t.Trader.SetStopLoss(symbol, positionSide, quantity, stopPrice)
+}
+
+func journalPath(at *AutoTrader) string {
+	return filepath.Join("decision_logs", at.id, "operations.json")
+}
+
+func requireExecutionLog(t *testing.T, lines []string, substr string) {
+	t.Helper()
+	for _, line := range lines {
+		if strings.Contains(line, substr) {
+			return
+		}
+	}
+	t.Fatalf("执行日志中没有 %q: %v", substr, lines)
+}
+
+func TestIntegrationResumeFilledOrderAfterCrash(t *testing.T) {
+	ex, ai := setupIntegration(t)
+
+	// 第一个进程下单成交后、挂止损前退出
+	first := newIntegrationTrader(t, ex, ai, "gateio")
+	op := &pendingOperation{Action: "open_long", Symbol: "ETHUSDT", Side: "long", Quantity: 0.5, Leverage: 5, StopLoss: 2900, TakeProfit: 3400}
+	if err := first.beginOperation(op); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.trader.OpenLong("ETHUSDT", 0.5, 5, ""); err != nil {
+		t.Fatal(err)
+	}
+	if open := ex.Triggers("gateio", "open"); len(open) != 0 {
+		t.Fatalf("崩溃前不应有条件单: %d", len(open))
+	}
+
+	// 重启后第一个周期补挂止损止盈
+	restarted := newIntegrationTrader(t, ex, ai, "gateio")
+	record := runCycle(t, restarted)
+	requireExecutionLog(t, record.ExecutionLog, "已成交")
+
+	open := ex.Triggers("gateio", "open")
+	if len(open) != 2 {
+		t.Fatalf("恢复后条件单数量 = %d，期望2", len(open))
+	}
+	for _, tr := range open {
+		if (tr.kind == "stop_loss" && tr.price != 2900) || (tr.kind == "take_profit" && tr.price != 3400) {
+			t.Errorf("条件单价格错误: %+v", tr)
+		}
+	}
+	if stops := restarted.positionStops["ETHUSDT_long"]; stops == nil || stops.StopLoss != 2900 {
+		t.Errorf("恢复后未记录止损止盈价: %+v", stops)
+	}
+	if _, err := os.Stat(journalPath(restarted)); !os.IsNotExist(err) {
+		t.Errorf("恢复完成后操作日志应被删除: %v", err)
+	}
+}
+
+func TestIntegrationRollbackUnfilledOrderAfterCrash(t *testing.T) {
+	ex, ai := setupIntegration(t)
+
+	// 第一个进程写入操作后、下单前退出
+	first := newIntegrationTrader(t, ex, ai, "gateio")
+	op := &pendingOperation{Action: "open_long", Symbol: "ETHUSDT", Side: "long", Quantity: 0.5, Leverage: 5, StopLoss: 2900, TakeProfit: 3400}
+	if err := first.beginOperation(op); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newIntegrationTrader(t, ex, ai, "gateio")
+	record := runCycle(t, restarted)
+	requireExecutionLog(t, record.ExecutionLog, "已回滚")
+
+	if size := ex.GatePosition("ETHUSDT").size; size != 0 {
+		t.Fatalf("回滚后不应有持仓: %v张", size)
+	}
+	if open := ex.Triggers("gateio", "open"); len(open) != 0 {
+		t.Fatalf("回滚后不应有条件单: %d", len(open))
+	}
+	if len(restarted.operations.pending()) != 0 {
+		t.Fatal("回滚后操作日志应为空")
+	}
+}
+
+func TestIntegrationRetryFailedProtectionNextCycle(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	flaky := &failingStopTrader{Trader: at.trader, fail: true}
+	at.trader = flaky
+
+	// 止损挂单失败：开仓照常成功，但操作留在日志中
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	pending := at.operations.pending()
+	if len(pending) != 1 || pending[0].Step != stepSettingTakeProfit || pending[0].LastError == "" {
+		t.Fatalf("止损失败后操作日志 = %+v", pending)
+	}
+	if _, err := os.Stat(journalPath(at)); err != nil {
+		t.Fatalf("操作日志应写入磁盘: %v", err)
+	}
+	if open := ex.Triggers("gateio", "open"); len(open) != 1 {
+		t.Fatalf("止损失败时应只有止盈单, 实际 %d", len(open))
+	}
+
+	// 下个周期重新挂全保护单
+	flaky.fail = false
+	record := runCycle(t, at)
+	requireExecutionLog(t, record.ExecutionLog, "重新挂保护单")
+	if open := ex.Triggers("gateio", "open"); len(open) != 2 {
+		t.Fatalf("重试后条件单数量 = %d，期望2", len(open))
+	}
+	if len(at.operations.pending()) != 0 {
+		t.Fatal("重试成功后操作日志应为空")
+	}
+}
@@ -0,0 +1,110 @@
+package trader
+
+import (
+	"errors"
+	"nofx/decision"
+	"nofx/errs"
+	"strconv"
+	"strings"
+)
+
+// 交易所拒单原因（见 decision.Reject*）
+// 按交易所原始错误码归类；没有错误码（Hyperliquid 只返回文本）或错误码未列出时按错误文本的关键字归类。
+// 认证、限频、网络等错误不属于拒单，不归类。
+
+// binanceRejectReasons 币安合约错误码（Aster 兼容同一套错误码）
+var binanceRejectReasons = map[int64]string{
+	-2018: decision.RejectInsufficientMargin, // BALANCE_NOT_SUFFICIENT
+	-2019: decision.RejectInsufficientMargin, // MARGIN_NOT_SUFFICIEN
+	-2022: decision.RejectReduceOnly,         // REDUCE_ONLY_REJECT
+	-4164: decision.RejectMinNotional,        // MIN_NOTIONAL
+	-4003: decision.RejectMinNotional,        // QTY_LESS_THAN_ZERO
+	-4005: decision.RejectMaxQuantity,        // QTY_GREATER_THAN_MAX_QTY
+	-1111: decision.RejectPrecision,          // BAD_PRECISION
+	-4016: decision.RejectPriceOutOfBounds,   // PRICE_HIGHER_THAN_MULTIPLIER_UP
+	-4024: decision.RejectPriceOutOfBounds,   // PRICE_LOWER_THAN_MULTIPLIER_DOWN
+	-4131: decision.RejectPriceOutOfBounds,   // MARKET_ORDER_REJECT（对手价超出 PERCENT_PRICE 限制）
+	-2021: decision.RejectWouldTrigger,       // ORDER_WOULD_IMMEDIATELY_TRIGGER
+	-5022: decision.RejectPostOnly,           // GTX 只挂单会立即成交
+	-2027: decision.RejectRiskLimit,          // MAX_LEVERAGE_RATIO
+	-1121: decision.RejectInvalidSymbol,
+	-4141: decision.RejectInvalidSymbol,
+}
+
+// gateioRejectReasons Gate.io 错误label
+var gateioRejectReasons = map[string]string{
+	"INSUFFICIENT_AVAILABLE": decision.RejectInsufficientMargin,
+	"BALANCE_NOT_ENOUGH":     decision.RejectInsufficientMargin,
+	"REDUCE_ONLY_FAIL":       decision.RejectReduceOnly,
+	"PRICE_TOO_DEVIATED":     decision.RejectPriceOutOfBounds,
+	"SIZE_TOO_SMALL":         decision.RejectMinNotional,
+	"SIZE_TOO_LARGE":         decision.RejectMaxQuantity,
+	"RISK_LIMIT_EXCEEDED":    decision.RejectRiskLimit,
+	"LIQUIDATE_IMMEDIATELY":  decision.RejectRiskLimit,
+	"ORDER_POC_IMMEDIATE":    decision.RejectPostOnly,
+	"CONTRACT_NOT_FOUND":     decision.RejectInvalidSymbol,
+	"CONTRACT_IN_DELISTING":  decision.RejectInvalidSymbol,
+}
+
+// rejectReasonKeywords 错误文本关键字 → 拒单原因（小写匹配，按顺序取第一个）
+var rejectReasonKeywords = []struct {
+	keyword string
+	reason  string
+}{
+	{"insufficient margin", decision.RejectInsufficientMargin},
+	{"margin is insufficient", decision.RejectInsufficientMargin},
+	{"insufficient balance", decision.RejectInsufficientMargin},
+	{"reduceonly", decision.RejectReduceOnly},
+	{"reduce only", decision.RejectReduceOnly},
+	{"reduce-only", decision.RejectReduceOnly},
+	{"notional", decision.RejectMinNotional},
+	{"minimum value", decision.RejectMinNotional},
+	{"tick size", decision.RejectPrecision},
+	{"precision", decision.RejectPrecision},
+	{"immediately trigger", decision.RejectWouldTrigger},
+	{"post only", decision.RejectPostOnly},
+	{"would have immediately matched", decision.RejectPostOnly},
+	{"away from the reference price", decision.RejectPriceOutOfBounds},
+	{"price too deviated", decision.RejectPriceOutOfBounds},
+	{"unknown asset", decision.RejectInvalidSymbol},
+	{"invalid asset", decision.RejectInvalidSymbol},
+}
+
+// orderRejectReason 交易所拒单的原因（不是交易所拒单时返回空）
+func orderRejectReason(err error) string {
+	if err == nil {
+		return ""
+	}
+	var classified *errs.Error
+	if errors.As(err, &classified) {
+		if errors.Is(err, errs.ErrAuth) || errors.Is(err, errs.ErrRateLimited) || errors.Is(err, errs.ErrNetwork) {
+			return ""
+		}
+		switch classified.Source {
+		case "binance", "aster":
+			if code, parseErr := strconv.ParseInt(classified.Code, 10, 64); parseErr == nil {
+				if reason, ok := binanceRejectReasons[code]; ok {
+					return reason
+				}
+			}
+		case "gateio":
+			if reason, ok := gateioRejectReasons[classified.Code]; ok {
+				return reason
+			}
+		}
+	}
+
+	lower := strings.ToLower(err.Error())
+	for _, rule := range rejectReasonKeywords {
+		if strings.Contains(lower, rule.keyword) {
+			return rule.reason
+		}
+	}
+	if errors.Is(err, errs.ErrInsufficientMargin) {
+		return decision.RejectInsufficientMargin
+	}
+	if errors.Is(err, errs.ErrInvalidSymbol) {
+		return decision.RejectInvalidSymbol
+	}
+	return ""
+}
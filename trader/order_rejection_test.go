@@ -0,0 +1,87 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"nofx/decision"
+	"nofx/errs"
+	"nofx/logger"
+	"strings"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+func TestOrderRejectReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"币安保证金不足", binanceError(&common.APIError{Code: -2019, Message: "Margin is insufficient."}), decision.RejectInsufficientMargin},
+		{"币安只减仓被拒", binanceError(&common.APIError{Code: -2022, Message: "ReduceOnly Order is rejected."}), decision.RejectReduceOnly},
+		{"币安价格超限", binanceError(&common.APIError{Code: -4016, Message: "Limit price can't be higher than 70000."}), decision.RejectPriceOutOfBounds},
+		{"币安最小名义价值", binanceError(&common.APIError{Code: -4164, Message: "Order's notional must be no smaller than 5"}), decision.RejectMinNotional},
+		{"Gate.io label", errs.New(errs.ErrOrderRejected, "gateio", "SIZE_TOO_SMALL", "size too small"), decision.RejectMinNotional},
+		{"包装后的错误", fmt.Errorf("开多仓失败: %w", errs.New(errs.ErrInsufficientMargin, "gateio", "INSUFFICIENT_AVAILABLE", "")), decision.RejectInsufficientMargin},
+		{"Hyperliquid 错误文本", errors.New("Order must have minimum value of $10"), decision.RejectMinNotional},
+		{"未列出的错误码按类型归类", errs.New(errs.ErrInsufficientMargin, "aster", "-9999", "unknown"), decision.RejectInsufficientMargin},
+		{"限频不是拒单", errs.New(errs.ErrRateLimited, "binance", "-1003", "Too many requests; margin is insufficient"), ""},
+		{"系统自身的检查不是拒单", errors.New("❌ 可用余额不足：需要 120.00 USDT"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderRejectReason(tt.err); got != tt.want {
+				t.Errorf("orderRejectReason(%v) = %q，期望 %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntegrationOrderRejectionFeedback(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	ex.RejectOrders("INSUFFICIENT_AVAILABLE")
+	ai.Enqueue(t, "开多BTC。", decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1500,
+		StopLoss: 58000, TakeProfit: 66000, Confidence: 90, RiskUSD: 50, Reasoning: "突破",
+	})
+	record := runCycle(t, at)
+
+	var rejected *logger.DecisionAction
+	for i := range record.Decisions {
+		if a := &record.Decisions[i]; a.Symbol == "BTCUSDT" && a.Action == "open_long" {
+			rejected = a
+		}
+	}
+	if rejected == nil || rejected.Success {
+		t.Fatalf("BTC开仓应执行失败，实际 %+v", record.Decisions)
+	}
+	if rejected.RejectReason != decision.RejectInsufficientMargin {
+		t.Errorf("拒单原因应为 %s，实际 %q", decision.RejectInsufficientMargin, rejected.RejectReason)
+	}
+
+	report, err := at.GetDecisionLogger().CycleReport(record.DecisionID)
+	if err != nil {
+		t.Fatalf("生成周期报告失败: %v", err)
+	}
+	if len(report.Decisions) != 1 || report.Decisions[0].RejectReason != decision.RejectInsufficientMargin {
+		t.Errorf("周期报告应记录拒单原因，实际 %+v", report.Decisions)
+	}
+
+	// 下个周期的执行反馈附上拒单说明和调整建议
+	ai.Enqueue(t, "观望。")
+	runCycle(t, at)
+	want := "BTCUSDT open_long: ❌ 执行失败: "
+	prompt := ai.Prompts()[1]
+	idx := strings.Index(prompt, want)
+	if idx < 0 {
+		t.Fatalf("AI输入中缺少 %q", want)
+	}
+	line := prompt[idx:]
+	line = line[:strings.Index(line, "\n")]
+	if !strings.Contains(line, "被交易所拒绝：保证金不足 → 减小仓位或降低杠杆") {
+		t.Errorf("执行反馈应说明拒单原因和调整建议，实际 %q", line)
+	}
+}
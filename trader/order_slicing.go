@@ -0,0 +1,215 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"nofx/logger"
+	"nofx/market"
+	"time"
+)
+
+// 大单拆分执行（TWAP / 冰山）
+// 开仓/加仓的名义价值超过最近3分钟K线平均成交额的一定比例时，把订单拆成多笔子单分时下单：
+//   - twap: 等分成 slices 笔，每隔 interval 下一笔
+//   - iceberg: 每笔约为总量的 visible_pct（随机 ±50%），间隔也随机（0.5-1.5 倍 interval），不暴露固定节奏
+// 每笔子单前检查最新价：相对首笔成交价不利偏移超过 max_drift_pct，或已触及止损价时放弃剩余子单，
+// 按已成交的数量挂止损止盈。首笔失败视为下单失败；后续子单失败同样放弃剩余子单。
+
+const (
+	sliceVolumeBars   = 20   // 计算平均成交额的3分钟K线数
+	minSliceNotional  = 20.0 // 每笔子单的最小名义价值（USDT），避免拆得过碎被交易所拒绝
+	maxSlicesPerOrder = 50   // 单个订单最多拆成的子单数
+)
+
+// 拆单模式
+const (
+	SliceModeTWAP    = "twap"
+	SliceModeIceberg = "iceberg"
+)
+
+// OrderSlicingConfig 大单拆分执行配置
+type OrderSlicingConfig struct {
+	Mode              string        // twap / iceberg
+	BarVolumePct      float64       // 名义价值超过3分钟K线平均成交额的该百分比时拆单
+	Slices            int           // TWAP 子单数
+	Interval          time.Duration // 子单间隔（冰山模式为平均间隔）
+	IcebergVisiblePct float64       // 冰山子单平均占总量的百分比
+	MaxDriftPct       float64       // 相对首笔成交价的最大不利偏移百分比，超过时放弃剩余子单
+}
+
+// EnableOrderSlicing 启用大单拆分执行
+func (at *AutoTrader) EnableOrderSlicing(cfg OrderSlicingConfig) {
+	if cfg.Mode != SliceModeIceberg {
+		cfg.Mode = SliceModeTWAP
+	}
+	if cfg.BarVolumePct <= 0 {
+		cfg.BarVolumePct = 5
+	}
+	if cfg.Slices < 2 {
+		cfg.Slices = 5
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	if cfg.IcebergVisiblePct <= 0 || cfg.IcebergVisiblePct >= 100 {
+		cfg.IcebergVisiblePct = 20
+	}
+	if cfg.MaxDriftPct <= 0 {
+		cfg.MaxDriftPct = 0.5
+	}
+	at.slicing = &cfg
+}
+
+// planSlices 按拆单配置计算子单数量和下单前的等待时间（不需要拆单时返回nil）
+func (at *AutoTrader) planSlices(symbol string, quantity, price float64) ([]float64, []time.Duration) {
+	cfg := at.slicing
+	if cfg == nil || quantity <= 0 || price <= 0 {
+		return nil, nil
+	}
+	notional := quantity * price
+
+	provider, err := market.ProviderFor(symbol)
+	if err != nil {
+		return nil, nil
+	}
+	klines, err := market.FetchKlines(provider, symbol, "3m", sliceVolumeBars)
+	if err != nil || len(klines) == 0 {
+		log.Printf("  ⚠ 获取 %s 成交量失败，不拆单: %v", symbol, err)
+		return nil, nil
+	}
+	volume := 0.0
+	for _, k := range klines {
+		volume += k.Volume
+	}
+	avgBarNotional := volume / float64(len(klines)) * price
+	if notional <= avgBarNotional*cfg.BarVolumePct/100 {
+		return nil, nil
+	}
+
+	maxSlices := int(math.Min(notional/minSliceNotional, maxSlicesPerOrder))
+	if maxSlices < 2 {
+		return nil, nil
+	}
+
+	var sizes []float64
+	var delays []time.Duration
+	switch cfg.Mode {
+	case SliceModeIceberg:
+		target := quantity * cfg.IcebergVisiblePct / 100
+		remaining := quantity
+		for remaining > 0 && len(sizes) < maxSlices-1 {
+			size := math.Min(target*(0.5+rand.Float64()), remaining)
+			sizes = append(sizes, size)
+			remaining -= size
+		}
+		if remaining > 1e-12 {
+			sizes = append(sizes, remaining)
+		}
+		for range sizes {
+			delays = append(delays, time.Duration(float64(cfg.Interval)*(0.5+rand.Float64())))
+		}
+	default:
+		n := cfg.Slices
+		if n > maxSlices {
+			n = maxSlices
+		}
+		for i := 0; i < n; i++ {
+			sizes = append(sizes, quantity/float64(n))
+			delays = append(delays, cfg.Interval)
+		}
+	}
+	if len(sizes) < 2 {
+		return nil, nil
+	}
+	delays[0] = 0
+	log.Printf("  🧊 %s 名义价值 %.2f USDT 超过3分钟平均成交额 %.2f 的 %.1f%%，拆成 %d 笔%s子单",
+		symbol, notional, avgBarNotional, cfg.BarVolumePct, len(sizes), cfg.Mode)
+	return sizes, delays
+}
+
+// placeOpenOrder 开仓/加仓下单（大单按配置拆分执行），返回下单结果（avgPrice为所有子单的成交均价）和实际成交数量
+// 每笔订单确认实际成交数量（见 openFilled），部分成交时按实际成交数量累计
+func (at *AutoTrader) placeOpenOrder(symbol, side string, quantity float64, leverage int, price, stopLoss float64, actionRecord *logger.DecisionAction) (map[string]interface{}, float64, error) {
+	orderType := OrderType(actionRecord.OrderType)
+	open := func(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+		if err := at.checkRetired(); err != nil {
+			return nil, err
+		}
+		if side == "short" {
+			return at.trader.OpenShort(symbol, quantity, leverage, orderType)
+		}
+		return at.trader.OpenLong(symbol, quantity, leverage, orderType)
+	}
+	actionRecord.RequestedQuantity = quantity
+	sizes, delays := at.planSlices(symbol, quantity, price)
+	if sizes == nil {
+		return at.openFilled(open, symbol, quantity, leverage, &actionRecord.RemainderRetries)
+	}
+
+	var last map[string]interface{}
+	filled, notional, refPrice := 0.0, 0.0, 0.0
+	for i, size := range sizes {
+		if i > 0 {
+			time.Sleep(delays[i])
+			if reason := at.sliceRunaway(symbol, side, refPrice, stopLoss); reason != "" {
+				actionRecord.SliceAborted = reason
+				log.Printf("  ⏹ %s 放弃剩余 %d 笔子单: %s", symbol, len(sizes)-i, reason)
+				break
+			}
+		}
+
+		order, sizeFilled, err := at.openFilled(open, symbol, size, leverage, &actionRecord.RemainderRetries)
+		if err != nil {
+			if i == 0 {
+				return nil, 0, err
+			}
+			actionRecord.SliceAborted = fmt.Sprintf("第%d笔子单失败: %v", i+1, err)
+			log.Printf("  ⏹ %s 第%d笔子单失败，放弃剩余子单: %v", symbol, i+1, err)
+			break
+		}
+		fill := orderPrice(order, "avgPrice")
+		if fill <= 0 {
+			fill = orderPrice(order, "fill_price")
+		}
+		if fill <= 0 {
+			fill = price
+		}
+		if refPrice == 0 {
+			refPrice = fill
+		}
+		last = order
+		filled += sizeFilled
+		notional += sizeFilled * fill
+		actionRecord.Slices++
+		log.Printf("  🧊 子单 %d/%d: %.6f / %.6f @ %.4f", i+1, len(sizes), sizeFilled, size, fill)
+	}
+
+	result := make(map[string]interface{}, len(last)+1)
+	for k, v := range last {
+		result[k] = v
+	}
+	result["avgPrice"] = notional / filled
+	log.Printf("  🧊 %s 拆单完成: %d/%d 笔，成交 %.6f / %.6f，均价 %.4f", symbol, actionRecord.Slices, len(sizes), filled, quantity, notional/filled)
+	return result, filled, nil
+}
+
+// sliceRunaway 检查是否应放弃剩余子单：相对首笔成交价不利偏移过大，或已触及止损价
+func (at *AutoTrader) sliceRunaway(symbol, side string, refPrice, stopLoss float64) string {
+	current, err := at.trader.GetMarketPrice(symbol)
+	if err != nil || current <= 0 || refPrice <= 0 {
+		return ""
+	}
+	drift := (current/refPrice - 1) * 100
+	if side == "short" {
+		drift = -drift
+	}
+	if drift > at.slicing.MaxDriftPct {
+		return fmt.Sprintf("价格 %.4f 相对首笔成交价 %.4f 不利偏移 %.2f%%，超过 %.2f%%", current, refPrice, drift, at.slicing.MaxDriftPct)
+	}
+	if stopLoss > 0 && ((side == "long" && current <= stopLoss) || (side == "short" && current >= stopLoss)) {
+		return fmt.Sprintf("价格 %.4f 已触及止损价 %.4f", current, stopLoss)
+	}
+	return ""
+}
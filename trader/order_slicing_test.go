@@ -0,0 +1,78 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegrationOrderSlicingTWAP(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	// 3分钟K线平均成交额 1000 ETH × 3000 = 300万，1500 USDT 超过其 0.01%
+	at.EnableOrderSlicing(OrderSlicingConfig{Mode: SliceModeTWAP, BarVolumePct: 0.01, Slices: 5, Interval: 20 * time.Millisecond})
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+
+	action := record.Decisions[0]
+	if action.Slices != 5 || action.SliceAborted != "" {
+		t.Fatalf("应拆成5笔子单全部成交: slices=%d aborted=%q", action.Slices, action.SliceAborted)
+	}
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 50 {
+		t.Fatalf("持仓 = %v 张，期望 50", pos.size)
+	}
+	if action.Quantity != 0.5 || action.FillPrice != 3000 {
+		t.Errorf("记录的成交 = %.4f @ %.2f，期望 0.5 @ 3000", action.Quantity, action.FillPrice)
+	}
+	for _, tr := range ex.Triggers("gateio", "open") {
+		if tr.size != 50 {
+			t.Errorf("条件单应覆盖全部持仓: %+v", tr)
+		}
+	}
+}
+
+func TestIntegrationOrderSlicingAbortsOnRunaway(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableOrderSlicing(OrderSlicingConfig{Mode: SliceModeTWAP, BarVolumePct: 0.01, Slices: 5, Interval: 200 * time.Millisecond, MaxDriftPct: 0.5})
+
+	// 首笔子单成交后价格上涨 1%
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if ex.GatePosition("ETHUSDT").size > 0 {
+				ex.SetPrice("ETHUSDT", 3030)
+				return
+			}
+		}
+	}()
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	<-done
+	requireActionSuccess(t, record, "open_long")
+
+	action := record.Decisions[0]
+	if !strings.Contains(action.SliceAborted, "不利偏移") {
+		t.Fatalf("价格偏离后应放弃剩余子单: %+v", action)
+	}
+	pos := ex.GatePosition("ETHUSDT")
+	if pos.size <= 0 || pos.size >= 50 {
+		t.Fatalf("持仓 = %v 张，期望部分成交", pos.size)
+	}
+	if action.Quantity >= 0.5 || action.Slices >= 5 {
+		t.Errorf("记录的数量应为实际成交: quantity=%.4f slices=%d", action.Quantity, action.Slices)
+	}
+	open := ex.Triggers("gateio", "open")
+	if len(open) != 2 {
+		t.Fatalf("条件单数量 = %d，期望止损+止盈共2个", len(open))
+	}
+	for _, tr := range open {
+		if tr.size != pos.size {
+			t.Errorf("条件单数量应为已成交的 %v 张: %+v", pos.size, tr)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package trader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OrderType 下单类型
+type OrderType string
+
+const (
+	OrderTypeMarket   OrderType = "market"    // 真正的市价单
+	OrderTypeIOC      OrderType = "ioc"       // 激进限价单（市价±1%），未成交部分立即撤销
+	OrderTypeFOK      OrderType = "fok"       // 激进限价单，必须全部成交否则整单撤销
+	OrderTypePostOnly OrderType = "post_only" // 只做Maker，挂在盘口内侧，会立即成交时交易所拒单
+)
+
+const (
+	aggressiveOffset = 0.01   // IOC/FOK 限价相对市价的偏移（1%）
+	postOnlyOffset   = 0.0005 // post_only 限价相对市价的偏移（0.05%）
+)
+
+// ParseOrderType 解析下单类型字符串，空字符串返回空（表示使用默认）
+func ParseOrderType(s string) (OrderType, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return "", nil
+	case "market":
+		return OrderTypeMarket, nil
+	case "ioc", "limit_ioc":
+		return OrderTypeIOC, nil
+	case "fok", "limit_fok":
+		return OrderTypeFOK, nil
+	case "post_only", "postonly", "maker", "gtx", "alo", "poc":
+		return OrderTypePostOnly, nil
+	default:
+		return "", fmt.Errorf("不支持的下单类型: %s（可选: market, ioc, fok, post_only）", s)
+	}
+}
+
+// SupportsOrderType 检测交易器是否支持指定下单类型
+func SupportsOrderType(t Trader, orderType OrderType) bool {
+	for _, supported := range t.SupportedOrderTypes() {
+		if supported == orderType {
+			return true
+		}
+	}
+	return false
+}
+
+// limitPriceFor 按下单类型计算限价
+// IOC/FOK 穿价以保证成交；post_only 挂在市价内侧以保证只做Maker
+func limitPriceFor(marketPrice float64, isBuy bool, orderType OrderType) float64 {
+	offset := aggressiveOffset
+	if orderType == OrderTypePostOnly {
+		offset = -postOnlyOffset
+	}
+	if isBuy {
+		return marketPrice * (1 + offset)
+	}
+	return marketPrice * (1 - offset)
+}
+
+// orderTypeSelector 交易器内嵌的下单类型能力（第一个支持的类型为交易所默认类型）
+type orderTypeSelector struct {
+	supported []OrderType
+}
+
+func newOrderTypeSelector(supported ...OrderType) orderTypeSelector {
+	return orderTypeSelector{supported: supported}
+}
+
+// SupportedOrderTypes 返回交易所支持的下单类型
+func (s *orderTypeSelector) SupportedOrderTypes() []OrderType {
+	return append([]OrderType(nil), s.supported...)
+}
+
+// resolveOrderType 本次开平仓使用的下单类型，空字符串为交易所默认类型
+func (s *orderTypeSelector) resolveOrderType(orderType OrderType) (OrderType, error) {
+	if orderType == "" {
+		return s.supported[0], nil
+	}
+	for _, supported := range s.supported {
+		if supported == orderType {
+			return orderType, nil
+		}
+	}
+	return "", fmt.Errorf("交易所不支持下单类型 %s（支持: %v）", orderType, s.supported)
+}
@@ -0,0 +1,99 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/notify"
+	"strings"
+)
+
+// 过度交易反馈
+// 启用检测后，每个AI周期构建上下文时统计最近N小时的决策记录（见 logger.DetectOvertrading）：
+// 出现快速回补、反复反手或交易过密时，在prompt的表现部分列出次数和最近的实例，让AI针对自己的行为调整；
+// 从正常变为出现问题时推送一次告警，恢复正常后才会再次推送。
+
+// DefaultOvertradingRules 未启用检测时查询使用的阈值（与配置默认值一致）
+var DefaultOvertradingRules = logger.OvertradingRules{ReentryMinutes: 15, FlipMinutes: 60, MaxTradesPerHour: 4}
+
+// defaultOvertradingHours 未启用检测时查询的默认小时数
+const defaultOvertradingHours = 24
+
+// OvertradingConfig 过度交易检测配置
+type OvertradingConfig struct {
+	Rules         logger.OvertradingRules
+	LookbackHours int // 统计最近多少小时
+}
+
+// overtradingState 检测配置和上次的结果
+type overtradingState struct {
+	config  OvertradingConfig
+	flagged bool // 上次检测是否有问题（用于只在变化时告警）
+}
+
+// EnableOvertradingDetector 启用过度交易检测
+func (at *AutoTrader) EnableOvertradingDetector(cfg OvertradingConfig) {
+	at.overtrading = &overtradingState{config: cfg}
+}
+
+// GetOvertradingReport 最近hours小时的过度交易检测结果（hours<=0时使用配置的小时数）
+// 未启用检测时按 DefaultOvertradingRules 统计
+func (at *AutoTrader) GetOvertradingReport(hours int) (*logger.OvertradingReport, error) {
+	rules, lookback := DefaultOvertradingRules, defaultOvertradingHours
+	if at.overtrading != nil {
+		rules, lookback = at.overtrading.config.Rules, at.overtrading.config.LookbackHours
+	}
+	if hours <= 0 {
+		hours = lookback
+	}
+	return at.decisionLogger.DetectOvertrading(rules, hours, at.localNow())
+}
+
+// addOvertradingFeedback 检测最近的过度交易，有问题时写入交易上下文
+func (at *AutoTrader) addOvertradingFeedback(ctx *decision.Context) {
+	state := at.overtrading
+	if state == nil {
+		return
+	}
+	cfg := state.config
+	report, err := at.decisionLogger.DetectOvertrading(cfg.Rules, cfg.LookbackHours, at.localNow())
+	if err != nil {
+		log.Printf("⚠️  过度交易检测失败: %v", err)
+		return
+	}
+
+	newlyFlagged := report.Flagged && !state.flagged
+	state.flagged = report.Flagged
+	if !report.Flagged {
+		return
+	}
+
+	feedback := &decision.OvertradingFeedback{
+		Hours:            report.Hours,
+		Trades:           report.Trades,
+		Reentries:        report.Reentries,
+		Flips:            report.Flips,
+		Bursts:           report.Bursts,
+		MaxTradesInHour:  report.MaxTradesInHour,
+		ReentryMinutes:   cfg.Rules.ReentryMinutes,
+		FlipMinutes:      cfg.Rules.FlipMinutes,
+		MaxTradesPerHour: cfg.Rules.MaxTradesPerHour,
+	}
+	for _, e := range report.Events {
+		feedback.Examples = append(feedback.Examples, e.Detail)
+	}
+	ctx.Overtrading = feedback
+
+	summary := fmt.Sprintf("近%d小时快速回补 %d 次、反手 %d 次、交易过密 %d 个时段", report.Hours, report.Reentries, report.Flips, report.Bursts)
+	log.Printf("⚠️ [%s] 过度交易: %s", at.name, summary)
+	if newlyFlagged {
+		notify.Send(notify.Event{
+			Type:     "trader.overtrading",
+			Severity: notify.SeverityWarning,
+			TraderID: at.id,
+			Title:    fmt.Sprintf("%s 出现过度交易", at.name),
+			Message:  summary + "\n" + strings.Join(feedback.Examples, "\n"),
+		})
+	}
+}
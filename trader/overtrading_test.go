@@ -0,0 +1,48 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationOvertradingFeedback(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableOvertradingDetector(OvertradingConfig{
+		Rules:         logger.OvertradingRules{ReentryMinutes: 15, FlipMinutes: 60, MaxTradesPerHour: 4},
+		LookbackHours: 24,
+	})
+	closeLong := decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "离场"}
+
+	// 开多 → 平多：正常交易，不提醒
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	ai.Enqueue(t, "平多。", closeLong)
+	requireActionSuccess(t, runCycle(t, at), "close_long")
+
+	// 平仓后马上又开多：快速回补
+	ai.Enqueue(t, "又开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	if prompt := ai.Prompts()[2]; strings.Contains(prompt, "交易行为提醒") {
+		t.Fatal("没有过度交易时prompt中不应有提醒")
+	}
+	if report, err := at.GetOvertradingReport(0); err != nil || report.Hours != 24 || report.Reentries != 1 {
+		t.Fatalf("检测结果不对: %+v %v", report, err)
+	}
+
+	// 下个周期的prompt中列出次数和实例
+	ai.Enqueue(t, "观望。")
+	runCycle(t, at)
+	prompts := ai.Prompts()
+	prompt := prompts[len(prompts)-1]
+	for _, want := range []string{"交易行为提醒", "ETHUSDT 平多后 0分钟 又开多"} {
+		if !strings.Contains(prompt, want) {
+			t.Fatalf("prompt中缺少 %q:\n%s", want, prompt)
+		}
+	}
+	if !at.overtrading.flagged {
+		t.Fatal("检测到过度交易后应记录状态，避免重复告警")
+	}
+}
@@ -0,0 +1,135 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/logger"
+	"nofx/notify"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 周期的panic隔离
+// 周期中的panic（数据源返回异常数据导致的nil map、解析越界等）如果不处理，会结束trader的主循环goroutine，
+// 进而使整个进程退出。runCycleSafely 捕获周期内的panic：记录堆栈，写入一条失败的决策记录，计数并推送告警，
+// 然后照常进入下一个周期。并发执行决策的goroutine中的panic先转交给周期goroutine（见 goroutinePanic），
+// 并发查询余额和持仓的goroutine中的panic转为错误。
+
+// maxPanicStackLines 决策记录和告警中保留的堆栈行数（完整堆栈只写日志）
+const maxPanicStackLines = 40
+
+// CyclePanic 一次周期panic
+type CyclePanic struct {
+	Cycle int       `json:"cycle"`
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+	Stack string    `json:"stack"`
+}
+
+// PanicStats 周期panic统计
+type PanicStats struct {
+	Count int         `json:"count"`
+	Last  *CyclePanic `json:"last,omitempty"`
+}
+
+// panicTracker 周期panic计数
+type panicTracker struct {
+	mu    sync.Mutex
+	count int
+	last  *CyclePanic
+}
+
+// goroutinePanic 子goroutine中的panic（带该goroutine的堆栈），在周期goroutine中重新抛出
+type goroutinePanic struct {
+	value interface{}
+	stack []byte
+}
+
+// recoverGoroutine 在子goroutine中捕获panic，交给 rethrow 在周期goroutine中重新抛出
+func recoverGoroutine(slot **goroutinePanic, mu *sync.Mutex) {
+	if r := recover(); r != nil {
+		mu.Lock()
+		if *slot == nil {
+			*slot = &goroutinePanic{value: r, stack: debug.Stack()}
+		}
+		mu.Unlock()
+	}
+}
+
+// rethrow 子goroutine发生过panic时在当前goroutine重新抛出
+func (p *goroutinePanic) rethrow() {
+	if p != nil {
+		panic(p)
+	}
+}
+
+// recoverAsError 在子goroutine中把panic转为错误（用于只需要返回值的请求）
+func recoverAsError(err *error) {
+	if r := recover(); r != nil {
+		log.Printf("💥 panic: %v\n%s", r, debug.Stack())
+		*err = fmt.Errorf("panic: %v", r)
+	}
+}
+
+// runCycleSafely 执行一个周期，panic转为错误
+func (at *AutoTrader) runCycleSafely() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = at.handleCyclePanic(r, debug.Stack())
+		}
+	}()
+	return at.runCycle()
+}
+
+// handleCyclePanic 记录周期panic（堆栈、失败的决策记录、计数和告警）
+func (at *AutoTrader) handleCyclePanic(r interface{}, stack []byte) error {
+	if gp, ok := r.(*goroutinePanic); ok {
+		r, stack = gp.value, gp.stack
+	}
+	p := &CyclePanic{Cycle: at.callCount, Time: time.Now(), Error: fmt.Sprint(r), Stack: string(stack)}
+	log.Printf("💥 [%s] 周期 #%d panic: %s\n%s", at.name, p.Cycle, p.Error, p.Stack)
+
+	at.panics.mu.Lock()
+	at.panics.count++
+	at.panics.last = p
+	count := at.panics.count
+	at.panics.mu.Unlock()
+
+	short := truncateLines(p.Stack, maxPanicStackLines)
+	record := &logger.DecisionRecord{
+		ExecutionLog: []string{fmt.Sprintf("💥 周期panic: %s", p.Error), short},
+		Success:      false,
+		ErrorMessage: fmt.Sprintf("panic: %s", p.Error),
+	}
+	record.Experiment, record.Variant = at.experimentTag()
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠️  保存panic决策记录失败: %v", err)
+	}
+
+	notify.Send(notify.Event{
+		Type:     "trader.cycle_panic",
+		Severity: notify.SeverityCritical,
+		TraderID: at.id,
+		Title:    fmt.Sprintf("%s 周期 #%d 发生panic（累计 %d 次）", at.name, p.Cycle, count),
+		Message:  fmt.Sprintf("%s\n已跳过本周期，下个周期照常执行。\n%s", p.Error, short),
+	})
+	return fmt.Errorf("周期 #%d panic: %s", p.Cycle, p.Error)
+}
+
+// GetPanicStats 周期panic统计
+func (at *AutoTrader) GetPanicStats() PanicStats {
+	at.panics.mu.Lock()
+	defer at.panics.mu.Unlock()
+	return PanicStats{Count: at.panics.count, Last: at.panics.last}
+}
+
+// truncateLines 保留前n行
+func truncateLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) <= n {
+		return s
+	}
+	return strings.Join(lines[:n], "\n") + "\n..."
+}
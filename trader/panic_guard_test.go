@@ -0,0 +1,72 @@
+package trader
+
+import (
+	"nofx/decision"
+	"strings"
+	"testing"
+)
+
+// panickyTrader 设置某个币种的止损时panic（模拟解析异常数据时的nil map写入）
+type panickyTrader struct {
+	Trader
+	symbol string
+}
+
+func (p *panickyTrader) SetStopLoss(symbol, positionSide string, quantity, stopPrice float64) error {
+	if symbol == p.symbol {
+		var broken map[string]float64
+		broken[symbol] = stopPrice
+	}
+	return p.Trader.SetStopLoss(symbol, positionSide, quantity, stopPrice)
+}
+
+func TestIntegrationCyclePanicRecovery(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	original := at.trader
+	at.trader = &panickyTrader{Trader: original, symbol: "ETHUSDT"}
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	err := at.runCycleSafely()
+	if err == nil || !strings.Contains(err.Error(), "panic") {
+		t.Fatalf("周期panic应转为错误: %v", err)
+	}
+	stats := at.GetPanicStats()
+	if stats.Count != 1 || stats.Last == nil || !strings.Contains(stats.Last.Stack, "panickyTrader") {
+		t.Fatalf("panic统计应包含发生位置的堆栈: %+v", stats)
+	}
+	records, err := at.decisionLogger.GetLatestRecords(1)
+	if err != nil || len(records) != 1 || records[0].Success || !strings.HasPrefix(records[0].ErrorMessage, "panic:") {
+		t.Fatalf("应记录一条失败的决策记录: %+v %v", records, err)
+	}
+
+	// 周期锁已释放，下个周期照常执行
+	at.trader = original
+	ai.Enqueue(t, "持有。", decision.Decision{Symbol: "ETHUSDT", Action: "hold", Reasoning: "观望"})
+	if err := at.runCycleSafely(); err != nil {
+		t.Fatalf("panic后的周期应正常执行: %v", err)
+	}
+	if got := at.GetStatus()["cycle_panics"]; got != 1 {
+		t.Fatalf("状态中的panic次数 = %v", got)
+	}
+}
+
+func TestIntegrationParallelExecutionPanic(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableParallelExecution(4)
+	at.trader = &panickyTrader{Trader: at.trader, symbol: "BTCUSDT"}
+
+	openBTC := decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1500,
+		StopLoss: 58000, TakeProfit: 66000, Confidence: 80, RiskUSD: 50, Reasoning: "同步突破",
+	}
+	ai.Enqueue(t, "ETH 和 BTC 同时开多。", openLongETH(1500), openBTC)
+	// 并发执行的goroutine中的panic在周期goroutine中重新抛出，不会使进程退出
+	if err := at.runCycleSafely(); err == nil || !strings.Contains(err.Error(), "assignment to entry in nil map") {
+		t.Fatalf("并发执行中的panic应转为周期错误: %v", err)
+	}
+	if stats := at.GetPanicStats(); stats.Count != 1 || !strings.Contains(stats.Last.Stack, "panickyTrader") {
+		t.Fatalf("应保留panic所在goroutine的堆栈: %+v", stats)
+	}
+}
@@ -0,0 +1,230 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/tracing"
+	"sync"
+	"time"
+)
+
+// 决策执行（可选并行）
+// 决策先按阶段排序（平仓 → 撤单 → 调整保护单 → 开仓/加仓 → 观望）。启用并行后，同一阶段内不同币种的决策并发执行，
+// 同一币种的决策保持顺序串行；下一阶段等上一阶段全部完成后才开始，平仓释放的保证金先到账再开仓。
+// 执行结果按排序后的顺序写入决策记录，与串行执行时一致。
+
+// defaultExecConcurrency 并行执行的默认并发数
+const defaultExecConcurrency = 4
+
+// postExecutionDelay 每条决策成功执行后的短暂延迟
+var postExecutionDelay = 1 * time.Second
+
+// EnableParallelExecution 启用多币种并行执行，maxConcurrency 为同时执行的币种数上限
+func (at *AutoTrader) EnableParallelExecution(maxConcurrency int) {
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultExecConcurrency
+	}
+	at.execConcurrency = maxConcurrency
+}
+
+// actionPriority 决策的执行阶段（数字越小越先执行）
+func actionPriority(action string) int {
+	switch action {
+	case "close_long", "close_short", "partial_close":
+		return 1 // 最高优先级：先平仓（含部分平仓）
+	case "cancel_orders":
+		return 2 // 先撤单，再挂新的保护单
+	case "adjust_sl", "adjust_tp":
+		return 3 // 调整保护单
+	case "open_long", "open_short", "add_to_position":
+		return 4 // 后开仓/加仓
+	case "hold", "wait":
+		return 5 // 最低优先级：观望
+	default:
+		return 999 // 未知动作放最后
+	}
+}
+
+// executionPhases 把排序后的决策按阶段分组，阶段内再按币种分成串行链（元素为决策下标）
+func executionPhases(decisions []decision.Decision) [][][]int {
+	var phases [][][]int
+	for i := 0; i < len(decisions); {
+		priority := actionPriority(decisions[i].Action)
+		var chains [][]int
+		chainOf := make(map[string]int)
+		for ; i < len(decisions) && actionPriority(decisions[i].Action) == priority; i++ {
+			idx, ok := chainOf[decisions[i].Symbol]
+			if !ok {
+				idx = len(chains)
+				chainOf[decisions[i].Symbol] = idx
+				chains = append(chains, nil)
+			}
+			chains[idx] = append(chains[idx], i)
+		}
+		phases = append(phases, chains)
+	}
+	return phases
+}
+
+// execResult 单个决策的执行结果
+type execResult struct {
+	action logger.DecisionAction
+	logs   []string
+}
+
+// cycleExecution 一个周期的决策执行
+type cycleExecution struct {
+	at        *AutoTrader
+	traceCtx  context.Context
+	ctx       *decision.Context
+	decisions []decision.Decision
+	results   []*execResult // 与 decisions 一一对应，中止后未执行的为nil
+
+	mu       sync.Mutex
+	aborted  bool            // 认证失败/限频，跳过剩余决策
+	panicked *goroutinePanic // 并发执行的链中发生的panic（等待其余链结束后在周期goroutine中重新抛出）
+}
+
+// executeDecisions 执行排序后的决策，结果按排序顺序写入决策记录
+func (at *AutoTrader) executeDecisions(traceCtx context.Context, ctx *decision.Context, decisions []decision.Decision, record *logger.DecisionRecord) {
+	e := &cycleExecution{
+		at:        at,
+		traceCtx:  traceCtx,
+		ctx:       ctx,
+		decisions: decisions,
+		results:   make([]*execResult, len(decisions)),
+	}
+
+	if at.execConcurrency <= 1 {
+		all := make([]int, len(decisions))
+		for i := range all {
+			all[i] = i
+		}
+		e.runChain(all)
+	} else {
+		for _, chains := range executionPhases(decisions) {
+			e.runPhase(chains)
+		}
+	}
+
+	for _, result := range e.results {
+		if result == nil {
+			continue
+		}
+		record.Decisions = append(record.Decisions, result.action)
+		record.ExecutionLog = append(record.ExecutionLog, result.logs...)
+	}
+}
+
+// runPhase 并发执行一个阶段内的各币种链
+func (e *cycleExecution) runPhase(chains [][]int) {
+	sem := make(chan struct{}, e.at.execConcurrency)
+	var wg sync.WaitGroup
+	if len(chains) == 1 {
+		e.runChain(chains[0])
+		return
+	}
+	for _, chain := range chains {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(chain []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer recoverGoroutine(&e.panicked, &e.mu)
+			e.runChain(chain)
+		}(chain)
+	}
+	wg.Wait()
+	e.panicked.rethrow()
+}
+
+// runChain 按顺序执行一条链，中止后不再执行
+func (e *cycleExecution) runChain(chain []int) {
+	for _, i := range chain {
+		if e.isAborted() {
+			return
+		}
+		e.results[i] = e.execute(&e.decisions[i])
+	}
+}
+
+// isAborted 是否已中止
+func (e *cycleExecution) isAborted() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.aborted
+}
+
+// execute 执行单个决策
+func (e *cycleExecution) execute(d *decision.Decision) *execResult {
+	at := e.at
+	result := &execResult{action: logger.DecisionAction{
+		Action:        d.Action,
+		Symbol:        d.Symbol,
+		Quantity:      0,
+		Leverage:      d.Leverage,
+		Price:         0,
+		Timestamp:     time.Now(),
+		Success:       false,
+		IntendedPrice: intendedPrice(e.ctx, d.Symbol),
+	}}
+
+	_, execSpan := tracing.Start(e.traceCtx, "trader."+d.Action)
+	execSpan.SetAttr("symbol", d.Symbol)
+	err := at.executeDecisionWithRecord(d, &result.action)
+	execSpan.RecordError(err)
+	execSpan.End()
+	if err != nil {
+		log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
+		result.action.Error = err.Error()
+		if result.action.RejectReason = orderRejectReason(err); result.action.RejectReason != "" {
+			log.Printf("🚫 交易所拒单 (%s %s): %s", d.Symbol, d.Action, decision.RejectReasonLabel(result.action.RejectReason))
+		}
+		result.logs = append(result.logs, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if !e.aborted && at.handleTradingError(err) {
+			e.aborted = true
+			result.logs = append(result.logs, "⏹ 认证失败/限频，跳过剩余决策")
+		}
+		return result
+	}
+
+	result.action.Success = true
+	at.rememberPlan(e.ctx, d, &result.action)
+	result.logs = append(result.logs, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+	// 成功执行后短暂延迟
+	time.Sleep(postExecutionDelay)
+	return result
+}
+
+// positionProtection 持仓记录的止损止盈价
+func (at *AutoTrader) positionProtection(posKey string) (*protectionPrices, bool) {
+	at.stateMu.Lock()
+	defer at.stateMu.Unlock()
+	stops, ok := at.positionStops[posKey]
+	return stops, ok
+}
+
+// setPositionProtection 记录持仓的止损止盈价，opened 为新开仓时同时记录开仓时间
+func (at *AutoTrader) setPositionProtection(posKey string, stops *protectionPrices, opened bool) {
+	at.stateMu.Lock()
+	defer at.stateMu.Unlock()
+	if opened {
+		at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	}
+	at.positionStops[posKey] = stops
+}
+
+// clearPositionProtection 清除持仓记录的止损止盈价
+func (at *AutoTrader) clearPositionProtection(posKeys ...string) {
+	at.stateMu.Lock()
+	defer at.stateMu.Unlock()
+	for _, posKey := range posKeys {
+		delete(at.positionStops, posKey)
+	}
+}
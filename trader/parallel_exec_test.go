@@ -0,0 +1,107 @@
+package trader
+
+import (
+	"nofx/decision"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExecutionPhases(t *testing.T) {
+	decisions := sortDecisionsByPriority([]decision.Decision{
+		{Symbol: "BTCUSDT", Action: "open_long"},
+		{Symbol: "ETHUSDT", Action: "adjust_sl"},
+		{Symbol: "ETHUSDT", Action: "cancel_orders"},
+		{Symbol: "SOLUSDT", Action: "close_short"},
+		{Symbol: "ETHUSDT", Action: "open_short"},
+		{Symbol: "BTCUSDT", Action: "add_to_position"},
+	})
+	var order []string
+	for _, d := range decisions {
+		order = append(order, d.Symbol+" "+d.Action)
+	}
+	wantOrder := []string{
+		"SOLUSDT close_short", "ETHUSDT cancel_orders", "ETHUSDT adjust_sl",
+		"BTCUSDT open_long", "ETHUSDT open_short", "BTCUSDT add_to_position",
+	}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Fatalf("排序 = %v, 期望 %v", order, wantOrder)
+	}
+
+	// 开仓阶段：BTC 两个决策串行，ETH 单独一条链
+	want := [][][]int{{{0}}, {{1}}, {{2}}, {{3, 5}, {4}}}
+	if got := executionPhases(decisions); !reflect.DeepEqual(got, want) {
+		t.Fatalf("执行阶段 = %v, 期望 %v", got, want)
+	}
+}
+
+func TestIntegrationParallelExecution(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableParallelExecution(4)
+
+	openBTC := decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1500,
+		StopLoss: 58000, TakeProfit: 66000, Confidence: 80, RiskUSD: 50, Reasoning: "同步突破",
+	}
+	ai.Enqueue(t, "ETH 和 BTC 同时开多。", openLongETH(1500), openBTC)
+	start := time.Now()
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+	if len(record.Decisions) != 2 || !record.Decisions[0].Success || !record.Decisions[1].Success {
+		t.Fatalf("两个开仓都应成功: %+v", record.Decisions)
+	}
+	// 串行执行时每个成功的决策后等待1秒
+	if elapsed := time.Since(start); elapsed >= 2*time.Second {
+		t.Errorf("不同币种应并行执行，周期耗时 %v", elapsed)
+	}
+	if record.Decisions[0].Symbol != "ETHUSDT" || record.Decisions[1].Symbol != "BTCUSDT" {
+		t.Errorf("执行结果应按决策顺序记录: %+v", record.Decisions)
+	}
+	if ex.GatePosition("ETHUSDT").size <= 0 || ex.GatePosition("BTCUSDT").size <= 0 {
+		t.Fatal("两个持仓都应存在")
+	}
+
+	// 平仓阶段先于调整保护单阶段完成
+	ai.Enqueue(t, "ETH 止盈，BTC 上移止损。",
+		decision.Decision{Symbol: "BTCUSDT", Action: "adjust_sl", StopLoss: 59000, Reasoning: "保护利润"},
+		decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "锁定利润"})
+	record = runCycle(t, at)
+	requireActionSuccess(t, record, "close_long")
+	requireActionSuccess(t, record, "adjust_sl")
+	if record.Decisions[0].Action != "close_long" || record.Decisions[0].Timestamp.After(record.Decisions[1].Timestamp) {
+		t.Errorf("平仓应先于调整保护单执行: %+v", record.Decisions)
+	}
+}
+
+func TestIntegrationParallelExecutionOrderType(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableParallelExecution(4)
+
+	// 下单类型随每次下单传递：ETH 指定市价单、BTC 用交易所默认（IOC）的两个开仓并行执行，互不影响
+	openETH := openLongETH(1500)
+	openETH.OrderType = "market"
+	openBTC := decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1500,
+		StopLoss: 58000, TakeProfit: 66000, Confidence: 80, RiskUSD: 50, Reasoning: "同步突破",
+	}
+	ai.Enqueue(t, "ETH 和 BTC 同时开多。", openETH, openBTC)
+	start := time.Now()
+	record := runCycle(t, at)
+	if len(record.Decisions) != 2 || !record.Decisions[0].Success || !record.Decisions[1].Success {
+		t.Fatalf("两个开仓都应成功: %+v", record.Decisions)
+	}
+	if elapsed := time.Since(start); elapsed >= 2*time.Second {
+		t.Errorf("使用非默认下单类型时也应并行执行，周期耗时 %v", elapsed)
+	}
+	if record.Decisions[0].OrderType != "market" || record.Decisions[1].OrderType != "ioc" {
+		t.Errorf("下单类型 = %q / %q，期望 market / ioc", record.Decisions[0].OrderType, record.Decisions[1].OrderType)
+	}
+	for _, order := range ex.GateOrders() {
+		market := order["price"] == "0"
+		if contract := order["contract"]; (contract == "ETH_USDT") != market {
+			t.Errorf("%v 订单价格 = %v（市价单为0）", contract, order["price"])
+		}
+	}
+}
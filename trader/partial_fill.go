@@ -0,0 +1,171 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+)
+
+// 部分成交跟踪
+// IOC 限价单在盘口深度不足时只成交一部分，剩余部分被交易所撤销。每笔开仓/加仓订单下单后确认最终成交数量
+// （交易所实现 OrderFillQuerier 时查询订单，否则读取下单返回中的成交字段），以实际成交数量作为持仓数量：
+// 止损止盈按实际成交数量挂单，决策日志记录请求数量和成交数量，完全未成交视为下单失败。
+// 启用补单时，未成交的剩余部分按当前价重新下单（最多 MaxRetries 次），剩余部分低于请求数量的
+// MinRemainderPct 时不再补单。挂在盘口上尚未结束的订单（post_only）无法确定最终成交，按请求数量处理。
+
+const (
+	fillQueryAttempts = 3 // 订单尚未结束时的查询次数（交易所撮合是异步的，下单返回时可能还是 NEW）
+)
+
+var fillQueryDelay = 200 * time.Millisecond
+
+// OrderFill 订单成交情况（数量为币数量）
+type OrderFill struct {
+	Requested float64 // 委托数量
+	Filled    float64 // 已成交数量
+	AvgPrice  float64 // 成交均价（未成交或未知时为0）
+	Final     bool    // 订单已结束（全部成交、撤销或过期），Filled 不会再变化
+}
+
+// OrderFillQuerier 可以查询订单成交情况的交易器（可选接口）
+type OrderFillQuerier interface {
+	// GetOrderFill 按下单返回的结果查询订单的成交情况
+	GetOrderFill(symbol string, order map[string]interface{}) (*OrderFill, error)
+}
+
+// PartialFillConfig 部分成交的补单策略
+type PartialFillConfig struct {
+	MaxRetries      int     // 对未成交的剩余部分重新下单的最多次数
+	MinRemainderPct float64 // 剩余部分低于请求数量的该百分比时不再补单
+}
+
+// EnablePartialFillRetry 启用部分成交后对剩余部分补单
+func (at *AutoTrader) EnablePartialFillRetry(cfg PartialFillConfig) {
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 2
+	}
+	if cfg.MinRemainderPct <= 0 {
+		cfg.MinRemainderPct = 10
+	}
+	at.partialFill = &cfg
+}
+
+// binanceOrderFill 按币安格式（status / origQty / executedQty / avgPrice）解析成交情况
+func binanceOrderFill(status, origQty, executedQty, avgPrice string, requested float64) *OrderFill {
+	fill := &OrderFill{Requested: requested}
+	if qty, err := strconv.ParseFloat(origQty, 64); err == nil && qty > 0 {
+		fill.Requested = qty
+	}
+	fill.Filled, _ = strconv.ParseFloat(executedQty, 64)
+	fill.AvgPrice, _ = strconv.ParseFloat(avgPrice, 64)
+	switch status {
+	case "FILLED", "CANCELED", "EXPIRED", "EXPIRED_IN_MATCH", "REJECTED":
+		fill.Final = true
+	}
+	return fill
+}
+
+// fillFromOrder 从下单返回中读取成交情况（币安格式），缺少成交字段时视为按请求数量成交
+func fillFromOrder(order map[string]interface{}, requested float64) OrderFill {
+	executed, ok := order["executedQty"]
+	if !ok {
+		return OrderFill{Requested: requested, Filled: requested, AvgPrice: orderPrice(order, "avgPrice")}
+	}
+	fill := binanceOrderFill(fmt.Sprint(order["status"]), fmt.Sprint(order["origQty"]), fmt.Sprint(executed), fmt.Sprint(order["avgPrice"]), requested)
+	return *fill
+}
+
+// orderFill 下单后确认订单的成交情况；订单尚未结束时按请求数量处理
+func (at *AutoTrader) orderFill(symbol string, order map[string]interface{}, requested float64) OrderFill {
+	fill := fillFromOrder(order, requested)
+	if querier, ok := at.trader.(OrderFillQuerier); ok {
+		for attempt := 0; attempt < fillQueryAttempts; attempt++ {
+			if attempt > 0 {
+				time.Sleep(fillQueryDelay)
+			}
+			queried, err := querier.GetOrderFill(symbol, order)
+			if err != nil {
+				log.Printf("  ⚠ 查询 %s 订单成交情况失败，按下单返回处理: %v", symbol, err)
+				break
+			}
+			fill = *queried
+			if fill.Final {
+				break
+			}
+		}
+	}
+	if !fill.Final {
+		// 挂单中（post_only）或交易所未返回成交信息
+		fill.Filled = math.Max(fill.Filled, requested)
+	}
+	return fill
+}
+
+// openFilled 下单并确认实际成交数量，启用补单时对未成交的剩余部分重新下单
+// 返回第一笔订单的结果（avgPrice 为所有订单的成交均价）和累计成交数量，完全未成交时返回错误
+func (at *AutoTrader) openFilled(open func(string, float64, int) (map[string]interface{}, error), symbol string, quantity float64, leverage int, retries *int) (map[string]interface{}, float64, error) {
+	order, err := open(symbol, quantity, leverage)
+	if err != nil {
+		return nil, 0, err
+	}
+	fill := at.orderFill(symbol, order, quantity)
+	filled, notional, priced, orders := fill.Filled, 0.0, true, 1
+	if price := fillPriceOf(order, fill); price > 0 {
+		notional = fill.Filled * price
+	} else {
+		priced = false
+	}
+
+	for at.partialFill != nil && *retries < at.partialFill.MaxRetries {
+		remainder := quantity - filled
+		if remainder <= quantity*at.partialFill.MinRemainderPct/100 {
+			break
+		}
+		*retries++
+		log.Printf("  🔁 %s 部分成交 %.6f / %.6f，补单剩余 %.6f（第%d次）", symbol, filled, quantity, remainder, *retries)
+		next, err := open(symbol, remainder, leverage)
+		if err != nil {
+			log.Printf("  ⚠ %s 补单失败: %v", symbol, err)
+			break
+		}
+		nextFill := at.orderFill(symbol, next, remainder)
+		if nextFill.Filled <= 0 {
+			continue
+		}
+		filled += nextFill.Filled
+		orders++
+		if price := fillPriceOf(next, nextFill); price > 0 {
+			notional += nextFill.Filled * price
+		} else {
+			priced = false
+		}
+	}
+
+	if filled <= 0 {
+		return nil, 0, fmt.Errorf("%s 订单未成交（%.6f 全部被撤销，限价未能立即成交）", symbol, quantity)
+	}
+	if filled < quantity*0.999 {
+		log.Printf("  ⚠ %s 部分成交: %.6f / %.6f（%.1f%%），按实际成交数量设置止损止盈", symbol, filled, quantity, filled/quantity*100)
+	}
+	result := make(map[string]interface{}, len(order)+1)
+	for k, v := range order {
+		result[k] = v
+	}
+	if orders > 1 && priced && notional > 0 {
+		result["avgPrice"] = notional / filled
+	}
+	return result, filled, nil
+}
+
+// fillPriceOf 成交均价：优先使用查询结果，其次下单返回的 avgPrice / fill_price
+func fillPriceOf(order map[string]interface{}, fill OrderFill) float64 {
+	if fill.AvgPrice > 0 {
+		return fill.AvgPrice
+	}
+	if price := orderPrice(order, "avgPrice"); price > 0 {
+		return price
+	}
+	return orderPrice(order, "fill_price")
+}
@@ -0,0 +1,97 @@
+package trader
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationPartialFillSizesProtection(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	ex.SetPartialFills(0.6) // 50张只成交30张
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 30 {
+		t.Fatalf("持仓 = %v 张，期望部分成交的 30 张", pos.size)
+	}
+	action := record.Decisions[0]
+	if math.Abs(action.Quantity-0.3) > 1e-9 || action.RequestedQuantity != 0.5 || action.RemainderRetries != 0 {
+		t.Errorf("记录的成交 = %.4f / %.4f（补单 %d 次），期望 0.3 / 0.5，不补单", action.Quantity, action.RequestedQuantity, action.RemainderRetries)
+	}
+	open := ex.Triggers("gateio", "open")
+	if len(open) != 2 {
+		t.Fatalf("条件单数量 = %d，期望止损+止盈共2个", len(open))
+	}
+	for _, tr := range open {
+		if tr.size != 30 {
+			t.Errorf("条件单应按实际成交的 30 张挂单: %+v", tr)
+		}
+	}
+}
+
+func TestIntegrationPartialFillRetriesRemainder(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnablePartialFillRetry(PartialFillConfig{MaxRetries: 2, MinRemainderPct: 10})
+	ex.SetPartialFills(0.6, 0.5) // 30张，剩余20张成交10张，剩余10张全部成交
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 50 {
+		t.Fatalf("持仓 = %v 张，期望补单后全部成交 50 张", pos.size)
+	}
+	action := record.Decisions[0]
+	if math.Abs(action.Quantity-0.5) > 1e-9 || action.RemainderRetries != 2 {
+		t.Errorf("记录的成交 = %.4f（补单 %d 次），期望 0.5，补单2次", action.Quantity, action.RemainderRetries)
+	}
+	for _, tr := range ex.Triggers("gateio", "open") {
+		if tr.size != 50 {
+			t.Errorf("条件单应覆盖全部持仓: %+v", tr)
+		}
+	}
+}
+
+func TestIntegrationPartialFillBinanceIOC(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "binance")
+
+	// 完全未成交：视为下单失败，不挂保护单
+	ex.SetPartialFills(0)
+	d := openLongETH(1500)
+	d.OrderType = "ioc"
+	ai.Enqueue(t, "开多。", d)
+	record := runCycle(t, at)
+	if action := record.Decisions[0]; action.Success || !strings.Contains(action.Error, "未成交") {
+		t.Fatalf("未成交的IOC单应失败: %+v", action)
+	}
+	if pos := ex.BinancePosition("ETHUSDT", "LONG"); pos.size != 0 {
+		t.Fatalf("未成交不应有持仓: %+v", pos)
+	}
+	if open := ex.Triggers("binance", "open"); len(open) != 0 {
+		t.Fatalf("未成交不应挂条件单: %d", len(open))
+	}
+
+	// 部分成交：按查询到的成交数量记录和挂单
+	ex.SetPartialFills(0.4)
+	ai.Enqueue(t, "开多。", d)
+	record = runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+	if pos := ex.BinancePosition("ETHUSDT", "LONG"); math.Abs(pos.size-0.2) > 1e-9 {
+		t.Fatalf("持仓 = %v ETH，期望部分成交的 0.2", pos.size)
+	}
+	action := record.Decisions[0]
+	if math.Abs(action.Quantity-0.2) > 1e-9 || action.RequestedQuantity != 0.5 {
+		t.Errorf("记录的成交 = %.4f / %.4f，期望 0.2 / 0.5", action.Quantity, action.RequestedQuantity)
+	}
+	for _, tr := range ex.Triggers("binance", "open") {
+		if tr.size != 0 && math.Abs(tr.size-0.2) > 1e-9 {
+			t.Errorf("条件单应按实际成交的 0.2 ETH 挂单: %+v", tr)
+		}
+	}
+}
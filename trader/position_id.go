@@ -0,0 +1,182 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 统一持仓ID
+// 开仓时生成持仓ID（"p" + 开仓时间纳秒的36进制），记录在 positions.json，直到持仓从交易所消失。
+// 同一持仓上的动作（开仓、加仓、部分平仓、调整止损止盈、平仓）、进行中的操作日志、每个周期的持仓快照
+// 和交易统计都带上该ID；支持的交易所（币安、Aster、Gate.io）止损止盈单的客户端订单ID也由它派生，
+// 按一个ID就能查询持仓的完整生命周期，部分平仓和重启后ID不变。
+// 不是本系统开的持仓（手动开仓、启用前已有的持仓）在第一次出现时补发ID。
+
+// PositionTagger 支持给止损止盈单附带客户端订单ID的交易器（可选接口）
+type PositionTagger interface {
+	// SetPositionTag 之后为该币种该方向（LONG/SHORT）挂的止损止盈单使用由tag派生的客户端订单ID，空字符串清除
+	SetPositionTag(symbol, positionSide, tag string)
+}
+
+// newPositionID 生成持仓ID（客户端订单ID有长度限制，保持简短）
+func newPositionID() string {
+	return "p" + strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// positionRegistry 当前持仓的ID（symbol_side -> 持仓ID），持久化到 positions.json
+type positionRegistry struct {
+	path string
+
+	mu     sync.Mutex
+	ids    map[string]string
+	frozen bool // 实例已退役，不再写文件（由新实例接管）
+}
+
+// loadPositionRegistry 读取持仓ID（文件不存在时为空）
+func loadPositionRegistry(path string) (*positionRegistry, error) {
+	r := &positionRegistry{path: path, ids: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.ids); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return r, nil
+}
+
+// get 持仓ID（没有记录时为空）
+func (r *positionRegistry) get(posKey string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ids[posKey]
+}
+
+// set 记录持仓ID（开仓成交后，或恢复操作日志中的开仓时）
+func (r *positionRegistry) set(posKey, id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ids[posKey] == id {
+		return
+	}
+	r.ids[posKey] = id
+	r.saveLocked()
+}
+
+// ensure 持仓ID，没有记录时补发
+func (r *positionRegistry) ensure(posKey string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id, ok := r.ids[posKey]; ok {
+		return id
+	}
+	id := newPositionID()
+	r.ids[posKey] = id
+	r.saveLocked()
+	return id
+}
+
+// prune 删除已不存在的持仓
+func (r *positionRegistry) prune(current map[string]bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := false
+	for key := range r.ids {
+		if !current[key] {
+			delete(r.ids, key)
+			changed = true
+		}
+	}
+	if changed {
+		r.saveLocked()
+	}
+}
+
+// freeze 停止写入文件
+func (r *positionRegistry) freeze() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frozen = true
+}
+
+// saveLocked 写入文件（先写临时文件再rename，失败只告警），调用方持有锁
+func (r *positionRegistry) saveLocked() {
+	if r.frozen {
+		return
+	}
+	data, err := json.MarshalIndent(r.ids, "", "  ")
+	if err == nil {
+		tmp := r.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, r.path)
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️ 写入持仓ID失败: %v", err)
+	}
+}
+
+// positionID 持仓的ID（没有记录时补发）
+func (at *AutoTrader) positionID(symbol, side string) string {
+	return at.positionIDs.ensure(symbol + "_" + side)
+}
+
+// tagProtectionOrders 之后为该持仓挂的止损止盈单带上持仓ID（交易所不支持时忽略）
+func (at *AutoTrader) tagProtectionOrders(symbol, side, positionID string) {
+	if tagger, ok := at.trader.(PositionTagger); ok {
+		tagger.SetPositionTag(symbol, positionSideOf(side), positionID)
+	}
+}
+
+// positionSideOf long/short -> LONG/SHORT
+func positionSideOf(side string) string {
+	if side == "short" {
+		return "SHORT"
+	}
+	return "LONG"
+}
+
+// positionTags 交易器记录的持仓ID，供止损止盈单生成客户端订单ID（嵌入交易器以实现 PositionTagger）
+type positionTags struct {
+	tagMu sync.Mutex
+	tags  map[string]string // symbol_LONG/SHORT -> 持仓ID
+}
+
+// SetPositionTag 实现 PositionTagger
+func (p *positionTags) SetPositionTag(symbol, positionSide, tag string) {
+	p.tagMu.Lock()
+	defer p.tagMu.Unlock()
+	if p.tags == nil {
+		p.tags = make(map[string]string)
+	}
+	key := symbol + "_" + positionSide
+	if tag == "" {
+		delete(p.tags, key)
+		return
+	}
+	p.tags[key] = tag
+}
+
+// positionTag 该币种该方向的持仓ID（没有时为空）
+func (p *positionTags) positionTag(symbol, positionSide string) string {
+	p.tagMu.Lock()
+	defer p.tagMu.Unlock()
+	return p.tags[symbol+"_"+positionSide]
+}
+
+// protectionClientID 止损（kind=sl）/止盈（kind=tp）单的客户端订单ID，没有持仓ID时为空
+func (p *positionTags) protectionClientID(symbol, positionSide, kind string) string {
+	tag := p.positionTag(symbol, positionSide)
+	if tag == "" {
+		return ""
+	}
+	return tag + "-" + kind
+}
@@ -0,0 +1,92 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+
+	"nofx/decision"
+)
+
+func TestIntegrationPositionIDOnProtectionOrders(t *testing.T) {
+	for _, exchange := range []string{"gateio", "binance"} {
+		t.Run(exchange, func(t *testing.T) {
+			ex, ai := setupIntegration(t)
+			at := newIntegrationTrader(t, ex, ai, exchange)
+
+			ai.Enqueue(t, "开多。", openLongETH(1500))
+			record := runCycle(t, at)
+			requireActionSuccess(t, record, "open_long")
+			id := record.Decisions[0].PositionID
+			if !strings.HasPrefix(id, "p") {
+				t.Fatalf("开仓应生成持仓ID: %q", id)
+			}
+			triggers := ex.Triggers(exchange, "open")
+			if len(triggers) != 2 {
+				t.Fatalf("条件单数量 = %d，期望2", len(triggers))
+			}
+			for _, tr := range triggers {
+				if !strings.Contains(tr.clientID, id) {
+					t.Errorf("保护单的客户端订单ID应包含持仓ID %s: %+v", id, tr)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegrationPositionIDLifecycle(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "binance")
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+	id := record.Decisions[0].PositionID
+
+	// 部分平仓沿用同一个ID，重挂的保护单也带上该ID
+	ai.Enqueue(t, "减仓。", decision.Decision{Symbol: "ETHUSDT", Action: "partial_close", ClosePercent: 50, Reasoning: "锁定部分利润"})
+	record = runCycle(t, at)
+	requireActionSuccess(t, record, "partial_close")
+	if got := record.Decisions[0].PositionID; got != id {
+		t.Fatalf("部分平仓的持仓ID = %q，期望 %q", got, id)
+	}
+	if len(record.Positions) != 1 || record.Positions[0].PositionID != id {
+		t.Fatalf("持仓快照应带持仓ID: %+v", record.Positions)
+	}
+	for _, tr := range ex.Triggers("binance", "open") {
+		if !strings.Contains(tr.clientID, id) {
+			t.Errorf("部分平仓后重挂的保护单应包含持仓ID: %+v", tr)
+		}
+	}
+
+	// 重启后平仓仍使用同一个ID
+	restarted := newIntegrationTrader(t, ex, ai, "binance")
+	ai.Enqueue(t, "平仓。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "离场"})
+	record = runCycle(t, restarted)
+	requireActionSuccess(t, record, "close_long")
+	if len(record.Positions) != 1 || record.Positions[0].PositionID != id || record.Decisions[0].PositionID != id {
+		t.Fatalf("重启后持仓ID应不变: positions=%+v action=%q", record.Positions, record.Decisions[0].PositionID)
+	}
+
+	lifecycle, err := restarted.decisionLogger.GetPositionLifecycle(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range lifecycle.Events {
+		actions = append(actions, e.Action)
+	}
+	if strings.Join(actions, ",") != "open_long,partial_close,close_long" || !lifecycle.Closed {
+		t.Fatalf("持仓生命周期 = %v closed=%v", actions, lifecycle.Closed)
+	}
+	if lifecycle.Symbol != "ETHUSDT" || lifecycle.Side != "long" || lifecycle.LastSnapshot == nil {
+		t.Errorf("持仓生命周期 = %+v", lifecycle)
+	}
+
+	// 新开仓使用新的ID
+	ai.Enqueue(t, "再次开多。", openLongETH(1500))
+	record = runCycle(t, restarted)
+	requireActionSuccess(t, record, "open_long")
+	if got := record.Decisions[0].PositionID; got == "" || got == id {
+		t.Fatalf("新持仓应使用新的ID: %q", got)
+	}
+}
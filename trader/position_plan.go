@@ -0,0 +1,173 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"os"
+	"sync"
+	"time"
+)
+
+// 持仓计划记忆
+// AI在开仓/加仓/持有/调整持仓的决策中可以写下 plan（如"持有至4h收盘站上X"），按持仓（symbol_side）
+// 保存在 plans.json，下个周期写回该持仓的信息中，让模型延续上次的持仓逻辑而不是每个周期重新推导。
+// 计划绑定持仓ID：平仓或持仓ID变化（平仓后重新开仓）后旧计划不再回放。
+
+// maxPlanRunes 单条计划的最大长度（超出截断，避免prompt膨胀）
+const maxPlanRunes = 300
+
+// storedPlan 持久化的持仓计划
+type storedPlan struct {
+	PositionID string `json:"position_id"`
+	decision.PositionPlan
+}
+
+// planMemory 当前持仓的计划（symbol_side -> 计划），持久化到 plans.json
+type planMemory struct {
+	path string
+
+	mu    sync.Mutex
+	plans map[string]*storedPlan
+}
+
+// loadPlanMemory 读取持仓计划（文件不存在时为空）
+func loadPlanMemory(path string) (*planMemory, error) {
+	m := &planMemory{path: path, plans: make(map[string]*storedPlan)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &m.plans); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return m, nil
+}
+
+// get 持仓的计划（没有记录或持仓ID不符时为nil）
+func (m *planMemory) get(posKey, positionID string) *decision.PositionPlan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	plan, ok := m.plans[posKey]
+	if !ok || plan.PositionID != positionID {
+		return nil
+	}
+	copied := plan.PositionPlan
+	return &copied
+}
+
+// remember 记录持仓的计划
+func (m *planMemory) remember(posKey, positionID, action, text string) {
+	if runes := []rune(text); len(runes) > maxPlanRunes {
+		text = string(runes[:maxPlanRunes]) + "..."
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.plans[posKey] = &storedPlan{
+		PositionID:   positionID,
+		PositionPlan: decision.PositionPlan{Text: text, Action: action, UpdatedAt: time.Now()},
+	}
+	m.saveLocked()
+}
+
+// forget 删除持仓的计划（平仓后）
+func (m *planMemory) forget(posKey string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.plans[posKey]; !ok {
+		return
+	}
+	delete(m.plans, posKey)
+	m.saveLocked()
+}
+
+// prune 删除已不存在的持仓的计划
+func (m *planMemory) prune(current map[string]bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changed := false
+	for key := range m.plans {
+		if !current[key] {
+			delete(m.plans, key)
+			changed = true
+		}
+	}
+	if changed {
+		m.saveLocked()
+	}
+}
+
+// saveLocked 写入文件（先写临时文件再rename，失败只告警），调用方持有锁
+func (m *planMemory) saveLocked() {
+	data, err := json.MarshalIndent(m.plans, "", "  ")
+	if err == nil {
+		tmp := m.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, m.path)
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️ 写入持仓计划失败: %v", err)
+	}
+}
+
+// rememberPlan 决策执行成功后更新持仓计划：平仓时删除，其余持仓动作带 plan 时记录（未带时保留上次计划）
+func (at *AutoTrader) rememberPlan(ctx *decision.Context, d *decision.Decision, action *logger.DecisionAction) {
+	switch d.Action {
+	case "close_long":
+		at.plans.forget(d.Symbol + "_long")
+		return
+	case "close_short":
+		at.plans.forget(d.Symbol + "_short")
+		return
+	case "wait", "cancel_orders":
+		return
+	}
+	if d.Plan == "" {
+		return
+	}
+
+	var posKey, positionID string
+	switch d.Action {
+	case "open_long", "open_short":
+		side := "long"
+		if d.Action == "open_short" {
+			side = "short"
+		}
+		posKey, positionID = d.Symbol+"_"+side, action.PositionID
+	default:
+		pos := planPosition(ctx, d.Symbol, d.Side)
+		if pos == nil {
+			return
+		}
+		posKey, positionID = pos.Symbol+"_"+pos.Side, pos.PositionID
+		if action.PositionID != "" {
+			positionID = action.PositionID
+		}
+	}
+	if positionID == "" {
+		return
+	}
+	at.plans.remember(posKey, positionID, d.Action, d.Plan)
+}
+
+// planPosition 决策针对的持仓（side为空时按币种推断，同币种同时持有多空仓时为nil）
+func planPosition(ctx *decision.Context, symbol, side string) *decision.PositionInfo {
+	var matched *decision.PositionInfo
+	for i := range ctx.Positions {
+		pos := &ctx.Positions[i]
+		if pos.Symbol != symbol || (side != "" && pos.Side != side) {
+			continue
+		}
+		if matched != nil {
+			return nil
+		}
+		matched = pos
+	}
+	return matched
+}
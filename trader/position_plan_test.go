@@ -0,0 +1,48 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+
+	"nofx/decision"
+)
+
+func TestIntegrationPositionPlanReplay(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "binance")
+
+	open := openLongETH(1500)
+	open.Plan = "持有至4h收盘站上3200，跌破3050离场"
+	ai.Enqueue(t, "开多。", open)
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	// 下个周期的持仓信息回放上次的计划；持有时未带plan保留原计划
+	ai.Enqueue(t, "继续持有。", decision.Decision{Symbol: "ETHUSDT", Action: "hold", Reasoning: "趋势未变"})
+	runCycle(t, at)
+	prompts := ai.Prompts()
+	if last := prompts[len(prompts)-1]; !strings.Contains(last, "**上次计划**") || !strings.Contains(last, "open_long）: 持有至4h收盘站上3200") {
+		t.Fatalf("持仓信息应回放开仓时的计划:\n%s", last)
+	}
+
+	// 重启后仍能回放，持有时带plan更新计划
+	restarted := newIntegrationTrader(t, ex, ai, "binance")
+	ai.Enqueue(t, "更新计划。", decision.Decision{Symbol: "ETHUSDT", Action: "hold", Reasoning: "放量", Plan: "目标上移至3400"})
+	runCycle(t, restarted)
+	if last := ai.Prompts()[len(ai.Prompts())-1]; !strings.Contains(last, "持有至4h收盘站上3200") {
+		t.Fatalf("重启后应回放计划:\n%s", last)
+	}
+	ai.Enqueue(t, "平仓。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "离场"})
+	requireActionSuccess(t, runCycle(t, restarted), "close_long")
+	if last := ai.Prompts()[len(ai.Prompts())-1]; !strings.Contains(last, "hold）: 目标上移至3400") {
+		t.Fatalf("持有时的plan应更新计划:\n%s", last)
+	}
+
+	// 平仓后计划删除，重新开仓不会回放旧计划
+	ai.Enqueue(t, "重新开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, restarted), "open_long")
+	ai.Enqueue(t, "观望。", decision.Decision{Symbol: "ETHUSDT", Action: "wait", Reasoning: "无信号"})
+	runCycle(t, restarted)
+	if last := ai.Prompts()[len(ai.Prompts())-1]; strings.Contains(last, "**上次计划**") {
+		t.Fatalf("平仓后不应回放旧计划:\n%s", last)
+	}
+}
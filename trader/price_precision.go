@@ -0,0 +1,38 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+)
+
+// 价格精度
+// 止损止盈是AI按行情估算的价格，精度通常比合约的最小价格变动更细。交易器实现 PriceRounder 时，
+// 决策在验证前按交易所规则取整（见 decision.roundDecisionPrices），验证的风险回报比和挂单的触发价是同一个价格。
+
+// PriceRounder 能按交易所规则取整价格的交易器（可选接口）
+type PriceRounder interface {
+	// RoundPrice 将价格取整到币种的最小价格变动
+	RoundPrice(symbol string, price float64) (float64, error)
+}
+
+// priceRounder 当前交易器的价格取整函数（交易器不支持时为nil，决策不取整）
+func (at *AutoTrader) priceRounder() decision.PriceRounder {
+	rounder, ok := at.trader.(PriceRounder)
+	if !ok {
+		return nil
+	}
+	return rounder.RoundPrice
+}
+
+// roundToTick 将价格取整到tick size的整数倍，并去掉浮点误差（0.1的倍数不会变成 x.1000000000004）
+func roundToTick(price, tick float64) float64 {
+	if tick <= 0 {
+		return price
+	}
+	decimals := 0
+	for t := tick; t < 1 && decimals < 12; t *= 10 {
+		decimals++
+	}
+	scale := math.Pow10(decimals)
+	return math.Round(roundToTickSize(price, tick)*scale) / scale
+}
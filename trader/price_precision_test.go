@@ -0,0 +1,34 @@
+package trader
+
+import "testing"
+
+func TestIntegrationStopsRoundedToTickSize(t *testing.T) {
+	for _, exchange := range []string{"gateio", "binance"} {
+		t.Run(exchange, func(t *testing.T) {
+			ex, ai := setupIntegration(t)
+			at := newIntegrationTrader(t, ex, ai, exchange)
+
+			// ETHUSDT 的 tick size 为 0.01，AI 给出更细的止损止盈
+			d := openLongETH(1500)
+			d.StopLoss, d.TakeProfit = 2900.004, 3400.006
+			ai.Enqueue(t, "ETH 放量突破，开多。", d)
+			record := runCycle(t, at)
+			requireActionSuccess(t, record, "open_long")
+			requireExecutionLog(t, record.ExecutionLog, "止损止盈按价格精度取整")
+
+			open := ex.Triggers(exchange, "open")
+			if len(open) != 2 {
+				t.Fatalf("条件单数量 = %d，期望止损+止盈共2个", len(open))
+			}
+			for _, tr := range open {
+				if (tr.kind == "stop_loss" && tr.price != 2900) || (tr.kind == "take_profit" && tr.price != 3400.01) {
+					t.Fatalf("条件单触发价未按tick size取整: %+v", tr)
+				}
+			}
+			// 本地记录与交易所挂单的触发价一致
+			if stops := at.positionStops["ETHUSDT_long"]; stops == nil || stops.StopLoss != 2900 || stops.TakeProfit != 3400.01 {
+				t.Fatalf("本地止损止盈记录 = %+v", stops)
+			}
+		})
+	}
+}
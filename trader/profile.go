@@ -0,0 +1,109 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"time"
+)
+
+// 策略配置热切换
+// 策略配置把提示词模板、扫描间隔、下单类型、杠杆和仓位上限、止损止盈自动补全和额外指标集打包在一起，
+// 切换时整体替换，在两个周期之间生效（与交易周期互斥），扫描间隔变化时重置定时器。
+
+// StrategyProfile 策略配置（各字段已合并全局配置）
+type StrategyProfile struct {
+	Name                 string
+	SystemPromptTemplate string
+	ScanInterval         time.Duration
+	OrderType            string
+	SymbolEdgeDays       int
+
+	BTCETHLeverage      int
+	AltcoinLeverage     int
+	MinPositionSizeUSD  float64
+	MaxPositionSizeUSD  float64
+	MaxMarginUsagePct   float64
+	MaxPositionSizeMult float64
+	SafetyBufferPct     float64
+
+	AutoStopLoss decision.AutoStopConfig
+	Indicators   []string // 写入prompt的额外指标（nil表示全部已启用的指标）
+}
+
+// ApplyTo 用策略配置覆盖trader配置中的对应字段
+func (p StrategyProfile) ApplyTo(cfg *AutoTraderConfig) {
+	cfg.Profile = p.Name
+	cfg.SystemPromptTemplate = p.SystemPromptTemplate
+	cfg.ScanInterval = p.ScanInterval
+	cfg.OrderType = p.OrderType
+	cfg.SymbolEdgeDays = p.SymbolEdgeDays
+	cfg.BTCETHLeverage = p.BTCETHLeverage
+	cfg.AltcoinLeverage = p.AltcoinLeverage
+	cfg.MinPositionSizeUSD = p.MinPositionSizeUSD
+	cfg.MaxPositionSizeUSD = p.MaxPositionSizeUSD
+	cfg.MaxMarginUsagePct = p.MaxMarginUsagePct
+	cfg.MaxPositionSizeMult = p.MaxPositionSizeMult
+	cfg.SafetyBufferPct = p.SafetyBufferPct
+	cfg.AutoStopLoss = p.AutoStopLoss
+	cfg.Indicators = p.Indicators
+}
+
+// ApplyProfile 切换策略配置（当前周期结束后生效）
+func (at *AutoTrader) ApplyProfile(p StrategyProfile) error {
+	if err := at.checkProfile(p); err != nil {
+		return err
+	}
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+	at.applyProfileLocked(p)
+	return nil
+}
+
+// checkProfile 检查策略配置能否用于当前交易所
+func (at *AutoTrader) checkProfile(p StrategyProfile) error {
+	if p.ScanInterval <= 0 {
+		return fmt.Errorf("策略配置 '%s' 的扫描间隔无效: %v", p.Name, p.ScanInterval)
+	}
+	orderType, err := ParseOrderType(p.OrderType)
+	if err != nil {
+		return err
+	}
+	if orderType != "" && !SupportsOrderType(at.trader, orderType) {
+		return fmt.Errorf("%s 不支持下单类型 %s（支持: %v）", at.exchange, orderType, at.trader.SupportedOrderTypes())
+	}
+	return nil
+}
+
+// applyProfileLocked 覆盖trader配置并通知交易循环重置定时器，调用方需持有 cycleMu
+func (at *AutoTrader) applyProfileLocked(p StrategyProfile) {
+	previous := at.config.Profile
+	p.ApplyTo(&at.config)
+	if at.config.SymbolEdgeDays == 0 {
+		at.config.SymbolEdgeDays = defaultSymbolEdgeDays
+	}
+
+	// 通知交易循环重置定时器（只保留最新的间隔）
+	select {
+	case <-at.intervalChanged:
+	default:
+	}
+	at.intervalChanged <- p.ScanInterval
+
+	log.Printf("🎛️ [%s] 策略配置切换: %s → %s（模板 %s，扫描间隔 %v，杠杆 %dx/%dx）",
+		at.name, orDefault(previous, "无"), p.Name, orDefault(p.SystemPromptTemplate, "default"), p.ScanInterval, p.BTCETHLeverage, p.AltcoinLeverage)
+}
+
+// GetProfile 当前策略配置名称（未使用策略配置时为空）
+func (at *AutoTrader) GetProfile() string {
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+	return at.config.Profile
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
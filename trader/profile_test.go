@@ -0,0 +1,44 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegrationApplyProfile(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	if err := at.ApplyProfile(StrategyProfile{Name: "bad", ScanInterval: time.Minute, OrderType: "limit"}); err == nil {
+		t.Fatal("无效的下单类型应拒绝切换")
+	}
+
+	swing := StrategyProfile{
+		Name:              "swing",
+		ScanInterval:      15 * time.Minute,
+		BTCETHLeverage:    3,
+		AltcoinLeverage:   2,
+		MaxMarginUsagePct: 50,
+		SafetyBufferPct:   5,
+		Indicators:        []string{},
+	}
+	if err := at.ApplyProfile(swing); err != nil {
+		t.Fatalf("切换策略配置失败: %v", err)
+	}
+	if at.GetProfile() != "swing" || at.GetScanInterval() != 15*time.Minute {
+		t.Fatalf("策略配置未生效: %s %v", at.GetProfile(), at.GetScanInterval())
+	}
+	if interval := <-at.intervalChanged; interval != 15*time.Minute {
+		t.Fatalf("交易循环应收到新的扫描间隔: %v", interval)
+	}
+
+	// 新配置的杠杆上限为3倍，5倍开仓被拒绝
+	ai.Enqueue(t, "ETH 5倍开多。", openLongETH(1500))
+	if err := at.runCycle(); err == nil || !strings.Contains(err.Error(), "杠杆必须在1-3之间") {
+		t.Fatalf("超过策略配置杠杆上限的决策应被拒绝: %v", err)
+	}
+	if ex.GatePosition("ETHUSDT").size != 0 {
+		t.Fatal("不应开仓")
+	}
+}
@@ -0,0 +1,159 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// 交易所上的止损止盈条件单
+// 持仓记录的止损止盈价只保存在内存中，重启后为空。交易所实现 ProtectionOrderManager 时，
+// 重新挂保护单（部分平仓、加仓、调整止损止盈）前先查询交易所上现有的条件单：没有记录的价格从现有条件单恢复，
+// 撤单时只撤该持仓方向、需要重新挂的那类条件单，双向持仓时不影响另一方向的保护单。
+// 不支持的交易所撤销该币种所有挂单后，按记录的价格恢复另一方向的保护单。
+
+// ProtectionOrder 交易所上的一个止损/止盈条件单
+type ProtectionOrder struct {
+	ID       string
+	Symbol   string
+	Side     string  // 保护的持仓方向 "long" | "short"
+	Kind     string  // "stop_loss" | "take_profit"
+	Price    float64 // 触发价
+	Quantity float64 // 平仓数量（币数量，0 表示全部平仓）
+}
+
+// ProtectionOrderManager 可以查询和单独撤销止损止盈条件单的交易器（可选接口）
+type ProtectionOrderManager interface {
+	// GetProtectionOrders 获取该币种未触发的止损止盈条件单
+	GetProtectionOrders(symbol string) ([]ProtectionOrder, error)
+
+	// CancelProtectionOrder 撤销一个条件单
+	CancelProtectionOrder(symbol, id string) error
+}
+
+// sideProtectionOrders 交易所上该持仓方向的止损止盈条件单（交易所不支持查询时 ok 为 false）
+func (at *AutoTrader) sideProtectionOrders(symbol, side string) (orders []ProtectionOrder, ok bool, err error) {
+	manager, ok := at.trader.(ProtectionOrderManager)
+	if !ok {
+		return nil, false, nil
+	}
+	all, err := manager.GetProtectionOrders(symbol)
+	if err != nil {
+		return nil, true, fmt.Errorf("获取%s现有止损止盈单失败: %w", symbol, err)
+	}
+	for _, order := range all {
+		if order.Side == side {
+			orders = append(orders, order)
+		}
+	}
+	return orders, true, nil
+}
+
+// knownProtection 持仓的止损止盈价：记录中没有的价格从交易所现有条件单补上（并写回记录）
+func (at *AutoTrader) knownProtection(symbol, side string, existing []ProtectionOrder) protectionPrices {
+	posKey := symbol + "_" + side
+	var stops protectionPrices
+	if recorded, ok := at.positionProtection(posKey); ok {
+		stops = *recorded
+	}
+	restored := false
+	for _, order := range existing {
+		switch {
+		case order.Kind == "stop_loss" && stops.StopLoss <= 0:
+			stops.StopLoss, restored = order.Price, true
+		case order.Kind == "take_profit" && stops.TakeProfit <= 0:
+			stops.TakeProfit, restored = order.Price, true
+		}
+	}
+	if restored {
+		log.Printf("  ↺ %s %s 从交易所条件单恢复止损止盈: 止损 %.4f | 止盈 %.4f", symbol, side, stops.StopLoss, stops.TakeProfit)
+		at.setPositionProtection(posKey, &stops, false)
+	}
+	return stops
+}
+
+// cancelProtectionOrders 撤销条件单中属于 kinds 的那些
+func (at *AutoTrader) cancelProtectionOrders(symbol string, orders []ProtectionOrder, kinds ...string) error {
+	manager := at.trader.(ProtectionOrderManager)
+	for _, order := range orders {
+		for _, kind := range kinds {
+			if order.Kind != kind {
+				continue
+			}
+			if err := manager.CancelProtectionOrder(symbol, order.ID); err != nil {
+				return fmt.Errorf("撤销%s条件单 %s 失败: %w", protectionKindName(kind), order.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// rememberProtection 从交易所现有条件单补全该币种两个方向记录的止损止盈价
+// （开仓/加仓下单时交易所接口会撤销该币种所有挂单，之后只能按记录的价格重新挂单）
+func (at *AutoTrader) rememberProtection(symbol string) {
+	manager, ok := at.trader.(ProtectionOrderManager)
+	if !ok {
+		return
+	}
+	orders, err := manager.GetProtectionOrders(symbol)
+	if err != nil {
+		log.Printf("  ⚠ 获取%s现有止损止盈单失败: %v", symbol, err)
+		return
+	}
+	for _, side := range []string{"long", "short"} {
+		var sideOrders []ProtectionOrder
+		for _, order := range orders {
+			if order.Side == side {
+				sideOrders = append(sideOrders, order)
+			}
+		}
+		if len(sideOrders) > 0 {
+			at.knownProtection(symbol, side, sideOrders)
+		}
+	}
+}
+
+// restoreProtection 按记录的价格为持仓补挂交易所上缺少的止损止盈单（撤销该币种所有挂单后恢复另一方向的保护单）
+func (at *AutoTrader) restoreProtection(symbol, side string) {
+	if at.retired.Load() {
+		return
+	}
+	stops, ok := at.positionProtection(symbol + "_" + side)
+	if !ok {
+		return
+	}
+	_, quantity, _, err := at.findPosition(symbol, side)
+	if err != nil {
+		return // 该方向没有持仓
+	}
+	existing, _, err := at.sideProtectionOrders(symbol, side)
+	if err != nil {
+		log.Printf("  ⚠ %v", err)
+		return
+	}
+	placed := make(map[string]bool)
+	for _, order := range existing {
+		placed[order.Kind] = true
+	}
+
+	at.tagProtectionOrders(symbol, side, at.positionID(symbol, side))
+	positionSide := strings.ToUpper(side)
+	if stops.StopLoss > 0 && !placed["stop_loss"] {
+		if err := at.trader.SetStopLoss(symbol, positionSide, quantity, stops.StopLoss); err != nil {
+			log.Printf("  ⚠ 恢复 %s %s 止损失败: %v", symbol, side, err)
+		}
+	}
+	if stops.TakeProfit > 0 && !placed["take_profit"] {
+		if err := at.trader.SetTakeProfit(symbol, positionSide, quantity, stops.TakeProfit); err != nil {
+			log.Printf("  ⚠ 恢复 %s %s 止盈失败: %v", symbol, side, err)
+		}
+	}
+}
+
+// protectionKindName stop_loss/take_profit -> 止损/止盈
+func protectionKindName(kind string) string {
+	if kind == "take_profit" {
+		return "止盈"
+	}
+	return "止损"
+}
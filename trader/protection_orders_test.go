@@ -0,0 +1,112 @@
+package trader
+
+import (
+	"testing"
+
+	"nofx/decision"
+)
+
+// openTriggerPrices 交易所上未触发的条件单：方向_类型 -> 触发价
+func openTriggerPrices(ex *mockExchange, exchange string) map[string]float64 {
+	prices := make(map[string]float64)
+	for _, tr := range ex.Triggers(exchange, "open") {
+		prices[tr.side+"_"+tr.kind] = tr.price
+	}
+	return prices
+}
+
+func TestIntegrationProtectionRestoredAfterRestart(t *testing.T) {
+	for _, exchange := range []string{"gateio", "binance"} {
+		t.Run(exchange, func(t *testing.T) {
+			ex, ai := setupIntegration(t)
+			at := newIntegrationTrader(t, ex, ai, exchange)
+			ai.Enqueue(t, "开多。", openLongETH(1500))
+			requireActionSuccess(t, runCycle(t, at), "open_long")
+
+			// 重启后没有记录的止损止盈价：只调整止盈，止损按交易所上的条件单保留原价
+			restarted := newIntegrationTrader(t, ex, ai, exchange)
+			ai.Enqueue(t, "上移止盈。", decision.Decision{Symbol: "ETHUSDT", Action: "adjust_tp", TakeProfit: 3500, Reasoning: "趋势延续"})
+			requireActionSuccess(t, runCycle(t, restarted), "adjust_tp")
+			prices := openTriggerPrices(ex, exchange)
+			if len(ex.Triggers(exchange, "open")) != 2 || prices["long_stop_loss"] != 2900 || prices["long_take_profit"] != 3500 {
+				t.Fatalf("调整止盈后条件单 = %+v", ex.Triggers(exchange, "open"))
+			}
+		})
+	}
+}
+
+func TestIntegrationProtectionPartialCloseAfterRestart(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "binance")
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	// 平仓时交易所撤销了所有挂单，重启后按平仓前交易所上的条件单为剩余仓位重新挂单
+	restarted := newIntegrationTrader(t, ex, ai, "binance")
+	ai.Enqueue(t, "减仓。", decision.Decision{Symbol: "ETHUSDT", Action: "partial_close", ClosePercent: 50, Reasoning: "锁定部分利润"})
+	requireActionSuccess(t, runCycle(t, restarted), "partial_close")
+	prices := openTriggerPrices(ex, "binance")
+	if len(ex.Triggers("binance", "open")) != 2 || prices["long_stop_loss"] != 2900 || prices["long_take_profit"] != 3400 {
+		t.Fatalf("部分平仓后应按原价重新挂止损止盈: %+v", ex.Triggers("binance", "open"))
+	}
+}
+
+func TestIntegrationProtectionHedgeKeepsOtherSide(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "binance")
+
+	// 双向持仓：多空各有止损止盈
+	if _, err := at.trader.OpenLong("ETHUSDT", 0.5, 5, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := at.trader.OpenShort("ETHUSDT", 0.5, 5, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{
+		at.trader.SetStopLoss("ETHUSDT", "LONG", 0.5, 2900),
+		at.trader.SetTakeProfit("ETHUSDT", "LONG", 0.5, 3400),
+		at.trader.SetStopLoss("ETHUSDT", "SHORT", 0.5, 3100),
+		at.trader.SetTakeProfit("ETHUSDT", "SHORT", 0.5, 2600),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var longIDs []int64
+	for _, tr := range ex.Triggers("binance", "open") {
+		if tr.side == "long" {
+			longIDs = append(longIDs, tr.id)
+		}
+	}
+
+	ai.Enqueue(t, "调整空仓止损。", decision.Decision{Symbol: "ETHUSDT", Side: "short", Action: "adjust_sl", StopLoss: 3050, Reasoning: "收紧止损"})
+	requireActionSuccess(t, runCycle(t, at), "adjust_sl")
+
+	prices := openTriggerPrices(ex, "binance")
+	if len(ex.Triggers("binance", "open")) != 4 || prices["short_stop_loss"] != 3050 || prices["short_take_profit"] != 2600 {
+		t.Fatalf("调整空仓止损后条件单 = %+v", ex.Triggers("binance", "open"))
+	}
+	for _, tr := range ex.Triggers("binance", "open") {
+		if tr.side == "long" && tr.id != longIDs[0] && tr.id != longIDs[1] {
+			t.Fatalf("多仓的保护单不应被撤销重挂: %+v", tr)
+		}
+	}
+}
+
+func TestIntegrationProtectionUnknownKeepsOrders(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	before := ex.Triggers("gateio", "open")
+
+	// 重启后交易所不支持查询条件单：没有已知的止损止盈价，不撤原有挂单
+	restarted := newIntegrationTrader(t, ex, ai, "gateio")
+	restarted.trader = struct{ Trader }{restarted.trader}
+	if err := restarted.replaceProtection("ETHUSDT", "long", 0.25); err == nil {
+		t.Fatal("没有已知的止损止盈价时应返回错误")
+	}
+	if after := ex.Triggers("gateio", "open"); len(after) != 2 || after[0].id != before[0].id || after[1].id != before[1].id {
+		t.Fatalf("没有已知价格时应保留原有条件单: %+v", after)
+	}
+}
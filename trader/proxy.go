@@ -0,0 +1,123 @@
+package trader
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"nofx/ratelimit"
+	"strings"
+	"time"
+)
+
+// 出口代理
+// 很多交易所API密钥绑定了IP白名单，而云主机（如Render）的出口IP会变化。
+// trader配置 proxy_url 后，该trader的交易所请求经 HTTP(S)/SOCKS5 代理或固定出口网关发出；
+// 启动时通过代理查询出口IP并调用一次交易所接口做连通性自检，方便核对白名单。
+
+// ProxyConfigurable 可以设置出口代理的交易器（可选接口）
+type ProxyConfigurable interface {
+	// SetProxy 之后的交易所请求都经过该代理
+	SetProxy(proxyURL *url.URL)
+}
+
+// egressIPURL 查询出口IP的服务（返回纯文本IP）
+var egressIPURL = "https://api.ipify.org"
+
+// ParseProxyURL 解析代理地址，支持 http / https / socks5 / socks5h
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("代理地址格式错误: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("不支持的代理协议 %q（支持 http, https, socks5, socks5h）", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("代理地址缺少主机: %s", u.Redacted())
+	}
+	return u, nil
+}
+
+// redactedProxyURL 隐藏代理地址中的密码（用于日志）
+func redactedProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "****"
+	}
+	return u.Redacted()
+}
+
+// withProxy 返回经过代理的 RoundTripper，保留限频层和原有的超时设置
+func withProxy(rt http.RoundTripper, proxyURL *url.URL) http.RoundTripper {
+	switch t := rt.(type) {
+	case *ratelimit.Transport:
+		return ratelimit.NewTransport(t.Scheduler, withProxy(t.Base, proxyURL))
+	case *http.Transport:
+		proxied := t.Clone()
+		proxied.Proxy = http.ProxyURL(proxyURL)
+		return proxied
+	default:
+		proxied := http.DefaultTransport.(*http.Transport).Clone()
+		proxied.Proxy = http.ProxyURL(proxyURL)
+		return proxied
+	}
+}
+
+// applyProxy 为交易器设置出口代理
+func applyProxy(trader Trader, exchange, raw string) error {
+	proxyURL, err := ParseProxyURL(raw)
+	if err != nil {
+		return err
+	}
+	configurable, ok := trader.(ProxyConfigurable)
+	if !ok {
+		return fmt.Errorf("%s 不支持单独配置代理（可通过 HTTPS_PROXY 环境变量为整个进程设置代理）", exchange)
+	}
+	configurable.SetProxy(proxyURL)
+	return nil
+}
+
+// CheckConnectivity 启动自检：查询出口IP并通过交易所接口验证连通性（未配置代理时只验证交易所接口）
+func (at *AutoTrader) CheckConnectivity() error {
+	egressIP := "未知"
+	if at.config.ProxyURL != "" {
+		ip, err := lookupEgressIP(at.config.ProxyURL)
+		if err != nil {
+			log.Printf("⚠️ [%s] 查询代理出口IP失败: %v", at.name, err)
+		} else {
+			egressIP = ip
+		}
+	}
+
+	if _, err := at.trader.GetBalance(); err != nil {
+		return fmt.Errorf("[%s] 交易所连通性自检失败（出口IP %s，IP受限的API密钥需要把它加入白名单）: %w", at.name, egressIP, err)
+	}
+	log.Printf("✅ [%s] 交易所连通性自检通过（出口IP %s）", at.name, egressIP)
+	return nil
+}
+
+// lookupEgressIP 通过代理查询出口IP
+func lookupEgressIP(rawProxyURL string) (string, error) {
+	proxyURL, err := ParseProxyURL(rawProxyURL)
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{Timeout: 10 * time.Second, Transport: withProxy(nil, proxyURL)}
+	resp, err := client.Get(egressIPURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,41 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/market"
+	"strings"
+)
+
+// 多计价币种
+// 候选币种池（AI500/OI Top）和默认币种按USDT交易对命名。trader配置 quote_asset 后（如币安的USDC永续），
+// 候选币种换成该计价币种的交易对，行情、下单和持仓都使用该交易对；余额按所有USD稳定币保证金合计。
+// EUR等非美元计价只用于识别交易对，不能作为trader的计价币种（仓位和风控都按USD计算）。
+
+// supportedQuotes 各交易所支持的计价币种（第一个为默认）
+var supportedQuotes = map[string][]string{
+	"binance":     {"USDT", "USDC"},
+	"aster":       {"USDT", "USDC"},
+	"hyperliquid": {"USDT", "USDC"}, // Hyperliquid统一以USDC结算，只影响交易对命名（BTCUSDT / BTCUSDC）
+	"gateio":      {"USDT"},         // 只接入了USDT结算合约
+}
+
+// QuoteConfigurable 交易对命名依赖计价币种的交易器（可选接口）
+type QuoteConfigurable interface {
+	// SetQuoteAsset 持仓和合约列表中的交易对使用该计价币种
+	SetQuoteAsset(quote string)
+}
+
+// resolveQuoteAsset 校验交易所是否支持该计价币种，空表示默认USDT
+func resolveQuoteAsset(exchange, quote string) (string, error) {
+	quote = strings.ToUpper(strings.TrimSpace(quote))
+	if quote == "" {
+		return market.DefaultQuote, nil
+	}
+	supported := supportedQuotes[exchange]
+	for _, q := range supported {
+		if q == quote {
+			return quote, nil
+		}
+	}
+	return "", fmt.Errorf("%s 不支持计价币种 %s（支持 %s）", exchange, quote, strings.Join(supported, ", "))
+}
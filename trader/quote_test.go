@@ -0,0 +1,76 @@
+package trader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveQuoteAsset(t *testing.T) {
+	if quote, err := resolveQuoteAsset("binance", ""); err != nil || quote != "USDT" {
+		t.Fatalf("未配置时应默认USDT: %q %v", quote, err)
+	}
+	if quote, err := resolveQuoteAsset("binance", "usdc"); err != nil || quote != "USDC" {
+		t.Fatalf("币安应支持USDC: %q %v", quote, err)
+	}
+	if _, err := resolveQuoteAsset("gateio", "USDC"); err == nil {
+		t.Fatal("Gate.io只接入了USDT结算合约，应拒绝USDC")
+	}
+	if _, err := resolveQuoteAsset("binance", "EUR"); err == nil {
+		t.Fatal("EUR不能作为trader的计价币种")
+	}
+}
+
+func TestIntegrationUSDCQuote(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	ex.SetPrice("ETHUSDC", 3000)
+
+	at, err := NewAutoTrader(AutoTraderConfig{
+		ID:                       "it_usdc",
+		Name:                     "integration_usdc",
+		AIModel:                  "custom",
+		Exchange:                 "binance",
+		BinanceAPIKey:            "test-key",
+		BinanceSecretKey:         "test-secret",
+		CustomAPIURL:             ai.URL(),
+		CustomAPIKey:             "test-key",
+		CustomModelName:          "mock",
+		ScanInterval:             time.Minute,
+		InitialBalance:           10000,
+		BTCETHLeverage:           10,
+		AltcoinLeverage:          5,
+		MaxMarginUsagePct:        80,
+		SafetyBufferPct:          5,
+		CheckAvailableBeforeOpen: true,
+		QuoteAsset:               "USDC",
+	})
+	if err != nil {
+		t.Fatalf("创建AutoTrader失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join("decision_logs", at.id), 0755); err != nil {
+		t.Fatal(err)
+	}
+	pointAtMock(t, at.trader, ex)
+
+	// 币种池给出的是 ETHUSDT，候选币种应换成 ETHUSDC；ETH按BTC/ETH的杠杆上限验证
+	open := openLongETH(1500)
+	open.Symbol = "ETHUSDC"
+	open.Leverage = 10
+	ai.Enqueue(t, "开多。", open)
+	record := runCycle(t, at)
+	if len(record.CandidateCoins) != 1 || record.CandidateCoins[0] != "ETHUSDC" {
+		t.Fatalf("候选币种应为 ETHUSDC，实际 %v", record.CandidateCoins)
+	}
+	if prompts := ai.Prompts(); len(prompts) == 0 || !strings.Contains(prompts[0], "ETHUSDC") {
+		t.Fatal("AI提示中应使用 ETHUSDC 交易对")
+	}
+	requireActionSuccess(t, record, "open_long")
+	if size := ex.BinancePosition("ETHUSDC", "LONG").size; size != 0.5 {
+		t.Fatalf("应在 ETHUSDC 开多 0.5 ETH，实际 %v", size)
+	}
+	if size := ex.BinancePosition("ETHUSDT", "LONG").size; size != 0 {
+		t.Fatalf("不应在 ETHUSDT 开仓，实际 %v", size)
+	}
+}
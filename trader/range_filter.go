@@ -0,0 +1,55 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+// 震荡区间的顺势开仓门槛
+// 启用震荡检测后，4小时震荡指数超过阈值的币种被标记为震荡区间。在这些币种上顺势开仓（价格在4小时EMA20之上做多、
+// 之下做空，没有EMA数据时按价格位于区间上半部/下半部判断）容易在区间边缘被反复止损，
+// 信心度低于 minConfidence 的此类开仓在执行前被跳过；逆势（区间边缘反转）开仓和其他动作不受影响。
+
+// EnableRangeFilter 震荡币种上的顺势开仓需要至少 minConfidence 的信心度
+func (at *AutoTrader) EnableRangeFilter(minConfidence int) {
+	at.rangeMinConfidence = minConfidence
+}
+
+// filterRangingEntries 跳过震荡币种上信心度不足的顺势开仓
+func (at *AutoTrader) filterRangingEntries(ctx *decision.Context, decisions []decision.Decision, record *logger.DecisionRecord) []decision.Decision {
+	if at.rangeMinConfidence <= 0 {
+		return decisions
+	}
+	kept := decisions[:0]
+	for _, d := range decisions {
+		if d.Action != "open_long" && d.Action != "open_short" || d.Confidence >= at.rangeMinConfidence {
+			kept = append(kept, d)
+			continue
+		}
+		data, ok := ctx.MarketDataMap[d.Symbol]
+		if !ok || data.Range == nil || !data.Range.Ranging || !isTrendFollowing(d.Action, data) {
+			kept = append(kept, d)
+			continue
+		}
+		log.Printf("  🌀 %s %s 被震荡过滤跳过：震荡指数 %.1f，顺势开仓信心度 %d < %d",
+			d.Symbol, d.Action, data.Range.Choppiness, d.Confidence, at.rangeMinConfidence)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🌀 %s %s 被震荡过滤跳过：%s处于震荡区间（4h震荡指数 %.1f，区间 %.4f - %.4f），顺势开仓需要信心度 ≥ %d（实际 %d）",
+			d.Symbol, d.Action, d.Symbol, data.Range.Choppiness, data.Range.Low, data.Range.High, at.rangeMinConfidence, d.Confidence))
+	}
+	return kept
+}
+
+// isTrendFollowing 开仓方向是否顺着当前走势：价格相对4小时EMA20的位置，没有时用区间内的位置
+func isTrendFollowing(action string, data *market.Data) bool {
+	above := data.Range.Position(data.CurrentPrice) >= 0.5
+	if data.LongerTermContext != nil && data.LongerTermContext.EMA20 > 0 {
+		above = data.CurrentPrice >= data.LongerTermContext.EMA20
+	}
+	if action == "open_long" {
+		return above
+	}
+	return !above
+}
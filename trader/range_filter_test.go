@@ -0,0 +1,48 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"reflect"
+	"testing"
+)
+
+func TestFilterRangingEntries(t *testing.T) {
+	box := &market.RangeData{Choppiness: 72, Period: 14, Ranging: true, High: 3100, Low: 2900}
+	ctx := &decision.Context{MarketDataMap: map[string]*market.Data{
+		// 价格在4h EMA20之上：做多是顺势，做空是逆势
+		"ETHUSDT": {Symbol: "ETHUSDT", CurrentPrice: 3080, Range: box, LongerTermContext: &market.LongerTermData{EMA20: 3000}},
+		// 没有EMA数据时按区间位置判断：价格靠近下沿，做空是顺势
+		"SOLUSDT": {Symbol: "SOLUSDT", CurrentPrice: 2910, Range: box},
+		"BTCUSDT": {Symbol: "BTCUSDT", CurrentPrice: 60000, Range: &market.RangeData{Choppiness: 30, Period: 14}},
+	}}
+	decisions := []decision.Decision{
+		{Symbol: "ETHUSDT", Action: "open_long", Confidence: 75},
+		{Symbol: "ETHUSDT", Action: "open_short", Confidence: 60},
+		{Symbol: "SOLUSDT", Action: "open_short", Confidence: 70},
+		{Symbol: "SOLUSDT", Action: "open_long", Confidence: 70},
+		{Symbol: "ETHUSDT", Action: "close_long", Confidence: 50},
+		{Symbol: "BTCUSDT", Action: "open_long", Confidence: 60},
+		{Symbol: "ETHUSDT", Action: "open_long", Confidence: 85},
+	}
+
+	at := &AutoTrader{}
+	record := &logger.DecisionRecord{}
+	if got := at.filterRangingEntries(ctx, append([]decision.Decision(nil), decisions...), record); len(got) != len(decisions) {
+		t.Fatalf("未启用时不应跳过决策: %d/%d", len(got), len(decisions))
+	}
+
+	at.EnableRangeFilter(80)
+	kept := at.filterRangingEntries(ctx, append([]decision.Decision(nil), decisions...), record)
+	var got []string
+	for _, d := range kept {
+		got = append(got, d.Symbol+" "+d.Action)
+	}
+	want := []string{"ETHUSDT open_short", "SOLUSDT open_long", "ETHUSDT close_long", "BTCUSDT open_long", "ETHUSDT open_long"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("保留的决策 = %v, 期望 %v", got, want)
+	}
+	requireExecutionLog(t, record.ExecutionLog, "ETHUSDT open_long 被震荡过滤跳过")
+	requireExecutionLog(t, record.ExecutionLog, "SOLUSDT open_short 被震荡过滤跳过")
+}
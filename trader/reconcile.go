@@ -0,0 +1,359 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/logger"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 交易所对账
+// 把决策日志中本系统的下单动作与交易所成交历史逐笔核对，并比较两边的已实现盈亏：
+//   - missed_fill：日志记录下单成功，交易所在下单时间附近没有（足够的）对应成交
+//   - external_trade：交易所有开仓成交，日志中没有对应的下单动作（网页/App手动下单或其他程序）
+//   - pnl_mismatch：某币种日志中的已实现盈亏与交易所盈亏流水相差超过容差
+// 没有对应下单动作的平仓成交（止损止盈触发、强平/ADL、手动平仓）单独列为交易所侧平仓，不算差异；
+// 区间内有交易所侧平仓或外部交易的币种不比较盈亏（日志中没有这部分盈亏）。
+// 日志动作按币种和订单方向匹配下单时间前 reconcileMatchBefore 到后 reconcileMatchAfter 之间的成交，
+// 平仓动作没有记录数量，匹配窗口内该方向的全部成交。交易所不提供盈亏流水时只核对成交。
+
+const (
+	reconcileMatchBefore  = time.Minute     // 成交早于日志动作时间的容差（时钟偏差）
+	reconcileMatchAfter   = 5 * time.Minute // 成交晚于日志动作时间的容差（拆单、补单）
+	reconcileQtyTolerance = 0.01            // 成交数量的相对容差（数量取整）
+	reconcileMinPnLDiff   = 1.0             // 盈亏差异的最小阈值（USDT）
+)
+
+// RealizedPnLRecord 交易所已实现盈亏流水中的一条记录
+type RealizedPnLRecord struct {
+	Symbol string
+	Amount float64
+	Time   time.Time
+}
+
+// RealizedPnLProvider 可以查询已实现盈亏流水的交易器（可选接口）
+type RealizedPnLProvider interface {
+	// GetRealizedPnL 获取 [since, until) 之间的已实现盈亏流水（不含手续费和资金费）
+	GetRealizedPnL(since, until time.Time) ([]RealizedPnLRecord, error)
+}
+
+// ReconcileItem 对账发现的一项差异或交易所侧平仓
+type ReconcileItem struct {
+	Kind       string    `json:"kind"`                  // missed_fill / external_trade / exchange_close / pnl_mismatch
+	Symbol     string    `json:"symbol"`                // 币种
+	Side       string    `json:"side,omitempty"`        // 订单方向 buy/sell
+	Quantity   float64   `json:"quantity,omitempty"`    // 数量（missed_fill 为缺少的数量）
+	Price      float64   `json:"price,omitempty"`       // 成交均价（日志动作为下单价）
+	Time       time.Time `json:"time"`                  // 动作或第一笔成交的时间
+	DecisionID string    `json:"decision_id,omitempty"` // 对应的决策记录
+	Action     string    `json:"action,omitempty"`      // 对应的日志动作
+	FillIDs    []string  `json:"fill_ids,omitempty"`    // 交易所成交ID
+	Detail     string    `json:"detail"`                // 说明
+}
+
+// SymbolPnL 单个币种的已实现盈亏对比
+type SymbolPnL struct {
+	Symbol      string  `json:"symbol"`
+	JournalPnL  float64 `json:"journal_pnl"`  // 日志中在区间内平仓的交易盈亏
+	ExchangePnL float64 `json:"exchange_pnl"` // 交易所盈亏流水合计
+	Compared    bool    `json:"compared"`     // 是否参与比较（有交易所侧平仓或外部交易时不比较）
+}
+
+// ReconcileReport 一次对账的结果
+type ReconcileReport struct {
+	TraderID       string          `json:"trader_id"`
+	Since          time.Time       `json:"since"`
+	Until          time.Time       `json:"until"`
+	GeneratedAt    time.Time       `json:"generated_at"`
+	JournalOrders  int             `json:"journal_orders"`  // 日志中成功的下单动作数
+	ExchangeFills  int             `json:"exchange_fills"`  // 交易所成交数
+	MatchedFills   int             `json:"matched_fills"`   // 匹配到日志动作的成交数
+	PnLAvailable   bool            `json:"pnl_available"`   // 交易所是否提供盈亏流水
+	JournalPnL     float64         `json:"journal_pnl"`     // 日志已实现盈亏合计
+	ExchangePnL    float64         `json:"exchange_pnl"`    // 交易所已实现盈亏合计
+	Symbols        []SymbolPnL     `json:"symbols"`         // 按币种的盈亏对比
+	Discrepancies  []ReconcileItem `json:"discrepancies"`   // 差异（missed_fill / external_trade / pnl_mismatch）
+	ExchangeCloses []ReconcileItem `json:"exchange_closes"` // 交易所侧平仓（止损止盈、强平、手动平仓）
+}
+
+// Format 对账结果的文本摘要（用于通知）
+func (r *ReconcileReport) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "区间: %s ~ %s\n", r.Since.Format("01-02 15:04"), r.Until.Format("01-02 15:04"))
+	fmt.Fprintf(&b, "日志下单 %d 笔，交易所成交 %d 笔（匹配 %d 笔），交易所侧平仓 %d 笔\n",
+		r.JournalOrders, r.ExchangeFills, r.MatchedFills, len(r.ExchangeCloses))
+	if r.PnLAvailable {
+		fmt.Fprintf(&b, "已实现盈亏: 日志 %+.2f / 交易所 %+.2f USDT\n", r.JournalPnL, r.ExchangePnL)
+	}
+	if len(r.Discrepancies) == 0 {
+		b.WriteString("没有发现差异")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "差异 %d 项:", len(r.Discrepancies))
+	for _, item := range r.Discrepancies {
+		fmt.Fprintf(&b, "\n• [%s] %s %s %s", item.Kind, item.Time.Format("01-02 15:04"), item.Symbol, item.Detail)
+	}
+	return b.String()
+}
+
+// journalOrder 日志中的一个下单动作
+type journalOrder struct {
+	decisionID string
+	action     logger.DecisionAction
+}
+
+// Reconcile 对账 [since, until) 之间的日志与交易所成交/盈亏流水，结果保存为最近一次对账
+// pnlTolerancePct: 币种盈亏差异超过交易所盈亏的该百分比（且至少1 USDT）时列为差异
+func (at *AutoTrader) Reconcile(since, until time.Time, pnlTolerancePct float64) (*ReconcileReport, error) {
+	provider, ok := at.trader.(TradeHistoryProvider)
+	if !ok {
+		return nil, fmt.Errorf("%s 不支持查询成交历史，无法对账", at.exchange)
+	}
+	if !since.Before(until) {
+		return nil, fmt.Errorf("对账区间无效: %s ~ %s", since.Format(time.RFC3339), until.Format(time.RFC3339))
+	}
+
+	// 下单前的成交属于窗口开始前的动作，窗口前后各放宽匹配容差
+	fills, err := provider.GetTradeHistory(since.Add(-reconcileMatchBefore), until.Add(reconcileMatchAfter))
+	if err != nil {
+		return nil, fmt.Errorf("获取成交历史失败: %w", err)
+	}
+	sort.SliceStable(fills, func(i, j int) bool { return fills[i].Time.Before(fills[j].Time) })
+
+	records, err := at.decisionLogger.GetRecordsBetween(since, until)
+	if err != nil {
+		return nil, fmt.Errorf("读取决策日志失败: %w", err)
+	}
+	var orders []journalOrder
+	for _, record := range records {
+		if record.Imported {
+			continue
+		}
+		for _, action := range record.Decisions {
+			if action.Success && action.Side != "" {
+				orders = append(orders, journalOrder{decisionID: record.DecisionID, action: action})
+			}
+		}
+	}
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].action.Timestamp.Before(orders[j].action.Timestamp) })
+
+	report := &ReconcileReport{
+		TraderID:       at.id,
+		Since:          since,
+		Until:          until,
+		GeneratedAt:    time.Now(),
+		JournalOrders:  len(orders),
+		Symbols:        []SymbolPnL{},
+		Discrepancies:  []ReconcileItem{},
+		ExchangeCloses: []ReconcileItem{},
+	}
+
+	remaining := matchJournalOrders(report, orders, fills)
+	unexplained := collectUnmatchedFills(report, fills, remaining, since, until)
+
+	if err := at.reconcilePnL(report, since, until, unexplained, pnlTolerancePct); err != nil {
+		return nil, err
+	}
+
+	at.stateMu.Lock()
+	at.lastReconcile = report
+	at.stateMu.Unlock()
+
+	log.Printf("🧾 [%s] 对账 %s ~ %s: 日志下单%d笔，交易所成交%d笔（匹配%d笔），差异%d项，交易所侧平仓%d笔",
+		at.name, since.Format("01-02 15:04"), until.Format("01-02 15:04"),
+		report.JournalOrders, report.ExchangeFills, report.MatchedFills, len(report.Discrepancies), len(report.ExchangeCloses))
+	return report, nil
+}
+
+// LastReconcileReport 最近一次对账的结果（还没有对账时为nil）
+func (at *AutoTrader) LastReconcileReport() *ReconcileReport {
+	at.stateMu.Lock()
+	defer at.stateMu.Unlock()
+	return at.lastReconcile
+}
+
+// matchJournalOrders 为每个日志动作消耗匹配的成交，记录缺少成交的动作，返回各成交未被匹配的数量
+func matchJournalOrders(report *ReconcileReport, orders []journalOrder, fills []HistoricalFill) []float64 {
+	const eps = 1e-9
+	remaining := make([]float64, len(fills))
+	for i, f := range fills {
+		remaining[i] = f.Quantity
+	}
+	matchedFills := make(map[int]bool)
+
+	for _, o := range orders {
+		a := o.action
+		want := a.Quantity // 平仓动作没有数量，匹配窗口内的全部成交
+		from, to := a.Timestamp.Add(-reconcileMatchBefore), a.Timestamp.Add(reconcileMatchAfter)
+		matched := 0.0
+		for i, f := range fills {
+			if f.Time.Before(from) {
+				continue
+			}
+			if f.Time.After(to) {
+				break
+			}
+			if f.Symbol != a.Symbol || f.Side != a.Side || remaining[i] <= eps {
+				continue
+			}
+			take := remaining[i]
+			if want > 0 {
+				take = math.Min(take, want-matched)
+			}
+			remaining[i] -= take
+			matched += take
+			matchedFills[i] = true
+			if want > 0 && matched >= want*(1-reconcileQtyTolerance) {
+				break
+			}
+		}
+
+		item := ReconcileItem{
+			Kind:       "missed_fill",
+			Symbol:     a.Symbol,
+			Side:       a.Side,
+			Price:      a.Price,
+			Time:       a.Timestamp,
+			DecisionID: o.decisionID,
+			Action:     a.Action,
+		}
+		switch {
+		case matched <= eps:
+			item.Quantity = a.Quantity
+			item.Detail = fmt.Sprintf("%s 日志记录成交，交易所没有对应成交", a.Action)
+			report.Discrepancies = append(report.Discrepancies, item)
+		case want > 0 && matched < want*(1-reconcileQtyTolerance):
+			item.Quantity = want - matched
+			item.Detail = fmt.Sprintf("%s 日志记录成交 %.6g，交易所只有 %.6g", a.Action, want, matched)
+			report.Discrepancies = append(report.Discrepancies, item)
+		}
+	}
+	report.MatchedFills = len(matchedFills)
+	return remaining
+}
+
+// collectUnmatchedFills 把 [since, until) 内没有匹配到日志动作的成交按连续的同币种同方向合并，
+// 平仓成交列为交易所侧平仓，其余列为外部交易；返回有这类成交的币种（不比较盈亏）
+func collectUnmatchedFills(report *ReconcileReport, fills []HistoricalFill, remaining []float64, since, until time.Time) map[string]bool {
+	const eps = 1e-9
+	unexplained := make(map[string]bool)
+	var current *ReconcileItem
+	var notional float64
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Price = notional / current.Quantity
+		if current.Kind == "exchange_close" {
+			current.Detail = fmt.Sprintf("交易所侧平仓 %s %.6g @ %.6g（止损止盈、强平或手动平仓）", current.Side, current.Quantity, current.Price)
+			report.ExchangeCloses = append(report.ExchangeCloses, *current)
+		} else {
+			current.Detail = fmt.Sprintf("日志中没有对应动作的成交 %s %.6g @ %.6g（手动或外部交易）", current.Side, current.Quantity, current.Price)
+			report.Discrepancies = append(report.Discrepancies, *current)
+		}
+		current = nil
+	}
+
+	for i, f := range fills {
+		if f.Time.Before(since) || !f.Time.Before(until) {
+			continue
+		}
+		report.ExchangeFills++
+		qty := remaining[i]
+		if qty <= eps*math.Max(f.Quantity, 1) || (qty < f.Quantity && qty <= f.Quantity*reconcileQtyTolerance) {
+			continue // 已匹配，或匹配后只剩取整误差
+		}
+		kind := "external_trade"
+		if isClosingFill(f) {
+			kind = "exchange_close"
+		}
+		unexplained[f.Symbol] = true
+		if current != nil && (current.Kind != kind || current.Symbol != f.Symbol || current.Side != f.Side ||
+			f.Time.Sub(current.Time) > reconcileMatchBefore) {
+			flush()
+		}
+		if current == nil {
+			current = &ReconcileItem{Kind: kind, Symbol: f.Symbol, Side: f.Side, Time: f.Time}
+			notional = 0
+		}
+		current.Quantity += qty
+		current.FillIDs = append(current.FillIDs, f.ID)
+		notional += qty * f.Price
+	}
+	flush()
+	return unexplained
+}
+
+// isClosingFill 成交是否减少了已有持仓（交易所标记了平仓数量，或双向持仓模式下与持仓方向相反）
+func isClosingFill(f HistoricalFill) bool {
+	if f.ClosedQty > 0 {
+		return true
+	}
+	switch f.PositionSide {
+	case "long":
+		return f.Side == "sell"
+	case "short":
+		return f.Side == "buy"
+	}
+	return false
+}
+
+// reconcilePnL 按币种比较日志与交易所流水的已实现盈亏（交易所不提供流水时跳过）
+func (at *AutoTrader) reconcilePnL(report *ReconcileReport, since, until time.Time, unexplained map[string]bool, tolerancePct float64) error {
+	provider, ok := at.trader.(RealizedPnLProvider)
+	if !ok {
+		return nil
+	}
+	records, err := provider.GetRealizedPnL(since, until)
+	if err != nil {
+		return fmt.Errorf("获取盈亏流水失败: %w", err)
+	}
+	trades, err := at.decisionLogger.TradesClosedBetween(since, until)
+	if err != nil {
+		return fmt.Errorf("读取平仓交易失败: %w", err)
+	}
+	report.PnLAvailable = true
+
+	bySymbol := make(map[string]*SymbolPnL)
+	entry := func(symbol string) *SymbolPnL {
+		if bySymbol[symbol] == nil {
+			bySymbol[symbol] = &SymbolPnL{Symbol: symbol}
+		}
+		return bySymbol[symbol]
+	}
+	for _, r := range records {
+		entry(r.Symbol).ExchangePnL += r.Amount
+		report.ExchangePnL += r.Amount
+	}
+	for _, t := range trades {
+		entry(t.Symbol).JournalPnL += t.PnL
+		report.JournalPnL += t.PnL
+	}
+
+	symbols := make([]string, 0, len(bySymbol))
+	for symbol := range bySymbol {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		s := bySymbol[symbol]
+		s.Compared = !unexplained[symbol]
+		report.Symbols = append(report.Symbols, *s)
+		if !s.Compared {
+			continue
+		}
+		diff := s.JournalPnL - s.ExchangePnL
+		if math.Abs(diff) > math.Max(reconcileMinPnLDiff, math.Abs(s.ExchangePnL)*tolerancePct/100) {
+			report.Discrepancies = append(report.Discrepancies, ReconcileItem{
+				Kind:   "pnl_mismatch",
+				Symbol: symbol,
+				Time:   until,
+				Detail: fmt.Sprintf("已实现盈亏 日志 %+.2f / 交易所 %+.2f USDT（相差 %+.2f）", s.JournalPnL, s.ExchangePnL, diff),
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"testing"
+	"time"
+)
+
+func TestIntegrationReconcile(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	ex.RecordFills()
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	start := time.Now().Add(-time.Minute)
+
+	// 本系统开仓、价格上涨后平仓：成交和盈亏都能对上
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	ex.SetPrice("ETHUSDT", 3100)
+	ai.Enqueue(t, "止盈。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "锁定利润"})
+	requireActionSuccess(t, runCycle(t, at), "close_long")
+
+	report, err := at.Reconcile(start, time.Now().Add(time.Second), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Discrepancies) != 0 {
+		t.Fatalf("不应有差异: %+v", report.Discrepancies)
+	}
+	if report.JournalOrders != 2 || report.ExchangeFills != 2 || report.MatchedFills != 2 {
+		t.Fatalf("应匹配开仓和平仓两笔成交: %+v", report)
+	}
+	if !report.PnLAvailable || len(report.Symbols) != 1 || !report.Symbols[0].Compared || report.ExchangePnL <= 0 {
+		t.Fatalf("应比较ETH的已实现盈亏: %+v", report.Symbols)
+	}
+
+	// 网页手动开空BTC、日志中有一笔交易所没有的成交
+	ex.AddHistoricalFills(mockFill{exchange: "gateio", symbol: "BTCUSDT", side: "sell", size: 100, price: 60000, time: time.Now()})
+	if err := at.GetDecisionLogger().LogDecision(&logger.DecisionRecord{
+		Success: true,
+		Decisions: []logger.DecisionAction{{
+			Action: "open_short", Symbol: "ETHUSDT", Side: "sell", Quantity: 0.3, Price: 3100, Timestamp: time.Now(), Success: true,
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = at.Reconcile(start, time.Now().Add(time.Second), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]ReconcileItem)
+	for _, item := range report.Discrepancies {
+		kinds[item.Kind] = item
+	}
+	if item, ok := kinds["external_trade"]; !ok || item.Symbol != "BTCUSDT" || item.Quantity != 0.01 {
+		t.Fatalf("应报告BTC的外部交易（100张 = 0.01 BTC）: %+v", report.Discrepancies)
+	}
+	if item, ok := kinds["missed_fill"]; !ok || item.Symbol != "ETHUSDT" || item.Action != "open_short" {
+		t.Fatalf("应报告日志中没有成交的开空: %+v", report.Discrepancies)
+	}
+	if at.LastReconcileReport() != report {
+		t.Fatal("应保存最近一次对账结果")
+	}
+}
+
+func TestIntegrationReconcileExchangeClose(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	ex.RecordFills()
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	start := time.Now().Add(-time.Minute)
+
+	// 止损在交易所触发：列为交易所侧平仓，不算差异，ETH不比较盈亏
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	ex.SetPrice("ETHUSDT", 2890)
+
+	report, err := at.Reconcile(start, time.Now().Add(time.Second), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Discrepancies) != 0 {
+		t.Fatalf("止损触发不应算差异: %+v", report.Discrepancies)
+	}
+	if len(report.ExchangeCloses) != 1 || report.ExchangeCloses[0].Side != "sell" {
+		t.Fatalf("应列出一笔交易所侧平仓: %+v", report.ExchangeCloses)
+	}
+	if len(report.Symbols) != 1 || report.Symbols[0].Compared || report.Symbols[0].ExchangePnL >= 0 {
+		t.Fatalf("有交易所侧平仓的币种不应比较盈亏: %+v", report.Symbols)
+	}
+}
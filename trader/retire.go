@@ -0,0 +1,50 @@
+package trader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// 卡死实例退役
+// watchdog 重启卡死的trader时，卡住的旧goroutine无法被强制结束，醒来后会继续执行当前周期。
+// Retire 把旧实例标记为已退役：不再开始新的周期，执行决策、开平仓、撤单和重新挂保护单前都会检查，
+// 返回 errTraderRetired；操作日志、持仓ID和风控状态也不再写入（由新实例接管 decision_logs/<id> 下的文件）。
+// Retire 等待进行中的周期结束（或ctx到期）后返回，之后才创建并启动新实例。
+
+// errTraderRetired 实例已被替换，不再下单
+var errTraderRetired = errors.New("trader实例已被替换（卡死后重启），不再下单")
+
+// Retire 退役旧实例并等待进行中的周期结束，ctx到期时返回错误（实例仍保持退役状态）
+func (at *AutoTrader) Retire(ctx context.Context) error {
+	at.retired.Store(true)
+	at.Stop()
+	at.operations.freeze()
+	at.positionIDs.freeze()
+
+	for !at.cycleMu.TryLock() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("等待进行中的周期结束超时: %w", ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	at.cycleMu.Unlock()
+	return nil
+}
+
+// IsRetired 实例是否已退役（被watchdog替换）
+func (at *AutoTrader) IsRetired() bool {
+	return at.retired.Load()
+}
+
+// checkRetired 已退役的实例返回错误（执行决策和每次下单前检查）
+func (at *AutoTrader) checkRetired() error {
+	if at.retired.Load() {
+		log.Printf("⏹ [%s] 实例已被替换，跳过下单", at.name)
+		return errTraderRetired
+	}
+	return nil
+}
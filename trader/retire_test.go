@@ -0,0 +1,81 @@
+package trader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegrationRetiredTraderPlacesNoOrders(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	// AI请求挂起，模拟卡住的周期；醒来后AI要求开仓
+	release := make(chan struct{})
+	ai.OnRequest(func() { <-release })
+	ai.Enqueue(t, "突破，开多。", openLongETH(1500))
+
+	done := make(chan error, 1)
+	go func() { done <- at.runCycle() }()
+	for deadline := time.Now().Add(5 * time.Second); len(ai.Prompts()) == 0; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("周期应已发出AI请求")
+		}
+	}
+
+	// 周期卡住时退役：等待超时，实例保持退役
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	err := at.Retire(ctx)
+	cancel()
+	if err == nil {
+		t.Fatal("周期卡住时 Retire 应等待超时")
+	}
+	if !at.IsRetired() || at.IsRunning() {
+		t.Fatal("退役后实例应标记为已退役且不再运行")
+	}
+
+	// 旧实例醒来，完成当前周期但不下单
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("旧周期应在AI返回后结束")
+	}
+	if orders := ex.GateOrders(); len(orders) != 0 {
+		t.Fatalf("退役实例不应下单: %v", orders)
+	}
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 0 {
+		t.Fatalf("退役实例不应开仓: %+v", pos)
+	}
+	if triggers := ex.Triggers("gateio", "open"); len(triggers) != 0 {
+		t.Fatalf("退役实例不应挂保护单: %+v", triggers)
+	}
+	if _, err := os.Stat(filepath.Join("decision_logs", at.id, "operations.json")); !os.IsNotExist(err) {
+		t.Fatalf("退役实例不应写操作日志: %v", err)
+	}
+
+	records, err := at.decisionLogger.GetLatestRecords(1)
+	if err != nil || len(records) == 0 {
+		t.Fatalf("读取决策记录失败: %v", err)
+	}
+	for _, a := range records[0].Decisions {
+		if a.Action == "open_long" && (a.Success || !strings.Contains(a.Error, "不再下单")) {
+			t.Fatalf("退役实例的开仓应被拦截: %+v", a)
+		}
+	}
+
+	// 周期已返回：再次退役立即完成，且不再开始新的周期
+	if err := at.Retire(context.Background()); err != nil {
+		t.Fatalf("周期结束后 Retire 应立即返回: %v", err)
+	}
+	ai.Enqueue(t, "突破，开多。", openLongETH(1500))
+	if err := at.runCycle(); err != nil {
+		t.Fatal(err)
+	}
+	if orders := ex.GateOrders(); len(orders) != 0 {
+		t.Fatalf("退役实例不应开始新的周期: %v", orders)
+	}
+}
@@ -0,0 +1,44 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// reviewDecisions 由复核模型检查第一轮决策，并把复核过程写入决策记录
+// record.DecisionJSON 保留第一轮决策，被否决/降级的决策不会出现在 record.Decisions 中
+func (at *AutoTrader) reviewDecisions(ctx *decision.Context, full *decision.FullDecision, record *logger.DecisionRecord) {
+	result := decision.ReviewDecisions(ctx, full, at.reviewClient)
+	if result == nil {
+		return
+	}
+
+	review := &logger.ReviewRecord{
+		Model:    result.Model,
+		CoTTrace: result.CoTTrace,
+		Error:    result.Error,
+	}
+	if result.Error != "" {
+		log.Printf("⚠️  二次复核失败，按第一轮决策执行: %s", result.Error)
+		record.ExecutionLog = append(record.ExecutionLog, "⚠️ 二次复核失败，按第一轮决策执行: "+result.Error)
+	}
+	for _, v := range result.Verdicts {
+		review.Verdicts = append(review.Verdicts, logger.ReviewVerdict{
+			Symbol:  v.Symbol,
+			Action:  v.Action,
+			Verdict: v.Verdict,
+			Reason:  v.Reason,
+		})
+		switch v.Verdict {
+		case decision.VerdictVeto:
+			log.Printf("  🚫 复核否决 %s %s: %s", v.Symbol, v.Action, v.Reason)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚫 %s %s 被复核否决: %s", v.Symbol, v.Action, v.Reason))
+		case decision.VerdictDowngrade:
+			log.Printf("  ⏸ 复核降级 %s %s → wait: %s", v.Symbol, v.Action, v.Reason)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏸ %s %s 被复核降级为观望: %s", v.Symbol, v.Action, v.Reason))
+		}
+	}
+	record.Review = review
+}
@@ -0,0 +1,44 @@
+package trader
+
+import (
+	"nofx/decision"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationReviewVeto(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.reviewClient = at.mcpClient // 复用主模型做第二次调用
+
+	ai.Enqueue(t, "ETH突破，开多。", openLongETH(1500))
+	ai.EnqueueRaw(`止损过近，风险回报比不达标。
+
+[{"symbol": "ETHUSDT", "action": "open_long", "verdict": "veto", "reason": "风险回报比不足"}]`)
+	record := runCycle(t, at)
+
+	for _, a := range record.Decisions {
+		if a.Action == "open_long" {
+			t.Fatalf("被否决的开仓仍被执行: %+v", a)
+		}
+	}
+	if size := ex.GatePosition("ETHUSDT").size; size != 0 {
+		t.Fatalf("被否决后不应有持仓: %v张", size)
+	}
+
+	// 两轮都要记录：DecisionJSON 为第一轮决策，Review 为复核结论
+	if !strings.Contains(record.DecisionJSON, "open_long") {
+		t.Errorf("DecisionJSON 应保留第一轮决策: %s", record.DecisionJSON)
+	}
+	if record.Review == nil || len(record.Review.Verdicts) != 1 || record.Review.Verdicts[0].Verdict != decision.VerdictVeto {
+		t.Fatalf("复核记录不符合预期: %+v", record.Review)
+	}
+	if record.Review.Model != "mock" || !strings.Contains(record.Review.CoTTrace, "风险回报比不达标") {
+		t.Errorf("复核记录缺少模型或分析: %+v", record.Review)
+	}
+
+	prompts := ai.Prompts()
+	if len(prompts) != 2 || !strings.Contains(prompts[1], "待复核的决策") {
+		t.Fatalf("复核模型应收到第一轮决策, prompts = %d", len(prompts))
+	}
+}
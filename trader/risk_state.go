@@ -0,0 +1,125 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"os"
+	"time"
+)
+
+// 风控状态持久化
+// 风控暂停（原因和截止时间）、当日降风险等级、当日起始净值和日重置时间写入 risk_state.json
+// （先写临时文件再rename），启动时恢复，避免重启进程清掉当日的风控限制。
+// 当前限制通过状态API返回，并写入AI提示。
+
+// riskState 持久化的风控状态
+type riskState struct {
+	StopUntil      time.Time   `json:"stop_until"`
+	StopReason     string      `json:"stop_reason,omitempty"`
+	DeriskLevel    DeriskLevel `json:"derisk_level"`
+	DayStartEquity float64     `json:"day_start_equity"`
+	LastResetTime  time.Time   `json:"last_reset_time"`
+	ExternalFlows  float64     `json:"external_flows,omitempty"` // 累计外部资金流动（计入初始余额）
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+// loadRiskState 读取风控状态（文件不存在时返回nil）
+func loadRiskState(path string) (*riskState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state riskState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return &state, nil
+}
+
+// restoreRiskState 用上次保存的状态恢复风控限制（已跨过trader时区零点的当日状态由下个周期的日重置清除）
+func (at *AutoTrader) restoreRiskState(state *riskState) {
+	at.stopUntil = state.StopUntil
+	at.stopReason = state.StopReason
+	at.derisk = deriskState{level: state.DeriskLevel, dayStartEquity: state.DayStartEquity}
+	if !state.LastResetTime.IsZero() {
+		at.lastResetTime = state.LastResetTime
+	}
+	at.externalFlows = state.ExternalFlows
+	at.initialBalance += state.ExternalFlows
+
+	if time.Now().Before(at.stopUntil) {
+		log.Printf("⏸ [%s] 恢复风控暂停: %s，暂停至 %s", at.name, at.stopReason, at.stopUntil.Format("01-02 15:04:05"))
+	}
+	if at.derisk.level != DeriskNone {
+		log.Printf("⚠️ [%s] 恢复当日降风险等级: %s", at.name, at.derisk.level)
+	}
+}
+
+// saveRiskState 写入风控状态（失败只告警）
+func (at *AutoTrader) saveRiskState() {
+	if at.retired.Load() {
+		return // 已被新实例接管
+	}
+	state := riskState{
+		StopUntil:      at.stopUntil,
+		StopReason:     at.stopReason,
+		DeriskLevel:    at.derisk.level,
+		DayStartEquity: at.derisk.dayStartEquity,
+		LastResetTime:  at.lastResetTime,
+		ExternalFlows:  at.externalFlows,
+		UpdatedAt:      time.Now(),
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		tmp := at.riskStatePath + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, at.riskStatePath)
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️ [%s] 保存风控状态失败: %v", at.name, err)
+	}
+}
+
+// haltTrading 触发风控后暂停交易并保存状态
+func (at *AutoTrader) haltTrading(reason string, d time.Duration) {
+	at.stopUntil = time.Now().Add(d)
+	at.stopReason = reason
+	at.saveRiskState()
+	log.Printf("⏸ [%s] %s，暂停交易至 %s", at.name, reason, at.stopUntil.Format("15:04:05"))
+}
+
+// riskRestrictions 当前生效的风控限制说明
+func (at *AutoTrader) riskRestrictions() []string {
+	restrictions := []string{}
+	if time.Now().Before(at.stopUntil) {
+		restrictions = append(restrictions, fmt.Sprintf("暂停交易至 %s（%s）", at.stopUntil.Format("01-02 15:04"), at.stopReason))
+	}
+	switch at.derisk.level {
+	case DeriskReduceSize:
+		restrictions = append(restrictions, fmt.Sprintf("单仓位上限缩减为 %.0f%%", at.config.DeriskLadder.SizeFactor*100))
+	case DeriskCloseOnly, DeriskFlatten:
+		restrictions = append(restrictions, "今日只允许平仓/减仓")
+	}
+	if halted, reason := at.flashGuard.active(); halted {
+		restrictions = append(restrictions, fmt.Sprintf("急跌熔断中，只允许平仓/减仓（%s）", reason))
+	}
+	return restrictions
+}
+
+// applyRiskNotice 今日触发过风控暂停时，在AI提示中说明（暂停期间不会请求AI，这里是暂停结束后的周期）
+func (at *AutoTrader) applyRiskNotice(ctx *decision.Context) {
+	if at.stopReason == "" || at.stopUntil.IsZero() {
+		return
+	}
+	notice := fmt.Sprintf("今日已因「%s」暂停交易至 %s，请控制风险", at.stopReason, at.stopUntil.Format("15:04"))
+	if ctx.RiskNotice != "" {
+		notice = ctx.RiskNotice + "；" + notice
+	}
+	ctx.RiskNotice = notice
+}
@@ -0,0 +1,66 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegrationRiskStateSurvivesRestart(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	ladder := DeriskConfig{Enabled: true, ReduceSizeLossPct: 0.5, CloseOnlyLossPct: 1, FlattenLossPct: 2, SizeFactor: 0.5}
+
+	first := newIntegrationTrader(t, ex, ai, "gateio")
+	first.config.DeriskLadder = ladder
+	first.config.StopTradingTime = 30 * time.Minute
+
+	open := openLongETH(1500)
+	open.StopLoss = 2000
+	open.TakeProfit = 4500
+	ai.Enqueue(t, "开多。", open)
+	requireActionSuccess(t, runCycle(t, first), "open_long")
+
+	// 亏损超过清仓线：清仓并暂停
+	ex.SetPrice("ETHUSDT", 2500)
+	runCycle(t, first)
+	if first.stopReason == "" || !first.stopUntil.After(time.Now()) {
+		t.Fatalf("应触发风控暂停: %v %q", first.stopUntil, first.stopReason)
+	}
+
+	// 重启后恢复暂停状态，周期内不请求AI
+	restarted := newIntegrationTrader(t, ex, ai, "gateio")
+	restarted.config.DeriskLadder = ladder
+	if !restarted.stopUntil.Equal(first.stopUntil) || restarted.stopReason != first.stopReason {
+		t.Fatalf("重启后暂停状态 = %v %q，期望 %v %q", restarted.stopUntil, restarted.stopReason, first.stopUntil, first.stopReason)
+	}
+	if restarted.derisk.level != DeriskFlatten {
+		t.Errorf("重启后降风险等级 = %s", restarted.derisk.level)
+	}
+	restrictions, _ := restarted.GetStatus()["restrictions"].([]string)
+	if len(restrictions) != 2 || !strings.Contains(restrictions[0], "暂停交易至") {
+		t.Errorf("状态中的风控限制 = %v", restrictions)
+	}
+
+	// 重启后周期编号从1开始，同一秒内的记录文件名排在重启前的记录之前，这里只检查是否请求AI
+	promptCount := len(ai.Prompts())
+	if err := restarted.runCycle(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ai.Prompts()) != promptCount {
+		t.Fatal("暂停期间不应请求AI")
+	}
+
+	// 暂停结束后当日仍只允许平仓，AI提示中说明暂停原因
+	restarted.stopUntil = time.Now().Add(-time.Minute)
+	runCycle(t, restarted)
+	prompts := ai.Prompts()
+	if len(prompts) != promptCount+1 {
+		t.Fatalf("暂停结束后应请求AI")
+	}
+	last := prompts[len(prompts)-1]
+	for _, want := range []string{"只允许平仓", "今日已因「日内亏损"} {
+		if !strings.Contains(last, want) {
+			t.Errorf("AI输入中缺少 %q", want)
+		}
+	}
+}
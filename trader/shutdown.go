@@ -0,0 +1,213 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/notify"
+	"strings"
+	"time"
+)
+
+// 退出时的持仓处理
+// 收到 SIGTERM 后按退出策略处理持仓，适用于托管平台上的计划内重新部署：
+//   - leave：保留持仓和交易所上的止损止盈（默认，与以往行为一致）
+//   - breakeven：盈利中的持仓把止损移到入场价（保本），只移动止损单，止盈单不动；亏损中的持仓保留原止损，移到入场价会立即触发
+//   - flatten：平掉全部持仓
+// 等待进行中的周期结束后执行，超时（ctx到期）后不再处理剩余持仓；之后不会再开始新的周期。
+// 结果写入一条决策记录，并推送通知。
+
+// 退出策略
+const (
+	ShutdownLeave     = "leave"
+	ShutdownBreakEven = "breakeven"
+	ShutdownFlatten   = "flatten"
+)
+
+// Shutdown 按退出策略处理持仓（Stop 之后调用），返回未完成的处理
+func (at *AutoTrader) Shutdown(ctx context.Context, policy string) error {
+	at.shuttingDown.Store(true)
+	if policy == "" || policy == ShutdownLeave {
+		return nil
+	}
+
+	// 等待进行中的周期结束（不能无限等待）
+	for !at.cycleMu.TryLock() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("等待进行中的周期结束超时，持仓未处理")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	defer at.cycleMu.Unlock()
+
+	record := &logger.DecisionRecord{
+		ExecutionLog: []string{fmt.Sprintf("⏏️ 退出策略 %s", policy)},
+		Success:      true,
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		err = fmt.Errorf("退出前获取持仓失败: %w", err)
+		at.finishShutdown(record, policy, err)
+		return err
+	}
+
+	var failures []string
+	for i, pos := range positions {
+		if ctx.Err() != nil {
+			failures = append(failures, fmt.Sprintf("超时，剩余 %d 个持仓未处理", len(positions)-i))
+			record.ExecutionLog = append(record.ExecutionLog, "⏱ "+failures[len(failures)-1])
+			break
+		}
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		if err := at.shutdownPosition(policy, pos, record); err != nil {
+			failures = append(failures, fmt.Sprintf("%s %s: %v", symbol, side, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		err = fmt.Errorf("退出策略 %s 未完成: %s", policy, strings.Join(failures, "; "))
+	}
+	at.finishShutdown(record, policy, err)
+	return err
+}
+
+// shutdownPosition 按退出策略处理一个持仓
+func (at *AutoTrader) shutdownPosition(policy string, pos map[string]interface{}, record *logger.DecisionRecord) error {
+	symbol, _ := pos["symbol"].(string)
+	side, _ := pos["side"].(string)
+	entryPrice, _ := pos["entryPrice"].(float64)
+	unrealizedPnl, _ := pos["unRealizedProfit"].(float64)
+
+	d := decision.Decision{Symbol: symbol, Side: side}
+	switch policy {
+	case ShutdownFlatten:
+		d.Action = "close_" + side
+		d.Reasoning = "退出前清仓"
+	case ShutdownBreakEven:
+		if entryPrice <= 0 || unrealizedPnl <= 0 {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("↔ %s %s 未盈利，保留原止损", symbol, side))
+			return nil
+		}
+		d.Action = "adjust_sl"
+		d.StopLoss = entryPrice
+		d.Reasoning = "退出前止损移到保本"
+	default:
+		return fmt.Errorf("未知的退出策略 %s", policy)
+	}
+
+	return at.executeRiskAction(&d, record)
+}
+
+// executeRiskAction 执行风控发起的平仓/调整止损（不经过AI），结果写入决策记录
+func (at *AutoTrader) executeRiskAction(d *decision.Decision, record *logger.DecisionRecord) error {
+	actionRecord := logger.DecisionAction{Action: d.Action, Symbol: d.Symbol, Timestamp: time.Now()}
+	var err error
+	switch d.Action {
+	case "close_long":
+		err = at.executeCloseLongWithRecord(d, &actionRecord)
+	case "close_short":
+		err = at.executeCloseShortWithRecord(d, &actionRecord)
+	case "adjust_sl":
+		err = at.executeMoveStopLossWithRecord(d, &actionRecord)
+	default:
+		err = at.executeAdjustProtectionWithRecord(d, &actionRecord)
+	}
+	if err != nil {
+		actionRecord.Error = err.Error()
+		actionRecord.RejectReason = orderRejectReason(err)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+	} else {
+		actionRecord.Success = true
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功（%s）", d.Symbol, d.Action, d.Reasoning))
+	}
+	record.Decisions = append(record.Decisions, actionRecord)
+	return err
+}
+
+// executeMoveStopLossWithRecord 只移动持仓的止损单（风控发起），止盈单不动，也不依赖记录的止损止盈价（刚重启时为空）
+// 交易所支持单独撤单时先撤原止损再挂新止损，挂新止损失败时按原价恢复；否则直接挂新止损，原止损保留在更远处
+func (at *AutoTrader) executeMoveStopLossWithRecord(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	if err := at.checkRetired(); err != nil {
+		return err
+	}
+	side, quantity, _, err := at.findPosition(d.Symbol, d.Side)
+	if err != nil {
+		return err
+	}
+	price, err := at.trader.GetMarketPrice(d.Symbol)
+	if err != nil {
+		return fmt.Errorf("获取价格失败: %w", err)
+	}
+	actionRecord.Price = price
+	actionRecord.Quantity = quantity
+	actionRecord.PositionID = at.positionID(d.Symbol, side)
+
+	if err := immediateTrigger(side, "stop_loss", d.StopLoss, []referencePrice{{"最新价", price}}); err != nil {
+		return err
+	}
+	if err := joinTriggerErrors(at.checkTriggers(d.Symbol, side, protectionPrices{StopLoss: d.StopLoss})); err != nil {
+		return err
+	}
+
+	existing, selective, err := at.sideProtectionOrders(d.Symbol, side)
+	if err != nil {
+		return err
+	}
+	if selective {
+		if err := at.cancelProtectionOrders(d.Symbol, existing, "stop_loss"); err != nil {
+			return err
+		}
+	}
+
+	at.tagProtectionOrders(d.Symbol, side, actionRecord.PositionID)
+	positionSide := strings.ToUpper(side)
+	if err := at.trader.SetStopLoss(d.Symbol, positionSide, quantity, d.StopLoss); err != nil {
+		for _, order := range existing {
+			if order.Kind == "stop_loss" {
+				if restoreErr := at.trader.SetStopLoss(d.Symbol, positionSide, quantity, order.Price); restoreErr != nil {
+					log.Printf("  ⚠ 恢复 %s %s 原止损 %.4f 失败: %v", d.Symbol, side, order.Price, restoreErr)
+				}
+			}
+		}
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+
+	posKey := d.Symbol + "_" + side
+	if stops, ok := at.positionProtection(posKey); ok {
+		updated := *stops
+		updated.StopLoss = d.StopLoss
+		at.setPositionProtection(posKey, &updated, false)
+	}
+	actionRecord.StopLoss = d.StopLoss
+	log.Printf("  ✓ %s %s 止损已移到 %.4f（止盈不变）", d.Symbol, side, d.StopLoss)
+	return nil
+}
+
+// finishShutdown 保存退出处理的决策记录并推送结果
+func (at *AutoTrader) finishShutdown(record *logger.DecisionRecord, policy string, err error) {
+	event := notify.Event{
+		Type:     "trader.shutdown",
+		Severity: notify.SeverityInfo,
+		TraderID: at.id,
+		Title:    fmt.Sprintf("%s 已按 %s 策略处理持仓后退出", at.name, policy),
+		Message:  strings.Join(record.ExecutionLog[1:], "\n"),
+	}
+	if err != nil {
+		record.Success = false
+		record.ErrorMessage = err.Error()
+		event.Severity = notify.SeverityCritical
+		event.Title = fmt.Sprintf("%s 退出时持仓处理未完成，请人工检查", at.name)
+		event.Message = err.Error()
+	}
+	if event.Message == "" {
+		event.Message = "没有持仓"
+	}
+	if logErr := at.decisionLogger.LogDecision(record); logErr != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", logErr)
+	}
+	notify.Send(event)
+}
@@ -0,0 +1,129 @@
+package trader
+
+import (
+	"context"
+	"math"
+	"nofx/decision"
+	"testing"
+	"time"
+)
+
+func TestIntegrationShutdownBreakEven(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	ai.Enqueue(t, "开多ETH和BTC。", openLongETH(1500), decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1500,
+		StopLoss: 58000, TakeProfit: 66000, Confidence: 80, RiskUSD: 50, Reasoning: "突破",
+	})
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+
+	ex.SetPrice("ETHUSDT", 3100)  // 盈利
+	ex.SetPrice("BTCUSDT", 59000) // 亏损，仍在止损之上
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := at.Shutdown(ctx, ShutdownBreakEven); err != nil {
+		t.Fatalf("退出处理失败: %v", err)
+	}
+
+	stops := make(map[string]float64)
+	for _, tr := range ex.Triggers("gateio", "open") {
+		if tr.kind == "stop_loss" {
+			stops[tr.symbol] = tr.price
+		}
+	}
+	entry := ex.GatePosition("ETHUSDT").entryPrice
+	if math.Abs(stops["ETHUSDT"]-entry) > 0.01 {
+		t.Errorf("盈利的ETH止损应移到入场价 %.2f，实际 %.2f", entry, stops["ETHUSDT"])
+	}
+	if stops["BTCUSDT"] != 58000 {
+		t.Errorf("亏损的BTC应保留原止损 58000，实际 %.2f", stops["BTCUSDT"])
+	}
+	if ex.GatePosition("ETHUSDT").size == 0 || ex.GatePosition("BTCUSDT").size == 0 {
+		t.Error("breakeven 不应平仓")
+	}
+
+	// 退出处理后不再开始新的周期
+	prompts := len(ai.Prompts())
+	if err := at.runCycle(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ai.Prompts()) != prompts {
+		t.Error("退出处理后不应再请求AI")
+	}
+}
+
+func TestIntegrationShutdownBreakEvenAfterRestart(t *testing.T) {
+	for _, exchange := range []string{"gateio", "binance"} {
+		t.Run(exchange, func(t *testing.T) {
+			ex, ai := setupIntegration(t)
+			at := newIntegrationTrader(t, ex, ai, exchange)
+			ai.Enqueue(t, "开多。", openLongETH(1500))
+			requireActionSuccess(t, runCycle(t, at), "open_long")
+			var tpID int64
+			for _, tr := range ex.Triggers(exchange, "open") {
+				if tr.kind == "take_profit" {
+					tpID = tr.id
+				}
+			}
+
+			// 重新部署：新进程没有记录的止损止盈价，只移动止损，止盈单保持不动
+			ex.SetPrice("ETHUSDT", 3100)
+			restarted := newIntegrationTrader(t, ex, ai, exchange)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := restarted.Shutdown(ctx, ShutdownBreakEven); err != nil {
+				t.Fatalf("退出处理失败: %v", err)
+			}
+
+			open := ex.Triggers(exchange, "open")
+			if len(open) != 2 {
+				t.Fatalf("应有止损和止盈两个条件单: %+v", open)
+			}
+			for _, tr := range open {
+				switch {
+				case tr.kind == "take_profit" && (tr.id != tpID || tr.price != 3400):
+					t.Errorf("止盈单不应被撤销重挂: %+v", tr)
+				case tr.kind == "stop_loss" && tr.price >= 3100:
+					t.Errorf("止损应移到入场价: %+v", tr)
+				case tr.kind == "stop_loss" && tr.price <= 2900:
+					t.Errorf("原止损应被替换: %+v", tr)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegrationShutdownFlatten(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	ai.Enqueue(t, "开多ETH。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := at.Shutdown(ctx, ShutdownFlatten); err != nil {
+		t.Fatalf("退出处理失败: %v", err)
+	}
+	if size := ex.GatePosition("ETHUSDT").size; size != 0 {
+		t.Errorf("flatten 应平掉全部持仓，剩余 %.0f 张", size)
+	}
+
+	records, err := at.decisionLogger.GetLatestRecords(1)
+	if err != nil || len(records) == 0 {
+		t.Fatalf("读取决策记录失败: %v", err)
+	}
+	requireActionSuccess(t, records[0], "close_long")
+
+	// 进行中的周期占用时，超时后放弃处理
+	at.shuttingDown.Store(false)
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+	short, cancelShort := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelShort()
+	if err := at.Shutdown(short, ShutdownFlatten); err == nil {
+		t.Error("等待进行中的周期超时应返回错误")
+	}
+}
@@ -0,0 +1,242 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/market"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 外部策略信号（webhook）
+// TradingView 警报等外部策略通过 webhook 发来信号，有两种用法：
+//   - hint（默认）：作为候选币种提示，在有效期内加入候选币种并写入prompt，由AI结合市场数据决定是否交易；
+//   - proposal：直接转换为开仓决策，按AI决策相同的规则校验后进入人工审批队列（与MCP下单提议相同，需启用 approval）。
+// 平仓信号只能作为提示。同一币种的新提示取代旧提示；黑名单和即将下架的币种不接受。
+
+const (
+	defaultSignalTTL = time.Hour
+	maxSignalHints   = 20 // 每个trader同时有效的提示数量上限（超出时丢弃最早的）
+)
+
+// WebhookSignal webhook收到的外部信号（兼容 TradingView 警报消息中的常用字段）
+type WebhookSignal struct {
+	TraderID   string  `json:"trader_id,omitempty"`  // 目标trader（为空时提示发给所有trader，提议发给第一个trader）
+	Token      string  `json:"token,omitempty"`      // webhook令牌（也可放在URL的 ?token=）
+	Passphrase string  `json:"passphrase,omitempty"` // 同 token（TradingView 策略常用的字段名）
+	Mode       string  `json:"mode,omitempty"`       // hint（默认）/ proposal
+	Source     string  `json:"source,omitempty"`     // 信号来源（默认 tradingview）
+	Symbol     string  `json:"symbol,omitempty"`     // 币种，如 BTCUSDT
+	Ticker     string  `json:"ticker,omitempty"`     // TradingView {{ticker}}，如 BINANCE:BTCUSDT.P（symbol 为空时使用）
+	Action     string  `json:"action"`               // buy/long、sell/short、close_long/exit_long、close_short/exit_short
+	Price      float64 `json:"price,omitempty"`      // 信号价（TradingView {{close}}）
+	StopLoss   float64 `json:"stop_loss,omitempty"`
+	TakeProfit float64 `json:"take_profit,omitempty"`
+	Leverage   int     `json:"leverage,omitempty"`   // 提议的杠杆（默认使用配置的杠杆上限）
+	SizeUSD    float64 `json:"size_usd,omitempty"`   // 提议的仓位名义价值（proposal 必填）
+	Confidence int     `json:"confidence,omitempty"` // 提议的信心度（默认70）
+	Comment    string  `json:"comment,omitempty"`    // 说明（写入prompt/提议理由）
+}
+
+// SignalResult 信号的处理结果
+type SignalResult struct {
+	TraderID  string    `json:"trader_id"`
+	Mode      string    `json:"mode"`
+	Symbol    string    `json:"symbol"`
+	Side      string    `json:"side"`
+	IdeaID    string    `json:"idea_id,omitempty"` // proposal 进入审批队列的想法ID
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// signalBook 未过期的外部信号提示
+type signalBook struct {
+	ttl   time.Duration
+	mu    sync.Mutex
+	hints []decision.ExternalSignal
+}
+
+// EnableExternalSignals 接受外部信号（ttl 为提示的有效期，<=0 时使用默认1小时）
+func (at *AutoTrader) EnableExternalSignals(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = defaultSignalTTL
+	}
+	at.signals = &signalBook{ttl: ttl}
+}
+
+// signalSymbol 信号的币种：优先 symbol，否则从 TradingView ticker 中去掉交易所前缀和永续后缀
+func (s WebhookSignal) signalSymbol() string {
+	symbol := strings.TrimSpace(s.Symbol)
+	if symbol == "" {
+		symbol = strings.TrimSpace(s.Ticker)
+		if i := strings.LastIndex(symbol, ":"); i >= 0 {
+			symbol = symbol[i+1:]
+		}
+		symbol = strings.TrimSuffix(strings.ToUpper(symbol), ".P")
+		symbol = strings.TrimSuffix(symbol, "PERP")
+	}
+	if symbol == "" {
+		return ""
+	}
+	return market.Normalize(symbol)
+}
+
+// signalSide 信号方向 long/short/close_long/close_short
+func (s WebhookSignal) signalSide() (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s.Action)) {
+	case "buy", "long", "open_long":
+		return "long", nil
+	case "sell", "short", "open_short":
+		return "short", nil
+	case "close_long", "exit_long":
+		return "close_long", nil
+	case "close_short", "exit_short":
+		return "close_short", nil
+	}
+	return "", fmt.Errorf("不支持的action: %q（可选 buy/long、sell/short、close_long、close_short）", s.Action)
+}
+
+// HandleSignal 处理外部信号：hint 加入候选币种提示，proposal 转为开仓提议进入审批队列
+func (at *AutoTrader) HandleSignal(sig WebhookSignal) (*SignalResult, error) {
+	if at.signals == nil {
+		return nil, fmt.Errorf("trader %s 未启用外部信号", at.id)
+	}
+	symbol := sig.signalSymbol()
+	if symbol == "" {
+		return nil, fmt.Errorf("信号缺少 symbol/ticker")
+	}
+	side, err := sig.signalSide()
+	if err != nil {
+		return nil, err
+	}
+	if !at.symbolFilter.Allowed(symbol) || !at.delistingFilter.Allowed(symbol) {
+		return nil, fmt.Errorf("%s 不在允许交易的币种范围内（黑白名单或即将下架）", symbol)
+	}
+	source := sig.Source
+	if source == "" {
+		source = "tradingview"
+	}
+
+	mode := strings.ToLower(sig.Mode)
+	switch mode {
+	case "", "hint":
+		return at.addSignalHint(decision.ExternalSignal{
+			Source:     source,
+			Symbol:     symbol,
+			Side:       side,
+			Price:      sig.Price,
+			StopLoss:   sig.StopLoss,
+			TakeProfit: sig.TakeProfit,
+			Comment:    sig.Comment,
+			ReceivedAt: time.Now(),
+		}), nil
+	case "proposal":
+		return at.proposeSignal(sig, source, symbol, side)
+	}
+	return nil, fmt.Errorf("不支持的mode: %q（可选 hint、proposal）", sig.Mode)
+}
+
+// addSignalHint 加入提示（同一币种的旧提示被取代）
+func (at *AutoTrader) addSignalHint(hint decision.ExternalSignal) *SignalResult {
+	b := at.signals
+	b.mu.Lock()
+	kept := b.hints[:0]
+	for _, h := range b.hints {
+		if h.Symbol != hint.Symbol {
+			kept = append(kept, h)
+		}
+	}
+	b.hints = append(kept, hint)
+	if len(b.hints) > maxSignalHints {
+		b.hints = b.hints[len(b.hints)-maxSignalHints:]
+	}
+	b.mu.Unlock()
+
+	log.Printf("📡 [%s] 收到%s信号提示: %s %s（%v 内有效）", at.name, hint.Source, hint.Symbol, hint.Side, b.ttl)
+	return &SignalResult{TraderID: at.id, Mode: "hint", Symbol: hint.Symbol, Side: hint.Side, ExpiresAt: hint.ReceivedAt.Add(b.ttl)}
+}
+
+// activeSignals 未过期的提示（同时清理过期的）
+func (b *signalBook) activeSignals(now time.Time) []decision.ExternalSignal {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	kept := b.hints[:0]
+	for _, h := range b.hints {
+		if now.Sub(h.ReceivedAt) < b.ttl {
+			kept = append(kept, h)
+		}
+	}
+	b.hints = kept
+	return append([]decision.ExternalSignal(nil), kept...)
+}
+
+// addExternalSignals 把未过期的提示写入上下文，提示的币种排到候选币种最前面（不在候选池中时加入），
+// 避免因候选数量上限拿不到市场数据
+func (at *AutoTrader) addExternalSignals(ctx *decision.Context) {
+	if at.signals == nil {
+		return
+	}
+	ctx.ExternalSignals = at.signals.activeSignals(time.Now())
+	if len(ctx.ExternalSignals) == 0 {
+		return
+	}
+	hinted := make(map[string]bool)
+	var coins []decision.CandidateCoin
+	for _, s := range ctx.ExternalSignals {
+		if hinted[s.Symbol] || !at.symbolFilter.Allowed(s.Symbol) || !at.delistingFilter.Allowed(s.Symbol) {
+			continue
+		}
+		hinted[s.Symbol] = true
+		coin := decision.CandidateCoin{Symbol: s.Symbol}
+		for _, c := range ctx.CandidateCoins {
+			if c.Symbol == s.Symbol {
+				coin.Sources = append(coin.Sources, c.Sources...)
+				break
+			}
+		}
+		coin.Sources = append(coin.Sources, "webhook")
+		coins = append(coins, coin)
+	}
+	for _, c := range ctx.CandidateCoins {
+		if !hinted[c.Symbol] {
+			coins = append(coins, c)
+		}
+	}
+	ctx.CandidateCoins = coins
+}
+
+// proposeSignal 把开仓信号转为决策，按AI决策相同的规则校验后进入审批队列
+func (at *AutoTrader) proposeSignal(sig WebhookSignal, source, symbol, side string) (*SignalResult, error) {
+	if side != "long" && side != "short" {
+		return nil, fmt.Errorf("平仓信号只能作为提示（mode=hint）")
+	}
+	leverage := sig.Leverage
+	if leverage <= 0 {
+		leverage = at.config.SymbolLimits.MaxLeverage(symbol, at.config.BTCETHLeverage, at.config.AltcoinLeverage)
+	}
+	confidence := sig.Confidence
+	if confidence <= 0 {
+		confidence = 70
+	}
+	reasoning := fmt.Sprintf("%s 信号", source)
+	if sig.Comment != "" {
+		reasoning += ": " + sig.Comment
+	}
+	d := decision.Decision{
+		SchemaVersion:   decision.DecisionSchemaVersion,
+		Symbol:          symbol,
+		Action:          "open_" + side,
+		Leverage:        leverage,
+		PositionSizeUSD: sig.SizeUSD,
+		StopLoss:        sig.StopLoss,
+		TakeProfit:      sig.TakeProfit,
+		Confidence:      confidence,
+		Reasoning:       reasoning,
+	}
+	idea, err := at.ProposeIdea(d, "webhook")
+	if err != nil {
+		return nil, err
+	}
+	return &SignalResult{TraderID: at.id, Mode: "proposal", Symbol: symbol, Side: side, IdeaID: idea.ID, ExpiresAt: idea.ExpiresAt}, nil
+}
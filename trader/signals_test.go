@@ -0,0 +1,106 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookSignalParsing(t *testing.T) {
+	cases := []struct {
+		sig    WebhookSignal
+		symbol string
+		side   string
+	}{
+		{WebhookSignal{Ticker: "BINANCE:BTCUSDT.P", Action: "buy"}, "BTCUSDT", "long"},
+		{WebhookSignal{Ticker: "BYBIT:SOLUSDT.P", Action: "Sell"}, "SOLUSDT", "short"},
+		{WebhookSignal{Ticker: "OKX:ETHUSDTPERP", Action: "exit_long"}, "ETHUSDT", "close_long"},
+		{WebhookSignal{Symbol: "ethusdt", Ticker: "BINANCE:BTCUSDT", Action: "close_short"}, "ETHUSDT", "close_short"},
+	}
+	for _, c := range cases {
+		side, err := c.sig.signalSide()
+		if got := c.sig.signalSymbol(); got != c.symbol || err != nil || side != c.side {
+			t.Errorf("%+v: 解析为 %s %s (%v)，期望 %s %s", c.sig, got, side, err, c.symbol, c.side)
+		}
+	}
+	if _, err := (WebhookSignal{Action: "hold"}).signalSide(); err == nil {
+		t.Error("不支持的action应报错")
+	}
+}
+
+func TestIntegrationSignalHint(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	if _, err := at.HandleSignal(WebhookSignal{Symbol: "SOLUSDT", Action: "buy"}); err == nil {
+		t.Fatal("未启用外部信号时不应接受信号")
+	}
+	at.EnableExternalSignals(time.Hour)
+	at.symbolFilter.Update([]string{"DOGEUSDT"}, nil)
+	if _, err := at.HandleSignal(WebhookSignal{Symbol: "DOGEUSDT", Action: "buy"}); err == nil {
+		t.Error("黑名单币种的信号应被拒绝")
+	}
+
+	if _, err := at.HandleSignal(WebhookSignal{Ticker: "BINANCE:SOLUSDT.P", Action: "sell", Comment: "跌破1h支撑"}); err != nil {
+		t.Fatalf("提示被拒绝: %v", err)
+	}
+	// 同一币种的新信号取代旧信号
+	result, err := at.HandleSignal(WebhookSignal{Ticker: "BINANCE:SOLUSDT.P", Action: "buy", Price: 150, Comment: "EMA金叉"})
+	if err != nil || result.Mode != "hint" || result.Symbol != "SOLUSDT" {
+		t.Fatalf("提示结果不符合预期: %+v (%v)", result, err)
+	}
+
+	ai.Enqueue(t, "观望。")
+	runCycle(t, at)
+	prompt := ai.Prompts()[0]
+	for _, want := range []string{"## 📡 外部策略信号", "SOLUSDT 做多（来源 tradingview", "EMA金叉", "SOLUSDT (外部信号)"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("AI输入中缺少 %q", want)
+		}
+	}
+	if strings.Contains(prompt, "跌破1h支撑") {
+		t.Error("旧信号应被取代")
+	}
+
+	// 过期后不再出现
+	at.signals.ttl = time.Nanosecond
+	ai.Enqueue(t, "观望。")
+	runCycle(t, at)
+	if prompts := ai.Prompts(); strings.Contains(prompts[len(prompts)-1], "外部策略信号") {
+		t.Error("过期的信号不应写入AI输入")
+	}
+}
+
+func TestIntegrationSignalProposal(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableExternalSignals(time.Hour)
+
+	sig := WebhookSignal{Mode: "proposal", Symbol: "ETHUSDT", Action: "buy", SizeUSD: 1500, StopLoss: 2900, TakeProfit: 3400, Comment: "突破"}
+	if _, err := at.HandleSignal(sig); err == nil {
+		t.Fatal("未启用人工审批时提议应被拒绝")
+	}
+	at.approval = newApprovalQueue(time.Minute)
+
+	if _, err := at.HandleSignal(WebhookSignal{Mode: "proposal", Symbol: "ETHUSDT", Action: "close_long"}); err == nil {
+		t.Error("平仓信号不能作为提议")
+	}
+	if _, err := at.HandleSignal(WebhookSignal{Mode: "proposal", Symbol: "ETHUSDT", Action: "buy", SizeUSD: 1500}); err == nil {
+		t.Error("缺少止损止盈的提议应被校验拒绝")
+	}
+
+	result, err := at.HandleSignal(sig)
+	if err != nil {
+		t.Fatalf("提议被拒绝: %v", err)
+	}
+	ideas := at.TradeIdeas()
+	if len(ideas) != 1 || ideas[0].ID != result.IdeaID || ideas[0].Source != "webhook" {
+		t.Fatalf("提议应进入审批队列: %+v", ideas)
+	}
+	if d := ideas[0].Decision; d.Action != "open_long" || d.Leverage != 10 || !strings.Contains(d.Reasoning, "突破") {
+		t.Errorf("提议的决策不符合预期: %+v", d)
+	}
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 0 {
+		t.Error("批准前不应开仓")
+	}
+}
@@ -0,0 +1,204 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/vectorstore"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 相似历史情形检索
+// 开仓时把当时的指标状态（离散化为 "rsi7:oversold" 这类特征词）和AI的理由存入向量库，
+// 平仓后从决策日志匹配结果；每个周期获取行情后，按币种检索最相似的已平仓情形写入AI提示。
+
+const (
+	defaultSimilarSetupsTopK     = 3
+	defaultSimilarSetupsMinScore = 0.7
+	maxSimilarSetups             = 10 // 写入提示的总条数上限
+	similarSetupsOutcomeDays     = 30 // 待补结果的开仓最多向前匹配的天数
+)
+
+// similarSetups 相似情形检索配置
+type similarSetups struct {
+	store    *vectorstore.Store
+	topK     int     // 每个币种最多检索几条
+	minScore float64 // 最低相似度
+}
+
+// EnableSimilarSetups 启用相似历史情形检索（向量库保存在决策日志目录的 setups.jsonl）
+func (at *AutoTrader) EnableSimilarSetups(embedder vectorstore.Embedder, topK int, minScore float64) error {
+	if topK <= 0 {
+		topK = defaultSimilarSetupsTopK
+	}
+	if minScore <= 0 {
+		minScore = defaultSimilarSetupsMinScore
+	}
+	store, err := vectorstore.Open(filepath.Join(at.decisionLogger.Dir(), "setups.jsonl"), embedder)
+	if err != nil {
+		return err
+	}
+	at.setups = &similarSetups{store: store, topK: topK, minScore: minScore}
+	return nil
+}
+
+// situationText 把行情离散化为特征词（相近的指标状态得到相近的向量）
+func situationText(symbol string, data *market.Data) string {
+	bucket := func(v float64, bounds [4]float64, names [5]string) string {
+		for i, b := range bounds {
+			if v < b {
+				return names[i]
+			}
+		}
+		return names[4]
+	}
+	moves := [5]string{"dump", "down", "flat", "up", "pump"}
+
+	features := []string{
+		symbol,
+		"chg1h:" + bucket(data.PriceChange1h, [4]float64{-2, -0.5, 0.5, 2}, moves),
+		"chg4h:" + bucket(data.PriceChange4h, [4]float64{-4, -1, 1, 4}, moves),
+		"rsi7:" + bucket(data.CurrentRSI7, [4]float64{30, 45, 55, 70}, [5]string{"oversold", "weak", "neutral", "strong", "overbought"}),
+	}
+	if data.CurrentMACD >= 0 {
+		features = append(features, "macd:positive")
+	} else {
+		features = append(features, "macd:negative")
+	}
+	if data.CurrentPrice >= data.CurrentEMA20 {
+		features = append(features, "price:above_ema20")
+	} else {
+		features = append(features, "price:below_ema20")
+	}
+	if lt := data.LongerTermContext; lt != nil {
+		if lt.EMA20 >= lt.EMA50 {
+			features = append(features, "trend4h:bull")
+		} else {
+			features = append(features, "trend4h:bear")
+		}
+		if lt.AverageVolume > 0 {
+			features = append(features, "volume:"+bucket(lt.CurrentVolume/lt.AverageVolume, [4]float64{0.5, 0.8, 1.2, 2}, [5]string{"very_low", "low", "normal", "high", "very_high"}))
+		}
+		if data.CurrentPrice > 0 {
+			features = append(features, "atr4h:"+bucket(lt.ATR14/data.CurrentPrice*100, [4]float64{0.5, 1, 2, 4}, [5]string{"very_low", "low", "normal", "high", "very_high"}))
+		}
+	}
+	features = append(features, "funding:"+bucket(data.FundingRate*100, [4]float64{-0.05, -0.005, 0.005, 0.05}, [5]string{"very_negative", "negative", "neutral", "positive", "very_positive"}))
+	return strings.Join(features, " ")
+}
+
+// recallSimilarSetups 按当前行情检索各币种相似的历史情形（作为 decision.Context.Recall）
+func (at *AutoTrader) recallSimilarSetups(marketData map[string]*market.Data) []decision.SimilarSetup {
+	symbols := make([]string, 0, len(marketData))
+	for symbol := range marketData {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	var setups []decision.SimilarSetup
+	for _, symbol := range symbols {
+		matches, err := at.setups.store.Search(symbol, situationText(symbol, marketData[symbol]), at.setups.topK, at.setups.minScore)
+		if err != nil {
+			log.Printf("⚠️  检索 %s 相似情形失败: %v", symbol, err)
+			return setups
+		}
+		for _, m := range matches {
+			setups = append(setups, decision.SimilarSetup{
+				Symbol:    m.Symbol,
+				Side:      m.Side,
+				Time:      m.Time,
+				Score:     m.Score,
+				Reasoning: m.Reasoning,
+				PnL:       m.Outcome.PnL,
+				RMultiple: m.Outcome.RMultiple,
+				Duration:  m.Outcome.Duration,
+			})
+		}
+	}
+	sort.SliceStable(setups, func(i, j int) bool { return setups[i].Score > setups[j].Score })
+	if len(setups) > maxSimilarSetups {
+		setups = setups[:maxSimilarSetups]
+	}
+	return setups
+}
+
+// indexOpenedSetups 本周期成功开仓的情形存入向量库（结果在平仓后补上）
+func (at *AutoTrader) indexOpenedSetups(ctx *decision.Context, decisions []decision.Decision, record *logger.DecisionRecord) {
+	if at.setups == nil {
+		return
+	}
+	for _, action := range record.Decisions {
+		if !action.Success || (action.Action != "open_long" && action.Action != "open_short") {
+			continue
+		}
+		data, ok := ctx.MarketDataMap[action.Symbol]
+		if !ok {
+			continue
+		}
+		reasoning := ""
+		for _, d := range decisions {
+			if d.Symbol == action.Symbol && d.Action == action.Action {
+				reasoning = d.Reasoning
+			}
+		}
+		side := strings.TrimPrefix(action.Action, "open_")
+		entry := vectorstore.Entry{
+			ID:         fmt.Sprintf("%s_%s_%d", action.Symbol, side, action.Timestamp.UnixNano()),
+			PositionID: action.PositionID,
+			Symbol:     action.Symbol,
+			Side:       side,
+			Time:       action.Timestamp,
+			Situation:  situationText(action.Symbol, data),
+			Reasoning:  reasoning,
+		}
+		if err := at.setups.store.Add(entry); err != nil {
+			log.Printf("⚠️  保存 %s 开仓情形失败: %v", action.Symbol, err)
+		}
+	}
+}
+
+// updateSetupOutcomes 从决策日志匹配已平仓交易，补上待定情形的结果
+func (at *AutoTrader) updateSetupOutcomes() {
+	pending := at.setups.store.Pending()
+	if len(pending) == 0 {
+		return
+	}
+	trades, err := at.decisionLogger.ClosedTrades(similarSetupsOutcomeDays, at.localNow())
+	if err != nil {
+		log.Printf("⚠️  读取已平仓交易失败: %v", err)
+		return
+	}
+
+	outcomes := make(map[string]vectorstore.Outcome)
+	for _, entry := range pending {
+		for _, t := range trades {
+			samePosition := entry.PositionID != "" && t.PositionID == entry.PositionID
+			if samePosition || (t.Symbol == entry.Symbol && t.Side == entry.Side && t.OpenTime.Equal(entry.Time)) {
+				outcomes[entry.ID] = vectorstore.Outcome{
+					PnL:       t.PnL,
+					PnLPct:    t.PnLPct,
+					RMultiple: t.RMultiple,
+					Duration:  t.Duration,
+					ClosedAt:  t.CloseTime,
+				}
+				break
+			}
+		}
+	}
+	if err := at.setups.store.SetOutcomes(outcomes); err != nil {
+		log.Printf("⚠️  保存交易结果失败: %v", err)
+	}
+}
+
+// addSimilarSetups 补上已平仓情形的结果，并在获取行情后检索相似情形
+func (at *AutoTrader) addSimilarSetups(ctx *decision.Context) {
+	if at.setups == nil {
+		return
+	}
+	at.updateSetupOutcomes()
+	ctx.Recall = at.recallSimilarSetups
+}
@@ -0,0 +1,53 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/vectorstore"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationSimilarSetupsInPrompt(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	if err := at.EnableSimilarSetups(vectorstore.NewHashEmbedder(0), 3, 0.7); err != nil {
+		t.Fatal(err)
+	}
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	if pending := at.setups.store.Pending(); len(pending) != 1 || pending[0].Reasoning != "突破关键阻力位" {
+		t.Fatalf("开仓情形应存入向量库: %+v", pending)
+	}
+
+	// 平仓前没有已有结果的情形，不写入提示
+	ex.SetPrice("ETHUSDT", 3100)
+	ai.Enqueue(t, "止盈。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "到达目标"})
+	requireActionSuccess(t, runCycle(t, at), "close_long")
+	prompts := ai.Prompts()
+	if strings.Contains(prompts[len(prompts)-1], "相似的历史情形") {
+		t.Error("没有已平仓的情形时不应输出相似情形")
+	}
+
+	// 平仓后补上结果，相同指标状态下检索到该情形
+	runCycle(t, at)
+	prompts = ai.Prompts()
+	last := prompts[len(prompts)-1]
+	for _, want := range []string{"相似的历史情形及结果", "ETHUSDT", "LONG（相似度1.00）| 结果 +50.00 USDT，+1.00R", "当时理由: 突破关键阻力位"} {
+		if !strings.Contains(last, want) {
+			t.Errorf("AI输入中缺少 %q", want)
+		}
+	}
+	if len(at.setups.store.Pending()) != 0 {
+		t.Error("平仓后应补上结果")
+	}
+
+	// 重启后从文件恢复
+	store, err := vectorstore.Open(at.decisionLogger.Dir()+"/setups.jsonl", vectorstore.NewHashEmbedder(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Len() != 1 || len(store.Pending()) != 0 {
+		t.Errorf("向量库文件内容不符合预期: %d 条, %d 条待定", store.Len(), len(store.Pending()))
+	}
+}
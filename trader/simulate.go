@@ -0,0 +1,260 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"nofx/decision"
+	"nofx/market"
+	"strconv"
+)
+
+// 交易模拟（仓位预览）
+// 按执行时相同的方式计算一个假设的开仓/加仓决策：数量按交易所精度（或合约张数）取整、所需保证金、
+// 估算强平价、手续费和下单后的保证金使用率，不下单、不写决策日志，用于界面预览和调试提示词。
+// 强平价按逐仓估算（固定维持保证金率），全仓模式下实际强平价取决于整个账户，仅供参考。
+
+const (
+	simulationMaintenanceRate = 0.005  // 估算强平价使用的维持保证金率（主流币第一档约0.4%-0.5%）
+	simulationTakerFeeRate    = 0.0005 // 未能查询账户费率时使用的吃单费率
+)
+
+// ContractSizer 以合约张数下单的交易器（可选接口，如Gate.io）
+type ContractSizer interface {
+	// ContractSize 币数量换算成下单张数（与下单时相同的取整和最小张数），multiplier为每张合约的币数量
+	ContractSize(symbol string, quantity float64) (contracts int64, multiplier float64, err error)
+}
+
+// SimulationAccount 模拟前后的账户保证金
+type SimulationAccount struct {
+	TotalEquity           float64 `json:"total_equity"`
+	AvailableBalance      float64 `json:"available_balance"`
+	MarginUsed            float64 `json:"margin_used"`
+	MarginUsedPct         float64 `json:"margin_used_pct"`
+	MarginUsedAfter       float64 `json:"margin_used_after"`
+	MarginUsedPctAfter    float64 `json:"margin_used_pct_after"`
+	AvailableBalanceAfter float64 `json:"available_balance_after"`
+	MaxMarginUsagePct     float64 `json:"max_margin_usage_pct"`
+	CheckBeforeOpen       bool    `json:"check_available_before_open"` // 执行时是否检查余额和保证金使用率
+}
+
+// TradeSimulation 交易模拟结果（金额为报告币种，价格为交易对计价币种）
+type TradeSimulation struct {
+	TraderID        string  `json:"trader_id"`
+	Symbol          string  `json:"symbol"`
+	Action          string  `json:"action"`
+	Side            string  `json:"side"`
+	Price           float64 `json:"price"` // 模拟成交价（当前价）
+	Leverage        int     `json:"leverage"`
+	PositionSizeUSD float64 `json:"position_size_usd"` // 决策给出的仓位金额
+	Quantity        float64 `json:"quantity"`          // 取整后的下单数量（币）
+	Contracts       float64 `json:"contracts"`         // 下单张数（以张下单的交易所），否则等于数量
+	ContractSize    float64 `json:"contract_size,omitempty"`
+	NotionalUSD     float64 `json:"notional_usd"` // 取整后的名义价值
+
+	MarginRequired   float64 `json:"margin_required"`
+	SafetyBuffer     float64 `json:"safety_buffer"`     // 执行时余额检查额外要求的缓冲
+	EntryPrice       float64 `json:"entry_price"`       // 加仓后的持仓均价（开仓为成交价）
+	LiquidationPrice float64 `json:"liquidation_price"` // 估算强平价（逐仓）
+	MaintenanceRate  float64 `json:"maintenance_rate"`
+
+	FeeRate   float64 `json:"fee_rate"`
+	OpenFee   float64 `json:"open_fee"`
+	CloseFee  float64 `json:"close_fee"` // 按当前价平仓的手续费
+	TotalFees float64 `json:"total_fees"`
+
+	StopLossPnL   float64 `json:"stop_loss_pnl,omitempty"`   // 止损出场的盈亏（含开平手续费）
+	TakeProfitPnL float64 `json:"take_profit_pnl,omitempty"` // 止盈出场的盈亏（含开平手续费）
+
+	Account         SimulationAccount `json:"account"`
+	ValidationError string            `json:"validation_error,omitempty"` // 作为AI决策提交时会被拒绝的原因
+	Warnings        []string          `json:"warnings,omitempty"`
+}
+
+// SimulateDecision 模拟执行一个开仓/加仓决策，不下单
+func (at *AutoTrader) SimulateDecision(d decision.Decision) (*TradeSimulation, error) {
+	d.Symbol = market.Normalize(d.Symbol)
+	if !entryActions[d.Action] {
+		return nil, fmt.Errorf("只能模拟开仓/加仓（open_long/open_short/add_to_position），收到: %s", d.Action)
+	}
+	if d.PositionSizeUSD <= 0 {
+		return nil, fmt.Errorf("需要大于0的position_size_usd")
+	}
+
+	snap, err := at.accountSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("获取账户失败: %w", err)
+	}
+	sim := &TradeSimulation{
+		TraderID:        at.id,
+		Symbol:          d.Symbol,
+		Action:          d.Action,
+		PositionSizeUSD: d.PositionSizeUSD,
+		Leverage:        d.Leverage,
+		MaintenanceRate: simulationMaintenanceRate,
+	}
+	validated := d
+	if err := decision.ValidateDecision(&validated, snap.TotalEquity, at.config.BTCETHLeverage, at.config.AltcoinLeverage,
+		at.config.MinPositionSizeUSD, at.config.MaxPositionSizeUSD, at.symbolFilter, at.config.SymbolLimits); err != nil {
+		sim.ValidationError = err.Error()
+	}
+
+	// 方向和杠杆（加仓沿用持仓）
+	var existingNotional, existingEntry float64
+	switch d.Action {
+	case "open_long":
+		sim.Side = "long"
+	case "open_short":
+		sim.Side = "short"
+	case "add_to_position":
+		pos := simulationPosition(snap.Positions, d)
+		if pos == nil {
+			return nil, fmt.Errorf("%s 没有可加仓的持仓", d.Symbol)
+		}
+		sim.Side, _ = pos["side"].(string)
+		existingEntry, _ = pos["entryPrice"].(float64)
+		amount, _ := pos["positionAmt"].(float64)
+		markPrice, _ := pos["markPrice"].(float64)
+		existingNotional = math.Abs(amount) * markPrice // 持仓数量的单位因交易所而异（Gate.io为张数），名义价值一致
+		if sim.Leverage <= 0 {
+			sim.Leverage = positionLeverage(pos)
+		}
+	}
+	if sim.Leverage <= 0 {
+		return nil, fmt.Errorf("需要大于0的leverage")
+	}
+
+	data, err := market.Get(d.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 行情失败: %w", d.Symbol, err)
+	}
+	sim.Price = data.CurrentPrice
+	if sim.Price <= 0 {
+		return nil, fmt.Errorf("%s 当前价无效", d.Symbol)
+	}
+
+	// 数量：与下单时相同的取整
+	rawQuantity, err := at.quantityFor(d.Symbol, d.PositionSizeUSD, sim.Price)
+	if err != nil {
+		return nil, err
+	}
+	sim.Quantity, sim.Contracts, sim.ContractSize, err = at.orderSize(d.Symbol, rawQuantity)
+	if err != nil {
+		return nil, err
+	}
+	if sim.Quantity <= 0 {
+		return nil, fmt.Errorf("%s 仓位 %.2f 按交易所精度取整后数量为0", d.Symbol, d.PositionSizeUSD)
+	}
+	perQuote := d.PositionSizeUSD / (rawQuantity * sim.Price) // 计价币种折算成报告币种
+	sim.NotionalUSD = sim.Quantity * sim.Price * perQuote
+	if math.Abs(sim.NotionalUSD-d.PositionSizeUSD) > d.PositionSizeUSD*0.01 {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("按交易所精度取整后仓位 %.2f → %.2f", d.PositionSizeUSD, sim.NotionalUSD))
+	}
+
+	// 保证金和强平价
+	sim.MarginRequired = sim.NotionalUSD / float64(sim.Leverage)
+	sim.SafetyBuffer = sim.MarginRequired * at.config.SafetyBufferPct / 100
+	sim.EntryPrice = sim.Price
+	if existingNotional > 0 && existingEntry > 0 {
+		existingQty := existingNotional / sim.Price
+		sim.EntryPrice = (existingQty*existingEntry + sim.Quantity*sim.Price) / (existingQty + sim.Quantity)
+	}
+	sim.LiquidationPrice = estimateLiquidationPrice(sim.Side, sim.EntryPrice, sim.Leverage)
+
+	// 手续费
+	sim.FeeRate = simulationTakerFeeRate
+	if at.accountPermissions != nil && at.accountPermissions.TakerFeeRate > 0 {
+		sim.FeeRate = at.accountPermissions.TakerFeeRate
+	}
+	sim.OpenFee = sim.NotionalUSD * sim.FeeRate
+	sim.CloseFee = sim.OpenFee
+	sim.TotalFees = sim.OpenFee + sim.CloseFee
+
+	// 止损/止盈出场的盈亏
+	exitPnL := func(exit float64) float64 {
+		move := exit - sim.Price
+		if sim.Side == "short" {
+			move = -move
+		}
+		return move*sim.Quantity*perQuote - sim.OpenFee - exit*sim.Quantity*perQuote*sim.FeeRate
+	}
+	if d.StopLoss > 0 {
+		sim.StopLossPnL = exitPnL(d.StopLoss)
+		if (sim.Side == "long" && d.StopLoss <= sim.LiquidationPrice) || (sim.Side == "short" && d.StopLoss >= sim.LiquidationPrice) {
+			sim.Warnings = append(sim.Warnings, fmt.Sprintf("止损价 %.4f 在估算强平价 %.4f 之外，止损前可能被强平", d.StopLoss, sim.LiquidationPrice))
+		}
+	}
+	if d.TakeProfit > 0 {
+		sim.TakeProfitPnL = exitPnL(d.TakeProfit)
+	}
+
+	// 下单后的账户
+	sim.Account = SimulationAccount{
+		TotalEquity:           snap.TotalEquity,
+		AvailableBalance:      snap.AvailableBalance,
+		MarginUsed:            snap.MarginUsed,
+		MarginUsedPct:         snap.MarginUsedPct,
+		MarginUsedAfter:       snap.MarginUsed + sim.MarginRequired,
+		AvailableBalanceAfter: snap.AvailableBalance - sim.MarginRequired - sim.OpenFee,
+		MaxMarginUsagePct:     at.config.MaxMarginUsagePct,
+		CheckBeforeOpen:       at.config.CheckAvailableBeforeOpen,
+	}
+	if snap.TotalEquity > 0 {
+		sim.Account.MarginUsedPctAfter = sim.Account.MarginUsedAfter / snap.TotalEquity * 100
+	}
+	if snap.AvailableBalance < sim.MarginRequired+sim.SafetyBuffer {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("可用余额不足：需要 %.2f（保证金 %.2f + 缓冲 %.2f），可用 %.2f",
+			sim.MarginRequired+sim.SafetyBuffer, sim.MarginRequired, sim.SafetyBuffer, snap.AvailableBalance))
+	}
+	if at.config.MaxMarginUsagePct > 0 && sim.Account.MarginUsedPctAfter > at.config.MaxMarginUsagePct {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("下单后保证金使用率 %.1f%% 超过上限 %.1f%%",
+			sim.Account.MarginUsedPctAfter, at.config.MaxMarginUsagePct))
+	}
+	return sim, nil
+}
+
+// orderSize 数量按交易所下单精度取整，返回取整后的币数量、下单张数和每张合约的币数量（不以张下单时为0）
+func (at *AutoTrader) orderSize(symbol string, quantity float64) (float64, float64, float64, error) {
+	if sizer, ok := at.trader.(ContractSizer); ok {
+		contracts, multiplier, err := sizer.ContractSize(symbol, quantity)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return float64(contracts) * multiplier, float64(contracts), multiplier, nil
+	}
+	formatted, err := at.trader.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	rounded, err := strconv.ParseFloat(formatted, 64)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("解析数量 %q 失败: %w", formatted, err)
+	}
+	return rounded, rounded, 0, nil
+}
+
+// estimateLiquidationPrice 逐仓强平价估算：亏损达到初始保证金减去维持保证金时强平
+func estimateLiquidationPrice(side string, entry float64, leverage int) float64 {
+	if side == "short" {
+		return entry * (1 + 1/float64(leverage) - simulationMaintenanceRate)
+	}
+	return math.Max(0, entry*(1-1/float64(leverage)+simulationMaintenanceRate))
+}
+
+// simulationPosition 加仓对应的持仓（未指定方向时该币种只能有一个方向的持仓）
+func simulationPosition(positions []map[string]interface{}, d decision.Decision) map[string]interface{} {
+	var found map[string]interface{}
+	for _, pos := range positions {
+		if symbol, _ := pos["symbol"].(string); symbol != d.Symbol {
+			continue
+		}
+		side, _ := pos["side"].(string)
+		if d.Side != "" && side != d.Side {
+			continue
+		}
+		if found != nil {
+			return nil // 双向持仓时需要指定side
+		}
+		found = pos
+	}
+	return found
+}
@@ -0,0 +1,73 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"testing"
+)
+
+func TestIntegrationSimulateDecision(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	// 1500 USDT / 3000 = 0.5 ETH = 50张（每张0.01 ETH），5倍杠杆
+	sim, err := at.SimulateDecision(openLongETH(1500))
+	if err != nil {
+		t.Fatalf("模拟失败: %v", err)
+	}
+	if sim.Side != "long" || sim.Contracts != 50 || sim.ContractSize != 0.01 || math.Abs(sim.Quantity-0.5) > 1e-9 {
+		t.Fatalf("数量/张数不对: %+v", sim)
+	}
+	if math.Abs(sim.MarginRequired-300) > 1e-6 || math.Abs(sim.SafetyBuffer-15) > 1e-6 {
+		t.Fatalf("保证金 = %.4f（缓冲 %.4f），期望 300（15）", sim.MarginRequired, sim.SafetyBuffer)
+	}
+	if math.Abs(sim.LiquidationPrice-2415) > 1e-6 {
+		t.Fatalf("估算强平价 = %.4f，期望 2415", sim.LiquidationPrice)
+	}
+	if math.Abs(sim.OpenFee-0.75) > 1e-9 || math.Abs(sim.TotalFees-1.5) > 1e-9 {
+		t.Fatalf("手续费不对: 开仓 %.4f 合计 %.4f", sim.OpenFee, sim.TotalFees)
+	}
+	// 止损 2900：-50 - 开仓0.75 - 平仓0.725
+	if math.Abs(sim.StopLossPnL+51.475) > 1e-9 {
+		t.Fatalf("止损盈亏 = %.4f，期望 -51.475", sim.StopLossPnL)
+	}
+	if math.Abs(sim.Account.MarginUsedAfter-300) > 1e-6 || math.Abs(sim.Account.MarginUsedPctAfter-300/sim.Account.TotalEquity*100) > 1e-9 {
+		t.Fatalf("下单后保证金使用率不对: %+v", sim.Account)
+	}
+	if sim.ValidationError != "" || len(sim.Warnings) != 0 {
+		t.Fatalf("不应有错误或警告: %q %v", sim.ValidationError, sim.Warnings)
+	}
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 0 {
+		t.Fatalf("模拟不应下单，持仓 %+v", pos)
+	}
+
+	// 超过保证金使用率上限（80%）：仍返回结果并给出警告
+	big := openLongETH(45000)
+	big.Leverage = 5
+	sim, err = at.SimulateDecision(big)
+	if err != nil {
+		t.Fatalf("模拟失败: %v", err)
+	}
+	if sim.Account.MarginUsedPctAfter <= 80 || len(sim.Warnings) == 0 {
+		t.Fatalf("应警告保证金使用率超限: %+v", sim)
+	}
+
+	// 加仓：沿用持仓杠杆，强平价按加仓后的均价估算
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	ex.SetPrice("ETHUSDT", 3200)
+	sim, err = at.SimulateDecision(decision.Decision{Symbol: "ETHUSDT", Action: "add_to_position", PositionSizeUSD: 1600})
+	if err != nil {
+		t.Fatalf("模拟加仓失败: %v", err)
+	}
+	if sim.Leverage != 5 || sim.Contracts != 50 || math.Abs(sim.EntryPrice-3100) > 1e-6 {
+		t.Fatalf("加仓模拟不对: %+v", sim)
+	}
+	if pos := ex.GatePosition("ETHUSDT"); pos.size != 50 {
+		t.Fatalf("模拟加仓不应改变持仓: %+v", pos)
+	}
+
+	if _, err := at.SimulateDecision(decision.Decision{Symbol: "ETHUSDT", Action: "close_long"}); err == nil {
+		t.Fatal("平仓决策不应支持模拟")
+	}
+}
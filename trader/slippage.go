@@ -0,0 +1,44 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"strconv"
+)
+
+// intendedPrice 决策时AI看到的价格（不在本周期行情数据中的币种返回0，之后以下单时市价代替）
+func intendedPrice(ctx *decision.Context, symbol string) float64 {
+	if data, ok := ctx.MarketDataMap[symbol]; ok && data != nil {
+		return data.CurrentPrice
+	}
+	return 0
+}
+
+// recordFill 记录订单方向、提交的限价和成交均价（交易所未返回的字段保持为0）
+// 下单结果按Binance格式读取 price/avgPrice，Gate.io 的成交均价在 fill_price 中
+func recordFill(actionRecord *logger.DecisionAction, order map[string]interface{}, isBuy bool) {
+	actionRecord.Side = "sell"
+	if isBuy {
+		actionRecord.Side = "buy"
+	}
+	if actionRecord.IntendedPrice <= 0 {
+		actionRecord.IntendedPrice = actionRecord.Price
+	}
+	actionRecord.LimitPrice = orderPrice(order, "price")
+	actionRecord.FillPrice = orderPrice(order, "avgPrice")
+	if actionRecord.FillPrice <= 0 {
+		actionRecord.FillPrice = orderPrice(order, "fill_price")
+	}
+}
+
+// orderPrice 读取下单结果中的价格字段（数字或字符串）
+func orderPrice(order map[string]interface{}, key string) float64 {
+	switch v := order[key].(type) {
+	case float64:
+		return v
+	case string:
+		price, _ := strconv.ParseFloat(v, 64)
+		return price
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"testing"
+)
+
+func TestIntegrationSlippageAnalytics(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	ex.SetFillSlippage(0.002) // 每笔成交不利偏移 20bp
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+
+	open := record.Decisions[0]
+	if open.Side != "buy" || open.IntendedPrice != 3000 || math.Abs(open.FillPrice-3006) > 0.01 {
+		t.Fatalf("开仓执行价格记录不符合预期: %+v", open)
+	}
+	if open.LimitPrice <= open.Price {
+		t.Errorf("IOC买单的限价应高于下单时市价: limit=%.2f market=%.2f", open.LimitPrice, open.Price)
+	}
+
+	ai.Enqueue(t, "平多。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "止盈离场"})
+	requireActionSuccess(t, runCycle(t, at), "close_long")
+
+	samples, err := at.decisionLogger.ExecutionSamples(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("成交样本数 = %d, 期望 2", len(samples))
+	}
+	for i := range samples {
+		samples[i].Exchange = at.GetExchange()
+	}
+
+	// 记录的执行价格进入滑点统计（统计口径见 logger 的单元测试）
+	eth := logger.AnalyzeSlippage(samples).BySymbol["ETHUSDT"]
+	if eth == nil || eth.Orders != 2 || math.Abs(eth.AvgBps-20) > 0.5 || eth.LimitOrders != 2 {
+		t.Fatalf("买卖两笔的不利滑点都应约为20bp: %+v", eth)
+	}
+}
@@ -0,0 +1,146 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"time"
+)
+
+// 决策延迟预算与过期行情保护
+// AI看到的是获取行情那一刻的价格，模型响应慢（或复核再调用一次）时，执行前价格可能已经走远。
+// 从行情快照到执行超过延迟预算，或最新价相对快照偏离超过阈值时，开仓/加仓按最新价重新校验：
+// 最新价仍在止损和止盈之间且风险回报比达标才执行，否则转为观望。
+// 下单价本来就由交易器按下单时的最新价计算（IOC限价也是），不会按快照价挂单，所以只需要校验止损止盈是否仍然成立。
+// 平仓、部分平仓和止损止盈调整降低风险，不受影响。
+
+const (
+	defaultLatencyBudget   = 90 * time.Second
+	defaultMaxPriceMovePct = 0.5
+	staleMinRiskReward     = 3.0 // 与决策验证的风险回报比要求一致
+)
+
+// staleGuard 过期行情保护配置
+type staleGuard struct {
+	budget     time.Duration // 行情快照到执行的最长耗时
+	maxMovePct float64       // 最新价相对快照的最大偏离（百分比）
+}
+
+// EnableStaleDataGuard 启用决策延迟预算与过期行情保护
+func (at *AutoTrader) EnableStaleDataGuard(budget time.Duration, maxMovePct float64) {
+	if budget <= 0 {
+		budget = defaultLatencyBudget
+	}
+	if maxMovePct <= 0 {
+		maxMovePct = defaultMaxPriceMovePct
+	}
+	at.staleGuard = &staleGuard{budget: budget, maxMovePct: maxMovePct}
+}
+
+// recordDecisionTiming 决策记录中保存行情快照和AI返回的时间
+func recordDecisionTiming(ctx *decision.Context, full *decision.FullDecision, record *logger.DecisionRecord) {
+	record.MarketDataAt = ctx.MarketDataTime
+	if full != nil && !full.Timestamp.IsZero() {
+		record.AIResponseAt = full.Timestamp
+		if !ctx.MarketDataTime.IsZero() {
+			record.DecisionLatencyMs = full.Timestamp.Sub(ctx.MarketDataTime).Milliseconds()
+		}
+	}
+}
+
+// guardStaleDecisions 行情过期或价格偏离时按最新价重新校验开仓/加仓，不再成立的转为观望
+func (at *AutoTrader) guardStaleDecisions(ctx *decision.Context, decisions []decision.Decision, record *logger.DecisionRecord) []decision.Decision {
+	if at.staleGuard == nil || ctx.MarketDataTime.IsZero() {
+		return decisions
+	}
+	elapsed := time.Since(ctx.MarketDataTime)
+	overBudget := elapsed > at.staleGuard.budget
+	if overBudget {
+		log.Printf("⏱ 行情快照已过去 %.0f 秒（预算 %.0f 秒），按最新价复核开仓/加仓", elapsed.Seconds(), at.staleGuard.budget.Seconds())
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏱ 行情快照已过去 %.0f 秒，超过延迟预算 %.0f 秒", elapsed.Seconds(), at.staleGuard.budget.Seconds()))
+	}
+
+	kept := make([]decision.Decision, 0, len(decisions))
+	for _, d := range decisions {
+		if d.Action != "open_long" && d.Action != "open_short" && d.Action != "add_to_position" {
+			kept = append(kept, d)
+			continue
+		}
+
+		fresh, err := at.trader.GetMarketPrice(d.Symbol)
+		if err != nil {
+			// 拿不到最新价时无法确认，保守起见不执行
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏱ %s %s 转为观望：获取最新价失败（%v）", d.Symbol, d.Action, err))
+			continue
+		}
+		snapshot := intendedPrice(ctx, d.Symbol)
+		movePct := 0.0
+		if snapshot > 0 {
+			movePct = math.Abs(fresh-snapshot) / snapshot * 100
+		}
+		if !overBudget && movePct <= at.staleGuard.maxMovePct {
+			kept = append(kept, d)
+			continue
+		}
+
+		if reason := at.staleRejection(d, fresh); reason != "" {
+			log.Printf("⏱ %s %s 转为观望: 快照价 %.4f → 最新价 %.4f（%.2f%%），%s", d.Symbol, d.Action, snapshot, fresh, movePct, reason)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏱ %s %s 转为观望：快照价 %.4f → 最新价 %.4f（偏离 %.2f%%），%s",
+				d.Symbol, d.Action, snapshot, fresh, movePct, reason))
+			continue
+		}
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏱ %s %s 按最新价 %.4f 复核通过（快照价 %.4f，偏离 %.2f%%）",
+			d.Symbol, d.Action, fresh, snapshot, movePct))
+		kept = append(kept, d)
+	}
+	return kept
+}
+
+// staleRejection 按最新价检查止损止盈是否仍然成立，返回拒绝原因（空表示通过）
+func (at *AutoTrader) staleRejection(d decision.Decision, price float64) string {
+	side := d.Side
+	switch d.Action {
+	case "open_long":
+		side = "long"
+	case "open_short":
+		side = "short"
+	}
+	stopLoss, takeProfit := d.StopLoss, d.TakeProfit
+	if d.Action == "add_to_position" {
+		// 加仓未给出的止损止盈沿用持仓当前的
+		if side == "" {
+			if s, _, _, err := at.findPosition(d.Symbol, ""); err == nil {
+				side = s
+			}
+		}
+		if stops, ok := at.positionStops[d.Symbol+"_"+side]; ok {
+			if stopLoss <= 0 {
+				stopLoss = stops.StopLoss
+			}
+			if takeProfit <= 0 {
+				takeProfit = stops.TakeProfit
+			}
+		}
+	}
+	if stopLoss <= 0 || takeProfit <= 0 {
+		return "" // 没有止损止盈可供校验
+	}
+
+	var risk, reward float64
+	if side == "short" {
+		risk, reward = stopLoss-price, price-takeProfit
+	} else {
+		risk, reward = price-stopLoss, takeProfit-price
+	}
+	switch {
+	case risk <= 0:
+		return fmt.Sprintf("最新价已越过止损价 %.4f", stopLoss)
+	case reward <= 0:
+		return fmt.Sprintf("最新价已越过止盈价 %.4f", takeProfit)
+	case d.Action != "add_to_position" && reward/risk < staleMinRiskReward:
+		return fmt.Sprintf("按最新价风险回报比只有 %.2f（要求 ≥%.1f）", reward/risk, staleMinRiskReward)
+	}
+	return ""
+}
@@ -0,0 +1,35 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntegrationStaleDataGuard(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableStaleDataGuard(90*time.Second, 0.5)
+
+	// AI思考期间ETH跌破止损价2900：开仓转为观望
+	ai.OnRequest(func() { ex.SetPrice("ETHUSDT", 2890) })
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	if len(record.Decisions) != 0 {
+		t.Fatalf("最新价已越过止损，开仓应转为观望: %+v", record.Decisions)
+	}
+	requireExecutionLog(t, record.ExecutionLog, "转为观望")
+	if size := ex.GatePosition("ETHUSDT").size; size != 0 {
+		t.Fatalf("不应开仓，实际持仓 %v", size)
+	}
+	if record.MarketDataAt.IsZero() || record.AIResponseAt.Before(record.MarketDataAt) {
+		t.Fatalf("决策记录应包含行情快照和AI返回时间: %v / %v", record.MarketDataAt, record.AIResponseAt)
+	}
+
+	// 偏离0.7%但止损止盈仍然成立（风险回报比 380/120 ≥ 3）：照常开仓
+	ex.SetPrice("ETHUSDT", 3000)
+	ai.OnRequest(func() { ex.SetPrice("ETHUSDT", 3020) })
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record = runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+	requireExecutionLog(t, record.ExecutionLog, "复核通过")
+}
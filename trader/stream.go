@@ -0,0 +1,91 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/notify"
+	"time"
+)
+
+// 交易所私有实时推送
+// 订阅后交易器的余额/持仓缓存随成交即时更新；持仓在交易所侧被平掉（止损止盈触发、强平、网页端操作）时，
+// 推送通知并提前开始下一个周期，让AI在一分钟内对止损出局做出反应，而不是等到下一次扫描。
+
+// defaultStreamCycleGap 两个周期之间的最短间隔（推送触发的提前周期也遵守）
+const defaultStreamCycleGap = 30 * time.Second
+
+// PositionClosedEvent 交易所推送的平仓事件（不含本程序下单的平仓）
+type PositionClosedEvent struct {
+	Symbol string
+	Side   string // "long" | "short"
+	Reason string // 止损触发 / 止盈触发 / 强平 / ...
+	Time   time.Time
+}
+
+// StreamingTrader 支持私有实时推送的交易器（可选接口）
+type StreamingTrader interface {
+	// StartStream 连接私有推送，持仓在交易所侧被平掉时回调onClosed，返回停止函数
+	StartStream(onClosed func(PositionClosedEvent)) (func(), error)
+}
+
+// realtimeStream 实时推送配置和触发提前周期的通道
+type realtimeStream struct {
+	minCycleGap time.Duration
+	wake        chan PositionClosedEvent
+}
+
+// EnableRealtimeStream 启用交易所私有推送（交易所不支持时忽略），minCycleGap 为提前周期与上个周期的最短间隔
+func (at *AutoTrader) EnableRealtimeStream(minCycleGap time.Duration) {
+	if minCycleGap <= 0 {
+		minCycleGap = defaultStreamCycleGap
+	}
+	at.stream = &realtimeStream{minCycleGap: minCycleGap, wake: make(chan PositionClosedEvent, 16)}
+}
+
+// startStream Run启动时连接推送，返回停止函数（未启用或不支持时为空函数）
+func (at *AutoTrader) startStream() func() {
+	if at.stream == nil {
+		return func() {}
+	}
+	streaming, ok := at.trader.(StreamingTrader)
+	if !ok {
+		log.Printf("⚠️ [%s] %s 不支持实时推送，继续按扫描间隔轮询", at.name, at.exchange)
+		return func() {}
+	}
+	stop, err := streaming.StartStream(at.onPositionClosed)
+	if err != nil {
+		log.Printf("⚠️ [%s] 启动实时推送失败，继续按扫描间隔轮询: %v", at.name, err)
+		return func() {}
+	}
+	return stop
+}
+
+// onPositionClosed 交易所侧平仓：推送通知并唤醒交易循环
+func (at *AutoTrader) onPositionClosed(event PositionClosedEvent) {
+	at.publishExchangeClose(event)
+	notify.Send(notify.Event{
+		Type:     "position.closed_by_exchange",
+		Severity: notify.SeverityWarning,
+		TraderID: at.id,
+		Symbol:   event.Symbol,
+		Title:    fmt.Sprintf("%s %s %s", event.Symbol, event.Side, event.Reason),
+		Message:  fmt.Sprintf("%s 的 %s %s 持仓已在交易所侧平仓（%s），将提前开始下一周期", at.name, event.Symbol, event.Side, event.Reason),
+	})
+	select {
+	case at.stream.wake <- event:
+	default: // 已有待处理的唤醒
+	}
+}
+
+// streamCycleDue 推送触发的提前周期是否可以执行（距上个周期太近时跳过，本周期内的平仓下个周期会看到）
+func (at *AutoTrader) streamCycleDue(event PositionClosedEvent) bool {
+	if time.Now().Before(at.backoffUntil) {
+		return false
+	}
+	if since := time.Since(at.lastCycleAt); since < at.stream.minCycleGap {
+		log.Printf("📡 %s %s %s，距上个周期仅 %.0f 秒，不提前执行", event.Symbol, event.Side, event.Reason, since.Seconds())
+		return false
+	}
+	log.Printf("📡 %s %s %s，提前开始下一周期", event.Symbol, event.Side, event.Reason)
+	return true
+}
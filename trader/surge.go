@@ -0,0 +1,96 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"nofx/market"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 成交量/持仓量异动候选
+// 异动扫描（manager）按多个窗口计算成交量和持仓量变化的z分数，超过阈值的币种推送给各trader，
+// 在有效期内（下次扫描前）排到候选币种前面（不在候选池中时加入，来源带 "anomaly"），
+// 并在prompt中标注异动窗口，不依赖外部 AI500/OI Top 接口。
+
+// surgeBook 最近一次扫描的异动
+type surgeBook struct {
+	mu      sync.Mutex
+	alerts  map[string][]market.SurgeAlert // symbol（USDT交易对）-> 超过阈值的窗口
+	expires time.Time
+}
+
+// SetSurgeAlerts 更新异动币种（替换上次扫描的结果），ttl 后失效
+func (at *AutoTrader) SetSurgeAlerts(alerts map[string][]market.SurgeAlert, ttl time.Duration) {
+	at.surges.mu.Lock()
+	defer at.surges.mu.Unlock()
+	at.surges.alerts = alerts
+	at.surges.expires = time.Now().Add(ttl)
+}
+
+// activeSurges 未失效的异动，币种换成trader的计价币种
+func (at *AutoTrader) activeSurges(now time.Time) map[string][]market.SurgeAlert {
+	at.surges.mu.Lock()
+	defer at.surges.mu.Unlock()
+	if len(at.surges.alerts) == 0 || now.After(at.surges.expires) {
+		return nil
+	}
+	active := make(map[string][]market.SurgeAlert, len(at.surges.alerts))
+	for symbol, alerts := range at.surges.alerts {
+		if quote := at.config.QuoteAsset; quote != "" && quote != market.DefaultQuote {
+			symbol = market.WithQuote(symbol, quote)
+		}
+		active[symbol] = alerts
+	}
+	return active
+}
+
+// addSurgeCandidates 把异动币种写入上下文并排到候选币种前面（黑名单和即将下架的币种除外）
+func (at *AutoTrader) addSurgeCandidates(ctx *decision.Context) {
+	surges := at.activeSurges(time.Now())
+	if len(surges) == 0 {
+		return
+	}
+	ctx.Surges = make(map[string][]market.SurgeAlert, len(surges))
+	var coins []decision.CandidateCoin
+	for _, symbol := range sortedSurgeSymbols(surges) {
+		if !at.symbolFilter.Allowed(symbol) || !at.delistingFilter.Allowed(symbol) {
+			continue
+		}
+		ctx.Surges[symbol] = surges[symbol]
+		coin := decision.CandidateCoin{Symbol: symbol}
+		for _, c := range ctx.CandidateCoins {
+			if c.Symbol == symbol {
+				coin.Sources = append(coin.Sources, c.Sources...)
+				break
+			}
+		}
+		coin.Sources = append(coin.Sources, "anomaly")
+		coins = append(coins, coin)
+	}
+	for _, c := range ctx.CandidateCoins {
+		if _, ok := ctx.Surges[c.Symbol]; !ok {
+			coins = append(coins, c)
+		}
+	}
+	ctx.CandidateCoins = coins
+}
+
+// sortedSurgeSymbols 按最强z分数从高到低排序的币种（每个币种的异动已按强度排序）
+func sortedSurgeSymbols(surges map[string][]market.SurgeAlert) []string {
+	symbols := make([]string, 0, len(surges))
+	for symbol, alerts := range surges {
+		if len(alerts) > 0 {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		si, sj := math.Abs(surges[symbols[i]][0].Z), math.Abs(surges[symbols[j]][0].Z)
+		if si != sj {
+			return si > sj
+		}
+		return symbols[i] < symbols[j]
+	})
+	return symbols
+}
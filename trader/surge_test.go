@@ -0,0 +1,39 @@
+package trader
+
+import (
+	"nofx/market"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegrationSurgeCandidates(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.symbolFilter.Update([]string{"DOGEUSDT"}, nil)
+
+	at.SetSurgeAlerts(map[string][]market.SurgeAlert{
+		"SOLUSDT":  {{Symbol: "SOLUSDT", Window: "15m", Metric: "volume", Z: 4.5, Change: 3.2}, {Symbol: "SOLUSDT", Window: "1h", Metric: "oi", Z: -3.1, Change: -2.4}},
+		"DOGEUSDT": {{Symbol: "DOGEUSDT", Window: "5m", Metric: "volume", Z: 6}},
+	}, time.Hour)
+
+	ai.Enqueue(t, "观望。")
+	runCycle(t, at)
+	prompt := ai.Prompts()[0]
+	for _, want := range []string{"SOLUSDT (异动)", "**异动**: 15m volume z=4.5 (3.2x avg); 1h OI z=-3.1 (-2.40%)"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("AI输入中缺少 %q", want)
+		}
+	}
+	if strings.Contains(prompt, "DOGEUSDT") {
+		t.Error("黑名单币种不应因异动加入候选")
+	}
+
+	// 失效后不再加入
+	at.SetSurgeAlerts(map[string][]market.SurgeAlert{"SOLUSDT": {{Symbol: "SOLUSDT", Window: "15m", Metric: "volume", Z: 4.5}}}, -time.Second)
+	ai.Enqueue(t, "观望。")
+	runCycle(t, at)
+	if prompts := ai.Prompts(); strings.Contains(prompts[len(prompts)-1], "(异动)") {
+		t.Error("失效的异动不应写入AI输入")
+	}
+}
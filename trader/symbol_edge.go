@@ -0,0 +1,33 @@
+package trader
+
+import (
+	"log"
+	"nofx/decision"
+)
+
+// defaultSymbolEdgeDays 分币种历史表现的默认统计天数
+const defaultSymbolEdgeDays = 14
+
+// addSymbolEdges 把最近的分币种表现（已实现盈亏、胜率、平均R）加入交易上下文
+func (at *AutoTrader) addSymbolEdges(ctx *decision.Context) {
+	days := at.config.SymbolEdgeDays
+	if days <= 0 {
+		return
+	}
+	edges, err := at.decisionLogger.SymbolEdges(days, at.localNow())
+	if err != nil {
+		log.Printf("⚠️  统计分币种表现失败: %v", err)
+		return
+	}
+	ctx.SymbolEdgeDays = days
+	for _, e := range edges {
+		ctx.SymbolEdges = append(ctx.SymbolEdges, decision.SymbolEdge{
+			Symbol:      e.Symbol,
+			Trades:      e.Trades,
+			WinRate:     e.WinRate,
+			RealizedPnL: e.RealizedPnL,
+			AvgR:        e.AvgR,
+			RTrades:     e.RTrades,
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package trader
+
+import (
+	"nofx/decision"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationSymbolEdgeInPrompt(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	// 开多 0.5 ETH @ 3000，止损 2900（风险 50 USDT），3100 平仓盈利 50 USDT = +1R
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	ex.SetPrice("ETHUSDT", 3100)
+	ai.Enqueue(t, "止盈。", decision.Decision{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "到达目标"})
+	requireActionSuccess(t, runCycle(t, at), "close_long")
+
+	runCycle(t, at)
+	prompts := ai.Prompts()
+	last := prompts[len(prompts)-1]
+	for _, want := range []string{"你的分币种历史表现（近14天已平仓交易）", "ETHUSDT | 1 | 100% | +50.00 USDT | +1.00R"} {
+		if !strings.Contains(last, want) {
+			t.Errorf("AI输入中缺少 %q", want)
+		}
+	}
+	if strings.Contains(prompts[0], "分币种历史表现") {
+		t.Error("没有已平仓交易时不应输出分币种表现")
+	}
+}
@@ -0,0 +1,52 @@
+package trader
+
+import (
+	"context"
+	"nofx/market"
+	"testing"
+)
+
+func TestIntegrationSymbolProviders(t *testing.T) {
+	alt := newMockExchange(t) // 需要在 setupIntegration 切换目录前创建
+	setupIntegration(t)
+	alt.SetPrice("ETHUSDT", 3100)
+
+	market.RegisterProvider("it_alt", &latencyProvider{BinanceProvider: market.NewBinanceProviderWithBaseURL(alt.URL()), name: "it_alt"})
+	market.RegisterProvider("it_down", &latencyProvider{BinanceProvider: market.NewBinanceProviderWithBaseURL(alt.URL()), name: "it_down", fail: true})
+	t.Cleanup(func() { market.SetSymbolProviders(nil) })
+
+	if err := market.SetSymbolProviders(map[string]string{"DOGEUSDT": "it_missing"}); err == nil {
+		t.Fatal("未注册的数据源应报错")
+	}
+	if err := market.SetSymbolProviders(map[string]string{"ETH": "it_alt", "BTCUSDT": "it_down"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// ETH（按币种配置）来自指定数据源
+	data, err := market.Get("ETHUSDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.CurrentPrice != 3100 {
+		t.Fatalf("ETHUSDT 应来自 it_alt（3100），实际 %v", data.CurrentPrice)
+	}
+
+	// 指定数据源故障时回退到默认数据源
+	data, err = market.Get("BTCUSDT")
+	if err != nil {
+		t.Fatalf("应回退到默认数据源: %v", err)
+	}
+	if data.CurrentPrice != 60000 {
+		t.Fatalf("BTCUSDT 应来自默认数据源（60000），实际 %v", data.CurrentPrice)
+	}
+
+	// 启动校验：数据源拿不到该币种时移除映射
+	warnings := market.ValidateSymbolProviders(context.Background())
+	if len(warnings) != 1 {
+		t.Fatalf("应有1个不可用的映射，实际 %v", warnings)
+	}
+	mapping := market.SymbolProviders()
+	if _, ok := mapping["BTCUSDT"]; ok || mapping["ETH"] != "it_alt" {
+		t.Fatalf("校验后应只保留 ETH -> it_alt，实际 %v", mapping)
+	}
+}
@@ -0,0 +1,41 @@
+package trader
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// trader时区
+// 配置 timezone（IANA名称，如 Asia/Shanghai、America/New_York）后，日盈亏和降风险阶梯的日重置、日报、
+// 分币种统计都按该时区的自然日划分，而不是服务器时区；夏令时切换日按实际的23/25小时计算。
+
+// loadTraderLocation 解析trader时区，空表示服务器本地时区
+func loadTraderLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区 %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// Location trader所在时区
+func (at *AutoTrader) Location() *time.Location {
+	return at.location
+}
+
+// localNow trader时区的当前时间
+func (at *AutoTrader) localNow() time.Time {
+	return time.Now().In(at.location)
+}
+
+// dayChanged 上次日重置之后是否跨过了trader时区的零点
+func (at *AutoTrader) dayChanged(now time.Time) bool {
+	y1, m1, d1 := at.lastResetTime.In(at.location).Date()
+	y2, m2, d2 := now.In(at.location).Date()
+	return y1 != y2 || m1 != m2 || d1 != d2
+}
@@ -0,0 +1,60 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraderDayChangedAcrossDST(t *testing.T) {
+	newYork, err := loadTraderLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := &AutoTrader{location: newYork}
+
+	// 2026-03-08 为夏令时开始日（当天只有23小时）
+	at.lastResetTime = time.Date(2026, 3, 8, 0, 30, 0, 0, newYork)
+	if at.dayChanged(time.Date(2026, 3, 8, 23, 30, 0, 0, newYork)) {
+		t.Error("同一自然日内不应重置")
+	}
+	// 距上次重置不到24小时，但已跨过纽约零点
+	if !at.dayChanged(time.Date(2026, 3, 9, 0, 10, 0, 0, newYork)) {
+		t.Error("跨过trader时区零点后应重置")
+	}
+
+	if _, err := loadTraderLocation("Mars/Olympus"); err == nil {
+		t.Error("无效时区应报错")
+	}
+}
+
+func TestIntegrationDailyReportInTraderTimezone(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	// UTC+14：与服务器时区的自然日几乎总是不同，记录文件按服务器日期命名
+	kiritimati, err := loadTraderLocation("Pacific/Kiritimati")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at.location = kiritimati
+
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+
+	report, err := at.decisionLogger.BuildDailyReport(at.localNow(), 0.05)
+	if err != nil {
+		t.Fatalf("生成日报失败: %v", err)
+	}
+	if report.Cycles != 1 || report.Opens != 1 || report.Date != at.localNow().Format("2006-01-02") {
+		t.Fatalf("trader时区当天的日报不正确: %+v", report)
+	}
+	yesterday, err := at.decisionLogger.BuildDailyReport(at.localNow().AddDate(0, 0, -1), 0.05)
+	if err != nil {
+		t.Fatalf("生成日报失败: %v", err)
+	}
+	if yesterday.Cycles != 0 {
+		t.Fatalf("trader时区前一天不应包含本周期: %+v", yesterday)
+	}
+	if status := at.GetStatus(); status["timezone"] != "Pacific/Kiritimati" {
+		t.Errorf("状态中的时区 = %v", status["timezone"])
+	}
+}
@@ -0,0 +1,230 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/logger"
+	"sort"
+	"time"
+)
+
+// 交易所成交历史导入
+// 安装本系统之前账户已有的交易不在决策日志中，交易表现分析、按币种表现和日报都要从零开始积累。
+// 导入时拉取交易所的成交历史（Gate.io my_trades；币安先从收入历史找出交易过的币种再查询各币种成交），
+// 按币种（双向持仓模式按币种+方向）从空仓开始累计仓位，重建为一笔笔完整的交易：
+// 第一笔成交写一条开仓记录（加权平均开仓价），仓位回到零时写一条平仓记录（加权平均平仓价），反向成交超出仓位的部分开始新的交易。
+// 只导入本系统第一条决策记录之前的成交（之后的交易已经在决策日志中）；导入区间结束时仍未平仓的交易只写开仓记录，
+// 之后由本系统平仓时可以匹配。区间开始前已有的持仓无法得知开仓价，平掉这部分持仓的成交会被跳过。
+
+// maxTradeImportDays 最多导入的天数
+const maxTradeImportDays = 365
+
+// HistoricalFill 交易所成交历史中的一笔成交
+type HistoricalFill struct {
+	ID           string    // 成交ID
+	Symbol       string    // 币种
+	Side         string    // buy/sell
+	PositionSide string    // 双向持仓模式的 long/short，单向持仓为空
+	Quantity     float64   // 数量（基础资产）
+	Price        float64   // 成交价
+	ClosedQty    float64   // 其中平仓的数量（交易所未提供时为0）
+	Time         time.Time // 成交时间
+}
+
+// TradeHistoryProvider 可以查询成交历史的交易器（可选接口）
+type TradeHistoryProvider interface {
+	// GetTradeHistory 获取 [since, until) 之间的成交，按时间正序
+	GetTradeHistory(since, until time.Time) ([]HistoricalFill, error)
+}
+
+// TradeImportResult 一次导入的结果
+type TradeImportResult struct {
+	Since    time.Time `json:"since"`    // 导入区间开始
+	Until    time.Time `json:"until"`    // 导入区间结束（本系统第一条决策记录的时间）
+	Fills    int       `json:"fills"`    // 区间内的成交数
+	Trades   int       `json:"trades"`   // 重建的已平仓交易数
+	Open     int       `json:"open"`     // 区间结束时仍未平仓的交易数
+	Skipped  int       `json:"skipped"`  // 平掉区间开始前已有持仓、无法匹配开仓的成交数
+	Imported int       `json:"imported"` // 新写入的记录数（之前已导入的不计）
+}
+
+// importedTrade 从成交重建的一笔交易
+type importedTrade struct {
+	symbol        string
+	side          string // long/short
+	openID        string // 第一笔开仓成交ID
+	closeID       string // 最后一笔平仓成交ID
+	openQty       float64
+	openNotional  float64
+	closeQty      float64
+	closeNotional float64
+	openTime      time.Time
+	closeTime     time.Time
+	closed        bool
+}
+
+// remaining 未平仓数量
+func (t *importedTrade) remaining() float64 {
+	return t.openQty - t.closeQty
+}
+
+// ImportTradeHistory 导入最近 days 天内、本系统第一条决策记录之前的交易所成交
+func (at *AutoTrader) ImportTradeHistory(days int) (*TradeImportResult, error) {
+	provider, ok := at.trader.(TradeHistoryProvider)
+	if !ok {
+		return nil, fmt.Errorf("%s 不支持查询成交历史", at.exchange)
+	}
+	if days <= 0 || days > maxTradeImportDays {
+		return nil, fmt.Errorf("导入天数应在1-%d之间: %d", maxTradeImportDays, days)
+	}
+
+	until, err := at.decisionLogger.FirstCycleTime()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if until.IsZero() {
+		until = now
+	}
+	result := &TradeImportResult{Since: now.AddDate(0, 0, -days), Until: until}
+	if !result.Since.Before(until) {
+		return result, nil
+	}
+
+	fills, err := provider.GetTradeHistory(result.Since, until)
+	if err != nil {
+		return nil, fmt.Errorf("获取成交历史失败: %w", err)
+	}
+	sort.SliceStable(fills, func(i, j int) bool { return fills[i].Time.Before(fills[j].Time) })
+	result.Fills = len(fills)
+
+	trades, skipped := reconstructTrades(fills)
+	result.Skipped = skipped
+	for _, trade := range trades {
+		if trade.closed {
+			result.Trades++
+		} else {
+			result.Open++
+		}
+		written, err := at.logImportedTrade(trade)
+		result.Imported += written
+		if err != nil {
+			return result, err
+		}
+	}
+
+	log.Printf("📥 [%s] 导入成交历史: %s ~ %s，%d笔成交 → %d笔已平仓交易、%d笔未平仓（跳过%d笔无法匹配开仓的成交），新写入%d条记录",
+		at.name, result.Since.Format("2006-01-02"), until.Format("2006-01-02 15:04"),
+		result.Fills, result.Trades, result.Open, result.Skipped, result.Imported)
+	return result, nil
+}
+
+// reconstructTrades 按成交累计仓位，重建完整的交易（按开仓时间排序），返回交易和跳过的成交数
+func reconstructTrades(fills []HistoricalFill) ([]*importedTrade, int) {
+	const eps = 1e-9
+	var trades []*importedTrade
+	open := make(map[string]*importedTrade) // symbol_持仓方向 -> 未平仓交易
+	skipped := 0
+
+	for _, f := range fills {
+		if f.Quantity <= 0 || f.Price <= 0 {
+			continue
+		}
+		key := f.Symbol + "_" + f.PositionSide
+		qty := f.Quantity
+		closedLeft := f.ClosedQty // 交易所标记的平仓数量中还没有匹配到持仓的部分
+		for qty > eps {
+			cur := open[key]
+			if cur == nil {
+				// 空仓时的平仓成交属于区间开始前已有的持仓
+				if closedLeft > eps {
+					closed := math.Min(closedLeft, qty)
+					qty -= closed
+					closedLeft = 0
+					skipped++
+					if qty <= eps {
+						break
+					}
+				}
+				side := "long"
+				if f.Side == "sell" {
+					side = "short"
+				}
+				if f.PositionSide != "" && f.PositionSide != side {
+					// 双向持仓模式下减少该方向仓位的成交，同样属于区间开始前的持仓
+					skipped++
+					break
+				}
+				cur = &importedTrade{symbol: f.Symbol, side: side, openID: f.ID, openTime: f.Time}
+				open[key] = cur
+				trades = append(trades, cur)
+			}
+
+			if (cur.side == "long") == (f.Side == "buy") {
+				cur.openQty += qty
+				cur.openNotional += qty * f.Price
+				break
+			}
+
+			closed := math.Min(qty, cur.remaining())
+			cur.closeQty += closed
+			cur.closeNotional += closed * f.Price
+			cur.closeID = f.ID
+			cur.closeTime = f.Time
+			qty -= closed
+			closedLeft -= closed
+			if cur.remaining() <= eps*math.Max(cur.openQty, 1) {
+				cur.closed = true
+				delete(open, key)
+			}
+			if f.PositionSide != "" {
+				break // 双向持仓模式下反向成交不会开出反向仓位
+			}
+		}
+	}
+	return trades, skipped
+}
+
+// logImportedTrade 把重建的交易写成开仓记录和平仓记录，返回新写入的记录数
+func (at *AutoTrader) logImportedTrade(trade *importedTrade) (int, error) {
+	ids := []string{trade.openID + "_open"}
+	actions := []logger.DecisionAction{{
+		Action:    "open_" + trade.side,
+		Symbol:    trade.symbol,
+		Quantity:  trade.openQty,
+		Leverage:  1, // 成交历史中没有杠杆，按1倍记录（盈亏百分比相对名义价值）
+		Price:     trade.openNotional / trade.openQty,
+		Timestamp: trade.openTime,
+		Success:   true,
+	}}
+	if trade.closed {
+		ids = append(ids, trade.closeID+"_close")
+		actions = append(actions, logger.DecisionAction{
+			Action:    "close_" + trade.side,
+			Symbol:    trade.symbol,
+			Quantity:  trade.closeQty,
+			Price:     trade.closeNotional / trade.closeQty,
+			Timestamp: trade.closeTime,
+			Success:   true,
+		})
+	}
+
+	written := 0
+	for i, action := range actions {
+		record := &logger.DecisionRecord{
+			Timestamp:    action.Timestamp,
+			Decisions:    []logger.DecisionAction{action},
+			ExecutionLog: []string{fmt.Sprintf("📥 从%s成交历史导入", at.exchange)},
+			Success:      true,
+		}
+		ok, err := at.decisionLogger.LogImported(at.exchange+"_"+ids[i], record)
+		if err != nil {
+			return written, err
+		}
+		if ok {
+			written++
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,99 @@
+package trader
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestIntegrationTradeImportGateio(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+
+	day := func(n int) time.Time { return time.Now().AddDate(0, 0, -n) }
+	ex.AddHistoricalFills(
+		mockFill{exchange: "gateio", symbol: "ETHUSDT", side: "sell", size: 20, closed: 20, price: 1900, time: day(10)}, // 平掉导入区间之前的持仓
+		mockFill{exchange: "gateio", symbol: "ETHUSDT", side: "buy", size: 30, price: 2000, time: day(9)},
+		mockFill{exchange: "gateio", symbol: "ETHUSDT", side: "buy", size: 20, price: 2100, time: day(8)},
+		mockFill{exchange: "gateio", symbol: "ETHUSDT", side: "sell", size: 50, closed: 50, price: 2200, time: day(7)},
+		mockFill{exchange: "gateio", symbol: "ETHUSDT", side: "sell", size: 10, price: 2300, time: day(6)},
+		mockFill{exchange: "gateio", symbol: "ETHUSDT", side: "buy", size: 30, closed: 10, price: 2250, time: day(5)}, // 平空并反手开多20张
+	)
+
+	result, err := at.ImportTradeHistory(30)
+	if err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if result.Fills != 6 || result.Trades != 2 || result.Open != 1 || result.Skipped != 1 || result.Imported != 5 {
+		t.Fatalf("导入结果 = %+v，期望6笔成交 → 2笔已平仓、1笔未平仓、跳过1笔、写入5条记录", result)
+	}
+
+	trades, err := at.GetDecisionLogger().ClosedTrades(30, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 {
+		t.Fatalf("已平仓交易 = %d，期望2", len(trades))
+	}
+	long, short := trades[0], trades[1]
+	if long.Side != "long" || math.Abs(long.Quantity-0.5) > 1e-9 || math.Abs(long.OpenPrice-2040) > 1e-9 || math.Abs(long.PnL-80) > 1e-6 {
+		t.Errorf("多单 = %+v，期望0.5 ETH、均价2040、盈利80", long)
+	}
+	if short.Side != "short" || math.Abs(short.Quantity-0.1) > 1e-9 || math.Abs(short.PnL-5) > 1e-6 {
+		t.Errorf("空单 = %+v，期望0.1 ETH、盈利5", short)
+	}
+	if stats, err := at.GetDecisionLogger().GetStatistics(); err != nil || stats.TotalCycles != 0 || stats.TotalOpenPositions != 3 {
+		t.Errorf("导入记录不应计入周期: %+v (%v)", stats, err)
+	}
+
+	// 重复导入不产生重复记录
+	again, err := at.ImportTradeHistory(30)
+	if err != nil || again.Imported != 0 {
+		t.Fatalf("重复导入应跳过已有记录: %+v (%v)", again, err)
+	}
+
+	// 本系统开始交易之后的成交已经在决策日志中，不再导入
+	runCycle(t, at)
+	ex.AddHistoricalFills(mockFill{exchange: "gateio", symbol: "ETHUSDT", side: "sell", size: 20, price: 2400, time: time.Now().Add(time.Second)})
+	after, err := at.ImportTradeHistory(30)
+	if err != nil || after.Fills != 6 || after.Imported != 0 {
+		t.Fatalf("只应导入第一条决策记录之前的成交: %+v (%v)", after, err)
+	}
+}
+
+func TestIntegrationTradeImportBinanceHedge(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "binance")
+
+	day := func(n int) time.Time { return time.Now().AddDate(0, 0, -n) }
+	ex.AddHistoricalFills(
+		mockFill{exchange: "binance", symbol: "ETHUSDT", side: "buy", positionSide: "SHORT", size: 0.2, closed: 0.2, price: 1900, time: day(20)}, // 区间之前开的空单
+		mockFill{exchange: "binance", symbol: "ETHUSDT", side: "buy", positionSide: "LONG", size: 0.5, price: 2000, time: day(12)},               // 跨7天查询窗口
+		mockFill{exchange: "binance", symbol: "ETHUSDT", side: "sell", positionSide: "SHORT", size: 0.3, price: 2050, time: day(11)},
+		mockFill{exchange: "binance", symbol: "ETHUSDT", side: "sell", positionSide: "LONG", size: 0.5, closed: 0.5, price: 2100, time: day(3)},
+		mockFill{exchange: "binance", symbol: "BTCUSDT", side: "sell", positionSide: "SHORT", size: 0.01, price: 60000, time: day(2)},
+		mockFill{exchange: "binance", symbol: "BTCUSDT", side: "buy", positionSide: "SHORT", size: 0.01, closed: 0.01, price: 59000, time: day(1)},
+	)
+
+	result, err := at.ImportTradeHistory(30)
+	if err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if result.Fills != 6 || result.Trades != 2 || result.Open != 1 || result.Skipped != 1 {
+		t.Fatalf("导入结果 = %+v，期望6笔成交 → 2笔已平仓（ETH多、BTC空）、1笔未平仓（ETH空）、跳过1笔", result)
+	}
+
+	analysis, err := at.GetDecisionLogger().AnalyzePerformance(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.TotalTrades != 2 || analysis.WinningTrades != 2 {
+		t.Fatalf("表现分析 = %d笔交易、%d笔盈利，期望导入的2笔盈利交易", analysis.TotalTrades, analysis.WinningTrades)
+	}
+	if eth := analysis.SymbolStats["ETHUSDT"]; eth == nil || math.Abs(eth.TotalPnL-50) > 1e-6 {
+		t.Errorf("ETH 盈亏 = %+v，期望50", eth)
+	}
+	if btc := analysis.SymbolStats["BTCUSDT"]; btc == nil || math.Abs(btc.TotalPnL-10) > 1e-6 {
+		t.Errorf("BTC 盈亏 = %+v，期望10", btc)
+	}
+}
@@ -0,0 +1,165 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+)
+
+// 条件单触发价检查
+// 挂止损止盈之前，把触发价与最新价、标记价和现货指数价比较：触发价已经越过其中任何一个价格（多仓止损不低于、
+// 止盈不高于；空仓相反）时，交易所会立即触发，新开的仓位马上被平掉。
+// 开仓后的保护单按策略处理：
+//   - rederive：按实际成交价重新推导（保持AI给出的止损/止盈相对决策时价格的距离），推导后仍会立即触发则不挂
+//   - reject：不挂该条件单，保护单错误中说明原因
+// 调整止损止盈、部分平仓和加仓后重新挂单时只检查，会立即触发的不挂并返回错误。
+// 标记价和指数价取自行情源（支持时），取不到的价格不参与比较。
+
+// 触发价处理策略
+const (
+	TriggerPolicyRederive = "rederive"
+	TriggerPolicyReject   = "reject"
+)
+
+// triggerCheck 触发价检查配置
+type triggerCheck struct {
+	policy string
+}
+
+// EnableTriggerPriceCheck 启用挂条件单前的触发价检查
+func (at *AutoTrader) EnableTriggerPriceCheck(policy string) {
+	if policy != TriggerPolicyReject {
+		policy = TriggerPolicyRederive
+	}
+	at.triggerCheck = &triggerCheck{policy: policy}
+}
+
+// referencePrice 判断触发价时参照的一个价格
+type referencePrice struct {
+	name  string
+	price float64
+}
+
+// triggerReferences 最新价、标记价和现货指数价（获取失败的省略）
+func (at *AutoTrader) triggerReferences(symbol string) []referencePrice {
+	var refs []referencePrice
+	if last, err := at.trader.GetMarketPrice(symbol); err == nil && last > 0 {
+		refs = append(refs, referencePrice{"最新价", last})
+	}
+	provider, err := market.ProviderFor(symbol)
+	if err != nil {
+		return refs
+	}
+	if ip, ok := provider.(market.IndexPriceProvider); ok {
+		mark, index, err := ip.GetIndexPrice(symbol)
+		if err != nil {
+			log.Printf("  ⚠ 获取 %s 标记价/指数价失败，只按最新价检查触发价: %v", symbol, err)
+			return refs
+		}
+		if mark > 0 {
+			refs = append(refs, referencePrice{"标记价", mark})
+		}
+		if index > 0 {
+			refs = append(refs, referencePrice{"指数价", index})
+		}
+	}
+	return refs
+}
+
+// immediateTrigger 触发价会立即触发时返回错误（说明越过了哪个价格）
+func immediateTrigger(side, kind string, price float64, refs []referencePrice) error {
+	if price <= 0 {
+		return nil
+	}
+	// 多仓止损和空仓止盈在价格下跌到触发价时触发，其余在上涨到触发价时触发
+	fallsTo := (side == "long") == (kind == "stop_loss")
+	for _, ref := range refs {
+		if (fallsTo && price >= ref.price) || (!fallsTo && price <= ref.price) {
+			name := "止损"
+			if kind == "take_profit" {
+				name = "止盈"
+			}
+			relation := "不低于"
+			if !fallsTo {
+				relation = "不高于"
+			}
+			return fmt.Errorf("%s仓%s触发价 %.4f %s当前%s %.4f，挂单会立即触发", sideName(side), name, price, relation, ref.name, ref.price)
+		}
+	}
+	return nil
+}
+
+// sideName 持仓方向的中文名
+func sideName(side string) string {
+	if side == "short" {
+		return "空"
+	}
+	return "多"
+}
+
+// checkTriggers 重新挂保护单前检查止损止盈（未启用时不检查）
+func (at *AutoTrader) checkTriggers(symbol, side string, stops protectionPrices) (slErr, tpErr error) {
+	if at.triggerCheck == nil {
+		return nil, nil
+	}
+	refs := at.triggerReferences(symbol)
+	return immediateTrigger(side, "stop_loss", stops.StopLoss, refs), immediateTrigger(side, "take_profit", stops.TakeProfit, refs)
+}
+
+// guardEntryTriggers 开仓成交后检查止损止盈，按策略重新推导（更新 d 和持仓记录的止损止盈），返回不能挂的条件单的错误
+func (at *AutoTrader) guardEntryTriggers(d *decision.Decision, side string, actionRecord *logger.DecisionAction) (slErr, tpErr error) {
+	if at.triggerCheck == nil {
+		return nil, nil
+	}
+	refs := at.triggerReferences(d.Symbol)
+	slErr = immediateTrigger(side, "stop_loss", d.StopLoss, refs)
+	tpErr = immediateTrigger(side, "take_profit", d.TakeProfit, refs)
+	if (slErr == nil && tpErr == nil) || at.triggerCheck.policy != TriggerPolicyRederive {
+		return slErr, tpErr
+	}
+
+	// 按成交价重新推导：止损止盈随成交价相对决策时价格的偏移平移
+	planned := actionRecord.IntendedPrice
+	if planned <= 0 {
+		planned = actionRecord.Price
+	}
+	shift := actionRecord.EntryPrice - planned
+	if at.config.AnchorStopsToFill && actionRecord.FillPrice > 0 {
+		shift = actionRecord.Price - planned // 已按成交价相对下单时市价平移过，只补上决策后到下单时的偏移
+	}
+	if sl, tp, ok := at.anchorStops(d, shift); ok && planned > 0 && shift != 0 {
+		if immediateTrigger(side, "stop_loss", sl, refs) == nil && immediateTrigger(side, "take_profit", tp, refs) == nil {
+			log.Printf("  📐 %s 止损止盈会立即触发，按成交价 %g 重新推导（决策时 %g）: 止损 %g→%g 止盈 %g→%g",
+				d.Symbol, actionRecord.EntryPrice, planned, d.StopLoss, sl, d.TakeProfit, tp)
+			d.StopLoss, d.TakeProfit = sl, tp
+			actionRecord.StopLoss, actionRecord.TakeProfit = sl, tp
+			at.setPositionProtection(d.Symbol+"_"+side, &protectionPrices{StopLoss: sl, TakeProfit: tp}, false)
+			return nil, nil
+		}
+	}
+	note := "，按成交价重新推导后仍会立即触发"
+	if slErr != nil {
+		slErr = fmt.Errorf("%w%s", slErr, note)
+	}
+	if tpErr != nil {
+		tpErr = fmt.Errorf("%w%s", tpErr, note)
+	}
+	return slErr, tpErr
+}
+
+// joinTriggerErrors 合并止损和止盈的检查错误（都没有时返回nil）
+func joinTriggerErrors(errs ...error) error {
+	var msgs []string
+	for _, err := range errs {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(msgs, "；"))
+}
@@ -0,0 +1,124 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+
+	"nofx/decision"
+)
+
+func TestIntegrationTriggerCheckRederive(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableTriggerPriceCheck(TriggerPolicyRederive)
+
+	// AI思考期间ETH跌到2890，成交价已低于止损2900：按成交价平移110后挂单
+	ai.OnRequest(func() { ex.SetPrice("ETHUSDT", 2890) })
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+	action := record.Decisions[0]
+	if action.ProtectionError != "" {
+		t.Fatalf("重新推导后应能挂保护单: %s", action.ProtectionError)
+	}
+	if action.StopLoss >= 2890 || action.TakeProfit >= 3400 {
+		t.Fatalf("止损止盈应随成交价下移: 止损 %v 止盈 %v", action.StopLoss, action.TakeProfit)
+	}
+	triggers := ex.Triggers("gateio", "open")
+	if len(triggers) != 2 {
+		t.Fatalf("应挂止损和止盈，实际 %d 个条件单", len(triggers))
+	}
+	for _, tr := range triggers {
+		if tr.kind == "stop_loss" && tr.price != action.StopLoss {
+			t.Fatalf("止损触发价 %v，应为 %v", tr.price, action.StopLoss)
+		}
+	}
+	if size := ex.GatePosition("ETHUSDT").size; size == 0 {
+		t.Fatal("持仓不应被立即平掉")
+	}
+}
+
+func TestIntegrationTriggerCheckReject(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	at.EnableTriggerPriceCheck(TriggerPolicyReject)
+
+	// 指数价2880低于止损2900：止损会立即触发，不挂；止盈照常挂
+	ex.SetIndexPrice("ETHUSDT", 2880)
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	record := runCycle(t, at)
+	requireActionSuccess(t, record, "open_long")
+	action := record.Decisions[0]
+	if !strings.Contains(action.ProtectionError, "指数价") || !strings.Contains(action.ProtectionError, "立即触发") {
+		t.Fatalf("保护单错误应说明越过指数价: %q", action.ProtectionError)
+	}
+	triggers := ex.Triggers("gateio", "open")
+	if len(triggers) != 1 || triggers[0].kind != "take_profit" {
+		t.Fatalf("应只挂止盈: %+v", triggers)
+	}
+	if size := ex.GatePosition("ETHUSDT").size; size == 0 {
+		t.Fatal("持仓不应被立即平掉")
+	}
+}
+
+func TestIntegrationTriggerCheckKeepsExistingOrders(t *testing.T) {
+	ex, ai := setupIntegration(t)
+	at := newIntegrationTrader(t, ex, ai, "gateio")
+	ai.Enqueue(t, "开多。", openLongETH(1500))
+	requireActionSuccess(t, runCycle(t, at), "open_long")
+	at.EnableTriggerPriceCheck(TriggerPolicyReject)
+	before := make(map[string]int64)
+	for _, tr := range ex.Triggers("gateio", "open") {
+		before[tr.kind] = tr.id
+	}
+
+	// 指数价2960：上移止损到2970会立即触发，调整失败，原有止损止盈保留
+	ex.SetIndexPrice("ETHUSDT", 2960)
+	ai.Enqueue(t, "上移止损。", decision.Decision{Symbol: "ETHUSDT", Action: "adjust_sl", StopLoss: 2970, Reasoning: "保护利润"})
+	record := runCycle(t, at)
+	if len(record.Decisions) != 1 || record.Decisions[0].Success || !strings.Contains(record.Decisions[0].Error, "指数价") {
+		t.Fatalf("调整止损应因指数价失败: %+v", record.Decisions)
+	}
+	if open := ex.Triggers("gateio", "open"); len(open) != 2 || open[0].id != before[open[0].kind] || open[1].id != before[open[1].kind] {
+		t.Fatalf("原有条件单应保留: %+v", open)
+	}
+
+	// 指数价跌破原止损：重新挂单时止损不撤不挂，只替换止盈
+	ex.SetIndexPrice("ETHUSDT", 2890)
+	if err := at.replaceProtection("ETHUSDT", "long", 0.5); err == nil || !strings.Contains(err.Error(), "止损") {
+		t.Fatalf("止损会立即触发时应返回错误: %v", err)
+	}
+	open := ex.Triggers("gateio", "open")
+	if len(open) != 2 {
+		t.Fatalf("应保留止损并重新挂止盈: %+v", open)
+	}
+	for _, tr := range open {
+		if tr.kind == "stop_loss" && tr.id != before["stop_loss"] || tr.kind == "take_profit" && tr.id == before["take_profit"] {
+			t.Fatalf("止损应保留原单、止盈应重新挂单: %+v", open)
+		}
+	}
+}
+
+func TestImmediateTrigger(t *testing.T) {
+	refs := []referencePrice{{"最新价", 100}, {"标记价", 101}}
+	cases := []struct {
+		side, kind string
+		price      float64
+		fires      bool
+	}{
+		{"long", "stop_loss", 99, false},
+		{"long", "stop_loss", 100, true},
+		{"long", "take_profit", 101, true},
+		{"long", "take_profit", 102, false},
+		{"short", "stop_loss", 101, true},
+		{"short", "stop_loss", 102, false},
+		{"short", "take_profit", 99, false},
+		{"short", "take_profit", 100.5, true},
+		{"long", "stop_loss", 0, false},
+	}
+	for _, c := range cases {
+		if err := immediateTrigger(c.side, c.kind, c.price, refs); (err != nil) != c.fires {
+			t.Errorf("%s %s %v: err=%v，期望立即触发=%v", c.side, c.kind, c.price, err, c.fires)
+		}
+	}
+}
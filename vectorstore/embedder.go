@@ -0,0 +1,169 @@
+package vectorstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Embedder 把文本转换为向量（可插拔：本地哈希向量或OpenAI兼容的 /embeddings 接口）
+type Embedder interface {
+	// Name 向量空间标识（不同Embedder生成的向量不能互相比较，存储时一并记录）
+	Name() string
+	Embed(texts []string) ([][]float64, error)
+}
+
+// NewEmbedder 按提供者创建Embedder
+// provider: "local"（默认，不需要网络）或 "openai"（任意OpenAI兼容的embeddings接口）
+func NewEmbedder(provider, baseURL, apiKey, model string) (Embedder, error) {
+	switch provider {
+	case "", "local":
+		return NewHashEmbedder(defaultHashDim), nil
+	case "openai":
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		if model == "" {
+			model = "text-embedding-3-small"
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("openai embedding需要api_key")
+		}
+		return &OpenAIEmbedder{
+			BaseURL: strings.TrimRight(baseURL, "/"),
+			APIKey:  apiKey,
+			Model:   model,
+			client:  &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的embedding提供者: %s（可选 local / openai）", provider)
+	}
+}
+
+const defaultHashDim = 256
+
+// HashEmbedder 本地特征哈希向量：文本按词切分，每个词哈希到固定维度（带符号），再做L2归一化。
+// 不理解语义，但对离散化的行情特征（如 "rsi7:oversold"）足够区分相似情形，且无需外部服务。
+type HashEmbedder struct {
+	dim int
+}
+
+// NewHashEmbedder 创建指定维度的哈希向量生成器
+func NewHashEmbedder(dim int) *HashEmbedder {
+	if dim <= 0 {
+		dim = defaultHashDim
+	}
+	return &HashEmbedder{dim: dim}
+}
+
+func (e *HashEmbedder) Name() string {
+	return fmt.Sprintf("hash-%d", e.dim)
+}
+
+func (e *HashEmbedder) Embed(texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vec := make([]float64, e.dim)
+		for _, token := range tokenize(text) {
+			h := fnv.New64a()
+			h.Write([]byte(token))
+			sum := h.Sum64()
+			sign := 1.0
+			if sum&1 == 1 {
+				sign = -1
+			}
+			vec[(sum>>1)%uint64(e.dim)] += sign
+		}
+		vectors[i] = normalize(vec)
+	}
+	return vectors, nil
+}
+
+// tokenize 按空白和标点切分（保留 ":" "_" 连接的特征词），转为小写
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == ':' || r == '_' || r == '+' || r == '-')
+	})
+}
+
+// OpenAIEmbedder OpenAI兼容的embeddings接口
+type OpenAIEmbedder struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	client  *http.Client
+}
+
+func (e *OpenAIEmbedder) Name() string {
+	return "openai:" + e.Model
+}
+
+func (e *OpenAIEmbedder) Embed(texts []string) ([][]float64, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.Model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.BaseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.APIKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求embedding接口失败: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取embedding响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding接口返回 HTTP %d: %s", resp.StatusCode, string(data))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析embedding响应失败: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding数量不匹配: 请求%d条，返回%d条", len(texts), len(result.Data))
+	}
+	vectors := make([][]float64, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding响应的index越界: %d", d.Index)
+		}
+		vectors[d.Index] = normalize(d.Embedding)
+	}
+	return vectors, nil
+}
+
+// normalize L2归一化（之后余弦相似度即点积）
+func normalize(vec []float64) []float64 {
+	norm := 0.0
+	for _, v := range vec {
+		norm += v * v
+	}
+	if norm == 0 {
+		return vec
+	}
+	norm = math.Sqrt(norm)
+	for i := range vec {
+		vec[i] /= norm
+	}
+	return vec
+}
@@ -0,0 +1,216 @@
+package vectorstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 历史决策向量库
+// 每次开仓时把当时的行情情形（离散化的指标特征）和AI的理由向量化保存，平仓后补上结果；
+// 新周期按币种检索最相似的已有结果的情形，写入AI提示作为「相似的历史情形及结果」。
+// 存储为本地 JSON Lines 文件（每行一条），启动时全部加载到内存，条目数量随交易次数增长，规模很小。
+
+// Outcome 交易结果
+type Outcome struct {
+	PnL       float64   `json:"pnl"`     // 已实现盈亏（USDT）
+	PnLPct    float64   `json:"pnl_pct"` // 相对保证金的百分比
+	RMultiple *float64  `json:"r_multiple,omitempty"`
+	Duration  string    `json:"duration"`
+	ClosedAt  time.Time `json:"closed_at"`
+}
+
+// Entry 一次开仓时的情形
+type Entry struct {
+	ID         string    `json:"id"`
+	PositionID string    `json:"position_id,omitempty"` // 开仓的持仓ID（用于匹配平仓结果）
+	Symbol     string    `json:"symbol"`
+	Side       string    `json:"side"` // long / short
+	Time       time.Time `json:"time"` // 开仓时间（与决策记录中开仓动作的时间一致）
+	Situation  string    `json:"situation"`
+	Reasoning  string    `json:"reasoning"`
+	Outcome    *Outcome  `json:"outcome,omitempty"` // 未平仓时为nil
+	Embedder   string    `json:"embedder"`
+	Vector     []float64 `json:"vector"`
+}
+
+// Match 检索结果
+type Match struct {
+	Entry
+	Score float64 `json:"score"` // 余弦相似度
+}
+
+// Store 向量库
+type Store struct {
+	path     string
+	embedder Embedder
+
+	mu      sync.RWMutex
+	entries []*Entry
+}
+
+// Open 打开（不存在时创建）向量库文件
+func Open(path string, embedder Embedder) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建向量库目录失败: %w", err)
+	}
+	s := &Store{path: path, embedder: embedder}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+		}
+		s.entries = append(s.entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+	}
+	return s, nil
+}
+
+// Len 条目数量
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// Add 向量化情形并追加保存
+func (s *Store) Add(entry Entry) error {
+	vectors, err := s.embedder.Embed([]string{entry.Situation})
+	if err != nil {
+		return err
+	}
+	entry.Embedder = s.embedder.Name()
+	entry.Vector = vectors[0]
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.entries = append(s.entries, &entry)
+	return nil
+}
+
+// Pending 还没有结果的条目
+func (s *Store) Pending() []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var pending []Entry
+	for _, e := range s.entries {
+		if e.Outcome == nil {
+			pending = append(pending, *e)
+		}
+	}
+	return pending
+}
+
+// SetOutcomes 补上平仓结果（id -> 结果）并重写文件
+func (s *Store) SetOutcomes(outcomes map[string]Outcome) error {
+	if len(outcomes) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if outcome, ok := outcomes[e.ID]; ok {
+			o := outcome
+			e.Outcome = &o
+		}
+	}
+	return s.rewriteLocked()
+}
+
+// rewriteLocked 先写临时文件再rename
+func (s *Store) rewriteLocked() error {
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, e := range s.entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Search 在同一币种已有结果的条目中检索与情形最相似的k条（相似度不低于minScore）
+func (s *Store) Search(symbol, situation string, k int, minScore float64) ([]Match, error) {
+	vectors, err := s.embedder.Embed([]string{situation})
+	if err != nil {
+		return nil, err
+	}
+	query := vectors[0]
+	name := s.embedder.Name()
+
+	s.mu.RLock()
+	var matches []Match
+	for _, e := range s.entries {
+		if e.Symbol != symbol || e.Outcome == nil || e.Embedder != name || len(e.Vector) != len(query) {
+			continue
+		}
+		score := 0.0
+		for i := range query {
+			score += query[i] * e.Vector[i]
+		}
+		if score >= minScore {
+			matches = append(matches, Match{Entry: *e, Score: score})
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Time.After(matches[j].Time)
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}